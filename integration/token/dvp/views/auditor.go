@@ -19,11 +19,11 @@ func (a *AuditView) Call(context view.Context) (interface{}, error) {
 	tx, err := ttx.ReceiveTransaction(context)
 	assert.NoError(err, "failed receiving transaction")
 
-	assert.NoError(tx.IsValid(), "failed verifying transaction")
+	assert.NoError(tx.IsValid(context.Context()), "failed verifying transaction")
 
 	w := ttx.MyAuditorWallet(context)
 	assert.NotNil(w, "failed getting default auditor wallet")
-	assert.NoError(ttx.NewAuditor(context, w).Validate(tx), "failed auditing verification")
+	assert.NoError(ttx.NewAuditor(context, w).Validate(context.Context(), tx), "failed auditing verification")
 
 	return context.RunView(ttx.NewAuditApproveView(w, tx))
 }
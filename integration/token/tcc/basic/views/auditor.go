@@ -28,7 +28,7 @@ func (a *AuditView) Call(context view.Context) (interface{}, error) {
 
 	// Validate
 	auditor := ttxcc.NewAuditor(context, w)
-	assert.NoError(auditor.Validate(tx), "failed auditing verification")
+	assert.NoError(auditor.Validate(context.Context(), tx), "failed auditing verification")
 
 	// Check limits
 
@@ -56,7 +56,7 @@ func (t *SwapInitiatorView) Call(context view.Context) (interface{}, error) {
 	assert.NoError(err, "failed collecting actions")
 
 	// check the content of the transaction
-	assert.NoError(tx.Verify(), "failed verifying transaction")
+	assert.NoError(tx.Verify(context.Context()), "failed verifying transaction")
 
 	outputs, err := tx.Outputs()
 	assert.NoError(err, "failed getting outputs")
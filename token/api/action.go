@@ -18,6 +18,12 @@ type IssueAction interface {
 	GetIssuer() []byte
 }
 
+// AuditableIssueAction is implemented by the issue actions carrying the audit infos of the owners of their outputs,
+// for example encrypted under the auditor's key. The metadata of such an action must carry the same audit infos.
+type AuditableIssueAction interface {
+	GetAuditInfos() [][]byte
+}
+
 type Output interface {
 	Serialize() ([]byte, error)
 	IsRedeem() bool
@@ -0,0 +1,67 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+
+	"github.com/pkg/errors"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+)
+
+// AttachmentSaltSize is the size, in bytes, of the random salt of an attachment
+const AttachmentSaltSize = 32
+
+// Attachment references a payload attached to a transfer, an invoice or a document for instance, that is too large
+// to travel with the token request. The payload is exchanged over a side channel, or stored in a content store at
+// URI, only its commitment is committed with the token request, see AttachmentDigest.
+type Attachment struct {
+	Name string
+	// URI, if set, locates the payload
+	URI string `json:",omitempty"`
+	// Salt is the random key of the commitment, it travels with the metadata only, not to the ledger
+	Salt []byte
+	// Hash is the commitment to the payload, the HMAC-SHA256 of the payload keyed by Salt, see AttachmentCommitment
+	Hash []byte
+}
+
+// NewAttachment returns the attachment of the passed payload, committed to under a fresh random salt
+func NewAttachment(name string, uri string, payload []byte) (*Attachment, error) {
+	salt := make([]byte, AttachmentSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.Wrapf(err, "failed generating salt of attachment [%s]", name)
+	}
+	return &Attachment{Name: name, URI: uri, Salt: salt, Hash: AttachmentCommitment(salt, payload)}, nil
+}
+
+// Verify checks that the passed payload is the one this attachment references
+func (a *Attachment) Verify(payload []byte) error {
+	if len(a.Salt) < AttachmentSaltSize {
+		return errors.Errorf("attachment [%s] has an invalid salt", a.Name)
+	}
+	if !hmac.Equal(a.Hash, AttachmentCommitment(a.Salt, payload)) {
+		return errors2.Errorf(errors2.InvalidProof, "payload does not match the hash of attachment [%s]", a.Name)
+	}
+	return nil
+}
+
+// AttachmentDigest commits, in the token request, to an attachment of a transfer action
+type AttachmentDigest struct {
+	// TransferIndex is the index of the transfer action in the token request
+	TransferIndex int
+	Hash          []byte
+}
+
+// AttachmentCommitment returns the commitment to the passed payload under the passed salt.
+// The ledger sees the commitment only, without the salt a low-entropy payload cannot be recovered by guessing.
+func AttachmentCommitment(salt []byte, payload []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,48 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+)
+
+func TestAttachment(t *testing.T) {
+	payload := []byte("invoice 42, 100 USD")
+	a, err := NewAttachment("invoice", "ipfs://invoice-42", payload)
+	assert.NoError(t, err)
+	assert.NoError(t, a.Verify(payload))
+	err = a.Verify([]byte("invoice 42, 1000 USD"))
+	assert.Error(t, err)
+	assert.True(t, errors2.HasCode(err, errors2.InvalidProof))
+
+	// the commitment is salted, the same payload is committed to differently each time
+	b, err := NewAttachment("invoice", "ipfs://invoice-42", payload)
+	assert.NoError(t, err)
+	assert.Len(t, a.Salt, AttachmentSaltSize)
+	assert.NotEqual(t, a.Salt, b.Salt)
+	assert.NotEqual(t, a.Hash, b.Hash)
+	assert.NotEqual(t, AttachmentCommitment(nil, payload), a.Hash)
+	b.Salt = nil
+	assert.Error(t, b.Verify(payload))
+
+	// the references of the attachments survive filtering, the request commits to their hashes anyway
+	m := &TokenRequestMetadata{Transfers: []TransferMetadata{{Attachments: []*Attachment{a}}}}
+	filtered := m.FilterBy()
+	assert.Equal(t, []*Attachment{a}, filtered.Transfers[0].Attachments)
+
+	// the digests of the attachments are signed
+	r := &TokenRequest{Attachments: []*AttachmentDigest{{TransferIndex: 0, Hash: a.Hash}}}
+	raw, err := r.MarshalToSign()
+	assert.NoError(t, err)
+	r.Attachments[0].Hash = AttachmentCommitment(a.Salt, []byte("another invoice"))
+	raw2, err := r.MarshalToSign()
+	assert.NoError(t, err)
+	assert.NotEqual(t, raw, raw2)
+}
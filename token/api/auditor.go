@@ -5,6 +5,105 @@ SPDX-License-Identifier: Apache-2.0
 */
 package api
 
+import (
+	"sort"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+)
+
 type AuditorService interface {
 	AuditorCheck(tokenRequest *TokenRequest, tokenRequestMetadata *TokenRequestMetadata, txID string) error
 }
+
+// AuditPolicy assigns, per token type, the auditor that must sign the token requests on that type,
+// for example a securities auditor and a cash auditor. A type without an entry is audited by the default auditor.
+type AuditPolicy struct {
+	// Auditors maps a token type, or AnyTokenType, to the serialized identity of its auditor
+	Auditors map[string][]byte `json:",omitempty"`
+}
+
+// Auditor returns the auditor assigned to the passed token type, nil if none
+func (p *AuditPolicy) Auditor(tokenType string) view.Identity {
+	if p == nil {
+		return nil
+	}
+	if auditor, ok := p.Auditors[tokenType]; ok {
+		return auditor
+	}
+	return p.Auditors[AnyTokenType]
+}
+
+// SetAuditor assigns the passed auditor to the passed token type. An empty auditor removes the entry.
+func (p *AuditPolicy) SetAuditor(tokenType string, auditor []byte) {
+	if len(auditor) == 0 {
+		delete(p.Auditors, tokenType)
+		return
+	}
+	if p.Auditors == nil {
+		p.Auditors = map[string][]byte{}
+	}
+	p.Auditors[tokenType] = auditor
+}
+
+// IsEmpty returns true if the policy assigns no auditor
+func (p *AuditPolicy) IsEmpty() bool {
+	return p == nil || len(p.Auditors) == 0
+}
+
+// RequestAuditors returns the auditors that must sign a token request on the passed token types,
+// in the order their signatures appear in the request: the auditors of the types sorted by type, without repetitions.
+// A request on no token type must be signed by the default auditor, if any.
+func RequestAuditors(pp PublicParameters, types []string) []view.Identity {
+	if len(types) == 0 {
+		if auditor := pp.AuditorIdentity(); auditor != nil {
+			return []view.Identity{auditor}
+		}
+		return nil
+	}
+	sorted := append([]string(nil), types...)
+	sort.Strings(sorted)
+	var auditors []view.Identity
+	for i, tokenType := range sorted {
+		if i > 0 && sorted[i-1] == tokenType {
+			continue
+		}
+		auditor := pp.TypeAuditor(tokenType)
+		if auditor == nil {
+			continue
+		}
+		found := false
+		for _, a := range auditors {
+			if a.Equal(auditor) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			auditors = append(auditors, auditor)
+		}
+	}
+	return auditors
+}
+
+// AuditThreshold returns the audit threshold of the passed public parameters, 0 if the driver does not support one
+func AuditThreshold(pp PublicParameters) uint64 {
+	thresholds, ok := pp.(AuditThresholds)
+	if !ok {
+		return 0
+	}
+	return thresholds.AuditThreshold()
+}
+
+// CheckUnaudited checks that the passed token request, carrying no auditor signature, may skip the auditors under
+// the passed audit threshold: it must be made of transfers only.
+// The drivers check that the total of the outputs of the transfers, change included, is below the threshold.
+func CheckUnaudited(tr *TokenRequest, threshold uint64) error {
+	if threshold == 0 {
+		return errors.New("the token request must be signed by the auditor")
+	}
+	if len(tr.Transfers) == 0 || len(tr.Issues) != 0 || len(tr.Migrations) != 0 || len(tr.Rebindings) != 0 {
+		return errors.New("only the token requests made of transfers only can skip the auditor")
+	}
+	return nil
+}
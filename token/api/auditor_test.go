@@ -0,0 +1,97 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/stretchr/testify/assert"
+)
+
+type auditedPublicParams struct {
+	PublicParameters
+	auditor view.Identity
+	policy  *AuditPolicy
+}
+
+func (pp *auditedPublicParams) AuditorIdentity() view.Identity {
+	return pp.auditor
+}
+
+func (pp *auditedPublicParams) TypeAuditor(tokenType string) view.Identity {
+	if auditor := pp.policy.Auditor(tokenType); auditor != nil {
+		return auditor
+	}
+	return pp.auditor
+}
+
+func TestAuditPolicy(t *testing.T) {
+	var nilPolicy *AuditPolicy
+	assert.True(t, nilPolicy.Auditor("USD").IsNone())
+	assert.True(t, nilPolicy.IsEmpty())
+
+	p := &AuditPolicy{}
+	p.SetAuditor("BOND", []byte("securities"))
+	assert.False(t, p.IsEmpty())
+	assert.Equal(t, view.Identity("securities"), p.Auditor("BOND"))
+	assert.True(t, p.Auditor("USD").IsNone())
+
+	p.SetAuditor(AnyTokenType, []byte("cash"))
+	assert.Equal(t, view.Identity("cash"), p.Auditor("USD"))
+
+	p.SetAuditor("BOND", nil)
+	assert.Equal(t, view.Identity("cash"), p.Auditor("BOND"))
+}
+
+func TestRequestAuditors(t *testing.T) {
+	pp := &auditedPublicParams{}
+	assert.Empty(t, RequestAuditors(pp, nil))
+	assert.Empty(t, RequestAuditors(pp, []string{"USD"}))
+
+	pp.auditor = []byte("default")
+	assert.Equal(t, []view.Identity{[]byte("default")}, RequestAuditors(pp, nil))
+	assert.Equal(t, []view.Identity{[]byte("default")}, RequestAuditors(pp, []string{"USD", "EUR"}))
+
+	// the auditors follow the order of the types, each one signs once
+	pp.policy = &AuditPolicy{}
+	pp.policy.SetAuditor("BOND", []byte("securities"))
+	pp.policy.SetAuditor("STOCK", []byte("securities"))
+	assert.Equal(t, []view.Identity{[]byte("securities"), []byte("default")}, RequestAuditors(pp, []string{"USD", "STOCK", "BOND", "USD"}))
+	assert.Equal(t, []view.Identity{[]byte("securities")}, RequestAuditors(pp, []string{"BOND"}))
+
+	// the types without an auditor need no signature
+	pp.auditor = nil
+	assert.Equal(t, []view.Identity{[]byte("securities")}, RequestAuditors(pp, []string{"USD", "BOND"}))
+}
+
+type thresholdPublicParams struct {
+	auditedPublicParams
+	threshold uint64
+}
+
+func (pp *thresholdPublicParams) AuditThreshold() uint64 {
+	return pp.threshold
+}
+
+func TestCheckUnaudited(t *testing.T) {
+	assert.Equal(t, uint64(0), AuditThreshold(&auditedPublicParams{}))
+	assert.Equal(t, uint64(100), AuditThreshold(&thresholdPublicParams{threshold: 100}))
+
+	tr := &TokenRequest{Transfers: [][]byte{[]byte("transfer")}}
+	assert.NoError(t, CheckUnaudited(tr, 100))
+	assert.EqualError(t, CheckUnaudited(tr, 0), "the token request must be signed by the auditor")
+
+	// only the requests made of transfers only can skip the auditor
+	for _, tr := range []*TokenRequest{
+		{},
+		{Transfers: [][]byte{[]byte("transfer")}, Issues: [][]byte{[]byte("issue")}},
+		{Transfers: [][]byte{[]byte("transfer")}, Migrations: [][]byte{[]byte("migration")}},
+		{Transfers: [][]byte{[]byte("transfer")}, Rebindings: [][]byte{[]byte("rebinding")}},
+	} {
+		assert.EqualError(t, CheckUnaudited(tr, 100), "only the token requests made of transfers only can skip the auditor")
+	}
+}
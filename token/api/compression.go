@@ -0,0 +1,69 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// zstdMagic opens every zstd frame. A JSON document cannot start with it, therefore compressed and plain payloads
+// can be told apart and the receivers of a payload do not need to know in advance whether it is compressed.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+var (
+	encoderOnce sync.Once
+	encoder     *zstd.Encoder
+	encoderErr  error
+)
+
+// IsCompressed returns true if the passed payload has been compressed with Compress
+func IsCompressed(raw []byte) bool {
+	return bytes.HasPrefix(raw, zstdMagic)
+}
+
+// Compress compresses the passed payload with zstd
+func Compress(raw []byte) ([]byte, error) {
+	encoderOnce.Do(func() {
+		encoder, encoderErr = zstd.NewWriter(nil)
+	})
+	if encoderErr != nil {
+		return nil, errors.Wrap(encoderErr, "failed creating zstd encoder")
+	}
+	return encoder.EncodeAll(raw, nil), nil
+}
+
+// DefaultMaxDecompressedSize bounds the size of the decompressed payloads when no request size limit is set,
+// so that a small compressed payload cannot exhaust the memory of the node
+const DefaultMaxDecompressedSize = 64 << 20
+
+// Decompress returns the decompression of the passed payload, or the payload itself if not compressed.
+// If maxSize is not zero, payloads decompressing to more than maxSize bytes are rejected.
+func Decompress(raw []byte, maxSize int) ([]byte, error) {
+	if !IsCompressed(raw) {
+		return raw, nil
+	}
+	var opts []zstd.DOption
+	if maxSize > 0 {
+		opts = append(opts, zstd.WithDecoderMaxMemory(uint64(maxSize)))
+	}
+	decoder, err := zstd.NewReader(nil, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating zstd decoder")
+	}
+	defer decoder.Close()
+	res, err := decoder.DecodeAll(raw, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed decompressing payload")
+	}
+	if maxSize > 0 && len(res) > maxSize {
+		return nil, errors.Errorf("decompressed payload size [%d] exceeds limit [%d]", len(res), maxSize)
+	}
+	return res, nil
+}
@@ -0,0 +1,60 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompression(t *testing.T) {
+	tr := &TokenRequest{
+		Issues:    [][]byte{bytes.Repeat([]byte("issue"), 1000)},
+		Transfers: [][]byte{bytes.Repeat([]byte("transfer"), 1000)},
+	}
+	plain, err := tr.Bytes()
+	assert.NoError(t, err)
+	assert.False(t, IsCompressed(plain))
+	compressed, err := tr.CompressedBytes()
+	assert.NoError(t, err)
+	assert.True(t, IsCompressed(compressed))
+	assert.True(t, len(compressed) < len(plain))
+
+	// both serializations unmarshal to the same request
+	for _, raw := range [][]byte{plain, compressed} {
+		tr2 := &TokenRequest{}
+		assert.NoError(t, tr2.FromBytes(raw))
+		assert.Equal(t, tr, tr2)
+	}
+
+	raw, err := Decompress(compressed, len(plain))
+	assert.NoError(t, err)
+	assert.Equal(t, plain, raw)
+	_, err = Decompress(compressed, len(plain)-1)
+	assert.Error(t, err)
+
+	// limits bound the decompressed request
+	opts := &ValidationOptions{Limits: &RequestLimits{MaxSize: len(plain) / 2}}
+	_, err = opts.DecompressRequest(compressed, &ValidationReport{})
+	assert.Error(t, err)
+	_, ok := GetValidationReport(err)
+	assert.True(t, ok)
+
+	// the request size limit bounds the decompression of a request
+	assert.Error(t, (&TokenRequest{}).FromBytesWithLimits(compressed, &RequestLimits{MaxSize: len(plain) / 2}))
+	assert.NoError(t, (&TokenRequest{}).FromBytesWithLimits(compressed, &RequestLimits{MaxSize: len(plain)}))
+	// without limits, the decompression is bounded anyway
+	var limits *RequestLimits
+	assert.Equal(t, DefaultMaxDecompressedSize, limits.DecompressionLimit())
+	assert.Equal(t, DefaultMaxDecompressedSize, (&RequestLimits{}).DecompressionLimit())
+	bomb, err := Compress(make([]byte, DefaultMaxDecompressedSize+1))
+	assert.NoError(t, err)
+	assert.Error(t, (&TokenRequest{}).FromBytes(bomb))
+	_, err = (&ValidationOptions{}).DecompressRequest(bomb, &ValidationReport{})
+	assert.Error(t, err)
+}
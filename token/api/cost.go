@@ -0,0 +1,68 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"time"
+)
+
+// CostEstimate predicts the cost of a token request, or of some of its actions, for the clients to budget
+// and batch their transactions. The figures are estimates, the actual ones depend on the identities involved
+// and on the hardware of the endorsers.
+type CostEstimate struct {
+	// Size is the size, in bytes, of the serialized token request
+	Size int
+	// Proofs is the number of zero-knowledge proofs the validator verifies
+	Proofs int
+	// Signatures is the number of signatures the validator verifies
+	Signatures int
+	// VerificationTime is the expected CPU time of the validation at each endorser
+	VerificationTime time.Duration
+	// Inputs and Outputs count the tokens spent and created
+	Inputs  int
+	Outputs int
+}
+
+// Add adds the passed estimates to this one, and returns it
+func (c *CostEstimate) Add(estimates ...*CostEstimate) *CostEstimate {
+	for _, e := range estimates {
+		c.Size += e.Size
+		c.Proofs += e.Proofs
+		c.Signatures += e.Signatures
+		c.VerificationTime += e.VerificationTime
+		c.Inputs += e.Inputs
+		c.Outputs += e.Outputs
+	}
+	return c
+}
+
+// CostModel is implemented by the token manager services able to estimate the cost of the requests they produce
+type CostModel interface {
+	// EstimateRequest returns the cost of a token request without actions, the auditor's signature for instance
+	EstimateRequest() *CostEstimate
+	// EstimateIssue returns the cost of an issue action with the passed number of outputs
+	EstimateIssue(outputs int) *CostEstimate
+	// EstimateTransfer returns the cost of a transfer action with the passed numbers of inputs and outputs
+	EstimateTransfer(inputs, outputs int) *CostEstimate
+}
+
+// Rough figures the cost models of the drivers build on
+const (
+	// RequestOverhead is the size of the envelope of a serialized token request
+	RequestOverhead = 128
+	// IdentitySize is the size of a serialized x509 identity
+	IdentitySize = 1024
+	// ECDSASignatureSize is the size of an ECDSA signature, ECDSAVerificationTime the time to verify it
+	ECDSASignatureSize    = 72
+	ECDSAVerificationTime = 100 * time.Microsecond
+	// InputKeySize is the size of the ledger key of a token
+	InputKeySize = 96
+)
+
+// Base64Size returns the size of the base64 encoding of the passed number of bytes, as in the JSON encoding of []byte
+func Base64Size(n int) int {
+	return (n + 2) / 3 * 4
+}
@@ -0,0 +1,30 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCostEstimateAdd(t *testing.T) {
+	e := &CostEstimate{Size: 10, Signatures: 1, VerificationTime: time.Millisecond}
+	res := e.Add(
+		&CostEstimate{Size: 5, Proofs: 2, Inputs: 1, Outputs: 2, VerificationTime: time.Millisecond},
+		&CostEstimate{Size: 1, Signatures: 2, Outputs: 1},
+	)
+	assert.Equal(t, e, res)
+	assert.Equal(t, &CostEstimate{Size: 16, Proofs: 2, Signatures: 3, VerificationTime: 2 * time.Millisecond, Inputs: 1, Outputs: 3}, e)
+}
+
+func TestBase64Size(t *testing.T) {
+	assert.Equal(t, 0, Base64Size(0))
+	assert.Equal(t, 4, Base64Size(1))
+	assert.Equal(t, 4, Base64Size(3))
+	assert.Equal(t, 8, Base64Size(4))
+}
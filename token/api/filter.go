@@ -0,0 +1,269 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+)
+
+// FilterBy returns a copy of the metadata containing only the information the passed parties are entitled to see:
+// the information about the outputs they receive and the inputs they send.
+// The information about any other input or output is removed and replaced by its digest,
+// therefore the filtered metadata has the same Digest as the full metadata.
+// Issuers, approvers, outputs, and token ids are public, they are always kept.
+func (m *TokenRequestMetadata) FilterBy(parties ...view.Identity) *TokenRequestMetadata {
+	res := &TokenRequestMetadata{}
+	for _, issue := range m.Issues {
+		filtered := IssueMetadata{
+			Issuer:        issue.Issuer,
+			Outputs:       issue.Outputs,
+			TokenInfo:     make([][]byte, len(issue.Outputs)),
+			Receivers:     make([]view.Identity, len(issue.Outputs)),
+			AuditInfos:    make([][]byte, len(issue.Outputs)),
+			OutputDigests: make([][]byte, len(issue.Outputs)),
+			Approver:      issue.Approver,
+		}
+		for i := range issue.Outputs {
+			if !issue.IsOutputRedacted(i) {
+				if receiver := identityAt(issue.Receivers, i); contains(parties, receiver) {
+					filtered.TokenInfo[i] = bytesAt(issue.TokenInfo, i)
+					filtered.Receivers[i] = receiver
+					filtered.AuditInfos[i] = bytesAt(issue.AuditInfos, i)
+					continue
+				}
+			}
+			filtered.OutputDigests[i] = issue.outputDigest(i)
+		}
+		res.Issues = append(res.Issues, filtered)
+	}
+	for _, transfer := range m.Transfers {
+		filtered := TransferMetadata{
+			TokenIDs:           transfer.TokenIDs,
+			Outputs:            transfer.Outputs,
+			TokenInfo:          make([][]byte, len(transfer.Outputs)),
+			Senders:            make([]view.Identity, len(transfer.TokenIDs)),
+			SenderAuditInfos:   make([][]byte, len(transfer.TokenIDs)),
+			Receivers:          make([]view.Identity, len(transfer.Outputs)),
+			ReceiverIsSender:   make([]bool, len(transfer.Outputs)),
+			ReceiverAuditInfos: make([][]byte, len(transfer.Outputs)),
+			InputDigests:       make([][]byte, len(transfer.TokenIDs)),
+			OutputDigests:      make([][]byte, len(transfer.Outputs)),
+			Attachments:        transfer.Attachments,
+		}
+		for i := range transfer.TokenIDs {
+			if !transfer.IsInputRedacted(i) {
+				if sender := identityAt(transfer.Senders, i); contains(parties, sender) {
+					filtered.Senders[i] = sender
+					filtered.SenderAuditInfos[i] = bytesAt(transfer.SenderAuditInfos, i)
+					continue
+				}
+			}
+			filtered.InputDigests[i] = transfer.inputDigest(i)
+		}
+		for i := range transfer.Outputs {
+			if !transfer.IsOutputRedacted(i) {
+				if receiver := identityAt(transfer.Receivers, i); contains(parties, receiver) {
+					filtered.TokenInfo[i] = bytesAt(transfer.TokenInfo, i)
+					filtered.Receivers[i] = receiver
+					filtered.ReceiverIsSender[i] = i < len(transfer.ReceiverIsSender) && transfer.ReceiverIsSender[i]
+					filtered.ReceiverAuditInfos[i] = bytesAt(transfer.ReceiverAuditInfos, i)
+					continue
+				}
+			}
+			filtered.OutputDigests[i] = transfer.outputDigest(i)
+		}
+		res.Transfers = append(res.Transfers, filtered)
+	}
+	for _, rebinding := range m.Rebindings {
+		filtered := RebindingMetadata{
+			Outputs:            rebinding.Outputs,
+			TokenInfo:          make([][]byte, len(rebinding.Outputs)),
+			Receivers:          make([]view.Identity, len(rebinding.Outputs)),
+			ReceiverAuditInfos: make([][]byte, len(rebinding.Outputs)),
+			OutputDigests:      make([][]byte, len(rebinding.Outputs)),
+		}
+		for i := range rebinding.Outputs {
+			if !rebinding.IsOutputRedacted(i) {
+				if receiver := identityAt(rebinding.Receivers, i); contains(parties, receiver) {
+					filtered.TokenInfo[i] = bytesAt(rebinding.TokenInfo, i)
+					filtered.Receivers[i] = receiver
+					filtered.ReceiverAuditInfos[i] = bytesAt(rebinding.ReceiverAuditInfos, i)
+					continue
+				}
+			}
+			filtered.OutputDigests[i] = rebinding.outputDigest(i)
+		}
+		res.Rebindings = append(res.Rebindings, filtered)
+	}
+	return res
+}
+
+// FilterByAuditScope returns a copy of the metadata containing only the information an auditor with the passed scope
+// is entitled to see: the openings of the outputs are removed if the scope excludes the amounts.
+// Unlike FilterBy, the filtered metadata does not have the same Digest as the full metadata.
+func (m *TokenRequestMetadata) FilterByAuditScope(scope AuditScope) *TokenRequestMetadata {
+	if scope.Amounts() {
+		return m
+	}
+	res := &TokenRequestMetadata{}
+	for _, issue := range m.Issues {
+		issue.TokenInfo = nil
+		res.Issues = append(res.Issues, issue)
+	}
+	for _, transfer := range m.Transfers {
+		transfer.TokenInfo = nil
+		res.Transfers = append(res.Transfers, transfer)
+	}
+	for _, rebinding := range m.Rebindings {
+		rebinding.TokenInfo = nil
+		res.Rebindings = append(res.Rebindings, rebinding)
+	}
+	return res
+}
+
+// Digest returns the digest of the metadata. Filtering does not change the digest,
+// therefore it links a filtered metadata to the full metadata it has been derived from.
+func (m *TokenRequestMetadata) Digest() []byte {
+	h := sha256.New()
+	writeUint(h, uint64(len(m.Issues)))
+	for _, issue := range m.Issues {
+		writeBytes(h, issue.Issuer)
+		writeUint(h, uint64(len(issue.Outputs)))
+		for i, output := range issue.Outputs {
+			writeBytes(h, output)
+			writeBytes(h, issue.outputDigest(i))
+		}
+	}
+	writeUint(h, uint64(len(m.Transfers)))
+	for _, transfer := range m.Transfers {
+		writeUint(h, uint64(len(transfer.TokenIDs)))
+		for i, id := range transfer.TokenIDs {
+			if id != nil {
+				writeBytes(h, []byte(id.TxId))
+				writeUint(h, uint64(id.Index))
+			}
+			writeBytes(h, transfer.inputDigest(i))
+		}
+		writeUint(h, uint64(len(transfer.Outputs)))
+		for i, output := range transfer.Outputs {
+			writeBytes(h, output)
+			writeBytes(h, transfer.outputDigest(i))
+		}
+	}
+	// the digests of the metadata without rebindings are unchanged
+	if len(m.Rebindings) != 0 {
+		writeUint(h, uint64(len(m.Rebindings)))
+		for _, rebinding := range m.Rebindings {
+			writeUint(h, uint64(len(rebinding.Outputs)))
+			for i, output := range rebinding.Outputs {
+				writeBytes(h, output)
+				writeBytes(h, rebinding.outputDigest(i))
+			}
+		}
+	}
+	return h.Sum(nil)
+}
+
+// IsOutputRedacted returns true if the information about the output at the passed index has been filtered out
+func (m *IssueMetadata) IsOutputRedacted(index int) bool {
+	return bytesAt(m.OutputDigests, index) != nil
+}
+
+// IsInputRedacted returns true if the information about the input at the passed index has been filtered out
+func (m *TransferMetadata) IsInputRedacted(index int) bool {
+	return bytesAt(m.InputDigests, index) != nil
+}
+
+// IsOutputRedacted returns true if the information about the output at the passed index has been filtered out
+func (m *TransferMetadata) IsOutputRedacted(index int) bool {
+	return bytesAt(m.OutputDigests, index) != nil
+}
+
+// IsOutputRedacted returns true if the information about the output at the passed index has been filtered out
+func (m *RebindingMetadata) IsOutputRedacted(index int) bool {
+	return bytesAt(m.OutputDigests, index) != nil
+}
+
+func (m *IssueMetadata) outputDigest(i int) []byte {
+	if d := bytesAt(m.OutputDigests, i); d != nil {
+		return d
+	}
+	return digest(bytesAt(m.TokenInfo, i), identityAt(m.Receivers, i), bytesAt(m.AuditInfos, i))
+}
+
+func (m *TransferMetadata) inputDigest(i int) []byte {
+	if d := bytesAt(m.InputDigests, i); d != nil {
+		return d
+	}
+	return digest(identityAt(m.Senders, i), bytesAt(m.SenderAuditInfos, i))
+}
+
+func (m *TransferMetadata) outputDigest(i int) []byte {
+	if d := bytesAt(m.OutputDigests, i); d != nil {
+		return d
+	}
+	receiverIsSender := []byte{0}
+	if i < len(m.ReceiverIsSender) && m.ReceiverIsSender[i] {
+		receiverIsSender[0] = 1
+	}
+	return digest(bytesAt(m.TokenInfo, i), identityAt(m.Receivers, i), receiverIsSender, bytesAt(m.ReceiverAuditInfos, i))
+}
+
+func (m *RebindingMetadata) outputDigest(i int) []byte {
+	if d := bytesAt(m.OutputDigests, i); d != nil {
+		return d
+	}
+	return digest(bytesAt(m.TokenInfo, i), identityAt(m.Receivers, i), bytesAt(m.ReceiverAuditInfos, i))
+}
+
+func digest(fields ...[]byte) []byte {
+	h := sha256.New()
+	for _, field := range fields {
+		writeBytes(h, field)
+	}
+	return h.Sum(nil)
+}
+
+func writeBytes(h hash.Hash, b []byte) {
+	writeUint(h, uint64(len(b)))
+	h.Write(b)
+}
+
+func writeUint(h hash.Hash, v uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	h.Write(buf[:])
+}
+
+func bytesAt(s [][]byte, i int) []byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return nil
+}
+
+func identityAt(s []view.Identity, i int) view.Identity {
+	if i < len(s) {
+		return s[i]
+	}
+	return nil
+}
+
+func contains(parties []view.Identity, id view.Identity) bool {
+	if len(id) == 0 {
+		return false
+	}
+	for _, party := range parties {
+		if party.Equal(id) {
+			return true
+		}
+	}
+	return false
+}
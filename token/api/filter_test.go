@@ -0,0 +1,157 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/stretchr/testify/assert"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+func TestFilterBy(t *testing.T) {
+	alice := view.Identity("alice")
+	bob := view.Identity("bob")
+	charlie := view.Identity("charlie")
+
+	m := &TokenRequestMetadata{
+		Issues: []IssueMetadata{{
+			Issuer:     view.Identity("issuer"),
+			Outputs:    [][]byte{[]byte("o1"), []byte("o2")},
+			TokenInfo:  [][]byte{[]byte("ti1"), []byte("ti2")},
+			Receivers:  []view.Identity{alice, bob},
+			AuditInfos: [][]byte{[]byte("ai1"), []byte("ai2")},
+		}},
+		Transfers: []TransferMetadata{{
+			TokenIDs:           []*token2.Id{{TxId: "tx1", Index: 0}, {TxId: "tx2", Index: 1}},
+			Outputs:            [][]byte{[]byte("o3"), []byte("o4")},
+			TokenInfo:          [][]byte{[]byte("ti3"), []byte("ti4")},
+			Senders:            []view.Identity{alice, charlie},
+			SenderAuditInfos:   [][]byte{[]byte("sai1"), []byte("sai2")},
+			Receivers:          []view.Identity{bob, alice},
+			ReceiverIsSender:   []bool{false, true},
+			ReceiverAuditInfos: [][]byte{[]byte("rai1"), []byte("rai2")},
+		}},
+	}
+	digest := m.Digest()
+
+	f := m.FilterBy(alice)
+	assert.Equal(t, digest, f.Digest())
+
+	// issue: alice sees only her output
+	assert.Equal(t, m.Issues[0].Outputs, f.Issues[0].Outputs)
+	assert.False(t, f.Issues[0].IsOutputRedacted(0))
+	assert.Equal(t, []byte("ti1"), f.Issues[0].TokenInfo[0])
+	assert.Equal(t, alice, f.Issues[0].Receivers[0])
+	assert.True(t, f.Issues[0].IsOutputRedacted(1))
+	assert.Nil(t, f.Issues[0].TokenInfo[1])
+	assert.Nil(t, f.Issues[0].Receivers[1])
+	assert.Nil(t, f.Issues[0].AuditInfos[1])
+
+	// transfer: alice sees the input she sends and the output she receives
+	tr := f.Transfers[0]
+	assert.Equal(t, m.Transfers[0].TokenIDs, tr.TokenIDs)
+	assert.False(t, tr.IsInputRedacted(0))
+	assert.Equal(t, []byte("sai1"), tr.SenderAuditInfos[0])
+	assert.True(t, tr.IsInputRedacted(1))
+	assert.Nil(t, tr.Senders[1])
+	assert.True(t, tr.IsOutputRedacted(0))
+	assert.Nil(t, tr.TokenInfo[0])
+	assert.Nil(t, tr.ReceiverAuditInfos[0])
+	assert.False(t, tr.IsOutputRedacted(1))
+	assert.Equal(t, []byte("ti4"), tr.TokenInfo[1])
+	assert.True(t, tr.ReceiverIsSender[1])
+
+	// filtering again keeps the digest, and cannot recover redacted information
+	ff := f.FilterBy(alice, bob)
+	assert.Equal(t, digest, ff.Digest())
+	assert.True(t, ff.Issues[0].IsOutputRedacted(1))
+	assert.True(t, ff.Transfers[0].IsOutputRedacted(0))
+
+	// tampering with the filtered metadata changes the digest
+	f.Transfers[0].TokenInfo[1] = []byte("ti5")
+	assert.NotEqual(t, digest, f.Digest())
+	f = m.FilterBy(alice)
+	f.Issues[0].OutputDigests[1][0] ^= 1
+	assert.NotEqual(t, digest, f.Digest())
+
+	// the full metadata is not modified by filtering
+	assert.Equal(t, digest, m.Digest())
+	assert.Empty(t, m.Issues[0].OutputDigests)
+
+	// filtering by nobody redacts everything
+	f = m.FilterBy()
+	assert.Equal(t, digest, f.Digest())
+	assert.True(t, f.Issues[0].IsOutputRedacted(0))
+	assert.True(t, f.Transfers[0].IsInputRedacted(0))
+	assert.True(t, f.Transfers[0].IsOutputRedacted(1))
+}
+
+func TestFilterRebindingsBy(t *testing.T) {
+	alice := view.Identity("alice")
+	bob := view.Identity("bob")
+
+	m := &TokenRequestMetadata{
+		Rebindings: []RebindingMetadata{{
+			Outputs:            [][]byte{[]byte("o1"), []byte("o2")},
+			TokenInfo:          [][]byte{[]byte("ti1"), []byte("ti2")},
+			Receivers:          []view.Identity{alice, bob},
+			ReceiverAuditInfos: [][]byte{[]byte("rai1"), []byte("rai2")},
+		}},
+	}
+	digest := m.Digest()
+	assert.NotEqual(t, (&TokenRequestMetadata{}).Digest(), digest)
+
+	// the successor owners get the openings of the rebound tokens
+	assert.Equal(t, []byte("ti2"), m.GetTokenInfo([]byte("o2")))
+	assert.Equal(t, []byte("rai2"), m.GetAuditInfo([]byte("o2")))
+	assert.Equal(t, [][]byte{alice, bob}, m.Recipients())
+
+	// alice sees only her output
+	f := m.FilterBy(alice)
+	assert.Equal(t, digest, f.Digest())
+	assert.False(t, f.Rebindings[0].IsOutputRedacted(0))
+	assert.Equal(t, []byte("ti1"), f.GetTokenInfo([]byte("o1")))
+	assert.True(t, f.Rebindings[0].IsOutputRedacted(1))
+	assert.Nil(t, f.GetTokenInfo([]byte("o2")))
+	assert.Nil(t, f.Rebindings[0].ReceiverAuditInfos[1])
+}
+
+func TestFilterByAuditScope(t *testing.T) {
+	m := &TokenRequestMetadata{
+		Issues: []IssueMetadata{{
+			Outputs:    [][]byte{[]byte("o1")},
+			TokenInfo:  [][]byte{[]byte("ti1")},
+			AuditInfos: [][]byte{[]byte("ai1")},
+		}},
+		Transfers: []TransferMetadata{{
+			Outputs:            [][]byte{[]byte("o2")},
+			TokenInfo:          [][]byte{[]byte("ti2")},
+			SenderAuditInfos:   [][]byte{[]byte("sai1")},
+			ReceiverAuditInfos: [][]byte{[]byte("rai1")},
+		}},
+		Rebindings: []RebindingMetadata{{
+			Outputs:   [][]byte{[]byte("o3")},
+			TokenInfo: [][]byte{[]byte("ti3")},
+		}},
+	}
+
+	// the scopes including the amounts get the openings
+	assert.Equal(t, m, m.FilterByAuditScope(FullAuditScope))
+	assert.Equal(t, m, m.FilterByAuditScope(AmountsAuditScope))
+
+	// the identities only scope gets the audit infos but no opening
+	f := m.FilterByAuditScope(IdentitiesAuditScope)
+	assert.Empty(t, f.TokenInfos())
+	assert.Equal(t, m.Issues[0].AuditInfos, f.Issues[0].AuditInfos)
+	assert.Equal(t, m.Transfers[0].SenderAuditInfos, f.Transfers[0].SenderAuditInfos)
+	assert.Equal(t, m.Transfers[0].ReceiverAuditInfos, f.Transfers[0].ReceiverAuditInfos)
+	assert.Equal(t, m.Rebindings[0].Outputs, f.Rebindings[0].Outputs)
+	// the original metadata is not changed
+	assert.Len(t, m.TokenInfos(), 3)
+}
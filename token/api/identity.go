@@ -7,8 +7,14 @@ package api
 
 import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
 )
 
+// ErrEncryptedAuditInfo is returned by GetEnrollmentID when the audit info is encrypted
+// under the auditor key and this node cannot open it
+var ErrEncryptedAuditInfo = errors2.New(errors2.Unauthorized, "audit info encrypted for the auditor")
+
 type IdentityUsage int
 
 const (
@@ -47,7 +53,16 @@ type IdentityProvider interface {
 	// GetSigner returns a Signer for passed identity.
 	GetSigner(identity view.Identity) (Signer, error)
 
+	// GetEnrollmentID returns the enrollment ID carried by the passed audit info.
+	// It returns ErrEncryptedAuditInfo if the audit info can be opened only by the auditor.
 	GetEnrollmentID(auditInfo []byte) (string, error)
 
 	GetIdentityMetadata(identity view.Identity) ([]byte, error)
+
+	// RegisterIdentity registers, at runtime, the long-term identity with the passed id, for the passed usage,
+	// loading its msp configuration from the passed path
+	RegisterIdentity(usage IdentityUsage, id string, mspID string, path string) error
+
+	// MSPType returns the type of the msps the long-term identities for the passed usage belong to
+	MSPType(usage IdentityUsage) (string, error)
 }
@@ -0,0 +1,43 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+)
+
+// AnyTokenType is the token type of the issue policy entry that applies to the types without an entry of their own
+const AnyTokenType = "*"
+
+// IssuePolicy lists, per token type, the approver that must co-sign the issue actions of that type,
+// for example a compliance officer. An issue action of a type without approver needs only the signature of its issuer.
+type IssuePolicy struct {
+	// Approvers maps a token type, or AnyTokenType, to the serialized identity of its approver
+	Approvers map[string][]byte `json:",omitempty"`
+}
+
+// Approver returns the approver of the issue actions of the passed token type, nil if none
+func (p *IssuePolicy) Approver(tokenType string) view.Identity {
+	if p == nil {
+		return nil
+	}
+	if approver, ok := p.Approvers[tokenType]; ok {
+		return approver
+	}
+	return p.Approvers[AnyTokenType]
+}
+
+// SetApprover sets the approver of the issue actions of the passed token type. An empty approver removes the entry.
+func (p *IssuePolicy) SetApprover(tokenType string, approver []byte) {
+	if len(approver) == 0 {
+		delete(p.Approvers, tokenType)
+		return
+	}
+	if p.Approvers == nil {
+		p.Approvers = map[string][]byte{}
+	}
+	p.Approvers[tokenType] = approver
+}
@@ -0,0 +1,33 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssuePolicy(t *testing.T) {
+	var nilPolicy *IssuePolicy
+	assert.True(t, nilPolicy.Approver("USD").IsNone())
+
+	p := &IssuePolicy{}
+	assert.True(t, p.Approver("USD").IsNone())
+
+	p.SetApprover("USD", []byte("compliance"))
+	assert.Equal(t, view.Identity("compliance"), p.Approver("USD"))
+	assert.True(t, p.Approver("EUR").IsNone())
+
+	// the fallback applies to the types without an entry of their own
+	p.SetApprover(AnyTokenType, []byte("officer"))
+	assert.Equal(t, view.Identity("compliance"), p.Approver("USD"))
+	assert.Equal(t, view.Identity("officer"), p.Approver("EUR"))
+
+	p.SetApprover("USD", nil)
+	assert.Equal(t, view.Identity("officer"), p.Approver("USD"))
+}
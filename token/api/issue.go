@@ -5,12 +5,26 @@ SPDX-License-Identifier: Apache-2.0
 */
 package api
 
-import "github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+)
+
+// IssueOptions contains the options a driver takes into account when generating an issue action
+type IssueOptions struct {
+	// Expiration, if not zero, is the time after which the issued tokens cannot be spent anymore by their owners.
+	// Expired tokens can only be reclaimed by their issuer.
+	// Drivers that cannot record the expiration in their tokens, like zkatdlog, reject a non-zero expiration.
+	Expiration time.Time
+}
 
 type IssueService interface {
-	Issue(id view.Identity, typ string, values []uint64, owners [][]byte) (IssueAction, [][]byte, view.Identity, error)
+	Issue(id view.Identity, typ string, values []uint64, owners [][]byte, opts *IssueOptions) (IssueAction, [][]byte, view.Identity, error)
 
-	VerifyIssue(tr IssueAction, tokenInfos [][]byte) error
+	// VerifyIssue checks the well-formedness of the passed issue action, it aborts once the passed context is done
+	VerifyIssue(ctx context.Context, tr IssueAction, tokenInfos [][]byte) error
 
 	DeserializeIssueAction(raw []byte) (IssueAction, error)
 }
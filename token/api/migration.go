@@ -0,0 +1,105 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// MigrationParams, part of the public parameters of a driver, enable the migration of the tokens of the driver
+// it replaces, the source driver. Until the cutover height, the validator accepts the token requests of the source
+// driver, validating them with the source public parameters. The migration actions are accepted at any height.
+// The support is limited to the validators and the chaincode. Assembling a migration request, collecting the
+// signatures of the owners of the inputs, and storing the migrated tokens in the vaults of their owners, who find
+// the openings in the action, are left to the application: there is no client or vault support yet.
+type MigrationParams struct {
+	// SourcePublicParams are the serialized public parameters of the source driver
+	SourcePublicParams []byte
+	// CutoverHeight is the ledger height from which the token requests of the source driver are rejected.
+	// Zero means that no cutover has been scheduled yet.
+	CutoverHeight uint64 `json:",omitempty"`
+}
+
+// CheckSourceRequest returns an error if the token requests of the source driver are not accepted at the passed height.
+// When a cutover is scheduled, an unknown height, zero, is treated as past the cutover.
+func (p *MigrationParams) CheckSourceRequest(height uint64) error {
+	if p.CutoverHeight == 0 {
+		return nil
+	}
+	if height == 0 {
+		return errors.Errorf("ledger height not available, cannot enforce cutover at [%d]", p.CutoverHeight)
+	}
+	if height >= p.CutoverHeight {
+		return errors.Errorf("requests of the source driver are not accepted from height [%d], current height [%d]", p.CutoverHeight, height)
+	}
+	return nil
+}
+
+// MigrationAction swaps tokens of the source driver for tokens of the current driver.
+// The input at a given index is migrated into the output at the same index, that must carry the same type and quantity.
+// The owners of the inputs sign the token request, in the order of the inputs, after the senders of the transfers.
+type MigrationAction struct {
+	// Inputs are the ledger keys of the tokens of the source driver
+	Inputs []string
+	// Outputs are the serialized tokens of the current driver
+	Outputs [][]byte
+	// Openings are the driver specific openings of the outputs, the validator checks them against the inputs.
+	// The inputs are in the clear, therefore the openings do not disclose anything new.
+	Openings [][]byte
+}
+
+func (m *MigrationAction) Serialize() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func (m *MigrationAction) Deserialize(raw []byte) error {
+	if err := json.Unmarshal(raw, m); err != nil {
+		return err
+	}
+	if len(m.Inputs) == 0 {
+		return errors.New("migration action without inputs")
+	}
+	if len(m.Inputs) != len(m.Outputs) || len(m.Inputs) != len(m.Openings) {
+		return errors.Errorf("migration action with [%d] inputs, [%d] outputs, and [%d] openings", len(m.Inputs), len(m.Outputs), len(m.Openings))
+	}
+	return nil
+}
+
+func (m *MigrationAction) NumOutputs() int {
+	return len(m.Outputs)
+}
+
+func (m *MigrationAction) GetSerializedOutputs() ([][]byte, error) {
+	return m.Outputs, nil
+}
+
+func (m *MigrationAction) SerializeOutputAt(index int) ([]byte, error) {
+	return m.Outputs[index], nil
+}
+
+// IsRedeemAt returns false, migrations do not redeem tokens
+func (m *MigrationAction) IsRedeemAt(index int) bool {
+	return false
+}
+
+func (m *MigrationAction) GetInputs() ([]string, error) {
+	return m.Inputs, nil
+}
+
+func (m *MigrationAction) IsGraphHiding() bool {
+	return false
+}
+
+// MigrationSource is implemented by the validators of the drivers whose tokens can be migrated
+type MigrationSource interface {
+	// VerifyMigrationInputs checks that the inputs of the passed migration action, at the passed index in the token
+	// request, are unspent and that their owners signed. It returns the inputs in the clear.
+	VerifyMigrationInputs(ledger Ledger, signatureProvider SignatureProvider, index int, action *MigrationAction, report *ValidationReport) ([]*token2.Token, error)
+}
@@ -0,0 +1,48 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrationParamsCheckSourceRequest(t *testing.T) {
+	p := &MigrationParams{}
+	assert.NoError(t, p.CheckSourceRequest(0))
+	assert.NoError(t, p.CheckSourceRequest(100))
+
+	p.CutoverHeight = 10
+	assert.NoError(t, p.CheckSourceRequest(9))
+	assert.Error(t, p.CheckSourceRequest(10))
+	assert.Error(t, p.CheckSourceRequest(11))
+	// an unknown height is treated as past the cutover
+	assert.Error(t, p.CheckSourceRequest(0))
+}
+
+func TestMigrationActionDeserialize(t *testing.T) {
+	action := &MigrationAction{
+		Inputs:   []string{"a", "b"},
+		Outputs:  [][]byte{[]byte("out-a"), []byte("out-b")},
+		Openings: [][]byte{[]byte("op-a"), []byte("op-b")},
+	}
+	raw, err := action.Serialize()
+	assert.NoError(t, err)
+	action2 := &MigrationAction{}
+	assert.NoError(t, action2.Deserialize(raw))
+	assert.Equal(t, action, action2)
+	assert.Equal(t, 2, action2.NumOutputs())
+	assert.False(t, action2.IsRedeemAt(0))
+
+	raw, err = (&MigrationAction{}).Serialize()
+	assert.NoError(t, err)
+	assert.Error(t, (&MigrationAction{}).Deserialize(raw))
+
+	raw, err = (&MigrationAction{Inputs: []string{"a"}, Outputs: [][]byte{[]byte("out-a")}}).Serialize()
+	assert.NoError(t, err)
+	assert.Error(t, (&MigrationAction{}).Deserialize(raw))
+}
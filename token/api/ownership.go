@@ -0,0 +1,40 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// OwnershipProof states that the owner of an unspent token controls it, without spending it.
+// The owner signs the token ID, the token as stored on the ledger, and a challenge chosen by the verifier.
+type OwnershipProof struct {
+	ID *token2.Id
+	// Output is the token as stored on the ledger
+	Output    []byte
+	Challenge []byte
+	Signature []byte
+}
+
+// MessageToSign returns the message the owner of the token signs
+func (p *OwnershipProof) MessageToSign() ([]byte, error) {
+	if p.ID == nil {
+		return nil, errors.New("token id not specified")
+	}
+	return json.Marshal(&OwnershipProof{ID: p.ID, Output: p.Output, Challenge: p.Challenge})
+}
+
+func (p *OwnershipProof) Serialize() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func (p *OwnershipProof) Deserialize(raw []byte) error {
+	return json.Unmarshal(raw, p)
+}
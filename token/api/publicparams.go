@@ -5,7 +5,12 @@ SPDX-License-Identifier: Apache-2.0
 */
 package api
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+)
 
 type SerializedPublicParameters struct {
 	Identifier string
@@ -23,15 +28,100 @@ type PublicParamsFetcher interface {
 	Fetch() ([]byte, error)
 }
 
+// ForceFetcher is implemented by the PublicParamsFetchers serving the public parameters from a cache.
+// ForceFetch fetches them again from their source, bypassing the cache.
+type ForceFetcher interface {
+	ForceFetch() ([]byte, error)
+}
+
+// ForceFetch fetches the public parameters with the passed fetcher, bypassing its cache, if any
+func ForceFetch(fetcher PublicParamsFetcher) ([]byte, error) {
+	if ff, ok := fetcher.(ForceFetcher); ok {
+		return ff.ForceFetch()
+	}
+	return fetcher.Fetch()
+}
+
 type PublicParameters interface {
 	Identifier() string
 	TokenDataHiding() bool
 	GraphHiding() bool
 	MaxTokenValue() uint64
 	CertificationDriver() string
+	// AuditorIdentity returns the identity of the auditor, nil if the deployment has no auditor.
+	// Without an auditor, the requests carry no audit infos and no auditor signature.
+	AuditorIdentity() view.Identity
+	// TypeAuditor returns the auditor of the token requests on the passed token type, see AuditPolicy.
+	// It falls back to the default auditor, nil if none.
+	TypeAuditor(tokenType string) view.Identity
+	// IssueApprover returns the identity that must co-sign the issue actions of the passed token type, nil if none
+	IssueApprover(tokenType string) view.Identity
+	// RedeemRequiresIssuer returns true if the redemptions must be co-signed by an issuer of the redeemed type,
+	// see BurnReceipt.Issuer
+	RedeemRequiresIssuer() bool
 	Bytes() ([]byte, error)
 }
 
+// SupplyCaps is implemented by the public parameters that can cap, per token type, the total value that can be issued
+type SupplyCaps interface {
+	// SupplyCap returns the cap of the passed token type, false if uncapped
+	SupplyCap(tokenType string) (uint64, bool)
+}
+
+// AuditThresholds is implemented by the public parameters that let the token requests of small value skip the auditor
+type AuditThresholds interface {
+	// AuditThreshold returns the value under which the total of the outputs of a token request made of transfers
+	// only does not require the signature of the auditor, 0 if every request does, see CheckUnaudited.
+	// The outputs include the change, their total is the total of the spent inputs: the threshold bounds the value
+	// a request spends, not the value it pays to others. A small payment spending large tokens is audited.
+	AuditThreshold() uint64
+}
+
+// AuditScope declares what the auditor can see of the token requests
+type AuditScope int
+
+const (
+	// FullAuditScope lets the auditor see both the amounts and the identities
+	FullAuditScope AuditScope = iota
+	// AmountsAuditScope lets the auditor see the amounts only, the requests carry no audit infos
+	AmountsAuditScope
+	// IdentitiesAuditScope lets the auditor see the identities only, the amounts are not audited
+	IdentitiesAuditScope
+)
+
+// Amounts returns true if the auditor can see the amounts
+func (s AuditScope) Amounts() bool {
+	return s != IdentitiesAuditScope
+}
+
+// Identities returns true if the auditor can see the identities of the owners
+func (s AuditScope) Identities() bool {
+	return s != AmountsAuditScope
+}
+
+// Validate returns an error if the scope is unknown
+func (s AuditScope) Validate() error {
+	switch s {
+	case FullAuditScope, AmountsAuditScope, IdentitiesAuditScope:
+		return nil
+	default:
+		return errors.Errorf("unknown audit scope [%d]", s)
+	}
+}
+
+// AuditScopes is implemented by the public parameters that can restrict what the auditor can see
+type AuditScopes interface {
+	// GetAuditScope returns what the auditor can see
+	GetAuditScope() AuditScope
+}
+
+// SelfCheckable is implemented by the public parameters that can check they are well-formed.
+// The drivers refuse to operate on public parameters that do not pass the check.
+type SelfCheckable interface {
+	// SelfCheck returns an error describing the problems found, nil if the public parameters are well-formed
+	SelfCheck() error
+}
+
 type PublicParamsManager interface {
 	SetAuditor(auditor []byte) ([]byte, error)
 
@@ -43,5 +133,16 @@ type PublicParamsManager interface {
 
 	NewCertifierKeyPair() ([]byte, []byte, error)
 
+	// SetIssueApprover sets the identity that must co-sign the issue actions of the passed token type,
+	// see IssuePolicy. An empty approver removes the requirement.
+	SetIssueApprover(tokenType string, approver []byte) ([]byte, error)
+
+	// SetTypeAuditor assigns the auditor of the token requests on the passed token type, see AuditPolicy.
+	// An empty auditor reassigns the type to the default auditor.
+	SetTypeAuditor(tokenType string, auditor []byte) ([]byte, error)
+
+	// SetRedeemRequiresIssuer sets whether the redemptions must be co-signed by an issuer of the redeemed type
+	SetRedeemRequiresIssuer(required bool) ([]byte, error)
+
 	ForceFetch() error
 }
@@ -0,0 +1,166 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// RebindingAction re-issues unspent tokens to successor owners when the organization of their owners leaves the
+// consortium. The input at a given index is rebound into the output at the same index, that must be the same token
+// with a different owner. Each input can be rebound once per token request. The owners of the inputs do not sign, the auditor approves the rebinding by signing the
+// token request. Only the tokens owned by the members of an organization listed as departed by the public parameters
+// can be rebound, see DepartedOrganizations.
+type RebindingAction struct {
+	// Organization is the departed organization the owners of the inputs belong to: the MSP ID of the owners
+	// in fabtoken, the organizational unit disclosed, and proven, by the idemix owners in zkatdlog
+	Organization string
+	// Inputs are the ledger keys of the tokens to rebind
+	Inputs []string
+	// Outputs are the serialized rebound tokens
+	Outputs [][]byte
+	// Proofs are, for each output, the driver specific proofs that the output matches its input, if any.
+	// In zkatdlog, the output re-randomizes the commitment of its input, so that the two cannot be linked.
+	Proofs [][]byte `json:",omitempty"`
+}
+
+func (r *RebindingAction) Serialize() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *RebindingAction) Deserialize(raw []byte) error {
+	if err := json.Unmarshal(raw, r); err != nil {
+		return err
+	}
+	if len(r.Inputs) == 0 {
+		return errors.New("rebinding action without inputs")
+	}
+	if len(r.Inputs) != len(r.Outputs) {
+		return errors.Errorf("rebinding action with [%d] inputs and [%d] outputs", len(r.Inputs), len(r.Outputs))
+	}
+	if len(r.Proofs) != 0 && len(r.Proofs) != len(r.Outputs) {
+		return errors.Errorf("rebinding action with [%d] outputs and [%d] proofs", len(r.Outputs), len(r.Proofs))
+	}
+	return nil
+}
+
+// ProofAt returns the proof of the output at the passed index, nil if the action carries no proofs
+func (r *RebindingAction) ProofAt(index int) []byte {
+	if len(r.Proofs) == 0 {
+		return nil
+	}
+	return r.Proofs[index]
+}
+
+func (r *RebindingAction) NumOutputs() int {
+	return len(r.Outputs)
+}
+
+func (r *RebindingAction) GetSerializedOutputs() ([][]byte, error) {
+	return r.Outputs, nil
+}
+
+func (r *RebindingAction) SerializeOutputAt(index int) ([]byte, error) {
+	return r.Outputs[index], nil
+}
+
+// IsRedeemAt returns false, rebindings do not redeem tokens
+func (r *RebindingAction) IsRedeemAt(index int) bool {
+	return false
+}
+
+func (r *RebindingAction) GetInputs() ([]string, error) {
+	return r.Inputs, nil
+}
+
+func (r *RebindingAction) IsGraphHiding() bool {
+	return false
+}
+
+// DepartedOrganizations is implemented by the public parameters that list the organizations that left the
+// consortium, whose tokens can be rebound
+type DepartedOrganizations interface {
+	// Departed returns true if the passed organization left the consortium
+	Departed(organization string) bool
+}
+
+// TokenOrganizations returns the organizations the owner of the passed serialized token belongs to
+type TokenOrganizations func(token []byte) ([]string, error)
+
+// RebindingMatcher returns an error if the passed serialized output is not the passed serialized input
+// bound to a different, non-empty, owner, as proven by the passed proof, if the driver requires one
+type RebindingMatcher func(input, output, proof []byte) error
+
+// UnmarshalRebindings returns the passed serialized rebinding actions, without verifying them
+func UnmarshalRebindings(raw [][]byte, report *ValidationReport) ([]*RebindingAction, error) {
+	res := make([]*RebindingAction, len(raw))
+	for i, r := range raw {
+		action := &RebindingAction{}
+		if err := action.Deserialize(r); err != nil {
+			return nil, report.Failed(RebindingActionType, i, FormatCheck, err)
+		}
+		res[i] = action
+	}
+	return res, nil
+}
+
+// VerifyRebindings checks that the inputs of the passed rebinding actions are unspent, rebound once across
+// the actions, owned by members of the departed organization of the action, and that each output matches its input. Rebindings are accepted only if the
+// public parameters name an auditor, whose signature on the token request is checked by the validator.
+func VerifyRebindings(ledger Ledger, rebindings []*RebindingAction, audited bool, departed DepartedOrganizations, organizations TokenOrganizations, match RebindingMatcher, report *ValidationReport) error {
+	if len(rebindings) != 0 && !audited {
+		return report.Failed(RebindingActionType, 0, SignatureCheck, errors.New("rebinding requires an auditor"))
+	}
+	seen := map[string]bool{}
+	for i, action := range rebindings {
+		if departed == nil || len(action.Organization) == 0 || !departed.Departed(action.Organization) {
+			return report.Failed(RebindingActionType, i, OrganizationCheck, errors.Errorf("organization [%s] has not departed", action.Organization))
+		}
+		for j, in := range action.Inputs {
+			if seen[in] {
+				return report.Failed(RebindingActionType, i, DoubleSpendCheck, errors.Errorf("input to rebind [%s] appears more than once", in), j)
+			}
+			seen[in] = true
+			raw, err := ledger.GetState(in)
+			if err != nil {
+				return errors.Wrapf(err, "failed to retrieve input to rebind [%s]", in)
+			}
+			if len(raw) == 0 {
+				return report.Failed(RebindingActionType, i, DoubleSpendCheck, errors.Errorf("input to rebind [%s] does not exists", in), j)
+			}
+			orgs, err := organizations(raw)
+			if err != nil {
+				return report.Failed(RebindingActionType, i, OrganizationCheck, errors.WithMessagef(err, "failed getting the organization of the owner of [%s]", in), j)
+			}
+			if !containsString(orgs, action.Organization) {
+				return report.Failed(RebindingActionType, i, OrganizationCheck, errors.Errorf("owner of [%s] is not a member of [%s]", in, action.Organization), j)
+			}
+			if err := match(raw, action.Outputs[j], action.ProofAt(j)); err != nil {
+				return report.Failed(RebindingActionType, i, FormatCheck, errors.WithMessagef(err, "invalid rebinding of [%s]", in), j)
+			}
+		}
+		report.Succeeded(RebindingActionType, i)
+	}
+	return nil
+}
+
+// DepartedList lists the organizations that left the consortium, it implements DepartedOrganizations
+type DepartedList []string
+
+func (l DepartedList) Departed(organization string) bool {
+	return containsString(l, organization)
+}
+
+func containsString(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type mapLedger map[string][]byte
+
+func (l mapLedger) GetState(key string) ([]byte, error) {
+	return l[key], nil
+}
+
+func TestRebindingActionDeserialize(t *testing.T) {
+	action := &RebindingAction{
+		Inputs:  []string{"a", "b"},
+		Outputs: [][]byte{[]byte("out-a"), []byte("out-b")},
+	}
+	raw, err := action.Serialize()
+	assert.NoError(t, err)
+	action2 := &RebindingAction{}
+	assert.NoError(t, action2.Deserialize(raw))
+	assert.Equal(t, action, action2)
+
+	raw, err = (&RebindingAction{}).Serialize()
+	assert.NoError(t, err)
+	assert.Error(t, (&RebindingAction{}).Deserialize(raw))
+
+	raw, err = (&RebindingAction{Inputs: []string{"a"}}).Serialize()
+	assert.NoError(t, err)
+	assert.Error(t, (&RebindingAction{}).Deserialize(raw))
+
+	// the proofs, if any, match the outputs
+	action.Proofs = [][]byte{[]byte("proof-a")}
+	raw, err = action.Serialize()
+	assert.NoError(t, err)
+	assert.Error(t, (&RebindingAction{}).Deserialize(raw))
+	action.Proofs = append(action.Proofs, []byte("proof-b"))
+	raw, err = action.Serialize()
+	assert.NoError(t, err)
+	assert.NoError(t, action2.Deserialize(raw))
+	assert.Equal(t, []byte("proof-b"), action2.ProofAt(1))
+	assert.Nil(t, (&RebindingAction{Outputs: action.Outputs}).ProofAt(1))
+}
+
+func TestVerifyRebindings(t *testing.T) {
+	ledger := mapLedger{"a": []byte("token-a"), "c": []byte("token-c")}
+	// the output must be the input with a different owner, here a suffix
+	match := func(input, output, proof []byte) error {
+		if !bytes.HasPrefix(output, input) || len(output) == len(input) {
+			return errors.New("output does not match input")
+		}
+		return nil
+	}
+
+	// the owner of a token is its prefix before the dash
+	organizations := func(raw []byte) ([]string, error) {
+		return []string{string(bytes.SplitN(raw, []byte("-"), 2)[0])}, nil
+	}
+	departed := DepartedList{"token", "org2"}
+
+	valid := []*RebindingAction{{Organization: "token", Inputs: []string{"a"}, Outputs: [][]byte{[]byte("token-a-successor")}}}
+	report := &ValidationReport{}
+	assert.NoError(t, VerifyRebindings(ledger, valid, true, departed, organizations, match, report))
+
+	report = &ValidationReport{}
+	err := VerifyRebindings(ledger, valid, false, departed, organizations, match, report)
+	assert.Error(t, err)
+	assert.Equal(t, SignatureCheck, report.Failure().Check)
+
+	report = &ValidationReport{}
+	err = VerifyRebindings(ledger, []*RebindingAction{{Organization: "token", Inputs: []string{"b"}, Outputs: [][]byte{[]byte("token-b-successor")}}}, true, departed, organizations, match, report)
+	assert.Error(t, err)
+	assert.Equal(t, DoubleSpendCheck, report.Failure().Check)
+
+	report = &ValidationReport{}
+	err = VerifyRebindings(ledger, []*RebindingAction{{Organization: "token", Inputs: []string{"a"}, Outputs: [][]byte{[]byte("token-a")}}}, true, departed, organizations, match, report)
+	assert.Error(t, err)
+	assert.Equal(t, FormatCheck, report.Failure().Check)
+
+	// the organization must have departed
+	report = &ValidationReport{}
+	err = VerifyRebindings(ledger, valid, true, DepartedList{"org2"}, organizations, match, report)
+	assert.EqualError(t, err, "organization [token] has not departed")
+	assert.Equal(t, OrganizationCheck, report.Failure().Check)
+
+	// the owners of the inputs must be members of the departed organization
+	report = &ValidationReport{}
+	err = VerifyRebindings(ledger, []*RebindingAction{{Organization: "org2", Inputs: []string{"a"}, Outputs: [][]byte{[]byte("token-a-successor")}}}, true, departed, organizations, match, report)
+	assert.EqualError(t, err, "owner of [a] is not a member of [org2]")
+	assert.Equal(t, OrganizationCheck, report.Failure().Check)
+
+	// an input is rebound once, within an action or across actions
+	report = &ValidationReport{}
+	err = VerifyRebindings(ledger, []*RebindingAction{{Organization: "token", Inputs: []string{"a", "a"}, Outputs: [][]byte{[]byte("token-a-successor"), []byte("token-a-other")}}}, true, departed, organizations, match, report)
+	assert.EqualError(t, err, "input to rebind [a] appears more than once")
+	assert.Equal(t, DoubleSpendCheck, report.Failure().Check)
+	report = &ValidationReport{}
+	err = VerifyRebindings(ledger, []*RebindingAction{
+		{Organization: "token", Inputs: []string{"a", "c"}, Outputs: [][]byte{[]byte("token-a-successor"), []byte("token-c-successor")}},
+		{Organization: "token", Inputs: []string{"a"}, Outputs: [][]byte{[]byte("token-a-other")}},
+	}, true, departed, organizations, match, report)
+	assert.EqualError(t, err, "input to rebind [a] appears more than once")
+	assert.Equal(t, DoubleSpendCheck, report.Failure().Check)
+	assert.Equal(t, 1, report.Failure().Index)
+
+	// no rebinding, no auditor needed
+	assert.NoError(t, VerifyRebindings(ledger, nil, false, nil, organizations, match, &ValidationReport{}))
+}
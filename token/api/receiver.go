@@ -0,0 +1,65 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+)
+
+// ReceiverPolicy whitelists the receivers of the transfers by an attribute of their identity, for example a KYC level.
+// A transfer is valid only if the owner of each of its outputs, redeemed outputs excluded, holds an accepted attribute.
+// fabtoken checks the organizational units of the x509 certificate of the receiver. zkatdlog checks the organizational
+// unit disclosed by the idemix identity of the receiver, whose possession is proven in zero-knowledge against the
+// idemix issuer public key, the other attributes of the credential, the enrollment ID included, stay hidden.
+type ReceiverPolicy struct {
+	// Attributes are the accepted attribute values, a receiver must hold at least one of them
+	Attributes []string
+	// RootCerts and IntermediateCerts are the PEM encoded certificates of the certification authorities of the
+	// receivers. fabtoken accepts the organizational units of a receiver only if its certificate chains to one of
+	// the root certificates. zkatdlog ignores them, the accepted idemix issuer public keys play their role.
+	RootCerts         [][]byte `json:",omitempty"`
+	IntermediateCerts [][]byte `json:",omitempty"`
+}
+
+// Accepts returns true if one of the passed attributes is accepted by the policy. A nil policy accepts any receiver.
+func (p *ReceiverPolicy) Accepts(attributes ...string) bool {
+	if p == nil {
+		return true
+	}
+	for _, attribute := range attributes {
+		for _, accepted := range p.Attributes {
+			if attribute == accepted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckReceiver returns an error if the passed receiver does not hold an accepted attribute.
+// The receiver of a time locked output is the owner of the lock. The attributes of an identity are extracted,
+// and verified, by the passed function.
+func (p *ReceiverPolicy) CheckReceiver(receiver view.Identity, attributes func(id view.Identity) ([]string, error)) error {
+	if p == nil {
+		return nil
+	}
+	lock, err := GetTimeLock(receiver)
+	if err != nil {
+		return err
+	}
+	if lock != nil {
+		receiver = lock.Owner
+	}
+	held, err := attributes(receiver)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting the attributes of receiver [%s]", receiver)
+	}
+	if !p.Accepts(held...) {
+		return errors.Errorf("receiver [%s] holds none of the attributes %v", receiver, p.Attributes)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiverPolicy(t *testing.T) {
+	attributes := func(id view.Identity) ([]string, error) {
+		switch string(id) {
+		case "alice":
+			return []string{"retail", "kyc-2"}, nil
+		case "bob":
+			return []string{"retail"}, nil
+		}
+		return nil, errors.New("unknown identity")
+	}
+
+	var nilPolicy *ReceiverPolicy
+	assert.True(t, nilPolicy.Accepts())
+	assert.NoError(t, nilPolicy.CheckReceiver([]byte("bob"), attributes))
+
+	p := &ReceiverPolicy{Attributes: []string{"kyc-2", "kyc-3"}}
+	assert.False(t, p.Accepts())
+	assert.True(t, p.Accepts("retail", "kyc-3"))
+	assert.NoError(t, p.CheckReceiver([]byte("alice"), attributes))
+	err := p.CheckReceiver([]byte("bob"), attributes)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "holds none of the attributes [kyc-2 kyc-3]")
+	assert.Error(t, p.CheckReceiver([]byte("charlie"), attributes))
+
+	// the receiver of a time locked output is the owner of the lock
+	lock, err := NewTimeLockOwner(&TimeLock{Owner: []byte("alice"), NotBefore: time.Unix(100, 0)})
+	assert.NoError(t, err)
+	assert.NoError(t, p.CheckReceiver(lock, attributes))
+	lock, err = NewTimeLockOwner(&TimeLock{Owner: []byte("bob"), NotBefore: time.Unix(100, 0)})
+	assert.NoError(t, err)
+	assert.Error(t, p.CheckReceiver(lock, attributes))
+}
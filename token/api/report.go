@@ -0,0 +1,271 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+)
+
+// ActionType identifies the kind of action a validation result refers to
+type ActionType string
+
+const (
+	AuditorActionType  ActionType = "auditor"
+	RequestActionType  ActionType = "request" // the token request as a whole
+	IssueActionType    ActionType = "issue"
+	TransferActionType ActionType = "transfer"
+	BurnActionType     ActionType = "burn"
+	// MigrationActionType identifies the migration of tokens from a replaced driver, see MigrationParams
+	MigrationActionType ActionType = "migration"
+	// RebindingActionType identifies the rebinding of tokens to successor owners, see RebindingAction
+	RebindingActionType ActionType = "rebinding"
+	// SwapActionType identifies the swap terms of a token request, see SwapTerms
+	SwapActionType ActionType = "swap"
+)
+
+// ValidationCheck identifies the check performed on an action
+type ValidationCheck string
+
+const (
+	FormatCheck      ValidationCheck = "format"
+	SignatureCheck   ValidationCheck = "signature"
+	ProofCheck       ValidationCheck = "proof"
+	BalanceCheck     ValidationCheck = "balance"
+	DoubleSpendCheck ValidationCheck = "double-spend"
+	ExpirationCheck  ValidationCheck = "expiration"
+	// HookCheck is a check enforced by a validation hook
+	HookCheck ValidationCheck = "hook"
+	// LimitCheck is the check of the size and complexity limits of a token request
+	LimitCheck ValidationCheck = "limit"
+	// CutoverCheck is the check that the token requests of a replaced driver are still accepted, see MigrationParams
+	CutoverCheck ValidationCheck = "cutover"
+	// TimeLockCheck is the check that the time locked inputs of a transfer can be spent, see TimeLock
+	TimeLockCheck ValidationCheck = "timelock"
+	// ExchangeRateCheck is the check that the legs of a swap respect the declared exchange rate, see SwapTerms
+	ExchangeRateCheck ValidationCheck = "exchange-rate"
+	// ReceiverCheck is the check that the receivers of a transfer hold an attribute accepted by the ReceiverPolicy
+	ReceiverCheck ValidationCheck = "receiver"
+	// ThresholdCheck is the check that a token request without auditor signature is below the audit threshold,
+	// see AuditThresholds
+	ThresholdCheck ValidationCheck = "audit-threshold"
+	// OrganizationCheck is the check that the rebound tokens are owned by members of a departed organization,
+	// see RebindingAction
+	OrganizationCheck ValidationCheck = "organization"
+	// WriteCheck is the check that the actions of a validated token request can be written on the ledger,
+	// for instance within the supply caps
+	WriteCheck ValidationCheck = "write"
+	// OtherCheck classifies a failure that does not carry a validation report, see RejectionCheck
+	OtherCheck ValidationCheck = "other"
+)
+
+// ActionResult is the outcome of the validation of a single action of a token request
+type ActionResult struct {
+	Type ActionType `json:"type"`
+	// Index is the position of the action among the actions of the same type in the token request
+	Index int  `json:"index"`
+	Valid bool `json:"valid"`
+	// Check is the check that failed, if any
+	Check ValidationCheck `json:"check,omitempty"`
+	// Indices are the indices of the inputs or outputs of the action that caused the failure, if any
+	Indices []int  `json:"indices,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ValidationReport lists the results of the validation of the actions of a token request,
+// in the order they have been validated
+type ValidationReport struct {
+	Actions []*ActionResult `json:"actions"`
+	// RWSet are the statistics of the accesses to the ledger of the token request, if collected.
+	// They depend on the state of the endorser, for instance on its caches, so they are attached only to the reports
+	// of failed validations, whose responses are not endorsed.
+	RWSet *RWSetStats `json:"rwset,omitempty"`
+}
+
+// Valid returns true if no action failed validation
+func (r *ValidationReport) Valid() bool {
+	return r.Failure() == nil
+}
+
+// Failure returns the result of the first action that failed validation, nil if none failed
+func (r *ValidationReport) Failure() *ActionResult {
+	for _, a := range r.Actions {
+		if !a.Valid {
+			return a
+		}
+	}
+	return nil
+}
+
+// Succeeded records that the passed action passed validation
+func (r *ValidationReport) Succeeded(actionType ActionType, index int) {
+	r.Actions = append(r.Actions, &ActionResult{Type: actionType, Index: index, Valid: true})
+}
+
+// Failed records that the passed action failed the passed check, and returns the passed error
+// wrapped in a ValidationError carrying this report
+func (r *ValidationReport) Failed(actionType ActionType, index int, check ValidationCheck, err error, indices ...int) error {
+	r.Actions = append(r.Actions, &ActionResult{
+		Type:    actionType,
+		Index:   index,
+		Check:   check,
+		Indices: indices,
+		Error:   err.Error(),
+	})
+	return &ValidationError{Report: r, err: err}
+}
+
+func (r *ValidationReport) Bytes() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *ValidationReport) String() string {
+	f := r.Failure()
+	if f == nil {
+		return fmt.Sprintf("valid, [%d] actions", len(r.Actions))
+	}
+	return fmt.Sprintf("%s action [%d] failed %s check %v: %s", f.Type, f.Index, f.Check, f.Indices, f.Error)
+}
+
+// RejectionCheck returns the check the passed validation error failed: the check of the first failure of its report,
+// DoubleSpendCheck if it has the DoubleSpend code, OtherCheck otherwise.
+// Unlike the message of the error, the check is the same on all the validators, it can be recorded on the ledger.
+func RejectionCheck(err error) ValidationCheck {
+	if report, ok := GetValidationReport(err); ok {
+		if f := report.Failure(); f != nil && len(f.Check) != 0 {
+			return f.Check
+		}
+	}
+	if errors2.HasCode(err, errors2.DoubleSpend) {
+		return DoubleSpendCheck
+	}
+	return OtherCheck
+}
+
+// ValidationError is returned by a validator when a token request is invalid.
+// It carries the report of the validation.
+type ValidationError struct {
+	Report *ValidationReport
+	err    error
+}
+
+func (e *ValidationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ValidationError) Cause() error {
+	return e.err
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.err
+}
+
+// Code returns the error code corresponding to the failed check, if any,
+// otherwise the code carried by the wrapped error
+func (e *ValidationError) Code() errors2.Code {
+	if f := e.Report.Failure(); f != nil {
+		switch f.Check {
+		case DoubleSpendCheck:
+			return errors2.DoubleSpend
+		case ProofCheck:
+			return errors2.InvalidProof
+		case SignatureCheck:
+			return errors2.Unauthorized
+		}
+	}
+	return errors2.GetCode(e.err)
+}
+
+// ResponseError returns the error of a rejected token request, as returned by the token chaincode:
+// the error message and the payload carrying the validation report, if any.
+// When the report records a failure, the returned error is a ValidationError whose code is the one of the failed check.
+func ResponseError(message string, payload []byte) error {
+	report := &ValidationReport{}
+	if len(payload) == 0 || json.Unmarshal(payload, report) != nil || report.Failure() == nil {
+		return errors.New(message)
+	}
+	return &ValidationError{Report: report, err: errors.New(message)}
+}
+
+// GetValidationReport returns the validation report carried by the passed error, if any
+func GetValidationReport(err error) (*ValidationReport, bool) {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ve.Report, true
+	}
+	return nil, false
+}
+
+// KeyStats counts the accesses to the ledger keys of the same kind
+type KeyStats struct {
+	Reads   int `json:"reads"`
+	Writes  int `json:"writes"`
+	Deletes int `json:"deletes"`
+}
+
+// RWSetStats are the statistics of the accesses to the ledger of a transaction.
+// The keys read by a transaction and written by another one in the same block make the former fail the MVCC check,
+// the breakdown by kind of key tells which keys are hot.
+type RWSetStats struct {
+	KeyStats
+	// KeyBytes is the total size of the keys accessed, MaxKeySize the size of the largest one
+	KeyBytes   int `json:"keyBytes"`
+	MaxKeySize int `json:"maxKeySize"`
+	// ValueBytes is the total size of the values written
+	ValueBytes int `json:"valueBytes"`
+	// Kinds breaks the accesses down by kind of key, for instance token, sn, setup, token_request
+	Kinds map[string]*KeyStats `json:"kinds,omitempty"`
+}
+
+func NewRWSetStats() *RWSetStats {
+	return &RWSetStats{Kinds: map[string]*KeyStats{}}
+}
+
+// Read records a read of the passed key of the passed kind
+func (s *RWSetStats) Read(kind, key string) {
+	s.Reads++
+	s.kind(kind).Reads++
+	s.key(key)
+}
+
+// Write records a write of the passed value under the passed key of the passed kind
+func (s *RWSetStats) Write(kind, key string, value []byte) {
+	s.Writes++
+	s.kind(kind).Writes++
+	s.ValueBytes += len(value)
+	s.key(key)
+}
+
+// Delete records the deletion of the passed key of the passed kind
+func (s *RWSetStats) Delete(kind, key string) {
+	s.Deletes++
+	s.kind(kind).Deletes++
+	s.key(key)
+}
+
+func (s *RWSetStats) kind(kind string) *KeyStats {
+	if s.Kinds == nil {
+		s.Kinds = map[string]*KeyStats{}
+	}
+	ks, ok := s.Kinds[kind]
+	if !ok {
+		ks = &KeyStats{}
+		s.Kinds[kind] = ks
+	}
+	return ks
+}
+
+func (s *RWSetStats) key(key string) {
+	s.KeyBytes += len(key)
+	if len(key) > s.MaxKeySize {
+		s.MaxKeySize = len(key)
+	}
+}
@@ -18,13 +18,95 @@ type TokenRequest struct {
 	Transfers        [][]byte
 	Signatures       [][]byte
 	AuditorSignature []byte
+	// AuditorSignatures are the signatures of the auditors after the first one, when the request
+	// spans token types assigned to different auditors, see RequestAuditors
+	AuditorSignatures [][]byte `json:",omitempty"`
+	// BurnReceipts record the redemptions performed by the transfers, they are stored on the ledger
+	BurnReceipts []*BurnReceipt `json:",omitempty"`
+	// Migrations are the serialized migration actions, see MigrationAction
+	Migrations [][]byte `json:",omitempty"`
+	// Rebindings are the serialized rebinding actions, see RebindingAction
+	Rebindings [][]byte `json:",omitempty"`
+	// Driver, if set, is the identifier of the driver that produced the actions of this request.
+	// It allows the validator of a driver that replaced another one to recognize the requests of the replaced driver.
+	Driver string `json:",omitempty"`
+	// SwapTerms, if set, declare the exchange rate between two outputs of the transfers
+	SwapTerms *SwapTerms `json:",omitempty"`
+	// Attachments commit to the attachments of the transfers, whose references travel in the metadata
+	Attachments []*AttachmentDigest `json:",omitempty"`
+	// ThresholdProof, if set, proves that the total of the outputs of a request without auditor signature is below
+	// the audit threshold, for the drivers that hide the values, see AuditThresholds
+	ThresholdProof []byte `json:",omitempty"`
 }
 
 func (r *TokenRequest) Bytes() ([]byte, error) {
 	return json.Marshal(r)
 }
 
+// CompressedBytes returns the serialization of this request compressed with zstd, see Compress
+func (r *TokenRequest) CompressedBytes() ([]byte, error) {
+	raw, err := r.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return Compress(raw)
+}
+
+// FromBytes unmarshals the passed serialization, compressed or not, of a request,
+// decompressing at most DefaultMaxDecompressedSize bytes
 func (r *TokenRequest) FromBytes(raw []byte) error {
+	return r.FromBytesWithLimits(raw, nil)
+}
+
+// FromBytesWithLimits unmarshals the passed serialization, compressed or not, of a request, decompressing at most
+// the maximum size of a request set by the passed limits, see RequestLimits.DecompressionLimit
+func (r *TokenRequest) FromBytesWithLimits(raw []byte, limits *RequestLimits) error {
+	raw, err := Decompress(raw, limits.DecompressionLimit())
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, r)
+}
+
+// MarshalToSign returns the serialization of the parts of this request covered by the signatures
+func (r *TokenRequest) MarshalToSign() ([]byte, error) {
+	return json.Marshal(&TokenRequest{
+		Issues:       r.Issues,
+		Transfers:    r.Transfers,
+		BurnReceipts: r.BurnReceipts,
+		Migrations:   r.Migrations,
+		Rebindings:   r.Rebindings,
+		Driver:       r.Driver,
+		SwapTerms:    r.SwapTerms,
+		Attachments:  r.Attachments,
+	})
+}
+
+// BurnReceipt describes the redemption of an output of a transfer action.
+// The validator checks that the output is redeemed and that it carries the declared type and quantity.
+type BurnReceipt struct {
+	// TransferIndex is the index of the transfer action in the token request
+	TransferIndex int
+	// OutputIndex is the index of the redeemed output in the transfer action
+	OutputIndex int
+	Type        string
+	Quantity    string
+	// EnrollmentID is the enrollment ID of the redeemer, it is checked by the auditor, if any
+	EnrollmentID string
+	// Reference is application data, for example the identifier of an off-chain settlement
+	Reference []byte `json:",omitempty"`
+	// TokenInfo, if required by the driver, opens the redeemed output
+	TokenInfo []byte `json:",omitempty"`
+	// Issuer, if set, is the issuer of the redeemed type that co-signs the redemption.
+	// It is required if the public parameters demand it, see PublicParameters.RedeemRequiresIssuer.
+	Issuer view.Identity `json:",omitempty"`
+}
+
+func (r *BurnReceipt) Bytes() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *BurnReceipt) FromBytes(raw []byte) error {
 	return json.Unmarshal(raw, r)
 }
 
@@ -34,6 +116,11 @@ type IssueMetadata struct {
 	TokenInfo  [][]byte
 	Receivers  []view.Identity
 	AuditInfos [][]byte
+	// Approver, if set, is the identity that must co-sign the issue action, see IssuePolicy
+	Approver view.Identity `json:",omitempty"`
+	// OutputDigests is set in filtered metadata, see TokenRequestMetadata.FilterBy.
+	// For each redacted output, it holds the digest of the removed information, nil otherwise.
+	OutputDigests [][]byte `json:",omitempty"`
 }
 
 // TransferMetadata contains the following information:
@@ -47,11 +134,31 @@ type TransferMetadata struct {
 	Receivers          []view.Identity
 	ReceiverIsSender   []bool
 	ReceiverAuditInfos [][]byte
+	// InputDigests and OutputDigests are set in filtered metadata, see TokenRequestMetadata.FilterBy.
+	// For each redacted input or output, they hold the digest of the removed information, nil otherwise.
+	InputDigests  [][]byte `json:",omitempty"`
+	OutputDigests [][]byte `json:",omitempty"`
+	// Attachments reference the payloads attached to the transfer, see Attachment
+	Attachments []*Attachment `json:",omitempty"`
+}
+
+// RebindingMetadata carries, for each output of a rebinding action, the opening of the rebound token, the one of its
+// input, so that the successor owner can spend it, see RebindingAction
+type RebindingMetadata struct {
+	Outputs            [][]byte
+	TokenInfo          [][]byte
+	Receivers          []view.Identity
+	ReceiverAuditInfos [][]byte
+	// OutputDigests is set in filtered metadata, see TokenRequestMetadata.FilterBy.
+	// For each redacted output, it holds the digest of the removed information, nil otherwise.
+	OutputDigests [][]byte `json:",omitempty"`
 }
 
 type TokenRequestMetadata struct {
 	Issues    []IssueMetadata
 	Transfers []TransferMetadata
+	// Rebindings are the metadata of the rebinding actions, in the order of the token request
+	Rebindings []RebindingMetadata `json:",omitempty"`
 }
 
 func (m *TokenRequestMetadata) TokenInfos() [][]byte {
@@ -62,9 +169,38 @@ func (m *TokenRequestMetadata) TokenInfos() [][]byte {
 	for _, transfer := range m.Transfers {
 		res = append(res, transfer.TokenInfo...)
 	}
+	for _, rebinding := range m.Rebindings {
+		res = append(res, rebinding.TokenInfo...)
+	}
 	return res
 }
 
+// GetAuditInfo returns the audit info of the owner of the passed output, nil if not found
+func (m *TokenRequestMetadata) GetAuditInfo(tokenRaw []byte) []byte {
+	for _, issue := range m.Issues {
+		for i, output := range issue.Outputs {
+			if bytes.Equal(output, tokenRaw) && i < len(issue.AuditInfos) {
+				return issue.AuditInfos[i]
+			}
+		}
+	}
+	for _, transfer := range m.Transfers {
+		for i, output := range transfer.Outputs {
+			if bytes.Equal(output, tokenRaw) && i < len(transfer.ReceiverAuditInfos) {
+				return transfer.ReceiverAuditInfos[i]
+			}
+		}
+	}
+	for _, rebinding := range m.Rebindings {
+		for i, output := range rebinding.Outputs {
+			if bytes.Equal(output, tokenRaw) && i < len(rebinding.ReceiverAuditInfos) {
+				return rebinding.ReceiverAuditInfos[i]
+			}
+		}
+	}
+	return nil
+}
+
 func (m *TokenRequestMetadata) GetTokenInfo(tokenRaw []byte) []byte {
 	for _, issue := range m.Issues {
 		for i, output := range issue.Outputs {
@@ -80,6 +216,13 @@ func (m *TokenRequestMetadata) GetTokenInfo(tokenRaw []byte) []byte {
 			}
 		}
 	}
+	for _, rebinding := range m.Rebindings {
+		for i, output := range rebinding.Outputs {
+			if bytes.Equal(output, tokenRaw) {
+				return bytesAt(rebinding.TokenInfo, i)
+			}
+		}
+	}
 	return nil
 }
 
@@ -95,6 +238,11 @@ func (m *TokenRequestMetadata) Recipients() [][]byte {
 			res = append(res, r.Bytes())
 		}
 	}
+	for _, rebinding := range m.Rebindings {
+		for _, r := range rebinding.Receivers {
+			res = append(res, r.Bytes())
+		}
+	}
 	return res
 }
 
@@ -0,0 +1,116 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+)
+
+// Version is the version of a key, the height of the transaction that last wrote it
+type Version struct {
+	BlockNum uint64
+	TxNum    uint64
+}
+
+// Equal returns true if the passed version is equal to this version, nil versions denote keys not yet written
+func (v *Version) Equal(o *Version) bool {
+	if v == nil || o == nil {
+		return v == o
+	}
+	return v.BlockNum == o.BlockNum && v.TxNum == o.TxNum
+}
+
+type GetStateWithVersionFnc = func(key string) ([]byte, *Version, error)
+
+// VersionedLedger is a ledger that returns, together with the value of a key, its version.
+// The version is nil if the key has never been written.
+type VersionedLedger interface {
+	GetStateWithVersion(key string) ([]byte, *Version, error)
+}
+
+// ReadSet lists the keys read during a validation, in reading order, and the versions observed
+type ReadSet struct {
+	Keys     []string
+	Versions []*Version
+}
+
+// Version returns the observed version of the passed key, and false if the key has not been read
+func (r *ReadSet) Version(key string) (*Version, bool) {
+	for i, k := range r.Keys {
+		if k == key {
+			return r.Versions[i], true
+		}
+	}
+	return nil, false
+}
+
+// Conflicts checks the read set against the passed ledger. It returns an error, with code errors.Conflict,
+// if any key has been written after the validation that produced the read set.
+func (r *ReadSet) Conflicts(ledger VersionedLedger) error {
+	for i, key := range r.Keys {
+		_, version, err := ledger.GetStateWithVersion(key)
+		if err != nil {
+			return errors.Wrapf(err, "failed reading [%s]", key)
+		}
+		if !version.Equal(r.Versions[i]) {
+			return errors2.Errorf(errors2.Conflict, "key [%s] changed since validation", key)
+		}
+	}
+	return nil
+}
+
+// SnapshotLedger is a Ledger, backed by a VersionedLedger, that records the version of each key read.
+// If pinned to a block height, reading a key written at or after that height fails,
+// therefore the validation only depends on the state of the ledger before that height.
+type SnapshotLedger struct {
+	ledger VersionedLedger
+	height uint64
+
+	lock    sync.Mutex
+	readSet ReadSet
+}
+
+// NewSnapshotLedger returns a SnapshotLedger on top of the passed ledger, pinned at the passed block height.
+// Height zero does not pin the ledger.
+func NewSnapshotLedger(ledger VersionedLedger, height uint64) *SnapshotLedger {
+	return &SnapshotLedger{ledger: ledger, height: height}
+}
+
+func (s *SnapshotLedger) GetState(key string) ([]byte, error) {
+	value, version, err := s.ledger.GetStateWithVersion(key)
+	if err != nil {
+		return nil, err
+	}
+	if s.height != 0 && version != nil && version.BlockNum >= s.height {
+		return nil, errors2.Errorf(errors2.Conflict, "key [%s] written at block [%d], after snapshot [%d]", key, version.BlockNum, s.height)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if observed, ok := s.readSet.Version(key); ok {
+		if !observed.Equal(version) {
+			return nil, errors2.Errorf(errors2.Conflict, "key [%s] changed during validation", key)
+		}
+		return value, nil
+	}
+	s.readSet.Keys = append(s.readSet.Keys, key)
+	s.readSet.Versions = append(s.readSet.Versions, version)
+	return value, nil
+}
+
+// ReadSet returns a copy of the keys read so far and their versions
+func (s *SnapshotLedger) ReadSet() *ReadSet {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return &ReadSet{
+		Keys:     append([]string(nil), s.readSet.Keys...),
+		Versions: append([]*Version(nil), s.readSet.Versions...),
+	}
+}
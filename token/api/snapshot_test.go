@@ -0,0 +1,76 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+)
+
+type versionedState struct {
+	value   []byte
+	version *Version
+}
+
+type versionedLedger map[string]versionedState
+
+func (l versionedLedger) GetStateWithVersion(key string) ([]byte, *Version, error) {
+	s, ok := l[key]
+	if !ok {
+		return nil, nil, nil
+	}
+	return s.value, s.version, nil
+}
+
+func TestSnapshotLedger(t *testing.T) {
+	ledger := versionedLedger{
+		"a": {value: []byte("1"), version: &Version{BlockNum: 3, TxNum: 1}},
+		"b": {value: []byte("2"), version: &Version{BlockNum: 5}},
+	}
+
+	snapshot := NewSnapshotLedger(ledger, 0)
+	v, err := snapshot.GetState("a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1"), v)
+	v, err = snapshot.GetState("c")
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+	_, err = snapshot.GetState("a")
+	assert.NoError(t, err)
+
+	rs := snapshot.ReadSet()
+	assert.Equal(t, []string{"a", "c"}, rs.Keys)
+	version, ok := rs.Version("a")
+	assert.True(t, ok)
+	assert.Equal(t, &Version{BlockNum: 3, TxNum: 1}, version)
+	version, ok = rs.Version("c")
+	assert.True(t, ok)
+	assert.Nil(t, version)
+	_, ok = rs.Version("b")
+	assert.False(t, ok)
+	assert.NoError(t, rs.Conflicts(ledger))
+
+	// a later transaction writes c
+	ledger["c"] = versionedState{value: []byte("3"), version: &Version{BlockNum: 6}}
+	err = rs.Conflicts(ledger)
+	assert.Error(t, err)
+	assert.True(t, errors2.HasCode(err, errors2.Conflict))
+
+	// a read during the validation observes a different version
+	_, err = snapshot.GetState("c")
+	assert.True(t, errors2.HasCode(err, errors2.Conflict))
+
+	// pinned snapshot
+	snapshot = NewSnapshotLedger(ledger, 5)
+	_, err = snapshot.GetState("a")
+	assert.NoError(t, err)
+	_, err = snapshot.GetState("b")
+	assert.Error(t, err)
+	assert.True(t, errors2.HasCode(err, errors2.Conflict))
+}
@@ -0,0 +1,36 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"encoding/json"
+)
+
+// Supply is the supply of a token type, as tracked on the ledger.
+// Issued counts the issue actions revealing the value they issue, Redeemed counts the redemptions with a burn receipt.
+type Supply struct {
+	Type     string
+	Issued   uint64
+	Redeemed uint64 `json:",omitempty"`
+}
+
+// Circulating returns the value issued and not redeemed yet.
+// It is zero if more value has been redeemed than issued, that is, if the redeemed tokens have been issued before
+// the supply was tracked.
+func (s *Supply) Circulating() uint64 {
+	if s.Redeemed > s.Issued {
+		return 0
+	}
+	return s.Issued - s.Redeemed
+}
+
+func (s *Supply) Bytes() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+func (s *Supply) FromBytes(raw []byte) error {
+	return json.Unmarshal(raw, s)
+}
@@ -0,0 +1,27 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupply(t *testing.T) {
+	s := &Supply{Type: "USD", Issued: 100, Redeemed: 30}
+	assert.Equal(t, uint64(70), s.Circulating())
+
+	raw, err := s.Bytes()
+	assert.NoError(t, err)
+	s2 := &Supply{}
+	assert.NoError(t, s2.FromBytes(raw))
+	assert.Equal(t, s, s2)
+
+	// the tokens redeemed have been issued before the supply was tracked
+	s = &Supply{Type: "USD", Issued: 10, Redeemed: 30}
+	assert.Equal(t, uint64(0), s.Circulating())
+}
@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// SwapLeg declares an output of a transfer action of a swap, with its type and quantity
+type SwapLeg struct {
+	// TransferIndex is the index of the transfer action in the token request
+	TransferIndex int
+	// OutputIndex is the index of the output in the transfer action
+	OutputIndex int
+	Type        string
+	Quantity    string
+	// TokenInfo, if required by the driver, opens the output
+	TokenInfo []byte `json:",omitempty"`
+}
+
+// SwapTerms declare the exchange rate between the two legs of a swap, a delivery versus payment for instance,
+// performed by a single token request. The terms are covered by the signatures of the token request, once signed
+// a party cannot alter the amounts without invalidating the request.
+// The validator checks that each leg matches its output and that
+// Second.Quantity * RateDenominator = First.Quantity * RateNumerator.
+type SwapTerms struct {
+	First  *SwapLeg
+	Second *SwapLeg
+	// RateNumerator and RateDenominator define the price of a unit of the first leg in units of the second leg
+	RateNumerator   uint64
+	RateDenominator uint64
+}
+
+// OutputMatcher checks that the passed output carries the passed type and quantity, tokenInfo opens the output
+// if required by the driver
+type OutputMatcher func(output Output, typ string, quantity string, tokenInfo []byte) error
+
+// VerifySwapTerms checks that the legs of the passed swap terms, if any, refer to outputs of the passed transfer
+// actions of distinct types, that the outputs match the legs, and that the legs respect the exchange rate
+func VerifySwapTerms(transfers []TransferAction, terms *SwapTerms, match OutputMatcher, report *ValidationReport) error {
+	if terms == nil {
+		return nil
+	}
+	if terms.First == nil || terms.Second == nil {
+		return report.Failed(SwapActionType, 0, FormatCheck, errors.Errorf("swap terms must declare two legs"))
+	}
+	if terms.RateNumerator == 0 || terms.RateDenominator == 0 {
+		return report.Failed(SwapActionType, 0, FormatCheck, errors.Errorf("invalid exchange rate [%d/%d]", terms.RateNumerator, terms.RateDenominator))
+	}
+	if terms.First.Type == terms.Second.Type {
+		return report.Failed(SwapActionType, 0, FormatCheck, errors.Errorf("the legs of a swap must have distinct types, got [%s]", terms.First.Type))
+	}
+	var quantities [2]*big.Int
+	for i, leg := range []*SwapLeg{terms.First, terms.Second} {
+		if leg.TransferIndex < 0 || leg.TransferIndex >= len(transfers) {
+			return report.Failed(SwapActionType, 0, FormatCheck, errors.Errorf("swap leg [%d] refers to transfer [%d], only [%d] available", i, leg.TransferIndex, len(transfers)))
+		}
+		t := transfers[leg.TransferIndex]
+		if leg.OutputIndex < 0 || leg.OutputIndex >= t.NumOutputs() {
+			return report.Failed(SwapActionType, 0, FormatCheck, errors.Errorf("swap leg [%d] refers to output [%d] of transfer [%d], only [%d] available", i, leg.OutputIndex, leg.TransferIndex, t.NumOutputs()))
+		}
+		// the quantity is in hex or decimal, its precision is checked by the matcher
+		q, ok := new(big.Int).SetString(leg.Quantity, 0)
+		if !ok || q.Sign() <= 0 {
+			return report.Failed(SwapActionType, 0, FormatCheck, errors.Errorf("swap leg [%d] has an invalid quantity [%s]", i, leg.Quantity))
+		}
+		if err := match(t.GetOutputs()[leg.OutputIndex], leg.Type, leg.Quantity, leg.TokenInfo); err != nil {
+			return report.Failed(SwapActionType, 0, ProofCheck, errors.WithMessagef(err, "swap leg [%d] does not match output [%d] of transfer [%d]", i, leg.OutputIndex, leg.TransferIndex))
+		}
+		quantities[i] = q
+	}
+	first := new(big.Int).Mul(quantities[0], new(big.Int).SetUint64(terms.RateNumerator))
+	second := new(big.Int).Mul(quantities[1], new(big.Int).SetUint64(terms.RateDenominator))
+	if first.Cmp(second) != 0 {
+		return report.Failed(SwapActionType, 0, ExchangeRateCheck, errors.Errorf("swap of [%s %s] for [%s %s] does not respect the exchange rate [%d/%d]", terms.First.Quantity, terms.First.Type, terms.Second.Quantity, terms.Second.Type, terms.RateNumerator, terms.RateDenominator))
+	}
+	report.Succeeded(SwapActionType, 0)
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type clearOutput struct {
+	Output
+	typ      string
+	quantity string
+}
+
+type clearTransfer struct {
+	TransferAction
+	outputs []Output
+}
+
+func (t *clearTransfer) NumOutputs() int {
+	return len(t.outputs)
+}
+
+func (t *clearTransfer) GetOutputs() []Output {
+	return t.outputs
+}
+
+func matchClearOutput(output Output, typ string, quantity string, _ []byte) error {
+	out := output.(*clearOutput)
+	if out.typ != typ || out.quantity != quantity {
+		return errors.Errorf("expected [%s %s], got [%s %s]", quantity, typ, out.quantity, out.typ)
+	}
+	return nil
+}
+
+func TestVerifySwapTerms(t *testing.T) {
+	// the seller delivers 10 shares, the buyer pays 250 USD
+	transfers := []TransferAction{
+		&clearTransfer{outputs: []Output{&clearOutput{typ: "SHARE", quantity: "10"}}},
+		&clearTransfer{outputs: []Output{&clearOutput{typ: "USD", quantity: "0xfa"}, &clearOutput{typ: "USD", quantity: "5"}}},
+	}
+	terms := func(second *SwapLeg, numerator uint64) *SwapTerms {
+		return &SwapTerms{
+			First:           &SwapLeg{TransferIndex: 0, OutputIndex: 0, Type: "SHARE", Quantity: "10"},
+			Second:          second,
+			RateNumerator:   numerator,
+			RateDenominator: 1,
+		}
+	}
+	payment := &SwapLeg{TransferIndex: 1, OutputIndex: 0, Type: "USD", Quantity: "0xfa"}
+
+	assert.NoError(t, VerifySwapTerms(transfers, nil, matchClearOutput, &ValidationReport{}))
+	report := &ValidationReport{}
+	assert.NoError(t, VerifySwapTerms(transfers, terms(payment, 25), matchClearOutput, report))
+	assert.True(t, report.Valid())
+
+	// the rate is not respected
+	report = &ValidationReport{}
+	err := VerifySwapTerms(transfers, terms(payment, 26), matchClearOutput, report)
+	assert.EqualError(t, err, "swap of [10 SHARE] for [0xfa USD] does not respect the exchange rate [26/1]")
+	assert.Equal(t, ExchangeRateCheck, report.Failure().Check)
+
+	// the leg does not match its output
+	report = &ValidationReport{}
+	err = VerifySwapTerms(transfers, terms(&SwapLeg{TransferIndex: 1, OutputIndex: 1, Type: "USD", Quantity: "0xfa"}, 25), matchClearOutput, report)
+	assert.Error(t, err)
+	assert.Equal(t, ProofCheck, report.Failure().Check)
+
+	// malformed terms
+	for _, tt := range []*SwapTerms{
+		terms(nil, 25),
+		terms(payment, 0),
+		terms(&SwapLeg{TransferIndex: 1, OutputIndex: 0, Type: "SHARE", Quantity: "0xfa"}, 25),
+		terms(&SwapLeg{TransferIndex: 2, OutputIndex: 0, Type: "USD", Quantity: "0xfa"}, 25),
+		terms(&SwapLeg{TransferIndex: 1, OutputIndex: 2, Type: "USD", Quantity: "0xfa"}, 25),
+		terms(&SwapLeg{TransferIndex: 1, OutputIndex: 0, Type: "USD", Quantity: "-1"}, 25),
+	} {
+		report = &ValidationReport{}
+		assert.Error(t, VerifySwapTerms(transfers, tt, matchClearOutput, report))
+		assert.Equal(t, FormatCheck, report.Failure().Check)
+	}
+}
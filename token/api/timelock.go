@@ -0,0 +1,99 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+)
+
+// timeLockPrefix marks the owners that are time locks, it cannot be the prefix of a serialized msp identity
+var timeLockPrefix = []byte("timelock:")
+
+// TimeLock is a script owner: the token can be spent by Owner only from the ledger height NotBeforeHeight
+// and from the time NotBefore, as fixed by the ledger. A zero value means no constraint.
+type TimeLock struct {
+	Owner           view.Identity
+	NotBefore       time.Time
+	NotBeforeHeight uint64 `json:",omitempty"`
+}
+
+// NewTimeLockOwner returns the owner identity of the tokens locked by the passed time lock
+func NewTimeLockOwner(lock *TimeLock) (view.Identity, error) {
+	if lock == nil || lock.Owner.IsNone() {
+		return nil, errors.New("time lock owner not specified")
+	}
+	if lock.NotBefore.IsZero() && lock.NotBeforeHeight == 0 {
+		return nil, errors.New("time lock has no constraint")
+	}
+	if IsTimeLock(lock.Owner) {
+		return nil, errors.New("time locks cannot be nested")
+	}
+	raw, err := json.Marshal(lock)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed serializing time lock")
+	}
+	return append(append([]byte{}, timeLockPrefix...), raw...), nil
+}
+
+// IsTimeLock returns true if the passed owner identity is a time lock
+func IsTimeLock(id view.Identity) bool {
+	return bytes.HasPrefix(id, timeLockPrefix)
+}
+
+// GetTimeLock returns the time lock the passed owner identity encodes, nil if the identity is not a time lock
+func GetTimeLock(id view.Identity) (*TimeLock, error) {
+	if !IsTimeLock(id) {
+		return nil, nil
+	}
+	lock := &TimeLock{}
+	if err := json.Unmarshal(id[len(timeLockPrefix):], lock); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling time lock")
+	}
+	if lock.Owner.IsNone() {
+		return nil, errors.New("invalid time lock, owner not specified")
+	}
+	return lock, nil
+}
+
+// Check returns an error if the lock does not allow spending at the passed time and ledger height.
+// Unknown time and height, zero values, do not satisfy the corresponding constraints.
+func (l *TimeLock) Check(txTime time.Time, height uint64) error {
+	if !l.NotBefore.IsZero() {
+		if txTime.IsZero() {
+			return errors.Errorf("transaction time not available, cannot check time lock [%s]", l.NotBefore)
+		}
+		if txTime.Before(l.NotBefore) {
+			return errors.Errorf("locked until [%s], transaction time [%s]", l.NotBefore, txTime)
+		}
+	}
+	if l.NotBeforeHeight != 0 {
+		if height == 0 {
+			return errors.Errorf("ledger height not available, cannot check time lock [%d]", l.NotBeforeHeight)
+		}
+		if height < l.NotBeforeHeight {
+			return errors.Errorf("locked until height [%d], current height [%d]", l.NotBeforeHeight, height)
+		}
+	}
+	return nil
+}
+
+// SpenderOf returns the identity that signs for the tokens owned by the passed identity:
+// the owner of the time lock, if the identity is a time lock, the identity itself otherwise
+func SpenderOf(id view.Identity) (view.Identity, error) {
+	lock, err := GetTimeLock(id)
+	if err != nil {
+		return nil, err
+	}
+	if lock == nil {
+		return id, nil
+	}
+	return lock.Owner, nil
+}
@@ -0,0 +1,52 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeLock(t *testing.T) {
+	alice := view.Identity("alice")
+	notBefore := time.Unix(1000, 0)
+
+	_, err := NewTimeLockOwner(&TimeLock{Owner: alice})
+	assert.Error(t, err)
+	_, err = NewTimeLockOwner(&TimeLock{NotBefore: notBefore})
+	assert.Error(t, err)
+
+	owner, err := NewTimeLockOwner(&TimeLock{Owner: alice, NotBefore: notBefore, NotBeforeHeight: 10})
+	assert.NoError(t, err)
+	assert.True(t, IsTimeLock(owner))
+	assert.False(t, IsTimeLock(alice))
+	_, err = NewTimeLockOwner(&TimeLock{Owner: owner, NotBeforeHeight: 10})
+	assert.Error(t, err)
+
+	spender, err := SpenderOf(owner)
+	assert.NoError(t, err)
+	assert.Equal(t, alice, spender)
+	spender, err = SpenderOf(alice)
+	assert.NoError(t, err)
+	assert.Equal(t, alice, spender)
+
+	lock, err := GetTimeLock(owner)
+	assert.NoError(t, err)
+	assert.True(t, notBefore.Equal(lock.NotBefore))
+	assert.NoError(t, lock.Check(notBefore, 10))
+	assert.NoError(t, lock.Check(notBefore.Add(time.Hour), 11))
+	assert.Error(t, lock.Check(notBefore.Add(-time.Second), 10))
+	assert.Error(t, lock.Check(notBefore, 9))
+	// unknown time and height do not satisfy the lock
+	assert.Error(t, lock.Check(time.Time{}, 10))
+	assert.Error(t, lock.Check(notBefore, 0))
+
+	_, err = GetTimeLock(append([]byte("timelock:"), []byte("{")...))
+	assert.Error(t, err)
+}
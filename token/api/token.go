@@ -13,4 +13,7 @@ import (
 type TokenService interface {
 	// DeserializeToken returns the token and its issuer (if any).
 	DeserializeToken(outputRaw []byte, tokenInfoRaw []byte) (*token2.Token, view.Identity, error)
+	// VerifyTokenInfo checks that the passed token information, received out of band for instance,
+	// opens the passed output.
+	VerifyTokenInfo(outputRaw []byte, tokenInfoRaw []byte) error
 }
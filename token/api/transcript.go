@@ -0,0 +1,229 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TranscriptVersion is the version of the encoding of the transcripts produced by this package
+const TranscriptVersion = 1
+
+// Transcript is the self-contained record of the verification of a token request: the public inputs, the
+// request with its proofs, the state of the ledger the verification read, and the outcome of each step.
+// A third party holding the public parameters can re-verify it offline, see Transcript.Verify.
+// The validation hooks and the context are not part of the transcript.
+// The transcript is self-attested: the reads carry no proof against the ledger, a third party re-verifying it trusts
+// the node that recorded it for the state the request was verified against.
+type Transcript struct {
+	Version int `json:"version"`
+	// PublicParamsHash is the SHA-256 hash of the serialized public parameters the request was verified with
+	PublicParamsHash []byte `json:"ppHash"`
+	// Binding is the ID of the transaction the request is bound to
+	Binding string `json:"binding"`
+	// Request is the serialized token request, signatures and proofs included
+	Request []byte `json:"request"`
+	// TxTime, Height, and Limits are the validation options in use, see ValidationOptions
+	TxTime time.Time      `json:"txTime,omitempty"`
+	Height uint64         `json:"height,omitempty"`
+	Limits *RequestLimits `json:"limits,omitempty"`
+	// Reads are the ledger keys read by the verification, in reading order, with the values observed in the
+	// vault of the recording node. They are not bound to the read set or to the block of the transaction.
+	Reads []*TranscriptRead `json:"reads"`
+	// Report lists the verification steps and their outcomes
+	Report *ValidationReport `json:"report"`
+}
+
+// TranscriptRead is a ledger key read during a verification, a nil value denotes a key not on the ledger
+type TranscriptRead struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value,omitempty"`
+}
+
+// NewTranscript verifies the passed serialized token request, bound to the passed transaction ID, and records the
+// verification. The returned error reports the failures preventing the recording, the validation failures are
+// in the report of the transcript.
+func NewTranscript(validator Validator, pp []byte, getState GetStateFnc, binding string, raw []byte, opts ...ValidationOption) (*Transcript, error) {
+	options, err := CompileValidationOptions(opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed compiling validation options")
+	}
+	ledger := &recordingLedger{getState: getState}
+	actions, err := validator.VerifyTokenRequestFromRaw(ledger.GetState, binding, raw, opts...)
+	return &Transcript{
+		Version:          TranscriptVersion,
+		PublicParamsHash: hashPublicParams(pp),
+		Binding:          binding,
+		Request:          raw,
+		TxTime:           options.TxTime,
+		Height:           options.Height,
+		Limits:           options.Limits,
+		Reads:            ledger.Reads(),
+		Report:           reportOf(actions, err),
+	}, nil
+}
+
+// TranscriptFromBytes decodes the passed transcript
+func TranscriptFromBytes(raw []byte) (*Transcript, error) {
+	t := &Transcript{}
+	if err := json.Unmarshal(raw, t); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling transcript")
+	}
+	if t.Version != TranscriptVersion {
+		return nil, errors.Errorf("unsupported transcript version [%d], expected [%d]", t.Version, TranscriptVersion)
+	}
+	if t.Report == nil {
+		return nil, errors.New("invalid transcript: missing report")
+	}
+	return t, nil
+}
+
+func (t *Transcript) Bytes() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+// Verify verifies again the request of the transcript with the passed validator, instantiated from the passed
+// public parameters, against the recorded reads only. The passed options, the validation hooks of the application
+// for instance, are added to the recorded ones.
+// It returns the report of the new verification, and an error if its outcome differs from the recorded one.
+func (t *Transcript) Verify(validator Validator, pp []byte, opts ...ValidationOption) (*ValidationReport, error) {
+	if !bytes.Equal(hashPublicParams(pp), t.PublicParamsHash) {
+		return nil, errors.New("the public parameters do not match the ones of the transcript")
+	}
+	var recorded []ValidationOption
+	if !t.TxTime.IsZero() {
+		recorded = append(recorded, WithTxTime(t.TxTime))
+	}
+	if t.Height != 0 {
+		recorded = append(recorded, WithHeight(t.Height))
+	}
+	if t.Limits != nil {
+		recorded = append(recorded, WithRequestLimits(t.Limits))
+	}
+	ledger := &transcriptLedger{reads: t.Reads}
+	actions, err := validator.VerifyTokenRequestFromRaw(ledger.GetState, t.Binding, t.Request, append(recorded, opts...)...)
+	report := reportOf(actions, err)
+	if !sameOutcome(report, t.Report) {
+		return report, errors.Errorf("outcome mismatch: recorded [%s], verified [%s]", t.Report, report)
+	}
+	return report, nil
+}
+
+// SucceededReport returns the report of a valid request made of the passed actions, in the order returned by the
+// validator
+func SucceededReport(actions []interface{}) *ValidationReport {
+	report := &ValidationReport{}
+	indices := map[ActionType]int{}
+	for _, action := range actions {
+		var typ ActionType
+		switch action.(type) {
+		case *MigrationAction:
+			typ = MigrationActionType
+		case *RebindingAction:
+			typ = RebindingActionType
+		case *BurnReceipt:
+			typ = BurnActionType
+		case TransferAction:
+			typ = TransferActionType
+		case IssueAction:
+			typ = IssueActionType
+		default:
+			continue
+		}
+		report.Succeeded(typ, indices[typ])
+		indices[typ]++
+	}
+	return report
+}
+
+// reportOf returns the report of a verification that returned the passed actions and error
+func reportOf(actions []interface{}, err error) *ValidationReport {
+	if err == nil {
+		return SucceededReport(actions)
+	}
+	report, ok := GetValidationReport(err)
+	if !ok {
+		report = &ValidationReport{}
+		report.Failed(RequestActionType, 0, "", err)
+	}
+	return report
+}
+
+// sameOutcome returns true if the passed reports agree on the validity of the request and, if invalid,
+// on the action and the check that failed. The error messages are not compared.
+func sameOutcome(a, b *ValidationReport) bool {
+	fa, fb := a.Failure(), b.Failure()
+	if fa == nil || fb == nil {
+		return fa == fb
+	}
+	if fa.Type != fb.Type || fa.Index != fb.Index || fa.Check != fb.Check || len(fa.Indices) != len(fb.Indices) {
+		return false
+	}
+	for i := range fa.Indices {
+		if fa.Indices[i] != fb.Indices[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func hashPublicParams(raw []byte) []byte {
+	h := sha256.Sum256(raw)
+	return h[:]
+}
+
+// recordingLedger reads the state with the passed function, recording the first read of each key
+type recordingLedger struct {
+	getState GetStateFnc
+
+	lock  sync.Mutex
+	reads []*TranscriptRead
+	keys  map[string]bool
+}
+
+func (l *recordingLedger) GetState(key string) ([]byte, error) {
+	value, err := l.getState(key)
+	if err != nil {
+		return nil, err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.keys == nil {
+		l.keys = map[string]bool{}
+	}
+	if !l.keys[key] {
+		l.keys[key] = true
+		l.reads = append(l.reads, &TranscriptRead{Key: key, Value: value})
+	}
+	return value, nil
+}
+
+func (l *recordingLedger) Reads() []*TranscriptRead {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]*TranscriptRead(nil), l.reads...)
+}
+
+// transcriptLedger serves the reads recorded in a transcript, reading any other key fails
+type transcriptLedger struct {
+	reads []*TranscriptRead
+}
+
+func (l *transcriptLedger) GetState(key string) ([]byte, error) {
+	for _, r := range l.reads {
+		if r.Key == key {
+			return r.Value, nil
+		}
+	}
+	return nil, errors.Errorf("key [%s] not in the transcript", key)
+}
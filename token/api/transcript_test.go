@@ -0,0 +1,95 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// spendingValidator accepts a request if the key named by the request holds a value, and fails otherwise
+type spendingValidator struct{}
+
+func (v *spendingValidator) VerifyTokenRequest(ledger Ledger, signatureProvider SignatureProvider, binding string, tr *TokenRequest, opts ...ValidationOption) ([]interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (v *spendingValidator) VerifyTokenRequestFromRaw(getState GetStateFnc, binding string, raw []byte, opts ...ValidationOption) ([]interface{}, error) {
+	options, err := CompileValidationOptions(opts...)
+	if err != nil {
+		return nil, err
+	}
+	report := &ValidationReport{}
+	if options.TxTime.IsZero() {
+		return nil, report.Failed(RequestActionType, 0, ExpirationCheck, errors.New("missing tx time"))
+	}
+	value, err := getState(string(raw))
+	if err != nil {
+		return nil, report.Failed(TransferActionType, 0, FormatCheck, err)
+	}
+	if value == nil {
+		return nil, report.Failed(TransferActionType, 0, DoubleSpendCheck, errors.Errorf("input [%s] spent", raw), 0)
+	}
+	return []interface{}{&MigrationAction{}}, nil
+}
+
+func (v *spendingValidator) UnmarshalActions(raw []byte) ([]interface{}, error) {
+	return nil, nil
+}
+
+func (v *spendingValidator) VerifyOwnership(proof *OwnershipProof) error {
+	return nil
+}
+
+func TestTranscript(t *testing.T) {
+	pp := []byte("pp")
+	ledger := map[string][]byte{"a": []byte("1")}
+	getState := func(key string) ([]byte, error) { return ledger[key], nil }
+	txTime := time.Unix(1000, 0)
+
+	transcript, err := NewTranscript(&spendingValidator{}, pp, getState, "tx1", []byte("a"), WithTxTime(txTime))
+	assert.NoError(t, err)
+	assert.True(t, transcript.Report.Valid())
+	assert.Equal(t, []*TranscriptRead{{Key: "a", Value: []byte("1")}}, transcript.Reads)
+	raw, err := transcript.Bytes()
+	assert.NoError(t, err)
+
+	// the transcript is verified without the ledger, even once the input is spent
+	delete(ledger, "a")
+	decoded, err := TranscriptFromBytes(raw)
+	assert.NoError(t, err)
+	report, err := decoded.Verify(&spendingValidator{}, pp)
+	assert.NoError(t, err)
+	assert.True(t, report.Valid())
+	_, err = decoded.Verify(&spendingValidator{}, []byte("other pp"))
+	assert.EqualError(t, err, "the public parameters do not match the ones of the transcript")
+
+	// tampering with the recorded reads changes the outcome
+	decoded.Reads[0].Value = nil
+	report, err = decoded.Verify(&spendingValidator{}, pp)
+	assert.Error(t, err)
+	assert.Equal(t, DoubleSpendCheck, report.Failure().Check)
+
+	// the failures are recorded and verified too
+	transcript, err = NewTranscript(&spendingValidator{}, pp, getState, "tx2", []byte("a"), WithTxTime(txTime))
+	assert.NoError(t, err)
+	assert.Equal(t, DoubleSpendCheck, transcript.Report.Failure().Check)
+	_, err = transcript.Verify(&spendingValidator{}, pp)
+	assert.NoError(t, err)
+
+	// a key not read while recording cannot be read
+	transcript.Request = []byte("b")
+	report, err = transcript.Verify(&spendingValidator{}, pp)
+	assert.Error(t, err)
+	assert.Equal(t, FormatCheck, report.Failure().Check)
+	assert.Contains(t, report.Failure().Error, "key [b] not in the transcript")
+
+	_, err = TranscriptFromBytes([]byte(`{"version":2}`))
+	assert.EqualError(t, err, "unsupported transcript version [2], expected [1]")
+}
@@ -6,13 +6,29 @@ SPDX-License-Identifier: Apache-2.0
 package api
 
 import (
+	"context"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 type TransferService interface {
 	Transfer(txID string, wallet OwnerWallet, ids []*token2.Id, Outputs ...*token2.Token) (TransferAction, *TransferMetadata, error)
 
-	VerifyTransfer(tr TransferAction, tokenInfos [][]byte) error
+	// Reclaim returns a transfer action, signed by the passed issuer, that moves the value of the passed expired tokens
+	// to the passed receiver. Only the issuer of the expired tokens can reclaim them.
+	Reclaim(txID string, issuer view.Identity, ids []*token2.Id, receiver view.Identity) (TransferAction, *TransferMetadata, error)
+
+	// VerifyTransfer checks the well-formedness of the passed transfer action, it aborts once the passed context is done
+	VerifyTransfer(ctx context.Context, tr TransferAction, tokenInfos [][]byte) error
 
 	DeserializeTransferAction(raw []byte) (TransferAction, error)
 }
+
+// AuditThresholdProver is implemented by the transfer services of the drivers that hide the values of the tokens.
+// It proves that the total of the outputs of the passed transfers, opened by the passed metadata, is below the
+// audit threshold, see TokenRequest.ThresholdProof.
+type AuditThresholdProver interface {
+	ProveAuditThreshold(transfers []TransferAction, metadata []TransferMetadata) ([]byte, error)
+}
@@ -5,7 +5,13 @@ SPDX-License-Identifier: Apache-2.0
 */
 package api
 
-import "github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+)
 
 type GetStateFnc = func(key string) ([]byte, error)
 
@@ -21,8 +27,302 @@ type SignatureProvider interface {
 	HasBeenSignedBy(id view.Identity, verifier Verifier) error
 }
 
+// ValidationOptions contains the options the validator takes into account when verifying a token request
+type ValidationOptions struct {
+	// TxTime is the time of the transaction the token request is bound to, as fixed by the ledger.
+	// It is used to check time-dependent conditions like token expiration.
+	TxTime time.Time
+	// IssueHooks are invoked on each issue action that passed the checks of the driver
+	IssueHooks []IssueValidationHook
+	// TransferHooks are invoked on each transfer action that passed the checks of the driver
+	TransferHooks []TransferValidationHook
+	// RequestHooks are invoked on the token request once all its actions passed validation
+	RequestHooks []RequestValidationHook
+	// Limits, if set, bounds the size and complexity of the token request.
+	// They are enforced before any cryptographic check is run.
+	Limits *RequestLimits
+	// Height is the height of the ledger the token request is validated at, zero if not available.
+	// It is used to enforce the cutover of a driver migration, see MigrationParams.
+	Height uint64
+	// Context, if set, bounds the verification: once it is done, the verification is aborted with its error
+	Context context.Context
+}
+
+// RequestLimits bounds the size and complexity of a token request, so that a malicious request
+// cannot consume unbounded resources of the validator. A zero value means no limit.
+type RequestLimits struct {
+	// MaxSize is the maximum size, in bytes, of the serialized token request
+	MaxSize int
+	// MaxActions is the maximum number of actions, issues, transfers, and migrations, of the token request
+	MaxActions int
+	// MaxInputs is the maximum number of inputs of a transfer action
+	MaxInputs int
+	// MaxOutputs is the maximum number of outputs of an issue or transfer action
+	MaxOutputs int
+}
+
+// DecompressionLimit returns the maximum size of a decompressed token request: the maximum size of the request,
+// if set, DefaultMaxDecompressedSize otherwise
+func (l *RequestLimits) DecompressionLimit() int {
+	if l == nil || l.MaxSize == 0 {
+		return DefaultMaxDecompressedSize
+	}
+	return l.MaxSize
+}
+
+// IssueValidationHook enforces additional rules on the issue action at the passed index of a token request.
+// The action is the one of the driver in use.
+type IssueValidationHook func(ledger Ledger, index int, action IssueAction) error
+
+// TransferValidationHook enforces additional rules on the transfer action at the passed index of a token request.
+// The action is the one of the driver in use.
+type TransferValidationHook func(ledger Ledger, index int, action TransferAction) error
+
+// RequestValidationHook enforces additional rules on a token request as a whole
+type RequestValidationHook func(ledger Ledger, binding string, tr *TokenRequest) error
+
+type ValidationOption func(*ValidationOptions) error
+
+// WithTxTime sets the time of the transaction the token request is bound to
+func WithTxTime(txTime time.Time) ValidationOption {
+	return func(o *ValidationOptions) error {
+		o.TxTime = txTime
+		return nil
+	}
+}
+
+// WithIssueHook appends a hook to be invoked on each issue action
+func WithIssueHook(hook IssueValidationHook) ValidationOption {
+	return func(o *ValidationOptions) error {
+		o.IssueHooks = append(o.IssueHooks, hook)
+		return nil
+	}
+}
+
+// WithTransferHook appends a hook to be invoked on each transfer action
+func WithTransferHook(hook TransferValidationHook) ValidationOption {
+	return func(o *ValidationOptions) error {
+		o.TransferHooks = append(o.TransferHooks, hook)
+		return nil
+	}
+}
+
+// WithRequestHook appends a hook to be invoked on the token request once all its actions are valid
+func WithRequestHook(hook RequestValidationHook) ValidationOption {
+	return func(o *ValidationOptions) error {
+		o.RequestHooks = append(o.RequestHooks, hook)
+		return nil
+	}
+}
+
+// WithContext sets the context bounding the verification, for instance to impose a deadline
+func WithContext(ctx context.Context) ValidationOption {
+	return func(o *ValidationOptions) error {
+		o.Context = ctx
+		return nil
+	}
+}
+
+// WithHeight sets the height of the ledger the token request is validated at
+func WithHeight(height uint64) ValidationOption {
+	return func(o *ValidationOptions) error {
+		o.Height = height
+		return nil
+	}
+}
+
+// WithRequestLimits sets the limits on the size and complexity of the token request
+func WithRequestLimits(limits *RequestLimits) ValidationOption {
+	return func(o *ValidationOptions) error {
+		o.Limits = limits
+		return nil
+	}
+}
+
+// CheckRequestSize checks the size of the passed serialized token request against the limits, if any
+func (o *ValidationOptions) CheckRequestSize(raw []byte, report *ValidationReport) error {
+	if o.Limits == nil || o.Limits.MaxSize == 0 || len(raw) <= o.Limits.MaxSize {
+		return nil
+	}
+	return report.Failed(RequestActionType, 0, LimitCheck, errors.Errorf("token request size [%d] exceeds limit [%d]", len(raw), o.Limits.MaxSize))
+}
+
+// DecompressRequest returns the decompression of the passed serialized token request, or the request itself if
+// not compressed. The size of the decompressed request is bounded, see RequestLimits.DecompressionLimit.
+func (o *ValidationOptions) DecompressRequest(raw []byte, report *ValidationReport) ([]byte, error) {
+	res, err := Decompress(raw, o.Limits.DecompressionLimit())
+	if err != nil {
+		return nil, report.Failed(RequestActionType, 0, FormatCheck, err)
+	}
+	return res, nil
+}
+
+// CheckRequest checks the number of actions of the passed token request against the limits, if any
+func (o *ValidationOptions) CheckRequest(tr *TokenRequest, report *ValidationReport) error {
+	if o.Limits == nil || o.Limits.MaxActions == 0 {
+		return nil
+	}
+	if n := len(tr.Issues) + len(tr.Transfers) + len(tr.Migrations) + len(tr.Rebindings); n > o.Limits.MaxActions {
+		return report.Failed(RequestActionType, 0, LimitCheck, errors.Errorf("number of actions [%d] exceeds limit [%d]", n, o.Limits.MaxActions))
+	}
+	return nil
+}
+
+// CheckIssue checks the number of outputs of the issue action at the passed index against the limits, if any
+func (o *ValidationOptions) CheckIssue(index int, action IssueAction, report *ValidationReport) error {
+	if o.Limits == nil || o.Limits.MaxOutputs == 0 {
+		return nil
+	}
+	if n := action.NumOutputs(); n > o.Limits.MaxOutputs {
+		return report.Failed(IssueActionType, index, LimitCheck, errors.Errorf("number of outputs [%d] exceeds limit [%d]", n, o.Limits.MaxOutputs))
+	}
+	return nil
+}
+
+// CheckTransfer checks the number of inputs and outputs of the transfer action at the passed index against the limits, if any
+func (o *ValidationOptions) CheckTransfer(index int, action TransferAction, report *ValidationReport) error {
+	if o.Limits == nil {
+		return nil
+	}
+	if o.Limits.MaxInputs != 0 {
+		inputs, err := action.GetInputs()
+		if err != nil {
+			return report.Failed(TransferActionType, index, FormatCheck, errors.WithMessage(err, "failed getting inputs"))
+		}
+		if len(inputs) > o.Limits.MaxInputs {
+			return report.Failed(TransferActionType, index, LimitCheck, errors.Errorf("number of inputs [%d] exceeds limit [%d]", len(inputs), o.Limits.MaxInputs))
+		}
+	}
+	if o.Limits.MaxOutputs != 0 {
+		if n := action.NumOutputs(); n > o.Limits.MaxOutputs {
+			return report.Failed(TransferActionType, index, LimitCheck, errors.Errorf("number of outputs [%d] exceeds limit [%d]", n, o.Limits.MaxOutputs))
+		}
+	}
+	return nil
+}
+
+// RunIssueHooks invokes the issue hooks on the passed action, stopping at the first error
+func (o *ValidationOptions) RunIssueHooks(ledger Ledger, index int, action IssueAction) error {
+	for _, hook := range o.IssueHooks {
+		if err := hook(ledger, index, action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunTransferHooks invokes the transfer hooks on the passed action, stopping at the first error
+func (o *ValidationOptions) RunTransferHooks(ledger Ledger, index int, action TransferAction) error {
+	for _, hook := range o.TransferHooks {
+		if err := hook(ledger, index, action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunRequestHooks invokes the request hooks on the passed token request, stopping at the first error
+func (o *ValidationOptions) RunRequestHooks(ledger Ledger, binding string, tr *TokenRequest) error {
+	for _, hook := range o.RequestHooks {
+		if err := hook(ledger, binding, tr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func CompileValidationOptions(opts ...ValidationOption) (*ValidationOptions, error) {
+	validationOptions := &ValidationOptions{}
+	for _, opt := range opts {
+		if err := opt(validationOptions); err != nil {
+			return nil, err
+		}
+	}
+	if validationOptions.Context == nil {
+		validationOptions.Context = context.Background()
+	}
+	return validationOptions, nil
+}
+
 type Validator interface {
-	VerifyTokenRequest(ledger Ledger, signatureProvider SignatureProvider, binding string, tr *TokenRequest) ([]interface{}, error)
+	VerifyTokenRequest(ledger Ledger, signatureProvider SignatureProvider, binding string, tr *TokenRequest, opts ...ValidationOption) ([]interface{}, error)
+
+	VerifyTokenRequestFromRaw(getState GetStateFnc, binding string, raw []byte, opts ...ValidationOption) ([]interface{}, error)
+
+	// UnmarshalActions returns the actions of the passed serialized token request, in the order returned
+	// by VerifyTokenRequestFromRaw, without verifying them
+	UnmarshalActions(raw []byte) ([]interface{}, error)
+
+	// VerifyOwnership checks that the passed proof has been signed by the owner of the token it carries
+	VerifyOwnership(proof *OwnershipProof) error
+}
+
+// BurnReceiptMatcher checks that the passed redeemed output carries the type and quantity declared by the passed receipt
+type BurnReceiptMatcher func(output Output, receipt *BurnReceipt) error
+
+// VerifyBurnReceipts checks that each of the passed burn receipts refers to a distinct redeemed output
+// of the passed transfer actions, and that the output matches the receipt
+func VerifyBurnReceipts(transfers []TransferAction, receipts []*BurnReceipt, match BurnReceiptMatcher, report *ValidationReport) error {
+	used := map[[2]int]bool{}
+	for i, r := range receipts {
+		if r == nil {
+			return report.Failed(BurnActionType, i, FormatCheck, errors.Errorf("burn receipt is empty"))
+		}
+		if r.TransferIndex < 0 || r.TransferIndex >= len(transfers) {
+			return report.Failed(BurnActionType, i, FormatCheck, errors.Errorf("burn receipt refers to transfer [%d], only [%d] available", r.TransferIndex, len(transfers)))
+		}
+		t := transfers[r.TransferIndex]
+		if r.OutputIndex < 0 || r.OutputIndex >= t.NumOutputs() {
+			return report.Failed(BurnActionType, i, FormatCheck, errors.Errorf("burn receipt refers to output [%d] of transfer [%d], only [%d] available", r.OutputIndex, r.TransferIndex, t.NumOutputs()))
+		}
+		if !t.IsRedeemAt(r.OutputIndex) {
+			return report.Failed(BurnActionType, i, FormatCheck, errors.Errorf("output [%d] of transfer [%d] is not redeemed", r.OutputIndex, r.TransferIndex))
+		}
+		key := [2]int{r.TransferIndex, r.OutputIndex}
+		if used[key] {
+			return report.Failed(BurnActionType, i, FormatCheck, errors.Errorf("output [%d] of transfer [%d] has more than one burn receipt", r.OutputIndex, r.TransferIndex))
+		}
+		used[key] = true
+		if err := match(t.GetOutputs()[r.OutputIndex], r); err != nil {
+			return report.Failed(BurnActionType, i, ProofCheck, errors.WithMessagef(err, "burn receipt does not match output [%d] of transfer [%d]", r.OutputIndex, r.TransferIndex))
+		}
+		report.Succeeded(BurnActionType, i)
+	}
+	return nil
+}
 
-	VerifyTokenRequestFromRaw(getState GetStateFnc, binding string, raw []byte) ([]interface{}, error)
+// VerifyRedeemIssuers checks the co-signatures of the issuers declared by the passed burn receipts, in the order
+// of the receipts. If required, each redeemed output of the passed transfer actions must have a burn receipt
+// declaring an issuer. The receipts are expected to be checked already with VerifyBurnReceipts.
+// The signatures are verified against the certificates of the issuers, whose chains are not checked: the issuers must be
+// listed in the issuer policies of the redeemed types, this is checked at commit time, see translator.RedeemIssuerValidator.
+func VerifyRedeemIssuers(transfers []TransferAction, receipts []*BurnReceipt, required bool, signatureProvider SignatureProvider, getVerifier func(id view.Identity) (Verifier, error), report *ValidationReport) error {
+	if required {
+		declared := map[[2]int]bool{}
+		for _, r := range receipts {
+			if !r.Issuer.IsNone() {
+				declared[[2]int{r.TransferIndex, r.OutputIndex}] = true
+			}
+		}
+		for i, t := range transfers {
+			for j := 0; j < t.NumOutputs(); j++ {
+				if t.IsRedeemAt(j) && !declared[[2]int{i, j}] {
+					return report.Failed(TransferActionType, i, SignatureCheck, errors.Errorf("redeemed output [%d] requires the co-signature of an issuer", j), j)
+				}
+			}
+		}
+	}
+	for i, r := range receipts {
+		if r.Issuer.IsNone() {
+			continue
+		}
+		verifier, err := getVerifier(r.Issuer)
+		if err != nil {
+			return report.Failed(BurnActionType, i, SignatureCheck, errors.Wrapf(err, "failed getting verifier for issuer [%s]", r.Issuer))
+		}
+		if err := signatureProvider.HasBeenSignedBy(r.Issuer, verifier); err != nil {
+			return report.Failed(BurnActionType, i, SignatureCheck, errors.Wrapf(err, "failed verifying issuer's signature"))
+		}
+	}
+	return nil
 }
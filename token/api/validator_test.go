@@ -0,0 +1,72 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package api
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type redeemTransfer struct {
+	TransferAction
+	redeemed []bool
+}
+
+func (t *redeemTransfer) NumOutputs() int {
+	return len(t.redeemed)
+}
+
+func (t *redeemTransfer) IsRedeemAt(index int) bool {
+	return t.redeemed[index]
+}
+
+// signers records the identities the signatures are checked for, and rejects those not in the signed set
+type signers struct {
+	checked []string
+	signed  map[string]bool
+}
+
+func (s *signers) HasBeenSignedBy(id view.Identity, verifier Verifier) error {
+	s.checked = append(s.checked, string(id))
+	if !s.signed[string(id)] {
+		return errors.Errorf("[%s] did not sign", id)
+	}
+	return nil
+}
+
+func TestVerifyRedeemIssuers(t *testing.T) {
+	getVerifier := func(id view.Identity) (Verifier, error) { return nil, nil }
+	transfers := []TransferAction{
+		&redeemTransfer{redeemed: []bool{true, false}},
+		&redeemTransfer{redeemed: []bool{true}},
+	}
+	receipts := []*BurnReceipt{
+		{TransferIndex: 0, OutputIndex: 0, Issuer: []byte("alice")},
+		{TransferIndex: 1, OutputIndex: 0, Issuer: []byte("bob")},
+	}
+
+	// the issuers are checked in the order of the receipts
+	sp := &signers{signed: map[string]bool{"alice": true, "bob": true}}
+	assert.NoError(t, VerifyRedeemIssuers(transfers, receipts, true, sp, getVerifier, &ValidationReport{}))
+	assert.Equal(t, []string{"alice", "bob"}, sp.checked)
+
+	sp = &signers{signed: map[string]bool{"alice": true}}
+	err := VerifyRedeemIssuers(transfers, receipts, false, sp, getVerifier, &ValidationReport{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed verifying issuer's signature")
+
+	// if required, each redeemed output needs an issuer
+	receipts[1].Issuer = nil
+	sp = &signers{signed: map[string]bool{"alice": true}}
+	assert.NoError(t, VerifyRedeemIssuers(transfers, receipts, false, sp, getVerifier, &ValidationReport{}))
+	err = VerifyRedeemIssuers(transfers, receipts, true, sp, getVerifier, &ValidationReport{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "redeemed output [0] requires the co-signature of an issuer")
+	assert.Error(t, VerifyRedeemIssuers(transfers, receipts[:1], true, sp, getVerifier, &ValidationReport{}))
+}
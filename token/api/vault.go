@@ -23,10 +23,18 @@ type CertificationStorage interface {
 type QueryEngine interface {
 	IsMine(id *token.Id) (bool, error)
 	ListUnspentTokens() (*token.UnspentTokens, error)
+	UnspentTokensByEnrollmentID(eID string) (*token.UnspentTokens, error)
+	UnspentAuditTokensByEnrollmentID(eID string) (*token.UnspentTokens, error)
 	ListAuditTokens(ids ...*token.Id) ([]*token.Token, error)
 	ListHistoryIssuedTokens() (*token.IssuedTokens, error)
 	PublicParams() ([]byte, error)
+	// PublicParamsVersion returns the version of the public parameters, zero if they have never been versioned
+	PublicParamsVersion() (uint64, error)
+	// Supply returns the supply of the passed token type, as tracked on the ledger
+	Supply(tokenType string) (*Supply, error)
 	GetTokenInfos(ids []*token.Id, callback QueryCallbackFunc) error
 	GetTokenCommitments(ids []*token.Id, callback QueryCallbackFunc) error
 	GetTokens(inputs ...*token.Id) ([]*token.Token, error)
+	// GetState returns the value stored under the passed ledger key of the namespace, nil if none
+	GetState(key string) ([]byte, error)
 }
@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package api
 
 import (
+	"time"
+
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
@@ -19,6 +21,61 @@ type ListTokensOptions struct {
 	TokenType string
 }
 
+// PseudonymPolicy tells an owner wallet when to derive a new pseudonym on a recipient identity request
+type PseudonymPolicy int
+
+const (
+	// FreshPerTransaction derives a new pseudonym for each request, this is the default
+	FreshPerTransaction PseudonymPolicy = iota
+	// FreshPerCounterparty reuses the pseudonym already derived for the same counterparty
+	FreshPerCounterparty
+	// Sticky always reuses the same pseudonym
+	Sticky
+)
+
+type RecipientIdentityOptions struct {
+	// Counterparty is the party the recipient identity is requested for, if known
+	Counterparty view.Identity
+	// Fresh, if true, requires a new pseudonym, never reused, whatever the pseudonym policy of the wallet.
+	// The wallets using their long-term identity as recipient identity ignore it.
+	Fresh bool
+}
+
+// RecipientIdentity is a recipient identity together with its audit information.
+// It is used to share the recipient identities of an owner wallet among the nodes using it.
+type RecipientIdentity struct {
+	Identity  view.Identity
+	AuditInfo []byte
+}
+
+// ExportedToken is what an owner wallet knows about one of its tokens
+type ExportedToken struct {
+	ID *token2.Id
+	// Token is the token as stored on the ledger
+	Token []byte
+	// Info is the information needed to spend the token, like the opening of its commitments
+	Info []byte
+	// Owner is the recipient identity owning the token, together with its audit information
+	Owner *RecipientIdentity
+}
+
+// TokenExport carries the knowledge of some tokens from an owner wallet to another owner wallet of the same node
+type TokenExport struct {
+	// Wallet is the ID of the wallet the tokens have been exported from
+	Wallet string
+	Tokens []*ExportedToken
+}
+
+// TokenReassignment records that some tokens have been moved, without a transfer, from an owner wallet to another
+type TokenReassignment struct {
+	ID        string
+	From      string
+	To        string
+	Tokens    []*token2.Id
+	Owners    []view.Identity
+	Timestamp time.Time
+}
+
 type Wallet interface {
 	// ID returns the ID of this wallet
 	ID() string
@@ -36,7 +93,11 @@ type OwnerWallet interface {
 
 	// GetRecipientIdentity returns a recipient identity.
 	// Depending on the underlying wallet implementation, this can be a long-term or ephemeral identity.
-	GetRecipientIdentity() (view.Identity, error)
+	// Ephemeral identities are derived following the pseudonym policy of the wallet.
+	GetRecipientIdentity(opts *RecipientIdentityOptions) (view.Identity, error)
+
+	// PseudonymPolicy returns the policy this wallet follows to derive recipient identities
+	PseudonymPolicy() PseudonymPolicy
 
 	// GetAuditInfo returns auditing information for the passed identity
 	GetAuditInfo(id view.Identity) ([]byte, error)
@@ -46,6 +107,31 @@ type OwnerWallet interface {
 
 	// GetTokenMetadata returns any information needed to implement the transfer
 	GetTokenMetadata(id view.Identity) ([]byte, error)
+
+	// EnrollmentID returns the enrollment ID of the owner of this wallet
+	EnrollmentID() string
+
+	// ExportRecipientIdentities returns the recipient identities of this wallet together with their audit information.
+	ExportRecipientIdentities() ([]*RecipientIdentity, error)
+
+	// ImportRecipientIdentities adds to this wallet the passed recipient identities exported by another node
+	// using the same wallet. The audit information must bind each identity to the enrollment ID of this wallet.
+	// Tokens owned by an imported identity are listed by this wallet but can be spent only by the node that generated it.
+	ImportRecipientIdentities(ids []*RecipientIdentity) error
+
+	// CanSpend returns true if this wallet holds the signing material of the passed identity
+	CanSpend(identity view.Identity) bool
+
+	// ExportTokens returns the knowledge this wallet has of the passed unspent tokens, to be imported by another
+	// owner wallet of the same node. All the tokens owned by the same recipient identity must be exported together.
+	ExportTokens(ids []*token2.Id) (*TokenExport, error)
+
+	// ImportTokens moves to this wallet the tokens exported by another owner wallet of the same node, no transfer
+	// happens on the ledger. It returns the record of the reassignment.
+	ImportTokens(export *TokenExport) (*TokenReassignment, error)
+
+	// TokenReassignments returns the reassignments of tokens from or to this wallet
+	TokenReassignments() ([]*TokenReassignment, error)
 }
 
 // IssuerWallet models the wallet of an issuer as a container of issuer identities.
@@ -89,8 +175,24 @@ type WalletService interface {
 
 	RegisterIssuer(label string, sk Key, pk Key) error
 
+	// RegisterIssuerCredential registers the anonymous issuer, for the token type passed as label, with the passed
+	// secret key and the credential issued to it by the authority of the issuer accumulator of the public parameters
+	RegisterIssuerCredential(label string, sk Key, credential []byte) error
+
+	// RegisterWallet registers, at runtime, the wallet with the passed id, for the passed usage, whose long-term
+	// identity has the msp configuration at the passed path. Registering again a known wallet is a no-op.
+	RegisterWallet(usage IdentityUsage, id string, mspID string, path string) error
+
+	// WalletMSPType returns the type of the msps the long-term identities of the wallets for the passed usage belong to
+	WalletMSPType(usage IdentityUsage) (string, error)
+
 	GetEnrollmentID(auditInfo []byte) (string, error)
 
+	// GetOwnerEnrollmentID returns the enrollment ID carried by the passed audit info, once checked that the audit info
+	// belongs to the passed identity, the one signing for a token.
+	// It returns ErrEncryptedAuditInfo if the audit info can be opened only by the auditor.
+	GetOwnerEnrollmentID(owner view.Identity, auditInfo []byte) (string, error)
+
 	// Wallet returns the wallet bound to the passed identity, if any is available
 	Wallet(identity view.Identity) Wallet
 
@@ -0,0 +1,166 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"sync"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// BalanceEventBufferSize is the number of balance events a subscription buffers,
+// the events published while the buffer is full are dropped
+const BalanceEventBufferSize = 100
+
+// BalanceChange tells if a token entered or left a wallet
+type BalanceChange string
+
+const (
+	// TokenReceived marks a token entering a wallet
+	TokenReceived BalanceChange = "received"
+	// TokenSpent marks a token leaving a wallet
+	TokenSpent BalanceChange = "spent"
+)
+
+// BalanceEvent is a change of the balance of an owner wallet, caused by a committed transaction
+type BalanceEvent struct {
+	Wallet   string
+	Change   BalanceChange
+	TokenID  *token2.Id
+	Type     string
+	Quantity string
+	// TxID is the transaction that created the token, if received, or that spent it
+	TxID string
+}
+
+// BalanceSubscription delivers the balance events of a wallet, see OwnerWallet.Subscribe
+type BalanceSubscription struct {
+	broker *balanceBroker
+	wallet string
+	typ    string
+	events chan *BalanceEvent
+	once   sync.Once
+}
+
+// Events returns the channel the balance events are delivered on, it is closed by Close
+func (s *BalanceSubscription) Events() <-chan *BalanceEvent {
+	return s.events
+}
+
+// Close stops the delivery of the balance events and closes the events channel
+func (s *BalanceSubscription) Close() {
+	s.once.Do(func() {
+		s.broker.remove(s)
+		close(s.events)
+	})
+}
+
+// Subscribe returns a subscription to the changes of the balance of this wallet in the passed token type,
+// all types if empty. The events are published by the vault once the transaction is final, so there is no need
+// to poll ListTokens. Close the subscription when done.
+func (o *OwnerWallet) Subscribe(typ string) *BalanceSubscription {
+	s := &BalanceSubscription{
+		broker: o.ms.balances,
+		wallet: o.ID(),
+		typ:    typ,
+		events: make(chan *BalanceEvent, BalanceEventBufferSize),
+	}
+	s.broker.add(s)
+	return s
+}
+
+// Finality waits for the finality of a transaction, see fabric.Finality
+type Finality interface {
+	// IsFinal returns nil once the passed transaction is committed as valid, an error if it is invalid
+	IsFinal(txID string) error
+}
+
+// PublishBalanceEvents delivers the passed events, caused by the passed transaction, to the subscriptions to the
+// wallets of this service, once the passed finality reports the transaction committed as valid.
+// The events of an invalid transaction are dropped. The vault calls it, without blocking, when it processes a transaction.
+func (t *ManagementService) PublishBalanceEvents(txID string, finality Finality, events ...*BalanceEvent) {
+	if len(events) == 0 {
+		return
+	}
+	go func() {
+		if err := finality.IsFinal(txID); err != nil {
+			logger.Debugf("transaction [%s] not committed, dropping its balance events: [%s]", txID, err)
+			return
+		}
+		for _, event := range events {
+			t.balances.publish(event)
+		}
+	}()
+}
+
+// balanceBrokers holds the balance brokers of the token management services, by network, channel and namespace
+type balanceBrokers struct {
+	lock    sync.Mutex
+	brokers map[string]*balanceBroker
+}
+
+func newBalanceBrokers() *balanceBrokers {
+	return &balanceBrokers{brokers: map[string]*balanceBroker{}}
+}
+
+// get returns the broker of the token management service of the passed network, channel and namespace
+func (b *balanceBrokers) get(network, channel, namespace string) *balanceBroker {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	key := network + "/" + channel + "/" + namespace
+	broker, ok := b.brokers[key]
+	if !ok {
+		broker = &balanceBroker{subscriptions: map[string][]*BalanceSubscription{}}
+		b.brokers[key] = broker
+	}
+	return broker
+}
+
+// balanceBroker holds the balance subscriptions to the wallets of a token management service, by wallet
+type balanceBroker struct {
+	lock          sync.RWMutex
+	subscriptions map[string][]*BalanceSubscription
+}
+
+func (b *balanceBroker) add(s *BalanceSubscription) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.subscriptions[s.wallet] = append(b.subscriptions[s.wallet], s)
+}
+
+func (b *balanceBroker) remove(s *BalanceSubscription) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	subscriptions := b.subscriptions[s.wallet]
+	for i, sub := range subscriptions {
+		if sub == s {
+			subscriptions = append(subscriptions[:i:i], subscriptions[i+1:]...)
+			break
+		}
+	}
+	if len(subscriptions) == 0 {
+		delete(b.subscriptions, s.wallet)
+		return
+	}
+	b.subscriptions[s.wallet] = subscriptions
+}
+
+// publish does not block the vault, an event is dropped for a subscription whose buffer is full.
+// The read lock is held while sending, a subscription is not closed meanwhile.
+func (b *balanceBroker) publish(event *BalanceEvent) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for _, s := range b.subscriptions[event.Wallet] {
+		if len(s.typ) != 0 && s.typ != event.Type {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			logger.Warnf("balance subscription of [%s] is full, dropping event of [%s]", event.Wallet, event.TxID)
+		}
+	}
+}
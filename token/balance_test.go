@@ -0,0 +1,75 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// finality reports the transactions in valid as committed, the others as invalid
+type finality struct {
+	valid map[string]bool
+}
+
+func (f *finality) IsFinal(txID string) error {
+	if !f.valid[txID] {
+		return errors.Errorf("transaction [%s] is not valid", txID)
+	}
+	return nil
+}
+
+func receive(t *testing.T, s *BalanceSubscription) *BalanceEvent {
+	select {
+	case event := <-s.Events():
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no balance event received")
+		return nil
+	}
+}
+
+func assertNoEvent(t *testing.T, s *BalanceSubscription) {
+	select {
+	case event := <-s.Events():
+		t.Fatalf("unexpected balance event of [%s]", event.TxID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestBalanceEvents(t *testing.T) {
+	brokers := newBalanceBrokers()
+	tms := &ManagementService{balances: brokers.get("n", "ch1", "ns")}
+	other := &ManagementService{balances: brokers.get("n", "ch2", "ns")}
+	alice := (&OwnerWallet{w: &recipientWallet{}, ms: tms}).Subscribe("")
+	defer alice.Close()
+	aliceOnOther := (&OwnerWallet{w: &recipientWallet{}, ms: other}).Subscribe("")
+	defer aliceOnOther.Close()
+
+	event := func(txID string) *BalanceEvent {
+		return &BalanceEvent{Wallet: "alice", Change: TokenReceived, TokenID: &token2.Id{TxId: txID}, Type: "USD", Quantity: "10", TxID: txID}
+	}
+	f := &finality{valid: map[string]bool{"tx1": true}}
+
+	// the events are published once the transaction is final, to the subscriptions of the same service only
+	tms.PublishBalanceEvents("tx1", f, event("tx1"))
+	assert.Equal(t, event("tx1"), receive(t, alice))
+	assertNoEvent(t, aliceOnOther)
+
+	// the events of an invalid transaction are dropped
+	tms.PublishBalanceEvents("tx2", f, event("tx2"))
+	assertNoEvent(t, alice)
+
+	// a closed subscription is removed from the broker of its service
+	aliceOnOther.Close()
+	assert.Empty(t, other.balances.subscriptions)
+	assert.Len(t, tms.balances.subscriptions["alice"], 1)
+}
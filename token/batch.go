@@ -0,0 +1,195 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+)
+
+// SubRequest is a token request of a BatchRequest
+type SubRequest struct {
+	// Binding replaces the transaction id for the sub-request: its signatures are bound to it, and its outputs
+	// are identified by it, as if the sub-request was committed in its own transaction. It must be unique on the ledger.
+	Binding string
+	// Request is the serialized token request, compressed or not, together with its signatures
+	Request []byte
+}
+
+// BatchMode tells how the sub-requests of a batch are committed
+type BatchMode int
+
+const (
+	// AllOrNothing rejects the whole batch if any of its sub-requests is not valid
+	AllOrNothing BatchMode = iota
+	// SkipInvalid rejects only the sub-requests that are not valid, the valid ones are committed.
+	// A sub-request spending a token already spent by a previous valid sub-request of the batch is not valid.
+	SkipInvalid
+)
+
+// BatchRequest packages token requests of different initiators, independent of each other, into a single
+// ledger transaction, for throughput. Each sub-request is prepared and signed by its own parties as usual,
+// the operator of the batch does not need to sign it. By default, the sub-requests are validated and committed
+// all-or-nothing: if any of them is not valid, or two of them spend the same token, the whole batch is rejected.
+// See BatchMode for the alternatives.
+//
+// Batches are assembled by the operator out of signed token requests, see AppendRequest, and submitted directly
+// to the invokeBatch function of the token chaincode. The ttx and ttxcc services do not assemble nor distribute them:
+// the parties of a sub-request are not sent the envelope of the batch, and learn about its outputs by querying
+// the chaincode.
+type BatchRequest struct {
+	Mode     BatchMode `json:",omitempty"`
+	Requests []*SubRequest
+}
+
+func NewBatchRequest() *BatchRequest {
+	return &BatchRequest{}
+}
+
+// SubRequestResult is the outcome of the validation of a sub-request of a batch
+type SubRequestResult struct {
+	Binding string
+	// Actions are the actions of the sub-request, if valid
+	Actions []interface{}
+	// Err is the reason why the sub-request has been rejected, nil if the sub-request is valid
+	Err error
+	// Check is the check the sub-request failed, if rejected, see tokenapi.RejectionCheck
+	Check tokenapi.ValidationCheck
+}
+
+// RejectedSubRequest is a sub-request of a batch rejected in SkipInvalid mode.
+// It carries the check the sub-request failed, not the error, whose message may differ between the endorsers.
+type RejectedSubRequest struct {
+	Index   int
+	Binding string
+	Check   tokenapi.ValidationCheck
+}
+
+// BatchReport lists, in the order of the batch, the sub-requests rejected in SkipInvalid mode.
+// It is returned by the token chaincode on a successful batch invocation.
+type BatchReport struct {
+	Rejected []*RejectedSubRequest `json:",omitempty"`
+}
+
+func (r *BatchReport) Bytes() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *BatchReport) FromBytes(raw []byte) error {
+	return json.Unmarshal(raw, r)
+}
+
+// Append appends the passed serialized token request, signed against the passed binding
+func (b *BatchRequest) Append(binding string, raw []byte) {
+	b.Requests = append(b.Requests, &SubRequest{Binding: binding, Request: raw})
+}
+
+// AppendRequest appends the passed token request, its binding is its transaction id
+func (b *BatchRequest) AppendRequest(request *Request) error {
+	raw, err := request.RequestToBytes()
+	if err != nil {
+		return errors.WithMessagef(err, "failed serializing token request [%s]", request.ID())
+	}
+	b.Append(request.ID(), raw)
+	return nil
+}
+
+// Validate checks that the batch is not empty and that the bindings of its sub-requests are valid transaction ids, and unique
+func (b *BatchRequest) Validate() error {
+	if len(b.Requests) == 0 {
+		return errors.New("empty batch")
+	}
+	if b.Mode != AllOrNothing && b.Mode != SkipInvalid {
+		return errors.Errorf("unknown batch mode [%d]", b.Mode)
+	}
+	bindings := map[string]bool{}
+	for i, r := range b.Requests {
+		if err := keys.ValidateTxID(r.Binding); err != nil {
+			return errors.WithMessagef(err, "sub-request [%d] has an invalid binding", i)
+		}
+		if len(r.Request) == 0 {
+			return errors.Errorf("sub-request [%d][%s] is empty", i, r.Binding)
+		}
+		if bindings[r.Binding] {
+			return errors.Errorf("binding [%s] of sub-request [%d] is not unique", r.Binding, i)
+		}
+		bindings[r.Binding] = true
+	}
+	return nil
+}
+
+func (b *BatchRequest) Bytes() ([]byte, error) {
+	return json.Marshal(b)
+}
+
+// FromBytes unmarshals the passed serialization, compressed or not, of a batch
+func (b *BatchRequest) FromBytes(raw []byte) error {
+	raw, err := tokenapi.Decompress(raw, tokenapi.DefaultMaxDecompressedSize)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, b)
+}
+
+// VerifyBatch verifies each sub-request of the passed batch, as UnmarshallAndVerify does, and checks that no token
+// is spent by more than one sub-request. It returns the result of each sub-request, in the order of the batch.
+// In AllOrNothing mode, the first failure rejects the whole batch. In SkipInvalid mode, a failure rejects only
+// its sub-request; the sub-requests are processed in the order of the batch, so that the results
+// depend only on the batch and on the ledger, and are the same for all the endorsers.
+func (c *Validator) VerifyBatch(ledger Ledger, batch *BatchRequest, opts ...ValidationOption) ([]*SubRequestResult, error) {
+	if err := batch.Validate(); err != nil {
+		return nil, errors.WithMessage(err, "invalid batch")
+	}
+	res := make([]*SubRequestResult, len(batch.Requests))
+	spentBy := map[string]string{}
+	for i, r := range batch.Requests {
+		actions, err := c.verifySubRequest(ledger, r, spentBy, opts...)
+		if err != nil {
+			if batch.Mode == AllOrNothing {
+				return nil, errors.WithMessagef(err, "sub-request [%d][%s] is not valid", i, r.Binding)
+			}
+			logger.Debugf("sub-request [%d][%s] rejected [%s]", i, r.Binding, err)
+			res[i] = &SubRequestResult{Binding: r.Binding, Err: err, Check: tokenapi.RejectionCheck(err)}
+			continue
+		}
+		res[i] = &SubRequestResult{Binding: r.Binding, Actions: actions}
+	}
+	return res, nil
+}
+
+// verifySubRequest verifies the passed sub-request and, if valid, marks its inputs as spent by it
+func (c *Validator) verifySubRequest(ledger Ledger, r *SubRequest, spentBy map[string]string, opts ...ValidationOption) ([]interface{}, error) {
+	actions, err := c.UnmarshallAndVerify(ledger, r.Binding, r.Request, opts...)
+	if err != nil {
+		return nil, err
+	}
+	var spent []string
+	for _, action := range actions {
+		transfer, ok := action.(interface{ GetInputs() ([]string, error) })
+		if !ok {
+			continue
+		}
+		inputs, err := transfer.GetInputs()
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed getting inputs")
+		}
+		for _, input := range inputs {
+			if other, ok := spentBy[input]; ok {
+				return nil, errors2.Errorf(errors2.DoubleSpend, "input [%s] already spent by sub-request [%s]", input, other)
+			}
+		}
+		spent = append(spent, inputs...)
+	}
+	for _, input := range spent {
+		spentBy[input] = r.Binding
+	}
+	return actions, nil
+}
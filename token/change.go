@@ -0,0 +1,122 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+)
+
+// DefaultMaxChangeOutputs is the maximum number of change outputs of a transfer, unless the change policy sets another one
+const DefaultMaxChangeOutputs = 16
+
+// ChangePolicy controls how the change of a transfer, the difference between the value of the inputs
+// and the value of the outputs, is returned to the sender.
+// By default, the change is returned as a single output.
+type ChangePolicy struct {
+	// Denominations, if not empty, are the standard values the change is split into.
+	// What cannot be expressed with the denominations is returned as a last output.
+	Denominations []uint64
+	// MaxValue, if not zero, is the maximum value of each change output
+	MaxValue uint64
+	// DustThreshold, if not zero, is the value below which the change is not returned but donated as a fee to FeeRecipient
+	DustThreshold uint64
+	// FeeRecipient receives the change below DustThreshold
+	FeeRecipient view.Identity
+	// MaxOutputs, if not zero, is the maximum number of change outputs, DefaultMaxChangeOutputs otherwise.
+	// A change that would be split into more outputs is rejected.
+	MaxOutputs int
+}
+
+func (p *ChangePolicy) Validate() error {
+	for _, d := range p.Denominations {
+		if d == 0 {
+			return errors.New("invalid change policy, denominations must be positive")
+		}
+	}
+	if p.DustThreshold != 0 && p.FeeRecipient.IsNone() {
+		return errors.New("invalid change policy, a dust threshold requires a fee recipient")
+	}
+	if p.MaxOutputs < 0 {
+		return errors.New("invalid change policy, the maximum number of outputs must not be negative")
+	}
+	return nil
+}
+
+// Split splits the passed change into the values of the change outputs, following the policy.
+// It returns also the value donated as a fee, if any.
+// It fails if the change would be split into more than the maximum number of outputs.
+func (p *ChangePolicy) Split(change uint64) ([]uint64, uint64, error) {
+	if change == 0 {
+		return nil, 0, nil
+	}
+	if change < p.DustThreshold {
+		return nil, change, nil
+	}
+
+	denominations := make([]uint64, len(p.Denominations))
+	copy(denominations, p.Denominations)
+	sort.Slice(denominations, func(i, j int) bool { return denominations[i] > denominations[j] })
+
+	split := &changeSplit{max: p.MaxOutputs, maxValue: p.MaxValue}
+	if split.max == 0 {
+		split.max = DefaultMaxChangeOutputs
+	}
+	rest := change
+	for _, d := range denominations {
+		if err := split.add(d, rest/d); err != nil {
+			return nil, 0, errors.WithMessagef(err, "cannot split change [%d]", change)
+		}
+		rest %= d
+	}
+	var fee uint64
+	if rest != 0 {
+		if len(split.values) != 0 && rest < p.DustThreshold {
+			fee = rest
+		} else if err := split.add(rest, 1); err != nil {
+			return nil, 0, errors.WithMessagef(err, "cannot split change [%d]", change)
+		}
+	}
+	return split.values, fee, nil
+}
+
+// changeSplit accumulates the values of the change outputs, capped at maxValue, if not zero,
+// up to max outputs
+type changeSplit struct {
+	max      int
+	maxValue uint64
+	values   []uint64
+}
+
+// add appends n outputs of the passed value, each split into outputs of at most maxValue
+func (s *changeSplit) add(value uint64, n uint64) error {
+	if n == 0 {
+		return nil
+	}
+	pieces, last := uint64(1), value
+	if s.maxValue != 0 && value > s.maxValue {
+		pieces, last = value/s.maxValue, value%s.maxValue
+		if last == 0 {
+			last = s.maxValue
+		} else {
+			pieces++
+		}
+	}
+	available := uint64(s.max - len(s.values))
+	if pieces > available || n > available/pieces {
+		return errors.Errorf("more than [%d] outputs needed", s.max)
+	}
+	for i := uint64(0); i < n; i++ {
+		for j := uint64(1); j < pieces; j++ {
+			s.values = append(s.values, s.maxValue)
+		}
+		s.values = append(s.values, last)
+	}
+	return nil
+}
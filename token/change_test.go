@@ -0,0 +1,63 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+)
+
+func TestChangePolicySplit(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *ChangePolicy
+		change uint64
+		values []uint64
+		fee    uint64
+	}{
+		{name: "no change", policy: &ChangePolicy{}, change: 0},
+		{name: "single output", policy: &ChangePolicy{}, change: 7, values: []uint64{7}},
+		{name: "denominations", policy: &ChangePolicy{Denominations: []uint64{1, 5, 2}}, change: 13, values: []uint64{5, 5, 2, 1}},
+		{name: "rest", policy: &ChangePolicy{Denominations: []uint64{5}}, change: 13, values: []uint64{5, 5, 3}},
+		{name: "max value", policy: &ChangePolicy{MaxValue: 4}, change: 10, values: []uint64{4, 4, 2}},
+		{name: "max value multiple", policy: &ChangePolicy{MaxValue: 5}, change: 10, values: []uint64{5, 5}},
+		{name: "denominations and max value", policy: &ChangePolicy{Denominations: []uint64{10}, MaxValue: 4}, change: 23, values: []uint64{4, 4, 2, 4, 4, 2, 3}},
+		{name: "dust", policy: &ChangePolicy{DustThreshold: 3, FeeRecipient: view.Identity("fees")}, change: 2, fee: 2},
+		{name: "dust rest", policy: &ChangePolicy{Denominations: []uint64{5}, DustThreshold: 3, FeeRecipient: view.Identity("fees")}, change: 12, values: []uint64{5, 5}, fee: 2},
+		{name: "max outputs", policy: &ChangePolicy{MaxValue: 1, MaxOutputs: 3}, change: 3, values: []uint64{1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, tt.policy.Validate())
+			values, fee, err := tt.policy.Split(tt.change)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.values, values)
+			assert.Equal(t, tt.fee, fee)
+		})
+	}
+}
+
+func TestChangePolicySplitMaxOutputs(t *testing.T) {
+	// more outputs than allowed
+	_, _, err := (&ChangePolicy{MaxValue: 1, MaxOutputs: 3}).Split(4)
+	assert.Error(t, err)
+	_, _, err = (&ChangePolicy{Denominations: []uint64{1}}).Split(DefaultMaxChangeOutputs + 1)
+	assert.Error(t, err)
+	_, _, err = (&ChangePolicy{Denominations: []uint64{2}, MaxOutputs: 2}).Split(5)
+	assert.Error(t, err)
+
+	// the bound is checked before the outputs are allocated
+	_, _, err = (&ChangePolicy{MaxValue: 1}).Split(math.MaxUint64)
+	assert.Error(t, err)
+	_, _, err = (&ChangePolicy{Denominations: []uint64{1}, MaxValue: 1}).Split(math.MaxUint64)
+	assert.Error(t, err)
+
+	assert.Error(t, (&ChangePolicy{MaxOutputs: -1}).Validate())
+}
@@ -20,16 +20,42 @@ type Identity struct {
 	Path    string `yaml:"path"`
 }
 
+// OwnerWalletConfig configures an owner wallet
+type OwnerWalletConfig struct {
+	ID string `yaml:"id"`
+	// PseudonymPolicy is one of fresh-per-transaction (default), fresh-per-counterparty, sticky
+	PseudonymPolicy string `yaml:"pseudonymPolicy,omitempty"`
+}
+
 type Wallets struct {
-	Certifiers []*Identity `yaml:"certifiers,omitempty"`
+	Certifiers []*Identity          `yaml:"certifiers,omitempty"`
+	Owners     []*OwnerWalletConfig `yaml:"owners,omitempty"`
+}
+
+type Auditor struct {
+	// DecryptionKey is the path of the file containing the key the auditor uses to open the encrypted audit infos
+	DecryptionKey string `yaml:"decryptionKey,omitempty"`
+}
+
+// PublicParameters configures how the public parameters are accepted
+type PublicParameters struct {
+	// CeremonyTranscript is the path of the published transcript of the setup ceremony
+	// the public parameters must have been generated by. If empty, the public parameters are accepted with a warning.
+	CeremonyTranscript string `yaml:"ceremonyTranscript,omitempty"`
+	// AllowUnverified, if true, accepts the public parameters that do not verify against the ceremony transcript
+	AllowUnverified bool `yaml:"allowUnverified,omitempty"`
 }
 
 type TMS struct {
 	Network       string         `yaml:"network,omitempty"`
 	Channel       string         `yaml:"channel,omitempty"`
 	Namespace     string         `yaml:"namespace,omitempty"`
+	Light         bool           `yaml:"light,omitempty"`
 	Certification *Certification `yaml:"certification,omitempty"`
 	Wallets       *Wallets       `yaml:"wallets,omitempty"`
+	Auditor       *Auditor       `yaml:"auditor,omitempty"`
+	// PublicParameters, if set, configures the verification of the public parameters
+	PublicParameters *PublicParameters `yaml:"publicParameters,omitempty"`
 }
 
 type Token struct {
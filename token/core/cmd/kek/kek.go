@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kek
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/atrest"
+)
+
+var id string
+var output string
+
+// RotateCmd returns the Cobra Command generating a new key encryption key, to rotate the one of a node
+func RotateCmd() *cobra.Command {
+	flags := cobraCommand.Flags()
+	flags.StringVarP(&id, "id", "i", "", "id of the new key encryption key")
+	flags.StringVarP(&output, "output", "o", ".", "output folder")
+
+	return cobraCommand
+}
+
+var cobraCommand = &cobra.Command{
+	Use:   "kek-rotate",
+	Short: "Gen a new key encryption key.",
+	Long: `Gen a new key encryption key to rotate the one the node seals its data at rest with.
+Add the key to token.atRest.keys and make it token.atRest.current, keeping the previous keys to open the data sealed with them.
+The wallet material and the audit infos are sealed again under the new key when the node restarts.
+The token information stored in the vault keeps the key it is sealed under, keep that key as long as the tokens are needed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("trailing args detected")
+		}
+		// Parsing of the command line is done so silence cmd usage
+		cmd.SilenceUsage = true
+		return rotate()
+	},
+}
+
+func rotate() error {
+	if len(id) == 0 {
+		return errors.New("missing key encryption key id")
+	}
+	key, err := atrest.NewKey()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(output, 0766); err != nil {
+		return errors.Wrap(err, "failed making output dir")
+	}
+	path := filepath.Join(output, id+".kek")
+	if _, err := os.Stat(path); err == nil {
+		return errors.Errorf("key encryption key [%s] already exists", path)
+	}
+	fmt.Printf("Store key encryption key [%s] to [%s]...\n", id, path)
+	if err := ioutil.WriteFile(path, key, 0600); err != nil {
+		return errors.Wrap(err, "failed writing key encryption key to file")
+	}
+	return nil
+}
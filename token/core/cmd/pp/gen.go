@@ -8,6 +8,7 @@ package pp
 
 import (
 	"bytes"
+	ecdsa2 "crypto/ecdsa"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -21,6 +22,7 @@ import (
 
 	packager2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/cmd/pp/packager"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/ecdsa"
 )
 
 const (
@@ -34,6 +36,8 @@ var output string
 var base int64
 var exponent int
 var cc bool
+var ceremony string
+var contributors []string
 
 // Cmd returns the Cobra Command for Version
 func Cmd() *cobra.Command {
@@ -45,6 +49,8 @@ func Cmd() *cobra.Command {
 	flags.Int64VarP(&base, "base", "b", 100, "max token quantity")
 	flags.IntVarP(&exponent, "exponent", "e", 2, "max token quantity")
 	flags.BoolVarP(&cc, "cc", "", false, "generate chaincode package")
+	flags.StringVarP(&ceremony, "ceremony", "", "", "name of the setup ceremony, if set its transcript is written to ceremony.json")
+	flags.StringSliceVarP(&contributors, "contributors", "", nil, "paths of the PEM encoded ecdsa private keys of the contributors of the setup ceremony, each signs its contribution")
 
 	return cobraCommand
 }
@@ -105,6 +111,11 @@ func dlogGen(args []string) ([]byte, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed setting up public parameters")
 	}
+	if len(ceremony) != 0 {
+		if err := recordCeremony(pp); err != nil {
+			return nil, err
+		}
+	}
 	// Store Public Params
 	raw, err := pp.Serialize()
 	if err != nil {
@@ -118,6 +129,56 @@ func dlogGen(args []string) ([]byte, error) {
 	return raw, nil
 }
 
+// recordCeremony writes the transcript of the setup ceremony and records it in the passed public parameters.
+// Publish the transcript, the nodes verify the public parameters against it.
+func recordCeremony(pp *crypto.PublicParams) error {
+	transcript, err := crypto.NewCeremonyTranscript(ceremony, pp)
+	if err != nil {
+		return errors.Wrap(err, "failed creating ceremony transcript")
+	}
+	for _, contributor := range contributors {
+		if err := contribute(transcript, contributor); err != nil {
+			return err
+		}
+	}
+	raw, err := transcript.Bytes()
+	if err != nil {
+		return errors.Wrap(err, "failed serializing ceremony transcript")
+	}
+	if err := pp.SetCeremony(raw); err != nil {
+		return errors.Wrap(err, "failed recording ceremony")
+	}
+	if err := ioutil.WriteFile(filepath.Join(output, "ceremony.json"), raw, 0755); err != nil {
+		return errors.Wrap(err, "failed writing ceremony transcript to file")
+	}
+	return nil
+}
+
+// contribute signs the contribution to the passed transcript with the ecdsa private key at the passed path
+func contribute(transcript *crypto.CeremonyTranscript, keyPath string) error {
+	raw, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed reading contributor key [%s]", keyPath)
+	}
+	key, err := ecdsa.PemDecodeKey(raw)
+	if err != nil {
+		return errors.WithMessagef(err, "failed decoding contributor key [%s]", keyPath)
+	}
+	sk, ok := key.(*ecdsa2.PrivateKey)
+	if !ok {
+		return errors.Errorf("contributor key [%s] is not an ecdsa private key", keyPath)
+	}
+	pk, err := ecdsa.PemEncodeKey(&sk.PublicKey)
+	if err != nil {
+		return errors.Wrapf(err, "failed encoding public key of contributor [%s]", keyPath)
+	}
+	signer := &ecdsa.ECDSASigner{SK: sk, ECDSAVerifier: ecdsa.NewECDSAVerifier(&sk.PublicKey)}
+	if err := transcript.Contribute(pk, nil, signer); err != nil {
+		return errors.WithMessagef(err, "failed contributing with key [%s]", keyPath)
+	}
+	return nil
+}
+
 func genChaincodePackage(raw []byte) error {
 	t, err := template.New("node").Funcs(template.FuncMap{
 		"Params": func() string { return base64.StdEncoding.EncodeToString(raw) },
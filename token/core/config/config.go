@@ -20,19 +20,84 @@ type Identity struct {
 	Path    string `yaml:"path"`
 }
 
+// OwnerWallet configures an owner wallet
+type OwnerWallet struct {
+	ID string `yaml:"id"`
+	// PseudonymPolicy is one of fresh-per-transaction (default), fresh-per-counterparty, sticky
+	PseudonymPolicy string `yaml:"pseudonymPolicy,omitempty"`
+	// MSPID and Path, if set, locate the msp configuration of the wallet, to register it at runtime
+	MSPID string `yaml:"mspID,omitempty"`
+	Path  string `yaml:"path,omitempty"`
+}
+
 type Wallets struct {
-	Certifiers []*Identity `yaml:"certifiers,omitempty"`
+	Certifiers []*Identity    `yaml:"certifiers,omitempty"`
+	Owners     []*OwnerWallet `yaml:"owners,omitempty"`
+	// Issuers and Auditors are registered at runtime
+	Issuers  []*Identity `yaml:"issuers,omitempty"`
+	Auditors []*Identity `yaml:"auditors,omitempty"`
+	// File, if set, is the path of a file, in the format of this section, watched for the owner, issuer,
+	// and auditor wallets to register at runtime
+	File string `yaml:"file,omitempty"`
+}
+
+type Auditor struct {
+	// DecryptionKey is the path of the file containing the key the auditor uses to open the encrypted audit infos
+	DecryptionKey string `yaml:"decryptionKey,omitempty"`
+}
+
+// PublicParameters configures how the public parameters are accepted
+type PublicParameters struct {
+	// CeremonyTranscript is the path of the published transcript of the setup ceremony
+	// the public parameters must have been generated by. If empty, the public parameters are accepted with a warning.
+	CeremonyTranscript string `yaml:"ceremonyTranscript,omitempty"`
+	// AllowUnverified, if true, accepts the public parameters that do not verify against the ceremony transcript
+	AllowUnverified bool `yaml:"allowUnverified,omitempty"`
 }
 
 type TMS struct {
-	Network       string         `yaml:"network,omitempty"`
-	Channel       string         `yaml:"channel,omitempty"`
-	Namespace     string         `yaml:"namespace,omitempty"`
+	Network   string `yaml:"network,omitempty"`
+	Channel   string `yaml:"channel,omitempty"`
+	Namespace string `yaml:"namespace,omitempty"`
+	// Application, if set, namespaces the ledger keys of the tokens, so that the token applications
+	// sharing the same namespace do not collide, see keys.NewScheme
+	Application string `yaml:"application,omitempty"`
+	// Light, if set, makes the vault keep only the tokens owned by this node, the ledger state of the others'
+	// tokens is dropped at commit time, see LightMode. The node still processes all the transactions of the namespace.
+	Light bool `yaml:"light,omitempty"`
+	// Transcripts, if set, makes the vault record at commit time the verification transcripts of the token requests
+	// of the transactions known to this node, see ManagementService.Transcript
+	Transcripts   bool           `yaml:"transcripts,omitempty"`
 	Certification *Certification `yaml:"certification,omitempty"`
 	Wallets       *Wallets       `yaml:"wallets,omitempty"`
+	Auditor       *Auditor       `yaml:"auditor,omitempty"`
+	// PublicParameters, if set, configures the verification of the public parameters
+	PublicParameters *PublicParameters `yaml:"publicParameters,omitempty"`
+	// Submitter, if set, is the identifier, in the local membership, of the Fabric identity that signs and submits
+	// the transactions of the application that request it, in place of the identities of their parties, see Submitter.
+	// Anonymous transactions are never submitted by it.
+	Submitter string `yaml:"submitter,omitempty"`
+}
+
+// AtRestKey is a key encryption key of the node
+type AtRestKey struct {
+	ID string `yaml:"id"`
+	// Path is the path of the file containing the key, 32 random bytes
+	Path string `yaml:"path"`
+}
+
+// AtRest configures the encryption of the wallet material, the token information and the audit infos
+// persisted by the node
+type AtRest struct {
+	// Current is the id of the key the data is sealed with
+	Current string `yaml:"current"`
+	// Keys are the key encryption keys. The keys that are no longer current are kept to open the data sealed with them.
+	Keys []*AtRestKey `yaml:"keys,omitempty"`
 }
 
 type Token struct {
 	Enabled bool   `yaml:"enabled,omitempty"`
 	TMS     []*TMS `yaml:"tms,omitempty"`
+	// AtRest, if set, enables the encryption of the data persisted by the node
+	AtRest *AtRest `yaml:"atRest,omitempty"`
 }
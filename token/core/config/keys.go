@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package config
+
+import (
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+)
+
+// KeyScheme returns the scheme of the ledger keys of the token application configured for the passed channel and namespace,
+// keys.Default if no application is configured
+func KeyScheme(sp view2.ServiceProvider, channel, namespace string) (*keys.Scheme, error) {
+	tms, err := lookup(sp, channel, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if tms == nil {
+		return keys.Default, nil
+	}
+	scheme, err := keys.NewScheme(tms.Application)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid application for [%s:%s]", channel, namespace)
+	}
+	return scheme, nil
+}
+
+// LightMode returns true if the vault of the token application configured for the passed channel and namespace
+// must keep only the tokens owned by this node
+func LightMode(sp view2.ServiceProvider, channel, namespace string) (bool, error) {
+	tms, err := lookup(sp, channel, namespace)
+	if err != nil {
+		return false, err
+	}
+	return tms != nil && tms.Light, nil
+}
+
+// Transcripts returns true if the vault of the token application configured for the passed channel and namespace
+// must record the verification transcripts of the token requests it commits
+func Transcripts(sp view2.ServiceProvider, channel, namespace string) (bool, error) {
+	tms, err := lookup(sp, channel, namespace)
+	if err != nil {
+		return false, err
+	}
+	return tms != nil && tms.Transcripts, nil
+}
+
+// Submitter returns the identifier of the Fabric identity submitting the transactions of the token application
+// configured for the passed channel and namespace, empty if none is designated
+func Submitter(sp view2.ServiceProvider, channel, namespace string) (string, error) {
+	tms, err := lookup(sp, channel, namespace)
+	if err != nil {
+		return "", err
+	}
+	if tms == nil {
+		return "", nil
+	}
+	return tms.Submitter, nil
+}
+
+// TMSs returns the configurations of the token applications
+func TMSs(sp view2.ServiceProvider) ([]*TMS, error) {
+	var tmsConfigs []*TMS
+	if err := view2.GetConfigService(sp).UnmarshalKey("token.tms", &tmsConfigs); err != nil {
+		return nil, errors.WithMessagef(err, "cannot load token-sdk configuration")
+	}
+	return tmsConfigs, nil
+}
+
+// lookup returns the configuration of the token application for the passed channel and namespace, nil if none
+func lookup(sp view2.ServiceProvider, channel, namespace string) (*TMS, error) {
+	tmsConfigs, err := TMSs(sp)
+	if err != nil {
+		return nil, err
+	}
+	for _, tms := range tmsConfigs {
+		if tms.Channel == channel && tms.Namespace == namespace {
+			return tms, nil
+		}
+	}
+	return nil, nil
+}
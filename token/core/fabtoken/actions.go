@@ -7,6 +7,7 @@ package fabtoken
 
 import (
 	"encoding/json"
+	"time"
 
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 
@@ -27,12 +28,45 @@ func (inf *TokenInformation) Serialize() ([]byte, error) {
 	return json.Marshal(inf)
 }
 
+// Token is the representation of a fabtoken token on the ledger.
+// Expiration and Issuer are set only for the tokens issued with an expiration.
+type Token struct {
+	token2.Token
+	// Expiration is the unix time, in seconds, after which the token can only be reclaimed by its issuer
+	Expiration int64 `json:"expiration,omitempty"`
+	// Issuer is the identity of the issuer of the token
+	Issuer view.Identity `json:"issuer,omitempty"`
+}
+
+func (t *Token) Deserialize(raw []byte) error {
+	return json.Unmarshal(raw, t)
+}
+
+// HasExpiration returns true if the token has been issued with an expiration
+func (t *Token) HasExpiration() bool {
+	return t.Expiration != 0
+}
+
+// IsExpiredAt returns true if the token has an expiration and the passed time is after it
+func (t *Token) IsExpiredAt(now time.Time) bool {
+	return t.HasExpiration() && now.Unix() > t.Expiration
+}
+
 type TransferOutput struct {
-	Output *token2.Token
+	Output     *token2.Token
+	Expiration int64         `json:",omitempty"`
+	Issuer     view.Identity `json:",omitempty"`
 }
 
 func (t *TransferOutput) Serialize() ([]byte, error) {
-	return json.Marshal(t.Output)
+	if t.Expiration == 0 {
+		return json.Marshal(t.Output)
+	}
+	return json.Marshal(&Token{
+		Token:      *t.Output,
+		Expiration: t.Expiration,
+		Issuer:     t.Issuer,
+	})
 }
 
 func (t *TransferOutput) IsRedeem() bool {
@@ -76,6 +110,20 @@ func (i *IssueAction) GetOutputs() []api.Output {
 	return res
 }
 
+// GetTokenTypes returns the distinct types of the outputs, in order of appearance
+func (i *IssueAction) GetTokenTypes() []string {
+	var types []string
+	seen := map[string]bool{}
+	for _, output := range i.Outputs {
+		if output.Output == nil || seen[output.Output.Type] {
+			continue
+		}
+		seen[output.Output.Type] = true
+		types = append(types, output.Output.Type)
+	}
+	return types
+}
+
 func (i *IssueAction) IsAnonymous() bool {
 	return false
 }
@@ -88,6 +136,8 @@ type TransferAction struct {
 	Sender  view.Identity
 	Inputs  []string
 	Outputs []*TransferOutput
+	// Reclaim is true if the action moves the value of expired tokens on behalf of their issuer
+	Reclaim bool `json:",omitempty"`
 }
 
 func (t *TransferAction) Serialize() ([]byte, error) {
@@ -0,0 +1,44 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package fabtoken
+
+import (
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+// outputSize is the size of an output in the clear: the owner, the type, and the quantity, JSON encoded
+var outputSize = api.Base64Size(api.IdentitySize) + 96
+
+// EstimateRequest returns the size of the envelope of a request and the cost of the auditor's signature, if any
+func (s *service) EstimateRequest() *api.CostEstimate {
+	estimate := &api.CostEstimate{Size: api.RequestOverhead}
+	if len(s.publicParams().Auditor) != 0 {
+		estimate.Add(signatures(1))
+	}
+	return estimate
+}
+
+// EstimateIssue returns the cost of an issue action signed by the issuer, fabtoken does not have proofs
+func (s *service) EstimateIssue(outputs int) *api.CostEstimate {
+	action := api.Base64Size(api.IdentitySize) + outputs*outputSize
+	return (&api.CostEstimate{Size: api.Base64Size(action), Outputs: outputs}).Add(signatures(1))
+}
+
+// EstimateTransfer returns the cost of a transfer action signed by the owner of each input
+func (s *service) EstimateTransfer(inputs, outputs int) *api.CostEstimate {
+	action := api.Base64Size(api.IdentitySize) + inputs*(api.InputKeySize+3) + outputs*outputSize
+	return (&api.CostEstimate{Size: api.Base64Size(action), Inputs: inputs, Outputs: outputs}).Add(signatures(inputs))
+}
+
+func signatures(n int) *api.CostEstimate {
+	return &api.CostEstimate{
+		Size:             n * api.Base64Size(api.ECDSASignatureSize),
+		Signatures:       n,
+		VerificationTime: time.Duration(n) * api.ECDSAVerificationTime,
+	}
+}
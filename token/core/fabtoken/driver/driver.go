@@ -11,6 +11,7 @@ import (
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/config"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/fabric"
@@ -29,9 +30,13 @@ func (d *Driver) PublicParametersFromBytes(params []byte) (api.PublicParameters,
 }
 
 func (d *Driver) NewTokenService(sp view2.ServiceProvider, publicParamsFetcher api.PublicParamsFetcher, network string, channel api.Channel, namespace string) (api.TokenManagerService, error) {
+	keyScheme, err := config.KeyScheme(sp, channel.Name(), namespace)
+	if err != nil {
+		return nil, err
+	}
 	qe := vault.NewVault(sp, channel, namespace).QueryEngine()
 	nodeIdentity := view2.GetIdentityProvider(sp).DefaultIdentity()
-	return fabtoken.NewService(
+	service := fabtoken.NewService(
 		sp,
 		channel,
 		namespace,
@@ -49,7 +54,9 @@ func (d *Driver) NewTokenService(sp view2.ServiceProvider, publicParamsFetcher a
 				api.OwnerRole:   fabric.NewMapper(fabric.X509MSPIdentity, nodeIdentity, fabric2.GetFabricNetworkService(sp, network).LocalMembership()),
 			},
 		),
-	), nil
+	)
+	service.SetKeyScheme(keyScheme)
+	return service, nil
 }
 
 func (d *Driver) NewValidator(params api.PublicParameters) (api.Validator, error) {
@@ -5,16 +5,29 @@ SPDX-License-Identifier: Apache-2.0
 */
 package fabtoken
 
-import "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+import (
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/fabric"
+)
 
 type PublicParamsManager struct {
 	pp *PublicParams
+	// fetch fetches the public parameters again, bypassing any cache
+	fetch func() error
 }
 
 func NewPublicParamsManager(pp *PublicParams) *PublicParamsManager {
 	return &PublicParamsManager{pp: pp}
 }
 
+// WithFetch sets the function ForceFetch calls to fetch the public parameters again
+func (v *PublicParamsManager) WithFetch(fetch func() error) *PublicParamsManager {
+	v.fetch = fetch
+	return v
+}
+
 func (v *PublicParamsManager) SetAuditor(auditor []byte) ([]byte, error) {
 	raw, err := v.pp.Serialize()
 	if err != nil {
@@ -34,6 +47,144 @@ func (v *PublicParamsManager) SetAuditor(auditor []byte) ([]byte, error) {
 	return raw, nil
 }
 
+func (v *PublicParamsManager) SetIssueApprover(tokenType string, approver []byte) ([]byte, error) {
+	if len(tokenType) == 0 {
+		return nil, errors.New("token type must be specified")
+	}
+	if len(approver) != 0 {
+		identityDeserializer := &fabric.MSPX509IdentityDeserializer{}
+		if _, err := identityDeserializer.GetVerifier(approver); err != nil {
+			return nil, errors.Wrap(err, "failed to retrieve issue approver's identity")
+		}
+	}
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	pp := &PublicParams{}
+	if err := pp.Deserialize(raw); err != nil {
+		return nil, err
+	}
+	if pp.IssuePolicy == nil {
+		pp.IssuePolicy = &api.IssuePolicy{}
+	}
+	pp.IssuePolicy.SetApprover(tokenType, approver)
+
+	raw, err = pp.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	v.pp = pp
+	return raw, nil
+}
+
+func (v *PublicParamsManager) SetTypeAuditor(tokenType string, auditor []byte) ([]byte, error) {
+	if len(tokenType) == 0 {
+		return nil, errors.New("token type must be specified")
+	}
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	pp := &PublicParams{}
+	if err := pp.Deserialize(raw); err != nil {
+		return nil, err
+	}
+	if pp.AuditPolicy == nil {
+		pp.AuditPolicy = &api.AuditPolicy{}
+	}
+	pp.AuditPolicy.SetAuditor(tokenType, auditor)
+
+	raw, err = pp.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	v.pp = pp
+	return raw, nil
+}
+
+func (v *PublicParamsManager) SetRedeemRequiresIssuer(required bool) ([]byte, error) {
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	pp := &PublicParams{}
+	if err := pp.Deserialize(raw); err != nil {
+		return nil, err
+	}
+	pp.RedeemIssuer = required
+
+	raw, err = pp.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	v.pp = pp
+	return raw, nil
+}
+
+// SetReceiverPolicy sets the policy the receivers of the transfers must satisfy, nil to accept any receiver
+func (v *PublicParamsManager) SetReceiverPolicy(policy *api.ReceiverPolicy) ([]byte, error) {
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	pp := &PublicParams{}
+	if err := pp.Deserialize(raw); err != nil {
+		return nil, err
+	}
+	pp.ReceiverPolicy = policy
+
+	raw, err = pp.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	v.pp = pp
+	return raw, nil
+}
+
+// AddDepartedOrganization lists the organization with the passed MSP ID as departed, its tokens can be rebound
+func (v *PublicParamsManager) AddDepartedOrganization(organization string) ([]byte, error) {
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	pp := &PublicParams{}
+	if err := pp.Deserialize(raw); err != nil {
+		return nil, err
+	}
+	if !pp.Departed(organization) {
+		pp.DepartedOrganizations = append(pp.DepartedOrganizations, organization)
+	}
+
+	raw, err = pp.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	v.pp = pp
+	return raw, nil
+}
+
+// SetAuditThreshold sets the total value under which the token requests made of transfers only can skip the auditors,
+// 0 to audit every request
+func (v *PublicParamsManager) SetAuditThreshold(threshold uint64) ([]byte, error) {
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	pp := &PublicParams{}
+	if err := pp.Deserialize(raw); err != nil {
+		return nil, err
+	}
+	pp.AuditFrom = threshold
+
+	raw, err = pp.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	v.pp = pp
+	return raw, nil
+}
+
 func (v *PublicParamsManager) AddIssuer(bytes []byte) ([]byte, error) {
 	panic("implement me")
 }
@@ -51,6 +202,8 @@ func (v *PublicParamsManager) NewCertifierKeyPair() ([]byte, []byte, error) {
 }
 
 func (v *PublicParamsManager) ForceFetch() error {
-	// TODO: implement this
-	return nil
+	if v.fetch == nil {
+		return errors.New("public parameters cannot be fetched, they have been passed")
+	}
+	return v.fetch()
 }
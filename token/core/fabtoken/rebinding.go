@@ -0,0 +1,37 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package fabtoken
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// NewRebindingAction returns the action rebinding the passed serialized tokens of the members of the passed departed
+// organization, stored on the ledger under the passed keys, to the passed owners. The i-th token is bound to the i-th owner, everything else is preserved.
+func NewRebindingAction(organization string, keys []string, tokens [][]byte, owners [][]byte) (*api.RebindingAction, error) {
+	if len(keys) != len(tokens) || len(keys) != len(owners) {
+		return nil, errors.Errorf("[%d] keys, [%d] tokens, and [%d] owners do not match", len(keys), len(tokens), len(owners))
+	}
+	action := &api.RebindingAction{Organization: organization, Inputs: keys}
+	for i, raw := range tokens {
+		tok := &Token{}
+		if err := tok.Deserialize(raw); err != nil {
+			return nil, errors.Wrapf(err, "failed to deserialize token [%s]", keys[i])
+		}
+		tok.Owner = &token2.Owner{Raw: owners[i]}
+		out, err := json.Marshal(tok)
+		if err != nil {
+			return nil, err
+		}
+		action.Outputs = append(action.Outputs, out)
+	}
+	return action, nil
+}
@@ -6,6 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package fabtoken
 
 import (
+	"context"
 	"encoding/json"
 	"sync"
 
@@ -14,10 +15,12 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/x509"
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
 )
 
@@ -52,6 +55,9 @@ type service struct {
 	issuerWallets    []*issuerWallet
 	auditorWallets   []*auditorWallet
 	walletsLock      sync.Mutex
+
+	// keys derives the ledger keys of the tokens
+	keys *keys.Scheme
 }
 
 func NewService(
@@ -71,9 +77,15 @@ func NewService(
 		publicParamsLoader:  publicParamsLoader,
 		qe:                  qe,
 		identityProvider:    identityProvider,
+		keys:                keys.Default,
 	}
 }
 
+// SetKeyScheme sets the scheme the ledger keys of the tokens are derived with
+func (s *service) SetKeyScheme(scheme *keys.Scheme) {
+	s.keys = scheme
+}
+
 func (s *service) PublicParams() interface{} {
 	if s.pp == nil {
 		var err error
@@ -85,8 +97,9 @@ func (s *service) PublicParams() interface{} {
 	return s.pp
 }
 
+// FetchPublicParams fetches the public parameters again, bypassing the cache of the fetcher, if any
 func (s *service) FetchPublicParams() error {
-	raw, err := s.publicParamsFetcher.Fetch()
+	raw, err := api.ForceFetch(s.publicParamsFetcher)
 	if err != nil {
 		return errors.WithMessagef(err, "failed fetching public params from fabric")
 	}
@@ -110,11 +123,7 @@ func (s *service) RegisterRecipientIdentity(id view.Identity, auditInfo []byte,
 		return err
 	}
 
-	if err := view2.GetSigService(s.sp).RegisterAuditInfo(id, auditInfo); err != nil {
-		return err
-	}
-
-	return nil
+	return identity.RegisterAuditInfo(s.sp, id, auditInfo)
 }
 
 func (s *service) GenerateIssuerKeyPair(tokenType string) (api.Key, api.Key, error) {
@@ -122,39 +131,81 @@ func (s *service) GenerateIssuerKeyPair(tokenType string) (api.Key, api.Key, err
 }
 
 func (s *service) RegisterAuditInfo(id view.Identity, auditInfo []byte) error {
-	if err := view2.GetSigService(s.sp).RegisterAuditInfo(id, auditInfo); err != nil {
-		return err
-	}
-	return nil
+	return identity.RegisterAuditInfo(s.sp, id, auditInfo)
 }
 
 func (s *service) RegisterIssuer(label string, sk api.Key, pk api.Key) error {
 	panic("implement me")
 }
 
+func (s *service) RegisterIssuerCredential(label string, sk api.Key, credential []byte) error {
+	return errors.New("issuer credentials are not supported by fabtoken")
+}
+
+func (s *service) RegisterWallet(usage api.IdentityUsage, id string, mspID string, path string) error {
+	return s.identityProvider.RegisterIdentity(usage, id, mspID, path)
+}
+
+func (s *service) WalletMSPType(usage api.IdentityUsage) (string, error) {
+	return s.identityProvider.MSPType(usage)
+}
+
 func (s *service) IssuerIdentity(label string) (view.Identity, error) {
 	panic("implement me")
 }
 
 func (s *service) GetAuditInfo(id view.Identity) ([]byte, error) {
-	return view2.GetSigService(s.sp).GetAuditInfo(id)
+	auditInfo, err := view2.GetSigService(s.sp).GetAuditInfo(id)
+	if err != nil {
+		return nil, err
+	}
+	return identity.OpenAuditInfo(s.sp, id, auditInfo)
 }
 
 func (s *service) GetEnrollmentID(auditInfo []byte) (string, error) {
 	return string(auditInfo), nil
 }
 
-func (s *service) Issue(issuerIdentity view.Identity, typ string, values []uint64, owners [][]byte) (api.IssueAction, [][]byte, view.Identity, error) {
+// GetOwnerEnrollmentID returns the passed audit info, the enrollment ID of an x509 identity,
+// if it is the common name of the certificate of the passed identity
+func (s *service) GetOwnerEnrollmentID(owner view.Identity, auditInfo []byte) (string, error) {
+	eID, err := x509.GetEnrollmentID(owner)
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed getting enrollment id of [%s]", owner)
+	}
+	if eID != string(auditInfo) {
+		return "", errors.Errorf("audit info does not match identity [%s]", owner)
+	}
+	return eID, nil
+}
+
+// requestAuditInfo returns the audit info of the passed identity to be carried by the metadata of a request,
+// nil if the deployment has no auditor
+func (s *service) requestAuditInfo(id view.Identity) ([]byte, error) {
+	if s.publicParams().AuditorIdentity() == nil {
+		return nil, nil
+	}
+	return s.GetAuditInfo(id)
+}
+
+func (s *service) Issue(issuerIdentity view.Identity, typ string, values []uint64, owners [][]byte, opts *api.IssueOptions) (api.IssueAction, [][]byte, view.Identity, error) {
 	for _, owner := range owners {
 		if len(owner) == 0 {
 			return nil, nil, nil, errors.Errorf("all recipients should be defined")
 		}
 	}
+	var expiration int64
+	if opts != nil && !opts.Expiration.IsZero() {
+		expiration = opts.Expiration.Unix()
+		if expiration <= 0 {
+			return nil, nil, nil, errors.Errorf("invalid expiration [%s]", opts.Expiration)
+		}
+	}
 
 	var outs []*TransferOutput
 	var infos [][]byte
 	for i, v := range values {
-		outs = append(outs, &TransferOutput{
+		out := &TransferOutput{
 			Output: &token2.Token{
 				Owner: &token2.Owner{
 					Raw: owners[i],
@@ -162,7 +213,12 @@ func (s *service) Issue(issuerIdentity view.Identity, typ string, values []uint6
 				Type:     typ,
 				Quantity: token2.NewQuantityFromUInt64(v).Hex(),
 			},
-		})
+		}
+		if expiration != 0 {
+			out.Expiration = expiration
+			out.Issuer = issuerIdentity
+		}
+		outs = append(outs, out)
 
 		ti := &TokenInformation{
 			Issuer: issuerIdentity,
@@ -180,7 +236,7 @@ func (s *service) Issue(issuerIdentity view.Identity, typ string, values []uint6
 		nil
 }
 
-func (s *service) VerifyIssue(tr api.IssueAction, tokenInfos [][]byte) error {
+func (s *service) VerifyIssue(ctx context.Context, tr api.IssueAction, tokenInfos [][]byte) error {
 	// TODO:
 	return nil
 }
@@ -194,12 +250,12 @@ func (s *service) DeserializeIssueAction(raw []byte) (api.IssueAction, error) {
 }
 
 func (s *service) Transfer(txID string, wallet api.OwnerWallet, ids []*token2.Id, Outputs ...*token2.Token) (api.TransferAction, *api.TransferMetadata, error) {
-	id, err := wallet.GetRecipientIdentity()
+	id, err := wallet.GetRecipientIdentity(&api.RecipientIdentityOptions{})
 	if err != nil {
 		return nil, nil, errors.WithMessagef(err, "failed getting sender identity")
 	}
 
-	var tokens []*token2.Token
+	var tokens []*Token
 	var inputIDs []string
 	var signers []view2.Signer
 	var signerIds []view.Identity
@@ -212,7 +268,7 @@ func (s *service) Transfer(txID string, wallet api.OwnerWallet, ids []*token2.Id
 
 	for _, id := range ids {
 		// Token and InputID
-		outputID, err := keys.CreateTokenKey(id.TxId, int(id.Index))
+		outputID, err := s.keys.CreateTokenKey(id.TxId, int(id.Index))
 		if err != nil {
 			return nil, nil, errors.Wrapf(err, "error creating output ID: %v", id)
 		}
@@ -222,15 +278,21 @@ func (s *service) Transfer(txID string, wallet api.OwnerWallet, ids []*token2.Id
 		}
 
 		logger.Debugf("loaded transfer input [%s]", hash.Hashable(val).String())
-		tok := &token2.Token{}
-		err = json.Unmarshal(val, tok)
-		if err != nil {
+		tok := &Token{}
+		if err := tok.Deserialize(val); err != nil {
 			return nil, nil, errors.Wrapf(err, "failed unmarshalling token for id [%v]", id)
 		}
 		logger.Debugf("Selected output [%s,%s,%s]", tok.Type, tok.Quantity, view.Identity(tok.Owner.Raw))
+		if len(tokens) != 0 && (tokens[0].Expiration != tok.Expiration || !tokens[0].Issuer.Equal(tok.Issuer)) {
+			return nil, nil, errors.Errorf("tokens with different expirations cannot be spent together [%v]", id)
+		}
 
-		// Signer
-		si, err := view2.GetSigService(s.sp).GetSigningIdentity(tok.Owner.Raw)
+		// Signer, the owner of the lock for time locked tokens
+		spender, err := api.SpenderOf(tok.Owner.Raw)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid owner for id [%v]", id)
+		}
+		si, err := view2.GetSigService(s.sp).GetSigningIdentity(spender)
 		if err != nil {
 			return nil, nil, errors.Wrapf(err, "failed getting signing identity for id [%v]", id)
 		}
@@ -248,9 +310,15 @@ func (s *service) Transfer(txID string, wallet api.OwnerWallet, ids []*token2.Id
 	var outs []*TransferOutput
 	var infos [][]byte
 	for _, output := range Outputs {
-		outs = append(outs, &TransferOutput{
+		out := &TransferOutput{
 			Output: output,
-		})
+		}
+		// outputs inherit the expiration of the inputs
+		if len(tokens) != 0 && tokens[0].HasExpiration() {
+			out.Expiration = tokens[0].Expiration
+			out.Issuer = tokens[0].Issuer
+		}
+		outs = append(outs, out)
 		ti := &TokenInformation{}
 		tiRaw, err := ti.Serialize()
 		if err != nil {
@@ -265,36 +333,40 @@ func (s *service) Transfer(txID string, wallet api.OwnerWallet, ids []*token2.Id
 		Outputs: outs,
 	}
 
+	// the receivers of time locked outputs are the owners of the locks
 	var ownerIdentities []view.Identity
+	var receiverAuditInfos [][]byte
 	for _, output := range outs {
+		receiver, err := api.SpenderOf(output.Output.Owner.Raw)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid recipient identity [%s]", view.Identity(output.Output.Owner.Raw).String())
+		}
+		auditInfo, err := s.requestAuditInfo(receiver)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed getting audit info for recipient identity [%s]", receiver.String())
+		}
+		receiverAuditInfos = append(receiverAuditInfos, auditInfo)
+
 		// add owner identity if not present already
 		found := false
 		for _, identity := range ownerIdentities {
-			if identity.Equal(output.Output.Owner.Raw) {
+			if identity.Equal(receiver) {
 				found = true
 				break
 			}
 		}
 		if !found {
-			ownerIdentities = append(ownerIdentities, output.Output.Owner.Raw)
+			ownerIdentities = append(ownerIdentities, receiver)
 		}
 	}
 	var senderAuditInfos [][]byte
-	for _, t := range tokens {
-		auditInfo, err := view2.GetSigService(s.sp).GetAuditInfo(t.Owner.Raw)
+	for i, t := range tokens {
+		auditInfo, err := s.requestAuditInfo(signerIds[i])
 		if err != nil {
 			return nil, nil, errors.Wrapf(err, "failed getting audit info for sender identity [%s]", view.Identity(t.Owner.Raw).String())
 		}
 		senderAuditInfos = append(senderAuditInfos, auditInfo)
 	}
-	var receiverAuditInfos [][]byte
-	for _, output := range outs {
-		auditInfo, err := view2.GetSigService(s.sp).GetAuditInfo(output.Output.Owner.Raw)
-		if err != nil {
-			return nil, nil, errors.Wrapf(err, "failed getting audit info for recipient identity [%s]", view.Identity(output.Output.Owner.Raw).String())
-		}
-		receiverAuditInfos = append(receiverAuditInfos, auditInfo)
-	}
 	outputs, err := transfer.GetSerializedOutputs()
 	if err != nil {
 		return nil, nil, errors.Wrapf(err, "failed getting serialized outputs")
@@ -319,7 +391,112 @@ func (s *service) Transfer(txID string, wallet api.OwnerWallet, ids []*token2.Id
 	return transfer, metadata, nil
 }
 
-func (s *service) VerifyTransfer(tr api.TransferAction, tokenInfos [][]byte) error {
+func (s *service) Reclaim(txID string, issuer view.Identity, ids []*token2.Id, receiver view.Identity) (api.TransferAction, *api.TransferMetadata, error) {
+	if receiver.IsNone() {
+		return nil, nil, errors.Errorf("the receiver of the reclaimed value should be defined")
+	}
+	if len(ids) == 0 {
+		return nil, nil, errors.Errorf("no tokens to reclaim")
+	}
+
+	qe, err := s.channel.Vault().NewQueryExecutor()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer qe.Done()
+
+	var inputIDs []string
+	var typ string
+	sum := token2.NewZeroQuantity(keys.Precision)
+	for _, id := range ids {
+		outputID, err := s.keys.CreateTokenKey(id.TxId, int(id.Index))
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "error creating output ID: %v", id)
+		}
+		val, err := qe.GetState(s.namespace, outputID)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed getting state [%s]", outputID)
+		}
+		if len(val) == 0 {
+			return nil, nil, errors.Errorf("token [%v] does not exist", id)
+		}
+		tok := &Token{}
+		if err := tok.Deserialize(val); err != nil {
+			return nil, nil, errors.Wrapf(err, "failed unmarshalling token for id [%v]", id)
+		}
+		if !tok.HasExpiration() {
+			return nil, nil, errors.Errorf("token [%v] has no expiration, it cannot be reclaimed", id)
+		}
+		if !issuer.Equal(tok.Issuer) {
+			return nil, nil, errors.Errorf("token [%v] has not been issued by [%s]", id, issuer)
+		}
+		if len(typ) == 0 {
+			typ = tok.Type
+		}
+		if typ != tok.Type {
+			return nil, nil, errors.Errorf("tokens must have the same type [%s]!=[%s]", typ, tok.Type)
+		}
+		q, err := token2.ToQuantity(tok.Quantity, keys.Precision)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed unmarshalling token quantity [%s]", tok.Quantity)
+		}
+		sum = sum.Add(q)
+		inputIDs = append(inputIDs, outputID)
+	}
+
+	transfer := &TransferAction{
+		Sender: issuer,
+		Inputs: inputIDs,
+		Outputs: []*TransferOutput{{
+			Output: &token2.Token{
+				Owner:    &token2.Owner{Raw: receiver},
+				Type:     typ,
+				Quantity: sum.Hex(),
+			},
+		}},
+		Reclaim: true,
+	}
+
+	ti := &TokenInformation{}
+	tiRaw, err := ti.Serialize()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed serializing token information")
+	}
+	// the issuer signs for each input
+	issuerAuditInfo, err := s.requestAuditInfo(issuer)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed getting audit info for issuer identity [%s]", issuer.String())
+	}
+	receiverAuditInfo, err := s.requestAuditInfo(receiver)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed getting audit info for recipient identity [%s]", receiver.String())
+	}
+	outputs, err := transfer.GetSerializedOutputs()
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed getting serialized outputs")
+	}
+	senders := make([]view.Identity, len(ids))
+	senderAuditInfos := make([][]byte, len(ids))
+	for i := range ids {
+		senders[i] = issuer
+		senderAuditInfos[i] = issuerAuditInfo
+	}
+
+	metadata := &api.TransferMetadata{
+		Outputs:            outputs,
+		Senders:            senders,
+		SenderAuditInfos:   senderAuditInfos,
+		TokenIDs:           ids,
+		TokenInfo:          [][]byte{tiRaw},
+		Receivers:          []view.Identity{receiver},
+		ReceiverIsSender:   []bool{s.ownerWallet(receiver) != nil},
+		ReceiverAuditInfos: [][]byte{receiverAuditInfo},
+	}
+
+	return transfer, metadata, nil
+}
+
+func (s *service) VerifyTransfer(ctx context.Context, tr api.TransferAction, tokenInfos [][]byte) error {
 	// TODO:
 	return nil
 }
@@ -354,6 +531,13 @@ func (s *service) DeserializeToken(outputRaw []byte, tokenInfoRaw []byte) (*toke
 	return tok, tokInfo.Issuer, nil
 }
 
+// VerifyTokenInfo checks that the passed output and token information are well-formed,
+// fabtoken outputs are in the clear, there is no commitment to open
+func (s *service) VerifyTokenInfo(outputRaw []byte, tokenInfoRaw []byte) error {
+	_, _, err := s.DeserializeToken(outputRaw, tokenInfoRaw)
+	return err
+}
+
 func (s *service) AuditorCheck(tokenRequest *api.TokenRequest, tokenRequestMetadata *api.TokenRequestMetadata, txID string) error {
 	// TODO:
 	return nil
@@ -368,7 +552,7 @@ func (s *service) Validator() api.Validator {
 }
 
 func (s *service) PublicParamsManager() api.PublicParamsManager {
-	return NewPublicParamsManager(s.publicParams())
+	return NewPublicParamsManager(s.publicParams()).WithFetch(s.FetchPublicParams)
 }
 
 func (s *service) NewCertificationRequest(ids []*token2.Id) ([]byte, error) {
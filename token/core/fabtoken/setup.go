@@ -8,6 +8,7 @@ package fabtoken
 import (
 	"encoding/json"
 
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/pkg/errors"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
@@ -20,6 +21,21 @@ const PublicParameters = "fabtoken"
 type PublicParams struct {
 	MTV     uint64
 	Auditor []byte
+	// IssuePolicy, if set, lists the approvers that must co-sign the issue actions
+	IssuePolicy *api.IssuePolicy `json:",omitempty"`
+	// AuditPolicy, if set, assigns auditors other than the default one to some token types
+	AuditPolicy *api.AuditPolicy `json:",omitempty"`
+	// ReceiverPolicy, if set, restricts the receivers of the transfers to the owners holding an accepted
+	// organizational unit
+	ReceiverPolicy *api.ReceiverPolicy `json:",omitempty"`
+	// RedeemIssuer, if true, requires the redemptions to be co-signed by an issuer of the redeemed type
+	RedeemIssuer bool `json:",omitempty"`
+	// AuditFrom, if set, is the total value from which the token requests made of transfers only must be signed
+	// by the auditors, the smaller ones can skip them
+	AuditFrom uint64 `json:",omitempty"`
+	// DepartedOrganizations are the MSP IDs of the organizations that left the consortium, whose tokens can be
+	// rebound to successor owners, see api.RebindingAction
+	DepartedOrganizations []string `json:",omitempty"`
 }
 
 func NewPublicParamsFromBytes(raw []byte) (*PublicParams, error) {
@@ -50,6 +66,39 @@ func (pp *PublicParams) MaxTokenValue() uint64 {
 	return pp.MTV
 }
 
+func (pp *PublicParams) AuditorIdentity() view.Identity {
+	if len(pp.Auditor) == 0 {
+		return nil
+	}
+	return pp.Auditor
+}
+
+func (pp *PublicParams) TypeAuditor(tokenType string) view.Identity {
+	if auditor := pp.AuditPolicy.Auditor(tokenType); auditor != nil {
+		return auditor
+	}
+	return pp.AuditorIdentity()
+}
+
+func (pp *PublicParams) IssueApprover(tokenType string) view.Identity {
+	return pp.IssuePolicy.Approver(tokenType)
+}
+
+func (pp *PublicParams) RedeemRequiresIssuer() bool {
+	return pp.RedeemIssuer
+}
+
+// AuditThreshold returns the total value under which the token requests made of transfers only can skip the auditors,
+// 0 if every request is audited
+func (pp *PublicParams) AuditThreshold() uint64 {
+	return pp.AuditFrom
+}
+
+// Departed returns true if the organization with the passed MSP ID left the consortium
+func (pp *PublicParams) Departed(organization string) bool {
+	return api.DepartedList(pp.DepartedOrganizations).Departed(organization)
+}
+
 func (pp *PublicParams) Bytes() ([]byte, error) {
 	return json.Marshal(pp)
 }
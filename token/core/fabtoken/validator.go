@@ -6,7 +6,10 @@ SPDX-License-Identifier: Apache-2.0
 package fabtoken
 
 import (
+	"bytes"
 	"encoding/json"
+	"math/big"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -15,6 +18,7 @@ import (
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/fabric"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
@@ -26,26 +30,60 @@ func NewValidator(pp *PublicParams) *Validator {
 	return &Validator{pp: pp}
 }
 
-func (v *Validator) VerifyTokenRequest(ledger api.Ledger, signatureProvider api.SignatureProvider, binding string, tr *api.TokenRequest) ([]interface{}, error) {
-	if err := v.verifyAuditorSignature(signatureProvider); err != nil {
-		return nil, errors.Wrapf(err, "failed to verifier auditor's signature [%s]", binding)
+func (v *Validator) VerifyTokenRequest(ledger api.Ledger, signatureProvider api.SignatureProvider, binding string, tr *api.TokenRequest, opts ...api.ValidationOption) ([]interface{}, error) {
+	validationOpts, err := api.CompileValidationOptions(opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed compiling validation options [%s]", binding)
+	}
+	report := &api.ValidationReport{}
+	// limits and format are checked before running any cryptographic check
+	if err := validationOpts.CheckRequest(tr, report); err != nil {
+		return nil, errors.Wrapf(err, "token request exceeds limits [%s]", binding)
+	}
+	if len(tr.Driver) != 0 && tr.Driver != PublicParameters {
+		return nil, report.Failed(api.RequestActionType, 0, api.FormatCheck, errors.Errorf("token request of driver [%s], expected [%s] [%s]", tr.Driver, PublicParameters, binding))
 	}
-	ia, err := v.unmarshalIssueActions(tr.Issues)
+	if len(tr.Migrations) != 0 {
+		return nil, report.Failed(api.MigrationActionType, 0, api.FormatCheck, errors.Errorf("migrations to fabtoken are not supported [%s]", binding))
+	}
+	ia, err := v.unmarshalIssueActions(tr.Issues, validationOpts, report)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to retrieve issue actions [%s]", binding)
 	}
-	ta, err := v.unmarshalTransferActions(tr.Transfers)
+	ta, err := v.unmarshalTransferActions(tr.Transfers, validationOpts, report)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to retrieve transfer actions [%s]", binding)
 	}
-	err = v.verifyIssues(ia, signatureProvider)
+	ra, err := api.UnmarshalRebindings(tr.Rebindings, report)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to retrieve rebinding actions [%s]", binding)
+	}
+	if err := v.verifyAuditorSignatures(ledger, signatureProvider, tr, ia, ta, ra, report); err != nil {
+		return nil, errors.Wrapf(err, "failed to verifier auditor's signature [%s]", binding)
+	}
+	err = v.verifyIssues(ledger, ia, signatureProvider, validationOpts, report)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to verify issuers' signatures [%s]", binding)
 	}
-	err = v.verifyTransfers(ledger, ta, signatureProvider)
+	err = v.verifyTransfers(ledger, ta, signatureProvider, validationOpts, report)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to verify senders' signatures [%s]", binding)
 	}
+	if err := api.VerifyRebindings(ledger, ra, v.pp.AuditorIdentity() != nil, v.pp, v.tokenOrganizations, v.matchRebinding, report); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify rebindings [%s]", binding)
+	}
+	if err := api.VerifyBurnReceipts(ta, tr.BurnReceipts, v.matchBurnReceipt, report); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify burn receipts [%s]", binding)
+	}
+	if err := api.VerifyRedeemIssuers(ta, tr.BurnReceipts, v.pp.RedeemRequiresIssuer(), signatureProvider, v.issuerVerifier, report); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify redeem issuers' signatures [%s]", binding)
+	}
+	if err := api.VerifySwapTerms(ta, tr.SwapTerms, v.matchOutput, report); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify swap terms [%s]", binding)
+	}
+	if err := validationOpts.RunRequestHooks(ledger, binding, tr); err != nil {
+		return nil, report.Failed(api.RequestActionType, 0, api.HookCheck, errors.WithMessagef(err, "token request rejected by validation hook [%s]", binding))
+	}
 
 	var actions []interface{}
 	for _, action := range ia {
@@ -54,26 +92,39 @@ func (v *Validator) VerifyTokenRequest(ledger api.Ledger, signatureProvider api.
 	for _, action := range ta {
 		actions = append(actions, action)
 	}
+	for _, action := range ra {
+		actions = append(actions, action)
+	}
+	for _, receipt := range tr.BurnReceipts {
+		actions = append(actions, receipt)
+	}
 
 	return actions, nil
 }
 
-func (v *Validator) VerifyTokenRequestFromRaw(getState api.GetStateFnc, binding string, raw []byte) ([]interface{}, error) {
+func (v *Validator) VerifyTokenRequestFromRaw(getState api.GetStateFnc, binding string, raw []byte, opts ...api.ValidationOption) ([]interface{}, error) {
 	if len(raw) == 0 {
 		return nil, errors.New("empty token request")
 	}
+	validationOpts, err := api.CompileValidationOptions(opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed compiling validation options [%s]", binding)
+	}
+	raw, err = validationOpts.DecompressRequest(raw, &api.ValidationReport{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed decompressing token request [%s]", binding)
+	}
+	if err := validationOpts.CheckRequestSize(raw, &api.ValidationReport{}); err != nil {
+		return nil, errors.Wrapf(err, "token request exceeds limits [%s]", binding)
+	}
 	tr := &api.TokenRequest{}
-	err := json.Unmarshal(raw, tr)
+	err = json.Unmarshal(raw, tr)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to unmarshal token request")
 	}
 
 	// Prepare message expected to be signed
-	// TODO: encapsulate this somewhere
-	req := &api.TokenRequest{}
-	req.Transfers = tr.Transfers
-	req.Issues = tr.Issues
-	bytes, err := json.Marshal(req)
+	bytes, err := tr.MarshalToSign()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to marshal signed token request"+err.Error())
 	}
@@ -81,10 +132,17 @@ func (v *Validator) VerifyTokenRequestFromRaw(getState api.GetStateFnc, binding
 	logger.Debugf("cc tx-id [%s][%s]", hash.Hashable(bytes).String(), binding)
 	signed := append(bytes, []byte(binding)...)
 	var signatures [][]byte
-	if len(v.pp.Auditor) != 0 {
-		signatures = append(signatures, tr.AuditorSignature)
+	if v.pp.AuditorIdentity() != nil || !v.pp.AuditPolicy.IsEmpty() {
+		// the auditors required by a request depend on its token types, see api.RequestAuditors
+		if len(tr.AuditorSignature) != 0 {
+			signatures = append(signatures, tr.AuditorSignature)
+		}
+		signatures = append(signatures, tr.AuditorSignatures...)
 		signatures = append(signatures, tr.Signatures...)
 	} else {
+		if len(tr.AuditorSignature) != 0 || len(tr.AuditorSignatures) != 0 {
+			return nil, errors.New("unexpected auditor signature, no auditor is set")
+		}
 		signatures = tr.Signatures
 	}
 
@@ -93,14 +151,59 @@ func (v *Validator) VerifyTokenRequestFromRaw(getState api.GetStateFnc, binding
 		message:    signed,
 		signatures: signatures,
 	}
-	return v.VerifyTokenRequest(backend, backend, binding, tr)
+	return v.VerifyTokenRequest(backend, backend, binding, tr, opts...)
+}
+
+// UnmarshalActions returns the actions of the passed serialized token request, in the order returned
+// by VerifyTokenRequestFromRaw, without verifying them
+func (v *Validator) UnmarshalActions(raw []byte) ([]interface{}, error) {
+	raw, err := api.Decompress(raw, api.DefaultMaxDecompressedSize)
+	if err != nil {
+		return nil, err
+	}
+	tr := &api.TokenRequest{}
+	if err := json.Unmarshal(raw, tr); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal token request")
+	}
+	validationOpts := &api.ValidationOptions{}
+	report := &api.ValidationReport{}
+	ia, err := v.unmarshalIssueActions(tr.Issues, validationOpts, report)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve issue actions")
+	}
+	ta, err := v.unmarshalTransferActions(tr.Transfers, validationOpts, report)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve transfer actions")
+	}
+	ra, err := api.UnmarshalRebindings(tr.Rebindings, report)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve rebinding actions")
+	}
+
+	var actions []interface{}
+	for _, action := range ia {
+		actions = append(actions, action)
+	}
+	for _, action := range ta {
+		actions = append(actions, action)
+	}
+	for _, action := range ra {
+		actions = append(actions, action)
+	}
+	for _, receipt := range tr.BurnReceipts {
+		actions = append(actions, receipt)
+	}
+	return actions, nil
 }
 
-func (v *Validator) unmarshalTransferActions(raw [][]byte) ([]api.TransferAction, error) {
+func (v *Validator) unmarshalTransferActions(raw [][]byte, validationOpts *api.ValidationOptions, report *api.ValidationReport) ([]api.TransferAction, error) {
 	res := make([]api.TransferAction, len(raw))
 	for i := 0; i < len(raw); i++ {
 		ta := &TransferAction{}
 		if err := ta.Deserialize(raw[i]); err != nil {
+			return nil, report.Failed(api.TransferActionType, i, api.FormatCheck, err)
+		}
+		if err := validationOpts.CheckTransfer(i, ta, report); err != nil {
 			return nil, err
 		}
 		res[i] = ta
@@ -108,11 +211,14 @@ func (v *Validator) unmarshalTransferActions(raw [][]byte) ([]api.TransferAction
 	return res, nil
 }
 
-func (v *Validator) unmarshalIssueActions(raw [][]byte) ([]api.IssueAction, error) {
+func (v *Validator) unmarshalIssueActions(raw [][]byte, validationOpts *api.ValidationOptions, report *api.ValidationReport) ([]api.IssueAction, error) {
 	res := make([]api.IssueAction, len(raw))
 	for i := 0; i < len(raw); i++ {
 		ia := &IssueAction{}
 		if err := ia.Deserialize(raw[i]); err != nil {
+			return nil, report.Failed(api.IssueActionType, i, api.FormatCheck, err)
+		}
+		if err := validationOpts.CheckIssue(i, ia, report); err != nil {
 			return nil, err
 		}
 		res[i] = ia
@@ -120,87 +226,475 @@ func (v *Validator) unmarshalIssueActions(raw [][]byte) ([]api.IssueAction, erro
 	return res, nil
 }
 
-func (v *Validator) verifyAuditorSignature(signatureProvider api.SignatureProvider) error {
-	if v.pp.Auditor != nil {
-		identityDeserializer := &fabric.MSPX509IdentityDeserializer{}
-		verifier, err := identityDeserializer.GetVerifier(v.pp.Auditor)
+// verifyAuditorSignatures checks that the request has been signed by the auditors of the token types of its outputs
+// and of the inputs it spends, read from the passed ledger. The outputs of a transfer are not bound to the type of its
+// inputs, a transfer with no outputs or of another type must still be signed by the auditors of what it spends.
+func (v *Validator) verifyAuditorSignatures(ledger api.Ledger, signatureProvider api.SignatureProvider, tr *api.TokenRequest, issues []api.IssueAction, transfers []api.TransferAction, rebindings []*api.RebindingAction, report *api.ValidationReport) error {
+	spent, err := v.spentTokens(ledger, transfers, report)
+	if err != nil {
+		return err
+	}
+	var types []string
+	for _, tok := range spent {
+		types = append(types, tok.Type)
+	}
+	for _, issue := range issues {
+		for _, output := range issue.(*IssueAction).Outputs {
+			if output != nil && output.Output != nil {
+				types = append(types, output.Output.Type)
+			}
+		}
+	}
+	for _, transfer := range transfers {
+		for _, output := range transfer.(*TransferAction).Outputs {
+			if output != nil && output.Output != nil {
+				types = append(types, output.Output.Type)
+			}
+		}
+	}
+	for i, rebinding := range rebindings {
+		for j, raw := range rebinding.Outputs {
+			output := &Token{}
+			if err := output.Deserialize(raw); err != nil {
+				return report.Failed(api.RebindingActionType, i, api.FormatCheck, errors.Wrapf(err, "failed to deserialize rebound token"), j)
+			}
+			types = append(types, output.Type)
+		}
+	}
+	auditors := api.RequestAuditors(v.pp, types)
+	if len(auditors) != 0 && len(tr.AuditorSignature) == 0 && len(tr.AuditorSignatures) == 0 && v.pp.AuditThreshold() != 0 {
+		// a request without auditor signatures must be below the audit threshold
+		if err := v.verifyUnaudited(tr, transfers, spent); err != nil {
+			return report.Failed(api.AuditorActionType, 0, api.ThresholdCheck, err)
+		}
+		return nil
+	}
+	if len(tr.AuditorSignatures) != 0 && len(tr.AuditorSignatures)+1 != len(auditors) {
+		return report.Failed(api.AuditorActionType, 0, api.SignatureCheck, errors.Errorf("expected [%d] auditor signatures, got [%d]", len(auditors), len(tr.AuditorSignatures)+1))
+	}
+
+	identityDeserializer := &fabric.MSPX509IdentityDeserializer{}
+	for i, auditor := range auditors {
+		verifier, err := identityDeserializer.GetVerifier(auditor)
 		if err != nil {
 			return errors.Errorf("failed to deserialize auditor's public key")
 		}
 
-		return signatureProvider.HasBeenSignedBy(v.pp.Auditor, verifier)
+		if err := signatureProvider.HasBeenSignedBy(auditor, verifier); err != nil {
+			return report.Failed(api.AuditorActionType, i, api.SignatureCheck, err)
+		}
+		report.Succeeded(api.AuditorActionType, i)
 	}
 	return nil
 }
 
-func (v *Validator) verifyIssues(issues []api.IssueAction, signatureProvider api.SignatureProvider) error {
-	for _, issue := range issues {
+// spentTokens returns the inputs spent by the passed transfers, as stored on the passed ledger.
+// It fails as verifyTransfers does on the inputs that are not on the ledger.
+func (v *Validator) spentTokens(ledger api.Ledger, transfers []api.TransferAction, report *api.ValidationReport) ([]*Token, error) {
+	var spent []*Token
+	for i, t := range transfers {
+		inputs, err := t.GetInputs()
+		if err != nil {
+			return nil, report.Failed(api.TransferActionType, i, api.FormatCheck, errors.Wrapf(err, "failed to retrieve input IDs"))
+		}
+		for j, in := range inputs {
+			bytes, err := ledger.GetState(in)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to retrieve input to spend [%s]", in)
+			}
+			if len(bytes) == 0 {
+				return nil, report.Failed(api.TransferActionType, i, api.DoubleSpendCheck, errors.Errorf("input to spend [%s] does not exists", in), j)
+			}
+			tok := &Token{}
+			if err := tok.Deserialize(bytes); err != nil {
+				return nil, report.Failed(api.TransferActionType, i, api.FormatCheck, errors.Wrapf(err, "failed to deserialize input to spend [%s]", in), j)
+			}
+			spent = append(spent, tok)
+		}
+	}
+	return spent, nil
+}
+
+// verifyUnaudited checks that the passed request can skip the auditors: it is made of transfers only and the totals
+// of their outputs and of the passed spent inputs are below the audit threshold. The inputs are bounded too, the
+// outputs of a transfer do not have to balance its inputs.
+func (v *Validator) verifyUnaudited(tr *api.TokenRequest, transfers []api.TransferAction, spent []*Token) error {
+	if err := api.CheckUnaudited(tr, v.pp.AuditThreshold()); err != nil {
+		return err
+	}
+	threshold := new(big.Int).SetUint64(v.pp.AuditThreshold())
+	total := big.NewInt(0)
+	for _, transfer := range transfers {
+		for j, output := range transfer.(*TransferAction).Outputs {
+			if output == nil || output.Output == nil {
+				continue
+			}
+			q, err := token2.ToQuantity(output.Output.Quantity, keys.Precision)
+			if err != nil {
+				return errors.Wrapf(err, "invalid quantity of output [%d]", j)
+			}
+			total.Add(total, q.ToBigInt())
+		}
+	}
+	if total.Cmp(threshold) >= 0 {
+		return errors.Errorf("total value [%s] is not below the audit threshold [%d], the auditor must sign", total, v.pp.AuditThreshold())
+	}
+	total = big.NewInt(0)
+	for j, tok := range spent {
+		q, err := token2.ToQuantity(tok.Quantity, keys.Precision)
+		if err != nil {
+			return errors.Wrapf(err, "invalid quantity of input [%d]", j)
+		}
+		total.Add(total, q.ToBigInt())
+	}
+	if total.Cmp(threshold) >= 0 {
+		return errors.Errorf("total value spent [%s] is not below the audit threshold [%d], the auditor must sign", total, v.pp.AuditThreshold())
+	}
+	return nil
+}
+
+func (v *Validator) verifyIssues(ledger api.Ledger, issues []api.IssueAction, signatureProvider api.SignatureProvider, opts *api.ValidationOptions, report *api.ValidationReport) error {
+	for i, issue := range issues {
 		a := issue.(*IssueAction)
 
-		if err := v.verifyIssue(a); err != nil {
-			return errors.Wrapf(err, "failed to verify issue action")
+		if err := v.verifyIssue(a, opts.TxTime); err != nil {
+			return report.Failed(api.IssueActionType, i, api.ExpirationCheck, errors.Wrapf(err, "failed to verify issue action"))
 		}
 
 		identityDeserializer := &fabric.MSPX509IdentityDeserializer{}
 		verifier, err := identityDeserializer.GetVerifier(a.Issuer)
 		if err != nil {
-			return errors.Wrapf(err, "failed getting verifier for [%s]", view.Identity(a.Issuer).String())
+			return report.Failed(api.IssueActionType, i, api.SignatureCheck, errors.Wrapf(err, "failed getting verifier for [%s]", view.Identity(a.Issuer).String()))
 		}
 		if err := signatureProvider.HasBeenSignedBy(a.Issuer, verifier); err != nil {
-			return errors.Wrapf(err, "failed verifying signature")
+			return report.Failed(api.IssueActionType, i, api.SignatureCheck, errors.Wrapf(err, "failed verifying signature"))
+		}
+		if err := v.verifyIssueApproval(a, signatureProvider); err != nil {
+			return report.Failed(api.IssueActionType, i, api.SignatureCheck, err)
 		}
+		if err := opts.RunIssueHooks(ledger, i, issue); err != nil {
+			return report.Failed(api.IssueActionType, i, api.HookCheck, errors.WithMessagef(err, "issue action rejected by validation hook"))
+		}
+		report.Succeeded(api.IssueActionType, i)
 	}
 	return nil
 }
 
-func (v *Validator) verifyTransfers(ledger api.Ledger, transferActions []api.TransferAction, signatureProvider api.SignatureProvider) error {
+// verifyIssueApproval checks the co-signature of the approver that the issue policy assigns to the type of the issued tokens
+func (v *Validator) verifyIssueApproval(issue *IssueAction, signatureProvider api.SignatureProvider) error {
+	if len(issue.Outputs) == 0 {
+		return nil
+	}
+	approver := v.pp.IssueApprover(issue.Outputs[0].Output.Type)
+	for _, output := range issue.Outputs[1:] {
+		if !approver.Equal(v.pp.IssueApprover(output.Output.Type)) {
+			return errors.Errorf("outputs of type [%s] and [%s] require different approvers", issue.Outputs[0].Output.Type, output.Output.Type)
+		}
+	}
+	if approver.IsNone() {
+		return nil
+	}
 	identityDeserializer := &fabric.MSPX509IdentityDeserializer{}
+	verifier, err := identityDeserializer.GetVerifier(approver)
+	if err != nil {
+		return errors.Wrapf(err, "failed getting verifier for approver [%s]", approver)
+	}
+	if err := signatureProvider.HasBeenSignedBy(approver, verifier); err != nil {
+		return errors.Wrapf(err, "failed verifying approver's signature")
+	}
+	return nil
+}
+
+func (v *Validator) verifyTransfers(ledger api.Ledger, transferActions []api.TransferAction, signatureProvider api.SignatureProvider, opts *api.ValidationOptions, report *api.ValidationReport) error {
+	identityDeserializer := &fabric.MSPX509IdentityDeserializer{}
+	receiverAttributes, err := v.receiverAttributes(identityDeserializer)
+	if err != nil {
+		return err
+	}
 	logger.Debugf("check sender start...")
 	defer logger.Debugf("check sender finished.")
 	for i, t := range transferActions {
-		var inputTokens [][]byte
+		action := t.(*TransferAction)
+		var inputTokens []*Token
 		inputs, err := t.GetInputs()
 		if err != nil {
-			return errors.Wrapf(err, "failed to retrieve input IDs")
+			return report.Failed(api.TransferActionType, i, api.FormatCheck, errors.Wrapf(err, "failed to retrieve input IDs"))
 		}
-		for _, in := range inputs {
+		for j, in := range inputs {
 			logger.Debugf("load token [%d][%s]", i, in)
 			bytes, err := ledger.GetState(in)
 			if err != nil {
 				return errors.Wrapf(err, "failed to retrieve input to spend [%s]", in)
 			}
 			if len(bytes) == 0 {
-				return errors.Errorf("finput to spend [%s] does not exists", in)
+				return report.Failed(api.TransferActionType, i, api.DoubleSpendCheck, errors.Errorf("finput to spend [%s] does not exists", in), j)
 			}
-			inputTokens = append(inputTokens, bytes)
-			tok := &token2.Token{}
-			err = json.Unmarshal(bytes, tok)
-			if err != nil {
-				return errors.Wrapf(err, "failed to deserialize input to spend [%s]", in)
+			tok := &Token{}
+			if err := tok.Deserialize(bytes); err != nil {
+				return report.Failed(api.TransferActionType, i, api.FormatCheck, errors.Wrapf(err, "failed to deserialize input to spend [%s]", in), j)
+			}
+			inputTokens = append(inputTokens, tok)
+
+			// the owner signs for the token unless the token is expired and the issuer reclaims it
+			signer := view.Identity(tok.Owner.Raw)
+			if tok.HasExpiration() {
+				if opts.TxTime.IsZero() {
+					return report.Failed(api.TransferActionType, i, api.ExpirationCheck, errors.Errorf("cannot spend input with expiration [%s], transaction time not available", in), j)
+				}
+				expired := tok.IsExpiredAt(opts.TxTime)
+				switch {
+				case action.Reclaim && !expired:
+					return report.Failed(api.TransferActionType, i, api.ExpirationCheck, errors.Errorf("input [%s] is not expired yet, it cannot be reclaimed", in), j)
+				case !action.Reclaim && expired:
+					return report.Failed(api.TransferActionType, i, api.ExpirationCheck, errors.Errorf("input [%s] is expired, it can only be reclaimed by its issuer", in), j)
+				}
+				if action.Reclaim {
+					signer = tok.Issuer
+				}
+			} else if action.Reclaim {
+				return report.Failed(api.TransferActionType, i, api.ExpirationCheck, errors.Errorf("input [%s] has no expiration, it cannot be reclaimed", in), j)
+			}
+			if !action.Reclaim {
+				lock, err := api.GetTimeLock(signer)
+				if err != nil {
+					return report.Failed(api.TransferActionType, i, api.FormatCheck, errors.Wrapf(err, "invalid owner of input [%s]", in), j)
+				}
+				if lock != nil {
+					if err := lock.Check(opts.TxTime, opts.Height); err != nil {
+						return report.Failed(api.TransferActionType, i, api.TimeLockCheck, errors.Wrapf(err, "input [%s] cannot be spent yet", in), j)
+					}
+					signer = lock.Owner
+				}
 			}
-			logger.Debugf("check sender [%d][%s]", i, view.Identity(tok.Owner.Raw).UniqueID())
+			logger.Debugf("check sender [%d][%s]", i, signer.UniqueID())
 
-			verifier, err := identityDeserializer.GetVerifier(tok.Owner.Raw)
+			verifier, err := identityDeserializer.GetVerifier(signer)
 			if err != nil {
-				return errors.Wrapf(err, "failed deserializing owner [%d][%s][%s]", i, in, view.Identity(tok.Owner.Raw).UniqueID())
+				return report.Failed(api.TransferActionType, i, api.SignatureCheck, errors.Wrapf(err, "failed deserializing signer [%d][%s][%s]", i, in, signer.UniqueID()), j)
+			}
+			logger.Debugf("signature verification [%d][%s][%s]", i, in, signer.UniqueID())
+			if err := signatureProvider.HasBeenSignedBy(signer, verifier); err != nil {
+				return report.Failed(api.TransferActionType, i, api.SignatureCheck, errors.Wrapf(err, "failed signature verification [%d][%s][%s]", i, in, signer.UniqueID()), j)
+			}
+		}
+		if err := v.verifyTransfer(inputTokens, action); err != nil {
+			return report.Failed(api.TransferActionType, i, api.ExpirationCheck, errors.Wrapf(err, "failed to verify transfer action"))
+		}
+		for j, output := range action.Outputs {
+			if output.Output == nil || output.Output.Owner == nil || len(output.Output.Owner.Raw) == 0 {
+				continue
+			}
+			if err := v.pp.ReceiverPolicy.CheckReceiver(output.Output.Owner.Raw, receiverAttributes); err != nil {
+				return report.Failed(api.TransferActionType, i, api.ReceiverCheck, errors.WithMessagef(err, "receiver of output [%d] not accepted", j), j)
 			}
-			logger.Debugf("signature verification [%d][%s][%s]", i, in, view.Identity(tok.Owner.Raw).UniqueID())
-			if err := signatureProvider.HasBeenSignedBy(tok.Owner.Raw, verifier); err != nil {
-				return errors.Wrapf(err, "failed signature verification [%d][%s][%s]", i, in, view.Identity(tok.Owner.Raw).UniqueID())
+		}
+		if err := opts.RunTransferHooks(ledger, i, t); err != nil {
+			return report.Failed(api.TransferActionType, i, api.HookCheck, errors.WithMessagef(err, "transfer action rejected by validation hook"))
+		}
+		report.Succeeded(api.TransferActionType, i)
+	}
+	return nil
+}
+
+// receiverAttributes returns the function extracting the organizational units of the receivers, verified against
+// the certification authorities of the receiver policy, nil if there is no policy
+func (v *Validator) receiverAttributes(identityDeserializer *fabric.MSPX509IdentityDeserializer) (func(view.Identity) ([]string, error), error) {
+	if v.pp.ReceiverPolicy == nil {
+		return nil, nil
+	}
+	roots, err := fabric.NewCertPool(v.pp.ReceiverPolicy.RootCerts)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid root certificates of the receiver policy")
+	}
+	intermediates, err := fabric.NewCertPool(v.pp.ReceiverPolicy.IntermediateCerts)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid intermediate certificates of the receiver policy")
+	}
+	return func(id view.Identity) ([]string, error) {
+		return identityDeserializer.GetOrganizationalUnits(id, roots, intermediates)
+	}, nil
+}
+
+// VerifyMigrationInputs checks that the inputs of the passed migration action are unspent tokens without expiration
+// and that their owners signed the token request. It returns the inputs in the clear.
+func (v *Validator) VerifyMigrationInputs(ledger api.Ledger, signatureProvider api.SignatureProvider, index int, action *api.MigrationAction, report *api.ValidationReport) ([]*token2.Token, error) {
+	identityDeserializer := &fabric.MSPX509IdentityDeserializer{}
+	res := make([]*token2.Token, len(action.Inputs))
+	for j, in := range action.Inputs {
+		bytes, err := ledger.GetState(in)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to retrieve input to migrate [%s]", in)
+		}
+		if len(bytes) == 0 {
+			return nil, report.Failed(api.MigrationActionType, index, api.DoubleSpendCheck, errors.Errorf("input to migrate [%s] does not exists", in), j)
+		}
+		tok := &Token{}
+		if err := tok.Deserialize(bytes); err != nil {
+			return nil, report.Failed(api.MigrationActionType, index, api.FormatCheck, errors.Wrapf(err, "failed to deserialize input to migrate [%s]", in), j)
+		}
+		if tok.HasExpiration() {
+			return nil, report.Failed(api.MigrationActionType, index, api.ExpirationCheck, errors.Errorf("input [%s] has an expiration, it cannot be migrated", in), j)
+		}
+		if api.IsTimeLock(tok.Owner.Raw) {
+			return nil, report.Failed(api.MigrationActionType, index, api.TimeLockCheck, errors.Errorf("input [%s] is time locked, it cannot be migrated", in), j)
+		}
+
+		signer := view.Identity(tok.Owner.Raw)
+		verifier, err := identityDeserializer.GetVerifier(signer)
+		if err != nil {
+			return nil, report.Failed(api.MigrationActionType, index, api.SignatureCheck, errors.Wrapf(err, "failed deserializing owner [%s][%s]", in, signer.UniqueID()), j)
+		}
+		if err := signatureProvider.HasBeenSignedBy(signer, verifier); err != nil {
+			return nil, report.Failed(api.MigrationActionType, index, api.SignatureCheck, errors.Wrapf(err, "failed signature verification [%s][%s]", in, signer.UniqueID()), j)
+		}
+		res[j] = &tok.Token
+	}
+	return res, nil
+}
+
+// tokenOrganizations returns the MSP ID of the owner of the passed serialized token
+func (v *Validator) tokenOrganizations(raw []byte) ([]string, error) {
+	tok := &Token{}
+	if err := tok.Deserialize(raw); err != nil {
+		return nil, errors.Wrap(err, "failed to deserialize token")
+	}
+	if tok.Owner == nil || len(tok.Owner.Raw) == 0 {
+		return nil, errors.New("token without owner")
+	}
+	mspID, err := fabric.GetMSPID(tok.Owner.Raw)
+	if err != nil {
+		return nil, err
+	}
+	return []string{mspID}, nil
+}
+
+// matchRebinding checks that the passed output is the passed input bound to a different owner.
+// Time locked inputs cannot be rebound, the rebinding would lift the lock.
+// The tokens are in the clear, no proof is expected.
+func (v *Validator) matchRebinding(input, output, proof []byte) error {
+	if len(proof) != 0 {
+		return errors.New("unexpected rebinding proof")
+	}
+	in := &Token{}
+	if err := in.Deserialize(input); err != nil {
+		return errors.Wrap(err, "failed to deserialize input")
+	}
+	if in.Owner != nil && api.IsTimeLock(in.Owner.Raw) {
+		return errors.New("time locked tokens cannot be rebound")
+	}
+	out := &Token{}
+	if err := out.Deserialize(output); err != nil {
+		return errors.Wrap(err, "failed to deserialize output")
+	}
+	if out.Owner == nil || len(out.Owner.Raw) == 0 {
+		return errors.New("rebound token without owner")
+	}
+	if in.Owner != nil && bytes.Equal(in.Owner.Raw, out.Owner.Raw) {
+		return errors.New("rebound token with the same owner")
+	}
+	out.Owner = in.Owner
+	expected, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	actual, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(expected, actual) {
+		return errors.New("rebound token differs from the input in more than the owner")
+	}
+	return nil
+}
+
+// verifyIssue checks that the outputs of the passed issue carry the same expiration and, if they have one,
+// the issuer of the action as issuer, and that they are not expired at the passed transaction time
+func (v *Validator) verifyIssue(issue *IssueAction, txTime time.Time) error {
+	if len(issue.Outputs) == 0 {
+		return nil
+	}
+	expiration := issue.Outputs[0].Expiration
+	for i, output := range issue.Outputs {
+		if output.Expiration != expiration {
+			return errors.Errorf("output [%d] has a different expiration", i)
+		}
+		if expiration == 0 {
+			if len(output.Issuer) != 0 {
+				return errors.Errorf("output [%d] has an issuer but no expiration", i)
 			}
+			continue
 		}
-		if err := v.verifyTransfer(inputTokens, t); err != nil {
-			return errors.Wrapf(err, "failed to verify transfer action")
+		if !issue.Issuer.Equal(output.Issuer) {
+			return errors.Errorf("output [%d] does not carry the issuer of the action", i)
 		}
 	}
+	if expiration == 0 {
+		return nil
+	}
+	if expiration < 0 {
+		return errors.Errorf("invalid expiration [%d]", expiration)
+	}
+	if txTime.IsZero() {
+		return errors.New("cannot issue tokens with expiration, transaction time not available")
+	}
+	if (&Token{Expiration: expiration}).IsExpiredAt(txTime) {
+		return errors.Errorf("tokens expired at [%s] before their issuance", time.Unix(expiration, 0))
+	}
 	return nil
 }
 
-func (v *Validator) verifyIssue(issue api.IssueAction) error {
+func (v *Validator) verifyTransfer(inputTokens []*Token, tr *TransferAction) error {
+	if len(inputTokens) == 0 {
+		return nil
+	}
+	// outputs of a reclaim carry no expiration, otherwise they inherit the expiration of the inputs
+	var expiration int64
+	var issuer view.Identity
+	if !tr.Reclaim {
+		expiration = inputTokens[0].Expiration
+		issuer = inputTokens[0].Issuer
+	}
+	for i, tok := range inputTokens {
+		if !tr.Reclaim && (tok.Expiration != expiration || !issuer.Equal(tok.Issuer)) {
+			return errors.Errorf("input [%d] has a different expiration", i)
+		}
+	}
+	for i, output := range tr.Outputs {
+		if output.Expiration != expiration || !issuer.Equal(output.Issuer) {
+			return errors.Errorf("output [%d] does not carry the expected expiration [%d]", i, expiration)
+		}
+	}
 	return nil
 }
 
-func (v *Validator) verifyTransfer(inputTokens [][]byte, tr api.TransferAction) error {
+// issuerVerifier returns the verifier of the passed issuer, co-signing a redemption.
+// The chain of the certificate of the issuer is not checked, the issuer must be listed in the issuer policy
+// of the redeemed type, see translator.RedeemIssuerValidator.
+func (v *Validator) issuerVerifier(issuer view.Identity) (api.Verifier, error) {
+	return (&fabric.MSPX509IdentityDeserializer{}).GetVerifier(issuer)
+}
+
+func (v *Validator) matchBurnReceipt(output api.Output, receipt *api.BurnReceipt) error {
+	return v.matchOutput(output, receipt.Type, receipt.Quantity, receipt.TokenInfo)
+}
+
+// matchOutput checks that the passed output carries the passed type and quantity, outputs are in the clear
+func (v *Validator) matchOutput(output api.Output, typ string, quantity string, _ []byte) error {
+	out := output.(*TransferOutput).Output
+	if out.Type != typ {
+		return errors.Errorf("type [%s] does not match [%s]", typ, out.Type)
+	}
+	q, err := token2.ToQuantity(out.Quantity, keys.Precision)
+	if err != nil {
+		return errors.Wrapf(err, "invalid output quantity [%s]", out.Quantity)
+	}
+	rq, err := token2.ToQuantity(quantity, keys.Precision)
+	if err != nil {
+		return errors.Wrapf(err, "invalid quantity [%s]", quantity)
+	}
+	if q.Cmp(rq) != 0 {
+		return errors.Errorf("quantity [%s] does not match [%s]", quantity, out.Quantity)
+	}
 	return nil
 }
 
@@ -224,3 +718,30 @@ func (b *backend) HasBeenSignedBy(id view.Identity, verifier api.Verifier) error
 func (b *backend) GetState(key string) ([]byte, error) {
 	return b.getState(key)
 }
+
+// VerifyOwnership checks that the passed proof has been signed by the owner of the token it carries
+func (v *Validator) VerifyOwnership(proof *api.OwnershipProof) error {
+	tok := &Token{}
+	if err := tok.Deserialize(proof.Output); err != nil {
+		return errors.Wrapf(err, "failed to deserialize token [%s]", proof.ID)
+	}
+	if tok.Owner == nil || len(tok.Owner.Raw) == 0 {
+		return errors.Errorf("token [%s] has been redeemed", proof.ID)
+	}
+	owner, err := api.SpenderOf(tok.Owner.Raw)
+	if err != nil {
+		return errors.Wrapf(err, "invalid owner of [%s]", proof.ID)
+	}
+	verifier, err := (&fabric.MSPX509IdentityDeserializer{}).GetVerifier(owner)
+	if err != nil {
+		return errors.Wrapf(err, "failed deserializing owner of [%s]", proof.ID)
+	}
+	msg, err := proof.MessageToSign()
+	if err != nil {
+		return err
+	}
+	if err := verifier.Verify(msg, proof.Signature); err != nil {
+		return errors.Wrapf(err, "invalid ownership proof for [%s]", proof.ID)
+	}
+	return nil
+}
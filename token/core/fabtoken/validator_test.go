@@ -0,0 +1,260 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package fabtoken_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/fabric"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// signedBy accepts the signatures of any identity
+type signedBy struct{}
+
+func (signedBy) HasBeenSignedBy(view.Identity, api.Verifier) error {
+	return nil
+}
+
+// emptyLedger holds no state
+type emptyLedger struct{}
+
+func (emptyLedger) GetState(string) ([]byte, error) {
+	return nil, nil
+}
+
+func newIdentity(t *testing.T, name string) view.Identity {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	id, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   "Org1MSP",
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}),
+	})
+	assert.NoError(t, err)
+	return id
+}
+
+func issueRequest(t *testing.T, issuer view.Identity, outputs ...*fabtoken.TransferOutput) *api.TokenRequest {
+	raw, err := (&fabtoken.IssueAction{Issuer: issuer, Outputs: outputs}).Serialize()
+	assert.NoError(t, err)
+	return &api.TokenRequest{Issues: [][]byte{raw}}
+}
+
+func output(owner view.Identity, expiration int64, issuer view.Identity) *fabtoken.TransferOutput {
+	return &fabtoken.TransferOutput{
+		Output: &token2.Token{
+			Owner:    &token2.Owner{Raw: owner},
+			Type:     "ABC",
+			Quantity: token2.NewQuantityFromUInt64(10).Hex(),
+		},
+		Expiration: expiration,
+		Issuer:     issuer,
+	}
+}
+
+func TestVerifyIssueExpiration(t *testing.T) {
+	pp, err := fabtoken.Setup()
+	assert.NoError(t, err)
+	v := fabtoken.NewValidator(pp)
+	issuer := newIdentity(t, "issuer")
+	alice := newIdentity(t, "alice")
+	now := time.Now()
+	future := now.Add(time.Hour).Unix()
+
+	verify := func(tr *api.TokenRequest, opts ...api.ValidationOption) error {
+		_, err := v.VerifyTokenRequest(emptyLedger{}, signedBy{}, "tx1", tr, opts...)
+		return err
+	}
+	assertExpirationFailure := func(err error) {
+		assert.Error(t, err)
+		report, ok := api.GetValidationReport(err)
+		assert.True(t, ok)
+		assert.Equal(t, api.ExpirationCheck, report.Failure().Check)
+	}
+
+	// tokens without expiration
+	assert.NoError(t, verify(issueRequest(t, issuer, output(alice, 0, nil))))
+	assertExpirationFailure(verify(issueRequest(t, issuer, output(alice, 0, issuer))))
+
+	// tokens expiring after the transaction time
+	assert.NoError(t, verify(issueRequest(t, issuer, output(alice, future, issuer), output(alice, future, issuer)), api.WithTxTime(now)))
+	// the transaction time is needed to check the expiration
+	assertExpirationFailure(verify(issueRequest(t, issuer, output(alice, future, issuer))))
+	// the outputs must carry the same expiration
+	assertExpirationFailure(verify(issueRequest(t, issuer, output(alice, future, issuer), output(alice, future+1, issuer)), api.WithTxTime(now)))
+	assertExpirationFailure(verify(issueRequest(t, issuer, output(alice, future, issuer), output(alice, 0, nil)), api.WithTxTime(now)))
+	// and the issuer of the action
+	assertExpirationFailure(verify(issueRequest(t, issuer, output(alice, future, alice)), api.WithTxTime(now)))
+	assertExpirationFailure(verify(issueRequest(t, issuer, output(alice, future, nil)), api.WithTxTime(now)))
+
+	// tokens already expired at the transaction time
+	assertExpirationFailure(verify(issueRequest(t, issuer, output(alice, now.Add(-time.Hour).Unix(), issuer)), api.WithTxTime(now)))
+	assertExpirationFailure(verify(issueRequest(t, issuer, output(alice, -1, issuer)), api.WithTxTime(now)))
+}
+
+func TestSetIssueApprover(t *testing.T) {
+	pp, err := fabtoken.Setup()
+	assert.NoError(t, err)
+	ppm := fabtoken.NewPublicParamsManager(pp)
+
+	_, err = ppm.SetIssueApprover("ABC", []byte("not an identity"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to retrieve issue approver's identity")
+	assert.Nil(t, ppm.PublicParameters().IssueApprover("ABC"))
+
+	approver := newIdentity(t, "approver")
+	_, err = ppm.SetIssueApprover("ABC", approver)
+	assert.NoError(t, err)
+	assert.Equal(t, approver, ppm.PublicParameters().IssueApprover("ABC"))
+
+	// an empty approver removes it
+	_, err = ppm.SetIssueApprover("ABC", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, ppm.PublicParameters().IssueApprover("ABC"))
+}
+
+func TestGetOwnerEnrollmentID(t *testing.T) {
+	s := fabtoken.NewService(nil, nil, "", nil, nil, nil, nil)
+	alice := newIdentity(t, "alice")
+
+	eID, err := s.GetOwnerEnrollmentID(alice, []byte("alice"))
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", eID)
+
+	// the audit info must be the common name of the certificate of the owner
+	_, err = s.GetOwnerEnrollmentID(alice, []byte("bob"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "audit info does not match identity")
+
+	_, err = s.GetOwnerEnrollmentID(view.Identity("alice"), []byte("alice"))
+	assert.Error(t, err)
+}
+
+func TestVerifyAuditorSignaturesPerType(t *testing.T) {
+	pp, err := fabtoken.Setup()
+	assert.NoError(t, err)
+	cashAuditor, cashAuditorSigner, _, err := fabric.NewSigner()
+	assert.NoError(t, err)
+	bondAuditor, bondAuditorSigner, _, err := fabric.NewSigner()
+	assert.NoError(t, err)
+	alice, aliceSigner, _, err := fabric.NewSigner()
+	assert.NoError(t, err)
+	pp.Auditor = cashAuditor
+	pp.AuditPolicy = &api.AuditPolicy{}
+	pp.AuditPolicy.SetAuditor("BOND", bondAuditor)
+	pp.AuditFrom = 100
+	v := fabtoken.NewValidator(pp)
+
+	// alice owns a token of each type, of the passed value
+	ledger := map[string][]byte{}
+	getState := func(key string) ([]byte, error) {
+		return ledger[key], nil
+	}
+	transfer := func(tokenType string, quantity uint64) []byte {
+		tok := &fabtoken.Token{Token: token2.Token{Owner: &token2.Owner{Raw: alice}, Type: tokenType, Quantity: token2.NewQuantityFromUInt64(quantity).Hex()}}
+		raw, err := json.Marshal(tok)
+		assert.NoError(t, err)
+		ledger[tokenType] = raw
+		raw, err = (&fabtoken.TransferAction{Sender: alice, Inputs: []string{tokenType}, Outputs: []*fabtoken.TransferOutput{{Output: &tok.Token}}}).Serialize()
+		assert.NoError(t, err)
+		return raw
+	}
+	// verify signs the passed transfers with the passed auditors, in order, then with alice, once per transfer
+	verify := func(transfers [][]byte, auditors ...api.Signer) error {
+		tr := &api.TokenRequest{Transfers: transfers}
+		message, err := tr.MarshalToSign()
+		assert.NoError(t, err)
+		message = append(message, []byte("tx1")...)
+		for i, auditor := range auditors {
+			sigma, err := auditor.Sign(message)
+			assert.NoError(t, err)
+			if i == 0 {
+				tr.AuditorSignature = sigma
+			} else {
+				tr.AuditorSignatures = append(tr.AuditorSignatures, sigma)
+			}
+		}
+		for range transfers {
+			sigma, err := aliceSigner.Sign(message)
+			assert.NoError(t, err)
+			tr.Signatures = append(tr.Signatures, sigma)
+		}
+		raw, err := json.Marshal(tr)
+		assert.NoError(t, err)
+		_, err = v.VerifyTokenRequestFromRaw(getState, "tx1", raw)
+		return err
+	}
+	assertFailure := func(err error, check api.ValidationCheck) {
+		assert.Error(t, err)
+		report, ok := api.GetValidationReport(err)
+		assert.True(t, ok)
+		assert.Equal(t, check, report.Failure().Check)
+	}
+
+	// below the threshold, across the types, the auditors are skipped
+	assert.NoError(t, verify([][]byte{transfer("USD", 40), transfer("BOND", 50)}))
+
+	// from the threshold on, each type is signed by its auditor, the auditors sorted by type
+	assertFailure(verify([][]byte{transfer("USD", 40), transfer("BOND", 60)}), api.ThresholdCheck)
+	assert.NoError(t, verify([][]byte{transfer("USD", 40), transfer("BOND", 60)}, bondAuditorSigner, cashAuditorSigner))
+	assertFailure(verify([][]byte{transfer("USD", 40), transfer("BOND", 60)}, cashAuditorSigner, bondAuditorSigner), api.SignatureCheck)
+	assertFailure(verify([][]byte{transfer("USD", 40), transfer("BOND", 60)}, bondAuditorSigner), api.SignatureCheck)
+
+	// the change counts, a transfer of 100 back to its sender is audited
+	assertFailure(verify([][]byte{transfer("USD", 100)}), api.ThresholdCheck)
+	assert.NoError(t, verify([][]byte{transfer("USD", 100)}, cashAuditorSigner))
+	assert.NoError(t, verify([][]byte{transfer("BOND", 100)}, bondAuditorSigner))
+	assertFailure(verify([][]byte{transfer("BOND", 100)}, cashAuditorSigner), api.SignatureCheck)
+
+	// convert spends a token of the first type into a token of the second type, or into nothing if empty
+	convert := func(from string, to string, quantity uint64) []byte {
+		tok := &fabtoken.Token{Token: token2.Token{Owner: &token2.Owner{Raw: alice}, Type: from, Quantity: token2.NewQuantityFromUInt64(quantity).Hex()}}
+		raw, err := json.Marshal(tok)
+		assert.NoError(t, err)
+		ledger[from] = raw
+		action := &fabtoken.TransferAction{Sender: alice, Inputs: []string{from}}
+		if len(to) != 0 {
+			action.Outputs = []*fabtoken.TransferOutput{{Output: &token2.Token{Owner: &token2.Owner{Raw: alice}, Type: to, Quantity: tok.Quantity}}}
+		}
+		raw, err = action.Serialize()
+		assert.NoError(t, err)
+		return raw
+	}
+
+	// the auditors of the spent types sign as well, whatever the type of the outputs
+	assertFailure(verify([][]byte{convert("BOND", "USD", 100)}, cashAuditorSigner), api.SignatureCheck)
+	assert.NoError(t, verify([][]byte{convert("BOND", "USD", 100)}, bondAuditorSigner, cashAuditorSigner))
+	assertFailure(verify([][]byte{convert("BOND", "", 100)}, cashAuditorSigner), api.SignatureCheck)
+	assert.NoError(t, verify([][]byte{convert("BOND", "", 100)}, bondAuditorSigner))
+
+	// the spent inputs are bounded by the threshold too, a transfer without outputs does not skip the auditors
+	assertFailure(verify([][]byte{convert("BOND", "", 100)}), api.ThresholdCheck)
+	assert.NoError(t, verify([][]byte{convert("BOND", "", 50)}))
+}
@@ -6,8 +6,11 @@ SPDX-License-Identifier: Apache-2.0
 package fabtoken
 
 import (
+	"time"
+
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	api2 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
 )
@@ -25,6 +28,10 @@ func (s *service) Wallet(identity view.Identity) api2.Wallet {
 }
 
 func (s *service) OwnerWalletByIdentity(identity view.Identity) api2.OwnerWallet {
+	// tokens locked by a time lock belong to the wallet of the owner of the lock
+	if spender, err := api2.SpenderOf(identity); err == nil {
+		identity = spender
+	}
 	return s.ownerWallet(identity)
 }
 
@@ -51,7 +58,7 @@ func (s *service) ownerWallet(id interface{}) api2.OwnerWallet {
 		if err != nil {
 			panic(err)
 		}
-		w := newOwnerWallet(s, idInfo.ID, id)
+		w := newOwnerWallet(s, idInfo.ID, idInfo.EnrollmentID, id)
 		s.ownerWallets = append(s.ownerWallets, w)
 		logger.Debugf("created owner wallet [%s]", walletID)
 		return w
@@ -145,13 +152,15 @@ func (s *service) CertifierWalletByIdentity(id view.Identity) api2.CertifierWall
 type ownerWallet struct {
 	tokenService *service
 	id           string
+	enrollmentID string
 	identity     view.Identity
 }
 
-func newOwnerWallet(tokenService *service, id string, identity view.Identity) *ownerWallet {
+func newOwnerWallet(tokenService *service, id, enrollmentID string, identity view.Identity) *ownerWallet {
 	return &ownerWallet{
 		tokenService: tokenService,
 		id:           id,
+		enrollmentID: enrollmentID,
 		identity:     identity,
 	}
 }
@@ -161,13 +170,22 @@ func (w *ownerWallet) ID() string {
 }
 
 func (w *ownerWallet) Contains(identity view.Identity) bool {
-	return w.identity.Equal(identity)
+	spender, err := api2.SpenderOf(identity)
+	if err != nil {
+		return false
+	}
+	return w.identity.Equal(spender)
 }
 
-func (w *ownerWallet) GetRecipientIdentity() (view.Identity, error) {
+func (w *ownerWallet) GetRecipientIdentity(opts *api2.RecipientIdentityOptions) (view.Identity, error) {
 	return w.identity, nil
 }
 
+// PseudonymPolicy returns Sticky, fabtoken owner wallets use their long-term identity as recipient identity
+func (w *ownerWallet) PseudonymPolicy() api2.PseudonymPolicy {
+	return api2.Sticky
+}
+
 func (w *ownerWallet) GetAuditInfo(id view.Identity) ([]byte, error) {
 	return w.tokenService.identityProvider.GetAuditInfo(id)
 }
@@ -176,9 +194,63 @@ func (w *ownerWallet) GetTokenMetadata(id view.Identity) ([]byte, error) {
 	return nil, nil
 }
 
+func (w *ownerWallet) EnrollmentID() string {
+	return w.enrollmentID
+}
+
+func (w *ownerWallet) ExportRecipientIdentities() ([]*api2.RecipientIdentity, error) {
+	auditInfo, err := w.GetAuditInfo(w.identity)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting audit info of wallet [%s]", w.ID())
+	}
+	return []*api2.RecipientIdentity{{Identity: w.identity, AuditInfo: auditInfo}}, nil
+}
+
+// ImportRecipientIdentities accepts only the identity of this wallet, fabtoken owner wallets
+// use their long-term identity as recipient identity, then the nodes must share it.
+func (w *ownerWallet) ImportRecipientIdentities(ids []*api2.RecipientIdentity) error {
+	for _, ri := range ids {
+		if ri == nil || !w.identity.Equal(ri.Identity) {
+			return errors2.Errorf(errors2.Unauthorized, "identity does not belong to this wallet [%s]", w.ID())
+		}
+	}
+	return nil
+}
+
+// ExportTokens is not supported, fabtoken tokens are owned by the long-term identity of the wallet,
+// they can be moved to another wallet only with a transfer.
+func (w *ownerWallet) ExportTokens(ids []*token2.Id) (*api2.TokenExport, error) {
+	return nil, errors.Errorf("token export not supported by fabtoken owner wallets")
+}
+
+// ImportTokens is not supported, see ExportTokens
+func (w *ownerWallet) ImportTokens(export *api2.TokenExport) (*api2.TokenReassignment, error) {
+	return nil, errors.Errorf("token import not supported by fabtoken owner wallets")
+}
+
+func (w *ownerWallet) TokenReassignments() ([]*api2.TokenReassignment, error) {
+	return nil, nil
+}
+
+// CanSpend returns false also for the time locked tokens whose lock has not expired yet.
+// Locks on the ledger height are enforced only by the validator.
+func (w *ownerWallet) CanSpend(identity view.Identity) bool {
+	lock, err := api2.GetTimeLock(identity)
+	if err != nil {
+		return false
+	}
+	if lock == nil {
+		return w.identity.Equal(identity)
+	}
+	if !lock.NotBefore.IsZero() && time.Now().Before(lock.NotBefore) {
+		return false
+	}
+	return w.identity.Equal(lock.Owner)
+}
+
 func (w *ownerWallet) GetSigner(identity view.Identity) (api2.Signer, error) {
-	if !w.identity.Equal(identity) {
-		return nil, errors.Errorf("identity does not belong to this wallet [%s]", identity.String())
+	if !w.Contains(identity) {
+		return nil, errors2.Errorf(errors2.Unauthorized, "identity does not belong to this wallet [%s]", identity.String())
 	}
 
 	si, err := w.tokenService.identityProvider.GetSigner(w.identity)
@@ -243,7 +315,7 @@ func (w *issuerWallet) GetIssuerIdentity(tokenType string) (view.Identity, error
 
 func (w *issuerWallet) GetSigner(identity view.Identity) (api2.Signer, error) {
 	if !w.Contains(identity) {
-		return nil, errors.Errorf("failed getting signer, the passed identity [%s] does not belong to this wallet [%s]", identity, w.ID())
+		return nil, errors2.Errorf(errors2.Unauthorized, "failed getting signer, the passed identity [%s] does not belong to this wallet [%s]", identity, w.ID())
 	}
 	si, err := w.tokenService.identityProvider.GetSigner(identity)
 	if err != nil {
@@ -307,7 +379,7 @@ func (w *auditorWallet) GetAuditorIdentity() (view.Identity, error) {
 
 func (w *auditorWallet) GetSigner(id view.Identity) (api2.Signer, error) {
 	if !w.Contains(id) {
-		return nil, errors.Errorf("identity does not belong to this wallet [%s]", id.String())
+		return nil, errors2.Errorf(errors2.Unauthorized, "identity does not belong to this wallet [%s]", id.String())
 	}
 
 	si, err := w.tokenService.identityProvider.GetSigner(w.identity)
@@ -12,6 +12,7 @@ import (
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
 )
@@ -37,6 +38,8 @@ type LocalMembership interface {
 	IsMe(id view.Identity) bool
 	GetIdentityInfoByLabel(mspType string, label string) *fabric.IdentityInfo
 	GetIdentityInfoByIdentity(mspType string, id view.Identity) *fabric.IdentityInfo
+	RegisterX509MSP(id string, path string, mspID string) error
+	RegisterIdemixMSP(id string, path string, mspID string) error
 }
 
 type Mapper struct {
@@ -72,6 +75,30 @@ func (i *Mapper) Info(id string) (string, string, identity.GetFunc) {
 	}
 }
 
+// MSPType returns the type, bccsp or idemix, of the msps the identities of this mapper belong to
+func (i *Mapper) MSPType() (string, error) {
+	switch i.mspType {
+	case X509MSPIdentity:
+		return BccspMSP, nil
+	case IdemixMSPIdentity:
+		return IdemixMSP, nil
+	default:
+		return "", errors.Errorf("type not recognized [%d]", i.mspType)
+	}
+}
+
+// Register registers, at runtime, the identity with the passed label whose msp configuration is at the passed path
+func (i *Mapper) Register(id string, mspID string, path string) error {
+	switch i.mspType {
+	case X509MSPIdentity:
+		return i.localMembership.RegisterX509MSP(id, path, mspID)
+	case IdemixMSPIdentity:
+		return i.localMembership.RegisterIdemixMSP(id, path, mspID)
+	default:
+		return errors.Errorf("msp type [%d] not supported", i.mspType)
+	}
+}
+
 func (i *Mapper) Map(v interface{}) (view.Identity, string) {
 	defaultID := i.localMembership.DefaultIdentity()
 
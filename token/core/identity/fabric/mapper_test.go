@@ -0,0 +1,25 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package fabric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapperMSPType(t *testing.T) {
+	mspType, err := NewMapper(X509MSPIdentity, nil, nil).MSPType()
+	assert.NoError(t, err)
+	assert.Equal(t, BccspMSP, mspType)
+
+	mspType, err = NewMapper(IdemixMSPIdentity, nil, nil).MSPType()
+	assert.NoError(t, err)
+	assert.Equal(t, IdemixMSP, mspType)
+
+	_, err = NewMapper(MSPType(-1), nil, nil).MSPType()
+	assert.EqualError(t, err, "type not recognized [-1]")
+}
@@ -54,6 +54,32 @@ type LocalMembership struct {
 	getIdentityInfoByIdentityReturnsOnCall map[int]struct {
 		result1 *fabricplatform.IdentityInfo
 	}
+	RegisterX509MSPStub        func(id string, path string, mspID string) error
+	registerX509MSPMutex       sync.RWMutex
+	registerX509MSPArgsForCall []struct {
+		id    string
+		path  string
+		mspID string
+	}
+	registerX509MSPReturns struct {
+		result1 error
+	}
+	registerX509MSPReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RegisterIdemixMSPStub        func(id string, path string, mspID string) error
+	registerIdemixMSPMutex       sync.RWMutex
+	registerIdemixMSPArgsForCall []struct {
+		id    string
+		path  string
+		mspID string
+	}
+	registerIdemixMSPReturns struct {
+		result1 error
+	}
+	registerIdemixMSPReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -244,6 +270,106 @@ func (fake *LocalMembership) GetIdentityInfoByIdentityReturnsOnCall(i int, resul
 	}{result1}
 }
 
+func (fake *LocalMembership) RegisterX509MSP(id string, path string, mspID string) error {
+	fake.registerX509MSPMutex.Lock()
+	ret, specificReturn := fake.registerX509MSPReturnsOnCall[len(fake.registerX509MSPArgsForCall)]
+	fake.registerX509MSPArgsForCall = append(fake.registerX509MSPArgsForCall, struct {
+		id    string
+		path  string
+		mspID string
+	}{id, path, mspID})
+	fake.recordInvocation("RegisterX509MSP", []interface{}{id, path, mspID})
+	fake.registerX509MSPMutex.Unlock()
+	if fake.RegisterX509MSPStub != nil {
+		return fake.RegisterX509MSPStub(id, path, mspID)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.registerX509MSPReturns.result1
+}
+
+func (fake *LocalMembership) RegisterX509MSPCallCount() int {
+	fake.registerX509MSPMutex.RLock()
+	defer fake.registerX509MSPMutex.RUnlock()
+	return len(fake.registerX509MSPArgsForCall)
+}
+
+func (fake *LocalMembership) RegisterX509MSPArgsForCall(i int) (string, string, string) {
+	fake.registerX509MSPMutex.RLock()
+	defer fake.registerX509MSPMutex.RUnlock()
+	return fake.registerX509MSPArgsForCall[i].id, fake.registerX509MSPArgsForCall[i].path, fake.registerX509MSPArgsForCall[i].mspID
+}
+
+func (fake *LocalMembership) RegisterX509MSPReturns(result1 error) {
+	fake.RegisterX509MSPStub = nil
+	fake.registerX509MSPReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *LocalMembership) RegisterX509MSPReturnsOnCall(i int, result1 error) {
+	fake.RegisterX509MSPStub = nil
+	if fake.registerX509MSPReturnsOnCall == nil {
+		fake.registerX509MSPReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.registerX509MSPReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *LocalMembership) RegisterIdemixMSP(id string, path string, mspID string) error {
+	fake.registerIdemixMSPMutex.Lock()
+	ret, specificReturn := fake.registerIdemixMSPReturnsOnCall[len(fake.registerIdemixMSPArgsForCall)]
+	fake.registerIdemixMSPArgsForCall = append(fake.registerIdemixMSPArgsForCall, struct {
+		id    string
+		path  string
+		mspID string
+	}{id, path, mspID})
+	fake.recordInvocation("RegisterIdemixMSP", []interface{}{id, path, mspID})
+	fake.registerIdemixMSPMutex.Unlock()
+	if fake.RegisterIdemixMSPStub != nil {
+		return fake.RegisterIdemixMSPStub(id, path, mspID)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.registerIdemixMSPReturns.result1
+}
+
+func (fake *LocalMembership) RegisterIdemixMSPCallCount() int {
+	fake.registerIdemixMSPMutex.RLock()
+	defer fake.registerIdemixMSPMutex.RUnlock()
+	return len(fake.registerIdemixMSPArgsForCall)
+}
+
+func (fake *LocalMembership) RegisterIdemixMSPArgsForCall(i int) (string, string, string) {
+	fake.registerIdemixMSPMutex.RLock()
+	defer fake.registerIdemixMSPMutex.RUnlock()
+	return fake.registerIdemixMSPArgsForCall[i].id, fake.registerIdemixMSPArgsForCall[i].path, fake.registerIdemixMSPArgsForCall[i].mspID
+}
+
+func (fake *LocalMembership) RegisterIdemixMSPReturns(result1 error) {
+	fake.RegisterIdemixMSPStub = nil
+	fake.registerIdemixMSPReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *LocalMembership) RegisterIdemixMSPReturnsOnCall(i int, result1 error) {
+	fake.RegisterIdemixMSPStub = nil
+	if fake.registerIdemixMSPReturnsOnCall == nil {
+		fake.registerIdemixMSPReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.registerIdemixMSPReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *LocalMembership) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -255,6 +381,10 @@ func (fake *LocalMembership) Invocations() map[string][][]interface{} {
 	defer fake.getIdentityInfoByLabelMutex.RUnlock()
 	fake.getIdentityInfoByIdentityMutex.RLock()
 	defer fake.getIdentityInfoByIdentityMutex.RUnlock()
+	fake.registerX509MSPMutex.RLock()
+	defer fake.registerX509MSPMutex.RUnlock()
+	fake.registerIdemixMSPMutex.RLock()
+	defer fake.registerIdemixMSPMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
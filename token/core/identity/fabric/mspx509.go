@@ -7,6 +7,9 @@ package fabric
 
 import (
 	ecdsa2 "crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/api"
@@ -34,3 +37,57 @@ func (deserializer *MSPX509IdentityDeserializer) GetVerifier(id view.Identity) (
 	}
 	return NewVerifier(publicKey), nil
 }
+
+// GetMSPID returns the identifier of the MSP of the passed serialized identity
+func GetMSPID(id view.Identity) (string, error) {
+	si := &msp.SerializedIdentity{}
+	if err := proto.Unmarshal(id, si); err != nil {
+		return "", errors.Wrap(err, "failed to unmarshal to msp.SerializedIdentity{}")
+	}
+	return si.Mspid, nil
+}
+
+// GetOrganizationalUnits returns the organizational units of the x509 certificate of the passed identity, once
+// verified that the certificate chains to one of the passed roots. As the MSPs do, the validity period of the
+// certificate is not checked, the verification does not depend on the time it runs at.
+func (deserializer *MSPX509IdentityDeserializer) GetOrganizationalUnits(id view.Identity, roots, intermediates *x509.CertPool) ([]string, error) {
+	if roots == nil {
+		return nil, errors.New("no root certificate to verify the identity against")
+	}
+	si := &msp.SerializedIdentity{}
+	err := proto.Unmarshal(id, si)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal to msp.SerializedIdentity{}")
+	}
+	block, _ := pem.Decode(si.IdBytes)
+	if block == nil {
+		return nil, errors.New("failed decoding certificate, expected PEM")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing certificate")
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   cert.NotBefore.Add(time.Second),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed verifying certificate chain")
+	}
+	return cert.Subject.OrganizationalUnit, nil
+}
+
+// NewCertPool returns a pool of the passed PEM encoded certificates, nil if none is passed
+func NewCertPool(pems [][]byte) (*x509.CertPool, error) {
+	if len(pems) == 0 {
+		return nil, nil
+	}
+	pool := x509.NewCertPool()
+	for i, raw := range pems {
+		if !pool.AppendCertsFromPEM(raw) {
+			return nil, errors.Errorf("no certificate found in [%d]", i)
+		}
+	}
+	return pool, nil
+}
@@ -7,6 +7,8 @@ package identity
 
 import (
 	"fmt"
+	"os"
+	"sync"
 
 	idemix2 "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/idemix"
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
@@ -16,6 +18,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/atrest"
 )
 
 var logger = flogging.MustGetLogger("token-sdk.driver.identity.fabric")
@@ -25,12 +28,18 @@ type GetFunc func() (view.Identity, []byte, error)
 type Mapper interface {
 	Info(id string) (string, string, GetFunc)
 	Map(v interface{}) (view.Identity, string)
+	// Register registers, at runtime, the identity with the passed label whose msp configuration is at the passed path
+	Register(id string, mspID string, path string) error
+	// MSPType returns the type of the msps the identities of this mapper belong to
+	MSPType() (string, error)
 }
 
 type Provider struct {
 	sp view2.ServiceProvider
 
 	mappers map[api.IdentityUsage]Mapper
+
+	registerLock sync.Mutex
 }
 
 func NewProvider(sp view2.ServiceProvider, mappers map[api.IdentityUsage]Mapper) *Provider {
@@ -69,6 +78,50 @@ func (i *Provider) GetIdentityInfo(usage api.IdentityUsage, id string) *api.Iden
 	}
 }
 
+// RegisterIdentity registers, at runtime, the long-term identity with the passed id, for the passed usage,
+// loading its msp configuration from the passed path. Registering again an id already known is a no-op.
+func (i *Provider) RegisterIdentity(usage api.IdentityUsage, id string, mspID string, path string) error {
+	mapper, ok := i.mappers[usage]
+	if !ok {
+		return errors.Errorf("mapper not found for usage [%d]", usage)
+	}
+	if len(id) == 0 || len(mspID) == 0 || len(path) == 0 {
+		return errors.Errorf("invalid identity [%s], msp id [%s], and path [%s]", id, mspID, path)
+	}
+	i.registerLock.Lock()
+	defer i.registerLock.Unlock()
+
+	if _, _, getIdentity := mapper.Info(id); getIdentity != nil {
+		logger.Debugf("identity [%s] already registered for usage [%d], skipping", id, usage)
+		return nil
+	}
+	if fi, err := os.Stat(path); err != nil || !fi.IsDir() {
+		return errors.Errorf("invalid msp configuration path [%s] for identity [%s]", path, id)
+	}
+	if err := mapper.Register(id, mspID, path); err != nil {
+		return errors.WithMessagef(err, "failed registering identity [%s]", id)
+	}
+	// check that the identity material is usable
+	_, _, getIdentity := mapper.Info(id)
+	if getIdentity == nil {
+		return errors.Errorf("identity [%s] not found after registration", id)
+	}
+	if _, _, err := getIdentity(); err != nil {
+		return errors.WithMessagef(err, "failed loading identity [%s]", id)
+	}
+	logger.Infof("registered identity [%s] for usage [%d] from [%s]", id, usage, path)
+	return nil
+}
+
+// MSPType returns the type of the msps the long-term identities for the passed usage belong to
+func (i *Provider) MSPType(usage api.IdentityUsage) (string, error) {
+	mapper, ok := i.mappers[usage]
+	if !ok {
+		return "", errors.Errorf("mapper not found for usage [%d]", usage)
+	}
+	return mapper.MSPType()
+}
+
 func (i *Provider) LookupIdentifier(usage api.IdentityUsage, v interface{}) (view.Identity, string) {
 	mapper, ok := i.mappers[usage]
 	if !ok {
@@ -84,7 +137,7 @@ func (i *Provider) GetAuditInfo(identity view.Identity) ([]byte, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed getting audit info for recipient identity [%s]", identity.String())
 	}
-	return auditInfo, nil
+	return OpenAuditInfo(i.sp, identity, auditInfo)
 }
 
 func (i *Provider) GetIdentityMetadata(identity view.Identity) ([]byte, error) {
@@ -108,7 +161,7 @@ func (i *Provider) RegisterRecipientIdentity(id view.Identity, auditInfo, metada
 		return err
 	}
 
-	if err := view2.GetSigService(i.sp).RegisterAuditInfo(id, auditInfo); err != nil {
+	if err := RegisterAuditInfo(i.sp, id, auditInfo); err != nil {
 		return err
 	}
 
@@ -118,10 +171,36 @@ func (i *Provider) RegisterRecipientIdentity(id view.Identity, auditInfo, metada
 }
 
 func (i *Provider) RegisterAuditInfo(id view.Identity, auditInfo []byte) error {
-	if err := view2.GetSigService(i.sp).RegisterAuditInfo(id, auditInfo); err != nil {
-		return err
+	return RegisterAuditInfo(i.sp, id, auditInfo)
+}
+
+// RegisterAuditInfo stores the passed audit info of the passed identity, sealed in the context of the identity,
+// see atrest.SealValue
+func RegisterAuditInfo(sp view2.ServiceProvider, id view.Identity, auditInfo []byte) error {
+	sealed, err := atrest.SealValue(atrest.GetSealer(sp), auditInfo, id)
+	if err != nil {
+		return errors.WithMessagef(err, "failed sealing audit info of [%s]", id)
 	}
-	return nil
+	return view2.GetSigService(sp).RegisterAuditInfo(id, sealed)
+}
+
+// OpenAuditInfo returns the passed stored audit info of the passed identity in the clear, see RegisterAuditInfo
+func OpenAuditInfo(sp view2.ServiceProvider, id view.Identity, auditInfo []byte) ([]byte, error) {
+	if len(auditInfo) == 0 {
+		return auditInfo, nil
+	}
+	opened, err := atrest.OpenValue(atrest.GetSealer(sp), auditInfo, id)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed opening audit info of [%s]", id)
+	}
+	return opened, nil
+}
+
+// RewrapAuditInfos seals again the stored audit infos under the current key encryption key, see atrest.Rewrap
+func RewrapAuditInfos(sp view2.ServiceProvider) (int, error) {
+	return atrest.Rewrap(sp, "fsc.platform.view.sig", func(context []byte, entry []byte) error {
+		return view2.GetSigService(sp).RegisterAuditInfo(context, entry)
+	})
 }
 
 func (i *Provider) GetEnrollmentID(auditInfo []byte) (string, error) {
@@ -104,3 +104,51 @@ func loadG1(wg *sync.WaitGroup, bytes []byte) {
 	}
 	wg.Done()
 }
+
+func TestZeroize(t *testing.T) {
+	rng, _ := GetRand()
+	r := RandModOrder(rng)
+	s := NewZrCopy(r)
+	assert.Equal(ConstantTimeEqual(r, s), true)
+	assert.Equal(ConstantTimeEqual(r, NewZrInt(1)), false)
+
+	Zeroize(r, nil)
+	assert.Equal(r.IsZero(), true)
+	assert.Equal(ConstantTimeEqual(r, NewZr()), true)
+	assert.Equal(ConstantTimeEqual(s, NewZr()), false)
+}
+
+func TestDRBG(t *testing.T) {
+	r := RandModOrder(NewDRBG([]byte("seed")))
+	assert.Equal(r, RandModOrder(NewDRBG([]byte("seed"))))
+	assert.Equal(r.Cmp(RandModOrder(NewDRBG([]byte("another seed")))) != 0, true)
+}
+
+func TestInPlace(t *testing.T) {
+	rng, _ := GetRand()
+	r := RandModOrder(rng)
+	s := RandModOrder(rng)
+
+	a := G1Gen()
+	g := GetG1().SetMul(a, r)
+	assert.Equal(g.Equals(a.Mul(r)), true)
+	g.AddMul(a, s)
+	assert.Equal(g.Equals(a.Mul(ModAdd(r, s, Order))), true)
+	PutG1(g, nil)
+
+	b := G2Gen()
+	h := GetG2().SetMul(b, r)
+	assert.Equal(h.Equals(b.Mul(r)), true)
+	h.AddMul(b, s)
+	assert.Equal(h.Equals(b.Mul(ModAdd(r, s, Order))), true)
+	PutG2(h)
+
+	z := GetZr().SetModMul(r, s, Order)
+	assert.Equal(z.Cmp(ModMul(r, s, Order)), 0)
+	z.SetModAdd(z, r, Order)
+	assert.Equal(z.Cmp(ModAdd(ModMul(r, s, Order), r, Order)), 0)
+	// PutZr zeroizes the scalars before returning them to the pool, they must not be read afterwards
+	Zeroize(z)
+	assert.Equal(z.IsZero(), true)
+	PutZr(z)
+}
@@ -0,0 +1,13 @@
+//go:build !hardened
+// +build !hardened
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bn256
+
+// Hardened is true in the builds with the hardened tag, see hardened.go
+const Hardened = false
@@ -33,9 +33,7 @@ func G1Gen() *G1 {
 }
 
 func (g *G1) Copy(a *G1) *G1 {
-	raw := (*bn256.G1Affine)(a).Bytes()
-	// TODO: catch error?
-	(*bn256.G1Affine)(g).SetBytes(clone(raw[:]))
+	*g = *a
 	return g
 }
 
@@ -49,11 +47,30 @@ func (g *G1) Bytes() []byte {
 }
 
 func (g *G1) Mul(a *Zr) *G1 {
-	return (*G1)((*bn256.G1Affine)(NewG1().Copy(g)).ScalarMultiplication((*bn256.G1Affine)(g), (*big.Int)(a)))
+	return NewG1().SetMul(g, a)
+}
+
+// SetMul sets g to the multiplication of the passed element by the passed scalar, and returns g
+func (g *G1) SetMul(a *G1, z *Zr) *G1 {
+	(*bn256.G1Affine)(g).ScalarMultiplication((*bn256.G1Affine)(a), (*big.Int)(z))
+	return g
+}
+
+// AddMul adds to g the multiplication of the passed element by the passed scalar, and returns g.
+// It saves the allocation of the product of g.Add(a.Mul(z)).
+func (g *G1) AddMul(a *G1, z *Zr) *G1 {
+	j := g1JacPool.Get().(*bn256.G1Jac)
+	defer g1JacPool.Put(j)
+	j.FromAffine((*bn256.G1Affine)(a))
+	j.ScalarMultiplication(j, (*big.Int)(z))
+	j.AddMixed((*bn256.G1Affine)(g))
+	(*bn256.G1Affine)(g).FromJacobian(j)
+	return g
 }
 
 func (g *G1) Add(a *G1) *G1 {
-	j := &bn256.G1Jac{}
+	j := g1JacPool.Get().(*bn256.G1Jac)
+	defer g1JacPool.Put(j)
 	j.FromAffine((*bn256.G1Affine)(g))
 	j.AddMixed((*bn256.G1Affine)(a))
 	(*bn256.G1Affine)(g).FromJacobian(j)
@@ -61,9 +78,11 @@ func (g *G1) Add(a *G1) *G1 {
 }
 
 func (g *G1) Sub(a *G1) *G1 {
-	left := &bn256.G1Jac{}
+	left := g1JacPool.Get().(*bn256.G1Jac)
+	right := g1JacPool.Get().(*bn256.G1Jac)
+	defer g1JacPool.Put(left)
+	defer g1JacPool.Put(right)
 	left.FromAffine((*bn256.G1Affine)(g))
-	right := &bn256.G1Jac{}
 	right.FromAffine((*bn256.G1Affine)(a))
 	left.SubAssign(right)
 
@@ -107,3 +126,9 @@ func clone(a []byte) []byte {
 	copy(res, a)
 	return res
 }
+
+// IsValid returns true if the point is on the curve, in the prime order subgroup, and not the point at infinity
+func (g *G1) IsValid() bool {
+	p := (*bn256.G1Affine)(g)
+	return !p.IsInfinity() && p.IsOnCurve() && p.IsInSubGroup()
+}
@@ -33,20 +33,35 @@ func G2Gen() *G2 {
 }
 
 func (g *G2) Copy(a *G2) *G2 {
-	raw := (*bn256.G2Affine)(a).Bytes()
-	// TODO: catch error?
-	(*bn256.G2Affine)(g).SetBytes(raw[:])
+	*g = *a
 	return g
 }
 
 func (g *G2) Mul(a *Zr) *G2 {
-	h := NewG2()
-	(*bn256.G2Affine)(h).ScalarMultiplication((*bn256.G2Affine)(g), (*big.Int)(a))
-	return h
+	return NewG2().SetMul(g, a)
+}
+
+// SetMul sets g to the multiplication of the passed element by the passed scalar, and returns g
+func (g *G2) SetMul(a *G2, z *Zr) *G2 {
+	(*bn256.G2Affine)(g).ScalarMultiplication((*bn256.G2Affine)(a), (*big.Int)(z))
+	return g
+}
+
+// AddMul adds to g the multiplication of the passed element by the passed scalar, and returns g.
+// It saves the allocation of the product of g.Add(a.Mul(z)).
+func (g *G2) AddMul(a *G2, z *Zr) *G2 {
+	j := g2JacPool.Get().(*bn256.G2Jac)
+	defer g2JacPool.Put(j)
+	j.FromAffine((*bn256.G2Affine)(a))
+	j.ScalarMultiplication(j, (*big.Int)(z))
+	j.AddMixed((*bn256.G2Affine)(g))
+	(*bn256.G2Affine)(g).FromJacobian(j)
+	return g
 }
 
 func (g *G2) Add(a *G2) *G2 {
-	j := &bn256.G2Jac{}
+	j := g2JacPool.Get().(*bn256.G2Jac)
+	defer g2JacPool.Put(j)
 	j.FromAffine((*bn256.G2Affine)(g))
 	j.AddMixed((*bn256.G2Affine)(a))
 	(*bn256.G2Affine)(g).FromJacobian(j)
@@ -54,9 +69,11 @@ func (g *G2) Add(a *G2) *G2 {
 }
 
 func (g *G2) Sub(a *G2) *G2 {
-	left := &bn256.G2Jac{}
+	left := g2JacPool.Get().(*bn256.G2Jac)
+	right := g2JacPool.Get().(*bn256.G2Jac)
+	defer g2JacPool.Put(left)
+	defer g2JacPool.Put(right)
 	left.FromAffine((*bn256.G2Affine)(g))
-	right := &bn256.G2Jac{}
 	right.FromAffine((*bn256.G2Affine)(a))
 	left.SubAssign(right)
 
@@ -96,3 +113,9 @@ func (g *G2) UnmarshalJSON(raw []byte) error {
 
 	return err
 }
+
+// IsValid returns true if the point is on the curve, in the prime order subgroup, and not the point at infinity
+func (g *G2) IsValid() bool {
+	p := (*bn256.G2Affine)(g)
+	return !p.IsInfinity() && p.IsOnCurve() && p.IsInSubGroup()
+}
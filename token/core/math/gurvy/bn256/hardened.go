@@ -0,0 +1,14 @@
+//go:build hardened
+// +build hardened
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bn256
+
+// Hardened is true in the builds with the hardened tag. The provers then wipe the randomness of the proofs
+// once generated, and RandModOrder never returns zero.
+const Hardened = true
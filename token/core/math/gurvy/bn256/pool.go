@@ -0,0 +1,69 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package bn256
+
+import (
+	"sync"
+
+	"github.com/consensys/gurvy/bn256"
+)
+
+// The pools reuse the transient values of the hot loops of the provers and verifiers, reducing the pressure on
+// the garbage collector. The Jacobian points are internal to the group operations, the other values are handed out
+// by the Get and Put functions below.
+var (
+	g1JacPool = sync.Pool{New: func() interface{} { return &bn256.G1Jac{} }}
+	g2JacPool = sync.Pool{New: func() interface{} { return &bn256.G2Jac{} }}
+	g1Pool    = sync.Pool{New: func() interface{} { return NewG1() }}
+	g2Pool    = sync.Pool{New: func() interface{} { return NewG2() }}
+	zrPool    = sync.Pool{New: func() interface{} { return NewZr() }}
+)
+
+// GetG1 returns an element of G1 from the pool, its value is undefined.
+// Return it with PutG1 once it is not referenced anymore.
+func GetG1() *G1 {
+	return g1Pool.Get().(*G1)
+}
+
+// PutG1 returns the passed elements to the pool, the nil ones are skipped
+func PutG1(elements ...*G1) {
+	for _, g := range elements {
+		if g != nil {
+			g1Pool.Put(g)
+		}
+	}
+}
+
+// GetG2 returns an element of G2 from the pool, its value is undefined.
+// Return it with PutG2 once it is not referenced anymore.
+func GetG2() *G2 {
+	return g2Pool.Get().(*G2)
+}
+
+// PutG2 returns the passed elements to the pool, the nil ones are skipped
+func PutG2(elements ...*G2) {
+	for _, g := range elements {
+		if g != nil {
+			g2Pool.Put(g)
+		}
+	}
+}
+
+// GetZr returns a scalar from the pool, its value is undefined.
+// Return it with PutZr once it is not referenced anymore.
+func GetZr() *Zr {
+	return zrPool.Get().(*Zr)
+}
+
+// PutZr zeroizes the passed scalars, they may hold secrets, and returns them to the pool
+func PutZr(elements ...*Zr) {
+	for _, z := range elements {
+		if z != nil {
+			Zeroize(z)
+			zrPool.Put(z)
+		}
+	}
+}
@@ -7,24 +7,120 @@ package bn256
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
 	"math/big"
+	"sync"
 
 	"github.com/consensys/gurvy/bn256/fr"
+	"github.com/pkg/errors"
 )
 
 var order = fr.Modulus()
 
 type Rand = func([]byte) (int, error)
 
-// RandModOrder returns a random element in 0, ..., GroupOrder-1
+// RandModOrder returns a random element in 0, ..., GroupOrder-1, drawn from the passed source of randomness,
+// crypto/rand if nil. It panics if the source fails. In hardened builds, zero is never returned.
 func RandModOrder(rng Rand) *Zr {
-	res := new(big.Int)
-	v := &fr.Element{}
-	v.SetRandom()
-
-	return (*Zr)(v.ToBigIntRegular(res))
+	if rng == nil {
+		rng = rand.Read
+	}
+	// twice the size of the order, the bias of the reduction is negligible
+	buf := make([]byte, 64)
+	for {
+		if _, err := io.ReadFull(reader(rng), buf); err != nil {
+			panic(errors.Wrap(err, "failed reading randomness"))
+		}
+		res := new(big.Int).SetBytes(buf)
+		res.Mod(res, order)
+		// wipe the raw randomness, the element is derived from it
+		for i := range buf {
+			buf[i] = 0
+		}
+		// a zero would cancel the blinding it is used for
+		if Hardened && res.Sign() == 0 {
+			continue
+		}
+		return (*Zr)(res)
+	}
 }
 
 func GetRand() (Rand, error) {
 	return rand.Read, nil
 }
+
+// RandOrDefault returns the passed source of randomness, the one returned by GetRand if nil
+func RandOrDefault(rng Rand) (Rand, error) {
+	if rng != nil {
+		return rng, nil
+	}
+	return GetRand()
+}
+
+// NewRandFromReader returns a source of randomness reading from the passed reader,
+// a hardware RNG or a certified DRBG for instance
+func NewRandFromReader(r io.Reader) Rand {
+	return func(b []byte) (int, error) {
+		return io.ReadFull(r, b)
+	}
+}
+
+// NewDRBG returns a deterministic source of randomness expanding the passed seed with SHA-256 in counter mode.
+// The same seed yields the same sequence, this lets tests and audits reproduce a proof.
+// The output is as unpredictable as the seed, a seed must be secret and used once.
+// The returned source is safe for concurrent use.
+func NewDRBG(seed []byte) Rand {
+	d := &drbg{seed: append([]byte(nil), seed...)}
+	return d.Read
+}
+
+// Fork returns a source of randomness seeded from the passed one, nil if the passed one is nil.
+// Concurrent tasks use forks, created in a fixed order, to draw from a deterministic source regardless of
+// the scheduling.
+func Fork(rng Rand) (Rand, error) {
+	if rng == nil {
+		return nil, nil
+	}
+	seed := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(reader(rng), seed); err != nil {
+		return nil, errors.Wrap(err, "failed reading seed")
+	}
+	return NewDRBG(seed), nil
+}
+
+type drbg struct {
+	lock    sync.Mutex
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func (d *drbg) Read(b []byte) (int, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	n := 0
+	for n < len(b) {
+		if len(d.buf) == 0 {
+			h := sha256.New()
+			h.Write(d.seed)
+			var c [8]byte
+			binary.BigEndian.PutUint64(c[:], d.counter)
+			h.Write(c[:])
+			d.buf = h.Sum(nil)
+			d.counter++
+		}
+		copied := copy(b[n:], d.buf)
+		d.buf = d.buf[copied:]
+		n += copied
+	}
+	return n, nil
+}
+
+type reader Rand
+
+func (r reader) Read(b []byte) (int, error) {
+	return r(b)
+}
@@ -0,0 +1,54 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package bn256
+
+import (
+	"crypto/subtle"
+	"math/big"
+)
+
+// scalarSize is the size in bytes of the elements reduced modulo the order
+const scalarSize = 32
+
+// Zeroize overwrites the passed elements with zero, the nil ones are skipped.
+// It wipes the witnesses and the randomness of the proofs once they are not needed anymore.
+func Zeroize(elements ...*Zr) {
+	for _, z := range elements {
+		if z == nil {
+			continue
+		}
+		b := (*big.Int)(z)
+		words := b.Bits()
+		for i := range words {
+			words[i] = 0
+		}
+		b.SetInt64(0)
+	}
+}
+
+// ConstantTimeEqual returns true if the passed elements are equal, in a time independent of their value
+// as long as they fit the size of the order
+func ConstantTimeEqual(a, b *Zr) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	size := scalarSize
+	if l := ((*big.Int)(a).BitLen() + 7) / 8; l > size {
+		size = l
+	}
+	if l := ((*big.Int)(b).BitLen() + 7) / 8; l > size {
+		size = l
+	}
+	return (*big.Int)(a).Sign() == (*big.Int)(b).Sign() && subtle.ConstantTimeCompare(pad(a, size), pad(b, size)) == 1
+}
+
+// pad returns the big-endian encoding of the absolute value of the passed element, on the passed number of bytes
+func pad(z *Zr, size int) []byte {
+	raw := (*big.Int)(z).Bytes()
+	res := make([]byte, size)
+	copy(res[size-len(raw):], raw)
+	return res
+}
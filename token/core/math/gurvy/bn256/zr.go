@@ -108,12 +108,21 @@ func ModMul(a1, b1, m *Zr) *Zr {
 	if a1 == nil || b1 == nil {
 		return NewZrInt(0)
 	}
-	a := NewZrCopy(a1)
-	b := NewZrCopy(b1)
-	a.Mod(m)
-	b.Mod(m)
-	d := (*big.Int)(a).Mul((*big.Int)(a), (*big.Int)(b))
-	return (*Zr)(d.Mod(d, (*big.Int)(m)))
+	return NewZr().SetModMul(a1, b1, m)
+}
+
+// SetModAdd sets z to a+b mod m, and returns z. z may alias a or b.
+func (z *Zr) SetModAdd(a, b, m *Zr) *Zr {
+	r := (*big.Int)(z).Add((*big.Int)(a), (*big.Int)(b))
+	r.Mod(r, (*big.Int)(m))
+	return z
+}
+
+// SetModMul sets z to a*b mod m, and returns z. z may alias a or b.
+func (z *Zr) SetModMul(a, b, m *Zr) *Zr {
+	r := (*big.Int)(z).Mul((*big.Int)(a), (*big.Int)(b))
+	r.Mod(r, (*big.Int)(m))
+	return z
 }
 
 func (z *Zr) IsZero() bool {
@@ -0,0 +1,243 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/pkg/errors"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	api2 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.core")
+
+const publicParamsCachePrefix = "token-sdk.pp"
+
+// PublicParamsStore persists the cached public parameters
+type PublicParamsStore interface {
+	Exists(id string) bool
+	Put(id string, state interface{}) error
+	Get(id string, state interface{}) error
+}
+
+// PublicParamsChangeHandler is notified when the public parameters observed for a namespace
+// do not match the pinned ones
+type PublicParamsChangeHandler func(network, channel, namespace string, pinned, observed []byte)
+
+type cachedPublicParams struct {
+	// Raw are the cached public parameters, empty if they must be fetched again
+	Raw []byte
+	// Hash is the pinned hash of the public parameters
+	Hash []byte
+}
+
+// PublicParamsCache persists the public parameters of each (network, channel, namespace), so that they are fetched
+// only once, and pins their hash. The hash is pinned the first time the public parameters are fetched,
+// unless it has been pinned in advance with Pin.
+// Public parameters not matching the pinned hash are rejected and reported to the change handlers.
+// An expected update of the public parameters must be pinned with Pin and then loaded with Refresh.
+type PublicParamsCache struct {
+	store PublicParamsStore
+
+	lock     sync.Mutex
+	fetchers map[string]api2.PublicParamsFetcher
+	handlers []PublicParamsChangeHandler
+}
+
+func NewPublicParamsCache(store PublicParamsStore) *PublicParamsCache {
+	return &PublicParamsCache{
+		store:    store,
+		fetchers: map[string]api2.PublicParamsFetcher{},
+		handlers: []PublicParamsChangeHandler{
+			func(network, channel, namespace string, pinned, observed []byte) {
+				logger.Errorf("public parameters of [%s,%s,%s] changed unexpectedly, pinned [%s], observed [%s]",
+					network, channel, namespace, hex.EncodeToString(pinned), hex.EncodeToString(observed))
+			},
+		},
+	}
+}
+
+// Fetcher returns a fetcher that serves the public parameters of the passed namespace from the cache,
+// resorting to the passed fetcher when they are not cached yet
+func (c *PublicParamsCache) Fetcher(network, channel, namespace string, fetcher api2.PublicParamsFetcher) api2.PublicParamsFetcher {
+	c.lock.Lock()
+	c.fetchers[c.key(network, channel, namespace)] = fetcher
+	c.lock.Unlock()
+	return &cachedFetcher{cache: c, network: network, channel: channel, namespace: namespace}
+}
+
+// AddChangeHandler appends a handler to be notified when the public parameters do not match the pinned ones
+func (c *PublicParamsCache) AddChangeHandler(handler PublicParamsChangeHandler) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.handlers = append(c.handlers, handler)
+}
+
+// Pin pins the passed hash for the public parameters of the passed namespace.
+// The cached public parameters, if not matching the new hash, are discarded.
+func (c *PublicParamsCache) Pin(network, channel, namespace string, hash []byte) error {
+	if len(hash) == 0 {
+		return errors.New("hash not specified")
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, err := c.load(network, channel, namespace)
+	if err != nil {
+		return err
+	}
+	if len(entry.Raw) != 0 && !bytes.Equal(HashPublicParams(entry.Raw), hash) {
+		entry.Raw = nil
+	}
+	entry.Hash = hash
+	return c.save(network, channel, namespace, entry)
+}
+
+// Pinned returns the pinned hash of the public parameters of the passed namespace, nil if none
+func (c *PublicParamsCache) Pinned(network, channel, namespace string) ([]byte, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, err := c.load(network, channel, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return entry.Hash, nil
+}
+
+// Refresh fetches again the public parameters of the passed namespace and, if they match the pinned hash,
+// replaces the cached ones
+func (c *PublicParamsCache) Refresh(network, channel, namespace string) ([]byte, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, err := c.load(network, channel, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return c.fetch(network, channel, namespace, entry)
+}
+
+// Check checks the passed public parameters, as observed for the passed namespace, for instance on the ledger,
+// against the pinned hash. If they do not match, the change handlers are notified.
+func (c *PublicParamsCache) Check(network, channel, namespace string, raw []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, err := c.load(network, channel, namespace)
+	if err != nil {
+		return err
+	}
+	return c.check(network, channel, namespace, entry, raw)
+}
+
+func (c *PublicParamsCache) get(network, channel, namespace string) ([]byte, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, err := c.load(network, channel, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if len(entry.Raw) != 0 {
+		return entry.Raw, nil
+	}
+	return c.fetch(network, channel, namespace, entry)
+}
+
+func (c *PublicParamsCache) fetch(network, channel, namespace string, entry *cachedPublicParams) ([]byte, error) {
+	fetcher, ok := c.fetchers[c.key(network, channel, namespace)]
+	if !ok {
+		return nil, errors.Errorf("no fetcher for the public parameters of [%s,%s,%s]", network, channel, namespace)
+	}
+	raw, err := fetcher.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.check(network, channel, namespace, entry, raw); err != nil {
+		return nil, err
+	}
+	logger.Debugf("caching public parameters of [%s,%s,%s], len [%d]", network, channel, namespace, len(raw))
+	if err := c.save(network, channel, namespace, &cachedPublicParams{Raw: raw, Hash: HashPublicParams(raw)}); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func (c *PublicParamsCache) check(network, channel, namespace string, entry *cachedPublicParams, raw []byte) error {
+	if len(entry.Hash) == 0 {
+		return nil
+	}
+	h := HashPublicParams(raw)
+	if bytes.Equal(entry.Hash, h) {
+		return nil
+	}
+	for _, handler := range c.handlers {
+		handler(network, channel, namespace, entry.Hash, h)
+	}
+	return errors.Errorf("public parameters of [%s,%s,%s] do not match the pinned hash [%s]", network, channel, namespace, hex.EncodeToString(entry.Hash))
+}
+
+func (c *PublicParamsCache) load(network, channel, namespace string) (*cachedPublicParams, error) {
+	entry := &cachedPublicParams{}
+	k := c.key(network, channel, namespace)
+	if !c.store.Exists(k) {
+		return entry, nil
+	}
+	if err := c.store.Get(k, entry); err != nil {
+		return nil, errors.WithMessagef(err, "failed loading cached public parameters of [%s,%s,%s]", network, channel, namespace)
+	}
+	return entry, nil
+}
+
+func (c *PublicParamsCache) save(network, channel, namespace string, entry *cachedPublicParams) error {
+	if err := c.store.Put(c.key(network, channel, namespace), entry); err != nil {
+		return errors.WithMessagef(err, "failed storing public parameters of [%s,%s,%s]", network, channel, namespace)
+	}
+	return nil
+}
+
+func (c *PublicParamsCache) key(network, channel, namespace string) string {
+	return kvs.CreateCompositeKeyOrPanic(publicParamsCachePrefix, []string{network, channel, namespace})
+}
+
+// HashPublicParams returns the hash the public parameters are pinned with
+func HashPublicParams(raw []byte) []byte {
+	h := sha256.Sum256(raw)
+	return h[:]
+}
+
+type cachedFetcher struct {
+	cache     *PublicParamsCache
+	network   string
+	channel   string
+	namespace string
+}
+
+func (f *cachedFetcher) Fetch() ([]byte, error) {
+	return f.cache.get(f.network, f.channel, f.namespace)
+}
+
+// ForceFetch fetches the public parameters again, bypassing the cache, see PublicParamsCache.Refresh
+func (f *cachedFetcher) ForceFetch() ([]byte, error) {
+	return f.cache.Refresh(f.network, f.channel, f.namespace)
+}
+
+// GetPublicParamsCache returns the public parameters cache registered in the passed service provider, nil if none
+func GetPublicParamsCache(sp view2.ServiceProvider) *PublicParamsCache {
+	s, err := sp.GetService(&PublicParamsCache{})
+	if err != nil {
+		return nil
+	}
+	return s.(*PublicParamsCache)
+}
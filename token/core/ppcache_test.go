@@ -0,0 +1,141 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	api2 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+type memoryStore map[string][]byte
+
+func (m memoryStore) Exists(id string) bool {
+	_, ok := m[id]
+	return ok
+}
+
+func (m memoryStore) Put(id string, state interface{}) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	m[id] = raw
+	return nil
+}
+
+func (m memoryStore) Get(id string, state interface{}) error {
+	raw, ok := m[id]
+	if !ok {
+		return errors.Errorf("[%s] not found", id)
+	}
+	return json.Unmarshal(raw, state)
+}
+
+type fetcher struct {
+	raw   []byte
+	calls int
+}
+
+func (f *fetcher) Fetch() ([]byte, error) {
+	f.calls++
+	return f.raw, nil
+}
+
+func TestPublicParamsCache(t *testing.T) {
+	store := memoryStore{}
+	upstream := &fetcher{raw: []byte("pp1")}
+	cache := NewPublicParamsCache(store)
+	var alerts int
+	cache.AddChangeHandler(func(network, channel, namespace string, pinned, observed []byte) {
+		alerts++
+	})
+
+	// fetched once, then served from the cache with the hash pinned
+	f := cache.Fetcher("n", "c", "ns", upstream)
+	for i := 0; i < 2; i++ {
+		raw, err := f.Fetch()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("pp1"), raw)
+	}
+	assert.Equal(t, 1, upstream.calls)
+	pinned, err := cache.Pinned("n", "c", "ns")
+	assert.NoError(t, err)
+	assert.Equal(t, HashPublicParams([]byte("pp1")), pinned)
+
+	// the cache survives a restart
+	raw, err := NewPublicParamsCache(store).Fetcher("n", "c", "ns", upstream).Fetch()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("pp1"), raw)
+	assert.Equal(t, 1, upstream.calls)
+
+	// unexpected change
+	upstream.raw = []byte("pp2")
+	_, err = cache.Refresh("n", "c", "ns")
+	assert.Error(t, err)
+	assert.Equal(t, 1, alerts)
+	assert.Error(t, cache.Check("n", "c", "ns", []byte("pp2")))
+	assert.Equal(t, 2, alerts)
+	assert.NoError(t, cache.Check("n", "c", "ns", []byte("pp1")))
+	raw, err = f.Fetch()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("pp1"), raw)
+
+	// expected change
+	assert.NoError(t, cache.Pin("n", "c", "ns", HashPublicParams([]byte("pp2"))))
+	raw, err = cache.Refresh("n", "c", "ns")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("pp2"), raw)
+	raw, err = f.Fetch()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("pp2"), raw)
+	assert.Equal(t, 2, alerts)
+}
+
+func TestPublicParamsCachePinnedInAdvance(t *testing.T) {
+	cache := NewPublicParamsCache(memoryStore{})
+	assert.NoError(t, cache.Pin("n", "c", "ns", HashPublicParams([]byte("pp1"))))
+
+	_, err := cache.Fetcher("n", "c", "ns", &fetcher{raw: []byte("pp2")}).Fetch()
+	assert.Error(t, err)
+	raw, err := cache.Fetcher("n", "c", "ns", &fetcher{raw: []byte("pp1")}).Fetch()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("pp1"), raw)
+}
+
+func TestPublicParamsCacheForceFetch(t *testing.T) {
+	cache := NewPublicParamsCache(memoryStore{})
+	upstream := &fetcher{raw: []byte("pp1")}
+	f := cache.Fetcher("n", "c", "ns", upstream)
+	raw, err := api2.ForceFetch(f)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("pp1"), raw)
+
+	// a forced fetch goes upstream, an unpinned change is rejected
+	upstream.raw = []byte("pp2")
+	_, err = api2.ForceFetch(f)
+	assert.Error(t, err)
+	assert.Equal(t, 2, upstream.calls)
+
+	// once pinned, the change is served by the forced fetch and then by the cache
+	assert.NoError(t, cache.Pin("n", "c", "ns", HashPublicParams([]byte("pp2"))))
+	raw, err = api2.ForceFetch(f)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("pp2"), raw)
+	raw, err = f.Fetch()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("pp2"), raw)
+	assert.Equal(t, 3, upstream.calls)
+
+	// fetchers without a cache are fetched as usual
+	raw, err = api2.ForceFetch(upstream)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("pp2"), raw)
+}
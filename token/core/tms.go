@@ -60,6 +60,10 @@ func (m *tmsProvider) GetTokenManagerService(network string, channel string, nam
 	key := network + channel + namespace
 	service, ok := m.services[key]
 	if !ok {
+		if cache := GetPublicParamsCache(m.sp); cache != nil {
+			// serve the public parameters from the cache, pinning their hash
+			publicParamsFetcher = cache.Fetcher(network, channel, namespace, publicParamsFetcher)
+		}
 		var err error
 		service, err = m.newTMS(network, channel, namespace, publicParamsFetcher)
 		if err != nil {
@@ -6,6 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package audit
 
 import (
+	"bytes"
 	"encoding/json"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/idemix"
@@ -21,6 +22,7 @@ import (
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/elgamal"
 	issue2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
@@ -77,6 +79,10 @@ type Auditor struct {
 	Signer         SigningIdentity
 	PedersenParams []*bn256.G1
 	NYMParams      []byte
+	// DecryptionKey opens the audit infos encrypted under the auditor's key, if any
+	DecryptionKey *elgamal.SecretKey
+	// Scope declares what the auditor checks, the requests carry no information outside of it
+	Scope api.AuditScope
 }
 
 func NewAuditor(pp []*bn256.G1, nymparams []byte, signer SigningIdentity) *Auditor {
@@ -89,7 +95,7 @@ func NewAuditor(pp []*bn256.G1, nymparams []byte, signer SigningIdentity) *Audit
 
 func (a *Auditor) Endorse(tokenRequest *api.TokenRequest, txID string) ([]byte, error) {
 	// Prepare signature
-	bytes, err := json.Marshal(&api.TokenRequest{Issues: tokenRequest.Issues, Transfers: tokenRequest.Transfers})
+	bytes, err := tokenRequest.MarshalToSign()
 	if err != nil {
 		return nil, errors.Errorf("audit of tx [%s] failed: error marshal token request for signature", txID)
 	}
@@ -99,7 +105,7 @@ func (a *Auditor) Endorse(tokenRequest *api.TokenRequest, txID string) ([]byte,
 }
 
 func (a *Auditor) Check(tokenRequest *api.TokenRequest, tokenRequestMetadata *api.TokenRequestMetadata, inputTokens [][]*token.Token, txID string) error {
-	outputsFromIssue, err := getAuditInfoForIssues(tokenRequest.Issues, tokenRequestMetadata.Issues)
+	outputsFromIssue, err := a.getAuditInfoForIssues(tokenRequest.Issues, tokenRequestMetadata.Issues)
 	if err != nil {
 		return errors.Wrapf(err, "failed getting audit info for issues")
 	}
@@ -108,7 +114,7 @@ func (a *Auditor) Check(tokenRequest *api.TokenRequest, tokenRequestMetadata *ap
 		return errors.Wrapf(err, "failed checking issues")
 	}
 
-	auditableInputs, outputsFromTransfer, err := getAuditInfoForTransfers(tokenRequest.Transfers, tokenRequestMetadata.Transfers, inputTokens)
+	auditableInputs, outputsFromTransfer, err := a.getAuditInfoForTransfers(tokenRequest.Transfers, tokenRequestMetadata.Transfers, inputTokens)
 	if err != nil {
 		return errors.Wrapf(err, "failed getting audit info for transfers")
 	}
@@ -151,12 +157,13 @@ func (a *Auditor) checkIssueRequests(outputsFromIssue [][]*AuditableToken, txID
 
 func (a *Auditor) inspectOutputs(tokens []*AuditableToken) error {
 	for i, t := range tokens {
-		err := a.inspectOutput(t, i)
-		if err != nil {
-			return errors.Wrapf(err, "failed inspecting output [%d]", i)
+		if a.Scope.Amounts() {
+			if err := a.inspectOutput(t, i); err != nil {
+				return errors.Wrapf(err, "failed inspecting output [%d]", i)
+			}
 		}
-		if !t.Token.IsRedeem() { // this is not a redeemed output
-			err = t.owner.ownerInfo.Match(t.Token.Owner)
+		if a.Scope.Identities() && !t.Token.IsRedeem() { // this is not a redeemed output
+			err := t.owner.ownerInfo.Match(t.Token.Owner)
 			if err != nil {
 				return errors.Wrapf(err, "output at index [%d] does not match the provided opening", i)
 			}
@@ -181,6 +188,10 @@ func (a *Auditor) inspectOutput(output *AuditableToken, index int) error {
 }
 
 func (a *Auditor) inspectInputs(inputs []*AuditableToken) error {
+	if !a.Scope.Identities() {
+		// the inputs reveal no amount, there is nothing to check
+		return nil
+	}
 	for i, input := range inputs {
 		if input == nil || input.Token == nil {
 			return errors.Errorf("invalid input at index [%d]", i)
@@ -197,7 +208,7 @@ func (a *Auditor) inspectInputs(inputs []*AuditableToken) error {
 	return nil
 }
 
-func getAuditInfoForIssues(issues [][]byte, metadata []api.IssueMetadata) ([][]*AuditableToken, error) {
+func (a *Auditor) getAuditInfoForIssues(issues [][]byte, metadata []api.IssueMetadata) ([][]*AuditableToken, error) {
 	if len(issues) != len(metadata) {
 		return nil, errors.Errorf("number of issues does not match number of provided metadata")
 	}
@@ -208,16 +219,14 @@ func getAuditInfoForIssues(issues [][]byte, metadata []api.IssueMetadata) ([][]*
 		if err != nil {
 			return nil, err
 		}
-		if len(ia.OutputTokens) != len(issue.AuditInfos) || len(ia.OutputTokens) != len(issue.TokenInfo) {
-			return nil, errors.Errorf("number of output does not match number of provided metadata")
+		if err := a.checkCounts(len(ia.OutputTokens), issue.AuditInfos, issue.TokenInfo); err != nil {
+			return nil, errors.Wrapf(err, "invalid metadata for issue [%d]", k)
 		}
-		for i := 0; i < len(issue.AuditInfos); i++ {
-			ti := &token.TokenInformation{}
-			err := json.Unmarshal(issue.TokenInfo[i], ti)
-			if err != nil {
-				return nil, err
-			}
-			ao, err := NewAuditableToken(ia.OutputTokens[i], issue.AuditInfos[i], ti.Type, ti.Value, ti.BlindingFactor)
+		if err := matchAuditInfos(ia.AuditInfos, issue.AuditInfos); err != nil {
+			return nil, errors.Wrapf(err, "audit infos of issue [%d] do not match", k)
+		}
+		for i := 0; i < len(ia.OutputTokens); i++ {
+			ao, err := a.auditableOutput(ia.OutputTokens[i], issue.AuditInfos, issue.TokenInfo, i)
 			if err != nil {
 				return nil, err
 			}
@@ -227,7 +236,7 @@ func getAuditInfoForIssues(issues [][]byte, metadata []api.IssueMetadata) ([][]*
 	return outputs, nil
 }
 
-func getAuditInfoForTransfers(transfers [][]byte, metadata []api.TransferMetadata, inputs [][]*token.Token) ([][]*AuditableToken, [][]*AuditableToken, error) {
+func (a *Auditor) getAuditInfoForTransfers(transfers [][]byte, metadata []api.TransferMetadata, inputs [][]*token.Token) ([][]*AuditableToken, [][]*AuditableToken, error) {
 	if len(transfers) != len(metadata) {
 		return nil, nil, errors.Errorf("number of transfers does not match the number of provided metadata")
 	}
@@ -237,31 +246,38 @@ func getAuditInfoForTransfers(transfers [][]byte, metadata []api.TransferMetadat
 	auditableInputs := make([][]*AuditableToken, len(inputs))
 	outputs := make([][]*AuditableToken, len(transfers))
 	for k, tr := range metadata {
-		if len(tr.SenderAuditInfos) != len(inputs[k]) {
-			return nil, nil, errors.Errorf("number of inputs does not match the number of senders")
-		}
-		for i := 0; i < len(tr.SenderAuditInfos); i++ {
-			ai, err := NewAuditableToken(inputs[k][i], tr.SenderAuditInfos[i], "", nil, nil)
-			if err != nil {
-				return nil, nil, err
+		if a.Scope.Identities() {
+			if len(tr.SenderAuditInfos) != len(inputs[k]) {
+				return nil, nil, errors.Errorf("number of inputs does not match the number of senders")
+			}
+			for i := 0; i < len(tr.SenderAuditInfos); i++ {
+				auditInfo, err := a.openAuditInfo(tr.SenderAuditInfos[i])
+				if err != nil {
+					return nil, nil, err
+				}
+				ai, err := NewAuditableToken(inputs[k][i], auditInfo, "", nil, nil)
+				if err != nil {
+					return nil, nil, err
+				}
+				auditableInputs[k] = append(auditableInputs[k], ai)
 			}
-			auditableInputs[k] = append(auditableInputs[k], ai)
 		}
 		ta := &transfer.TransferAction{}
 		err := json.Unmarshal(transfers[k], ta)
 		if err != nil {
 			return nil, nil, err
 		}
-		if len(ta.OutputTokens) != len(tr.ReceiverAuditInfos) {
-			return nil, nil, errors.Errorf("number of outputs does not match the number of receivers")
+		if err := a.checkCounts(len(ta.OutputTokens), tr.ReceiverAuditInfos, tr.TokenInfo); err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid metadata for transfer [%d]", k)
 		}
-		for i := 0; i < len(tr.ReceiverAuditInfos); i++ {
-			ti := &token.TokenInformation{}
-			err := json.Unmarshal(tr.TokenInfo[i], ti)
-			if err != nil {
-				return nil, nil, err
-			}
-			ao, err := NewAuditableToken(ta.OutputTokens[i], tr.ReceiverAuditInfos[i], ti.Type, ti.Value, ti.BlindingFactor)
+		if err := matchAuditInfos(ta.SenderAuditInfos, tr.SenderAuditInfos); err != nil {
+			return nil, nil, errors.Wrapf(err, "sender audit infos of transfer [%d] do not match", k)
+		}
+		if err := matchAuditInfos(ta.ReceiverAuditInfos, tr.ReceiverAuditInfos); err != nil {
+			return nil, nil, errors.Wrapf(err, "receiver audit infos of transfer [%d] do not match", k)
+		}
+		for i := 0; i < len(ta.OutputTokens); i++ {
+			ao, err := a.auditableOutput(ta.OutputTokens[i], tr.ReceiverAuditInfos, tr.TokenInfo, i)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -270,3 +286,63 @@ func getAuditInfoForTransfers(transfers [][]byte, metadata []api.TransferMetadat
 	}
 	return auditableInputs, outputs, nil
 }
+
+// checkCounts checks that the metadata carries an audit info and a token information for each of the passed
+// number of outputs, as far as they are within the scope of the auditor
+func (a *Auditor) checkCounts(outputs int, auditInfos [][]byte, tokenInfos [][]byte) error {
+	if a.Scope.Identities() && outputs != len(auditInfos) {
+		return errors.Errorf("number of outputs does not match the number of audit infos")
+	}
+	if a.Scope.Amounts() && outputs != len(tokenInfos) {
+		return errors.Errorf("number of outputs does not match the number of token infos")
+	}
+	return nil
+}
+
+// auditableOutput returns the passed output together with its openings within the scope of the auditor
+func (a *Auditor) auditableOutput(output *token.Token, auditInfos [][]byte, tokenInfos [][]byte, index int) (*AuditableToken, error) {
+	ti := &token.TokenInformation{}
+	if a.Scope.Amounts() {
+		if err := json.Unmarshal(tokenInfos[index], ti); err != nil {
+			return nil, err
+		}
+	}
+	if !a.Scope.Identities() {
+		return &AuditableToken{
+			Token: output,
+			data:  &tokenDataOpening{ttype: ti.Type, value: ti.Value, bf: ti.BlindingFactor},
+		}, nil
+	}
+	auditInfo, err := a.openAuditInfo(auditInfos[index])
+	if err != nil {
+		return nil, err
+	}
+	return NewAuditableToken(output, auditInfo, ti.Type, ti.Value, ti.BlindingFactor)
+}
+
+// openAuditInfo returns the passed audit info in the clear, decrypting it if it is encrypted under the auditor's key
+func (a *Auditor) openAuditInfo(auditInfo []byte) ([]byte, error) {
+	if !IsEncryptedAuditInfo(auditInfo) {
+		return auditInfo, nil
+	}
+	if a.DecryptionKey == nil {
+		return nil, errors.Errorf("audit info is encrypted, but no decryption key is available")
+	}
+	return DecryptAuditInfo(a.DecryptionKey, auditInfo)
+}
+
+// matchAuditInfos checks that the audit infos carried by an action, if any, are those in the metadata
+func matchAuditInfos(actionAuditInfos [][]byte, metadataAuditInfos [][]byte) error {
+	if len(actionAuditInfos) == 0 {
+		return nil
+	}
+	if len(actionAuditInfos) != len(metadataAuditInfos) {
+		return errors.Errorf("expected [%d] audit infos, got [%d]", len(actionAuditInfos), len(metadataAuditInfos))
+	}
+	for i := range actionAuditInfos {
+		if !bytes.Equal(actionAuditInfos[i], metadataAuditInfos[i]) {
+			return errors.Errorf("audit info at index [%d] does not match", i)
+		}
+	}
+	return nil
+}
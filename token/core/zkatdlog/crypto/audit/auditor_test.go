@@ -25,6 +25,7 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/audit"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/audit/mock"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/elgamal"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/anonym"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
@@ -129,8 +130,132 @@ var _ = Describe("Auditor", func() {
 			})
 		})
 	})
+	Describe("Audit a transfer with encrypted audit infos", func() {
+		var sk *elgamal.SecretKey
+		BeforeEach(func() {
+			var err error
+			sk, err = elgamal.NewKeyPair(bn256.G1Gen())
+			Expect(err).NotTo(HaveOccurred())
+		})
+		When("the auditor holds the decryption key", func() {
+			It("succeeds", func() {
+				transfer, metadata, tokens := createTransfer(pp)
+				encryptAuditInfos(sk.PublicKey, transfer, &metadata)
+				raw, err := transfer.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				auditor.DecryptionKey = sk
+				err = auditor.Check(&api.TokenRequest{Transfers: [][]byte{raw}}, &api.TokenRequestMetadata{Transfers: []api.TransferMetadata{metadata}}, tokens, "1")
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+		When("the auditor does not hold the decryption key", func() {
+			It("fails", func() {
+				transfer, metadata, tokens := createTransfer(pp)
+				encryptAuditInfos(sk.PublicKey, transfer, &metadata)
+				raw, err := transfer.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				err = auditor.Check(&api.TokenRequest{Transfers: [][]byte{raw}}, &api.TokenRequestMetadata{Transfers: []api.TransferMetadata{metadata}}, tokens, "1")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("no decryption key is available"))
+			})
+		})
+		When("the audit infos in the metadata are not those in the action", func() {
+			It("fails", func() {
+				transfer, metadata, tokens := createTransfer(pp)
+				encryptAuditInfos(sk.PublicKey, transfer, &metadata)
+				raw, err := transfer.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				metadata.ReceiverAuditInfos[0], err = audit.EncryptAuditInfo(sk.PublicKey, metadata.ReceiverAuditInfos[1])
+				Expect(err).NotTo(HaveOccurred())
+				auditor.DecryptionKey = sk
+				err = auditor.Check(&api.TokenRequest{Transfers: [][]byte{raw}}, &api.TokenRequestMetadata{Transfers: []api.TransferMetadata{metadata}}, tokens, "1")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("audit info at index [0] does not match"))
+			})
+		})
+	})
+	Describe("Audit a transfer within a scope", func() {
+		When("the scope is amounts only", func() {
+			BeforeEach(func() {
+				auditor.Scope = api.AmountsAuditScope
+			})
+			It("succeeds without audit infos", func() {
+				transfer, metadata, tokens := createTransfer(pp)
+				metadata.SenderAuditInfos = nil
+				metadata.ReceiverAuditInfos = nil
+				raw, err := transfer.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				err = auditor.Check(&api.TokenRequest{Transfers: [][]byte{raw}}, &api.TokenRequestMetadata{Transfers: []api.TransferMetadata{metadata}}, tokens, "1")
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("fails when the token info does not match the output", func() {
+				transfer, metadata, tokens := createTransferWithBogusOutput(pp)
+				metadata.ReceiverAuditInfos = nil
+				raw, err := transfer.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				err = auditor.Check(&api.TokenRequest{Transfers: [][]byte{raw}}, &api.TokenRequestMetadata{Transfers: []api.TransferMetadata{metadata}}, tokens, "1")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("output at index [0] does not match the provided opening"))
+			})
+		})
+		When("the scope is identities only", func() {
+			BeforeEach(func() {
+				auditor.Scope = api.IdentitiesAuditScope
+			})
+			It("succeeds without token infos", func() {
+				transfer, metadata, tokens := createTransfer(pp)
+				metadata.TokenInfo = nil
+				raw, err := transfer.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				err = auditor.Check(&api.TokenRequest{Transfers: [][]byte{raw}}, &api.TokenRequestMetadata{Transfers: []api.TransferMetadata{metadata}}, tokens, "1")
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("gets the metadata filtered by its scope, that does not open the outputs", func() {
+				transfer, metadata, tokens := createTransfer(pp)
+				raw, err := transfer.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				filtered := (&api.TokenRequestMetadata{Transfers: []api.TransferMetadata{metadata}}).FilterByAuditScope(api.IdentitiesAuditScope)
+				Expect(filtered.TokenInfos()).To(BeEmpty())
+				err = auditor.Check(&api.TokenRequest{Transfers: [][]byte{raw}}, filtered, tokens, "1")
+				Expect(err).NotTo(HaveOccurred())
+
+				// the values cannot be checked without the openings
+				auditor.Scope = api.FullAuditScope
+				err = auditor.Check(&api.TokenRequest{Transfers: [][]byte{raw}}, filtered, tokens, "1")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("number of outputs does not match the number of token infos"))
+			})
+			It("fails when the recipient audit info does not match the output", func() {
+				transfer, metadata, tokens := createTransfer(pp)
+				metadata.TokenInfo = nil
+				_, auditinfo := getIdemixInfo("./testdata/idemix")
+				raw, err := auditinfo.Bytes()
+				Expect(err).NotTo(HaveOccurred())
+				metadata.ReceiverAuditInfos[0] = raw
+				raw, err = transfer.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				err = auditor.Check(&api.TokenRequest{Transfers: [][]byte{raw}}, &api.TokenRequestMetadata{Transfers: []api.TransferMetadata{metadata}}, tokens, "1")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("output at index [0] does not match the provided opening"))
+			})
+		})
+	})
 })
 
+func encryptAuditInfos(pk *elgamal.PublicKey, transfer *transfer2.TransferAction, metadata *api.TransferMetadata) {
+	var err error
+	for i := range metadata.SenderAuditInfos {
+		metadata.SenderAuditInfos[i], err = audit.EncryptAuditInfo(pk, metadata.SenderAuditInfos[i])
+		Expect(err).NotTo(HaveOccurred())
+	}
+	for i := range metadata.ReceiverAuditInfos {
+		metadata.ReceiverAuditInfos[i], err = audit.EncryptAuditInfo(pk, metadata.ReceiverAuditInfos[i])
+		Expect(err).NotTo(HaveOccurred())
+	}
+	transfer.SenderAuditInfos = metadata.SenderAuditInfos
+	transfer.ReceiverAuditInfos = append([][]byte{}, metadata.ReceiverAuditInfos...)
+}
+
 func createIssue(pp *crypto.PublicParams) (*issue.IssueAction, api.IssueMetadata) {
 	issuer := prepareIssuer(pp)
 	id, auditInfo := getIdemixInfo("./testdata/idemix")
@@ -0,0 +1,62 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package audit
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/elgamal"
+)
+
+// encryptedAuditInfo is an audit info encrypted under the auditor's key
+type encryptedAuditInfo struct {
+	Encrypted *elgamal.EncryptedMessage
+}
+
+// EncryptAuditInfo encrypts the passed audit info under the passed auditor's key
+func EncryptAuditInfo(pk *elgamal.PublicKey, auditInfo []byte) ([]byte, error) {
+	c, err := pk.EncryptMessage(auditInfo)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed encrypting audit info")
+	}
+	return json.Marshal(&encryptedAuditInfo{Encrypted: c})
+}
+
+// IsEncryptedAuditInfo returns true if the passed audit info is encrypted
+func IsEncryptedAuditInfo(raw []byte) bool {
+	eai := &encryptedAuditInfo{}
+	return json.Unmarshal(raw, eai) == nil && eai.Encrypted != nil
+}
+
+// CheckEncryptedAuditInfo checks that the passed audit info is a well-formed encrypted audit info
+func CheckEncryptedAuditInfo(raw []byte) error {
+	eai := &encryptedAuditInfo{}
+	if err := json.Unmarshal(raw, eai); err != nil {
+		return errors.Wrap(err, "failed unmarshalling encrypted audit info")
+	}
+	if eai.Encrypted == nil {
+		return errors.Errorf("audit info is not encrypted")
+	}
+	return eai.Encrypted.WellFormed()
+}
+
+// DecryptAuditInfo decrypts the passed encrypted audit info with the auditor's secret key
+func DecryptAuditInfo(sk *elgamal.SecretKey, raw []byte) ([]byte, error) {
+	if err := CheckEncryptedAuditInfo(raw); err != nil {
+		return nil, err
+	}
+	eai := &encryptedAuditInfo{}
+	if err := json.Unmarshal(raw, eai); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling encrypted audit info")
+	}
+	auditInfo, err := sk.DecryptMessage(eai.Encrypted)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed decrypting audit info")
+	}
+	return auditInfo, nil
+}
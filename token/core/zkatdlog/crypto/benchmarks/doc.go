@@ -0,0 +1,24 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package benchmarks measures the provers and verifiers of zkatdlog: issue, transfer for a varying number of
+// inputs and outputs, range proofs for a varying base and exponent, and the validator end-to-end.
+//
+// Run them with
+//
+//	make benchmarks
+//
+// that writes the results to bench.txt. To compare them with the results of a previous run, for instance on the
+// main branch, to spot regressions, run
+//
+//	make benchmarks BENCH_BASE=main.txt
+//
+// To record a CPU profile whose samples are labelled with the proof being computed, see common.EnableProfilingLabels,
+// and break it down by label, run
+//
+//	go test -run XXX -bench . -cpuprofile cpu.out ./token/core/zkatdlog/crypto/benchmarks -args -labels
+//	go tool pprof -tags cpu.out
+package benchmarks
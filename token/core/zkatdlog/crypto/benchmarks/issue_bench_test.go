@@ -0,0 +1,50 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package benchmarks_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
+)
+
+var issueOutputs = []int{1, 8, 32}
+
+func BenchmarkIssueProve(b *testing.B) {
+	pp := setup(b, 100, 2)
+	for _, outputs := range issueOutputs {
+		b.Run(fmt.Sprintf("outputs=%d", outputs), func(b *testing.B) {
+			tw, coms := tokens(b, pp, outputs, 10)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := issue.NewProver(tw, coms, true, pp).Prove(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkIssueVerify(b *testing.B) {
+	pp := setup(b, 100, 2)
+	for _, outputs := range issueOutputs {
+		b.Run(fmt.Sprintf("outputs=%d", outputs), func(b *testing.B) {
+			tw, coms := tokens(b, pp, outputs, 10)
+			proof, err := issue.NewProver(tw, coms, true, pp).Prove()
+			if err != nil {
+				b.Fatal(err)
+			}
+			verifier := issue.NewVerifier(coms, true, pp)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := verifier.Verify(proof); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package benchmarks_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	rangeproof "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/range"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+)
+
+// rangeParams are the bases and exponents of the benchmarked range proofs, the bound is base^exponent
+var rangeParams = []struct {
+	base     int64
+	exponent int
+}{
+	{16, 4}, {100, 2}, {100, 3}, {256, 2},
+}
+
+func BenchmarkRangeProve(b *testing.B) {
+	for _, params := range rangeParams {
+		b.Run(fmt.Sprintf("base=%d/exponent=%d", params.base, params.exponent), func(b *testing.B) {
+			pp := setup(b, params.base, params.exponent)
+			tw, coms := tokens(b, pp, 2, pp.RangeBound()-1)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := rangeProver(b, pp, tw, coms).Prove(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRangeVerify(b *testing.B) {
+	for _, params := range rangeParams {
+		b.Run(fmt.Sprintf("base=%d/exponent=%d", params.base, params.exponent), func(b *testing.B) {
+			pp := setup(b, params.base, params.exponent)
+			tw, coms := tokens(b, pp, 2, pp.RangeBound()-1)
+			proof, err := rangeProver(b, pp, tw, coms).Prove()
+			if err != nil {
+				b.Fatal(err)
+			}
+			verifier := rangeproof.NewVerifier(coms, uint64(len(pp.RangeProofParams.SignedValues)), pp.RangeProofParams.Exponent, pp.ZKATPedParams, pp.RangeProofParams.SignPK, pp.P, pp.RangeProofParams.Q)
+			verifier.Hash = pp.ChallengeHash
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := verifier.Verify(proof); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// rangeProver returns a prover of the range of the passed tokens, as configured by the transfer prover
+func rangeProver(b *testing.B, pp *crypto.PublicParams, tw []*token.TokenDataWitness, coms []*bn256.G1) *rangeproof.Prover {
+	prover := rangeproof.NewProver(tw, coms, pp.RangeProofParams.SignedValues, pp.RangeProofParams.Exponent, pp.ZKATPedParams, pp.RangeProofParams.SignPK, pp.P, pp.RangeProofParams.Q)
+	prover.Hash = pp.ChallengeHash
+	table, err := rangeproof.GetDigitTable(pp)
+	if err != nil {
+		b.Fatal(err)
+	}
+	prover.Table = table
+	return prover
+}
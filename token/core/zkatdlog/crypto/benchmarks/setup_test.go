@@ -0,0 +1,134 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package benchmarks_test
+
+import (
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	idemix2 "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/idemix"
+	api2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/api"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/core/sig"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	registry2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	msp2 "github.com/hyperledger/fabric/msp"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+)
+
+// idemixDir holds the idemix credentials of the owners of the tokens, shared with the validator tests
+const idemixDir = "../validator/testdata/idemix"
+
+var labels = flag.Bool("labels", false, "label the samples of the CPU profile with the proof being computed")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	common.EnableProfilingLabels(*labels)
+	os.Exit(m.Run())
+}
+
+// setup returns public parameters with the passed base and exponent, and no idemix issuer public key
+func setup(b *testing.B, base int64, exponent int) *crypto.PublicParams {
+	pp, err := crypto.Setup(base, exponent, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return pp
+}
+
+// tokens returns n tokens of the passed value and their witnesses
+func tokens(b *testing.B, pp *crypto.PublicParams, n int, value uint64) ([]*token.TokenDataWitness, []*bn256.G1) {
+	values := make([]uint64, n)
+	for i := range values {
+		values[i] = value
+	}
+	coms, tw, err := token.GetTokensWithWitness(values, "ABC", pp.ZKATPedParams)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return tw, coms
+}
+
+// idemixIdentity returns an idemix owner identity and its signer
+func idemixIdentity(b *testing.B) (view.Identity, api2.SigningIdentity) {
+	registry := registry2.New()
+	if err := registry.RegisterService(&memoryConfig{}); err != nil {
+		b.Fatal(err)
+	}
+	kvss, err := kvs.New("memory", "", registry)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := registry.RegisterService(kvss); err != nil {
+		b.Fatal(err)
+	}
+	if err := registry.RegisterService(sig.NewSignService(registry, nil)); err != nil {
+		b.Fatal(err)
+	}
+	config, err := msp2.GetLocalMspConfigWithType(idemixDir, nil, "idemix", "idemix")
+	if err != nil {
+		b.Fatal(err)
+	}
+	p, err := idemix2.NewProvider(config, registry)
+	if err != nil {
+		b.Fatal(err)
+	}
+	id, _, err := p.Identity()
+	if err != nil {
+		b.Fatal(err)
+	}
+	signer, err := p.DeserializeSigningIdentity(id)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return id, signer
+}
+
+// memoryConfig configures an in-memory key-value store
+type memoryConfig struct{}
+
+func (c *memoryConfig) GetString(key string) string {
+	return "memory"
+}
+
+func (c *memoryConfig) GetDuration(key string) time.Duration {
+	return 0
+}
+
+func (c *memoryConfig) GetBool(key string) bool {
+	return false
+}
+
+func (c *memoryConfig) GetStringSlice(key string) []string {
+	return nil
+}
+
+func (c *memoryConfig) IsSet(key string) bool {
+	return false
+}
+
+func (c *memoryConfig) UnmarshalKey(key string, rawVal interface{}) error {
+	*(rawVal.(*kvs.Opts)) = kvs.Opts{}
+	return nil
+}
+
+func (c *memoryConfig) ConfigFileUsed() string {
+	return ""
+}
+
+func (c *memoryConfig) GetPath(key string) string {
+	return ""
+}
+
+func (c *memoryConfig) TranslatePath(path string) string {
+	return ""
+}
@@ -0,0 +1,63 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package benchmarks_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
+)
+
+// transferShapes are the numbers of inputs and outputs of the benchmarked transfers
+var transferShapes = []struct{ inputs, outputs int }{
+	{1, 1}, {1, 2}, {2, 2}, {4, 4}, {8, 8}, {1, 16},
+}
+
+func BenchmarkTransferProve(b *testing.B) {
+	pp := setup(b, 100, 2)
+	for _, shape := range transferShapes {
+		b.Run(fmt.Sprintf("inputs=%d/outputs=%d", shape.inputs, shape.outputs), func(b *testing.B) {
+			inw, in, outw, out := transferTokens(b, pp, shape.inputs, shape.outputs)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := transfer.NewProver(inw, outw, in, out, pp).Prove(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkTransferVerify(b *testing.B) {
+	pp := setup(b, 100, 2)
+	for _, shape := range transferShapes {
+		b.Run(fmt.Sprintf("inputs=%d/outputs=%d", shape.inputs, shape.outputs), func(b *testing.B) {
+			inw, in, outw, out := transferTokens(b, pp, shape.inputs, shape.outputs)
+			proof, err := transfer.NewProver(inw, outw, in, out, pp).Prove()
+			if err != nil {
+				b.Fatal(err)
+			}
+			verifier := transfer.NewVerifier(in, out, pp)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := verifier.Verify(proof); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// transferTokens returns inputs and outputs of the same total value, and their witnesses
+func transferTokens(b *testing.B, pp *crypto.PublicParams, inputs, outputs int) ([]*token.TokenDataWitness, []*bn256.G1, []*token.TokenDataWitness, []*bn256.G1) {
+	inw, in := tokens(b, pp, inputs, uint64(outputs))
+	outw, out := tokens(b, pp, outputs, uint64(inputs))
+	return inw, in, outw, out
+}
@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package benchmarks_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/validator"
+)
+
+// BenchmarkValidateTransfer measures the validation of a signed transfer request, from the raw request to the
+// validated actions, proofs, signatures, and ledger reads included
+func BenchmarkValidateTransfer(b *testing.B) {
+	ipk, err := ioutil.ReadFile(filepath.Join(idemixDir, "msp", "IssuerPublicKey"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	pp, err := crypto.Setup(100, 2, ipk)
+	if err != nil {
+		b.Fatal(err)
+	}
+	engine := validator.New(pp)
+	for _, shape := range transferShapes {
+		b.Run(fmt.Sprintf("inputs=%d/outputs=%d", shape.inputs, shape.outputs), func(b *testing.B) {
+			raw, ledger := transferRequest(b, pp, shape.inputs, shape.outputs)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := engine.VerifyTokenRequestFromRaw(ledger.GetState, "1", raw); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// transferRequest returns a signed transfer request, without auditor, and the ledger holding its inputs
+func transferRequest(b *testing.B, pp *crypto.PublicParams, inputs, outputs int) ([]byte, mapLedger) {
+	id, signer := idemixIdentity(b)
+	inw, in := tokens(b, pp, inputs, uint64(outputs))
+
+	ledger := mapLedger{}
+	signers := make([]view2.Signer, inputs)
+	ids := make([]string, inputs)
+	toks := make([]*token.Token, inputs)
+	infos := make([]*token.TokenInformation, inputs)
+	for i := 0; i < inputs; i++ {
+		signers[i] = signer
+		ids[i] = fmt.Sprintf("%d", i)
+		toks[i] = &token.Token{Data: in[i], Owner: id}
+		infos[i] = &token.TokenInformation{Type: inw[i].Type, Value: inw[i].Value, BlindingFactor: inw[i].BlindingFactor}
+		raw, err := toks[i].Serialize()
+		if err != nil {
+			b.Fatal(err)
+		}
+		ledger[ids[i]] = raw
+	}
+	sender, err := transfer.NewSender(signers, toks, ids, infos, pp)
+	if err != nil {
+		b.Fatal(err)
+	}
+	values := make([]uint64, outputs)
+	owners := make([][]byte, outputs)
+	for i := 0; i < outputs; i++ {
+		values[i] = uint64(inputs)
+		owners[i] = id
+	}
+	action, _, err := sender.GenerateZKTransfer(values, owners)
+	if err != nil {
+		b.Fatal(err)
+	}
+	raw, err := action.Serialize()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	tr := &api.TokenRequest{Transfers: [][]byte{raw}}
+	raw, err = json.Marshal(tr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	tr.Signatures, err = sender.SignTokenActions(raw, "1")
+	if err != nil {
+		b.Fatal(err)
+	}
+	raw, err = json.Marshal(tr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return raw, ledger
+}
+
+// mapLedger is a ledger holding the serialized tokens by their key
+type mapLedger map[string][]byte
+
+func (l mapLedger) GetState(key string) ([]byte, error) {
+	raw, ok := l[key]
+	if !ok {
+		return nil, errors.Errorf("key [%s] not found", key)
+	}
+	return raw, nil
+}
@@ -0,0 +1,218 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package crypto
+
+import (
+	"bytes"
+	ecdsa2 "crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/ecdsa"
+)
+
+// Ceremony records the setup ceremony that generated the public parameters.
+// The transcript of the ceremony is published separately, see VerifyCeremony.
+type Ceremony struct {
+	Name string
+	// TranscriptHash is the SHA-256 hash of the serialized transcript
+	TranscriptHash []byte
+	Contributors   []view.Identity
+}
+
+// CeremonyTranscript is the published record of a setup ceremony
+type CeremonyTranscript struct {
+	Name string
+	// Output is the digest of the parameters generated by the ceremony, see PublicParams.CeremonyOutput
+	Output []byte
+	// Contributions are the signed contributions, in order, see Contribute
+	Contributions []*Contribution
+}
+
+// Contribution is the contribution of a participant to a setup ceremony, signed by the participant
+type Contribution struct {
+	// Contributor is the PEM encoded public key, or certificate, of the participant
+	Contributor view.Identity
+	// Message is the message of the participant, if any
+	Message []byte `json:",omitempty"`
+	// Signature is the signature of the participant on the contribution, see CeremonyTranscript.Contribute
+	Signature []byte
+}
+
+// ContributionSigner signs a contribution to a setup ceremony
+type ContributionSigner interface {
+	Sign(message []byte) ([]byte, error)
+}
+
+// NewCeremonyTranscript returns the transcript of the ceremony with the passed name that generated the passed
+// public parameters. The participants then contribute to it, see Contribute.
+func NewCeremonyTranscript(name string, pp *PublicParams) (*CeremonyTranscript, error) {
+	output, err := pp.CeremonyOutput()
+	if err != nil {
+		return nil, err
+	}
+	return &CeremonyTranscript{Name: name, Output: output}, nil
+}
+
+// Contribute appends to the transcript the contribution of the passed contributor, with the passed message,
+// signed by the passed signer. The signature covers the name and the output of the ceremony, the position
+// of the contribution and the signature of the previous one, so that contributions cannot be reordered or moved
+// to another ceremony.
+func (t *CeremonyTranscript) Contribute(contributor view.Identity, message []byte, signer ContributionSigner) error {
+	c := &Contribution{Contributor: contributor, Message: message}
+	msg, err := t.contributionMessage(len(t.Contributions), c)
+	if err != nil {
+		return err
+	}
+	c.Signature, err = signer.Sign(msg)
+	if err != nil {
+		return errors.Wrapf(err, "failed signing contribution of [%s]", contributor)
+	}
+	t.Contributions = append(t.Contributions, c)
+	return nil
+}
+
+// Contributors returns the identities of the contributors, in order
+func (t *CeremonyTranscript) Contributors() []view.Identity {
+	contributors := make([]view.Identity, len(t.Contributions))
+	for i, c := range t.Contributions {
+		contributors[i] = c.Contributor
+	}
+	return contributors
+}
+
+// verifyContributions checks that the transcript has contributions, each signed by its contributor
+func (t *CeremonyTranscript) verifyContributions() error {
+	if len(t.Contributions) == 0 {
+		return errors.Errorf("ceremony [%s] has no contributions", t.Name)
+	}
+	for i, c := range t.Contributions {
+		pk, err := ecdsa.PemDecodeKey(c.Contributor)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid contributor [%d] of ceremony [%s]", i, t.Name)
+		}
+		ecdsaPK, ok := pk.(*ecdsa2.PublicKey)
+		if !ok {
+			return errors.Errorf("contributor [%d] of ceremony [%s] is not an ecdsa public key", i, t.Name)
+		}
+		msg, err := t.contributionMessage(i, c)
+		if err != nil {
+			return err
+		}
+		if err := ecdsa.NewECDSAVerifier(ecdsaPK).Verify(msg, c.Signature); err != nil {
+			return errors.WithMessagef(err, "invalid signature on contribution [%d] of ceremony [%s]", i, t.Name)
+		}
+	}
+	return nil
+}
+
+// contributionMessage returns the message the contributor of the contribution in the passed position signs
+func (t *CeremonyTranscript) contributionMessage(index int, c *Contribution) ([]byte, error) {
+	var previous []byte
+	if index > 0 {
+		previous = t.Contributions[index-1].Signature
+	}
+	raw, err := json.Marshal(&struct {
+		Name        string
+		Output      []byte
+		Index       int
+		Previous    []byte
+		Contributor view.Identity
+		Message     []byte
+	}{Name: t.Name, Output: t.Output, Index: index, Previous: previous, Contributor: c.Contributor, Message: c.Message})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshalling contribution")
+	}
+	return raw, nil
+}
+
+func (t *CeremonyTranscript) Bytes() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+func (t *CeremonyTranscript) FromBytes(raw []byte) error {
+	return json.Unmarshal(raw, t)
+}
+
+// CeremonyOutput returns the digest of the parameters a setup ceremony generates: the Pedersen generators and
+// the range proof parameters. The issuers and the auditor, set afterwards, are not covered.
+func (pp *PublicParams) CeremonyOutput() ([]byte, error) {
+	raw, err := json.Marshal(&struct {
+		P                *bn256.G1
+		ZKATPedParams    []*bn256.G1
+		RangeProofParams *RangeProofParams
+	}{P: pp.P, ZKATPedParams: pp.ZKATPedParams, RangeProofParams: pp.RangeProofParams})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshalling ceremony output")
+	}
+	h := sha256.Sum256(raw)
+	return h[:], nil
+}
+
+// SetCeremony records in the public parameters the ceremony with the passed transcript, that must have generated them
+func (pp *PublicParams) SetCeremony(transcriptRaw []byte) error {
+	defer pp.ResetHash()
+	transcript := &CeremonyTranscript{}
+	if err := transcript.FromBytes(transcriptRaw); err != nil {
+		return errors.Wrap(err, "failed unmarshalling ceremony transcript")
+	}
+	if err := pp.checkCeremonyOutput(transcript); err != nil {
+		return err
+	}
+	if err := transcript.verifyContributions(); err != nil {
+		return err
+	}
+	h := sha256.Sum256(transcriptRaw)
+	pp.Ceremony = &Ceremony{Name: transcript.Name, TranscriptHash: h[:], Contributors: transcript.Contributors()}
+	return nil
+}
+
+// VerifyCeremony checks that the public parameters have been generated by the ceremony with the passed
+// published transcript
+func (pp *PublicParams) VerifyCeremony(transcriptRaw []byte) error {
+	if pp.Ceremony == nil {
+		return errors.New("public parameters do not record a setup ceremony")
+	}
+	h := sha256.Sum256(transcriptRaw)
+	if !bytes.Equal(pp.Ceremony.TranscriptHash, h[:]) {
+		return errors.Errorf("transcript does not match the one of ceremony [%s]", pp.Ceremony.Name)
+	}
+	transcript := &CeremonyTranscript{}
+	if err := transcript.FromBytes(transcriptRaw); err != nil {
+		return errors.Wrap(err, "failed unmarshalling ceremony transcript")
+	}
+	if transcript.Name != pp.Ceremony.Name {
+		return errors.Errorf("transcript refers to ceremony [%s], expected [%s]", transcript.Name, pp.Ceremony.Name)
+	}
+	contributors := transcript.Contributors()
+	if len(contributors) != len(pp.Ceremony.Contributors) {
+		return errors.Errorf("transcript lists [%d] contributors, expected [%d]", len(contributors), len(pp.Ceremony.Contributors))
+	}
+	for i, contributor := range contributors {
+		if !contributor.Equal(pp.Ceremony.Contributors[i]) {
+			return errors.Errorf("contributor [%d] of the transcript does not match", i)
+		}
+	}
+	if err := pp.checkCeremonyOutput(transcript); err != nil {
+		return err
+	}
+	return transcript.verifyContributions()
+}
+
+func (pp *PublicParams) checkCeremonyOutput(transcript *CeremonyTranscript) error {
+	output, err := pp.CeremonyOutput()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(output, transcript.Output) {
+		return errors.Errorf("public parameters have not been generated by ceremony [%s]", transcript.Name)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package crypto
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/ecdsa"
+)
+
+func newContributor(t *testing.T) (view.Identity, *ecdsa.ECDSASigner) {
+	signer, err := ecdsa.NewECDSASigner()
+	assert.NoError(t, err)
+	pk, err := ecdsa.PemEncodeKey(signer.PK)
+	assert.NoError(t, err)
+	return pk, signer
+}
+
+func TestCeremony(t *testing.T) {
+	pp, err := Setup(16, 2, nil)
+	assert.NoError(t, err)
+	assert.Error(t, pp.VerifyCeremony(nil))
+
+	alice, aliceSigner := newContributor(t)
+	bob, bobSigner := newContributor(t)
+	transcript, err := NewCeremonyTranscript("genesis", pp)
+	assert.NoError(t, err)
+	// a ceremony without contributions is refused
+	raw, err := transcript.Bytes()
+	assert.NoError(t, err)
+	assert.Error(t, pp.SetCeremony(raw))
+
+	assert.NoError(t, transcript.Contribute(alice, []byte("alice's entropy"), aliceSigner))
+	assert.NoError(t, transcript.Contribute(bob, nil, bobSigner))
+	raw, err = transcript.Bytes()
+	assert.NoError(t, err)
+	assert.NoError(t, pp.SetCeremony(raw))
+	assert.Equal(t, []view.Identity{alice, bob}, pp.Ceremony.Contributors)
+
+	// the ceremony survives serialization
+	ppRaw, err := pp.Serialize()
+	assert.NoError(t, err)
+	pp, err = NewPublicParamsFromBytes(ppRaw)
+	assert.NoError(t, err)
+	assert.NoError(t, pp.VerifyCeremony(raw))
+
+	// a different transcript does not verify
+	transcript.Contributions = transcript.Contributions[:1]
+	other, err := transcript.Bytes()
+	assert.NoError(t, err)
+	assert.Error(t, pp.VerifyCeremony(other))
+
+	// parameters generated by another ceremony do not verify
+	pp2, err := Setup(16, 2, nil)
+	assert.NoError(t, err)
+	assert.Error(t, pp2.SetCeremony(raw))
+	pp2.Ceremony = pp.Ceremony
+	assert.Error(t, pp2.VerifyCeremony(raw))
+}
+
+func TestCeremonyContributions(t *testing.T) {
+	pp, err := Setup(16, 2, nil)
+	assert.NoError(t, err)
+	alice, aliceSigner := newContributor(t)
+	bob, bobSigner := newContributor(t)
+
+	newTranscript := func() *CeremonyTranscript {
+		transcript, err := NewCeremonyTranscript("genesis", pp)
+		assert.NoError(t, err)
+		assert.NoError(t, transcript.Contribute(alice, []byte("alice's entropy"), aliceSigner))
+		assert.NoError(t, transcript.Contribute(bob, []byte("bob's entropy"), bobSigner))
+		return transcript
+	}
+	assert.NoError(t, newTranscript().verifyContributions())
+
+	// a contribution signed by someone else does not verify
+	transcript, err := NewCeremonyTranscript("genesis", pp)
+	assert.NoError(t, err)
+	assert.NoError(t, transcript.Contribute(alice, nil, bobSigner))
+	assert.Error(t, transcript.verifyContributions())
+
+	// a tampered message does not verify
+	transcript = newTranscript()
+	transcript.Contributions[0].Message = []byte("mallory's entropy")
+	assert.Error(t, transcript.verifyContributions())
+
+	// reordered contributions do not verify
+	transcript = newTranscript()
+	transcript.Contributions[0], transcript.Contributions[1] = transcript.Contributions[1], transcript.Contributions[0]
+	assert.Error(t, transcript.verifyContributions())
+
+	// contributions do not move to another ceremony
+	transcript = newTranscript()
+	transcript.Name = "another"
+	assert.Error(t, transcript.verifyContributions())
+
+	// contributors are public keys
+	transcript = newTranscript()
+	transcript.Contributions[1].Contributor = []byte("bob")
+	assert.Error(t, transcript.verifyContributions())
+}
@@ -0,0 +1,282 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package common
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+)
+
+// EncodingVersion is the version of the binary encoding of the proofs, it is the first byte of an encoded proof.
+// The proofs encoded with JSON, by the previous releases, start with '{' instead.
+const EncodingVersion byte = 1
+
+// IsBinary returns true if the passed proof has the binary encoding, false if it has the JSON one
+func IsBinary(raw []byte) bool {
+	return len(raw) != 0 && raw[0] == EncodingVersion
+}
+
+// ProofEncoding identifies the encoding the provers use for the zero-knowledge proofs.
+// The verifiers read both the encodings, but the nodes of the previous releases read only the JSON one,
+// then the binary encoding is used only once the public parameters select it.
+type ProofEncoding byte
+
+const (
+	// JSONEncoding is the encoding of the previous releases, the default
+	JSONEncoding ProofEncoding = 0
+	// BinaryEncoding is the compact binary encoding, see Encoder
+	BinaryEncoding ProofEncoding = ProofEncoding(EncodingVersion)
+)
+
+// Proof is a zero-knowledge proof that can be serialized with both the encodings
+type Proof interface {
+	// Serialize returns the JSON encoding of the proof
+	Serialize() ([]byte, error)
+	// SerializeBinary returns the binary encoding of the proof
+	SerializeBinary() ([]byte, error)
+}
+
+// Validate returns an error if the encoding is unknown
+func (e ProofEncoding) Validate() error {
+	switch e {
+	case JSONEncoding, BinaryEncoding:
+		return nil
+	default:
+		return errors.Errorf("unknown proof encoding [%d]", e)
+	}
+}
+
+// Encode returns the passed proof in this encoding
+func (e ProofEncoding) Encode(p Proof) ([]byte, error) {
+	switch e {
+	case JSONEncoding:
+		return p.Serialize()
+	case BinaryEncoding:
+		return p.SerializeBinary()
+	default:
+		return nil, errors.Errorf("unknown proof encoding [%d]", e)
+	}
+}
+
+// Encoder encodes the elements of a proof in a compact binary format: the version byte followed by the elements
+// in a fixed order. Each element is prefixed by its length plus one as a varint, zero denoting a nil element,
+// each list by its number of elements.
+type Encoder struct {
+	buf []byte
+}
+
+func NewEncoder() *Encoder {
+	return &Encoder{buf: []byte{EncodingVersion}}
+}
+
+func (e *Encoder) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	e.buf = append(e.buf, b[:n]...)
+}
+
+func (e *Encoder) bytes(b []byte, isNil bool) {
+	if isNil {
+		e.uvarint(0)
+		return
+	}
+	e.uvarint(uint64(len(b)) + 1)
+	e.buf = append(e.buf, b...)
+}
+
+func (e *Encoder) Bytes(b []byte) {
+	e.bytes(b, b == nil)
+}
+
+func (e *Encoder) String(s string) {
+	e.bytes([]byte(s), false)
+}
+
+func (e *Encoder) Zr(z *bn256.Zr) {
+	if z == nil {
+		e.bytes(nil, true)
+		return
+	}
+	e.bytes(z.Bytes(), false)
+}
+
+func (e *Encoder) G1(g *bn256.G1) {
+	if g == nil {
+		e.bytes(nil, true)
+		return
+	}
+	e.bytes(g.Bytes(), false)
+}
+
+// Count writes the number of elements of a list whose elements are written next, one by one
+func (e *Encoder) Count(n int) {
+	e.uvarint(uint64(n))
+}
+
+func (e *Encoder) Zrs(zs []*bn256.Zr) {
+	e.uvarint(uint64(len(zs)))
+	for _, z := range zs {
+		e.Zr(z)
+	}
+}
+
+func (e *Encoder) G1s(gs []*bn256.G1) {
+	e.uvarint(uint64(len(gs)))
+	for _, g := range gs {
+		e.G1(g)
+	}
+}
+
+func (e *Encoder) BytesList(bs [][]byte) {
+	e.uvarint(uint64(len(bs)))
+	for _, b := range bs {
+		e.Bytes(b)
+	}
+}
+
+// Encoded returns the encoding of the elements written so far
+func (e *Encoder) Encoded() []byte {
+	return e.buf
+}
+
+// Decoder decodes the elements of a proof encoded by an Encoder, in the same order.
+// The first error is kept and returned by Err, the elements read afterwards are nil.
+type Decoder struct {
+	raw []byte
+	err error
+}
+
+// NewDecoder returns a decoder of the passed proof, it fails if the proof has not the binary encoding
+func NewDecoder(raw []byte) (*Decoder, error) {
+	if !IsBinary(raw) {
+		return nil, errors.Errorf("unsupported proof encoding")
+	}
+	return &Decoder{raw: raw[1:]}, nil
+}
+
+func (d *Decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.raw)
+	if n <= 0 {
+		d.err = errors.Errorf("invalid proof encoding: malformed length")
+		return 0
+	}
+	d.raw = d.raw[n:]
+	return v
+}
+
+// Count reads the number of elements of a list written with Encoder.Count
+func (d *Decoder) Count() int {
+	return d.count()
+}
+
+func (d *Decoder) count() int {
+	n := d.uvarint()
+	// each element takes at least a byte, this bounds the allocations
+	if n > uint64(len(d.raw)) {
+		d.err = errors.Errorf("invalid proof encoding: [%d] elements exceed the remaining [%d] bytes", n, len(d.raw))
+		return 0
+	}
+	return int(n)
+}
+
+// bytes returns the next element and false if it is nil
+func (d *Decoder) bytes() ([]byte, bool) {
+	l := d.uvarint()
+	if d.err != nil || l == 0 {
+		return nil, false
+	}
+	l--
+	if l > uint64(len(d.raw)) {
+		d.err = errors.Errorf("invalid proof encoding: [%d] bytes exceed the remaining [%d]", l, len(d.raw))
+		return nil, false
+	}
+	b := append([]byte{}, d.raw[:l]...)
+	d.raw = d.raw[l:]
+	return b, true
+}
+
+func (d *Decoder) Bytes() []byte {
+	b, _ := d.bytes()
+	return b
+}
+
+func (d *Decoder) String() string {
+	return string(d.Bytes())
+}
+
+func (d *Decoder) Zr() *bn256.Zr {
+	b, ok := d.bytes()
+	if !ok {
+		return nil
+	}
+	return bn256.NewZrFromBytes(b)
+}
+
+func (d *Decoder) G1() *bn256.G1 {
+	b, ok := d.bytes()
+	if !ok {
+		return nil
+	}
+	g, err := bn256.NewG1FromBytes(b)
+	if err != nil {
+		d.err = errors.Wrap(err, "invalid proof encoding: invalid G1 element")
+		return nil
+	}
+	return g
+}
+
+func (d *Decoder) Zrs() []*bn256.Zr {
+	n := d.count()
+	if d.err != nil {
+		return nil
+	}
+	res := make([]*bn256.Zr, n)
+	for i := range res {
+		res[i] = d.Zr()
+	}
+	return res
+}
+
+func (d *Decoder) G1s() []*bn256.G1 {
+	n := d.count()
+	if d.err != nil {
+		return nil
+	}
+	res := make([]*bn256.G1, n)
+	for i := range res {
+		res[i] = d.G1()
+	}
+	return res
+}
+
+func (d *Decoder) BytesList() [][]byte {
+	n := d.count()
+	if d.err != nil {
+		return nil
+	}
+	res := make([][]byte, n)
+	for i := range res {
+		res[i] = d.Bytes()
+	}
+	return res
+}
+
+// Err returns the first error met, or an error if there are bytes left to decode
+func (d *Decoder) Err() error {
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.raw) != 0 {
+		return errors.Errorf("invalid proof encoding: [%d] trailing bytes", len(d.raw))
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package common_test
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type proof struct {
+	Challenge *bn256.Zr
+}
+
+func (p *proof) Serialize() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func (p *proof) SerializeBinary() ([]byte, error) {
+	e := common.NewEncoder()
+	e.Zr(p.Challenge)
+	return e.Encoded(), nil
+}
+
+var _ = Describe("Encoding", func() {
+	var g *bn256.G1
+	BeforeEach(func() {
+		var err error
+		g, err = bn256.HashToG1([]byte("generator"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("decodes the elements in the order they are encoded", func() {
+		e := common.NewEncoder()
+		e.Bytes([]byte("bytes"))
+		e.Bytes([]byte{})
+		e.String("string")
+		e.Zr(bn256.NewZrInt(42))
+		e.G1(g)
+		e.Count(2)
+		e.Zrs([]*bn256.Zr{bn256.NewZrInt(1), nil})
+		e.G1s([]*bn256.G1{g})
+		e.BytesList([][]byte{[]byte("a"), nil})
+		raw := e.Encoded()
+		Expect(common.IsBinary(raw)).To(BeTrue())
+
+		d, err := common.NewDecoder(raw)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Bytes()).To(Equal([]byte("bytes")))
+		Expect(d.Bytes()).To(Equal([]byte{}))
+		Expect(d.String()).To(Equal("string"))
+		Expect(d.Zr()).To(Equal(bn256.NewZrInt(42)))
+		Expect(d.G1().Equals(g)).To(BeTrue())
+		Expect(d.Count()).To(Equal(2))
+		zs := d.Zrs()
+		Expect(zs).To(HaveLen(2))
+		Expect(zs[0]).To(Equal(bn256.NewZrInt(1)))
+		Expect(zs[1]).To(BeNil())
+		gs := d.G1s()
+		Expect(gs).To(HaveLen(1))
+		Expect(gs[0].Equals(g)).To(BeTrue())
+		Expect(d.BytesList()).To(Equal([][]byte{[]byte("a"), nil}))
+		Expect(d.Err()).To(Succeed())
+	})
+
+	It("keeps the nil elements nil", func() {
+		e := common.NewEncoder()
+		e.Bytes(nil)
+		e.Zr(nil)
+		e.G1(nil)
+		d, err := common.NewDecoder(e.Encoded())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Bytes()).To(BeNil())
+		Expect(d.Zr()).To(BeNil())
+		Expect(d.G1()).To(BeNil())
+		Expect(d.Err()).To(Succeed())
+	})
+
+	It("rejects the proofs that are not in the binary encoding", func() {
+		raw, err := json.Marshal(&proof{Challenge: bn256.NewZrInt(1)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(common.IsBinary(raw)).To(BeFalse())
+		_, err = common.NewDecoder(raw)
+		Expect(err).To(MatchError("unsupported proof encoding"))
+		_, err = common.NewDecoder(nil)
+		Expect(err).To(MatchError("unsupported proof encoding"))
+	})
+
+	It("rejects the truncated elements", func() {
+		e := common.NewEncoder()
+		e.Bytes([]byte("bytes"))
+		raw := e.Encoded()
+		d, err := common.NewDecoder(raw[:len(raw)-1])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Bytes()).To(BeNil())
+		Expect(d.Err()).To(MatchError("invalid proof encoding: [5] bytes exceed the remaining [4]"))
+	})
+
+	It("rejects the malformed lengths", func() {
+		d, err := common.NewDecoder([]byte{common.EncodingVersion, 0xff})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Bytes()).To(BeNil())
+		Expect(d.Err()).To(MatchError("invalid proof encoding: malformed length"))
+		// the elements read after the first error are nil
+		Expect(d.Zr()).To(BeNil())
+		Expect(d.Err()).To(MatchError("invalid proof encoding: malformed length"))
+	})
+
+	It("rejects the lists with more elements than the remaining bytes", func() {
+		e := common.NewEncoder()
+		e.Count(1000)
+		e.Zr(bn256.NewZrInt(1))
+		d, err := common.NewDecoder(e.Encoded())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Zrs()).To(BeNil())
+		Expect(d.Err()).To(MatchError(ContainSubstring("[1000] elements exceed the remaining")))
+	})
+
+	It("rejects the trailing bytes", func() {
+		e := common.NewEncoder()
+		e.Zr(bn256.NewZrInt(1))
+		raw := append(e.Encoded(), 0, 0)
+		d, err := common.NewDecoder(raw)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Zr()).NotTo(BeNil())
+		Expect(d.Err()).To(MatchError("invalid proof encoding: [2] trailing bytes"))
+	})
+
+	It("rejects the invalid G1 elements", func() {
+		e := common.NewEncoder()
+		e.Bytes([]byte("not a point"))
+		d, err := common.NewDecoder(e.Encoded())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.G1()).To(BeNil())
+		Expect(d.Err()).To(MatchError(ContainSubstring("invalid proof encoding: invalid G1 element")))
+	})
+
+	Describe("ProofEncoding", func() {
+		It("encodes the proofs with JSON unless the binary encoding is selected", func() {
+			p := &proof{Challenge: bn256.NewZrInt(1)}
+			var e common.ProofEncoding
+			Expect(e).To(Equal(common.JSONEncoding))
+			Expect(e.Validate()).To(Succeed())
+			raw, err := e.Encode(p)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(raw).To(MatchJSON(`{"Challenge":` + mustMarshal(p.Challenge) + `}`))
+
+			Expect(common.BinaryEncoding.Validate()).To(Succeed())
+			raw, err = common.BinaryEncoding.Encode(p)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(common.IsBinary(raw)).To(BeTrue())
+
+			Expect(common.ProofEncoding(7).Validate()).To(MatchError("unknown proof encoding [7]"))
+			_, err = common.ProofEncoding(7).Encode(p)
+			Expect(err).To(MatchError("unknown proof encoding [7]"))
+		})
+	})
+})
+
+func mustMarshal(v interface{}) string {
+	raw, err := json.Marshal(v)
+	Expect(err).NotTo(HaveOccurred())
+	return string(raw)
+}
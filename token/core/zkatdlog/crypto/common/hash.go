@@ -0,0 +1,93 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package common
+
+import (
+	"crypto/sha256"
+	"hash"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+)
+
+// ChallengeHash identifies the hash function from which the challenges of the zero-knowledge proofs are derived.
+// The empty identifier stands for SHA256, the hash function used before the challenge hash became selectable.
+type ChallengeHash string
+
+const (
+	SHA256     ChallengeHash = "SHA-256"
+	SHA3256    ChallengeHash = "SHA3-256"
+	BLAKE2b256 ChallengeHash = "BLAKE2b-256"
+)
+
+var (
+	hashesLock sync.RWMutex
+	hashes     = map[ChallengeHash]func() hash.Hash{
+		SHA256:  sha256.New,
+		SHA3256: sha3.New256,
+		BLAKE2b256: func() hash.Hash {
+			h, _ := blake2b.New256(nil)
+			return h
+		},
+	}
+)
+
+// RegisterChallengeHash makes the passed hash function available as challenge hash under the passed identifier.
+// The provers and the verifiers must register the same functions.
+func RegisterChallengeHash(id ChallengeHash, h func() hash.Hash) error {
+	if len(id) == 0 || h == nil {
+		return errors.Errorf("invalid challenge hash [%s]", id)
+	}
+	hashesLock.Lock()
+	defer hashesLock.Unlock()
+	if _, ok := hashes[id]; ok {
+		return errors.Errorf("challenge hash [%s] already registered", id)
+	}
+	hashes[id] = h
+	return nil
+}
+
+// Validate returns an error if the challenge hash is not registered
+func (c ChallengeHash) Validate() error {
+	_, err := c.new()
+	return err
+}
+
+// Digest returns the digest of the passed data
+func (c ChallengeHash) Digest(data []byte) []byte {
+	h, err := c.new()
+	if err != nil {
+		// the public parameters are validated, an unknown hash is a programming error
+		panic(err)
+	}
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// HashModOrder returns the digest of the passed data reduced modulo the order of the group,
+// see bn256.HashModOrder
+func (c ChallengeHash) HashModOrder(data []byte) *bn256.Zr {
+	digest := bn256.NewZrFromBytes(c.Digest(data))
+	digest.Mod(bn256.Order)
+	return digest
+}
+
+func (c ChallengeHash) new() (hash.Hash, error) {
+	if len(c) == 0 {
+		return sha256.New(), nil
+	}
+	hashesLock.RLock()
+	defer hashesLock.RUnlock()
+	h, ok := hashes[c]
+	if !ok {
+		return nil, errors.Errorf("unknown challenge hash [%s]", c)
+	}
+	return h(), nil
+}
@@ -84,7 +84,7 @@ func (v *NYMVerifier) Verify(message []byte, signature []byte) error {
 	sp := &SchnorrProof{Challenge: sig.Challenge, Proof: []*bn256.Zr{sig.SK, sig.BF}, Statement: v.NYM}
 	com := sv.RecomputeCommitment(sp)
 	chal := bn256.HashModOrder(append(message, GetG1Array(v.NYMParams, []*bn256.G1{v.NYM, com}).Bytes()...))
-	if chal.Cmp(sig.Challenge) != 0 {
+	if !bn256.ConstantTimeEqual(chal, sig.Challenge) {
 		return errors.Errorf("invalid nym signature")
 	}
 	return nil
@@ -0,0 +1,56 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package common
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Parallel invokes f on each index in 0, ..., n-1 using at most workers goroutines.
+// If workers is not positive, the number of available CPUs is used.
+// Parallel returns the error of the first failed invocation, if any.
+func Parallel(n int, workers int, f func(i int) error) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			if err := f(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	indices := make(chan int, n)
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+	close(indices)
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				if err := f(i); err != nil {
+					once.Do(func() {
+						firstErr = err
+					})
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
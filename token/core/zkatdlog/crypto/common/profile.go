@@ -0,0 +1,41 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package common
+
+import (
+	"context"
+	"runtime/pprof"
+	"sync/atomic"
+)
+
+// ProfileLabel is the key of the pprof label attached to the hot loops of the provers and verifiers
+const ProfileLabel = "zkatdlog"
+
+// profiling is not zero when the pprof labels are enabled
+var profiling int32
+
+// EnableProfilingLabels turns on or off the pprof labels of the hot loops of the provers and verifiers, so that
+// the samples of a CPU profile are attributed to the proof being computed. The labels are off by default.
+func EnableProfilingLabels(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&profiling, v)
+}
+
+// Profile invokes f with the passed context, labelled with the passed value if the pprof labels are enabled.
+// The labels of the passed context are kept, and the goroutines started by f inherit them.
+func Profile(ctx context.Context, value string, f func(ctx context.Context) error) error {
+	if atomic.LoadInt32(&profiling) == 0 {
+		return f(ctx)
+	}
+	var err error
+	pprof.Do(ctx, pprof.Labels(ProfileLabel, value), func(ctx context.Context) {
+		err = f(ctx)
+	})
+	return err
+}
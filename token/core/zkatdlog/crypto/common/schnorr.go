@@ -20,6 +20,8 @@ type SchnorrProof struct {
 
 type SchnorrVerifier struct {
 	PedParams []*bn256.G1
+	// Hash derives the challenges, SHA256 if empty
+	Hash ChallengeHash
 }
 
 type SchnorrProver struct {
@@ -32,9 +34,11 @@ type SchnorrProver struct {
 func (v *SchnorrVerifier) RecomputeCommitment(zkp *SchnorrProof) *bn256.G1 {
 	com := bn256.NewG1()
 	for i, p := range zkp.Proof {
-		com.Add(v.PedParams[i].Mul(p))
+		com.AddMul(v.PedParams[i], p)
 	}
-	com.Sub(zkp.Statement.Mul(zkp.Challenge))
+	statement := bn256.GetG1().SetMul(zkp.Statement, zkp.Challenge)
+	com.Sub(statement)
+	bn256.PutG1(statement)
 	return com
 }
 
@@ -52,6 +56,11 @@ func ComputeChallenge(pub PublicInput) *bn256.Zr {
 	return bn256.HashModOrder(raw)
 }
 
+// ComputeChallenge returns the challenge over the passed public input, derived with the hash of the verifier
+func (v *SchnorrVerifier) ComputeChallenge(pub PublicInput) *bn256.Zr {
+	return v.Hash.HashModOrder(pub.Bytes())
+}
+
 func (p *SchnorrProver) Prove() ([]*bn256.Zr, error) {
 	if len(p.Witness) != len(p.Randomness) {
 		return nil, errors.Errorf("cannot compute proof")
@@ -70,7 +79,7 @@ func ComputePedersenCommitment(opening []*bn256.Zr, base []*bn256.G1) (*bn256.G1
 	}
 	com := bn256.NewG1()
 	for i := 0; i < len(base); i++ {
-		com.Add(base[i].Mul(opening[i]))
+		com.AddMul(base[i], opening[i])
 	}
 	return com, nil
 }
@@ -5,6 +5,8 @@ SPDX-License-Identifier: Apache-2.0
 */
 package common
 
+import "context"
+
 type PublicInput interface {
 	Bytes() []byte
 }
@@ -16,3 +18,32 @@ type Prover interface {
 type Verifier interface {
 	Verify([]byte) error
 }
+
+// ContextVerifier is a Verifier whose verification can be aborted
+type ContextVerifier interface {
+	VerifyWithContext(ctx context.Context, raw []byte) error
+}
+
+// VerifyWithContext verifies the passed proof with the passed verifier. The verification is aborted
+// with the error of the passed context, once done, if the verifier supports it.
+func VerifyWithContext(ctx context.Context, v Verifier, raw []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if cv, ok := v.(ContextVerifier); ok {
+		return cv.VerifyWithContext(ctx, raw)
+	}
+	return v.Verify(raw)
+}
+
+// Zeroizer is a Prover that can wipe the secrets it holds
+type Zeroizer interface {
+	Zeroize()
+}
+
+// Zeroize wipes the secrets held by the passed prover, if it supports it
+func Zeroize(p Prover) {
+	if z, ok := p.(Zeroizer); ok {
+		z.Zeroize()
+	}
+}
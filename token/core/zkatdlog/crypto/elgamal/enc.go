@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package elgamal
 
 import (
+	"encoding/json"
+
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
 	"github.com/pkg/errors"
 )
@@ -35,6 +37,41 @@ func NewSecretKey(sk *bn256.Zr, gen, pk *bn256.G1) *SecretKey {
 	}
 }
 
+// NewKeyPair generates a new Elgamal secret key for the passed generator
+func NewKeyPair(gen *bn256.G1) (*SecretKey, error) {
+	rand, err := bn256.GetRand()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to generate Elgamal key pair")
+	}
+	x := bn256.RandModOrder(rand)
+	return NewSecretKey(x, gen, gen.Mul(x)), nil
+}
+
+type serializedSecretKey struct {
+	Gen *bn256.G1
+	H   *bn256.G1
+	X   *bn256.Zr
+}
+
+func (sk *SecretKey) Serialize() ([]byte, error) {
+	return json.Marshal(&serializedSecretKey{Gen: sk.Gen, H: sk.H, X: sk.x})
+}
+
+func (sk *SecretKey) Deserialize(raw []byte) error {
+	s := &serializedSecretKey{}
+	if err := json.Unmarshal(raw, s); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal Elgamal secret key")
+	}
+	if s.Gen == nil || s.H == nil || s.X == nil {
+		return errors.Errorf("invalid Elgamal secret key")
+	}
+	if !s.Gen.Mul(s.X).Equals(s.H) {
+		return errors.Errorf("invalid Elgamal secret key: secret and public key do not match")
+	}
+	*sk = *NewSecretKey(s.X, s.Gen, s.H)
+	return nil
+}
+
 // encrypt using Elgamal encryption
 func (pk *PublicKey) Encrypt(M *bn256.G1) (*Ciphertext, *bn256.Zr, error) {
 	if pk.Gen == nil || pk.H == nil {
@@ -28,4 +28,60 @@ var _ = Describe("Elgamal encryption", func() {
 			})
 		})
 	})
+
+	Describe("EncryptMessage", func() {
+		var SK *elgamal.SecretKey
+		BeforeEach(func() {
+			var err error
+			SK, err = elgamal.NewKeyPair(bn256.G1Gen())
+			Expect(err).NotTo(HaveOccurred())
+		})
+		Context("Encryption performed correctly", func() {
+			It("Succeeds", func() {
+				c, err := SK.PublicKey.EncryptMessage([]byte("audit info"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(c.WellFormed()).To(Succeed())
+				raw, err := c.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				c2 := &elgamal.EncryptedMessage{}
+				Expect(c2.Deserialize(raw)).To(Succeed())
+				m, err := SK.DecryptMessage(c2)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(m).To(Equal([]byte("audit info")))
+			})
+		})
+		Context("Decryption with another key", func() {
+			It("Fails", func() {
+				c, err := SK.PublicKey.EncryptMessage([]byte("audit info"))
+				Expect(err).NotTo(HaveOccurred())
+				other, err := elgamal.NewKeyPair(bn256.G1Gen())
+				Expect(err).NotTo(HaveOccurred())
+				_, err = other.DecryptMessage(c)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+		Context("Malformed encrypted message", func() {
+			It("Fails", func() {
+				c, err := SK.PublicKey.EncryptMessage([]byte("audit info"))
+				Expect(err).NotTo(HaveOccurred())
+				c.Nonce = c.Nonce[1:]
+				Expect(c.WellFormed()).NotTo(Succeed())
+				_, err = SK.DecryptMessage(c)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+		Context("Secret key serialization", func() {
+			It("Succeeds", func() {
+				raw, err := SK.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				sk := &elgamal.SecretKey{}
+				Expect(sk.Deserialize(raw)).To(Succeed())
+				c, err := SK.PublicKey.EncryptMessage([]byte("audit info"))
+				Expect(err).NotTo(HaveOccurred())
+				m, err := sk.DecryptMessage(c)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(m).To(Equal([]byte("audit info")))
+			})
+		})
+	})
 })
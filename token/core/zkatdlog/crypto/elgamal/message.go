@@ -0,0 +1,111 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package elgamal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/pkg/errors"
+)
+
+const (
+	nonceSize = 12
+	tagSize   = 16
+)
+
+// EncryptedMessage is the encryption of an arbitrary message.
+// The message is encrypted with AES-GCM under a key derived from a random group element,
+// and the group element is Elgamal encrypted.
+type EncryptedMessage struct {
+	Key     *Ciphertext
+	Nonce   []byte
+	Payload []byte
+}
+
+// EncryptMessage encrypts the passed message
+func (pk *PublicKey) EncryptMessage(msg []byte) (*EncryptedMessage, error) {
+	if pk.Gen == nil || pk.H == nil {
+		return nil, errors.Errorf("Provide a non-nil Elgamal public key")
+	}
+	rand, err := bn256.GetRand()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get RNG")
+	}
+	M := pk.Gen.Mul(bn256.RandModOrder(rand))
+	c, _, err := pk.Encrypt(M)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(M)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := rand(nonce); err != nil {
+		return nil, errors.Wrapf(err, "failed to generate nonce")
+	}
+	return &EncryptedMessage{
+		Key:     c,
+		Nonce:   nonce,
+		Payload: aead.Seal(nil, nonce, msg, nil),
+	}, nil
+}
+
+// DecryptMessage decrypts the passed encrypted message
+func (sk *SecretKey) DecryptMessage(c *EncryptedMessage) ([]byte, error) {
+	if err := c.WellFormed(); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(sk.Decrypt(c.Key))
+	if err != nil {
+		return nil, err
+	}
+	msg, err := aead.Open(nil, c.Nonce, c.Payload, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decrypt message")
+	}
+	return msg, nil
+}
+
+// WellFormed checks that the encrypted message has the expected structure.
+// It does not check under which key the message has been encrypted.
+func (c *EncryptedMessage) WellFormed() error {
+	if c.Key == nil || c.Key.C1 == nil || c.Key.C2 == nil {
+		return errors.Errorf("invalid encrypted message: missing key ciphertext")
+	}
+	if len(c.Nonce) != nonceSize {
+		return errors.Errorf("invalid encrypted message: nonce must be [%d] bytes, got [%d]", nonceSize, len(c.Nonce))
+	}
+	if len(c.Payload) < tagSize {
+		return errors.Errorf("invalid encrypted message: payload too short")
+	}
+	return nil
+}
+
+func (c *EncryptedMessage) Serialize() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+func (c *EncryptedMessage) Deserialize(raw []byte) error {
+	return json.Unmarshal(raw, c)
+}
+
+func newAEAD(M *bn256.G1) (cipher.AEAD, error) {
+	key := sha256.Sum256(M.Bytes())
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to instantiate cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to instantiate cipher")
+	}
+	return aead, nil
+}
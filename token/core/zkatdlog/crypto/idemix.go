@@ -0,0 +1,83 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package crypto
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+)
+
+// IdemixIssuerKey is an idemix issuer public key the credentials of the token owners can be issued under
+type IdemixIssuerKey struct {
+	PK []byte
+	// Epoch is the epoch the key has been introduced at
+	Epoch uint64
+	// ExpiresAt, if not zero, is the epoch from which the credentials issued under this key are no longer accepted
+	ExpiresAt uint64 `json:",omitempty"`
+}
+
+// Accepted returns true if the credentials issued under this key are accepted at the passed epoch
+func (k *IdemixIssuerKey) Accepted(epoch uint64) bool {
+	return epoch >= k.Epoch && (k.ExpiresAt == 0 || epoch < k.ExpiresAt)
+}
+
+// RotateIdemixPK starts a new epoch whose owner credentials are issued under the passed idemix issuer public key.
+// The credentials issued under the previous keys are still accepted for the passed number of epochs, forever if zero.
+// IdemixPK is set to the new key.
+func (pp *PublicParams) RotateIdemixPK(pk []byte, gracePeriod uint64) error {
+	defer pp.ResetHash()
+	if len(pk) == 0 {
+		return errors.New("invalid idemix issuer public key")
+	}
+	for _, k := range pp.idemixKeys() {
+		if bytes.Equal(k.PK, pk) {
+			return errors.Errorf("idemix issuer public key already introduced at epoch [%d]", k.Epoch)
+		}
+	}
+	if len(pp.IdemixKeys) == 0 && len(pp.IdemixPK) != 0 {
+		pp.IdemixKeys = []*IdemixIssuerKey{{PK: pp.IdemixPK, Epoch: pp.IdemixEpoch}}
+	}
+	pp.IdemixEpoch++
+	for _, k := range pp.IdemixKeys {
+		if k.ExpiresAt == 0 && gracePeriod != 0 {
+			k.ExpiresAt = pp.IdemixEpoch + gracePeriod
+		}
+	}
+	pp.IdemixKeys = append(pp.IdemixKeys, &IdemixIssuerKey{PK: pk, Epoch: pp.IdemixEpoch})
+	pp.IdemixPK = pk
+	return nil
+}
+
+// AdvanceIdemixEpoch moves to the next epoch without introducing a new key, expiring the keys whose grace period ends
+func (pp *PublicParams) AdvanceIdemixEpoch() {
+	defer pp.ResetHash()
+	if len(pp.IdemixKeys) == 0 && len(pp.IdemixPK) != 0 {
+		pp.IdemixKeys = []*IdemixIssuerKey{{PK: pp.IdemixPK, Epoch: pp.IdemixEpoch}}
+	}
+	pp.IdemixEpoch++
+}
+
+// AcceptedIdemixPKs returns the idemix issuer public keys whose credentials are accepted at the current epoch,
+// the most recent first
+func (pp *PublicParams) AcceptedIdemixPKs() [][]byte {
+	var res [][]byte
+	keys := pp.idemixKeys()
+	for i := len(keys) - 1; i >= 0; i-- {
+		if keys[i].Accepted(pp.IdemixEpoch) {
+			res = append(res, keys[i].PK)
+		}
+	}
+	return res
+}
+
+// idemixKeys returns the idemix issuer keys, the public parameters that never rotated have IdemixPK only
+func (pp *PublicParams) idemixKeys() []*IdemixIssuerKey {
+	if len(pp.IdemixKeys) != 0 {
+		return pp.IdemixKeys
+	}
+	return []*IdemixIssuerKey{{PK: pp.IdemixPK, Epoch: pp.IdemixEpoch}}
+}
@@ -0,0 +1,40 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotateIdemixPK(t *testing.T) {
+	pp := &PublicParams{IdemixPK: []byte("pk0")}
+	assert.Equal(t, [][]byte{[]byte("pk0")}, pp.AcceptedIdemixPKs())
+
+	// the credentials of pk0 are accepted for one more epoch
+	assert.NoError(t, pp.RotateIdemixPK([]byte("pk1"), 1))
+	assert.Equal(t, []byte("pk1"), pp.IdemixPK)
+	assert.Equal(t, uint64(1), pp.IdemixEpoch)
+	assert.Equal(t, [][]byte{[]byte("pk1"), []byte("pk0")}, pp.AcceptedIdemixPKs())
+	assert.Error(t, pp.RotateIdemixPK([]byte("pk0"), 1))
+
+	pp.AdvanceIdemixEpoch()
+	assert.Equal(t, [][]byte{[]byte("pk1")}, pp.AcceptedIdemixPKs())
+
+	// the credentials of pk1 are accepted forever
+	assert.NoError(t, pp.RotateIdemixPK([]byte("pk2"), 0))
+	pp.AdvanceIdemixEpoch()
+	pp.AdvanceIdemixEpoch()
+	assert.Equal(t, [][]byte{[]byte("pk2"), []byte("pk1")}, pp.AcceptedIdemixPKs())
+
+	// the keys survive serialization
+	raw, err := pp.Serialize()
+	assert.NoError(t, err)
+	pp2, err := NewPublicParamsFromBytes(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, pp.AcceptedIdemixPKs(), pp2.AcceptedIdemixPKs())
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	issue2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/pkg/errors"
 )
@@ -36,6 +37,8 @@ func (i *Issuer) GenerateZKIssue(values []uint64, owners [][]byte) (*issue2.Issu
 	}
 
 	prover := issue2.NewProver(tw, tokens, true, i.PublicParams)
+	// the witnesses are wiped on return, what outlives them holds copies
+	defer prover.Zeroize()
 	proof, err := prover.Prove()
 	if err != nil {
 		return nil, nil, errors.Errorf("failed to generate zero knwoledge proof for issue")
@@ -43,11 +46,12 @@ func (i *Issuer) GenerateZKIssue(values []uint64, owners [][]byte) (*issue2.Issu
 
 	i.Signer, err = CreateSigner(
 		tokens[0],
-		tw[0].Value,
-		tw[0].BlindingFactor,
+		bn256.NewZrCopy(tw[0].Value),
+		bn256.NewZrCopy(tw[0].BlindingFactor),
 		bn256.HashModOrder([]byte(i.Type)),
 		i.Signer.(*Signer).Witness.Sk,
 		i.Signer.(*Signer).Witness.Index,
+		i.Signer.(*Signer).Witness.Credential,
 		i.PublicParams,
 	)
 
@@ -63,8 +67,8 @@ func (i *Issuer) GenerateZKIssue(values []uint64, owners [][]byte) (*issue2.Issu
 	for j := 0; j < len(inf); j++ {
 		inf[j] = &token.TokenInformation{
 			Type:           i.Type,
-			Value:          tw[j].Value,
-			BlindingFactor: tw[j].BlindingFactor,
+			Value:          bn256.NewZrCopy(tw[j].Value),
+			BlindingFactor: bn256.NewZrCopy(tw[j].BlindingFactor),
 			Owner:          owners[j],
 		}
 	}
@@ -76,7 +80,7 @@ func (i *Issuer) SignTokenActions(raw []byte, txID string) ([]byte, error) {
 	return i.Signer.Sign(append(raw, []byte(txID)...))
 }
 
-func CreateSigner(token *bn256.G1, value, tokenBF, ttype, sk *bn256.Zr, index int, pp *crypto.PublicParams) (*Signer, error) {
+func CreateSigner(token *bn256.G1, value, tokenBF, ttype, sk *bn256.Zr, index int, credential *pssign.Signature, pp *crypto.PublicParams) (*Signer, error) {
 	rand, err := bn256.GetRand()
 	if err != nil {
 		return nil, errors.Errorf("failed to get random generator for issuer's signer")
@@ -97,11 +101,25 @@ func CreateSigner(token *bn256.G1, value, tokenBF, ttype, sk *bn256.Zr, index in
 
 	// initialize issuer witness
 	auth := NewAuthorization(typeNym, token)
+	if ip.Accumulator != nil {
+		witness := NewAccumulatorWitness(sk, ttype, value, tnymbf, tokenBF, credential)
+
+		logger.Debugf("NewIssuerAuthSigner with accumulator [%d]", ip.Accumulator.Epoch)
+
+		signer := NewSigner(witness, nil, auth, 0, pp.ZKATPedParams)
+		signer.Accumulator = ip.Accumulator
+		signer.Hash = pp.ChallengeHash
+		signer.Encoding = pp.ProofEncoding
+		return signer, nil
+	}
 	witness := NewWitness(sk, ttype, value, tnymbf, tokenBF, index)
 
 	logger.Debugf("NewIssuerAuthSigner [%d,%d,%d]", len(ip.Issuers), ip.IssuersNumber, ip.BitLength)
 
-	return NewSigner(witness, ip.Issuers, auth, ip.BitLength, pp.ZKATPedParams), nil
+	signer := NewSigner(witness, ip.Issuers, auth, ip.BitLength, pp.ZKATPedParams)
+	signer.Hash = pp.ChallengeHash
+	signer.Encoding = pp.ProofEncoding
+	return signer, nil
 }
 
 func GenerateKeyPair(ttype string, pp *crypto.PublicParams) (*bn256.Zr, *bn256.G1, error) {
@@ -13,7 +13,11 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/api"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/o2omp"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/sigproof"
 	"github.com/pkg/errors"
 )
 
@@ -30,11 +34,15 @@ type AuthorizationWitness struct {
 	Value   *bn256.Zr // Value in token
 	TokenBF *bn256.Zr // randomness in token
 	Index   int       // index of Type
+	// Credential is the signature of the issuer accumulator on (Sk, TType), if the accumulator is in use
+	Credential *pssign.Signature
 }
 
 type Signer struct {
 	*Verifier
 	Witness *AuthorizationWitness
+	// Encoding is the encoding of the membership proof. It is not part of the identity of the issuer.
+	Encoding common.ProofEncoding `json:"-"`
 }
 
 type Verifier struct {
@@ -42,11 +50,17 @@ type Verifier struct {
 	Issuers        []*bn256.G1 // g_0^skg_1^type
 	Auth           *Authorization
 	BitLength      int
+	// Accumulator, if set, replaces Issuers
+	Accumulator *crypto.IssuerAccumulator
+	// Hash derives the challenges of the proofs, SHA256 if empty. It is not part of the identity of the issuer.
+	Hash common.ChallengeHash `json:"-"`
 }
 
 type Signature struct {
 	AuthorizationCorrectness []byte
 	TypeCorrectness          []byte
+	// Membership proves that the issuer holds a credential of the accumulator, it replaces AuthorizationCorrectness
+	Membership []byte
 }
 
 // check that the issuer knows the secret key of one of the commitments that link issuers to type
@@ -56,25 +70,35 @@ func (s *Signer) Sign(message []byte) ([]byte, error) {
 		return nil, errors.Errorf("length of Pedersen parameters != 3")
 	}
 
-	// one out of many proofs
-	commitments := make([]*bn256.G1, len(s.Issuers))
-	for k, i := range s.Issuers {
-		commitments[k] = bn256.NewG1()
-		commitments[k].Copy(s.Auth.Type)
-		commitments[k].Sub(i)
-	}
-	o2omp := o2omp.NewProver(commitments, message, []*bn256.G1{s.PedersenParams[0], s.PedersenParams[2]}, s.BitLength, s.Witness.Index, s.Witness.TNymBF)
-
 	sig := &Signature{}
 	var err error
-	sig.AuthorizationCorrectness, err = o2omp.Prove()
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to compute issuer's signature")
+	if s.Accumulator != nil {
+		// membership proof
+		sig.Membership, err = s.proveMembership()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to compute issuer's signature")
+		}
+	} else {
+		// one out of many proofs
+		commitments := make([]*bn256.G1, len(s.Issuers))
+		for k, i := range s.Issuers {
+			commitments[k] = bn256.NewG1()
+			commitments[k].Copy(s.Auth.Type)
+			commitments[k].Sub(i)
+		}
+		o2omp := o2omp.NewProver(commitments, message, []*bn256.G1{s.PedersenParams[0], s.PedersenParams[2]}, s.BitLength, s.Witness.Index, s.Witness.TNymBF)
+		o2omp.Hash = s.Hash
+
+		sig.AuthorizationCorrectness, err = o2omp.Prove()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to compute issuer's signature")
+		}
 	}
 
 	w := NewTypeCorrectnessWitness(s.Witness.Sk, s.Witness.TType, s.Witness.Value, s.Witness.TNymBF, s.Witness.TokenBF)
 
 	tcp := NewTypeCorrectnessProver(w, s.Auth.Type, s.Auth.Token, message, s.PedersenParams)
+	tcp.Hash = s.Hash
 	sig.TypeCorrectness, err = tcp.Prove()
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to compute issuer's signature")
@@ -93,21 +117,70 @@ func (v *Verifier) Verify(message, rawsig []byte) error {
 	if err != nil {
 		return errors.Errorf("failed to unmarshal issuer's signature")
 	}
-	commitments := make([]*bn256.G1, len(v.Issuers))
-	for k, i := range v.Issuers {
-		commitments[k] = bn256.NewG1()
-		commitments[k].Copy(v.Auth.Type)
-		commitments[k].Sub(i)
+	if v.Accumulator != nil {
+		// verify membership proof: issuer authorization
+		if err := v.verifyMembership(sig.Membership); err != nil {
+			return errors.Wrapf(err, "failed to verify issuer's pseudonym")
+		}
+	} else {
+		commitments := make([]*bn256.G1, len(v.Issuers))
+		for k, i := range v.Issuers {
+			commitments[k] = bn256.NewG1()
+			commitments[k].Copy(v.Auth.Type)
+			commitments[k].Sub(i)
+		}
+
+		// verify one out of many proof: issuer authorization
+		verifier := o2omp.NewVerifier(commitments, message, []*bn256.G1{v.PedersenParams[0], v.PedersenParams[2]}, v.BitLength)
+		verifier.Hash = v.Hash
+		err = verifier.Verify(sig.AuthorizationCorrectness)
+		if err != nil {
+			return errors.Wrapf(err, "failed to verify issuer's pseudonym")
+		}
 	}
 
-	// verify one out of many proof: issuer authorization
-	err = o2omp.NewVerifier(commitments, message, []*bn256.G1{v.PedersenParams[0], v.PedersenParams[2]}, v.BitLength).Verify(sig.AuthorizationCorrectness)
+	// verify that type in authorization corresponds to type in token
+	tcv := NewTypeCorrectnessVerifier(v.Auth.Type, v.Auth.Token, message, v.PedersenParams)
+	tcv.Hash = v.Hash
+	return tcv.Verify(sig.TypeCorrectness)
+}
+
+// proveMembership proves that the secret key and the type in the issuer's pseudonym are signed by the accumulator
+func (s *Signer) proveMembership() ([]byte, error) {
+	if s.Witness.Credential == nil {
+		return nil, errors.Errorf("issuer credential not available")
+	}
+	hidden := []*bn256.Zr{s.Witness.Sk, s.Witness.TType}
+	credential := &pssign.Signature{}
+	credential.Copy(s.Witness.Credential)
+	prover := sigproof.NewSigProver(
+		hidden, nil, credential, sigproof.HashMessages(hidden), s.Witness.TNymBF, s.Auth.Type,
+		[]int{0, 1}, nil, s.Accumulator.P, s.Accumulator.Q, s.Accumulator.PK, s.PedersenParams,
+	)
+	prover.Hash = s.Hash
+	proof, err := prover.Prove()
 	if err != nil {
-		return errors.Wrapf(err, "failed to verify issuer's pseudonym")
+		return nil, err
 	}
+	return s.Encoding.Encode(proof)
+}
 
-	// verify that type in authorization corresponds to type in token
-	return NewTypeCorrectnessVerifier(v.Auth.Type, v.Auth.Token, message, v.PedersenParams).Verify(sig.TypeCorrectness)
+func (v *Verifier) verifyMembership(raw []byte) error {
+	if len(raw) == 0 {
+		return errors.Errorf("missing membership proof")
+	}
+	proof := &sigproof.SigProof{}
+	if err := proof.Deserialize(raw); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal membership proof")
+	}
+	if proof.Commitment == nil || !proof.Commitment.Equals(v.Auth.Type) {
+		return errors.Errorf("membership proof does not refer to the issuer's pseudonym")
+	}
+	verifier := sigproof.NewSigVerifier(
+		[]int{0, 1}, nil, nil, v.Auth.Type, v.Accumulator.P, v.Accumulator.Q, v.Accumulator.PK, v.PedersenParams,
+	)
+	verifier.Hash = v.Hash
+	return verifier.Verify(proof)
 }
 
 func (s *Signature) Serialize() ([]byte, error) {
@@ -129,6 +202,33 @@ func NewWitness(sk, ttype, value, tNymBF, tokenBF *bn256.Zr, index int) *Authori
 	}
 }
 
+// NewAccumulatorWitness returns the witness of an issuer authorized by the issuer accumulator with the passed credential
+func NewAccumulatorWitness(sk, ttype, value, tNymBF, tokenBF *bn256.Zr, credential *pssign.Signature) *AuthorizationWitness {
+	w := NewWitness(sk, ttype, value, tNymBF, tokenBF, 0)
+	w.Credential = credential
+	return w
+}
+
+// AuthorizeIssuer returns the credential, issued by the passed accumulator signer, of the issuer with the passed
+// secret key for the passed type
+func AuthorizeIssuer(authority *pssign.Signer, sk *bn256.Zr, ttype string) (*pssign.Signature, error) {
+	credential, err := authority.Sign([]*bn256.Zr{sk, bn256.HashModOrder([]byte(ttype))})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to issue credential")
+	}
+	return credential, nil
+}
+
+// VerifyCredential checks that the passed credential has been issued by the authority of the passed accumulator
+// to the issuer with the passed secret key for the passed type, see AuthorizeIssuer
+func VerifyCredential(accumulator *crypto.IssuerAccumulator, sk *bn256.Zr, ttype string, credential *pssign.Signature) error {
+	m := []*bn256.Zr{sk, bn256.HashModOrder([]byte(ttype))}
+	if err := pssign.NewVerifier(accumulator.PK, accumulator.Q).Verify(append(m, sigproof.HashMessages(m)), credential); err != nil {
+		return errors.Wrapf(err, "invalid issuer credential for [%s]", ttype)
+	}
+	return nil
+}
+
 // Initialize the prover
 func NewSigner(witness *AuthorizationWitness, issuers []*bn256.G1, auth *Authorization, bitLength int, pp []*bn256.G1) *Signer {
 
@@ -156,7 +256,7 @@ func (v *Verifier) Serialize() ([]byte, error) {
 	return json.Marshal(v)
 }
 
-func (v *Verifier) Deserialize(bitLength int, issuers, pp []*bn256.G1, token *bn256.G1, raw []byte) error {
+func (v *Verifier) Deserialize(bitLength int, issuers []*bn256.G1, accumulator *crypto.IssuerAccumulator, pp []*bn256.G1, token *bn256.G1, raw []byte) error {
 
 	err := json.Unmarshal(raw, &v)
 	if err != nil {
@@ -167,11 +267,12 @@ func (v *Verifier) Deserialize(bitLength int, issuers, pp []*bn256.G1, token *bn
 	v.BitLength = bitLength
 	v.PedersenParams = pp
 	v.Issuers = issuers
+	v.Accumulator = accumulator
 	return nil
 }
 
 func (s *Signer) GetPublicVersion() api.Identity {
-	return &Verifier{Auth: s.Auth, Issuers: s.Issuers, PedersenParams: s.PedersenParams, BitLength: s.BitLength}
+	return &Verifier{Auth: s.Auth, Issuers: s.Issuers, PedersenParams: s.PedersenParams, BitLength: s.BitLength, Accumulator: s.Accumulator, Hash: s.Hash}
 }
 
 func (s *Signer) ToUniqueIdentifier() ([]byte, error) {
@@ -7,7 +7,10 @@ package anonym_test
 
 import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/anonym"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -40,8 +43,77 @@ var _ = Describe("Issuer NYM", func() {
 			})
 		})
 	})
+
+	Describe("Signer with issuer accumulator", func() {
+		var (
+			accumulator *crypto.IssuerAccumulator
+			authority   *pssign.Signer
+		)
+		BeforeEach(func() {
+			var err error
+			accumulator, authority, err = crypto.NewIssuerAccumulator(0)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		When("the issuer holds a credential for the type", func() {
+			BeforeEach(func() {
+				signer = getAccumulatorSigner("ABC", "ABC", authority, accumulator, pp)
+				verifier = signer.Verifier
+			})
+			It("succeeds", func() {
+				sig, err := signer.Sign([]byte("message"))
+				Expect(err).NotTo(HaveOccurred())
+				err = verifier.Verify([]byte("message"), sig)
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("fails when the accumulator is rotated", func() {
+				sig, err := signer.Sign([]byte("message"))
+				Expect(err).NotTo(HaveOccurred())
+				rotated, _, err := crypto.NewIssuerAccumulator(1)
+				Expect(err).NotTo(HaveOccurred())
+				verifier.Accumulator = rotated
+				err = verifier.Verify([]byte("message"), sig)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to verify issuer's pseudonym"))
+			})
+		})
+		When("the issuer holds a credential for another type", func() {
+			BeforeEach(func() {
+				signer = getAccumulatorSigner("ABC", "DEF", authority, accumulator, pp)
+				verifier = signer.Verifier
+			})
+			It("fails", func() {
+				sig, err := signer.Sign([]byte("message"))
+				Expect(err).NotTo(HaveOccurred())
+				err = verifier.Verify([]byte("message"), sig)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed to verify issuer's pseudonym"))
+			})
+		})
+	})
 })
 
+func getAccumulatorSigner(authorizedType, issuedType string, authority *pssign.Signer, accumulator *crypto.IssuerAccumulator, pp []*bn256.G1) *anonym.Signer {
+	rand, err := bn256.GetRand()
+	Expect(err).NotTo(HaveOccurred())
+	sk := bn256.RandModOrder(rand)
+	value := bn256.RandModOrder(rand)
+	bf := []*bn256.Zr{bn256.RandModOrder(rand), bn256.RandModOrder(rand)}
+	ttype := bn256.HashModOrder([]byte(issuedType))
+
+	credential, err := anonym.AuthorizeIssuer(authority, sk, authorizedType)
+	Expect(err).NotTo(HaveOccurred())
+
+	typeNym, err := common.ComputePedersenCommitment([]*bn256.Zr{sk, ttype, bf[0]}, pp)
+	Expect(err).NotTo(HaveOccurred())
+	token, err := common.ComputePedersenCommitment([]*bn256.Zr{ttype, value, bf[1]}, pp)
+	Expect(err).NotTo(HaveOccurred())
+
+	witness := anonym.NewAccumulatorWitness(sk, ttype, value, bf[0], bf[1], credential)
+	signer := anonym.NewSigner(witness, nil, anonym.NewAuthorization(typeNym, token), 0, pp)
+	signer.Accumulator = accumulator
+	return signer
+}
+
 func GetIssuers(N, index int, pk *bn256.G1, pp []*bn256.G1) []*bn256.G1 {
 	rand, err := bn256.GetRand()
 	Expect(err).NotTo(HaveOccurred())
@@ -18,11 +18,15 @@ type TypeCorrectnessVerifier struct {
 	TypeNym        *bn256.G1
 	Token          *bn256.G1
 	Message        []byte
+	// Hash derives the challenges, SHA256 if empty
+	Hash common.ChallengeHash
 }
 
 type TypeCorrectnessProver struct {
 	*TypeCorrectnessVerifier
 	Witness *TypeCorrectnessWitness
+	// Rand is the source of the randomness of the proof, the default one if nil, see bn256.NewDRBG
+	Rand bn256.Rand
 }
 
 type TypeCorrectness struct {
@@ -60,7 +64,7 @@ func (p *TypeCorrectnessProver) Prove() ([]byte, error) {
 	if len(p.PedersenParams) != 3 {
 		return nil, errors.Errorf("provide Pedersen parameters of length 3")
 	}
-	rand, err := bn256.GetRand()
+	rand, err := bn256.RandOrDefault(p.Rand)
 	if err != nil {
 		return nil, errors.Errorf("failed to get random number generator")
 	}
@@ -89,7 +93,7 @@ func (p *TypeCorrectnessProver) Prove() ([]byte, error) {
 	g1Array := common.GetG1Array([]*bn256.G1{p.TypeNym, p.Token, coms.NYM, coms.Token}, p.PedersenParams)
 	bytes := g1Array.Bytes()
 	bytes = append(bytes, p.Message...)
-	proof.Challenge = bn256.HashModOrder(bytes)
+	proof.Challenge = p.Hash.HashModOrder(bytes)
 	// compute proof
 	proof.SK = bn256.ModAdd(bn256.ModMul(proof.Challenge, p.Witness.SK, bn256.Order), randomness.sk, bn256.Order)
 	proof.Type = bn256.ModAdd(bn256.ModMul(proof.Challenge, p.Witness.Type, bn256.Order), randomness.ttype, bn256.Order)
@@ -128,9 +132,9 @@ func (v *TypeCorrectnessVerifier) Verify(proof []byte) error {
 	bytes := g1array.Bytes()
 	bytes = append(bytes, v.Message...)
 	// recompute challenge
-	chal := bn256.HashModOrder(bytes)
+	chal := v.Hash.HashModOrder(bytes)
 	// check proof
-	if chal.Cmp(tc.Challenge) != 0 {
+	if !bn256.ConstantTimeEqual(chal, tc.Challenge) {
 		return errors.Errorf("origin of transaction is not authorized to issue")
 	}
 
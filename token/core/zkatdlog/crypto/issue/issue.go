@@ -6,7 +6,10 @@ SPDX-License-Identifier: Apache-2.0
 package issue
 
 import (
+	"context"
 	"encoding/json"
+	"math/big"
+	"sync"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
@@ -26,6 +29,59 @@ type IssueAction struct {
 	Proof []byte
 	// flag to indicate type of issue
 	Anonymous bool
+	// AuditInfos are the audit infos of the owners of the outputs,
+	// encrypted under the auditor's key, if the public parameters declare one
+	AuditInfos [][]byte `json:",omitempty"`
+	// Supply, if set, reveals the total value issued by a non-anonymous issue action, so that the supply of
+	// the issued type can be tracked. It is required if the public parameters cap the supply of the issued type.
+	Supply *IssuedSupply `json:",omitempty"`
+}
+
+// IssuedSupply opens the sum of the output tokens of an issue action: it reveals the type and the total value issued,
+// but not the values of the single outputs
+type IssuedSupply struct {
+	Type  string
+	Value uint64
+	// BlindingFactor is the sum of the blinding factors of the outputs
+	BlindingFactor *bn256.Zr
+}
+
+// NewIssuedSupply returns the opening of the sum of the tokens with the passed witnesses, all of the passed type
+func NewIssuedSupply(ttype string, tw []*token.TokenDataWitness) (*IssuedSupply, error) {
+	var value uint64
+	bf := bn256.NewZrInt(0)
+	for _, w := range tw {
+		v := (*big.Int)(w.Value)
+		if !v.IsUint64() || value+v.Uint64() < value {
+			return nil, errors.Errorf("total value issued out of range")
+		}
+		value += v.Uint64()
+		bf = bn256.ModAdd(bf, w.BlindingFactor, bn256.Order)
+	}
+	return &IssuedSupply{Type: ttype, Value: value, BlindingFactor: bf}, nil
+}
+
+// Verify checks that the sum of the passed commitments opens to this supply
+func (s *IssuedSupply) Verify(coms []*bn256.G1, ped []*bn256.G1) error {
+	if s.BlindingFactor == nil {
+		return errors.New("invalid issued supply: missing blinding factor")
+	}
+	if len(coms) == 0 {
+		return errors.New("invalid issued supply: no outputs")
+	}
+	sum := bn256.NewG1()
+	for _, c := range coms {
+		sum.Add(c)
+	}
+	typeHash := bn256.ModMul(bn256.HashModOrder([]byte(s.Type)), bn256.NewZrInt(len(coms)), bn256.Order)
+	expected, err := common.ComputePedersenCommitment([]*bn256.Zr{typeHash, bn256.NewZrInt(0).SetUint64(s.Value), s.BlindingFactor}, ped)
+	if err != nil {
+		return errors.WithMessagef(err, "failed computing issued supply commitment")
+	}
+	if !sum.Equals(expected) {
+		return errors.Errorf("issued supply of [%s] does not match the outputs", s.Type)
+	}
+	return nil
 }
 
 func (i *IssueAction) GetProof() []byte {
@@ -64,10 +120,38 @@ func (i *IssueAction) GetSerializedOutputs() ([][]byte, error) {
 	return res, nil
 }
 
+func (i *IssueAction) GetAuditInfos() [][]byte {
+	return i.AuditInfos
+}
+
 func (i *IssueAction) GetIssuer() []byte {
 	return i.Issuer
 }
 
+// GetIssuedSupply returns the type and the total value issued by this action, false if not revealed, see IssuedSupply
+func (i *IssueAction) GetIssuedSupply() (string, uint64, bool) {
+	if i.Supply == nil {
+		return "", 0, false
+	}
+	return i.Supply.Type, i.Supply.Value, true
+}
+
+// TypeInTheClear returns the type of the issued tokens, as proven by the proof of a non-anonymous issue action
+func (i *IssueAction) TypeInTheClear() (string, error) {
+	if i.Anonymous {
+		return "", errors.New("the type of the tokens issued by an anonymous issue action is hidden")
+	}
+	proof := &Proof{}
+	if err := proof.Deserialize(i.Proof); err != nil {
+		return "", errors.Wrap(err, "failed to unmarshal issue proof")
+	}
+	wf := &WellFormedness{}
+	if err := wf.Deserialize(proof.WellFormedness); err != nil {
+		return "", errors.Wrap(err, "failed to unmarshal well-formedness proof")
+	}
+	return wf.TypeInTheClear, nil
+}
+
 func (i *IssueAction) Deserialize(raw []byte) error {
 	return json.Unmarshal(raw, i)
 }
@@ -120,23 +204,50 @@ type Verifier struct {
 type Prover struct {
 	WellFormedness   *WellFormednessProver
 	RangeCorrectness common.Prover
+	// Encoding is the encoding of the proof, see common.ProofEncoding
+	Encoding common.ProofEncoding
 }
 
-// serialize
+// Serialize returns the JSON encoding of the proof
 func (p *Proof) Serialize() ([]byte, error) {
 	return json.Marshal(p)
 }
 
-// deserialize
-func (p *Proof) Deserialize(bytes []byte) error {
-	return json.Unmarshal(bytes, p)
+// SerializeBinary returns the binary encoding of the proof, see common.Encoder
+func (p *Proof) SerializeBinary() ([]byte, error) {
+	e := common.NewEncoder()
+	e.Bytes(p.WellFormedness)
+	e.Bytes(p.RangeCorrectness)
+	return e.Encoded(), nil
+}
+
+// Deserialize decodes the passed proof, in the binary encoding or in the JSON one
+func (p *Proof) Deserialize(raw []byte) error {
+	if !common.IsBinary(raw) {
+		return json.Unmarshal(raw, p)
+	}
+	d, err := common.NewDecoder(raw)
+	if err != nil {
+		return err
+	}
+	p.WellFormedness = d.Bytes()
+	p.RangeCorrectness = d.Bytes()
+	return d.Err()
 }
 
 func NewProver(tw []*token.TokenDataWitness, tokens []*bn256.G1, anonymous bool, pp *crypto.PublicParams) *Prover {
-	p := &Prover{}
+	p := &Prover{Encoding: pp.ProofEncoding}
 	p.WellFormedness = NewWellFormednessProver(tw, tokens, anonymous, pp.ZKATPedParams)
+	p.WellFormedness.Hash = pp.ChallengeHash
+	p.WellFormedness.Encoding = pp.ProofEncoding
 
-	p.RangeCorrectness = rp.NewProver(tw, tokens, pp.RangeProofParams.SignedValues, pp.RangeProofParams.Exponent, pp.ZKATPedParams, pp.RangeProofParams.SignPK, pp.P, pp.RangeProofParams.Q)
+	rangeProver := rp.NewProver(tw, tokens, pp.RangeProofParams.SignedValues, pp.RangeProofParams.Exponent, pp.ZKATPedParams, pp.RangeProofParams.SignPK, pp.P, pp.RangeProofParams.Q)
+	rangeProver.Hash = pp.ChallengeHash
+	rangeProver.Encoding = pp.ProofEncoding
+	if table, err := rp.GetDigitTable(pp); err == nil {
+		rangeProver.Table = table
+	}
+	p.RangeCorrectness = rangeProver
 
 	return p
 }
@@ -144,31 +255,72 @@ func NewProver(tw []*token.TokenDataWitness, tokens []*bn256.G1, anonymous bool,
 func NewVerifier(tokens []*bn256.G1, anonymous bool, pp *crypto.PublicParams) *Verifier {
 	v := &Verifier{}
 	v.WellFormedness = NewWellFormednessVerifier(tokens, anonymous, pp.ZKATPedParams)
-	v.RangeCorrectness = rp.NewVerifier(tokens, uint64(len(pp.RangeProofParams.SignedValues)), pp.RangeProofParams.Exponent, pp.ZKATPedParams, pp.RangeProofParams.SignPK, pp.P, pp.RangeProofParams.Q)
+	v.WellFormedness.Hash = pp.ChallengeHash
+	rangeVerifier := rp.NewVerifier(tokens, uint64(len(pp.RangeProofParams.SignedValues)), pp.RangeProofParams.Exponent, pp.ZKATPedParams, pp.RangeProofParams.SignPK, pp.P, pp.RangeProofParams.Q)
+	rangeVerifier.Hash = pp.ChallengeHash
+	v.RangeCorrectness = rangeVerifier
 	return v
 }
 
-func (p *Prover) Prove() ([]byte, error) {
-	// well-formedness proof
-	wf, err := p.WellFormedness.Prove()
+// WithRand makes the prover draw the randomness of the proofs from the passed source, see bn256.NewDRBG.
+// The proofs are generated concurrently, each gets its own fork of the source.
+func (p *Prover) WithRand(rng bn256.Rand) (*Prover, error) {
+	rc, ok := p.RangeCorrectness.(*rp.Prover)
+	if !ok {
+		return nil, errors.Errorf("range prover does not support randomness injection")
+	}
+	var err error
+	if rc.Rand, err = bn256.Fork(rng); err != nil {
+		return nil, err
+	}
+	p.WellFormedness.Rand, err = bn256.Fork(rng)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to generate issue proof")
+		return nil, err
 	}
+	return p, nil
+}
+
+// Zeroize wipes the witnesses of the prover and the randomness of the last proof.
+// The witnesses are shared with the caller, call it once done with them.
+func (p *Prover) Zeroize() {
+	common.Zeroize(p.WellFormedness)
+	common.Zeroize(p.RangeCorrectness)
+}
 
+func (p *Prover) Prove() ([]byte, error) {
+	// well-formedness and range proofs are independent, generate them concurrently
+	var wf, rc []byte
+	var wfErr, rcErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// well-formedness proof
+		wf, wfErr = p.WellFormedness.Prove()
+	}()
 	// range proof
-	rc, err := p.RangeCorrectness.Prove()
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to generate range proof for issue")
+	rc, rcErr = p.RangeCorrectness.Prove()
+	wg.Wait()
+	if wfErr != nil {
+		return nil, errors.Wrapf(wfErr, "failed to generate issue proof")
+	}
+	if rcErr != nil {
+		return nil, errors.Wrapf(rcErr, "failed to generate range proof for issue")
 	}
 
 	proof := &Proof{
 		WellFormedness:   wf,
 		RangeCorrectness: rc,
 	}
-	return proof.Serialize()
+	return p.Encoding.Encode(proof)
 }
 
 func (v *Verifier) Verify(proof []byte) error {
+	return v.VerifyWithContext(context.Background(), proof)
+}
+
+// VerifyWithContext verifies the passed issue proof, it aborts with the error of the passed context once done
+func (v *Verifier) VerifyWithContext(ctx context.Context, proof []byte) error {
 	ip := &Proof{}
 	err := ip.Deserialize(proof)
 	if err != nil {
@@ -181,5 +333,5 @@ func (v *Verifier) Verify(proof []byte) error {
 	}
 
 	// verify range proof
-	return v.RangeCorrectness.Verify(ip.RangeCorrectness)
+	return common.VerifyWithContext(ctx, v.RangeCorrectness, ip.RangeCorrectness)
 }
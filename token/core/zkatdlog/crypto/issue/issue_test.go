@@ -32,6 +32,18 @@ var _ = Describe("Issue Correctness", func() {
 				Expect(err).NotTo(HaveOccurred())
 			})
 		})
+		Context("many tokens are issued at once", func() {
+			BeforeEach(func() {
+				prover, verifier = prepareBatchZKIssue(16)
+			})
+			It("Succeeds", func() {
+				proof, err := prover.Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(proof).NotTo(BeNil())
+				err = verifier.Verify(proof)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
 	})
 })
 
@@ -40,6 +52,10 @@ func prepareInputsForZKIssue(pp *crypto.PublicParams) ([]*token.TokenDataWitness
 	values[0] = bn256.NewZrInt(120)
 	values[1] = bn256.NewZrInt(190)
 
+	return prepareTokensForZKIssue(pp, values)
+}
+
+func prepareTokensForZKIssue(pp *crypto.PublicParams, values []*bn256.Zr) ([]*token.TokenDataWitness, []*bn256.G1) {
 	rand, _ := bn256.GetRand()
 	bF := make([]*bn256.Zr, len(values))
 	for i := 0; i < len(values); i++ {
@@ -62,3 +78,19 @@ func prepareZKIssue() (*issue.Prover, *issue.Verifier) {
 
 	return prover, verifier
 }
+
+func prepareBatchZKIssue(n int) (*issue.Prover, *issue.Verifier) {
+	pp, err := crypto.Setup(100, 2, nil)
+	Expect(err).NotTo(HaveOccurred())
+
+	values := make([]*bn256.Zr, n)
+	for i := 0; i < n; i++ {
+		values[i] = bn256.NewZrInt(10*i + 1)
+	}
+	tw, tokens := prepareTokensForZKIssue(pp, values)
+
+	prover := issue.NewProver(tw, tokens, true, pp)
+	verifier := issue.NewVerifier(tokens, true, pp)
+
+	return prover, verifier
+}
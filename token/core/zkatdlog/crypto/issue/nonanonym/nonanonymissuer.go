@@ -7,6 +7,7 @@ package nonanonym
 
 import (
 	api2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	issue2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
@@ -47,6 +48,8 @@ func (i *Issuer) GenerateZKIssue(values []uint64, owners [][]byte) (*issue2.Issu
 	}
 
 	prover := issue2.NewProver(tw, tokens, false, i.PublicParams)
+	// the witnesses are wiped on return, the token information holds copies
+	defer prover.Zeroize()
 	proof, err := prover.Prove()
 	if err != nil {
 		return nil, nil, errors.Errorf("failed to generate zero knwoledge proof for issue")
@@ -56,6 +59,11 @@ func (i *Issuer) GenerateZKIssue(values []uint64, owners [][]byte) (*issue2.Issu
 	if err != nil {
 		return nil, nil, err
 	}
+	// reveal the total value issued, so that the supply of the type can be tracked on the ledger
+	issue.Supply, err = issue2.NewIssuedSupply(i.Type, tw)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	signerRaw, err := i.Signer.GetPublicVersion().Serialize()
 	if err != nil {
@@ -66,8 +74,8 @@ func (i *Issuer) GenerateZKIssue(values []uint64, owners [][]byte) (*issue2.Issu
 	for j := 0; j < len(inf); j++ {
 		inf[j] = &token.TokenInformation{
 			Type:           i.Type,
-			Value:          tw[j].Value,
-			BlindingFactor: tw[j].BlindingFactor,
+			Value:          bn256.NewZrCopy(tw[j].Value),
+			BlindingFactor: bn256.NewZrCopy(tw[j].BlindingFactor),
 			Owner:          owners[j],
 			Issuer:         signerRaw,
 		}
@@ -6,6 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package issue
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
@@ -33,12 +34,37 @@ func NewTokenDataWitness(ttype string, values, bfs []*bn256.Zr) []*token.TokenDa
 	return witness
 }
 
+// Serialize returns the JSON encoding of the proof
 func (wf *WellFormedness) Serialize() ([]byte, error) {
 	return json.Marshal(wf)
 }
 
-func (wf *WellFormedness) Deserialize(bytes []byte) error {
-	return json.Unmarshal(bytes, wf)
+// SerializeBinary returns the binary encoding of the proof, see common.Encoder
+func (wf *WellFormedness) SerializeBinary() ([]byte, error) {
+	e := common.NewEncoder()
+	e.Zr(wf.Type)
+	e.Zrs(wf.Values)
+	e.Zrs(wf.BlindingFactors)
+	e.String(wf.TypeInTheClear)
+	e.Zr(wf.Challenge)
+	return e.Encoded(), nil
+}
+
+// Deserialize decodes the passed proof, in the binary encoding or in the JSON one
+func (wf *WellFormedness) Deserialize(raw []byte) error {
+	if !common.IsBinary(raw) {
+		return json.Unmarshal(raw, wf)
+	}
+	d, err := common.NewDecoder(raw)
+	if err != nil {
+		return err
+	}
+	wf.Type = d.Zr()
+	wf.Values = d.Zrs()
+	wf.BlindingFactors = d.Zrs()
+	wf.TypeInTheClear = d.String()
+	wf.Challenge = d.Zr()
+	return d.Err()
 }
 
 // randomness used in well-formedness proof
@@ -48,6 +74,15 @@ type WellFormednessRandomness struct {
 	ttype           *bn256.Zr
 }
 
+func (r *WellFormednessRandomness) zeroize() {
+	if r == nil {
+		return
+	}
+	bn256.Zeroize(r.blindingFactors...)
+	bn256.Zeroize(r.values...)
+	bn256.Zeroize(r.ttype)
+}
+
 // zero knowledge verifier for issue
 type WellFormednessVerifier struct {
 	*common.SchnorrVerifier
@@ -60,6 +95,13 @@ type WellFormednessProver struct {
 	witness     []*token.TokenDataWitness
 	randomness  *WellFormednessRandomness
 	Commitments []*bn256.G1
+	// Workers is the maximum number of goroutines used to compute the commitments.
+	// If not positive, the number of available CPUs is used.
+	Workers int
+	// Rand is the source of the randomness of the proof, the default one if nil, see bn256.NewDRBG
+	Rand bn256.Rand
+	// Encoding is the encoding of the proof
+	Encoding common.ProofEncoding
 }
 
 func NewWellFormednessProver(witness []*token.TokenDataWitness, tokens []*bn256.G1, anonymous bool, pp []*bn256.G1) *WellFormednessProver {
@@ -77,20 +119,31 @@ func NewWellFormednessVerifier(tokens []*bn256.G1, anonymous bool, pp []*bn256.G
 	}
 }
 
+// Zeroize wipes the witness of the prover and the randomness of the last proof
+func (p *WellFormednessProver) Zeroize() {
+	for _, w := range p.witness {
+		w.Zeroize()
+	}
+	p.randomness.zeroize()
+}
+
 func (p *WellFormednessProver) Prove() ([]byte, error) {
+	if bn256.Hardened {
+		defer func() { p.randomness.zeroize() }()
+	}
 	err := p.computeCommitments()
 	if err != nil {
 		return nil, errors.Wrap(err, "The computation of the transfer proof failed 1")
 	}
 	// compute challenge for proof
-	chal := common.ComputeChallenge(common.GetG1Array(p.Commitments, p.Tokens))
+	chal := p.ComputeChallenge(common.GetG1Array(p.Commitments, p.Tokens))
 	// compute proof
 	wf, err := p.computeProof(chal)
 	if err != nil {
 		return nil, errors.Wrap(err, "The computation of the transfer proof failed 3")
 	}
 	// serialize proof
-	return wf.Serialize()
+	return p.Encoding.Encode(wf)
 }
 
 func (v *WellFormednessVerifier) Verify(proof []byte) error {
@@ -106,9 +159,9 @@ func (v *WellFormednessVerifier) Verify(proof []byte) error {
 	// recompute commitments used in proof
 	coms := ver.RecomputeCommitments(zkps, wf.Challenge)
 	// recompute challenge
-	chal := common.ComputeChallenge(common.GetG1Array(coms, v.Tokens))
+	chal := v.ComputeChallenge(common.GetG1Array(coms, v.Tokens))
 	// check proof
-	if chal.Cmp(wf.Challenge) != 0 {
+	if !bn256.ConstantTimeEqual(chal, wf.Challenge) {
 		return errors.Errorf("invalid zero-knowledge issue")
 	}
 	return nil
@@ -138,7 +191,7 @@ func (p *WellFormednessProver) computeCommitments() error {
 		return errors.Errorf("computation of issue proof failed: invalid public parameters")
 	}
 	// get random number generator
-	rand, err := bn256.GetRand()
+	rand, err := bn256.RandOrDefault(p.Rand)
 	if err != nil {
 		return errors.Errorf("failed to get RNG")
 	}
@@ -155,18 +208,22 @@ func (p *WellFormednessProver) computeCommitments() error {
 		p.randomness.ttype = bn256.RandModOrder(rand)
 		Q = p.PedParams[0].Mul(p.randomness.ttype)
 	}
-	// compute commitment
+	// randomness for value and blinding factor proofs
 	for i := 0; i < len(p.Tokens); i++ {
-		// randomness for value proof
 		p.randomness.values[i] = bn256.RandModOrder(rand)
-		p.Commitments[i] = p.PedParams[1].Mul(p.randomness.values[i])
-		// randomness for blinding factor proof
 		p.randomness.blindingFactors[i] = bn256.RandModOrder(rand)
-		p.Commitments[i].Add(p.PedParams[2].Mul(p.randomness.blindingFactors[i]))
-		// add type
-		p.Commitments[i].Add(Q)
 	}
-	return nil
+	// compute commitments, the tokens are independent of each other
+	return common.Profile(context.Background(), "issue-prove", func(context.Context) error {
+		return common.Parallel(len(p.Tokens), p.Workers, func(i int) error {
+			com := p.PedParams[1].Mul(p.randomness.values[i])
+			com.Add(p.PedParams[2].Mul(p.randomness.blindingFactors[i]))
+			// add type
+			com.Add(Q)
+			p.Commitments[i] = com
+			return nil
+		})
+	})
 }
 
 func (p *WellFormednessProver) computeProof(chal *bn256.Zr) (*WellFormedness, error) {
@@ -8,13 +8,61 @@ package crypto
 import (
 	"encoding/json"
 
+	"github.com/pkg/errors"
+
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
 )
 
 type IssuingPolicy struct {
 	Issuers       []*bn256.G1
 	IssuersNumber int
 	BitLength     int
+	// Accumulator, if set, replaces the list of issuers: an anonymous issuer proves to hold a credential
+	// of the accumulator, with a proof whose cost does not depend on the number of issuers
+	Accumulator *IssuerAccumulator
+}
+
+// IssuerAccumulator is the public key of the authority that authorizes the anonymous issuers.
+// The credential of an issuer is a Pointcheval-Sanders signature on the secret key of the issuer and the type
+// it can issue. Issuers are added without updating the public parameters; rotating the accumulator,
+// with a new epoch, revokes all the credentials issued so far.
+type IssuerAccumulator struct {
+	PK    []*bn256.G2
+	Q     *bn256.G2
+	P     *bn256.G1
+	Epoch uint64
+}
+
+// NewIssuerAccumulator returns a new accumulator for the passed epoch, and the signer that issues its credentials
+func NewIssuerAccumulator(epoch uint64) (*IssuerAccumulator, *pssign.Signer, error) {
+	signer := &pssign.Signer{}
+	// secret key and type
+	if err := signer.KeyGen(2); err != nil {
+		return nil, nil, errors.Wrap(err, "failed generating issuer accumulator key")
+	}
+	rand, err := bn256.GetRand()
+	if err != nil {
+		return nil, nil, errors.Errorf("failed to get RNG")
+	}
+	return &IssuerAccumulator{
+		PK:    signer.PK,
+		Q:     signer.Q,
+		P:     bn256.G1Gen().Mul(bn256.RandModOrder(rand)),
+		Epoch: epoch,
+	}, signer, nil
+}
+
+func (a *IssuerAccumulator) Validate() error {
+	if len(a.PK) != 4 || a.Q == nil || a.P == nil {
+		return errors.New("invalid issuer accumulator")
+	}
+	for _, pk := range a.PK {
+		if pk == nil {
+			return errors.New("invalid issuer accumulator")
+		}
+	}
+	return nil
 }
 
 func (ip *IssuingPolicy) Serialize() ([]byte, error) {
@@ -18,6 +18,8 @@ import (
 type Prover struct {
 	*Verifier
 	witness *Witness
+	// Rand is the source of the randomness of the proof, the default one if nil, see bn256.NewDRBG
+	Rand bn256.Rand
 }
 
 func NewProver(commitments []*bn256.G1, message []byte, pp []*bn256.G1, length int, index int, randomness *bn256.Zr) *Prover {
@@ -45,6 +47,8 @@ type Verifier struct {
 	Message        []byte
 	PedersenParams []*bn256.G1 // Pedersen commitments parameters
 	BitLength      int
+	// Hash derives the challenges, SHA256 if empty
+	Hash common.ChallengeHash
 }
 
 // Witness information
@@ -111,7 +115,7 @@ func (p *Prover) Prove() ([]byte, error) {
 	bytes := publicInput.Bytes()
 	bytes = append(bytes, []byte(strconv.Itoa(p.BitLength))...)
 	bytes = append(bytes, p.Message...)
-	chal := bn256.HashModOrder(bytes)
+	chal := p.Hash.HashModOrder(bytes)
 
 	p.computeO2OMProof(proof, indexBits, chal, a, r, s, t, rho)
 
@@ -145,7 +149,7 @@ func (v *Verifier) Verify(p []byte) error {
 	bytes := publicInput.Bytes()
 	bytes = append(bytes, []byte(strconv.Itoa(v.BitLength))...)
 	bytes = append(bytes, v.Message...)
-	hash := bn256.HashModOrder(bytes)
+	hash := v.Hash.HashModOrder(bytes)
 
 	for i := 0; i < v.BitLength; i++ {
 		t := proof.Commitments.L[i].Mul(hash)
@@ -210,7 +214,7 @@ func (p *Prover) compute3OMPCommitments(a, r, s, t, rho []*bn256.Zr, indexBits [
 	commitments.B = make([]*bn256.G1, p.BitLength)
 	commitments.D = make([]*bn256.G1, p.BitLength)
 
-	rand, err := bn256.GetRand()
+	rand, err := bn256.RandOrDefault(p.Rand)
 	if err != nil {
 		return nil, err
 	}
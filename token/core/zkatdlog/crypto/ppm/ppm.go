@@ -12,21 +12,32 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/fabric"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/elgamal"
 )
 
 var logger = flogging.MustGetLogger("token-sdk.zkatdlog")
 
 type PublicParamsManager struct {
 	pp *crypto.PublicParams
+	// fetch fetches the public parameters again, bypassing any cache
+	fetch func() error
 }
 
 func New(pp *crypto.PublicParams) *PublicParamsManager {
 	return &PublicParamsManager{pp: pp}
 }
 
+// WithFetch sets the function ForceFetch calls to fetch the public parameters again
+func (v *PublicParamsManager) WithFetch(fetch func() error) *PublicParamsManager {
+	v.fetch = fetch
+	return v
+}
+
 func (v *PublicParamsManager) SetAuditor(auditor []byte) ([]byte, error) {
 	identityDeserializer := &fabric.MSPX509IdentityDeserializer{}
 	_, err := identityDeserializer.GetVerifier(auditor)
@@ -34,6 +45,104 @@ func (v *PublicParamsManager) SetAuditor(auditor []byte) ([]byte, error) {
 		return nil, errors.Wrap(err, "failed to retrieve auditor's identity")
 	}
 	v.pp.Auditor = auditor
+	v.pp.ResetHash()
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to serialize public parameters")
+	}
+	return raw, nil
+}
+
+// SetAuditorEncryptionKey sets the key, a serialized Elgamal public key, under which the audit infos are encrypted
+func (v *PublicParamsManager) SetAuditorEncryptionKey(raw []byte) ([]byte, error) {
+	pk := &elgamal.PublicKey{}
+	if err := json.Unmarshal(raw, pk); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal auditor encryption key")
+	}
+	if err := v.pp.SetAuditorEncryptionKey(pk); err != nil {
+		return nil, err
+	}
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to serialize public parameters")
+	}
+	return raw, nil
+}
+
+// SetAuditScope sets what the auditor can see
+func (v *PublicParamsManager) SetAuditScope(scope api.AuditScope) ([]byte, error) {
+	if err := v.pp.SetAuditScope(scope); err != nil {
+		return nil, err
+	}
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to serialize public parameters")
+	}
+	return raw, nil
+}
+
+// SetChallengeHash sets the hash function from which the challenges of the zero-knowledge proofs are derived
+func (v *PublicParamsManager) SetChallengeHash(h common.ChallengeHash) ([]byte, error) {
+	if err := v.pp.SetChallengeHash(h); err != nil {
+		return nil, err
+	}
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to serialize public parameters")
+	}
+	return raw, nil
+}
+
+// NewAuditorEncryptionKeyPair returns a new serialized auditor encryption key pair
+func (v *PublicParamsManager) NewAuditorEncryptionKeyPair() ([]byte, []byte, error) {
+	sk, err := elgamal.NewKeyPair(bn256.G1Gen())
+	if err != nil {
+		return nil, nil, err
+	}
+	pkRaw, err := json.Marshal(sk.PublicKey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to serialize auditor encryption key")
+	}
+	skRaw, err := sk.Serialize()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to serialize auditor decryption key")
+	}
+	return pkRaw, skRaw, nil
+}
+
+// SetIssueApprover sets the approver that must co-sign the issue actions.
+// Token types are hidden, therefore the approver can only be set for api.AnyTokenType.
+func (v *PublicParamsManager) SetIssueApprover(tokenType string, approver []byte) ([]byte, error) {
+	if tokenType != api.AnyTokenType {
+		return nil, errors.Errorf("issue approvers per token type are not supported, use [%s]", api.AnyTokenType)
+	}
+	if len(approver) != 0 {
+		identityDeserializer := &fabric.MSPX509IdentityDeserializer{}
+		if _, err := identityDeserializer.GetVerifier(approver); err != nil {
+			return nil, errors.Wrap(err, "failed to retrieve issue approver's identity")
+		}
+	}
+	if v.pp.IssuePolicy == nil {
+		v.pp.IssuePolicy = &api.IssuePolicy{}
+	}
+	v.pp.IssuePolicy.SetApprover(tokenType, approver)
+	v.pp.ResetHash()
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to serialize public parameters")
+	}
+	return raw, nil
+}
+
+// SetTypeAuditor is not supported, token types are hidden therefore all the requests are audited by the default auditor
+func (v *PublicParamsManager) SetTypeAuditor(tokenType string, auditor []byte) ([]byte, error) {
+	return nil, errors.New("auditors per token type are not supported, token types are hidden")
+}
+
+// SetReceiverPolicy sets the policy the receivers of the transfers must satisfy, nil to accept any receiver
+func (v *PublicParamsManager) SetReceiverPolicy(policy *api.ReceiverPolicy) ([]byte, error) {
+	v.pp.ReceiverPolicy = policy
+	v.pp.ResetHash()
 	raw, err := v.pp.Serialize()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to serialize public parameters")
@@ -41,6 +150,114 @@ func (v *PublicParamsManager) SetAuditor(auditor []byte) ([]byte, error) {
 	return raw, nil
 }
 
+// SetAuditThreshold sets the total value under which the token requests made of transfers only can skip the auditor,
+// 0 to audit every request
+func (v *PublicParamsManager) SetAuditThreshold(threshold uint64) ([]byte, error) {
+	if err := v.pp.SetAuditThreshold(threshold); err != nil {
+		return nil, err
+	}
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to serialize public parameters")
+	}
+	return raw, nil
+}
+
+// SetRedeemRequiresIssuer sets whether the redemptions must be co-signed by an issuer of the redeemed type
+func (v *PublicParamsManager) SetRedeemRequiresIssuer(required bool) ([]byte, error) {
+	v.pp.RedeemIssuer = required
+	v.pp.ResetHash()
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to serialize public parameters")
+	}
+	return raw, nil
+}
+
+// RotateIdemixPK sets the idemix issuer public key the owner credentials are issued under from the next epoch on.
+// The credentials issued under the previous keys are accepted for the passed number of epochs, forever if zero.
+func (v *PublicParamsManager) RotateIdemixPK(pk []byte, gracePeriod uint64) ([]byte, error) {
+	if err := v.pp.RotateIdemixPK(pk, gracePeriod); err != nil {
+		return nil, errors.Wrap(err, "failed rotating idemix issuer public key")
+	}
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to serialize public parameters")
+	}
+	return raw, nil
+}
+
+// AdvanceIdemixEpoch moves to the next epoch of the idemix issuer public keys, expiring the keys whose grace period ends
+func (v *PublicParamsManager) AdvanceIdemixEpoch() ([]byte, error) {
+	v.pp.AdvanceIdemixEpoch()
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to serialize public parameters")
+	}
+	return raw, nil
+}
+
+// SetMigration enables the migration of the tokens of fabtoken, whose serialized public parameters are passed.
+// The fabtoken token requests are rejected from the passed cutover height, zero means no cutover.
+func (v *PublicParamsManager) SetMigration(source []byte, cutoverHeight uint64) ([]byte, error) {
+	if _, err := fabtoken.NewPublicParamsFromBytes(source); err != nil {
+		return nil, errors.Wrap(err, "invalid source public parameters")
+	}
+	v.pp.Migration = &api.MigrationParams{
+		SourcePublicParams: source,
+		CutoverHeight:      cutoverHeight,
+	}
+	v.pp.ResetHash()
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to serialize public parameters")
+	}
+	return raw, nil
+}
+
+// AddDepartedOrganization lists the organization with the passed organizational unit as departed,
+// its tokens can be rebound
+func (v *PublicParamsManager) AddDepartedOrganization(organization string) ([]byte, error) {
+	if !v.pp.Departed(organization) {
+		v.pp.DepartedOrganizations = append(v.pp.DepartedOrganizations, organization)
+	}
+	v.pp.ResetHash()
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to serialize public parameters")
+	}
+	return raw, nil
+}
+
+// NewIssuerAccumulator sets a new accumulator of the anonymous issuers, revoking the credentials of the previous one, if any.
+// It returns the public parameters and the serialized signer that issues the credentials of the new accumulator.
+func (v *PublicParamsManager) NewIssuerAccumulator() ([]byte, []byte, error) {
+	ip, err := v.pp.GetIssuingPolicy()
+	if err != nil {
+		return nil, nil, err
+	}
+	epoch := uint64(0)
+	if ip.Accumulator != nil {
+		epoch = ip.Accumulator.Epoch + 1
+	}
+	acc, signer, err := crypto.NewIssuerAccumulator(epoch)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := v.pp.SetIssuerAccumulator(acc); err != nil {
+		return nil, nil, err
+	}
+	signerRaw, err := signer.Serialize()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to serialize issuer accumulator signer")
+	}
+	raw, err := v.pp.Serialize()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to serialize public parameters")
+	}
+	return raw, signerRaw, nil
+}
+
 func (v *PublicParamsManager) AddIssuer(bytes []byte) ([]byte, error) {
 	i := &bn256.G1{}
 	err := json.Unmarshal(bytes, i)
@@ -69,6 +286,8 @@ func (v *PublicParamsManager) NewCertifierKeyPair() ([]byte, []byte, error) {
 }
 
 func (v *PublicParamsManager) ForceFetch() error {
-	// TODO: implement this
-	return nil
+	if v.fetch == nil {
+		return errors.New("public parameters cannot be fetched, they have been passed")
+	}
+	return v.fetch()
 }
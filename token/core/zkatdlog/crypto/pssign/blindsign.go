@@ -254,7 +254,7 @@ func (v *EncVerifier) Verify(proof []byte) error {
 	// compute challenge
 	chal := common.ComputeChallenge(common.GetG1Array(v.PedersenParameters, []*bn256.G1{v.EncPK.Gen, v.EncPK.H}, ciphertexts, []*bn256.G1{v.Commitment}, commitments.C1, commitments.C2, []*bn256.G1{commitments.Commitment}))
 	// check challenge
-	if chal.Cmp(p.Challenge) != 0 {
+	if !bn256.ConstantTimeEqual(chal, p.Challenge) {
 		return errors.Errorf("verification of encryption correctness failed")
 	}
 	return nil
@@ -110,7 +110,12 @@ func (v *SignVerifier) Verify(m []*bn256.Zr, sig *Signature) error {
 }
 
 func (sig *Signature) Randomize() error {
-	rand, err := bn256.GetRand()
+	return sig.RandomizeWithRand(nil)
+}
+
+// RandomizeWithRand randomizes the signature with the passed source of randomness, the default one if nil
+func (sig *Signature) RandomizeWithRand(rng bn256.Rand) error {
+	rand, err := bn256.RandOrDefault(rng)
 	if err != nil {
 		return err
 	}
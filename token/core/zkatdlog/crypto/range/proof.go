@@ -6,7 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package rangeproof
 
 import (
-	"crypto/sha256"
+	"context"
 	"encoding/json"
 	"math"
 
@@ -37,6 +37,54 @@ type MembershipProof struct {
 	SignatureProofs [][]byte
 }
 
+// Serialize returns the JSON encoding of the proof
+func (p *Proof) Serialize() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// SerializeBinary returns the binary encoding of the proof, see common.Encoder
+func (p *Proof) SerializeBinary() ([]byte, error) {
+	e := common.NewEncoder()
+	e.Zr(p.Challenge)
+	eq := p.EqualityProofs
+	if eq == nil {
+		eq = &EqualityProofs{}
+	}
+	e.Zr(eq.Type)
+	e.Zrs(eq.Value)
+	e.Zrs(eq.TokenBlindingFactor)
+	e.Zrs(eq.CommitmentBlindingFactor)
+	e.Count(len(p.MembershipProofs))
+	for _, mp := range p.MembershipProofs {
+		e.G1s(mp.Commitments)
+		e.BytesList(mp.SignatureProofs)
+	}
+	return e.Encoded(), nil
+}
+
+// Deserialize decodes the passed proof, in the binary encoding or in the JSON one
+func (p *Proof) Deserialize(raw []byte) error {
+	if !common.IsBinary(raw) {
+		return json.Unmarshal(raw, p)
+	}
+	d, err := common.NewDecoder(raw)
+	if err != nil {
+		return err
+	}
+	p.Challenge = d.Zr()
+	p.EqualityProofs = &EqualityProofs{
+		Type:                     d.Zr(),
+		Value:                    d.Zrs(),
+		TokenBlindingFactor:      d.Zrs(),
+		CommitmentBlindingFactor: d.Zrs(),
+	}
+	p.MembershipProofs = make([]*MembershipProof, d.Count())
+	for i := range p.MembershipProofs {
+		p.MembershipProofs[i] = &MembershipProof{Commitments: d.G1s(), SignatureProofs: d.BytesList()}
+	}
+	return d.Err()
+}
+
 type Prover struct {
 	*Verifier
 	tokenWitness             []*token.TokenDataWitness
@@ -45,6 +93,16 @@ type Prover struct {
 	Signatures               []*pssign.Signature
 	randomness               *Randomness
 	Commitment               *Commitment
+	// Encoding is the encoding of the proof and of its membership proofs
+	Encoding common.ProofEncoding
+	// Workers is the maximum number of goroutines generating membership proofs.
+	// If not positive, the number of CPUs is used.
+	Workers int
+	// Table is the digit table to be used by the prover, see GetDigitTable.
+	// If nil, the prover computes it from the signatures.
+	Table *DigitTable
+	// Rand is the source of the randomness of the proof, the default one if nil, see bn256.NewDRBG
+	Rand bn256.Rand
 }
 
 func NewProver(tw []*token.TokenDataWitness, token []*bn256.G1, signatures []*pssign.Signature, exponent int, pp []*bn256.G1, PK []*bn256.G2, P *bn256.G1, Q *bn256.G2) *Prover {
@@ -71,6 +129,8 @@ type Verifier struct {
 	Q              *bn256.G2
 	P              *bn256.G1
 	PK             []*bn256.G2
+	// Hash derives the challenges, SHA256 if empty
+	Hash common.ChallengeHash
 }
 
 func NewVerifier(token []*bn256.G1, base uint64, exponent int, pp []*bn256.G1, PK []*bn256.G2, P *bn256.G1, Q *bn256.G2) *Verifier {
@@ -97,7 +157,39 @@ type Commitment struct {
 	CommitmentToValue []*bn256.G1
 }
 
+func (r *Randomness) zeroize() {
+	if r == nil {
+		return
+	}
+	bn256.Zeroize(r.Type)
+	bn256.Zeroize(r.Value...)
+	bn256.Zeroize(r.TokenBlindingFactor...)
+	bn256.Zeroize(r.CommitmentBlindingFactor...)
+}
+
+// Zeroize wipes the token witnesses of the prover and the randomness of the last proof
+func (p *Prover) Zeroize() {
+	for _, w := range p.tokenWitness {
+		w.Zeroize()
+	}
+	p.zeroizeRandomness()
+}
+
+// zeroizeRandomness wipes the randomness of the last proof, the blinding factors of the digits included
+func (p *Prover) zeroizeRandomness() {
+	p.randomness.zeroize()
+	bn256.Zeroize(p.commitmentBlindingFactor...)
+	for _, mw := range p.membershipWitness {
+		for _, w := range mw {
+			w.Zeroize()
+		}
+	}
+}
+
 func (p *Prover) Prove() ([]byte, error) {
+	if bn256.Hardened {
+		defer p.zeroizeRandomness()
+	}
 	proof := &Proof{}
 	var err error
 	coms, err := p.computeMembershipWitness()
@@ -110,14 +202,30 @@ func (p *Prover) Prove() ([]byte, error) {
 		proof.MembershipProofs[k] = &MembershipProof{}
 		proof.MembershipProofs[k].Commitments = make([]*bn256.G1, p.Exponent)
 		proof.MembershipProofs[k].SignatureProofs = make([][]byte, p.Exponent)
-		for i := 0; i < p.Exponent; i++ {
+	}
+	// each membership proof draws from its own fork of the source of randomness, whatever the scheduling
+	rands := make([]bn256.Rand, len(p.Token)*p.Exponent)
+	for j := range rands {
+		if rands[j], err = bn256.Fork(p.Rand); err != nil {
+			return nil, err
+		}
+	}
+	// membership proofs are independent of each other, one per digit of each token
+	err = common.Profile(context.Background(), "range-prove", func(context.Context) error {
+		return common.Parallel(len(p.Token)*p.Exponent, p.Workers, func(j int) error {
+			k, i := j/p.Exponent, j%p.Exponent
+			var err error
 			proof.MembershipProofs[k].Commitments[i] = coms[k][i]
 			mp := sigproof.NewMembershipProver(p.membershipWitness[k][i], proof.MembershipProofs[k].Commitments[i], p.P, p.Q, p.PK, p.PedersenParams[:2])
+			mp.Rand = rands[j]
+			mp.Hash = p.Hash
+			mp.Encoding = p.Encoding
 			proof.MembershipProofs[k].SignatureProofs[i], err = mp.Prove()
-			if err != nil {
-				return nil, err
-			}
-		}
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 	// show that value in token = value in the aggregate commitment
 	err = p.computeCommitment()
@@ -142,13 +250,18 @@ func (p *Prover) Prove() ([]byte, error) {
 	proof.EqualityProofs.Type = bn256.ModMul(proof.Challenge, bn256.HashModOrder([]byte(p.tokenWitness[0].Type)), bn256.Order)
 	proof.EqualityProofs.Type = bn256.ModAdd(proof.EqualityProofs.Type, p.randomness.Type, bn256.Order)
 
-	return json.Marshal(proof)
+	return p.Encoding.Encode(proof)
 }
 
 func (v *Verifier) Verify(raw []byte) error {
+	return v.VerifyWithContext(context.Background(), raw)
+}
 
+// VerifyWithContext verifies the passed range proof, it aborts with the error of the passed context once done.
+// The context is checked before each membership proof, the most expensive part of the verification.
+func (v *Verifier) VerifyWithContext(ctx context.Context, raw []byte) error {
 	proof := &Proof{}
-	err := json.Unmarshal(raw, proof)
+	err := proof.Deserialize(raw)
 	if err != nil {
 		return err
 	}
@@ -156,17 +269,26 @@ func (v *Verifier) Verify(raw []byte) error {
 		return errors.Errorf("failed to verify range proofz")
 	}
 	//  verify membership
-	for k := 0; k < len(v.Token); k++ {
-		if len(proof.MembershipProofs[k].Commitments) != len(proof.MembershipProofs[k].SignatureProofs) {
-			return errors.Errorf("failed to verify range proof")
-		}
-		for i := 0; i < len(proof.MembershipProofs[k].Commitments); i++ {
-			mv := sigproof.NewMembershipVerifier(proof.MembershipProofs[k].Commitments[i], v.P, v.Q, v.PK, v.PedersenParams[:2])
-			err = mv.Verify(proof.MembershipProofs[k].SignatureProofs[i])
-			if err != nil {
-				return errors.Wrapf(err, "failed to verify range proof")
+	err = common.Profile(ctx, "range-verify", func(ctx context.Context) error {
+		for k := 0; k < len(v.Token); k++ {
+			if len(proof.MembershipProofs[k].Commitments) != len(proof.MembershipProofs[k].SignatureProofs) {
+				return errors.Errorf("failed to verify range proof")
+			}
+			for i := 0; i < len(proof.MembershipProofs[k].Commitments); i++ {
+				if err := ctx.Err(); err != nil {
+					return errors.Wrapf(err, "range proof verification aborted")
+				}
+				mv := sigproof.NewMembershipVerifier(proof.MembershipProofs[k].Commitments[i], v.P, v.Q, v.PK, v.PedersenParams[:2])
+				mv.Hash = v.Hash
+				if err := mv.Verify(proof.MembershipProofs[k].SignatureProofs[i]); err != nil {
+					return errors.Wrapf(err, "failed to verify range proof")
+				}
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	//  verify equality
@@ -178,17 +300,20 @@ func (v *Verifier) Verify(raw []byte) error {
 		}
 	}
 	chal := v.computeChallenge(com, coms)
-	if chal.Cmp(proof.Challenge) != 0 {
+	if !bn256.ConstantTimeEqual(chal, proof.Challenge) {
 		return errors.Errorf("failed to verify range proof")
 	}
 
 	return nil
 }
 func (p *Prover) computeMembershipWitness() ([][]*bn256.G1, error) {
-	rand, err := bn256.GetRand()
+	rand, err := bn256.RandOrDefault(p.Rand)
 	if err != nil {
 		return nil, err
 	}
+	if p.Table == nil {
+		p.Table = NewDigitTable(p.Signatures, p.Exponent, p.PedersenParams)
+	}
 	p.membershipWitness = make([][]*sigproof.MembershipWitness, len(p.tokenWitness))
 	p.commitmentBlindingFactor = make([]*bn256.Zr, len(p.tokenWitness))
 	coms := make([][]*bn256.G1, len(p.tokenWitness))
@@ -210,44 +335,57 @@ func (p *Prover) computeMembershipWitness() ([][]*bn256.G1, error) {
 		coms[k] = make([]*bn256.G1, p.Exponent)
 		for i := 0; i < p.Exponent; i++ {
 			bf := bn256.RandModOrder(rand)
-			coms[k][i], err = common.ComputePedersenCommitment([]*bn256.Zr{bn256.NewZrInt(values[i]), bf}, p.PedersenParams[:2])
-			if err != nil {
-				return nil, err
-			}
-
-			p.membershipWitness[k][i] = sigproof.NewMembershipWitness(p.Signatures[values[i]], bn256.NewZrInt(values[i]), bf)
-			pow := bn256.NewZrInt(int(math.Pow(float64(p.Base), float64(i))))
-			p.commitmentBlindingFactor[k] = bn256.ModAdd(p.commitmentBlindingFactor[k], bn256.ModMul(bf, pow, bn256.Order), bn256.Order)
+			// commitment to the digit value, the value part is precomputed
+			coms[k][i] = p.PedersenParams[1].Mul(bf)
+			coms[k][i].Add(p.Table.ValueCommitments[values[i]])
+
+			// the membership prover randomizes the signature, work on a copy
+			sig := &pssign.Signature{}
+			sig.Copy(p.Table.Signatures[values[i]])
+			p.membershipWitness[k][i] = sigproof.NewMembershipWitnessWithHash(sig, p.Table.Values[values[i]], p.Table.Hashes[values[i]], bf)
+			t := bn256.GetZr().SetModMul(bf, p.Table.Powers[i], bn256.Order)
+			p.commitmentBlindingFactor[k].SetModAdd(p.commitmentBlindingFactor[k], t, bn256.Order)
+			bn256.PutZr(t)
 		}
 	}
 	return coms, nil
 }
 
 func (p *Prover) computeCommitment() error {
-	rand, err := bn256.GetRand()
+	rand, err := bn256.RandOrDefault(p.Rand)
 	if err != nil {
 		return err
 	}
 	// generate randomness
-	p.randomness = &Randomness{}
+	p.randomness = &Randomness{
+		Value:                    make([]*bn256.Zr, len(p.Token)),
+		CommitmentBlindingFactor: make([]*bn256.Zr, len(p.Token)),
+		TokenBlindingFactor:      make([]*bn256.Zr, len(p.Token)),
+	}
 	p.randomness.Type = bn256.RandModOrder(rand)
 	for i := 0; i < len(p.Token); i++ {
-		p.randomness.Value = append(p.randomness.Value, bn256.RandModOrder(rand))
-		p.randomness.CommitmentBlindingFactor = append(p.randomness.CommitmentBlindingFactor, bn256.RandModOrder(rand))
-		p.randomness.TokenBlindingFactor = append(p.randomness.TokenBlindingFactor, bn256.RandModOrder(rand))
+		p.randomness.Value[i] = bn256.RandModOrder(rand)
+		p.randomness.CommitmentBlindingFactor[i] = bn256.RandModOrder(rand)
+		p.randomness.TokenBlindingFactor[i] = bn256.RandModOrder(rand)
 	}
 
 	// compute commitment
-	p.Commitment = &Commitment{}
+	p.Commitment = &Commitment{
+		Token:             make([]*bn256.G1, len(p.tokenWitness)),
+		CommitmentToValue: make([]*bn256.G1, len(p.tokenWitness)),
+	}
+	// the type part is the same for all the tokens
+	typeCom := p.PedersenParams[0].Mul(p.randomness.Type)
 	for i := 0; i < len(p.tokenWitness); i++ {
-		tok := p.PedersenParams[0].Mul(p.randomness.Type)
-		tok.Add(p.PedersenParams[1].Mul(p.randomness.Value[i]))
-		tok.Add(p.PedersenParams[2].Mul(p.randomness.TokenBlindingFactor[i]))
-		p.Commitment.Token = append(p.Commitment.Token, tok)
+		tok := bn256.NewG1()
+		tok.Copy(typeCom)
+		tok.AddMul(p.PedersenParams[1], p.randomness.Value[i])
+		tok.AddMul(p.PedersenParams[2], p.randomness.TokenBlindingFactor[i])
+		p.Commitment.Token[i] = tok
 
 		com := p.PedersenParams[0].Mul(p.randomness.Value[i])
-		com.Add(p.PedersenParams[1].Mul(p.randomness.CommitmentBlindingFactor[i]))
-		p.Commitment.CommitmentToValue = append(p.Commitment.CommitmentToValue, com)
+		com.AddMul(p.PedersenParams[1], p.randomness.CommitmentBlindingFactor[i])
+		p.Commitment.CommitmentToValue[i] = com
 	}
 
 	return nil
@@ -261,8 +399,7 @@ func (v *Verifier) computeChallenge(commitment *Commitment, comToValue [][]*bn25
 	for i := 0; i < len(comToValue); i++ {
 		bytes = append(bytes, common.GetG1Array(comToValue[i]).Bytes()...)
 	}
-	hash := sha256.Sum256(bytes)
-	return bn256.NewZrFromBytes(hash[:])
+	return bn256.NewZrFromBytes(v.Hash.Digest(bytes))
 }
 
 func (v *Verifier) recomputeCommitments(p *Proof) *Commitment {
@@ -278,7 +415,7 @@ func (v *Verifier) recomputeCommitments(p *Proof) *Commitment {
 		com := bn256.NewG1()
 		for i := 0; i < v.Exponent; i++ {
 			pow := bn256.NewZrInt(int(math.Pow(float64(v.Base), float64(i))))
-			com.Add(p.MembershipProofs[j].Commitments[i].Mul(pow))
+			com.AddMul(p.MembershipProofs[j].Commitments[i], pow)
 		}
 
 		ver := &common.SchnorrVerifier{PedParams: v.PedersenParams[:2]}
@@ -6,12 +6,17 @@ SPDX-License-Identifier: Apache-2.0
 package rangeproof_test
 
 import (
+	"context"
+
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
 	rp "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/range"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
 )
 
 var _ = Describe("range proof", func() {
@@ -32,6 +37,117 @@ var _ = Describe("range proof", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 	})
+	Context("when the proof is serialized", func() {
+		It("does not change the proof", func() {
+			proof := &rp.Proof{Challenge: bn256.NewZrInt(1)}
+			raw, err := proof.SerializeBinary()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(proof.EqualityProofs).To(BeNil())
+
+			decoded := &rp.Proof{}
+			Expect(decoded.Deserialize(raw)).To(Succeed())
+			Expect(decoded.Challenge).To(Equal(proof.Challenge))
+			Expect(decoded.EqualityProofs).NotTo(BeNil())
+		})
+		It("uses the binary encoding only if selected", func() {
+			raw, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(raw[0]).To(Equal(byte('{')))
+			prover.Encoding = common.BinaryEncoding
+			raw, err = prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(common.IsBinary(raw)).To(BeTrue())
+			Expect(verifier.Verify(raw)).To(Succeed())
+		})
+	})
+	Context("when the verification context is done", func() {
+		It("aborts", func() {
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err = verifier.VerifyWithContext(ctx, proof)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Cause(err)).To(Equal(context.Canceled))
+		})
+	})
+	Context("when the prover uses a cached digit table", func() {
+		var pp *crypto.PublicParams
+		BeforeEach(func() {
+			var err error
+			pp, err = crypto.Setup(100, 2, nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("Succeeds", func() {
+			table, err := rp.GetDigitTable(pp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(table.Signatures).To(HaveLen(100))
+			Expect(table.Powers).To(HaveLen(2))
+
+			rand, err := bn256.GetRand()
+			Expect(err).NotTo(HaveOccurred())
+			value := bn256.NewZrInt(4321)
+			bf := bn256.RandModOrder(rand)
+			tok := bn256.NewG1()
+			tok.Add(pp.ZKATPedParams[0].Mul(bn256.HashModOrder([]byte("ABC"))))
+			tok.Add(pp.ZKATPedParams[1].Mul(value))
+			tok.Add(pp.ZKATPedParams[2].Mul(bf))
+			tw := &token.TokenDataWitness{Value: value, Type: "ABC", BlindingFactor: bf}
+
+			prover := rp.NewProver([]*token.TokenDataWitness{tw}, []*bn256.G1{tok}, pp.RangeProofParams.SignedValues, pp.RangeProofParams.Exponent, pp.ZKATPedParams, pp.RangeProofParams.SignPK, pp.P, pp.RangeProofParams.Q)
+			prover.Table = table
+			proof, err := prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			err = prover.Verifier.Verify(proof)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("is shared until the public parameters change", func() {
+			table, err := rp.GetDigitTable(pp)
+			Expect(err).NotTo(HaveOccurred())
+			cached, err := rp.GetDigitTable(pp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached).To(BeIdenticalTo(table))
+
+			oldHash, err := pp.Hash()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pp.AddIssuer(bn256.G1Gen())).To(Succeed())
+			newHash, err := pp.Hash()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newHash).NotTo(Equal(oldHash))
+
+			changed, err := rp.GetDigitTable(pp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).NotTo(BeIdenticalTo(table))
+			rp.InvalidateDigitTable(oldHash)
+		})
+		It("evicts the least recently used tables beyond the bound", func() {
+			first, err := rp.GetDigitTable(pp)
+			Expect(err).NotTo(HaveOccurred())
+			raw, err := pp.Serialize()
+			Expect(err).NotTo(HaveOccurred())
+			same, err := crypto.NewPublicParamsFromBytes(raw)
+			Expect(err).NotTo(HaveOccurred())
+			cached, err := rp.GetDigitTable(same)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached).To(BeIdenticalTo(first))
+			for i := 0; i < rp.MaxDigitTables; i++ {
+				Expect(pp.AddIssuer(bn256.G1Gen())).To(Succeed())
+				_, err := rp.GetDigitTable(pp)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			last, err := rp.GetDigitTable(pp)
+			Expect(err).NotTo(HaveOccurred())
+			cached, err = rp.GetDigitTable(pp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached).To(BeIdenticalTo(last))
+
+			pp, err = crypto.NewPublicParamsFromBytes(raw)
+			Expect(err).NotTo(HaveOccurred())
+			evicted, err := rp.GetDigitTable(pp)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(evicted).NotTo(BeIdenticalTo(first))
+		})
+	})
 })
 
 func getRangeProver() *rp.Prover {
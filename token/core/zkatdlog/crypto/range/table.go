@@ -0,0 +1,130 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package rangeproof
+
+import (
+	"container/list"
+	"math"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
+	"github.com/pkg/errors"
+)
+
+// DigitTable contains what the prover needs, for each digit value, to show that a digit is in range.
+// It depends only on the public parameters, therefore it can be shared by all the proofs.
+type DigitTable struct {
+	// Signatures are the PS signatures of the digit values
+	Signatures []*pssign.Signature
+	// Values are the digit values
+	Values []*bn256.Zr
+	// Hashes are the hashes of the digit values, as signed
+	Hashes []*bn256.Zr
+	// ValueCommitments are the commitments to the digit values with zero randomness
+	ValueCommitments []*bn256.G1
+	// Powers are the powers of the base, one for each digit
+	Powers []*bn256.Zr
+}
+
+func NewDigitTable(signatures []*pssign.Signature, exponent int, pedersenParams []*bn256.G1) *DigitTable {
+	t := &DigitTable{
+		Signatures:       signatures,
+		Values:           make([]*bn256.Zr, len(signatures)),
+		Hashes:           make([]*bn256.Zr, len(signatures)),
+		ValueCommitments: make([]*bn256.G1, len(signatures)),
+		Powers:           make([]*bn256.Zr, exponent),
+	}
+	for i := 0; i < len(signatures); i++ {
+		t.Values[i] = bn256.NewZrInt(i)
+		t.Hashes[i] = bn256.HashModOrder(t.Values[i].Bytes())
+		t.ValueCommitments[i] = pedersenParams[0].Mul(t.Values[i])
+	}
+	for i := 0; i < exponent; i++ {
+		t.Powers[i] = bn256.NewZrInt(int(math.Pow(float64(len(signatures)), float64(i))))
+	}
+	return t
+}
+
+// MaxDigitTables bounds the number of digit tables kept in the cache,
+// the least recently used one is evicted first
+const MaxDigitTables = 8
+
+// digitTables caches the digit tables by public parameters hash
+var digitTables = newDigitTableCache(MaxDigitTables)
+
+type digitTableCache struct {
+	lock    sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type digitTableEntry struct {
+	key   string
+	table *DigitTable
+}
+
+func newDigitTableCache(size int) *digitTableCache {
+	return &digitTableCache{
+		size:    size,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// get returns the table cached under the passed key, if any, and marks it as the most recently used
+func (c *digitTableCache) get(key string) (*DigitTable, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*digitTableEntry).table, true
+}
+
+// add caches the passed table under the passed key, evicting the least recently used tables beyond the size
+func (c *digitTableCache) add(key string, table *DigitTable) {
+	c.entries[key] = c.lru.PushFront(&digitTableEntry{key: key, table: table})
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *digitTableCache) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*digitTableEntry).key)
+}
+
+// GetDigitTable returns the digit table for the passed public parameters.
+// Tables are cached by the hash of the public parameters, at most MaxDigitTables of them.
+func GetDigitTable(pp *crypto.PublicParams) (*DigitTable, error) {
+	h, err := pp.Hash()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed hashing public parameters")
+	}
+	key := string(h)
+
+	digitTables.lock.Lock()
+	defer digitTables.lock.Unlock()
+	if t, ok := digitTables.get(key); ok {
+		return t, nil
+	}
+	t := NewDigitTable(pp.RangeProofParams.SignedValues, pp.RangeProofParams.Exponent, pp.ZKATPedParams)
+	digitTables.add(key, t)
+	return t, nil
+}
+
+// InvalidateDigitTable removes from the cache the digit table of the public parameters with the passed hash.
+// It must be called when those public parameters are no longer in use.
+func InvalidateDigitTable(ppHash []byte) {
+	digitTables.lock.Lock()
+	defer digitTables.lock.Unlock()
+	if e, ok := digitTables.entries[string(ppHash)]; ok {
+		digitTables.remove(e)
+	}
+}
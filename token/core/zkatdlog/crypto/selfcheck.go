@@ -0,0 +1,159 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package crypto
+
+import (
+	"fmt"
+	"strings"
+
+	idemix2 "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/idemix"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/fabric"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+)
+
+// SelfCheck checks that the public parameters are well-formed: the group elements are valid, hence not the identity,
+// the Pedersen bases are pairwise distinct and differ from the generator P, and the keys of the issuers, the auditor
+// and the idemix issuers parse. The independence of the Pedersen bases cannot be checked, it relies on the setup.
+// It returns an error listing all the problems found, nil if none.
+func (pp *PublicParams) SelfCheck() error {
+	c := &selfCheck{}
+
+	c.g1("generator P", pp.P)
+	if len(pp.ZKATPedParams) < 3 {
+		c.failf("expected at least 3 Pedersen bases, got [%d]", len(pp.ZKATPedParams))
+	}
+	for i, base := range pp.ZKATPedParams {
+		if !c.g1(fmt.Sprintf("Pedersen base [%d]", i), base) {
+			continue
+		}
+		if pp.P != nil && base.Equals(pp.P) {
+			c.failf("Pedersen base [%d] equals the generator P", i)
+		}
+		for j := 0; j < i; j++ {
+			if pp.ZKATPedParams[j] != nil && base.Equals(pp.ZKATPedParams[j]) {
+				c.failf("Pedersen bases [%d] and [%d] are equal", j, i)
+			}
+		}
+	}
+
+	if rp := pp.RangeProofParams; rp == nil {
+		c.failf("missing range proof parameters")
+	} else {
+		if rp.Exponent <= 0 {
+			c.failf("invalid range proof exponent [%d]", rp.Exponent)
+		}
+		if len(rp.SignedValues) < 2 {
+			c.failf("expected at least 2 range proof signed values, got [%d]", len(rp.SignedValues))
+		}
+		for i, sig := range rp.SignedValues {
+			if sig == nil {
+				c.failf("range proof signed value [%d] is missing", i)
+				continue
+			}
+			c.g1(fmt.Sprintf("range proof signed value [%d] R", i), sig.R)
+			c.g1(fmt.Sprintf("range proof signed value [%d] S", i), sig.S)
+		}
+		c.g2("range proof Q", rp.Q)
+		if len(rp.SignPK) != 3 {
+			c.failf("expected 3 range proof signature public keys, got [%d]", len(rp.SignPK))
+		}
+		for i, pk := range rp.SignPK {
+			c.g2(fmt.Sprintf("range proof signature public key [%d]", i), pk)
+		}
+		if pp.AuditFrom > pp.RangeBound() {
+			c.failf("audit threshold [%d] exceeds the bound of the range proofs [%d]", pp.AuditFrom, pp.RangeBound())
+		}
+	}
+
+	if ip, err := pp.GetIssuingPolicy(); err != nil {
+		c.failf("invalid issuing policy: %s", err)
+	} else {
+		for i, issuer := range ip.Issuers {
+			c.g1(fmt.Sprintf("issuer [%d]", i), issuer)
+		}
+		if acc := ip.Accumulator; acc != nil {
+			if err := acc.Validate(); err != nil {
+				c.failf("%s", err)
+			} else {
+				c.g1("issuer accumulator P", acc.P)
+				c.g2("issuer accumulator Q", acc.Q)
+				for i, pk := range acc.PK {
+					c.g2(fmt.Sprintf("issuer accumulator public key [%d]", i), pk)
+				}
+			}
+		}
+	}
+
+	if len(pp.Auditor) != 0 {
+		if _, err := (&fabric.MSPX509IdentityDeserializer{}).GetVerifier(pp.Auditor); err != nil {
+			c.failf("invalid auditor identity: %s", err)
+		}
+	}
+	if pk := pp.AuditorEncryptionKey; pk != nil {
+		c.g1("auditor encryption key generator", pk.Gen)
+		c.g1("auditor encryption key", pk.H)
+	}
+
+	for _, k := range pp.idemixKeys() {
+		if len(k.PK) == 0 {
+			c.failf("missing idemix issuer public key of epoch [%d]", k.Epoch)
+			continue
+		}
+		if _, err := idemix2.NewDeserializer(k.PK); err != nil {
+			c.failf("invalid idemix issuer public key of epoch [%d]: %s", k.Epoch, err)
+		}
+	}
+
+	if err := pp.AuditScope.Validate(); err != nil {
+		c.failf("%s", err)
+	}
+	if err := pp.ChallengeHash.Validate(); err != nil {
+		c.failf("%s", err)
+	}
+	return c.err()
+}
+
+// selfCheck collects the problems found by SelfCheck
+type selfCheck struct {
+	problems []string
+}
+
+func (c *selfCheck) failf(format string, args ...interface{}) {
+	c.problems = append(c.problems, fmt.Sprintf(format, args...))
+}
+
+func (c *selfCheck) g1(name string, g *bn256.G1) bool {
+	if g == nil {
+		c.failf("%s is missing", name)
+		return false
+	}
+	if !g.IsValid() {
+		c.failf("%s is not a valid point of G1", name)
+		return false
+	}
+	return true
+}
+
+func (c *selfCheck) g2(name string, g *bn256.G2) bool {
+	if g == nil {
+		c.failf("%s is missing", name)
+		return false
+	}
+	if !g.IsValid() {
+		c.failf("%s is not a valid point of G2", name)
+		return false
+	}
+	return true
+}
+
+func (c *selfCheck) err() error {
+	if len(c.problems) == 0 {
+		return nil
+	}
+	return errors.Errorf("malformed public parameters: %s", strings.Join(c.problems, "; "))
+}
@@ -0,0 +1,48 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package crypto
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+)
+
+func TestSelfCheck(t *testing.T) {
+	ipk, err := ioutil.ReadFile("./ppm/testdata/idemix/msp/IssuerPublicKey")
+	assert.NoError(t, err)
+	pp, err := Setup(16, 2, ipk)
+	assert.NoError(t, err)
+	assert.NoError(t, pp.SelfCheck())
+
+	// the check survives serialization
+	raw, err := pp.Serialize()
+	assert.NoError(t, err)
+	pp, err = NewPublicParamsFromBytes(raw)
+	assert.NoError(t, err)
+	assert.NoError(t, pp.SelfCheck())
+
+	// all the problems are reported
+	pp.ZKATPedParams[1] = bn256.NewG1()
+	pp.ZKATPedParams[2] = pp.ZKATPedParams[0]
+	pp.IdemixPK = []byte("not a key")
+	pp.Auditor = []byte("not an identity")
+	err = pp.SelfCheck()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "malformed public parameters")
+	assert.Contains(t, err.Error(), "Pedersen base [1] is not a valid point of G1")
+	assert.Contains(t, err.Error(), "Pedersen bases [0] and [2] are equal")
+	assert.Contains(t, err.Error(), "invalid idemix issuer public key of epoch [0]")
+	assert.Contains(t, err.Error(), "invalid auditor identity")
+
+	// parameters without an idemix issuer public key are refused
+	pp, err = Setup(16, 2, nil)
+	assert.NoError(t, err)
+	assert.EqualError(t, pp.SelfCheck(), "malformed public parameters: missing idemix issuer public key of epoch [0]")
+}
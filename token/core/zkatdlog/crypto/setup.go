@@ -6,13 +6,18 @@ SPDX-License-Identifier: Apache-2.0
 package crypto
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	math2 "math"
+	"sync"
 
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/pkg/errors"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/elgamal"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
 )
 
@@ -27,6 +32,47 @@ type PublicParams struct {
 	IdemixPK         []byte
 	IssuingPolicy    []byte
 	Auditor          []byte
+	// AuditorEncryptionKey, if set, is the key under which the audit infos of the token owners are encrypted,
+	// so that only the auditor can open them
+	AuditorEncryptionKey *elgamal.PublicKey `json:",omitempty"`
+	// AuditScope declares what the auditor can see, both the amounts and the identities by default
+	AuditScope api.AuditScope `json:",omitempty"`
+	// ChallengeHash derives the challenges of the zero-knowledge proofs, SHA256 if empty
+	ChallengeHash common.ChallengeHash `json:",omitempty"`
+	// ProofEncoding is the encoding of the zero-knowledge proofs, the JSON one if zero.
+	// The binary encoding must be selected only once all the nodes can read it.
+	ProofEncoding common.ProofEncoding `json:",omitempty"`
+	// IssuePolicy, if set, lists the approvers that must co-sign the issue actions.
+	// Token types are hidden, therefore only the entry for api.AnyTokenType is enforced.
+	IssuePolicy *api.IssuePolicy `json:",omitempty"`
+	// Migration, if set, enables the migration of the tokens of the driver replaced by zkatdlog
+	Migration *api.MigrationParams `json:",omitempty"`
+	// SupplyCaps, if set, bounds per token type the total value that can be issued.
+	// The issue actions of a capped type reveal the total value they issue, see issue.IssuedSupply.
+	SupplyCaps map[string]uint64 `json:",omitempty"`
+	// RedeemIssuer, if true, requires the redemptions to be co-signed by an issuer of the redeemed type.
+	// The type of a redeemed output is revealed by its burn receipt.
+	RedeemIssuer bool `json:",omitempty"`
+	// ReceiverPolicy, if set, restricts the receivers of the transfers to the owners whose idemix identity
+	// discloses an accepted organizational unit
+	ReceiverPolicy *api.ReceiverPolicy `json:",omitempty"`
+	// AuditFrom, if set, is the total value from which the token requests made of transfers only must be signed
+	// by the auditor. The smaller ones prove that their total is below it instead, see transfer.ThresholdProof.
+	AuditFrom uint64 `json:",omitempty"`
+	// Ceremony, if set, records the setup ceremony that generated the parameters, see VerifyCeremony
+	Ceremony *Ceremony `json:",omitempty"`
+	// IdemixKeys, if set, are the idemix issuer public keys introduced so far, IdemixPK being the last one.
+	// The credentials issued under any of them are accepted until they expire, see RotateIdemixPK.
+	IdemixKeys []*IdemixIssuerKey `json:",omitempty"`
+	// IdemixEpoch is the current epoch of the idemix issuer public keys
+	IdemixEpoch uint64 `json:",omitempty"`
+	// DepartedOrganizations are the organizational units, disclosed by the idemix owners, of the organizations that
+	// left the consortium, whose tokens can be rebound to successor owners, see api.RebindingAction
+	DepartedOrganizations []string `json:",omitempty"`
+
+	// hash caches the hash of the serialized public parameters
+	hashLock sync.Mutex
+	hash     []byte
 }
 
 type RangeProofParams struct {
@@ -64,6 +110,72 @@ func (pp *PublicParams) MaxTokenValue() uint64 {
 	return uint64(len(pp.RangeProofParams.SignedValues)) - 1
 }
 
+// RangeBound returns the bound of the values proven by the range proofs, base to the power of the exponent
+func (pp *PublicParams) RangeBound() uint64 {
+	bound := uint64(1)
+	for i := 0; i < pp.RangeProofParams.Exponent; i++ {
+		bound *= uint64(len(pp.RangeProofParams.SignedValues))
+	}
+	return bound
+}
+
+func (pp *PublicParams) AuditorIdentity() view.Identity {
+	if len(pp.Auditor) == 0 {
+		return nil
+	}
+	return pp.Auditor
+}
+
+// TypeAuditor returns the default auditor, token types are hidden and cannot be assigned to different auditors
+func (pp *PublicParams) TypeAuditor(tokenType string) view.Identity {
+	return pp.AuditorIdentity()
+}
+
+func (pp *PublicParams) IssueApprover(tokenType string) view.Identity {
+	return pp.IssuePolicy.Approver(api.AnyTokenType)
+}
+
+func (pp *PublicParams) RedeemRequiresIssuer() bool {
+	return pp.RedeemIssuer
+}
+
+// SupplyCap returns the cap of the total value that can be issued of the passed token type, false if uncapped
+func (pp *PublicParams) SupplyCap(tokenType string) (uint64, bool) {
+	supplyCap, ok := pp.SupplyCaps[tokenType]
+	return supplyCap, ok
+}
+
+// SetSupplyCap sets the cap of the total value that can be issued of the passed token type
+func (pp *PublicParams) SetSupplyCap(tokenType string, supplyCap uint64) {
+	defer pp.ResetHash()
+	if pp.SupplyCaps == nil {
+		pp.SupplyCaps = map[string]uint64{}
+	}
+	pp.SupplyCaps[tokenType] = supplyCap
+}
+
+// Departed returns true if the organization with the passed organizational unit left the consortium
+func (pp *PublicParams) Departed(organization string) bool {
+	return api.DepartedList(pp.DepartedOrganizations).Departed(organization)
+}
+
+// AuditThreshold returns the total value under which the token requests made of transfers only can skip the auditor,
+// 0 if every request is audited
+func (pp *PublicParams) AuditThreshold() uint64 {
+	return pp.AuditFrom
+}
+
+// SetAuditThreshold sets the total value under which the token requests made of transfers only can skip the auditor,
+// 0 to audit every request. The threshold cannot exceed the bound of the range proofs.
+func (pp *PublicParams) SetAuditThreshold(threshold uint64) error {
+	defer pp.ResetHash()
+	if threshold > pp.RangeBound() {
+		return errors.Errorf("audit threshold [%d] exceeds the bound of the range proofs [%d]", threshold, pp.RangeBound())
+	}
+	pp.AuditFrom = threshold
+	return nil
+}
+
 func (pp *PublicParams) Bytes() ([]byte, error) {
 	return pp.Serialize()
 }
@@ -79,7 +191,33 @@ func (pp *PublicParams) Serialize() ([]byte, error) {
 	})
 }
 
+// Hash returns the hash of the serialized public parameters.
+// The hash is computed once and reset by the methods modifying the public parameters.
+// Who modifies the fields directly must call ResetHash.
+func (pp *PublicParams) Hash() ([]byte, error) {
+	pp.hashLock.Lock()
+	defer pp.hashLock.Unlock()
+	if len(pp.hash) != 0 {
+		return pp.hash, nil
+	}
+	raw, err := pp.Serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed serializing public parameters")
+	}
+	h := sha256.Sum256(raw)
+	pp.hash = h[:]
+	return pp.hash, nil
+}
+
+// ResetHash forces the next call to Hash to recompute the hash of the public parameters
+func (pp *PublicParams) ResetHash() {
+	pp.hashLock.Lock()
+	pp.hash = nil
+	pp.hashLock.Unlock()
+}
+
 func (pp *PublicParams) Deserialize(raw []byte) error {
+	defer pp.ResetHash()
 	publicParams := &api.SerializedPublicParameters{}
 	if err := json.Unmarshal(raw, publicParams); err != nil {
 		return err
@@ -88,10 +226,17 @@ func (pp *PublicParams) Deserialize(raw []byte) error {
 		return errors.Errorf("invalid identifier, expecting 'dlog', got [%s]", publicParams.Identifier)
 	}
 	// logger.Debugf("unmarshall zkatdlog public params [%s]", string(publicParams.Raw))
-	return json.Unmarshal(publicParams.Raw, pp)
+	if err := json.Unmarshal(publicParams.Raw, pp); err != nil {
+		return err
+	}
+	if err := pp.ProofEncoding.Validate(); err != nil {
+		return err
+	}
+	return pp.ChallengeHash.Validate()
 }
 
 func (pp *PublicParams) GeneratePedersenParameters() error {
+	defer pp.ResetHash()
 	rand, err := bn256.GetRand()
 	if err != nil {
 		return errors.Errorf("failed to get RNG")
@@ -106,6 +251,7 @@ func (pp *PublicParams) GeneratePedersenParameters() error {
 }
 
 func (pp *PublicParams) GenerateRangeProofParameters(signer *pssign.Signer, maxValue int64) error {
+	defer pp.ResetHash()
 	pp.RangeProofParams = &RangeProofParams{Q: signer.Q, SignPK: signer.PK}
 
 	pp.RangeProofParams.SignedValues = make([]*pssign.Signature, maxValue)
@@ -123,7 +269,15 @@ func (pp *PublicParams) GenerateRangeProofParameters(signer *pssign.Signer, maxV
 }
 
 func (pp *PublicParams) SetIssuingPolicy(issuers []*bn256.G1) error {
+	defer pp.ResetHash()
 	ip := &IssuingPolicy{BitLength: int(math2.Ceil(math2.Log2(float64(len(issuers))))), IssuersNumber: len(issuers)}
+	if len(pp.IssuingPolicy) != 0 {
+		old, err := pp.GetIssuingPolicy()
+		if err != nil {
+			return err
+		}
+		ip.Accumulator = old.Accumulator
+	}
 
 	// pad list of issuers with a dummy commitment
 	if len(issuers) != int(math2.Exp2(math2.Ceil(math2.Log2(float64(len(issuers)))))) {
@@ -157,6 +311,71 @@ func (pp *PublicParams) AddIssuer(issuer *bn256.G1) error {
 	return nil
 }
 
+// SetIssuerAccumulator sets the accumulator of the anonymous issuers, nil to resort to the list of issuers.
+// The epoch of the accumulator must be greater than the one of the accumulator it replaces.
+func (pp *PublicParams) SetIssuerAccumulator(acc *IssuerAccumulator) error {
+	defer pp.ResetHash()
+	ip, err := pp.GetIssuingPolicy()
+	if err != nil {
+		return err
+	}
+	if acc != nil {
+		if err := acc.Validate(); err != nil {
+			return err
+		}
+		if ip.Accumulator != nil && acc.Epoch <= ip.Accumulator.Epoch {
+			return errors.Errorf("issuer accumulator epoch must be greater than [%d]", ip.Accumulator.Epoch)
+		}
+	}
+	ip.Accumulator = acc
+	pp.IssuingPolicy, err = ip.Serialize()
+	return err
+}
+
+func (pp *PublicParams) SetAuditorEncryptionKey(pk *elgamal.PublicKey) error {
+	defer pp.ResetHash()
+	if pk == nil || pk.Gen == nil || pk.H == nil {
+		return errors.Errorf("invalid auditor encryption key")
+	}
+	pp.AuditorEncryptionKey = pk
+	return nil
+}
+
+// GetAuditScope returns what the auditor can see
+func (pp *PublicParams) GetAuditScope() api.AuditScope {
+	return pp.AuditScope
+}
+
+// SetAuditScope sets what the auditor can see
+func (pp *PublicParams) SetAuditScope(scope api.AuditScope) error {
+	defer pp.ResetHash()
+	if err := scope.Validate(); err != nil {
+		return err
+	}
+	pp.AuditScope = scope
+	return nil
+}
+
+// SetChallengeHash sets the hash function from which the challenges of the zero-knowledge proofs are derived
+func (pp *PublicParams) SetChallengeHash(h common.ChallengeHash) error {
+	defer pp.ResetHash()
+	if err := h.Validate(); err != nil {
+		return err
+	}
+	pp.ChallengeHash = h
+	return nil
+}
+
+// SetProofEncoding sets the encoding of the zero-knowledge proofs
+func (pp *PublicParams) SetProofEncoding(e common.ProofEncoding) error {
+	defer pp.ResetHash()
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	pp.ProofEncoding = e
+	return nil
+}
+
 func (pp *PublicParams) GetIssuingPolicy() (*IssuingPolicy, error) {
 	ip := &IssuingPolicy{}
 	err := ip.Deserialize(pp.IssuingPolicy)
@@ -189,6 +408,7 @@ func Setup(base int64, exponent int, nymPK []byte) (*PublicParams, error) {
 	}
 	pp.IdemixPK = nymPK
 	pp.RangeProofParams.Exponent = exponent
+	pp.ResetHash()
 	// max value of any given token is max = base^exponent - 1
 	return pp, nil
 }
@@ -25,12 +25,59 @@ type MembershipProof struct {
 	Commitment        *bn256.G1
 }
 
+// Serialize returns the JSON encoding of the proof
 func (p *MembershipProof) Serialize() ([]byte, error) {
 	return json.Marshal(p)
 }
 
+// SerializeBinary returns the binary encoding of the proof, see common.Encoder
+func (p *MembershipProof) SerializeBinary() ([]byte, error) {
+	e := common.NewEncoder()
+	e.Zr(p.Challenge)
+	encodeSignature(e, p.Signature)
+	e.Zr(p.Value)
+	e.Zr(p.ComBlindingFactor)
+	e.Zr(p.SigBlindingFactor)
+	e.Zr(p.Hash)
+	e.G1(p.Commitment)
+	return e.Encoded(), nil
+}
+
+// Deserialize decodes the passed proof, in the binary encoding or in the JSON one
 func (p *MembershipProof) Deserialize(raw []byte) error {
-	return json.Unmarshal(raw, p)
+	if !common.IsBinary(raw) {
+		return json.Unmarshal(raw, p)
+	}
+	d, err := common.NewDecoder(raw)
+	if err != nil {
+		return err
+	}
+	p.Challenge = d.Zr()
+	p.Signature = decodeSignature(d)
+	p.Value = d.Zr()
+	p.ComBlindingFactor = d.Zr()
+	p.SigBlindingFactor = d.Zr()
+	p.Hash = d.Zr()
+	p.Commitment = d.G1()
+	return d.Err()
+}
+
+func encodeSignature(e *common.Encoder, sig *pssign.Signature) {
+	if sig == nil {
+		e.G1(nil)
+		e.G1(nil)
+		return
+	}
+	e.G1(sig.R)
+	e.G1(sig.S)
+}
+
+func decodeSignature(d *common.Decoder) *pssign.Signature {
+	r, s := d.G1(), d.G1()
+	if r == nil && s == nil {
+		return nil
+	}
+	return &pssign.Signature{R: r, S: s}
 }
 
 // witness for membership proof
@@ -46,6 +93,16 @@ func NewMembershipWitness(sig *pssign.Signature, value *bn256.Zr, bf *bn256.Zr)
 	return &MembershipWitness{signature: sig, value: value, comBlidingFactor: bf}
 }
 
+// Zeroize wipes the blinding factors of the witness
+func (w *MembershipWitness) Zeroize() {
+	bn256.Zeroize(w.sigBlindingFactor, w.comBlidingFactor)
+}
+
+// NewMembershipWitnessWithHash returns a witness for which the hash of the value is already known
+func NewMembershipWitnessWithHash(sig *pssign.Signature, value *bn256.Zr, hash *bn256.Zr, bf *bn256.Zr) *MembershipWitness {
+	return &MembershipWitness{signature: sig, value: value, hash: hash, comBlidingFactor: bf}
+}
+
 func NewMembershipProver(witness *MembershipWitness, com, P *bn256.G1, Q *bn256.G2, PK []*bn256.G2, pp []*bn256.G1) *MembershipProver {
 	return &MembershipProver{witness: witness, MembershipVerifier: NewMembershipVerifier(com, P, Q, PK, pp)}
 }
@@ -60,6 +117,10 @@ type MembershipProver struct {
 	witness    *MembershipWitness
 	randomness *MembershipRandomness
 	Commitment *MembershipCommitment
+	// Rand is the source of the randomness of the proof, the default one if nil, see bn256.NewDRBG
+	Rand bn256.Rand
+	// Encoding is the encoding of the proof
+	Encoding common.ProofEncoding
 }
 
 // MembershipCommitment to randomness in proof
@@ -123,7 +184,7 @@ func (p *MembershipProver) Prove() ([]byte, error) {
 	proof.Hash = proofs[2]
 	proof.SigBlindingFactor = proofs[3]
 
-	return proof.Serialize()
+	return p.Encoding.Encode(proof)
 }
 
 // verify membership proof
@@ -149,33 +210,33 @@ func (v *MembershipVerifier) Verify(raw []byte) error {
 	if err != nil {
 		return nil
 	}
-	if chal.Cmp(proof.Challenge) != 0 {
+	if !bn256.ConstantTimeEqual(chal, proof.Challenge) {
 		return errors.Errorf("invalid membership proof")
 	}
 	return nil
 }
 
 func (p *MembershipProver) obfuscateSignature() (*pssign.Signature, error) {
-	rand, err := bn256.GetRand()
+	rand, err := bn256.RandOrDefault(p.Rand)
 	if err != nil {
 		return nil, errors.Errorf("failed to get RNG")
 	}
 
 	p.witness.sigBlindingFactor = bn256.RandModOrder(rand)
-	err = p.witness.signature.Randomize()
+	err = p.witness.signature.RandomizeWithRand(p.Rand)
 	if err != nil {
 		return nil, err
 	}
 	sig := &pssign.Signature{}
 	sig.Copy(p.witness.signature)
-	sig.S.Add(p.P.Mul(p.witness.sigBlindingFactor))
+	sig.S.AddMul(p.P, p.witness.sigBlindingFactor)
 
 	return sig, nil
 }
 
 func (p *MembershipProver) computeCommitment() error {
 	// Get RNG
-	rand, err := bn256.GetRand()
+	rand, err := bn256.RandOrDefault(p.Rand)
 	if err != nil {
 		return errors.Errorf("failed to get RNG")
 	}
@@ -185,16 +246,19 @@ func (p *MembershipProver) computeCommitment() error {
 	p.randomness.hash = bn256.RandModOrder(rand)
 	p.randomness.sigBlindingFactor = bn256.RandModOrder(rand)
 
-	t := p.PK[1].Mul(p.randomness.value)
-	t.Add(p.PK[2].Mul(p.randomness.hash))
+	t := bn256.GetG2().SetMul(p.PK[1], p.randomness.value)
+	t.AddMul(p.PK[2], p.randomness.hash)
+	blinding := bn256.GetG1().SetMul(p.P, p.randomness.sigBlindingFactor)
 
 	p.Commitment = &MembershipCommitment{}
-	p.Commitment.Signature = bn256.Pairing(t, p.witness.signature.R, p.Q, p.P.Mul(p.randomness.sigBlindingFactor))
+	p.Commitment.Signature = bn256.Pairing(t, p.witness.signature.R, p.Q, blinding)
 	p.Commitment.Signature = bn256.FinalExp(p.Commitment.Signature)
+	bn256.PutG2(t)
+	bn256.PutG1(blinding)
 
 	p.randomness.comBlindingFactor = bn256.RandModOrder(rand)
 	p.Commitment.CommitmentToValue = p.PedersenParams[0].Mul(p.randomness.value)
-	p.Commitment.CommitmentToValue.Add(p.PedersenParams[1].Mul(p.randomness.comBlindingFactor))
+	p.Commitment.CommitmentToValue.AddMul(p.PedersenParams[1], p.randomness.comBlindingFactor)
 
 	return nil
 }
@@ -209,10 +273,13 @@ func (v *MembershipVerifier) computeChallenge(comToValue *bn256.G1, com *Members
 	}
 	raw = append(raw, bytes...)
 
-	return bn256.HashModOrder(raw), nil
+	return v.Hash.HashModOrder(raw), nil
 }
 
 func (p *MembershipProver) computeHash() {
+	if p.witness.hash != nil {
+		return
+	}
 	bytes := p.witness.value.Bytes()
 	p.witness.hash = bn256.HashModOrder(bytes)
 	return
@@ -220,7 +287,7 @@ func (p *MembershipProver) computeHash() {
 
 // recompute commitments for verification
 func (v *MembershipVerifier) recomputeCommitments(p *MembershipProof) (*MembershipCommitment, error) {
-	psv := &POKVerifier{P: v.P, Q: v.Q, PK: v.PK}
+	psv := &POKVerifier{P: v.P, Q: v.Q, PK: v.PK, Hash: v.Hash}
 	c := &MembershipCommitment{}
 
 	psp := &POK{
@@ -41,12 +41,16 @@ type POKProver struct {
 	*POKVerifier
 	Witness    *POKWitness
 	randomness *POKRandomness
+	// Rand is the source of the randomness of the proof, the default one if nil, see bn256.NewDRBG
+	Rand bn256.Rand
 }
 
 type POKVerifier struct {
 	PK []*bn256.G2
 	Q  *bn256.G2
 	P  *bn256.G1
+	// Hash derives the challenges, SHA256 if empty
+	Hash common.ChallengeHash
 }
 
 func (p *POKProver) Prove() ([]byte, error) {
@@ -84,22 +88,25 @@ func (p *POKProver) Prove() ([]byte, error) {
 }
 func (p *POKProver) computeCommitment() (*bn256.GT, error) {
 	// Get RNG
-	rand, err := bn256.GetRand()
+	rand, err := bn256.RandOrDefault(p.Rand)
 	if err != nil {
 		return nil, errors.Errorf("failed to get RNG")
 	}
 	// compute commitment
 	p.randomness = &POKRandomness{}
 	p.randomness.hash = bn256.RandModOrder(rand)
-	t := p.PK[len(p.Witness.Messages)+1].Mul(p.randomness.hash)
+	t := bn256.GetG2().SetMul(p.PK[len(p.Witness.Messages)+1], p.randomness.hash)
+	defer bn256.PutG2(t)
 	p.randomness.messages = make([]*bn256.Zr, len(p.Witness.Messages))
 	for i := 0; i < len(p.Witness.Messages); i++ {
 		p.randomness.messages[i] = bn256.RandModOrder(rand)
-		t.Add(p.PK[i+1].Mul(p.randomness.messages[i]))
+		t.AddMul(p.PK[i+1], p.randomness.messages[i])
 	}
 
 	p.randomness.blindingFactor = bn256.RandModOrder(rand)
-	com := bn256.Pairing(t, p.Witness.Signature.R, p.Q, p.P.Mul(p.randomness.blindingFactor))
+	blinding := bn256.GetG1().SetMul(p.P, p.randomness.blindingFactor)
+	defer bn256.PutG1(blinding)
+	com := bn256.Pairing(t, p.Witness.Signature.R, p.Q, blinding)
 
 	return bn256.FinalExp(com), nil
 }
@@ -123,7 +130,7 @@ func (v *POKVerifier) Verify(p []byte) error {
 	}
 
 	// check proof is valid
-	if proof.Challenge.Cmp(chal) != 0 {
+	if !bn256.ConstantTimeEqual(proof.Challenge, chal) {
 		return errors.Errorf("proof of PS signature is not valid")
 	}
 	return nil
@@ -135,16 +142,21 @@ func (v *POKVerifier) RecomputeCommitment(p *POK) (*bn256.GT, error) {
 	}
 	t := bn256.NewG2()
 	for i := 0; i < len(p.Messages); i++ {
-		t.Add(v.PK[i+1].Mul(p.Messages[i]))
+		t.AddMul(v.PK[i+1], p.Messages[i])
 	}
-	t.Add(v.PK[len(p.Messages)+1].Mul(p.Hash))
+	t.AddMul(v.PK[len(p.Messages)+1], p.Hash)
 
 	pk := bn256.NewG2()
 	pk.Sub(v.PK[0])
 
-	com := bn256.Pairing(v.Q, p.Signature.S.Mul(p.Challenge), pk, p.Signature.R.Mul(p.Challenge))
+	// the products are copied by the pairings, they are transient
+	s := bn256.GetG1().SetMul(p.Signature.S, p.Challenge)
+	r := bn256.GetG1().SetMul(p.Signature.R, p.Challenge)
+	blinding := bn256.GetG1().SetMul(v.P, p.BlindingFactor)
+	defer bn256.PutG1(s, r, blinding)
+	com := bn256.Pairing(v.Q, s, pk, r)
 	com.Inverse()
-	com.Mul(bn256.Pairing(t, p.Signature.R, v.Q, v.P.Mul(p.BlindingFactor)))
+	com.Mul(bn256.Pairing(t, p.Signature.R, v.Q, blinding))
 
 	return bn256.FinalExp(com), nil
 }
@@ -160,19 +172,19 @@ func HashMessages(m []*bn256.Zr) *bn256.Zr {
 }
 
 func (p *POKProver) obfuscateSignature() (*pssign.Signature, error) {
-	rand, err := bn256.GetRand()
+	rand, err := bn256.RandOrDefault(p.Rand)
 	if err != nil {
 		return nil, errors.Errorf("failed to get RNG")
 	}
 
 	p.Witness.BlindingFactor = bn256.RandModOrder(rand)
-	err = p.Witness.Signature.Randomize()
+	err = p.Witness.Signature.RandomizeWithRand(p.Rand)
 	if err != nil {
 		return nil, err
 	}
 	sig := &pssign.Signature{}
 	sig.Copy(p.Witness.Signature)
-	sig.S.Add(p.P.Mul(p.Witness.BlindingFactor))
+	sig.S.AddMul(p.P, p.Witness.BlindingFactor)
 
 	return sig, nil
 }
@@ -185,6 +197,6 @@ func (v *POKVerifier) computeChallenge(com *bn256.GT, signature *pssign.Signatur
 		return nil, errors.Wrapf(err, "failed to compute challenge")
 	}
 	// compute challenge
-	return bn256.HashModOrder(common.GetBytesArray(v.P.Bytes(), g2a.Bytes(), bytes, com.Bytes())), nil
+	return v.Hash.HashModOrder(common.GetBytesArray(v.P.Bytes(), g2a.Bytes(), bytes, com.Bytes())), nil
 
 }
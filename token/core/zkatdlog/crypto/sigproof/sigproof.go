@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package sigproof
 
 import (
+	"encoding/json"
+
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
@@ -22,12 +24,51 @@ type SigProof struct {
 	Commitment        *bn256.G1 // for hidden values
 }
 
+// Serialize returns the JSON encoding of the proof
+func (p *SigProof) Serialize() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// SerializeBinary returns the binary encoding of the proof, see common.Encoder
+func (p *SigProof) SerializeBinary() ([]byte, error) {
+	e := common.NewEncoder()
+	e.Zr(p.Challenge)
+	e.Zrs(p.Hidden)
+	e.Zr(p.Hash)
+	encodeSignature(e, p.Signature)
+	e.Zr(p.SigBlindingFactor)
+	e.Zr(p.ComBlindingFactor)
+	e.G1(p.Commitment)
+	return e.Encoded(), nil
+}
+
+// Deserialize decodes the passed proof, in the binary encoding or in the JSON one
+func (p *SigProof) Deserialize(raw []byte) error {
+	if !common.IsBinary(raw) {
+		return json.Unmarshal(raw, p)
+	}
+	d, err := common.NewDecoder(raw)
+	if err != nil {
+		return err
+	}
+	p.Challenge = d.Zr()
+	p.Hidden = d.Zrs()
+	p.Hash = d.Zr()
+	p.Signature = decodeSignature(d)
+	p.SigBlindingFactor = d.Zr()
+	p.ComBlindingFactor = d.Zr()
+	p.Commitment = d.G1()
+	return d.Err()
+}
+
 // commitments how they are computed
 type SigProver struct {
 	*SigVerifier
 	witness    *SigWitness
 	randomness *SigRandomness
 	Commitment *SigCommitment
+	// Rand is the source of the randomness of the proof, the default one if nil, see bn256.NewDRBG
+	Rand bn256.Rand
 }
 
 type SigVerifier struct {
@@ -138,7 +179,7 @@ func (p *SigProver) computeCommitment() error {
 		return errors.Errorf("size of signature public key does not mathc the size of the witness")
 	}
 	// Get RNG
-	rand, err := bn256.GetRand()
+	rand, err := bn256.RandOrDefault(p.Rand)
 	if err != nil {
 		return errors.Errorf("failed to get RNG")
 	}
@@ -155,33 +196,36 @@ func (p *SigProver) computeCommitment() error {
 	p.Commitment = &SigCommitment{}
 	p.Commitment.CommitmentToMessages = p.PedersenParams[len(p.witness.hidden)].Mul(p.randomness.comBlindingFactor)
 	for i, r := range p.randomness.hidden {
-		p.Commitment.CommitmentToMessages.Add(p.PedersenParams[i].Mul(r))
+		p.Commitment.CommitmentToMessages.AddMul(p.PedersenParams[i], r)
 	}
 
-	t := p.PK[len(p.Disclosed)+len(p.witness.hidden)+1].Mul(p.randomness.hash)
+	t := bn256.GetG2().SetMul(p.PK[len(p.Disclosed)+len(p.witness.hidden)+1], p.randomness.hash)
 	for i, index := range p.HiddenIndices {
-		t.Add(p.PK[index+1].Mul(p.randomness.hidden[i]))
+		t.AddMul(p.PK[index+1], p.randomness.hidden[i])
 	}
+	blinding := bn256.GetG1().SetMul(p.P, p.randomness.sigBlindingFactor)
 
-	p.Commitment.Signature = bn256.Pairing(t, p.witness.signature.R, p.Q, p.P.Mul(p.randomness.sigBlindingFactor))
+	p.Commitment.Signature = bn256.Pairing(t, p.witness.signature.R, p.Q, blinding)
 	p.Commitment.Signature = bn256.FinalExp(p.Commitment.Signature)
+	bn256.PutG2(t)
+	bn256.PutG1(blinding)
 	return nil
 }
 
 func (p *SigProver) obfuscateSignature() (*pssign.Signature, error) {
-	rand, err := bn256.GetRand()
+	rand, err := bn256.RandOrDefault(p.Rand)
 	if err != nil {
 		return nil, errors.Errorf("failed to get RNG")
 	}
 
 	p.witness.sigBlindingFactor = bn256.RandModOrder(rand)
-	err = p.witness.signature.Randomize()
+	err = p.witness.signature.RandomizeWithRand(p.Rand)
 	if err != nil {
 		return nil, err
 	}
 	sig := &pssign.Signature{}
 	sig.Copy(p.witness.signature)
-	sig.S.Add(p.P.Mul(p.witness.sigBlindingFactor))
+	sig.S.AddMul(p.P, p.witness.sigBlindingFactor)
 
 	return sig, nil
 }
@@ -197,7 +241,7 @@ func (v *SigVerifier) computeChallenge(comToMessages *bn256.G1, signature *pssig
 	}
 	raw = append(raw, bytes...)
 
-	return bn256.HashModOrder(raw), nil
+	return v.Hash.HashModOrder(raw), nil
 }
 
 // recompute commitments for verification
@@ -227,7 +271,7 @@ func (v *SigVerifier) recomputeCommitments(p *SigProof) (*SigCommitment, error)
 		BlindingFactor: p.SigBlindingFactor,
 	}
 
-	sv := &POKVerifier{P: v.P, Q: v.Q, PK: v.PK}
+	sv := &POKVerifier{P: v.P, Q: v.Q, PK: v.PK, Hash: v.Hash}
 	var err error
 	c.Signature, err = sv.RecomputeCommitment(sp)
 	if err != nil {
@@ -241,14 +285,14 @@ func (v *SigVerifier) Verify(p *SigProof) error {
 
 	com, err := v.recomputeCommitments(p)
 	if err != nil {
-		return nil
+		return err
 	}
 
 	chal, err := v.computeChallenge(p.Commitment, p.Signature, com)
 	if err != nil {
-		return nil
+		return err
 	}
-	if chal.Cmp(p.Challenge) != 0 {
+	if !bn256.ConstantTimeEqual(chal, p.Challenge) {
 		return errors.Errorf("invalid signature proof")
 	}
 	return nil
@@ -0,0 +1,61 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// NewMigrationAction returns the action migrating the passed cleartext tokens, stored on the ledger under the passed keys,
+// to the passed owners. The i-th token is migrated into a commitment to its type and quantity owned by the i-th owner.
+// The returned token information are the openings of the outputs, the owners need them to spend the outputs.
+func NewMigrationAction(keys []string, tokens []*token2.Token, owners [][]byte, precision uint64, pp *crypto.PublicParams) (*api.MigrationAction, []*TokenInformation, error) {
+	if len(keys) != len(tokens) || len(keys) != len(owners) {
+		return nil, nil, errors.Errorf("[%d] keys, [%d] tokens, and [%d] owners do not match", len(keys), len(tokens), len(owners))
+	}
+	rand, err := bn256.GetRand()
+	if err != nil {
+		return nil, nil, errors.Errorf("failed to get random number generator")
+	}
+	action := &api.MigrationAction{Inputs: keys}
+	infos := make([]*TokenInformation, len(tokens))
+	for i, tok := range tokens {
+		q, err := token2.ToQuantity(tok.Quantity, precision)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid quantity [%s]", tok.Quantity)
+		}
+		if !q.ToBigInt().IsUint64() || q.ToBigInt().Uint64() > pp.MaxTokenValue() {
+			return nil, nil, errors.Errorf("quantity [%s] exceeds the maximum token value [%d]", tok.Quantity, pp.MaxTokenValue())
+		}
+		infos[i] = &TokenInformation{
+			Type:           tok.Type,
+			Value:          bn256.NewZrInt(0).SetUint64(q.ToBigInt().Uint64()),
+			BlindingFactor: bn256.RandModOrder(rand),
+			Owner:          owners[i],
+		}
+		com, err := common.ComputePedersenCommitment([]*bn256.Zr{bn256.HashModOrder([]byte(tok.Type)), infos[i].Value, infos[i].BlindingFactor}, pp.ZKATPedParams)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed to compute token")
+		}
+		out, err := (&Token{Owner: owners[i], Data: com}).Serialize()
+		if err != nil {
+			return nil, nil, err
+		}
+		opening, err := infos[i].Serialize()
+		if err != nil {
+			return nil, nil, err
+		}
+		action.Outputs = append(action.Outputs, out)
+		action.Openings = append(action.Openings, opening)
+	}
+	return action, infos, nil
+}
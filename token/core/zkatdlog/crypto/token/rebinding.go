@@ -0,0 +1,130 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+)
+
+// RebindingProof proves that the commitment of a rebound token re-randomizes the one of its input: their difference
+// is a multiple of the generator of the blinding factors. It is a Schnorr proof of knowledge of that multiple.
+type RebindingProof struct {
+	Challenge *bn256.Zr
+	Response  *bn256.Zr
+}
+
+func (p *RebindingProof) Serialize() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func (p *RebindingProof) Deserialize(raw []byte) error {
+	return json.Unmarshal(raw, p)
+}
+
+// NewRebindingAction returns the action rebinding the passed serialized tokens of the members of the passed departed
+// organization, stored on the ledger under the passed keys, to the passed owners. The commitment of the i-th token
+// is re-randomized, so that the output cannot be linked to its input, and bound to the i-th owner.
+// The blinding factor of the i-th output is the one of its input plus the i-th returned factor,
+// see RebindTokenInformation. The openings of the outputs reach the new owners with the metadata of the action,
+// see api.RebindingMetadata.
+func NewRebindingAction(organization string, keys []string, tokens [][]byte, owners [][]byte, pp *crypto.PublicParams) (*api.RebindingAction, []*bn256.Zr, error) {
+	if len(keys) != len(tokens) || len(keys) != len(owners) {
+		return nil, nil, errors.Errorf("[%d] keys, [%d] tokens, and [%d] owners do not match", len(keys), len(tokens), len(owners))
+	}
+	rand, err := bn256.GetRand()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed getting random number generator")
+	}
+	action := &api.RebindingAction{Organization: organization, Inputs: keys}
+	factors := make([]*bn256.Zr, len(tokens))
+	for i, raw := range tokens {
+		in := &Token{}
+		if err := in.Deserialize(raw); err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to deserialize token [%s]", keys[i])
+		}
+		if in.Data == nil {
+			return nil, nil, errors.Errorf("token [%s] has no commitment", keys[i])
+		}
+		factors[i] = bn256.RandModOrder(rand)
+		out := &Token{
+			Owner: owners[i],
+			Data:  bn256.NewG1().Copy(in.Data).AddMul(pp.ZKATPedParams[2], factors[i]),
+		}
+		proof := proveRebinding(in, out, factors[i], bn256.RandModOrder(rand), pp)
+		outRaw, err := out.Serialize()
+		if err != nil {
+			return nil, nil, err
+		}
+		proofRaw, err := proof.Serialize()
+		if err != nil {
+			return nil, nil, err
+		}
+		action.Outputs = append(action.Outputs, outRaw)
+		action.Proofs = append(action.Proofs, proofRaw)
+	}
+	return action, factors, nil
+}
+
+// RebindTokenInformation returns the opening of a rebound token, given the opening of its input,
+// the owner of the rebound token and its re-randomization factor, see NewRebindingAction
+func RebindTokenInformation(input *TokenInformation, owner []byte, factor *bn256.Zr) *TokenInformation {
+	return &TokenInformation{
+		Type:           input.Type,
+		Value:          input.Value,
+		BlindingFactor: bn256.ModAdd(input.BlindingFactor, factor, bn256.Order),
+		Owner:          owner,
+		Issuer:         input.Issuer,
+	}
+}
+
+// VerifyRebinding checks that the passed output re-randomizes the commitment of the passed input,
+// by checking the passed serialized RebindingProof
+func VerifyRebinding(in, out *Token, proofRaw []byte, pp *crypto.PublicParams) error {
+	if in.Data == nil || out.Data == nil {
+		return errors.New("rebinding without commitments")
+	}
+	proof := &RebindingProof{}
+	if err := proof.Deserialize(proofRaw); err != nil {
+		return errors.Wrap(err, "failed to deserialize rebinding proof")
+	}
+	if proof.Challenge == nil || proof.Response == nil {
+		return errors.New("incomplete rebinding proof")
+	}
+	// the commitment of the prover is recomputed as response*H - challenge*(out - in)
+	statement := bn256.NewG1().Copy(out.Data).Sub(in.Data)
+	commitment := pp.ZKATPedParams[2].Mul(proof.Response).Sub(statement.Mul(proof.Challenge))
+	if rebindingChallenge(in, out, commitment, pp).Cmp(proof.Challenge) != 0 {
+		return errors.New("invalid rebinding proof")
+	}
+	return nil
+}
+
+// proveRebinding returns the proof that the output re-randomizes the input by the passed factor,
+// the passed randomness is the one of the Schnorr proof
+func proveRebinding(in, out *Token, factor, randomness *bn256.Zr, pp *crypto.PublicParams) *RebindingProof {
+	commitment := pp.ZKATPedParams[2].Mul(randomness)
+	challenge := rebindingChallenge(in, out, commitment, pp)
+	response := bn256.ModAdd(randomness, bn256.ModMul(challenge, factor, bn256.Order), bn256.Order)
+	return &RebindingProof{Challenge: challenge, Response: response}
+}
+
+// rebindingChallenge returns the challenge of the rebinding proof, bound to the input, the output and its owner
+func rebindingChallenge(in, out *Token, commitment *bn256.G1, pp *crypto.PublicParams) *bn256.Zr {
+	var buf bytes.Buffer
+	buf.Write(pp.ZKATPedParams[2].Bytes())
+	buf.Write(in.Data.Bytes())
+	buf.Write(out.Data.Bytes())
+	buf.Write(out.Owner)
+	buf.Write(commitment.Bytes())
+	return pp.ChallengeHash.HashModOrder(buf.Bytes())
+}
@@ -38,14 +38,28 @@ func (t *Token) GetCommitment() *bn256.G1 {
 	return t.Data
 }
 
-func (t *Token) GetTokenInTheClear(inf *TokenInformation, pp *crypto.PublicParams) (*token2.Token, error) {
-	com, err := common.ComputePedersenCommitment([]*bn256.Zr{bn256.HashModOrder([]byte(inf.Type)), inf.Value, inf.BlindingFactor}, pp.ZKATPedParams)
+// VerifyOpening checks that the passed type, value and blinding factor open the commitment of the token
+func (t *Token) VerifyOpening(ttype string, value, blindingFactor *bn256.Zr, pp *crypto.PublicParams) error {
+	if t.Data == nil {
+		return errors.Errorf("token has no commitment")
+	}
+	if value == nil || blindingFactor == nil {
+		return errors.Errorf("incomplete opening")
+	}
+	com, err := common.ComputePedersenCommitment([]*bn256.Zr{bn256.HashModOrder([]byte(ttype)), value, blindingFactor}, pp.ZKATPedParams)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to check token data")
+		return errors.Wrapf(err, "failed to check token data")
 	}
-	// check that token matches inf
 	if !com.Equals(t.Data) {
-		return nil, errors.Errorf("output does not math provided opening")
+		return errors.Errorf("output does not math provided opening")
+	}
+	return nil
+}
+
+func (t *Token) GetTokenInTheClear(inf *TokenInformation, pp *crypto.PublicParams) (*token2.Token, error) {
+	// check that token matches inf
+	if err := t.VerifyOpening(inf.Type, inf.Value, inf.BlindingFactor, pp); err != nil {
+		return nil, err
 	}
 	// todo identity mixer opening is missing
 	return &token2.Token{
@@ -111,3 +125,8 @@ type TokenDataWitness struct {
 	Value          *bn256.Zr
 	BlindingFactor *bn256.Zr
 }
+
+// Zeroize wipes the value and the blinding factor of the witness
+func (w *TokenDataWitness) Zeroize() {
+	bn256.Zeroize(w.Value, w.BlindingFactor)
+}
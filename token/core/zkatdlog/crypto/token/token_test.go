@@ -47,4 +47,21 @@ var _ = Describe("Token", func() {
 			})
 		})
 	})
+	Describe("verify opening", func() {
+		When("the opening is correct", func() {
+			It("succeeds", func() {
+				Expect(token.VerifyOpening(inf.Type, inf.Value, inf.BlindingFactor, pp)).To(Succeed())
+			})
+		})
+		When("the opening is altered", func() {
+			It("fails", func() {
+				err := token.VerifyOpening(inf.Type, bn256.NewZrInt(51), inf.BlindingFactor, pp)
+				Expect(err).To(MatchError("output does not math provided opening"))
+				err = token.VerifyOpening("DEF", inf.Value, inf.BlindingFactor, pp)
+				Expect(err).To(MatchError("output does not math provided opening"))
+				err = token.VerifyOpening(inf.Type, inf.Value, nil, pp)
+				Expect(err).To(MatchError("incomplete opening"))
+			})
+		})
+	})
 })
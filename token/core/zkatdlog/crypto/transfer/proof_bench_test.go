@@ -0,0 +1,42 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package transfer_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+)
+
+// BenchmarkTransferProofSize reports the size of a transfer proof in the binary encoding and in the JSON one
+func BenchmarkTransferProofSize(b *testing.B) {
+	pp, err := crypto.Setup(100, 2, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	encodings := map[string]common.ProofEncoding{"json": common.JSONEncoding, "binary": common.BinaryEncoding}
+	for _, outputs := range []int{2, 8} {
+		for name, encoding := range encodings {
+			b.Run(fmt.Sprintf("outputs=%d/encoding=%s", outputs, name), func(b *testing.B) {
+				if err := pp.SetProofEncoding(encoding); err != nil {
+					b.Fatal(err)
+				}
+				sender, values, owners := prepareSenderForBenchmark(b, pp, outputs)
+				var raw []byte
+				for i := 0; i < b.N; i++ {
+					transfer, _, err := sender.GenerateZKTransfer(values, owners)
+					if err != nil {
+						b.Fatal(err)
+					}
+					raw = transfer.Proof
+				}
+				b.ReportMetric(float64(len(raw)), "proof-bytes")
+			})
+		}
+	}
+}
@@ -30,6 +30,9 @@ type Sender struct {
 	InputIDs         []string
 	InputInformation []*token.TokenInformation // contains the opening of the inputs to be spent
 	PublicParams     *crypto.PublicParams
+	// Workers is the maximum number of goroutines used to generate the transfer proof.
+	// If not positive, the number of CPUs is used.
+	Workers int
 }
 
 func NewSender(signers []view.Signer, tokens []*token.Token, ids []string, inf []*token.TokenInformation, pp *crypto.PublicParams) (*Sender, error) {
@@ -48,9 +51,11 @@ func (s *Sender) GenerateZKTransfer(values []uint64, owners [][]byte) (*Transfer
 	in := getTokenData(s.Inputs)
 	intw := make([]*token.TokenDataWitness, len(s.InputInformation))
 	for i := 0; i < len(s.InputInformation); i++ {
-		intw[i] = &token.TokenDataWitness{Value: s.InputInformation[i].Value, Type: s.InputInformation[i].Type, BlindingFactor: s.InputInformation[i].BlindingFactor}
+		intw[i] = &token.TokenDataWitness{Value: bn256.NewZrCopy(s.InputInformation[i].Value), Type: s.InputInformation[i].Type, BlindingFactor: bn256.NewZrCopy(s.InputInformation[i].BlindingFactor)}
 	}
-	prover := NewProver(intw, outtw, in, out, s.PublicParams)
+	prover := NewParallelProver(intw, outtw, in, out, s.PublicParams, s.Workers)
+	// the witnesses are wiped on return, the token information holds copies
+	defer prover.Zeroize()
 	proof, err := prover.Prove()
 	if err != nil {
 		return nil, nil, errors.Wrapf(err, "failed to generate zero-knowledge proof for transfer request")
@@ -64,8 +69,8 @@ func (s *Sender) GenerateZKTransfer(values []uint64, owners [][]byte) (*Transfer
 	for i := 0; i < len(inf); i++ {
 		inf[i] = &token.TokenInformation{
 			Type:           s.InputInformation[0].Type,
-			Value:          outtw[i].Value,
-			BlindingFactor: outtw[i].BlindingFactor,
+			Value:          bn256.NewZrCopy(outtw[i].Value),
+			BlindingFactor: bn256.NewZrCopy(outtw[i].BlindingFactor),
 			Owner:          owners[i],
 		}
 	}
@@ -94,6 +99,10 @@ type TransferAction struct {
 	OutputTokens []*token.Token
 	// ZK Proof
 	Proof []byte
+	// SenderAuditInfos and ReceiverAuditInfos are the audit infos of the owners of the inputs and outputs,
+	// encrypted under the auditor's key, if the public parameters declare one
+	SenderAuditInfos   [][]byte `json:",omitempty"`
+	ReceiverAuditInfos [][]byte `json:",omitempty"`
 }
 
 func NewTransfer(inputs []string, inputCommitments []*bn256.G1, outputs []*bn256.G1, owners [][]byte, proof []byte) (*TransferAction, error) {
@@ -0,0 +1,73 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package transfer_test
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	transfer2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer/mock"
+)
+
+func BenchmarkGenerateZKTransfer(b *testing.B) {
+	pp, err := crypto.Setup(100, 2, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	workerCounts := []int{1}
+	if runtime.NumCPU() > 1 {
+		workerCounts = append(workerCounts, runtime.NumCPU())
+	}
+	for _, outputs := range []int{2, 8, 32} {
+		for _, workers := range workerCounts {
+			b.Run(fmt.Sprintf("outputs=%d/workers=%d", outputs, workers), func(b *testing.B) {
+				sender, values, owners := prepareSenderForBenchmark(b, pp, outputs)
+				sender.Workers = workers
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, _, err := sender.GenerateZKTransfer(values, owners); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// prepareSenderForBenchmark returns a sender spending a single input into the passed number of outputs
+func prepareSenderForBenchmark(b *testing.B, pp *crypto.PublicParams, outputs int) (*transfer2.Sender, []uint64, [][]byte) {
+	rand, err := bn256.GetRand()
+	if err != nil {
+		b.Fatal(err)
+	}
+	values := make([]uint64, outputs)
+	owners := make([][]byte, outputs)
+	for i := 0; i < outputs; i++ {
+		values[i] = 10
+		owners[i] = []byte(fmt.Sprintf("owner-%d", i))
+	}
+	inValue := bn256.NewZrInt(10 * outputs)
+	inBF := bn256.RandModOrder(rand)
+	in := PrepareTokens([]*bn256.Zr{inValue}, []*bn256.Zr{inBF}, "ABC", pp.ZKATPedParams)
+
+	sender, err := transfer2.NewSender(
+		[]view2.Signer{&mock.SigningIdentity{}},
+		[]*token.Token{{Data: in[0], Owner: []byte("alice")}},
+		[]string{"0"},
+		[]*token.TokenInformation{{Type: "ABC", Value: inValue, BlindingFactor: inBF}},
+		pp,
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return sender, values, owners
+}
@@ -14,6 +14,7 @@ import (
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	transfer2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer/mock"
@@ -106,6 +107,40 @@ var _ = Describe("Sender", func() {
 				Expect(err).NotTo(HaveOccurred())
 			})
 		})
+		When("the witnesses are wiped after the proof", func() {
+			It("returns the openings of the outputs and keeps those of the inputs", func() {
+				var err error
+				var inf []*token.TokenInformation
+				transfer, inf, err = sender.GenerateZKTransfer(outvalues, owners)
+				Expect(err).NotTo(HaveOccurred())
+				for i, o := range transfer.OutputTokens {
+					expected, err := common.ComputePedersenCommitment([]*bn256.Zr{bn256.HashModOrder([]byte(inf[i].Type)), inf[i].Value, inf[i].BlindingFactor}, pp.ZKATPedParams)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(o.Data).To(Equal(expected))
+				}
+				for _, in := range sender.InputInformation {
+					Expect(in.Value.Cmp(bn256.NewZrInt(0))).NotTo(Equal(0))
+				}
+			})
+		})
+		When("the proof is generated by multiple workers", func() {
+			BeforeEach(func() {
+				sender.Workers = 4
+			})
+			It("succeeds and the proof verifies", func() {
+				var err error
+				transfer, _, err = sender.GenerateZKTransfer(outvalues, owners)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(transfer).NotTo(BeNil())
+
+				out := make([]*bn256.G1, len(transfer.OutputTokens))
+				for i, o := range transfer.OutputTokens {
+					out[i] = o.Data
+				}
+				err = transfer2.NewVerifier(transfer.InputCommitments, out, pp).Verify(transfer.Proof)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
 		When("when signature fails", func() {
 			BeforeEach(func() {
 				fakeSigningIdentity.SignReturnsOnCall(2, nil, errors.New("banana republic"))
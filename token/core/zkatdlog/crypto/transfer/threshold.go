@@ -0,0 +1,219 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package transfer
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
+	rangeproof "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/range"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/pkg/errors"
+)
+
+// ThresholdProof shows that the total value of a set of output tokens is below a threshold, without revealing it.
+// Total commits to the total value shifted up by the distance between the threshold and the bound of the range
+// proofs, its range proof shows that the total is below the threshold.
+// Type and BlindingFactor prove that the outputs, shifted likewise, and Total commit to the same value.
+type ThresholdProof struct {
+	Total          *bn256.G1
+	Range          []byte
+	Challenge      *bn256.Zr
+	Type           *bn256.Zr
+	BlindingFactor *bn256.Zr
+}
+
+// Serialize returns the binary encoding of the proof, see common.Encoder
+func (p *ThresholdProof) Serialize() ([]byte, error) {
+	e := common.NewEncoder()
+	e.G1(p.Total)
+	e.Bytes(p.Range)
+	e.Zr(p.Challenge)
+	e.Zr(p.Type)
+	e.Zr(p.BlindingFactor)
+	return e.Encoded(), nil
+}
+
+// Deserialize decodes the passed proof, in the binary encoding or in the JSON one
+func (p *ThresholdProof) Deserialize(raw []byte) error {
+	if !common.IsBinary(raw) {
+		return json.Unmarshal(raw, p)
+	}
+	d, err := common.NewDecoder(raw)
+	if err != nil {
+		return err
+	}
+	p.Total = d.G1()
+	p.Range = d.Bytes()
+	p.Challenge = d.Zr()
+	p.Type = d.Zr()
+	p.BlindingFactor = d.Zr()
+	return d.Err()
+}
+
+// ThresholdVerifier verifies that the total value of the passed outputs is below the threshold
+type ThresholdVerifier struct {
+	Outputs   []*bn256.G1
+	Threshold uint64
+	PP        *crypto.PublicParams
+}
+
+// ThresholdProver proves that the total value of the passed outputs is below the threshold
+type ThresholdProver struct {
+	*ThresholdVerifier
+	witness []*token.TokenDataWitness
+	// Rand is the source of the randomness of the proof, the default one if nil, see bn256.NewDRBG
+	Rand bn256.Rand
+}
+
+func NewThresholdVerifier(outputs []*bn256.G1, threshold uint64, pp *crypto.PublicParams) *ThresholdVerifier {
+	return &ThresholdVerifier{Outputs: outputs, Threshold: threshold, PP: pp}
+}
+
+func NewThresholdProver(outputwitness []*token.TokenDataWitness, outputs []*bn256.G1, threshold uint64, pp *crypto.PublicParams) *ThresholdProver {
+	return &ThresholdProver{
+		ThresholdVerifier: NewThresholdVerifier(outputs, threshold, pp),
+		witness:           outputwitness,
+	}
+}
+
+// Zeroize wipes the output witnesses of the prover
+func (p *ThresholdProver) Zeroize() {
+	for _, w := range p.witness {
+		w.Zeroize()
+	}
+}
+
+func (p *ThresholdProver) Prove() ([]byte, error) {
+	if len(p.witness) == 0 || len(p.witness) != len(p.Outputs) {
+		return nil, errors.Errorf("cannot compute threshold proof: [%d] witnesses for [%d] outputs", len(p.witness), len(p.Outputs))
+	}
+	shift, err := p.shift()
+	if err != nil {
+		return nil, err
+	}
+	rand, err := bn256.RandOrDefault(p.Rand)
+	if err != nil {
+		return nil, err
+	}
+	total := big.NewInt(0)
+	typeHash := bn256.NewZrInt(0)
+	bf := bn256.NewZrInt(0)
+	for _, w := range p.witness {
+		total.Add(total, (*big.Int)(w.Value))
+		typeHash = bn256.ModAdd(typeHash, bn256.HashModOrder([]byte(w.Type)), bn256.Order)
+		bf = bn256.ModAdd(bf, w.BlindingFactor, bn256.Order)
+	}
+	if !total.IsUint64() || total.Uint64() >= p.Threshold {
+		return nil, errors.Errorf("cannot compute threshold proof: total value is not below the threshold [%d]", p.Threshold)
+	}
+
+	// Total commits to the shifted total under the type of the first output
+	tw := &token.TokenDataWitness{
+		Type:           p.witness[0].Type,
+		Value:          bn256.NewZrInt(0).SetUint64(total.Uint64() + shift),
+		BlindingFactor: bn256.RandModOrder(rand),
+	}
+	proof := &ThresholdProof{}
+	proof.Total, err = common.ComputePedersenCommitment([]*bn256.Zr{bn256.HashModOrder([]byte(tw.Type)), tw.Value, tw.BlindingFactor}, p.PP.ZKATPedParams)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed computing total commitment")
+	}
+	rp := rangeproof.NewProver([]*token.TokenDataWitness{tw}, []*bn256.G1{proof.Total}, p.PP.RangeProofParams.SignedValues, p.PP.RangeProofParams.Exponent, p.PP.ZKATPedParams, p.PP.RangeProofParams.SignPK, p.PP.P, p.PP.RangeProofParams.Q)
+	// the total and its blinding factor are secret too
+	defer rp.Zeroize()
+	rp.Hash = p.PP.ChallengeHash
+	rp.Encoding = p.PP.ProofEncoding
+	if table, err := rangeproof.GetDigitTable(p.PP); err == nil {
+		rp.Table = table
+	}
+	if rp.Rand, err = bn256.Fork(p.Rand); err != nil {
+		return nil, err
+	}
+	proof.Range, err = rp.Prove()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to generate range proof of the total")
+	}
+
+	// the difference between the shifted outputs and Total commits to zero value
+	typeHash = bn256.ModSub(typeHash, bn256.HashModOrder([]byte(tw.Type)), bn256.Order)
+	bf = bn256.ModSub(bf, tw.BlindingFactor, bn256.Order)
+	randomness := []*bn256.Zr{bn256.RandModOrder(rand), bn256.RandModOrder(rand)}
+	commitment, err := common.ComputePedersenCommitment(randomness, p.differenceParams())
+	if err != nil {
+		return nil, err
+	}
+	proof.Challenge = p.challenge(proof.Total, p.difference(proof.Total, shift), commitment)
+	sp := &common.SchnorrProver{Challenge: proof.Challenge, Randomness: randomness, Witness: []*bn256.Zr{typeHash, bf}}
+	responses, err := sp.Prove()
+	if err != nil {
+		return nil, err
+	}
+	proof.Type, proof.BlindingFactor = responses[0], responses[1]
+	return proof.Serialize()
+}
+
+func (v *ThresholdVerifier) Verify(raw []byte) error {
+	proof := &ThresholdProof{}
+	if err := proof.Deserialize(raw); err != nil {
+		return errors.Wrap(err, "invalid threshold proof: cannot parse proof")
+	}
+	if proof.Total == nil || proof.Challenge == nil || proof.Type == nil || proof.BlindingFactor == nil {
+		return errors.New("invalid threshold proof: missing elements")
+	}
+	if len(v.Outputs) == 0 {
+		return errors.New("invalid threshold proof: no outputs")
+	}
+	shift, err := v.shift()
+	if err != nil {
+		return err
+	}
+	rv := rangeproof.NewVerifier([]*bn256.G1{proof.Total}, uint64(len(v.PP.RangeProofParams.SignedValues)), v.PP.RangeProofParams.Exponent, v.PP.ZKATPedParams, v.PP.RangeProofParams.SignPK, v.PP.P, v.PP.RangeProofParams.Q)
+	rv.Hash = v.PP.ChallengeHash
+	if err := rv.Verify(proof.Range); err != nil {
+		return errors.Wrap(err, "invalid threshold proof: total out of range")
+	}
+
+	sv := &common.SchnorrVerifier{PedParams: v.differenceParams()}
+	difference := v.difference(proof.Total, shift)
+	commitment := sv.RecomputeCommitment(&common.SchnorrProof{Statement: difference, Proof: []*bn256.Zr{proof.Type, proof.BlindingFactor}, Challenge: proof.Challenge})
+	if !bn256.ConstantTimeEqual(v.challenge(proof.Total, difference, commitment), proof.Challenge) {
+		return errors.New("invalid threshold proof: total does not match the outputs")
+	}
+	return nil
+}
+
+// shift returns the distance between the threshold and the bound of the range proofs
+func (v *ThresholdVerifier) shift() (uint64, error) {
+	bound := v.PP.RangeBound()
+	if v.Threshold == 0 || v.Threshold > bound {
+		return 0, errors.Errorf("invalid threshold [%d], expected in (0, %d]", v.Threshold, bound)
+	}
+	return bound - v.Threshold, nil
+}
+
+// difference returns the sum of the outputs, shifted up by the passed value, minus the passed total
+func (v *ThresholdVerifier) difference(total *bn256.G1, shift uint64) *bn256.G1 {
+	d := v.PP.ZKATPedParams[1].Mul(bn256.NewZrInt(0).SetUint64(shift))
+	for _, output := range v.Outputs {
+		d.Add(output)
+	}
+	d.Sub(total)
+	return d
+}
+
+// differenceParams returns the generators of the type and of the blinding factor
+func (v *ThresholdVerifier) differenceParams() []*bn256.G1 {
+	return []*bn256.G1{v.PP.ZKATPedParams[0], v.PP.ZKATPedParams[2]}
+}
+
+func (v *ThresholdVerifier) challenge(total, difference, commitment *bn256.G1) *bn256.Zr {
+	raw := common.GetG1Array(v.PP.ZKATPedParams, v.Outputs, []*bn256.G1{total, difference, commitment}).Bytes()
+	return v.PP.ChallengeHash.HashModOrder(raw)
+}
@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package transfer_test
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Threshold proof", func() {
+	var pp *crypto.PublicParams
+	BeforeEach(func() {
+		var err error
+		pp, err = crypto.Setup(100, 2, nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("the total of the outputs is below the threshold", func() {
+		It("verifies against the same outputs and threshold only", func() {
+			outputs, tw, err := token.GetTokensWithWitness([]uint64{40, 59}, "ABC", pp.ZKATPedParams)
+			Expect(err).NotTo(HaveOccurred())
+			proof, err := transfer.NewThresholdProver(tw, outputs, 100, pp).Prove()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transfer.NewThresholdVerifier(outputs, 100, pp).Verify(proof)).To(Succeed())
+
+			Expect(transfer.NewThresholdVerifier(outputs, 99, pp).Verify(proof)).NotTo(Succeed())
+			Expect(transfer.NewThresholdVerifier(outputs[:1], 100, pp).Verify(proof)).NotTo(Succeed())
+			others, _, err := token.GetTokensWithWitness([]uint64{40, 59}, "ABC", pp.ZKATPedParams)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transfer.NewThresholdVerifier(others, 100, pp).Verify(proof)).NotTo(Succeed())
+		})
+		It("wipes the witnesses once zeroized", func() {
+			outputs, tw, err := token.GetTokensWithWitness([]uint64{40, 59}, "ABC", pp.ZKATPedParams)
+			Expect(err).NotTo(HaveOccurred())
+			prover := transfer.NewThresholdProver(tw, outputs, 100, pp)
+			_, err = prover.Prove()
+			Expect(err).NotTo(HaveOccurred())
+			prover.Zeroize()
+			for _, w := range tw {
+				Expect(w.Value.Cmp(bn256.NewZrInt(0))).To(Equal(0))
+				Expect(w.BlindingFactor.Cmp(bn256.NewZrInt(0))).To(Equal(0))
+			}
+		})
+		It("covers outputs of different types", func() {
+			abc, abcw, err := token.GetTokensWithWitness([]uint64{10}, "ABC", pp.ZKATPedParams)
+			Expect(err).NotTo(HaveOccurred())
+			xyz, xyzw, err := token.GetTokensWithWitness([]uint64{20}, "XYZ", pp.ZKATPedParams)
+			Expect(err).NotTo(HaveOccurred())
+			outputs := append(abc, xyz...)
+			proof, err := transfer.NewThresholdProver(append(abcw, xyzw...), outputs, 31, pp).Prove()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transfer.NewThresholdVerifier(outputs, 31, pp).Verify(proof)).To(Succeed())
+		})
+	})
+
+	Context("the total of the outputs is not below the threshold", func() {
+		It("cannot be proven", func() {
+			outputs, tw, err := token.GetTokensWithWitness([]uint64{40, 60}, "ABC", pp.ZKATPedParams)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = transfer.NewThresholdProver(tw, outputs, 100, pp).Prove()
+			Expect(err).To(MatchError("cannot compute threshold proof: total value is not below the threshold [100]"))
+		})
+	})
+
+	Context("the threshold exceeds the bound of the range proofs", func() {
+		It("is rejected", func() {
+			outputs, tw, err := token.GetTokensWithWitness([]uint64{1}, "ABC", pp.ZKATPedParams)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = transfer.NewThresholdProver(tw, outputs, 10001, pp).Prove()
+			Expect(err).To(MatchError("invalid threshold [10001], expected in (0, 10000]"))
+			Expect(pp.SetAuditThreshold(10001)).To(MatchError("audit threshold [10001] exceeds the bound of the range proofs [10000]"))
+			Expect(pp.SetAuditThreshold(10000)).To(Succeed())
+		})
+	})
+})
@@ -6,7 +6,9 @@ SPDX-License-Identifier: Apache-2.0
 package transfer
 
 import (
+	"context"
 	"encoding/json"
+	"sync"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
@@ -32,66 +34,155 @@ type Verifier struct {
 type Prover struct {
 	WellFormedness   common.Prover
 	RangeCorrectness common.Prover
+	// Encoding is the encoding of the proof, see common.ProofEncoding
+	Encoding common.ProofEncoding
 }
 
 func NewProver(inputwitness, outputwitness []*token.TokenDataWitness, inputs, outputs []*bn256.G1, pp *crypto.PublicParams) *Prover {
+	return NewParallelProver(inputwitness, outputwitness, inputs, outputs, pp, 0)
+}
+
+// NewParallelProver returns a prover that uses at most the passed number of goroutines to generate the range proof.
+// If workers is not positive, the number of CPUs is used.
+func NewParallelProver(inputwitness, outputwitness []*token.TokenDataWitness, inputs, outputs []*bn256.G1, pp *crypto.PublicParams, workers int) *Prover {
 
-	p := &Prover{}
+	p := &Prover{Encoding: pp.ProofEncoding}
 
-	p.RangeCorrectness = rangeproof.NewProver(outputwitness, outputs, pp.RangeProofParams.SignedValues, pp.RangeProofParams.Exponent, pp.ZKATPedParams, pp.RangeProofParams.SignPK, pp.P, pp.RangeProofParams.Q)
+	rp := rangeproof.NewProver(outputwitness, outputs, pp.RangeProofParams.SignedValues, pp.RangeProofParams.Exponent, pp.ZKATPedParams, pp.RangeProofParams.SignPK, pp.P, pp.RangeProofParams.Q)
+	rp.Workers = workers
+	rp.Hash = pp.ChallengeHash
+	rp.Encoding = pp.ProofEncoding
+	if table, err := rangeproof.GetDigitTable(pp); err == nil {
+		rp.Table = table
+	}
+	p.RangeCorrectness = rp
 	wfw := NewWellFormednessWitness(inputwitness, outputwitness)
-	p.WellFormedness = NewWellFormednessProver(wfw, pp.ZKATPedParams, inputs, outputs)
+	wfp := NewWellFormednessProver(wfw, pp.ZKATPedParams, inputs, outputs)
+	wfp.Hash = pp.ChallengeHash
+	wfp.Encoding = pp.ProofEncoding
+	p.WellFormedness = wfp
 	return p
 }
 
 func NewVerifier(inputs, outputs []*bn256.G1, pp *crypto.PublicParams) *Verifier {
 	v := &Verifier{}
-	v.RangeCorrectness = rangeproof.NewVerifier(outputs, uint64(len(pp.RangeProofParams.SignedValues)), pp.RangeProofParams.Exponent, pp.ZKATPedParams, pp.RangeProofParams.SignPK, pp.P, pp.RangeProofParams.Q)
-	v.WellFormedness = NewWellFormednessVerifier(pp.ZKATPedParams, inputs, outputs)
+	rv := rangeproof.NewVerifier(outputs, uint64(len(pp.RangeProofParams.SignedValues)), pp.RangeProofParams.Exponent, pp.ZKATPedParams, pp.RangeProofParams.SignPK, pp.P, pp.RangeProofParams.Q)
+	rv.Hash = pp.ChallengeHash
+	v.RangeCorrectness = rv
+	wfv := NewWellFormednessVerifier(pp.ZKATPedParams, inputs, outputs)
+	wfv.Hash = pp.ChallengeHash
+	v.WellFormedness = wfv
 
 	return v
 }
 
+// Serialize returns the JSON encoding of the proof
 func (p *Proof) Serialize() ([]byte, error) {
 	return json.Marshal(p)
 }
 
-func (p *Proof) Deserialize(bytes []byte) error {
-	return json.Unmarshal(bytes, p)
+// SerializeBinary returns the binary encoding of the proof, see common.Encoder
+func (p *Proof) SerializeBinary() ([]byte, error) {
+	e := common.NewEncoder()
+	e.Bytes(p.WellFormedness)
+	e.Bytes(p.RangeCorrectness)
+	return e.Encoded(), nil
 }
 
-func (p *Prover) Prove() ([]byte, error) {
-	wf, err := p.WellFormedness.Prove()
+// Deserialize decodes the passed proof, in the binary encoding or in the JSON one
+func (p *Proof) Deserialize(raw []byte) error {
+	if !common.IsBinary(raw) {
+		return json.Unmarshal(raw, p)
+	}
+	d, err := common.NewDecoder(raw)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to generate transfer proof")
+		return err
 	}
+	p.WellFormedness = d.Bytes()
+	p.RangeCorrectness = d.Bytes()
+	return d.Err()
+}
 
-	// add range proof
-	rc, err := p.RangeCorrectness.Prove()
+// WithRand makes the prover draw the randomness of the proofs from the passed source, see bn256.NewDRBG.
+// The proofs are generated concurrently, each gets its own fork of the source.
+func (p *Prover) WithRand(rng bn256.Rand) (*Prover, error) {
+	rc, ok := p.RangeCorrectness.(*rangeproof.Prover)
+	if !ok {
+		return nil, errors.Errorf("range prover does not support randomness injection")
+	}
+	wf, ok := p.WellFormedness.(*WellFormednessProver)
+	if !ok {
+		return nil, errors.Errorf("well-formedness prover does not support randomness injection")
+	}
+	var err error
+	if rc.Rand, err = bn256.Fork(rng); err != nil {
+		return nil, err
+	}
+	wf.Rand, err = bn256.Fork(rng)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to generate range proof for transfer")
+		return nil, err
+	}
+	return p, nil
+}
+
+// Zeroize wipes the witnesses of the prover and the randomness of the last proof.
+// The witnesses are shared with the caller, call it once done with them.
+func (p *Prover) Zeroize() {
+	common.Zeroize(p.WellFormedness)
+	common.Zeroize(p.RangeCorrectness)
+}
+
+func (p *Prover) Prove() ([]byte, error) {
+	// well-formedness and range proofs are independent, generate them concurrently
+	var wf, rc []byte
+	var wfErr, rcErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		wfErr = common.Profile(context.Background(), "transfer-prove", func(context.Context) error {
+			var err error
+			wf, err = p.WellFormedness.Prove()
+			return err
+		})
+	}()
+	// add range proof
+	rc, rcErr = p.RangeCorrectness.Prove()
+	wg.Wait()
+	if wfErr != nil {
+		return nil, errors.Wrapf(wfErr, "failed to generate transfer proof")
+	}
+	if rcErr != nil {
+		return nil, errors.Wrapf(rcErr, "failed to generate range proof for transfer")
 	}
 
 	proof := &Proof{
 		WellFormedness:   wf,
 		RangeCorrectness: rc,
 	}
-	return proof.Serialize()
+	return p.Encoding.Encode(proof)
 }
 
 func (v *Verifier) Verify(proof []byte) error {
+	return v.VerifyWithContext(context.Background(), proof)
+}
+
+// VerifyWithContext verifies the passed transfer proof, it aborts with the error of the passed context once done
+func (v *Verifier) VerifyWithContext(ctx context.Context, proof []byte) error {
 	tp := *&Proof{}
 	err := tp.Deserialize(proof)
 	if err != nil {
 		return errors.Wrapf(err, "invalid transfer proof: cannot parse proof")
 	}
 	// verifiy well-formedness of inputs and outputs
-	err = v.WellFormedness.Verify(tp.WellFormedness)
+	err = common.Profile(ctx, "transfer-verify", func(context.Context) error {
+		return v.WellFormedness.Verify(tp.WellFormedness)
+	})
 	if err != nil {
 		return err
 	}
 	// verify range proof
-	return v.RangeCorrectness.Verify(tp.RangeCorrectness)
+	return common.VerifyWithContext(ctx, v.RangeCorrectness, tp.RangeCorrectness)
 }
 
 func (w *WellFormednessWitness) GetInValues() []*bn256.Zr {
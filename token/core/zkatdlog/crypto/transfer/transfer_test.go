@@ -8,6 +8,7 @@ package transfer_test
 import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
 	. "github.com/onsi/ginkgo"
@@ -32,6 +33,71 @@ var _ = Describe("Transfer", func() {
 				Expect(err).NotTo(HaveOccurred())
 			})
 		})
+		Context("the randomness is injected", func() {
+			It("generates the same proof from the same seed", func() {
+				prove := func(seed string) []byte {
+					_, err := prover.WithRand(bn256.NewDRBG([]byte(seed)))
+					Expect(err).NotTo(HaveOccurred())
+					proof, err := prover.Prove()
+					Expect(err).NotTo(HaveOccurred())
+					return proof
+				}
+				proof := prove("seed")
+				Expect(verifier.Verify(proof)).To(Succeed())
+				Expect(prove("seed")).To(Equal(proof))
+				Expect(prove("another seed")).NotTo(Equal(proof))
+			})
+		})
+		Context("the public parameters select the proof encoding", func() {
+			It("uses the JSON encoding of the previous releases unless the binary one is selected", func() {
+				pp, err := crypto.Setup(100, 2, nil)
+				Expect(err).NotTo(HaveOccurred())
+				prover, verifier, _, _ := prepareZKTransferWithPublicParams(pp)
+				jsonProof, err := prover.Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(common.IsBinary(jsonProof)).To(BeFalse())
+				Expect(jsonProof[0]).To(Equal(byte('{')))
+				Expect(verifier.Verify(jsonProof)).To(Succeed())
+
+				Expect(pp.SetProofEncoding(common.BinaryEncoding)).To(Succeed())
+				prover, verifier, _, _ = prepareZKTransferWithPublicParams(pp)
+				proof, err := prover.Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(common.IsBinary(proof)).To(BeTrue())
+				Expect(len(proof)).To(BeNumerically("<", len(jsonProof)))
+				Expect(verifier.Verify(proof)).To(Succeed())
+
+				Expect(pp.SetProofEncoding(common.ProofEncoding(7))).To(MatchError("unknown proof encoding [7]"))
+			})
+		})
+		Context("the public parameters select the challenge hash", func() {
+			It("verifies only with the same challenge hash", func() {
+				pp, err := crypto.Setup(100, 2, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pp.SetChallengeHash(common.SHA3256)).To(Succeed())
+				prover, verifier, in, out := prepareZKTransferWithPublicParams(pp)
+
+				proof, err := prover.Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(verifier.Verify(proof)).To(Succeed())
+
+				Expect(pp.SetChallengeHash(common.SHA256)).To(Succeed())
+				Expect(transfer.NewVerifier(in, out, pp).Verify(proof)).NotTo(Succeed())
+				Expect(pp.SetChallengeHash("MD5")).To(MatchError("unknown challenge hash [MD5]"))
+			})
+		})
+		Context("the prover is zeroized", func() {
+			It("cannot generate a valid proof anymore", func() {
+				proof, err := prover.Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(verifier.Verify(proof)).To(Succeed())
+
+				prover.Zeroize()
+				proof, err = prover.Prove()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(verifier.Verify(proof)).NotTo(Succeed())
+			})
+		})
 		Context("Output Values > Input Values", func() {
 			BeforeEach(func() {
 				prover, verifier = prepareZKTransferWithWrongSum()
@@ -64,6 +130,11 @@ func prepareZKTransfer() (*transfer.Prover, *transfer.Verifier) {
 	pp, err := crypto.Setup(100, 2, nil)
 	Expect(err).NotTo(HaveOccurred())
 
+	prover, verifier, _, _ := prepareZKTransferWithPublicParams(pp)
+	return prover, verifier
+}
+
+func prepareZKTransferWithPublicParams(pp *crypto.PublicParams) (*transfer.Prover, *transfer.Verifier, []*bn256.G1, []*bn256.G1) {
 	wfw, in, out := prepareInputsForZKTransfer(pp)
 
 	inBF := wfw.GetInBlindingFators()
@@ -85,7 +156,7 @@ func prepareZKTransfer() (*transfer.Prover, *transfer.Verifier) {
 	prover := transfer.NewProver(intw, outtw, in, out, pp)
 	verifier := transfer.NewVerifier(in, out, pp)
 
-	return prover, verifier
+	return prover, verifier, in, out
 }
 
 func prepareZKTransferWithWrongSum() (*transfer.Prover, *transfer.Verifier) {
@@ -27,12 +27,41 @@ type WellFormedness struct {
 	Challenge             *bn256.Zr
 }
 
+// Serialize returns the JSON encoding of the proof
 func (wf *WellFormedness) Serialize() ([]byte, error) {
 	return json.Marshal(wf)
 }
 
-func (wf *WellFormedness) Deserialize(bytes []byte) error {
-	return json.Unmarshal(bytes, wf)
+// SerializeBinary returns the binary encoding of the proof, see common.Encoder
+func (wf *WellFormedness) SerializeBinary() ([]byte, error) {
+	e := crypto.NewEncoder()
+	e.Zrs(wf.InputBlindingFactors)
+	e.Zrs(wf.OutputBlindingFactors)
+	e.Zrs(wf.InputValues)
+	e.Zrs(wf.OutputValues)
+	e.Zr(wf.Type)
+	e.Zr(wf.Sum)
+	e.Zr(wf.Challenge)
+	return e.Encoded(), nil
+}
+
+// Deserialize decodes the passed proof, in the binary encoding or in the JSON one
+func (wf *WellFormedness) Deserialize(raw []byte) error {
+	if !crypto.IsBinary(raw) {
+		return json.Unmarshal(raw, wf)
+	}
+	d, err := crypto.NewDecoder(raw)
+	if err != nil {
+		return err
+	}
+	wf.InputBlindingFactors = d.Zrs()
+	wf.OutputBlindingFactors = d.Zrs()
+	wf.InputValues = d.Zrs()
+	wf.OutputValues = d.Zrs()
+	wf.Type = d.Zr()
+	wf.Sum = d.Zr()
+	wf.Challenge = d.Zr()
+	return d.Err()
 }
 
 // inputs and outputs witness for zkat proof
@@ -60,12 +89,25 @@ func NewWellFormednessWitness(in, out []*token.TokenDataWitness) *WellFormedness
 	return &WellFormednessWitness{inValues: inValues, outValues: outValues, Type: in[0].Type, inBlindingFactors: inBF, outBlindingFactors: outBF}
 }
 
+// Zeroize wipes the values and the blinding factors of the witness,
+// they are shared with the token witnesses the witness has been created from
+func (w *WellFormednessWitness) Zeroize() {
+	bn256.Zeroize(w.inValues...)
+	bn256.Zeroize(w.outValues...)
+	bn256.Zeroize(w.inBlindingFactors...)
+	bn256.Zeroize(w.outBlindingFactors...)
+}
+
 // Prover for input output correctness
 type WellFormednessProver struct {
 	*WellFormednessVerifier
 	witness     *WellFormednessWitness
 	randomness  *WellFormednessRandomness
 	Commitments *WellFormednessCommitments
+	// Rand is the source of the randomness of the proof, the default one if nil, see bn256.NewDRBG
+	Rand bn256.Rand
+	// Encoding is the encoding of the proof
+	Encoding crypto.ProofEncoding
 }
 
 func NewWellFormednessProver(witness *WellFormednessWitness, pp []*bn256.G1, inputs []*bn256.G1, outputs []*bn256.G1) *WellFormednessProver {
@@ -94,6 +136,23 @@ type WellFormednessRandomness struct {
 	sum       *bn256.Zr
 }
 
+func (r *WellFormednessRandomness) zeroize() {
+	if r == nil {
+		return
+	}
+	bn256.Zeroize(r.inValues...)
+	bn256.Zeroize(r.inBF...)
+	bn256.Zeroize(r.outValues...)
+	bn256.Zeroize(r.outBF...)
+	bn256.Zeroize(r.Type, r.sum)
+}
+
+// Zeroize wipes the witness of the prover and the randomness of the last proof
+func (p *WellFormednessProver) Zeroize() {
+	p.witness.Zeroize()
+	p.randomness.zeroize()
+}
+
 // Commitments to the randomness in the proof
 type WellFormednessCommitments struct {
 	Inputs    []*bn256.G1
@@ -107,18 +166,21 @@ func (p *WellFormednessProver) Prove() ([]byte, error) {
 	if len(p.witness.inValues) != len(p.Inputs) || len(p.witness.inBlindingFactors) != len(p.Inputs) || len(p.witness.outValues) != len(p.Outputs) || len(p.witness.outBlindingFactors) != len(p.Outputs) {
 		return nil, errors.Errorf("cannot compute transfer proof: malformed witness")
 	}
+	if bn256.Hardened {
+		defer func() { p.randomness.zeroize() }()
+	}
 	err := p.computeCommitments()
 	if err != nil {
 		return nil, err
 	}
 
-	chal := crypto.ComputeChallenge(crypto.GetG1Array(p.Commitments.Inputs, []*bn256.G1{p.Commitments.InputSum}, p.Commitments.Outputs, []*bn256.G1{p.Commitments.OutputSum},
+	chal := p.ComputeChallenge(crypto.GetG1Array(p.Commitments.Inputs, []*bn256.G1{p.Commitments.InputSum}, p.Commitments.Outputs, []*bn256.G1{p.Commitments.OutputSum},
 		p.Inputs, p.Outputs))
 	iop, err := p.computeProof(p.randomness, chal)
 	if err != nil {
 		return nil, err
 	}
-	return iop.Serialize()
+	return p.Encoding.Encode(iop)
 }
 
 // Verify returns an error when zktp is not a valid transfer proof
@@ -139,8 +201,8 @@ func (v *WellFormednessVerifier) Verify(p []byte) error {
 	}
 	outCommitments := v.RecomputeCommitments(zkps, iop.Challenge)
 
-	chal := crypto.ComputeChallenge(crypto.GetG1Array(inCommitments, outCommitments, v.Inputs, v.Outputs))
-	if chal.Cmp(iop.Challenge) != 0 {
+	chal := v.ComputeChallenge(crypto.GetG1Array(inCommitments, outCommitments, v.Inputs, v.Outputs))
+	if !bn256.ConstantTimeEqual(chal, iop.Challenge) {
 		return errors.Errorf("invalid zero-knowledge transfer")
 	}
 	return nil
@@ -237,7 +299,7 @@ func (p *WellFormednessProver) computeCommitments() error {
 		return errors.Errorf("proof generation failed: invalid public parameters")
 	}
 
-	rand, err := bn256.GetRand()
+	rand, err := bn256.RandOrDefault(p.Rand)
 	if err != nil {
 		return errors.Errorf("proof generation failed: failed to get random generator")
 	}
@@ -0,0 +1,177 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package validator
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/csp"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/csp/idemix/bridge"
+	idemixcrypto "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/csp/idemix/crypto"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/csp/idemix/handlers"
+	idemix2 "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/idemix"
+	api2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/api"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	m "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+)
+
+type verifierDeserializer interface {
+	DeserializeVerifier(raw []byte) (api2.Verifier, error)
+}
+
+// ownerDeserializer deserializes the owners of the tokens, whose credentials may have been issued under any of the
+// idemix issuer public keys accepted at the current epoch, see crypto.PublicParams.AcceptedIdemixPKs
+type ownerDeserializer struct {
+	deserializers []verifierDeserializer
+}
+
+func newOwnerDeserializer(pp *crypto.PublicParams) (*ownerDeserializer, error) {
+	d := &ownerDeserializer{}
+	for _, pk := range pp.AcceptedIdemixPKs() {
+		des, err := idemix2.NewDeserializer(pk)
+		if err != nil {
+			return nil, err
+		}
+		d.deserializers = append(d.deserializers, des)
+	}
+	if len(d.deserializers) == 0 {
+		return nil, errors.Errorf("no idemix issuer public key accepted at epoch [%d]", pp.IdemixEpoch)
+	}
+	return d, nil
+}
+
+// DeserializeVerifier returns a verifier accepting the signatures of the passed owner under any accepted key
+func (d *ownerDeserializer) DeserializeVerifier(raw []byte) (api2.Verifier, error) {
+	if len(d.deserializers) == 1 {
+		return d.deserializers[0].DeserializeVerifier(raw)
+	}
+	v := &ownerVerifier{}
+	var err error
+	for _, des := range d.deserializers {
+		verifier, err2 := des.DeserializeVerifier(raw)
+		if err2 != nil {
+			err = err2
+			continue
+		}
+		v.verifiers = append(v.verifiers, verifier)
+	}
+	if len(v.verifiers) == 0 {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ownerVerifier accepts a signature valid under any of the accepted idemix issuer public keys
+type ownerVerifier struct {
+	verifiers []api2.Verifier
+}
+
+func (v *ownerVerifier) Verify(message, sigma []byte) error {
+	var err error
+	for _, verifier := range v.verifiers {
+		if err = verifier.Verify(message, sigma); err == nil {
+			return nil
+		}
+	}
+	return errors.WithMessagef(err, "signature not valid under any accepted idemix issuer public key")
+}
+
+// rhIndex is the index of the revocation handle among the attributes of the owner credentials
+const rhIndex = 3
+
+// noRevocationPK stands for the revocation public key, unused by the owner credentials that are not revocable
+var noRevocationPK = &ecdsa.PublicKey{Curve: elliptic.P256()}
+
+// ownerAttributes extracts the organizational unit disclosed by the idemix identities of the token owners.
+// Each identity carries a zero-knowledge proof that its owner holds a credential, issued under an idemix issuer
+// public key, with the disclosed organizational unit and role. The other attributes stay hidden.
+type ownerAttributes struct {
+	ipks []handlers.IssuerPublicKey
+}
+
+func newOwnerAttributes(pp *crypto.PublicParams) (*ownerAttributes, error) {
+	a := &ownerAttributes{}
+	for _, pk := range pp.AcceptedIdemixPKs() {
+		ipk, err := (&bridge.Issuer{}).NewPublicKeyFromBytes(pk, []string{
+			msp.AttributeNameOU,
+			msp.AttributeNameRole,
+			msp.AttributeNameEnrollmentId,
+			msp.AttributeNameRevocationHandle,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed importing idemix issuer public key")
+		}
+		a.ipks = append(a.ipks, ipk)
+	}
+	if len(a.ipks) == 0 {
+		return nil, errors.Errorf("no idemix issuer public key accepted at epoch [%d]", pp.IdemixEpoch)
+	}
+	return a, nil
+}
+
+// OrganizationalUnits returns the organizational unit disclosed by the passed owner identity, once checked the proof
+// that the owner holds a credential with that organizational unit under one of the accepted idemix issuer public keys
+func (a *ownerAttributes) OrganizationalUnits(id view.Identity) ([]string, error) {
+	si := &m.SerializedIdentity{}
+	if err := proto.Unmarshal(id, si); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal to msp.SerializedIdentity{}")
+	}
+	serialized := &m.SerializedIdemixIdentity{}
+	if err := proto.Unmarshal(si.IdBytes, serialized); err != nil {
+		return nil, errors.Wrap(err, "could not deserialize a SerializedIdemixIdentity")
+	}
+	ou := &m.OrganizationUnit{}
+	if err := proto.Unmarshal(serialized.Ou, ou); err != nil {
+		return nil, errors.Wrap(err, "cannot deserialize the OU of the identity")
+	}
+	role := &m.MSPRole{}
+	if err := proto.Unmarshal(serialized.Role, role); err != nil {
+		return nil, errors.Wrap(err, "cannot deserialize the role of the identity")
+	}
+
+	attributes := []csp.IdemixAttribute{
+		{Type: csp.IdemixBytesAttribute, Value: []byte(ou.OrganizationalUnitIdentifier)},
+		{Type: csp.IdemixIntAttribute, Value: idemixRole(role.Role)},
+		{Type: csp.IdemixHiddenAttribute},
+		{Type: csp.IdemixHiddenAttribute},
+	}
+	// the proof is bound to the pseudonym of the owner, the one its signatures are verified against
+	proof := &idemixcrypto.Signature{}
+	if err := proto.Unmarshal(serialized.Proof, proof); err != nil {
+		return nil, errors.Wrap(err, "cannot deserialize the proof of the identity")
+	}
+	if proof.Nym == nil || !bytes.Equal(proof.Nym.X, serialized.NymX) || !bytes.Equal(proof.Nym.Y, serialized.NymY) {
+		return nil, errors.New("the proof of the identity is not bound to its pseudonym")
+	}
+	var err error
+	for _, ipk := range a.ipks {
+		if err = (&bridge.SignatureScheme{}).Verify(ipk, serialized.Proof, nil, attributes, rhIndex, noRevocationPK, 0); err == nil {
+			return []string{ou.OrganizationalUnitIdentifier}, nil
+		}
+	}
+	return nil, errors.WithMessagef(err, "invalid proof of the organizational unit [%s]", ou.OrganizationalUnitIdentifier)
+}
+
+// idemixRole returns the value of the role attribute of the idemix credentials for the passed msp role
+func idemixRole(role m.MSPRole_MSPRoleType) int {
+	switch role {
+	case m.MSPRole_ADMIN:
+		return int(idemix2.ADMIN)
+	case m.MSPRole_CLIENT:
+		return int(idemix2.CLIENT)
+	case m.MSPRole_PEER:
+		return int(idemix2.PEER)
+	default:
+		return int(idemix2.MEMBER)
+	}
+}
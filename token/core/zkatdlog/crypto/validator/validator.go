@@ -6,51 +6,77 @@ SPDX-License-Identifier: Apache-2.0
 package validator
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"sync"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/pkg/errors"
 
-	idemix2 "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/idemix"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/fabric"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/audit"
 	issue2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/anonym"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 var logger = flogging.MustGetLogger("token-sdk.zkatdlog")
 
 type Validator struct {
 	pp *crypto.PublicParams
+
+	// attributes extracts the organizational units of the owners, it is instantiated once, on first use
+	attributesOnce sync.Once
+	attributes     *ownerAttributes
+	attributesErr  error
 }
 
 func New(pp *crypto.PublicParams) *Validator {
 	return &Validator{pp: pp}
 }
 
-func (v *Validator) VerifyTokenRequestFromRaw(getState api.GetStateFnc, binding string, raw []byte) ([]interface{}, error) {
+func (v *Validator) VerifyTokenRequestFromRaw(getState api.GetStateFnc, binding string, raw []byte, opts ...api.ValidationOption) ([]interface{}, error) {
 	if len(raw) == 0 {
 		return nil, errors.New("empty token request")
 	}
+	validationOpts, err := api.CompileValidationOptions(opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed compiling validation options [%s]", binding)
+	}
+	raw, err = validationOpts.DecompressRequest(raw, &api.ValidationReport{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed decompressing token request [%s]", binding)
+	}
+	if err := validationOpts.CheckRequestSize(raw, &api.ValidationReport{}); err != nil {
+		return nil, errors.Wrapf(err, "token request exceeds limits [%s]", binding)
+	}
 	tr := &api.TokenRequest{}
-	err := json.Unmarshal(raw, tr)
+	err = json.Unmarshal(raw, tr)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to unmarshal token request")
 	}
+	if tr.Driver == fabtoken.PublicParameters {
+		// a request of the replaced driver, it is validated, signatures included, by the replaced driver
+		source, err := v.checkSourceRequest(validationOpts, &api.ValidationReport{}, binding)
+		if err != nil {
+			return nil, err
+		}
+		return source.VerifyTokenRequestFromRaw(getState, binding, raw, opts...)
+	}
 
 	// Prepare message expected to be signed
-	// TODO: encapsulate this somewhere
-	req := &api.TokenRequest{}
-	req.Transfers = tr.Transfers
-	req.Issues = tr.Issues
-	bytes, err := json.Marshal(req)
+	bytes, err := tr.MarshalToSign()
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to marshal signed token request"+err.Error())
 	}
@@ -58,10 +84,13 @@ func (v *Validator) VerifyTokenRequestFromRaw(getState api.GetStateFnc, binding
 	logger.Debugf("cc tx-id [%s][%s]", hash.Hashable(bytes).String(), binding)
 	signed := append(bytes, []byte(binding)...)
 	var signatures [][]byte
-	if len(v.pp.Auditor) != 0 {
+	if v.pp.AuditorIdentity() != nil && len(tr.ThresholdProof) == 0 {
 		signatures = append(signatures, tr.AuditorSignature)
 		signatures = append(signatures, tr.Signatures...)
 	} else {
+		if len(tr.AuditorSignature) != 0 {
+			return nil, errors.New("unexpected auditor signature, no auditor is set")
+		}
 		signatures = tr.Signatures
 	}
 
@@ -70,29 +99,75 @@ func (v *Validator) VerifyTokenRequestFromRaw(getState api.GetStateFnc, binding
 		message:    signed,
 		signatures: signatures,
 	}
-	return v.VerifyTokenRequest(backend, backend, binding, tr)
+	return v.VerifyTokenRequest(backend, backend, binding, tr, opts...)
 }
 
-func (v *Validator) VerifyTokenRequest(ledger api.Ledger, signatureProvider api.SignatureProvider, binding string, tr *api.TokenRequest) ([]interface{}, error) {
-	if err := v.verifyAuditorSignature(signatureProvider); err != nil {
-		return nil, errors.Wrapf(err, "failed to verifier auditor's signature [%s]", binding)
+func (v *Validator) VerifyTokenRequest(ledger api.Ledger, signatureProvider api.SignatureProvider, binding string, tr *api.TokenRequest, opts ...api.ValidationOption) ([]interface{}, error) {
+	validationOpts, err := api.CompileValidationOptions(opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed compiling validation options [%s]", binding)
+	}
+	if err := validationOpts.Context.Err(); err != nil {
+		return nil, errors.Wrapf(err, "verification aborted [%s]", binding)
 	}
-	ia, err := v.unmarshalIssueActions(tr.Issues)
+	report := &api.ValidationReport{}
+	// limits and format are checked before running any cryptographic check
+	if err := validationOpts.CheckRequest(tr, report); err != nil {
+		return nil, errors.Wrapf(err, "token request exceeds limits [%s]", binding)
+	}
+	switch tr.Driver {
+	case "", crypto.DLogPublicParameters:
+	case fabtoken.PublicParameters:
+		source, err := v.checkSourceRequest(validationOpts, report, binding)
+		if err != nil {
+			return nil, err
+		}
+		return source.VerifyTokenRequest(ledger, signatureProvider, binding, tr, opts...)
+	default:
+		return nil, report.Failed(api.RequestActionType, 0, api.FormatCheck, errors.Errorf("token request of driver [%s], expected [%s] [%s]", tr.Driver, crypto.DLogPublicParameters, binding))
+	}
+	ia, err := v.unmarshalIssueActions(tr.Issues, validationOpts, report)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to retrieve issue actions [%s]", binding)
 	}
-	ta, err := v.unmarshalTransferActions(tr.Transfers)
+	ta, err := v.unmarshalTransferActions(tr.Transfers, validationOpts, report)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to retrieve transfer actions [%s]", binding)
 	}
-	err = v.verifyIssues(ia, signatureProvider)
+	ra, err := api.UnmarshalRebindings(tr.Rebindings, report)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to retrieve rebinding actions [%s]", binding)
+	}
+	if err := v.verifyAuditorSignature(signatureProvider, tr, ta, report); err != nil {
+		return nil, errors.Wrapf(err, "failed to verifier auditor's signature [%s]", binding)
+	}
+	err = v.verifyIssues(ledger, ia, signatureProvider, validationOpts, report)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to verify issuers' signatures [%s]", binding)
 	}
-	err = v.verifyTransfers(ledger, ta, signatureProvider)
+	err = v.verifyTransfers(ledger, ta, signatureProvider, validationOpts, report)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to verify senders' signatures [%s]", binding)
 	}
+	ma, err := v.verifyMigrations(ledger, tr.Migrations, signatureProvider, report)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to verify migrations [%s]", binding)
+	}
+	if err := api.VerifyRebindings(ledger, ra, v.pp.AuditorIdentity() != nil, v.pp, v.tokenOrganizations, v.matchRebinding, report); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify rebindings [%s]", binding)
+	}
+	if err := api.VerifyBurnReceipts(ta, tr.BurnReceipts, v.matchBurnReceipt, report); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify burn receipts [%s]", binding)
+	}
+	if err := api.VerifyRedeemIssuers(ta, tr.BurnReceipts, v.pp.RedeemRequiresIssuer(), signatureProvider, v.issuerVerifier, report); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify redeem issuers' signatures [%s]", binding)
+	}
+	if err := api.VerifySwapTerms(ta, tr.SwapTerms, v.matchOutput, report); err != nil {
+		return nil, errors.Wrapf(err, "failed to verify swap terms [%s]", binding)
+	}
+	if err := validationOpts.RunRequestHooks(ledger, binding, tr); err != nil {
+		return nil, report.Failed(api.RequestActionType, 0, api.HookCheck, errors.WithMessagef(err, "token request rejected by validation hook [%s]", binding))
+	}
 
 	var actions []interface{}
 	for _, action := range ia {
@@ -101,15 +176,114 @@ func (v *Validator) VerifyTokenRequest(ledger api.Ledger, signatureProvider api.
 	for _, action := range ta {
 		actions = append(actions, action)
 	}
+	for _, action := range ma {
+		actions = append(actions, action)
+	}
+	for _, action := range ra {
+		actions = append(actions, action)
+	}
+	for _, receipt := range tr.BurnReceipts {
+		actions = append(actions, receipt)
+	}
+
+	return actions, nil
+}
+
+// migrationSource returns the validator of the driver replaced by zkatdlog, nil if the migration is not enabled
+func (v *Validator) migrationSource() (*fabtoken.Validator, error) {
+	if v.pp.Migration == nil {
+		return nil, nil
+	}
+	pp, err := fabtoken.NewPublicParamsFromBytes(v.pp.Migration.SourcePublicParams)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling source public parameters")
+	}
+	return fabtoken.NewValidator(pp), nil
+}
+
+// checkSourceRequest checks that a token request of the replaced driver is accepted at the current height,
+// and returns the validator of the replaced driver
+func (v *Validator) checkSourceRequest(validationOpts *api.ValidationOptions, report *api.ValidationReport, binding string) (*fabtoken.Validator, error) {
+	source, err := v.migrationSource()
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, report.Failed(api.RequestActionType, 0, api.FormatCheck, errors.Errorf("token request of driver [%s] but migration is not enabled [%s]", fabtoken.PublicParameters, binding))
+	}
+	if err := v.pp.Migration.CheckSourceRequest(validationOpts.Height); err != nil {
+		return nil, report.Failed(api.RequestActionType, 0, api.CutoverCheck, errors.WithMessagef(err, "token request of driver [%s] rejected [%s]", fabtoken.PublicParameters, binding))
+	}
+	return source, nil
+}
+
+// UnmarshalActions returns the actions of the passed serialized token request, in the order returned
+// by VerifyTokenRequestFromRaw, without verifying them
+func (v *Validator) UnmarshalActions(raw []byte) ([]interface{}, error) {
+	raw, err := api.Decompress(raw, api.DefaultMaxDecompressedSize)
+	if err != nil {
+		return nil, err
+	}
+	tr := &api.TokenRequest{}
+	if err := json.Unmarshal(raw, tr); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal token request")
+	}
+	if tr.Driver == fabtoken.PublicParameters {
+		source, err := v.migrationSource()
+		if err != nil {
+			return nil, err
+		}
+		if source == nil {
+			return nil, errors.Errorf("token request of driver [%s] but migration is not enabled", fabtoken.PublicParameters)
+		}
+		return source.UnmarshalActions(raw)
+	}
+	validationOpts := &api.ValidationOptions{}
+	report := &api.ValidationReport{}
+	ia, err := v.unmarshalIssueActions(tr.Issues, validationOpts, report)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve issue actions")
+	}
+	ta, err := v.unmarshalTransferActions(tr.Transfers, validationOpts, report)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve transfer actions")
+	}
 
+	var actions []interface{}
+	for _, action := range ia {
+		actions = append(actions, action)
+	}
+	for _, action := range ta {
+		actions = append(actions, action)
+	}
+	for i, raw := range tr.Migrations {
+		action := &api.MigrationAction{}
+		if err := action.Deserialize(raw); err != nil {
+			return nil, errors.Wrapf(err, "failed to retrieve migration action [%d]", i)
+		}
+		actions = append(actions, action)
+	}
+	for i, raw := range tr.Rebindings {
+		action := &api.RebindingAction{}
+		if err := action.Deserialize(raw); err != nil {
+			return nil, errors.Wrapf(err, "failed to retrieve rebinding action [%d]", i)
+		}
+		actions = append(actions, action)
+	}
+	for _, receipt := range tr.BurnReceipts {
+		actions = append(actions, receipt)
+	}
 	return actions, nil
 }
 
-func (v *Validator) unmarshalTransferActions(raw [][]byte) ([]api.TransferAction, error) {
+func (v *Validator) unmarshalTransferActions(raw [][]byte, validationOpts *api.ValidationOptions, report *api.ValidationReport) ([]api.TransferAction, error) {
 	res := make([]api.TransferAction, len(raw))
 	for i := 0; i < len(raw); i++ {
 		ta := &transfer.TransferAction{}
 		if err := ta.Deserialize(raw[i]); err != nil {
+			return nil, report.Failed(api.TransferActionType, i, api.FormatCheck, err)
+		}
+		if err := validationOpts.CheckTransfer(i, ta, report); err != nil {
 			return nil, err
 		}
 		res[i] = ta
@@ -117,11 +291,14 @@ func (v *Validator) unmarshalTransferActions(raw [][]byte) ([]api.TransferAction
 	return res, nil
 }
 
-func (v *Validator) unmarshalIssueActions(raw [][]byte) ([]api.IssueAction, error) {
+func (v *Validator) unmarshalIssueActions(raw [][]byte, validationOpts *api.ValidationOptions, report *api.ValidationReport) ([]api.IssueAction, error) {
 	res := make([]api.IssueAction, len(raw))
 	for i := 0; i < len(raw); i++ {
 		ia := &issue2.IssueAction{}
 		if err := ia.Deserialize(raw[i]); err != nil {
+			return nil, report.Failed(api.IssueActionType, i, api.FormatCheck, err)
+		}
+		if err := validationOpts.CheckIssue(i, ia, report); err != nil {
 			return nil, err
 		}
 		res[i] = ia
@@ -129,25 +306,63 @@ func (v *Validator) unmarshalIssueActions(raw [][]byte) ([]api.IssueAction, erro
 	return res, nil
 }
 
-func (v *Validator) verifyAuditorSignature(signatureProvider api.SignatureProvider) error {
-	if v.pp.Auditor != nil {
+func (v *Validator) verifyAuditorSignature(signatureProvider api.SignatureProvider, tr *api.TokenRequest, transfers []api.TransferAction, report *api.ValidationReport) error {
+	if v.pp.AuditorIdentity() != nil && len(tr.ThresholdProof) != 0 {
+		// a request without auditor signature must prove that it is below the audit threshold
+		if err := v.verifyUnaudited(tr, transfers); err != nil {
+			return report.Failed(api.AuditorActionType, 0, api.ThresholdCheck, err)
+		}
+		return nil
+	}
+	if v.pp.AuditorIdentity() != nil {
 		identityDeserializer := &fabric.MSPX509IdentityDeserializer{}
 		verifier, err := identityDeserializer.GetVerifier(v.pp.Auditor)
 		if err != nil {
 			return errors.Errorf("failed to deserialize auditor's public key")
 		}
 
-		return signatureProvider.HasBeenSignedBy(v.pp.Auditor, verifier)
+		if err := signatureProvider.HasBeenSignedBy(v.pp.Auditor, verifier); err != nil {
+			return report.Failed(api.AuditorActionType, 0, api.SignatureCheck, err)
+		}
+		report.Succeeded(api.AuditorActionType, 0)
 	}
 	return nil
 }
 
-func (v *Validator) verifyIssues(issues []api.IssueAction, signatureProvider api.SignatureProvider) error {
-	for _, issue := range issues {
+// verifyUnaudited checks that the passed request can skip the auditor: it is made of transfers only and its threshold
+// proof shows that the total of their outputs is below the audit threshold
+func (v *Validator) verifyUnaudited(tr *api.TokenRequest, transfers []api.TransferAction) error {
+	if err := api.CheckUnaudited(tr, v.pp.AuditThreshold()); err != nil {
+		return err
+	}
+	if len(tr.AuditorSignature) != 0 {
+		return errors.New("unexpected auditor signature, the request proves it is below the audit threshold")
+	}
+	var outputs []*bn256.G1
+	for i, t := range transfers {
+		for j, output := range t.(*transfer.TransferAction).OutputTokens {
+			if output == nil || output.Data == nil {
+				return errors.Errorf("missing output [%d] of transfer [%d]", j, i)
+			}
+			outputs = append(outputs, output.Data)
+		}
+	}
+	return transfer.NewThresholdVerifier(outputs, v.pp.AuditThreshold(), v.pp).Verify(tr.ThresholdProof)
+}
+
+func (v *Validator) verifyIssues(ledger api.Ledger, issues []api.IssueAction, signatureProvider api.SignatureProvider, opts *api.ValidationOptions, report *api.ValidationReport) error {
+	for i, issue := range issues {
 		a := issue.(*issue2.IssueAction)
 
-		if err := v.verifyIssue(a); err != nil {
-			return errors.Wrapf(err, "failed to verify issue action")
+		if err := v.verifyAuditInfos(a.AuditInfos, a.NumOutputs()); err != nil {
+			return report.Failed(api.IssueActionType, i, api.FormatCheck, errors.Wrapf(err, "invalid audit infos"))
+		}
+
+		if err := v.verifyIssue(opts.Context, a); err != nil {
+			return report.Failed(api.IssueActionType, i, api.ProofCheck, errors.Wrapf(err, "failed to verify issue action"))
+		}
+		if err := v.verifyIssuedSupply(a); err != nil {
+			return report.Failed(api.IssueActionType, i, api.ProofCheck, errors.Wrapf(err, "invalid issued supply"))
 		}
 
 		if a.Anonymous {
@@ -157,29 +372,55 @@ func (v *Validator) verifyIssues(issues []api.IssueAction, signatureProvider api
 			if err != nil {
 				return err
 			}
-			err = verifier.Deserialize(ip.BitLength, ip.Issuers, v.pp.ZKATPedParams, a.OutputTokens[0].Data, a.Issuer)
+			err = verifier.Deserialize(ip.BitLength, ip.Issuers, ip.Accumulator, v.pp.ZKATPedParams, a.OutputTokens[0].Data, a.Issuer)
 			if err != nil {
-				return err
+				return report.Failed(api.IssueActionType, i, api.SignatureCheck, err)
 			}
+			verifier.Hash = v.pp.ChallengeHash
 			if err := signatureProvider.HasBeenSignedBy(a.Issuer, verifier); err != nil {
-				return errors.Wrapf(err, "failed verifying signature")
+				return report.Failed(api.IssueActionType, i, api.SignatureCheck, errors.Wrapf(err, "failed verifying signature"))
 			}
 		} else {
 			identityDeserializer := &fabric.MSPX509IdentityDeserializer{}
 			verifier, err := identityDeserializer.GetVerifier(a.Issuer)
 			if err != nil {
-				return errors.Wrapf(err, "failed getting verifier for [%s]", view.Identity(a.Issuer).String())
+				return report.Failed(api.IssueActionType, i, api.SignatureCheck, errors.Wrapf(err, "failed getting verifier for [%s]", view.Identity(a.Issuer).String()))
 			}
 			if err := signatureProvider.HasBeenSignedBy(a.Issuer, verifier); err != nil {
-				return errors.Wrapf(err, "failed verifying signature")
+				return report.Failed(api.IssueActionType, i, api.SignatureCheck, errors.Wrapf(err, "failed verifying signature"))
 			}
 		}
+		if err := v.verifyIssueApproval(signatureProvider); err != nil {
+			return report.Failed(api.IssueActionType, i, api.SignatureCheck, err)
+		}
+		if err := opts.RunIssueHooks(ledger, i, issue); err != nil {
+			return report.Failed(api.IssueActionType, i, api.HookCheck, errors.WithMessagef(err, "issue action rejected by validation hook"))
+		}
+		report.Succeeded(api.IssueActionType, i)
 	}
 	return nil
 }
 
-func (v *Validator) verifyTransfers(ledger api.Ledger, transferActions []api.TransferAction, signatureProvider api.SignatureProvider) error {
-	identityDeserializer, err := idemix2.NewDeserializer(v.pp.IdemixPK)
+// verifyIssueApproval checks the co-signature of the issue approver, if any.
+// Token types are hidden, therefore the approver for api.AnyTokenType applies to all issue actions.
+func (v *Validator) verifyIssueApproval(signatureProvider api.SignatureProvider) error {
+	approver := v.pp.IssueApprover(api.AnyTokenType)
+	if approver.IsNone() {
+		return nil
+	}
+	identityDeserializer := &fabric.MSPX509IdentityDeserializer{}
+	verifier, err := identityDeserializer.GetVerifier(approver)
+	if err != nil {
+		return errors.Wrapf(err, "failed getting verifier for approver [%s]", approver)
+	}
+	if err := signatureProvider.HasBeenSignedBy(approver, verifier); err != nil {
+		return errors.Wrapf(err, "failed verifying approver's signature")
+	}
+	return nil
+}
+
+func (v *Validator) verifyTransfers(ledger api.Ledger, transferActions []api.TransferAction, signatureProvider api.SignatureProvider, opts *api.ValidationOptions, report *api.ValidationReport) error {
+	identityDeserializer, err := newOwnerDeserializer(v.pp)
 	if err != nil {
 		return errors.Wrap(err, "failed instantiating deserializer")
 	}
@@ -190,50 +431,214 @@ func (v *Validator) verifyTransfers(ledger api.Ledger, transferActions []api.Tra
 		var inputTokens [][]byte
 		inputs, err := t.GetInputs()
 		if err != nil {
-			errors.Wrapf(err, "failed to retrieve inputs to spend")
+			return report.Failed(api.TransferActionType, i, api.FormatCheck, errors.Wrapf(err, "failed to retrieve inputs to spend"))
+		}
+		a := t.(*transfer.TransferAction)
+		if err := v.verifyAuditInfos(a.SenderAuditInfos, len(inputs)); err != nil {
+			return report.Failed(api.TransferActionType, i, api.FormatCheck, errors.Wrapf(err, "invalid sender audit infos"))
+		}
+		if err := v.verifyAuditInfos(a.ReceiverAuditInfos, a.NumOutputs()); err != nil {
+			return report.Failed(api.TransferActionType, i, api.FormatCheck, errors.Wrapf(err, "invalid receiver audit infos"))
 		}
-		for _, in := range inputs {
+		if err := v.verifyReceivers(a, report, i); err != nil {
+			return err
+		}
+		for j, in := range inputs {
 			logger.Debugf("load token [%d][%s]", i, in)
 			bytes, err := ledger.GetState(in)
 			if err != nil {
 				return errors.Wrapf(err, "failed to retrieve input to spend [%s]", in)
 			}
 			if len(bytes) == 0 {
-				return errors.Errorf("input to spend [%s] does not exists", in)
+				return report.Failed(api.TransferActionType, i, api.DoubleSpendCheck, errors.Errorf("input to spend [%s] does not exists", in), j)
 			}
 			inputTokens = append(inputTokens, bytes)
 			tok := &token.Token{}
 			err = tok.Deserialize(bytes)
 			if err != nil {
-				return errors.Wrapf(err, "failed to deserialize input to spend [%s]", in)
+				return report.Failed(api.TransferActionType, i, api.FormatCheck, errors.Wrapf(err, "failed to deserialize input to spend [%s]", in), j)
 			}
 			logger.Debugf("check sender [%d][%s]", i, view.Identity(tok.Owner).UniqueID())
 			verifier, err := identityDeserializer.DeserializeVerifier(tok.Owner)
 			if err != nil {
-				return errors.Wrapf(err, "failed deserializing owner [%d][%s][%s]", i, in, view.Identity(tok.Owner).UniqueID())
+				return report.Failed(api.TransferActionType, i, api.SignatureCheck, errors.Wrapf(err, "failed deserializing owner [%d][%s][%s]", i, in, view.Identity(tok.Owner).UniqueID()), j)
 			}
 			logger.Debugf("signature verification [%d][%s][%s]", i, in, view.Identity(tok.Owner).UniqueID())
 			if err := signatureProvider.HasBeenSignedBy(tok.Owner, verifier); err != nil {
-				return errors.Wrapf(err, "failed signature verification [%d][%s][%s]", i, in, view.Identity(tok.Owner).UniqueID())
+				return report.Failed(api.TransferActionType, i, api.SignatureCheck, errors.Wrapf(err, "failed signature verification [%d][%s][%s]", i, in, view.Identity(tok.Owner).UniqueID()), j)
 			}
 		}
-		if err := v.verifyTransfer(inputTokens, t); err != nil {
-			return errors.Wrapf(err, "failed to verify transfer action")
+		if err := v.verifyTransfer(opts.Context, inputTokens, t); err != nil {
+			// the transfer proof guarantees, among the others, that inputs and outputs balance
+			return report.Failed(api.TransferActionType, i, api.ProofCheck, errors.Wrapf(err, "failed to verify transfer action"))
+		}
+		if err := opts.RunTransferHooks(ledger, i, t); err != nil {
+			return report.Failed(api.TransferActionType, i, api.HookCheck, errors.WithMessagef(err, "transfer action rejected by validation hook"))
 		}
+		report.Succeeded(api.TransferActionType, i)
 	}
 	return nil
 }
 
-func (v *Validator) verifyIssue(issue api.IssueAction) error {
+// verifyMigrations checks that the inputs of the migration actions are unspent tokens of the replaced driver,
+// signed by their owners, and that each output carries the type and quantity of the corresponding input
+func (v *Validator) verifyMigrations(ledger api.Ledger, migrations [][]byte, signatureProvider api.SignatureProvider, report *api.ValidationReport) ([]*api.MigrationAction, error) {
+	if len(migrations) == 0 {
+		return nil, nil
+	}
+	source, err := v.migrationSource()
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, report.Failed(api.MigrationActionType, 0, api.FormatCheck, errors.New("migration is not enabled"))
+	}
+	res := make([]*api.MigrationAction, len(migrations))
+	for i, raw := range migrations {
+		action := &api.MigrationAction{}
+		if err := action.Deserialize(raw); err != nil {
+			return nil, report.Failed(api.MigrationActionType, i, api.FormatCheck, err)
+		}
+		inputs, err := source.VerifyMigrationInputs(ledger, signatureProvider, i, action, report)
+		if err != nil {
+			return nil, err
+		}
+		for j, input := range inputs {
+			if err := v.verifyMigrationOutput(action.Outputs[j], action.Openings[j], input); err != nil {
+				return nil, report.Failed(api.MigrationActionType, i, api.ProofCheck, errors.WithMessagef(err, "invalid output [%d]", j), j)
+			}
+		}
+		report.Succeeded(api.MigrationActionType, i)
+		res[i] = action
+	}
+	return res, nil
+}
+
+func (v *Validator) verifyMigrationOutput(raw []byte, opening []byte, input *token2.Token) error {
+	tok := &token.Token{}
+	if err := tok.Deserialize(raw); err != nil {
+		return errors.Wrapf(err, "failed deserializing output")
+	}
+	if tok.IsRedeem() {
+		return errors.New("output without owner")
+	}
+	if tok.Data == nil {
+		return errors.New("output without commitment")
+	}
+	ti := &token.TokenInformation{}
+	if err := ti.Deserialize(opening); err != nil {
+		return errors.Wrapf(err, "failed deserializing opening")
+	}
+	if ti.Value == nil || ti.BlindingFactor == nil {
+		return errors.New("incomplete opening")
+	}
+	out, err := tok.GetTokenInTheClear(ti, v.pp)
+	if err != nil {
+		return err
+	}
+	if out.Type != input.Type {
+		return errors.Errorf("type [%s] does not match [%s]", out.Type, input.Type)
+	}
+	q, err := token2.ToQuantity(out.Quantity, keys.Precision)
+	if err != nil {
+		return errors.Wrapf(err, "invalid output quantity [%s]", out.Quantity)
+	}
+	iq, err := token2.ToQuantity(input.Quantity, keys.Precision)
+	if err != nil {
+		return errors.Wrapf(err, "invalid input quantity [%s]", input.Quantity)
+	}
+	if q.Cmp(iq) != 0 {
+		return errors.Errorf("quantity [%s] does not match [%s]", out.Quantity, input.Quantity)
+	}
+	if q.Cmp(token2.NewQuantityFromUInt64(v.pp.MaxTokenValue())) > 0 {
+		return errors.Errorf("quantity [%s] exceeds the maximum token value [%d]", out.Quantity, v.pp.MaxTokenValue())
+	}
+	return nil
+}
+
+// verifyAuditInfos checks that, if the public parameters declare an auditor encryption key,
+// an action carries one well-formed encrypted audit info for each of its inputs or outputs.
+// If the identities are out of the audit scope, the action must carry none.
+func (v *Validator) verifyAuditInfos(auditInfos [][]byte, expected int) error {
+	if !v.pp.AuditScope.Identities() {
+		if len(auditInfos) != 0 {
+			return errors.Errorf("unexpected audit infos, the identities are out of the audit scope")
+		}
+		return nil
+	}
+	if v.pp.AuditorEncryptionKey == nil {
+		return nil
+	}
+	if len(auditInfos) != expected {
+		return errors.Errorf("expected [%d] encrypted audit infos, got [%d]", expected, len(auditInfos))
+	}
+	for i, auditInfo := range auditInfos {
+		if err := audit.CheckEncryptedAuditInfo(auditInfo); err != nil {
+			return errors.WithMessagef(err, "invalid encrypted audit info at index [%d]", i)
+		}
+	}
+	return nil
+}
+
+// verifyReceivers checks the owners of the outputs of the passed transfer action against the receiver policy, if any
+func (v *Validator) verifyReceivers(action *transfer.TransferAction, report *api.ValidationReport, index int) error {
+	if v.pp.ReceiverPolicy == nil {
+		return nil
+	}
+	attributes, err := v.ownerAttributes()
+	if err != nil {
+		return err
+	}
+	for j, output := range action.OutputTokens {
+		if output == nil || output.IsRedeem() {
+			continue
+		}
+		if err := v.pp.ReceiverPolicy.CheckReceiver(output.Owner, attributes.OrganizationalUnits); err != nil {
+			return report.Failed(api.TransferActionType, index, api.ReceiverCheck, errors.WithMessagef(err, "receiver of output [%d] not accepted", j), j)
+		}
+	}
+	return nil
+}
+
+func (v *Validator) verifyIssue(ctx context.Context, issue api.IssueAction) error {
 	action := issue.(*issue2.IssueAction)
 
 	return issue2.NewVerifier(
 		action.GetCommitments(),
 		action.IsAnonymous(),
-		v.pp).Verify(action.GetProof())
+		v.pp).VerifyWithContext(ctx, action.GetProof())
 }
 
-func (v *Validator) verifyTransfer(inputTokens [][]byte, tr api.TransferAction) error {
+// verifyIssuedSupply checks the total value revealed by the passed issue action, required if the public parameters
+// cap the supply of its type. The caps themselves are enforced against the supply on the ledger, at commit time.
+// The type of anonymous issue actions is hidden, therefore they are rejected if any cap is declared.
+func (v *Validator) verifyIssuedSupply(action *issue2.IssueAction) error {
+	if action.Anonymous {
+		if action.Supply != nil {
+			return errors.New("anonymous issue actions cannot reveal the issued supply")
+		}
+		if len(v.pp.SupplyCaps) != 0 {
+			return errors.New("anonymous issue actions are not allowed when supply caps are declared")
+		}
+		return nil
+	}
+	tokenType, err := action.TypeInTheClear()
+	if err != nil {
+		return err
+	}
+	if action.Supply == nil {
+		if _, capped := v.pp.SupplyCap(tokenType); capped {
+			return errors.Errorf("the supply of [%s] is capped, the issued supply must be revealed", tokenType)
+		}
+		return nil
+	}
+	if action.Supply.Type != tokenType {
+		return errors.Errorf("issued supply of [%s], expected [%s]", action.Supply.Type, tokenType)
+	}
+	return action.Supply.Verify(action.GetCommitments(), v.pp.ZKATPedParams)
+}
+
+func (v *Validator) verifyTransfer(ctx context.Context, inputTokens [][]byte, tr api.TransferAction) error {
 	action := tr.(*transfer.TransferAction)
 
 	in := make([]*bn256.G1, len(inputTokens))
@@ -248,7 +653,107 @@ func (v *Validator) verifyTransfer(inputTokens [][]byte, tr api.TransferAction)
 	return transfer.NewVerifier(
 		in,
 		action.GetOutputCommitments(),
-		v.pp).Verify(action.GetProof())
+		v.pp).VerifyWithContext(ctx, action.GetProof())
+}
+
+// issuerVerifier returns the verifier of the passed issuer, co-signing a redemption.
+// The chain of the certificate of the issuer is not checked, the issuer must be listed in the issuer policy
+// of the redeemed type, see translator.RedeemIssuerValidator.
+func (v *Validator) issuerVerifier(issuer view.Identity) (api.Verifier, error) {
+	return (&fabric.MSPX509IdentityDeserializer{}).GetVerifier(issuer)
+}
+
+// ownerAttributes returns the extractor of the organizational units of the owners, instantiated on first use
+func (v *Validator) ownerAttributes() (*ownerAttributes, error) {
+	v.attributesOnce.Do(func() {
+		v.attributes, v.attributesErr = newOwnerAttributes(v.pp)
+	})
+	if v.attributesErr != nil {
+		return nil, errors.Wrap(v.attributesErr, "failed instantiating owner attributes")
+	}
+	return v.attributes, nil
+}
+
+// tokenOrganizations returns the organizational unit disclosed, and proven, by the idemix owner of the passed
+// serialized token
+func (v *Validator) tokenOrganizations(raw []byte) ([]string, error) {
+	tok := &token.Token{}
+	if err := tok.Deserialize(raw); err != nil {
+		return nil, errors.Wrap(err, "failed to deserialize token")
+	}
+	if len(tok.Owner) == 0 {
+		return nil, errors.New("token without owner")
+	}
+	attributes, err := v.ownerAttributes()
+	if err != nil {
+		return nil, err
+	}
+	return attributes.OrganizationalUnits(tok.Owner)
+}
+
+// matchRebinding checks that the passed output commits to the same type and value of the passed input, with a
+// re-randomized commitment as the passed proof shows, and that it is bound to a different owner.
+// Time locked inputs cannot be rebound, the rebinding would lift the lock.
+func (v *Validator) matchRebinding(input, output, proof []byte) error {
+	in := &token.Token{}
+	if err := in.Deserialize(input); err != nil {
+		return errors.Wrap(err, "failed to deserialize input")
+	}
+	if api.IsTimeLock(in.Owner) {
+		return errors.New("time locked tokens cannot be rebound")
+	}
+	out := &token.Token{}
+	if err := out.Deserialize(output); err != nil {
+		return errors.Wrap(err, "failed to deserialize output")
+	}
+	if len(out.Owner) == 0 {
+		return errors.New("rebound token without owner")
+	}
+	if bytes.Equal(in.Owner, out.Owner) {
+		return errors.New("rebound token with the same owner")
+	}
+	if in.Data == nil || out.Data == nil {
+		return errors.New("rebound token without commitment")
+	}
+	if in.Data.Equals(out.Data) {
+		return errors.New("rebound token does not re-randomize the commitment of the input")
+	}
+	if err := token.VerifyRebinding(in, out, proof, v.pp); err != nil {
+		return errors.WithMessagef(err, "rebound token does not carry the commitment of the input")
+	}
+	return nil
+}
+
+func (v *Validator) matchBurnReceipt(output api.Output, receipt *api.BurnReceipt) error {
+	return v.matchOutput(output, receipt.Type, receipt.Quantity, receipt.TokenInfo)
+}
+
+// matchOutput checks that the passed token information opens the passed output, and that it carries
+// the passed type and quantity
+func (v *Validator) matchOutput(output api.Output, typ string, quantity string, tokenInfo []byte) error {
+	ti := &token.TokenInformation{}
+	if err := ti.Deserialize(tokenInfo); err != nil {
+		return errors.Wrapf(err, "failed deserializing token information")
+	}
+	out, err := output.(*token.Token).GetTokenInTheClear(ti, v.pp)
+	if err != nil {
+		return err
+	}
+	if out.Type != typ {
+		return errors.Errorf("type [%s] does not match [%s]", typ, out.Type)
+	}
+	q, err := token2.ToQuantity(out.Quantity, keys.Precision)
+	if err != nil {
+		return errors.Wrapf(err, "invalid output quantity [%s]", out.Quantity)
+	}
+	rq, err := token2.ToQuantity(quantity, keys.Precision)
+	if err != nil {
+		return errors.Wrapf(err, "invalid quantity [%s]", quantity)
+	}
+	if q.Cmp(rq) != 0 {
+		return errors.Errorf("quantity [%s] does not match [%s]", quantity, out.Quantity)
+	}
+	return nil
 }
 
 type backend struct {
@@ -271,3 +776,30 @@ func (b *backend) HasBeenSignedBy(id view.Identity, verifier api.Verifier) error
 func (b *backend) GetState(key string) ([]byte, error) {
 	return b.getState(key)
 }
+
+// VerifyOwnership checks that the passed proof has been signed by the owner of the token it carries
+func (v *Validator) VerifyOwnership(proof *api.OwnershipProof) error {
+	tok := &token.Token{}
+	if err := tok.Deserialize(proof.Output); err != nil {
+		return errors.Wrapf(err, "failed to deserialize token [%s]", proof.ID)
+	}
+	if tok.IsRedeem() {
+		return errors.Errorf("token [%s] has been redeemed", proof.ID)
+	}
+	identityDeserializer, err := newOwnerDeserializer(v.pp)
+	if err != nil {
+		return errors.Wrap(err, "failed instantiating deserializer")
+	}
+	verifier, err := identityDeserializer.DeserializeVerifier(tok.Owner)
+	if err != nil {
+		return errors.Wrapf(err, "failed deserializing owner of [%s]", proof.ID)
+	}
+	msg, err := proof.MessageToSign()
+	if err != nil {
+		return err
+	}
+	if err := verifier.Verify(msg, proof.Signature); err != nil {
+		return errors.Wrapf(err, "invalid ownership proof for [%s]", proof.ID)
+	}
+	return nil
+}
@@ -10,9 +10,12 @@ import (
 	"io/ioutil"
 	"time"
 
+	"github.com/golang/protobuf/proto"
+	m "github.com/hyperledger/fabric-protos-go/msp"
 	msp2 "github.com/hyperledger/fabric/msp"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
 
 	idemix2 "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/idemix"
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
@@ -22,10 +25,12 @@ import (
 	registry2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/audit"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/ecdsa"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/elgamal"
 	issue2 "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/anonym"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/nonanonym"
@@ -33,6 +38,8 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
 	enginedlog "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/validator"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/validator/mock"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 var fakeldger *mock.Ledger
@@ -48,13 +55,16 @@ var _ = Describe("validator", func() {
 
 		anonymissuer *anonym.Issuer
 		sender       *transfer.Sender
+		redeemer     *transfer.Sender
 		auditor      *audit.Auditor
 		ipk          []byte
 
 		air *api.TokenRequest // anonymous issue request
 		ir  *api.TokenRequest // regular issue request
 		rr  *api.TokenRequest // redeem request
+		rrm *api.TokenRequestMetadata
 		tr  *api.TokenRequest // transfer request
+		trm *api.TokenRequestMetadata
 		ar  *api.TokenRequest // atomic action request
 	)
 	BeforeEach(func() {
@@ -98,14 +108,15 @@ var _ = Describe("validator", func() {
 		Expect(imetadata).NotTo(BeNil())
 
 		// prepare redeem
-		sender, rr, _, inputsForRedeem = prepareRedeemRequest(pp, auditor)
-		Expect(sender).NotTo(BeNil())
+		redeemer, rr, rrm, inputsForRedeem = prepareRedeemRequest(pp, auditor)
+		Expect(redeemer).NotTo(BeNil())
 
 		// prepare transfer
 		var trmetadata *api.TokenRequestMetadata
 		sender, tr, trmetadata, inputsForTransfer = prepareTransferRequest(pp, auditor)
 		Expect(sender).NotTo(BeNil())
 		Expect(trmetadata).NotTo(BeNil())
+		trm = trmetadata
 
 		// atomic action request
 		ar = &api.TokenRequest{Issues: air.Issues, Transfers: tr.Transfers}
@@ -154,6 +165,126 @@ var _ = Describe("validator", func() {
 			})
 		})
 
+		Context("Validator is called without an auditor", func() {
+			BeforeEach(func() {
+				pp.Auditor = nil
+			})
+			It("succeeds when the request carries no auditor signature", func() {
+				air.AuditorSignature = nil
+				raw, err := json.Marshal(air)
+				Expect(err).NotTo(HaveOccurred())
+
+				actions, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(actions)).To(Equal(1))
+			})
+			It("fails when the request carries an auditor signature", func() {
+				air.AuditorSignature = []byte("signature")
+				raw, err := json.Marshal(air)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("unexpected auditor signature"))
+			})
+		})
+
+		Context("Validator is called with a validation hook rejecting issue actions", func() {
+			var (
+				raw []byte
+				err error
+			)
+			BeforeEach(func() {
+				raw, err = json.Marshal(air)
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("fails", func() {
+				hook := api.WithIssueHook(func(ledger api.Ledger, index int, action api.IssueAction) error {
+					return errors.Errorf("issuer not in whitelist")
+				})
+				_, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw, hook)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("issuer not in whitelist"))
+
+				report, ok := api.GetValidationReport(err)
+				Expect(ok).To(BeTrue())
+				Expect(report.Failure().Type).To(Equal(api.IssueActionType))
+				Expect(report.Failure().Check).To(Equal(api.HookCheck))
+			})
+		})
+
+		Context("Validator is called with request limits", func() {
+			var (
+				raw []byte
+				err error
+			)
+			BeforeEach(func() {
+				raw, err = json.Marshal(air)
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("fails when the request is too big", func() {
+				limits := api.WithRequestLimits(&api.RequestLimits{MaxSize: len(raw) - 1})
+				_, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw, limits)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("exceeds limit"))
+
+				report, ok := api.GetValidationReport(err)
+				Expect(ok).To(BeTrue())
+				Expect(report.Failure().Type).To(Equal(api.RequestActionType))
+				Expect(report.Failure().Check).To(Equal(api.LimitCheck))
+			})
+			It("fails when an action has too many inputs", func() {
+				raw, err = json.Marshal(tr)
+				Expect(err).NotTo(HaveOccurred())
+				limits := api.WithRequestLimits(&api.RequestLimits{MaxActions: 1, MaxInputs: 1})
+				_, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw, limits)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("number of inputs [2] exceeds limit [1]"))
+
+				report, ok := api.GetValidationReport(err)
+				Expect(ok).To(BeTrue())
+				Expect(report.Failure().Type).To(Equal(api.TransferActionType))
+				Expect(report.Failure().Check).To(Equal(api.LimitCheck))
+				Expect(fakeldger.GetStateCallCount()).To(Equal(0))
+			})
+			It("fails when the request has too many actions", func() {
+				limits := api.WithRequestLimits(&api.RequestLimits{MaxActions: 1})
+				_, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw, limits)
+				Expect(err).NotTo(HaveOccurred())
+
+				air.Issues = append(air.Issues, air.Issues[0])
+				raw, err = json.Marshal(air)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw, limits)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("number of actions [2] exceeds limit [1]"))
+			})
+		})
+
+		Context("Validator is called with an issue action without encrypted audit infos", func() {
+			var (
+				raw []byte
+				err error
+			)
+			BeforeEach(func() {
+				raw, err = json.Marshal(air)
+				Expect(err).NotTo(HaveOccurred())
+				sk, err := elgamal.NewKeyPair(bn256.G1Gen())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pp.SetAuditorEncryptionKey(sk.PublicKey)).To(Succeed())
+			})
+			It("fails", func() {
+				_, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("expected [1] encrypted audit infos, got [0]"))
+
+				report, ok := api.GetValidationReport(err)
+				Expect(ok).To(BeTrue())
+				Expect(report.Failure().Type).To(Equal(api.IssueActionType))
+				Expect(report.Failure().Check).To(Equal(api.FormatCheck))
+			})
+		})
+
 		Context("Validator is called correctly with a non-anonymous issue action", func() {
 			var (
 				err error
@@ -170,6 +301,344 @@ var _ = Describe("validator", func() {
 			})
 		})
 
+		Context("Validator is called with a compressed token request", func() {
+			var (
+				err error
+				raw []byte
+			)
+			BeforeEach(func() {
+				raw, err = ir.CompressedBytes()
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("succeeds", func() {
+				actions, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(actions)).To(Equal(1))
+			})
+			It("fails when the decompressed request exceeds the size limit", func() {
+				plain, err := ir.Bytes()
+				Expect(err).NotTo(HaveOccurred())
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw, api.WithRequestLimits(&api.RequestLimits{MaxSize: len(plain) - 1}))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed decompressing token request"))
+			})
+		})
+
+		Context("Validator is called with an issue action and an issue approver", func() {
+			var approver *ecdsa.ECDSASigner
+			BeforeEach(func() {
+				approver, _ = prepareECDSASigner()
+				araw, err := approver.GetPublicVersion().Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				pp.IssuePolicy = &api.IssuePolicy{}
+				pp.IssuePolicy.SetApprover(api.AnyTokenType, araw)
+			})
+			It("succeeds when the approver co-signs", func() {
+				msg, err := ir.MarshalToSign()
+				Expect(err).NotTo(HaveOccurred())
+				sigma, err := approver.Sign(append(msg, []byte("1")...))
+				Expect(err).NotTo(HaveOccurred())
+				ir.Signatures = append(ir.Signatures, sigma)
+				raw, err := json.Marshal(ir)
+				Expect(err).NotTo(HaveOccurred())
+
+				actions, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(actions)).To(Equal(1))
+			})
+			It("fails when the approver does not co-sign", func() {
+				raw, err := json.Marshal(ir)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("insufficient number of signatures"))
+			})
+			It("fails when the approver signs another transaction", func() {
+				msg, err := ir.MarshalToSign()
+				Expect(err).NotTo(HaveOccurred())
+				sigma, err := approver.Sign(append(msg, []byte("2")...))
+				Expect(err).NotTo(HaveOccurred())
+				ir.Signatures = append(ir.Signatures, sigma)
+				raw, err := json.Marshal(ir)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed verifying approver's signature"))
+			})
+		})
+
+		Context("Validator is called with an issue action and supply caps", func() {
+			var tamper func(f func(action *issue2.IssueAction)) []byte
+			BeforeEach(func() {
+				pp.SetSupplyCap("ABC", 100)
+				tamper = func(f func(action *issue2.IssueAction)) []byte {
+					action := &issue2.IssueAction{}
+					Expect(action.Deserialize(ir.Issues[0])).To(Succeed())
+					f(action)
+					var err error
+					ir.Issues[0], err = action.Serialize()
+					Expect(err).NotTo(HaveOccurred())
+					// the auditor endorses the tampered request, the issued supply is checked before the issuer's signature
+					ir.AuditorSignature, err = auditor.Endorse(ir, "1")
+					Expect(err).NotTo(HaveOccurred())
+					raw, err := json.Marshal(ir)
+					Expect(err).NotTo(HaveOccurred())
+					return raw
+				}
+			})
+			It("succeeds when the issued supply is revealed", func() {
+				raw, err := json.Marshal(ir)
+				Expect(err).NotTo(HaveOccurred())
+				actions, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(actions)).To(Equal(1))
+				tokenType, value, revealed := actions[0].(*issue2.IssueAction).GetIssuedSupply()
+				Expect(revealed).To(BeTrue())
+				Expect(tokenType).To(Equal("ABC"))
+				Expect(value).To(Equal(uint64(40)))
+			})
+			It("fails when the issued supply does not match the outputs", func() {
+				raw := tamper(func(action *issue2.IssueAction) {
+					action.Supply.Value = 39
+				})
+				_, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("issued supply of [ABC] does not match the outputs"))
+
+				report, ok := api.GetValidationReport(err)
+				Expect(ok).To(BeTrue())
+				Expect(report.Failure().Check).To(Equal(api.ProofCheck))
+			})
+			It("fails when the issued supply is of another type", func() {
+				raw := tamper(func(action *issue2.IssueAction) {
+					action.Supply.Type = "DEF"
+				})
+				_, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("issued supply of [DEF], expected [ABC]"))
+			})
+			It("fails when the issued supply is not revealed", func() {
+				raw := tamper(func(action *issue2.IssueAction) {
+					action.Supply = nil
+				})
+				_, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("the supply of [ABC] is capped, the issued supply must be revealed"))
+			})
+			It("rejects anonymous issue actions", func() {
+				raw, err := json.Marshal(air)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("anonymous issue actions are not allowed when supply caps are declared"))
+			})
+		})
+
+		Context("Validator is called with a migration action", func() {
+			var (
+				owner  *ecdsa.ECDSASigner
+				input  *fabtoken.Token
+				action *api.MigrationAction
+			)
+			BeforeEach(func() {
+				src, err := fabtoken.Setup()
+				Expect(err).NotTo(HaveOccurred())
+				srcRaw, err := src.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				pp.Migration = &api.MigrationParams{SourcePublicParams: srcRaw}
+
+				owner, _ = prepareECDSASigner()
+				oraw, err := owner.GetPublicVersion().Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				input = &fabtoken.Token{Token: token2.Token{Type: "ABC", Quantity: "0x0a", Owner: &token2.Owner{Raw: oraw}}}
+				action, _, err = tokn.NewMigrationAction([]string{"input"}, []*token2.Token{&input.Token}, [][]byte{oraw}, keys.Precision, pp)
+				Expect(err).NotTo(HaveOccurred())
+			})
+			sign := func(tr *api.TokenRequest) []byte {
+				var err error
+				tr.AuditorSignature, err = auditor.Endorse(tr, "1")
+				Expect(err).NotTo(HaveOccurred())
+				msg, err := tr.MarshalToSign()
+				Expect(err).NotTo(HaveOccurred())
+				sigma, err := owner.Sign(append(msg, []byte("1")...))
+				Expect(err).NotTo(HaveOccurred())
+				tr.Signatures = append(tr.Signatures, sigma)
+				raw, err := json.Marshal(tr)
+				Expect(err).NotTo(HaveOccurred())
+				return raw
+			}
+			migrationRequest := func() *api.TokenRequest {
+				raw, err := action.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				return &api.TokenRequest{Migrations: [][]byte{raw}}
+			}
+			It("succeeds", func() {
+				inputRaw, err := json.Marshal(input)
+				Expect(err).NotTo(HaveOccurred())
+				fakeldger.GetStateReturns(inputRaw, nil)
+
+				actions, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetState, "1", sign(migrationRequest()))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(actions)).To(Equal(1))
+				Expect(actions[0]).To(Equal(action))
+			})
+			It("fails when the output does not carry the quantity of the input", func() {
+				input.Quantity = "0x0b"
+				inputRaw, err := json.Marshal(input)
+				Expect(err).NotTo(HaveOccurred())
+				fakeldger.GetStateReturns(inputRaw, nil)
+
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetState, "1", sign(migrationRequest()))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("quantity [0xa] does not match [0x0b]"))
+			})
+			It("fails when the migration is not enabled", func() {
+				pp.Migration = nil
+				_, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetState, "1", sign(migrationRequest()))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("migration is not enabled"))
+			})
+			It("accepts fabtoken requests only before the cutover", func() {
+				pp.Migration.CutoverHeight = 10
+				raw, err := json.Marshal(&api.TokenRequest{Driver: fabtoken.PublicParameters})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw, api.WithHeight(9))
+				Expect(err).NotTo(HaveOccurred())
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw, api.WithHeight(10))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("not accepted from height [10]"))
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetStateStub, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("ledger height not available"))
+			})
+		})
+
+		Context("Validator is called with a rebinding action", func() {
+			var (
+				successor []byte
+				inputRaw  []byte
+			)
+			BeforeEach(func() {
+				signer, _ := prepareECDSASigner()
+				var err error
+				successor, err = signer.GetPublicVersion().Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				inputRaw, err = inputsForTransfer[0].Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				fakeldger.GetStateReturns(inputRaw, nil)
+				pp.DepartedOrganizations = []string{"idemixorg.example.com", "other.example.com"}
+			})
+			rebindingRequest := func(action *api.RebindingAction) []byte {
+				raw, err := action.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				tr := &api.TokenRequest{Rebindings: [][]byte{raw}}
+				if len(pp.Auditor) != 0 {
+					tr.AuditorSignature, err = auditor.Endorse(tr, "1")
+					Expect(err).NotTo(HaveOccurred())
+				}
+				raw, err = json.Marshal(tr)
+				Expect(err).NotTo(HaveOccurred())
+				return raw
+			}
+			It("succeeds", func() {
+				action, _, err := tokn.NewRebindingAction("idemixorg.example.com", []string{"input"}, [][]byte{inputRaw}, [][]byte{successor}, pp)
+				Expect(err).NotTo(HaveOccurred())
+
+				actions, err := engine.VerifyTokenRequestFromRaw(fakeldger.GetState, "1", rebindingRequest(action))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(actions)).To(Equal(1))
+				Expect(actions[0]).To(Equal(action))
+
+				// the output commits to the same type and value with a different blinding factor
+				in := &tokn.Token{}
+				Expect(in.Deserialize(inputRaw)).To(Succeed())
+				out := &tokn.Token{}
+				Expect(out.Deserialize(action.Outputs[0])).To(Succeed())
+				Expect(out.Data.Equals(in.Data)).To(BeFalse())
+				Expect(out.Owner).To(Equal(successor))
+			})
+			It("fails when the output reuses the commitment of the input", func() {
+				in := &tokn.Token{}
+				Expect(in.Deserialize(inputRaw)).To(Succeed())
+				outRaw, err := (&tokn.Token{Owner: successor, Data: in.Data}).Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				action := &api.RebindingAction{Organization: "idemixorg.example.com", Inputs: []string{"input"}, Outputs: [][]byte{outRaw}}
+
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetState, "1", rebindingRequest(action))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("does not re-randomize"))
+			})
+			It("fails when the owner of the output is not the one the proof is bound to", func() {
+				action, _, err := tokn.NewRebindingAction("idemixorg.example.com", []string{"input"}, [][]byte{inputRaw}, [][]byte{successor}, pp)
+				Expect(err).NotTo(HaveOccurred())
+				out := &tokn.Token{}
+				Expect(out.Deserialize(action.Outputs[0])).To(Succeed())
+				out.Owner = inputRaw
+				action.Outputs[0], err = out.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetState, "1", rebindingRequest(action))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid rebinding proof"))
+			})
+			It("fails when the same input is rebound twice", func() {
+				action, _, err := tokn.NewRebindingAction("idemixorg.example.com", []string{"input", "input"}, [][]byte{inputRaw, inputRaw}, [][]byte{successor, successor}, pp)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetState, "1", rebindingRequest(action))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("input to rebind [input] appears more than once"))
+				report, ok := api.GetValidationReport(err)
+				Expect(ok).To(BeTrue())
+				Expect(report.Failure().Check).To(Equal(api.DoubleSpendCheck))
+			})
+			It("fails when the output does not carry the commitment of the input", func() {
+				other, err := inputsForTransfer[1].Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				action, _, err := tokn.NewRebindingAction("idemixorg.example.com", []string{"input"}, [][]byte{other}, [][]byte{successor}, pp)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetState, "1", rebindingRequest(action))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("does not carry the commitment of the input"))
+			})
+			It("fails when the public parameters do not name an auditor", func() {
+				action, _, err := tokn.NewRebindingAction("idemixorg.example.com", []string{"input"}, [][]byte{inputRaw}, [][]byte{successor}, pp)
+				Expect(err).NotTo(HaveOccurred())
+				pp.Auditor = nil
+
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetState, "1", rebindingRequest(action))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("rebinding requires an auditor"))
+			})
+			It("fails when the organization has not departed", func() {
+				action, _, err := tokn.NewRebindingAction("idemixorg.example.com", []string{"input"}, [][]byte{inputRaw}, [][]byte{successor}, pp)
+				Expect(err).NotTo(HaveOccurred())
+				pp.DepartedOrganizations = []string{"other.example.com"}
+
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetState, "1", rebindingRequest(action))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("organization [idemixorg.example.com] has not departed"))
+				report, ok := api.GetValidationReport(err)
+				Expect(ok).To(BeTrue())
+				Expect(report.Failure().Check).To(Equal(api.OrganizationCheck))
+			})
+			It("fails when the owner of an input is not a member of the departed organization", func() {
+				action, _, err := tokn.NewRebindingAction("other.example.com", []string{"input"}, [][]byte{inputRaw}, [][]byte{successor}, pp)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = engine.VerifyTokenRequestFromRaw(fakeldger.GetState, "1", rebindingRequest(action))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("owner of [input] is not a member of [other.example.com]"))
+				report, ok := api.GetValidationReport(err)
+				Expect(ok).To(BeTrue())
+				Expect(report.Failure().Check).To(Equal(api.OrganizationCheck))
+			})
+		})
+
 		Context("validator is called correctly with a transfer action", func() {
 			var (
 				err error
@@ -203,6 +672,73 @@ var _ = Describe("validator", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(len(actions)).To(Equal(1))
 			})
+			It("succeeds when the receivers disclose an accepted organizational unit", func() {
+				pp.ReceiverPolicy = &api.ReceiverPolicy{Attributes: []string{"kyc-2", "idemixorg.example.com"}}
+				actions, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(actions)).To(Equal(1))
+			})
+			It("fails when the receivers do not disclose an accepted organizational unit", func() {
+				pp.ReceiverPolicy = &api.ReceiverPolicy{Attributes: []string{"kyc-2"}}
+				_, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("holds none of the attributes [kyc-2]"))
+				report, ok := api.GetValidationReport(err)
+				Expect(ok).To(BeTrue())
+				Expect(report.Failure().Check).To(Equal(api.ReceiverCheck))
+			})
+			It("fails when the proof of a receiver is not bound to its pseudonym", func() {
+				action := &transfer.TransferAction{}
+				Expect(action.Deserialize(tr.Transfers[0])).To(Succeed())
+				other, _, _ := getIdemixInfo("./testdata/idemix")
+				action.OutputTokens[0].Owner = swapNym(action.OutputTokens[0].Owner, other)
+				tr.Transfers[0], err = action.Serialize()
+				Expect(err).NotTo(HaveOccurred())
+				tr.AuditorSignature, err = auditor.Endorse(tr, "1")
+				Expect(err).NotTo(HaveOccurred())
+				raw, err = json.Marshal(tr)
+				Expect(err).NotTo(HaveOccurred())
+
+				pp.ReceiverPolicy = &api.ReceiverPolicy{Attributes: []string{"idemixorg.example.com"}}
+				_, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("not bound to its pseudonym"))
+			})
+			It("succeeds without auditor signature when the request proves it is below the audit threshold", func() {
+				Expect(pp.SetAuditThreshold(101)).To(Succeed())
+				tr.AuditorSignature = nil
+				tr.ThresholdProof = proveAuditThreshold(pp, tr, trm)
+				raw, err = json.Marshal(tr)
+				Expect(err).NotTo(HaveOccurred())
+				actions, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(actions)).To(Equal(1))
+			})
+			It("fails without auditor signature when the request is not below the audit threshold", func() {
+				Expect(pp.SetAuditThreshold(101)).To(Succeed())
+				tr.AuditorSignature = nil
+				tr.ThresholdProof = proveAuditThreshold(pp, tr, trm)
+				Expect(pp.SetAuditThreshold(100)).To(Succeed())
+				raw, err = json.Marshal(tr)
+				Expect(err).NotTo(HaveOccurred())
+				_, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("invalid threshold proof"))
+				report, ok := api.GetValidationReport(err)
+				Expect(ok).To(BeTrue())
+				Expect(report.Failure().Check).To(Equal(api.ThresholdCheck))
+			})
+			It("fails without auditor signature when the public parameters declare no audit threshold", func() {
+				Expect(pp.SetAuditThreshold(101)).To(Succeed())
+				tr.AuditorSignature = nil
+				tr.ThresholdProof = proveAuditThreshold(pp, tr, trm)
+				Expect(pp.SetAuditThreshold(0)).To(Succeed())
+				raw, err = json.Marshal(tr)
+				Expect(err).NotTo(HaveOccurred())
+				_, err := engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("the token request must be signed by the auditor"))
+			})
 		})
 		Context("validator is called correctly with a redeem action", func() {
 			var (
@@ -239,6 +775,73 @@ var _ = Describe("validator", func() {
 				Expect(len(actions)).To(Equal(1))
 			})
 		})
+		Context("validator is called with a redeem action and its burn receipt", func() {
+			var (
+				br      *api.TokenRequest
+				receipt *api.BurnReceipt
+			)
+			BeforeEach(func() {
+				for i := 0; i < 4; i++ {
+					raw, err := inputsForRedeem[i%2].Serialize()
+					Expect(err).NotTo(HaveOccurred())
+					fakeldger.GetStateReturnsOnCall(i, raw, nil)
+				}
+				fakeldger.GetStateReturnsOnCall(4, nil, nil)
+
+				// the second output is the redeemed one
+				receipt = &api.BurnReceipt{
+					TransferIndex: 0,
+					OutputIndex:   1,
+					Type:          "ABC",
+					Quantity:      "35",
+					EnrollmentID:  "alice",
+					Reference:     []byte("settlement"),
+					TokenInfo:     rrm.Transfers[0].TokenInfo[1],
+				}
+			})
+			sign := func() []byte {
+				br = &api.TokenRequest{Transfers: rr.Transfers, BurnReceipts: []*api.BurnReceipt{receipt}}
+				var err error
+				br.AuditorSignature, err = auditor.Endorse(br, "1")
+				Expect(err).NotTo(HaveOccurred())
+				msg, err := br.MarshalToSign()
+				Expect(err).NotTo(HaveOccurred())
+				br.Signatures, err = redeemer.SignTokenActions(msg, "1")
+				Expect(err).NotTo(HaveOccurred())
+				raw, err := json.Marshal(br)
+				Expect(err).NotTo(HaveOccurred())
+				return raw
+			}
+			It("succeeds and returns the burn receipt", func() {
+				actions, err := engine.VerifyTokenRequestFromRaw(getState, "1", sign())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(actions)).To(Equal(2))
+				Expect(actions[1]).To(Equal(receipt))
+			})
+			It("fails when the quantity does not match", func() {
+				receipt.Quantity = "30"
+				_, err := engine.VerifyTokenRequestFromRaw(getState, "1", sign())
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("burn receipt does not match output [1] of transfer [0]"))
+				report, ok := api.GetValidationReport(err)
+				Expect(ok).To(BeTrue())
+				Expect(report.Failure().Type).To(Equal(api.BurnActionType))
+			})
+			It("fails when the output is not redeemed", func() {
+				receipt.OutputIndex = 0
+				_, err := engine.VerifyTokenRequestFromRaw(getState, "1", sign())
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("output [0] of transfer [0] is not redeemed"))
+			})
+			It("fails when the burn receipt is not signed", func() {
+				sign()
+				br.BurnReceipts[0].Reference = []byte("tampered")
+				raw, err := json.Marshal(br)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = engine.VerifyTokenRequestFromRaw(getState, "1", raw)
+				Expect(err).To(HaveOccurred())
+			})
+		})
 		Context("enginve is called correctly with atomic swap", func() {
 			var (
 				err error
@@ -288,6 +891,13 @@ var _ = Describe("validator", func() {
 				It("fails", func() {
 					_, err := engine.VerifyTokenRequestFromRaw(getState, "2", raw)
 					Expect(err.Error()).To(ContainSubstring("failed to verify issuers' signatures"))
+
+					report, ok := api.GetValidationReport(err)
+					Expect(ok).To(BeTrue())
+					Expect(report.Valid()).To(BeFalse())
+					Expect(report.Failure().Type).To(Equal(api.IssueActionType))
+					Expect(report.Failure().Index).To(Equal(0))
+					Expect(report.Failure().Check).To(Equal(api.SignatureCheck))
 				})
 			})
 			Context("when the sender's signature is not valid: wrong txID", func() {
@@ -308,12 +918,130 @@ var _ = Describe("validator", func() {
 					_, err := engine.VerifyTokenRequestFromRaw(getState, "2", raw)
 					Expect(err.Error()).To(ContainSubstring("pseudonym signature invalid"))
 
+					report, ok := api.GetValidationReport(err)
+					Expect(ok).To(BeTrue())
+					Expect(report.Failure().Type).To(Equal(api.TransferActionType))
+					Expect(report.Failure().Index).To(Equal(0))
+					Expect(report.Failure().Check).To(Equal(api.SignatureCheck))
+					Expect(report.Failure().Indices).To(Equal([]int{0}))
+					// the issue action has been validated before the transfer
+					Expect(report.Actions).To(ContainElement(&api.ActionResult{Type: api.IssueActionType, Index: 0, Valid: true}))
+
 				})
 			})
 		})
 	})
 })
 
+var _ = Describe("ownership proof", func() {
+	var (
+		engine *enginedlog.Validator
+		signer api2.SigningIdentity
+		proof  *api.OwnershipProof
+	)
+	BeforeEach(func() {
+		ipk, err := ioutil.ReadFile("./testdata/idemix/msp/IssuerPublicKey")
+		Expect(err).NotTo(HaveOccurred())
+		pp, err := crypto.Setup(100, 2, ipk)
+		Expect(err).NotTo(HaveOccurred())
+		engine = enginedlog.New(pp)
+
+		var id view.Identity
+		id, _, signer = getIdemixInfo("./testdata/idemix")
+		output, err := json.Marshal(&tokn.Token{Owner: id, Data: prepareToken(bn256.NewZrInt(10), bn256.NewZrInt(1), "ABC", pp.ZKATPedParams)})
+		Expect(err).NotTo(HaveOccurred())
+		proof = &api.OwnershipProof{ID: &token2.Id{TxId: "tx", Index: 0}, Output: output, Challenge: []byte("challenge")}
+		msg, err := proof.MessageToSign()
+		Expect(err).NotTo(HaveOccurred())
+		proof.Signature, err = signer.Sign(msg)
+		Expect(err).NotTo(HaveOccurred())
+	})
+	It("succeeds", func() {
+		Expect(engine.VerifyOwnership(proof)).To(Succeed())
+	})
+	It("fails when the challenge is replaced", func() {
+		proof.Challenge = []byte("another challenge")
+		err := engine.VerifyOwnership(proof)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("invalid ownership proof"))
+	})
+	It("fails when the token has been redeemed", func() {
+		output, err := json.Marshal(&tokn.Token{Data: bn256.G1Gen()})
+		Expect(err).NotTo(HaveOccurred())
+		proof.Output = output
+		err = engine.VerifyOwnership(proof)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("has been redeemed"))
+	})
+})
+
+var _ = Describe("owners under rotated idemix issuer keys", func() {
+	var (
+		pp        *crypto.PublicParams
+		newIPK    []byte
+		oldOwner  *api.OwnershipProof
+		newOwner  *api.OwnershipProof
+		verifyOld = func() error { return enginedlog.New(pp).VerifyOwnership(oldOwner) }
+		verifyNew = func() error { return enginedlog.New(pp).VerifyOwnership(newOwner) }
+	)
+	BeforeEach(func() {
+		ipk, err := ioutil.ReadFile("./testdata/idemix/msp/IssuerPublicKey")
+		Expect(err).NotTo(HaveOccurred())
+		newIPK, err = ioutil.ReadFile("./testdata/idemix2/msp/IssuerPublicKey")
+		Expect(err).NotTo(HaveOccurred())
+		pp, err = crypto.Setup(100, 2, ipk)
+		Expect(err).NotTo(HaveOccurred())
+		oldOwner = prepareOwnershipProof(pp, "./testdata/idemix")
+		newOwner = prepareOwnershipProof(pp, "./testdata/idemix2")
+	})
+	It("accepts only the owners under the current key before the rotation", func() {
+		Expect(verifyOld()).To(Succeed())
+		Expect(verifyNew()).NotTo(Succeed())
+	})
+	It("accepts the owners under the old key during the grace period", func() {
+		Expect(pp.RotateIdemixPK(newIPK, 2)).To(Succeed())
+		Expect(verifyOld()).To(Succeed())
+		Expect(verifyNew()).To(Succeed())
+		pp.AdvanceIdemixEpoch()
+		Expect(verifyOld()).To(Succeed())
+		Expect(verifyNew()).To(Succeed())
+	})
+	It("refuses the owners under the old key once expired", func() {
+		Expect(pp.RotateIdemixPK(newIPK, 1)).To(Succeed())
+		pp.AdvanceIdemixEpoch()
+		Expect(verifyOld()).NotTo(Succeed())
+		Expect(verifyNew()).To(Succeed())
+	})
+	It("accepts the owners under the old key forever without a grace period", func() {
+		Expect(pp.RotateIdemixPK(newIPK, 0)).To(Succeed())
+		for i := 0; i < 3; i++ {
+			pp.AdvanceIdemixEpoch()
+		}
+		Expect(verifyOld()).To(Succeed())
+		Expect(verifyNew()).To(Succeed())
+	})
+	It("refuses a signature valid under no accepted key", func() {
+		Expect(pp.RotateIdemixPK(newIPK, 1)).To(Succeed())
+		newOwner.Signature = oldOwner.Signature
+		err := verifyNew()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("invalid ownership proof"))
+	})
+})
+
+// prepareOwnershipProof returns the proof that the owner with the idemix credentials in the passed directory owns a token
+func prepareOwnershipProof(pp *crypto.PublicParams, dir string) *api.OwnershipProof {
+	id, _, signer := getIdemixInfo(dir)
+	output, err := json.Marshal(&tokn.Token{Owner: id, Data: prepareToken(bn256.NewZrInt(10), bn256.NewZrInt(1), "ABC", pp.ZKATPedParams)})
+	Expect(err).NotTo(HaveOccurred())
+	proof := &api.OwnershipProof{ID: &token2.Id{TxId: "tx", Index: 0}, Output: output, Challenge: []byte("challenge")}
+	msg, err := proof.MessageToSign()
+	Expect(err).NotTo(HaveOccurred())
+	proof.Signature, err = signer.Sign(msg)
+	Expect(err).NotTo(HaveOccurred())
+	return proof
+}
+
 func prepareECDSASigner() (*ecdsa.ECDSASigner, *ecdsa.ECDSAVerifier) {
 	signer, err := ecdsa.NewECDSASigner()
 	Expect(err).NotTo(HaveOccurred())
@@ -438,6 +1166,26 @@ func (f *fakeProv) TranslatePath(path string) string {
 	return ""
 }
 
+// swapNym returns the passed idemix identity with the pseudonym of the other one
+func swapNym(id, other view.Identity) view.Identity {
+	unmarshal := func(id view.Identity) (*m.SerializedIdentity, *m.SerializedIdemixIdentity) {
+		si := &m.SerializedIdentity{}
+		Expect(proto.Unmarshal(id, si)).To(Succeed())
+		serialized := &m.SerializedIdemixIdentity{}
+		Expect(proto.Unmarshal(si.IdBytes, serialized)).To(Succeed())
+		return si, serialized
+	}
+	si, serialized := unmarshal(id)
+	_, otherSerialized := unmarshal(other)
+	serialized.NymX, serialized.NymY = otherSerialized.NymX, otherSerialized.NymY
+	var err error
+	si.IdBytes, err = proto.Marshal(serialized)
+	Expect(err).NotTo(HaveOccurred())
+	raw, err := proto.Marshal(si)
+	Expect(err).NotTo(HaveOccurred())
+	return raw
+}
+
 func getIdemixInfo(dir string) (view.Identity, *idemix2.AuditInfo, api2.SigningIdentity) {
 	registry := registry2.New()
 	registry.RegisterService(&fakeProv{typ: "memory"})
@@ -607,6 +1355,22 @@ func prepareTransfer(pp *crypto.PublicParams, signer api2.SigningIdentity, audit
 	return sender, tr, transferMetadata, tokens
 }
 
+// proveAuditThreshold returns the proof that the total of the outputs of the passed transfer request is below the
+// audit threshold
+func proveAuditThreshold(pp *crypto.PublicParams, tr *api.TokenRequest, metadata *api.TokenRequestMetadata) []byte {
+	action := &transfer.TransferAction{}
+	Expect(action.Deserialize(tr.Transfers[0])).To(Succeed())
+	witness := make([]*tokn.TokenDataWitness, len(action.OutputTokens))
+	for i, raw := range metadata.Transfers[0].TokenInfo {
+		ti := &tokn.TokenInformation{}
+		Expect(ti.Deserialize(raw)).To(Succeed())
+		witness[i] = &tokn.TokenDataWitness{Type: ti.Type, Value: ti.Value, BlindingFactor: ti.BlindingFactor}
+	}
+	proof, err := transfer.NewThresholdProver(witness, action.GetOutputCommitments(), pp.AuditThreshold(), pp).Prove()
+	Expect(err).NotTo(HaveOccurred())
+	return proof
+}
+
 func getState(key string) ([]byte, error) {
 	return fakeldger.GetState(key)
 }
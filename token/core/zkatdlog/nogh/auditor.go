@@ -6,13 +6,19 @@ SPDX-License-Identifier: Apache-2.0
 package nogh
 
 import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	api3 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/audit"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/elgamal"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/pkg/errors"
 )
 
 func (s *service) AuditorCheck(tokenRequest *api3.TokenRequest, tokenRequestMetadata *api3.TokenRequestMetadata, txID string) error {
+	if s.PublicParams().AuditorIdentity() == nil {
+		// without an auditor, the request carries no audit infos to check
+		return nil
+	}
 	logger.Debugf("check token request validity...")
 	var inputTokens [][]*token.Token
 	for _, transfer := range tokenRequestMetadata.Transfers {
@@ -24,7 +30,10 @@ func (s *service) AuditorCheck(tokenRequest *api3.TokenRequest, tokenRequestMeta
 	}
 
 	pp := s.PublicParams()
-	if err := audit.NewAuditor(pp.ZKATPedParams, pp.IdemixPK, nil).Check(
+	auditor := audit.NewAuditor(pp.ZKATPedParams, pp.IdemixPK, nil)
+	auditor.DecryptionKey = s.auditorDecryptionKey
+	auditor.Scope = pp.AuditScope
+	if err := auditor.Check(
 		tokenRequest,
 		tokenRequestMetadata,
 		inputTokens,
@@ -34,3 +43,37 @@ func (s *service) AuditorCheck(tokenRequest *api3.TokenRequest, tokenRequestMeta
 	}
 	return nil
 }
+
+// SetAuditorDecryptionKey sets the secret key the auditor uses to open the audit infos
+// encrypted under the auditor's key declared in the public parameters
+func (s *service) SetAuditorDecryptionKey(sk *elgamal.SecretKey) {
+	s.auditorDecryptionKey = sk
+}
+
+// requestAuditInfo returns the audit info of the passed identity to be carried by the metadata of a request,
+// nil if the deployment has no auditor or the identities are out of the audit scope
+func (s *service) requestAuditInfo(id view.Identity) ([]byte, error) {
+	pp := s.PublicParams()
+	if pp.AuditorIdentity() == nil || !pp.AuditScope.Identities() {
+		return nil, nil
+	}
+	return s.identityProvider.GetAuditInfo(id)
+}
+
+// encryptAuditInfos encrypts the passed audit infos under the auditor's key, if the public parameters declare one.
+// Otherwise, it returns the passed audit infos.
+func (s *service) encryptAuditInfos(auditInfos [][]byte) ([][]byte, error) {
+	pk := s.PublicParams().AuditorEncryptionKey
+	if pk == nil {
+		return auditInfos, nil
+	}
+	res := make([][]byte, len(auditInfos))
+	for i, auditInfo := range auditInfos {
+		var err error
+		res[i], err = audit.EncryptAuditInfo(pk, auditInfo)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed encrypting audit info [%d]", i)
+		}
+	}
+	return res, nil
+}
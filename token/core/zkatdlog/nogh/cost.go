@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package nogh
+
+import (
+	"time"
+
+	api3 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+// Rough figures of the cost model of zkatdlog. The sizes are measured on the requests generated by the issuers
+// and the senders, see TestEstimatesMatchRequests.
+const (
+	// g1Size is the size of a serialized group element
+	g1Size = 32
+	// issuerIdentitySize is the size of the identity of a non-anonymous issuer, a serialized ECDSA public key
+	issuerIdentitySize = 184
+	// nymIdentitySize and nymSignatureSize are the sizes of an idemix pseudonym and of a signature under it,
+	// nymVerificationTime the time to verify such a signature
+	nymIdentitySize     = 1032
+	nymSignatureSize    = 136
+	nymVerificationTime = 10 * time.Millisecond
+	// auditInfoSize is the size of the audit info of an owner encrypted under the auditor's key
+	auditInfoSize = 4 * g1Size
+	// issueProofSize and transferProofSize are the sizes of the JSON encoded proofs without inputs and outputs,
+	// inputProofSize and outputProofSize what each input and output adds to the well-formedness proof
+	issueProofSize    = 504
+	transferProofSize = 672
+	inputProofSize    = 128
+	outputProofSize   = 364
+	// digitProofSize is the size of the JSON encoded proof that a digit of a value is in range, digitVerificationTime
+	// the time to verify it, dominated by the pairings checking the signature of the digit
+	digitProofSize        = 920
+	digitVerificationTime = 2 * time.Millisecond
+	// commitmentVerificationTime is the time to check the well-formedness of a commitment
+	commitmentVerificationTime = 500 * time.Microsecond
+)
+
+// EstimateRequest returns the size of the envelope of a request and the cost of the auditor's signature, if any
+func (s *service) EstimateRequest() *api3.CostEstimate {
+	estimate := &api3.CostEstimate{Size: api3.RequestOverhead}
+	if len(s.PublicParams().Auditor) != 0 {
+		estimate.Add(&api3.CostEstimate{
+			Size:             api3.Base64Size(api3.ECDSASignatureSize),
+			Signatures:       1,
+			VerificationTime: api3.ECDSAVerificationTime,
+		})
+	}
+	return estimate
+}
+
+// EstimateIssue returns the cost of a non-anonymous issue action: the well-formedness of the outputs
+// and their range proof, and the issuer's signature
+func (s *service) EstimateIssue(outputs int) *api3.CostEstimate {
+	action := api3.Base64Size(issuerIdentitySize) + outputs*s.outputSize() + api3.Base64Size(s.proofSize(0, outputs))
+	return &api3.CostEstimate{
+		Size:             api3.Base64Size(action) + api3.Base64Size(api3.ECDSASignatureSize),
+		Proofs:           2,
+		Signatures:       1,
+		VerificationTime: s.proofVerificationTime(0, outputs) + api3.ECDSAVerificationTime,
+		Outputs:          outputs,
+	}
+}
+
+// EstimateTransfer returns the cost of a transfer action: the well-formedness of the inputs and outputs,
+// the range proof of the outputs, and the signatures of the owners of the inputs
+func (s *service) EstimateTransfer(inputs, outputs int) *api3.CostEstimate {
+	action := inputs*(api3.InputKeySize+3+api3.Base64Size(g1Size)+3) + outputs*s.outputSize() + api3.Base64Size(s.proofSize(inputs, outputs))
+	if s.PublicParams().AuditorEncryptionKey != nil {
+		action += inputs * api3.Base64Size(auditInfoSize)
+	}
+	return &api3.CostEstimate{
+		Size:             api3.Base64Size(action) + inputs*api3.Base64Size(nymSignatureSize),
+		Proofs:           2,
+		Signatures:       inputs,
+		VerificationTime: s.proofVerificationTime(inputs, outputs) + time.Duration(inputs)*nymVerificationTime,
+		Inputs:           inputs,
+		Outputs:          outputs,
+	}
+}
+
+// outputSize is the size of an output: the owner and the commitment, JSON encoded, and its audit info, if any
+func (s *service) outputSize() int {
+	size := api3.Base64Size(nymIdentitySize) + api3.Base64Size(g1Size) + 32
+	if s.PublicParams().AuditorEncryptionKey != nil {
+		size += api3.Base64Size(auditInfoSize)
+	}
+	return size
+}
+
+// proofSize is the size of the well-formedness proof of the passed inputs and outputs and of the range proof
+// of the outputs, of an issue action if there are no inputs
+func (s *service) proofSize(inputs, outputs int) int {
+	size := issueProofSize
+	if inputs > 0 {
+		size = transferProofSize
+	}
+	exponent := s.PublicParams().RangeProofParams.Exponent
+	return size + inputs*inputProofSize + outputs*(outputProofSize+exponent*digitProofSize)
+}
+
+func (s *service) proofVerificationTime(inputs, outputs int) time.Duration {
+	exponent := s.PublicParams().RangeProofParams.Exponent
+	return time.Duration(inputs+outputs)*commitmentVerificationTime + time.Duration(outputs*exponent)*digitVerificationTime
+}
@@ -0,0 +1,125 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package nogh
+
+import (
+	"encoding/json"
+	"testing"
+
+	idemix2 "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/idemix"
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	api2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/api"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/core/sig"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	msp2 "github.com/hyperledger/fabric/msp"
+	"github.com/stretchr/testify/assert"
+
+	api3 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/ecdsa"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/nonanonym"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/transfer"
+)
+
+// costTolerance is the relative error accepted between the estimated and the actual size of a request
+const costTolerance = 0.05
+
+// idemixOwner returns an idemix identity of the testdata of the validator and its signer
+func idemixOwner(t *testing.T) (view.Identity, api2.SigningIdentity) {
+	sp := registry.New()
+	assert.NoError(t, sp.RegisterService(&configProvider{}))
+	kvss, err := kvs.New("memory", "", sp)
+	assert.NoError(t, err)
+	assert.NoError(t, sp.RegisterService(kvss))
+	assert.NoError(t, sp.RegisterService(sig.NewSignService(sp, nil)))
+
+	config, err := msp2.GetLocalMspConfigWithType("../crypto/validator/testdata/idemix", nil, "idemix", "idemix")
+	assert.NoError(t, err)
+	p, err := idemix2.NewProvider(config, sp)
+	assert.NoError(t, err)
+	id, _, err := p.Identity()
+	assert.NoError(t, err)
+	signer, err := p.DeserializeSigningIdentity(id)
+	assert.NoError(t, err)
+	return id, signer
+}
+
+// assertSize checks that the passed estimate is within the tolerance of the size of the passed request
+func assertSize(t *testing.T, estimate *api3.CostEstimate, tr *api3.TokenRequest) {
+	raw, err := json.Marshal(tr)
+	assert.NoError(t, err)
+	delta := float64(estimate.Size-len(raw)) / float64(len(raw))
+	assert.True(t, delta > -costTolerance && delta < costTolerance, "estimated [%d] bytes, actual [%d]", estimate.Size, len(raw))
+}
+
+func TestEstimatesMatchRequests(t *testing.T) {
+	owner, ownerSigner := idemixOwner(t)
+	for _, exponent := range []int{2, 3} {
+		estimatesMatchRequests(t, exponent, owner, ownerSigner)
+	}
+}
+
+// estimatesMatchRequests checks the estimates of an issue and of a transfer against the actual requests, for
+// range proofs of the passed number of digits
+func estimatesMatchRequests(t *testing.T, exponent int, owner view.Identity, ownerSigner api2.SigningIdentity) {
+	pp, err := crypto.Setup(100, exponent, nil)
+	assert.NoError(t, err)
+	s := &service{pp: pp}
+
+	// issue
+	issuerSigner, err := ecdsa.NewECDSASigner()
+	assert.NoError(t, err)
+	issuer := &nonanonym.Issuer{}
+	issuer.New("ABC", issuerSigner, pp)
+	issue, _, err := issuer.GenerateZKIssue([]uint64{40, 60}, [][]byte{owner, owner})
+	assert.NoError(t, err)
+	raw, err := issue.Serialize()
+	assert.NoError(t, err)
+	tr := &api3.TokenRequest{Issues: [][]byte{raw}}
+	sigma, err := issuer.SignTokenActions(raw, "tx")
+	assert.NoError(t, err)
+	tr.Signatures = append(tr.Signatures, sigma)
+
+	estimate := s.EstimateRequest().Add(s.EstimateIssue(2))
+	assertSize(t, estimate, tr)
+	assert.Equal(t, 1, estimate.Signatures)
+	assert.Equal(t, 2, estimate.Outputs)
+
+	// transfer
+	rand, err := bn256.GetRand()
+	assert.NoError(t, err)
+	values := []*bn256.Zr{bn256.NewZrInt(70), bn256.NewZrInt(30)}
+	tokens := make([]*token.Token, len(values))
+	infos := make([]*token.TokenInformation, len(values))
+	for i, value := range values {
+		bf := bn256.RandModOrder(rand)
+		data := pp.ZKATPedParams[0].Mul(bn256.HashModOrder([]byte("ABC")))
+		data.Add(pp.ZKATPedParams[1].Mul(value))
+		data.Add(pp.ZKATPedParams[2].Mul(bf))
+		tokens[i] = &token.Token{Owner: owner, Data: data}
+		infos[i] = &token.TokenInformation{Type: "ABC", Value: value, BlindingFactor: bf}
+	}
+	sender, err := transfer.NewSender([]view2.Signer{ownerSigner, ownerSigner}, tokens, []string{"0", "1"}, infos, pp)
+	assert.NoError(t, err)
+	action, _, err := sender.GenerateZKTransfer([]uint64{65, 35}, [][]byte{owner, owner})
+	assert.NoError(t, err)
+	raw, err = action.Serialize()
+	assert.NoError(t, err)
+	tr = &api3.TokenRequest{Transfers: [][]byte{raw}}
+	signatures, err := sender.SignTokenActions(raw, "tx")
+	assert.NoError(t, err)
+	tr.Signatures = append(tr.Signatures, signatures...)
+
+	estimate = s.EstimateRequest().Add(s.EstimateTransfer(2, 2))
+	assertSize(t, estimate, tr)
+	assert.Equal(t, 2, estimate.Signatures)
+	assert.Equal(t, 2, estimate.Inputs)
+	assert.Equal(t, 2, estimate.Outputs)
+}
@@ -6,14 +6,20 @@ SPDX-License-Identifier: Apache-2.0
 package driver
 
 import (
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+
 	fabric2 "github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/config"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/fabric"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/elgamal"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/ppm"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/validator"
 	zkatdlog "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh"
@@ -33,7 +39,23 @@ func (d *Driver) PublicParametersFromBytes(params []byte) (api.PublicParameters,
 
 func (d *Driver) NewTokenService(sp view2.ServiceProvider, publicParamsFetcher api.PublicParamsFetcher, network string, channel api.Channel, namespace string) (api.TokenManagerService, error) {
 	nodeIdentity := view2.GetIdentityProvider(sp).DefaultIdentity()
-	return zkatdlog.NewTokenService(
+	auditorDecryptionKey, err := loadAuditorDecryptionKey(sp, channel.Name(), namespace)
+	if err != nil {
+		return nil, err
+	}
+	pseudonymPolicies, err := loadPseudonymPolicies(sp, channel.Name(), namespace)
+	if err != nil {
+		return nil, err
+	}
+	transcript, allowUnverified, err := loadCeremonyPolicy(sp, channel.Name(), namespace)
+	if err != nil {
+		return nil, err
+	}
+	keyScheme, err := config.KeyScheme(sp, channel.Name(), namespace)
+	if err != nil {
+		return nil, err
+	}
+	service, err := zkatdlog.NewTokenService(
 		channel,
 		namespace,
 		sp,
@@ -49,6 +71,21 @@ func (d *Driver) NewTokenService(sp view2.ServiceProvider, publicParamsFetcher a
 			},
 		),
 	)
+	if err != nil {
+		return nil, err
+	}
+	if auditorDecryptionKey != nil {
+		service.SetAuditorDecryptionKey(auditorDecryptionKey)
+	}
+	for walletID, policy := range pseudonymPolicies {
+		service.SetPseudonymPolicy(walletID, policy)
+	}
+	service.SetKeyScheme(keyScheme)
+	service.SetCeremonyPolicy(transcript, allowUnverified)
+	if err := service.LoadPublicParams(); err != nil {
+		return nil, errors.WithMessagef(err, "failed loading public parameters")
+	}
+	return service, nil
 }
 
 func (d *Driver) NewValidator(params api.PublicParameters) (api.Validator, error) {
@@ -59,6 +96,85 @@ func (d *Driver) NewPublicParametersManager(params api.PublicParameters) (api.Pu
 	return ppm.New(params.(*crypto.PublicParams)), nil
 }
 
+// loadAuditorDecryptionKey loads the key the auditor uses to open the encrypted audit infos, if configured
+func loadAuditorDecryptionKey(sp view2.ServiceProvider, channel, namespace string) (*elgamal.SecretKey, error) {
+	var tmsConfigs []*config.TMS
+	if err := view2.GetConfigService(sp).UnmarshalKey("token.tms", &tmsConfigs); err != nil {
+		return nil, errors.WithMessagef(err, "cannot load token-sdk configuration")
+	}
+	for _, tms := range tmsConfigs {
+		if tms.Channel != channel || tms.Namespace != namespace {
+			continue
+		}
+		if tms.Auditor == nil || len(tms.Auditor.DecryptionKey) == 0 {
+			return nil, nil
+		}
+		raw, err := ioutil.ReadFile(view2.GetConfigService(sp).TranslatePath(tms.Auditor.DecryptionKey))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed reading auditor decryption key [%s]", tms.Auditor.DecryptionKey)
+		}
+		sk := &elgamal.SecretKey{}
+		if err := sk.Deserialize(raw); err != nil {
+			return nil, errors.WithMessagef(err, "failed loading auditor decryption key [%s]", tms.Auditor.DecryptionKey)
+		}
+		return sk, nil
+	}
+	return nil, nil
+}
+
+// loadCeremonyPolicy loads the transcript of the setup ceremony the public parameters are verified against,
+// if configured, and whether the public parameters that cannot be verified are accepted
+func loadCeremonyPolicy(sp view2.ServiceProvider, channel, namespace string) ([]byte, bool, error) {
+	var tmsConfigs []*config.TMS
+	if err := view2.GetConfigService(sp).UnmarshalKey("token.tms", &tmsConfigs); err != nil {
+		return nil, false, errors.WithMessagef(err, "cannot load token-sdk configuration")
+	}
+	for _, tms := range tmsConfigs {
+		if tms.Channel != channel || tms.Namespace != namespace {
+			continue
+		}
+		if tms.PublicParameters == nil {
+			return nil, false, nil
+		}
+		if len(tms.PublicParameters.CeremonyTranscript) == 0 {
+			return nil, tms.PublicParameters.AllowUnverified, nil
+		}
+		raw, err := ioutil.ReadFile(view2.GetConfigService(sp).TranslatePath(tms.PublicParameters.CeremonyTranscript))
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "failed reading ceremony transcript [%s]", tms.PublicParameters.CeremonyTranscript)
+		}
+		return raw, tms.PublicParameters.AllowUnverified, nil
+	}
+	return nil, false, nil
+}
+
+// loadPseudonymPolicies loads the pseudonym policies of the owner wallets, if configured
+func loadPseudonymPolicies(sp view2.ServiceProvider, channel, namespace string) (map[string]api.PseudonymPolicy, error) {
+	var tmsConfigs []*config.TMS
+	if err := view2.GetConfigService(sp).UnmarshalKey("token.tms", &tmsConfigs); err != nil {
+		return nil, errors.WithMessagef(err, "cannot load token-sdk configuration")
+	}
+	policies := map[string]api.PseudonymPolicy{}
+	for _, tms := range tmsConfigs {
+		if tms.Channel != channel || tms.Namespace != namespace || tms.Wallets == nil {
+			continue
+		}
+		for _, owner := range tms.Wallets.Owners {
+			switch owner.PseudonymPolicy {
+			case "", "fresh-per-transaction":
+				policies[owner.ID] = api.FreshPerTransaction
+			case "fresh-per-counterparty":
+				policies[owner.ID] = api.FreshPerCounterparty
+			case "sticky":
+				policies[owner.ID] = api.Sticky
+			default:
+				return nil, errors.Errorf("invalid pseudonym policy [%s] for owner wallet [%s]", owner.PseudonymPolicy, owner.ID)
+			}
+		}
+	}
+	return policies, nil
+}
+
 func init() {
 	core.Register(crypto.DLogPublicParameters, &Driver{})
 }
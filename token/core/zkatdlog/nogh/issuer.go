@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package nogh
 
 import (
+	"context"
+
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	api3 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/common"
@@ -14,12 +16,15 @@ import (
 	"github.com/pkg/errors"
 )
 
-func (s *service) Issue(issuerIdentity view.Identity, typ string, values []uint64, owners [][]byte) (api3.IssueAction, [][]byte, view.Identity, error) {
+func (s *service) Issue(issuerIdentity view.Identity, typ string, values []uint64, owners [][]byte, opts *api3.IssueOptions) (api3.IssueAction, [][]byte, view.Identity, error) {
 	for _, owner := range owners {
 		if len(owner) == 0 {
 			return nil, nil, nil, errors.Errorf("all recipients should be defined")
 		}
 	}
+	if opts != nil && !opts.Expiration.IsZero() {
+		return nil, nil, nil, errors.Errorf("token expiration is not supported by zkatdlog, only fabtoken records the expiration of the issued tokens")
+	}
 
 	signer, err := s.IssuerWalletByIdentity(issuerIdentity).GetSigner(issuerIdentity)
 	if err != nil {
@@ -37,6 +42,21 @@ func (s *service) Issue(issuerIdentity view.Identity, typ string, values []uint6
 		return nil, nil, nil, err
 	}
 
+	if pp := s.PublicParams(); pp.AuditorEncryptionKey != nil && pp.AuditScope.Identities() {
+		// only the auditor can open the audit infos, the action carries them for the validators to check
+		auditInfos := make([][]byte, len(owners))
+		for i, owner := range owners {
+			auditInfos[i], err = s.identityProvider.GetAuditInfo(owner)
+			if err != nil {
+				return nil, nil, nil, errors.WithMessagef(err, "failed getting audit info for recipient identity [%s]", view.Identity(owner).String())
+			}
+		}
+		issue.AuditInfos, err = s.encryptAuditInfos(auditInfos)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
 	//if err := s.registerIssuerSigner(issuer.Signer); err != nil {
 	//	return nil, nil, nil, errors.WithMessage(err, "failed registering zkat issuer")
 	//}
@@ -58,13 +78,13 @@ func (s *service) Issue(issuerIdentity view.Identity, typ string, values []uint6
 	return issue, infoRaws, fid, err
 }
 
-func (s *service) VerifyIssue(ia api3.IssueAction, tokenInfos [][]byte) error {
+func (s *service) VerifyIssue(ctx context.Context, ia api3.IssueAction, tokenInfos [][]byte) error {
 	action := ia.(*issue.IssueAction)
 
 	return issue.NewVerifier(
 		action.GetCommitments(),
 		action.IsAnonymous(),
-		s.PublicParams()).Verify(action.GetProof())
+		s.PublicParams()).VerifyWithContext(ctx, action.GetProof())
 }
 
 func (s *service) DeserializeIssueAction(raw []byte) (api3.IssueAction, error) {
@@ -0,0 +1,27 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package nogh
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	api3 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+func TestIssueRejectsExpiration(t *testing.T) {
+	s := &service{}
+	for _, expiration := range []time.Time{time.Now().Add(time.Hour), time.Now().Add(-time.Hour)} {
+		_, _, _, err := s.Issue([]byte("issuer"), "ABC", []uint64{10}, [][]byte{[]byte("alice")}, &api3.IssueOptions{Expiration: expiration})
+		assert.EqualError(t, err, "token expiration is not supported by zkatdlog, only fabtoken records the expiration of the issued tokens")
+	}
+
+	_, _, err := s.Reclaim("tx1", []byte("issuer"), []*token3.Id{{TxId: "tx0"}}, []byte("bob"))
+	assert.EqualError(t, err, "token expiration is not supported by zkatdlog, only fabtoken tokens can be reclaimed")
+}
@@ -0,0 +1,306 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package nogh
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	idemix2 "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/idemix"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+
+	api2 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/atrest"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// ExportTokens returns the openings and the audit information of the passed tokens.
+// The recipient identities owning the tokens move with them, then all their unspent tokens must be exported together.
+func (w *wallet) ExportTokens(ids []*token2.Id) (*api2.TokenExport, error) {
+	if len(ids) == 0 {
+		return nil, errors.Errorf("no tokens to export from wallet [%s]", w.ID())
+	}
+	unspent, err := w.ListTokens(&api2.ListTokensOptions{})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed listing tokens of wallet [%s]", w.ID())
+	}
+	byID := map[string]*token2.UnspentToken{}
+	for _, t := range unspent.Tokens {
+		byID[t.Id.String()] = t
+	}
+
+	export := &api2.TokenExport{Wallet: w.ID()}
+	exported := map[string]*api2.ExportedToken{}
+	owners := map[string]bool{}
+	for _, id := range ids {
+		if id == nil {
+			return nil, errors.Errorf("invalid token id, it is empty")
+		}
+		if _, ok := exported[id.String()]; ok {
+			continue
+		}
+		t, ok := byID[id.String()]
+		if !ok {
+			return nil, errors.Errorf("token [%s] is not an unspent token of wallet [%s]", id, w.ID())
+		}
+		owner := view.Identity(t.Owner.Raw)
+		if !w.CanSpend(owner) {
+			return nil, errors2.Errorf(errors2.Unauthorized, "token [%s] cannot be spent from this node, it cannot be exported", id)
+		}
+		et := &api2.ExportedToken{ID: id, Owner: &api2.RecipientIdentity{Identity: owner}}
+		exported[id.String()] = et
+		owners[owner.UniqueID()] = true
+		export.Tokens = append(export.Tokens, et)
+	}
+	for _, t := range unspent.Tokens {
+		if _, ok := exported[t.Id.String()]; !ok && owners[view.Identity(t.Owner.Raw).UniqueID()] {
+			return nil, errors.Errorf("token [%s] is owned by the recipient identity of an exported token, it must be exported too", t.Id)
+		}
+	}
+
+	tokenIDs := make([]*token2.Id, len(export.Tokens))
+	for i, et := range export.Tokens {
+		tokenIDs[i] = et.ID
+	}
+	if err := w.tokenService.qe.GetTokenCommitments(tokenIDs, func(id *token2.Id, raw []byte) error {
+		exported[id.String()].Token = raw
+		return nil
+	}); err != nil {
+		return nil, errors.WithMessagef(err, "failed loading tokens to export from wallet [%s]", w.ID())
+	}
+	if err := w.tokenService.qe.GetTokenInfos(tokenIDs, func(id *token2.Id, raw []byte) error {
+		exported[id.String()].Info = raw
+		return nil
+	}); err != nil {
+		return nil, errors.WithMessagef(err, "failed loading information of the tokens to export from wallet [%s]", w.ID())
+	}
+	for _, et := range export.Tokens {
+		if err := w.tokenService.VerifyTokenInfo(et.Token, et.Info); err != nil {
+			return nil, errors.WithMessagef(err, "invalid information of token [%s]", et.ID)
+		}
+		et.Owner.AuditInfo, err = w.GetAuditInfo(et.Owner.Identity)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed getting audit info of [%s]", et.Owner.Identity)
+		}
+	}
+	logger.Debugf("wallet [%s]: exported [%d] tokens", w.ID(), len(export.Tokens))
+	return export, nil
+}
+
+// ImportTokens checks that the exported tokens are still unspent tokens of the source wallet, that their openings
+// match the commitments on the ledger, and that the audit information binds their owners to the enrollment ID
+// of this wallet. Then, it records the reassignment, which moves to this wallet the recipient identities owning the tokens.
+// The audit information cannot be bound to another enrollment ID, the tokens of another enrollment ID cannot be imported.
+func (w *wallet) ImportTokens(export *api2.TokenExport) (*api2.TokenReassignment, error) {
+	if export == nil || len(export.Tokens) == 0 {
+		return nil, errors.Errorf("no tokens to import into wallet [%s]", w.ID())
+	}
+	w.tokenService.importLock.Lock()
+	defer w.tokenService.importLock.Unlock()
+	if export.Wallet == w.ID() {
+		return nil, errors.Errorf("tokens exported by wallet [%s] itself", w.ID())
+	}
+	source, ok := w.tokenService.OwnerWallet(export.Wallet).(*wallet)
+	if !ok || source == nil || source.ID() != export.Wallet {
+		return nil, errors.Errorf("source wallet [%s] not found", export.Wallet)
+	}
+	unspent, err := source.ListTokens(&api2.ListTokensOptions{})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed listing tokens of wallet [%s]", source.ID())
+	}
+	byID := map[string]*token2.UnspentToken{}
+	for _, t := range unspent.Tokens {
+		byID[t.Id.String()] = t
+	}
+
+	reassignment := &api2.TokenReassignment{From: source.ID(), To: w.ID()}
+	imported := map[string]*api2.ExportedToken{}
+	owners := map[string]bool{}
+	for _, et := range export.Tokens {
+		if et == nil || et.ID == nil || et.Owner == nil {
+			return nil, errors.Errorf("invalid exported token, it is empty")
+		}
+		if _, ok := imported[et.ID.String()]; ok {
+			return nil, errors.Errorf("token [%s] exported twice", et.ID)
+		}
+		t, ok := byID[et.ID.String()]
+		if !ok {
+			return nil, errors.Errorf("token [%s] is not an unspent token of wallet [%s]", et.ID, source.ID())
+		}
+		if !et.Owner.Identity.Equal(t.Owner.Raw) {
+			return nil, errors.Errorf("token [%s] is not owned by [%s]", et.ID, et.Owner.Identity)
+		}
+		if !source.CanSpend(et.Owner.Identity) {
+			return nil, errors2.Errorf(errors2.Unauthorized, "token [%s] cannot be spent from this node, it cannot be imported", et.ID)
+		}
+		ai := &idemix2.AuditInfo{}
+		if err := ai.FromBytes(et.Owner.AuditInfo); err != nil {
+			return nil, errors.Wrapf(err, "failed unmarshalling audit info of [%s]", et.Owner.Identity)
+		}
+		if err := ai.Match(et.Owner.Identity); err != nil {
+			return nil, errors.Wrapf(err, "audit info does not match identity [%s]", et.Owner.Identity)
+		}
+		// otherwise, the auditor would attribute the spends of this wallet to the enrollment ID of the source wallet
+		if len(ai.Attributes) < 3 || ai.EnrollmentID() != w.identityInfo.EnrollmentID {
+			return nil, errors2.Errorf(errors2.Unauthorized, "token [%s] is owned by an identity that does not belong to enrollment ID [%s]", et.ID, w.identityInfo.EnrollmentID)
+		}
+		imported[et.ID.String()] = et
+		reassignment.Tokens = append(reassignment.Tokens, et.ID)
+		if !owners[et.Owner.Identity.UniqueID()] {
+			owners[et.Owner.Identity.UniqueID()] = true
+			reassignment.Owners = append(reassignment.Owners, et.Owner.Identity)
+		}
+	}
+	for _, t := range unspent.Tokens {
+		if _, ok := imported[t.Id.String()]; !ok && owners[view.Identity(t.Owner.Raw).UniqueID()] {
+			return nil, errors.Errorf("token [%s] is owned by the recipient identity of an imported token, it must be imported too", t.Id)
+		}
+	}
+	if err := w.tokenService.qe.GetTokenCommitments(reassignment.Tokens, func(id *token2.Id, raw []byte) error {
+		if err := w.tokenService.VerifyTokenInfo(raw, imported[id.String()].Info); err != nil {
+			return errors.WithMessagef(err, "information of token [%s] does not open its commitment", id)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	reassignment.ID, err = newReassignmentID()
+	if err != nil {
+		return nil, err
+	}
+	reassignment.Timestamp = time.Now()
+	// the record of the reassignment moves the recipient identities, it is the only write, nothing is left halfway
+	if err := w.tokenService.putReassignment(reassignment); err != nil {
+		return nil, errors.WithMessagef(err, "failed storing reassignment [%s]", reassignment.ID)
+	}
+	logger.Infof("reassignment [%s]: tokens %v moved from wallet [%s] to wallet [%s]", reassignment.ID, reassignment.Tokens, reassignment.From, reassignment.To)
+	return reassignment, nil
+}
+
+func (w *wallet) TokenReassignments() ([]*api2.TokenReassignment, error) {
+	it, err := kvs.GetService(w.tokenService.sp).GetByPartialCompositeID(
+		"zkatdlog.owner.wallet.reassignment",
+		[]string{w.tokenService.channel.Name()},
+	)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed iterating over token reassignments")
+	}
+	defer it.Close()
+
+	var res []*api2.TokenReassignment
+	for it.HasNext() {
+		var raw json.RawMessage
+		if err := it.Next(&raw); err != nil {
+			return nil, errors.WithMessagef(err, "failed reading token reassignment")
+		}
+		r := &api2.TokenReassignment{}
+		k, err := atrest.DecodeEntry(atrest.GetSealer(w.tokenService.sp), raw, r)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed reading token reassignment")
+		}
+		if len(k) != 0 && k != kvs.CreateCompositeKeyOrPanic("zkatdlog.owner.wallet.reassignment", []string{w.tokenService.channel.Name(), r.ID}) {
+			return nil, errors.Errorf("token reassignment [%s] stored under another key", r.ID)
+		}
+		if r.From == w.ID() || r.To == w.ID() {
+			res = append(res, r)
+		}
+	}
+	return res, nil
+}
+
+// putReassignment stores the passed reassignment and moves its recipient identities to its destination wallet
+func (s *service) putReassignment(r *api2.TokenReassignment) error {
+	if err := s.loadReassignments(); err != nil {
+		return err
+	}
+	k := kvs.CreateCompositeKeyOrPanic(
+		"zkatdlog.owner.wallet.reassignment",
+		[]string{
+			s.channel.Name(),
+			r.ID,
+		},
+	)
+	if err := atrest.Put(s.sp, k, r); err != nil {
+		return err
+	}
+
+	s.reassignmentsLock.Lock()
+	defer s.reassignmentsLock.Unlock()
+	for _, owner := range r.Owners {
+		s.reassignedIdentities[string(owner)] = r.To
+	}
+	return nil
+}
+
+// reassignedRecipientIdentityWallet returns the id of the owner wallet the passed identity has been reassigned to, if any.
+// The reassignments are read from the kvs once, then they are kept in memory.
+func (s *service) reassignedRecipientIdentityWallet(id view.Identity) string {
+	if err := s.loadReassignments(); err != nil {
+		logger.Errorf("failed loading token reassignments: [%s]", err)
+		return ""
+	}
+	s.reassignmentsLock.Lock()
+	defer s.reassignmentsLock.Unlock()
+	return s.reassignedIdentities[string(id)]
+}
+
+// loadReassignments reads the reassignments from the kvs, if not done yet, and applies them in the order they happened
+func (s *service) loadReassignments() error {
+	s.reassignmentsLock.Lock()
+	defer s.reassignmentsLock.Unlock()
+	if s.reassignedIdentities != nil {
+		return nil
+	}
+
+	it, err := kvs.GetService(s.sp).GetByPartialCompositeID(
+		"zkatdlog.owner.wallet.reassignment",
+		[]string{s.channel.Name()},
+	)
+	if err != nil {
+		return errors.WithMessagef(err, "failed iterating over token reassignments")
+	}
+	defer it.Close()
+
+	var reassignments []*api2.TokenReassignment
+	for it.HasNext() {
+		var raw json.RawMessage
+		if err := it.Next(&raw); err != nil {
+			return errors.WithMessagef(err, "failed reading token reassignment")
+		}
+		r := &api2.TokenReassignment{}
+		if _, err := atrest.DecodeEntry(atrest.GetSealer(s.sp), raw, r); err != nil {
+			return errors.WithMessagef(err, "failed reading token reassignment")
+		}
+		reassignments = append(reassignments, r)
+	}
+	sort.SliceStable(reassignments, func(i, j int) bool {
+		return reassignments[i].Timestamp.Before(reassignments[j].Timestamp)
+	})
+	reassigned := map[string]string{}
+	for _, r := range reassignments {
+		for _, owner := range r.Owners {
+			reassigned[string(owner)] = r.To
+		}
+	}
+	s.reassignedIdentities = reassigned
+	return nil
+}
+
+func newReassignmentID() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Wrap(err, "failed generating reassignment id")
+	}
+	return hex.EncodeToString(nonce), nil
+}
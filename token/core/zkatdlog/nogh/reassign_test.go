@@ -0,0 +1,214 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package nogh
+
+import (
+	"testing"
+
+	idemix2 "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/idemix"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/core/sig"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	msp2 "github.com/hyperledger/fabric/msp"
+	"github.com/stretchr/testify/assert"
+
+	api3 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// vaultQueryEngine lists its tokens as unspent and returns their commitments and openings
+type vaultQueryEngine struct {
+	QueryEngine
+	tokens      []*token3.UnspentToken
+	commitments map[string][]byte
+	infos       map[string][]byte
+}
+
+func (q *vaultQueryEngine) ListUnspentTokens() (*token3.UnspentTokens, error) {
+	return &token3.UnspentTokens{Tokens: q.tokens}, nil
+}
+
+func (q *vaultQueryEngine) GetTokenCommitments(ids []*token3.Id, callback api3.QueryCallbackFunc) error {
+	for _, id := range ids {
+		if err := callback(id, q.commitments[id.String()]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *vaultQueryEngine) GetTokenInfos(ids []*token3.Id, callback api3.QueryCallbackFunc) error {
+	for _, id := range ids {
+		if err := callback(id, q.infos[id.String()]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add adds to the vault a token of the passed owner, with a valid opening
+func (q *vaultQueryEngine) add(t *testing.T, pp *crypto.PublicParams, txID string, owner view.Identity, value uint64) {
+	rand, err := bn256.GetRand()
+	assert.NoError(t, err)
+	bf := bn256.RandModOrder(rand)
+	v := bn256.NewZrInt(int(value))
+	data := pp.ZKATPedParams[0].Mul(bn256.HashModOrder([]byte("ABC")))
+	data.Add(pp.ZKATPedParams[1].Mul(v))
+	data.Add(pp.ZKATPedParams[2].Mul(bf))
+	raw, err := (&token.Token{Owner: owner, Data: data}).Serialize()
+	assert.NoError(t, err)
+	info, err := (&token.TokenInformation{Type: "ABC", Value: v, BlindingFactor: bf}).Serialize()
+	assert.NoError(t, err)
+
+	id := &token3.Id{TxId: txID}
+	q.tokens = append(q.tokens, &token3.UnspentToken{Id: id, Owner: &token3.Owner{Raw: owner}, Type: "ABC", Quantity: "0x0a"})
+	q.commitments[id.String()] = raw
+	q.infos[id.String()] = info
+}
+
+// walletsIdentityProvider resolves the wallets by id and returns the audit infos of the identities it knows
+type walletsIdentityProvider struct {
+	api3.IdentityProvider
+	auditInfos map[string][]byte
+}
+
+func (p *walletsIdentityProvider) LookupIdentifier(usage api3.IdentityUsage, v interface{}) (view.Identity, string) {
+	switch id := v.(type) {
+	case string:
+		return nil, id
+	case view.Identity:
+		return id, ""
+	}
+	return nil, ""
+}
+
+func (p *walletsIdentityProvider) GetAuditInfo(id view.Identity) ([]byte, error) {
+	return p.auditInfos[string(id)], nil
+}
+
+func TestExportImportTokens(t *testing.T) {
+	sp := registry.New()
+	assert.NoError(t, sp.RegisterService(&configProvider{}))
+	kvss, err := kvs.New("memory", "", sp)
+	assert.NoError(t, err)
+	assert.NoError(t, sp.RegisterService(kvss))
+	assert.NoError(t, sp.RegisterService(sig.NewSignService(sp, nil)))
+
+	// two pseudonyms of the same enrollment ID
+	config, err := msp2.GetLocalMspConfigWithType("../crypto/validator/testdata/idemix", nil, "idemix", "idemix")
+	assert.NoError(t, err)
+	p, err := idemix2.NewProvider(config, sp)
+	assert.NoError(t, err)
+	ip := &walletsIdentityProvider{auditInfos: map[string][]byte{}}
+	pseudonym := func() view.Identity {
+		id, auditInfo, err := p.Identity()
+		assert.NoError(t, err)
+		ip.auditInfos[string(id)] = auditInfo
+		return id
+	}
+	owner1, owner2 := pseudonym(), pseudonym()
+	ai := &idemix2.AuditInfo{}
+	assert.NoError(t, ai.FromBytes(ip.auditInfos[string(owner1)]))
+	eID := ai.EnrollmentID()
+
+	pp, err := crypto.Setup(100, 2, nil)
+	assert.NoError(t, err)
+	qe := &vaultQueryEngine{commitments: map[string][]byte{}, infos: map[string][]byte{}}
+	qe.add(t, pp, "tx1", owner1, 10)
+	qe.add(t, pp, "tx2", owner1, 20)
+	qe.add(t, pp, "tx3", owner2, 30)
+	s := &service{channel: channel{}, sp: sp, pp: pp, identityProvider: ip, qe: qe}
+	alice := newOwnerWallet(s, "alice", &api3.IdentityInfo{ID: "alice", EnrollmentID: eID})
+	bob := newOwnerWallet(s, "bob", &api3.IdentityInfo{ID: "bob", EnrollmentID: eID})
+	charlie := newOwnerWallet(s, "charlie", &api3.IdentityInfo{ID: "charlie", EnrollmentID: "charlie"})
+	s.ownerWallets = []*wallet{alice, bob, charlie}
+	assert.NoError(t, alice.putRecipientIdentity(owner1))
+	assert.NoError(t, alice.putRecipientIdentity(owner2))
+	tx1, tx2, tx3 := qe.tokens[0].Id, qe.tokens[1].Id, qe.tokens[2].Id
+
+	listed := func(w *wallet) []*token3.Id {
+		tokens, err := w.ListTokens(&api3.ListTokensOptions{})
+		assert.NoError(t, err)
+		var ids []*token3.Id
+		for _, t := range tokens.Tokens {
+			ids = append(ids, t.Id)
+		}
+		return ids
+	}
+
+	// the tokens of a recipient identity move together
+	_, err = alice.ExportTokens([]*token3.Id{tx1})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "must be exported too")
+
+	export, err := alice.ExportTokens([]*token3.Id{tx1, tx2})
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", export.Wallet)
+	assert.Len(t, export.Tokens, 2)
+
+	// the tokens cannot be imported by a wallet of another enrollment ID
+	_, err = charlie.ImportTokens(export)
+	assert.Error(t, err)
+	assert.True(t, errors2.HasCode(err, errors2.Unauthorized))
+	assert.Equal(t, []*token3.Id{tx1, tx2, tx3}, listed(alice))
+
+	// an opening that does not match the commitment is refused
+	tampered := *export
+	tampered.Tokens = []*api3.ExportedToken{export.Tokens[0], {ID: export.Tokens[1].ID, Token: export.Tokens[1].Token, Info: export.Tokens[0].Info, Owner: export.Tokens[1].Owner}}
+	_, err = bob.ImportTokens(&tampered)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not open its commitment")
+
+	reassignment, err := bob.ImportTokens(export)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", reassignment.From)
+	assert.Equal(t, "bob", reassignment.To)
+	assert.Equal(t, []*token3.Id{tx1, tx2}, reassignment.Tokens)
+	assert.Equal(t, []view.Identity{owner1}, reassignment.Owners)
+
+	// the recipient identity belongs to the destination wallet now
+	assert.False(t, alice.Contains(owner1))
+	assert.True(t, bob.Contains(owner1))
+	assert.True(t, bob.CanSpend(owner1))
+	assert.True(t, alice.Contains(owner2))
+	assert.Equal(t, "bob", s.OwnerWalletByIdentity(owner1).ID())
+	assert.Equal(t, []*token3.Id{tx3}, listed(alice))
+	assert.Equal(t, []*token3.Id{tx1, tx2}, listed(bob))
+
+	// the tokens cannot be imported twice
+	_, err = charlie.ImportTokens(export)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is not an unspent token of wallet [alice]")
+
+	// the reassignment is recorded for both wallets
+	for _, w := range []*wallet{alice, bob} {
+		reassignments, err := w.TokenReassignments()
+		assert.NoError(t, err)
+		assert.Len(t, reassignments, 1)
+		assert.Equal(t, reassignment.ID, reassignments[0].ID)
+	}
+	reassignments, err := charlie.TokenReassignments()
+	assert.NoError(t, err)
+	assert.Empty(t, reassignments)
+
+	// the reassignments survive a restart
+	s2 := &service{channel: channel{}, sp: sp, pp: pp, identityProvider: ip, qe: qe}
+	assert.False(t, newOwnerWallet(s2, "alice", alice.identityInfo).Contains(owner1))
+	assert.True(t, newOwnerWallet(s2, "bob", bob.identityInfo).Contains(owner1))
+
+	// and the tokens can move back
+	export, err = bob.ExportTokens([]*token3.Id{tx1, tx2})
+	assert.NoError(t, err)
+	_, err = alice.ImportTokens(export)
+	assert.NoError(t, err)
+	assert.Equal(t, []*token3.Id{tx1, tx2, tx3}, listed(alice))
+	assert.Empty(t, listed(bob))
+}
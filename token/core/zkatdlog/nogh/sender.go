@@ -6,6 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package nogh
 
 import (
+	"context"
 	"strconv"
 
 	"github.com/pkg/errors"
@@ -23,6 +24,11 @@ import (
 
 func (s *service) Transfer(txID string, wallet api3.OwnerWallet, ids []*token3.Id, outputTokens ...*token3.Token) (api3.TransferAction, *api3.TransferMetadata, error) {
 	logger.Debugf("Prepare Transfer Action [%s,%v]", txID, ids)
+	for _, output := range outputTokens {
+		if output.Owner != nil && api3.IsTimeLock(output.Owner.Raw) {
+			return nil, nil, errors.New("time locked outputs are not supported by zkatdlog")
+		}
+	}
 
 	var tokens []*token.Token
 	var inputIDs []string
@@ -54,7 +60,7 @@ func (s *service) Transfer(txID string, wallet api3.OwnerWallet, ids []*token3.I
 		}
 
 		// Token and InputID
-		outputID, err = keys.CreateTokenKey(id.TxId, int(id.Index))
+		outputID, err = s.keys.CreateTokenKey(id.TxId, int(id.Index))
 		if err != nil {
 			return nil, nil, errors.Wrapf(err, "error creating output ID: %v", id)
 		}
@@ -136,7 +142,7 @@ func (s *service) Transfer(txID string, wallet api3.OwnerWallet, ids []*token3.I
 
 	var receiverAuditInfos [][]byte
 	for _, output := range outputTokens {
-		auditInfo, err := view2.GetSigService(s.sp).GetAuditInfo(output.Owner.Raw)
+		auditInfo, err := s.requestAuditInfo(output.Owner.Raw)
 		if err != nil {
 			return nil, nil, errors.Wrapf(err, "failed getting audit info for recipient identity [%s]", view.Identity(output.Owner.Raw).String())
 		}
@@ -145,13 +151,25 @@ func (s *service) Transfer(txID string, wallet api3.OwnerWallet, ids []*token3.I
 
 	var senderAuditInfos [][]byte
 	for _, t := range tokens {
-		auditInfo, err := view2.GetSigService(s.sp).GetAuditInfo(t.Owner)
+		auditInfo, err := s.requestAuditInfo(t.Owner)
 		if err != nil {
 			return nil, nil, errors.Wrapf(err, "failed getting audit info for sender identity [%s]", view.Identity(t.Owner).String())
 		}
 		senderAuditInfos = append(senderAuditInfos, auditInfo)
 	}
 
+	if pp := s.PublicParams(); pp.AuditorEncryptionKey != nil && pp.AuditScope.Identities() {
+		// only the auditor can open the audit infos, the action carries them for the validators to check
+		if receiverAuditInfos, err = s.encryptAuditInfos(receiverAuditInfos); err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed encrypting receiver audit infos for txid [%s]", txID)
+		}
+		if senderAuditInfos, err = s.encryptAuditInfos(senderAuditInfos); err != nil {
+			return nil, nil, errors.WithMessagef(err, "failed encrypting sender audit infos for txid [%s]", txID)
+		}
+		transfer.ReceiverAuditInfos = receiverAuditInfos
+		transfer.SenderAuditInfos = senderAuditInfos
+	}
+
 	outputs, err := transfer.GetSerializedOutputs()
 	if err != nil {
 		return nil, nil, errors.Wrapf(err, "failed getting serialized outputs")
@@ -176,7 +194,11 @@ func (s *service) Transfer(txID string, wallet api3.OwnerWallet, ids []*token3.I
 	return transfer, metadata, nil
 }
 
-func (s *service) VerifyTransfer(action api3.TransferAction, tokenInfos [][]byte) error {
+func (s *service) Reclaim(txID string, issuer view.Identity, ids []*token3.Id, receiver view.Identity) (api3.TransferAction, *api3.TransferMetadata, error) {
+	return nil, nil, errors.Errorf("token expiration is not supported by zkatdlog, only fabtoken tokens can be reclaimed")
+}
+
+func (s *service) VerifyTransfer(ctx context.Context, action api3.TransferAction, tokenInfos [][]byte) error {
 	tr, ok := action.(*transfer.TransferAction)
 	if !ok {
 		return errors.Errorf("expected *zkatdlog.Transfer")
@@ -199,7 +221,38 @@ func (s *service) VerifyTransfer(action api3.TransferAction, tokenInfos [][]byte
 		}
 		logger.Debugf("transfer output [%s,%s,%s]", tok.Type, tok.Quantity, view.Identity(tok.Owner.Raw))
 	}
-	return transfer.NewVerifier(tr.InputCommitments, com, pp).Verify(tr.Proof)
+	return transfer.NewVerifier(tr.InputCommitments, com, pp).VerifyWithContext(ctx, tr.Proof)
+}
+
+// ProveAuditThreshold proves that the total of the outputs of the passed transfers, change included, is below
+// the audit threshold, see api.AuditThresholds
+func (s *service) ProveAuditThreshold(transfers []api3.TransferAction, metadata []api3.TransferMetadata) ([]byte, error) {
+	if len(transfers) != len(metadata) {
+		return nil, errors.Errorf("[%d] transfers with [%d] metadata", len(transfers), len(metadata))
+	}
+	pp := s.PublicParams()
+	var outputs []*bn256.G1
+	var witness []*token.TokenDataWitness
+	for i, action := range transfers {
+		tr, ok := action.(*transfer.TransferAction)
+		if !ok {
+			return nil, errors.Errorf("expected *zkatdlog.Transfer")
+		}
+		if len(metadata[i].TokenInfo) != len(tr.OutputTokens) {
+			return nil, errors.Errorf("transfer [%d] has [%d] outputs and [%d] token infos", i, len(tr.OutputTokens), len(metadata[i].TokenInfo))
+		}
+		for j, output := range tr.OutputTokens {
+			ti := &token.TokenInformation{}
+			if err := ti.Deserialize(metadata[i].TokenInfo[j]); err != nil {
+				return nil, errors.Wrapf(err, "failed unmarshalling token information of output [%d] of transfer [%d]", j, i)
+			}
+			outputs = append(outputs, output.Data)
+			witness = append(witness, &token.TokenDataWitness{Type: ti.Type, Value: ti.Value, BlindingFactor: ti.BlindingFactor})
+		}
+	}
+	prover := transfer.NewThresholdProver(witness, outputs, pp.AuditThreshold(), pp)
+	defer prover.Zeroize()
+	return prover.Prove()
 }
 
 func (s *service) DeserializeTransferAction(raw []byte) (api3.TransferAction, error) {
@@ -6,6 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package nogh
 
 import (
+	"bytes"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -16,7 +17,10 @@ import (
 	api3 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/elgamal"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/ppm"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
+	rangeproof "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/range"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/validator"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
@@ -41,6 +45,8 @@ type QueryEngine interface {
 	ListUnspentTokens() (*token3.UnspentTokens, error)
 	ListAuditTokens(ids ...*token3.Id) ([]*token3.Token, error)
 	ListHistoryIssuedTokens() (*token3.IssuedTokens, error)
+	GetTokenInfos(ids []*token3.Id, callback api3.QueryCallbackFunc) error
+	GetTokenCommitments(ids []*token3.Id, callback api3.QueryCallbackFunc) error
 }
 
 type service struct {
@@ -58,6 +64,8 @@ type service struct {
 		sk    *bn256.Zr
 		pk    *bn256.G1
 		fID   view.Identity
+		// credential authorizes the issuer when the public parameters set an issuer accumulator
+		credential *pssign.Signature
 	}
 
 	identityProvider api3.IdentityProvider
@@ -65,6 +73,28 @@ type service struct {
 	issuerWallets    []*issuerWallet
 	auditorWallets   []*auditorWallet
 	walletsLock      sync.Mutex
+
+	// auditorDecryptionKey opens the audit infos encrypted under the auditor's key, it is set only at the auditor
+	auditorDecryptionKey *elgamal.SecretKey
+
+	// pseudonymPolicies maps owner wallet ids to the policy they follow to derive pseudonyms
+	pseudonymPolicies map[string]api3.PseudonymPolicy
+
+	// keys derives the ledger keys of the tokens and the public parameters
+	keys *keys.Scheme
+
+	// ceremonyTranscript, if set, is the transcript of the setup ceremony the public parameters are verified against.
+	// Without it, the public parameters are accepted with a warning.
+	// If the verification fails, the public parameters are refused unless allowUnverifiedParams is set.
+	ceremonyTranscript    []byte
+	allowUnverifiedParams bool
+
+	// reassignedIdentities maps the recipient identities moved by ImportTokens to the id of their owner wallet,
+	// it is loaded from the records of the reassignments at first use
+	reassignedIdentities map[string]string
+	reassignmentsLock    sync.Mutex
+	// importLock serializes the imports of tokens, each checks the tokens against the current reassignments
+	importLock sync.Mutex
 }
 
 func NewTokenService(
@@ -84,10 +114,42 @@ func NewTokenService(
 		tokenCommitmentLoader: tokenCommitmentLoader,
 		qe:                    queryEngine,
 		identityProvider:      identityProvider,
+		pseudonymPolicies:     map[string]api3.PseudonymPolicy{},
+		keys:                  keys.Default,
 	}
 	return s, nil
 }
 
+// SetKeyScheme sets the scheme the ledger keys of the tokens and the public parameters are derived with
+func (s *service) SetKeyScheme(scheme *keys.Scheme) {
+	s.keys = scheme
+}
+
+// SetCeremonyPolicy sets the transcript of the setup ceremony the public parameters must have been generated by,
+// and whether the public parameters that cannot be verified are accepted anyway
+func (s *service) SetCeremonyPolicy(transcript []byte, allowUnverified bool) {
+	s.ceremonyTranscript = transcript
+	s.allowUnverifiedParams = allowUnverified
+}
+
+// checkCeremony checks the passed public parameters against the transcript of the setup ceremony, if configured.
+// Without a transcript, the public parameters are accepted with a warning.
+func (s *service) checkCeremony(pp *crypto.PublicParams) error {
+	if len(s.ceremonyTranscript) == 0 {
+		logger.Warnf("no ceremony transcript configured, the public parameters are not verified")
+		return nil
+	}
+	err := pp.VerifyCeremony(s.ceremonyTranscript)
+	if err == nil {
+		return nil
+	}
+	if !s.allowUnverifiedParams {
+		return errors.WithMessagef(err, "refusing unverifiable public parameters")
+	}
+	logger.Warnf("accepting unverifiable public parameters: [%s]", err)
+	return nil
+}
+
 func (s *service) DeserializeToken(tok []byte, infoRaw []byte) (*token3.Token, view.Identity, error) {
 	output := &token.Token{}
 	if err := output.Deserialize(tok); err != nil {
@@ -108,6 +170,18 @@ func (s *service) DeserializeToken(tok []byte, infoRaw []byte) (*token3.Token, v
 	return to, ti.Issuer, nil
 }
 
+func (s *service) VerifyTokenInfo(tok []byte, infoRaw []byte) error {
+	output := &token.Token{}
+	if err := output.Deserialize(tok); err != nil {
+		return errors.Wrap(err, "failed unmarshalling token")
+	}
+	ti := &token.TokenInformation{}
+	if err := ti.Deserialize(infoRaw); err != nil {
+		return errors.Wrap(err, "failed unmarshalling token information")
+	}
+	return output.VerifyOpening(ti.Type, ti.Value, ti.BlindingFactor, s.PublicParams())
+}
+
 func (s *service) IdentityProvider() api3.IdentityProvider {
 	return s.identityProvider
 }
@@ -117,56 +191,70 @@ func (s *service) Validator() api3.Validator {
 }
 
 func (s *service) PublicParamsManager() api3.PublicParamsManager {
-	return ppm.New(s.PublicParams())
+	return ppm.New(s.PublicParams()).WithFetch(s.FetchPublicParams)
 }
 
+// PublicParams returns the public parameters, loading them if needed, see LoadPublicParams.
+// It returns nil if they cannot be loaded.
 func (s *service) PublicParams() *crypto.PublicParams {
 	if s.pp == nil {
-		// load
-		qe, err := s.channel.Vault().NewQueryExecutor()
-		if err != nil {
-			panic(err)
+		if err := s.LoadPublicParams(); err != nil {
+			logger.Errorf("failed loading public params [%s]", err)
+			return nil
 		}
-		defer qe.Done()
+	}
+	return s.pp
+}
 
-		setupKey, err := keys.CreateSetupKey()
-		if err != nil {
-			panic(err)
-		}
-		logger.Debugf("get public parameters with key [%s]", setupKey)
-		raw, err := qe.GetState(s.namespace, setupKey)
-		if err != nil {
-			panic(err)
-		}
-		if len(raw) == 0 {
-			logger.Warnf("public parameters with key [%s] not found, fetch them", setupKey)
-			raw, err = s.publicParamsFetcher.Fetch()
-			if err != nil {
-				logger.Errorf("failed retrieving public params [%s]", err)
-				return nil
-			}
-		}
+// LoadPublicParams loads the public parameters from the vault, or fetches them if not there yet,
+// and checks them before using them
+func (s *service) LoadPublicParams() error {
+	qe, err := s.channel.Vault().NewQueryExecutor()
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting query executor")
+	}
+	defer qe.Done()
 
-		logger.Debugf("unmarshal public parameters with key [%s], len [%d]", setupKey, len(raw))
-		s.pp = &crypto.PublicParams{}
-		err = s.pp.Deserialize(raw)
+	setupKey, err := s.keys.CreateSetupKey()
+	if err != nil {
+		return errors.WithMessagef(err, "failed creating setup key")
+	}
+	logger.Debugf("get public parameters with key [%s]", setupKey)
+	raw, err := qe.GetState(s.namespace, setupKey)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting public params with key [%s]", setupKey)
+	}
+	if len(raw) == 0 {
+		logger.Warnf("public parameters with key [%s] not found, fetch them", setupKey)
+		raw, err = s.publicParamsFetcher.Fetch()
 		if err != nil {
-			panic(err)
+			return errors.WithMessagef(err, "failed retrieving public params")
 		}
-		logger.Debugf("unmarshal public parameters with key [%s] done", setupKey)
 	}
 
-	ip, err := s.pp.GetIssuingPolicy()
+	logger.Debugf("unmarshal public parameters with key [%s], len [%d]", setupKey, len(raw))
+	pp := &crypto.PublicParams{}
+	if err := pp.Deserialize(raw); err != nil {
+		return errors.Wrapf(err, "failed deserializing public params")
+	}
+	if err := pp.SelfCheck(); err != nil {
+		return errors.WithMessagef(err, "refusing public params")
+	}
+	if err := s.checkCeremony(pp); err != nil {
+		return err
+	}
+	ip, err := pp.GetIssuingPolicy()
 	if err != nil {
-		panic(err)
+		return errors.Wrapf(err, "failed deserializing issuing policy")
 	}
-	logger.Debugf("returning public parameters [%d,%d,%d,%d]", len(s.pp.ZKATPedParams), len(ip.Issuers), ip.IssuersNumber, ip.BitLength)
-
-	return s.pp
+	logger.Debugf("unmarshal public parameters with key [%s] done [%d,%d,%d,%d]", setupKey, len(pp.ZKATPedParams), len(ip.Issuers), ip.IssuersNumber, ip.BitLength)
+	s.pp = pp
+	return nil
 }
 
+// FetchPublicParams fetches the public parameters again, bypassing the cache of the fetcher, if any
 func (s *service) FetchPublicParams() error {
-	raw, err := s.publicParamsFetcher.Fetch()
+	raw, err := api3.ForceFetch(s.publicParamsFetcher)
 	if err != nil {
 		return errors.WithMessagef(err, "failed fetching public params from fabric")
 	}
@@ -176,13 +264,33 @@ func (s *service) FetchPublicParams() error {
 	if err != nil {
 		return errors.Wrapf(err, "failed deserializing public params")
 	}
+	if err := pp.SelfCheck(); err != nil {
+		return errors.WithMessagef(err, "refusing public params")
+	}
 
 	ip, err := pp.GetIssuingPolicy()
 	if err != nil {
 		return errors.Wrapf(err, "failed deserializing issuing policy")
 	}
 	logger.Debugf("fetching public parameters done, issue policy [%d,%d,%d]", len(ip.Issuers), ip.IssuersNumber, ip.BitLength)
+	if err := s.checkCeremony(pp); err != nil {
+		return err
+	}
 
+	// the precomputed range proof material of the old public parameters is no longer needed
+	if s.pp != nil {
+		oldHash, err := s.pp.Hash()
+		if err != nil {
+			return errors.Wrapf(err, "failed hashing old public params")
+		}
+		newHash, err := pp.Hash()
+		if err != nil {
+			return errors.Wrapf(err, "failed hashing public params")
+		}
+		if !bytes.Equal(oldHash, newHash) {
+			rangeproof.InvalidateDigitTable(oldHash)
+		}
+	}
 	s.pp = pp
 	return nil
 }
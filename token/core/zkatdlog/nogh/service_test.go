@@ -0,0 +1,44 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package nogh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/ecdsa"
+)
+
+func TestCheckCeremony(t *testing.T) {
+	pp, err := crypto.Setup(16, 2, nil)
+	assert.NoError(t, err)
+
+	// without a transcript, the public parameters are accepted
+	s := &service{}
+	assert.NoError(t, s.checkCeremony(pp))
+
+	signer, err := ecdsa.NewECDSASigner()
+	assert.NoError(t, err)
+	contributor, err := ecdsa.PemEncodeKey(signer.PK)
+	assert.NoError(t, err)
+	transcript, err := crypto.NewCeremonyTranscript("genesis", pp)
+	assert.NoError(t, err)
+	assert.NoError(t, transcript.Contribute(contributor, nil, signer))
+	raw, err := transcript.Bytes()
+	assert.NoError(t, err)
+
+	// with a transcript, the public parameters must match it, unless unverified ones are allowed
+	s.SetCeremonyPolicy(raw, false)
+	assert.Error(t, s.checkCeremony(pp))
+	s.SetCeremonyPolicy(raw, true)
+	assert.NoError(t, s.checkCeremony(pp))
+
+	assert.NoError(t, pp.SetCeremony(raw))
+	s.SetCeremonyPolicy(raw, false)
+	assert.NoError(t, s.checkCeremony(pp))
+}
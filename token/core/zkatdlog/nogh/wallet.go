@@ -6,18 +6,35 @@ SPDX-License-Identifier: Apache-2.0
 package nogh
 
 import (
+	"encoding/json"
+	"sync"
+
 	"github.com/pkg/errors"
 
+	idemix2 "github.com/hyperledger-labs/fabric-smart-client/platform/fabric/core/generic/msp/idemix"
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 
 	api2 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/audit"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/anonym"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/pssign"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/atrest"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
+// SealedStatePrefixes are the prefixes of the kvs entries of the owner wallets sealed at rest, see atrest.Put
+var SealedStatePrefixes = []string{
+	"zkatdlog.owner.wallet.recipient.id",
+	"zkatdlog.owner.wallet.pseudonym",
+	"zkatdlog.owner.wallet.imported.id",
+	"zkatdlog.owner.wallet.reassignment",
+}
+
 func (s *service) IssuerIdentity(label string) (view.Identity, error) {
 	logger.Debugf("searching issuer for [%s] at [%s]", label, s.channel.Name())
 
@@ -32,6 +49,9 @@ func (s *service) IssuerIdentity(label string) (view.Identity, error) {
 			logger.Debugf("issuer for [%s] at [%s] found (index %d)", issuer.label, s.channel.Name(), issuer.index)
 
 			witness := anonym.NewWitness(issuer.sk, nil, nil, nil, nil, issuer.index)
+			if issuer.credential != nil {
+				witness = anonym.NewAccumulatorWitness(issuer.sk, nil, nil, nil, nil, issuer.credential)
+			}
 			signer := anonym.NewSigner(witness, nil, nil, 0, pp.ZKATPedParams)
 
 			fID, err := signer.ToUniqueIdentifier()
@@ -61,6 +81,14 @@ func (s *service) GenerateIssuerKeyPair(tokenType string) (api2.Key, api2.Key, e
 	return anonym.GenerateKeyPair(tokenType, s.PublicParams())
 }
 
+func (s *service) RegisterWallet(usage api2.IdentityUsage, id string, mspID string, path string) error {
+	return s.identityProvider.RegisterIdentity(usage, id, mspID, path)
+}
+
+func (s *service) WalletMSPType(usage api2.IdentityUsage) (string, error) {
+	return s.identityProvider.MSPType(usage)
+}
+
 func (s *service) RegisterIssuer(label string, sk api2.Key, pk api2.Key) error {
 	if err := s.FetchPublicParams(); err != nil {
 		return errors.WithMessagef(err, "failed fetching public params")
@@ -77,11 +105,12 @@ func (s *service) RegisterIssuer(label string, sk api2.Key, pk api2.Key) error {
 	for index, issuer := range ip.Issuers {
 		if issuer.Equals(_pk) {
 			s.issuers = append(s.issuers, &struct {
-				label string
-				index int
-				sk    *bn256.Zr
-				pk    *bn256.G1
-				fID   view.Identity
+				label      string
+				index      int
+				sk         *bn256.Zr
+				pk         *bn256.G1
+				fID        view.Identity
+				credential *pssign.Signature
 			}{label: label, index: index, sk: sk.(*bn256.Zr), pk: _pk, fID: nil})
 
 			logger.Debugf("registered issuer for [%s] at [%s], fetching public params", label, s.channel.Name())
@@ -93,14 +122,86 @@ func (s *service) RegisterIssuer(label string, sk api2.Key, pk api2.Key) error {
 	return errors.Errorf("public key not found in public parameters")
 }
 
+// RegisterIssuerCredential registers the anonymous issuer, for the token type passed as label, with the passed secret key
+// and the credential issued to it by the authority of the issuer accumulator of the public parameters, see anonym.AuthorizeIssuer
+func (s *service) RegisterIssuerCredential(label string, sk api2.Key, credential []byte) error {
+	if err := s.FetchPublicParams(); err != nil {
+		return errors.WithMessagef(err, "failed fetching public params")
+	}
+	ip, err := s.PublicParams().GetIssuingPolicy()
+	if err != nil {
+		return errors.WithMessagef(err, "failed parsing issuing policy")
+	}
+	return s.registerIssuerCredential(ip, label, sk, credential)
+}
+
+func (s *service) registerIssuerCredential(ip *crypto.IssuingPolicy, label string, sk api2.Key, credential []byte) error {
+	if ip.Accumulator == nil {
+		return errors.Errorf("the public parameters do not set an issuer accumulator")
+	}
+	_sk, ok := sk.(*bn256.Zr)
+	if !ok {
+		return errors.Errorf("invalid issuer secret key")
+	}
+	sig := &pssign.Signature{}
+	if err := sig.Deserialize(credential); err != nil {
+		return errors.Wrapf(err, "failed unmarshalling issuer credential")
+	}
+	if err := anonym.VerifyCredential(ip.Accumulator, _sk, label, sig); err != nil {
+		return err
+	}
+	s.issuers = append(s.issuers, &struct {
+		label      string
+		index      int
+		sk         *bn256.Zr
+		pk         *bn256.G1
+		fID        view.Identity
+		credential *pssign.Signature
+	}{label: label, sk: _sk, credential: sig})
+
+	logger.Debugf("registered issuer for [%s] at [%s] with a credential of accumulator [%d]", label, s.channel.Name(), ip.Accumulator.Epoch)
+	return nil
+}
+
 func (s *service) GetAuditInfo(id view.Identity) ([]byte, error) {
 	return s.identityProvider.GetAuditInfo(id)
 }
 
 func (s *service) GetEnrollmentID(auditInfo []byte) (string, error) {
+	auditInfo, err := s.openAuditInfo(auditInfo)
+	if err != nil {
+		return "", err
+	}
 	return s.identityProvider.GetEnrollmentID(auditInfo)
 }
 
+func (s *service) GetOwnerEnrollmentID(owner view.Identity, auditInfo []byte) (string, error) {
+	auditInfo, err := s.openAuditInfo(auditInfo)
+	if err != nil {
+		return "", err
+	}
+	ai := &idemix2.AuditInfo{}
+	if err := ai.FromBytes(auditInfo); err != nil {
+		return "", errors.Wrapf(err, "failed unmarshalling audit info of [%s]", owner)
+	}
+	if err := ai.Match(owner); err != nil {
+		return "", errors.Wrapf(err, "audit info does not match identity [%s]", owner)
+	}
+	return ai.EnrollmentID(), nil
+}
+
+// openAuditInfo returns the passed audit info in the clear, decrypting it if it is encrypted for the auditor
+func (s *service) openAuditInfo(auditInfo []byte) ([]byte, error) {
+	if !audit.IsEncryptedAuditInfo(auditInfo) {
+		return auditInfo, nil
+	}
+	if s.auditorDecryptionKey == nil {
+		// only the auditor can open the audit info
+		return nil, api2.ErrEncryptedAuditInfo
+	}
+	return audit.DecryptAuditInfo(s.auditorDecryptionKey, auditInfo)
+}
+
 func (s *service) registerIssuerSigner(signer SigningIdentity) error {
 	fID, err := signer.Serialize()
 	if err != nil {
@@ -148,6 +249,12 @@ func (s *service) ownerWallet(id interface{}) api2.OwnerWallet {
 
 	// check if there is already a wallet
 	identity, walletID := s.identityProvider.LookupIdentifier(api2.OwnerRole, id)
+	if !identity.IsNone() {
+		// the identity might have been reassigned to another wallet of this node
+		if reassignedWalletID := s.reassignedRecipientIdentityWallet(identity); len(reassignedWalletID) != 0 {
+			walletID = reassignedWalletID
+		}
+	}
 	for _, w := range s.ownerWallets {
 		if w.Contains(identity) || w.ID() == walletID {
 			logger.Debugf("found owner wallet [%s:%s]", identity, walletID)
@@ -156,7 +263,20 @@ func (s *service) ownerWallet(id interface{}) api2.OwnerWallet {
 	}
 
 	// Create the wallet
-	if idInfo := s.identityProvider.GetIdentityInfo(api2.OwnerRole, walletID); idInfo != nil {
+	idInfo := s.identityProvider.GetIdentityInfo(api2.OwnerRole, walletID)
+	if idInfo == nil && !identity.IsNone() {
+		// the identity might have been imported from another node using the same wallet
+		if importedWalletID := s.importedRecipientIdentityWallet(identity); len(importedWalletID) != 0 {
+			for _, w := range s.ownerWallets {
+				if w.ID() == importedWalletID {
+					logger.Debugf("found owner wallet [%s:%s] by imported identity", identity, importedWalletID)
+					return w
+				}
+			}
+			idInfo = s.identityProvider.GetIdentityInfo(api2.OwnerRole, importedWalletID)
+		}
+	}
+	if idInfo != nil {
 		w := newOwnerWallet(s, idInfo.ID, idInfo)
 		s.ownerWallets = append(s.ownerWallets, w)
 		logger.Debugf("created owner wallet [%s:%s]", identity, walletID)
@@ -167,6 +287,27 @@ func (s *service) ownerWallet(id interface{}) api2.OwnerWallet {
 	return nil
 }
 
+// importedRecipientIdentityWallet returns the id of the owner wallet the passed identity has been imported into, if any
+func (s *service) importedRecipientIdentityWallet(id view.Identity) string {
+	k := kvs.CreateCompositeKeyOrPanic(
+		"zkatdlog.owner.wallet.imported.id",
+		[]string{
+			s.channel.Name(),
+			id.String(),
+		},
+	)
+	kvss := kvs.GetService(s.sp)
+	if !kvss.Exists(k) {
+		return ""
+	}
+	var walletID string
+	if err := atrest.Get(s.sp, k, &walletID); err != nil {
+		logger.Warnf("failed getting wallet of imported identity [%s]: [%s]", id, err)
+		return ""
+	}
+	return walletID
+}
+
 func (s *service) IssuerWallet(id string) api2.IssuerWallet {
 	return s.issuerWallet(id)
 }
@@ -241,6 +382,21 @@ func (s *service) auditorWallet(id interface{}) api2.AuditorWallet {
 	return nil
 }
 
+// SetPseudonymPolicy sets the policy the owner wallet with the passed id follows to derive pseudonyms
+func (s *service) SetPseudonymPolicy(walletID string, policy api2.PseudonymPolicy) {
+	s.walletsLock.Lock()
+	defer s.walletsLock.Unlock()
+
+	s.pseudonymPolicies[walletID] = policy
+}
+
+func (s *service) pseudonymPolicy(walletID string) api2.PseudonymPolicy {
+	s.walletsLock.Lock()
+	defer s.walletsLock.Unlock()
+
+	return s.pseudonymPolicies[walletID]
+}
+
 func (s *service) CertifierWallet(id string) api2.CertifierWallet {
 	return nil
 }
@@ -253,6 +409,8 @@ type wallet struct {
 	tokenService *service
 	id           string
 	identityInfo *api2.IdentityInfo
+	// pseudonymsLock serializes the derivation of the pseudonyms that are reused
+	pseudonymsLock sync.Mutex
 }
 
 func newOwnerWallet(tokenService *service, id string, identityInfo *api2.IdentityInfo) *wallet {
@@ -268,22 +426,62 @@ func (w *wallet) ID() string {
 }
 
 func (w *wallet) Contains(identity view.Identity) bool {
+	if walletID := w.tokenService.reassignedRecipientIdentityWallet(identity); len(walletID) != 0 {
+		return walletID == w.ID()
+	}
 	return w.existsRecipientIdentity(identity)
 }
 
-func (w *wallet) GetRecipientIdentity() (view.Identity, error) {
+func (w *wallet) GetRecipientIdentity(opts *api2.RecipientIdentityOptions) (view.Identity, error) {
+	// Is there a pseudonym to reuse?
+	var reuse string
+	policy := w.PseudonymPolicy()
+	if opts != nil && opts.Fresh {
+		policy = api2.FreshPerTransaction
+	}
+	switch policy {
+	case api2.Sticky:
+		reuse = "sticky"
+	case api2.FreshPerCounterparty:
+		if opts != nil && !opts.Counterparty.IsNone() {
+			reuse = opts.Counterparty.UniqueID()
+		}
+	}
+	if len(reuse) != 0 {
+		w.pseudonymsLock.Lock()
+		defer w.pseudonymsLock.Unlock()
+
+		pseudonym, err := w.getReusablePseudonym(reuse)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed getting pseudonym to reuse from wallet [%s]", w.ID())
+		}
+		if !pseudonym.IsNone() {
+			logger.Debugf("wallet [%s]: reuse pseudonym [%s] for [%s]", w.ID(), pseudonym, reuse)
+			return pseudonym, nil
+		}
+	}
+
 	// Get a new pseudonym
 	pseudonym, err := w.identityInfo.GetIdentity()
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed getting recipient identity from wallet [%s]", w.ID())
 	}
 	// Register the pseudonym
-	if err := w.putRecipientIdentity(pseudonym, []byte{}); err != nil {
+	if err := w.putRecipientIdentity(pseudonym); err != nil {
 		return nil, errors.WithMessagef(err, "failed storing recipient identity in wallet [%s]", w.ID())
 	}
+	if len(reuse) != 0 {
+		if err := w.putReusablePseudonym(reuse, pseudonym); err != nil {
+			return nil, errors.WithMessagef(err, "failed storing pseudonym to reuse in wallet [%s]", w.ID())
+		}
+	}
 	return pseudonym, nil
 }
 
+func (w *wallet) PseudonymPolicy() api2.PseudonymPolicy {
+	return w.tokenService.pseudonymPolicy(w.id)
+}
+
 func (w *wallet) GetAuditInfo(id view.Identity) ([]byte, error) {
 	return w.tokenService.identityProvider.GetAuditInfo(id)
 }
@@ -292,9 +490,123 @@ func (w *wallet) GetTokenMetadata(id view.Identity) ([]byte, error) {
 	return nil, nil
 }
 
+func (w *wallet) EnrollmentID() string {
+	return w.identityInfo.EnrollmentID
+}
+
+func (w *wallet) ExportRecipientIdentities() ([]*api2.RecipientIdentity, error) {
+	it, err := kvs.GetService(w.tokenService.sp).GetByPartialCompositeID(
+		"zkatdlog.owner.wallet.recipient.id",
+		[]string{
+			w.tokenService.channel.Name(),
+			w.identityInfo.ID,
+			w.identityInfo.EnrollmentID,
+		},
+	)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed iterating over recipient identities of wallet [%s]", w.ID())
+	}
+	defer it.Close()
+
+	var res []*api2.RecipientIdentity
+	exported := map[string]bool{}
+	for it.HasNext() {
+		var raw json.RawMessage
+		if err := it.Next(&raw); err != nil {
+			return nil, errors.WithMessagef(err, "failed reading recipient identity of wallet [%s]", w.ID())
+		}
+		var id view.Identity
+		k, err := atrest.DecodeEntry(atrest.GetSealer(w.tokenService.sp), raw, &id)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed reading recipient identity of wallet [%s]", w.ID())
+		}
+		if len(k) != 0 && k != w.recipientIdentityKey(id) {
+			return nil, errors.Errorf("recipient identity [%s] of wallet [%s] stored under another key", id, w.ID())
+		}
+		if id.IsNone() {
+			// recipient identity stored without its value, it is recovered below from the tokens it owns
+			continue
+		}
+		if !w.Contains(id) {
+			// recipient identity reassigned to another wallet
+			continue
+		}
+		auditInfo, err := w.GetAuditInfo(id)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed getting audit info of [%s] in wallet [%s]", id, w.ID())
+		}
+		res = append(res, &api2.RecipientIdentity{Identity: id, AuditInfo: auditInfo})
+		exported[id.UniqueID()] = true
+	}
+
+	// The previous versions stored the recipient identities without their value. Those owning unspent tokens are
+	// recovered from the tokens and stored again with their value. The others, once they receive a token,
+	// are shared with the transaction, see ttxcc.SyncTransactionView.
+	unspent, err := w.ListTokens(&api2.ListTokensOptions{})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed listing tokens of wallet [%s]", w.ID())
+	}
+	for _, t := range unspent.Tokens {
+		owner := view.Identity(t.Owner.Raw)
+		if exported[owner.UniqueID()] {
+			continue
+		}
+		exported[owner.UniqueID()] = true
+		if err := w.putRecipientIdentity(owner); err != nil {
+			return nil, errors.WithMessagef(err, "failed storing recipient identity [%s] in wallet [%s]", owner, w.ID())
+		}
+		auditInfo, err := w.GetAuditInfo(owner)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed getting audit info of [%s] in wallet [%s]", owner, w.ID())
+		}
+		res = append(res, &api2.RecipientIdentity{Identity: owner, AuditInfo: auditInfo})
+	}
+	logger.Debugf("wallet [%s]: exported [%d] recipient identities", w.ID(), len(res))
+	return res, nil
+}
+
+func (w *wallet) ImportRecipientIdentities(ids []*api2.RecipientIdentity) error {
+	for _, ri := range ids {
+		if ri == nil || ri.Identity.IsNone() {
+			return errors.Errorf("invalid recipient identity, it is empty")
+		}
+		if w.Contains(ri.Identity) {
+			continue
+		}
+
+		// the audit info must bind the identity to the owner of this wallet
+		ai := &idemix2.AuditInfo{}
+		if err := ai.FromBytes(ri.AuditInfo); err != nil {
+			return errors.Wrapf(err, "failed unmarshalling audit info of [%s]", ri.Identity)
+		}
+		if err := ai.Match(ri.Identity); err != nil {
+			return errors.Wrapf(err, "audit info does not match identity [%s]", ri.Identity)
+		}
+		if len(ai.Attributes) < 3 || ai.EnrollmentID() != w.identityInfo.EnrollmentID {
+			return errors2.Errorf(errors2.Unauthorized, "identity [%s] does not belong to enrollment ID [%s]", ri.Identity, w.identityInfo.EnrollmentID)
+		}
+
+		if err := w.tokenService.identityProvider.RegisterRecipientIdentity(ri.Identity, ri.AuditInfo, nil); err != nil {
+			return errors.WithMessagef(err, "failed registering recipient identity [%s]", ri.Identity)
+		}
+		if err := w.putImportedRecipientIdentity(ri.Identity); err != nil {
+			return errors.WithMessagef(err, "failed storing imported recipient identity [%s]", ri.Identity)
+		}
+		if err := w.putRecipientIdentity(ri.Identity); err != nil {
+			return errors.WithMessagef(err, "failed storing recipient identity [%s] in wallet [%s]", ri.Identity, w.ID())
+		}
+		logger.Debugf("wallet [%s]: imported recipient identity [%s]", w.ID(), ri.Identity)
+	}
+	return nil
+}
+
+func (w *wallet) CanSpend(identity view.Identity) bool {
+	return w.Contains(identity) && len(w.tokenService.importedRecipientIdentityWallet(identity)) == 0
+}
+
 func (w *wallet) GetSigner(identity view.Identity) (api2.Signer, error) {
 	if !w.Contains(identity) {
-		return nil, errors.Errorf("identity [%s] does not belong to this wallet [%s]", identity, w.ID())
+		return nil, errors2.Errorf(errors2.Unauthorized, "identity [%s] does not belong to this wallet [%s]", identity, w.ID())
 	}
 
 	si, err := w.tokenService.identityProvider.GetSigner(identity)
@@ -345,8 +657,12 @@ func (w *wallet) existsRecipientIdentity(id view.Identity) bool {
 	return kvss.Exists(k)
 }
 
-func (w *wallet) putRecipientIdentity(id view.Identity, meta []byte) error {
-	k := kvs.CreateCompositeKeyOrPanic(
+func (w *wallet) putRecipientIdentity(id view.Identity) error {
+	return atrest.Put(w.tokenService.sp, w.recipientIdentityKey(id), id)
+}
+
+func (w *wallet) recipientIdentityKey(id view.Identity) string {
+	return kvs.CreateCompositeKeyOrPanic(
 		"zkatdlog.owner.wallet.recipient.id",
 		[]string{
 			w.tokenService.channel.Name(),
@@ -355,11 +671,55 @@ func (w *wallet) putRecipientIdentity(id view.Identity, meta []byte) error {
 			id.String(),
 		},
 	)
+}
+
+func (w *wallet) getReusablePseudonym(reuse string) (view.Identity, error) {
+	k := kvs.CreateCompositeKeyOrPanic(
+		"zkatdlog.owner.wallet.pseudonym",
+		[]string{
+			w.tokenService.channel.Name(),
+			w.identityInfo.ID,
+			w.identityInfo.EnrollmentID,
+			reuse,
+		},
+	)
 	kvss := kvs.GetService(w.tokenService.sp)
-	if err := kvss.Put(k, meta); err != nil {
-		return err
+	if !kvss.Exists(k) {
+		return nil, nil
 	}
-	return nil
+	var pseudonym view.Identity
+	if err := atrest.Get(w.tokenService.sp, k, &pseudonym); err != nil {
+		return nil, err
+	}
+	if !w.Contains(pseudonym) {
+		// the pseudonym has been reassigned to another wallet, derive a new one
+		return nil, nil
+	}
+	return pseudonym, nil
+}
+
+func (w *wallet) putReusablePseudonym(reuse string, pseudonym view.Identity) error {
+	k := kvs.CreateCompositeKeyOrPanic(
+		"zkatdlog.owner.wallet.pseudonym",
+		[]string{
+			w.tokenService.channel.Name(),
+			w.identityInfo.ID,
+			w.identityInfo.EnrollmentID,
+			reuse,
+		},
+	)
+	return atrest.Put(w.tokenService.sp, k, pseudonym)
+}
+
+func (w *wallet) putImportedRecipientIdentity(id view.Identity) error {
+	k := kvs.CreateCompositeKeyOrPanic(
+		"zkatdlog.owner.wallet.imported.id",
+		[]string{
+			w.tokenService.channel.Name(),
+			id.String(),
+		},
+	)
+	return atrest.Put(w.tokenService.sp, k, w.ID())
 }
 
 type IssuerKeyPair struct {
@@ -396,7 +756,7 @@ func (w *issuerWallet) GetIssuerIdentity(tokenType string) (view.Identity, error
 
 func (w *issuerWallet) GetSigner(identity view.Identity) (api2.Signer, error) {
 	if !w.Contains(identity) {
-		return nil, errors.Errorf("identity [%s] does not belong to this wallet [%s]", identity, w.ID())
+		return nil, errors2.Errorf(errors2.Unauthorized, "identity [%s] does not belong to this wallet [%s]", identity, w.ID())
 	}
 	si, err := w.tokenService.identityProvider.GetSigner(identity)
 	if err != nil {
@@ -460,7 +820,7 @@ func (w *auditorWallet) GetAuditorIdentity() (view.Identity, error) {
 
 func (w *auditorWallet) GetSigner(id view.Identity) (api2.Signer, error) {
 	if !w.Contains(id) {
-		return nil, errors.Errorf("identity [%s] does not belong to this wallet [%s]", id, w.ID())
+		return nil, errors2.Errorf(errors2.Unauthorized, "identity [%s] does not belong to this wallet [%s]", id, w.ID())
 	}
 
 	si, err := w.tokenService.identityProvider.GetSigner(w.identity)
@@ -0,0 +1,159 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package nogh
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/api"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/stretchr/testify/assert"
+
+	api3 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/math/gurvy/bn256"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/audit"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/elgamal"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto/issue/anonym"
+	token3 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// configProvider configures the in memory kvs
+type configProvider struct {
+	api.ConfigProvider
+}
+
+func (*configProvider) UnmarshalKey(string, interface{}) error {
+	return nil
+}
+
+type channel struct{}
+
+func (channel) Name() string {
+	return "channel"
+}
+
+func (channel) Vault() *fabric.Vault {
+	return nil
+}
+
+// queryEngine lists the passed tokens as unspent
+type queryEngine struct {
+	QueryEngine
+	tokens []*token3.UnspentToken
+}
+
+func (q *queryEngine) ListUnspentTokens() (*token3.UnspentTokens, error) {
+	return &token3.UnspentTokens{Tokens: q.tokens}, nil
+}
+
+// identityProvider returns the identity itself as its audit info
+type identityProvider struct {
+	api3.IdentityProvider
+}
+
+func (identityProvider) GetAuditInfo(id view.Identity) ([]byte, error) {
+	return append([]byte("audit-"), id...), nil
+}
+
+func (identityProvider) GetEnrollmentID(auditInfo []byte) (string, error) {
+	return string(auditInfo), nil
+}
+
+func TestGetEnrollmentIDEncrypted(t *testing.T) {
+	sk, err := elgamal.NewKeyPair(bn256.G1Gen())
+	assert.NoError(t, err)
+	auditInfo, err := audit.EncryptAuditInfo(sk.PublicKey, []byte("alice"))
+	assert.NoError(t, err)
+
+	// only the auditor can open the audit info
+	s := &service{identityProvider: identityProvider{}}
+	_, err = s.GetEnrollmentID(auditInfo)
+	assert.Equal(t, api3.ErrEncryptedAuditInfo, err)
+
+	s.auditorDecryptionKey = sk
+	eID, err := s.GetEnrollmentID(auditInfo)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", eID)
+}
+
+func TestExportLegacyRecipientIdentities(t *testing.T) {
+	sp := registry.New()
+	assert.NoError(t, sp.RegisterService(&configProvider{}))
+	kvss, err := kvs.New("memory", "", sp)
+	assert.NoError(t, err)
+	assert.NoError(t, sp.RegisterService(kvss))
+
+	unspent := func(txID string, owner view.Identity) *token3.UnspentToken {
+		return &token3.UnspentToken{Id: &token3.Id{TxId: txID}, Owner: &token3.Owner{Raw: owner}, Type: "ABC", Quantity: "0x0a"}
+	}
+	current, legacy, idle, other := view.Identity("current"), view.Identity("legacy"), view.Identity("idle"), view.Identity("other")
+	s := &service{
+		channel:          channel{},
+		sp:               sp,
+		identityProvider: identityProvider{},
+		qe:               &queryEngine{tokens: []*token3.UnspentToken{unspent("tx1", legacy), unspent("tx2", legacy), unspent("tx3", other)}},
+	}
+	w := newOwnerWallet(s, "alice", &api3.IdentityInfo{ID: "alice", EnrollmentID: "alice"})
+
+	assert.NoError(t, w.putRecipientIdentity(current))
+	// the previous versions stored the recipient identities without their value
+	legacyKey := func(id view.Identity) string {
+		return kvs.CreateCompositeKeyOrPanic("zkatdlog.owner.wallet.recipient.id", []string{"channel", "alice", "alice", id.String()})
+	}
+	assert.NoError(t, kvss.Put(legacyKey(legacy), []byte(nil)))
+	assert.NoError(t, kvss.Put(legacyKey(idle), []byte(nil)))
+
+	export := func() map[string]string {
+		ids, err := w.ExportRecipientIdentities()
+		assert.NoError(t, err)
+		res := map[string]string{}
+		for _, ri := range ids {
+			res[string(ri.Identity)] = string(ri.AuditInfo)
+		}
+		assert.Len(t, res, len(ids))
+		return res
+	}
+	// the legacy identity owning tokens is recovered from them, the one without tokens cannot be
+	expected := map[string]string{"current": "audit-current", "legacy": "audit-legacy"}
+	assert.Equal(t, expected, export())
+	// and it is stored again with its value
+	assert.Equal(t, expected, export())
+	s.qe = &queryEngine{}
+	assert.Equal(t, expected, export())
+}
+
+func TestRegisterIssuerCredential(t *testing.T) {
+	accumulator, authority, err := crypto.NewIssuerAccumulator(0)
+	assert.NoError(t, err)
+	rand, err := bn256.GetRand()
+	assert.NoError(t, err)
+	sk := bn256.RandModOrder(rand)
+	credential, err := anonym.AuthorizeIssuer(authority, sk, "ABC")
+	assert.NoError(t, err)
+	raw, err := credential.Serialize()
+	assert.NoError(t, err)
+
+	s := &service{channel: channel{}}
+	ip := &crypto.IssuingPolicy{Accumulator: accumulator}
+
+	// the credential must authorize the secret key for the type
+	assert.Error(t, s.registerIssuerCredential(ip, "DEF", sk, raw))
+	assert.Error(t, s.registerIssuerCredential(ip, "ABC", bn256.RandModOrder(rand), raw))
+	assert.Error(t, s.registerIssuerCredential(&crypto.IssuingPolicy{}, "ABC", sk, raw))
+	rotated, _, err := crypto.NewIssuerAccumulator(1)
+	assert.NoError(t, err)
+	assert.Error(t, s.registerIssuerCredential(&crypto.IssuingPolicy{Accumulator: rotated}, "ABC", sk, raw))
+	assert.Empty(t, s.issuers)
+
+	assert.NoError(t, s.registerIssuerCredential(ip, "ABC", sk, raw))
+	assert.Len(t, s.issuers, 1)
+	assert.Equal(t, "ABC", s.issuers[0].label)
+	assert.Equal(t, credential, s.issuers[0].credential)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/pkg/errors"
+
+	api2 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+const walletRecordPrefix = "token-sdk.wallet"
+
+// CredentialIssuer issues the credentials of the wallets created with WalletManager.NewOwnerWallet,
+// for instance a fabric-ca client for x509 credentials or an idemix issuer client.
+// Applications register their implementation as a service.
+type CredentialIssuer interface {
+	// IssueCredentials generates fresh credentials, of the passed msp type (bccsp or idemix), for the passed id,
+	// and writes them, following the msp directory layout, under the passed directory.
+	// It returns the id of the msp the credentials belong to.
+	IssueCredentials(id string, mspType string, dir string) (string, error)
+}
+
+// GetCredentialIssuer returns the CredentialIssuer registered in the passed service provider
+func GetCredentialIssuer(sp ServiceProvider) (CredentialIssuer, error) {
+	s, err := sp.GetService(reflect.TypeOf((*CredentialIssuer)(nil)))
+	if err != nil {
+		return nil, errors.WithMessage(err, "credential issuer not available")
+	}
+	return s.(CredentialIssuer), nil
+}
+
+// walletRecord records where the material of a wallet created at runtime is, to register it again after a restart
+type walletRecord struct {
+	ID    string
+	MSPID string
+	Path  string
+}
+
+// newWallet issues fresh credentials for the wallet with the passed id, stores them under the directory
+// configured with token.wallets.path, registers the wallet, and records it
+func (t *WalletManager) newWallet(usage api2.IdentityUsage, role string, id string) error {
+	issuer, err := GetCredentialIssuer(t.ms.sp)
+	if err != nil {
+		return err
+	}
+	if err := checkWalletID(id); err != nil {
+		return err
+	}
+	root := view2.GetConfigService(t.ms.sp).GetPath("token.wallets.path")
+	if len(root) == 0 {
+		return errors.New("token.wallets.path not set, cannot store the wallet material")
+	}
+	mspType, err := t.ts.WalletMSPType(usage)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting the msp type of wallet [%s]", id)
+	}
+	dir := filepath.Join(root, t.ms.Network(), t.ms.Channel(), t.ms.Namespace(), role, id)
+	mspID, err := issuer.IssueCredentials(id, mspType, dir)
+	if err != nil {
+		return errors.WithMessagef(err, "failed issuing credentials for [%s]", id)
+	}
+	if err := t.ts.RegisterWallet(usage, id, mspID, dir); err != nil {
+		return err
+	}
+	k, err := t.walletRecordKey(role, id)
+	if err != nil {
+		return err
+	}
+	if err := kvs.GetService(t.ms.sp).Put(k, &walletRecord{ID: id, MSPID: mspID, Path: dir}); err != nil {
+		return errors.WithMessagef(err, "failed recording wallet [%s]", id)
+	}
+	return nil
+}
+
+// restoreWallet registers again the wallet with the passed id, if it was created at runtime.
+// It returns false if there is no such wallet.
+func (t *WalletManager) restoreWallet(usage api2.IdentityUsage, role string, id string) bool {
+	if t.ms == nil {
+		return false
+	}
+	k, err := t.walletRecordKey(role, id)
+	if err != nil {
+		return false
+	}
+	kvss := kvs.GetService(t.ms.sp)
+	if !kvss.Exists(k) {
+		return false
+	}
+	record := &walletRecord{}
+	if err := kvss.Get(k, record); err != nil {
+		logger.Errorf("failed loading record of wallet [%s]: [%s]", id, err)
+		return false
+	}
+	if err := t.ts.RegisterWallet(usage, record.ID, record.MSPID, record.Path); err != nil {
+		logger.Errorf("failed restoring wallet [%s]: [%s]", id, err)
+		return false
+	}
+	return true
+}
+
+func (t *WalletManager) walletRecordKey(role string, id string) (string, error) {
+	return kvs.CreateCompositeKey(walletRecordPrefix, []string{t.ms.Network(), t.ms.Channel(), t.ms.Namespace(), role, id})
+}
+
+// checkWalletID checks that the passed wallet id can name the directory of the wallet material,
+// it must not step out of the directory of the wallets
+func checkWalletID(id string) error {
+	if len(id) == 0 || id == "." || id == ".." || strings.ContainsAny(id, `/\`) || strings.ContainsRune(id, 0) {
+		return errors.Errorf("invalid wallet id [%s], it must be a non-empty name without path separators", id)
+	}
+	return nil
+}
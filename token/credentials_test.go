@@ -0,0 +1,21 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckWalletID(t *testing.T) {
+	for _, id := range []string{"alice", "alice.bob", "..alice", "alice-1"} {
+		assert.NoError(t, checkWalletID(id), id)
+	}
+	for _, id := range []string{"", ".", "..", "../alice", "alice/..", "alice/bob", `..\alice`, "/alice", "alice\x00"} {
+		assert.Error(t, checkWalletID(id), id)
+	}
+}
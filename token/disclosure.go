@@ -0,0 +1,264 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"fmt"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+)
+
+// DisclosureRole is the role a party plays in a transaction, it determines what the party learns
+type DisclosureRole string
+
+const (
+	// SenderRole is played by the owners of the inputs of the transfers
+	SenderRole DisclosureRole = "sender"
+	// ReceiverRole is played by the owners of the outputs
+	ReceiverRole DisclosureRole = "receiver"
+	// AuditorRole is played by the auditor, if any, that gets the full metadata within its audit scope
+	AuditorRole DisclosureRole = "auditor"
+	// EndorserRole is played by the endorsers of the token chaincode, that get the token request only
+	EndorserRole DisclosureRole = "endorser"
+	// ObserverRole is played by anyone reading the ledger
+	ObserverRole DisclosureRole = "observer"
+)
+
+// Disclosure tells what a party learns about an input or an output of a token request
+type Disclosure struct {
+	// Action is either "issue" or "transfer"
+	Action      string
+	ActionIndex int
+	// Input is true if Index refers to an input of a transfer, false if it refers to an output
+	Input bool
+	Index int
+	// Owner is true if the party learns the identity owning the token, a pseudonym in general
+	Owner bool
+	// EnrollmentID is true if the party learns the enrollment ID of the owner, via its audit info
+	EnrollmentID bool
+	Type         bool
+	Quantity     bool
+}
+
+// PartyDisclosure lists what a party learns about a token request. Party is nil for
+// the auditor, the endorsers, and the observers, whose identity the request does not carry.
+type PartyDisclosure struct {
+	Party       view.Identity
+	Roles       []DisclosureRole
+	Disclosures []*Disclosure
+}
+
+// LedgerField is a field the token request writes in the clear on the ledger, any party reading the ledger learns it
+type LedgerField struct {
+	// Action is the part of the request carrying the field: "issue", "transfer", "burn receipt" or "swap terms"
+	Action      string
+	ActionIndex int
+	Name        string
+	Value       string
+}
+
+// DisclosureReport enumerates what each party of a token request learns about it
+type DisclosureReport struct {
+	TxID            string
+	TokenDataHiding bool
+	GraphHiding     bool
+	// Ledger lists the fields of the request in the clear on the ledger, the endorsers and the observers learn them
+	// as every other party. The disclosures of the inputs and outputs account for them.
+	Ledger  []*LedgerField
+	Parties []*PartyDisclosure
+}
+
+// DisclosureReport returns what the parties of this request learn, derived from the privacy the driver provides
+// and from the metadata each party gets. Pass filtered if the parties get the metadata filtered by
+// FilterMetadataBy, they get the full metadata otherwise. The auditor always gets the full metadata,
+// without the openings of the outputs if its audit scope excludes the amounts.
+// The report considers the request alone, the parties may learn more from the history of the tokens.
+func (t *Request) DisclosureReport(filtered bool) (*DisclosureReport, error) {
+	ppm := t.TokenService.PublicParametersManager()
+	report := &DisclosureReport{
+		TxID:            t.TxID,
+		TokenDataHiding: ppm.TokenDataHiding(),
+		GraphHiding:     ppm.GraphHiding(),
+	}
+	if err := t.ledgerFields(report); err != nil {
+		return nil, err
+	}
+
+	ledger := t.disclosures(report, false, nil, false)
+
+	// senders and receivers, in order of appearance
+	var parties []*PartyDisclosure
+	index := map[string]*PartyDisclosure{}
+	addRole := func(id view.Identity, role DisclosureRole) {
+		if id.IsNone() {
+			return
+		}
+		p, ok := index[id.UniqueID()]
+		if !ok {
+			p = &PartyDisclosure{Party: id}
+			index[id.UniqueID()] = p
+			parties = append(parties, p)
+		}
+		for _, r := range p.Roles {
+			if r == role {
+				return
+			}
+		}
+		p.Roles = append(p.Roles, role)
+	}
+	for _, issue := range t.Metadata.Issues {
+		for _, receiver := range issue.Receivers {
+			addRole(receiver, ReceiverRole)
+		}
+	}
+	for _, transfer := range t.Metadata.Transfers {
+		for _, sender := range transfer.Senders {
+			addRole(sender, SenderRole)
+		}
+		for _, receiver := range transfer.Receivers {
+			addRole(receiver, ReceiverRole)
+		}
+	}
+	for _, p := range parties {
+		p.Disclosures = t.disclosures(report, !filtered, p.Party, false)
+	}
+
+	auditor := t.disclosures(report, true, nil, true)
+	if !ppm.AuditScope().Amounts() {
+		// the auditor learns the types and quantities the ledger shows, see FilterMetadataByAuditScope
+		for i, d := range auditor {
+			d.Type, d.Quantity = ledger[i].Type, ledger[i].Quantity
+		}
+	}
+	report.Parties = append(report.Parties,
+		&PartyDisclosure{Roles: []DisclosureRole{AuditorRole}, Disclosures: auditor},
+		&PartyDisclosure{Roles: []DisclosureRole{EndorserRole}, Disclosures: ledger},
+		&PartyDisclosure{Roles: []DisclosureRole{ObserverRole}, Disclosures: ledger},
+	)
+	report.Parties = append(report.Parties, parties...)
+	return report, nil
+}
+
+// ledgerFields fills the fields of this request written in the clear on the ledger: the identities of the
+// non-anonymous issuers, the spent token ids, unless the graph is hidden, the burn receipts and the swap terms
+func (t *Request) ledgerFields(report *DisclosureReport) error {
+	add := func(action string, index int, name string, value string) {
+		report.Ledger = append(report.Ledger, &LedgerField{Action: action, ActionIndex: index, Name: name, Value: value})
+	}
+	for i, raw := range t.Actions.Issues {
+		issue, err := t.TokenService.tms.DeserializeIssueAction(raw)
+		if err != nil {
+			return errors.WithMessagef(err, "failed deserializing issue action [%d]", i)
+		}
+		if !issue.IsAnonymous() {
+			add("issue", i, "issuer", view.Identity(issue.GetIssuer()).String())
+		}
+	}
+	if !report.GraphHiding {
+		for i, transfer := range t.Metadata.Transfers {
+			for _, id := range transfer.TokenIDs {
+				add("transfer", i, "input", id.String())
+			}
+		}
+	}
+	for i, receipt := range t.Actions.BurnReceipts {
+		add("burn receipt", i, "transfer", fmt.Sprintf("%d", receipt.TransferIndex))
+		add("burn receipt", i, "output", fmt.Sprintf("%d", receipt.OutputIndex))
+		add("burn receipt", i, "type", receipt.Type)
+		add("burn receipt", i, "quantity", receipt.Quantity)
+		add("burn receipt", i, "enrollment id", receipt.EnrollmentID)
+		if len(receipt.Reference) != 0 {
+			add("burn receipt", i, "reference", fmt.Sprintf("%x", receipt.Reference))
+		}
+	}
+	if terms := t.Actions.SwapTerms; terms != nil {
+		for _, leg := range []struct {
+			name string
+			leg  *SwapLeg
+		}{{"first", terms.First}, {"second", terms.Second}} {
+			if leg.leg == nil {
+				continue
+			}
+			add("swap terms", 0, leg.name+" type", leg.leg.Type)
+			add("swap terms", 0, leg.name+" quantity", leg.leg.Quantity)
+		}
+		add("swap terms", 0, "rate", fmt.Sprintf("%d/%d", terms.RateNumerator, terms.RateDenominator))
+	}
+	return nil
+}
+
+// disclosures returns the disclosures of the inputs and outputs of this request to a party that sees the ledger and
+// the metadata of the tokens of self, or of all the tokens if full is true. The metadata of an input discloses its
+// owner, its type and quantity are known to the owner and to the auditor, that audited the transaction creating it.
+func (t *Request) disclosures(report *DisclosureReport, full bool, self view.Identity, auditor bool) []*Disclosure {
+	metadata := func(owner view.Identity) bool {
+		return full || (!self.IsNone() && self.Equal(owner))
+	}
+	// the ledger shows the owners of the outputs, and their types and quantities if the token data is not hidden.
+	// Without graph hiding, the inputs are outputs of previous transactions, on the ledger as well.
+	clear := !report.TokenDataHiding
+	// the burn receipts and the swap terms show the types and quantities of their outputs whatever the driver,
+	// the burn receipts show the enrollment ID of the senders of their transfers as well
+	revealed := map[[2]int]bool{}
+	senders := map[int]bool{}
+	for _, receipt := range t.Actions.BurnReceipts {
+		revealed[[2]int{receipt.TransferIndex, receipt.OutputIndex}] = true
+		senders[receipt.TransferIndex] = true
+	}
+	if terms := t.Actions.SwapTerms; terms != nil {
+		for _, leg := range []*SwapLeg{terms.First, terms.Second} {
+			if leg != nil {
+				revealed[[2]int{leg.TransferIndex, leg.OutputIndex}] = true
+			}
+		}
+	}
+	var res []*Disclosure
+	for i, issue := range t.Metadata.Issues {
+		for j := range issue.Outputs {
+			d := &Disclosure{Action: "issue", ActionIndex: i, Index: j, Owner: true, Type: clear, Quantity: clear}
+			if metadata(identityAt(issue.Receivers, j)) {
+				d.EnrollmentID, d.Type, d.Quantity = true, true, true
+			}
+			res = append(res, d)
+		}
+	}
+	for i, transfer := range t.Metadata.Transfers {
+		for j := range transfer.TokenIDs {
+			linked := !report.GraphHiding
+			d := &Disclosure{Action: "transfer", ActionIndex: i, Input: true, Index: j, Owner: linked, Type: linked && clear, Quantity: linked && clear}
+			sender := identityAt(transfer.Senders, j)
+			if metadata(sender) {
+				d.Owner, d.EnrollmentID = true, true
+			}
+			if auditor || (!self.IsNone() && self.Equal(sender)) {
+				d.Type, d.Quantity = true, true
+			}
+			if senders[i] {
+				d.EnrollmentID = true
+			}
+			res = append(res, d)
+		}
+		for j := range transfer.Outputs {
+			d := &Disclosure{Action: "transfer", ActionIndex: i, Index: j, Owner: true, Type: clear, Quantity: clear}
+			if metadata(identityAt(transfer.Receivers, j)) {
+				d.EnrollmentID, d.Type, d.Quantity = true, true, true
+			}
+			if revealed[[2]int{i, j}] {
+				d.Type, d.Quantity = true, true
+			}
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+func identityAt(ids []view.Identity, i int) view.Identity {
+	if i < len(ids) {
+		return ids[i]
+	}
+	return nil
+}
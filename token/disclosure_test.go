@@ -0,0 +1,104 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/stretchr/testify/assert"
+
+	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// hidingTMS is the service of a driver hiding the token data but not the transaction graph
+type hidingTMS struct {
+	tokenapi.TokenManagerService
+}
+
+func (s *hidingTMS) PublicParamsManager() tokenapi.PublicParamsManager {
+	return &hidingPPM{}
+}
+
+func (s *hidingTMS) DeserializeIssueAction(raw []byte) (tokenapi.IssueAction, error) {
+	return &issueAction{issuer: raw}, nil
+}
+
+type hidingPPM struct {
+	tokenapi.PublicParamsManager
+}
+
+func (p *hidingPPM) PublicParameters() tokenapi.PublicParameters {
+	return &hidingPP{}
+}
+
+type hidingPP struct {
+	tokenapi.PublicParameters
+}
+
+func (p *hidingPP) TokenDataHiding() bool {
+	return true
+}
+
+func (p *hidingPP) GraphHiding() bool {
+	return false
+}
+
+type issueAction struct {
+	tokenapi.IssueAction
+	issuer []byte
+}
+
+func (a *issueAction) IsAnonymous() bool {
+	return false
+}
+
+func (a *issueAction) GetIssuer() []byte {
+	return a.issuer
+}
+
+func TestDisclosureReportLedgerFields(t *testing.T) {
+	alice, bob := view.Identity("alice"), view.Identity("bob")
+	request := NewRequest(&ManagementService{tms: &hidingTMS{}}, "tx1")
+	request.Actions.Issues = [][]byte{[]byte("issuer")}
+	request.Actions.Transfers = [][]byte{[]byte("transfer")}
+	request.Actions.BurnReceipts = []*BurnReceipt{{TransferIndex: 0, OutputIndex: 1, Type: "USD", Quantity: "5", EnrollmentID: "alice"}}
+	request.Metadata.Issues = []tokenapi.IssueMetadata{{Outputs: [][]byte{[]byte("output")}, Receivers: []view.Identity{alice}}}
+	request.Metadata.Transfers = []tokenapi.TransferMetadata{{
+		TokenIDs:  []*token2.Id{{TxId: "tx0"}},
+		Senders:   []view.Identity{alice},
+		Outputs:   [][]byte{[]byte("output"), []byte("redeemed")},
+		Receivers: []view.Identity{bob, nil},
+	}}
+
+	report, err := request.DisclosureReport(true)
+	assert.NoError(t, err)
+	assert.Equal(t, []*LedgerField{
+		{Action: "issue", Name: "issuer", Value: view.Identity("issuer").String()},
+		{Action: "transfer", Name: "input", Value: (&token2.Id{TxId: "tx0"}).String()},
+		{Action: "burn receipt", Name: "transfer", Value: "0"},
+		{Action: "burn receipt", Name: "output", Value: "1"},
+		{Action: "burn receipt", Name: "type", Value: "USD"},
+		{Action: "burn receipt", Name: "quantity", Value: "5"},
+		{Action: "burn receipt", Name: "enrollment id", Value: "alice"},
+	}, report.Ledger)
+
+	// the observers learn the redeemed quantity and the enrollment ID of the redeemer, despite the hiding of the token data
+	var observer *PartyDisclosure
+	for _, p := range report.Parties {
+		if len(p.Roles) == 1 && p.Roles[0] == ObserverRole {
+			observer = p
+		}
+	}
+	assert.NotNil(t, observer)
+	assert.Equal(t, []*Disclosure{
+		{Action: "issue", Index: 0, Owner: true},
+		{Action: "transfer", Input: true, Index: 0, Owner: true, EnrollmentID: true},
+		{Action: "transfer", Index: 0, Owner: true},
+		{Action: "transfer", Index: 1, Owner: true, Type: true, Quantity: true},
+	}, observer.Disclosures)
+}
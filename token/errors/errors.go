@@ -0,0 +1,99 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package errors
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Code classifies the cause of a failure so that callers can branch on it
+type Code string
+
+const (
+	// InsufficientFunds signals that the available tokens do not cover the requested amount
+	InsufficientFunds Code = "insufficient-funds"
+	// DoubleSpend signals that a token has already been spent
+	DoubleSpend Code = "double-spend"
+	// InvalidProof signals that a zero-knowledge proof, or an equivalent check, did not verify
+	InvalidProof Code = "invalid-proof"
+	// Unauthorized signals that a party is not entitled to perform an operation
+	Unauthorized Code = "unauthorized"
+	// AlreadyExists signals that an entity with the same key already exists
+	AlreadyExists Code = "already-exists"
+	// Conflict signals that the state an operation depends on has changed
+	Conflict Code = "conflict"
+)
+
+// Error is an error carrying a Code.
+// Error does not implement Cause, therefore errors.Cause still returns the error itself,
+// this allows sentinel errors to be defined with New and compared with errors.Cause.
+type Error struct {
+	code Code
+	msg  string
+	err  error
+}
+
+// New returns a new error with the passed code and message
+func New(code Code, msg string) error {
+	return &Error{code: code, msg: msg}
+}
+
+// Errorf returns a new error with the passed code and formatted message
+func Errorf(code Code, format string, args ...interface{}) error {
+	return &Error{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// Wrap annotates the passed error with the passed code. If err is nil, Wrap returns nil.
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{code: code, err: err}
+}
+
+// Wrapf annotates the passed error with the passed code and formatted message. If err is nil, Wrapf returns nil.
+func Wrapf(code Code, err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{code: code, msg: fmt.Sprintf(format, args...), err: err}
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.err == nil:
+		return e.msg
+	case len(e.msg) == 0:
+		return e.err.Error()
+	default:
+		return e.msg + ": " + e.err.Error()
+	}
+}
+
+func (e *Error) Code() Code {
+	return e.code
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// GetCode returns the code of the first error in the chain of the passed error that carries one,
+// the empty code if none does.
+func GetCode(err error) Code {
+	var coded interface{ Code() Code }
+	if errors.As(err, &coded) {
+		return coded.Code()
+	}
+	return ""
+}
+
+// HasCode returns true if the passed error carries the passed code
+func HasCode(err error, code Code) bool {
+	return len(code) != 0 && GetCode(err) == code
+}
@@ -0,0 +1,34 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package errors
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodes(t *testing.T) {
+	sentinel := New(InsufficientFunds, "insufficient funds")
+	err := errors.WithMessagef(sentinel, "only [%d] available", 5)
+	assert.Equal(t, InsufficientFunds, GetCode(err))
+	assert.True(t, HasCode(err, InsufficientFunds))
+	assert.False(t, HasCode(err, DoubleSpend))
+	assert.Equal(t, sentinel, errors.Cause(err))
+	assert.Equal(t, "only [5] available: insufficient funds", err.Error())
+
+	err = Wrapf(Unauthorized, errors.New("bad signature"), "failed verifying [%s]", "alice")
+	assert.Equal(t, "failed verifying [alice]: bad signature", err.Error())
+	assert.Equal(t, Unauthorized, GetCode(errors.Wrap(err, "failed")))
+	assert.Equal(t, "bad signature", Wrap(DoubleSpend, errors.New("bad signature")).Error())
+
+	assert.Nil(t, Wrap(DoubleSpend, nil))
+	assert.Nil(t, Wrapf(DoubleSpend, nil, "ignored"))
+	assert.Equal(t, Code(""), GetCode(errors.New("plain")))
+	assert.Equal(t, Code(""), GetCode(nil))
+	assert.False(t, HasCode(nil, ""))
+}
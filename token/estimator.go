@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"github.com/pkg/errors"
+
+	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// CostEstimate predicts the size, the proofs, the signatures, and the endorsement CPU time of a token request
+type CostEstimate = tokenapi.CostEstimate
+
+// Estimator predicts the cost of the token requests of a TMS, as produced by its driver,
+// for the clients to budget and batch their transactions
+type Estimator struct {
+	ms    *ManagementService
+	model tokenapi.CostModel
+}
+
+// Estimator returns the estimator of the requests of this TMS, an error if the driver does not estimate costs
+func (t *ManagementService) Estimator() (*Estimator, error) {
+	model, ok := t.tms.(tokenapi.CostModel)
+	if !ok {
+		return nil, errors.Errorf("driver of [%s] does not estimate costs", t)
+	}
+	return &Estimator{ms: t, model: model}, nil
+}
+
+// Issue returns the cost of an issue action of the passed values
+func (e *Estimator) Issue(values []uint64) *CostEstimate {
+	return e.model.EstimateIssue(len(values))
+}
+
+// Transfer returns the cost of a transfer, from the passed wallet, of the passed values of the passed type.
+// The inputs are the tokens the selector would pick now, they are not locked. The change, if any, is an extra output.
+func (e *Estimator) Transfer(wallet *OwnerWallet, typ string, values []uint64) (*CostEstimate, error) {
+	total := token2.NewQuantityFromUInt64(0)
+	for _, v := range values {
+		total = total.Add(token2.NewQuantityFromUInt64(v))
+	}
+	sm, err := e.ms.SelectorManager()
+	if err != nil {
+		return nil, err
+	}
+	ids, sum, err := sm.Peek(wallet, total.Decimal(), typ)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed estimating the inputs of [%s:%s]", total.Decimal(), typ)
+	}
+	outputs := len(values)
+	if sum.Cmp(total) > 0 {
+		outputs++
+	}
+	return e.model.EstimateTransfer(len(ids), outputs), nil
+}
+
+// Request returns the cost of a token request made of the actions whose costs are passed
+func (e *Estimator) Request(actions ...*CostEstimate) *CostEstimate {
+	return e.model.EstimateRequest().Add(actions...)
+}
@@ -17,6 +17,7 @@ import (
 type queryService interface {
 	// DeserializeToken returns the token and its issuer (if any).
 	DeserializeToken(outputRaw []byte, tokenInfoRaw []byte) (*token2.Token, view.Identity, error)
+	GetEnrollmentID(auditInfo []byte) (string, error)
 }
 
 type Metadata struct {
@@ -37,6 +38,20 @@ func (m *Metadata) GetToken(raw []byte) (*token2.Token, view.Identity, []byte, e
 	return tok, id, tokenInfoRaw, nil
 }
 
+// GetEnrollmentID returns the enrollment ID of the owner of the passed output.
+// It returns the empty string if the output carries no audit info, as it happens for redeemed outputs.
+func (m *Metadata) GetEnrollmentID(raw []byte) (string, error) {
+	auditInfo := m.tokenRequestMetadata.GetAuditInfo(raw)
+	if len(auditInfo) == 0 {
+		return "", nil
+	}
+	eID, err := m.queryService.GetEnrollmentID(auditInfo)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed getting enrollment id for [%s]", hash.Hashable(raw).String())
+	}
+	return eID, nil
+}
+
 func (m *Metadata) SpentTokenID() []*token2.Id {
 	var res []*token2.Id
 	for _, transfer := range m.tokenRequestMetadata.Transfers {
@@ -44,3 +59,45 @@ func (m *Metadata) SpentTokenID() []*token2.Id {
 	}
 	return res
 }
+
+// SenderEnrollmentIDs returns the enrollment IDs of the senders of the transfers, where disclosed
+func (m *Metadata) SenderEnrollmentIDs() []string {
+	var auditInfos [][]byte
+	for _, transfer := range m.tokenRequestMetadata.Transfers {
+		auditInfos = append(auditInfos, transfer.SenderAuditInfos...)
+	}
+	return m.enrollmentIDs(auditInfos)
+}
+
+// ReceiverEnrollmentIDs returns the enrollment IDs of the recipients of the issues and transfers, where disclosed
+func (m *Metadata) ReceiverEnrollmentIDs() []string {
+	var auditInfos [][]byte
+	for _, issue := range m.tokenRequestMetadata.Issues {
+		auditInfos = append(auditInfos, issue.AuditInfos...)
+	}
+	for _, transfer := range m.tokenRequestMetadata.Transfers {
+		auditInfos = append(auditInfos, transfer.ReceiverAuditInfos...)
+	}
+	return m.enrollmentIDs(auditInfos)
+}
+
+func (m *Metadata) enrollmentIDs(auditInfos [][]byte) []string {
+	var res []string
+	found := map[string]bool{}
+	for _, auditInfo := range auditInfos {
+		if len(auditInfo) == 0 {
+			continue
+		}
+		eID, err := m.queryService.GetEnrollmentID(auditInfo)
+		if err != nil {
+			logger.Debugf("failed getting enrollment id, skipping [%s]", err)
+			continue
+		}
+		if len(eID) == 0 || found[eID] {
+			continue
+		}
+		found[eID] = true
+		res = append(res, eID)
+	}
+	return res
+}
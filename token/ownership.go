@@ -0,0 +1,138 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+
+	api2 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// ProveOwnership returns a proof that this wallet controls the passed unspent token, without spending it.
+// The proof answers the passed challenge, chosen by the verifier to prevent replays, and can be checked
+// with an OwnershipVerifier.
+func (o *OwnerWallet) ProveOwnership(id *token2.Id, challenge []byte) ([]byte, error) {
+	if id == nil {
+		return nil, errors.New("token id not specified")
+	}
+	if len(challenge) == 0 {
+		return nil, errors.New("challenge not specified")
+	}
+	if o.ms == nil {
+		return nil, errors.New("vault not available")
+	}
+	qe := o.ms.Vault().NewQueryEngine()
+	tokens, err := qe.GetTokens(id)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "token [%s] is not unspent", id)
+	}
+	owner := tokens[0].Owner.Raw
+	if !o.Contains(owner) {
+		return nil, errors.Errorf("token [%s] is not owned by wallet [%s]", id, o.ID())
+	}
+	var output []byte
+	if err := qe.GetTokenCommitments([]*token2.Id{id}, func(_ *token2.Id, raw []byte) error {
+		output = raw
+		return nil
+	}); err != nil {
+		return nil, errors.WithMessagef(err, "failed loading token [%s]", id)
+	}
+	if len(output) == 0 {
+		return nil, errors.Errorf("token [%s] not found on the ledger", id)
+	}
+
+	proof := &api2.OwnershipProof{ID: id, Output: output, Challenge: challenge}
+	msg, err := proof.MessageToSign()
+	if err != nil {
+		return nil, err
+	}
+	signer, err := o.GetSigner(owner)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting signer for the owner of [%s]", id)
+	}
+	proof.Signature, err = signer.Sign(msg)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed signing ownership proof for [%s]", id)
+	}
+	return proof.Serialize()
+}
+
+// OwnershipVerifier verifies the proofs returned by OwnerWallet.ProveOwnership.
+// It needs only the public parameters and access to the ledger, therefore it can be used by off-chain services.
+type OwnershipVerifier struct {
+	validator api2.Validator
+	keys      *keys.Scheme
+}
+
+// NewOwnershipVerifier returns a new verifier for the passed serialized public parameters
+func NewOwnershipVerifier(params []byte) (*OwnershipVerifier, error) {
+	pp, err := checkedPublicParametersFromBytes(params)
+	if err != nil {
+		return nil, err
+	}
+	validator, err := core.NewValidator(pp)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed instantiating validator")
+	}
+	return &OwnershipVerifier{validator: validator, keys: keys.Default}, nil
+}
+
+// SetKeyScheme sets the scheme VerifyOnLedger derives the ledger keys of the tokens with,
+// it must match the one of the token application the proofs refer to
+func (v *OwnershipVerifier) SetKeyScheme(scheme *keys.Scheme) {
+	v.keys = scheme
+}
+
+// Verify checks that the passed proof answers the passed challenge, that it refers to the passed output,
+// the token as currently stored on the ledger, and that it has been signed by the owner of the token.
+// An empty output means that the token has been spent. It returns the id of the token.
+func (v *OwnershipVerifier) Verify(raw []byte, challenge []byte, output []byte) (*token2.Id, error) {
+	proof := &api2.OwnershipProof{}
+	if err := proof.Deserialize(raw); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling ownership proof")
+	}
+	if proof.ID == nil {
+		return nil, errors.New("invalid ownership proof, token id not specified")
+	}
+	if len(challenge) == 0 || !bytes.Equal(proof.Challenge, challenge) {
+		return nil, errors.Errorf("ownership proof for [%s] does not answer the challenge", proof.ID)
+	}
+	if len(output) == 0 {
+		return nil, errors.Errorf("token [%s] is not unspent", proof.ID)
+	}
+	if !bytes.Equal(proof.Output, output) {
+		return nil, errors.Errorf("ownership proof for [%s] does not match the ledger", proof.ID)
+	}
+	if err := v.validator.VerifyOwnership(proof); err != nil {
+		return nil, err
+	}
+	return proof.ID, nil
+}
+
+// VerifyOnLedger behaves like Verify, reading the output from the passed ledger
+func (v *OwnershipVerifier) VerifyOnLedger(ledger Ledger, raw []byte, challenge []byte) (*token2.Id, error) {
+	proof := &api2.OwnershipProof{}
+	if err := proof.Deserialize(raw); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling ownership proof")
+	}
+	if proof.ID == nil {
+		return nil, errors.New("invalid ownership proof, token id not specified")
+	}
+	key, err := v.keys.CreateTokenKey(proof.ID.TxId, int(proof.ID.Index))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating key for [%s]", proof.ID)
+	}
+	output, err := ledger.GetState(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading token [%s] from the ledger", proof.ID)
+	}
+	return v.Verify(raw, challenge, output)
+}
@@ -6,7 +6,10 @@ SPDX-License-Identifier: Apache-2.0
 package token
 
 import (
+	"time"
+
 	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 type Normalizer interface {
@@ -20,10 +23,22 @@ type VaultProvider interface {
 type SelectorManager interface {
 	NewSelector(id string) (Selector, error)
 	Unlock(txID string) error
+	// UnlockIDs releases the passed tokens, whoever holds them
+	UnlockIDs(ids ...*token2.Id) error
+	// Reserve locks tokens of the passed type, owned as filtered, for at least the passed amount, until released
+	// or until the passed time to live elapses, forever if zero
+	Reserve(ownerFilter OwnerFilter, amount, tokenType string, ttl time.Duration) (*Reservation, error)
+	// Release unlocks the tokens of the passed reservation
+	Release(reservationID string) error
+	// ReleaseSpent releases the reservations holding any of the passed tokens, spent by a committed transaction
+	ReleaseSpent(ids ...*token2.Id) error
+	// Peek returns the tokens a selection of the passed amount would pick now. It only reads the unspent tokens,
+	// it does not lock them, nor it waits for the ones locked by other selections
+	Peek(ownerFilter OwnerFilter, amount, tokenType string) ([]*token2.Id, token2.Quantity, error)
 }
 
 type SelectorManagerProvider interface {
-	SelectorManager(network string, channel string, namespace string) SelectorManager
+	SelectorManager(network string, channel string, namespace string) (SelectorManager, error)
 }
 
 type CertificationClientProvider interface {
@@ -38,6 +53,7 @@ type ManagementServiceProvider struct {
 	selectorManagerProvider     SelectorManagerProvider
 	vaultProvider               VaultProvider
 	sigService                  tokenapi.SigService
+	balances                    *balanceBrokers
 }
 
 func NewManagementServiceProvider(
@@ -57,6 +73,7 @@ func NewManagementServiceProvider(
 		certificationClientProvider: certificationClientProvider,
 		selectorManagerProvider:     selectorManagerProvider,
 		sigService:                  sigService,
+		balances:                    newBalanceBrokers(),
 	}
 }
 
@@ -90,6 +107,7 @@ func (p *ManagementServiceProvider) GetManagementService(opts ...ServiceOption)
 		certificationClientProvider: p.certificationClientProvider,
 		selectorManagerProvider:     p.selectorManagerProvider,
 		signatureService:            &SignatureService{p.sigService},
+		balances:                    p.balances.get(opt.Network, opt.Channel, opt.Namespace),
 	}
 }
 
@@ -5,7 +5,11 @@ SPDX-License-Identifier: Apache-2.0
 */
 package token
 
-import tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+
+	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
 
 type PublicParamsFetcher interface {
 	Fetch() ([]byte, error)
@@ -27,6 +31,83 @@ func (c *PublicParametersManager) AddIssuer(bytes []byte) ([]byte, error) {
 	return c.ppm.AddIssuer(bytes)
 }
 
+// SetIssueApprover sets the identity that must co-sign the issue actions of the passed token type.
+// An empty approver removes the requirement.
+func (c *PublicParametersManager) SetIssueApprover(tokenType string, approver []byte) ([]byte, error) {
+	return c.ppm.SetIssueApprover(tokenType, approver)
+}
+
+// AuditorIdentity returns the identity of the auditor, nil if the deployment has no auditor
+func (c *PublicParametersManager) AuditorIdentity() view.Identity {
+	return c.ppm.PublicParameters().AuditorIdentity()
+}
+
+// AuditScope returns what the auditor can see, everything if the driver does not support audit scopes
+func (c *PublicParametersManager) AuditScope() tokenapi.AuditScope {
+	scopes, ok := c.ppm.PublicParameters().(tokenapi.AuditScopes)
+	if !ok {
+		return tokenapi.FullAuditScope
+	}
+	return scopes.GetAuditScope()
+}
+
+// SetTypeAuditor assigns the auditor of the token requests on the passed token type.
+// An empty auditor reassigns the type to the default auditor.
+func (c *PublicParametersManager) SetTypeAuditor(tokenType string, auditor []byte) ([]byte, error) {
+	return c.ppm.SetTypeAuditor(tokenType, auditor)
+}
+
+// TypeAuditor returns the auditor of the token requests on the passed token type, the default auditor if none
+func (c *PublicParametersManager) TypeAuditor(tokenType string) view.Identity {
+	return c.ppm.PublicParameters().TypeAuditor(tokenType)
+}
+
+// RequestAuditors returns the auditors that must sign a token request on the passed token types, in order
+func (c *PublicParametersManager) RequestAuditors(types []string) []view.Identity {
+	return tokenapi.RequestAuditors(c.ppm.PublicParameters(), types)
+}
+
+// IssueApprover returns the identity that must co-sign the issue actions of the passed token type, nil if none
+func (c *PublicParametersManager) IssueApprover(tokenType string) view.Identity {
+	return c.ppm.PublicParameters().IssueApprover(tokenType)
+}
+
+// SetRedeemRequiresIssuer sets whether the redemptions must be co-signed by an issuer of the redeemed type
+func (c *PublicParametersManager) SetRedeemRequiresIssuer(required bool) ([]byte, error) {
+	return c.ppm.SetRedeemRequiresIssuer(required)
+}
+
+// RedeemRequiresIssuer returns true if the redemptions must be co-signed by an issuer of the redeemed type
+func (c *PublicParametersManager) RedeemRequiresIssuer() bool {
+	return c.ppm.PublicParameters().RedeemRequiresIssuer()
+}
+
+// SupplyCap returns the cap of the total value that can be issued of the passed token type,
+// false if uncapped or if the driver does not support supply caps
+func (c *PublicParametersManager) SupplyCap(tokenType string) (uint64, bool) {
+	caps, ok := c.ppm.PublicParameters().(tokenapi.SupplyCaps)
+	if !ok {
+		return 0, false
+	}
+	return caps.SupplyCap(tokenType)
+}
+
+// AuditThreshold returns the total value under which the token requests made of transfers only can skip the auditors,
+// 0 if every request is audited or if the driver does not support audit thresholds
+func (c *PublicParametersManager) AuditThreshold() uint64 {
+	return tokenapi.AuditThreshold(c.ppm.PublicParameters())
+}
+
+// SelfCheck checks that the public parameters are well-formed,
+// it returns nil if the driver does not support the check
+func (c *PublicParametersManager) SelfCheck() error {
+	checkable, ok := c.ppm.PublicParameters().(tokenapi.SelfCheckable)
+	if !ok {
+		return nil
+	}
+	return checkable.SelfCheck()
+}
+
 func (c *PublicParametersManager) CertificationDriver() string {
 	return c.ppm.PublicParameters().CertificationDriver()
 }
@@ -6,7 +6,11 @@ SPDX-License-Identifier: Apache-2.0
 package token
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -14,16 +18,60 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 
 	api2 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tracing"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
+// DefaultTransferRetries is the number of times a transfer is prepared again, with freshly selected inputs,
+// when the selected inputs get spent by a concurrent transaction before the transfer is computed
+const DefaultTransferRetries = 3
+
 type TransferOptions struct {
 	Selector Selector
 	TokenIDs []*token2.Id
+	// BurnReference is the reference data recorded in the burn receipt of a redeem
+	BurnReference []byte
+	// RedeemIssuer is the issuer co-signing a redeem, see WithRedeemIssuer
+	RedeemIssuer view.Identity
+	// Retries is the number of times the inputs are selected again when the selected ones get spent concurrently
+	Retries int
+	// ChangePolicy controls how the change is returned to the sender, nil for a single output
+	ChangePolicy *ChangePolicy
+	// AnonymousChange, if true, assigns the change to fresh pseudonyms of the sender, see WithAnonymousChange
+	AnonymousChange bool
+	// TimeLock, if not nil, locks the outputs assigned to the recipients, see WithTimeLock
+	TimeLock *TimeLockOptions
+	// Context carries the span the spans of the selection and of the proof generation are children of
+	Context context.Context
+	// MaxInputs, if not zero, bounds the number of inputs the selector can pick, see WithMaxInputs
+	MaxInputs int
+}
+
+// TooManyInputsError is returned when the selection needs more inputs than allowed by WithMaxInputs.
+// IDs are the selected inputs, they stay locked by the transaction.
+type TooManyInputsError struct {
+	Type string
+	IDs  []*token2.Id
+	Max  int
+}
+
+func (e *TooManyInputsError) Error() string {
+	return fmt.Sprintf("selection of type [%s] needs [%d] inputs, more than [%d]", e.Type, len(e.IDs), e.Max)
+}
+
+// TimeLockOptions are the constraints of the time locked outputs of a transfer, zero values mean no constraint
+type TimeLockOptions struct {
+	NotBefore       time.Time
+	NotBeforeHeight uint64
 }
 
 func compileTransferOptions(opts ...TransferOption) (*TransferOptions, error) {
-	txOptions := &TransferOptions{}
+	txOptions := &TransferOptions{
+		Retries: DefaultTransferRetries,
+		Context: context.Background(),
+	}
 	for _, opt := range opts {
 		if err := opt(txOptions); err != nil {
 			return nil, err
@@ -48,6 +96,132 @@ func WithTokenIDs(ids ...*token2.Id) TransferOption {
 	}
 }
 
+// WithTransferRetries sets the number of times the inputs are selected again, and the transfer prepared again,
+// when the selected inputs get spent by a concurrent transaction. Zero disables the retries.
+func WithTransferRetries(retries int) TransferOption {
+	return func(o *TransferOptions) error {
+		if retries < 0 {
+			return errors.Errorf("invalid number of retries [%d]", retries)
+		}
+		o.Retries = retries
+		return nil
+	}
+}
+
+// WithChangePolicy sets the policy used to return the change of the transfer to the sender
+func WithChangePolicy(policy *ChangePolicy) TransferOption {
+	return func(o *TransferOptions) error {
+		if policy != nil {
+			if err := policy.Validate(); err != nil {
+				return err
+			}
+		}
+		o.ChangePolicy = policy
+		return nil
+	}
+}
+
+// WithAnonymousChange assigns the change of the transfer to fresh pseudonyms of the sender, derived for this
+// transfer only, whatever the pseudonym policy of the wallet, so that the change cannot be linked to the identities
+// the sender published. The pseudonyms are registered with the wallet, and their audit information is available
+// to the auditor, as for any other recipient identity. Wallets using their long-term identity ignore it.
+func WithAnonymousChange() TransferOption {
+	return func(o *TransferOptions) error {
+		o.AnonymousChange = true
+		return nil
+	}
+}
+
+// WithTimeLock locks the outputs assigned to the recipients of the transfer: the recipients can spend them,
+// with a regular transfer, only from the passed time, as fixed by the ledger, and from the passed ledger height.
+// The change returned to the sender is not locked.
+func WithTimeLock(notBefore time.Time, notBeforeHeight uint64) TransferOption {
+	return func(o *TransferOptions) error {
+		if notBefore.IsZero() && notBeforeHeight == 0 {
+			return errors.New("time lock has no constraint")
+		}
+		o.TimeLock = &TimeLockOptions{NotBefore: notBefore, NotBeforeHeight: notBeforeHeight}
+		return nil
+	}
+}
+
+// WithMaxInputs bounds the number of inputs the selector can pick, so that the proof and the validation
+// of the transfer stay within the limits of the driver and of the validator. If more inputs are needed,
+// the transfer fails with a TooManyInputsError, the inputs can then be merged first, see ttxcc.Transaction.ChainedTransfer.
+func WithMaxInputs(max int) TransferOption {
+	return func(o *TransferOptions) error {
+		if max < 2 {
+			return errors.Errorf("invalid maximum number of inputs [%d], at least 2 expected", max)
+		}
+		o.MaxInputs = max
+		return nil
+	}
+}
+
+// WithTransferContext sets the context carrying the span the spans of the transfer are children of, see tracing.Tracer
+func WithTransferContext(ctx context.Context) TransferOption {
+	return func(o *TransferOptions) error {
+		o.Context = ctx
+		return nil
+	}
+}
+
+// WithBurnReference sets the reference data recorded in the burn receipt of a redeem,
+// for example the identifier of the off-chain settlement of the redemption
+func WithBurnReference(reference []byte) TransferOption {
+	return func(o *TransferOptions) error {
+		o.BurnReference = reference
+		return nil
+	}
+}
+
+// WithRedeemIssuer sets the issuer of the redeemed type that co-signs a redeem.
+// It is required if the public parameters demand it, see PublicParametersManager.RedeemRequiresIssuer.
+func WithRedeemIssuer(issuer view.Identity) TransferOption {
+	return func(o *TransferOptions) error {
+		o.RedeemIssuer = issuer
+		return nil
+	}
+}
+
+type IssueOptions struct {
+	Expiration time.Time
+	// Context carries the span the span of the proof generation is child of
+	Context context.Context
+}
+
+func compileIssueOptions(opts ...IssueOption) (*IssueOptions, error) {
+	txOptions := &IssueOptions{Context: context.Background()}
+	for _, opt := range opts {
+		if err := opt(txOptions); err != nil {
+			return nil, err
+		}
+	}
+	return txOptions, nil
+}
+
+type IssueOption func(*IssueOptions) error
+
+// WithExpiration sets the time after which the issued tokens cannot be spent anymore by their owners.
+// Expired tokens can be reclaimed by their issuer, see Request.Reclaim.
+// Only the fabtoken driver records the expiration, in the clear in the issued tokens, and enforces it.
+// The zkatdlog driver rejects the issues with an expiration: its tokens are commitments, they carry no expiration
+// the validators could check.
+func WithExpiration(expiration time.Time) IssueOption {
+	return func(o *IssueOptions) error {
+		o.Expiration = expiration
+		return nil
+	}
+}
+
+// WithIssueContext sets the context carrying the span the spans of the issue are children of, see tracing.Tracer
+func WithIssueContext(ctx context.Context) IssueOption {
+	return func(o *IssueOptions) error {
+		o.Context = ctx
+		return nil
+	}
+}
+
 type AuditRecord struct {
 	TxID   string
 	Inputs *InputStream
@@ -57,6 +231,8 @@ type AuditRecord struct {
 type Issue struct {
 	Issuer    view.Identity
 	Receivers []view.Identity
+	// Approver, if not none, is the identity that must co-sign the issue
+	Approver view.Identity
 }
 
 type Transfer struct {
@@ -64,6 +240,18 @@ type Transfer struct {
 	Receivers []view.Identity
 }
 
+// BurnReceipt records on the ledger the redemption of an output
+type BurnReceipt = api2.BurnReceipt
+
+// Attachment references a payload attached to a transfer and stored off the request, see Request.Attach
+type Attachment = api2.Attachment
+
+// SwapTerms declare the exchange rate between two outputs of the transfers of a request, see Request.SetSwapTerms
+type SwapTerms = api2.SwapTerms
+
+// SwapLeg declares an output of a swap, see Request.NewSwapLeg
+type SwapLeg = api2.SwapLeg
+
 type Request struct {
 	TxID         string
 	Actions      *api2.TokenRequest
@@ -103,9 +291,29 @@ func (t *Request) ID() string {
 	return t.TxID
 }
 
-func (t *Request) Issue(wallet *IssuerWallet, receiver view.Identity, typ string, q uint64) (*IssueAction, error) {
-	if receiver.IsNone() {
-		return nil, errors.Errorf("all recipients should be defined")
+func (t *Request) Issue(wallet *IssuerWallet, receiver view.Identity, typ string, q uint64, opts ...IssueOption) (*IssueAction, error) {
+	return t.BatchIssue(wallet, []view.Identity{receiver}, typ, []uint64{q}, opts...)
+}
+
+// BatchIssue appends a single issue action creating a token of the passed type for each receiver and value.
+// Compared to multiple calls to Issue, the proofs for all the outputs are generated at once.
+func (t *Request) BatchIssue(wallet *IssuerWallet, receivers []view.Identity, typ string, values []uint64, opts ...IssueOption) (*IssueAction, error) {
+	if len(receivers) == 0 {
+		return nil, errors.Errorf("at least one recipient should be defined")
+	}
+	if len(receivers) != len(values) {
+		return nil, errors.Errorf("number of recipients [%d] does not match number of values [%d]", len(receivers), len(values))
+	}
+	owners := make([][]byte, len(receivers))
+	for i, receiver := range receivers {
+		if receiver.IsNone() {
+			return nil, errors.Errorf("all recipients should be defined")
+		}
+		owners[i] = receiver
+	}
+	issueOpts, err := compileIssueOptions(opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed compiling issue options [%v]", opts)
 	}
 
 	id, err := wallet.GetIssuerIdentity(typ)
@@ -114,7 +322,7 @@ func (t *Request) Issue(wallet *IssuerWallet, receiver view.Identity, typ string
 	}
 
 	// Compute Issue
-	issue, tokenInfos, issuer, err := t.TokenService.tms.Issue(id, typ, []uint64{q}, [][]byte{receiver})
+	issue, tokenInfos, issuer, err := t.computeIssue(issueOpts, id, typ, values, owners)
 	if err != nil {
 		return nil, err
 	}
@@ -131,9 +339,17 @@ func (t *Request) Issue(wallet *IssuerWallet, receiver view.Identity, typ string
 		return nil, err
 	}
 
-	auditInfo, err := t.TokenService.tms.GetAuditInfo(receiver)
-	if err != nil {
-		return nil, err
+	var auditInfos [][]byte
+	if auditable, ok := issue.(api2.AuditableIssueAction); ok && len(auditable.GetAuditInfos()) != 0 {
+		auditInfos = auditable.GetAuditInfos()
+	} else if ppm := t.TokenService.PublicParametersManager(); ppm.TypeAuditor(typ) != nil && ppm.AuditScope().Identities() {
+		auditInfos = make([][]byte, len(receivers))
+		for i, receiver := range receivers {
+			auditInfos[i], err = t.TokenService.tms.GetAuditInfo(receiver)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	t.Metadata.Issues = append(t.Metadata.Issues,
@@ -141,8 +357,9 @@ func (t *Request) Issue(wallet *IssuerWallet, receiver view.Identity, typ string
 			Issuer:     issuer,
 			Outputs:    outputs,
 			TokenInfo:  tokenInfos,
-			Receivers:  []view.Identity{receiver},
-			AuditInfos: [][]byte{auditInfo},
+			Receivers:  receivers,
+			AuditInfos: auditInfos,
+			Approver:   t.TokenService.PublicParametersManager().IssueApprover(typ),
 		},
 	)
 
@@ -150,23 +367,9 @@ func (t *Request) Issue(wallet *IssuerWallet, receiver view.Identity, typ string
 }
 
 func (t *Request) Transfer(wallet *OwnerWallet, typ string, values []uint64, owners []view.Identity, opts ...TransferOption) (*TransferAction, error) {
-	tokenIDs, outputTokens, err := t.prepareTransfer(false, wallet, typ, values, owners, opts...)
+	transfer, transferMetadata, _, err := t.transfer(false, wallet, typ, values, owners, opts...)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed preparing transfer")
-	}
-
-	logger.Debugf("Prepare Transfer Action [id:%s,ins:%d,outs:%d]", t.TxID, len(tokenIDs), len(outputTokens))
-
-	ts := t.TokenService.tms
-
-	// Compute transfer
-	transfer, transferMetadata, err := ts.Transfer(t.TxID, wallet.w, tokenIDs, outputTokens...)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed creating transfer action")
-	}
-	// double check
-	if err := ts.VerifyTransfer(transfer, transferMetadata.TokenInfo); err != nil {
-		return nil, errors.Wrap(err, "failed checking generated proof")
+		return nil, err
 	}
 
 	// Append
@@ -180,36 +383,82 @@ func (t *Request) Transfer(wallet *OwnerWallet, typ string, values []uint64, own
 	return &TransferAction{a: transfer}, nil
 }
 
+// Redeem appends to the request a transfer action that redeems the passed value, and the burn receipt of the redemption.
+// The burn receipt is stored on the ledger when the request is committed.
 func (t *Request) Redeem(wallet *OwnerWallet, typ string, value uint64, opts ...TransferOption) error {
-	tokenIDs, outputTokens, err := t.prepareTransfer(true, wallet, typ, []uint64{value}, []view.Identity{nil}, opts...)
+	transferOpts, err := compileTransferOptions(opts...)
 	if err != nil {
-		return errors.Wrap(err, "failed preparing transfer")
+		return errors.Wrapf(err, "failed compiling transfer options [%v]", opts)
+	}
+	if transferOpts.RedeemIssuer.IsNone() && t.TokenService.PublicParametersManager().RedeemRequiresIssuer() {
+		return errors.Errorf("redeem of [%s] requires the co-signature of an issuer, see WithRedeemIssuer", typ)
+	}
+	// Compute redeem, it is a transfer with owner set to nil
+	transfer, transferMetadata, outputTokens, err := t.transfer(true, wallet, typ, []uint64{value}, []view.Identity{nil}, opts...)
+	if err != nil {
+		return err
 	}
 
-	logger.Debugf("Prepare Redeem Action [ins:%d,outs:%d]", len(tokenIDs), len(outputTokens))
+	// Append
+	raw, err := transfer.Serialize()
+	if err != nil {
+		return errors.Wrap(err, "failed serializing transfer action")
+	}
+	t.Actions.Transfers = append(t.Actions.Transfers, raw)
+	t.Metadata.Transfers = append(t.Metadata.Transfers, *transferMetadata)
 
-	ts := t.TokenService.tms
+	// the redeemed output is the first one, the second, if any, is the rest
+	if !transfer.IsRedeemAt(0) {
+		return errors.Errorf("expected redeemed output at index 0")
+	}
+	var tokenInfo []byte
+	if len(transferMetadata.TokenInfo) != 0 {
+		tokenInfo = transferMetadata.TokenInfo[0]
+	}
+	t.Actions.BurnReceipts = append(t.Actions.BurnReceipts, &api2.BurnReceipt{
+		TransferIndex: len(t.Actions.Transfers) - 1,
+		OutputIndex:   0,
+		Type:          typ,
+		Quantity:      outputTokens[0].Quantity,
+		EnrollmentID:  wallet.EnrollmentID(),
+		Reference:     transferOpts.BurnReference,
+		TokenInfo:     tokenInfo,
+		Issuer:        transferOpts.RedeemIssuer,
+	})
 
-	// Compute redeem, it is a transfer with owner set to nil
-	transfer, transferMetadata, err := ts.Transfer(t.TxID, wallet.w, tokenIDs, outputTokens...)
+	return nil
+}
+
+// Reclaim appends to the request a transfer action that moves the value of the passed expired tokens,
+// issued by the passed wallet, to the passed receiver.
+// Only the fabtoken driver supports it, see WithExpiration.
+func (t *Request) Reclaim(wallet *IssuerWallet, typ string, receiver view.Identity, ids ...*token2.Id) (*TransferAction, error) {
+	id, err := wallet.GetIssuerIdentity(typ)
 	if err != nil {
-		return errors.Wrap(err, "failed creating transfer action")
+		return nil, errors.WithMessagef(err, "failed getting issuer identity for type [%s]", typ)
 	}
 
-	// double check
-	if err := ts.VerifyTransfer(transfer, transferMetadata.TokenInfo); err != nil {
-		return errors.Wrap(err, "failed checking generated proof")
+	ts := t.TokenService.tms
+	transfer, transferMetadata, err := ts.Reclaim(t.TxID, id, ids, receiver)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating reclaim action")
 	}
 
 	// Append
 	raw, err := transfer.Serialize()
 	if err != nil {
-		return errors.Wrap(err, "failed serializing transfer action")
+		return nil, errors.Wrap(err, "failed serializing reclaim action")
 	}
 	t.Actions.Transfers = append(t.Actions.Transfers, raw)
 	t.Metadata.Transfers = append(t.Metadata.Transfers, *transferMetadata)
 
-	return nil
+	return &TransferAction{a: transfer}, nil
+}
+
+// withoutOpening returns true if the output at the passed index has no opening among the passed ones because
+// the metadata has been filtered for an auditor not entitled to see the amounts, see FilterMetadataByAuditScope
+func (t *Request) withoutOpening(tokenInfos [][]byte, index int) bool {
+	return index >= len(tokenInfos) && !t.TokenService.PublicParametersManager().AuditScope().Amounts()
 }
 
 func (t *Request) Outputs() (*OutputStream, error) {
@@ -220,6 +469,9 @@ func (t *Request) Outputs() (*OutputStream, error) {
 			return nil, errors.Wrapf(err, "failed deserializing issue action [%d]", i)
 		}
 		for j, output := range action.GetOutputs() {
+			if t.Metadata.Issues[i].IsOutputRedacted(j) || t.withoutOpening(t.Metadata.Issues[i].TokenInfo, j) {
+				continue
+			}
 			raw, err := output.Serialize()
 			if err != nil {
 				return nil, errors.Wrapf(err, "failed deserializing issue action output [%d,%d]", i, j)
@@ -228,7 +480,7 @@ func (t *Request) Outputs() (*OutputStream, error) {
 			if err != nil {
 				return nil, errors.Wrapf(err, "failed getting issue action output in the clear [%d,%d]", i, j)
 			}
-			eID, err := t.TokenService.tms.GetEnrollmentID(t.Metadata.Issues[i].AuditInfos[j])
+			eID, err := t.enrollmentID(t.Metadata.Issues[i].AuditInfos, j)
 			if err != nil {
 				return nil, errors.Wrapf(err, "failed getting enrollment id [%d,%d]", i, j)
 			}
@@ -248,6 +500,9 @@ func (t *Request) Outputs() (*OutputStream, error) {
 			return nil, errors.Wrapf(err, "failed deserializing transfer action [%d]", i)
 		}
 		for j, output := range action.GetOutputs() {
+			if t.Metadata.Transfers[i].IsOutputRedacted(j) || t.withoutOpening(t.Metadata.Transfers[i].TokenInfo, j) {
+				continue
+			}
 			raw, err := output.Serialize()
 			if err != nil {
 				return nil, errors.Wrapf(err, "failed deserializing transfer action output [%d,%d]", i, j)
@@ -258,7 +513,7 @@ func (t *Request) Outputs() (*OutputStream, error) {
 			}
 			var eID string
 			if len(tok.Owner.Raw) != 0 {
-				eID, err = t.TokenService.tms.GetEnrollmentID(t.Metadata.Transfers[i].ReceiverAuditInfos[j])
+				eID, err = t.enrollmentID(t.Metadata.Transfers[i].ReceiverAuditInfos, j)
 				if err != nil {
 					return nil, errors.Wrapf(err, "failed getting enrollment id [%d,%d]", i, j)
 				}
@@ -283,7 +538,10 @@ func (t *Request) Inputs() (*InputStream, error) {
 		meta := t.Metadata.Transfers[i]
 
 		for j, id := range meta.TokenIDs {
-			eID, err := t.TokenService.tms.GetEnrollmentID(t.Metadata.Transfers[i].SenderAuditInfos[j])
+			if meta.IsInputRedacted(j) {
+				continue
+			}
+			eID, err := t.ownerEnrollmentID(meta.Senders[j], meta.SenderAuditInfos, j)
 			if err != nil {
 				return nil, errors.Wrapf(err, "failed getting enrollment id [%d,%d]", i, j)
 			}
@@ -298,25 +556,57 @@ func (t *Request) Inputs() (*InputStream, error) {
 	return NewInputStream(t.TokenService.Vault().NewQueryEngine(), inputs), nil
 }
 
-func (t *Request) Verify() error {
+// enrollmentID returns the enrollment ID carried by the audit info at the passed index, the empty string if none.
+// The requests of deployments without an auditor carry no audit infos.
+// The audit infos encrypted for the auditor are not disclosed to the other parties, their enrollment ID is empty as well.
+func (t *Request) enrollmentID(auditInfos [][]byte, i int) (string, error) {
+	if i >= len(auditInfos) || len(auditInfos[i]) == 0 {
+		return "", nil
+	}
+	eID, err := t.TokenService.tms.GetEnrollmentID(auditInfos[i])
+	if errors.Is(err, api2.ErrEncryptedAuditInfo) {
+		return "", nil
+	}
+	return eID, err
+}
+
+// ownerEnrollmentID returns the enrollment ID carried by the audit info at the passed index, as enrollmentID does,
+// once checked that the audit info belongs to the passed owner, see GetOwnerEnrollmentID
+func (t *Request) ownerEnrollmentID(owner view.Identity, auditInfos [][]byte, i int) (string, error) {
+	if i >= len(auditInfos) || len(auditInfos[i]) == 0 {
+		return "", nil
+	}
+	eID, err := t.TokenService.tms.GetOwnerEnrollmentID(owner, auditInfos[i])
+	if errors.Is(err, api2.ErrEncryptedAuditInfo) {
+		return "", nil
+	}
+	return eID, err
+}
+
+// Verify checks the well-formedness of the actions of this request, it aborts once the passed context is done.
+// On failure, the returned error carries a ValidationReport, see GetValidationReport.
+func (t *Request) Verify(ctx context.Context) error {
 	ts := t.TokenService.tms
+	report := &api2.ValidationReport{}
 	for i, issue := range t.Actions.Issues {
 		action, err := ts.DeserializeIssueAction(issue)
 		if err != nil {
-			return errors.WithMessagef(err, "failed deserializing issue action")
+			return report.Failed(api2.IssueActionType, i, api2.FormatCheck, errors.WithMessagef(err, "failed deserializing issue action"))
 		}
-		if err := ts.VerifyIssue(action, t.Metadata.Issues[i].TokenInfo); err != nil {
-			return errors.WithMessagef(err, "failed verifying issue action")
+		if err := ts.VerifyIssue(ctx, action, t.Metadata.Issues[i].TokenInfo); err != nil {
+			return report.Failed(api2.IssueActionType, i, api2.ProofCheck, errors.WithMessagef(err, "failed verifying issue action"))
 		}
+		report.Succeeded(api2.IssueActionType, i)
 	}
 	for i, transfer := range t.Actions.Transfers {
 		action, err := ts.DeserializeTransferAction(transfer)
 		if err != nil {
-			return errors.WithMessagef(err, "failed deserializing transfer action")
+			return report.Failed(api2.TransferActionType, i, api2.FormatCheck, errors.WithMessagef(err, "failed deserializing transfer action"))
 		}
-		if err := ts.VerifyTransfer(action, t.Metadata.Transfers[i].TokenInfo); err != nil {
-			return errors.WithMessagef(err, "failed verifying transfer action")
+		if err := ts.VerifyTransfer(ctx, action, t.Metadata.Transfers[i].TokenInfo); err != nil {
+			return report.Failed(api2.TransferActionType, i, api2.ProofCheck, errors.WithMessagef(err, "failed verifying transfer action"))
 		}
+		report.Succeeded(api2.TransferActionType, i)
 	}
 
 	if _, err := t.Inputs(); err != nil {
@@ -329,7 +619,7 @@ func (t *Request) Verify() error {
 	return nil
 }
 
-func (t *Request) IsValid() error {
+func (t *Request) IsValid(ctx context.Context) error {
 	// TODO: IsValid tokens
 	numTokens, err := t.countOutputs()
 	if err != nil {
@@ -340,11 +630,11 @@ func (t *Request) IsValid() error {
 		return errors.Errorf("invalid transaction, the number of tokens differs from the number of token info [%d],[%d]", numTokens, len(tis))
 	}
 
-	return t.Verify()
+	return t.Verify(ctx)
 }
 
 func (t *Request) MarshallToAudit() ([]byte, error) {
-	bytes, err := json.Marshal(&api2.TokenRequest{Issues: t.Actions.Issues, Transfers: t.Actions.Transfers})
+	bytes, err := t.Actions.MarshalToSign()
 	if err != nil {
 		return nil, errors.Wrapf(err, "audit of tx [%s] failed: error marshal token request for signature", t.TxID)
 	}
@@ -352,25 +642,207 @@ func (t *Request) MarshallToAudit() ([]byte, error) {
 }
 
 func (t *Request) MarshallToSign() ([]byte, error) {
-	req := &api2.TokenRequest{
-		Issues:    t.Actions.Issues,
-		Transfers: t.Actions.Transfers,
-	}
-	return json.Marshal(req)
+	return t.Actions.MarshalToSign()
 }
 
 func (t *Request) RequestToBytes() ([]byte, error) {
 	return t.Actions.Bytes()
 }
 
+// RequestToCompressedBytes returns the serialization of the token request compressed with zstd.
+// The validators and NewRequestFromBytes accept both compressed and plain serializations.
+func (t *Request) RequestToCompressedBytes() ([]byte, error) {
+	return t.Actions.CompressedBytes()
+}
+
 func (t *Request) MetadataToBytes() ([]byte, error) {
 	return t.Metadata.Bytes()
 }
 
+// FilterMetadataBy returns a copy of this request whose metadata contains only the information
+// the passed parties are entitled to see: the outputs they receive and the inputs they send.
+// The filtered metadata has the same digest of the full metadata, see MetadataDigest.
+func (t *Request) FilterMetadataBy(parties ...view.Identity) *Request {
+	return &Request{
+		TxID:         t.TxID,
+		Actions:      t.Actions,
+		Metadata:     t.Metadata.FilterBy(parties...),
+		TokenService: t.TokenService,
+	}
+}
+
+// FilterMetadataByAuditScope returns a copy of this request whose metadata contains only the information an auditor
+// with the passed scope is entitled to see, see TokenRequestMetadata.FilterByAuditScope
+func (t *Request) FilterMetadataByAuditScope(scope api2.AuditScope) *Request {
+	return &Request{
+		TxID:         t.TxID,
+		Actions:      t.Actions,
+		Metadata:     t.Metadata.FilterByAuditScope(scope),
+		TokenService: t.TokenService,
+	}
+}
+
+// MetadataDigest returns the digest of the metadata of this request, it does not change with filtering
+func (t *Request) MetadataDigest() []byte {
+	return t.Metadata.Digest()
+}
+
+// VerifyMetadata checks that the metadata of this request, possibly filtered,
+// has been derived from the full metadata with the passed digest, and that it refers to the actions of this request
+func (t *Request) VerifyMetadata(digest []byte) error {
+	if !bytes.Equal(t.Metadata.Digest(), digest) {
+		return errors2.New(errors2.InvalidProof, "metadata digest does not match")
+	}
+	if len(t.Metadata.Issues) != len(t.Actions.Issues) {
+		return errors.Errorf("number of issues does not match the number of issue metadata")
+	}
+	for i, issue := range t.Actions.Issues {
+		action, err := t.TokenService.tms.DeserializeIssueAction(issue)
+		if err != nil {
+			return errors.Wrapf(err, "failed deserializing issue action [%d]", i)
+		}
+		outputs, err := action.GetSerializedOutputs()
+		if err != nil {
+			return errors.Wrapf(err, "failed getting outputs of issue action [%d]", i)
+		}
+		if err := matchOutputs(outputs, t.Metadata.Issues[i].Outputs); err != nil {
+			return errors.WithMessagef(err, "metadata does not match issue action [%d]", i)
+		}
+	}
+	if len(t.Metadata.Transfers) != len(t.Actions.Transfers) {
+		return errors.Errorf("number of transfers does not match the number of transfer metadata")
+	}
+	for i, transfer := range t.Actions.Transfers {
+		action, err := t.TokenService.tms.DeserializeTransferAction(transfer)
+		if err != nil {
+			return errors.Wrapf(err, "failed deserializing transfer action [%d]", i)
+		}
+		outputs, err := action.GetSerializedOutputs()
+		if err != nil {
+			return errors.Wrapf(err, "failed getting outputs of transfer action [%d]", i)
+		}
+		if err := matchOutputs(outputs, t.Metadata.Transfers[i].Outputs); err != nil {
+			return errors.WithMessagef(err, "metadata does not match transfer action [%d]", i)
+		}
+	}
+	return nil
+}
+
 func (t *Request) SetAuditorSignature(sigma []byte) {
 	t.Actions.AuditorSignature = sigma
 }
 
+// AppendAuditorSignature appends the signature of an auditor other than the first one, see Auditors
+func (t *Request) AppendAuditorSignature(sigma []byte) {
+	t.Actions.AuditorSignatures = append(t.Actions.AuditorSignatures, sigma)
+}
+
+// Auditors returns the auditors that must sign this request given the token types of its outputs and, if the token
+// types are in the clear, of the inputs it spends, in the order of their signatures.
+// A request below the audit threshold needs none, see SpendsBelowAuditThreshold.
+func (t *Request) Auditors() ([]view.Identity, error) {
+	below, err := t.SpendsBelowAuditThreshold()
+	if err != nil {
+		return nil, err
+	}
+	if below {
+		return nil, nil
+	}
+	outputs, err := t.Outputs()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting outputs")
+	}
+	types := outputs.TokenTypes()
+	ppm := t.TokenService.PublicParametersManager()
+	if !ppm.TokenDataHiding() {
+		inputs, err := t.spentTokens()
+		if err != nil {
+			return nil, err
+		}
+		for _, input := range inputs {
+			types = append(types, input.Type)
+		}
+	}
+	return ppm.RequestAuditors(types), nil
+}
+
+// spentTokens returns the tokens spent by the transfers of this request, as stored in the vault
+func (t *Request) spentTokens() ([]*token2.Token, error) {
+	var ids []*token2.Id
+	for _, meta := range t.Metadata.Transfers {
+		for _, id := range meta.TokenIDs {
+			if id != nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	tokens, err := t.TokenService.Vault().NewQueryEngine().GetTokens(ids...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting the spent tokens")
+	}
+	return tokens, nil
+}
+
+// SpendsBelowAuditThreshold returns true if this request can skip the auditors: it is made of transfers only and
+// the total of their outputs is below the audit threshold of the public parameters.
+// The total of the outputs, change included, is the total of the spent inputs: the threshold bounds what the request
+// spends, see api.AuditThresholds. To pay a small amount without the auditor, the inputs must be small too.
+// It returns false if some outputs are redacted from the metadata, their value is unknown.
+func (t *Request) SpendsBelowAuditThreshold() (bool, error) {
+	threshold := t.TokenService.PublicParametersManager().AuditThreshold()
+	if api2.CheckUnaudited(t.Actions, threshold) != nil {
+		return false, nil
+	}
+	outputs, err := t.Outputs()
+	if err != nil {
+		return false, errors.WithMessagef(err, "failed getting outputs")
+	}
+	count, err := t.countOutputs()
+	if err != nil {
+		return false, errors.WithMessagef(err, "failed counting outputs")
+	}
+	if outputs.Count() != count {
+		return false, nil
+	}
+	total := big.NewInt(0)
+	for i := 0; i < outputs.Count(); i++ {
+		q, err := token2.ToQuantity(outputs.At(i).Quantity, keys.Precision)
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid quantity of output [%d]", i)
+		}
+		total.Add(total, q.ToBigInt())
+	}
+	return total.Cmp(new(big.Int).SetUint64(threshold)) < 0, nil
+}
+
+// ProveAuditThreshold attaches to this request the proof that it is below the audit threshold, for the drivers
+// that hide the values of the tokens. It does nothing if the request is not below the threshold.
+func (t *Request) ProveAuditThreshold() error {
+	below, err := t.SpendsBelowAuditThreshold()
+	if err != nil || !below {
+		return err
+	}
+	prover, ok := t.TokenService.tms.(api2.AuditThresholdProver)
+	if !ok {
+		return nil
+	}
+	transfers := make([]api2.TransferAction, len(t.Actions.Transfers))
+	for i, raw := range t.Actions.Transfers {
+		if transfers[i], err = t.TokenService.tms.DeserializeTransferAction(raw); err != nil {
+			return errors.Wrapf(err, "failed deserializing transfer action [%d]", i)
+		}
+	}
+	proof, err := prover.ProveAuditThreshold(transfers, t.Metadata.Transfers)
+	if err != nil {
+		return errors.WithMessagef(err, "failed proving that [%s] is below the audit threshold", t.TxID)
+	}
+	t.Actions.ThresholdProof = proof
+	return nil
+}
+
 func (t *Request) AppendSignature(sigma []byte) {
 	t.Actions.Signatures = append(t.Actions.Signatures, sigma)
 }
@@ -423,6 +895,7 @@ func (t *Request) Issues() []*Issue {
 		issues = append(issues, &Issue{
 			Issuer:    issue.Issuer,
 			Receivers: issue.Receivers,
+			Approver:  issue.Approver,
 		})
 	}
 	return issues
@@ -440,6 +913,25 @@ func (t *Request) Transfers() []*Transfer {
 }
 
 func (t *Request) Import(request *Request) error {
+	if terms := request.Actions.SwapTerms; terms != nil {
+		if t.Actions.SwapTerms != nil {
+			return errors.Errorf("both requests declare swap terms")
+		}
+		first, second := *terms.First, *terms.Second
+		first.TransferIndex += len(t.Actions.Transfers)
+		second.TransferIndex += len(t.Actions.Transfers)
+		t.Actions.SwapTerms = &SwapTerms{First: &first, Second: &second, RateNumerator: terms.RateNumerator, RateDenominator: terms.RateDenominator}
+	}
+	for _, digest := range request.Actions.Attachments {
+		d := *digest
+		d.TransferIndex += len(t.Actions.Transfers)
+		t.Actions.Attachments = append(t.Actions.Attachments, &d)
+	}
+	for _, receipt := range request.Actions.BurnReceipts {
+		r := *receipt
+		r.TransferIndex += len(t.Actions.Transfers)
+		t.Actions.BurnReceipts = append(t.Actions.BurnReceipts, &r)
+	}
 	for _, issue := range request.Actions.Issues {
 		t.Actions.Issues = append(t.Actions.Issues, issue)
 	}
@@ -455,8 +947,135 @@ func (t *Request) Import(request *Request) error {
 	return nil
 }
 
-func (t *Request) AuditCheck() error {
-	if err := t.Verify(); err != nil {
+// BurnReceipts returns the burn receipts of the redemptions performed by this request
+func (t *Request) BurnReceipts() []*BurnReceipt {
+	return t.Actions.BurnReceipts
+}
+
+// NewSwapLeg returns the leg of a swap made of the output at the passed index of the transfer at the passed index
+func (t *Request) NewSwapLeg(transferIndex, outputIndex int) (*SwapLeg, error) {
+	if transferIndex < 0 || transferIndex >= len(t.Metadata.Transfers) {
+		return nil, errors.Errorf("transfer [%d] not found", transferIndex)
+	}
+	transfer := t.Metadata.Transfers[transferIndex]
+	if outputIndex < 0 || outputIndex >= len(transfer.Outputs) || outputIndex >= len(transfer.TokenInfo) {
+		return nil, errors.Errorf("output [%d] of transfer [%d] not found", outputIndex, transferIndex)
+	}
+	tok, _, err := t.TokenService.tms.DeserializeToken(transfer.Outputs[outputIndex], transfer.TokenInfo[outputIndex])
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed deserializing output [%d] of transfer [%d]", outputIndex, transferIndex)
+	}
+	return &SwapLeg{
+		TransferIndex: transferIndex,
+		OutputIndex:   outputIndex,
+		Type:          tok.Type,
+		Quantity:      tok.Quantity,
+		TokenInfo:     transfer.TokenInfo[outputIndex],
+	}, nil
+}
+
+// SetSwapTerms declares that the passed legs are swapped at the passed exchange rate:
+// a unit of the first leg is worth numerator/denominator units of the second leg.
+// The terms are signed with the request, the validator rejects the request if the legs do not respect them.
+func (t *Request) SetSwapTerms(first, second *SwapLeg, numerator, denominator uint64) error {
+	if t.Actions.SwapTerms != nil {
+		return errors.Errorf("swap terms already set")
+	}
+	if first == nil || second == nil {
+		return errors.Errorf("swap terms must declare two legs")
+	}
+	if numerator == 0 || denominator == 0 {
+		return errors.Errorf("invalid exchange rate [%d/%d]", numerator, denominator)
+	}
+	t.Actions.SwapTerms = &SwapTerms{First: first, Second: second, RateNumerator: numerator, RateDenominator: denominator}
+	return nil
+}
+
+// SwapTerms returns the swap terms of this request, nil if not set
+func (t *Request) SwapTerms() *SwapTerms {
+	return t.Actions.SwapTerms
+}
+
+// Attach attaches the passed payload to the transfer at the passed index. The payload does not travel with
+// the request: the transfer metadata references it by name, hash and, if set, the URI it is stored at,
+// the request commits to its salted hash, see api.AttachmentCommitment. Send the payload to the parties over a side channel, or store it at URI.
+func (t *Request) Attach(transferIndex int, name string, uri string, payload []byte) (*Attachment, error) {
+	if transferIndex < 0 || transferIndex >= len(t.Metadata.Transfers) || transferIndex >= len(t.Actions.Transfers) {
+		return nil, errors.Errorf("transfer [%d] not found", transferIndex)
+	}
+	for _, a := range t.Metadata.Transfers[transferIndex].Attachments {
+		if a.Name == name {
+			return nil, errors.Errorf("attachment [%s] already in transfer [%d]", name, transferIndex)
+		}
+	}
+	attachment, err := api2.NewAttachment(name, uri, payload)
+	if err != nil {
+		return nil, err
+	}
+	t.Metadata.Transfers[transferIndex].Attachments = append(t.Metadata.Transfers[transferIndex].Attachments, attachment)
+	t.Actions.Attachments = append(t.Actions.Attachments, &api2.AttachmentDigest{TransferIndex: transferIndex, Hash: attachment.Hash})
+	return attachment, nil
+}
+
+// Attachments returns the attachments of the transfer at the passed index
+func (t *Request) Attachments(transferIndex int) []*Attachment {
+	if transferIndex < 0 || transferIndex >= len(t.Metadata.Transfers) {
+		return nil
+	}
+	return t.Metadata.Transfers[transferIndex].Attachments
+}
+
+// VerifyAttachment checks that the passed payload is the one referenced by the attachment with the passed name
+// of the transfer at the passed index, and that the request commits to it
+func (t *Request) VerifyAttachment(transferIndex int, name string, payload []byte) error {
+	var attachment *Attachment
+	for _, a := range t.Attachments(transferIndex) {
+		if a.Name == name {
+			attachment = a
+			break
+		}
+	}
+	if attachment == nil {
+		return errors.Errorf("attachment [%s] not found in transfer [%d]", name, transferIndex)
+	}
+	if err := attachment.Verify(payload); err != nil {
+		return err
+	}
+	for _, digest := range t.Actions.Attachments {
+		if digest.TransferIndex == transferIndex && bytes.Equal(digest.Hash, attachment.Hash) {
+			return nil
+		}
+	}
+	return errors2.Errorf(errors2.InvalidProof, "request [%s] does not commit to attachment [%s] of transfer [%d]", t.TxID, name, transferIndex)
+}
+
+// AppendMigration appends a serialized migration action, see MigrationParams.
+// The owners of the migrated tokens sign the request after the senders of the transfers, in the order of the inputs.
+// The request carries no metadata for the migrated tokens, the vaults of their owners do not store them.
+func (t *Request) AppendMigration(raw []byte) {
+	t.Actions.Migrations = append(t.Actions.Migrations, raw)
+}
+
+// AppendRebinding appends a serialized rebinding action, see RebindingAction, and its metadata.
+// The metadata carries the openings of the rebound tokens, they reach the successor owners with the metadata.
+// The owners of the rebound tokens do not sign, the request must be approved by the auditor.
+func (t *Request) AppendRebinding(raw []byte, metadata *RebindingMetadata) {
+	t.Actions.Rebindings = append(t.Actions.Rebindings, raw)
+	t.Metadata.Rebindings = append(t.Metadata.Rebindings, *metadata)
+}
+
+// SetDriver marks the request as produced by the passed driver.
+// The validator of a driver replacing another one validates the requests marked with the replaced driver
+// as the replaced driver would, until the cutover height.
+func (t *Request) SetDriver(driver string) {
+	t.Actions.Driver = driver
+}
+
+func (t *Request) AuditCheck(ctx context.Context) error {
+	if err := t.Verify(ctx); err != nil {
+		return err
+	}
+	if err := t.checkBurnReceipts(); err != nil {
 		return err
 	}
 	return t.TokenService.tms.AuditorCheck(
@@ -466,6 +1085,29 @@ func (t *Request) AuditCheck() error {
 	)
 }
 
+// checkBurnReceipts checks that the senders of each redeeming transfer have the enrollment ID declared by its burn receipt
+func (t *Request) checkBurnReceipts() error {
+	for i, receipt := range t.Actions.BurnReceipts {
+		if receipt.TransferIndex < 0 || receipt.TransferIndex >= len(t.Metadata.Transfers) {
+			return errors.Errorf("burn receipt [%d] refers to an unknown transfer [%d]", i, receipt.TransferIndex)
+		}
+		for _, auditInfo := range t.Metadata.Transfers[receipt.TransferIndex].SenderAuditInfos {
+			if len(auditInfo) == 0 {
+				// without an auditor, the senders are not disclosed
+				continue
+			}
+			eID, err := t.TokenService.tms.GetEnrollmentID(auditInfo)
+			if err != nil {
+				return errors.WithMessagef(err, "failed getting enrollment id of the senders of burn receipt [%d]", i)
+			}
+			if eID != receipt.EnrollmentID {
+				return errors2.Errorf(errors2.Unauthorized, "burn receipt [%d] declares redeemer [%s], sender is [%s]", i, receipt.EnrollmentID, eID)
+			}
+		}
+	}
+	return nil
+}
+
 func (t *Request) AuditRecord() (*AuditRecord, error) {
 	inputs, err := t.AuditInputs()
 	if err != nil {
@@ -552,6 +1194,110 @@ func (t *Request) parseInputIDs(inputs []*token2.Id) ([]*token2.Id, token2.Quant
 	return inputs, sum, typ, nil
 }
 
+// computeIssue computes an issue action, under a span child of the span carried by the issue options
+func (t *Request) computeIssue(issueOpts *IssueOptions, id view.Identity, typ string, values []uint64, owners [][]byte) (issue api2.IssueAction, tokenInfos [][]byte, issuer view.Identity, err error) {
+	_, span := tracing.Start(t.TokenService.sp, issueOpts.Context, "token.issue.proof")
+	span.SetAttribute("tx.id", t.TxID)
+	span.SetAttribute("outputs", len(values))
+	defer func() { tracing.EndWithError(span, err) }()
+
+	return t.TokenService.tms.Issue(id, typ, values, owners, &api2.IssueOptions{
+		Expiration: issueOpts.Expiration,
+	})
+}
+
+// transfer prepares and computes a transfer action.
+// If the inputs chosen by the token selector get spent by a concurrent transaction before the action is computed,
+// they are released and the transfer is prepared again, with freshly selected inputs, up to the configured number of retries.
+func (t *Request) transfer(redeem bool, wallet *OwnerWallet, typ string, values []uint64, owners []view.Identity, opts ...TransferOption) (api2.TransferAction, *api2.TransferMetadata, []*token2.Token, error) {
+	transferOpts, err := compileTransferOptions(opts...)
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "failed compiling transfer options [%v]", opts)
+	}
+
+	for i := 0; ; i++ {
+		tokenIDs, outputTokens, err := t.selectInputs(transferOpts, i, redeem, wallet, typ, values, owners, opts...)
+		if err != nil {
+			return nil, nil, nil, errors.Wrap(err, "failed preparing transfer")
+		}
+
+		logger.Debugf("Prepare Transfer Action [id:%s,ins:%d,outs:%d,redeem:%v]", t.TxID, len(tokenIDs), len(outputTokens), redeem)
+
+		// Compute transfer
+		transfer, transferMetadata, err := t.computeTransfer(transferOpts, wallet, tokenIDs, outputTokens)
+		if err == nil {
+			return transfer, transferMetadata, outputTokens, nil
+		}
+		if _, ok := err.(invalidProofError); ok {
+			return nil, nil, nil, err
+		}
+
+		// inputs passed explicitly cannot be replaced
+		spent := t.inputsSpent(tokenIDs)
+		if spent && (len(transferOpts.TokenIDs) != 0 || i >= transferOpts.Retries) {
+			return nil, nil, nil, errors2.Wrapf(errors2.DoubleSpend, err, "failed creating transfer action, inputs [%v] spent", tokenIDs)
+		}
+		if !spent {
+			return nil, nil, nil, errors.Wrap(err, "failed creating transfer action")
+		}
+		logger.Warnf("inputs [%v] of [%s] spent by a concurrent transaction, select again [%d/%d]", tokenIDs, t.TxID, i+1, transferOpts.Retries)
+		sm, err := t.TokenService.SelectorManager()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if err := sm.UnlockIDs(tokenIDs...); err != nil {
+			return nil, nil, nil, errors.WithMessagef(err, "failed releasing inputs [%v]", tokenIDs)
+		}
+	}
+}
+
+// selectInputs prepares a transfer, selecting its inputs, under a span child of the span carried by the transfer options
+func (t *Request) selectInputs(transferOpts *TransferOptions, attempt int, redeem bool, wallet *OwnerWallet, typ string, values []uint64, owners []view.Identity, opts ...TransferOption) (tokenIDs []*token2.Id, outputTokens []*token2.Token, err error) {
+	_, span := tracing.Start(t.TokenService.sp, transferOpts.Context, "token.selection")
+	span.SetAttribute("tx.id", t.TxID)
+	span.SetAttribute("attempt", attempt)
+	defer func() { tracing.EndWithError(span, err) }()
+
+	return t.prepareTransfer(redeem, wallet, typ, values, owners, opts...)
+}
+
+// invalidProofError is returned by computeTransfer when the generated transfer action does not verify
+type invalidProofError struct{ error }
+
+func (e invalidProofError) Code() errors2.Code {
+	return errors2.InvalidProof
+}
+
+// computeTransfer computes a transfer action, and verifies it, under a span child of the span carried by the transfer options
+func (t *Request) computeTransfer(transferOpts *TransferOptions, wallet *OwnerWallet, tokenIDs []*token2.Id, outputTokens []*token2.Token) (transfer api2.TransferAction, transferMetadata *api2.TransferMetadata, err error) {
+	_, span := tracing.Start(t.TokenService.sp, transferOpts.Context, "token.transfer.proof")
+	span.SetAttribute("tx.id", t.TxID)
+	span.SetAttribute("inputs", len(tokenIDs))
+	span.SetAttribute("outputs", len(outputTokens))
+	defer func() { tracing.EndWithError(span, err) }()
+
+	ts := t.TokenService.tms
+	transfer, transferMetadata, err = ts.Transfer(t.TxID, wallet.w, tokenIDs, outputTokens...)
+	if err != nil {
+		return nil, nil, err
+	}
+	// double check
+	if err := ts.VerifyTransfer(transferOpts.Context, transfer, transferMetadata.TokenInfo); err != nil {
+		return nil, nil, invalidProofError{errors.Wrap(err, "failed checking generated proof")}
+	}
+	return transfer, transferMetadata, nil
+}
+
+// inputsSpent returns true if any of the passed tokens is not unspent anymore
+func (t *Request) inputsSpent(ids []*token2.Id) bool {
+	_, err := t.TokenService.Vault().NewQueryEngine().GetTokens(ids...)
+	if err != nil {
+		logger.Debugf("inputs [%v] not available anymore [%s]", ids, err)
+		return true
+	}
+	return false
+}
+
 func (t *Request) prepareTransfer(redeem bool, wallet *OwnerWallet, typ string, values []uint64, owners []view.Identity, opts ...TransferOption) ([]*token2.Id, []*token2.Token, error) {
 	// compile options
 	transferOpts, err := compileTransferOptions(opts...)
@@ -570,6 +1316,23 @@ func (t *Request) prepareTransfer(redeem bool, wallet *OwnerWallet, typ string,
 			}
 		}
 	}
+	if transferOpts.TimeLock != nil {
+		if redeem {
+			return nil, nil, errors.Errorf("redeemed tokens cannot be time locked")
+		}
+		locked := make([]view.Identity, len(owners))
+		for i, owner := range owners {
+			locked[i], err = api2.NewTimeLockOwner(&api2.TimeLock{
+				Owner:           owner,
+				NotBefore:       transferOpts.TimeLock.NotBefore,
+				NotBeforeHeight: transferOpts.TimeLock.NotBeforeHeight,
+			})
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "failed locking recipient [%s]", owner)
+			}
+		}
+		owners = locked
+	}
 	var tokenIDs []*token2.Id
 	var inputSum token2.Quantity
 
@@ -602,7 +1365,11 @@ func (t *Request) prepareTransfer(redeem bool, wallet *OwnerWallet, typ string,
 		selector := transferOpts.Selector
 		if selector == nil {
 			// resort to default strategy
-			selector, err = t.TokenService.SelectorManager().NewSelector(t.TxID)
+			sm, err := t.TokenService.SelectorManager()
+			if err != nil {
+				return nil, nil, err
+			}
+			selector, err = sm.NewSelector(t.TxID)
 			if err != nil {
 				return nil, nil, errors.Wrapf(err, "failed getting default selector")
 			}
@@ -611,6 +1378,13 @@ func (t *Request) prepareTransfer(redeem bool, wallet *OwnerWallet, typ string,
 		if err != nil {
 			return nil, nil, errors.Wrap(err, "failed selecting tokens")
 		}
+		if transferOpts.MaxInputs != 0 && len(tokenIDs) > transferOpts.MaxInputs {
+			return nil, nil, &TooManyInputsError{Type: typ, IDs: tokenIDs, Max: transferOpts.MaxInputs}
+		}
+	} else if transferOpts.MaxInputs != 0 && len(tokenIDs) > transferOpts.MaxInputs {
+		return nil, nil, errors.Errorf("[%d] inputs passed, more than [%d]", len(tokenIDs), transferOpts.MaxInputs)
+	} else if inputSum.Cmp(qOutputSum) < 0 {
+		return nil, nil, errors2.Errorf(errors2.InsufficientFunds, "inputs passed sum to [%s], less than the [%s] transferred", inputSum.Decimal(), qOutputSum.Decimal())
 	}
 
 	// Is there a rest?
@@ -618,17 +1392,82 @@ func (t *Request) prepareTransfer(redeem bool, wallet *OwnerWallet, typ string,
 		diff := inputSum.Sub(qOutputSum)
 		logger.Debugf("reassign rest [%s] to sender", diff.Decimal())
 
-		pseudonym, err := wallet.GetRecipientIdentity()
-		if err != nil {
-			return nil, nil, errors.WithMessagef(err, "failed getting recipient identity for the rest, wallet [%s]", wallet.ID())
+		if transferOpts.ChangePolicy == nil {
+			pseudonym, err := t.changeIdentity(wallet, transferOpts)
+			if err != nil {
+				return nil, nil, errors.WithMessagef(err, "failed getting recipient identity for the rest, wallet [%s]", wallet.ID())
+			}
+
+			outputTokens = append(outputTokens, &token2.Token{
+				Owner:    &token2.Owner{Raw: pseudonym},
+				Type:     typ,
+				Quantity: diff.Decimal(),
+			})
+		} else {
+			change, err := t.splitChange(transferOpts, wallet, typ, diff)
+			if err != nil {
+				return nil, nil, err
+			}
+			outputTokens = append(outputTokens, change...)
 		}
+	}
 
-		outputTokens = append(outputTokens, &token2.Token{
+	return tokenIDs, outputTokens, nil
+}
+
+// changeIdentity returns the recipient identity of the sender the change is assigned to,
+// a fresh pseudonym if the transfer requires an anonymous change
+func (t *Request) changeIdentity(wallet *OwnerWallet, transferOpts *TransferOptions) (view.Identity, error) {
+	if transferOpts.AnonymousChange {
+		return wallet.GetRecipientIdentity(WithFreshPseudonym())
+	}
+	return wallet.GetRecipientIdentity()
+}
+
+// splitChange returns the outputs that return the passed change to the sender, following the change policy of the transfer.
+// Each change output is assigned to its own recipient identity of the wallet, see changeIdentity.
+func (t *Request) splitChange(transferOpts *TransferOptions, wallet *OwnerWallet, typ string, change token2.Quantity) ([]*token2.Token, error) {
+	policy := transferOpts.ChangePolicy
+	c := change.ToBigInt()
+	if !c.IsUint64() {
+		return nil, errors.Errorf("change [%s] out of range", change.Decimal())
+	}
+	values, fee, err := policy.Split(c.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf("split rest [%s] into [%v], fee [%d]", change.Decimal(), values, fee)
+
+	var outputs []*token2.Token
+	for _, value := range values {
+		pseudonym, err := t.changeIdentity(wallet, transferOpts)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed getting recipient identity for the rest, wallet [%s]", wallet.ID())
+		}
+		outputs = append(outputs, &token2.Token{
 			Owner:    &token2.Owner{Raw: pseudonym},
 			Type:     typ,
-			Quantity: diff.Decimal(),
+			Quantity: token2.NewQuantityFromUInt64(value).Decimal(),
+		})
+	}
+	if fee != 0 {
+		outputs = append(outputs, &token2.Token{
+			Owner:    &token2.Owner{Raw: policy.FeeRecipient},
+			Type:     typ,
+			Quantity: token2.NewQuantityFromUInt64(fee).Decimal(),
 		})
 	}
+	return outputs, nil
+}
 
-	return tokenIDs, outputTokens, nil
+func matchOutputs(outputs [][]byte, metadataOutputs [][]byte) error {
+	if len(outputs) != len(metadataOutputs) {
+		return errors.Errorf("expected [%d] outputs, got [%d]", len(outputs), len(metadataOutputs))
+	}
+	for i := range outputs {
+		if !bytes.Equal(outputs[i], metadataOutputs[i]) {
+			return errors2.Errorf(errors2.InvalidProof, "output [%d] does not match", i)
+		}
+	}
+	return nil
 }
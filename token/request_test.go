@@ -0,0 +1,224 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// spendingTMS fails the transfers spending tokens that are no longer unspent
+type spendingTMS struct {
+	tokenapi.TokenManagerService
+	unspent map[string]bool
+	inputs  [][]*token2.Id
+}
+
+func (s *spendingTMS) Transfer(txID string, wallet tokenapi.OwnerWallet, ids []*token2.Id, outputs ...*token2.Token) (tokenapi.TransferAction, *tokenapi.TransferMetadata, error) {
+	s.inputs = append(s.inputs, ids)
+	for _, id := range ids {
+		if !s.unspent[id.String()] {
+			return nil, nil, errors.Errorf("token [%s] not found", id)
+		}
+	}
+	return &transferAction{}, &tokenapi.TransferMetadata{}, nil
+}
+
+func (s *spendingTMS) VerifyTransfer(ctx context.Context, tr tokenapi.TransferAction, tokenInfos [][]byte) error {
+	return nil
+}
+
+func (s *spendingTMS) Vault(network string, channel string, namespace string) tokenapi.Vault {
+	return s
+}
+
+func (s *spendingTMS) QueryEngine() tokenapi.QueryEngine {
+	return &unspentQueryEngine{unspent: s.unspent}
+}
+
+func (s *spendingTMS) SelectorManager(network string, channel string, namespace string) (SelectorManager, error) {
+	return &unlockingSelectorManager{}, nil
+}
+
+type unspentQueryEngine struct {
+	tokenapi.QueryEngine
+	unspent map[string]bool
+}
+
+func (q *unspentQueryEngine) GetTokens(inputs ...*token2.Id) ([]*token2.Token, error) {
+	var tokens []*token2.Token
+	for _, id := range inputs {
+		if !q.unspent[id.String()] {
+			return nil, errors.Errorf("token [%s] not found", id)
+		}
+		tokens = append(tokens, &token2.Token{})
+	}
+	return tokens, nil
+}
+
+type unlockingSelectorManager struct {
+	SelectorManager
+}
+
+func (m *unlockingSelectorManager) UnlockIDs(ids ...*token2.Id) error {
+	return nil
+}
+
+type transferAction struct {
+	tokenapi.TransferAction
+}
+
+func (a *transferAction) Serialize() ([]byte, error) {
+	return []byte("transfer"), nil
+}
+
+// queuedSelector returns the queued selections one by one
+type queuedSelector struct {
+	selections [][]*token2.Id
+}
+
+func (s *queuedSelector) Select(ownerFilter OwnerFilter, q, tokenType string) ([]*token2.Id, token2.Quantity, error) {
+	ids := s.selections[0]
+	s.selections = s.selections[1:]
+	return ids, token2.NewQuantityFromUInt64(uint64(len(ids))), nil
+}
+
+func TestTransferReselectsSpentInputs(t *testing.T) {
+	a := &token2.Id{TxId: "a"}
+	b := &token2.Id{TxId: "b"}
+	c := &token2.Id{TxId: "c"}
+	newRequest := func(tms *spendingTMS) *Request {
+		return NewRequest(&ManagementService{
+			sp:                      registry.New(),
+			tms:                     tms,
+			vaultProvider:           tms,
+			selectorManagerProvider: tms,
+		}, "tx1")
+	}
+	recipient := []view.Identity{view.Identity("alice")}
+
+	// a has been spent by a concurrent transaction after the selection, c is selected in its place
+	tms := &spendingTMS{unspent: map[string]bool{b.String(): true, c.String(): true}}
+	selector := &queuedSelector{selections: [][]*token2.Id{{a, b}, {b, c}}}
+	_, err := newRequest(tms).Transfer(&OwnerWallet{}, "USD", []uint64{2}, recipient, WithTokenSelector(selector))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]*token2.Id{{a, b}, {b, c}}, tms.inputs)
+
+	// up to the number of retries
+	tms = &spendingTMS{unspent: map[string]bool{}}
+	selector = &queuedSelector{selections: [][]*token2.Id{{a}, {b}, {c}}}
+	_, err = newRequest(tms).Transfer(&OwnerWallet{}, "USD", []uint64{1}, recipient, WithTokenSelector(selector), WithTransferRetries(1))
+	assert.Error(t, err)
+	assert.True(t, errors2.HasCode(err, errors2.DoubleSpend))
+	assert.Len(t, tms.inputs, 2)
+
+	// the inputs passed explicitly are not replaced
+	tms = &spendingTMS{unspent: map[string]bool{}}
+	_, err = newRequest(tms).Transfer(&OwnerWallet{}, "USD", []uint64{1}, recipient, WithTokenIDs(a))
+	assert.Error(t, err)
+	assert.Len(t, tms.inputs, 0)
+}
+
+// recipientWallet returns a new recipient identity on each request, recording the options passed
+type recipientWallet struct {
+	tokenapi.OwnerWallet
+	opts []*tokenapi.RecipientIdentityOptions
+}
+
+func (w *recipientWallet) ID() string {
+	return "alice"
+}
+
+func (w *recipientWallet) GetRecipientIdentity(opts *tokenapi.RecipientIdentityOptions) (view.Identity, error) {
+	w.opts = append(w.opts, opts)
+	return view.Identity(fmt.Sprintf("alice%d", len(w.opts))), nil
+}
+
+func TestChangeIdentity(t *testing.T) {
+	request := &Request{}
+
+	w := &recipientWallet{}
+	id, err := request.changeIdentity(&OwnerWallet{w: w}, &TransferOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, view.Identity("alice1"), id)
+	assert.False(t, w.opts[0].Fresh)
+
+	// an anonymous change requires a fresh pseudonym, whatever the policy of the wallet
+	id, err = request.changeIdentity(&OwnerWallet{w: w}, &TransferOptions{AnonymousChange: true})
+	assert.NoError(t, err)
+	assert.Equal(t, view.Identity("alice2"), id)
+	assert.True(t, w.opts[1].Fresh)
+}
+
+func TestSplitChange(t *testing.T) {
+	request := &Request{}
+	policy := &ChangePolicy{Denominations: []uint64{10}, DustThreshold: 3, FeeRecipient: view.Identity("bank")}
+
+	// each change output is assigned to its own pseudonym, the dust goes to the fee recipient
+	w := &recipientWallet{}
+	outputs, err := request.splitChange(&TransferOptions{ChangePolicy: policy, AnonymousChange: true}, &OwnerWallet{w: w}, "USD", token2.NewQuantityFromUInt64(22))
+	assert.NoError(t, err)
+	assert.Equal(t, []*token2.Token{
+		{Owner: &token2.Owner{Raw: view.Identity("alice1")}, Type: "USD", Quantity: token2.NewQuantityFromUInt64(10).Decimal()},
+		{Owner: &token2.Owner{Raw: view.Identity("alice2")}, Type: "USD", Quantity: token2.NewQuantityFromUInt64(10).Decimal()},
+		{Owner: &token2.Owner{Raw: view.Identity("bank")}, Type: "USD", Quantity: token2.NewQuantityFromUInt64(2).Decimal()},
+	}, outputs)
+	assert.Len(t, w.opts, 2)
+	for _, opts := range w.opts {
+		assert.True(t, opts.Fresh)
+	}
+
+	// the policy errors are returned
+	_, err = request.splitChange(&TransferOptions{ChangePolicy: &ChangePolicy{MaxValue: 1, MaxOutputs: 2}}, &OwnerWallet{w: w}, "USD", token2.NewQuantityFromUInt64(3))
+	assert.Error(t, err)
+}
+
+// auditInfoTMS reads the audit info "eID:owner" as binding the owner to the enrollment ID
+type auditInfoTMS struct {
+	spendingTMS
+}
+
+func (s *auditInfoTMS) GetOwnerEnrollmentID(owner view.Identity, auditInfo []byte) (string, error) {
+	parts := strings.SplitN(string(auditInfo), ":", 2)
+	if len(parts) != 2 || parts[1] != string(owner) {
+		return "", errors.Errorf("audit info does not match identity [%s]", owner)
+	}
+	return parts[0], nil
+}
+
+func TestInputsVerifyEnrollmentIDs(t *testing.T) {
+	tms := &auditInfoTMS{}
+	newRequest := func(senders []view.Identity, auditInfos [][]byte) *Request {
+		request := NewRequest(&ManagementService{tms: tms, vaultProvider: tms}, "tx1")
+		request.Actions.Transfers = [][]byte{[]byte("transfer")}
+		ids := make([]*token2.Id, len(senders))
+		for i := range ids {
+			ids[i] = &token2.Id{TxId: fmt.Sprintf("tx%d", i)}
+		}
+		request.Metadata.Transfers = []tokenapi.TransferMetadata{{TokenIDs: ids, Senders: senders, SenderAuditInfos: auditInfos}}
+		return request
+	}
+
+	inputs, err := newRequest([]view.Identity{view.Identity("alice"), view.Identity("bob")}, [][]byte{[]byte("alice:alice"), nil}).Inputs()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", ""}, inputs.EnrollmentIDs())
+
+	// an audit info of another identity does not disclose the enrollment ID it carries
+	_, err = newRequest([]view.Identity{view.Identity("mallory")}, [][]byte{[]byte("alice:alice")}).Inputs()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "audit info does not match identity")
+}
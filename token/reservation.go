@@ -0,0 +1,75 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"time"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// Reservation holds tokens of a wallet for a later transfer, a quote or an order for instance.
+// The reserved tokens are not selected by the other transfers until the reservation is released or expires.
+type Reservation struct {
+	ID       string
+	Type     string
+	Quantity string
+	TokenIDs []*token2.Id
+	// Expiry is the time the reservation is released at, zero if it never expires
+	Expiry time.Time
+}
+
+// Expired returns true if the reservation has expired at the passed time
+func (r *Reservation) Expired(now time.Time) bool {
+	return !r.Expiry.IsZero() && !now.Before(r.Expiry)
+}
+
+// Reserve reserves tokens of this wallet of the passed type for at least the passed amount, for the passed time
+// to live, forever if zero. Transfer the reserved tokens with WithReservation, the reservation is released once
+// the transfer commits. Release the reservation to give up on it.
+func (o *OwnerWallet) Reserve(amount, typ string, ttl time.Duration) (*Reservation, error) {
+	sm, err := o.ms.SelectorManager()
+	if err != nil {
+		return nil, err
+	}
+	return sm.Reserve(o, amount, typ, ttl)
+}
+
+// Release releases the passed reservation, its tokens can be selected again
+func (o *OwnerWallet) Release(reservationID string) error {
+	sm, err := o.ms.SelectorManager()
+	if err != nil {
+		return err
+	}
+	return sm.Release(reservationID)
+}
+
+// ReleaseSpentReservations releases the reservations holding any of the passed tokens, spent by the passed transaction,
+// once the transaction is final
+func (t *ManagementService) ReleaseSpentReservations(txID string, finality Finality, spent ...*token2.Id) {
+	if len(spent) == 0 {
+		return
+	}
+	go func() {
+		if err := finality.IsFinal(txID); err != nil {
+			logger.Debugf("transaction [%s] not committed, its inputs stay reserved: [%s]", txID, err)
+			return
+		}
+		sm, err := t.SelectorManager()
+		if err != nil {
+			logger.Warnf("transaction [%s], failed releasing the reservations of its inputs [%s]", txID, err)
+			return
+		}
+		if err := sm.ReleaseSpent(spent...); err != nil {
+			logger.Warnf("transaction [%s], failed releasing the reservations of its inputs [%s]", txID, err)
+		}
+	}()
+}
+
+// WithReservation spends the tokens of the passed reservation
+func WithReservation(r *Reservation) TransferOption {
+	return WithTokenIDs(r.TokenIDs...)
+}
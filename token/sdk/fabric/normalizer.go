@@ -9,7 +9,7 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
-	tcc "github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc/fetcher"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
 )
 
@@ -32,7 +32,7 @@ func (n *Normalizer) Normalize(opt *token.ServiceOptions) *token.ServiceOptions
 		opt.Namespace = keys.TokenNameSpace
 	}
 	if opt.PublicParamsFetcher == nil {
-		opt.PublicParamsFetcher = tcc.NewPublicParamsFetcher(n.sp, opt.Network, opt.Channel, opt.Namespace)
+		opt.PublicParamsFetcher = network.NewPublicParamsFetcher(n.sp, opt.Network, opt.Channel, opt.Namespace)
 	}
 	return opt
 }
@@ -7,6 +7,7 @@ package token
 
 import (
 	"context"
+	"reflect"
 	"time"
 
 	"github.com/pkg/errors"
@@ -15,25 +16,40 @@ import (
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/assert"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/config"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh/driver"
 	fabric2 "github.com/hyperledger-labs/fabric-token-sdk/token/sdk/fabric"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/sdk/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/atrest"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb/db/badger"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb/db/memory"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/certifier/dummy"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/certifier/interactive"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/history"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/network/fabric"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/services/network/orion"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/query"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/selector"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tracing"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxcc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/processor"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/wallets"
 )
 
 var logger = flogging.MustGetLogger("token-sdk")
 
+// walletsWatchInterval is how often the wallets configuration files are checked for new wallets
+const walletsWatchInterval = 5 * time.Second
+
 type Registry interface {
 	GetService(v interface{}) (interface{}, error)
 
@@ -60,15 +76,40 @@ func (p *SDK) Install() error {
 	}
 	logger.Infof("Token platform enabled, installing...")
 
+	// Encryption at rest of the data persisted by the node. A sealer registered before installing the sdk,
+	// backed by an HSM for instance, takes precedence over the configured key encryption keys.
+	if _, err := p.registry.GetService(reflect.TypeOf((*atrest.Sealer)(nil))); err != nil {
+		keyring, err := atrest.LoadKeyring(p.registry)
+		if err != nil {
+			return errors.WithMessagef(err, "failed loading key encryption keys")
+		}
+		if keyring != nil {
+			logger.Infof("encryption at rest enabled, current key encryption key [%s]", keyring.Current())
+			assert.NoError(p.registry.RegisterService(keyring))
+		}
+	}
+
 	logger.Infof("Set Token Service")
 	fabricNetwork := fabric.GetDefaultNetwork(p.registry)
 
 	tmsProvider := core.NewTMSProvider(fabricNetwork, p.registry,
 		func(network, channel, namespace string) error {
 			n := fabric.GetFabricNetworkService(p.registry, network)
+			scheme, err := config.KeyScheme(p.registry, channel, namespace)
+			if err != nil {
+				return errors.WithMessagef(err, "failed loading key scheme")
+			}
+			light, err := config.LightMode(p.registry, channel, namespace)
+			if err != nil {
+				return errors.WithMessagef(err, "failed loading light mode")
+			}
+			transcripts, err := config.Transcripts(p.registry, channel, namespace)
+			if err != nil {
+				return errors.WithMessagef(err, "failed loading transcripts")
+			}
 			if err := n.ProcessorManager().AddProcessor(
 				namespace,
-				processor.NewTokenRWSetProcessor(n, namespace, p.registry),
+				processor.NewTokenRWSetProcessor(n, namespace, p.registry, scheme).WithLightMode(light).WithTranscripts(transcripts),
 			); err != nil {
 				return errors.Wrapf(err, "failed adding transaction processors")
 			}
@@ -76,21 +117,41 @@ func (p *SDK) Install() error {
 		},
 	)
 	assert.NoError(p.registry.RegisterService(tmsProvider))
+	assert.NoError(p.registry.RegisterService(core.NewPublicParamsCache(kvs.GetService(p.registry))))
 
+	selectorProvider := selector.NewProvider(p.registry, fabric2.NewLockerProvider(
+		p.registry,
+		2*time.Second,
+		(5*time.Minute).Milliseconds(),
+	), 2, 5*time.Second)
+	// Number of selections from the same wallet that run at once, and how long the others wait for their turn
+	if view2.GetConfigService(p.registry).IsSet("token.selector.partitions") {
+		var partitions int
+		if err := view2.GetConfigService(p.registry).UnmarshalKey("token.selector.partitions", &partitions); err != nil {
+			return errors.WithMessagef(err, "failed loading token selector partitions")
+		}
+		selectorProvider.SetPartitions(partitions)
+	}
+	if view2.GetConfigService(p.registry).IsSet("token.selector.queueTimeout") {
+		selectorProvider.SetQueueTimeout(view2.GetConfigService(p.registry).GetDuration("token.selector.queueTimeout"))
+	}
+	// Reservations of tokens, persisted to be honored across restarts. The locks of the transactions in flight are not.
+	if view2.GetConfigService(p.registry).GetBool("token.selector.reservations.persistent") {
+		selectorProvider.SetReservationStore(kvs.GetService(p.registry))
+	}
 	assert.NoError(p.registry.RegisterService(token.NewManagementServiceProvider(
 		p.registry,
 		tmsProvider,
 		fabric2.NewNormalizer(p.registry),
 		fabric2.NewVaultProvider(p.registry),
 		fabric2.NewCertificationClientProvider(p.registry),
-		selector.NewProvider(p.registry, fabric2.NewLockerProvider(
-			p.registry,
-			2*time.Second,
-			(5*time.Minute).Milliseconds(),
-		), 2, 5*time.Second),
+		selectorProvider,
 		view.NewSigServiceWrapper(view2.GetSigService(p.registry)),
 	)))
 
+	// Networks
+	assert.NoError(p.registry.RegisterService(network.NewProvider(p.registry)))
+
 	// AuditDB
 	driverName := view2.GetConfigService(p.registry).GetString("token.auditor.auditdb.persistence.type")
 	if len(driverName) == 0 {
@@ -98,6 +159,19 @@ func (p *SDK) Install() error {
 	}
 	assert.NoError(p.registry.RegisterService(auditdb.NewManager(p.registry, driverName)))
 
+	// History of the tokens of the local wallets
+	if view2.GetConfigService(p.registry).GetBool("token.history.enabled") {
+		assert.NoError(p.registry.RegisterService(history.NewService(kvs.GetService(p.registry))))
+	}
+
+	// Spans of the token transaction lifecycle, logged. Register a tracing.Tracer before installing the sdk to
+	// export them to a tracing backend instead.
+	if view2.GetConfigService(p.registry).GetBool("token.tracing.enabled") {
+		if _, err := p.registry.GetService(reflect.TypeOf((*tracing.Tracer)(nil))); err != nil {
+			assert.NoError(p.registry.RegisterService(tracing.NewLogTracer()))
+		}
+	}
+
 	logger.Infof("Install View Handlers")
 	query.InstallQueryViewFactories(p.registry)
 
@@ -105,5 +179,72 @@ func (p *SDK) Install() error {
 }
 
 func (p *SDK) Start(ctx context.Context) error {
+	configProvider := view2.GetConfigService(p.registry)
+	if !configProvider.GetBool("token.enabled") {
+		return nil
+	}
+	// seal again the data sealed under the previous key encryption keys, once the key has been rotated
+	if err := p.rewrap(); err != nil {
+		return errors.WithMessagef(err, "failed sealing data under the current key encryption key")
+	}
+	// resolve the transactions left pending by a previous run, the networks must be up, then do it in the background
+	if configProvider.GetBool("token.ttxcc.recovery.enabled") {
+		go func() {
+			valid, err := ttxcc.RecoverPendingTransactions(p.registry, ttxcc.DefaultFinalityTimeout)
+			if err != nil {
+				logger.Errorf("failed recovering pending transactions [%s]", err)
+				return
+			}
+			logger.Infof("recovered pending transactions, [%d] committed", len(valid))
+		}()
+	}
+	return p.watchWallets(ctx)
+}
+
+// watchWallets registers at runtime the wallets listed in the wallets configuration files of the token applications,
+// until the passed context is done
+func (p *SDK) watchWallets(ctx context.Context) error {
+	tmsConfigs, err := config.TMSs(p.registry)
+	if err != nil {
+		return err
+	}
+	for _, c := range tmsConfigs {
+		if c.Wallets == nil || len(c.Wallets.File) == 0 {
+			continue
+		}
+		tms := token.GetManagementService(
+			p.registry,
+			token.WithNetwork(c.Network),
+			token.WithChannel(c.Channel),
+			token.WithNamespace(c.Namespace),
+		)
+		logger.Infof("watching [%s] for the wallets of [%s]", c.Wallets.File, tms)
+		wallets.NewWatcher(tms.WalletManager(), c.Wallets.File, walletsWatchInterval).Start(ctx)
+	}
+	return nil
+}
+
+// rewrap seals again under the current key encryption key the wallet material and the audit infos,
+// the token information in the vault is stored with the ledger transactions and keeps its key
+func (p *SDK) rewrap() error {
+	if _, ok := atrest.GetSealer(p.registry).(atrest.Rewrapper); !ok {
+		return nil
+	}
+	total := 0
+	for _, prefix := range append(nogh.SealedStatePrefixes, processor.SyncedTokensPrefix) {
+		n, err := atrest.Rewrap(p.registry, prefix, atrest.StoreState(p.registry))
+		if err != nil {
+			return err
+		}
+		total += n
+	}
+	n, err := identity.RewrapAuditInfos(p.registry)
+	if err != nil {
+		return err
+	}
+	total += n
+	if total != 0 {
+		logger.Infof("sealed [%d] entries under the current key encryption key", total)
+	}
 	return nil
 }
@@ -6,24 +6,39 @@ SPDX-License-Identifier: Apache-2.0
 package token
 
 import (
-	"github.com/pkg/errors"
-
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 var (
-	SelectorInsufficientFunds                  = errors.New("insufficient funds")
-	SelectorSufficientButLockedFunds           = errors.New("sufficient but partially locked funds")
-	SelectorSufficientButNotCertifiedFunds     = errors.New("sufficient but partially not certified")
-	SelectorSufficientFundsButConcurrencyIssue = errors.New("sufficient funds but concurrency issue")
+	SelectorInsufficientFunds                  = errors2.New(errors2.InsufficientFunds, "insufficient funds")
+	SelectorSufficientButLockedFunds           = errors2.New(errors2.Conflict, "sufficient but partially locked funds")
+	SelectorSufficientButNotCertifiedFunds     = errors2.New(errors2.Conflict, "sufficient but partially not certified")
+	SelectorSufficientFundsButConcurrencyIssue = errors2.New(errors2.Conflict, "sufficient funds but concurrency issue")
 )
 
 type OwnerFilter interface {
 	Contains(identity view.Identity) bool
 }
 
+// WalletFilter is an OwnerFilter selecting the identities of a wallet.
+// The selectors queue fairly the concurrent selections from the same wallet.
+type WalletFilter interface {
+	OwnerFilter
+	// ID returns the identifier of the wallet
+	ID() string
+}
+
+// SpenderFilter is an OwnerFilter telling apart the identities whose tokens can be spent from this node.
+// The selectors skip the tokens of the other identities, like those imported from a paired device.
+type SpenderFilter interface {
+	OwnerFilter
+	// CanSpend returns true if the tokens owned by the passed identity can be spent from this node
+	CanSpend(identity view.Identity) bool
+}
+
 type Selector interface {
 	Select(ownerFilter OwnerFilter, q, tokenType string) ([]*token2.Id, token2.Quantity, error)
 }
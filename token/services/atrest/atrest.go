@@ -0,0 +1,170 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package atrest
+
+import (
+	"encoding/json"
+	"reflect"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.atrest")
+
+// Sealer encrypts the data the node persists: the wallet material, the token information and the audit infos.
+// The Keyring seals with key encryption keys loaded from the keystore of the node. To keep the key encryption keys
+// in an HSM, register in the service provider a Sealer backed by the HSM before installing the sdk.
+// The data is sealed in a context, the key it is stored under for instance, that must be passed again to open it,
+// so that the sealed data cannot be moved under another key.
+type Sealer interface {
+	// Seal returns the encryption of the passed data, bound to the passed context
+	Seal(data []byte, context []byte) ([]byte, error)
+	// Open returns the data sealed in the passed one in the passed context
+	Open(raw []byte, context []byte) ([]byte, error)
+}
+
+// Rewrapper is implemented by the sealers whose key encryption key can be rotated
+type Rewrapper interface {
+	// NeedsRewrap returns true if the passed data is sealed under a key that is not the current one
+	NeedsRewrap(raw []byte) bool
+	// Rewrap returns the passed data, sealed in the passed context, sealed again under the current key encryption key
+	Rewrap(raw []byte, context []byte) ([]byte, error)
+}
+
+// GetSealer returns the sealer registered in the passed service provider,
+// a sealer that keeps the data in the clear if none
+func GetSealer(sp view2.ServiceProvider) Sealer {
+	s, err := sp.GetService(reflect.TypeOf((*Sealer)(nil)))
+	if err != nil {
+		return plain{}
+	}
+	return s.(Sealer)
+}
+
+type plain struct{}
+
+func (plain) Seal(data []byte, _ []byte) ([]byte, error) {
+	return data, nil
+}
+
+func (plain) Open(raw []byte, _ []byte) ([]byte, error) {
+	if IsSealed(raw) {
+		return nil, errors.New("data is sealed but encryption at rest is not configured")
+	}
+	return raw, nil
+}
+
+// sealedState is the kvs entry of a sealed state, it carries the context the state is sealed in
+// to seal it again when the key encryption key is rotated, see Rewrap
+type sealedState struct {
+	Context []byte `json:",omitempty"`
+	Sealed  []byte
+}
+
+// Put stores in the kvs under the passed key the passed state, sealed, in the context of the key, with the sealer
+// of the passed service provider
+func Put(sp view2.ServiceProvider, k string, state interface{}) error {
+	s := GetSealer(sp)
+	if _, ok := s.(plain); ok {
+		return kvs.GetService(sp).Put(k, state)
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrapf(err, "failed marshalling state [%s]", k)
+	}
+	entry, err := sealEntry(s, raw, []byte(k))
+	if err != nil {
+		return errors.WithMessagef(err, "failed sealing state [%s]", k)
+	}
+	return kvs.GetService(sp).Put(k, entry)
+}
+
+// Get reads from the kvs the state stored under the passed key, see Put
+func Get(sp view2.ServiceProvider, k string, state interface{}) error {
+	var raw json.RawMessage
+	if err := kvs.GetService(sp).Get(k, &raw); err != nil {
+		return err
+	}
+	if err := Decode(GetSealer(sp), raw, state, k); err != nil {
+		return errors.WithMessagef(err, "failed reading state [%s]", k)
+	}
+	return nil
+}
+
+// Decode unmarshals into the passed state the passed kvs entry stored under the passed key, see Put.
+// With encryption at rest, the entries in the clear are rejected.
+func Decode(s Sealer, raw []byte, state interface{}, k string) error {
+	opened, err := openEntry(s, raw, []byte(k))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(opened, state)
+}
+
+// DecodeEntry unmarshals into the passed state the passed kvs entry, read iterating over the kvs, and returns
+// the key it is bound to. The caller must check that the entry has been read under that key.
+// In the clear, the key is unknown and the empty string is returned.
+func DecodeEntry(s Sealer, raw []byte, state interface{}) (string, error) {
+	if _, ok := s.(plain); ok {
+		return "", Decode(s, raw, state, "")
+	}
+	entry, err := unmarshalSealed(raw)
+	if err != nil {
+		return "", err
+	}
+	return string(entry.Context), Decode(s, raw, state, string(entry.Context))
+}
+
+// SealValue returns the passed value, stored by a service other than the kvs, sealed in the passed context.
+// In the clear, the value is returned as it is.
+func SealValue(s Sealer, value []byte, context []byte) ([]byte, error) {
+	if _, ok := s.(plain); ok {
+		return value, nil
+	}
+	entry, err := sealEntry(s, value, context)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(entry)
+}
+
+// OpenValue returns the value sealed in the passed one in the passed context, see SealValue
+func OpenValue(s Sealer, raw []byte, context []byte) ([]byte, error) {
+	return openEntry(s, raw, context)
+}
+
+func sealEntry(s Sealer, data []byte, context []byte) (*sealedState, error) {
+	sealed, err := s.Seal(data, context)
+	if err != nil {
+		return nil, err
+	}
+	return &sealedState{Context: context, Sealed: sealed}, nil
+}
+
+func openEntry(s Sealer, raw []byte, context []byte) ([]byte, error) {
+	if _, ok := s.(plain); ok {
+		if entry, err := unmarshalSealed(raw); err == nil && len(entry.Sealed) != 0 {
+			return nil, errors.New("data is sealed but encryption at rest is not configured")
+		}
+		return raw, nil
+	}
+	entry, err := unmarshalSealed(raw)
+	if err != nil {
+		return nil, err
+	}
+	return s.Open(entry.Sealed, context)
+}
+
+func unmarshalSealed(raw []byte) (*sealedState, error) {
+	entry := &sealedState{}
+	if err := json.Unmarshal(raw, entry); err != nil || len(entry.Sealed) == 0 {
+		return nil, errors.New("sealed data expected, the data is in the clear")
+	}
+	return entry, nil
+}
@@ -0,0 +1,240 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package atrest
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/config"
+)
+
+// KeySize is the size of the key encryption keys and of the data encryption keys
+const KeySize = 32
+
+// magic prefixes the sealed data, the data without it has been persisted in the clear
+var magic = []byte{0x00, 'K', 'E', 'K', 0x02}
+
+// Keyring seals the data with envelope encryption: each piece of data is encrypted, with AES-GCM, under a fresh data
+// encryption key, that is wrapped under the current key encryption key. The sealed data names the key encryption
+// key that wraps it, so that the data sealed under the previous keys can still be opened, see Rewrap.
+// The context the data is sealed in, the key it is stored under for instance, is authenticated with it.
+type Keyring struct {
+	current string
+	keks    map[string]cipher.AEAD
+}
+
+// NewKeyring returns a keyring sealing with the key with the passed id among the passed key encryption keys
+func NewKeyring(current string, keks map[string][]byte) (*Keyring, error) {
+	if _, ok := keks[current]; !ok {
+		return nil, errors.Errorf("current key encryption key [%s] not found", current)
+	}
+	k := &Keyring{current: current, keks: map[string]cipher.AEAD{}}
+	for id, key := range keks {
+		if len(id) == 0 || len(id) > 255 {
+			return nil, errors.Errorf("invalid key encryption key id [%s]", id)
+		}
+		if len(key) != KeySize {
+			return nil, errors.Errorf("invalid key encryption key [%s], expected [%d] bytes, got [%d]", id, KeySize, len(key))
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid key encryption key [%s]", id)
+		}
+		k.keks[id] = aead
+	}
+	return k, nil
+}
+
+// LoadKeyring returns the keyring of the key encryption keys listed in the configuration of the node,
+// nil if the encryption at rest is not configured
+func LoadKeyring(sp view2.ServiceProvider) (*Keyring, error) {
+	cs := view2.GetConfigService(sp)
+	if !cs.IsSet("token.atRest") {
+		return nil, nil
+	}
+	c := &config.AtRest{}
+	if err := cs.UnmarshalKey("token.atRest", c); err != nil {
+		return nil, errors.WithMessagef(err, "cannot load encryption at rest configuration")
+	}
+	keks := map[string][]byte{}
+	for _, key := range c.Keys {
+		raw, err := ioutil.ReadFile(cs.TranslatePath(key.Path))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed reading key encryption key [%s]", key.ID)
+		}
+		keks[key.ID] = raw
+	}
+	return NewKeyring(c.Current, keks)
+}
+
+// NewKey returns a fresh key encryption key
+func NewKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, errors.Wrap(err, "failed generating key")
+	}
+	return key, nil
+}
+
+// Current returns the id of the key encryption key the data is sealed with
+func (k *Keyring) Current() string {
+	return k.current
+}
+
+func (k *Keyring) Seal(data []byte, context []byte) ([]byte, error) {
+	dek, err := NewKey()
+	if err != nil {
+		return nil, err
+	}
+	header, err := k.wrap(k.current, dek)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+	return seal(aead, header, additionalData(header, context), data)
+}
+
+func (k *Keyring) Open(raw []byte, context []byte) ([]byte, error) {
+	if !IsSealed(raw) {
+		return nil, errors.New("data is not sealed")
+	}
+	header, body, dek, err := k.unwrap(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+	data, err := open(aead, additionalData(header, context), body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed opening sealed data")
+	}
+	return data, nil
+}
+
+// Rewrap returns the passed data, sealed in the passed context, sealed again under the current key encryption key
+func (k *Keyring) Rewrap(raw []byte, context []byte) ([]byte, error) {
+	if !k.NeedsRewrap(raw) {
+		return raw, nil
+	}
+	data, err := k.Open(raw, context)
+	if err != nil {
+		return nil, err
+	}
+	return k.Seal(data, context)
+}
+
+// NeedsRewrap returns true if the passed data is sealed under a key that is not the current one
+func (k *Keyring) NeedsRewrap(raw []byte) bool {
+	if !IsSealed(raw) {
+		return false
+	}
+	id, _, err := parseHeader(raw)
+	return err == nil && id != k.current
+}
+
+// wrap returns the header naming the passed key encryption key and carrying the passed data encryption key wrapped
+func (k *Keyring) wrap(id string, dek []byte) ([]byte, error) {
+	kek := k.keks[id]
+	header := bytes.NewBuffer(nil)
+	header.Write(magic)
+	header.WriteByte(byte(len(id)))
+	header.WriteString(id)
+	wrapped, err := seal(kek, nil, []byte(id), dek)
+	if err != nil {
+		return nil, err
+	}
+	var l [binary.MaxVarintLen64]byte
+	header.Write(l[:binary.PutUvarint(l[:], uint64(len(wrapped)))])
+	header.Write(wrapped)
+	return header.Bytes(), nil
+}
+
+// unwrap returns the header and the body of the passed sealed data, and its data encryption key
+func (k *Keyring) unwrap(raw []byte) ([]byte, []byte, []byte, error) {
+	id, rest, err := parseHeader(raw)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	kek, ok := k.keks[id]
+	if !ok {
+		return nil, nil, nil, errors.Errorf("data sealed under unknown key encryption key [%s]", id)
+	}
+	l, n := binary.Uvarint(rest)
+	if n <= 0 || uint64(len(rest)-n) < l {
+		return nil, nil, nil, errors.New("invalid sealed data, malformed wrapped key")
+	}
+	wrapped := rest[n : n+int(l)]
+	body := rest[n+int(l):]
+	dek, err := open(kek, []byte(id), wrapped)
+	if err != nil {
+		return nil, nil, nil, errors.Wrapf(err, "failed unwrapping data encryption key with [%s]", id)
+	}
+	return raw[:len(raw)-len(body)], body, dek, nil
+}
+
+// IsSealed returns true if the passed data has been sealed
+func IsSealed(raw []byte) bool {
+	return bytes.HasPrefix(raw, magic)
+}
+
+// additionalData returns the data authenticated with the body of the sealed data: its header, binding the body
+// to the wrapped data encryption key, followed by the context it is sealed in
+func additionalData(header []byte, context []byte) []byte {
+	return append(append([]byte{}, header...), context...)
+}
+
+func parseHeader(raw []byte) (string, []byte, error) {
+	rest := raw[len(magic):]
+	if len(rest) == 0 || len(rest) < 1+int(rest[0]) {
+		return "", nil, errors.New("invalid sealed data, malformed key id")
+	}
+	return string(rest[1 : 1+int(rest[0])]), rest[1+int(rest[0]):], nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating cipher")
+	}
+	return aead, nil
+}
+
+// seal returns the passed prefix followed by a fresh nonce and the encryption of the passed data,
+// the additional data is authenticated
+func seal(aead cipher.AEAD, prefix []byte, additionalData []byte, data []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, "failed generating nonce")
+	}
+	out := append(append([]byte{}, prefix...), nonce...)
+	return aead.Seal(out, nonce, data, additionalData), nil
+}
+
+// open returns the data encrypted by the passed body, a nonce followed by a ciphertext, see seal
+func open(aead cipher.AEAD, additionalData []byte, body []byte) ([]byte, error) {
+	if len(body) < aead.NonceSize() {
+		return nil, errors.New("invalid sealed data, too short")
+	}
+	return aead.Open(nil, body[:aead.NonceSize()], body[aead.NonceSize():], additionalData)
+}
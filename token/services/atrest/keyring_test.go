@@ -0,0 +1,223 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package atrest
+
+import (
+	"encoding/json"
+	"testing"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/api"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func newKeyring(t *testing.T, current string, ids ...string) (*Keyring, map[string][]byte) {
+	keks := map[string][]byte{}
+	for _, id := range ids {
+		key, err := NewKey()
+		assert.NoError(t, err)
+		keks[id] = key
+	}
+	k, err := NewKeyring(current, keks)
+	assert.NoError(t, err)
+	return k, keks
+}
+
+func TestKeyring(t *testing.T) {
+	k, keks := newKeyring(t, "k1", "k1")
+	data := []byte("token information")
+	context := []byte("key")
+
+	sealed, err := k.Seal(data, context)
+	assert.NoError(t, err)
+	assert.True(t, IsSealed(sealed))
+	assert.NotContains(t, string(sealed), string(data))
+	opened, err := k.Open(sealed, context)
+	assert.NoError(t, err)
+	assert.Equal(t, data, opened)
+
+	// the data does not open in another context
+	_, err = k.Open(sealed, []byte("another key"))
+	assert.Error(t, err)
+
+	// the data in the clear is rejected
+	_, err = k.Open(data, context)
+	assert.EqualError(t, err, "data is not sealed")
+	assert.False(t, k.NeedsRewrap(data))
+
+	// tampered data does not open
+	tampered := append([]byte{}, sealed...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = k.Open(tampered, context)
+	assert.Error(t, err)
+
+	// rotate: the data sealed under the previous key still opens, and is sealed again under the new one
+	k2Key, err := NewKey()
+	assert.NoError(t, err)
+	rotated, err := NewKeyring("k2", map[string][]byte{"k1": keks["k1"], "k2": k2Key})
+	assert.NoError(t, err)
+	opened, err = rotated.Open(sealed, context)
+	assert.NoError(t, err)
+	assert.Equal(t, data, opened)
+	assert.True(t, rotated.NeedsRewrap(sealed))
+	_, err = rotated.Rewrap(sealed, []byte("another key"))
+	assert.Error(t, err)
+	rewrapped, err := rotated.Rewrap(sealed, context)
+	assert.NoError(t, err)
+	assert.False(t, rotated.NeedsRewrap(rewrapped))
+	opened, err = rotated.Open(rewrapped, context)
+	assert.NoError(t, err)
+	assert.Equal(t, data, opened)
+
+	// once the previous key is dropped, only the data sealed again opens
+	k2, err := NewKeyring("k2", map[string][]byte{"k2": k2Key})
+	assert.NoError(t, err)
+	_, err = k2.Open(sealed, context)
+	assert.EqualError(t, err, "data sealed under unknown key encryption key [k1]")
+	opened, err = k2.Open(rewrapped, context)
+	assert.NoError(t, err)
+	assert.Equal(t, data, opened)
+
+	_, err = NewKeyring("k3", keks)
+	assert.EqualError(t, err, "current key encryption key [k3] not found")
+	_, err = NewKeyring("k1", map[string][]byte{"k1": []byte("short")})
+	assert.EqualError(t, err, "invalid key encryption key [k1], expected [32] bytes, got [5]")
+}
+
+func TestDecode(t *testing.T) {
+	k, _ := newKeyring(t, "k1", "k1")
+
+	// a state stored in the clear
+	raw, err := json.Marshal("wallet")
+	assert.NoError(t, err)
+	var state string
+	assert.NoError(t, Decode(plain{}, raw, &state, "k"))
+	assert.Equal(t, "wallet", state)
+	// is rejected when the states are expected to be sealed
+	assert.EqualError(t, Decode(k, raw, &state, "k"), "sealed data expected, the data is in the clear")
+
+	// a sealed state
+	entry, err := sealEntry(k, raw, []byte("k"))
+	assert.NoError(t, err)
+	raw, err = json.Marshal(entry)
+	assert.NoError(t, err)
+	state = ""
+	assert.NoError(t, Decode(k, raw, &state, "k"))
+	assert.Equal(t, "wallet", state)
+	key, err := DecodeEntry(k, raw, &state)
+	assert.NoError(t, err)
+	assert.Equal(t, "k", key)
+
+	// does not open under another key
+	assert.Error(t, Decode(k, raw, &state, "another key"))
+	// nor without the keys
+	assert.EqualError(t, Decode(plain{}, raw, &state, "k"), "data is sealed but encryption at rest is not configured")
+}
+
+// configProvider configures the in memory kvs
+type configProvider struct {
+	api.ConfigProvider
+}
+
+func (*configProvider) UnmarshalKey(string, interface{}) error {
+	return nil
+}
+
+func newServiceProvider(t *testing.T, sealer Sealer) view2.ServiceProvider {
+	sp := registry.New()
+	assert.NoError(t, sp.RegisterService(&configProvider{}))
+	kvss, err := kvs.New("memory", "", sp)
+	assert.NoError(t, err)
+	assert.NoError(t, sp.RegisterService(kvss))
+	if sealer != nil {
+		assert.NoError(t, sp.RegisterService(sealer))
+	}
+	return sp
+}
+
+func TestPutGet(t *testing.T) {
+	k, _ := newKeyring(t, "k1", "k1")
+	sp := newServiceProvider(t, k)
+	k1 := kvs.CreateCompositeKeyOrPanic("test", []string{"a"})
+	k2 := kvs.CreateCompositeKeyOrPanic("test", []string{"b"})
+
+	assert.NoError(t, Put(sp, k1, "wallet"))
+	var state string
+	assert.NoError(t, Get(sp, k1, &state))
+	assert.Equal(t, "wallet", state)
+
+	// a sealed state moved under another key does not open
+	var raw json.RawMessage
+	assert.NoError(t, kvs.GetService(sp).Get(k1, &raw))
+	assert.NoError(t, kvs.GetService(sp).Put(k2, raw))
+	assert.Error(t, Get(sp, k2, &state))
+
+	// a state in the clear is rejected
+	assert.NoError(t, kvs.GetService(sp).Put(k2, "wallet"))
+	assert.Error(t, Get(sp, k2, &state))
+}
+
+func TestRewrap(t *testing.T) {
+	k, keks := newKeyring(t, "k1", "k1")
+	sp := newServiceProvider(t, k)
+	k1 := kvs.CreateCompositeKeyOrPanic("test", []string{"a"})
+	assert.NoError(t, Put(sp, k1, "wallet"))
+	var before json.RawMessage
+	assert.NoError(t, kvs.GetService(sp).Get(k1, &before))
+
+	// rotate
+	k2Key, err := NewKey()
+	assert.NoError(t, err)
+	rotated, err := NewKeyring("k2", map[string][]byte{"k1": keks["k1"], "k2": k2Key})
+	assert.NoError(t, err)
+	sp2 := registry.New()
+	assert.NoError(t, sp2.RegisterService(kvs.GetService(sp)))
+	assert.NoError(t, sp2.RegisterService(rotated))
+
+	// reads do not write
+	var state string
+	assert.NoError(t, Get(sp2, k1, &state))
+	var after json.RawMessage
+	assert.NoError(t, kvs.GetService(sp).Get(k1, &after))
+	assert.Equal(t, before, after)
+
+	// the rewrap pass seals again under the new key, once
+	n, err := Rewrap(sp2, "test", StoreState(sp2))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = Rewrap(sp2, "test", StoreState(sp2))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// and the previous key can be dropped
+	k2, err := NewKeyring("k2", map[string][]byte{"k2": k2Key})
+	assert.NoError(t, err)
+	sp3 := registry.New()
+	assert.NoError(t, sp3.RegisterService(kvs.GetService(sp)))
+	assert.NoError(t, sp3.RegisterService(k2))
+	state = ""
+	assert.NoError(t, Get(sp3, k1, &state))
+	assert.Equal(t, "wallet", state)
+
+	// values sealed for other services are sealed again too
+	value, err := SealValue(k, []byte("audit info"), []byte("alice"))
+	assert.NoError(t, err)
+	k2Value := kvs.CreateCompositeKeyOrPanic("values", []string{"alice"})
+	assert.NoError(t, kvs.GetService(sp).Put(k2Value, value))
+	assert.NoError(t, kvs.GetService(sp).Put(kvs.CreateCompositeKeyOrPanic("values", []string{"bob"}), []byte("in the clear")))
+	n, err = Rewrap(sp2, "values", func(context []byte, entry []byte) error {
+		return kvs.GetService(sp).Put(kvs.CreateCompositeKeyOrPanic("values", []string{string(context)}), entry)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	var stored []byte
+	assert.NoError(t, kvs.GetService(sp).Get(k2Value, &stored))
+	opened, err := OpenValue(k2, stored, []byte("alice"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("audit info"), opened)
+}
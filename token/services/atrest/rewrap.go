@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package atrest
+
+import (
+	"encoding/json"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/pkg/errors"
+)
+
+// StoreFunc stores again the passed entry, sealed in the passed context
+type StoreFunc func(context []byte, entry []byte) error
+
+// StoreState stores the passed entry in the kvs under the key it is sealed in, see Put
+func StoreState(sp view2.ServiceProvider) StoreFunc {
+	return func(context []byte, entry []byte) error {
+		return kvs.GetService(sp).Put(string(context), json.RawMessage(entry))
+	}
+}
+
+// Rewrap seals again under the current key encryption key the entries of the kvs under the passed prefix, the states
+// stored by Put and the values sealed by SealValue, and stores them with the passed function.
+// The entries in the clear are skipped. It returns the number of entries sealed again.
+// Run it once the key encryption key has been rotated.
+func Rewrap(sp view2.ServiceProvider, prefix string, store StoreFunc) (int, error) {
+	r, ok := GetSealer(sp).(Rewrapper)
+	if !ok {
+		return 0, nil
+	}
+
+	// collect the entries first, the kvs is not updated while iterating over it
+	var entries []*sealedState
+	it, err := kvs.GetService(sp).GetByPartialCompositeID(prefix, nil)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "failed iterating over [%s]", prefix)
+	}
+	for it.HasNext() {
+		var raw json.RawMessage
+		if err := it.Next(&raw); err != nil {
+			it.Close()
+			return 0, errors.WithMessagef(err, "failed reading entry under [%s]", prefix)
+		}
+		entry, err := unmarshalEntry(raw)
+		if err != nil {
+			// the prefix may be shared with the entries of other services, they are not sealed by the sdk
+			logger.Debugf("skipping entry under [%s]: [%s]", prefix, err)
+			continue
+		}
+		if r.NeedsRewrap(entry.Sealed) {
+			entries = append(entries, entry)
+		}
+	}
+	it.Close()
+
+	for _, entry := range entries {
+		sealed, err := r.Rewrap(entry.Sealed, entry.Context)
+		if err != nil {
+			return 0, errors.WithMessagef(err, "failed sealing again entry [%s]", entry.Context)
+		}
+		raw, err := json.Marshal(&sealedState{Context: entry.Context, Sealed: sealed})
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed marshalling entry [%s]", entry.Context)
+		}
+		if err := store(entry.Context, raw); err != nil {
+			return 0, errors.WithMessagef(err, "failed storing entry [%s]", entry.Context)
+		}
+	}
+	return len(entries), nil
+}
+
+// unmarshalEntry returns the sealed state of the passed kvs entry, a state stored by Put or a value sealed by SealValue
+func unmarshalEntry(raw []byte) (*sealedState, error) {
+	if entry, err := unmarshalSealed(raw); err == nil {
+		return entry, nil
+	}
+	var value []byte
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, errors.New("sealed data expected, the data is in the clear")
+	}
+	return unmarshalSealed(value)
+}
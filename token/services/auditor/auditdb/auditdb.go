@@ -9,7 +9,9 @@ import (
 	"math/big"
 	"sort"
 	"sync"
+	"time"
 
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
@@ -106,10 +108,19 @@ type AuditDB struct {
 	// * an exclusive lock is held when Commit is called.
 	db        driver.AuditDB
 	storeLock sync.RWMutex
+
+	// committed is closed, and replaced, each time a transaction is appended to the commit log
+	committedLock sync.Mutex
+	committed     chan struct{}
+
+	// sp gives access to the kvs where the transactions waiting for finality are recorded, under id,
+	// see TrackFinality. If nil, they are not recorded.
+	sp view2.ServiceProvider
+	id string
 }
 
 func newAuditDB(p driver.AuditDB) *AuditDB {
-	return &AuditDB{db: p}
+	return &AuditDB{db: p, committed: make(chan struct{})}
 }
 
 func (db *AuditDB) Append(record *token.AuditRecord) error {
@@ -221,11 +232,61 @@ func (db *AuditDB) SetStatus(txID string, status Status) error {
 	return nil
 }
 
+// Confirm marks the records of the passed transaction as confirmed and appends the transaction, committed
+// in the passed block with the passed timestamp, to the commit log, notifying the subscribers
+func (db *AuditDB) Confirm(txID string, blockNumber uint64, timestamp time.Time) error {
+	logger.Debugf("Confirm [%s]...[%d]", txID, db.counter)
+	db.storeLock.Lock()
+
+	if err := db.db.BeginUpdate(); err != nil {
+		db.storeLock.Unlock()
+		return errors.WithMessagef(err, "begin update for txid '%s' failed", txID)
+	}
+	if err := db.db.SetStatus(txID, driver.Confirmed); err != nil {
+		db.discard(err)
+		db.storeLock.Unlock()
+		return errors.Wrapf(err, "failed setting status [%s][%s]", txID, Valid)
+	}
+	if _, err := db.db.AppendCommit(txID, blockNumber, timestamp); err != nil {
+		db.discard(err)
+		db.storeLock.Unlock()
+		return errors.Wrapf(err, "failed appending [%s] to the commit log", txID)
+	}
+	if err := db.db.Commit(); err != nil {
+		db.storeLock.Unlock()
+		return errors.WithMessagef(err, "committing tx for txid '%s' failed", txID)
+	}
+	db.storeLock.Unlock()
+
+	db.committedLock.Lock()
+	close(db.committed)
+	db.committed = make(chan struct{})
+	db.committedLock.Unlock()
+
+	logger.Debugf("Confirm [%s] done without errors", txID)
+	return nil
+}
+
+// Subscribe returns a subscription to the transactions committed after the passed cursor.
+// Use cursor zero to start from the first committed transaction, or the cursor of the last event processed to resume.
+func (db *AuditDB) Subscribe(cursor uint64) *Subscription {
+	return &Subscription{db: db, cursor: cursor}
+}
+
+func (db *AuditDB) discard(err error) {
+	if err1 := db.db.Discard(); err1 != nil {
+		logger.Errorf("got error %s; discarding caused %s", err.Error(), err1.Error())
+	}
+}
+
 type Manager struct {
 	sp         view2.ServiceProvider
 	driver     string
 	mutex      sync.Mutex
 	committers map[string]*AuditDB
+	// finality returns the finality of the passed network and channel,
+	// it resumes the tracking of the transactions recorded before a restart
+	finality FinalityProvider
 }
 
 func NewManager(sp view2.ServiceProvider, driver string) *Manager {
@@ -233,6 +294,9 @@ func NewManager(sp view2.ServiceProvider, driver string) *Manager {
 		sp:         sp,
 		driver:     driver,
 		committers: map[string]*AuditDB{},
+		finality: func(network, channel string) Finality {
+			return NewChannelFinality(fabric.GetChannel(sp, network, channel))
+		},
 	}
 }
 
@@ -248,6 +312,10 @@ func (cm *Manager) AuditDB(w *token.AuditorWallet) (*AuditDB, error) {
 			return nil, errors.Wrapf(err, "failed instantiating audit db driver")
 		}
 		c = newAuditDB(driver)
+		c.sp, c.id = cm.sp, id
+		if err := c.ResumeFinality(cm.finality); err != nil {
+			return nil, errors.WithMessagef(err, "failed resuming the finality tracking of [%s]", id)
+		}
 		cm.committers[id] = c
 	}
 	return c, nil
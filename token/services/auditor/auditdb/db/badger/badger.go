@@ -11,6 +11,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/dgraph-io/badger/v3"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb/db/badger/keys"
@@ -132,7 +133,106 @@ func (db *Persistence) AddRecord(record *driver.Record) error {
 }
 
 func (db *Persistence) SetStatus(txID string, status driver.Status) error {
-	panic("implement me")
+	if db.txn == nil {
+		return errors.New("no commit in progress")
+	}
+	records, err := db.queryRecords(db.txn, func(record *Record) bool { return record.Record.TxID == txID })
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		record.Record.Status = status
+		bytes, err := json.Marshal(record)
+		if err != nil {
+			return errors.Wrapf(err, "could not marshal record [%d]", record.Id)
+		}
+		dbKey := dbKey("default", fmt.Sprintf("%d", record.Id))
+		if err := db.txn.Set([]byte(dbKey), bytes); err != nil {
+			return errors.Wrapf(err, "could not set value for key %s", dbKey)
+		}
+	}
+	return nil
+}
+
+func (db *Persistence) QueryByTxID(txID string) ([]*driver.Record, error) {
+	txn := db.db.NewTransaction(false)
+	defer txn.Discard()
+	records, err := db.queryRecords(txn, func(record *Record) bool { return record.Record.TxID == txID })
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(records)
+	var res []*driver.Record
+	for _, record := range records {
+		res = append(res, record.Record)
+	}
+	return res, nil
+}
+
+func (db *Persistence) AppendCommit(txID string, blockNumber uint64, timestamp time.Time) (uint64, error) {
+	if db.txn == nil {
+		return 0, errors.New("no commit in progress")
+	}
+	next, err := db.seq.Next()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed getting next index")
+	}
+	// sequence numbers start from zero, cursors from one
+	cursor := next + 1
+	bytes, err := json.Marshal(&driver.Commit{Cursor: cursor, TxID: txID, BlockNumber: blockNumber, Timestamp: timestamp})
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not marshal commit of [%s]", txID)
+	}
+	if err := db.txn.Set([]byte(commitKey(cursor)), bytes); err != nil {
+		return 0, errors.Wrapf(err, "could not set commit of [%s]", txID)
+	}
+	return cursor, nil
+}
+
+func (db *Persistence) QueryCommits(after uint64, numRecords int) ([]*driver.Commit, error) {
+	txn := db.db.NewTransaction(false)
+	defer txn.Discard()
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	prefix := []byte("commit" + keys.NamespaceSeparator)
+	var res []*driver.Commit
+	for it.Seek([]byte(commitKey(after + 1))); it.ValidForPrefix(prefix); it.Next() {
+		if numRecords > 0 && len(res) >= numRecords {
+			break
+		}
+		commit := &driver.Commit{}
+		err := it.Item().Value(func(val []byte) error {
+			return json.Unmarshal(val, commit)
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not get value for key %s", string(it.Item().Key()))
+		}
+		res = append(res, commit)
+	}
+	return res, nil
+}
+
+// queryRecords returns the records, read with the passed transaction, that satisfy the passed filter
+func (db *Persistence) queryRecords(txn *badger.Txn, filter func(record *Record) bool) (RecordSlice, error) {
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	prefix := []byte("default" + keys.NamespaceSeparator)
+	var records RecordSlice
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		record := &Record{}
+		err := it.Item().Value(func(val []byte) error {
+			return json.Unmarshal(val, record)
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not get value for key %s", string(it.Item().Key()))
+		}
+		if filter(record) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
 }
 
 func (db *Persistence) Query(ids []string, types []string, status []driver.Status, direction driver.Direction, value driver.Value, numRecords int) ([]*driver.Record, error) {
@@ -234,6 +334,12 @@ func dbKey(namespace, key string) string {
 	return namespace + keys.NamespaceSeparator + key
 }
 
+// commitKey returns the key of the commit log entry with the passed cursor.
+// Cursors are zero padded so that keys sort as cursors do.
+func commitKey(cursor uint64) string {
+	return dbKey("commit", fmt.Sprintf("%020d", cursor))
+}
+
 type RecordSlice []*Record
 
 func (p RecordSlice) Len() int           { return len(p) }
@@ -12,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -61,6 +62,62 @@ func TestDB(t *testing.T) {
 	assert.Len(t, records, 2)
 }
 
+func TestCommits(t *testing.T) {
+	dbpath := filepath.Join(tempDir, "DB-TestCommits")
+	db, err := OpenDB(dbpath)
+	defer db.Close()
+	assert.NoError(t, err)
+	assert.NotNil(t, db)
+
+	assert.NoError(t, db.BeginUpdate())
+	for _, txID := range []string{"0", "1", "1"} {
+		err = db.AddRecord(&driver.Record{
+			TxID:         txID,
+			EnrollmentID: "alice",
+			Type:         "magic",
+			Amount:       big.NewInt(10),
+			Status:       driver.Pending,
+		})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, db.Commit())
+
+	assert.NoError(t, db.BeginUpdate())
+	assert.NoError(t, db.SetStatus("1", driver.Confirmed))
+	c1, err := db.AppendCommit("1", 5, time.Now())
+	assert.NoError(t, err)
+	assert.NoError(t, db.Commit())
+
+	assert.NoError(t, db.BeginUpdate())
+	c2, err := db.AppendCommit("0", 6, time.Now())
+	assert.NoError(t, err)
+	assert.NoError(t, db.Commit())
+	assert.True(t, c1 < c2)
+
+	records, err := db.QueryByTxID("1")
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	for _, record := range records {
+		assert.Equal(t, driver.Confirmed, record.Status)
+	}
+	records, err = db.Query(nil, nil, []driver.Status{driver.Pending}, driver.FromBeginning, driver.Received, 0)
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	commits, err := db.QueryCommits(0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, commits, 2)
+	assert.Equal(t, "1", commits[0].TxID)
+	assert.Equal(t, c1, commits[0].Cursor)
+	commits, err = db.QueryCommits(c1, 0)
+	assert.NoError(t, err)
+	assert.Len(t, commits, 1)
+	assert.Equal(t, "0", commits[0].TxID)
+	commits, err = db.QueryCommits(c2, 0)
+	assert.NoError(t, err)
+	assert.Len(t, commits, 0)
+}
+
 var tempDir string
 
 func TestMain(m *testing.M) {
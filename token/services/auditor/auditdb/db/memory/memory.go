@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package memory
 
 import (
+	"time"
+
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb/driver"
@@ -13,6 +15,7 @@ import (
 
 type Persistence struct {
 	records []*driver.Record
+	commits []*driver.Commit
 }
 
 func (p *Persistence) Query(ids []string, types []string, status []driver.Status, direction driver.Direction, value driver.Value, numRecords int) ([]*driver.Record, error) {
@@ -115,6 +118,33 @@ func (p *Persistence) SetStatus(txID string, status driver.Status) error {
 	return nil
 }
 
+func (p *Persistence) QueryByTxID(txID string) ([]*driver.Record, error) {
+	var res []*driver.Record
+	for _, record := range p.records {
+		if record.TxID == txID {
+			res = append(res, record)
+		}
+	}
+	return res, nil
+}
+
+func (p *Persistence) AppendCommit(txID string, blockNumber uint64, timestamp time.Time) (uint64, error) {
+	cursor := uint64(len(p.commits) + 1)
+	p.commits = append(p.commits, &driver.Commit{Cursor: cursor, TxID: txID, BlockNumber: blockNumber, Timestamp: timestamp})
+	return cursor, nil
+}
+
+func (p *Persistence) QueryCommits(after uint64, numRecords int) ([]*driver.Commit, error) {
+	if after >= uint64(len(p.commits)) {
+		return nil, nil
+	}
+	res := p.commits[after:]
+	if numRecords > 0 && len(res) > numRecords {
+		res = res[:numRecords]
+	}
+	return res, nil
+}
+
 func (p *Persistence) Close() error {
 	return nil
 }
@@ -8,6 +8,7 @@ package memory
 import (
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb/driver"
 	"github.com/stretchr/testify/assert"
@@ -66,3 +67,34 @@ func Test(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, records, 0)
 }
+
+func TestCommits(t *testing.T) {
+	db := &Persistence{}
+	assert.NoError(t, db.AddRecord(&driver.Record{TxID: "0", EnrollmentID: "alice", Amount: big.NewInt(10), Type: "EUR", Status: driver.Pending}))
+	assert.NoError(t, db.AddRecord(&driver.Record{TxID: "1", EnrollmentID: "bob", Amount: big.NewInt(20), Type: "EUR", Status: driver.Pending}))
+	assert.NoError(t, db.AddRecord(&driver.Record{TxID: "1", EnrollmentID: "bob", Amount: big.NewInt(-5), Type: "EUR", Status: driver.Pending}))
+
+	records, err := db.QueryByTxID("1")
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	cursor, err := db.AppendCommit("1", 5, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), cursor)
+	cursor, err = db.AppendCommit("0", 6, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), cursor)
+
+	commits, err := db.QueryCommits(0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, commits, 2)
+	assert.Equal(t, "1", commits[0].TxID)
+	commits, err = db.QueryCommits(1, 1)
+	assert.NoError(t, err)
+	assert.Len(t, commits, 1)
+	assert.Equal(t, "0", commits[0].TxID)
+	assert.Equal(t, uint64(2), commits[0].Cursor)
+	commits, err = db.QueryCommits(2, 0)
+	assert.NoError(t, err)
+	assert.Len(t, commits, 0)
+}
@@ -7,6 +7,7 @@ package driver
 
 import (
 	"math/big"
+	"time"
 
 	view "github.com/hyperledger-labs/fabric-smart-client/platform/view"
 )
@@ -44,6 +45,19 @@ type Record struct {
 	Status Status
 }
 
+// Commit is an entry of the commit log, it records that a transaction has been committed
+type Commit struct {
+	// Cursor is the position of this entry in the commit log, it increases with each entry
+	Cursor uint64
+	TxID   string
+	// BlockNumber is the number of the block the transaction has been committed in,
+	// zero for the entries appended before it was recorded
+	BlockNumber uint64 `json:",omitempty"`
+	// Timestamp is the time of the transaction as fixed by the ledger, the timestamp of its channel header,
+	// zero for the entries appended before it was recorded
+	Timestamp time.Time `json:",omitempty"`
+}
+
 type AuditDB interface {
 	Close() error
 	BeginUpdate() error
@@ -52,6 +66,14 @@ type AuditDB interface {
 	AddRecord(record *Record) error
 	SetStatus(txID string, status Status) error
 	Query(ids []string, types []string, status []Status, direction Direction, value Value, numRecords int) ([]*Record, error)
+	// QueryByTxID returns the records of the passed transaction
+	QueryByTxID(txID string) ([]*Record, error)
+	// AppendCommit appends the passed transaction, committed in the passed block with the passed timestamp,
+	// to the commit log and returns its cursor
+	AppendCommit(txID string, blockNumber uint64, timestamp time.Time) (uint64, error)
+	// QueryCommits returns at most numRecords entries of the commit log whose cursor is greater than the passed one.
+	// If numRecords is zero, all the entries are returned.
+	QueryCommits(after uint64, numRecords int) ([]*Commit, error)
 }
 
 type Driver interface {
@@ -0,0 +1,121 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package auditdb
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb/driver"
+)
+
+// MinSaltSize is the minimum size, in bytes, of the salt used to pseudonymize the enrollment IDs
+const MinSaltSize = 16
+
+// ExportOptions controls the pseudonymization of the exported records
+type ExportOptions struct {
+	// Salt keys the hashes replacing the enrollment IDs. The same salt produces the same pseudonyms,
+	// then reports exported with the same salt can be joined, and must be kept secret.
+	Salt []byte
+	// BucketSize, if not nil, is the granularity of the exported amounts: each amount is rounded, toward zero,
+	// to a multiple of BucketSize
+	BucketSize *big.Int
+	// Statuses are the statuses of the exported records, only the confirmed records if empty
+	Statuses []Status
+	// Types, if not empty, are the token types of the exported records
+	Types []string
+}
+
+// ExportedRecord is a pseudonymized audit record
+type ExportedRecord struct {
+	TxID        string
+	ActionIndex uint32
+	// Pseudonym replaces the enrollment ID, it is stable for a given salt
+	Pseudonym string
+	Type      string
+	// Amount is the bucketed amount, positive if received, negative if sent
+	Amount *big.Int
+	Status Status
+}
+
+// Pseudonymizer turns audit records into pseudonymized records
+type Pseudonymizer struct {
+	salt       []byte
+	bucketSize *big.Int
+}
+
+func NewPseudonymizer(opts *ExportOptions) (*Pseudonymizer, error) {
+	if opts == nil {
+		return nil, errors.New("export options not specified")
+	}
+	if len(opts.Salt) < MinSaltSize {
+		return nil, errors.Errorf("salt must be at least [%d] bytes", MinSaltSize)
+	}
+	if opts.BucketSize != nil && opts.BucketSize.Sign() <= 0 {
+		return nil, errors.Errorf("invalid bucket size [%s]", opts.BucketSize)
+	}
+	return &Pseudonymizer{salt: opts.Salt, bucketSize: opts.BucketSize}, nil
+}
+
+// Pseudonym returns the pseudonym of the passed enrollment ID
+func (p *Pseudonymizer) Pseudonym(eID string) string {
+	mac := hmac.New(sha256.New, p.salt)
+	mac.Write([]byte(eID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Bucket returns the passed amount rounded, toward zero, to a multiple of the bucket size
+func (p *Pseudonymizer) Bucket(amount *big.Int) *big.Int {
+	if amount == nil {
+		return big.NewInt(0)
+	}
+	if p.bucketSize == nil {
+		return new(big.Int).Set(amount)
+	}
+	bucket := new(big.Int).Quo(amount, p.bucketSize)
+	return bucket.Mul(bucket, p.bucketSize)
+}
+
+func (p *Pseudonymizer) Pseudonymize(record *driver.Record) *ExportedRecord {
+	return &ExportedRecord{
+		TxID:        record.TxID,
+		ActionIndex: record.ActionIndex,
+		Pseudonym:   p.Pseudonym(record.EnrollmentID),
+		Type:        record.Type,
+		Amount:      p.Bucket(record.Amount),
+		Status:      Status(record.Status),
+	}
+}
+
+// Export returns the records of the audit db, in insertion order, pseudonymized as specified by the passed options:
+// the enrollment IDs are replaced by salted hashes and the amounts are bucketed.
+// The result can be shared without exposing the identifiers of the customers.
+func (qe *QueryExecutor) Export(opts *ExportOptions) ([]*ExportedRecord, error) {
+	p, err := NewPseudonymizer(opts)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []driver.Status{driver.Confirmed}
+	if len(opts.Statuses) != 0 {
+		statuses = nil
+		for _, status := range opts.Statuses {
+			statuses = append(statuses, driver.Status(status))
+		}
+	}
+	records, err := qe.db.db.Query(nil, opts.Types, statuses, driver.FromBeginning, driver.All, 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed querying records")
+	}
+	exported := make([]*ExportedRecord, len(records))
+	for i, record := range records {
+		exported[i] = p.Pseudonymize(record)
+	}
+	return exported, nil
+}
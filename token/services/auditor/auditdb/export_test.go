@@ -0,0 +1,55 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package auditdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb/driver"
+)
+
+func TestPseudonymizer(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+
+	_, err := NewPseudonymizer(&ExportOptions{Salt: []byte("short")})
+	assert.Error(t, err)
+	_, err = NewPseudonymizer(&ExportOptions{Salt: salt, BucketSize: big.NewInt(0)})
+	assert.Error(t, err)
+
+	p, err := NewPseudonymizer(&ExportOptions{Salt: salt, BucketSize: big.NewInt(100)})
+	assert.NoError(t, err)
+	other, err := NewPseudonymizer(&ExportOptions{Salt: []byte("fedcba9876543210")})
+	assert.NoError(t, err)
+
+	// pseudonyms are stable for a given salt
+	assert.Equal(t, p.Pseudonym("alice"), p.Pseudonym("alice"))
+	assert.NotEqual(t, p.Pseudonym("alice"), p.Pseudonym("bob"))
+	assert.NotEqual(t, p.Pseudonym("alice"), other.Pseudonym("alice"))
+	assert.NotContains(t, p.Pseudonym("alice"), "alice")
+
+	assert.Equal(t, big.NewInt(200), p.Bucket(big.NewInt(250)))
+	assert.Equal(t, big.NewInt(-200), p.Bucket(big.NewInt(-250)))
+	assert.Equal(t, 0, p.Bucket(big.NewInt(99)).Sign())
+	assert.Equal(t, big.NewInt(250), other.Bucket(big.NewInt(250)))
+
+	record := &driver.Record{
+		TxID:         "tx1",
+		EnrollmentID: "alice",
+		Type:         "EUR",
+		Amount:       big.NewInt(1234),
+		Status:       driver.Confirmed,
+	}
+	exported := p.Pseudonymize(record)
+	assert.Equal(t, "tx1", exported.TxID)
+	assert.Equal(t, p.Pseudonym("alice"), exported.Pseudonym)
+	assert.Equal(t, big.NewInt(1200), exported.Amount)
+	assert.Equal(t, Valid, exported.Status)
+	// the original record is untouched
+	assert.Equal(t, big.NewInt(1234), record.Amount)
+}
@@ -56,6 +56,11 @@ func (f *PaymentsFilter) Sum() token2.Quantity {
 	return token2.NewQuantityFromBig64(sum)
 }
 
+// Records returns the records selected by the last execution of this filter
+func (f *PaymentsFilter) Records() []*driver.Record {
+	return f.records
+}
+
 type HoldingsFilter struct {
 	db *AuditDB
 
@@ -91,3 +96,8 @@ func (f *HoldingsFilter) Sum() token2.Quantity {
 	}
 	return token2.NewQuantityFromBig64(sum)
 }
+
+// Records returns the records selected by the last execution of this filter
+func (f *HoldingsFilter) Records() []*driver.Record {
+	return f.records
+}
@@ -0,0 +1,131 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package auditdb
+
+import (
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb/driver"
+)
+
+// finalityPrefix is the kvs prefix of the transactions waiting for finality, see TrackFinality
+const finalityPrefix = "token-sdk.auditdb.finality"
+
+// Finality gives access to the finality of the transactions of a channel and to the blocks they are committed in
+type Finality interface {
+	// IsFinal waits for the finality of the passed transaction, it returns nil if the transaction has been committed as valid
+	IsFinal(txID string) error
+	// BlockNumber returns the number of the block the passed transaction has been committed in
+	BlockNumber(txID string) (uint64, error)
+}
+
+// FinalityProvider returns the finality of the passed network and channel
+type FinalityProvider = func(network, channel string) Finality
+
+// channelFinality is the Finality of a fabric channel
+type channelFinality struct {
+	ch *fabric.Channel
+}
+
+// NewChannelFinality returns the finality of the passed channel, the block numbers are read from its ledger
+func NewChannelFinality(ch *fabric.Channel) Finality {
+	return &channelFinality{ch: ch}
+}
+
+func (f *channelFinality) IsFinal(txID string) error {
+	return f.ch.Finality().IsFinal(txID)
+}
+
+func (f *channelFinality) BlockNumber(txID string) (uint64, error) {
+	return f.ch.Ledger().GetBlockNumberByTxID(txID)
+}
+
+// pendingFinality is a transaction waiting for finality, as recorded in the kvs.
+// The kvs does not support deletion, then an entry is marked as Done once the transaction is final.
+type pendingFinality struct {
+	Network   string
+	Channel   string
+	TxID      string
+	Timestamp time.Time
+	Done      bool
+}
+
+// TrackFinality waits, in the background, for the finality of the passed transaction, whose timestamp,
+// as fixed by the ledger, is the passed one.
+// If the transaction is committed as valid, its records are confirmed, with the block the transaction has been
+// committed in, otherwise they are deleted.
+// The transaction is recorded in the kvs until then, to resume the tracking after a restart, see ResumeFinality.
+func (db *AuditDB) TrackFinality(network, channel, txID string, timestamp time.Time, finality Finality) error {
+	if db.sp != nil {
+		p := &pendingFinality{Network: network, Channel: channel, TxID: txID, Timestamp: timestamp}
+		if err := kvs.GetService(db.sp).Put(db.finalityKey(txID), p); err != nil {
+			return errors.WithMessagef(err, "failed recording the finality tracking of [%s]", txID)
+		}
+	}
+	go db.waitFinality(txID, timestamp, finality)
+	return nil
+}
+
+// ResumeFinality resumes the tracking of the transactions recorded by TrackFinality and not final yet
+func (db *AuditDB) ResumeFinality(finality FinalityProvider) error {
+	if db.sp == nil {
+		return nil
+	}
+	it, err := kvs.GetService(db.sp).GetByPartialCompositeID(finalityPrefix, []string{db.id})
+	if err != nil {
+		return errors.WithMessage(err, "failed iterating over the transactions waiting for finality")
+	}
+	defer it.Close()
+	var pending []*pendingFinality
+	for it.HasNext() {
+		p := &pendingFinality{}
+		if err := it.Next(p); err != nil {
+			return errors.WithMessage(err, "failed reading a transaction waiting for finality")
+		}
+		if !p.Done {
+			pending = append(pending, p)
+		}
+	}
+	for _, p := range pending {
+		logger.Debugf("resume the finality tracking of [%s:%s:%s]", p.Network, p.Channel, p.TxID)
+		go db.waitFinality(p.TxID, p.Timestamp, finality(p.Network, p.Channel))
+	}
+	return nil
+}
+
+func (db *AuditDB) waitFinality(txID string, timestamp time.Time, finality Finality) {
+	if err := finality.IsFinal(txID); err != nil {
+		logger.Warnf("transaction [%s] not committed, deleting its records: [%s]", txID, err)
+		if err := db.SetStatus(txID, Status(driver.Deleted)); err != nil {
+			logger.Errorf("failed deleting records of [%s]: [%s]", txID, err)
+			return
+		}
+	} else {
+		// the tracking is resumed after a restart if the block of the transaction is not available yet
+		blockNumber, err := finality.BlockNumber(txID)
+		if err != nil {
+			logger.Errorf("failed getting the block of [%s]: [%s]", txID, err)
+			return
+		}
+		if err := db.Confirm(txID, blockNumber, timestamp); err != nil {
+			logger.Errorf("failed confirming records of [%s]: [%s]", txID, err)
+			return
+		}
+	}
+	if db.sp != nil {
+		if err := kvs.GetService(db.sp).Put(db.finalityKey(txID), &pendingFinality{TxID: txID, Done: true}); err != nil {
+			logger.Errorf("failed closing the finality tracking of [%s]: [%s]", txID, err)
+		}
+	}
+}
+
+func (db *AuditDB) finalityKey(txID string) string {
+	return kvs.CreateCompositeKeyOrPanic(finalityPrefix, []string{db.id, txID})
+}
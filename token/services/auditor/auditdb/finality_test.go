@@ -0,0 +1,166 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package auditdb
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/api"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb/driver"
+)
+
+// configProvider configures the in memory kvs
+type configProvider struct {
+	api.ConfigProvider
+}
+
+func (*configProvider) UnmarshalKey(string, interface{}) error {
+	return nil
+}
+
+// statusLog is an audit db driver recording the status of the transactions
+type statusLog struct {
+	driver.AuditDB
+	lock     sync.Mutex
+	statuses map[string]driver.Status
+	commits  []*driver.Commit
+}
+
+func (l *statusLog) BeginUpdate() error { return nil }
+func (l *statusLog) Commit() error      { return nil }
+func (l *statusLog) Discard() error     { return nil }
+
+func (l *statusLog) SetStatus(txID string, status driver.Status) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.statuses[txID] = status
+	return nil
+}
+
+func (l *statusLog) AppendCommit(txID string, blockNumber uint64, timestamp time.Time) (uint64, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.commits = append(l.commits, &driver.Commit{Cursor: uint64(len(l.commits) + 1), TxID: txID, BlockNumber: blockNumber, Timestamp: timestamp})
+	return uint64(len(l.commits)), nil
+}
+
+func (l *statusLog) commit(txID string) *driver.Commit {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for _, commit := range l.commits {
+		if commit.TxID == txID {
+			return commit
+		}
+	}
+	return nil
+}
+
+func (l *statusLog) status(txID string) driver.Status {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.statuses[txID]
+}
+
+// finality commits the transactions, except the invalid ones, in the passed blocks
+type finality struct {
+	wait    chan struct{}
+	invalid map[string]bool
+	blocks  map[string]uint64
+}
+
+func (f *finality) IsFinal(txID string) error {
+	<-f.wait
+	if f.invalid[txID] {
+		return errors.New("invalid")
+	}
+	return nil
+}
+
+func (f *finality) BlockNumber(txID string) (uint64, error) {
+	block, ok := f.blocks[txID]
+	if !ok {
+		return 0, errors.Errorf("block of [%s] not found", txID)
+	}
+	return block, nil
+}
+
+func TestTrackFinality(t *testing.T) {
+	log := &statusLog{statuses: map[string]driver.Status{}}
+	db := newAuditDB(log)
+	timestamp := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	f := &finality{wait: make(chan struct{}), blocks: map[string]uint64{"tx1": 7}}
+	close(f.wait)
+
+	// the commit log records the block and the ledger time of the transaction
+	assert.NoError(t, db.TrackFinality("network", "channel", "tx1", timestamp, f))
+	assert.Eventually(t, func() bool {
+		return log.status("tx1") == driver.Confirmed
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, &driver.Commit{Cursor: 1, TxID: "tx1", BlockNumber: 7, Timestamp: timestamp}, log.commit("tx1"))
+}
+
+func TestResumeFinality(t *testing.T) {
+	sp := registry.New()
+	assert.NoError(t, sp.RegisterService(&configProvider{}))
+	kvss, err := kvs.New("memory", "", sp)
+	assert.NoError(t, err)
+	assert.NoError(t, sp.RegisterService(kvss))
+
+	// track two transactions whose finality never comes before the restart
+	before := newAuditDB(&statusLog{statuses: map[string]driver.Status{}})
+	before.sp, before.id = sp, "auditor"
+	timestamp := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	never := &finality{wait: make(chan struct{})}
+	assert.NoError(t, before.TrackFinality("network", "channel", "tx1", timestamp, never))
+	assert.NoError(t, before.TrackFinality("network", "channel", "tx2", timestamp, never))
+
+	// after the restart, the tracking resumes on the recorded network and channel
+	log := &statusLog{statuses: map[string]driver.Status{}}
+	after := newAuditDB(log)
+	after.sp, after.id = sp, "auditor"
+	final := &finality{wait: make(chan struct{}), invalid: map[string]bool{"tx2": true}, blocks: map[string]uint64{"tx1": 3}}
+	close(final.wait)
+	assert.NoError(t, after.ResumeFinality(func(network, channel string) Finality {
+		assert.Equal(t, "network", network)
+		assert.Equal(t, "channel", channel)
+		return final
+	}))
+	assert.Eventually(t, func() bool {
+		return log.status("tx1") == driver.Confirmed && log.status("tx2") == driver.Deleted
+	}, time.Second, 10*time.Millisecond)
+	// the ledger time recorded before the restart is kept
+	assert.Equal(t, &driver.Commit{Cursor: 1, TxID: "tx1", BlockNumber: 3, Timestamp: timestamp}, log.commit("tx1"))
+
+	// the final transactions are not resumed again
+	assert.Eventually(t, func() bool {
+		for _, txID := range []string{"tx1", "tx2"} {
+			p := &pendingFinality{}
+			if err := kvss.Get(after.finalityKey(txID), p); err != nil || !p.Done {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, after.ResumeFinality(func(network, channel string) Finality {
+		t.Errorf("unexpected transaction of [%s:%s]", network, channel)
+		return final
+	}))
+
+	// another auditor wallet does not see these transactions
+	other := newAuditDB(log)
+	other.sp, other.id = sp, "other"
+	assert.NoError(t, other.ResumeFinality(func(network, channel string) Finality {
+		t.Errorf("unexpected transaction of [%s:%s]", network, channel)
+		return final
+	}))
+}
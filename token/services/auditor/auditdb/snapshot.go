@@ -0,0 +1,133 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package auditdb
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb/driver"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// Snapshot holds the balances, per enrollment ID and token type, resulting from the transactions committed
+// up to a point of the commit log. It is computed by replaying the commit log, see QueryExecutor.SnapshotAt.
+type Snapshot struct {
+	// Cursor is the position in the commit log of the last transaction included in the snapshot, zero if none
+	Cursor uint64
+	// Height is the ledger height following the highest block of the transactions included in the snapshot, zero if none
+	Height uint64
+	// Timestamp is the latest ledger time of the transactions included in the snapshot
+	Timestamp time.Time
+
+	balances map[string]map[string]*big.Int
+}
+
+func newSnapshot() *Snapshot {
+	return &Snapshot{balances: map[string]map[string]*big.Int{}}
+}
+
+// Balance returns the balance of the passed enrollment ID in the passed token type
+func (s *Snapshot) Balance(eID string, tokenType string) token2.Quantity {
+	balance, ok := s.balances[eID][tokenType]
+	if !ok {
+		return token2.NewQuantityFromBig64(big.NewInt(0))
+	}
+	return token2.NewQuantityFromBig64(new(big.Int).Set(balance))
+}
+
+// EnrollmentIDs returns, sorted, the enrollment IDs involved in the transactions included in the snapshot
+func (s *Snapshot) EnrollmentIDs() []string {
+	var res []string
+	for eID := range s.balances {
+		res = append(res, eID)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// TokenTypes returns, sorted, the token types held or moved by the passed enrollment ID
+func (s *Snapshot) TokenTypes(eID string) []string {
+	var res []string
+	for tokenType := range s.balances[eID] {
+		res = append(res, tokenType)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (s *Snapshot) add(commit *driver.Commit, records []*driver.Record) {
+	// the commit log follows the order of confirmation, not necessarily the order of the ledger
+	s.Cursor = commit.Cursor
+	if commit.BlockNumber != 0 && commit.BlockNumber >= s.Height {
+		s.Height = commit.BlockNumber + 1
+	}
+	if commit.Timestamp.After(s.Timestamp) {
+		s.Timestamp = commit.Timestamp
+	}
+	for _, record := range records {
+		if record.Status != driver.Confirmed || record.Amount == nil {
+			continue
+		}
+		byType, ok := s.balances[record.EnrollmentID]
+		if !ok {
+			byType = map[string]*big.Int{}
+			s.balances[record.EnrollmentID] = byType
+		}
+		balance, ok := byType[record.Type]
+		if !ok {
+			balance = big.NewInt(0)
+			byType[record.Type] = balance
+		}
+		balance.Add(balance, record.Amount)
+	}
+}
+
+// SnapshotAt returns the balances resulting from the transactions whose ledger time is up to the passed time, included.
+// The transactions appended to the commit log before their ledger time was recorded are always included.
+func (qe *QueryExecutor) SnapshotAt(t time.Time) (*Snapshot, error) {
+	return qe.snapshot(func(commit *driver.Commit) bool {
+		return !commit.Timestamp.After(t)
+	})
+}
+
+// SnapshotAtHeight returns the balances resulting from the transactions committed in the blocks below the passed
+// ledger height. The transactions appended to the commit log before their block was recorded are always included.
+func (qe *QueryExecutor) SnapshotAtHeight(height uint64) (*Snapshot, error) {
+	return qe.snapshot(func(commit *driver.Commit) bool {
+		return commit.BlockNumber < height
+	})
+}
+
+// SnapshotAtCursor returns the balances resulting from the transactions committed up to the passed position
+// of the commit log, included. Cursors are those returned by the events of a Subscription.
+func (qe *QueryExecutor) SnapshotAtCursor(cursor uint64) (*Snapshot, error) {
+	return qe.snapshot(func(commit *driver.Commit) bool {
+		return commit.Cursor <= cursor
+	})
+}
+
+func (qe *QueryExecutor) snapshot(include func(commit *driver.Commit) bool) (*Snapshot, error) {
+	commits, err := qe.db.db.QueryCommits(0, 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed querying commits")
+	}
+	s := newSnapshot()
+	for _, commit := range commits {
+		if !include(commit) {
+			continue
+		}
+		records, err := qe.db.db.QueryByTxID(commit.TxID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed querying records of [%s]", commit.TxID)
+		}
+		s.add(commit, records)
+	}
+	return s, nil
+}
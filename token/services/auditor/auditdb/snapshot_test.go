@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package auditdb
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb/driver"
+)
+
+// commitLog is an audit db driver serving the commit log and the records of the committed transactions
+type commitLog struct {
+	driver.AuditDB
+	commits []*driver.Commit
+	records map[string][]*driver.Record
+}
+
+func (l *commitLog) QueryCommits(after uint64, numRecords int) ([]*driver.Commit, error) {
+	return l.commits[after:], nil
+}
+
+func (l *commitLog) QueryByTxID(txID string) ([]*driver.Record, error) {
+	return l.records[txID], nil
+}
+
+func TestSnapshot(t *testing.T) {
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	record := func(txID, eID string, amount int64, status driver.Status) *driver.Record {
+		return &driver.Record{TxID: txID, EnrollmentID: eID, Type: "EUR", Amount: big.NewInt(amount), Status: status}
+	}
+	log := &commitLog{
+		commits: []*driver.Commit{
+			{Cursor: 1, TxID: "tx1", BlockNumber: 4, Timestamp: day.Add(1 * time.Hour)},
+			{Cursor: 2, TxID: "tx2", BlockNumber: 7, Timestamp: day.Add(2 * time.Hour)},
+			{Cursor: 3, TxID: "tx3", BlockNumber: 12, Timestamp: day.Add(26 * time.Hour)},
+		},
+		records: map[string][]*driver.Record{
+			"tx1": {record("tx1", "alice", 100, driver.Confirmed)},
+			"tx2": {record("tx2", "alice", -30, driver.Confirmed), record("tx2", "bob", 30, driver.Confirmed)},
+			"tx3": {record("tx3", "bob", -10, driver.Confirmed), record("tx3", "charlie", 10, driver.Confirmed), record("tx3", "bob", 5, driver.Deleted)},
+		},
+	}
+	qe := &QueryExecutor{db: newAuditDB(log)}
+
+	// end of the first day
+	s, err := qe.SnapshotAt(day.Add(24 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), s.Cursor)
+	assert.Equal(t, uint64(8), s.Height)
+	assert.Equal(t, day.Add(2*time.Hour), s.Timestamp)
+	assert.Equal(t, []string{"alice", "bob"}, s.EnrollmentIDs())
+	assert.Equal(t, []string{"EUR"}, s.TokenTypes("alice"))
+	assert.Equal(t, "70", s.Balance("alice", "EUR").Decimal())
+	assert.Equal(t, "30", s.Balance("bob", "EUR").Decimal())
+	assert.Equal(t, "0", s.Balance("charlie", "EUR").Decimal())
+
+	s, err = qe.SnapshotAtCursor(3)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob", "charlie"}, s.EnrollmentIDs())
+	assert.Equal(t, "20", s.Balance("bob", "EUR").Decimal())
+	assert.Equal(t, "10", s.Balance("charlie", "EUR").Decimal())
+
+	// the blocks below the height
+	s, err = qe.SnapshotAtHeight(12)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), s.Cursor)
+	assert.Equal(t, "30", s.Balance("bob", "EUR").Decimal())
+	s, err = qe.SnapshotAtHeight(13)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(13), s.Height)
+	assert.Equal(t, "20", s.Balance("bob", "EUR").Decimal())
+
+	s, err = qe.SnapshotAt(day)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), s.Cursor)
+	assert.Empty(t, s.EnrollmentIDs())
+}
@@ -0,0 +1,77 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package auditdb
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb/driver"
+)
+
+// Event carries the audit records of a committed transaction
+type Event struct {
+	// Cursor is the position of the transaction in the commit log,
+	// pass it to Subscribe to resume after this event
+	Cursor  uint64
+	TxID    string
+	Records []*driver.Record
+}
+
+// Subscription is a stream of the transactions committed after a given cursor, in commit order
+type Subscription struct {
+	db     *AuditDB
+	cursor uint64
+}
+
+// Cursor returns the cursor of the last event returned by Next
+func (s *Subscription) Cursor() uint64 {
+	return s.cursor
+}
+
+// Next returns the next committed transaction, blocking until there is one or the passed context is done
+func (s *Subscription) Next(ctx context.Context) (*Event, error) {
+	for {
+		// get the notification channel before querying, not to miss commits happening in between
+		s.db.committedLock.Lock()
+		committed := s.db.committed
+		s.db.committedLock.Unlock()
+
+		event, err := s.next()
+		if err != nil {
+			return nil, err
+		}
+		if event != nil {
+			s.cursor = event.Cursor
+			return event, nil
+		}
+
+		select {
+		case <-committed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (s *Subscription) next() (*Event, error) {
+	s.db.storeLock.RLock()
+	defer s.db.storeLock.RUnlock()
+
+	commits, err := s.db.db.QueryCommits(s.cursor, 1)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed querying commits after [%d]", s.cursor)
+	}
+	if len(commits) == 0 {
+		return nil, nil
+	}
+	records, err := s.db.db.QueryByTxID(commits[0].TxID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed querying records of [%s]", commits[0].TxID)
+	}
+	return &Event{Cursor: commits[0].Cursor, TxID: commits[0].TxID, Records: records}, nil
+}
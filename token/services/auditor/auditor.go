@@ -6,11 +6,14 @@ SPDX-License-Identifier: Apache-2.0
 package auditor
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 type QueryExecutor struct {
@@ -30,15 +33,16 @@ func (a *QueryExecutor) Done() {
 }
 
 type Auditor struct {
+	sp view2.ServiceProvider
 	db *auditdb.AuditDB
 }
 
 func New(sp view2.ServiceProvider, w *token.AuditorWallet) *Auditor {
-	return &Auditor{db: auditdb.GetAuditDB(sp, w)}
+	return &Auditor{sp: sp, db: auditdb.GetAuditDB(sp, w)}
 }
 
-func (a *Auditor) Validate(request *token.Request) error {
-	return request.AuditCheck()
+func (a *Auditor) Validate(ctx context.Context, request *token.Request) error {
+	return request.AuditCheck(ctx)
 }
 
 func (a *Auditor) Audit(request *token.Request) (*token.InputStream, *token.OutputStream, error) {
@@ -57,3 +61,18 @@ func (a *Auditor) Audit(request *token.Request) (*token.InputStream, *token.Outp
 func (a *Auditor) NewQueryExecutor() *QueryExecutor {
 	return &QueryExecutor{QueryExecutor: a.db.NewQueryExecutor()}
 }
+
+// UnspentTokensByEnrollmentID returns the unspent tokens, audited by this auditor, whose owner has the passed enrollment ID.
+// The options select the token management service to query.
+func (a *Auditor) UnspentTokensByEnrollmentID(eID string, opts ...token.ServiceOption) (*token2.UnspentTokens, error) {
+	tms := token.GetManagementService(a.sp, opts...)
+	if tms == nil {
+		return nil, errors.Errorf("token management service not found")
+	}
+	return tms.Vault().NewQueryEngine().UnspentAuditTokensByEnrollmentID(eID)
+}
+
+// Subscribe returns a subscription to the audit records of the transactions committed after the passed cursor
+func (a *Auditor) Subscribe(cursor uint64) *auditdb.Subscription {
+	return a.db.Subscribe(cursor)
+}
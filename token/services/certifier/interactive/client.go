@@ -91,6 +91,16 @@ func (d *CertificationClient) RequestCertification(ids ...*token2.Id) error {
 		return nil
 	}
 
+	// the certifiers accept a bounded number of token ids in a single round-trip
+	for _, chunk := range splitIDs(toBeCertified, DefaultBatchSize*MaxBatchRequests) {
+		if err := d.requestCertification(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *CertificationClient) requestCertification(toBeCertified []*token2.Id) error {
 	resultBoxed, err := d.viewManager.InitiateView(NewCertificationRequestView(d.channel, d.namespace, d.certifiers[0], toBeCertified...))
 	if err != nil {
 		return err
@@ -64,6 +64,11 @@ func (d *Driver) NewCertificationClient(sp view2.ServiceProvider, network, chann
 			return nil, errors.Errorf("no certifier id configured")
 		}
 
+		// pre-warm the certification cache, so that restarts don't trigger the certification of all the tokens again
+		if err := tokenVault.CertificationStorage().Load(); err != nil {
+			return nil, errors.WithMessagef(err, "failed loading certifications")
+		}
+
 		inst := NewCertificationClient(
 			context.Background(),
 			channel,
@@ -20,15 +20,48 @@ import (
 	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
+const (
+	// DefaultBatchSize is the default maximum number of token ids certified with a single certification request
+	DefaultBatchSize = 100
+	// DefaultCertifierWorkers is the default number of certification requests processed in parallel by the certifier
+	DefaultCertifierWorkers = 4
+	// DefaultVerificationWorkers is the default number of certification batches verified in parallel by the client
+	DefaultVerificationWorkers = 4
+	// DefaultResponseTimeout is the default time the client waits for the certifier to respond
+	DefaultResponseTimeout = 60 * time.Second
+	// MaxBatchRequests is the maximum number of certification requests the certifier accepts in a single batch
+	MaxBatchRequests = 16
+	// MaxRequestIDs is the maximum number of token ids the certifier accepts in a single certification request
+	MaxRequestIDs = 1000
+
+	// BatchProtocolVersion is the version of the batch certification protocol.
+	// The certifier serves also the clients sending a single CertificationRequest, the protocol without version.
+	BatchProtocolVersion = 1
+)
+
 type CertificationService struct {
-	sp      view2.ServiceProvider
-	wallets map[string]string
+	sp          view2.ServiceProvider
+	walletsLock sync.RWMutex
+	wallets     map[string]string
+
+	workers     int
+	queue       chan *certificationJob
+	workersOnce sync.Once
+	// certifier certifies a request, it is set to certify by default
+	certifier func(cr *CertificationRequest) ([][]byte, error)
+}
+
+// certificationJob is a certification request waiting in the certifier work queue
+type certificationJob struct {
+	request  *CertificationRequest
+	response chan *CertificationResponse
 }
 
 func NewCertificationService(sp view2.ServiceProvider) *CertificationService {
 	return &CertificationService{
 		sp:      sp,
 		wallets: map[string]string{},
+		workers: DefaultCertifierWorkers,
 	}
 }
 
@@ -36,71 +69,185 @@ func (c *CertificationService) Start() error {
 	(&sync.Once{}).Do(func() {
 		view2.GetRegistry(c.sp).RegisterResponder(c, &CertificationRequestView{})
 	})
+	c.workersOnce.Do(c.startWorkers)
 	return nil
 }
 
 func (c *CertificationService) SetWallet(network string, channel string, namespace string, wallet string) {
+	c.walletsLock.Lock()
+	defer c.walletsLock.Unlock()
 	c.wallets[network+":"+channel+":"+namespace] = wallet
 }
 
 func (c *CertificationService) Call(context view.Context) (interface{}, error) {
-	// 1. receive request
+	// 1. receive the batch of requests
 	logger.Debugf("receive certification request [%s]", context.ID())
 	s := session.JSon(context)
-	var cr *CertificationRequest
-	if err := s.Receive(&cr); err != nil {
+	var msg *certificationRequestMessage
+	if err := s.Receive(&msg); err != nil {
 		return nil, errors.WithMessage(err, "failed receiving certification request")
 	}
-	logger.Debugf("received certification request [%v]", cr)
 
-	// TODO: 2. validate request, if needed
+	// 2. process the requests
+	res, err := c.serve(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. respond
+	logger.Debugf("send back certifications")
+	if err := s.Send(res); err != nil {
+		return nil, errors.WithMessagef(err, "failed sending certifications")
+	}
+
+	return nil, nil
+}
+
+// serve processes the passed message and returns the response to send back, a BatchCertificationResponse,
+// or the certifications alone to a client of the protocol without version
+func (c *CertificationService) serve(msg *certificationRequestMessage) (interface{}, error) {
+	if msg == nil {
+		return nil, errors.Errorf("empty certification request")
+	}
+	switch msg.Version {
+	case 0:
+		if len(msg.Requests) != 0 {
+			return nil, errors.Errorf("batch certification request without version")
+		}
+		cr := msg.CertificationRequest
+		if err := validateRequest(&cr); err != nil {
+			return nil, errors.WithMessagef(err, "invalid certification request")
+		}
+		res := c.process([]*CertificationRequest{&cr})[0]
+		if len(res.Error) != 0 {
+			return nil, errors.Errorf("failed certifying [%s]: [%s]", &cr, res.Error)
+		}
+		return res.Certifications, nil
+	case BatchProtocolVersion:
+		if len(msg.Requests) == 0 {
+			return nil, errors.Errorf("empty certification request")
+		}
+		if len(msg.Requests) > MaxBatchRequests {
+			return nil, errors.Errorf("too many certification requests, [%d] > [%d]", len(msg.Requests), MaxBatchRequests)
+		}
+		for j, cr := range msg.Requests {
+			if err := validateRequest(cr); err != nil {
+				return nil, errors.WithMessagef(err, "invalid certification request [%d]", j)
+			}
+		}
+		logger.Debugf("received [%d] certification requests", len(msg.Requests))
+		return &BatchCertificationResponse{Version: BatchProtocolVersion, Responses: c.process(msg.Requests)}, nil
+	default:
+		return nil, errors.Errorf("unsupported certification protocol version [%d], expected [%d]", msg.Version, BatchProtocolVersion)
+	}
+}
+
+// process enqueues the passed requests and waits for their processing
+func (c *CertificationService) process(requests []*CertificationRequest) []*CertificationResponse {
+	c.workersOnce.Do(c.startWorkers)
+
+	jobs := make([]*certificationJob, len(requests))
+	for i, cr := range requests {
+		jobs[i] = &certificationJob{request: cr, response: make(chan *CertificationResponse, 1)}
+		c.queue <- jobs[i]
+	}
+	res := make([]*CertificationResponse, len(jobs))
+	for i, job := range jobs {
+		res[i] = <-job.response
+	}
+	return res
+}
+
+func (c *CertificationService) startWorkers() {
+	// the service might not have been created by NewCertificationService
+	if c.workers <= 0 {
+		c.workers = DefaultCertifierWorkers
+	}
+	if c.queue == nil {
+		c.queue = make(chan *certificationJob, MaxBatchRequests)
+	}
+	if c.certifier == nil {
+		c.certifier = c.certify
+	}
+	for i := 0; i < c.workers; i++ {
+		go func() {
+			for job := range c.queue {
+				certifications, err := c.certifier(job.request)
+				if err != nil {
+					logger.Errorf("failed certifying [%s]: [%s]", job.request, err)
+					job.response <- &CertificationResponse{Error: err.Error()}
+					continue
+				}
+				job.response <- &CertificationResponse{Certifications: certifications}
+			}
+		}()
+	}
+}
 
-	// 3. invoke chaincode to get token commitment
+// certify certifies the tokens of the passed request with a single invocation of the certification manager
+func (c *CertificationService) certify(cr *CertificationRequest) ([][]byte, error) {
+	// invoke chaincode to get token commitment
 	logger.Debugf("invoke chaincode to get commitments for [%v]", cr.IDs)
 	// TODO: if the certifier fetches all token transactions, it might have the tokens in its on vault.
-	tokensBoxed, err := context.RunView(tcc.NewGetTokensView(cr.Channel, cr.Namespace, cr.IDs...))
+	tokensBoxed, err := view2.GetManager(c.sp).InitiateView(tcc.NewGetTokensView(cr.Channel, cr.Namespace, cr.IDs...))
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed getting tokens [%s:%s][%v]", cr.Channel, cr.Namespace, cr.IDs)
 	}
-
 	tokens, ok := tokensBoxed.([][]byte)
 	if !ok {
-		return nil, errors.Errorf("expected [][]byte, got [%T]", tokens)
+		return nil, errors.Errorf("expected [][]byte, got [%T]", tokensBoxed)
 	}
 
-	// 4. certify token commitment
+	// certify token commitment
 	logger.Debugf("certify commitments for [%v]...", cr.IDs)
 	tms := token2.GetManagementService(
-		context,
+		c.sp,
 		token2.WithNetwork(cr.Network),
 		token2.WithChannel(cr.Channel),
 		token2.WithNamespace(cr.Namespace),
 	)
 	walletKey := tms.Network() + ":" + tms.Channel() + ":" + tms.Namespace()
 	logger.Debugf("lookup wallet ID with key [%s]", walletKey)
+	c.walletsLock.RLock()
 	walletID, ok := c.wallets[walletKey]
+	c.walletsLock.RUnlock()
 	if !ok {
-		logger.Errorf("failed getting certifier wallet, namespace not registered [%s]: [%s]", cr, err)
-		return nil, errors.WithMessagef(err, "failed getting certifier wallet, namespace not registered [%s]", cr)
+		return nil, errors.Errorf("failed getting certifier wallet, namespace not registered [%s]", cr)
 	}
 	logger.Debugf("certify with wallet [%s]", walletID)
 	w := tms.WalletManager().CertifierWallet(walletID)
 	if w == nil {
-		return nil, errors.WithMessagef(err, "failed getting certifier wallet, wallet [%s] not found [%s:%s][%v]", walletID, cr.Channel, cr.Namespace, cr.IDs)
+		return nil, errors.Errorf("failed getting certifier wallet, wallet [%s] not found [%s:%s][%v]", walletID, cr.Channel, cr.Namespace, cr.IDs)
 	}
-	logger.Debugf("certify request [%v]", cr)
 	certifications, err := tms.CertificationManager().Certify(w, cr.IDs, tokens, cr.Request)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed certifying tokens [%s:%s][%v]", cr.Channel, cr.Namespace, cr.IDs)
 	}
+	return certifications, nil
+}
 
-	// 5. respond
-	logger.Debugf("send back certifications for [%v]", cr.IDs)
-	if err := s.Send(certifications); err != nil {
-		return nil, errors.WithMessagef(err, "failed sending certifications")
+// validateRequest checks that the passed request lists a bounded number of distinct token ids of a namespace
+func validateRequest(cr *CertificationRequest) error {
+	if cr == nil || len(cr.IDs) == 0 {
+		return errors.Errorf("no token ids to certify")
 	}
-
-	return nil, nil
+	if len(cr.IDs) > MaxRequestIDs {
+		return errors.Errorf("too many token ids to certify, [%d] > [%d]", len(cr.IDs), MaxRequestIDs)
+	}
+	if len(cr.Channel) == 0 || len(cr.Namespace) == 0 {
+		return errors.Errorf("no channel or namespace specified")
+	}
+	seen := make(map[token.Id]bool, len(cr.IDs))
+	for _, id := range cr.IDs {
+		if id == nil || len(id.TxId) == 0 {
+			return errors.Errorf("invalid token id, it is empty")
+		}
+		if seen[*id] {
+			return errors.Errorf("token id [%s] listed more than once", id)
+		}
+		seen[*id] = true
+	}
+	return nil
 }
 
 type CertificationRequest struct {
@@ -113,64 +260,137 @@ func (cr *CertificationRequest) String() string {
 	return fmt.Sprintf("CertificationRequest[%s,%s,%s][%v]", cr.Request, cr.Channel, cr.Namespace, cr.IDs)
 }
 
+// BatchCertificationRequest carries certification requests to be served in a single round-trip
+type BatchCertificationRequest struct {
+	// Version is the version of the protocol, BatchProtocolVersion
+	Version  int
+	Requests []*CertificationRequest
+}
+
+// certificationRequestMessage is the message received by the certifier, a BatchCertificationRequest or,
+// without version, a single CertificationRequest
+type certificationRequestMessage struct {
+	Version  int
+	Requests []*CertificationRequest
+	CertificationRequest
+}
+
+// CertificationResponse carries the certifications of the token ids of a CertificationRequest, or the reason of the failure
+type CertificationResponse struct {
+	Certifications [][]byte
+	Error          string
+}
+
+// BatchCertificationResponse carries the responses to a BatchCertificationRequest, in the same order of the requests
+type BatchCertificationResponse struct {
+	Version   int
+	Responses []*CertificationResponse
+}
+
 type CertificationRequestView struct {
 	network, channel, ns string
 	ids                  []*token.Id
 	certifier            view.Identity
+	batchSize            int
+	verificationWorkers  int
+	timeout              time.Duration
 }
 
 func NewCertificationRequestView(channel, ns string, certifier view.Identity, ids ...*token.Id) *CertificationRequestView {
 	return &CertificationRequestView{
-		channel:   channel,
-		certifier: certifier,
-		ns:        ns,
-		ids:       ids,
+		channel:             channel,
+		certifier:           certifier,
+		ns:                  ns,
+		ids:                 ids,
+		batchSize:           DefaultBatchSize,
+		verificationWorkers: DefaultVerificationWorkers,
+		timeout:             DefaultResponseTimeout,
 	}
 }
 
+// WithBatchSize sets the maximum number of token ids certified with a single certification request,
+// at most MaxRequestIDs
+func (i *CertificationRequestView) WithBatchSize(batchSize int) *CertificationRequestView {
+	if batchSize > 0 && batchSize <= MaxRequestIDs {
+		i.batchSize = batchSize
+	}
+	return i
+}
+
+// WithVerificationWorkers sets the number of batches of certifications verified in parallel
+func (i *CertificationRequestView) WithVerificationWorkers(workers int) *CertificationRequestView {
+	if workers > 0 {
+		i.verificationWorkers = workers
+	}
+	return i
+}
+
 func (i *CertificationRequestView) Call(context view.Context) (interface{}, error) {
-	// 1. prepare request
-	logger.Debugf("prepare certification request for [%v]", i.ids)
+	if i.certifier.IsNone() {
+		return nil, errors.Errorf("no certifiers defined")
+	}
+
+	// 1. prepare a request for each batch of ids
+	batches := splitIDs(i.ids, i.batchSize)
+	if len(batches) > MaxBatchRequests {
+		return nil, errors.Errorf("too many token ids to certify in a single round-trip, [%d] > [%d]", len(i.ids), MaxBatchRequests*i.batchSize)
+	}
+	logger.Debugf("prepare [%d] certification requests for [%v]", len(batches), i.ids)
 	cm := token2.GetManagementService(
 		context,
 		token2.WithNetwork(i.network),
 		token2.WithChannel(i.channel),
 		token2.WithNamespace(i.ns),
 	).CertificationManager()
-	cr, err := cm.NewCertificationRequest(i.ids)
-	if err != nil {
-		return nil, errors.WithMessagef(err, "failed creating certification request fo [%v]", i.ids)
+	req := &BatchCertificationRequest{Version: BatchProtocolVersion, Requests: make([]*CertificationRequest, len(batches))}
+	for j, batch := range batches {
+		cr, err := cm.NewCertificationRequest(batch)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed creating certification request fo [%v]", batch)
+		}
+		req.Requests[j] = &CertificationRequest{
+			Network:   i.network,
+			Channel:   i.channel,
+			Namespace: i.ns,
+			IDs:       batch,
+			Request:   cr,
+		}
 	}
 
-	// 2. send request
+	// 2. send requests
 	logger.Debugf("send certification request for [%v]", i.ids)
-	if i.certifier.IsNone() {
-		return nil, errors.Errorf("no certifiers defined")
-	}
-
 	s, err := session.NewJSon(context, i, i.certifier)
 	if err != nil {
 		return nil, errors.WithMessagef(err, "failed opening session to [%s]", i.certifier)
 	}
-	if err := s.Send(&CertificationRequest{
-		Channel:   i.channel,
-		Namespace: i.ns,
-		IDs:       i.ids,
-		Request:   cr,
-	}); err != nil {
+	if err := s.Send(req); err != nil {
 		return nil, errors.WithMessagef(err, "failed sending certification request [%v] to [%s]", i.ids, i.certifier)
 	}
 
 	// 3. wait response
 	logger.Debugf("wait certification request response for [%v]", i.ids)
-	var certifications [][]byte
-	if err := s.ReceiveWithTimeout(&certifications, 60*time.Second); err != nil {
+	var res *BatchCertificationResponse
+	if err := s.ReceiveWithTimeout(&res, i.timeout); err != nil {
 		return nil, errors.WithMessagef(err, "failed receiving certifications [%v] from [%s]", i.ids, i.certifier)
 	}
+	if res == nil || res.Version != BatchProtocolVersion {
+		return nil, errors.Errorf("invalid certification response from [%s], expected version [%d]", i.certifier, BatchProtocolVersion)
+	}
+	if len(res.Responses) != len(batches) {
+		return nil, errors.Errorf("invalid certification response from [%s], expected [%d] responses", i.certifier, len(batches))
+	}
+	for j, r := range res.Responses {
+		if r == nil {
+			return nil, errors.Errorf("invalid certification response from [%s], missing response [%d]", i.certifier, j)
+		}
+		if len(r.Error) != 0 {
+			return nil, errors.Errorf("certifier [%s] failed certifying [%v]: [%s]", i.certifier, batches[j], r.Error)
+		}
+	}
 
 	// 4. Validate response
 	logger.Debugf("validate certification request response for [%v]", i.ids)
-	if err := cm.VerifyCertifications(i.ids, certifications); err != nil {
+	if err := i.verify(cm, batches, res.Responses); err != nil {
 		logger.Errorf("failed verifying certifications of [%v] from [%s] with err [%s]", i.ids, i.certifier, err)
 		return nil, errors.WithMessagef(err, "failed verifying certifications of [%v] from [%s]", i.ids, i.certifier)
 	}
@@ -179,8 +399,57 @@ func (i *CertificationRequestView) Call(context view.Context) (interface{}, erro
 
 	// 5. return token certifications in the form of a map
 	result := map[*token.Id][]byte{}
-	for index, id := range i.ids {
-		result[id] = certifications[index]
+	for j, batch := range batches {
+		for index, id := range batch {
+			result[id] = res.Responses[j].Certifications[index]
+		}
 	}
 	return result, nil
 }
+
+// verify verifies the certifications of the passed batches in parallel
+func (i *CertificationRequestView) verify(cm *token2.CertificationManager, batches [][]*token.Id, responses []*CertificationResponse) error {
+	jobs := make(chan int, len(batches))
+	for j := range batches {
+		jobs <- j
+	}
+	close(jobs)
+
+	workers := i.verificationWorkers
+	if workers > len(batches) {
+		workers = len(batches)
+	}
+	errs := make(chan error, len(batches))
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if len(responses[j].Certifications) != len(batches[j]) {
+					errs <- errors.Errorf("expected [%d] certifications, got [%d]", len(batches[j]), len(responses[j].Certifications))
+					continue
+				}
+				if err := cm.VerifyCertifications(batches[j], responses[j].Certifications); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// splitIDs splits the passed ids in batches of at most the passed size
+func splitIDs(ids []*token.Id, size int) [][]*token.Id {
+	var batches [][]*token.Id
+	for len(ids) > size {
+		batches = append(batches, ids[:size])
+		ids = ids[size:]
+	}
+	if len(ids) != 0 {
+		batches = append(batches, ids)
+	}
+	return batches
+}
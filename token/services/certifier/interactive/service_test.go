@@ -0,0 +1,121 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package interactive
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+func ids(n int) []*token.Id {
+	res := make([]*token.Id, n)
+	for i := range res {
+		res[i] = &token.Id{TxId: fmt.Sprintf("tx%d", i)}
+	}
+	return res
+}
+
+func request(ids ...*token.Id) *CertificationRequest {
+	return &CertificationRequest{Channel: "channel", Namespace: "ns", IDs: ids}
+}
+
+// newService returns a certification service whose certifications are the ids of the tokens
+func newService() *CertificationService {
+	// not created by NewCertificationService, the workers and the queue are set when needed
+	return &CertificationService{certifier: func(cr *CertificationRequest) ([][]byte, error) {
+		if cr.Namespace == "failing" {
+			return nil, errors.New("certification failed")
+		}
+		res := make([][]byte, len(cr.IDs))
+		for i, id := range cr.IDs {
+			res[i] = []byte(id.TxId)
+		}
+		return res, nil
+	}}
+}
+
+// decode decodes the passed message as the certifier receives it
+func decode(t *testing.T, msg interface{}) *certificationRequestMessage {
+	raw, err := json.Marshal(msg)
+	assert.NoError(t, err)
+	res := &certificationRequestMessage{}
+	assert.NoError(t, json.Unmarshal(raw, res))
+	return res
+}
+
+func TestServeBatch(t *testing.T) {
+	c := newService()
+	failing := request(ids(1)...)
+	failing.Namespace = "failing"
+	res, err := c.serve(decode(t, &BatchCertificationRequest{
+		Version:  BatchProtocolVersion,
+		Requests: []*CertificationRequest{request(ids(2)...), failing},
+	}))
+	assert.NoError(t, err)
+	batch, ok := res.(*BatchCertificationResponse)
+	assert.True(t, ok)
+	assert.Equal(t, BatchProtocolVersion, batch.Version)
+	assert.Len(t, batch.Responses, 2)
+	assert.Equal(t, [][]byte{[]byte("tx0"), []byte("tx1")}, batch.Responses[0].Certifications)
+	assert.Equal(t, "certification failed", batch.Responses[1].Error)
+}
+
+func TestServeSingleRequestWithoutVersion(t *testing.T) {
+	c := newService()
+	res, err := c.serve(decode(t, request(ids(2)...)))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("tx0"), []byte("tx1")}, res)
+
+	failing := request(ids(1)...)
+	failing.Namespace = "failing"
+	_, err = c.serve(decode(t, failing))
+	assert.Error(t, err)
+}
+
+func TestServeRejectsInvalidRequests(t *testing.T) {
+	c := newService()
+	batch := func(requests ...*CertificationRequest) *certificationRequestMessage {
+		return decode(t, &BatchCertificationRequest{Version: BatchProtocolVersion, Requests: requests})
+	}
+	tooMany := make([]*CertificationRequest, MaxBatchRequests+1)
+	for i := range tooMany {
+		tooMany[i] = request(ids(1)...)
+	}
+	duplicated := ids(2)
+	duplicated[1] = &token.Id{TxId: duplicated[0].TxId}
+
+	for name, msg := range map[string]*certificationRequestMessage{
+		"nil":               nil,
+		"empty batch":       batch(),
+		"too many requests": batch(tooMany...),
+		"too many ids":      batch(request(ids(MaxRequestIDs + 1)...)),
+		"no ids":            batch(request()),
+		"nil id":            batch(request(nil)),
+		"duplicated id":     batch(request(duplicated...)),
+		"no namespace":      batch(&CertificationRequest{Channel: "channel", IDs: ids(1)}),
+		"nil request":       batch(nil),
+		"unknown version":   decode(t, &BatchCertificationRequest{Version: BatchProtocolVersion + 1, Requests: []*CertificationRequest{request(ids(1)...)}}),
+		"no version":        decode(t, &BatchCertificationRequest{Requests: []*CertificationRequest{request(ids(1)...)}}),
+	} {
+		_, err := c.serve(msg)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSplitIDs(t *testing.T) {
+	assert.Empty(t, splitIDs(nil, 10))
+	batches := splitIDs(ids(25), 10)
+	assert.Len(t, batches, 3)
+	assert.Len(t, batches[0], 10)
+	assert.Len(t, batches[2], 5)
+	assert.Len(t, splitIDs(ids(20), 10), 2)
+}
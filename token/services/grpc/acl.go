@@ -0,0 +1,50 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package grpc
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+)
+
+// ACL lists, for each client, named by the common name of its TLS certificate, the wallets it can access.
+// The default wallet is named by the empty string.
+type ACL map[string][]string
+
+// Authorize checks that the client of the passed context can access the wallet with the passed id
+func (a ACL) Authorize(ctx context.Context, wallet string) error {
+	client, err := ClientName(ctx)
+	if err != nil {
+		return err
+	}
+	for _, w := range a[client] {
+		if w == wallet {
+			return nil
+		}
+	}
+	return errors2.Errorf(errors2.Unauthorized, "client [%s] not authorized to access wallet [%s]", client, wallet)
+}
+
+// ClientName returns the common name of the verified TLS certificate of the client of the passed context
+func ClientName(ctx context.Context) (string, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", errors.New("no client information")
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return "", errors.New("client not authenticated with TLS")
+	}
+	if len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", errors.New("no verified client certificate")
+	}
+	return info.State.VerifiedChains[0][0].Subject.CommonName, nil
+}
@@ -0,0 +1,42 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package grpc
+
+import (
+	"encoding/json"
+
+	grpc2 "google.golang.org/grpc"
+)
+
+// CodecName is the name of the codec used by the token service.
+// Messages are JSON encoded, clients in any language select the codec with the content type application/grpc+json.
+const CodecName = "json"
+
+// codec encodes the messages of the token service. It is not registered globally, not to replace the codecs
+// other packages of the process rely on: the server forces it with ServerCodec, the client with each call.
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return CodecName
+}
+
+func (codec) String() string {
+	return CodecName
+}
+
+// ServerCodec returns the option making a gRPC server encode the messages of the token service.
+// It applies to all the services of the server.
+func ServerCodec() grpc2.ServerOption {
+	return grpc2.CustomCodec(codec{})
+}
@@ -0,0 +1,39 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package grpc
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+)
+
+// statusCodes maps the token error codes to the gRPC status codes returned to the clients
+var statusCodes = map[errors2.Code]codes.Code{
+	errors2.InsufficientFunds: codes.FailedPrecondition,
+	errors2.DoubleSpend:       codes.Aborted,
+	errors2.Conflict:          codes.Aborted,
+	errors2.InvalidProof:      codes.InvalidArgument,
+	errors2.Unauthorized:      codes.PermissionDenied,
+	errors2.AlreadyExists:     codes.AlreadyExists,
+}
+
+// toStatus converts the passed error into a gRPC status error whose code corresponds to the token error code
+// carried by the error, if any. The other errors are returned as they are, gRPC reports them as unknown.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code, ok := statusCodes[errors2.GetCode(err)]
+	if !ok {
+		return err
+	}
+	return status.Error(code, err.Error())
+}
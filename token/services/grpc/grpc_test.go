@@ -0,0 +1,200 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	grpc2 "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+type fakeServer struct {
+	TokenServiceServer
+	acl       ACL
+	transfers []*TransferRequest
+}
+
+func (f *fakeServer) Transfer(ctx context.Context, in *TransferRequest) (*TransactionResponse, error) {
+	if err := f.acl.Authorize(ctx, in.Wallet); err != nil {
+		return nil, err
+	}
+	f.transfers = append(f.transfers, in)
+	return &TransactionResponse{TxID: "tx1"}, nil
+}
+
+func (f *fakeServer) Balance(ctx context.Context, in *BalanceRequest) (*BalanceResponse, error) {
+	return nil, errors.Errorf("owner wallet [%s] not found", in.Wallet)
+}
+
+func TestTokenService(t *testing.T) {
+	ca, caKey := newCA(t)
+	serverCert := newCert(t, ca, caKey, "server")
+	clientCert := newCert(t, ca, caKey, "client")
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	srv := &fakeServer{acl: ACL{"client": {"alice"}}}
+	lis := bufconn.Listen(1024 * 1024)
+	s := NewGRPCServer(ServerTLSConfig(serverCert, pool), srv)
+	go s.Serve(lis)
+	defer s.Stop()
+
+	dial := func(tlsConfig *tls.Config) (*TokenServiceClient, func()) {
+		tlsConfig.ServerName = "server"
+		cc, err := grpc2.Dial("bufnet",
+			grpc2.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) { return lis.Dial() }),
+			grpc2.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
+		)
+		assert.NoError(t, err)
+		return NewTokenServiceClient(cc), func() { cc.Close() }
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// a client with a certificate issued by the CA is served
+	client, closeFunc := dial(ClientTLSConfig(clientCert, pool))
+	defer closeFunc()
+	res, err := client.Transfer(ctx, &TransferRequest{
+		Wallet:    "alice",
+		TokenType: "USD",
+		Quantity:  10,
+		Recipient: "bob",
+		TokenIDs:  []*token.Id{{TxId: "tx0", Index: 1}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "tx1", res.TxID)
+	assert.Len(t, srv.transfers, 1)
+	assert.Equal(t, "alice", srv.transfers[0].Wallet)
+	assert.Equal(t, uint64(10), srv.transfers[0].Quantity)
+	assert.Equal(t, []*token.Id{{TxId: "tx0", Index: 1}}, srv.transfers[0].TokenIDs)
+
+	_, err = client.Balance(ctx, &BalanceRequest{Wallet: "charlie"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "owner wallet [charlie] not found")
+
+	// the client accesses only its wallets
+	_, err = client.Transfer(ctx, &TransferRequest{Wallet: "bob"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "client [client] not authorized to access wallet [bob]")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Len(t, srv.transfers, 1)
+
+	// a client without a certificate is rejected
+	anonymous, closeFunc2 := dial(&tls.Config{RootCAs: pool})
+	defer closeFunc2()
+	_, err = anonymous.Transfer(ctx, &TransferRequest{Wallet: "alice"}, grpc2.WaitForReady(false))
+	assert.Error(t, err)
+	assert.Len(t, srv.transfers, 1)
+}
+
+func TestServerAuthorization(t *testing.T) {
+	ca, caKey := newCA(t)
+	clientCert, err := x509.ParseCertificate(newCert(t, ca, caKey, "client").Certificate[0])
+	assert.NoError(t, err)
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{clientCert, ca}}}},
+	})
+
+	// without ACL no wallet can be accessed
+	s := NewServer(nil)
+	_, err = s.Balance(ctx, &BalanceRequest{Wallet: "alice"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no access control list set")
+
+	s.WithACL(ACL{"client": {"alice", ""}, "another": {"bob"}})
+	assert.NoError(t, s.authorize(ctx, "alice"))
+	assert.NoError(t, s.authorize(ctx, ""))
+	for _, wallet := range []string{"bob", "charlie"} {
+		_, err = s.Balance(ctx, &BalanceRequest{Wallet: wallet})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not authorized to access wallet")
+		_, err = s.Transfer(ctx, &TransferRequest{Wallet: wallet})
+		assert.Error(t, err)
+		_, err = s.History(ctx, &HistoryRequest{Wallet: wallet})
+		assert.Error(t, err)
+	}
+
+	// a client not authenticated with TLS is rejected
+	_, err = s.Balance(context.Background(), &BalanceRequest{Wallet: "alice"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no client information")
+
+	// a request already cancelled is rejected
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = s.Transfer(cancelled, &TransferRequest{Wallet: "alice"})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func newCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(raw)
+	assert.NoError(t, err)
+	return cert, key
+}
+
+func newCert(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, name string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	assert.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{raw}, PrivateKey: key}
+}
+
+func TestToStatus(t *testing.T) {
+	assert.NoError(t, toStatus(nil))
+
+	err := toStatus(errors.WithMessage(errors2.New(errors2.InsufficientFunds, "insufficient funds"), "token selection failed"))
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, "token selection failed: insufficient funds", status.Convert(err).Message())
+
+	assert.Equal(t, codes.Aborted, status.Code(toStatus(errors2.Errorf(errors2.DoubleSpend, "input is already spent"))))
+
+	// the errors without a code are returned as they are
+	plain := errors.New("wallet not found")
+	assert.Equal(t, plain, toStatus(plain))
+}
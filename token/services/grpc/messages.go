@@ -0,0 +1,91 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package grpc
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/history"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+type WalletRequest struct {
+	// Wallet is the id of the owner wallet, empty for the default wallet
+	Wallet string `json:"wallet,omitempty"`
+}
+
+type RecipientIdentityResponse struct {
+	Identity []byte `json:"identity"`
+}
+
+type IssueRequest struct {
+	// Wallet is the id of the issuer wallet, empty for the default wallet
+	Wallet    string `json:"wallet,omitempty"`
+	TokenType string `json:"token_type"`
+	Quantity  uint64 `json:"quantity"`
+	// Recipient is the endpoint label of the node receiving the tokens
+	Recipient string `json:"recipient"`
+	// Auditor is the label of the auditor identity, empty if the transaction is not audited
+	Auditor string `json:"auditor,omitempty"`
+}
+
+type TransferRequest struct {
+	// Wallet is the id of the owner wallet, empty for the default wallet
+	Wallet    string `json:"wallet,omitempty"`
+	TokenType string `json:"token_type"`
+	Quantity  uint64 `json:"quantity"`
+	// Recipient is the endpoint label of the node receiving the tokens
+	Recipient string `json:"recipient"`
+	// TokenIDs are the tokens to spend, if empty they are selected from the wallet
+	TokenIDs []*token.Id `json:"token_ids,omitempty"`
+	// Auditor is the label of the auditor identity, empty if the transaction is not audited
+	Auditor string `json:"auditor,omitempty"`
+	// Memo, if set, is attached to the records of the transaction in the history of the wallet
+	Memo string `json:"memo,omitempty"`
+}
+
+type RedeemRequest struct {
+	// Wallet is the id of the owner wallet, empty for the default wallet
+	Wallet    string `json:"wallet,omitempty"`
+	TokenType string `json:"token_type"`
+	Quantity  uint64 `json:"quantity"`
+	// TokenIDs are the tokens to spend, if empty they are selected from the wallet
+	TokenIDs []*token.Id `json:"token_ids,omitempty"`
+	// Auditor is the label of the auditor identity, empty if the transaction is not audited
+	Auditor string `json:"auditor,omitempty"`
+	// Memo, if set, is attached to the records of the transaction in the history of the wallet
+	Memo string `json:"memo,omitempty"`
+}
+
+type TransactionResponse struct {
+	TxID string `json:"tx_id"`
+}
+
+type BalanceRequest struct {
+	// Wallet is the id of the owner wallet, empty for the default wallet
+	Wallet    string `json:"wallet,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+}
+
+type BalanceResponse struct {
+	// Quantity is the sum of the unspent tokens, in decimal representation
+	Quantity string `json:"quantity"`
+}
+
+type UnspentTokensResponse struct {
+	Tokens []*token.UnspentToken `json:"tokens"`
+}
+
+type HistoryRequest struct {
+	// Wallet is the id of the issuer or owner wallet, empty for the default wallet
+	Wallet    string `json:"wallet,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+}
+
+type HistoryResponse struct {
+	// Tokens are the tokens issued by the wallet, if it is an issuer wallet
+	Tokens []*token.IssuedToken `json:"tokens"`
+	// Records are the tokens received and spent by the wallet, if it is an owner wallet and the history is enabled
+	Records []*history.Record `json:"records,omitempty"`
+}
@@ -0,0 +1,274 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package grpc
+
+import (
+	"context"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/history"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxcc"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.grpc")
+
+// Server implements TokenServiceServer on top of the token management service selected by the passed options.
+// The clients access only the wallets granted to them by the ACL, see WithACL.
+type Server struct {
+	sp   view2.ServiceProvider
+	opts []token.ServiceOption
+	acl  ACL
+}
+
+func NewServer(sp view2.ServiceProvider, opts ...token.ServiceOption) *Server {
+	return &Server{sp: sp, opts: opts}
+}
+
+// WithACL sets the wallets each client can access, without it no wallet can be accessed
+func (s *Server) WithACL(acl ACL) *Server {
+	s.acl = acl
+	return s
+}
+
+func (s *Server) RecipientIdentity(ctx context.Context, in *WalletRequest) (*RecipientIdentityResponse, error) {
+	if err := s.authorize(ctx, in.Wallet); err != nil {
+		return nil, err
+	}
+	w, err := s.ownerWallet(in.Wallet)
+	if err != nil {
+		return nil, err
+	}
+	id, err := w.GetRecipientIdentity()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting recipient identity from wallet [%s]", w.ID())
+	}
+	return &RecipientIdentityResponse{Identity: id}, nil
+}
+
+func (s *Server) Issue(ctx context.Context, in *IssueRequest) (*TransactionResponse, error) {
+	if err := s.authorize(ctx, in.Wallet); err != nil {
+		return nil, err
+	}
+	if in.Quantity == 0 {
+		return nil, errors.New("invalid quantity, it must be greater than zero")
+	}
+	w := s.tms().WalletManager().IssuerWallet(in.Wallet)
+	if w == nil {
+		return nil, errors.Errorf("issuer wallet [%s] not found", in.Wallet)
+	}
+	recipient, err := s.resolve(in.Recipient)
+	if err != nil {
+		return nil, err
+	}
+	return s.initiate(ctx, &issueView{
+		wallet:    w,
+		tokenType: in.TokenType,
+		quantity:  in.Quantity,
+		recipient: recipient,
+		txOpts:    s.txOptions(in.Auditor, ""),
+	})
+}
+
+func (s *Server) Transfer(ctx context.Context, in *TransferRequest) (*TransactionResponse, error) {
+	if err := s.authorize(ctx, in.Wallet); err != nil {
+		return nil, err
+	}
+	if in.Quantity == 0 {
+		return nil, errors.New("invalid quantity, it must be greater than zero")
+	}
+	w, err := s.ownerWallet(in.Wallet)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := s.resolve(in.Recipient)
+	if err != nil {
+		return nil, err
+	}
+	return s.initiate(ctx, &transferView{
+		wallet:    w,
+		tokenType: in.TokenType,
+		quantity:  in.Quantity,
+		recipient: recipient,
+		tokenIDs:  in.TokenIDs,
+		txOpts:    s.txOptions(in.Auditor, in.Memo),
+	})
+}
+
+func (s *Server) Redeem(ctx context.Context, in *RedeemRequest) (*TransactionResponse, error) {
+	if err := s.authorize(ctx, in.Wallet); err != nil {
+		return nil, err
+	}
+	if in.Quantity == 0 {
+		return nil, errors.New("invalid quantity, it must be greater than zero")
+	}
+	w, err := s.ownerWallet(in.Wallet)
+	if err != nil {
+		return nil, err
+	}
+	return s.initiate(ctx, &transferView{
+		wallet:    w,
+		tokenType: in.TokenType,
+		quantity:  in.Quantity,
+		tokenIDs:  in.TokenIDs,
+		txOpts:    s.txOptions(in.Auditor, in.Memo),
+	})
+}
+
+func (s *Server) Balance(ctx context.Context, in *BalanceRequest) (*BalanceResponse, error) {
+	if err := s.authorize(ctx, in.Wallet); err != nil {
+		return nil, err
+	}
+	unspent, err := s.unspentTokens(in)
+	if err != nil {
+		return nil, err
+	}
+	return &BalanceResponse{Quantity: unspent.Sum(64).Decimal()}, nil
+}
+
+func (s *Server) UnspentTokens(ctx context.Context, in *BalanceRequest) (*UnspentTokensResponse, error) {
+	if err := s.authorize(ctx, in.Wallet); err != nil {
+		return nil, err
+	}
+	unspent, err := s.unspentTokens(in)
+	if err != nil {
+		return nil, err
+	}
+	return &UnspentTokensResponse{Tokens: unspent.Tokens}, nil
+}
+
+func (s *Server) History(ctx context.Context, in *HistoryRequest) (*HistoryResponse, error) {
+	if err := s.authorize(ctx, in.Wallet); err != nil {
+		return nil, err
+	}
+	var opts []token.ListTokensOption
+	if len(in.TokenType) != 0 {
+		opts = append(opts, ttxcc.WithType(in.TokenType))
+	}
+	res := &HistoryResponse{}
+	found := false
+	if w := s.tms().WalletManager().IssuerWallet(in.Wallet); w != nil {
+		found = true
+		issued, err := w.HistoryTokens(opts...)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed listing issued tokens of wallet [%s]", w.ID())
+		}
+		res.Tokens = issued.Tokens
+	}
+	if w := s.tms().WalletManager().OwnerWallet(in.Wallet); w != nil {
+		found = true
+		if h := history.GetService(s.sp); h != nil {
+			records, err := h.ByWallet(w.ID())
+			if err != nil {
+				return nil, errors.WithMessagef(err, "failed listing history of wallet [%s]", w.ID())
+			}
+			for _, record := range records {
+				if len(in.TokenType) == 0 || record.Type == in.TokenType {
+					res.Records = append(res.Records, record)
+				}
+			}
+		}
+	}
+	if !found {
+		return nil, errors.Errorf("wallet [%s] not found", in.Wallet)
+	}
+	return res, nil
+}
+
+// authorize checks that the request has not been cancelled and that its client can access the passed wallet
+func (s *Server) authorize(ctx context.Context, wallet string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.acl == nil {
+		return errors.New("no access control list set, the wallets cannot be accessed")
+	}
+	return s.acl.Authorize(ctx, wallet)
+}
+
+func (s *Server) tms() *token.ManagementService {
+	return token.GetManagementService(s.sp, s.opts...)
+}
+
+func (s *Server) ownerWallet(id string) (*token.OwnerWallet, error) {
+	w := s.tms().WalletManager().OwnerWallet(id)
+	if w == nil {
+		return nil, errors.Errorf("owner wallet [%s] not found", id)
+	}
+	return w, nil
+}
+
+func (s *Server) unspentTokens(in *BalanceRequest) (*token2.UnspentTokens, error) {
+	w, err := s.ownerWallet(in.Wallet)
+	if err != nil {
+		return nil, err
+	}
+	var opts []token.ListTokensOption
+	if len(in.TokenType) != 0 {
+		opts = append(opts, ttxcc.WithType(in.TokenType))
+	}
+	unspent, err := w.ListTokens(opts...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed listing tokens of wallet [%s]", w.ID())
+	}
+	return unspent, nil
+}
+
+func (s *Server) resolve(label string) (view.Identity, error) {
+	if len(label) == 0 {
+		return nil, errors.New("recipient not specified")
+	}
+	ids, err := view2.GetEndpointService(s.sp).ResolveIdentities(label)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed resolving recipient [%s]", label)
+	}
+	return ids[0], nil
+}
+
+func (s *Server) txOptions(auditor string, memo string) []ttxcc.TxOption {
+	tms := s.tms()
+	opts := []ttxcc.TxOption{
+		ttxcc.WithNetwork(tms.Network()),
+		ttxcc.WithChannel(tms.Channel()),
+		ttxcc.WithNamespace(tms.Namespace()),
+	}
+	if len(auditor) != 0 {
+		opts = append(opts, ttxcc.WithAuditor(view2.GetIdentityProvider(s.sp).Identity(auditor)))
+	}
+	if len(memo) != 0 {
+		opts = append(opts, ttxcc.WithMemo(memo))
+	}
+	return opts
+}
+
+// initiate runs the passed view, until the passed context is done. A transaction not committed by then
+// may still be committed afterwards, the client finds it in the history.
+func (s *Server) initiate(ctx context.Context, v view.View) (*TransactionResponse, error) {
+	type result struct {
+		txID interface{}
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		txID, err := view2.GetManager(s.sp).InitiateView(v)
+		done <- result{txID: txID, err: err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return nil, r.err
+		}
+		logger.Debugf("transaction [%s] committed", r.txID)
+		return &TransactionResponse{TxID: r.txID.(string)}, nil
+	case <-ctx.Done():
+		return nil, errors.WithMessage(ctx.Err(), "request done before the transaction has been committed")
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package grpc
+
+import (
+	"context"
+
+	grpc2 "google.golang.org/grpc"
+)
+
+const ServiceName = "token.TokenService"
+
+// TokenServiceServer is the server API of the token service
+type TokenServiceServer interface {
+	RecipientIdentity(context.Context, *WalletRequest) (*RecipientIdentityResponse, error)
+	Issue(context.Context, *IssueRequest) (*TransactionResponse, error)
+	Transfer(context.Context, *TransferRequest) (*TransactionResponse, error)
+	Redeem(context.Context, *RedeemRequest) (*TransactionResponse, error)
+	Balance(context.Context, *BalanceRequest) (*BalanceResponse, error)
+	UnspentTokens(context.Context, *BalanceRequest) (*UnspentTokensResponse, error)
+	History(context.Context, *HistoryRequest) (*HistoryResponse, error)
+}
+
+// RegisterTokenServiceServer registers the passed token service on the passed gRPC server
+func RegisterTokenServiceServer(s *grpc2.Server, srv TokenServiceServer) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+var serviceDesc = grpc2.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*TokenServiceServer)(nil),
+	Methods: []grpc2.MethodDesc{
+		{
+			MethodName: "RecipientIdentity",
+			Handler: handler("RecipientIdentity", func() interface{} { return &WalletRequest{} }, func(srv TokenServiceServer, ctx context.Context, in interface{}) (interface{}, error) {
+				return srv.RecipientIdentity(ctx, in.(*WalletRequest))
+			}),
+		},
+		{
+			MethodName: "Issue",
+			Handler: handler("Issue", func() interface{} { return &IssueRequest{} }, func(srv TokenServiceServer, ctx context.Context, in interface{}) (interface{}, error) {
+				return srv.Issue(ctx, in.(*IssueRequest))
+			}),
+		},
+		{
+			MethodName: "Transfer",
+			Handler: handler("Transfer", func() interface{} { return &TransferRequest{} }, func(srv TokenServiceServer, ctx context.Context, in interface{}) (interface{}, error) {
+				return srv.Transfer(ctx, in.(*TransferRequest))
+			}),
+		},
+		{
+			MethodName: "Redeem",
+			Handler: handler("Redeem", func() interface{} { return &RedeemRequest{} }, func(srv TokenServiceServer, ctx context.Context, in interface{}) (interface{}, error) {
+				return srv.Redeem(ctx, in.(*RedeemRequest))
+			}),
+		},
+		{
+			MethodName: "Balance",
+			Handler: handler("Balance", func() interface{} { return &BalanceRequest{} }, func(srv TokenServiceServer, ctx context.Context, in interface{}) (interface{}, error) {
+				return srv.Balance(ctx, in.(*BalanceRequest))
+			}),
+		},
+		{
+			MethodName: "UnspentTokens",
+			Handler: handler("UnspentTokens", func() interface{} { return &BalanceRequest{} }, func(srv TokenServiceServer, ctx context.Context, in interface{}) (interface{}, error) {
+				return srv.UnspentTokens(ctx, in.(*BalanceRequest))
+			}),
+		},
+		{
+			MethodName: "History",
+			Handler: handler("History", func() interface{} { return &HistoryRequest{} }, func(srv TokenServiceServer, ctx context.Context, in interface{}) (interface{}, error) {
+				return srv.History(ctx, in.(*HistoryRequest))
+			}),
+		},
+	},
+	Streams:  []grpc2.StreamDesc{},
+	Metadata: "token/services/grpc/service.go",
+}
+
+type call func(srv TokenServiceServer, ctx context.Context, in interface{}) (interface{}, error)
+
+// handler returns the gRPC handler of the passed method, in the same shape of the generated ones.
+// The errors carrying a token error code are returned with the corresponding status code, see toStatus.
+func handler(method string, newRequest func() interface{}, c call) func(interface{}, context.Context, func(interface{}) error, grpc2.UnaryServerInterceptor) (interface{}, error) {
+	c = withStatus(c)
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc2.UnaryServerInterceptor) (interface{}, error) {
+		in := newRequest()
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return c(srv.(TokenServiceServer), ctx, in)
+		}
+		info := &grpc2.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: "/" + ServiceName + "/" + method,
+		}
+		return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return c(srv.(TokenServiceServer), ctx, req)
+		})
+	}
+}
+
+func withStatus(c call) call {
+	return func(srv TokenServiceServer, ctx context.Context, in interface{}) (interface{}, error) {
+		res, err := c(srv, ctx, in)
+		return res, toStatus(err)
+	}
+}
+
+// TokenServiceClient is the client API of the token service
+type TokenServiceClient struct {
+	cc *grpc2.ClientConn
+}
+
+func NewTokenServiceClient(cc *grpc2.ClientConn) *TokenServiceClient {
+	return &TokenServiceClient{cc: cc}
+}
+
+func (c *TokenServiceClient) RecipientIdentity(ctx context.Context, in *WalletRequest, opts ...grpc2.CallOption) (*RecipientIdentityResponse, error) {
+	out := &RecipientIdentityResponse{}
+	if err := c.invoke(ctx, "RecipientIdentity", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TokenServiceClient) Issue(ctx context.Context, in *IssueRequest, opts ...grpc2.CallOption) (*TransactionResponse, error) {
+	out := &TransactionResponse{}
+	if err := c.invoke(ctx, "Issue", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TokenServiceClient) Transfer(ctx context.Context, in *TransferRequest, opts ...grpc2.CallOption) (*TransactionResponse, error) {
+	out := &TransactionResponse{}
+	if err := c.invoke(ctx, "Transfer", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TokenServiceClient) Redeem(ctx context.Context, in *RedeemRequest, opts ...grpc2.CallOption) (*TransactionResponse, error) {
+	out := &TransactionResponse{}
+	if err := c.invoke(ctx, "Redeem", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TokenServiceClient) Balance(ctx context.Context, in *BalanceRequest, opts ...grpc2.CallOption) (*BalanceResponse, error) {
+	out := &BalanceResponse{}
+	if err := c.invoke(ctx, "Balance", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TokenServiceClient) UnspentTokens(ctx context.Context, in *BalanceRequest, opts ...grpc2.CallOption) (*UnspentTokensResponse, error) {
+	out := &UnspentTokensResponse{}
+	if err := c.invoke(ctx, "UnspentTokens", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TokenServiceClient) History(ctx context.Context, in *HistoryRequest, opts ...grpc2.CallOption) (*HistoryResponse, error) {
+	out := &HistoryResponse{}
+	if err := c.invoke(ctx, "History", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *TokenServiceClient) invoke(ctx context.Context, method string, in, out interface{}, opts ...grpc2.CallOption) error {
+	opts = append([]grpc2.CallOption{grpc2.ForceCodec(codec{})}, opts...)
+	return c.cc.Invoke(ctx, "/"+ServiceName+"/"+method, in, out, opts...)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package grpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+
+	"github.com/pkg/errors"
+	grpc2 "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Config is the configuration of the gRPC endpoint of the token service
+type Config struct {
+	ListenAddress string
+	// CertFile and KeyFile are the PEM encoded TLS certificate and key of the server
+	CertFile string
+	KeyFile  string
+	// ClientCAFiles are the PEM encoded CA certificates used to verify the client certificates
+	ClientCAFiles []string
+	// ACL lists the wallets each client can access, set it on the served Server with WithACL
+	ACL ACL
+}
+
+// ServerTLSConfig returns a TLS configuration that requires and verifies the client certificates
+func ServerTLSConfig(cert tls.Certificate, clientCAs *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// ClientTLSConfig returns a TLS configuration that authenticates the client with the passed certificate
+func ClientTLSConfig(cert tls.Certificate, serverCAs *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      serverCAs,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// NewGRPCServer returns a gRPC server, with mutual TLS, serving the passed token service
+func NewGRPCServer(tlsConfig *tls.Config, srv TokenServiceServer, opts ...grpc2.ServerOption) *grpc2.Server {
+	opts = append([]grpc2.ServerOption{grpc2.Creds(credentials.NewTLS(tlsConfig)), ServerCodec()}, opts...)
+	s := grpc2.NewServer(opts...)
+	RegisterTokenServiceServer(s, srv)
+	return s
+}
+
+// Serve starts serving the passed token service as described by the passed configuration.
+// It returns the gRPC server and a channel where the outcome of serving is delivered.
+func Serve(config *Config, srv TokenServiceServer) (*grpc2.Server, <-chan error, error) {
+	tlsConfig, err := config.TLSConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	lis, err := net.Listen("tcp", config.ListenAddress)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed listening on [%s]", config.ListenAddress)
+	}
+	s := NewGRPCServer(tlsConfig, srv)
+	done := make(chan error, 1)
+	go func() {
+		logger.Infof("token service listening on [%s]", lis.Addr())
+		done <- s.Serve(lis)
+	}()
+	return s, done, nil
+}
+
+// TLSConfig loads the server TLS configuration from the files referenced by this configuration
+func (c *Config) TLSConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed loading key pair [%s,%s]", c.CertFile, c.KeyFile)
+	}
+	if len(c.ClientCAFiles) == 0 {
+		return nil, errors.New("no client CA specified, mutual TLS requires at least one")
+	}
+	clientCAs := x509.NewCertPool()
+	for _, file := range c.ClientCAFiles {
+		raw, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed reading client CA [%s]", file)
+		}
+		if !clientCAs.AppendCertsFromPEM(raw) {
+			return nil, errors.Errorf("no certificate found in client CA [%s]", file)
+		}
+	}
+	return ServerTLSConfig(cert, clientCAs), nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package grpc
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxcc"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// issueView issues tokens to a recipient, and waits for the transaction to be committed
+type issueView struct {
+	wallet    *token.IssuerWallet
+	tokenType string
+	quantity  uint64
+	recipient view.Identity
+	txOpts    []ttxcc.TxOption
+}
+
+func (v *issueView) Call(context view.Context) (interface{}, error) {
+	recipient, err := ttxcc.RequestRecipientIdentity(context, v.recipient)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting recipient identity")
+	}
+	issuer, err := v.wallet.GetIssuerIdentity(v.tokenType)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting issuer identity for token type [%s]", v.tokenType)
+	}
+	tx, err := ttxcc.NewTransaction(context, issuer, v.txOpts...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed creating issue transaction")
+	}
+	if err := tx.Issue(v.wallet, recipient, v.tokenType, v.quantity); err != nil {
+		return nil, errors.WithMessage(err, "failed adding new issued token")
+	}
+	return commit(context, tx)
+}
+
+// transferView transfers tokens to a recipient, or redeems them if there is no recipient,
+// and waits for the transaction to be committed
+type transferView struct {
+	wallet    *token.OwnerWallet
+	tokenType string
+	quantity  uint64
+	recipient view.Identity
+	tokenIDs  []*token2.Id
+	txOpts    []ttxcc.TxOption
+}
+
+func (v *transferView) Call(context view.Context) (interface{}, error) {
+	tx, err := ttxcc.NewAnonymousTransaction(context, v.txOpts...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed creating transaction")
+	}
+	if v.recipient == nil {
+		if err := tx.Redeem(v.wallet, v.tokenType, v.quantity, token.WithTokenIDs(v.tokenIDs...)); err != nil {
+			return nil, errors.WithMessage(err, "failed redeeming tokens")
+		}
+		return commit(context, tx)
+	}
+
+	recipient, err := ttxcc.RequestRecipientIdentity(context, v.recipient)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting recipient identity")
+	}
+	if err := tx.Transfer(v.wallet, v.tokenType, []uint64{v.quantity}, []view.Identity{recipient}, token.WithTokenIDs(v.tokenIDs...)); err != nil {
+		return nil, errors.WithMessage(err, "failed transferring tokens")
+	}
+	return commit(context, tx)
+}
+
+func commit(context view.Context, tx *ttxcc.Transaction) (interface{}, error) {
+	if _, err := context.RunView(ttxcc.NewCollectEndorsementsView(tx)); err != nil {
+		return nil, errors.WithMessagef(err, "failed collecting endorsements for [%s]", tx.ID())
+	}
+	if _, err := context.RunView(ttxcc.NewOrderingView(tx)); err != nil {
+		return nil, errors.WithMessagef(err, "failed ordering [%s]", tx.ID())
+	}
+	return tx.ID(), nil
+}
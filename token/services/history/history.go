@@ -0,0 +1,268 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package history
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/pkg/errors"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.history")
+
+const historyPrefix = "token-sdk.history"
+
+type Direction string
+
+const (
+	// Received marks a token entering a local wallet
+	Received Direction = "received"
+	// Spent marks a token leaving a local wallet
+	Spent Direction = "spent"
+)
+
+// Record is an entry of the history of a token owned by a local wallet
+type Record struct {
+	TokenID   *token2.Id
+	Direction Direction
+	// TxID is the transaction that created the token, if received, or that spent it
+	TxID     string
+	Wallet   string
+	Type     string
+	Quantity string
+	// Counterparties are the enrollment IDs of the other parties of the transaction, where disclosed:
+	// the senders for a received token, the recipients for a spent token
+	Counterparties []string
+	Timestamp      time.Time
+	Memo           string
+}
+
+// Store persists the history
+type Store interface {
+	Exists(id string) bool
+	Put(id string, state interface{}) error
+	Get(id string, state interface{}) error
+}
+
+// recordRef points to a record of a token
+type recordRef struct {
+	TokenID   *token2.Id
+	Direction Direction
+}
+
+// Service records the tokens entering and leaving the local wallets, and answers history queries
+// per token and per counterparty, for statements and reconciliation.
+type Service struct {
+	store Store
+	// lock serializes the updates of the indices
+	lock sync.Mutex
+}
+
+func NewService(store Store) *Service {
+	return &Service{store: store}
+}
+
+// GetService returns the history service registered in the passed service provider, nil if the history is not enabled
+func GetService(sp view2.ServiceProvider) *Service {
+	s, err := sp.GetService(&Service{})
+	if err != nil {
+		return nil
+	}
+	return s.(*Service)
+}
+
+// SetMemo sets the memo of the passed transaction, it is attached to the records of the transaction appended afterwards
+func (s *Service) SetMemo(txID string, memo string) error {
+	k, err := kvs.CreateCompositeKey(historyPrefix, []string{"memo", txID})
+	if err != nil {
+		return err
+	}
+	return s.store.Put(k, memo)
+}
+
+// Append stores the passed records. A record of a token already recorded with the same direction replaces it.
+func (s *Service) Append(records ...*Record) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, record := range records {
+		if record.TokenID == nil {
+			return errors.New("token id not specified")
+		}
+		if len(record.Memo) == 0 {
+			memo, err := s.memo(record.TxID)
+			if err != nil {
+				return err
+			}
+			record.Memo = memo
+		}
+
+		tokenRecords, err := s.ByToken(record.TokenID)
+		if err != nil {
+			return err
+		}
+		replaced := false
+		for i, r := range tokenRecords {
+			if r.Direction == record.Direction {
+				tokenRecords[i] = record
+				replaced = true
+			}
+		}
+		if !replaced {
+			tokenRecords = append(tokenRecords, record)
+		}
+		k, err := s.tokenKey(record.TokenID)
+		if err != nil {
+			return err
+		}
+		if err := s.store.Put(k, tokenRecords); err != nil {
+			return errors.WithMessagef(err, "failed storing history of [%s]", record.TokenID)
+		}
+
+		ref := &recordRef{TokenID: record.TokenID, Direction: record.Direction}
+		for _, eID := range record.Counterparties {
+			k, err := s.counterpartyKey(eID)
+			if err != nil {
+				return err
+			}
+			if err := s.addRef(k, ref); err != nil {
+				return err
+			}
+		}
+		if len(record.Wallet) != 0 {
+			k, err := s.walletKey(record.Wallet)
+			if err != nil {
+				return err
+			}
+			if err := s.addRef(k, ref); err != nil {
+				return err
+			}
+		}
+		logger.Debugf("token [%s] %s in [%s]", record.TokenID, record.Direction, record.TxID)
+	}
+	return nil
+}
+
+// ByToken returns the records of the passed token, at most one per direction
+func (s *Service) ByToken(id *token2.Id) ([]*Record, error) {
+	k, err := s.tokenKey(id)
+	if err != nil {
+		return nil, err
+	}
+	if !s.store.Exists(k) {
+		return nil, nil
+	}
+	var records []*Record
+	if err := s.store.Get(k, &records); err != nil {
+		return nil, errors.WithMessagef(err, "failed loading history of [%s]", id)
+	}
+	return records, nil
+}
+
+// ByCounterparty returns the records involving the party with the passed enrollment ID, sorted by timestamp
+func (s *Service) ByCounterparty(eID string) ([]*Record, error) {
+	k, err := s.counterpartyKey(eID)
+	if err != nil {
+		return nil, err
+	}
+	return s.byRefs(k, func(*Record) bool { return true })
+}
+
+// ByWallet returns the records of the tokens received and spent by the local wallet with the passed id,
+// sorted by timestamp
+func (s *Service) ByWallet(wallet string) ([]*Record, error) {
+	k, err := s.walletKey(wallet)
+	if err != nil {
+		return nil, err
+	}
+	return s.byRefs(k, func(r *Record) bool { return r.Wallet == wallet })
+}
+
+// byRefs returns the records referenced by the index stored under the passed key and selected by the passed filter,
+// sorted by timestamp
+func (s *Service) byRefs(k string, filter func(*Record) bool) ([]*Record, error) {
+	refs, err := s.refs(k)
+	if err != nil {
+		return nil, err
+	}
+	var res []*Record
+	for _, ref := range refs {
+		records, err := s.ByToken(ref.TokenID)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			if record.Direction == ref.Direction && filter(record) {
+				res = append(res, record)
+			}
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Timestamp.Before(res[j].Timestamp) })
+	return res, nil
+}
+
+// addRef adds the passed reference to the index stored under the passed key
+func (s *Service) addRef(k string, ref *recordRef) error {
+	refs, err := s.refs(k)
+	if err != nil {
+		return err
+	}
+	for _, r := range refs {
+		if r.Direction == ref.Direction && r.TokenID.TxId == ref.TokenID.TxId && r.TokenID.Index == ref.TokenID.Index {
+			return nil
+		}
+	}
+	if err := s.store.Put(k, append(refs, ref)); err != nil {
+		return errors.WithMessagef(err, "failed indexing history of [%s]", ref.TokenID)
+	}
+	return nil
+}
+
+func (s *Service) refs(k string) ([]*recordRef, error) {
+	if !s.store.Exists(k) {
+		return nil, nil
+	}
+	var refs []*recordRef
+	if err := s.store.Get(k, &refs); err != nil {
+		return nil, errors.WithMessagef(err, "failed loading history index [%s]", k)
+	}
+	return refs, nil
+}
+
+func (s *Service) memo(txID string) (string, error) {
+	k, err := kvs.CreateCompositeKey(historyPrefix, []string{"memo", txID})
+	if err != nil {
+		return "", err
+	}
+	if !s.store.Exists(k) {
+		return "", nil
+	}
+	var memo string
+	if err := s.store.Get(k, &memo); err != nil {
+		return "", errors.WithMessagef(err, "failed loading memo of [%s]", txID)
+	}
+	return memo, nil
+}
+
+func (s *Service) tokenKey(id *token2.Id) (string, error) {
+	return kvs.CreateCompositeKey(historyPrefix, []string{"token", id.TxId, strconv.FormatUint(uint64(id.Index), 10)})
+}
+
+func (s *Service) counterpartyKey(eID string) (string, error) {
+	return kvs.CreateCompositeKey(historyPrefix, []string{"counterparty", eID})
+}
+
+func (s *Service) walletKey(wallet string) (string, error) {
+	return kvs.CreateCompositeKey(historyPrefix, []string{"wallet", wallet})
+}
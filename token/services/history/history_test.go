@@ -0,0 +1,94 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package history
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+type memoryStore map[string][]byte
+
+func (m memoryStore) Exists(id string) bool {
+	_, ok := m[id]
+	return ok
+}
+
+func (m memoryStore) Put(id string, state interface{}) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	m[id] = raw
+	return nil
+}
+
+func (m memoryStore) Get(id string, state interface{}) error {
+	raw, ok := m[id]
+	if !ok {
+		return errors.Errorf("[%s] not found", id)
+	}
+	return json.Unmarshal(raw, state)
+}
+
+func TestHistory(t *testing.T) {
+	s := NewService(memoryStore{})
+	now := time.Now()
+	id1 := &token2.Id{TxId: "tx1", Index: 0}
+	id2 := &token2.Id{TxId: "tx1", Index: 1}
+
+	assert.NoError(t, s.SetMemo("tx1", "invoice 42"))
+	assert.NoError(t, s.Append(
+		&Record{TokenID: id1, Direction: Received, TxID: "tx1", Wallet: "alice", Type: "EUR", Quantity: "0x10", Counterparties: []string{"bob"}, Timestamp: now},
+		&Record{TokenID: id2, Direction: Received, TxID: "tx1", Wallet: "alice", Type: "EUR", Quantity: "0x5", Counterparties: []string{"bob"}, Timestamp: now},
+	))
+	assert.NoError(t, s.Append(
+		&Record{TokenID: id1, Direction: Spent, TxID: "tx2", Wallet: "alice", Type: "EUR", Quantity: "0x10", Counterparties: []string{"charlie"}, Timestamp: now.Add(time.Minute)},
+	))
+	// processing the same transaction again does not duplicate the records
+	assert.NoError(t, s.Append(
+		&Record{TokenID: id1, Direction: Spent, TxID: "tx2", Wallet: "alice", Type: "EUR", Quantity: "0x10", Counterparties: []string{"charlie"}, Timestamp: now.Add(time.Minute)},
+	))
+
+	records, err := s.ByToken(id1)
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, Received, records[0].Direction)
+	assert.Equal(t, "invoice 42", records[0].Memo)
+	assert.Equal(t, Spent, records[1].Direction)
+	assert.Equal(t, "tx2", records[1].TxID)
+	assert.Empty(t, records[1].Memo)
+
+	records, err = s.ByCounterparty("bob")
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	records, err = s.ByCounterparty("charlie")
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, id1, records[0].TokenID)
+	records, err = s.ByCounterparty("dave")
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	records, err = s.ByWallet("alice")
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, Spent, records[2].Direction)
+	records, err = s.ByWallet("bob")
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	records, err = s.ByToken(&token2.Id{TxId: "tx3"})
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+	assert.Error(t, s.Append(&Record{TxID: "tx3"}))
+}
@@ -0,0 +1,234 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package common
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	"github.com/pkg/errors"
+)
+
+// GetStateFnc returns the committed value of the passed key in the passed namespace
+type GetStateFnc = func(namespace, key string) ([]byte, error)
+
+// Read is a key read from the backend
+type Read struct {
+	Namespace string
+	Key       string
+	Value     []byte
+}
+
+// Write is a key written, or deleted, by a transaction
+type Write struct {
+	Namespace string
+	Key       string
+	Value     []byte
+	Delete    bool
+	Metadata  map[string][]byte `json:",omitempty"`
+}
+
+// RWSet is a read-write set kept in memory, for backends that do not provide their own.
+// Reads are served by the backend, writes are buffered until the transaction is committed.
+type RWSet struct {
+	TxID   string
+	Reads  []*Read
+	Writes []*Write
+
+	getState GetStateFnc
+	done     bool
+}
+
+func NewRWSet(txID string, getState GetStateFnc) *RWSet {
+	return &RWSet{TxID: txID, getState: getState}
+}
+
+func (r *RWSet) SetState(namespace string, key string, value []byte) error {
+	if r.done {
+		return errors.Errorf("rwset [%s] already closed", r.TxID)
+	}
+	w := r.write(namespace, key)
+	w.Value = value
+	w.Delete = false
+	return nil
+}
+
+func (r *RWSet) GetState(namespace string, key string, opts ...fabric.GetStateOpt) ([]byte, error) {
+	for _, read := range r.Reads {
+		if read.Namespace == namespace && read.Key == key {
+			return read.Value, nil
+		}
+	}
+	if r.getState == nil {
+		return nil, errors.Errorf("rwset [%s] cannot read from the backend", r.TxID)
+	}
+	value, err := r.getState(namespace, key)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed reading [%s:%s]", namespace, key)
+	}
+	r.Reads = append(r.Reads, &Read{Namespace: namespace, Key: key, Value: value})
+	return value, nil
+}
+
+func (r *RWSet) DeleteState(namespace string, key string) error {
+	if r.done {
+		return errors.Errorf("rwset [%s] already closed", r.TxID)
+	}
+	w := r.write(namespace, key)
+	w.Value = nil
+	w.Delete = true
+	return nil
+}
+
+func (r *RWSet) Bytes() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *RWSet) Done() {
+	r.done = true
+}
+
+func (r *RWSet) GetStateMetadata(namespace, key string, opts ...fabric.GetStateOpt) (map[string][]byte, error) {
+	for _, w := range r.Writes {
+		if w.Namespace == namespace && w.Key == key {
+			return w.Metadata, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *RWSet) SetStateMetadata(namespace, key string, metadata map[string][]byte) error {
+	if r.done {
+		return errors.Errorf("rwset [%s] already closed", r.TxID)
+	}
+	r.write(namespace, key).Metadata = metadata
+	return nil
+}
+
+// RWSetFromBlob returns the read-write set carried by the passed blob,
+// either a *RWSet or its serialization, checking that it is bound to the passed transaction id
+func RWSetFromBlob(txID string, blob interface{}) (*RWSet, error) {
+	var rws *RWSet
+	switch b := blob.(type) {
+	case *RWSet:
+		rws = b
+	case []byte:
+		rws = &RWSet{}
+		if err := json.Unmarshal(b, rws); err != nil {
+			return nil, errors.Wrapf(err, "failed unmarshalling rwset of [%s]", txID)
+		}
+	default:
+		return nil, errors.Errorf("invalid blob type [%T], expected rwset", blob)
+	}
+	if len(rws.TxID) != 0 && rws.TxID != txID {
+		return nil, errors.Errorf("rwset bound to [%s], expected [%s]", rws.TxID, txID)
+	}
+	return rws, nil
+}
+
+// AppendRWSet appends the reads and writes of the passed serialized RWSet, limited to the passed namespaces, if any
+func (r *RWSet) AppendRWSet(raw []byte, nss ...string) error {
+	other := &RWSet{}
+	if err := json.Unmarshal(raw, other); err != nil {
+		return errors.Wrap(err, "failed unmarshalling rwset")
+	}
+	filter := func(ns string) bool {
+		if len(nss) == 0 {
+			return true
+		}
+		for _, n := range nss {
+			if n == ns {
+				return true
+			}
+		}
+		return false
+	}
+	for _, read := range other.Reads {
+		if filter(read.Namespace) {
+			r.Reads = append(r.Reads, read)
+		}
+	}
+	for _, write := range other.Writes {
+		if filter(write.Namespace) {
+			w := r.write(write.Namespace, write.Key)
+			*w = *write
+		}
+	}
+	return nil
+}
+
+func (r *RWSet) GetReadAt(ns string, i int) (string, []byte, error) {
+	reads := r.reads(ns)
+	if i < 0 || i >= len(reads) {
+		return "", nil, errors.Errorf("read index [%d] out of range in namespace [%s]", i, ns)
+	}
+	return reads[i].Key, reads[i].Value, nil
+}
+
+func (r *RWSet) GetWriteAt(ns string, i int) (string, []byte, error) {
+	writes := r.writes(ns)
+	if i < 0 || i >= len(writes) {
+		return "", nil, errors.Errorf("write index [%d] out of range in namespace [%s]", i, ns)
+	}
+	return writes[i].Key, writes[i].Value, nil
+}
+
+func (r *RWSet) NumReads(ns string) int {
+	return len(r.reads(ns))
+}
+
+func (r *RWSet) NumWrites(ns string) int {
+	return len(r.writes(ns))
+}
+
+func (r *RWSet) Namespaces() []string {
+	set := map[string]bool{}
+	for _, read := range r.Reads {
+		set[read.Namespace] = true
+	}
+	for _, write := range r.Writes {
+		set[write.Namespace] = true
+	}
+	var res []string
+	for ns := range set {
+		res = append(res, ns)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// write returns the write of the passed key, appending a new one if the key has not been written yet
+func (r *RWSet) write(namespace, key string) *Write {
+	for _, w := range r.Writes {
+		if w.Namespace == namespace && w.Key == key {
+			return w
+		}
+	}
+	w := &Write{Namespace: namespace, Key: key}
+	r.Writes = append(r.Writes, w)
+	return w
+}
+
+func (r *RWSet) reads(ns string) []*Read {
+	var res []*Read
+	for _, read := range r.Reads {
+		if read.Namespace == ns {
+			res = append(res, read)
+		}
+	}
+	return res
+}
+
+func (r *RWSet) writes(ns string) []*Write {
+	var res []*Write
+	for _, write := range r.Writes {
+		if write.Namespace == ns {
+			res = append(res, write)
+		}
+	}
+	return res
+}
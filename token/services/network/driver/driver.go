@@ -0,0 +1,41 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package driver
+
+import (
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
+)
+
+// Network models the backend the token requests are committed to
+type Network interface {
+	// Name returns the name of this network
+	Name() string
+
+	// Channel returns the channel of this network the token requests are committed to, if any
+	Channel() string
+
+	// NewRWSet returns a new read-write set, bound to the passed transaction id, for the translator to write to
+	NewRWSet(txID string) (translator.RWSet, error)
+
+	// FetchPublicParameters returns the public parameters stored in the passed namespace
+	FetchPublicParameters(namespace string) ([]byte, error)
+
+	// Broadcast submits the passed transaction to the backend.
+	// The content of the blob depends on the backend.
+	Broadcast(txID string, blob interface{}) error
+
+	// IsFinal returns nil if the passed transaction has been committed as valid, an error otherwise.
+	// It blocks until the status of the transaction is known.
+	IsFinal(txID string) error
+}
+
+// Driver instantiates networks
+type Driver interface {
+	// New returns the network with the passed name and channel, or an error if this driver does not handle it
+	New(sp view2.ServiceProvider, network, channel string) (Network, error)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package fabric
+
+import (
+	"reflect"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric/api"
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/driver"
+	fetcher "github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc/fetcher"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
+)
+
+// Network commits token requests to a Fabric channel, via the token chaincode
+type Network struct {
+	sp      view2.ServiceProvider
+	fns     *fabric.NetworkService
+	channel *fabric.Channel
+}
+
+func NewNetwork(sp view2.ServiceProvider, fns *fabric.NetworkService, channel *fabric.Channel) *Network {
+	return &Network{sp: sp, fns: fns, channel: channel}
+}
+
+func (n *Network) Name() string {
+	return n.fns.Name()
+}
+
+func (n *Network) Channel() string {
+	return n.channel.Name()
+}
+
+func (n *Network) NewRWSet(txID string) (translator.RWSet, error) {
+	rws, err := n.channel.Vault().NewRWSet(txID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed creating rwset for [%s]", txID)
+	}
+	return rws, nil
+}
+
+func (n *Network) FetchPublicParameters(namespace string) ([]byte, error) {
+	raw, err := fetcher.NewPublicParamsFetcher(n.sp, n.Name(), n.Channel(), namespace).Fetch()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed querying public parameters from [%s:%s:%s]", n.Name(), n.Channel(), namespace)
+	}
+	return raw, nil
+}
+
+func (n *Network) Broadcast(txID string, blob interface{}) error {
+	if err := n.fns.Ordering().Broadcast(blob); err != nil {
+		return errors.WithMessagef(err, "failed broadcasting [%s]", txID)
+	}
+	return nil
+}
+
+func (n *Network) IsFinal(txID string) error {
+	return n.channel.Finality().IsFinal(txID)
+}
+
+type Driver struct{}
+
+func (d *Driver) New(sp view2.ServiceProvider, network, channel string) (driver.Network, error) {
+	s, err := sp.GetService(reflect.TypeOf((*api.FabricNetworkServiceProvider)(nil)))
+	if err != nil {
+		return nil, errors.WithMessage(err, "fabric platform not available")
+	}
+	if _, err := s.(api.FabricNetworkServiceProvider).FabricNetworkService(network); err != nil {
+		return nil, errors.WithMessagef(err, "fabric network [%s] not found", network)
+	}
+	fns := fabric.GetFabricNetworkService(sp, network)
+	ch, err := fns.Channel(channel)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "channel [%s] not found in fabric network [%s]", channel, network)
+	}
+	return NewNetwork(sp, fns, ch), nil
+}
+
+func init() {
+	network.Register("fabric", &Driver{})
+}
@@ -0,0 +1,71 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package memory
+
+import (
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
+)
+
+// Network commits token requests to an in-memory ledger
+type Network struct {
+	name    string
+	channel string
+	ledger  *Ledger
+}
+
+func NewNetwork(name, channel string, ledger *Ledger) *Network {
+	return &Network{name: name, channel: channel, ledger: ledger}
+}
+
+func (n *Network) Name() string {
+	return n.name
+}
+
+func (n *Network) Channel() string {
+	return n.channel
+}
+
+func (n *Network) NewRWSet(txID string) (translator.RWSet, error) {
+	return common.NewRWSet(txID, n.ledger.GetState), nil
+}
+
+func (n *Network) FetchPublicParameters(namespace string) ([]byte, error) {
+	return n.ledger.PublicParamsFetcher(namespace).Fetch()
+}
+
+// Broadcast commits the passed read-write set, either a *common.RWSet or its serialization
+func (n *Network) Broadcast(txID string, blob interface{}) error {
+	rws, err := common.RWSetFromBlob(txID, blob)
+	if err != nil {
+		return err
+	}
+	return n.ledger.Commit(txID, rws)
+}
+
+func (n *Network) IsFinal(txID string) error {
+	return n.ledger.IsFinal(txID)
+}
+
+// Driver serves all the networks from the in-memory ledger registered as a service, if any
+type Driver struct{}
+
+func (d *Driver) New(sp view2.ServiceProvider, network, channel string) (driver.Network, error) {
+	s, err := sp.GetService(&Ledger{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "in-memory ledger not available")
+	}
+	return NewNetwork(network, channel, s.(*Ledger)), nil
+}
+
+func init() {
+	network.Register("memory", &Driver{})
+}
@@ -0,0 +1,208 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package memory
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
+)
+
+// Validator validates token requests, token.Validator implements it
+type Validator interface {
+	UnmarshallAndVerify(ledger token.Ledger, binding string, raw []byte, opts ...token.ValidationOption) ([]interface{}, error)
+}
+
+// Ledger simulates, in memory, a ledger running the token chaincode.
+// Transactions are committed synchronously, in the order they are submitted.
+// It is meant for testing.
+type Ledger struct {
+	lock    sync.RWMutex
+	state   map[string][]byte
+	status  map[string]error
+	counter uint64
+}
+
+func NewLedger() *Ledger {
+	return &Ledger{
+		state:  map[string][]byte{},
+		status: map[string]error{},
+	}
+}
+
+// NextTxID returns a new transaction id.
+// Transaction ids are derived from a counter, therefore all ledgers return the same sequence of ids.
+func (l *Ledger) NextTxID() string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.counter++
+	h := sha256.Sum256([]byte("tx" + strconv.FormatUint(l.counter, 10)))
+	return hex.EncodeToString(h[:])
+}
+
+// GetState returns the committed value of the passed key in the passed namespace
+func (l *Ledger) GetState(namespace, key string) ([]byte, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return l.state[namespace+"/"+key], nil
+}
+
+// Commit applies the writes of the passed read-write set, if the values it read are still current.
+// Otherwise, the transaction is marked as invalid.
+func (l *Ledger) Commit(txID string, rws *common.RWSet) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if _, ok := l.status[txID]; ok {
+		return errors.Errorf("transaction [%s] already committed", txID)
+	}
+	for _, read := range rws.Reads {
+		if !bytes.Equal(l.state[read.Namespace+"/"+read.Key], read.Value) {
+			l.status[txID] = errors.Errorf("transaction [%s] invalid, read conflict on [%s:%s]", txID, read.Namespace, read.Key)
+			return l.status[txID]
+		}
+	}
+	for _, w := range rws.Writes {
+		if w.Delete {
+			delete(l.state, w.Namespace+"/"+w.Key)
+			continue
+		}
+		l.state[w.Namespace+"/"+w.Key] = w.Value
+	}
+	l.status[txID] = nil
+	return nil
+}
+
+// IsFinal returns nil if the passed transaction has been committed as valid, an error otherwise
+func (l *Ledger) IsFinal(txID string) error {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	err, ok := l.status[txID]
+	if !ok {
+		return errors.Errorf("transaction [%s] unknown", txID)
+	}
+	return err
+}
+
+// Setup stores the passed public parameters in the passed namespace, as the token chaincode does when instantiated
+func (l *Ledger) Setup(namespace string, pp []byte) (string, error) {
+	txID := l.NextTxID()
+	rws := common.NewRWSet(txID, l.GetState)
+	w := translator.New(&allIssuersValid{}, txID, rws, namespace)
+	if err := w.Write(&setupAction{pp: pp}); err != nil {
+		return "", errors.WithMessagef(err, "failed writing public parameters to [%s]", namespace)
+	}
+	rws.Done()
+	return txID, l.Commit(txID, rws)
+}
+
+// Submit validates the passed token request with the passed validator and, if valid, commits it to the passed namespace,
+// as the token chaincode does. The token request must be bound to the passed transaction id.
+// If the request is invalid, the transaction is marked as invalid and the validation error returned.
+func (l *Ledger) Submit(namespace string, validator Validator, txID string, raw []byte, opts ...token.ValidationOption) error {
+	rws := common.NewRWSet(txID, l.GetState)
+	if err := l.write(namespace, validator, rws, raw, opts...); err != nil {
+		l.lock.Lock()
+		if _, ok := l.status[txID]; !ok {
+			l.status[txID] = err
+		}
+		l.lock.Unlock()
+		return err
+	}
+	return l.Commit(txID, rws)
+}
+
+// NamespaceLedger returns a view of the passed namespace, to be used where a token.Ledger is expected
+func (l *Ledger) NamespaceLedger(namespace string) token.Ledger {
+	return &namespaceLedger{ledger: l, namespace: namespace}
+}
+
+// PublicParamsFetcher returns a fetcher of the public parameters stored in the passed namespace
+func (l *Ledger) PublicParamsFetcher(namespace string) *PublicParamsFetcher {
+	return &PublicParamsFetcher{ledger: l, namespace: namespace}
+}
+
+func (l *Ledger) write(namespace string, validator Validator, rws *common.RWSet, raw []byte, opts ...token.ValidationOption) error {
+	actions, err := validator.UnmarshallAndVerify(&rwsLedger{rws: rws, namespace: namespace}, rws.TxID, raw, opts...)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to verify token request [%s]", rws.TxID)
+	}
+	w := translator.New(&allIssuersValid{}, rws.TxID, rws, namespace)
+	for _, action := range actions {
+		if err := w.Write(action); err != nil {
+			return errors.WithMessagef(err, "failed to write token action [%s]", rws.TxID)
+		}
+	}
+	if err := w.CommitTokenRequest(raw); err != nil {
+		return errors.WithMessagef(err, "failed to write token request [%s]", rws.TxID)
+	}
+	rws.Done()
+	return nil
+}
+
+// PublicParamsFetcher fetches the public parameters from a ledger
+type PublicParamsFetcher struct {
+	ledger    *Ledger
+	namespace string
+}
+
+func (f *PublicParamsFetcher) Fetch() ([]byte, error) {
+	key, err := keys.CreateSetupKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating setup key")
+	}
+	raw, err := f.ledger.GetState(f.namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, errors.Errorf("public parameters not found in [%s]", f.namespace)
+	}
+	return raw, nil
+}
+
+type namespaceLedger struct {
+	ledger    *Ledger
+	namespace string
+}
+
+func (n *namespaceLedger) GetState(key string) ([]byte, error) {
+	return n.ledger.GetState(n.namespace, key)
+}
+
+// rwsLedger reads through a read-write set, so that the reads of the validator are checked at commit time
+type rwsLedger struct {
+	rws       *common.RWSet
+	namespace string
+}
+
+func (r *rwsLedger) GetState(key string) ([]byte, error) {
+	return r.rws.GetState(r.namespace, key)
+}
+
+type setupAction struct {
+	pp []byte
+}
+
+func (s *setupAction) GetSetupParameters() ([]byte, error) {
+	return s.pp, nil
+}
+
+type allIssuersValid struct{}
+
+func (i *allIssuersValid) Validate(creator view2.Identity, tokenType string) error {
+	return nil
+}
@@ -0,0 +1,183 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package memory_test
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/api"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	api2 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/fabric"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/memory"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+var _ = Describe("In-memory ledger", func() {
+	var (
+		ledger    *memory.Ledger
+		validator *token.Validator
+
+		issuer, alice, bob        view.Identity
+		issuerSigner, aliceSigner api.Signer
+	)
+
+	BeforeEach(func() {
+		ledger = memory.NewLedger()
+
+		pp, err := fabtoken.Setup()
+		Expect(err).NotTo(HaveOccurred())
+		ppRaw, err := pp.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = ledger.Setup("tns", ppRaw)
+		Expect(err).NotTo(HaveOccurred())
+
+		fetched, err := ledger.PublicParamsFetcher("tns").Fetch()
+		Expect(err).NotTo(HaveOccurred())
+		_, validator, err = token.NewServicesFromPublicParams(fetched)
+		Expect(err).NotTo(HaveOccurred())
+
+		issuer, issuerSigner, _, err = fabric.NewSigner()
+		Expect(err).NotTo(HaveOccurred())
+		alice, aliceSigner, _, err = fabric.NewSigner()
+		Expect(err).NotTo(HaveOccurred())
+		bob, _, _, err = fabric.NewSigner()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("returns deterministic transaction ids", func() {
+		Expect(memory.NewLedger().NextTxID()).To(Equal(memory.NewLedger().NextTxID()))
+		Expect(ledger.NextTxID()).NotTo(Equal(ledger.NextTxID()))
+	})
+
+	It("commits issue, transfer, and redeem", func() {
+		// issue 10 to alice
+		issueTxID := ledger.NextTxID()
+		raw := request(issueTxID, []*fabtoken.IssueAction{{
+			Issuer:  issuer,
+			Outputs: []*fabtoken.TransferOutput{output(alice, 10)},
+		}}, nil, issuerSigner)
+		Expect(ledger.Submit("tns", validator, issueTxID, raw)).NotTo(HaveOccurred())
+		Expect(ledger.IsFinal(issueTxID)).NotTo(HaveOccurred())
+		issued := tokenKey(issueTxID, 0)
+		Expect(ledger.NamespaceLedger("tns").GetState(issued)).NotTo(BeEmpty())
+
+		// alice transfers 7 to bob and redeems 3
+		transferTxID := ledger.NextTxID()
+		raw = request(transferTxID, nil, []*fabtoken.TransferAction{{
+			Sender:  alice,
+			Inputs:  []string{issued},
+			Outputs: []*fabtoken.TransferOutput{output(bob, 7), output(nil, 3)},
+		}}, aliceSigner)
+		Expect(ledger.Submit("tns", validator, transferTxID, raw)).NotTo(HaveOccurred())
+		Expect(ledger.IsFinal(transferTxID)).NotTo(HaveOccurred())
+		Expect(ledger.NamespaceLedger("tns").GetState(issued)).To(BeEmpty())
+		Expect(ledger.NamespaceLedger("tns").GetState(tokenKey(transferTxID, 0))).NotTo(BeEmpty())
+		Expect(ledger.NamespaceLedger("tns").GetState(tokenKey(transferTxID, 1))).To(BeEmpty())
+
+		// alice cannot spend the same token again
+		doubleSpendTxID := ledger.NextTxID()
+		raw = request(doubleSpendTxID, nil, []*fabtoken.TransferAction{{
+			Sender:  alice,
+			Inputs:  []string{issued},
+			Outputs: []*fabtoken.TransferOutput{output(alice, 10)},
+		}}, aliceSigner)
+		err := ledger.Submit("tns", validator, doubleSpendTxID, raw)
+		Expect(err).To(HaveOccurred())
+		report, ok := token.GetValidationReport(err)
+		Expect(ok).To(BeTrue())
+		Expect(report.Failure().Check).To(Equal(api2.DoubleSpendCheck))
+		Expect(ledger.IsFinal(doubleSpendTxID)).To(HaveOccurred())
+	})
+
+	It("serves the networks through the network driver", func() {
+		sp := registry.New()
+		Expect(sp.RegisterService(ledger)).NotTo(HaveOccurred())
+		net, err := network.NewProvider(sp).GetNetwork("memory", "ch")
+		Expect(err).NotTo(HaveOccurred())
+
+		pp, err := net.FetchPublicParameters("tns")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pp).NotTo(BeEmpty())
+
+		txID := ledger.NextTxID()
+		rws, err := net.NewRWSet(txID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rws.SetState("tns", "key", []byte("value"))).NotTo(HaveOccurred())
+		Expect(net.Broadcast(txID, rws)).NotTo(HaveOccurred())
+		Expect(net.IsFinal(txID)).NotTo(HaveOccurred())
+		Expect(ledger.GetState("tns", "key")).To(Equal([]byte("value")))
+	})
+
+	It("tells apart the networks by name and channel", func() {
+		sp := registry.New()
+		Expect(sp.RegisterService(ledger)).NotTo(HaveOccurred())
+		Expect(sp.RegisterService(network.NewProvider(sp))).NotTo(HaveOccurred())
+
+		n1, err := network.GetNetwork(sp, "ab", "c")
+		Expect(err).NotTo(HaveOccurred())
+		n2, err := network.GetNetwork(sp, "a", "bc")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n1.Name()).To(Equal("ab"))
+		Expect(n1.Channel()).To(Equal("c"))
+		Expect(n2.Name()).To(Equal("a"))
+		Expect(n2.Channel()).To(Equal("bc"))
+		Expect(network.GetInstance(sp, "ab", "c")).To(BeIdenticalTo(n1))
+
+		pp, err := network.NewPublicParamsFetcher(sp, "a", "bc", "tns").Fetch()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pp).NotTo(BeEmpty())
+	})
+})
+
+func output(owner view.Identity, quantity uint64) *fabtoken.TransferOutput {
+	return &fabtoken.TransferOutput{Output: &token2.Token{
+		Owner:    &token2.Owner{Raw: owner},
+		Type:     "ABC",
+		Quantity: token2.NewQuantityFromUInt64(quantity).Hex(),
+	}}
+}
+
+func tokenKey(txID string, index int) string {
+	key, err := keys.CreateTokenKey(txID, index)
+	Expect(err).NotTo(HaveOccurred())
+	return key
+}
+
+// request returns a serialized token request bound to the passed transaction id and signed by the passed signers
+func request(txID string, issues []*fabtoken.IssueAction, transfers []*fabtoken.TransferAction, signers ...api.Signer) []byte {
+	tr := &api2.TokenRequest{}
+	for _, issue := range issues {
+		raw, err := issue.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		tr.Issues = append(tr.Issues, raw)
+	}
+	for _, transfer := range transfers {
+		raw, err := transfer.Serialize()
+		Expect(err).NotTo(HaveOccurred())
+		tr.Transfers = append(tr.Transfers, raw)
+	}
+	message, err := json.Marshal(&api2.TokenRequest{Issues: tr.Issues, Transfers: tr.Transfers})
+	Expect(err).NotTo(HaveOccurred())
+	message = append(message, []byte(txID)...)
+	for _, signer := range signers {
+		sigma, err := signer.Sign(message)
+		Expect(err).NotTo(HaveOccurred())
+		tr.Signatures = append(tr.Signatures, sigma)
+	}
+	raw, err := json.Marshal(tr)
+	Expect(err).NotTo(HaveOccurred())
+	return raw
+}
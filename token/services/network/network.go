@@ -0,0 +1,170 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package network
+
+import (
+	"sort"
+	"sync"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.network")
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]driver.Driver)
+)
+
+// Register makes a network driver available by the provided name.
+// If Register is called twice with the same name or if driver is nil,
+// it panics.
+func Register(name string, driver driver.Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if driver == nil {
+		panic("network: Register driver is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("network: Register called twice for driver " + name)
+	}
+	drivers[name] = driver
+}
+
+// Drivers returns a sorted list of the names of the registered drivers.
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	list := make([]string, 0, len(drivers))
+	for name := range drivers {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Network gives access to the backend the token requests are committed to,
+// independently of its nature (a Fabric channel, an Orion database, ...)
+type Network struct {
+	n driver.Network
+}
+
+func (n *Network) Name() string {
+	return n.n.Name()
+}
+
+func (n *Network) Channel() string {
+	return n.n.Channel()
+}
+
+// NewRWSet returns a new read-write set, bound to the passed transaction id, for the translator to write to
+func (n *Network) NewRWSet(txID string) (translator.RWSet, error) {
+	return n.n.NewRWSet(txID)
+}
+
+// FetchPublicParameters returns the public parameters stored in the passed namespace
+func (n *Network) FetchPublicParameters(namespace string) ([]byte, error) {
+	return n.n.FetchPublicParameters(namespace)
+}
+
+// Broadcast submits the passed transaction to the backend
+func (n *Network) Broadcast(txID string, blob interface{}) error {
+	return n.n.Broadcast(txID, blob)
+}
+
+// IsFinal returns nil if the passed transaction has been committed as valid, an error otherwise
+func (n *Network) IsFinal(txID string) error {
+	return n.n.IsFinal(txID)
+}
+
+// networkKey identifies a network by its name and channel
+type networkKey struct {
+	network string
+	channel string
+}
+
+// Provider returns the networks, instantiating them with the first registered driver that handles them
+type Provider struct {
+	sp view2.ServiceProvider
+
+	lock     sync.Mutex
+	networks map[networkKey]*Network
+}
+
+func NewProvider(sp view2.ServiceProvider) *Provider {
+	return &Provider{sp: sp, networks: map[networkKey]*Network{}}
+}
+
+func (p *Provider) GetNetwork(network string, channel string) (*Network, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	key := networkKey{network: network, channel: channel}
+	if n, ok := p.networks[key]; ok {
+		return n, nil
+	}
+
+	var errs []string
+	for _, name := range Drivers() {
+		driversMu.RLock()
+		d := drivers[name]
+		driversMu.RUnlock()
+		n, err := d.New(p.sp, network, channel)
+		if err != nil {
+			logger.Debugf("driver [%s] cannot handle network [%s:%s]: [%s]", name, network, channel, err)
+			errs = append(errs, name+": "+err.Error())
+			continue
+		}
+		logger.Debugf("network [%s:%s] handled by driver [%s]", network, channel, name)
+		net := &Network{n: n}
+		p.networks[key] = net
+		return net, nil
+	}
+	return nil, errors.Errorf("no driver found for network [%s:%s] %v", network, channel, errs)
+}
+
+// GetNetwork returns the network with the passed name and channel, or an error if no driver handles it
+func GetNetwork(sp view2.ServiceProvider, network, channel string) (*Network, error) {
+	s, err := sp.GetService(&Provider{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "network provider not found")
+	}
+	return s.(*Provider).GetNetwork(network, channel)
+}
+
+// GetInstance returns the network with the passed name and channel, it panics if no driver handles it
+func GetInstance(sp view2.ServiceProvider, network, channel string) *Network {
+	n, err := GetNetwork(sp, network, channel)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// PublicParamsFetcher fetches the public parameters stored in a namespace of a network
+type PublicParamsFetcher struct {
+	sp        view2.ServiceProvider
+	network   string
+	channel   string
+	namespace string
+}
+
+func NewPublicParamsFetcher(sp view2.ServiceProvider, network, channel, namespace string) *PublicParamsFetcher {
+	return &PublicParamsFetcher{sp: sp, network: network, channel: channel, namespace: namespace}
+}
+
+func (f *PublicParamsFetcher) Fetch() ([]byte, error) {
+	n, err := GetNetwork(f.sp, f.network, f.channel)
+	if err != nil {
+		return nil, err
+	}
+	return n.FetchPublicParameters(f.namespace)
+}
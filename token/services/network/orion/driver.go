@@ -0,0 +1,122 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package orion is the network driver that commits the token requests to Hyperledger Orion.
+// The driver maps the token namespaces to Orion databases and the token requests to Orion data transactions,
+// it does not connect to Orion by itself: this module does not depend on the Orion SDK, the deployment registers a
+// SessionProvider backed by its Orion client. Without one, opening an Orion network fails.
+package orion
+
+import (
+	"reflect"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
+)
+
+// Session gives access to an Orion server, on behalf of an Orion user allowed to read and write the token databases.
+// Token namespaces are mapped to Orion databases.
+type Session interface {
+	// GetState returns the committed value of the passed key in the passed database
+	GetState(db, key string) ([]byte, error)
+
+	// Commit submits the passed read-write set as an Orion data transaction with the passed id.
+	// The reads must be committed with the versions they have been read at, so that Orion rejects a transaction
+	// spending a token spent meanwhile by another one.
+	Commit(txID string, rws *common.RWSet) error
+
+	// IsFinal returns nil if the passed transaction has been committed as valid, an error otherwise.
+	// It blocks until the status of the transaction is known.
+	IsFinal(txID string) error
+}
+
+// SessionProvider returns the sessions to the Orion servers known to this node.
+// It must be registered as a service by the deployment that wants to use Orion.
+type SessionProvider interface {
+	// Session returns a session to the passed Orion network
+	Session(network string) (Session, error)
+}
+
+// Network commits token requests to an Orion server
+type Network struct {
+	name    string
+	session Session
+}
+
+func NewNetwork(name string, session Session) *Network {
+	return &Network{name: name, session: session}
+}
+
+func (n *Network) Name() string {
+	return n.name
+}
+
+// Channel returns the empty string, Orion has no channels
+func (n *Network) Channel() string {
+	return ""
+}
+
+func (n *Network) NewRWSet(txID string) (translator.RWSet, error) {
+	return common.NewRWSet(txID, n.session.GetState), nil
+}
+
+func (n *Network) FetchPublicParameters(namespace string) ([]byte, error) {
+	key, err := keys.CreateSetupKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating setup key")
+	}
+	raw, err := n.session.GetState(namespace, key)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed reading public parameters from [%s:%s]", n.name, namespace)
+	}
+	if len(raw) == 0 {
+		return nil, errors.Errorf("public parameters not found in [%s:%s]", n.name, namespace)
+	}
+	return raw, nil
+}
+
+// Broadcast commits the passed read-write set, either a *common.RWSet or its serialization
+func (n *Network) Broadcast(txID string, blob interface{}) error {
+	rws, err := common.RWSetFromBlob(txID, blob)
+	if err != nil {
+		return err
+	}
+	if err := n.session.Commit(txID, rws); err != nil {
+		return errors.WithMessagef(err, "failed committing [%s] to [%s]", txID, n.name)
+	}
+	return nil
+}
+
+func (n *Network) IsFinal(txID string) error {
+	return n.session.IsFinal(txID)
+}
+
+type Driver struct{}
+
+func (d *Driver) New(sp view2.ServiceProvider, network, channel string) (driver.Network, error) {
+	if len(channel) != 0 {
+		return nil, errors.Errorf("orion networks have no channels, got [%s]", channel)
+	}
+	s, err := sp.GetService(reflect.TypeOf((*SessionProvider)(nil)))
+	if err != nil {
+		return nil, errors.WithMessage(err, "orion session provider not available")
+	}
+	session, err := s.(SessionProvider).Session(network)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed opening session to orion network [%s]", network)
+	}
+	return NewNetwork(network, session), nil
+}
+
+func init() {
+	network.Register("orion", &Driver{})
+}
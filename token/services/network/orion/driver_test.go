@@ -0,0 +1,138 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package orion_test
+
+import (
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/common"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network/orion"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
+)
+
+var _ = Describe("Orion network", func() {
+	var (
+		session *fakeSession
+		net     *network.Network
+	)
+
+	BeforeEach(func() {
+		session = &fakeSession{state: map[string][]byte{}, committed: map[string]bool{}}
+		sp := registry.New()
+		Expect(sp.RegisterService(&fakeSessionProvider{session: session})).NotTo(HaveOccurred())
+
+		var err error
+		net, err = network.NewProvider(sp).GetNetwork("orion-net", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(net.Name()).To(Equal("orion-net"))
+		Expect(net.Channel()).To(BeEmpty())
+	})
+
+	Describe("committing the public parameters", func() {
+		It("makes them available to fetch", func() {
+			_, err := net.FetchPublicParameters("tns")
+			Expect(err).To(HaveOccurred())
+
+			rws, err := net.NewRWSet("tx1")
+			Expect(err).NotTo(HaveOccurred())
+			w := translator.New(&allIssuersValid{}, "tx1", rws, "tns")
+			Expect(w.Write(&setupAction{pp: []byte("public parameters")})).NotTo(HaveOccurred())
+			rws.Done()
+
+			Expect(net.Broadcast("tx1", rws)).NotTo(HaveOccurred())
+			Expect(net.IsFinal("tx1")).NotTo(HaveOccurred())
+
+			pp, err := net.FetchPublicParameters("tns")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pp).To(Equal([]byte("public parameters")))
+		})
+	})
+
+	Describe("broadcasting", func() {
+		It("accepts serialized rwsets", func() {
+			rws, err := net.NewRWSet("tx2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rws.SetState("tns", "key", []byte("value"))).NotTo(HaveOccurred())
+			raw, err := rws.Bytes()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(net.Broadcast("tx2", raw)).NotTo(HaveOccurred())
+			Expect(session.state["tns/key"]).To(Equal([]byte("value")))
+		})
+		It("rejects rwsets bound to another transaction", func() {
+			rws, err := net.NewRWSet("tx3")
+			Expect(err).NotTo(HaveOccurred())
+			err = net.Broadcast("tx4", rws)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("rwset bound to [tx3], expected [tx4]"))
+			Expect(net.IsFinal("tx4")).To(HaveOccurred())
+		})
+	})
+})
+
+type fakeSessionProvider struct {
+	session *fakeSession
+}
+
+func (p *fakeSessionProvider) Session(network string) (orion.Session, error) {
+	return p.session, nil
+}
+
+type fakeSession struct {
+	lock      sync.Mutex
+	state     map[string][]byte
+	committed map[string]bool
+}
+
+func (s *fakeSession) GetState(db, key string) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.state[db+"/"+key], nil
+}
+
+func (s *fakeSession) Commit(txID string, rws *common.RWSet) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, w := range rws.Writes {
+		if w.Delete {
+			delete(s.state, w.Namespace+"/"+w.Key)
+			continue
+		}
+		s.state[w.Namespace+"/"+w.Key] = w.Value
+	}
+	s.committed[txID] = true
+	return nil
+}
+
+func (s *fakeSession) IsFinal(txID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !s.committed[txID] {
+		return errors.Errorf("transaction [%s] not committed", txID)
+	}
+	return nil
+}
+
+type setupAction struct {
+	pp []byte
+}
+
+func (s *setupAction) GetSetupParameters() ([]byte, error) {
+	return s.pp, nil
+}
+
+type allIssuersValid struct{}
+
+func (i *allIssuersValid) Validate(creator view2.Identity, tokenType string) error {
+	return nil
+}
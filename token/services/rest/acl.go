@@ -0,0 +1,67 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package rest
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// Authenticator returns the name of the authenticated client of the passed request
+type Authenticator func(r *http.Request) (string, error)
+
+// Grant is what a client can access
+type Grant struct {
+	// Wallets are the ids of the owner wallets whose balance and tokens the client can query,
+	// the default wallet is named by the empty string
+	Wallets []string
+	// Audit is true if the client can run the audit queries
+	Audit bool
+}
+
+// ACL lists, for each client, named as returned by the Authenticator, what it can access.
+// Any client in the ACL can query the status of a transaction.
+type ACL map[string]*Grant
+
+// CanAccessWallet returns true if the passed client can access the owner wallet with the passed id
+func (a ACL) CanAccessWallet(client, wallet string) bool {
+	g, ok := a[client]
+	if !ok {
+		return false
+	}
+	for _, w := range g.Wallets {
+		if w == wallet {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAudit returns true if the passed client can run the audit queries
+func (a ACL) CanAudit(client string) bool {
+	g, ok := a[client]
+	return ok && g.Audit
+}
+
+// Contains returns true if the passed client is in the ACL
+func (a ACL) Contains(client string) bool {
+	_, ok := a[client]
+	return ok
+}
+
+// ClientName returns the common name of the verified TLS certificate of the client of the passed request.
+// It is the default Authenticator, serve the handler with a TLS configuration that requires and verifies
+// the client certificates.
+func ClientName(r *http.Request) (string, error) {
+	if r.TLS == nil {
+		return "", errors.New("client not authenticated with TLS")
+	}
+	if len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", errors.New("no verified client certificate")
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.CommonName, nil
+}
@@ -0,0 +1,194 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/pkg/errors"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.rest")
+
+const transactionsPath = "/v1/transactions/"
+
+// Handler serves the read-only token API over HTTP with JSON bodies:
+//
+//	GET /v1/balance?wallet=<id>&type=<type>
+//	GET /v1/tokens?wallet=<id>&type=<type>
+//	GET /v1/transactions/<tx_id>
+//	GET /v1/audit/payments?enrollment_id=<id>&type=<type>&last=<n>
+//	GET /v1/audit/holdings?enrollment_id=<id>&type=<type>
+//
+// The query parameters enrollment_id and type of the audit queries can be repeated.
+// Handler can be mounted on any mux, use http.StripPrefix to serve it under a sub path.
+// The clients are authenticated by the common name of their TLS certificate, see WithAuthenticator,
+// and access only what the ACL grants them, see WithACL.
+type Handler struct {
+	service      Service
+	mux          *http.ServeMux
+	authenticate Authenticator
+	acl          ACL
+}
+
+func NewHandler(service Service) *Handler {
+	h := &Handler{service: service, mux: http.NewServeMux(), authenticate: ClientName}
+	h.mux.HandleFunc("/v1/balance", h.balance)
+	h.mux.HandleFunc("/v1/tokens", h.unspentTokens)
+	h.mux.HandleFunc(transactionsPath, h.transactionStatus)
+	h.mux.HandleFunc("/v1/audit/payments", h.payments)
+	h.mux.HandleFunc("/v1/audit/holdings", h.holdings)
+	return h
+}
+
+// WithAuthenticator sets how the clients are authenticated, the default is ClientName
+func (h *Handler) WithAuthenticator(authenticate Authenticator) *Handler {
+	h.authenticate = authenticate
+	return h
+}
+
+// WithACL sets what each client can access, without it every request is denied
+func (h *Handler) WithACL(acl ACL) *Handler {
+	h.acl = acl
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.Errorf("method [%s] not allowed", r.Method))
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) balance(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if !h.authorize(w, r, func(client string) bool { return h.acl.CanAccessWallet(client, q.Get("wallet")) }) {
+		return
+	}
+	res, err := h.service.Balance(q.Get("wallet"), q.Get("type"))
+	write(w, res, err)
+}
+
+func (h *Handler) unspentTokens(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if !h.authorize(w, r, func(client string) bool { return h.acl.CanAccessWallet(client, q.Get("wallet")) }) {
+		return
+	}
+	res, err := h.service.UnspentTokens(q.Get("wallet"), q.Get("type"))
+	write(w, res, err)
+}
+
+func (h *Handler) transactionStatus(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, h.acl.Contains) {
+		return
+	}
+	txID := strings.TrimPrefix(r.URL.Path, transactionsPath)
+	if len(txID) == 0 || strings.Contains(txID, "/") {
+		writeError(w, http.StatusBadRequest, errors.Errorf("invalid transaction id [%s]", txID))
+		return
+	}
+	res, err := h.service.TransactionStatus(txID)
+	write(w, res, err)
+}
+
+func (h *Handler) payments(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, h.acl.CanAudit) {
+		return
+	}
+	query, err := auditQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	res, err := h.service.Payments(query)
+	write(w, res, err)
+}
+
+func (h *Handler) holdings(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, h.acl.CanAudit) {
+		return
+	}
+	query, err := auditQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	res, err := h.service.Holdings(query)
+	write(w, res, err)
+}
+
+// authorize authenticates the client of the passed request and checks that it is allowed.
+// Otherwise, it answers with 401 or 403 and returns false.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request, allowed func(client string) bool) bool {
+	client, err := h.authenticate(r)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return false
+	}
+	if !allowed(client) {
+		writeError(w, http.StatusForbidden, errors.Errorf("client [%s] not authorized to access [%s]", client, r.URL.Path))
+		return false
+	}
+	return true
+}
+
+func auditQuery(r *http.Request) (*AuditQuery, error) {
+	q := r.URL.Query()
+	query := &AuditQuery{
+		EnrollmentIDs: q["enrollment_id"],
+		TokenTypes:    q["type"],
+	}
+	if last := q.Get("last"); len(last) != 0 {
+		n, err := strconv.Atoi(last)
+		if err != nil || n < 0 {
+			return nil, errors.Errorf("invalid last [%s], expected a non-negative integer", last)
+		}
+		query.Last = n
+	}
+	return query, nil
+}
+
+// statusCodes maps the token error codes to the HTTP status codes returned to the clients
+var statusCodes = map[errors2.Code]int{
+	errors2.Unauthorized: http.StatusForbidden,
+	errors2.Conflict:     http.StatusConflict,
+}
+
+func write(w http.ResponseWriter, res interface{}, err error) {
+	if err != nil {
+		if errors.Cause(err) == ErrNotFound {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		if code, ok := statusCodes[errors2.GetCode(err)]; ok {
+			writeError(w, code, err)
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	logger.Debugf("request failed with [%d]: [%s]", code, err)
+	writeJSON(w, code, &ErrorResponse{Code: code, Message: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Errorf("failed writing response: [%s]", err)
+	}
+}
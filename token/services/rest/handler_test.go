@@ -0,0 +1,164 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+type fakeService struct {
+	Service
+	query *AuditQuery
+}
+
+func (f *fakeService) Balance(wallet, tokenType string) (*BalanceResponse, error) {
+	if wallet != "alice" {
+		return nil, errors.Wrapf(ErrNotFound, "owner wallet [%s]", wallet)
+	}
+	return &BalanceResponse{Wallet: wallet, TokenType: tokenType, Quantity: "10"}, nil
+}
+
+func (f *fakeService) UnspentTokens(wallet, tokenType string) (*UnspentTokensResponse, error) {
+	return &UnspentTokensResponse{Wallet: wallet, Tokens: toTokenEntries([]*token.UnspentToken{
+		{Id: &token.Id{TxId: "tx1", Index: 2}, Type: "USD", Quantity: "0x0a"},
+	})}, nil
+}
+
+func (f *fakeService) TransactionStatus(txID string) (*TransactionStatusResponse, error) {
+	if txID == "tx2" {
+		return nil, errors2.Errorf(errors2.Unauthorized, "transaction [%s] not visible", txID)
+	}
+	return &TransactionStatusResponse{TxID: txID, Status: "Valid"}, nil
+}
+
+func (f *fakeService) Payments(query *AuditQuery) (*AuditResponse, error) {
+	f.query = query
+	return nil, errors.New("db closed")
+}
+
+func (f *fakeService) Holdings(query *AuditQuery) (*AuditResponse, error) {
+	f.query = query
+	return &AuditResponse{Sum: "-5", Records: toAuditRecords([]*driver.Record{
+		{TxID: "tx1", EnrollmentID: "alice", Type: "USD", Status: driver.Confirmed},
+	})}, nil
+}
+
+// headerAuthenticator authenticates the clients by the X-Client header
+func headerAuthenticator(r *http.Request) (string, error) {
+	client := r.Header.Get("X-Client")
+	if len(client) == 0 {
+		return "", errors.New("no client")
+	}
+	return client, nil
+}
+
+func TestHandler(t *testing.T) {
+	s := &fakeService{}
+	srv := httptest.NewServer(NewHandler(s).WithAuthenticator(headerAuthenticator).WithACL(ACL{
+		"app": {Wallets: []string{"alice", "bob"}, Audit: true},
+	}))
+	defer srv.Close()
+
+	get := func(path string, code int, out interface{}) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		assert.NoError(t, err)
+		req.Header.Set("X-Client", "app")
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, code, resp.StatusCode, path)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+
+	balance := &BalanceResponse{}
+	get("/v1/balance?wallet=alice&type=USD", http.StatusOK, balance)
+	assert.Equal(t, &BalanceResponse{Wallet: "alice", TokenType: "USD", Quantity: "10"}, balance)
+
+	e := &ErrorResponse{}
+	get("/v1/balance?wallet=bob", http.StatusNotFound, e)
+	assert.Equal(t, http.StatusNotFound, e.Code)
+	assert.Contains(t, e.Message, "owner wallet [bob]")
+
+	tokens := &UnspentTokensResponse{}
+	get("/v1/tokens?wallet=alice", http.StatusOK, tokens)
+	assert.Equal(t, []*TokenEntry{{TxID: "tx1", Index: 2, Type: "USD", Quantity: "10"}}, tokens.Tokens)
+
+	status := &TransactionStatusResponse{}
+	get("/v1/transactions/tx1", http.StatusOK, status)
+	assert.Equal(t, &TransactionStatusResponse{TxID: "tx1", Status: "Valid"}, status)
+	get("/v1/transactions/", http.StatusBadRequest, &ErrorResponse{})
+	get("/v1/transactions/tx2", http.StatusForbidden, e)
+	assert.Equal(t, "transaction [tx2] not visible", e.Message)
+
+	holdings := &AuditResponse{}
+	get("/v1/audit/holdings?enrollment_id=alice&enrollment_id=bob&type=USD", http.StatusOK, holdings)
+	assert.Equal(t, &AuditQuery{EnrollmentIDs: []string{"alice", "bob"}, TokenTypes: []string{"USD"}}, s.query)
+	assert.Equal(t, "-5", holdings.Sum)
+	assert.Equal(t, []*AuditRecord{{TxID: "tx1", EnrollmentID: "alice", Type: "USD", Amount: "0", Status: "Confirmed"}}, holdings.Records)
+
+	get("/v1/audit/payments?last=3", http.StatusInternalServerError, e)
+	assert.Equal(t, 3, s.query.Last)
+	assert.Equal(t, "db closed", e.Message)
+	get("/v1/audit/payments?last=-1", http.StatusBadRequest, e)
+
+	resp, err := http.Post(srv.URL+"/v1/balance", "application/json", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestHandlerAccessControl(t *testing.T) {
+	h := NewHandler(&fakeService{}).WithAuthenticator(headerAuthenticator).WithACL(ACL{
+		"alice-app": {Wallets: []string{"alice"}},
+		"dashboard": {Audit: true},
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	get := func(client, path string) int {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		assert.NoError(t, err)
+		if len(client) != 0 {
+			req.Header.Set("X-Client", client)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get("", "/v1/balance?wallet=alice"))
+	assert.Equal(t, http.StatusOK, get("alice-app", "/v1/balance?wallet=alice"))
+	assert.Equal(t, http.StatusForbidden, get("alice-app", "/v1/tokens?wallet=bob"))
+	assert.Equal(t, http.StatusForbidden, get("alice-app", "/v1/audit/holdings?enrollment_id=bob"))
+	assert.Equal(t, http.StatusOK, get("alice-app", "/v1/transactions/tx1"))
+	assert.Equal(t, http.StatusOK, get("dashboard", "/v1/audit/holdings?enrollment_id=bob"))
+	assert.Equal(t, http.StatusForbidden, get("dashboard", "/v1/balance?wallet=alice"))
+	assert.Equal(t, http.StatusForbidden, get("mallory", "/v1/transactions/tx1"))
+
+	// without an acl every request is denied
+	h.WithACL(nil)
+	assert.Equal(t, http.StatusForbidden, get("alice-app", "/v1/balance?wallet=alice"))
+
+	// by default, the clients must authenticate with a verified TLS certificate
+	srv2 := httptest.NewServer(NewHandler(&fakeService{}).WithACL(ACL{"alice-app": {Wallets: []string{"alice"}}}))
+	defer srv2.Close()
+	resp, err := http.Get(srv2.URL + "/v1/balance?wallet=alice")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
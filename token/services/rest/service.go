@@ -0,0 +1,164 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package rest
+
+import (
+	"math/big"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/ttxcc"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// ErrNotFound signals that the requested resource does not exist, the gateway answers with 404
+var ErrNotFound = errors.New("not found")
+
+// Service is the backend of the gateway
+type Service interface {
+	Balance(wallet, tokenType string) (*BalanceResponse, error)
+	UnspentTokens(wallet, tokenType string) (*UnspentTokensResponse, error)
+	TransactionStatus(txID string) (*TransactionStatusResponse, error)
+	Payments(query *AuditQuery) (*AuditResponse, error)
+	Holdings(query *AuditQuery) (*AuditResponse, error)
+}
+
+type service struct {
+	sp      view2.ServiceProvider
+	auditor *auditor.Auditor
+	opts    []token.ServiceOption
+}
+
+// NewService returns a Service on top of the token management service selected by the passed options.
+// If the auditor is nil, the audit queries are not available.
+func NewService(sp view2.ServiceProvider, auditor *auditor.Auditor, opts ...token.ServiceOption) Service {
+	return &service{sp: sp, auditor: auditor, opts: opts}
+}
+
+func (s *service) Balance(wallet, tokenType string) (*BalanceResponse, error) {
+	unspent, err := s.unspentTokens(wallet, tokenType)
+	if err != nil {
+		return nil, err
+	}
+	return &BalanceResponse{
+		Wallet:    wallet,
+		TokenType: tokenType,
+		Quantity:  unspent.Sum(64).Decimal(),
+	}, nil
+}
+
+func (s *service) UnspentTokens(wallet, tokenType string) (*UnspentTokensResponse, error) {
+	unspent, err := s.unspentTokens(wallet, tokenType)
+	if err != nil {
+		return nil, err
+	}
+	return &UnspentTokensResponse{Wallet: wallet, Tokens: toTokenEntries(unspent.Tokens)}, nil
+}
+
+func (s *service) TransactionStatus(txID string) (*TransactionStatusResponse, error) {
+	tms := token.GetManagementService(s.sp, s.opts...)
+	code, _, err := fabric.GetChannel(s.sp, tms.Network(), tms.Channel()).Vault().Status(txID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting status of [%s]", txID)
+	}
+	var status string
+	switch code {
+	case fabric.Valid:
+		status = "Valid"
+	case fabric.Invalid:
+		status = "Invalid"
+	case fabric.Busy:
+		status = "Busy"
+	default:
+		status = "Unknown"
+	}
+	return &TransactionStatusResponse{TxID: txID, Status: status}, nil
+}
+
+func (s *service) Payments(query *AuditQuery) (*AuditResponse, error) {
+	if s.auditor == nil {
+		return nil, errors.Wrap(ErrNotFound, "audit queries not enabled")
+	}
+	qe := s.auditor.NewQueryExecutor()
+	defer qe.Done()
+
+	filter := qe.Payments()
+	for _, id := range query.EnrollmentIDs {
+		filter.ByEnrollmentId(id)
+	}
+	for _, typ := range query.TokenTypes {
+		filter.ByType(typ)
+	}
+	if query.Last > 0 {
+		filter.Last(query.Last)
+	}
+	filter, err := filter.Execute()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed querying payments")
+	}
+	return &AuditResponse{Sum: filter.Sum().Decimal(), Records: toAuditRecords(filter.Records())}, nil
+}
+
+func (s *service) Holdings(query *AuditQuery) (*AuditResponse, error) {
+	if s.auditor == nil {
+		return nil, errors.Wrap(ErrNotFound, "audit queries not enabled")
+	}
+	qe := s.auditor.NewQueryExecutor()
+	defer qe.Done()
+
+	filter := qe.Holdings()
+	for _, id := range query.EnrollmentIDs {
+		filter.ByEnrollmentId(id)
+	}
+	for _, typ := range query.TokenTypes {
+		filter.ByType(typ)
+	}
+	filter, err := filter.Execute()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed querying holdings")
+	}
+	return &AuditResponse{Sum: filter.Sum().Decimal(), Records: toAuditRecords(filter.Records())}, nil
+}
+
+func (s *service) unspentTokens(wallet, tokenType string) (*token2.UnspentTokens, error) {
+	w := token.GetManagementService(s.sp, s.opts...).WalletManager().OwnerWallet(wallet)
+	if w == nil {
+		return nil, errors.Wrapf(ErrNotFound, "owner wallet [%s]", wallet)
+	}
+	var opts []token.ListTokensOption
+	if len(tokenType) != 0 {
+		opts = append(opts, ttxcc.WithType(tokenType))
+	}
+	unspent, err := w.ListTokens(opts...)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed listing tokens of wallet [%s]", wallet)
+	}
+	return unspent, nil
+}
+
+func toAuditRecords(records []*driver.Record) []*AuditRecord {
+	res := make([]*AuditRecord, len(records))
+	for i, r := range records {
+		amount := r.Amount
+		if amount == nil {
+			amount = big.NewInt(0)
+		}
+		res[i] = &AuditRecord{
+			TxID:         r.TxID,
+			ActionIndex:  r.ActionIndex,
+			EnrollmentID: r.EnrollmentID,
+			Type:         r.Type,
+			Amount:       amount.Text(10),
+			Status:       string(r.Status),
+		}
+	}
+	return res
+}
@@ -0,0 +1,91 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package rest
+
+import (
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// The following structures are the request and response bodies of the gateway.
+// Field names and json tags follow the OpenAPI conventions, quantities are decimal strings
+// to not lose precision in clients whose numbers are floats.
+
+// BalanceResponse is returned by GET /v1/balance
+type BalanceResponse struct {
+	Wallet    string `json:"wallet"`
+	TokenType string `json:"token_type,omitempty"`
+	// Quantity is the sum of the unspent tokens, in decimal representation
+	Quantity string `json:"quantity"`
+}
+
+// UnspentTokensResponse is returned by GET /v1/tokens
+type UnspentTokensResponse struct {
+	Wallet string        `json:"wallet"`
+	Tokens []*TokenEntry `json:"tokens"`
+}
+
+type TokenEntry struct {
+	TxID     string `json:"tx_id"`
+	Index    uint32 `json:"index"`
+	Type     string `json:"type"`
+	Quantity string `json:"quantity"`
+}
+
+// TransactionStatusResponse is returned by GET /v1/transactions/{tx_id}
+type TransactionStatusResponse struct {
+	TxID string `json:"tx_id"`
+	// Status is one of Valid, Invalid, Busy, Unknown
+	Status string `json:"status"`
+}
+
+// AuditQuery selects the audit records returned by GET /v1/audit/payments and GET /v1/audit/holdings
+type AuditQuery struct {
+	EnrollmentIDs []string `json:"enrollment_id,omitempty"`
+	TokenTypes    []string `json:"type,omitempty"`
+	// Last is the number of most recent records to return, zero for all. Payments only.
+	Last int `json:"last,omitempty"`
+}
+
+// AuditResponse is returned by the audit queries
+type AuditResponse struct {
+	// Sum is the sum of the amounts of the selected records, in decimal representation
+	Sum     string         `json:"sum"`
+	Records []*AuditRecord `json:"records"`
+}
+
+type AuditRecord struct {
+	TxID         string `json:"tx_id"`
+	ActionIndex  uint32 `json:"action_index"`
+	EnrollmentID string `json:"enrollment_id"`
+	Type         string `json:"type"`
+	// Amount is positive for received tokens and negative for sent ones
+	Amount string `json:"amount"`
+	Status string `json:"status"`
+}
+
+// ErrorResponse is returned with any non 2xx status code
+type ErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func toTokenEntries(tokens []*token.UnspentToken) []*TokenEntry {
+	res := make([]*TokenEntry, len(tokens))
+	for i, t := range tokens {
+		res[i] = &TokenEntry{
+			Type:     t.Type,
+			Quantity: t.Quantity,
+		}
+		if q, err := token.ToQuantity(t.Quantity, 64); err == nil {
+			res[i].Quantity = q.Decimal()
+		}
+		if t.Id != nil {
+			res[i].TxID = t.Id.TxId
+			res[i].Index = t.Id.Index
+		}
+	}
+	return res
+}
@@ -8,13 +8,18 @@ package selector
 import (
 	"time"
 
+	"github.com/pkg/errors"
+
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 type NewQueryEngineFunc func() QueryService
 
 type manager struct {
 	locker               Locker
+	queue                *fairQueue
+	reservations         *reservations
 	newQueryEngine       NewQueryEngineFunc
 	certClient           CertClient
 	numRetry             int
@@ -22,9 +27,11 @@ type manager struct {
 	requestCertification bool
 }
 
-func newManager(locker Locker, newQueryEngine NewQueryEngineFunc, certClient CertClient, numRetry int, timeout time.Duration, requestCertification bool) *manager {
+func newManager(locker Locker, queue *fairQueue, reservations *reservations, newQueryEngine NewQueryEngineFunc, certClient CertClient, numRetry int, timeout time.Duration, requestCertification bool) *manager {
 	return &manager{
 		locker:               locker,
+		queue:                queue,
+		reservations:         reservations,
 		newQueryEngine:       newQueryEngine,
 		certClient:           certClient,
 		numRetry:             numRetry,
@@ -34,10 +41,52 @@ func newManager(locker Locker, newQueryEngine NewQueryEngineFunc, certClient Cer
 }
 
 func (m *manager) NewSelector(id string) (token.Selector, error) {
-	return newSelector(id, m.locker, m.newQueryEngine(), m.certClient, m.numRetry, m.timeout, m.requestCertification), nil
+	return newSelector(id, m.locker, m.queue, m.newQueryEngine(), m.certClient, m.numRetry, m.timeout, m.requestCertification), nil
 }
 
 func (m *manager) Unlock(txID string) error {
 	m.locker.UnlockByTxID(txID)
 	return nil
 }
+
+func (m *manager) UnlockIDs(ids ...*token2.Id) error {
+	m.locker.UnlockIDs(ids...)
+	return nil
+}
+
+func (m *manager) Reserve(ownerFilter token.OwnerFilter, amount, tokenType string, ttl time.Duration) (*token.Reservation, error) {
+	id, err := newLockID("reservation")
+	if err != nil {
+		return nil, err
+	}
+	ids, sum, err := newSelector(id, m.locker, m.queue, m.newQueryEngine(), m.certClient, m.numRetry, m.timeout, m.requestCertification).Select(ownerFilter, amount, tokenType)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed reserving [%s:%s]", amount, tokenType)
+	}
+	reservation := &token.Reservation{ID: id, Type: tokenType, Quantity: sum.Decimal(), TokenIDs: ids}
+	if ttl > 0 {
+		reservation.Expiry = time.Now().Add(ttl)
+	}
+	if err := m.reservations.add(reservation); err != nil {
+		m.locker.UnlockByTxID(id)
+		return nil, err
+	}
+	logger.Debugf("reserved [%v] for [%s:%s] until [%s]", ids, amount, tokenType, reservation.Expiry)
+	return reservation, nil
+}
+
+func (m *manager) Release(reservationID string) error {
+	return m.reservations.release(reservationID)
+}
+
+func (m *manager) ReleaseSpent(ids ...*token2.Id) error {
+	return m.reservations.releaseSpent(ids)
+}
+
+func (m *manager) Peek(ownerFilter token.OwnerFilter, amount, tokenType string) ([]*token2.Id, token2.Quantity, error) {
+	ids, sum, err := peek(m.newQueryEngine(), ownerFilter, amount, tokenType)
+	if err != nil {
+		return nil, nil, errors.WithMessagef(err, "failed peeking [%s:%s]", amount, tokenType)
+	}
+	return ids, sum, nil
+}
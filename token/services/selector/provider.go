@@ -11,6 +11,9 @@ import (
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/pkg/errors"
+
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
@@ -37,10 +40,15 @@ type selectorService struct {
 	numRetry             int
 	timeout              time.Duration
 	requestCertification bool
+	partitions           int
+	queueTimeout         time.Duration
 
 	lock           sync.Mutex
 	lockerProvider LockerProvider
 	lockers        map[string]Locker
+	queues         map[string]*fairQueue
+	reservations   map[string]*reservations
+	store          ReservationStore
 }
 
 func NewProvider(sp view.ServiceProvider, lockerProvider LockerProvider, numRetry int, timeout time.Duration) *selectorService {
@@ -48,13 +56,17 @@ func NewProvider(sp view.ServiceProvider, lockerProvider LockerProvider, numRetr
 		sp:                   sp,
 		lockerProvider:       lockerProvider,
 		lockers:              map[string]Locker{},
+		queues:               map[string]*fairQueue{},
+		reservations:         map[string]*reservations{},
+		partitions:           DefaultPartitions,
+		queueTimeout:         DefaultQueueTimeout,
 		numRetry:             numRetry,
 		timeout:              timeout,
 		requestCertification: true,
 	}
 }
 
-func (s *selectorService) SelectorManager(network string, channel string, namespace string) token.SelectorManager {
+func (s *selectorService) SelectorManager(network string, channel string, namespace string) (token.SelectorManager, error) {
 	tms := token.GetManagementService(
 		s.sp,
 		token.WithNetwork(network),
@@ -74,9 +86,28 @@ func (s *selectorService) SelectorManager(network string, channel string, namesp
 	} else {
 		logger.Debugf("in-memory selector for [%s:%s:%s] exists", tms.Network(), tms.Channel(), tms.Namespace())
 	}
+	queue, ok := s.queues[key]
+	if !ok {
+		queue = newFairQueue(s.partitions, s.queueTimeout)
+		s.queues[key] = queue
+	}
+	res, ok := s.reservations[key]
+	if !ok {
+		storeKey, err := reservationsKey(tms.Network(), tms.Channel(), tms.Namespace())
+		if err != nil {
+			return nil, err
+		}
+		res, err = newReservations(locker, s.store, storeKey)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed loading reservations of [%s:%s:%s]", tms.Network(), tms.Channel(), tms.Namespace())
+		}
+		s.reservations[key] = res
+	}
 
 	return newManager(
 		locker,
+		queue,
+		res,
 		func() QueryService {
 			return tms.Vault().NewQueryEngine()
 		},
@@ -84,7 +115,7 @@ func (s *selectorService) SelectorManager(network string, channel string, namesp
 		s.numRetry,
 		s.timeout,
 		s.requestCertification,
-	)
+	), nil
 }
 
 func (s *selectorService) SetNumRetries(n uint) {
@@ -95,6 +126,39 @@ func (s *selectorService) SetRetryTimeout(t time.Duration) {
 	s.timeout = t
 }
 
+// SetPartitions sets the number of selections from the same wallet that run at once, the others wait in arrival
+// order. The tokens of the wallet are partitioned among the running selections, so that they do not collide.
+// It applies to the TMSs whose selector manager is created afterwards.
+func (s *selectorService) SetPartitions(n int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.partitions = n
+}
+
+// SetQueueTimeout sets the time a selection waits for its turn among the selections from the same wallet,
+// forever if zero. It applies to the TMSs whose selector manager is created afterwards.
+func (s *selectorService) SetQueueTimeout(t time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.queueTimeout = t
+}
+
+// SetReservationStore persists the reservations in the passed store, they are locked again after a restart.
+// It applies to the TMSs whose selector manager is created afterwards.
+func (s *selectorService) SetReservationStore(store ReservationStore) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.store = store
+}
+
 func (s *selectorService) SetRequestCertification(v bool) {
 	s.requestCertification = v
 }
+
+func reservationsKey(network, channel, namespace string) (string, error) {
+	key, err := kvs.CreateCompositeKey("token-sdk.selector.reservations", []string{network, channel, namespace})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed creating reservations key of [%s:%s:%s]", network, channel, namespace)
+	}
+	return key, nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package selector
+
+import (
+	"context"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// DefaultPartitions is the default number of selections from the same wallet that run at once
+const DefaultPartitions = 4
+
+// DefaultQueueTimeout is the default time a selection waits for its turn
+const DefaultQueueTimeout = 30 * time.Second
+
+// fairQueue queues the selections from the same wallet and token type: at most partitions selections run at once,
+// the others wait in arrival order. Each running selection owns a partition of the tokens, it tries them first,
+// so that the concurrent selections do not collide over the same tokens.
+type fairQueue struct {
+	lock       sync.Mutex
+	partitions int
+	// timeout is the time a selection waits for its turn, forever if zero
+	timeout time.Duration
+	queues  map[string]*walletQueue
+}
+
+type walletQueue struct {
+	free    []int
+	waiting []chan int
+	users   int
+}
+
+func newFairQueue(partitions int, timeout time.Duration) *fairQueue {
+	if partitions <= 0 {
+		partitions = 1
+	}
+	return &fairQueue{partitions: partitions, timeout: timeout, queues: map[string]*walletQueue{}}
+}
+
+// acquire waits for the turn of a selection with the passed key and returns the partition the selection owns.
+// The partition must be released when the selection is done.
+// It fails if the passed context is done, or the timeout of the queue expires, before the turn comes.
+func (f *fairQueue) acquire(ctx context.Context, key string) (int, error) {
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+
+	f.lock.Lock()
+	q, ok := f.queues[key]
+	if !ok {
+		q = &walletQueue{}
+		for i := 0; i < f.partitions; i++ {
+			q.free = append(q.free, i)
+		}
+		f.queues[key] = q
+	}
+	q.users++
+	if len(q.free) != 0 && len(q.waiting) == 0 {
+		partition := q.free[0]
+		q.free = q.free[1:]
+		f.lock.Unlock()
+		return partition, nil
+	}
+	turn := make(chan int, 1)
+	q.waiting = append(q.waiting, turn)
+	f.lock.Unlock()
+
+	select {
+	case partition := <-turn:
+		return partition, nil
+	case <-ctx.Done():
+	}
+	f.lock.Lock()
+	for i, t := range q.waiting {
+		if t == turn {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			q.users--
+			if q.users == 0 {
+				delete(f.queues, key)
+			}
+			f.lock.Unlock()
+			return -1, errors.Wrapf(ctx.Err(), "failed waiting for the turn of the selection [%s]", key)
+		}
+	}
+	f.lock.Unlock()
+	// the turn came in the meantime, hand it over
+	f.release(key, <-turn)
+	return -1, errors.Wrapf(ctx.Err(), "failed waiting for the turn of the selection [%s]", key)
+}
+
+// release hands the passed partition to the first waiting selection with the passed key, if any
+func (f *fairQueue) release(key string, partition int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	q := f.queues[key]
+	q.users--
+	if len(q.waiting) != 0 {
+		turn := q.waiting[0]
+		q.waiting = q.waiting[1:]
+		turn <- partition
+		return
+	}
+	if q.users == 0 {
+		delete(f.queues, key)
+		return
+	}
+	q.free = append(q.free, partition)
+}
+
+// order returns the passed tokens with the ones of the passed partition first, the relative order is kept
+func (f *fairQueue) order(tokens []*token2.UnspentToken, partition int) []*token2.UnspentToken {
+	if f.partitions == 1 {
+		return tokens
+	}
+	res := make([]*token2.UnspentToken, 0, len(tokens))
+	var others []*token2.UnspentToken
+	for _, t := range tokens {
+		if f.partitionOf(t.Id) == partition {
+			res = append(res, t)
+		} else {
+			others = append(others, t)
+		}
+	}
+	return append(res, others...)
+}
+
+func (f *fairQueue) partitionOf(id *token2.Id) int {
+	h := fnv.New32a()
+	h.Write([]byte(id.TxId))
+	h.Write([]byte(strconv.FormatUint(uint64(id.Index), 10)))
+	return int(h.Sum32() % uint32(f.partitions))
+}
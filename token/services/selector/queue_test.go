@@ -0,0 +1,121 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package selector
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+func TestFairQueueTurns(t *testing.T) {
+	q := newFairQueue(2, 0)
+
+	p1, err := q.acquire(context.Background(), "alice/USD")
+	assert.NoError(t, err)
+	p2, err := q.acquire(context.Background(), "alice/USD")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int{0, 1}, []int{p1, p2})
+
+	// another wallet does not wait
+	_, err = q.acquire(context.Background(), "bob/USD")
+	assert.NoError(t, err)
+
+	// the next selections wait, in arrival order
+	turns := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			_, err := q.acquire(context.Background(), "alice/USD")
+			assert.NoError(t, err)
+			turns <- i
+		}(i)
+		waitFor(t, func() bool { return q.waiting("alice/USD") == i+1 })
+	}
+	select {
+	case <-turns:
+		t.Fatal("the selection did not wait for its turn")
+	case <-time.After(50 * time.Millisecond):
+	}
+	q.release("alice/USD", p1)
+	assert.Equal(t, 0, <-turns)
+	q.release("alice/USD", p2)
+	assert.Equal(t, 1, <-turns)
+}
+
+func TestFairQueueTimeout(t *testing.T) {
+	q := newFairQueue(1, 10*time.Millisecond)
+	p, err := q.acquire(context.Background(), "alice/USD")
+	assert.NoError(t, err)
+
+	// the timeout of the queue expires
+	_, err = q.acquire(context.Background(), "alice/USD")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), context.DeadlineExceeded.Error())
+	assert.Equal(t, 0, q.waiting("alice/USD"))
+
+	// the context of the selection is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = q.acquire(ctx, "alice/USD")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), context.Canceled.Error())
+	assert.Equal(t, 0, q.waiting("alice/USD"))
+
+	// the partition is still handed over once released, and the queue is cleaned up once unused
+	q.release("alice/USD", p)
+	p, err = q.acquire(context.Background(), "alice/USD")
+	assert.NoError(t, err)
+	q.release("alice/USD", p)
+	assert.Empty(t, q.queues)
+}
+
+func TestFairQueueOrder(t *testing.T) {
+	q := newFairQueue(2, 0)
+	var tokens []*token2.UnspentToken
+	for i := 0; i < 10; i++ {
+		tokens = append(tokens, &token2.UnspentToken{Id: &token2.Id{TxId: fmt.Sprintf("tx%d", i)}})
+	}
+	for partition := 0; partition < 2; partition++ {
+		ordered := q.order(tokens, partition)
+		assert.ElementsMatch(t, tokens, ordered)
+		// the tokens of the partition come first
+		own := true
+		for _, tok := range ordered {
+			if q.partitionOf(tok.Id) != partition {
+				own = false
+				continue
+			}
+			assert.True(t, own, "token [%s] of the partition after the others", tok.Id)
+		}
+	}
+	assert.Equal(t, tokens, newFairQueue(1, 0).order(tokens, 0))
+}
+
+// waiting returns the number of selections waiting for their turn with the passed key
+func (f *fairQueue) waiting(key string) int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	q, ok := f.queues[key]
+	if !ok {
+		return 0
+	}
+	return len(q.waiting)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met")
+}
@@ -0,0 +1,170 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package selector
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// ReservationStore persists the reservations, so that they survive a restart
+type ReservationStore interface {
+	Exists(id string) bool
+	Put(id string, state interface{}) error
+	Get(id string, state interface{}) error
+}
+
+// reservations tracks the reservations of a TMS. The tokens of a reservation are locked, by the locker,
+// with the reservation ID as transaction ID, a transaction that never commits.
+type reservations struct {
+	locker Locker
+	store  ReservationStore
+	key    string
+
+	lock   sync.Mutex
+	active map[string]*token.Reservation
+	timers map[string]*time.Timer
+}
+
+// newReservations returns the reservations of a TMS, persisted in the passed store under the passed key,
+// if the store is not nil. The persisted reservations that did not expire are locked again.
+func newReservations(locker Locker, store ReservationStore, key string) (*reservations, error) {
+	r := &reservations{
+		locker: locker,
+		store:  store,
+		key:    key,
+		active: map[string]*token.Reservation{},
+		timers: map[string]*time.Timer{},
+	}
+	if store == nil || !store.Exists(key) {
+		return r, nil
+	}
+
+	var persisted []*token.Reservation
+	if err := store.Get(key, &persisted); err != nil {
+		return nil, errors.WithMessagef(err, "failed loading reservations [%s]", key)
+	}
+	now := time.Now()
+	for _, reservation := range persisted {
+		if reservation.Expired(now) {
+			logger.Debugf("reservation [%s] expired at [%s], drop", reservation.ID, reservation.Expiry)
+			continue
+		}
+		for _, id := range reservation.TokenIDs {
+			if _, err := locker.Lock(id, reservation.ID); err != nil {
+				logger.Warnf("failed locking token [%s] of reservation [%s] again [%s]", id, reservation.ID, err)
+			}
+		}
+		r.track(reservation)
+	}
+	if err := r.persist(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *reservations) add(reservation *token.Reservation) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.track(reservation)
+	if err := r.persist(); err != nil {
+		r.untrack(reservation.ID)
+		return err
+	}
+	return nil
+}
+
+func (r *reservations) release(id string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.active[id]; !ok {
+		return errors.Errorf("reservation [%s] not found", id)
+	}
+	r.untrack(id)
+	r.locker.UnlockByTxID(id)
+	return r.persist()
+}
+
+// releaseSpent releases the reservations holding any of the passed tokens, the other tokens of those reservations
+// can be selected again
+func (r *reservations) releaseSpent(ids []*token2.Id) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	spent := map[string]bool{}
+	for _, id := range ids {
+		spent[id.String()] = true
+	}
+	released := false
+	for reservationID, reservation := range r.active {
+		for _, id := range reservation.TokenIDs {
+			if spent[id.String()] {
+				logger.Debugf("token [%s] of reservation [%s] spent, release", id, reservationID)
+				r.untrack(reservationID)
+				r.locker.UnlockByTxID(reservationID)
+				released = true
+				break
+			}
+		}
+	}
+	if !released {
+		return nil
+	}
+	return r.persist()
+}
+
+func (r *reservations) expire(id string) {
+	logger.Debugf("reservation [%s] expired, release", id)
+	if err := r.release(id); err != nil {
+		logger.Warnf("failed releasing expired reservation [%s]: [%s]", id, err)
+	}
+}
+
+// track must be called with the lock held, or before the reservations are shared
+func (r *reservations) track(reservation *token.Reservation) {
+	r.active[reservation.ID] = reservation
+	if !reservation.Expiry.IsZero() {
+		id := reservation.ID
+		r.timers[id] = time.AfterFunc(time.Until(reservation.Expiry), func() { r.expire(id) })
+	}
+}
+
+func (r *reservations) untrack(id string) {
+	delete(r.active, id)
+	if timer, ok := r.timers[id]; ok {
+		timer.Stop()
+		delete(r.timers, id)
+	}
+}
+
+func (r *reservations) persist() error {
+	if r.store == nil {
+		return nil
+	}
+	list := make([]*token.Reservation, 0, len(r.active))
+	for _, reservation := range r.active {
+		list = append(list, reservation)
+	}
+	if err := r.store.Put(r.key, list); err != nil {
+		return errors.WithMessagef(err, "failed storing reservations [%s]", r.key)
+	}
+	return nil
+}
+
+// newLockID returns a random identifier, with the passed prefix, to lock tokens outside of a transaction
+func newLockID(prefix string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrapf(err, "failed generating %s id", prefix)
+	}
+	return prefix + "-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,198 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package selector
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// syncLocker is a locker safe for concurrent use, the reservations expire in their own goroutines
+type syncLocker struct {
+	lock   sync.Mutex
+	locker locker
+}
+
+func newSyncLocker() *syncLocker {
+	return &syncLocker{locker: locker{}}
+}
+
+func (l *syncLocker) Lock(id *token2.Id, txID string) (string, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.locker.Lock(id, txID)
+}
+
+func (l *syncLocker) UnlockIDs(ids ...*token2.Id) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.locker.UnlockIDs(ids...)
+}
+
+func (l *syncLocker) UnlockByTxID(txID string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.locker.UnlockByTxID(txID)
+}
+
+func (l *syncLocker) holder(id *token2.Id) string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.locker[id.String()]
+}
+
+// reservationStore keeps the reservations in memory, serialized
+type reservationStore map[string][]byte
+
+func (s reservationStore) Exists(id string) bool {
+	_, ok := s[id]
+	return ok
+}
+
+func (s reservationStore) Put(id string, state interface{}) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	s[id] = raw
+	return nil
+}
+
+func (s reservationStore) Get(id string, state interface{}) error {
+	raw, ok := s[id]
+	if !ok {
+		return errors.Errorf("[%s] not found", id)
+	}
+	return json.Unmarshal(raw, state)
+}
+
+// failingStore fails loading the reservations
+type failingStore struct{ reservationStore }
+
+func (failingStore) Get(id string, state interface{}) error {
+	return errors.New("store unavailable")
+}
+
+func newReservationManager(t *testing.T, l Locker, store ReservationStore) *manager {
+	res, err := newReservations(l, store, "reservations")
+	assert.NoError(t, err)
+	qs := queryService{unspent("tx1", "alice"), unspent("tx2", "alice"), unspent("tx3", "alice")}
+	return newManager(l, nil, res, func() QueryService { return qs }, nil, 1, 0, false)
+}
+
+func TestReserveAndRelease(t *testing.T) {
+	l := newSyncLocker()
+	m := newReservationManager(t, l, nil)
+	alice := spender{"alice": true}
+
+	r1, err := m.Reserve(alice, "20", "ABC", 0)
+	assert.NoError(t, err)
+	assert.Len(t, r1.TokenIDs, 2)
+	assert.True(t, r1.Expiry.IsZero())
+	for _, id := range r1.TokenIDs {
+		assert.Equal(t, r1.ID, l.holder(id))
+	}
+
+	// the reserved tokens are not selected again
+	_, err = m.Reserve(alice, "20", "ABC", 0)
+	assert.Error(t, err)
+	r2, err := m.Reserve(alice, "10", "ABC", 0)
+	assert.NoError(t, err)
+	assert.NotEqual(t, r1.ID, r2.ID)
+
+	// once released, they are
+	assert.NoError(t, m.Release(r1.ID))
+	assert.Error(t, m.Release(r1.ID))
+	for _, id := range r1.TokenIDs {
+		assert.Empty(t, l.holder(id))
+	}
+	_, err = m.Reserve(alice, "20", "ABC", 0)
+	assert.NoError(t, err)
+}
+
+func TestReservationExpires(t *testing.T) {
+	l := newSyncLocker()
+	m := newReservationManager(t, l, nil)
+
+	r, err := m.Reserve(spender{"alice": true}, "10", "ABC", 10*time.Millisecond)
+	assert.NoError(t, err)
+	assert.False(t, r.Expiry.IsZero())
+	assert.Equal(t, r.ID, l.holder(r.TokenIDs[0]))
+	waitFor(t, func() bool { return l.holder(r.TokenIDs[0]) == "" })
+	assert.Error(t, m.Release(r.ID))
+}
+
+func TestReservationsReleasedWhenSpent(t *testing.T) {
+	l := newSyncLocker()
+	store := reservationStore{}
+	m := newReservationManager(t, l, store)
+	alice := spender{"alice": true}
+
+	r1, err := m.Reserve(alice, "20", "ABC", 0)
+	assert.NoError(t, err)
+	r2, err := m.Reserve(alice, "10", "ABC", 0)
+	assert.NoError(t, err)
+
+	// spending a token of a reservation releases the reservation, its other tokens are unlocked
+	assert.NoError(t, m.ReleaseSpent(r1.TokenIDs[0], &token2.Id{TxId: "other"}))
+	for _, id := range r1.TokenIDs {
+		assert.Empty(t, l.holder(id))
+	}
+	assert.Equal(t, r2.ID, l.holder(r2.TokenIDs[0]))
+	assert.Error(t, m.Release(r1.ID))
+
+	// the released reservation is not locked again after a restart
+	var persisted []*token.Reservation
+	assert.NoError(t, store.Get("reservations", &persisted))
+	assert.Len(t, persisted, 1)
+	assert.Equal(t, r2.ID, persisted[0].ID)
+
+	// tokens of no reservation are ignored
+	assert.NoError(t, m.ReleaseSpent(&token2.Id{TxId: "other"}))
+}
+
+func TestReservationsPersisted(t *testing.T) {
+	store := reservationStore{}
+	m := newReservationManager(t, newSyncLocker(), store)
+	alice := spender{"alice": true}
+
+	kept, err := m.Reserve(alice, "10", "ABC", 0)
+	assert.NoError(t, err)
+	expiring, err := m.Reserve(alice, "10", "ABC", time.Hour)
+	assert.NoError(t, err)
+
+	// after a restart, the reservations are locked again, but the expired ones
+	var persisted []*token.Reservation
+	assert.NoError(t, store.Get("reservations", &persisted))
+	for _, r := range persisted {
+		if r.ID == expiring.ID {
+			r.Expiry = time.Now().Add(-time.Minute)
+		}
+	}
+	assert.NoError(t, store.Put("reservations", persisted))
+	l := newSyncLocker()
+	restarted := newReservationManager(t, l, store)
+	assert.Equal(t, kept.ID, l.holder(kept.TokenIDs[0]))
+	assert.Empty(t, l.holder(expiring.TokenIDs[0]))
+	assert.NoError(t, store.Get("reservations", &persisted))
+	assert.Len(t, persisted, 1)
+
+	assert.NoError(t, restarted.Release(kept.ID))
+	assert.Empty(t, l.holder(kept.TokenIDs[0]))
+
+	// a store that cannot be read fails the loading
+	_, err = newReservations(newSyncLocker(), failingStore{store}, "reservations")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "store unavailable")
+}
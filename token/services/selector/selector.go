@@ -6,6 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package selector
 
 import (
+	"context"
 	"time"
 
 	"github.com/pkg/errors"
@@ -44,16 +45,18 @@ type selector struct {
 	queryService QueryService
 	certClient   CertClient
 	precision    uint64
+	queue        *fairQueue
 
 	numRetry             int
 	timeout              time.Duration
 	requestCertification bool
 }
 
-func newSelector(txID string, locker Locker, service QueryService, certClient CertClient, numRetry int, timeout time.Duration, requestCertification bool) *selector {
+func newSelector(txID string, locker Locker, queue *fairQueue, service QueryService, certClient CertClient, numRetry int, timeout time.Duration, requestCertification bool) *selector {
 	return &selector{
 		txID:                 txID,
 		locker:               locker,
+		queue:                queue,
 		queryService:         service,
 		certClient:           certClient,
 		precision:            keys.Precision,
@@ -78,6 +81,18 @@ func (s *selector) Select(ownerFilter token.OwnerFilter, q, tokenType string) ([
 		return nil, nil, errors.Wrap(err, "failed to convert quantity")
 	}
 
+	// the selections from the same wallet take turns, each trying first the tokens of its own partition
+	partition := -1
+	if wf, ok := ownerFilter.(token.WalletFilter); ok && s.queue != nil {
+		key := wf.ID() + "/" + tokenType
+		partition, err = s.queue.acquire(context.Background(), key)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer s.queue.release(key, partition)
+		logger.Debugf("token selection for [%s] got partition [%d]", key, partition)
+	}
+
 	i := 0
 	for {
 		logger.Debugf("start token selection, iteration [%d/%d]", i, s.numRetry)
@@ -95,7 +110,11 @@ func (s *selector) Select(ownerFilter token.OwnerFilter, q, tokenType string) ([
 		var toBeCertified []*token2.Id
 		var locked []*token2.Id
 
-		for _, t := range unspentTokens.Tokens {
+		tokens := unspentTokens.Tokens
+		if partition >= 0 {
+			tokens = s.queue.order(tokens, partition)
+		}
+		for _, t := range tokens {
 			q, err := token2.ToQuantity(t.Quantity, s.precision)
 			if err != nil {
 				s.locker.UnlockIDs(toBeSpent...)
@@ -115,6 +134,10 @@ func (s *selector) Select(ownerFilter token.OwnerFilter, q, tokenType string) ([
 				logger.Debugf("token [%s,%s,%v] owner does not belong to the passed wallet", q, tokenType, ownerFilter.Contains(t.Owner.Raw))
 				continue
 			}
+			if sf, ok := ownerFilter.(token.SpenderFilter); ok && !sf.CanSpend(t.Owner.Raw) {
+				logger.Debugf("token [%s,%s] cannot be spent from this node, skipping", q, tokenType)
+				continue
+			}
 
 			// lock the token
 			if _, err := s.locker.Lock(t.Id, s.txID); err != nil {
@@ -232,6 +255,46 @@ func (s *selector) Select(ownerFilter token.OwnerFilter, q, tokenType string) ([
 	}
 }
 
+// peek returns the unspent tokens a selection of the passed amount would pick, in the order listed by the passed
+// query service. It only reads, the tokens are not locked and they might be locked by other selections,
+// as well as not certified yet.
+func peek(qs QueryService, ownerFilter token.OwnerFilter, q, tokenType string) ([]*token2.Id, token2.Quantity, error) {
+	if ownerFilter == nil {
+		ownerFilter = &allOwners{}
+	}
+	target, err := token2.ToQuantity(q, keys.Precision)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to convert quantity")
+	}
+	unspentTokens, err := qs.ListUnspentTokens()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "token selection failed")
+	}
+	var ids []*token2.Id
+	sum := token2.NewZeroQuantity(keys.Precision)
+	for _, t := range unspentTokens.Tokens {
+		if t.Type != tokenType || !ownerFilter.Contains(t.Owner.Raw) {
+			continue
+		}
+		if sf, ok := ownerFilter.(token.SpenderFilter); ok && !sf.CanSpend(t.Owner.Raw) {
+			continue
+		}
+		q, err := token2.ToQuantity(t.Quantity, keys.Precision)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to convert quantity")
+		}
+		ids = append(ids, t.Id)
+		sum = sum.Add(q)
+		if target.Cmp(sum) <= 0 {
+			return ids, sum, nil
+		}
+	}
+	return nil, nil, errors.WithMessagef(
+		token.SelectorInsufficientFunds,
+		"token selection failed: insufficient funds, only [%s] tokens of type [%s] are available", sum, tokenType,
+	)
+}
+
 func (s *selector) concurrencyCheck(ids []*token2.Id) error {
 	_, err := s.queryService.GetTokens(ids...)
 	return err
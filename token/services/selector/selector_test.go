@@ -0,0 +1,116 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package selector
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// locker locks every token once
+type locker map[string]string
+
+func (l locker) Lock(id *token2.Id, txID string) (string, error) {
+	if other, ok := l[id.String()]; ok {
+		return other, errors.Errorf("token [%s] already locked by [%s]", id, other)
+	}
+	l[id.String()] = txID
+	return "", nil
+}
+
+func (l locker) UnlockIDs(ids ...*token2.Id) {
+	for _, id := range ids {
+		delete(l, id.String())
+	}
+}
+
+func (l locker) UnlockByTxID(txID string) {
+	for k, v := range l {
+		if v == txID {
+			delete(l, k)
+		}
+	}
+}
+
+// queryService lists the passed tokens as unspent
+type queryService []*token2.UnspentToken
+
+func (q queryService) ListUnspentTokens() (*token2.UnspentTokens, error) {
+	return &token2.UnspentTokens{Tokens: q}, nil
+}
+
+func (q queryService) GetTokens(inputs ...*token2.Id) ([]*token2.Token, error) {
+	return nil, nil
+}
+
+// spender contains every identity but can spend only the passed ones
+type spender map[string]bool
+
+func (spender) Contains(view.Identity) bool {
+	return true
+}
+
+func (s spender) CanSpend(identity view.Identity) bool {
+	return s[string(identity)]
+}
+
+func unspent(txID, owner string) *token2.UnspentToken {
+	return &token2.UnspentToken{
+		Id:       &token2.Id{TxId: txID},
+		Owner:    &token2.Owner{Raw: []byte(owner)},
+		Type:     "ABC",
+		Quantity: token2.NewQuantityFromUInt64(10).Hex(),
+	}
+}
+
+func TestSelectSkipsTokensNotSpendable(t *testing.T) {
+	qs := queryService{unspent("tx1", "imported"), unspent("tx2", "local"), unspent("tx3", "imported")}
+	s := newSelector("tx", locker{}, nil, qs, nil, 1, 0, false)
+
+	ids, sum, err := s.Select(spender{"local": true}, "10", "ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, []*token2.Id{{TxId: "tx2"}}, ids)
+	assert.Equal(t, "10", sum.Decimal())
+
+	// the tokens of the imported identities do not count as funds
+	_, _, err = newSelector("tx", locker{}, nil, qs, nil, 1, 0, false).Select(spender{"local": true}, "20", "ABC")
+	assert.Error(t, err)
+
+	// without the spender filter, every token contained is selected
+	ids, _, err = newSelector("tx", locker{}, nil, qs, nil, 1, 0, false).Select(nil, "30", "ABC")
+	assert.NoError(t, err)
+	assert.Len(t, ids, 3)
+}
+
+func TestPeekDoesNotLock(t *testing.T) {
+	qs := queryService{unspent("tx1", "imported"), unspent("tx2", "local"), unspent("tx3", "local")}
+	l := locker{}
+	m := newManager(l, nil, nil, func() QueryService { return qs }, nil, 1, 0, false)
+
+	ids, sum, err := m.Peek(spender{"local": true}, "15", "ABC")
+	assert.NoError(t, err)
+	assert.Equal(t, []*token2.Id{{TxId: "tx2"}, {TxId: "tx3"}}, ids)
+	assert.Equal(t, "20", sum.Decimal())
+	assert.Empty(t, l)
+
+	// the tokens locked by a selection are peeked all the same, and stay locked by it
+	_, err = l.Lock(&token2.Id{TxId: "tx2"}, "transfer")
+	assert.NoError(t, err)
+	ids, _, err = m.Peek(spender{"local": true}, "15", "ABC")
+	assert.NoError(t, err)
+	assert.Len(t, ids, 2)
+	assert.Equal(t, locker{(&token2.Id{TxId: "tx2"}).String(): "transfer"}, l)
+
+	_, _, err = m.Peek(spender{"local": true}, "30", "ABC")
+	assert.Error(t, err)
+	_, _, err = m.Peek(nil, "10", "XYZ")
+	assert.Error(t, err)
+}
@@ -42,7 +42,7 @@ func (r *RegisterAuditorView) Call(context view.Context) (interface{}, error) {
 			token.WithChannel(r.Channel),
 			token.WithNamespace(r.Namespace),
 		)
-		_, err := context.RunView(chaincode.NewInvokeView(
+		res, err := context.RunView(chaincode.NewInvokeView(
 			tms.Namespace(), AddAuditorFunction, r.Id.Bytes(),
 		).WithNetwork(tms.Network()).WithChannel(tms.Channel()))
 		if err != nil {
@@ -53,7 +53,9 @@ func (r *RegisterAuditorView) Call(context view.Context) (interface{}, error) {
 			logger.Errorf("failed recording auditor has been registered to the chaincode [%s]", err)
 		}
 
-		if err := tms.PublicParametersManager().ForceFetch(); err != nil {
+		// the chaincode returns the new public parameters, pin them so that the cache accepts the update
+		raw, _ := res.([]byte)
+		if err := tms.UpdatePublicParameters(raw); err != nil {
 			logger.Warnf("failed fetching parameters [%s]", err)
 		}
 	}
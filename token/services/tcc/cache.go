@@ -0,0 +1,182 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package tcc
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+const (
+	DefaultValidationCacheSize = 1000
+	DefaultValidationCacheTTL  = time.Minute
+)
+
+// ValidationCache is a size and time bounded LRU cache of the actions obtained by validating token requests.
+// Entries are keyed by the digest of the public parameters and the hash of the token request bound to its
+// transaction id and to the validation options, therefore a cached request is never accepted under a different
+// transaction id, public parameters, transaction time, ledger height, or request limits.
+// Each entry records the ledger reads performed during the validation: an entry is used only if
+// those reads still return the same values, and the reads are replayed to keep the read set of the endorsement unchanged.
+type ValidationCache struct {
+	lock    sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	lru     *list.List
+	now     func() time.Time
+}
+
+type ledgerRead struct {
+	Key   string
+	Value []byte
+}
+
+type cacheEntry struct {
+	key     string
+	actions []interface{}
+	reads   []ledgerRead
+	expiry  time.Time
+}
+
+// NewValidationCache returns a new cache holding at most size entries, each valid for ttl.
+// Non-positive values select DefaultValidationCacheSize and DefaultValidationCacheTTL.
+func NewValidationCache(size int, ttl time.Duration) *ValidationCache {
+	if size <= 0 {
+		size = DefaultValidationCacheSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultValidationCacheTTL
+	}
+	return &ValidationCache{
+		size:    size,
+		ttl:     ttl,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+// Len returns the number of entries in the cache, including the expired ones not yet evicted
+func (c *ValidationCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.Len()
+}
+
+// Get returns the actions cached for the passed key, if the entry has not expired and the
+// ledger reads recorded with it return the same values on the passed ledger
+func (c *ValidationCache) Get(key string, ledger token.Ledger) ([]interface{}, bool) {
+	c.lock.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		c.lock.Unlock()
+		return nil, false
+	}
+	entry := e.Value.(*cacheEntry)
+	if c.now().After(entry.expiry) {
+		c.remove(e)
+		c.lock.Unlock()
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	c.lock.Unlock()
+
+	for _, read := range entry.reads {
+		v, err := ledger.GetState(read.Key)
+		if err != nil || !bytes.Equal(v, read.Value) {
+			logger.Debugf("cached validation of [%x] is stale, key [%s] changed", key, read.Key)
+			return nil, false
+		}
+	}
+	return entry.actions, true
+}
+
+// Add caches the passed actions, and the ledger reads their validation depended on, under the passed key
+func (c *ValidationCache) Add(key string, actions []interface{}, reads []ledgerRead) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{
+		key:     key,
+		actions: actions,
+		reads:   reads,
+		expiry:  c.now().Add(c.ttl),
+	})
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *ValidationCache) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*cacheEntry).key)
+}
+
+// validationCacheKey returns the cache key of the passed token request, bound to the passed transaction id and
+// to the time-dependent validation options, under the public parameters with the passed digest.
+// The validation hooks are not part of the key, they are fixed by the chaincode.
+func validationCacheKey(ppDigest []byte, txID string, raw []byte, opts ...token.ValidationOption) (string, error) {
+	options, err := tokenapi.CompileValidationOptions(opts...)
+	if err != nil {
+		return "", errors.Wrap(err, "failed compiling validation options")
+	}
+	h := sha256.New()
+	writeField := func(b []byte) {
+		var l [8]byte
+		binary.BigEndian.PutUint64(l[:], uint64(len(b)))
+		h.Write(l[:])
+		h.Write(b)
+	}
+	writeUint := func(v uint64) {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], v)
+		writeField(b[:])
+	}
+	writeField(raw)
+	writeField([]byte(txID))
+	if options.TxTime.IsZero() {
+		writeField(nil)
+	} else {
+		writeUint(uint64(options.TxTime.UnixNano()))
+	}
+	writeUint(options.Height)
+	if options.Limits == nil {
+		writeField(nil)
+	} else {
+		for _, limit := range []int{options.Limits.MaxSize, options.Limits.MaxActions, options.Limits.MaxInputs, options.Limits.MaxOutputs} {
+			writeUint(uint64(limit))
+		}
+	}
+	return string(ppDigest) + string(h.Sum(nil)), nil
+}
+
+// recordingLedger records the reads performed on the wrapped ledger
+type recordingLedger struct {
+	ledger token.Ledger
+	reads  []ledgerRead
+}
+
+func (r *recordingLedger) GetState(key string) ([]byte, error) {
+	v, err := r.ledger.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	r.reads = append(r.reads, ledgerRead{Key: key, Value: v})
+	return v, nil
+}
@@ -53,7 +53,7 @@ func (r *RegisterCertifierView) Call(context view.Context) (interface{}, error)
 
 	if !set {
 		logger.Debugf("register certifier [%s]", r.Id.String())
-		_, err := context.RunView(chaincode.NewInvokeView(
+		res, err := context.RunView(chaincode.NewInvokeView(
 			tms.Namespace(), AddCertifierFunction, r.Id.Bytes(),
 		).WithNetwork(tms.Network()).WithChannel(tms.Channel()).WithInvokerIdentity(
 			fabric.GetFabricNetworkService(context, tms.Network()).IdentityProvider().DefaultIdentity(),
@@ -64,6 +64,11 @@ func (r *RegisterCertifierView) Call(context view.Context) (interface{}, error)
 		if err := kvs.GetService(context).Put(key, true); err != nil {
 			logger.Errorf("failed recording auditor has been registered to the chaincode [%s]", err)
 		}
+		// the chaincode returns the new public parameters, pin them so that the cache accepts the update
+		raw, _ := res.([]byte)
+		if err := tms.UpdatePublicParameters(raw); err != nil {
+			return nil, errors.WithMessagef(err, "failed fetching parameters")
+		}
 	}
 	return nil, nil
 }
@@ -0,0 +1,36 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package tcc
+
+import (
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
+)
+
+// NewMSPKeyPolicy returns a key policy setting, on each token, a state-based endorsement policy requiring the peers
+// of all the passed MSPs, the organizations running the token chaincode for instance. Then a token cannot be
+// modified, or spent, by a transaction not endorsed by all of them, whatever the chaincode endorsement policy.
+func NewMSPKeyPolicy(mspIDs ...string) (translator.KeyPolicy, error) {
+	if len(mspIDs) == 0 {
+		return nil, errors.New("no msp passed")
+	}
+	ep, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating state-based endorsement policy")
+	}
+	if err := ep.AddOrgs(statebased.RoleTypePeer, mspIDs...); err != nil {
+		return nil, errors.Wrap(err, "failed adding msps to state-based endorsement policy")
+	}
+	policy, err := ep.Policy()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed serializing state-based endorsement policy")
+	}
+	return func(key string, output []byte) ([]byte, error) {
+		return policy, nil
+	}, nil
+}
@@ -0,0 +1,29 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package tcc
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+)
+
+// LedgerHeight returns the height of the ledger of the channel of the passed stub, as known to the endorsing peer.
+// It queries the chain info from the query system chaincode, a chaincode-to-chaincode call the peers allow.
+// The endorsing peers may be at different heights, the requests validated close to a height boundary, a time lock
+// or the cutover of a driver migration, may then get different endorsements.
+func LedgerHeight(stub shim.ChaincodeStubInterface) (uint64, error) {
+	response := stub.InvokeChaincode("qscc", [][]byte{[]byte("GetChainInfo"), []byte(stub.GetChannelID())}, "")
+	if response.Status != shim.OK {
+		return 0, errors.Errorf("failed querying chain info: [%d] %s", response.Status, response.Message)
+	}
+	info := &common.BlockchainInfo{}
+	if err := proto.Unmarshal(response.Payload, info); err != nil {
+		return 0, errors.Wrap(err, "failed unmarshalling chain info")
+	}
+	return info.Height, nil
+}
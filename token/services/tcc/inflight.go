@@ -0,0 +1,136 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package tcc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+)
+
+const DefaultInFlightLease = 2 * time.Second
+
+// InFlightTracker tracks the tokens spent by the token requests this peer endorsed recently, whose transactions
+// might not be committed yet. The translator checks the double spending against the committed state only, then two
+// transactions of the same block spending the same token are both endorsed, and the second fails at commit.
+// The tracker rejects the second already at endorsement. A spent token is tracked for a short lease: if the
+// transaction is not submitted, or gets invalidated, the token can be spent again once the lease expires.
+// The tracker is local to each peer: a conflict fails the endorsement of the whole transaction.
+// The tokens spent twice by the sub-requests of a batch are detected from the batch itself, see spendsOnce.
+type InFlightTracker struct {
+	lock      sync.Mutex
+	lease     time.Duration
+	spent     map[string]*inFlightSpend
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type inFlightSpend struct {
+	txID   string
+	expiry time.Time
+}
+
+// NewInFlightTracker returns a new tracker leasing the spent tokens for the passed duration,
+// DefaultInFlightLease if not positive
+func NewInFlightTracker(lease time.Duration) *InFlightTracker {
+	if lease <= 0 {
+		lease = DefaultInFlightLease
+	}
+	return &InFlightTracker{
+		lease: lease,
+		spent: map[string]*inFlightSpend{},
+		now:   time.Now,
+	}
+}
+
+// Acquire marks the passed inputs as spent by the passed transaction. It fails, marking none, if any of them
+// is spent by another transaction whose lease has not expired. The same transaction can acquire its inputs again,
+// when its endorsement is retried, renewing their lease.
+func (t *InFlightTracker) Acquire(txID string, inputs []string) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := t.now()
+	t.sweep(now)
+	for _, input := range inputs {
+		if s, ok := t.spent[input]; ok && s.txID != txID && now.Before(s.expiry) {
+			return errors2.Errorf(errors2.DoubleSpend, "input [%s] is being spent by transaction [%s]", input, s.txID)
+		}
+	}
+	for _, input := range inputs {
+		t.spent[input] = &inFlightSpend{txID: txID, expiry: now.Add(t.lease)}
+	}
+	return nil
+}
+
+// Release unmarks the inputs spent by the passed transaction, if its endorsement failed
+func (t *InFlightTracker) Release(txID string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for input, s := range t.spent {
+		if s.txID == txID {
+			delete(t.spent, input)
+		}
+	}
+}
+
+// Len returns the number of tracked inputs, including the expired ones not yet evicted
+func (t *InFlightTracker) Len() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return len(t.spent)
+}
+
+// sweep evicts the expired inputs, at most once per lease
+func (t *InFlightTracker) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < t.lease {
+		return
+	}
+	t.lastSweep = now
+	for input, s := range t.spent {
+		if !now.Before(s.expiry) {
+			delete(t.spent, input)
+		}
+	}
+}
+
+// inputsOf returns the ledger keys of the inputs spent by the passed actions
+func inputsOf(actions []interface{}) ([]string, error) {
+	var inputs []string
+	for _, action := range actions {
+		transfer, ok := action.(interface{ GetInputs() ([]string, error) })
+		if !ok {
+			continue
+		}
+		ins, err := transfer.GetInputs()
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed getting inputs")
+		}
+		inputs = append(inputs, ins...)
+	}
+	return inputs, nil
+}
+
+// spendsOnce checks that the inputs spent by the passed actions of the sub-request with the passed binding
+// are not spent by a previous sub-request of the same batch, and marks them as spent by it
+func spendsOnce(actions []interface{}, binding string, spentBy map[string]string) error {
+	inputs, err := inputsOf(actions)
+	if err != nil {
+		return err
+	}
+	for _, input := range inputs {
+		if other, ok := spentBy[input]; ok {
+			return errors2.Errorf(errors2.DoubleSpend, "input [%s] already spent by sub-request [%s]", input, other)
+		}
+	}
+	for _, input := range inputs {
+		spentBy[input] = binding
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package tcc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric/services/chaincode"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
+)
+
+// AdminPolicy returns no error if the creator of the transaction of the passed stub is an administrator of the chaincode
+type AdminPolicy func(stub shim.ChaincodeStubInterface) error
+
+// adminRole is the role of the administrators, as carried by the OU of their certificate when the node OUs are
+// enabled, or by the hf.Type attribute of the certificates issued by the Fabric CA
+const adminRole = "admin"
+
+// NewMSPAdminPolicy returns an AdminPolicy accepting the creators that belong to one of the passed MSPs with the
+// admin role. The clients and the peers of the passed MSPs are refused.
+func NewMSPAdminPolicy(mspIDs ...string) AdminPolicy {
+	return func(stub shim.ChaincodeStubInterface) error {
+		mspID, err := cid.GetMSPID(stub)
+		if err != nil {
+			return errors.Wrap(err, "failed getting the msp of the creator")
+		}
+		listed := false
+		for _, id := range mspIDs {
+			if id == mspID {
+				listed = true
+				break
+			}
+		}
+		if !listed {
+			return errors2.Errorf(errors2.Unauthorized, "creator of msp [%s] is not an administrator", mspID)
+		}
+		admin, err := hasAdminRole(stub)
+		if err != nil {
+			return err
+		}
+		if !admin {
+			return errors2.Errorf(errors2.Unauthorized, "creator of msp [%s] does not have the admin role", mspID)
+		}
+		return nil
+	}
+}
+
+// hasAdminRole returns true if the certificate of the creator of the transaction of the passed stub carries the
+// admin role, in its OUs or in its hf.Type attribute
+func hasAdminRole(stub shim.ChaincodeStubInterface) (bool, error) {
+	cert, err := cid.GetX509Certificate(stub)
+	if err != nil {
+		return false, errors.Wrap(err, "failed getting the certificate of the creator")
+	}
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		if strings.EqualFold(ou, adminRole) {
+			return true, nil
+		}
+	}
+	role, found, err := cid.GetAttributeValue(stub, "hf.Type")
+	if err != nil {
+		return false, errors.Wrap(err, "failed getting the role of the creator")
+	}
+	return found && role == adminRole, nil
+}
+
+// setIssuerPolicy stores the issuer policy of a token type, replacing the current one, if any.
+// A policy without issuers removes the policy of its type, whose tokens can then be issued by any issuer.
+func (cc *TokenChaincode) setIssuerPolicy(raw []byte, stub shim.ChaincodeStubInterface) pb.Response {
+	if cc.AdminPolicy == nil {
+		return shim.Error("issuer policies cannot be managed, no admin policy set")
+	}
+	if err := cc.AdminPolicy(stub); err != nil {
+		return shim.Error(fmt.Sprintf("not authorized to set issuer policies: [%s]", err))
+	}
+
+	policy := &translator.IssuerPolicy{}
+	if err := policy.FromBytes(raw); err != nil {
+		return shim.Error(fmt.Sprintf("failed unmarshalling issuer policy: [%s]", err))
+	}
+	for i, issuer := range policy.Issuers {
+		if issuer.IsNone() {
+			return shim.Error(fmt.Sprintf("invalid issuer policy for [%s], issuer [%d] not specified", policy.Type, i))
+		}
+	}
+	if err := translator.WriteIssuerPolicy(&rwsWrapper{stub: stub}, "", cc.keyScheme(), policy); err != nil {
+		return shim.Error(fmt.Sprintf("failed writing issuer policy for [%s]: [%s]", policy.Type, err))
+	}
+	logger.Infof("issuer policy for [%s] set, [%d] issuers", policy.Type, len(policy.Issuers))
+	return shim.Success(nil)
+}
+
+// queryIssuerPolicy returns the issuer policy of the passed token type, empty if there is none
+func (cc *TokenChaincode) queryIssuerPolicy(tokenType string, stub shim.ChaincodeStubInterface) pb.Response {
+	policy, err := translator.ReadIssuerPolicy(&rwsWrapper{stub: stub}, "", cc.keyScheme(), tokenType)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if policy == nil {
+		return shim.Success(nil)
+	}
+	raw, err := policy.Bytes()
+	if err != nil {
+		return shim.Error(fmt.Sprintf("failed marshalling issuer policy for [%s]: [%s]", tokenType, err))
+	}
+	return shim.Success(raw)
+}
+
+// querySupply returns the supply of the passed token type: the value issued and the value redeemed, see token.Supply
+func (cc *TokenChaincode) querySupply(tokenType string, stub shim.ChaincodeStubInterface) pb.Response {
+	supply, err := translator.ReadSupply(&rwsWrapper{stub: stub}, "", cc.keyScheme(), tokenType)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	raw, err := supply.Bytes()
+	if err != nil {
+		return shim.Error(fmt.Sprintf("failed marshalling supply of [%s]: [%s]", tokenType, err))
+	}
+	return shim.Success(raw)
+}
+
+func (cc *TokenChaincode) keyScheme() *keys.Scheme {
+	if cc.KeyScheme == nil {
+		return keys.Default
+	}
+	return cc.KeyScheme
+}
+
+type SetIssuerPolicyView struct {
+	Network   string
+	Channel   string
+	Namespace string
+	Policy    *translator.IssuerPolicy
+}
+
+// NewSetIssuerPolicyView returns a view setting the passed issuer policy at the token chaincode,
+// the invoker must satisfy the admin policy of the chaincode
+func NewSetIssuerPolicyView(network string, channel string, namespace string, policy *translator.IssuerPolicy) *SetIssuerPolicyView {
+	return &SetIssuerPolicyView{Network: network, Channel: channel, Namespace: namespace, Policy: policy}
+}
+
+func (s *SetIssuerPolicyView) Call(context view.Context) (interface{}, error) {
+	tms := token.GetManagementService(
+		context,
+		token.WithNetwork(s.Network),
+		token.WithChannel(s.Channel),
+		token.WithNamespace(s.Namespace),
+	)
+	raw, err := s.Policy.Bytes()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed marshalling issuer policy for [%s]", s.Policy.Type)
+	}
+	_, err = context.RunView(chaincode.NewInvokeView(
+		tms.Namespace(), SetIssuerPolicyFunction, raw,
+	).WithNetwork(tms.Network()).WithChannel(tms.Channel()).WithInvokerIdentity(
+		fabric.GetFabricNetworkService(context, tms.Network()).IdentityProvider().DefaultIdentity(),
+	))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed setting issuer policy for [%s]", s.Policy.Type)
+	}
+	return nil, nil
+}
+
+type QuerySupplyView struct {
+	Network   string
+	Channel   string
+	Namespace string
+	TokenType string
+}
+
+// NewQuerySupplyView returns a view querying the token chaincode for the supply of the passed token type,
+// the view returns a *token.Supply
+func NewQuerySupplyView(network string, channel string, namespace string, tokenType string) *QuerySupplyView {
+	return &QuerySupplyView{Network: network, Channel: channel, Namespace: namespace, TokenType: tokenType}
+}
+
+func (q *QuerySupplyView) Call(context view.Context) (interface{}, error) {
+	tms := token.GetManagementService(
+		context,
+		token.WithNetwork(q.Network),
+		token.WithChannel(q.Channel),
+		token.WithNamespace(q.Namespace),
+	)
+	payloadBoxed, err := context.RunView(chaincode.NewQueryView(
+		tms.Namespace(), QuerySupplyFunction, q.TokenType,
+	).WithNetwork(tms.Network()).WithChannel(tms.Channel()))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed querying supply of [%s]", q.TokenType)
+	}
+	raw, ok := payloadBoxed.([]byte)
+	if !ok {
+		return nil, errors.Errorf("expected []byte from TCC, got [%T]", payloadBoxed)
+	}
+	supply := &token.Supply{}
+	if err := supply.FromBytes(raw); err != nil {
+		return nil, errors.Wrapf(err, "failed unmarshalling supply of [%s]", q.TokenType)
+	}
+	return supply, nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package tcc_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	chaincode2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc/mock"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// attrsOID is the OID of the extension carrying the attributes of the certificates issued by the Fabric CA
+var attrsOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// creator returns the serialized identity of the passed MSP, whose certificate has the passed OUs and, if not
+// empty, the passed Fabric CA attributes
+func creator(mspID string, ous []string, attrs string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "alice", OrganizationalUnit: ous},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if len(attrs) != 0 {
+		template.ExtraExtensions = []pkix.Extension{{Id: attrsOID, Value: []byte(attrs)}}
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	id, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   mspID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}),
+	})
+	Expect(err).NotTo(HaveOccurred())
+	return id
+}
+
+var _ = Describe("NewMSPAdminPolicy", func() {
+	var (
+		fakestub *mock.ChaincodeStubInterface
+		policy   chaincode2.AdminPolicy
+	)
+	BeforeEach(func() {
+		fakestub = &mock.ChaincodeStubInterface{}
+		policy = chaincode2.NewMSPAdminPolicy("Org1MSP", "Org2MSP")
+	})
+
+	It("accepts the admins of the listed MSPs", func() {
+		fakestub.GetCreatorReturns(creator("Org2MSP", []string{"admin"}, ""), nil)
+		Expect(policy(fakestub)).To(Succeed())
+	})
+	It("accepts the admins by the role assigned by the Fabric CA", func() {
+		fakestub.GetCreatorReturns(creator("Org1MSP", nil, `{"attrs":{"hf.Type":"admin"}}`), nil)
+		Expect(policy(fakestub)).To(Succeed())
+	})
+	It("refuses the other members of the listed MSPs", func() {
+		fakestub.GetCreatorReturns(creator("Org1MSP", []string{"client"}, `{"attrs":{"hf.Type":"client"}}`), nil)
+		err := policy(fakestub)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("does not have the admin role"))
+		Expect(errors2.HasCode(err, errors2.Unauthorized)).To(BeTrue())
+
+		fakestub.GetCreatorReturns(creator("Org1MSP", []string{"peer"}, ""), nil)
+		Expect(policy(fakestub)).NotTo(Succeed())
+	})
+	It("refuses the admins of the other MSPs", func() {
+		fakestub.GetCreatorReturns(creator("Org3MSP", []string{"admin"}, ""), nil)
+		err := policy(fakestub)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("is not an administrator"))
+		Expect(errors2.HasCode(err, errors2.Unauthorized)).To(BeTrue())
+	})
+})
@@ -0,0 +1,99 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package tcc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/pkg/errors"
+)
+
+// LogSubsystems maps the subsystems whose log level can be set at runtime to their loggers
+var LogSubsystems = map[string][]string{
+	"tcc":        {"token-sdk.tcc"},
+	"validator":  {"token-sdk.zkatdlog", "token-sdk.fabtoken"},
+	"translator": {"token-sdk.vault.translator"},
+}
+
+// LogLevelRequest asks to set the log level of a subsystem, see LogSubsystems
+type LogLevelRequest struct {
+	Subsystem string
+	Level     string
+}
+
+func (r *LogLevelRequest) Bytes() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func (r *LogLevelRequest) FromBytes(raw []byte) error {
+	return json.Unmarshal(raw, r)
+}
+
+// SetLogLevel sets the log level of the loggers of the passed subsystem, leaving the levels of the other loggers
+// unchanged, and returns the resulting logging specification
+func SetLogLevel(subsystem string, level string) (string, error) {
+	loggers, ok := LogSubsystems[subsystem]
+	if !ok {
+		return "", errors.Errorf("unknown subsystem [%s], expected one of %v", subsystem, logSubsystemNames())
+	}
+	if !flogging.IsValidLevel(level) {
+		return "", errors.Errorf("invalid log level [%s]", level)
+	}
+	replaced := map[string]bool{}
+	for _, l := range loggers {
+		replaced[l] = true
+	}
+	// the active spec is normalized, one logger per field and the default level last
+	var fields []string
+	for _, field := range strings.Split(flogging.Global.Spec(), ":") {
+		if i := strings.Index(field, "="); i >= 0 && replaced[field[:i]] {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	spec := strings.Join(loggers, ",") + "=" + strings.ToLower(level) + ":" + strings.Join(fields, ":")
+	if err := flogging.Global.ActivateSpec(spec); err != nil {
+		return "", errors.Wrapf(err, "failed activating logging spec [%s]", spec)
+	}
+	return flogging.Global.Spec(), nil
+}
+
+func logSubsystemNames() []string {
+	var names []string
+	for name := range LogSubsystems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setLogLevel sets the log level of a subsystem of this chaincode instance, it does not write to the ledger.
+// The level is in memory, it applies to the peers the proposal is sent to and until they restart the chaincode.
+func (cc *TokenChaincode) setLogLevel(raw []byte, stub shim.ChaincodeStubInterface) pb.Response {
+	if cc.AdminPolicy == nil {
+		return shim.Error("log levels cannot be managed, no admin policy set")
+	}
+	if err := cc.AdminPolicy(stub); err != nil {
+		return shim.Error(fmt.Sprintf("not authorized to set log levels: [%s]", err))
+	}
+
+	request := &LogLevelRequest{}
+	if err := request.FromBytes(raw); err != nil {
+		return shim.Error(fmt.Sprintf("failed unmarshalling log level request: [%s]", err))
+	}
+	spec, err := SetLogLevel(request.Subsystem, request.Level)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	logger.Infof("log level of [%s] set to [%s], logging spec [%s]", request.Subsystem, request.Level, spec)
+	return shim.Success([]byte(spec))
+}
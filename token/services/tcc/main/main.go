@@ -7,14 +7,23 @@ package main
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken/driver"
 	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh/driver"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc/remote"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
 )
 
 type serverConfig struct {
@@ -23,6 +32,201 @@ type serverConfig struct {
 	LogLevel  string
 }
 
+// validationCache returns the validation cache configured by the environment, nil if disabled
+func validationCache() *tcc.ValidationCache {
+	sizeEnv := os.Getenv("CHAINCODE_VALIDATION_CACHE_SIZE")
+	if sizeEnv == "" {
+		return nil
+	}
+	size, err := strconv.Atoi(sizeEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid validation cache size [%s], cache disabled: %s\n", sizeEnv, err)
+		return nil
+	}
+	var ttl time.Duration
+	if ttlEnv := os.Getenv("CHAINCODE_VALIDATION_CACHE_TTL"); ttlEnv != "" {
+		ttl, err = time.ParseDuration(ttlEnv)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid validation cache ttl [%s], using default: %s\n", ttlEnv, err)
+		}
+	}
+	return tcc.NewValidationCache(size, ttl)
+}
+
+// inFlightTracker returns the tracker of the in-flight token requests configured by the environment, nil if disabled
+func inFlightTracker() *tcc.InFlightTracker {
+	leaseEnv := os.Getenv("CHAINCODE_INFLIGHT_LEASE")
+	if leaseEnv == "" {
+		return nil
+	}
+	lease, err := time.ParseDuration(leaseEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid in-flight lease [%s], using default: %s\n", leaseEnv, err)
+	}
+	return tcc.NewInFlightTracker(lease)
+}
+
+// maxClockSkew returns the bound on the deviation of the transaction timestamps configured by the environment,
+// zero to use the default
+func maxClockSkew() time.Duration {
+	env := os.Getenv("CHAINCODE_MAX_CLOCK_SKEW")
+	if env == "" {
+		return 0
+	}
+	skew, err := time.ParseDuration(env)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid max clock skew [%s], using default: %s\n", env, err)
+		return 0
+	}
+	return skew
+}
+
+// keyScheme returns the key scheme of the application configured by the environment, nil if none is set
+func keyScheme() *keys.Scheme {
+	application := os.Getenv("CHAINCODE_KEY_APPLICATION")
+	if application == "" {
+		return nil
+	}
+	scheme, err := keys.NewScheme(application)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid key application [%s]: %s\n", application, err)
+		os.Exit(2)
+	}
+	return scheme
+}
+
+// migrateFrom returns the key scheme the keys can be migrated from, configured by the environment, nil if none is set.
+// An empty application, set explicitly, is the default key scheme.
+func migrateFrom() *keys.Scheme {
+	application, ok := os.LookupEnv("CHAINCODE_MIGRATE_FROM_KEY_APPLICATION")
+	if !ok {
+		return nil
+	}
+	scheme, err := keys.NewScheme(application)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid key application to migrate from [%s]: %s\n", application, err)
+		os.Exit(2)
+	}
+	return scheme
+}
+
+// rwsetMetrics returns the metrics of the accesses to the ledger, pushed to the statsd server configured by
+// the environment, nil if none is set
+func rwsetMetrics() *translator.Metrics {
+	address := os.Getenv("CHAINCODE_METRICS_STATSD_ADDRESS")
+	if address == "" {
+		return nil
+	}
+	provider, err := tcc.NewStatsdProvider(address, os.Getenv("CHAINCODE_METRICS_STATSD_PREFIX"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid statsd address [%s]: %s\n", address, err)
+		os.Exit(2)
+	}
+	return translator.NewMetrics(provider)
+}
+
+// adminPolicy returns the admin policy configured by the environment, nil if none is set
+func adminPolicy() tcc.AdminPolicy {
+	env := os.Getenv("CHAINCODE_ADMIN_MSPIDS")
+	if env == "" {
+		return nil
+	}
+	return tcc.NewMSPAdminPolicy(strings.Split(env, ",")...)
+}
+
+// keyPolicy returns the state-based endorsement policy of the tokens configured by the environment, nil if none is set
+func keyPolicy() translator.KeyPolicy {
+	env := os.Getenv("CHAINCODE_KEY_POLICY_MSPIDS")
+	if env == "" {
+		return nil
+	}
+	policy, err := tcc.NewMSPKeyPolicy(strings.Split(env, ",")...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid key policy msps [%s]: %s\n", env, err)
+		os.Exit(2)
+	}
+	return policy
+}
+
+// requestLimits returns the token request limits configured by the environment, nil if none is set
+func requestLimits() *token.RequestLimits {
+	limits := &token.RequestLimits{}
+	set := false
+	for env, limit := range map[string]*int{
+		"CHAINCODE_MAX_REQUEST_SIZE":    &limits.MaxSize,
+		"CHAINCODE_MAX_REQUEST_ACTIONS": &limits.MaxActions,
+		"CHAINCODE_MAX_ACTION_INPUTS":   &limits.MaxInputs,
+		"CHAINCODE_MAX_ACTION_OUTPUTS":  &limits.MaxOutputs,
+	} {
+		v := os.Getenv(env)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			fmt.Fprintf(os.Stderr, "invalid %s [%s], limit disabled\n", env, v)
+			continue
+		}
+		*limit = n
+		set = true
+	}
+	if !set {
+		return nil
+	}
+	return limits
+}
+
+// validationServiceTLSConfig returns the mutual TLS configuration of the connection to the validation service.
+// The service must present a certificate, issued by one of the CAs in CHAINCODE_VALIDATION_SERVICE_TLS_CAS,
+// for CHAINCODE_VALIDATION_SERVICE_TLS_SERVER_NAME, the host of the address if not set.
+func validationServiceTLSConfig(address string) remote.TLSConfig {
+	serverName := os.Getenv("CHAINCODE_VALIDATION_SERVICE_TLS_SERVER_NAME")
+	if serverName == "" {
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			serverName = host
+		}
+	}
+	var cas []string
+	if env := os.Getenv("CHAINCODE_VALIDATION_SERVICE_TLS_CAS"); env != "" {
+		cas = strings.Split(env, ",")
+	}
+	return remote.TLSConfig{
+		CertFile:  os.Getenv("CHAINCODE_VALIDATION_SERVICE_TLS_CERT"),
+		KeyFile:   os.Getenv("CHAINCODE_VALIDATION_SERVICE_TLS_KEY"),
+		CAFiles:   cas,
+		PeerNames: []string{serverName},
+	}
+}
+
+// tokenServicesFactory returns the factory of the token services. If the environment sets the address of a
+// validation service, the token requests are validated by it over mutual TLS.
+func tokenServicesFactory() func([]byte) (tcc.PublicParametersManager, tcc.Validator, error) {
+	address := os.Getenv("CHAINCODE_VALIDATION_SERVICE_ADDRESS")
+	if address == "" {
+		return func(bytes []byte) (tcc.PublicParametersManager, tcc.Validator, error) {
+			return token.NewServicesFromPublicParams(bytes)
+		}
+	}
+	config := validationServiceTLSConfig(address)
+	tlsConfig, err := config.ClientConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid validation service TLS configuration: %s\n", err)
+		os.Exit(2)
+	}
+	cc, err := grpc.Dial(address, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid validation service address [%s]: %s\n", address, err)
+		os.Exit(2)
+	}
+	return func(bytes []byte) (tcc.PublicParametersManager, tcc.Validator, error) {
+		ppm, validator, err := token.NewServicesFromPublicParams(bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ppm, remote.NewRemoteValidator(cc, bytes, validator), nil
+	}
+}
+
 func main() {
 	config := serverConfig{
 		CCID:      os.Getenv("CHAINCODE_ID"),
@@ -37,9 +241,17 @@ func main() {
 		}
 		err := shim.Start(
 			&tcc.TokenChaincode{
-				TokenServicesFactory: func(bytes []byte) (tcc.PublicParametersManager, tcc.Validator, error) {
-					return token.NewServicesFromPublicParams(bytes)
-				},
+				TokenServicesFactory: tokenServicesFactory(),
+				ValidationCache:      validationCache(),
+				InFlight:             inFlightTracker(),
+				RequestLimits:        requestLimits(),
+				MaxClockSkew:         maxClockSkew(),
+				HeightProvider:       tcc.LedgerHeight,
+				KeyScheme:            keyScheme(),
+				MigrateFrom:          migrateFrom(),
+				AdminPolicy:          adminPolicy(),
+				KeyPolicy:            keyPolicy(),
+				Metrics:              rwsetMetrics(),
 			},
 		)
 		if err != nil {
@@ -54,10 +266,18 @@ func main() {
 			CCID:    config.CCID,
 			Address: config.CCaddress,
 			CC: &tcc.TokenChaincode{
-				TokenServicesFactory: func(bytes []byte) (tcc.PublicParametersManager, tcc.Validator, error) {
-					return token.NewServicesFromPublicParams(bytes)
-				},
-				LogLevel: config.LogLevel,
+				TokenServicesFactory: tokenServicesFactory(),
+				LogLevel:             config.LogLevel,
+				ValidationCache:      validationCache(),
+				InFlight:             inFlightTracker(),
+				RequestLimits:        requestLimits(),
+				MaxClockSkew:         maxClockSkew(),
+				HeightProvider:       tcc.LedgerHeight,
+				KeyScheme:            keyScheme(),
+				MigrateFrom:          migrateFrom(),
+				AdminPolicy:          adminPolicy(),
+				KeyPolicy:            keyPolicy(),
+				Metrics:              rwsetMetrics(),
 			},
 			TLSProps: shim.TLSProperties{
 				// TODO : enable TLS
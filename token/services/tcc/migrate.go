@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package tcc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+)
+
+// DefaultMigrationBatchSize is the maximum number of keys a migration invocation moves, if not specified
+const DefaultMigrationBatchSize = 1000
+
+// MigrateKeysRequest asks to move the keys of the passed application to the key scheme of the chaincode, see keys.NewScheme
+type MigrateKeysRequest struct {
+	// From is the application the keys are currently derived for, empty for keys.Default
+	From string
+	// Limit bounds the number of keys moved by the invocation, DefaultMigrationBatchSize if zero
+	Limit int
+}
+
+// MigrateKeysResponse tells how many keys an invocation moved
+type MigrateKeysResponse struct {
+	Migrated int
+	// Done is true if there are no more keys to move
+	Done bool
+}
+
+// migrateKeys moves, under the key scheme of the chaincode, the keys derived by the scheme of the requested application.
+// The keys are moved in batches, to bound the size of the transaction: the function is invoked until it reports
+// that it is done. The token requests in flight must be drained before the migration starts, and the clients
+// must be configured with the new scheme once it is done.
+// The invoker must satisfy the admin policy of the chaincode, and the requested application must be the one
+// the chaincode is configured to migrate from, see TokenChaincode.MigrateFrom.
+func (cc *TokenChaincode) migrateKeys(raw []byte, stub shim.ChaincodeStubInterface) pb.Response {
+	if cc.AdminPolicy == nil {
+		return shim.Error("keys cannot be migrated, no admin policy set")
+	}
+	if err := cc.AdminPolicy(stub); err != nil {
+		return shim.Error(fmt.Sprintf("not authorized to migrate keys: [%s]", err))
+	}
+	if cc.MigrateFrom == nil {
+		return shim.Error("keys cannot be migrated, no source scheme set")
+	}
+
+	req := &MigrateKeysRequest{}
+	if err := json.Unmarshal(raw, req); err != nil {
+		return shim.Error(fmt.Sprintf("failed unmarshalling migrate keys request: [%s]", err))
+	}
+	from, err := keys.NewScheme(req.From)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if from.Prefix() != cc.MigrateFrom.Prefix() {
+		return shim.Error(fmt.Sprintf("keys can be migrated only from scheme [%s], got [%s]", cc.MigrateFrom.Prefix(), from.Prefix()))
+	}
+	to := cc.keyScheme()
+	if from.Prefix() == to.Prefix() {
+		return shim.Error(fmt.Sprintf("keys already derived with scheme [%s]", to.Prefix()))
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultMigrationBatchSize
+	}
+
+	it, err := stub.GetStateByPartialCompositeKey(from.Prefix(), []string{})
+	if err != nil {
+		return shim.Error(fmt.Sprintf("failed querying keys of scheme [%s]: [%s]", from.Prefix(), err))
+	}
+	defer it.Close()
+	res := &MigrateKeysResponse{}
+	for {
+		if !it.HasNext() {
+			res.Done = true
+			break
+		}
+		if res.Migrated == limit {
+			break
+		}
+		kv, err := it.Next()
+		if err != nil {
+			return shim.Error(fmt.Sprintf("failed reading keys of scheme [%s]: [%s]", from.Prefix(), err))
+		}
+		key, err := to.Rekey(kv.Key, from)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if err := stub.PutState(key, kv.Value); err != nil {
+			return shim.Error(fmt.Sprintf("failed writing [%q]: [%s]", key, err))
+		}
+		if err := stub.DelState(kv.Key); err != nil {
+			return shim.Error(fmt.Sprintf("failed deleting [%q]: [%s]", kv.Key, err))
+		}
+		res.Migrated++
+	}
+	logger.Infof("migrated [%d] keys from [%s] to [%s], done [%v]", res.Migrated, from.Prefix(), to.Prefix(), res.Done)
+	payload, err := json.Marshal(res)
+	if err != nil {
+		return shim.Error(fmt.Sprintf("failed marshalling migrate keys response: [%s]", err))
+	}
+	return shim.Success(payload)
+}
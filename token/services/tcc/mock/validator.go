@@ -9,12 +9,26 @@ import (
 )
 
 type Validator struct {
-	UnmarshallAndVerifyStub        func(token.Ledger, string, []byte) ([]interface{}, error)
+	UnmarshalActionsStub        func([]byte) ([]interface{}, error)
+	unmarshalActionsMutex       sync.RWMutex
+	unmarshalActionsArgsForCall []struct {
+		arg1 []byte
+	}
+	unmarshalActionsReturns struct {
+		result1 []interface{}
+		result2 error
+	}
+	unmarshalActionsReturnsOnCall map[int]struct {
+		result1 []interface{}
+		result2 error
+	}
+	UnmarshallAndVerifyStub        func(token.Ledger, string, []byte, ...token.ValidationOption) ([]interface{}, error)
 	unmarshallAndVerifyMutex       sync.RWMutex
 	unmarshallAndVerifyArgsForCall []struct {
 		arg1 token.Ledger
 		arg2 string
 		arg3 []byte
+		arg4 []token.ValidationOption
 	}
 	unmarshallAndVerifyReturns struct {
 		result1 []interface{}
@@ -24,11 +38,94 @@ type Validator struct {
 		result1 []interface{}
 		result2 error
 	}
+	VerifyBatchStub        func(token.Ledger, *token.BatchRequest, ...token.ValidationOption) ([]*token.SubRequestResult, error)
+	verifyBatchMutex       sync.RWMutex
+	verifyBatchArgsForCall []struct {
+		arg1 token.Ledger
+		arg2 *token.BatchRequest
+		arg3 []token.ValidationOption
+	}
+	verifyBatchReturns struct {
+		result1 []*token.SubRequestResult
+		result2 error
+	}
+	verifyBatchReturnsOnCall map[int]struct {
+		result1 []*token.SubRequestResult
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *Validator) UnmarshallAndVerify(arg1 token.Ledger, arg2 string, arg3 []byte) ([]interface{}, error) {
+func (fake *Validator) UnmarshalActions(arg1 []byte) ([]interface{}, error) {
+	var arg1Copy []byte
+	if arg1 != nil {
+		arg1Copy = make([]byte, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.unmarshalActionsMutex.Lock()
+	ret, specificReturn := fake.unmarshalActionsReturnsOnCall[len(fake.unmarshalActionsArgsForCall)]
+	fake.unmarshalActionsArgsForCall = append(fake.unmarshalActionsArgsForCall, struct {
+		arg1 []byte
+	}{arg1Copy})
+	fake.recordInvocation("UnmarshalActions", []interface{}{arg1Copy})
+	fake.unmarshalActionsMutex.Unlock()
+	if fake.UnmarshalActionsStub != nil {
+		return fake.UnmarshalActionsStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.unmarshalActionsReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *Validator) UnmarshalActionsCallCount() int {
+	fake.unmarshalActionsMutex.RLock()
+	defer fake.unmarshalActionsMutex.RUnlock()
+	return len(fake.unmarshalActionsArgsForCall)
+}
+
+func (fake *Validator) UnmarshalActionsCalls(stub func([]byte) ([]interface{}, error)) {
+	fake.unmarshalActionsMutex.Lock()
+	defer fake.unmarshalActionsMutex.Unlock()
+	fake.UnmarshalActionsStub = stub
+}
+
+func (fake *Validator) UnmarshalActionsArgsForCall(i int) []byte {
+	fake.unmarshalActionsMutex.RLock()
+	defer fake.unmarshalActionsMutex.RUnlock()
+	argsForCall := fake.unmarshalActionsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *Validator) UnmarshalActionsReturns(result1 []interface{}, result2 error) {
+	fake.unmarshalActionsMutex.Lock()
+	defer fake.unmarshalActionsMutex.Unlock()
+	fake.UnmarshalActionsStub = nil
+	fake.unmarshalActionsReturns = struct {
+		result1 []interface{}
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *Validator) UnmarshalActionsReturnsOnCall(i int, result1 []interface{}, result2 error) {
+	fake.unmarshalActionsMutex.Lock()
+	defer fake.unmarshalActionsMutex.Unlock()
+	fake.UnmarshalActionsStub = nil
+	if fake.unmarshalActionsReturnsOnCall == nil {
+		fake.unmarshalActionsReturnsOnCall = make(map[int]struct {
+			result1 []interface{}
+			result2 error
+		})
+	}
+	fake.unmarshalActionsReturnsOnCall[i] = struct {
+		result1 []interface{}
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *Validator) UnmarshallAndVerify(arg1 token.Ledger, arg2 string, arg3 []byte, arg4 ...token.ValidationOption) ([]interface{}, error) {
 	var arg3Copy []byte
 	if arg3 != nil {
 		arg3Copy = make([]byte, len(arg3))
@@ -40,11 +137,12 @@ func (fake *Validator) UnmarshallAndVerify(arg1 token.Ledger, arg2 string, arg3
 		arg1 token.Ledger
 		arg2 string
 		arg3 []byte
-	}{arg1, arg2, arg3Copy})
-	fake.recordInvocation("UnmarshallAndVerify", []interface{}{arg1, arg2, arg3Copy})
+		arg4 []token.ValidationOption
+	}{arg1, arg2, arg3Copy, arg4})
+	fake.recordInvocation("UnmarshallAndVerify", []interface{}{arg1, arg2, arg3Copy, arg4})
 	fake.unmarshallAndVerifyMutex.Unlock()
 	if fake.UnmarshallAndVerifyStub != nil {
-		return fake.UnmarshallAndVerifyStub(arg1, arg2, arg3)
+		return fake.UnmarshallAndVerifyStub(arg1, arg2, arg3, arg4...)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -59,17 +157,17 @@ func (fake *Validator) UnmarshallAndVerifyCallCount() int {
 	return len(fake.unmarshallAndVerifyArgsForCall)
 }
 
-func (fake *Validator) UnmarshallAndVerifyCalls(stub func(token.Ledger, string, []byte) ([]interface{}, error)) {
+func (fake *Validator) UnmarshallAndVerifyCalls(stub func(token.Ledger, string, []byte, ...token.ValidationOption) ([]interface{}, error)) {
 	fake.unmarshallAndVerifyMutex.Lock()
 	defer fake.unmarshallAndVerifyMutex.Unlock()
 	fake.UnmarshallAndVerifyStub = stub
 }
 
-func (fake *Validator) UnmarshallAndVerifyArgsForCall(i int) (token.Ledger, string, []byte) {
+func (fake *Validator) UnmarshallAndVerifyArgsForCall(i int) (token.Ledger, string, []byte, []token.ValidationOption) {
 	fake.unmarshallAndVerifyMutex.RLock()
 	defer fake.unmarshallAndVerifyMutex.RUnlock()
 	argsForCall := fake.unmarshallAndVerifyArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *Validator) UnmarshallAndVerifyReturns(result1 []interface{}, result2 error) {
@@ -98,11 +196,80 @@ func (fake *Validator) UnmarshallAndVerifyReturnsOnCall(i int, result1 []interfa
 	}{result1, result2}
 }
 
+func (fake *Validator) VerifyBatch(arg1 token.Ledger, arg2 *token.BatchRequest, arg3 ...token.ValidationOption) ([]*token.SubRequestResult, error) {
+	fake.verifyBatchMutex.Lock()
+	ret, specificReturn := fake.verifyBatchReturnsOnCall[len(fake.verifyBatchArgsForCall)]
+	fake.verifyBatchArgsForCall = append(fake.verifyBatchArgsForCall, struct {
+		arg1 token.Ledger
+		arg2 *token.BatchRequest
+		arg3 []token.ValidationOption
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("VerifyBatch", []interface{}{arg1, arg2, arg3})
+	fake.verifyBatchMutex.Unlock()
+	if fake.VerifyBatchStub != nil {
+		return fake.VerifyBatchStub(arg1, arg2, arg3...)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.verifyBatchReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *Validator) VerifyBatchCallCount() int {
+	fake.verifyBatchMutex.RLock()
+	defer fake.verifyBatchMutex.RUnlock()
+	return len(fake.verifyBatchArgsForCall)
+}
+
+func (fake *Validator) VerifyBatchCalls(stub func(token.Ledger, *token.BatchRequest, ...token.ValidationOption) ([]*token.SubRequestResult, error)) {
+	fake.verifyBatchMutex.Lock()
+	defer fake.verifyBatchMutex.Unlock()
+	fake.VerifyBatchStub = stub
+}
+
+func (fake *Validator) VerifyBatchArgsForCall(i int) (token.Ledger, *token.BatchRequest, []token.ValidationOption) {
+	fake.verifyBatchMutex.RLock()
+	defer fake.verifyBatchMutex.RUnlock()
+	argsForCall := fake.verifyBatchArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *Validator) VerifyBatchReturns(result1 []*token.SubRequestResult, result2 error) {
+	fake.verifyBatchMutex.Lock()
+	defer fake.verifyBatchMutex.Unlock()
+	fake.VerifyBatchStub = nil
+	fake.verifyBatchReturns = struct {
+		result1 []*token.SubRequestResult
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *Validator) VerifyBatchReturnsOnCall(i int, result1 []*token.SubRequestResult, result2 error) {
+	fake.verifyBatchMutex.Lock()
+	defer fake.verifyBatchMutex.Unlock()
+	fake.VerifyBatchStub = nil
+	if fake.verifyBatchReturnsOnCall == nil {
+		fake.verifyBatchReturnsOnCall = make(map[int]struct {
+			result1 []*token.SubRequestResult
+			result2 error
+		})
+	}
+	fake.verifyBatchReturnsOnCall[i] = struct {
+		result1 []*token.SubRequestResult
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *Validator) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.unmarshalActionsMutex.RLock()
+	defer fake.unmarshalActionsMutex.RUnlock()
 	fake.unmarshallAndVerifyMutex.RLock()
 	defer fake.unmarshallAndVerifyMutex.RUnlock()
+	fake.verifyBatchMutex.RLock()
+	defer fake.verifyBatchMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
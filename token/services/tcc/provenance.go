@@ -0,0 +1,174 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package tcc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// DefaultMaxProvenanceSteps is the maximum number of tokens a provenance query walks through, if not configured
+const DefaultMaxProvenanceSteps = 1000
+
+// ProvenanceStep tells how a token has been created
+type ProvenanceStep struct {
+	ID *token2.Id
+	// Action is the type of the action that created the token: issue, transfer, or migration
+	Action api.ActionType
+	// Inputs are the tokens spent by the action, nil for an issue or if the action hides them
+	Inputs []*token2.Id
+	// GraphHiding is true if the action hides its inputs, then the provenance cannot be walked back further
+	GraphHiding bool
+}
+
+// Provenance is the graph of the transactions a token descends from, walked back from the token to the issues
+type Provenance struct {
+	// Steps are in breadth-first order, starting from the queried token. Each token appears once.
+	Steps []*ProvenanceStep
+	// Truncated is true if the walk stopped before reaching all the issues, see TokenChaincode.MaxProvenanceSteps
+	Truncated bool
+}
+
+func (cc *TokenChaincode) queryTokenRequest(txID string, stub shim.ChaincodeStubInterface) pb.Response {
+	w := cc.newTranslator(&allIssuersValid{}, stub.GetTxID(), &rwsWrapper{stub: stub})
+	raw, err := w.ReadTokenRequest(txID)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(raw) == 0 {
+		return shim.Error(fmt.Sprintf("token request [%s] not found", txID))
+	}
+	return shim.Success(raw)
+}
+
+func (cc *TokenChaincode) queryProvenance(idRaw []byte, stub shim.ChaincodeStubInterface) pb.Response {
+	id := &token2.Id{}
+	if err := json.Unmarshal(idRaw, id); err != nil {
+		return shim.Error(fmt.Sprintf("failed unmarshalling token id: [%s]", err))
+	}
+	services, err := cc.tokenServices(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	w := cc.newTranslator(&allIssuersValid{}, stub.GetTxID(), &rwsWrapper{stub: stub})
+	provenance, err := cc.provenance(services.validator, w, id)
+	if err != nil {
+		logger.Errorf("failed computing provenance of [%s]: [%s]", id, err)
+		return shim.Error(fmt.Sprintf("failed computing provenance of [%s]: [%s]", id, err))
+	}
+	raw, err := json.Marshal(provenance)
+	if err != nil {
+		return shim.Error(fmt.Sprintf("failed marshalling provenance: [%s]", err))
+	}
+	return shim.Success(raw)
+}
+
+// provenance walks back, from the passed token, the token requests stored on the ledger
+func (cc *TokenChaincode) provenance(validator Validator, w *translator.Translator, id *token2.Id) (*Provenance, error) {
+	maxSteps := cc.MaxProvenanceSteps
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxProvenanceSteps
+	}
+	res := &Provenance{}
+	requests := map[string][]interface{}{}
+	visited := map[string]bool{}
+	queue := []*token2.Id{id}
+	for len(queue) != 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if visited[current.String()] {
+			continue
+		}
+		if len(res.Steps) == maxSteps {
+			res.Truncated = true
+			break
+		}
+		visited[current.String()] = true
+
+		actions, ok := requests[current.TxId]
+		if !ok {
+			raw, err := w.ReadTokenRequest(current.TxId)
+			if err != nil {
+				return nil, err
+			}
+			if len(raw) == 0 {
+				return nil, errors.Errorf("token request [%s] not found", current.TxId)
+			}
+			actions, err = validator.UnmarshalActions(raw)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "failed unmarshalling token request [%s]", current.TxId)
+			}
+			requests[current.TxId] = actions
+		}
+		step, err := provenanceStep(current, actions)
+		if err != nil {
+			return nil, err
+		}
+		res.Steps = append(res.Steps, step)
+		queue = append(queue, step.Inputs...)
+	}
+	return res, nil
+}
+
+// provenanceStep finds the action that created the passed token. The outputs are indexed as done by the translator:
+// following the order of the actions, the outputs of the issues first, then those of the transfers.
+func provenanceStep(id *token2.Id, actions []interface{}) (*ProvenanceStep, error) {
+	base := 0
+	for _, action := range actions {
+		var numOutputs int
+		switch a := action.(type) {
+		case translator.TransferAction:
+			numOutputs = a.NumOutputs()
+		case translator.IssueAction:
+			numOutputs = a.NumOutputs()
+		default:
+			continue
+		}
+		if int(id.Index) >= base+numOutputs {
+			base += numOutputs
+			continue
+		}
+
+		step := &ProvenanceStep{ID: id, Action: api.IssueActionType}
+		transfer, ok := action.(translator.TransferAction)
+		if !ok {
+			return step, nil
+		}
+		step.Action = api.TransferActionType
+		switch action.(type) {
+		case *api.MigrationAction:
+			step.Action = api.MigrationActionType
+		case *api.RebindingAction:
+			step.Action = api.RebindingActionType
+		}
+		if transfer.IsGraphHiding() {
+			step.GraphHiding = true
+			return step, nil
+		}
+		inputs, err := transfer.GetInputs()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed getting inputs of the action creating [%s]", id)
+		}
+		for _, input := range inputs {
+			inputID, err := keys.GetTokenIdFromKey(input)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "invalid input [%s] of the action creating [%s]", input, id)
+			}
+			step.Inputs = append(step.Inputs, inputID)
+		}
+		return step, nil
+	}
+	return nil, errors.Errorf("token [%s] not found in its token request", id)
+}
@@ -0,0 +1,76 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package main
+
+import (
+	"net"
+	"os"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken/driver"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc/remote"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.tcc.remote.service")
+
+// tlsConfig returns the mutual TLS configuration of the service. The clients, the token chaincodes, must present
+// a certificate issued by one of the CAs in VALIDATION_SERVICE_TLS_CLIENT_CAS carrying one of the names in
+// VALIDATION_SERVICE_TLS_CLIENT_NAMES, both comma separated.
+func tlsConfig() remote.TLSConfig {
+	return remote.TLSConfig{
+		CertFile:  os.Getenv("VALIDATION_SERVICE_TLS_CERT"),
+		KeyFile:   os.Getenv("VALIDATION_SERVICE_TLS_KEY"),
+		CAFiles:   split(os.Getenv("VALIDATION_SERVICE_TLS_CLIENT_CAS")),
+		PeerNames: split(os.Getenv("VALIDATION_SERVICE_TLS_CLIENT_NAMES")),
+	}
+}
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// main runs the validation service the token chaincode calls when CHAINCODE_VALIDATION_SERVICE_ADDRESS is set
+func main() {
+	address := os.Getenv("VALIDATION_SERVICE_ADDRESS")
+	if address == "" {
+		address = "0.0.0.0:7070"
+	}
+	config := tlsConfig()
+	tlsConfig, err := config.ServerConfig()
+	if err != nil {
+		logger.Errorf("invalid TLS configuration: %s", err)
+		os.Exit(2)
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		logger.Errorf("failed listening on [%s]: %s", address, err)
+		os.Exit(2)
+	}
+
+	opts := append(remote.ServerOptions(), grpc.Creds(credentials.NewTLS(tlsConfig)))
+	server := grpc.NewServer(opts...)
+	remote.RegisterValidationServiceServer(server, remote.NewServer(func(ppRaw []byte) (remote.Validator, error) {
+		_, validator, err := token.NewServicesFromPublicParams(ppRaw)
+		if err != nil {
+			return nil, err
+		}
+		return validator, nil
+	}))
+	logger.Infof("running validation service on [%s]", address)
+	if err := server.Serve(listener); err != nil {
+		logger.Errorf("exiting validation service: %s", err)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package remote
+
+import (
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+// ValidationRequest opens a validation, it is the first message the client sends.
+// It carries either a token request or a batch.
+type ValidationRequest struct {
+	// PPDigest is the SHA-256 digest of the public parameters the request is validated against
+	PPDigest []byte `json:"pp_digest"`
+	Binding  string `json:"binding,omitempty"`
+	// Request is the serialized token request, compressed or not, together with its signatures
+	Request []byte               `json:"request,omitempty"`
+	Batch   *token.BatchRequest  `json:"batch,omitempty"`
+	TxTime  time.Time            `json:"tx_time,omitempty"`
+	Height  uint64               `json:"height,omitempty"`
+	Limits  *token.RequestLimits `json:"limits,omitempty"`
+}
+
+// ClientMessage is a message the client sends on the validation stream
+type ClientMessage struct {
+	Request *ValidationRequest `json:"request,omitempty"`
+	// State answers a StateQuery of the service
+	State *StateResponse `json:"state,omitempty"`
+	// PublicParams answers a request of the service for the public parameters
+	PublicParams []byte `json:"public_params,omitempty"`
+}
+
+// StateQuery asks the client for the value of a key of the ledger
+type StateQuery struct {
+	Key string `json:"key"`
+}
+
+type StateResponse struct {
+	Value []byte `json:"value,omitempty"`
+	// Err is set if the client failed reading the key
+	Err string `json:"err,omitempty"`
+}
+
+// ValidationResult closes a validation
+type ValidationResult struct {
+	// Err is set if the request, or the batch in AllOrNothing mode, is not valid
+	Err string `json:"err,omitempty"`
+	// SubRequestErrs are the reasons the sub-requests of a batch have been rejected, in the order of the batch,
+	// empty for the valid ones
+	SubRequestErrs []string `json:"sub_request_errs,omitempty"`
+	// SubRequestChecks are the checks the sub-requests of a batch failed, in the order of the batch,
+	// empty for the valid ones
+	SubRequestChecks []tokenapi.ValidationCheck `json:"sub_request_checks,omitempty"`
+}
+
+// ServerMessage is a message the service sends on the validation stream
+type ServerMessage struct {
+	Query *StateQuery `json:"query,omitempty"`
+	// NeedPublicParams asks the client for the public parameters, unknown to the service
+	NeedPublicParams bool              `json:"need_public_params,omitempty"`
+	Result           *ValidationResult `json:"result,omitempty"`
+}
@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package remote
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	grpc2 "google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+)
+
+// fakeValidator accepts the requests whose key on the ledger is set to "ok"
+type fakeValidator struct{}
+
+func (f *fakeValidator) UnmarshallAndVerify(ledger token.Ledger, binding string, raw []byte, opts ...token.ValidationOption) ([]interface{}, error) {
+	v, err := ledger.GetState(string(raw))
+	if err != nil {
+		return nil, err
+	}
+	if string(v) != "ok" {
+		return nil, errors.Errorf("request [%s] not valid", raw)
+	}
+	return nil, nil
+}
+
+func (f *fakeValidator) VerifyBatch(ledger token.Ledger, batch *token.BatchRequest, opts ...token.ValidationOption) ([]*token.SubRequestResult, error) {
+	var res []*token.SubRequestResult
+	for _, r := range batch.Requests {
+		_, err := f.UnmarshallAndVerify(ledger, r.Binding, r.Request, opts...)
+		res = append(res, &token.SubRequestResult{Binding: r.Binding, Err: err})
+	}
+	return res, nil
+}
+
+func (f *fakeValidator) UnmarshalActions(raw []byte) ([]interface{}, error) {
+	return []interface{}{string(raw)}, nil
+}
+
+type mapLedger map[string][]byte
+
+func (m mapLedger) GetState(key string) ([]byte, error) {
+	return m[key], nil
+}
+
+func TestRemoteValidator(t *testing.T) {
+	instantiations := 0
+	server := NewServer(func(ppRaw []byte) (Validator, error) {
+		assert.Equal(t, "public parameters", string(ppRaw))
+		instantiations++
+		return &fakeValidator{}, nil
+	})
+	listener := bufconn.Listen(1024 * 1024)
+	s := grpc2.NewServer(ServerOptions()...)
+	RegisterValidationServiceServer(s, server)
+	go s.Serve(listener)
+	defer s.Stop()
+
+	cc, err := grpc2.Dial("bufnet", grpc2.WithInsecure(), grpc2.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	assert.NoError(t, err)
+	defer cc.Close()
+
+	v := NewRemoteValidator(cc, []byte("public parameters"), &fakeValidator{})
+	ledger := mapLedger{"tr1": []byte("ok"), "tr2": []byte("ko")}
+
+	actions, err := v.UnmarshallAndVerify(ledger, "tx1", []byte("tr1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"tr1"}, actions)
+
+	_, err = v.UnmarshallAndVerify(ledger, "tx2", []byte("tr2"))
+	assert.EqualError(t, err, "request [tr2] not valid")
+
+	// the public parameters are sent once
+	assert.Equal(t, 1, instantiations)
+
+	results, err := v.VerifyBatch(ledger, &token.BatchRequest{
+		Mode: token.SkipInvalid,
+		Requests: []*token.SubRequest{
+			{Binding: "tx3", Request: []byte("tr1")},
+			{Binding: "tx4", Request: []byte("tr2")},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, []interface{}{"tr1"}, results[0].Actions)
+	assert.EqualError(t, results[1].Err, "request [tr2] not valid")
+
+	// the hooks of the chaincode cannot travel
+	_, err = v.UnmarshallAndVerify(ledger, "tx5", []byte("tr1"), token.WithRequestHook(nil))
+	assert.Error(t, err)
+}
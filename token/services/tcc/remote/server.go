@@ -0,0 +1,164 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package remote
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.tcc.remote")
+
+// Validator verifies token requests and batches, token.Validator for instance
+type Validator interface {
+	UnmarshallAndVerify(ledger token.Ledger, binding string, raw []byte, opts ...token.ValidationOption) ([]interface{}, error)
+	VerifyBatch(ledger token.Ledger, batch *token.BatchRequest, opts ...token.ValidationOption) ([]*token.SubRequestResult, error)
+}
+
+// ValidatorFactory returns the validator of the passed public parameters
+type ValidatorFactory func(ppRaw []byte) (Validator, error)
+
+// Server validates token requests on behalf of the token chaincode, so that the verification of the proofs
+// scales independently of the peers. It reads the ledger via the chaincode, and gets the public parameters from it
+// the first time it sees them.
+type Server struct {
+	factory ValidatorFactory
+	// hooks are the validation hooks enforced on each request, the ones of the chaincode cannot travel
+	hooks []token.ValidationOption
+
+	lock       sync.RWMutex
+	validators map[string]Validator
+}
+
+func NewServer(factory ValidatorFactory, hooks ...token.ValidationOption) *Server {
+	return &Server{factory: factory, hooks: hooks, validators: map[string]Validator{}}
+}
+
+func (s *Server) Validate(stream ValidationStream) error {
+	m, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if m.Request == nil {
+		return errors.New("expected a validation request")
+	}
+	request := m.Request
+
+	validator, err := s.validator(stream, request.PPDigest)
+	if err != nil {
+		return err
+	}
+
+	opts := append([]token.ValidationOption{
+		token.WithTxTime(request.TxTime),
+		token.WithHeight(request.Height),
+		token.WithContext(stream.Context()),
+	}, s.hooks...)
+	if request.Limits != nil {
+		opts = append(opts, token.WithRequestLimits(request.Limits))
+	}
+	ledger := &streamLedger{stream: stream}
+
+	result := &ValidationResult{}
+	if request.Batch != nil {
+		results, err := validator.VerifyBatch(ledger, request.Batch, opts...)
+		if err != nil {
+			result.Err = err.Error()
+		}
+		for _, r := range results {
+			if r.Err != nil {
+				result.SubRequestErrs = append(result.SubRequestErrs, r.Err.Error())
+				result.SubRequestChecks = append(result.SubRequestChecks, r.Check)
+				continue
+			}
+			result.SubRequestErrs = append(result.SubRequestErrs, "")
+			result.SubRequestChecks = append(result.SubRequestChecks, "")
+		}
+	} else {
+		if _, err := validator.UnmarshallAndVerify(ledger, request.Binding, request.Request, opts...); err != nil {
+			result.Err = err.Error()
+		}
+	}
+	if ledger.err != nil {
+		return ledger.err
+	}
+	logger.Debugf("validated [%s], err [%s]", request.Binding, result.Err)
+	return stream.Send(&ServerMessage{Result: result})
+}
+
+// validator returns the validator of the public parameters with the passed digest, asking the client for them
+// if they are unknown
+func (s *Server) validator(stream ValidationStream, digest []byte) (Validator, error) {
+	s.lock.RLock()
+	validator, ok := s.validators[string(digest)]
+	s.lock.RUnlock()
+	if ok {
+		return validator, nil
+	}
+
+	if err := stream.Send(&ServerMessage{NeedPublicParams: true}); err != nil {
+		return nil, err
+	}
+	m, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(m.PublicParams)
+	if !bytes.Equal(h[:], digest) {
+		return nil, errors.New("public parameters do not match their digest")
+	}
+	validator, err = s.factory(m.PublicParams)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed instantiating validator")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if v, ok := s.validators[string(digest)]; ok {
+		return v, nil
+	}
+	s.validators[string(digest)] = validator
+	return validator, nil
+}
+
+// streamLedger reads the ledger via the client, one key at a time
+type streamLedger struct {
+	stream ValidationStream
+	lock   sync.Mutex
+	// err is the failure of the stream, if any, the validation cannot be trusted
+	err error
+}
+
+func (l *streamLedger) GetState(key string) ([]byte, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.err != nil {
+		return nil, l.err
+	}
+	if err := l.stream.Send(&ServerMessage{Query: &StateQuery{Key: key}}); err != nil {
+		l.err = errors.Wrapf(err, "failed querying state [%s]", key)
+		return nil, l.err
+	}
+	m, err := l.stream.Recv()
+	if err != nil {
+		l.err = errors.Wrapf(err, "failed receiving state [%s]", key)
+		return nil, l.err
+	}
+	if m.State == nil {
+		l.err = errors.Errorf("expected state [%s]", key)
+		return nil, l.err
+	}
+	if len(m.State.Err) != 0 {
+		return nil, errors.New(m.State.Err)
+	}
+	return m.State.Value, nil
+}
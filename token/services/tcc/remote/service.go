@@ -0,0 +1,131 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package remote
+
+import (
+	"context"
+	"encoding/json"
+
+	grpc2 "google.golang.org/grpc"
+)
+
+const (
+	ServiceName = "token.ValidationService"
+	// CodecName is the name of the codec of the validation service, messages are JSON encoded.
+	// The codec is not registered globally, it is set by ServerOptions and by the client on each call.
+	CodecName = "token-validation+json"
+)
+
+// ValidationServiceServer is the server API of the validation service
+type ValidationServiceServer interface {
+	// Validate validates a token request, or a batch, reading the ledger via the client, see ClientMessage
+	Validate(ValidationStream) error
+}
+
+// ValidationStream is the server side of a validation
+type ValidationStream interface {
+	Context() context.Context
+	Send(*ServerMessage) error
+	Recv() (*ClientMessage, error)
+}
+
+// ServerOptions returns the options of the gRPC server of the validation service, they select its codec.
+// The server serves only the validation service.
+func ServerOptions() []grpc2.ServerOption {
+	return []grpc2.ServerOption{grpc2.CustomCodec(codec{})}
+}
+
+// RegisterValidationServiceServer registers the passed validation service on the passed gRPC server
+func RegisterValidationServiceServer(s *grpc2.Server, srv ValidationServiceServer) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+var serviceDesc = grpc2.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*ValidationServiceServer)(nil),
+	Methods:     []grpc2.MethodDesc{},
+	Streams: []grpc2.StreamDesc{
+		{
+			StreamName: "Validate",
+			Handler: func(srv interface{}, stream grpc2.ServerStream) error {
+				return srv.(ValidationServiceServer).Validate(&serverStream{stream})
+			},
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "token/services/tcc/remote/service.go",
+}
+
+type serverStream struct {
+	grpc2.ServerStream
+}
+
+func (s *serverStream) Send(m *ServerMessage) error {
+	return s.ServerStream.SendMsg(m)
+}
+
+func (s *serverStream) Recv() (*ClientMessage, error) {
+	m := &ClientMessage{}
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ValidationServiceClient is the client API of the validation service
+type ValidationServiceClient struct {
+	cc *grpc2.ClientConn
+}
+
+func NewValidationServiceClient(cc *grpc2.ClientConn) *ValidationServiceClient {
+	return &ValidationServiceClient{cc: cc}
+}
+
+// Validate opens a validation stream
+func (c *ValidationServiceClient) Validate(ctx context.Context, opts ...grpc2.CallOption) (*ClientStream, error) {
+	opts = append([]grpc2.CallOption{grpc2.ForceCodec(codec{})}, opts...)
+	stream, err := c.cc.NewStream(ctx, &serviceDesc.Streams[0], "/"+ServiceName+"/Validate", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientStream{stream}, nil
+}
+
+// ClientStream is the client side of a validation
+type ClientStream struct {
+	grpc2.ClientStream
+}
+
+func (s *ClientStream) Send(m *ClientMessage) error {
+	return s.ClientStream.SendMsg(m)
+}
+
+func (s *ClientStream) Recv() (*ServerMessage, error) {
+	m := &ServerMessage{}
+	if err := s.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return CodecName
+}
+
+func (codec) String() string {
+	return CodecName
+}
@@ -0,0 +1,120 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package remote
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// TLSConfig is the mutual TLS configuration of an end of the validation service
+type TLSConfig struct {
+	// CertFile and KeyFile are the PEM encoded TLS certificate and key of this end
+	CertFile string
+	KeyFile  string
+	// CAFiles are the PEM encoded CA certificates used to verify the certificate of the other end
+	CAFiles []string
+	// PeerNames are the names, common name or DNS subject alternative names, the certificate of the other end must
+	// carry one of
+	PeerNames []string
+}
+
+// ServerTLSConfig returns a TLS configuration of the validation service that requires the client certificates,
+// verifies them against the passed CAs, and accepts only those carrying one of the passed names
+func ServerTLSConfig(cert tls.Certificate, clientCAs *x509.CertPool, clientNames ...string) (*tls.Config, error) {
+	if len(clientNames) == 0 {
+		return nil, errors.New("no client name specified, the identity of the clients must be checked")
+	}
+	return &tls.Config{
+		Certificates:          []tls.Certificate{cert},
+		ClientAuth:            tls.RequireAndVerifyClientCert,
+		ClientCAs:             clientCAs,
+		MinVersion:            tls.VersionTLS12,
+		VerifyPeerCertificate: verifyPeerName(clientNames),
+	}, nil
+}
+
+// ClientTLSConfig returns a TLS configuration of the client of the validation service that authenticates with the
+// passed certificate and accepts only a server certificate, verified against the passed CAs, issued to serverName
+func ClientTLSConfig(cert tls.Certificate, serverCAs *x509.CertPool, serverName string) (*tls.Config, error) {
+	if len(serverName) == 0 {
+		return nil, errors.New("no server name specified, the identity of the server must be checked")
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      serverCAs,
+		ServerName:   serverName,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// ServerConfig loads the TLS configuration of the validation service from the files referenced by this configuration
+func (c *TLSConfig) ServerConfig() (*tls.Config, error) {
+	cert, cas, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	return ServerTLSConfig(cert, cas, c.PeerNames...)
+}
+
+// ClientConfig loads the TLS configuration of the client from the files referenced by this configuration,
+// the server certificate must be issued to the first of the peer names
+func (c *TLSConfig) ClientConfig() (*tls.Config, error) {
+	cert, cas, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	if len(c.PeerNames) == 0 {
+		return nil, errors.New("no server name specified, the identity of the server must be checked")
+	}
+	return ClientTLSConfig(cert, cas, c.PeerNames[0])
+}
+
+func (c *TLSConfig) load() (tls.Certificate, *x509.CertPool, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return tls.Certificate{}, nil, errors.Wrapf(err, "failed loading key pair [%s,%s]", c.CertFile, c.KeyFile)
+	}
+	if len(c.CAFiles) == 0 {
+		return tls.Certificate{}, nil, errors.New("no CA specified, mutual TLS requires at least one")
+	}
+	cas := x509.NewCertPool()
+	for _, file := range c.CAFiles {
+		raw, err := ioutil.ReadFile(file)
+		if err != nil {
+			return tls.Certificate{}, nil, errors.Wrapf(err, "failed reading CA [%s]", file)
+		}
+		if !cas.AppendCertsFromPEM(raw) {
+			return tls.Certificate{}, nil, errors.Errorf("no certificate found in CA [%s]", file)
+		}
+	}
+	return cert, cas, nil
+}
+
+// verifyPeerName returns a check, run after the chain verification, that the leaf certificate carries one of
+// the passed names
+func verifyPeerName(names []string) func([][]byte, [][]*x509.Certificate) error {
+	return func(_ [][]byte, chains [][]*x509.Certificate) error {
+		if len(chains) == 0 || len(chains[0]) == 0 {
+			return errors.New("no verified peer certificate")
+		}
+		leaf := chains[0][0]
+		for _, name := range names {
+			if leaf.Subject.CommonName == name {
+				return nil
+			}
+			for _, dns := range leaf.DNSNames {
+				if dns == name {
+					return nil
+				}
+			}
+		}
+		return errors.Errorf("peer [%s] not authorized", leaf.Subject.CommonName)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package remote
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	grpc2 "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(raw)
+	assert.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+func (ca *testCA) issue(t *testing.T, name string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	assert.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{raw}, PrivateKey: key}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverTLS, err := ServerTLSConfig(ca.issue(t, "validator"), ca.pool, "chaincode")
+	assert.NoError(t, err)
+	_, err = ServerTLSConfig(ca.issue(t, "validator"), ca.pool)
+	assert.Error(t, err)
+
+	server := NewServer(func(ppRaw []byte) (Validator, error) {
+		return &fakeValidator{}, nil
+	})
+	listener := bufconn.Listen(1024 * 1024)
+	s := grpc2.NewServer(append(ServerOptions(), grpc2.Creds(credentials.NewTLS(serverTLS)))...)
+	RegisterValidationServiceServer(s, server)
+	go s.Serve(listener)
+	defer s.Stop()
+
+	validate := func(clientTLS *tls.Config) error {
+		cc, err := grpc2.Dial("bufnet", grpc2.WithTransportCredentials(credentials.NewTLS(clientTLS)), grpc2.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return listener.Dial()
+		}))
+		assert.NoError(t, err)
+		defer cc.Close()
+		v := NewRemoteValidator(cc, []byte("public parameters"), &fakeValidator{})
+		_, err = v.UnmarshallAndVerify(mapLedger{"tr1": []byte("ok")}, "tx1", []byte("tr1"))
+		return err
+	}
+
+	// the authorized chaincode is served
+	clientTLS, err := ClientTLSConfig(ca.issue(t, "chaincode"), ca.pool, "validator")
+	assert.NoError(t, err)
+	assert.NoError(t, validate(clientTLS))
+
+	// a client with another name is rejected
+	clientTLS, err = ClientTLSConfig(ca.issue(t, "intruder"), ca.pool, "validator")
+	assert.NoError(t, err)
+	assert.Error(t, validate(clientTLS))
+
+	// a client without certificate is rejected
+	assert.Error(t, validate(&tls.Config{RootCAs: ca.pool, ServerName: "validator"}))
+
+	// the chaincode rejects a server with another name
+	clientTLS, err = ClientTLSConfig(ca.issue(t, "chaincode"), ca.pool, "another-validator")
+	assert.NoError(t, err)
+	assert.Error(t, validate(clientTLS))
+
+	// a client certificate issued by another CA is rejected
+	clientTLS, err = ClientTLSConfig(newTestCA(t).issue(t, "chaincode"), ca.pool, "validator")
+	assert.NoError(t, err)
+	assert.Error(t, validate(clientTLS))
+}
@@ -0,0 +1,147 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package remote
+
+import (
+	"context"
+	"crypto/sha256"
+
+	"github.com/pkg/errors"
+	grpc2 "google.golang.org/grpc"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+// ActionsUnmarshaller returns the actions of a serialized token request without verifying them
+type ActionsUnmarshaller interface {
+	UnmarshalActions(raw []byte) ([]interface{}, error)
+}
+
+// RemoteValidator verifies the token requests via the validation service, reading the ledger on its behalf.
+// It can be returned by the TokenServicesFactory of the token chaincode in place of the local validator,
+// the actions of the verified requests are unmarshalled locally.
+// The validation hooks cannot travel, they must be set on the validation service, see NewServer.
+type RemoteValidator struct {
+	client  *ValidationServiceClient
+	ppRaw   []byte
+	digest  []byte
+	actions ActionsUnmarshaller
+}
+
+// NewRemoteValidator returns a validator of the token requests against the passed public parameters that calls the
+// validation service over the passed connection
+func NewRemoteValidator(cc *grpc2.ClientConn, ppRaw []byte, actions ActionsUnmarshaller) *RemoteValidator {
+	digest := sha256.Sum256(ppRaw)
+	return &RemoteValidator{
+		client:  NewValidationServiceClient(cc),
+		ppRaw:   ppRaw,
+		digest:  digest[:],
+		actions: actions,
+	}
+}
+
+func (v *RemoteValidator) UnmarshallAndVerify(ledger token.Ledger, binding string, raw []byte, opts ...token.ValidationOption) ([]interface{}, error) {
+	result, err := v.validate(ledger, &ValidationRequest{Binding: binding, Request: raw}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Err) != 0 {
+		return nil, errors.New(result.Err)
+	}
+	return v.actions.UnmarshalActions(raw)
+}
+
+func (v *RemoteValidator) UnmarshalActions(raw []byte) ([]interface{}, error) {
+	return v.actions.UnmarshalActions(raw)
+}
+
+func (v *RemoteValidator) VerifyBatch(ledger token.Ledger, batch *token.BatchRequest, opts ...token.ValidationOption) ([]*token.SubRequestResult, error) {
+	result, err := v.validate(ledger, &ValidationRequest{Batch: batch}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Err) != 0 {
+		return nil, errors.New(result.Err)
+	}
+	if len(result.SubRequestErrs) != len(batch.Requests) {
+		return nil, errors.Errorf("expected [%d] results, got [%d]", len(batch.Requests), len(result.SubRequestErrs))
+	}
+	res := make([]*token.SubRequestResult, len(batch.Requests))
+	for i, r := range batch.Requests {
+		if len(result.SubRequestErrs[i]) != 0 {
+			res[i] = &token.SubRequestResult{Binding: r.Binding, Err: errors.New(result.SubRequestErrs[i]), Check: tokenapi.OtherCheck}
+			if i < len(result.SubRequestChecks) && len(result.SubRequestChecks[i]) != 0 {
+				res[i].Check = result.SubRequestChecks[i]
+			}
+			continue
+		}
+		actions, err := v.actions.UnmarshalActions(r.Request)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed unmarshalling actions of sub-request [%d][%s]", i, r.Binding)
+		}
+		res[i] = &token.SubRequestResult{Binding: r.Binding, Actions: actions}
+	}
+	return res, nil
+}
+
+// validate runs a validation on the service, answering its queries until it returns the result
+func (v *RemoteValidator) validate(ledger token.Ledger, request *ValidationRequest, opts ...token.ValidationOption) (*ValidationResult, error) {
+	options := &tokenapi.ValidationOptions{}
+	for _, opt := range opts {
+		if err := opt(options); err != nil {
+			return nil, errors.Wrap(err, "failed applying validation option")
+		}
+	}
+	if len(options.IssueHooks) != 0 || len(options.TransferHooks) != 0 || len(options.RequestHooks) != 0 {
+		return nil, errors.New("validation hooks are not supported by the remote validator, set them on the validation service")
+	}
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	request.PPDigest = v.digest
+	request.TxTime = options.TxTime
+	request.Height = options.Height
+	request.Limits = options.Limits
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := v.client.Validate(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed opening validation stream")
+	}
+	if err := stream.Send(&ClientMessage{Request: request}); err != nil {
+		return nil, errors.Wrap(err, "failed sending validation request")
+	}
+	for {
+		m, err := stream.Recv()
+		if err != nil {
+			return nil, errors.Wrap(err, "validation failed")
+		}
+		switch {
+		case m.Result != nil:
+			return m.Result, nil
+		case m.NeedPublicParams:
+			if err := stream.Send(&ClientMessage{PublicParams: v.ppRaw}); err != nil {
+				return nil, errors.Wrap(err, "failed sending public parameters")
+			}
+		case m.Query != nil:
+			state := &StateResponse{}
+			value, err := ledger.GetState(m.Query.Key)
+			if err != nil {
+				state.Err = err.Error()
+			} else {
+				state.Value = value
+			}
+			if err := stream.Send(&ClientMessage{State: state}); err != nil {
+				return nil, errors.Wrapf(err, "failed sending state [%s]", m.Query.Key)
+			}
+		default:
+			return nil, errors.New("unexpected message from the validation service")
+		}
+	}
+}
@@ -8,6 +8,10 @@ package tcc
 import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
 )
 
 type rwsWrapper struct {
@@ -29,11 +33,27 @@ func (rwset *rwsWrapper) Bytes() ([]byte, error) {
 func (rwset *rwsWrapper) Done() {
 	return
 }
+
+// GetStateMetadata returns the state-based endorsement policy of the passed key, if any, the other metadata
+// are not stored by the chaincode
 func (rwset *rwsWrapper) GetStateMetadata(namespace, key string, opts ...fabric.GetStateOpt) (map[string][]byte, error) {
-	return nil, nil
+	ep, err := rwset.stub.GetStateValidationParameter(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ep) == 0 {
+		return nil, nil
+	}
+	return map[string][]byte{keys.ValidationParameter: ep}, nil
 }
+
+// SetStateMetadata sets the state-based endorsement policy of the passed key, if any in the passed metadata
 func (rwset *rwsWrapper) SetStateMetadata(namespace, key string, metadata map[string][]byte) error {
-	return nil
+	ep, ok := metadata[keys.ValidationParameter]
+	if !ok {
+		return nil
+	}
+	return rwset.stub.SetStateValidationParameter(key, ep)
 }
 func (rwset *rwsWrapper) AppendRWSet(raw []byte, nss ...string) error {
 	return nil
@@ -53,3 +73,14 @@ func (rwset *rwsWrapper) NumWrites(ns string) int {
 func (rwset *rwsWrapper) Namespaces() []string {
 	return nil
 }
+
+// statsLedger records the reads performed on the wrapped ledger into the passed stats
+type statsLedger struct {
+	ledger token.Ledger
+	stats  *token.RWSetStats
+}
+
+func (s *statsLedger) GetState(key string) ([]byte, error) {
+	s.stats.Read(translator.KeyKind(key), key)
+	return s.ledger.GetState(key)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package tcc
+
+import (
+	"encoding/base64"
+	"os"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PublicParamsCollectionVarEnv, if set, names the private data collection the public parameters are read from
+	// at init, under PublicParamsCollectionKey. ImplicitCollection selects the implicit collection of the organization
+	// of the peer.
+	PublicParamsCollectionVarEnv = "PUBLIC_PARAMS_COLLECTION"
+	PublicParamsCollectionKey    = "publicParams"
+	ImplicitCollection           = "implicit"
+)
+
+// ParamsSource provides the public parameters at init, so that their distribution can follow the governance of
+// the channel instead of being baked into the chaincode image. It returns nil if it has none.
+type ParamsSource interface {
+	PublicParams(stub shim.ChaincodeStubInterface) ([]byte, error)
+}
+
+// CollectionParamsSource reads the public parameters, serialized, from a private data collection.
+// The organizations members of the collection agree on them via the endorsement policy of the collection.
+type CollectionParamsSource struct {
+	// Collection is the name of the collection, ImplicitCollection or empty for the implicit collection of
+	// the organization of the peer
+	Collection string
+	// Key is the key of the public parameters in the collection, PublicParamsCollectionKey if empty
+	Key string
+}
+
+func (s *CollectionParamsSource) PublicParams(stub shim.ChaincodeStubInterface) ([]byte, error) {
+	collection := s.Collection
+	if len(collection) == 0 || collection == ImplicitCollection {
+		mspID, err := shim.GetMSPID()
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed getting the msp id of the peer")
+		}
+		collection = "_implicit_org_" + mspID
+	}
+	key := s.Key
+	if len(key) == 0 {
+		key = PublicParamsCollectionKey
+	}
+	raw, err := stub.GetPrivateData(collection, key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading public parameters from collection [%s]", collection)
+	}
+	return raw, nil
+}
+
+// initParams returns the public parameters to init the chaincode with, looking, in order, at the file
+// PublicParamsPathVarEnv points to, at ParamsSource, at the collection PublicParamsCollectionVarEnv names,
+// at Params, and at the init arguments. A source without public parameters is skipped, a failing one fails the init.
+func (cc *TokenChaincode) initParams(stub shim.ChaincodeStubInterface) ([]byte, error) {
+	if params := cc.readParamsFromFile(); len(params) != 0 {
+		return decodeParams(params)
+	}
+	if cc.ParamsSource != nil {
+		raw, err := cc.ParamsSource.PublicParams(stub)
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) != 0 {
+			return raw, nil
+		}
+		logger.Infof("no public parameters from the params source, continue looking")
+	}
+	if collection := os.Getenv(PublicParamsCollectionVarEnv); len(collection) != 0 {
+		raw, err := (&CollectionParamsSource{Collection: collection}).PublicParams(stub)
+		if err != nil {
+			return nil, err
+		}
+		if len(raw) != 0 {
+			return raw, nil
+		}
+		logger.Infof("no public parameters in collection [%s], continue looking", collection)
+	}
+	if len(Params) != 0 {
+		return decodeParams(Params)
+	}
+
+	args := stub.GetArgs()
+	// args[0] public parameters
+	if len(args) != 2 {
+		return nil, errors.New("length of provided arguments != 2")
+	}
+	if string(args[0]) != "init" {
+		return nil, errors.New("expected init function")
+	}
+	return decodeParams(string(args[1]))
+}
+
+func decodeParams(params string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(params)
+	if err != nil {
+		return nil, errors.New("failed to decode public parameters: " + err.Error())
+	}
+	return raw, nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package tcc
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/pkg/errors"
+)
+
+// StatsdProvider is a metrics.Provider pushing the metrics to a statsd server over UDP, as they are recorded.
+// The chaincode does not expose an endpoint to scrape, its metrics are pushed, see translator.NewMetrics.
+// The names of the metrics follow their StatsdFormat, %{#fqname} if empty.
+type StatsdProvider struct {
+	conn   net.Conn
+	prefix string
+}
+
+// NewStatsdProvider returns a provider pushing the metrics to the statsd server at the passed address,
+// their names prefixed by the passed prefix, if any
+func NewStatsdProvider(address string, prefix string) (*StatsdProvider, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed connecting to statsd server [%s]", address)
+	}
+	if len(prefix) != 0 && !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+	return &StatsdProvider{conn: conn, prefix: prefix}, nil
+}
+
+func (p *StatsdProvider) NewCounter(o metrics.CounterOpts) metrics.Counter {
+	return &statsdMetric{p: p, namespace: o.Namespace, subsystem: o.Subsystem, name: o.Name, format: o.StatsdFormat, kind: "c"}
+}
+
+func (p *StatsdProvider) NewGauge(o metrics.GaugeOpts) metrics.Gauge {
+	return statsdGauge{&statsdMetric{p: p, namespace: o.Namespace, subsystem: o.Subsystem, name: o.Name, format: o.StatsdFormat, kind: "g"}}
+}
+
+func (p *StatsdProvider) NewHistogram(o metrics.HistogramOpts) metrics.Histogram {
+	return statsdHistogram{&statsdMetric{p: p, namespace: o.Namespace, subsystem: o.Subsystem, name: o.Name, format: o.StatsdFormat, kind: "ms"}}
+}
+
+// Close closes the connection to the statsd server
+func (p *StatsdProvider) Close() error {
+	return p.conn.Close()
+}
+
+func (p *StatsdProvider) send(name string, value string, kind string) {
+	if _, err := fmt.Fprintf(p.conn, "%s%s:%s|%s", p.prefix, name, value, kind); err != nil {
+		logger.Debugf("failed sending metric [%s] to statsd: [%s]", name, err)
+	}
+}
+
+// statsdMetric is a counter, a gauge or a histogram, depending on its statsd kind
+type statsdMetric struct {
+	p         *StatsdProvider
+	namespace string
+	subsystem string
+	name      string
+	format    string
+	kind      string
+	// labels are the label name and value pairs passed to With
+	labels []string
+}
+
+func (m *statsdMetric) with(labelValues ...string) *statsdMetric {
+	c := *m
+	c.labels = append(append([]string{}, m.labels...), labelValues...)
+	return &c
+}
+
+func (m *statsdMetric) With(labelValues ...string) metrics.Counter {
+	return m.with(labelValues...)
+}
+
+func (m *statsdMetric) Add(delta float64) {
+	value := fmt.Sprintf("%g", delta)
+	if m.kind == "g" && delta >= 0 {
+		// a gauge is changed, not set, by a signed value
+		value = "+" + value
+	}
+	m.p.send(m.fqname(), value, m.kind)
+}
+
+func (m *statsdMetric) Set(value float64) {
+	m.p.send(m.fqname(), fmt.Sprintf("%g", value), m.kind)
+}
+
+func (m *statsdMetric) Observe(value float64) {
+	m.p.send(m.fqname(), fmt.Sprintf("%g", value), m.kind)
+}
+
+// fqname returns the name of the metric, formatted with its StatsdFormat
+func (m *statsdMetric) fqname() string {
+	format := m.format
+	if len(format) == 0 {
+		format = "%{#fqname}"
+	}
+	var parts []string
+	for _, part := range []string{m.namespace, m.subsystem, m.name} {
+		if len(part) != 0 {
+			parts = append(parts, part)
+		}
+	}
+	replacements := []string{
+		"%{#fqname}", strings.Join(parts, "."),
+		"%{#namespace}", m.namespace,
+		"%{#subsystem}", m.subsystem,
+		"%{#name}", m.name,
+	}
+	for i := 0; i+1 < len(m.labels); i += 2 {
+		replacements = append(replacements, "%{"+m.labels[i]+"}", strings.ReplaceAll(m.labels[i+1], ".", "_"))
+	}
+	return strings.NewReplacer(replacements...).Replace(format)
+}
+
+// statsdGauge is a statsdMetric whose With returns a gauge
+type statsdGauge struct{ *statsdMetric }
+
+func (g statsdGauge) With(labelValues ...string) metrics.Gauge {
+	return statsdGauge{g.statsdMetric.with(labelValues...)}
+}
+
+// statsdHistogram is a statsdMetric whose With returns a histogram
+type statsdHistogram struct{ *statsdMetric }
+
+func (h statsdHistogram) With(labelValues ...string) metrics.Histogram {
+	return statsdHistogram{h.statsdMetric.with(labelValues...)}
+}
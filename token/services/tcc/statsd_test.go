@@ -0,0 +1,67 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package tcc_test
+
+import (
+	"net"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	chaincode2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
+	"github.com/hyperledger/fabric/common/metrics"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StatsdProvider", func() {
+	var (
+		server   net.PacketConn
+		provider *chaincode2.StatsdProvider
+	)
+	BeforeEach(func() {
+		var err error
+		server, err = net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		provider, err = chaincode2.NewStatsdProvider(server.LocalAddr().String(), "peer0")
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		Expect(provider.Close()).To(Succeed())
+		Expect(server.Close()).To(Succeed())
+	})
+
+	receive := func() string {
+		buf := make([]byte, 1024)
+		Expect(server.SetReadDeadline(time.Now().Add(5 * time.Second))).To(Succeed())
+		n, _, err := server.ReadFrom(buf)
+		Expect(err).NotTo(HaveOccurred())
+		return string(buf[:n])
+	}
+
+	It("pushes the statistics of the accesses to the ledger", func() {
+		stats := api.NewRWSetStats()
+		stats.Kinds[keys.TokenRequestKeyPrefix] = &api.KeyStats{Writes: 1}
+		stats.MaxKeySize = 42
+		stats.ValueBytes = 1024
+		translator.NewMetrics(provider).Observe(stats)
+
+		Expect(receive()).To(Equal("peer0.token.rwset.writes.token_request:1|c"))
+		Expect(receive()).To(Equal("peer0.token.rwset.max_key_size:42|ms"))
+		Expect(receive()).To(Equal("peer0.token.rwset.value_bytes:1024|ms"))
+	})
+
+	It("pushes gauges", func() {
+		gauge := provider.NewGauge(metrics.GaugeOpts{Namespace: "token", Name: "pending", LabelNames: []string{"channel"}, StatsdFormat: "%{#fqname}.%{channel}"})
+		gauge.With("channel", "ch1").Set(3)
+		Expect(receive()).To(Equal("peer0.token.pending.ch1:3|g"))
+		gauge.With("channel", "ch1").Add(-1)
+		Expect(receive()).To(Equal("peer0.token.pending.ch1:-1|g"))
+		gauge.With("channel", "ch1").Add(2)
+		Expect(receive()).To(Equal("peer0.token.pending.ch1:+2|g"))
+	})
+})
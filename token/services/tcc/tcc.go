@@ -14,6 +14,9 @@ import (
 	"io/ioutil"
 	"os"
 	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
@@ -24,6 +27,9 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
@@ -37,6 +43,14 @@ const (
 	AddIssuerFunction         = "addIssuer"
 	AddCertifierFunction      = "addCertifier"
 	QueryTokensFunctions      = "queryTokens"
+	QueryTokenRequestFunction = "queryTokenRequest"
+	QueryProvenanceFunction   = "queryProvenance"
+	InvokeBatchFunction       = "invokeBatch"
+	MigrateKeysFunction       = "migrateKeys"
+	SetIssuerPolicyFunction   = "setIssuerPolicy"
+	QueryIssuerPolicyFunction = "queryIssuerPolicy"
+	QuerySupplyFunction       = "querySupply"
+	SetLogLevelFunction       = "setLogLevel"
 
 	PublicParamsPathVarEnv = "PUBLIC_PARAMS_FILE_PATH"
 )
@@ -58,7 +72,11 @@ func (i *allIssuersValid) Validate(creator view2.Identity, tokenType string) err
 //go:generate counterfeiter -o mock/validator.go -fake-name Validator . Validator
 
 type Validator interface {
-	UnmarshallAndVerify(ledger token.Ledger, binding string, raw []byte) ([]interface{}, error)
+	UnmarshallAndVerify(ledger token.Ledger, binding string, raw []byte, opts ...token.ValidationOption) ([]interface{}, error)
+	// UnmarshalActions returns the actions of the passed serialized token request, without verifying them
+	UnmarshalActions(raw []byte) ([]interface{}, error)
+	// VerifyBatch verifies all the sub-requests of the passed batch, see token.BatchRequest
+	VerifyBatch(ledger token.Ledger, batch *token.BatchRequest, opts ...token.ValidationOption) ([]*token.SubRequestResult, error)
 }
 
 //go:generate counterfeiter -o mock/public_parameters_manager.go -fake-name PublicParametersManager . PublicParametersManager
@@ -69,44 +87,93 @@ type PublicParametersManager interface {
 	SetCertifier(certifier []byte) ([]byte, error)
 }
 
+// tokenServices is an immutable snapshot of the services instantiated from the public parameters with a given digest
+type tokenServices struct {
+	digest                  []byte
+	publicParametersManager PublicParametersManager
+	validator               Validator
+}
+
+// supplyCaps returns the supply caps declared by the public parameters, nil if the driver does not support them
+func (s *tokenServices) supplyCaps() translator.SupplyCaps {
+	if caps, ok := s.publicParametersManager.(translator.SupplyCaps); ok {
+		return caps
+	}
+	return nil
+}
+
+// servicesCall is an in-flight instantiation of the token services
+type servicesCall struct {
+	done     chan struct{}
+	services *tokenServices
+	err      error
+}
+
 type TokenChaincode struct {
-	LogLevel                string
+	LogLevel string
+	// Validator, PublicParametersManager, and PPDigest optionally preset the services to use for the public
+	// parameters with digest PPDigest. They are never modified by the chaincode.
 	Validator               Validator
 	PublicParametersManager PublicParametersManager
+	PPDigest                []byte
 
-	PPDigest             []byte
 	TokenServicesFactory func([]byte) (PublicParametersManager, Validator, error)
+	// MaxClockSkew bounds the deviation of the transaction timestamp, set by the client, from the local clock,
+	// token.DefaultMaxClockSkew if zero. The approvers validate the token requests at the same time.
+	MaxClockSkew time.Duration
+	// ValidationHooks are additional validation rules, see token.WithIssueHook, token.WithTransferHook, and token.WithRequestHook,
+	// enforced on each token request
+	ValidationHooks []token.ValidationOption
+	// RequestLimits, if set, bounds the size and complexity of the token requests
+	RequestLimits *token.RequestLimits
+	// ValidationCache, if set, caches the actions of the validated token requests,
+	// to avoid validating again the same request when the endorsement is retried
+	ValidationCache *ValidationCache
+	// HeightProvider, if set, returns the ledger height the token requests are validated at, see LedgerHeight.
+	// It is needed to enforce the height locks and the cutover of a driver migration, the chaincode stub does not
+	// expose the height.
+	HeightProvider func(stub shim.ChaincodeStubInterface) (uint64, error)
+	// MaxProvenanceSteps bounds the number of tokens a provenance query walks through, DefaultMaxProvenanceSteps if zero
+	MaxProvenanceSteps int
+	// KeyScheme, if set, derives the ledger keys of the tokens of this chaincode, keys.Default if nil.
+	// Token applications sharing the same namespace must have different key schemes, see keys.NewScheme
+	KeyScheme *keys.Scheme
+	// AdminPolicy, if set, authorizes the invocations of the functions managing the issuer policies,
+	// the log levels and the migration of the keys, see NewMSPAdminPolicy. If nil, they cannot be invoked.
+	AdminPolicy AdminPolicy
+	// MigrateFrom, if set, is the key scheme the keys can be migrated from to KeyScheme, see migrateKeys.
+	// If nil, the keys cannot be migrated.
+	MigrateFrom *keys.Scheme
+	// KeyPolicy, if set, sets the state-based endorsement policies of the created tokens, see NewMSPKeyPolicy
+	KeyPolicy translator.KeyPolicy
+	// InFlight, if set, rejects at endorsement the token requests spending tokens spent by requests endorsed
+	// recently, whose transactions are not committed yet
+	InFlight *InFlightTracker
+	// ParamsSource, if set, provides the public parameters at init, see CollectionParamsSource
+	ParamsSource ParamsSource
+	// Metrics, if set, records the statistics of the accesses to the ledger of the token requests, see translator.NewMetrics
+	Metrics *translator.Metrics
+
+	servicesLock sync.Mutex
+	services     *tokenServices
+	pending      map[string]*servicesCall
 }
 
 func (cc *TokenChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
 	logger.Infof("init token chaincode...")
 
-	params := cc.readParamsFromFile()
-	if params == "" {
-		if len(Params) == 0 {
-			args := stub.GetArgs()
-			// args[0] public parameters
-			if len(args) != 2 {
-				return shim.Error("length of provided arguments != 2")
-			}
-
-			if string(args[0]) != "init" {
-				return shim.Error("expected init function")
-			}
-			params = string(args[1])
-		} else {
-			params = Params
-		}
-	}
-
-	ppRaw, err := base64.StdEncoding.DecodeString(params)
+	ppRaw, err := cc.initParams(stub)
 	if err != nil {
-		return shim.Error("failed to decode public parameters: " + err.Error())
+		return shim.Error(err.Error())
+	}
+	// the public parameters are refused now, if the token services do not accept them, not at the first invocation
+	if _, _, err := cc.TokenServicesFactory(ppRaw); err != nil {
+		return shim.Error(errors.WithMessage(err, "invalid public parameters").Error())
 	}
 
 	issuingValidator := &allIssuersValid{}
 	rwset := &rwsWrapper{stub: stub}
-	w := translator.New(issuingValidator, "", rwset, "")
+	w := cc.newTranslator(issuingValidator, "", rwset)
 	action := &SetupAction{
 		SetupParameters: ppRaw,
 	}
@@ -137,10 +204,23 @@ func (cc *TokenChaincode) Invoke(stub shim.ChaincodeStubInterface) (res pb.Respo
 		logger.Infof("running function [%s]", string(args[0]))
 		switch f := string(args[0]); f {
 		case InvokeFunction:
-			if len(args) != 2 {
+			if len(args) != 2 && len(args) != 3 {
 				return shim.Error("empty token request")
 			}
-			return cc.invoke(args[1], stub)
+			version, err := pinnedVersion(args)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+			return cc.invoke(args[1], version, stub)
+		case InvokeBatchFunction:
+			if len(args) != 2 && len(args) != 3 {
+				return shim.Error("empty batch token request")
+			}
+			version, err := pinnedVersion(args)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+			return cc.invokeBatch(args[1], version, stub)
 		case QueryPublicParamsFunction:
 			return cc.queryPublicParams(stub)
 		case AddAuditorFunction:
@@ -163,6 +243,41 @@ func (cc *TokenChaincode) Invoke(stub shim.ChaincodeStubInterface) (res pb.Respo
 				return shim.Error("request to retrieve tokens is empty")
 			}
 			return cc.queryTokens(args[1], stub)
+		case QueryTokenRequestFunction:
+			if len(args) != 2 {
+				return shim.Error("request to retrieve token request is empty")
+			}
+			return cc.queryTokenRequest(string(args[1]), stub)
+		case QueryProvenanceFunction:
+			if len(args) != 2 {
+				return shim.Error("request to retrieve provenance is empty")
+			}
+			return cc.queryProvenance(args[1], stub)
+		case MigrateKeysFunction:
+			if len(args) != 2 {
+				return shim.Error("request to migrate keys is empty")
+			}
+			return cc.migrateKeys(args[1], stub)
+		case SetIssuerPolicyFunction:
+			if len(args) != 2 {
+				return shim.Error("request to set issuer policy is empty")
+			}
+			return cc.setIssuerPolicy(args[1], stub)
+		case QueryIssuerPolicyFunction:
+			if len(args) != 2 {
+				return shim.Error("request to retrieve issuer policy is empty")
+			}
+			return cc.queryIssuerPolicy(string(args[1]), stub)
+		case QuerySupplyFunction:
+			if len(args) != 2 {
+				return shim.Error("request to retrieve supply is empty")
+			}
+			return cc.querySupply(string(args[1]), stub)
+		case SetLogLevelFunction:
+			if len(args) != 2 {
+				return shim.Error("request to set log level is empty")
+			}
+			return cc.setLogLevel(args[1], stub)
 		default:
 			return shim.Error(fmt.Sprintf("function not [%s] recognized", f))
 		}
@@ -189,93 +304,384 @@ func (cc *TokenChaincode) readParamsFromFile() string {
 }
 
 func (cc *TokenChaincode) publicParametersManager(stub shim.ChaincodeStubInterface) (PublicParametersManager, error) {
-	if err := cc.init(stub); err != nil {
+	services, err := cc.tokenServices(stub)
+	if err != nil {
 		return nil, err
 	}
-	return cc.PublicParametersManager, nil
+	return services.publicParametersManager, nil
 }
 
-func (cc *TokenChaincode) validator(stub shim.ChaincodeStubInterface) (Validator, error) {
-	if err := cc.init(stub); err != nil {
-		return nil, err
-	}
-	return cc.Validator, nil
+// tokenServices returns the services for the public parameters currently on the ledger.
+// The services are instantiated at most once per public parameters digest, even under concurrent invocations.
+func (cc *TokenChaincode) tokenServices(stub shim.ChaincodeStubInterface) (*tokenServices, error) {
+	return cc.tokenServicesAt(stub, 0)
 }
 
-func (cc *TokenChaincode) init(stub shim.ChaincodeStubInterface) error {
-	logger.Infof("reading public parameters...")
+// tokenServicesAt returns the services for the public parameters with the passed version, if still the current ones.
+// Unlike the key of the current public parameters, written by every update, the key of a version is written
+// only when the version is superseded, the reads of a pinned version do not conflict with the other updates.
+// Version zero stands for the current public parameters, whatever their version.
+func (cc *TokenChaincode) tokenServicesAt(stub shim.ChaincodeStubInterface, version uint64) (*tokenServices, error) {
+	logger.Infof("reading public parameters [version %d]...", version)
 
 	rwset := &rwsWrapper{stub: stub}
 	issuingValidator := &allIssuersValid{}
-	w := translator.New(issuingValidator, stub.GetTxID(), rwset, "")
-	ppRaw, err := w.ReadSetupParameters()
+	w := cc.newTranslator(issuingValidator, stub.GetTxID(), rwset)
+	var ppRaw []byte
+	var err error
+	if version == 0 {
+		ppRaw, err = w.ReadSetupParameters()
+	} else {
+		ppRaw, err = w.ReadSetupParametersAt(version)
+	}
 	if err != nil {
-		return errors.Wrapf(err, "failed to retrieve public parameters")
+		return nil, errors.Wrapf(err, "failed to retrieve public parameters")
 	}
 	logger.Infof("public parameters read [%d]", len(ppRaw))
 	if len(ppRaw) == 0 {
-		return errors.Errorf("public parameters are not initiliazed yet")
+		if version != 0 {
+			return nil, errors2.Errorf(errors2.Conflict, "public parameters version [%d] is not the current one", version)
+		}
+		return nil, errors.Errorf("public parameters are not initiliazed yet")
 	}
 	hash := sha256.New()
 	n, err := hash.Write(ppRaw)
 	if n != len(ppRaw) {
-		return errors.New("failed hashing public parameters, bytes not consumed")
+		return nil, errors.New("failed hashing public parameters, bytes not consumed")
 	}
 	if err != nil {
-		return errors.Wrap(err, "failed hashing public parameters")
+		return nil, errors.Wrap(err, "failed hashing public parameters")
 	}
 	digest := hash.Sum(nil)
-	if len(cc.PPDigest) != 0 && cc.Validator != nil && bytes.Equal(digest, cc.PPDigest) {
+
+	cc.servicesLock.Lock()
+	if cc.services == nil && len(cc.PPDigest) != 0 && cc.Validator != nil {
+		cc.services = &tokenServices{
+			digest:                  cc.PPDigest,
+			publicParametersManager: cc.PublicParametersManager,
+			validator:               cc.Validator,
+		}
+	}
+	if cc.services != nil && bytes.Equal(digest, cc.services.digest) {
+		services := cc.services
+		cc.servicesLock.Unlock()
 		logger.Infof("no need instantiate public parameter manager and validator, already set")
-		return nil
+		return services, nil
 	}
+	if call, ok := cc.pending[string(digest)]; ok {
+		cc.servicesLock.Unlock()
+		logger.Infof("wait for the instantiation of public parameter manager and validator...")
+		<-call.done
+		return call.services, call.err
+	}
+	call := &servicesCall{done: make(chan struct{})}
+	if cc.pending == nil {
+		cc.pending = map[string]*servicesCall{}
+	}
+	cc.pending[string(digest)] = call
+	cc.servicesLock.Unlock()
 
 	logger.Infof("instantiate public parameter manager and validator...")
 	ppm, validator, err := cc.TokenServicesFactory(ppRaw)
 	logger.Infof("instantiate public parameter manager and validator done with err [%v]", err)
 	if err != nil {
-		return errors.Wrap(err, "failed to instantiate public parameter manager and validator")
+		call.err = errors.Wrap(err, "failed to instantiate public parameter manager and validator")
+	} else {
+		call.services = &tokenServices{
+			digest:                  digest,
+			publicParametersManager: ppm,
+			validator:               validator,
+		}
 	}
-	cc.PublicParametersManager = ppm
-	cc.Validator = validator
-	cc.PPDigest = digest
 
-	return nil
+	cc.servicesLock.Lock()
+	if call.err == nil {
+		cc.services = call.services
+	}
+	delete(cc.pending, string(digest))
+	cc.servicesLock.Unlock()
+	close(call.done)
+
+	return call.services, call.err
 }
 
-func (cc *TokenChaincode) invoke(raw []byte, stub shim.ChaincodeStubInterface) pb.Response {
-	validator, err := cc.validator(stub)
+func (cc *TokenChaincode) invoke(raw []byte, version uint64, stub shim.ChaincodeStubInterface) pb.Response {
+	services, err := cc.tokenServicesAt(stub, version)
 	if err != nil {
 		return shim.Error(err.Error())
 	}
 
 	// Verify
-	actions, err := validator.UnmarshallAndVerify(stub, stub.GetTxID(), raw)
+	opts, err := cc.validationOptions(stub)
 	if err != nil {
-		return shim.Error("failed to verify token request: " + err.Error())
+		return shim.Error(err.Error())
 	}
+	// the statistics of the accesses to the ledger, to diagnose the conflicts between token requests
+	stats := &token.RWSetStats{}
+	actions, err := cc.verify(services, stub, &statsLedger{ledger: stub, stats: stats}, raw, opts...)
+	if err != nil {
+		response := shim.Error("failed to verify token request: " + err.Error())
+		// return the validation report, if available, to let the client know what went wrong
+		if report, ok := token.GetValidationReport(err); ok {
+			report.RWSet = stats
+			response.Payload, _ = report.Bytes()
+		}
+		return response
+	}
+	if err := cc.acquireInputs(stub.GetTxID(), actions); err != nil {
+		return cc.statsError(api.DoubleSpendCheck, errors.WithMessage(err, "failed to verify token request"), stats)
+	}
+	success := false
+	defer func() {
+		if !success {
+			cc.releaseInputs(stub.GetTxID())
+		}
+	}()
 
 	// Write
-	rwset := &rwsWrapper{stub: stub}
-	issuingValidator := &allIssuersValid{}
-	w := translator.New(issuingValidator, stub.GetTxID(), rwset, "")
+	events := translator.NewEventRWSet(&rwsWrapper{stub: stub}, stub.GetTxID())
+	rwset := translator.NewStatsRWSet(events, stats)
+	issuingValidator := translator.NewPolicyIssuingValidator(rwset, "", cc.KeyScheme)
+	w := cc.newTranslator(issuingValidator, stub.GetTxID(), rwset)
+	w.SupplyCaps = services.supplyCaps()
 	for _, action := range actions {
 		err = w.Write(action)
 		if err != nil {
-			return shim.Error("failed to write token action: " + err.Error())
+			return cc.statsError(api.WriteCheck, errors.WithMessage(err, "failed to write token action"), stats)
 		}
 	}
 	err = w.CommitTokenRequest(raw)
 	if err != nil {
-		return shim.Error("failed to write token request:" + err.Error())
+		return cc.statsError(api.WriteCheck, errors.WithMessage(err, "failed to write token request"), stats)
+	}
+	// emit the keys written by the request, to let the clients learn the tokens created and spent without parsing the rwset
+	event, err := events.Event().Bytes()
+	if err != nil {
+		return shim.Error("failed to marshal token event: " + err.Error())
 	}
+	if err := stub.SetEvent(translator.TokenEventName, event); err != nil {
+		return shim.Error("failed to set token event: " + err.Error())
+	}
+	// the stats are kept out of the payload, it must be the same on all the endorsers
+	cc.observe(stats)
+	success = true
 	return shim.Success(nil)
 }
 
+// statsError returns an error response carrying a validation report with the passed stats, where the token request
+// as a whole failed the passed check with the passed error
+func (cc *TokenChaincode) statsError(check api.ValidationCheck, err error, stats *token.RWSetStats) pb.Response {
+	report := &token.ValidationReport{RWSet: stats}
+	response := shim.Error(report.Failed(api.RequestActionType, 0, check, err).Error())
+	response.Payload, _ = report.Bytes()
+	return response
+}
+
+// observe records the passed stats in the metrics of the chaincode, if any
+func (cc *TokenChaincode) observe(stats *token.RWSetStats) {
+	logger.Debugf("ledger accesses [reads:%d,writes:%d,deletes:%d]", stats.Reads, stats.Writes, stats.Deletes)
+	if cc.Metrics != nil {
+		cc.Metrics.Observe(stats)
+	}
+}
+
+// invokeBatch validates and commits the sub-requests of the passed batch token request, according to the mode of the batch.
+// Each sub-request is committed as if it was in its own transaction, with its binding as transaction id.
+// The payload of the response is a token.BatchReport listing the rejected sub-requests, if any.
+// Batches are submitted by the operator, see token.BatchRequest.
+func (cc *TokenChaincode) invokeBatch(raw []byte, version uint64, stub shim.ChaincodeStubInterface) pb.Response {
+	services, err := cc.tokenServicesAt(stub, version)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	batch := token.NewBatchRequest()
+	if err := batch.FromBytes(raw); err != nil {
+		return shim.Error("failed to unmarshal batch token request: " + err.Error())
+	}
+
+	// Verify
+	opts, err := cc.validationOptions(stub)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	stats := &token.RWSetStats{}
+	results, err := services.validator.VerifyBatch(&statsLedger{ledger: stub, stats: stats}, batch, opts...)
+	if err != nil {
+		response := shim.Error("failed to verify batch token request: " + err.Error())
+		if report, ok := token.GetValidationReport(err); ok {
+			report.RWSet = stats
+			response.Payload, _ = report.Bytes()
+		}
+		return response
+	}
+	// the results depend only on the batch and on the ledger, then they are the same on all the endorsers
+	var actions []interface{}
+	spentBy := map[string]string{}
+	for i, r := range batch.Requests {
+		if results[i].Err != nil {
+			continue
+		}
+		if err := spendsOnce(results[i].Actions, r.Binding, spentBy); err != nil {
+			if batch.Mode == token.AllOrNothing {
+				return cc.statsError(api.DoubleSpendCheck, errors.WithMessagef(err, "failed to verify batch token request: sub-request [%d][%s] is not valid", i, r.Binding), stats)
+			}
+			results[i] = &token.SubRequestResult{Binding: r.Binding, Err: err, Check: api.DoubleSpendCheck}
+			continue
+		}
+		actions = append(actions, results[i].Actions...)
+	}
+	// the in-flight inputs are local to this peer, a conflict fails the whole endorsement and never the single
+	// sub-requests, whose results would differ between the endorsers
+	if err := cc.acquireInputs(stub.GetTxID(), actions); err != nil {
+		return cc.statsError(api.DoubleSpendCheck, errors.WithMessage(err, "failed to verify batch token request"), stats)
+	}
+	success := false
+	defer func() {
+		if !success {
+			cc.releaseInputs(stub.GetTxID())
+		}
+	}()
+
+	// Write
+	events := translator.NewEventRWSet(&rwsWrapper{stub: stub}, stub.GetTxID())
+	rwset := translator.NewStatsRWSet(events, stats)
+	entries := make([]*translator.BatchEntry, len(batch.Requests))
+	for i, r := range batch.Requests {
+		entries[i] = &translator.BatchEntry{Binding: r.Binding, Request: r.Request, Actions: results[i].Actions}
+		if results[i].Err != nil {
+			logger.Debugf("sub-request [%d][%s] of batch [%s] rejected [%s]", i, r.Binding, stub.GetTxID(), results[i].Err)
+			entries[i].Rejection = results[i].Check
+			if len(entries[i].Rejection) == 0 {
+				entries[i].Rejection = api.RejectionCheck(results[i].Err)
+			}
+		}
+	}
+	rejected, err := translator.WriteBatch(translator.NewPolicyIssuingValidator(rwset, "", cc.KeyScheme), rwset, "", cc.KeyScheme, services.supplyCaps(), cc.KeyPolicy, entries, batch.Mode == token.SkipInvalid)
+	if err != nil {
+		return cc.statsError(api.WriteCheck, errors.WithMessage(err, "failed to write batch token request"), stats)
+	}
+	// emit the keys written by all the sub-requests, the rejections included, as invoke does for a single request
+	event, err := events.Event().Bytes()
+	if err != nil {
+		return shim.Error("failed to marshal token event: " + err.Error())
+	}
+	if err := stub.SetEvent(translator.TokenEventName, event); err != nil {
+		return shim.Error("failed to set token event: " + err.Error())
+	}
+	cc.observe(stats)
+	report := &token.BatchReport{}
+	for i, entry := range entries {
+		if len(entry.Rejection) == 0 {
+			continue
+		}
+		report.Rejected = append(report.Rejected, &token.RejectedSubRequest{
+			Index:   i,
+			Binding: entry.Binding,
+			Check:   entry.Rejection,
+		})
+	}
+	raw, err = report.Bytes()
+	if err != nil {
+		return shim.Error("failed to marshal batch report: " + err.Error())
+	}
+	logger.Debugf("batch [%s] of [%d] token requests committed, [%d] rejected", stub.GetTxID(), len(entries), len(rejected))
+	success = true
+	return shim.Success(raw)
+}
+
+// acquireInputs marks the inputs of the passed actions as spent by the passed transaction, if InFlight is set
+func (cc *TokenChaincode) acquireInputs(txID string, actions []interface{}) error {
+	if cc.InFlight == nil {
+		return nil
+	}
+	inputs, err := inputsOf(actions)
+	if err != nil {
+		return err
+	}
+	return cc.InFlight.Acquire(txID, inputs)
+}
+
+func (cc *TokenChaincode) releaseInputs(txID string) {
+	if cc.InFlight != nil {
+		cc.InFlight.Release(txID)
+	}
+}
+
+// pinnedVersion returns the version of the public parameters pinned by the token request in the passed arguments,
+// if any, zero otherwise
+func pinnedVersion(args [][]byte) (uint64, error) {
+	if len(args) < 3 {
+		return 0, nil
+	}
+	version, err := strconv.ParseUint(string(args[2]), 10, 64)
+	if err != nil || version == 0 {
+		return 0, errors.Errorf("invalid public parameters version [%s]", string(args[2]))
+	}
+	return version, nil
+}
+
+// newTranslator returns a translator over the passed rwset, deriving the keys with the key scheme of the chaincode
+func (cc *TokenChaincode) newTranslator(issuingValidator translator.IssuingValidator, txID string, rwset translator.RWSet) *translator.Translator {
+	w := translator.New(issuingValidator, txID, rwset, "")
+	w.Keys = cc.KeyScheme
+	w.KeyPolicy = cc.KeyPolicy
+	return w
+}
+
+// validationOptions returns the options to validate the token requests of the transaction of the passed stub
+func (cc *TokenChaincode) validationOptions(stub shim.ChaincodeStubInterface) ([]token.ValidationOption, error) {
+	opts := append([]token.ValidationOption{}, cc.ValidationHooks...)
+	if cc.RequestLimits != nil {
+		opts = append(opts, token.WithRequestLimits(cc.RequestLimits))
+	}
+	ts, err := stub.GetTxTimestamp()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get transaction timestamp")
+	}
+	if ts != nil {
+		txTime := time.Unix(ts.Seconds, int64(ts.Nanos))
+		if err := token.CheckTxTime(txTime, time.Now(), cc.MaxClockSkew); err != nil {
+			return nil, errors.Wrap(err, "invalid transaction timestamp")
+		}
+		opts = append(opts, token.WithTxTime(txTime))
+	}
+	if cc.HeightProvider != nil {
+		height, err := cc.HeightProvider(stub)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get ledger height")
+		}
+		opts = append(opts, token.WithHeight(height))
+	}
+	return opts, nil
+}
+
+func (cc *TokenChaincode) verify(services *tokenServices, stub shim.ChaincodeStubInterface, ledger token.Ledger, raw []byte, opts ...token.ValidationOption) ([]interface{}, error) {
+	if cc.ValidationCache == nil {
+		return services.validator.UnmarshallAndVerify(ledger, stub.GetTxID(), raw, opts...)
+	}
+
+	key, err := validationCacheKey(services.digest, stub.GetTxID(), raw, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// the recorded reads are replayed on the passed ledger, so that its statistics account for them
+	if actions, ok := cc.ValidationCache.Get(key, ledger); ok {
+		logger.Debugf("token request [%s] already validated, using cached actions", stub.GetTxID())
+		return actions, nil
+	}
+	recorder := &recordingLedger{ledger: ledger}
+	actions, err := services.validator.UnmarshallAndVerify(recorder, stub.GetTxID(), raw, opts...)
+	if err != nil {
+		return nil, err
+	}
+	cc.ValidationCache.Add(key, actions, recorder.reads)
+	return actions, nil
+}
+
 func (cc *TokenChaincode) queryPublicParams(stub shim.ChaincodeStubInterface) pb.Response {
 	rwset := &rwsWrapper{stub: stub}
 	issuingValidator := &allIssuersValid{}
-	w := translator.New(issuingValidator, stub.GetTxID(), rwset, "")
+	w := cc.newTranslator(issuingValidator, stub.GetTxID(), rwset)
 	raw, err := w.ReadSetupParameters()
 	if err != nil {
 		shim.Error("failed to retrieve public parameters: " + err.Error())
@@ -299,7 +705,7 @@ func (cc *TokenChaincode) addIssuer(args [][]byte, stub shim.ChaincodeStubInterf
 
 	issuingValidator := &allIssuersValid{}
 	rwset := &rwsWrapper{stub: stub}
-	w := translator.New(issuingValidator, "", rwset, "")
+	w := cc.newTranslator(issuingValidator, "", rwset)
 	setupAction := &SetupAction{SetupParameters: raw}
 	if err := w.Write(setupAction); err != nil {
 		return shim.Error("failed to update issuing policy: " + err.Error())
@@ -330,7 +736,7 @@ func (cc *TokenChaincode) addAuditor(auditor []byte, stub shim.ChaincodeStubInte
 
 	// TODO: seems redundant
 	logger.Infof("translate...")
-	w := &translator.Translator{RWSet: &rwsWrapper{stub: stub}}
+	w := &translator.Translator{RWSet: &rwsWrapper{stub: stub}, Keys: cc.KeyScheme}
 	setupAction := &SetupAction{SetupParameters: raw}
 	if err := w.Write(setupAction); err != nil {
 		return shim.Error("failed to write auditor key")
@@ -352,7 +758,7 @@ func (cc *TokenChaincode) addCertifier(certifier []byte, stub shim.ChaincodeStub
 		return shim.Error(err.Error())
 	}
 
-	w := &translator.Translator{RWSet: &rwsWrapper{stub: stub}}
+	w := &translator.Translator{RWSet: &rwsWrapper{stub: stub}, Keys: cc.KeyScheme}
 	setupAction := &SetupAction{SetupParameters: raw}
 	if err := w.Write(setupAction); err != nil {
 		return shim.Error("failed to write auditor key")
@@ -371,7 +777,7 @@ func (cc *TokenChaincode) queryTokens(idsRaw []byte, stub shim.ChaincodeStubInte
 
 	rwset := &rwsWrapper{stub: stub}
 	issuingValidator := &allIssuersValid{}
-	w := translator.New(issuingValidator, stub.GetTxID(), rwset, "")
+	w := cc.newTranslator(issuingValidator, stub.GetTxID(), rwset)
 	res, err := w.QueryTokens(ids)
 	if err != nil {
 		logger.Errorf("failed query tokens [%v]: [%s]", ids, err)
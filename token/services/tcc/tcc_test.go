@@ -7,9 +7,30 @@ package tcc_test
 
 import (
 	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
 	chaincode2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc/mock"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
+	mock2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator/mock"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
@@ -41,10 +62,61 @@ var _ = Describe("ccvalidator", func() {
 	})
 	Describe("Init", func() {
 		Context("when init is called correctly", func() {
+			BeforeEach(func() {
+				// no public parameters have been versioned yet
+				fakestub.GetStateReturnsOnCall(0, nil, nil)
+			})
 			It("Succeeds", func() {
 				response := chaincode.Init(fakestub)
 				Expect(response).NotTo(BeNil())
 				Expect(response.Status).To(Equal(int32(200)))
+
+				// the public parameters are stored as the first version
+				versionKey, err := keys.CreateSetupVersionKey()
+				Expect(err).NotTo(HaveOccurred())
+				v1Key, err := keys.CreateVersionedSetupKey(1)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakestub.PutStateCallCount()).To(Equal(3))
+				key, value := fakestub.PutStateArgsForCall(1)
+				Expect(key).To(Equal(v1Key))
+				Expect(value).To(Equal([]byte("public parameters")))
+				key, value = fakestub.PutStateArgsForCall(2)
+				Expect(key).To(Equal(versionKey))
+				Expect(value).To(Equal([]byte("1")))
+				Expect(fakestub.DelStateCallCount()).To(Equal(0))
+			})
+		})
+		Context("when the public parameters are read from a collection", func() {
+			BeforeEach(func() {
+				fakestub.GetStateReturnsOnCall(0, nil, nil)
+				fakestub.GetArgsReturns(nil)
+				chaincode.ParamsSource = &chaincode2.CollectionParamsSource{Collection: "params"}
+			})
+			It("Succeeds", func() {
+				fakestub.GetPrivateDataReturns([]byte("public parameters"), nil)
+				response := chaincode.Init(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+
+				collection, key := fakestub.GetPrivateDataArgsForCall(0)
+				Expect(collection).To(Equal("params"))
+				Expect(key).To(Equal(chaincode2.PublicParamsCollectionKey))
+				v1Key, err := keys.CreateVersionedSetupKey(1)
+				Expect(err).NotTo(HaveOccurred())
+				key, value := fakestub.PutStateArgsForCall(1)
+				Expect(key).To(Equal(v1Key))
+				Expect(value).To(Equal([]byte("public parameters")))
+			})
+			It("Falls back to the init arguments when the collection has none", func() {
+				fakestub.GetPrivateDataReturns(nil, nil)
+				fakestub.GetArgsReturns([][]byte{[]byte("init"), []byte(base64.StdEncoding.EncodeToString([]byte("public parameters")))})
+				response := chaincode.Init(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+			})
+			It("Fails when the collection cannot be read", func() {
+				fakestub.GetPrivateDataReturns(nil, errors.New("collection not found"))
+				response := chaincode.Init(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("failed reading public parameters from collection [params]: collection not found"))
 			})
 		})
 	})
@@ -99,7 +171,8 @@ var _ = Describe("ccvalidator", func() {
 					response := chaincode.Invoke(fakestub)
 					Expect(response).NotTo(BeNil())
 					Expect(response.Status).To(Equal(int32(200)))
-					Expect(fakestub.GetStateCallCount()).To(Equal(1))
+					// the public parameters and their version
+					Expect(fakestub.GetStateCallCount()).To(Equal(2))
 				})
 			})
 			Context("chaincode fails to add issuer", func() {
@@ -169,6 +242,705 @@ var _ = Describe("ccvalidator", func() {
 				Expect(response).NotTo(BeNil())
 				Expect(response.Status).To(Equal(int32(200)))
 			})
+			It("rejects a transaction time too far from the local time", func() {
+				fakestub.GetStateReturnsOnCall(1, []byte("public parameters"), nil)
+				fakestub.GetTxTimestampReturns(&timestamp.Timestamp{Seconds: time.Now().Add(-time.Hour).Unix()}, nil)
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("invalid transaction timestamp"))
+
+				chaincode.MaxClockSkew = 2 * time.Hour
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				Expect(fakeValidator.UnmarshallAndVerifyCallCount()).To(Equal(1))
+			})
+			It("keeps the statistics of the accesses to the ledger out of the endorsed payload", func() {
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+				Expect(response.Payload).To(BeNil())
+			})
+			It("emits the keys written by the request as an event", func() {
+				fakestub.GetTxIDReturns("tx1")
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+
+				Expect(fakestub.SetEventCallCount()).To(Equal(1))
+				name, payload := fakestub.SetEventArgsForCall(0)
+				Expect(name).To(Equal(translator.TokenEventName))
+				event := &translator.TokenEvent{}
+				Expect(event.FromBytes(payload)).To(Succeed())
+				Expect(event.TxID).To(Equal("tx1"))
+				Expect(event.Writes).To(HaveLen(1))
+				Expect(event.Writes[0].Deleted).To(BeFalse())
+				// the values are not duplicated in the event
+				Expect(string(payload)).NotTo(ContainSubstring(base64.StdEncoding.EncodeToString([]byte("token request"))))
+			})
+			It("records the statistics in the metrics", func() {
+				provider := &metricsfakes.Provider{}
+				writes := &metricsfakes.Counter{}
+				writes.WithReturns(writes)
+				provider.NewCounterStub = func(opts metrics.CounterOpts) metrics.Counter {
+					if opts.Name == "writes" {
+						return writes
+					}
+					c := &metricsfakes.Counter{}
+					c.WithReturns(c)
+					return c
+				}
+				provider.NewHistogramReturns(&metricsfakes.Histogram{})
+				chaincode.Metrics = translator.NewMetrics(provider)
+
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+				Expect(writes.WithCallCount()).To(Equal(1))
+				Expect(writes.WithArgsForCall(0)).To(Equal([]string{"kind", keys.TokenRequestKeyPrefix}))
+				Expect(writes.AddArgsForCall(0)).To(Equal(float64(1)))
+			})
+		})
+
+		Context("Invoke is called with a pinned version of the public parameters", func() {
+			var setupKey, v2Key string
+			BeforeEach(func() {
+				var err error
+				setupKey, err = keys.CreateSetupKey()
+				Expect(err).NotTo(HaveOccurred())
+				v2Key, err = keys.CreateVersionedSetupKey(2)
+				Expect(err).NotTo(HaveOccurred())
+				fakestub.GetStateReturnsOnCall(0, nil, nil)
+				fakestub.GetStateStub = func(key string) ([]byte, error) {
+					if key == v2Key {
+						return []byte("public parameters"), nil
+					}
+					return nil, nil
+				}
+				fakeValidator.UnmarshallAndVerifyReturns([]interface{}{}, nil)
+			})
+			It("reads only the pinned version", func() {
+				fakestub.GetArgsReturns([][]byte{[]byte("invoke"), []byte("token request"), []byte("2")})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+				Expect(fakestub.GetStateArgsForCall(0)).To(Equal(v2Key))
+				for i := 0; i < fakestub.GetStateCallCount(); i++ {
+					Expect(fakestub.GetStateArgsForCall(i)).NotTo(Equal(setupKey))
+				}
+			})
+			It("fails if the pinned version has been superseded", func() {
+				fakestub.GetArgsReturns([][]byte{[]byte("invoke"), []byte("token request"), []byte("1")})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("public parameters version [1] is not the current one"))
+				Expect(fakeValidator.UnmarshallAndVerifyCallCount()).To(Equal(0))
+			})
+			It("fails if the pinned version is invalid", func() {
+				fakestub.GetArgsReturns([][]byte{[]byte("invoke"), []byte("token request"), []byte("0")})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("invalid public parameters version [0]"))
+			})
+			It("supersedes the current version when the public parameters are updated", func() {
+				versionKey, err := keys.CreateSetupVersionKey()
+				Expect(err).NotTo(HaveOccurred())
+				fakestub.GetStateStub = func(key string) ([]byte, error) {
+					switch key {
+					case setupKey, v2Key:
+						return []byte("public parameters"), nil
+					case versionKey:
+						return []byte("2"), nil
+					}
+					return nil, nil
+				}
+				fakePPM.AddIssuerReturns([]byte("new public parameters"), nil)
+				fakestub.GetArgsReturns([][]byte{[]byte("addIssuer"), []byte("issuer")})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+
+				Expect(fakestub.DelStateCallCount()).To(Equal(1))
+				Expect(fakestub.DelStateArgsForCall(0)).To(Equal(v2Key))
+				v3Key, err := keys.CreateVersionedSetupKey(3)
+				Expect(err).NotTo(HaveOccurred())
+				written := map[string]string{}
+				for i := 0; i < fakestub.PutStateCallCount(); i++ {
+					key, value := fakestub.PutStateArgsForCall(i)
+					written[key] = string(value)
+				}
+				Expect(written).To(Equal(map[string]string{
+					setupKey:   "new public parameters",
+					v3Key:      "new public parameters",
+					versionKey: "3",
+				}))
+			})
+		})
+
+		Context("Issuer policies are managed", func() {
+			var policyKey string
+			var raw []byte
+			BeforeEach(func() {
+				var err error
+				policyKey, err = keys.CreateIssuerPolicyKey("USD")
+				Expect(err).NotTo(HaveOccurred())
+				raw, err = (&translator.IssuerPolicy{Type: "USD", Issuers: []view.Identity{[]byte("alice")}}).Bytes()
+				Expect(err).NotTo(HaveOccurred())
+				fakestub.GetArgsReturns([][]byte{[]byte("setIssuerPolicy"), raw})
+			})
+			It("refuses to set a policy without an admin policy", func() {
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("no admin policy set"))
+				Expect(fakestub.PutStateCallCount()).To(Equal(0))
+			})
+			It("refuses to set a policy if the creator is not an admin", func() {
+				chaincode.AdminPolicy = func(stub shim.ChaincodeStubInterface) error {
+					return errors.New("not an admin")
+				}
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("not an admin"))
+				Expect(fakestub.PutStateCallCount()).To(Equal(0))
+			})
+			It("sets the policy if the creator is an admin", func() {
+				chaincode.AdminPolicy = func(stub shim.ChaincodeStubInterface) error {
+					return nil
+				}
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+				Expect(fakestub.PutStateCallCount()).To(Equal(1))
+				key, value := fakestub.PutStateArgsForCall(0)
+				Expect(key).To(Equal(policyKey))
+				Expect(value).To(Equal(raw))
+			})
+			It("returns the policy", func() {
+				fakestub.GetStateStub = func(key string) ([]byte, error) {
+					if key == policyKey {
+						return raw, nil
+					}
+					return nil, nil
+				}
+				fakestub.GetArgsReturns([][]byte{[]byte("queryIssuerPolicy"), []byte("USD")})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+				Expect(response.Payload).To(Equal(raw))
+			})
+		})
+
+		Context("Log levels are set at runtime", func() {
+			var raw []byte
+			BeforeEach(func() {
+				var err error
+				raw, err = (&chaincode2.LogLevelRequest{Subsystem: "validator", Level: "debug"}).Bytes()
+				Expect(err).NotTo(HaveOccurred())
+				fakestub.GetArgsReturns([][]byte{[]byte("setLogLevel"), raw})
+			})
+			AfterEach(func() {
+				_, err := chaincode2.SetLogLevel("validator", "info")
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("refuses to set a log level without an admin policy", func() {
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("no admin policy set"))
+			})
+			It("refuses to set a log level if the creator is not an admin", func() {
+				chaincode.AdminPolicy = func(stub shim.ChaincodeStubInterface) error {
+					return errors.New("not an admin")
+				}
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("not an admin"))
+			})
+			It("sets the log level of the subsystem if the creator is an admin", func() {
+				chaincode.AdminPolicy = func(stub shim.ChaincodeStubInterface) error {
+					return nil
+				}
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+				Expect(string(response.Payload)).To(ContainSubstring("token-sdk.fabtoken=debug:token-sdk.zkatdlog=debug"))
+				Expect(fakestub.PutStateCallCount()).To(Equal(0))
+			})
+			It("refuses an unknown subsystem", func() {
+				chaincode.AdminPolicy = func(stub shim.ChaincodeStubInterface) error {
+					return nil
+				}
+				raw, err := (&chaincode2.LogLevelRequest{Subsystem: "orderer", Level: "debug"}).Bytes()
+				Expect(err).NotTo(HaveOccurred())
+				fakestub.GetArgsReturns([][]byte{[]byte("setLogLevel"), raw})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("unknown subsystem [orderer]"))
+			})
+		})
+
+		Context("The supply of a token type is queried", func() {
+			It("returns the supply stored on the ledger", func() {
+				supplyKey, err := keys.CreateSupplyKey("USD")
+				Expect(err).NotTo(HaveOccurred())
+				raw, err := (&translator.Supply{Type: "USD", Issued: 42}).Bytes()
+				Expect(err).NotTo(HaveOccurred())
+				fakestub.GetStateStub = func(key string) ([]byte, error) {
+					if key == supplyKey {
+						return raw, nil
+					}
+					return nil, nil
+				}
+				fakestub.GetArgsReturns([][]byte{[]byte("querySupply"), []byte("USD")})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+				Expect(response.Payload).To(Equal(raw))
+			})
+			It("returns a zero supply for the types never issued", func() {
+				fakestub.GetStateStub = func(key string) ([]byte, error) {
+					return nil, nil
+				}
+				fakestub.GetArgsReturns([][]byte{[]byte("querySupply"), []byte("EUR")})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+				supply := &translator.Supply{}
+				Expect(supply.FromBytes(response.Payload)).To(Succeed())
+				Expect(supply).To(Equal(&translator.Supply{Type: "EUR"}))
+			})
+		})
+
+		Context("Invoke is called with validation hooks", func() {
+			BeforeEach(func() {
+				args := make([][]byte, 2)
+				args[0] = []byte("invoke")
+				args[1] = []byte("token request")
+				fakestub.GetArgsReturns(args)
+				fakeValidator.UnmarshallAndVerifyReturns([]interface{}{}, nil)
+				chaincode.ValidationHooks = []token.ValidationOption{
+					token.WithIssueHook(func(ledger api.Ledger, index int, action api.IssueAction) error {
+						return nil
+					}),
+				}
+			})
+			It("passes the hooks to the validator", func() {
+				response := chaincode.Invoke(fakestub)
+				Expect(response).NotTo(BeNil())
+				Expect(response.Status).To(Equal(int32(200)))
+
+				Expect(fakeValidator.UnmarshallAndVerifyCallCount()).To(Equal(1))
+				_, _, _, opts := fakeValidator.UnmarshallAndVerifyArgsForCall(0)
+				compiled, err := api.CompileValidationOptions(opts...)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(compiled.IssueHooks).To(HaveLen(1))
+			})
+		})
+
+		Context("Invoke is called with the ledger height of the peer", func() {
+			BeforeEach(func() {
+				fakestub.GetArgsReturns([][]byte{[]byte("invoke"), []byte("token request")})
+				fakestub.GetChannelIDReturns("testchannel")
+				fakeValidator.UnmarshallAndVerifyReturns([]interface{}{}, nil)
+				chaincode.HeightProvider = chaincode2.LedgerHeight
+			})
+			It("validates at the height of the chain", func() {
+				info, err := proto.Marshal(&common.BlockchainInfo{Height: 10})
+				Expect(err).NotTo(HaveOccurred())
+				fakestub.InvokeChaincodeReturns(pb.Response{Status: shim.OK, Payload: info})
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+
+				name, args, channel := fakestub.InvokeChaincodeArgsForCall(0)
+				Expect(name).To(Equal("qscc"))
+				Expect(args).To(Equal([][]byte{[]byte("GetChainInfo"), []byte("testchannel")}))
+				Expect(channel).To(BeEmpty())
+				_, _, _, opts := fakeValidator.UnmarshallAndVerifyArgsForCall(0)
+				compiled, err := api.CompileValidationOptions(opts...)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(compiled.Height).To(Equal(uint64(10)))
+			})
+			It("fails if the height is not available", func() {
+				fakestub.InvokeChaincodeReturns(pb.Response{Status: shim.ERROR, Message: "access denied"})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("failed to get ledger height: failed querying chain info: [500] access denied"))
+				Expect(fakeValidator.UnmarshallAndVerifyCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("Invoke is called with a validation cache", func() {
+			var value []byte
+			BeforeEach(func() {
+				args := make([][]byte, 2)
+				args[0] = []byte("invoke")
+				args[1] = []byte("token request")
+				fakestub.GetArgsReturns(args)
+				fakestub.GetTxIDReturns("tx1")
+				value = []byte("unspent")
+				fakestub.GetStateStub = func(key string) ([]byte, error) {
+					switch {
+					case key == "token":
+						return value, nil
+					case strings.Contains(key, "setup"):
+						return []byte("public parameters"), nil
+					default:
+						return nil, nil
+					}
+				}
+				fakeValidator.UnmarshallAndVerifyStub = func(ledger token.Ledger, binding string, raw []byte, opts ...token.ValidationOption) ([]interface{}, error) {
+					if _, err := ledger.GetState("token"); err != nil {
+						return nil, err
+					}
+					return []interface{}{}, nil
+				}
+				chaincode.ValidationCache = chaincode2.NewValidationCache(10, time.Minute)
+			})
+			It("validates the same request only once", func() {
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				Expect(fakeValidator.UnmarshallAndVerifyCallCount()).To(Equal(1))
+				Expect(chaincode.ValidationCache.Len()).To(Equal(1))
+			})
+			It("validates again the same request under a different transaction id", func() {
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				fakestub.GetTxIDReturns("tx2")
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				Expect(fakeValidator.UnmarshallAndVerifyCallCount()).To(Equal(2))
+				Expect(chaincode.ValidationCache.Len()).To(Equal(2))
+			})
+			It("validates again the same request if the state it depends on changed", func() {
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				value = []byte("changed")
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				Expect(fakeValidator.UnmarshallAndVerifyCallCount()).To(Equal(2))
+			})
+			It("validates again the same request at a different transaction time or height", func() {
+				now := time.Now().Unix()
+				fakestub.GetTxTimestampReturns(&timestamp.Timestamp{Seconds: now}, nil)
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				fakestub.GetTxTimestampReturns(&timestamp.Timestamp{Seconds: now + 1}, nil)
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				Expect(fakeValidator.UnmarshallAndVerifyCallCount()).To(Equal(2))
+
+				chaincode.HeightProvider = func(stub shim.ChaincodeStubInterface) (uint64, error) {
+					return 10, nil
+				}
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				Expect(fakeValidator.UnmarshallAndVerifyCallCount()).To(Equal(3))
+				Expect(chaincode.ValidationCache.Len()).To(Equal(3))
+			})
+			It("reports the reads of the cached validation", func() {
+				first := chaincode.Invoke(fakestub)
+				Expect(first.Status).To(Equal(int32(200)))
+				second := chaincode.Invoke(fakestub)
+				Expect(second.Status).To(Equal(int32(200)))
+				Expect(fakeValidator.UnmarshallAndVerifyCallCount()).To(Equal(1))
+				Expect(second.Payload).To(Equal(first.Payload))
+			})
+			It("does not cache failed validations", func() {
+				fakeValidator.UnmarshallAndVerifyStub = nil
+				fakeValidator.UnmarshallAndVerifyReturns(nil, errors.Errorf("flying monkeys"))
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(500)))
+				Expect(chaincode.ValidationCache.Len()).To(Equal(0))
+			})
+			It("evicts the least recently used entries", func() {
+				chaincode.ValidationCache = chaincode2.NewValidationCache(1, time.Minute)
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				fakestub.GetTxIDReturns("tx2")
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				fakestub.GetTxIDReturns("tx1")
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				Expect(fakeValidator.UnmarshallAndVerifyCallCount()).To(Equal(3))
+				Expect(chaincode.ValidationCache.Len()).To(Equal(1))
+			})
+		})
+
+		Context("Invoke is called with in-flight tracking", func() {
+			BeforeEach(func() {
+				fakestub.GetArgsReturns([][]byte{[]byte("invoke"), []byte("token request")})
+				fakestub.GetTxIDReturns("tx1")
+				in, err := keys.CreateTokenKey("tx0", 0)
+				Expect(err).NotTo(HaveOccurred())
+				fakestub.GetStateStub = func(key string) ([]byte, error) {
+					switch {
+					case strings.Contains(key, "setup"):
+						return []byte("public parameters"), nil
+					case key == in:
+						return []byte("token"), nil
+					default:
+						return nil, nil
+					}
+				}
+				transfer := &mock2.TransferAction{}
+				transfer.GetInputsReturns([]string{in}, nil)
+				fakeValidator.UnmarshallAndVerifyReturns([]interface{}{transfer}, nil)
+				chaincode.InFlight = chaincode2.NewInFlightTracker(time.Minute)
+			})
+			It("rejects a request spending the inputs of a request not committed yet", func() {
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				// the endorsement of the same transaction can be retried
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+
+				fakestub.GetTxIDReturns("tx2")
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("is being spent by transaction [tx1]"))
+
+				report := &api.ValidationReport{}
+				Expect(json.Unmarshal(response.Payload, report)).To(Succeed())
+				Expect(report.Failure()).NotTo(BeNil())
+				Expect(report.Failure().Type).To(Equal(api.RequestActionType))
+				Expect(report.Failure().Check).To(Equal(api.DoubleSpendCheck))
+				Expect(report.Failure().Error).To(ContainSubstring("is being spent by transaction [tx1]"))
+
+				// the clients branch on the code of the rejection
+				err := token.ResponseError(response.Message, response.Payload)
+				Expect(errors2.HasCode(err, errors2.DoubleSpend)).To(BeTrue())
+				Expect(err.Error()).To(Equal(response.Message))
+			})
+			It("accepts the request once the lease expires", func() {
+				chaincode.InFlight = chaincode2.NewInFlightTracker(10 * time.Millisecond)
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				time.Sleep(20 * time.Millisecond)
+				fakestub.GetTxIDReturns("tx2")
+				Expect(chaincode.Invoke(fakestub).Status).To(Equal(int32(200)))
+				Expect(chaincode.InFlight.Len()).To(Equal(1))
+			})
+			It("releases the inputs of a request that failed", func() {
+				fakestub.PutStateReturns(errors.New("flying monkeys"))
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(chaincode.InFlight.Len()).To(Equal(0))
+
+				report := &api.ValidationReport{}
+				Expect(json.Unmarshal(response.Payload, report)).To(Succeed())
+				Expect(report.Failure().Check).To(Equal(api.WriteCheck))
+				Expect(report.Failure().Error).To(ContainSubstring("flying monkeys"))
+			})
+		})
+
+		Context("Invoke is called concurrently", func() {
+			var instantiations int32
+			BeforeEach(func() {
+				args := make([][]byte, 2)
+				args[0] = []byte("invoke")
+				args[1] = []byte("token request")
+				fakestub.GetArgsReturns(args)
+				fakestub.GetStateStub = func(key string) ([]byte, error) {
+					if strings.Contains(key, "setup") {
+						return []byte("public parameters"), nil
+					}
+					return nil, nil
+				}
+				fakeValidator.UnmarshallAndVerifyReturns([]interface{}{}, nil)
+				instantiations = 0
+				chaincode.TokenServicesFactory = func(i []byte) (chaincode2.PublicParametersManager, chaincode2.Validator, error) {
+					atomic.AddInt32(&instantiations, 1)
+					time.Sleep(10 * time.Millisecond)
+					return fakePPM, fakeValidator, nil
+				}
+			})
+			It("instantiates the token services only once", func() {
+				var wg sync.WaitGroup
+				statuses := make([]int32, 10)
+				for i := 0; i < len(statuses); i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						statuses[i] = chaincode.Invoke(fakestub).Status
+					}(i)
+				}
+				wg.Wait()
+				for _, status := range statuses {
+					Expect(status).To(Equal(int32(200)))
+				}
+				Expect(atomic.LoadInt32(&instantiations)).To(Equal(int32(1)))
+				Expect(fakeValidator.UnmarshallAndVerifyCallCount()).To(Equal(len(statuses)))
+			})
+		})
+
+		Context("Provenance is queried", func() {
+			BeforeEach(func() {
+				setupKey, err := keys.CreateSetupKey()
+				Expect(err).NotTo(HaveOccurred())
+				requests := map[string][]byte{}
+				for _, txID := range []string{"tx1", "tx2", "tx3"} {
+					k, err := keys.CreateTokenRequestKey(txID)
+					Expect(err).NotTo(HaveOccurred())
+					requests[k] = []byte(txID)
+				}
+				fakestub.GetStateStub = func(key string) ([]byte, error) {
+					if key == setupKey {
+						return []byte("public parameters"), nil
+					}
+					return requests[key], nil
+				}
+
+				// tx1 issues two tokens, tx2 spends the second, tx3 spends the output of tx2 and the first token of tx1
+				issue := &mock2.IssueAction{}
+				issue.NumOutputsReturns(2)
+				in1, err := keys.CreateTokenKey("tx1", 1)
+				Expect(err).NotTo(HaveOccurred())
+				transfer1 := &mock2.TransferAction{}
+				transfer1.NumOutputsReturns(1)
+				transfer1.GetInputsReturns([]string{in1}, nil)
+				in2, err := keys.CreateTokenKey("tx2", 0)
+				Expect(err).NotTo(HaveOccurred())
+				in3, err := keys.CreateTokenKey("tx1", 0)
+				Expect(err).NotTo(HaveOccurred())
+				transfer2 := &mock2.TransferAction{}
+				transfer2.NumOutputsReturns(2)
+				transfer2.GetInputsReturns([]string{in2, in3}, nil)
+				fakeValidator.UnmarshalActionsStub = func(raw []byte) ([]interface{}, error) {
+					switch string(raw) {
+					case "tx1":
+						return []interface{}{issue}, nil
+					case "tx2":
+						return []interface{}{transfer1}, nil
+					default:
+						return []interface{}{transfer2}, nil
+					}
+				}
+			})
+			It("returns the token request", func() {
+				fakestub.GetArgsReturns([][]byte{[]byte("queryTokenRequest"), []byte("tx2")})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+				Expect(response.Payload).To(Equal([]byte("tx2")))
+
+				fakestub.GetArgsReturns([][]byte{[]byte("queryTokenRequest"), []byte("tx4")})
+				response = chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("not found"))
+			})
+			It("walks back to the issues", func() {
+				id, err := json.Marshal(&token2.Id{TxId: "tx3", Index: 1})
+				Expect(err).NotTo(HaveOccurred())
+				fakestub.GetArgsReturns([][]byte{[]byte("queryProvenance"), id})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+
+				provenance := &chaincode2.Provenance{}
+				Expect(json.Unmarshal(response.Payload, provenance)).To(Succeed())
+				Expect(provenance.Truncated).To(BeFalse())
+				Expect(provenance.Steps).To(HaveLen(4))
+				Expect(provenance.Steps[0].Action).To(Equal(api.TransferActionType))
+				Expect(provenance.Steps[0].Inputs).To(Equal([]*token2.Id{{TxId: "tx2", Index: 0}, {TxId: "tx1", Index: 0}}))
+				Expect(provenance.Steps[1].ID).To(Equal(&token2.Id{TxId: "tx2", Index: 0}))
+				Expect(provenance.Steps[1].Inputs).To(Equal([]*token2.Id{{TxId: "tx1", Index: 1}}))
+				Expect(provenance.Steps[2].ID).To(Equal(&token2.Id{TxId: "tx1", Index: 0}))
+				Expect(provenance.Steps[2].Action).To(Equal(api.IssueActionType))
+				Expect(provenance.Steps[3].ID).To(Equal(&token2.Id{TxId: "tx1", Index: 1}))
+				// each request is unmarshalled once
+				Expect(fakeValidator.UnmarshalActionsCallCount()).To(Equal(3))
+			})
+			It("stops at the graph hiding actions and at the limit", func() {
+				chaincode.MaxProvenanceSteps = 2
+				id, err := json.Marshal(&token2.Id{TxId: "tx3", Index: 0})
+				Expect(err).NotTo(HaveOccurred())
+				fakestub.GetArgsReturns([][]byte{[]byte("queryProvenance"), id})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+				provenance := &chaincode2.Provenance{}
+				Expect(json.Unmarshal(response.Payload, provenance)).To(Succeed())
+				Expect(provenance.Truncated).To(BeTrue())
+				Expect(provenance.Steps).To(HaveLen(2))
+
+				hiding := &mock2.TransferAction{}
+				hiding.NumOutputsReturns(1)
+				hiding.IsGraphHidingReturns(true)
+				fakeValidator.UnmarshalActionsReturns([]interface{}{hiding}, nil)
+				fakeValidator.UnmarshalActionsStub = nil
+				response = chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+				provenance = &chaincode2.Provenance{}
+				Expect(json.Unmarshal(response.Payload, provenance)).To(Succeed())
+				Expect(provenance.Truncated).To(BeFalse())
+				Expect(provenance.Steps).To(HaveLen(1))
+				Expect(provenance.Steps[0].GraphHiding).To(BeTrue())
+				Expect(provenance.Steps[0].Inputs).To(BeEmpty())
+			})
+		})
+
+		Context("Keys are migrated to the scheme of the chaincode", func() {
+			var scheme *keys.Scheme
+			BeforeEach(func() {
+				var err error
+				scheme, err = keys.NewScheme("app1")
+				Expect(err).NotTo(HaveOccurred())
+				chaincode.KeyScheme = scheme
+				chaincode.MigrateFrom = keys.Default
+				chaincode.AdminPolicy = func(stub shim.ChaincodeStubInterface) error { return nil }
+
+				k1, err := keys.CreateTokenKey("tx1", 0)
+				Expect(err).NotTo(HaveOccurred())
+				k2, err := keys.CreateSetupKey()
+				Expect(err).NotTo(HaveOccurred())
+				fakestub.GetStateByPartialCompositeKeyStub = func(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+					return &kvIterator{kvs: []*queryresult.KV{
+						{Key: k1, Value: []byte("token")},
+						{Key: k2, Value: []byte("pp")},
+					}}, nil
+				}
+			})
+			It("moves the keys in batches", func() {
+				raw, err := json.Marshal(&chaincode2.MigrateKeysRequest{Limit: 1})
+				Expect(err).NotTo(HaveOccurred())
+				fakestub.GetArgsReturns([][]byte{[]byte("migrateKeys"), raw})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+
+				res := &chaincode2.MigrateKeysResponse{}
+				Expect(json.Unmarshal(response.Payload, res)).To(Succeed())
+				Expect(res).To(Equal(&chaincode2.MigrateKeysResponse{Migrated: 1, Done: false}))
+				objectType, _ := fakestub.GetStateByPartialCompositeKeyArgsForCall(0)
+				Expect(objectType).To(Equal(keys.TokenKeyPrefix))
+
+				Expect(fakestub.PutStateCallCount()).To(Equal(1))
+				key, value := fakestub.PutStateArgsForCall(0)
+				newKey, err := scheme.CreateTokenKey("tx1", 0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(key).To(Equal(newKey))
+				Expect(value).To(Equal([]byte("token")))
+				Expect(fakestub.DelStateCallCount()).To(Equal(1))
+				oldKey, err := keys.CreateTokenKey("tx1", 0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakestub.DelStateArgsForCall(0)).To(Equal(oldKey))
+			})
+			It("reports when it is done", func() {
+				fakestub.GetArgsReturns([][]byte{[]byte("migrateKeys"), []byte("{}")})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+
+				res := &chaincode2.MigrateKeysResponse{}
+				Expect(json.Unmarshal(response.Payload, res)).To(Succeed())
+				Expect(res).To(Equal(&chaincode2.MigrateKeysResponse{Migrated: 2, Done: true}))
+				Expect(fakestub.PutStateCallCount()).To(Equal(2))
+			})
+			It("fails if the keys are already derived with the scheme", func() {
+				chaincode.MigrateFrom = scheme
+				fakestub.GetArgsReturns([][]byte{[]byte("migrateKeys"), []byte(`{"From":"app1"}`)})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("already derived"))
+			})
+			It("fails if the keys are not migrated from the configured scheme", func() {
+				fakestub.GetArgsReturns([][]byte{[]byte("migrateKeys"), []byte(`{"From":"app2"}`)})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("keys can be migrated only from scheme [ztoken]"))
+				Expect(fakestub.PutStateCallCount()).To(Equal(0))
+			})
+			It("fails if no source scheme is configured", func() {
+				chaincode.MigrateFrom = nil
+				fakestub.GetArgsReturns([][]byte{[]byte("migrateKeys"), []byte("{}")})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("no source scheme set"))
+			})
+			It("fails if the invoker is not an administrator", func() {
+				chaincode.AdminPolicy = func(stub shim.ChaincodeStubInterface) error { return errors.New("not an admin") }
+				fakestub.GetArgsReturns([][]byte{[]byte("migrateKeys"), []byte("{}")})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("not authorized to migrate keys: [not an admin]"))
+				Expect(fakestub.PutStateCallCount()).To(Equal(0))
+				Expect(fakestub.DelStateCallCount()).To(Equal(0))
+			})
+			It("fails if no admin policy is set", func() {
+				chaincode.AdminPolicy = nil
+				fakestub.GetArgsReturns([][]byte{[]byte("migrateKeys"), []byte("{}")})
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("no admin policy set"))
+			})
 		})
 
 		Context("When VerifyTokenRequest fails", func() {
@@ -189,5 +961,189 @@ var _ = Describe("ccvalidator", func() {
 			})
 		})
 
+		Context("When VerifyTokenRequest fails with a validation report", func() {
+			BeforeEach(func() {
+				args := make([][]byte, 2)
+				args[0] = []byte("invoke")
+				args[1] = []byte("token request")
+				fakestub.GetArgsReturns(args)
+				report := &api.ValidationReport{}
+				report.Succeeded(api.IssueActionType, 0)
+				err := report.Failed(api.TransferActionType, 0, api.DoubleSpendCheck, errors.Errorf("flying monkeys"), 1)
+				fakeValidator.UnmarshallAndVerifyReturns(nil, errors.Wrapf(err, "failed to verify senders' signatures"))
+			})
+			It("fails and returns the report", func() {
+				response := chaincode.Invoke(fakestub)
+				Expect(response).NotTo(BeNil())
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("flying monkeys"))
+
+				report := &api.ValidationReport{}
+				Expect(json.Unmarshal(response.Payload, report)).To(Succeed())
+				Expect(report.Actions).To(HaveLen(2))
+				Expect(report.Failure()).To(Equal(&api.ActionResult{
+					Type:    api.TransferActionType,
+					Index:   0,
+					Check:   api.DoubleSpendCheck,
+					Indices: []int{1},
+					Error:   "flying monkeys",
+				}))
+			})
+		})
+
+		Context("Invoke is called with a batch token request", func() {
+			BeforeEach(func() {
+				batch := token.NewBatchRequest()
+				batch.Append("tx1", []byte("token request 1"))
+				batch.Append("tx2", []byte("token request 2"))
+				raw, err := batch.Bytes()
+				Expect(err).NotTo(HaveOccurred())
+				fakestub.GetArgsReturns([][]byte{[]byte("invokeBatch"), raw})
+				fakestub.GetTxIDReturns("batch")
+				fakeValidator.VerifyBatchReturns([]*token.SubRequestResult{
+					{Binding: "tx1", Actions: []interface{}{}},
+					{Binding: "tx2", Actions: []interface{}{}},
+				}, nil)
+			})
+			It("commits each sub-request under its binding", func() {
+				response := chaincode.Invoke(fakestub)
+				Expect(response).NotTo(BeNil())
+				Expect(response.Status).To(Equal(int32(200)))
+
+				Expect(fakeValidator.VerifyBatchCallCount()).To(Equal(1))
+				_, batch, _ := fakeValidator.VerifyBatchArgsForCall(0)
+				Expect(batch.Requests).To(HaveLen(2))
+				Expect(fakestub.PutStateCallCount()).To(Equal(2))
+				key1, _ := fakestub.PutStateArgsForCall(0)
+				key2, _ := fakestub.PutStateArgsForCall(1)
+				Expect(key1).To(ContainSubstring("tx1"))
+				Expect(key2).To(ContainSubstring("tx2"))
+
+				report := &token.BatchReport{}
+				Expect(report.FromBytes(response.Payload)).To(Succeed())
+				Expect(report.Rejected).To(BeEmpty())
+			})
+			It("emits the keys written by all the sub-requests as an event", func() {
+				response := chaincode.Invoke(fakestub)
+				Expect(response.Status).To(Equal(int32(200)))
+
+				Expect(fakestub.SetEventCallCount()).To(Equal(1))
+				name, payload := fakestub.SetEventArgsForCall(0)
+				Expect(name).To(Equal(translator.TokenEventName))
+				event := &translator.TokenEvent{}
+				Expect(event.FromBytes(payload)).To(Succeed())
+				Expect(event.TxID).To(Equal("batch"))
+				Expect(event.Writes).To(HaveLen(2))
+				key1, _ := fakestub.PutStateArgsForCall(0)
+				key2, _ := fakestub.PutStateArgsForCall(1)
+				Expect(event.Writes[0].Key).To(Equal(key1))
+				Expect(event.Writes[1].Key).To(Equal(key2))
+			})
+			It("writes nothing if the batch is not valid", func() {
+				fakeValidator.VerifyBatchReturns(nil, errors.Errorf("sub-request [1][tx2] is not valid: flying monkeys"))
+				response := chaincode.Invoke(fakestub)
+				Expect(response).NotTo(BeNil())
+				Expect(response.Status).To(Equal(int32(500)))
+				Expect(response.Message).To(ContainSubstring("flying monkeys"))
+				Expect(fakestub.PutStateCallCount()).To(Equal(0))
+				Expect(fakestub.SetEventCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("Invoke is called with a batch token request skipping the invalid sub-requests", func() {
+			BeforeEach(func() {
+				batch := token.NewBatchRequest()
+				batch.Mode = token.SkipInvalid
+				batch.Append("tx1", []byte("token request 1"))
+				batch.Append("tx2", []byte("token request 2"))
+				raw, err := batch.Bytes()
+				Expect(err).NotTo(HaveOccurred())
+				fakestub.GetArgsReturns([][]byte{[]byte("invokeBatch"), raw})
+				fakeValidator.VerifyBatchReturns([]*token.SubRequestResult{
+					{Binding: "tx1", Err: errors.Errorf("flying monkeys")},
+					{Binding: "tx2", Actions: []interface{}{}},
+				}, nil)
+			})
+			It("commits the valid ones and records the others as rejected", func() {
+				response := chaincode.Invoke(fakestub)
+				Expect(response).NotTo(BeNil())
+				Expect(response.Status).To(Equal(int32(200)))
+
+				Expect(fakestub.PutStateCallCount()).To(Equal(2))
+				key1, reason := fakestub.PutStateArgsForCall(0)
+				Expect(key1).To(ContainSubstring("rejected_token_request"))
+				Expect(key1).To(ContainSubstring("tx1"))
+				Expect(reason).To(Equal([]byte(api.OtherCheck)))
+				key2, _ := fakestub.PutStateArgsForCall(1)
+				Expect(key2).To(ContainSubstring("tx2"))
+
+				report := &token.BatchReport{}
+				Expect(report.FromBytes(response.Payload)).To(Succeed())
+				Expect(report.Rejected).To(Equal([]*token.RejectedSubRequest{
+					{Index: 0, Binding: "tx1", Check: api.OtherCheck},
+				}))
+			})
+			Context("and the sub-requests spend the same inputs", func() {
+				var in string
+				BeforeEach(func() {
+					fakestub.GetTxIDReturns("batch")
+					var err error
+					in, err = keys.CreateTokenKey("tx0", 0)
+					Expect(err).NotTo(HaveOccurred())
+					fakestub.GetStateStub = func(key string) ([]byte, error) {
+						switch {
+						case strings.Contains(key, "setup"):
+							return []byte("public parameters"), nil
+						case key == in:
+							return []byte("token"), nil
+						default:
+							return nil, nil
+						}
+					}
+					transfer := &mock2.TransferAction{}
+					transfer.GetInputsReturns([]string{in}, nil)
+					fakeValidator.VerifyBatchReturns([]*token.SubRequestResult{
+						{Binding: "tx1", Actions: []interface{}{transfer}},
+						{Binding: "tx2", Actions: []interface{}{transfer}},
+					}, nil)
+					chaincode.InFlight = chaincode2.NewInFlightTracker(time.Minute)
+				})
+				It("rejects the sub-requests spending the inputs of a previous one", func() {
+					response := chaincode.Invoke(fakestub)
+					Expect(response.Status).To(Equal(int32(200)))
+					report := &token.BatchReport{}
+					Expect(report.FromBytes(response.Payload)).To(Succeed())
+					Expect(report.Rejected).To(Equal([]*token.RejectedSubRequest{
+						{Index: 1, Binding: "tx2", Check: api.DoubleSpendCheck},
+					}))
+				})
+				It("fails the whole batch if its inputs are in flight on this peer", func() {
+					Expect(chaincode.InFlight.Acquire("tx0", []string{in})).To(Succeed())
+					response := chaincode.Invoke(fakestub)
+					Expect(response.Status).To(Equal(int32(500)))
+					Expect(response.Message).To(ContainSubstring("is being spent by transaction [tx0]"))
+					Expect(fakestub.PutStateCallCount()).To(Equal(0))
+				})
+			})
+		})
+
 	})
 })
+
+type kvIterator struct {
+	kvs []*queryresult.KV
+}
+
+func (i *kvIterator) HasNext() bool {
+	return len(i.kvs) != 0
+}
+
+func (i *kvIterator) Close() error {
+	return nil
+}
+
+func (i *kvIterator) Next() (*queryresult.KV, error) {
+	kv := i.kvs[0]
+	i.kvs = i.kvs[1:]
+	return kv, nil
+}
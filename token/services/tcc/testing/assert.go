@@ -0,0 +1,68 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package testing
+
+import (
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// TestingT is the part of testing.TB the assertions use, GinkgoT() implements it too
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// AssertTokenExists reports an error to the passed test if the token with the passed id is not on the ledger
+func (h *Harness) AssertTokenExists(t TestingT, id *token2.Id) bool {
+	if th, ok := t.(tHelper); ok {
+		th.Helper()
+	}
+	exists, err := h.TokenExists(id)
+	if err != nil {
+		t.Errorf("failed checking token [%s]: %s", id, err)
+		return false
+	}
+	if !exists {
+		t.Errorf("token [%s] does not exist", id)
+	}
+	return exists
+}
+
+// AssertTokenSpent reports an error to the passed test if the token with the passed id has not been spent
+func (h *Harness) AssertTokenSpent(t TestingT, id *token2.Id) bool {
+	if th, ok := t.(tHelper); ok {
+		th.Helper()
+	}
+	spent, err := h.TokenSpent(id)
+	if err != nil {
+		t.Errorf("failed checking token [%s]: %s", id, err)
+		return false
+	}
+	if !spent {
+		t.Errorf("token [%s] has not been spent", id)
+	}
+	return spent
+}
+
+// AssertRequestCommitted reports an error to the passed test if the token request of the passed transaction
+// has not been committed
+func (h *Harness) AssertRequestCommitted(t TestingT, txID string) bool {
+	if th, ok := t.(tHelper); ok {
+		th.Helper()
+	}
+	committed, err := h.RequestCommitted(txID)
+	if err != nil {
+		t.Errorf("failed checking token request [%s]: %s", txID, err)
+		return false
+	}
+	if !committed {
+		t.Errorf("token request [%s] has not been committed", txID)
+	}
+	return committed
+}
+
+type tHelper interface {
+	Helper()
+}
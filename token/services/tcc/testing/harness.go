@@ -0,0 +1,209 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package testing runs the token chaincode on a fake stub, to test at the chaincode level the token requests of a
+// driver or of an application without a running peer.
+package testing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	_ "github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/nogh/driver"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// Harness runs a token chaincode on a Stub
+type Harness struct {
+	Chaincode *tcc.TokenChaincode
+	Stub      *Stub
+
+	txs int
+}
+
+// NewHarness returns a harness running the passed chaincode on an empty ledger.
+// If the chaincode has no TokenServicesFactory, the token services are instantiated by the drivers of the sdk,
+// as the chaincode deployed on a peer does.
+func NewHarness(cc *tcc.TokenChaincode) *Harness {
+	if cc.TokenServicesFactory == nil {
+		cc.TokenServicesFactory = func(raw []byte) (tcc.PublicParametersManager, tcc.Validator, error) {
+			return token.NewServicesFromPublicParams(raw)
+		}
+	}
+	return &Harness{Chaincode: cc, Stub: NewStub("tcc", cc)}
+}
+
+// NewTxID returns a fresh transaction id
+func (h *Harness) NewTxID() string {
+	h.txs++
+	return fmt.Sprintf("tx%d", h.txs)
+}
+
+// Init initializes the chaincode with the passed serialized public parameters
+func (h *Harness) Init(pp []byte) error {
+	res := h.Stub.Init(h.NewTxID(), []byte("init"), []byte(base64.StdEncoding.EncodeToString(pp)))
+	if res.Status >= 400 {
+		return errors.Errorf("failed initializing chaincode: %s", res.Message)
+	}
+	return nil
+}
+
+// Invoke invokes the passed function of the chaincode in a fresh transaction
+func (h *Harness) Invoke(function string, args ...[]byte) pb.Response {
+	return h.Stub.Invoke(h.NewTxID(), append([][]byte{[]byte(function)}, args...)...)
+}
+
+// Submit submits the passed serialized token request in the transaction with the passed id.
+// The request is committed only if the response is successful.
+func (h *Harness) Submit(txID string, request []byte) pb.Response {
+	return h.Stub.Invoke(txID, []byte(tcc.InvokeFunction), request)
+}
+
+// PublicParams returns the public parameters on the ledger
+func (h *Harness) PublicParams() ([]byte, error) {
+	res := h.Invoke(tcc.QueryPublicParamsFunction)
+	if res.Status >= 400 {
+		return nil, errors.Errorf("failed querying public parameters: %s", res.Message)
+	}
+	return res.Payload, nil
+}
+
+// TokenExists returns true if the token with the passed id is on the ledger, unspent
+func (h *Harness) TokenExists(id *token2.Id) (bool, error) {
+	k, err := h.keys().CreateTokenKey(id.TxId, int(id.Index))
+	if err != nil {
+		return false, err
+	}
+	raw, err := h.Stub.GetState(k)
+	if err != nil {
+		return false, err
+	}
+	return len(raw) != 0, nil
+}
+
+// TokenSpent returns true if the token with the passed id has been created by a committed token request and
+// is not on the ledger anymore
+func (h *Harness) TokenSpent(id *token2.Id) (bool, error) {
+	committed, err := h.RequestCommitted(id.TxId)
+	if err != nil || !committed {
+		return false, err
+	}
+	exists, err := h.TokenExists(id)
+	if err != nil {
+		return false, err
+	}
+	return !exists, nil
+}
+
+// RequestCommitted returns true if the token request of the passed transaction has been committed
+func (h *Harness) RequestCommitted(txID string) (bool, error) {
+	k, err := h.keys().CreateTokenRequestKey(txID)
+	if err != nil {
+		return false, err
+	}
+	raw, err := h.Stub.GetState(k)
+	if err != nil {
+		return false, err
+	}
+	return len(raw) != 0, nil
+}
+
+func (h *Harness) keys() *keys.Scheme {
+	if h.Chaincode.KeyScheme != nil {
+		return h.Chaincode.KeyScheme
+	}
+	return keys.Default
+}
+
+// FabtokenOutput returns a fabtoken output of the passed quantity of the passed type, owned by the passed identity
+func FabtokenOutput(owner view.Identity, tokenType string, quantity uint64) *fabtoken.TransferOutput {
+	return &fabtoken.TransferOutput{Output: &token2.Token{
+		Owner:    &token2.Owner{Raw: owner},
+		Type:     tokenType,
+		Quantity: token2.NewQuantityFromUInt64(quantity).Hex(),
+	}}
+}
+
+// FabtokenIssue submits, in a fresh transaction, a fabtoken request issuing the passed outputs, signed by the passed issuer.
+// It returns the id of the transaction, the outputs are the tokens with that transaction id and their index.
+func (h *Harness) FabtokenIssue(issuer view.Identity, signer api.Signer, outputs ...*fabtoken.TransferOutput) (string, pb.Response, error) {
+	raw, err := (&fabtoken.IssueAction{Issuer: issuer, Outputs: outputs}).Serialize()
+	if err != nil {
+		return "", pb.Response{}, errors.Wrap(err, "failed serializing issue action")
+	}
+	return h.submitSigned(&api.TokenRequest{Issues: [][]byte{raw}}, signer)
+}
+
+// FabtokenTransfer submits, in a fresh transaction, a fabtoken request spending the passed tokens into the passed
+// outputs, signed by the passed owner of the tokens. It returns the id of the transaction, see FabtokenIssue.
+func (h *Harness) FabtokenTransfer(owner view.Identity, signer api.Signer, inputs []*token2.Id, outputs ...*fabtoken.TransferOutput) (string, pb.Response, error) {
+	action := &fabtoken.TransferAction{Sender: owner, Outputs: outputs}
+	for _, id := range inputs {
+		k, err := h.keys().CreateTokenKey(id.TxId, int(id.Index))
+		if err != nil {
+			return "", pb.Response{}, err
+		}
+		action.Inputs = append(action.Inputs, k)
+	}
+	raw, err := action.Serialize()
+	if err != nil {
+		return "", pb.Response{}, errors.Wrap(err, "failed serializing transfer action")
+	}
+	return h.submitSigned(&api.TokenRequest{Transfers: [][]byte{raw}}, signer)
+}
+
+// submitSigned signs the passed token request, bound to a fresh transaction id, with the passed signers and submits it
+func (h *Harness) submitSigned(tr *api.TokenRequest, signers ...api.Signer) (string, pb.Response, error) {
+	txID := h.NewTxID()
+	message, err := tr.MarshalToSign()
+	if err != nil {
+		return "", pb.Response{}, errors.Wrap(err, "failed marshalling token request to sign")
+	}
+	message = append(message, []byte(txID)...)
+	for _, signer := range signers {
+		sigma, err := signer.Sign(message)
+		if err != nil {
+			return "", pb.Response{}, errors.Wrap(err, "failed signing token request")
+		}
+		tr.Signatures = append(tr.Signatures, sigma)
+	}
+	raw, err := json.Marshal(tr)
+	if err != nil {
+		return "", pb.Response{}, errors.Wrap(err, "failed marshalling token request")
+	}
+	return txID, h.Submit(txID, raw), nil
+}
+
+// FabtokenPublicParams returns serialized fabtoken public parameters
+func FabtokenPublicParams() ([]byte, error) {
+	pp, err := fabtoken.Setup()
+	if err != nil {
+		return nil, err
+	}
+	return pp.Serialize()
+}
+
+// ZKATPublicParams returns serialized zkatdlog public parameters with the passed range proof base and exponent,
+// and the passed idemix issuer public key
+func ZKATPublicParams(base int64, exponent int, idemixIssuerPK []byte) ([]byte, error) {
+	pp, err := crypto.Setup(base, exponent, idemixIssuerPK)
+	if err != nil {
+		return nil, err
+	}
+	return pp.Serialize()
+}
@@ -0,0 +1,179 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package testing_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/identity/fabric"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc"
+	tcctesting "github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc/testing"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// recorder records the errors reported by the assertions
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// writer writes its second argument under its first one, and fails if the first argument was already written
+type writer struct{}
+
+func (w *writer) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (w *writer) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	args := stub.GetArgs()
+	if err := stub.PutState(string(args[0]), args[1]); err != nil {
+		return shim.Error(err.Error())
+	}
+	v, err := stub.GetState(string(args[0]))
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	if len(v) != 0 {
+		return shim.Error("already written")
+	}
+	if string(args[1]) == "fail" {
+		return shim.Error("failed")
+	}
+	if err := stub.SetEvent("written", args[1]); err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success(nil)
+}
+
+func TestStub(t *testing.T) {
+	stub := tcctesting.NewStub("writer", &writer{})
+
+	// the writes of a failed invocation are discarded
+	res := stub.Invoke("tx1", []byte("k"), []byte("fail"))
+	assert.Equal(t, int32(shim.ERROR), res.Status)
+	assert.Empty(t, stub.State)
+	assert.Empty(t, stub.Events)
+
+	// the reads do not see the writes of the same invocation
+	res = stub.Invoke("tx2", []byte("k"), []byte("v"))
+	assert.Equal(t, int32(shim.OK), res.Status, res.Message)
+	assert.Equal(t, []byte("v"), stub.State["k"])
+	assert.Len(t, stub.Events, 1)
+	assert.Equal(t, "tx2", stub.Events[0].TxId)
+
+	res = stub.Invoke("tx3", []byte("k"), []byte("w"))
+	assert.Equal(t, int32(shim.ERROR), res.Status)
+	assert.Equal(t, []byte("v"), stub.State["k"])
+
+	// no writes outside a transaction
+	assert.Error(t, stub.PutState("k", []byte("w")))
+}
+
+func TestHarness(t *testing.T) {
+	pp, err := tcctesting.FabtokenPublicParams()
+	assert.NoError(t, err)
+
+	h := tcctesting.NewHarness(&tcc.TokenChaincode{})
+	assert.NoError(t, h.Init(pp))
+	raw, err := h.PublicParams()
+	assert.NoError(t, err)
+	assert.Equal(t, pp, raw)
+
+	// an invalid token request is not committed
+	txID := h.NewTxID()
+	res := h.Submit(txID, []byte("invalid request"))
+	assert.Equal(t, int32(shim.ERROR), res.Status)
+	committed, err := h.RequestCommitted(txID)
+	assert.NoError(t, err)
+	assert.False(t, committed)
+
+	r := &recorder{}
+	assert.False(t, h.AssertRequestCommitted(r, txID))
+	assert.False(t, h.AssertTokenExists(r, &token2.Id{TxId: txID, Index: 0}))
+	assert.False(t, h.AssertTokenSpent(r, &token2.Id{TxId: txID, Index: 0}))
+	assert.Equal(t, []string{
+		fmt.Sprintf("token request [%s] has not been committed", txID),
+		fmt.Sprintf("token [[%s:0]] does not exist", txID),
+		fmt.Sprintf("token [[%s:0]] has not been spent", txID),
+	}, r.errors)
+}
+
+func TestHarnessRefusesMalformedPublicParams(t *testing.T) {
+	// zkatdlog public parameters without an idemix issuer public key fail their self check
+	pp, err := tcctesting.ZKATPublicParams(16, 2, nil)
+	assert.NoError(t, err)
+	h := tcctesting.NewHarness(&tcc.TokenChaincode{})
+	err = h.Init(pp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "missing idemix issuer public key of epoch [0]")
+	_, err = h.PublicParams()
+	assert.Error(t, err)
+
+	_, _, err = token.NewServicesFromPublicParams(pp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "refusing public parameters")
+
+	ipk, err := ioutil.ReadFile("../../../core/zkatdlog/crypto/ppm/testdata/idemix/msp/IssuerPublicKey")
+	assert.NoError(t, err)
+	pp, err = tcctesting.ZKATPublicParams(16, 2, ipk)
+	assert.NoError(t, err)
+	h = tcctesting.NewHarness(&tcc.TokenChaincode{})
+	assert.NoError(t, h.Init(pp))
+}
+
+func TestHarnessFabtoken(t *testing.T) {
+	pp, err := tcctesting.FabtokenPublicParams()
+	assert.NoError(t, err)
+	h := tcctesting.NewHarness(&tcc.TokenChaincode{})
+	assert.NoError(t, h.Init(pp))
+
+	issuer, issuerSigner, _, err := fabric.NewSigner()
+	assert.NoError(t, err)
+	alice, aliceSigner, _, err := fabric.NewSigner()
+	assert.NoError(t, err)
+	bob, _, _, err := fabric.NewSigner()
+	assert.NoError(t, err)
+
+	// issue 10 to alice
+	issueTxID, res, err := h.FabtokenIssue(issuer, issuerSigner, tcctesting.FabtokenOutput(alice, "ABC", 10))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(shim.OK), res.Status, res.Message)
+	issued := &token2.Id{TxId: issueTxID, Index: 0}
+	r := &recorder{}
+	assert.True(t, h.AssertRequestCommitted(r, issueTxID))
+	assert.True(t, h.AssertTokenExists(r, issued))
+	assert.False(t, h.AssertTokenSpent(r, issued))
+	assert.Len(t, r.errors, 1)
+
+	// alice transfers 7 to bob and keeps 3
+	transferTxID, res, err := h.FabtokenTransfer(alice, aliceSigner, []*token2.Id{issued}, tcctesting.FabtokenOutput(bob, "ABC", 7), tcctesting.FabtokenOutput(alice, "ABC", 3))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(shim.OK), res.Status, res.Message)
+	r = &recorder{}
+	assert.True(t, h.AssertRequestCommitted(r, transferTxID))
+	assert.True(t, h.AssertTokenSpent(r, issued))
+	assert.True(t, h.AssertTokenExists(r, &token2.Id{TxId: transferTxID, Index: 0}))
+	assert.True(t, h.AssertTokenExists(r, &token2.Id{TxId: transferTxID, Index: 1}))
+	assert.Empty(t, r.errors)
+
+	// the spent token cannot be spent again
+	doubleSpendTxID, res, err := h.FabtokenTransfer(alice, aliceSigner, []*token2.Id{issued}, tcctesting.FabtokenOutput(alice, "ABC", 10))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(shim.ERROR), res.Status)
+	committed, err := h.RequestCommitted(doubleSpendTxID)
+	assert.NoError(t, err)
+	assert.False(t, committed)
+}
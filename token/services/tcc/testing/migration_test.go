@@ -0,0 +1,54 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package testing_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/fabtoken"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/zkatdlog/crypto"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc"
+	tcctesting "github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc/testing"
+)
+
+func TestMigrationCutover(t *testing.T) {
+	ipk, err := ioutil.ReadFile("../../../core/zkatdlog/crypto/ppm/testdata/idemix/msp/IssuerPublicKey")
+	assert.NoError(t, err)
+	source, err := tcctesting.FabtokenPublicParams()
+	assert.NoError(t, err)
+	pp, err := crypto.Setup(100, 2, ipk)
+	assert.NoError(t, err)
+	pp.Migration = &api.MigrationParams{SourcePublicParams: source, CutoverHeight: 4}
+	raw, err := pp.Serialize()
+	assert.NoError(t, err)
+
+	h := tcctesting.NewHarness(&tcc.TokenChaincode{HeightProvider: tcc.LedgerHeight})
+	assert.NoError(t, h.Init(raw))
+	request, err := json.Marshal(&api.TokenRequest{Driver: fabtoken.PublicParameters})
+	assert.NoError(t, err)
+
+	// the requests of the source driver are accepted up to the block before the cutover
+	for h.Stub.Height < 4 {
+		txID := h.NewTxID()
+		res := h.Submit(txID, request)
+		assert.Equal(t, int32(shim.OK), res.Status, res.Message)
+		h.AssertRequestCommitted(t, txID)
+	}
+	// and rejected from the cutover on
+	txID := h.NewTxID()
+	res := h.Submit(txID, request)
+	assert.Equal(t, int32(shim.ERROR), res.Status)
+	assert.Contains(t, res.Message, "not accepted from height [4]")
+	committed, err := h.RequestCommitted(txID)
+	assert.NoError(t, err)
+	assert.False(t, committed)
+}
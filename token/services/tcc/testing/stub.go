@@ -0,0 +1,176 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package testing
+
+import (
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-protos-go/common"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/pkg/errors"
+)
+
+// Stub is a fake chaincode stub backed by an in-memory ledger.
+// As on a peer, the reads of an invocation do not see its own writes, and the writes, the state-based endorsement
+// policies, and the event of an invocation reach the ledger only if the invocation succeeds.
+type Stub struct {
+	*shimtest.MockStub
+	// Clock, if set, returns the timestamp of the transactions, the current time if nil
+	Clock func() time.Time
+	// Events are the events set by the committed invocations, in order
+	Events []*pb.ChaincodeEvent
+	// Height is the height of the ledger the query system chaincode reports, see tcc.LedgerHeight.
+	// Each committed invocation advances it by one block.
+	Height uint64
+
+	chaincode shim.Chaincode
+	args      [][]byte
+	writes    map[string][]byte
+	policies  map[string][]byte
+	event     *pb.ChaincodeEvent
+}
+
+// NewStub returns a stub with an empty ledger for the passed chaincode
+func NewStub(name string, cc shim.Chaincode) *Stub {
+	return &Stub{MockStub: shimtest.NewMockStub(name, cc), chaincode: cc}
+}
+
+// Init runs the init of the chaincode in a transaction with the passed id
+func (s *Stub) Init(txID string, args ...[]byte) pb.Response {
+	s.start(txID, args)
+	defer s.end()
+	return s.commit(s.chaincode.Init(s))
+}
+
+// Invoke runs the invoke of the chaincode in a transaction with the passed id
+func (s *Stub) Invoke(txID string, args ...[]byte) pb.Response {
+	s.start(txID, args)
+	defer s.end()
+	return s.commit(s.chaincode.Invoke(s))
+}
+
+func (s *Stub) GetArgs() [][]byte {
+	return s.args
+}
+
+func (s *Stub) GetStringArgs() []string {
+	res := make([]string, len(s.args))
+	for i, arg := range s.args {
+		res[i] = string(arg)
+	}
+	return res
+}
+
+func (s *Stub) GetFunctionAndParameters() (string, []string) {
+	args := s.GetStringArgs()
+	if len(args) == 0 {
+		return "", []string{}
+	}
+	return args[0], args[1:]
+}
+
+func (s *Stub) PutState(key string, value []byte) error {
+	if len(value) == 0 {
+		return s.DelState(key)
+	}
+	if s.writes == nil {
+		return errors.New("cannot put state outside a transaction")
+	}
+	s.writes[key] = value
+	return nil
+}
+
+func (s *Stub) DelState(key string) error {
+	if s.writes == nil {
+		return errors.New("cannot delete state outside a transaction")
+	}
+	s.writes[key] = nil
+	return nil
+}
+
+func (s *Stub) SetStateValidationParameter(key string, ep []byte) error {
+	if s.policies == nil {
+		return errors.New("cannot set state validation parameter outside a transaction")
+	}
+	s.policies[key] = ep
+	return nil
+}
+
+func (s *Stub) SetEvent(name string, payload []byte) error {
+	s.event = &pb.ChaincodeEvent{EventName: name, Payload: payload, TxId: s.TxID}
+	return nil
+}
+
+// InvokeChaincode answers the chain info queries to the query system chaincode with the height of the ledger,
+// the other invocations are served by the mock stub
+func (s *Stub) InvokeChaincode(name string, args [][]byte, channel string) pb.Response {
+	if name == "qscc" && len(args) != 0 && string(args[0]) == "GetChainInfo" {
+		raw, err := proto.Marshal(&common.BlockchainInfo{Height: s.Height})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(raw)
+	}
+	return s.MockStub.InvokeChaincode(name, args, channel)
+}
+
+func (s *Stub) start(txID string, args [][]byte) {
+	s.MockTransactionStart(txID)
+	if s.Clock != nil {
+		t := s.Clock()
+		s.TxTimestamp = &timestamp.Timestamp{Seconds: t.Unix(), Nanos: int32(t.Nanosecond())}
+	}
+	s.args = args
+	s.writes = map[string][]byte{}
+	s.policies = map[string][]byte{}
+	s.event = nil
+}
+
+func (s *Stub) end() {
+	s.MockTransactionEnd(s.TxID)
+	s.args = nil
+	s.writes = nil
+	s.policies = nil
+	s.event = nil
+}
+
+// commit applies to the ledger the writes of the current invocation, if successful
+func (s *Stub) commit(res pb.Response) pb.Response {
+	if res.Status >= shim.ERRORTHRESHOLD {
+		return res
+	}
+	keys := make([]string, 0, len(s.writes))
+	for k := range s.writes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if v := s.writes[k]; len(v) != 0 {
+			if err := s.MockStub.PutState(k, v); err != nil {
+				return shim.Error(err.Error())
+			}
+			continue
+		}
+		if err := s.MockStub.DelState(k); err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	for k, ep := range s.policies {
+		if err := s.MockStub.SetStateValidationParameter(k, ep); err != nil {
+			return shim.Error(err.Error())
+		}
+	}
+	if s.event != nil {
+		s.Events = append(s.Events, s.event)
+	}
+	s.Height++
+	return res
+}
@@ -0,0 +1,203 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package timestamp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	queryContentType = "application/timestamp-query"
+	replyContentType = "application/timestamp-reply"
+
+	// MaxResponseSize bounds the size of the responses read from a time stamping authority
+	MaxResponseSize = 1 << 20
+)
+
+var (
+	oidSHA256     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+)
+
+// Authority attests the time at which it has seen a digest
+type Authority interface {
+	// Timestamp returns the token, issued by the authority, attesting the passed SHA-256 digest,
+	// and the time it attests
+	Timestamp(digest []byte) ([]byte, time.Time, error)
+}
+
+// RFC3161Authority is a client of a time stamping authority speaking the RFC3161 protocol over HTTP.
+// The token it returns is the CMS SignedData issued by the authority, to be kept as evidence.
+// It checks that the token attests the requested digest, the signature of the authority is not verified.
+type RFC3161Authority struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewRFC3161Authority returns a client of the RFC3161 time stamping authority at the passed URL
+func NewRFC3161Authority(url string) *RFC3161Authority {
+	return &RFC3161Authority{URL: url, Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (a *RFC3161Authority) Timestamp(digest []byte) ([]byte, time.Time, error) {
+	if len(digest) != 32 {
+		return nil, time.Time{}, errors.Errorf("invalid digest length [%d], expected a SHA-256 digest", len(digest))
+	}
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, time.Time{}, errors.Wrap(err, "failed generating nonce")
+	}
+	req, err := asn1.Marshal(timeStampReq{
+		Version:        1,
+		MessageImprint: newMessageImprint(digest),
+		Nonce:          nonce,
+		CertReq:        true,
+	})
+	if err != nil {
+		return nil, time.Time{}, errors.Wrap(err, "failed marshalling timestamp request")
+	}
+
+	resp, err := a.Client.Post(a.URL, queryContentType, bytes.NewReader(req))
+	if err != nil {
+		return nil, time.Time{}, errors.Wrapf(err, "failed requesting timestamp to [%s]", a.URL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, errors.Errorf("timestamp authority [%s] replied [%s]", a.URL, resp.Status)
+	}
+	raw, err := ioutil.ReadAll(io.LimitReader(resp.Body, MaxResponseSize+1))
+	if err != nil {
+		return nil, time.Time{}, errors.Wrapf(err, "failed reading timestamp reply from [%s]", a.URL)
+	}
+	if len(raw) > MaxResponseSize {
+		return nil, time.Time{}, errors.Errorf("timestamp reply from [%s] exceeds [%d] bytes", a.URL, MaxResponseSize)
+	}
+	return ParseResponse(raw, digest, nonce)
+}
+
+// ParseResponse returns the token carried by the passed RFC3161 response and the time it attests.
+// It checks that the token attests the passed digest and, if not nil, carries the passed nonce.
+func ParseResponse(raw []byte, digest []byte, nonce *big.Int) ([]byte, time.Time, error) {
+	resp := &timeStampResp{}
+	if _, err := asn1.Unmarshal(raw, resp); err != nil {
+		return nil, time.Time{}, errors.Wrap(err, "failed unmarshalling timestamp response")
+	}
+	var status int
+	if _, err := asn1.Unmarshal(resp.Status.Bytes, &status); err != nil {
+		return nil, time.Time{}, errors.Wrap(err, "failed unmarshalling timestamp response status")
+	}
+	// 0 is granted, 1 is granted with modifications
+	if status != 0 && status != 1 {
+		return nil, time.Time{}, errors.Errorf("timestamp request rejected with status [%d]", status)
+	}
+	if len(resp.TimeStampToken.FullBytes) == 0 {
+		return nil, time.Time{}, errors.New("timestamp response carries no token")
+	}
+	info, err := parseToken(resp.TimeStampToken.FullBytes)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if !info.MessageImprint.HashAlgorithm.Algorithm.Equal(oidSHA256) || !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return nil, time.Time{}, errors.New("timestamp token does not attest the requested digest")
+	}
+	if nonce != nil && (info.Nonce == nil || info.Nonce.Cmp(nonce) != 0) {
+		return nil, time.Time{}, errors.New("timestamp token does not carry the requested nonce")
+	}
+	return resp.TimeStampToken.FullBytes, info.GenTime, nil
+}
+
+func parseToken(raw []byte) (*tstInfo, error) {
+	ci := &contentInfo{}
+	if _, err := asn1.Unmarshal(raw, ci); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling timestamp token")
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, errors.Errorf("timestamp token is not a signed data, got [%s]", ci.ContentType)
+	}
+	sd := &signedData{}
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, sd); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling timestamp token signed data")
+	}
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, errors.Errorf("timestamp token does not carry a timestamp info, got [%s]", sd.EncapContentInfo.EContentType)
+	}
+	info := &tstInfo{}
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent, info); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling timestamp info")
+	}
+	return info, nil
+}
+
+func newMessageImprint(digest []byte) messageImprint {
+	return messageImprint{
+		HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+		HashedMessage: digest,
+	}
+}
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional"`
+}
+
+type timeStampResp struct {
+	Status         asn1.RawValue
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+// signedData is the prefix of a CMS SignedData up to the content, the certificates and the signer infos follow
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	EncapContentInfo encapsulatedContentInfo
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+// tstInfo is the prefix of a TSTInfo up to the nonce, the authority name and the extensions follow
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+	Accuracy       accuracy  `asn1:"optional"`
+	Ordering       bool      `asn1:"optional"`
+	Nonce          *big.Int  `asn1:"optional"`
+}
@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package timestamp
+
+import (
+	"crypto/sha256"
+	"encoding/asn1"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newResponse(t *testing.T, status int, digest []byte, nonce *big.Int, genTime time.Time) []byte {
+	eContent, err := asn1.Marshal(tstInfo{
+		Version:        1,
+		Policy:         asn1.ObjectIdentifier{1, 2, 3},
+		MessageImprint: newMessageImprint(digest),
+		SerialNumber:   big.NewInt(42),
+		GenTime:        genTime,
+		Nonce:          nonce,
+	})
+	assert.NoError(t, err)
+	sd, err := asn1.Marshal(signedData{
+		Version:          3,
+		DigestAlgorithms: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true},
+		EncapContentInfo: encapsulatedContentInfo{EContentType: oidTSTInfo, EContent: eContent},
+	})
+	assert.NoError(t, err)
+	token, err := asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd},
+	})
+	assert.NoError(t, err)
+	statusRaw, err := asn1.Marshal(struct{ Status int }{Status: status})
+	assert.NoError(t, err)
+	raw, err := asn1.Marshal(timeStampResp{
+		Status:         asn1.RawValue{FullBytes: statusRaw},
+		TimeStampToken: asn1.RawValue{FullBytes: token},
+	})
+	assert.NoError(t, err)
+	return raw
+}
+
+func TestRFC3161Authority(t *testing.T) {
+	genTime := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, queryContentType, r.Header.Get("Content-Type"))
+		raw, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		req := &timeStampReq{}
+		_, err = asn1.Unmarshal(raw, req)
+		assert.NoError(t, err)
+		assert.True(t, req.CertReq)
+
+		w.Header().Set("Content-Type", replyContentType)
+		_, err = w.Write(newResponse(t, 0, req.MessageImprint.HashedMessage, req.Nonce, genTime))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	digest := sha256.Sum256([]byte("token request"))
+	token, attested, err := NewRFC3161Authority(server.URL).Timestamp(digest[:])
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.True(t, genTime.Equal(attested))
+
+	_, _, err = NewRFC3161Authority(server.URL).Timestamp([]byte("not a digest"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid digest length")
+
+	// the replies are bounded
+	huge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", replyContentType)
+		_, err := w.Write(make([]byte, MaxResponseSize+1))
+		assert.NoError(t, err)
+	}))
+	defer huge.Close()
+	_, _, err = NewRFC3161Authority(huge.URL).Timestamp(digest[:])
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds")
+}
+
+func TestParseResponse(t *testing.T) {
+	genTime := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	digest := sha256.Sum256([]byte("token request"))
+	other := sha256.Sum256([]byte("another token request"))
+	nonce := big.NewInt(7)
+
+	_, attested, err := ParseResponse(newResponse(t, 0, digest[:], nonce, genTime), digest[:], nonce)
+	assert.NoError(t, err)
+	assert.True(t, genTime.Equal(attested))
+
+	_, _, err = ParseResponse(newResponse(t, 2, digest[:], nonce, genTime), digest[:], nonce)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "rejected with status [2]")
+
+	_, _, err = ParseResponse(newResponse(t, 0, other[:], nonce, genTime), digest[:], nonce)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not attest the requested digest")
+
+	_, _, err = ParseResponse(newResponse(t, 0, digest[:], big.NewInt(8), genTime), digest[:], nonce)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not carry the requested nonce")
+}
@@ -0,0 +1,126 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.tracing")
+
+// TraceParentKey is the key of the carrier holding the propagated span, in the W3C Trace Context format,
+// the same used by the OpenTelemetry propagators
+const TraceParentKey = "traceparent"
+
+type spanContextKey struct{}
+
+// SpanContext identifies a span within a trace
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// SpanContextFromContext returns the span carried by the passed context, nil if none
+func SpanContextFromContext(ctx context.Context) *SpanContext {
+	sc, ok := ctx.Value(spanContextKey{}).(*SpanContext)
+	if !ok {
+		return nil
+	}
+	return sc
+}
+
+// LogTracer is a Tracer that logs each span, with its trace, parent, duration, and attributes, once it ends.
+// It does not need any tracing backend, the latency breakdown of a transaction is obtained by grouping
+// the log entries by trace id, across the nodes of the parties involved.
+type LogTracer struct{}
+
+func NewLogTracer() *LogTracer {
+	return &LogTracer{}
+}
+
+func (t *LogTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &logSpan{name: name, start: time.Now(), attributes: map[string]interface{}{}}
+	if parent := SpanContextFromContext(ctx); parent != nil {
+		s.parentID = parent.SpanID
+		s.ctx = &SpanContext{TraceID: parent.TraceID, SpanID: randomHex(8)}
+	} else {
+		s.ctx = &SpanContext{TraceID: randomHex(16), SpanID: randomHex(8)}
+	}
+	return context.WithValue(ctx, spanContextKey{}, s.ctx), s
+}
+
+func (t *LogTracer) Inject(ctx context.Context, carrier map[string]string) {
+	sc := SpanContextFromContext(ctx)
+	if sc == nil || carrier == nil {
+		return
+	}
+	carrier[TraceParentKey] = fmt.Sprintf("00-%s-%s-01", sc.TraceID, sc.SpanID)
+}
+
+func (t *LogTracer) Extract(ctx context.Context, carrier map[string]string) context.Context {
+	parts := strings.Split(carrier[TraceParentKey], "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+	return context.WithValue(ctx, spanContextKey{}, &SpanContext{TraceID: parts[1], SpanID: parts[2]})
+}
+
+type logSpan struct {
+	name     string
+	ctx      *SpanContext
+	parentID string
+	start    time.Time
+
+	lock       sync.Mutex
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (s *logSpan) SetAttribute(key string, value interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.attributes[key] = value
+}
+
+func (s *logSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.err = err
+}
+
+func (s *logSpan) End() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.ended {
+		return
+	}
+	s.ended = true
+	if s.err != nil {
+		logger.Infof("span [%s] trace [%s] id [%s] parent [%s] took [%s] attributes %v failed [%s]", s.name, s.ctx.TraceID, s.ctx.SpanID, s.parentID, time.Since(s.start), s.attributes, s.err)
+		return
+	}
+	logger.Infof("span [%s] trace [%s] id [%s] parent [%s] took [%s] attributes %v", s.name, s.ctx.TraceID, s.ctx.SpanID, s.parentID, time.Since(s.start), s.attributes)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		logger.Errorf("failed generating span id [%s]", err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,47 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogTracer(t *testing.T) {
+	tracer := NewLogTracer()
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	rootSC := SpanContextFromContext(ctx)
+	assert.NotNil(t, rootSC)
+	assert.Len(t, rootSC.TraceID, 32)
+	assert.Len(t, rootSC.SpanID, 16)
+
+	// the span is propagated to another party via the carrier
+	carrier := map[string]string{}
+	tracer.Inject(ctx, carrier)
+	assert.Equal(t, "00-"+rootSC.TraceID+"-"+rootSC.SpanID+"-01", carrier[TraceParentKey])
+	remote := tracer.Extract(context.Background(), carrier)
+	assert.Equal(t, rootSC, SpanContextFromContext(remote))
+
+	childCtx, child := tracer.Start(remote, "child")
+	childSC := SpanContextFromContext(childCtx)
+	assert.Equal(t, rootSC.TraceID, childSC.TraceID)
+	assert.NotEqual(t, rootSC.SpanID, childSC.SpanID)
+	assert.Equal(t, rootSC.SpanID, child.(*logSpan).parentID)
+
+	child.SetAttribute("inputs", 2)
+	EndWithError(child, errors.New("boom"))
+	assert.True(t, child.(*logSpan).ended)
+	root.End()
+	root.End()
+
+	// a malformed carrier is ignored
+	assert.Nil(t, SpanContextFromContext(tracer.Extract(context.Background(), map[string]string{TraceParentKey: "00-abc"})))
+	assert.Nil(t, SpanContextFromContext(tracer.Extract(context.Background(), nil)))
+}
@@ -0,0 +1,78 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package tracing
+
+import (
+	"context"
+	"reflect"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+)
+
+// Span is a unit of work of a trace, see Tracer
+type Span interface {
+	// SetAttribute attaches a key-value pair to the span
+	SetAttribute(key string, value interface{})
+	// RecordError marks the span as failed with the passed error, a nil error is ignored
+	RecordError(err error)
+	// End completes the span, its duration is the time elapsed since its start
+	End()
+}
+
+// Tracer creates the spans of the token transaction lifecycle. Its methods mirror the OpenTelemetry API,
+// so that an OpenTelemetry tracer and propagator can be plugged in by registering an adapter in the service provider.
+type Tracer interface {
+	// Start starts a span child of the span carried by the passed context, if any,
+	// and returns a context carrying the new span
+	Start(ctx context.Context, name string) (context.Context, Span)
+	// Inject writes into the passed carrier the span carried by the passed context, to propagate it to another party
+	Inject(ctx context.Context, carrier map[string]string)
+	// Extract returns a context carrying the span propagated via the passed carrier, if any
+	Extract(ctx context.Context, carrier map[string]string) context.Context
+}
+
+// GetTracer returns the tracer registered in the passed service provider, a tracer that does nothing if none
+func GetTracer(sp view2.ServiceProvider) Tracer {
+	s, err := sp.GetService(reflect.TypeOf((*Tracer)(nil)))
+	if err != nil {
+		return noopTracer{}
+	}
+	return s.(Tracer)
+}
+
+// Start starts a span with the tracer registered in the passed service provider, see Tracer.Start
+func Start(sp view2.ServiceProvider, ctx context.Context, name string) (context.Context, Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return GetTracer(sp).Start(ctx, name)
+}
+
+// EndWithError records the passed error, if any, and ends the passed span
+func EndWithError(span Span, err error) {
+	span.RecordError(err)
+	span.End()
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (noopTracer) Inject(ctx context.Context, carrier map[string]string) {}
+
+func (noopTracer) Extract(ctx context.Context, carrier map[string]string) context.Context {
+	return ctx
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+
+func (noopSpan) RecordError(err error) {}
+
+func (noopSpan) End() {}
@@ -6,6 +6,10 @@ SPDX-License-Identifier: Apache-2.0
 package ttx
 
 import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
 	"github.com/pkg/errors"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
@@ -31,11 +35,24 @@ func (a approver) Validate(tx *Transaction) error {
 		return errors.WithMessage(err, "failed getting rws")
 	}
 
+	txTime, err := proposalTime(tx)
+	if err != nil {
+		return errors.WithMessage(err, "failed getting transaction time")
+	}
+
+	ch := fabric.GetChannel(tx.tx.ServiceProvider, tx.Network(), tx.Channel())
+	height, err := ledgerHeight(ch)
+	if err != nil {
+		return errors.WithMessage(err, "failed getting ledger height")
+	}
+
 	ts := tx.tokenService()
 	app := approver2.NewTokenRWSetApprover(
 		ts.Validator(),
-		fabric.GetVault(tx.tx.ServiceProvider, tx.Network(), tx.Channel()),
+		ch.Vault(),
 		tx.ID(),
+		txTime,
+		height,
 		rws,
 		ts.Namespace(),
 	)
@@ -44,3 +61,37 @@ func (a approver) Validate(tx *Transaction) error {
 		return tx.HasBeenEndorsedBy(id)
 	}, tx.TokenRequest)
 }
+
+// proposalTime returns the timestamp of the proposal of the passed transaction,
+// the time the token chaincode validates the token request at
+func proposalTime(tx *Transaction) (time.Time, error) {
+	header := &common.Header{}
+	if err := proto.Unmarshal(tx.tx.Transaction.Proposal().Header(), header); err != nil {
+		return time.Time{}, errors.Wrap(err, "failed unmarshalling proposal header")
+	}
+	channelHeader := &common.ChannelHeader{}
+	if err := proto.Unmarshal(header.ChannelHeader, channelHeader); err != nil {
+		return time.Time{}, errors.Wrap(err, "failed unmarshalling channel header")
+	}
+	if channelHeader.Timestamp == nil {
+		return time.Time{}, errors.New("proposal without timestamp")
+	}
+	return time.Unix(channelHeader.Timestamp.Seconds, int64(channelHeader.Timestamp.Nanos)), nil
+}
+
+// ledgerHeight returns the height of the ledger known to this node, the one following the block of the last
+// transaction committed in its vault, zero if none has been committed yet
+func ledgerHeight(ch *fabric.Channel) (uint64, error) {
+	last, err := ch.Vault().GetLastTxID()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed getting last committed transaction")
+	}
+	if len(last) == 0 {
+		return 0, nil
+	}
+	block, err := ch.Ledger().GetBlockNumberByTxID(last)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed getting block of transaction [%s]", last)
+	}
+	return block + 1, nil
+}
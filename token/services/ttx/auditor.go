@@ -7,6 +7,7 @@ package ttx
 
 import (
 	"bytes"
+	"context"
 	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
@@ -31,8 +32,8 @@ func NewAuditor(sp view2.ServiceProvider, w *token.AuditorWallet) *txAuditor {
 	}
 }
 
-func (a *txAuditor) Validate(tx *Transaction) error {
-	return a.auditor.Validate(tx.TokenRequest)
+func (a *txAuditor) Validate(ctx context.Context, tx *Transaction) error {
+	return a.auditor.Validate(ctx, tx.TokenRequest)
 }
 
 func (a *txAuditor) Audit(tx *Transaction) (*token.InputStream, *token.OutputStream, error) {
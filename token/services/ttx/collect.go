@@ -162,7 +162,7 @@ func (c *collectActionsView) collectRemote(context view.Context, actionTransfer
 	}
 
 	// Match Request with Metadata
-	if err := tokenRequest.Verify(); err != nil {
+	if err := tokenRequest.Verify(context.Context()); err != nil {
 		return errors.Wrap(err, "failed verifying response")
 	}
 
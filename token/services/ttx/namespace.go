@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package ttx
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
@@ -43,8 +45,8 @@ func NewNamespace(tx *endorser.Transaction, opts ...TxOption) (*Namespace, error
 	return n, nil
 }
 
-func (t *Namespace) Issue(wallet *token.IssuerWallet, receiver view.Identity, typ string, q uint64) error {
-	action, err := t.TokenRequest.Issue(wallet, receiver, typ, q)
+func (t *Namespace) Issue(wallet *token.IssuerWallet, receiver view.Identity, typ string, q uint64, opts ...token.IssueOption) error {
+	action, err := t.TokenRequest.Issue(wallet, receiver, typ, q, opts...)
 	if err != nil {
 		return errors.Wrapf(err, "failed issuing")
 	}
@@ -67,12 +69,12 @@ func (t *Namespace) Inputs() (*token.InputStream, error) {
 	return t.TokenRequest.Inputs()
 }
 
-func (t *Namespace) Verify() error {
-	return t.TokenRequest.Verify()
+func (t *Namespace) Verify(ctx context.Context) error {
+	return t.TokenRequest.Verify(ctx)
 }
 
-func (t *Namespace) IsValid() error {
-	return t.TokenRequest.IsValid()
+func (t *Namespace) IsValid(ctx context.Context) error {
+	return t.TokenRequest.IsValid(ctx)
 }
 
 func (t *Namespace) Signers() []view.Identity {
@@ -132,7 +134,12 @@ func (t *Namespace) SetProposal() {
 
 func (t *Namespace) Release() {
 	logger.Debugf("releasing resources for tx [%s]", t.tx.ID())
-	if err := t.tokenService().SelectorManager().Unlock(t.tx.ID()); err != nil {
+	sm, err := t.tokenService().SelectorManager()
+	if err != nil {
+		logger.Warnf("failed releasing tokens locked by [%s], [%s]", t.tx.ID(), err)
+		return
+	}
+	if err := sm.Unlock(t.tx.ID()); err != nil {
 		logger.Warnf("failed releasing tokens locked by [%s], [%s]", t.tx.ID(), err)
 	}
 }
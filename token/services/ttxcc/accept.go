@@ -6,6 +6,8 @@ SPDX-License-Identifier: Apache-2.0
 package ttxcc
 
 import (
+	"encoding/json"
+
 	"github.com/pkg/errors"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
@@ -45,11 +47,33 @@ func (s *acceptView) Call(context view.Context) (interface{}, error) {
 		return nil, errors.WithMessagef(err, "failed storing tx env [%s]", s.tx.ID())
 	}
 
-	logger.Debugf("send back ack")
-	// Ack for distribution
+	// Forward to the devices sharing the wallets involved
+	if err := syncWithDevices(context, s.tx); err != nil {
+		return nil, errors.WithMessagef(err, "failed syncing tx [%s] with paired devices", s.tx.ID())
+	}
+
+	// Ack for distribution, with a receipt signed by the receiver
+	ack := []byte("ack")
+	signer, err := receiptSigner(s.tx)
+	if err != nil {
+		return nil, err
+	}
+	if !signer.IsNone() {
+		logger.Debugf("send back receipt signed by [%s]", signer.UniqueID())
+		receipt, err := newReceipt(s.tx, signer)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed creating receipt for [%s]", s.tx.ID())
+		}
+		ack, err = json.Marshal(receipt)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed marshalling receipt for [%s]", s.tx.ID())
+		}
+	} else {
+		logger.Debugf("send back ack")
+	}
 	session := context.Session()
 	// Send the proposal response back
-	err = session.Send([]byte("ack"))
+	err = session.Send(ack)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,130 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+)
+
+// RequestIssueApprovalView asks the approver of an issue, see token.Issue, to co-sign the transaction.
+// The approver must register a responder for this view that receives the transaction with ReceiveTransaction,
+// inspects it, and runs NewApproveIssueView.
+type RequestIssueApprovalView struct {
+	tx       *Transaction
+	approver view.Identity
+}
+
+func newRequestIssueApprovalView(tx *Transaction, approver view.Identity) *RequestIssueApprovalView {
+	return &RequestIssueApprovalView{tx: tx, approver: approver}
+}
+
+func (r *RequestIssueApprovalView) Call(context view.Context) (interface{}, error) {
+	return requestCoSignature(context, r, r.tx, r.approver)
+}
+
+// requestCoSignature sends the passed transaction to the passed party, on a session initiated by the passed view,
+// and returns the signature of the party on the token request, once verified
+func requestCoSignature(context view.Context, initiator view.View, tx *Transaction, party view.Identity) ([]byte, error) {
+	session, err := context.GetSession(initiator, party)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed getting session")
+	}
+
+	// Send transaction
+	txRaw, err := tx.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if err := session.Send(txRaw); err != nil {
+		return nil, errors.Wrap(err, "failed sending transaction")
+	}
+
+	// Receive signature
+	ch := session.Receive()
+	var msg *view.Message
+	select {
+	case msg = <-ch:
+		logger.Debugf("co-signature received from [%s]", party)
+	case <-time.After(60 * time.Second):
+		return nil, errors.Errorf("Timeout from party %s", party)
+	}
+	if msg.Status == view.ERROR {
+		return nil, errors.New(string(msg.Payload))
+	}
+
+	// Check signature
+	requestRaw, err := tx.TokenRequest.MarshallToSign()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed marshalling message to sign")
+	}
+	verifier, err := tx.TokenService().SigService().GetVerifier(party)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed getting verifier for [%s]", party)
+	}
+	if err := verifier.Verify(append(requestRaw, []byte(tx.ID())...), msg.Payload); err != nil {
+		return nil, errors.Wrapf(err, "failed verifying signature from [%s]", party)
+	}
+	return msg.Payload, nil
+}
+
+type ApproveIssueView struct {
+	tx       *Transaction
+	approver view.Identity
+}
+
+// NewApproveIssueView returns a view that co-signs, with the passed approver identity, the issues of the passed
+// transaction and sends the signature back to the issuer. The approver must be the one the public parameters
+// assign to the issued token types.
+func NewApproveIssueView(tx *Transaction, approver view.Identity) *ApproveIssueView {
+	return &ApproveIssueView{tx: tx, approver: approver}
+}
+
+func (a *ApproveIssueView) Call(context view.Context) (interface{}, error) {
+	approved := false
+	for _, issue := range a.tx.TokenRequest.Issues() {
+		if a.approver.Equal(issue.Approver) {
+			approved = true
+			break
+		}
+	}
+	if !approved {
+		return nil, errors.Errorf("[%s] is not the approver of any issue in transaction [%s]", a.approver, a.tx.ID())
+	}
+
+	logger.Debugf("approve issues [%s][%s]", a.approver.UniqueID(), a.tx.ID())
+	if err := coSign(context, a.tx, a.approver); err != nil {
+		return nil, errors.WithMessagef(err, "failed co-signing issues as approver [%s]", a.approver)
+	}
+	return nil, nil
+}
+
+// coSign signs the token request of the passed transaction with the passed identity
+// and sends the signature back on the session of the passed context
+func coSign(context view.Context, tx *Transaction, signerID view.Identity) error {
+	signer, err := view2.GetSigService(context).GetSigner(signerID)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting signer for [%s]", signerID)
+	}
+	requestRaw, err := tx.TokenRequest.MarshallToSign()
+	if err != nil {
+		return errors.Wrapf(err, "failed marshalling tx [%s] to sign", tx.ID())
+	}
+	logger.Debugf("co-sign [%s][%s][%s]", signerID.UniqueID(), hash.Hashable(requestRaw).String(), tx.ID())
+	sigma, err := signer.Sign(append(requestRaw, []byte(tx.ID())...))
+	if err != nil {
+		return errors.Wrapf(err, "failed signing tx [%s]", tx.ID())
+	}
+	if err := context.Session().Send(sigma); err != nil {
+		return errors.WithMessagef(err, "failed sending back signature")
+	}
+	return nil
+}
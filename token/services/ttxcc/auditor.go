@@ -6,8 +6,11 @@ SPDX-License-Identifier: Apache-2.0
 package ttxcc
 
 import (
+	"context"
 	"time"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
 	"github.com/pkg/errors"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
@@ -16,9 +19,11 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	api2 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/auditor/auditdb"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 type txAuditor struct {
@@ -31,8 +36,8 @@ func NewAuditor(sp view2.ServiceProvider, w *token.AuditorWallet) *txAuditor {
 	}
 }
 
-func (a *txAuditor) Validate(tx *Transaction) error {
-	return a.auditor.Validate(tx.TokenRequest)
+func (a *txAuditor) Validate(ctx context.Context, tx *Transaction) error {
+	return a.auditor.Validate(ctx, tx.TokenRequest)
 }
 
 func (a *txAuditor) Audit(tx *Transaction) (*token.InputStream, *token.OutputStream, error) {
@@ -43,6 +48,16 @@ func (a *txAuditor) NewQueryExecutor() *auditor.QueryExecutor {
 	return a.auditor.NewQueryExecutor()
 }
 
+// UnspentTokensByEnrollmentID returns the unspent tokens, audited by this auditor, whose owner has the passed enrollment ID
+func (a *txAuditor) UnspentTokensByEnrollmentID(eID string, opts ...token.ServiceOption) (*token2.UnspentTokens, error) {
+	return a.auditor.UnspentTokensByEnrollmentID(eID, opts...)
+}
+
+// Subscribe returns a subscription to the audit records of the transactions committed after the passed cursor
+func (a *txAuditor) Subscribe(cursor uint64) *auditdb.Subscription {
+	return a.auditor.Subscribe(cursor)
+}
+
 type RegisterAuditorView struct {
 	Network   string
 	Channel   string
@@ -62,21 +77,23 @@ func (r *RegisterAuditorView) Call(context view.Context) (interface{}, error) {
 }
 
 type AuditingViewInitiator struct {
-	tx *Transaction
+	tx      *Transaction
+	auditor view.Identity
 }
 
-func newAuditingViewInitiator(tx *Transaction) *AuditingViewInitiator {
-	return &AuditingViewInitiator{tx: tx}
+func newAuditingViewInitiator(tx *Transaction, auditor view.Identity) *AuditingViewInitiator {
+	return &AuditingViewInitiator{tx: tx, auditor: auditor}
 }
 
+// Call sends the transaction to the auditor and returns its signature
 func (a *AuditingViewInitiator) Call(context view.Context) (interface{}, error) {
-	session, err := context.GetSession(a, a.tx.opts.auditor)
+	session, err := context.GetSession(a, a.auditor)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed getting session")
 	}
 
-	// Send transaction
-	txRaw, err := a.tx.Bytes()
+	// Send transaction, the auditor gets the metadata within its scope
+	txRaw, err := a.tx.auditorBytes(a.tx.TokenService().PublicParametersManager().AuditScope())
 	if err != nil {
 		return nil, err
 	}
@@ -90,9 +107,9 @@ func (a *AuditingViewInitiator) Call(context view.Context) (interface{}, error)
 	var msg *view.Message
 	select {
 	case msg = <-ch:
-		logger.Debug("reply received from %s", a.tx.opts.auditor)
+		logger.Debug("reply received from %s", a.auditor)
 	case <-time.After(60 * time.Second):
-		return nil, errors.Errorf("Timeout from party %s", a.tx.opts.auditor)
+		return nil, errors.Errorf("Timeout from party %s", a.auditor)
 	}
 	if msg.Status == view.ERROR {
 		return nil, errors.New(string(msg.Payload))
@@ -105,9 +122,9 @@ func (a *AuditingViewInitiator) Call(context view.Context) (interface{}, error)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed marshalling message to sign")
 	}
-	logger.Debugf("Verifying auditor signature on [%s][%s][%s]", a.tx.opts.auditor.UniqueID(), hash.Hashable(signed).String(), a.tx.ID())
+	logger.Debugf("Verifying auditor signature on [%s][%s][%s]", a.auditor.UniqueID(), hash.Hashable(signed).String(), a.tx.ID())
 
-	v, err := a.tx.TokenService().SigService().GetVerifier(a.tx.opts.auditor)
+	v, err := a.tx.TokenService().SigService().GetVerifier(a.auditor)
 	if err != nil {
 		return nil, err
 	}
@@ -115,9 +132,34 @@ func (a *AuditingViewInitiator) Call(context view.Context) (interface{}, error)
 		return nil, errors.Wrapf(err, "failed verifying auditor signature")
 	}
 
-	a.tx.TokenRequest.SetAuditorSignature(msg.Payload)
+	return msg.Payload, nil
+}
+
+// auditorBytes returns the serialization of this transaction to be sent to an auditor with the passed scope,
+// its metadata filtered by the scope, see token.Request.FilterMetadataByAuditScope
+func (t *Transaction) auditorBytes(scope api2.AuditScope) ([]byte, error) {
+	payload := *t.Payload
+	payload.TokenRequest = t.TokenRequest.FilterMetadataByAuditScope(scope)
+	return (&Transaction{Payload: &payload, opts: t.opts}).Bytes()
+}
 
-	return nil, nil
+// auditors returns the auditors that must sign the token request, in the order of their signatures.
+// The default auditor of the public parameters is reached at the identity passed with WithAuditor, if any.
+func (t *Transaction) auditors() ([]view.Identity, error) {
+	auditors, err := t.TokenRequest.Auditors()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting the auditors of [%s]", t.ID())
+	}
+	if t.opts.auditor.IsNone() {
+		return auditors, nil
+	}
+	defaultAuditor := t.TokenService().PublicParametersManager().AuditorIdentity()
+	for i, auditor := range auditors {
+		if auditor.Equal(defaultAuditor) {
+			auditors[i] = t.opts.auditor
+		}
+	}
+	return auditors, nil
 }
 
 type AuditApproveView struct {
@@ -216,6 +258,15 @@ func (a *AuditApproveView) waitFabricEnvelope(context view.Context) error {
 		return errors.WithMessagef(err, "failed storing tx env [%s]", tx.ID())
 	}
 
+	// Confirm the audit records once the transaction becomes final
+	txTime, err := envelopeTime(rawEnv)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting the time of tx [%s]", tx.ID())
+	}
+	if err := auditdb.GetAuditDB(context, a.w).TrackFinality(tx.Network(), tx.Channel(), tx.ID(), txTime, auditdb.NewChannelFinality(ch)); err != nil {
+		return errors.WithMessagef(err, "failed tracking the finality of [%s]", tx.ID())
+	}
+
 	// Send the proposal response back
 	logger.Debugf("Send the ack")
 	err = context.Session().Send([]byte("ack"))
@@ -225,3 +276,27 @@ func (a *AuditApproveView) waitFabricEnvelope(context view.Context) error {
 
 	return nil
 }
+
+// envelopeTime returns the timestamp of the channel header of the passed envelope,
+// the time of the transaction as fixed by the ledger
+func envelopeTime(raw []byte) (time.Time, error) {
+	env := &common.Envelope{}
+	if err := proto.Unmarshal(raw, env); err != nil {
+		return time.Time{}, errors.Wrap(err, "failed unmarshalling envelope")
+	}
+	payload := &common.Payload{}
+	if err := proto.Unmarshal(env.Payload, payload); err != nil {
+		return time.Time{}, errors.Wrap(err, "failed unmarshalling payload")
+	}
+	if payload.Header == nil {
+		return time.Time{}, errors.New("payload without header")
+	}
+	channelHeader := &common.ChannelHeader{}
+	if err := proto.Unmarshal(payload.Header.ChannelHeader, channelHeader); err != nil {
+		return time.Time{}, errors.Wrap(err, "failed unmarshalling channel header")
+	}
+	if channelHeader.Timestamp == nil {
+		return time.Time{}, errors.New("channel header without timestamp")
+	}
+	return time.Unix(channelHeader.Timestamp.Seconds, int64(channelHeader.Timestamp.Nanos)), nil
+}
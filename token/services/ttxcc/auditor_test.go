@@ -0,0 +1,77 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	api2 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+func TestEnvelopeTime(t *testing.T) {
+	marshal := func(m proto.Message) []byte {
+		raw, err := proto.Marshal(m)
+		assert.NoError(t, err)
+		return raw
+	}
+	envelope := func(ts *timestamp.Timestamp) []byte {
+		return marshal(&common.Envelope{Payload: marshal(&common.Payload{
+			Header: &common.Header{ChannelHeader: marshal(&common.ChannelHeader{TxId: "tx1", Timestamp: ts})},
+		})})
+	}
+
+	txTime, err := envelopeTime(envelope(&timestamp.Timestamp{Seconds: 1622505600, Nanos: 42}))
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2021, 6, 1, 0, 0, 0, 42, time.UTC).Equal(txTime))
+
+	_, err = envelopeTime(envelope(nil))
+	assert.EqualError(t, err, "channel header without timestamp")
+	_, err = envelopeTime([]byte("garbage"))
+	assert.Error(t, err)
+}
+
+func TestAuditorBytesFilterTheOpenings(t *testing.T) {
+	tx := &Transaction{
+		Payload: &Payload{
+			Id: fabric.TxID{Nonce: []byte("nonce"), Creator: []byte("alice")},
+			TokenRequest: &token.Request{
+				TxID:    "tx1",
+				Actions: &api2.TokenRequest{Transfers: [][]byte{[]byte("transfer")}},
+				Metadata: &api2.TokenRequestMetadata{Transfers: []api2.TransferMetadata{{
+					Outputs:            [][]byte{[]byte("output")},
+					TokenInfo:          [][]byte{[]byte("opening")},
+					ReceiverAuditInfos: [][]byte{[]byte("audit info")},
+				}}},
+			},
+		},
+		opts: defaultTxOptions(),
+	}
+	received := func(scope api2.AuditScope) *api2.TransferMetadata {
+		raw, err := tx.auditorBytes(scope)
+		assert.NoError(t, err)
+		payload := &Payload{}
+		assert.NoError(t, json.Unmarshal(raw, payload))
+		assert.Equal(t, tx.TokenRequest.Actions, payload.TokenRequest.Actions)
+		return &payload.TokenRequest.Metadata.Transfers[0]
+	}
+
+	assert.Equal(t, [][]byte{[]byte("opening")}, received(api2.FullAuditScope).TokenInfo)
+	// the auditor of the identities only cannot open the outputs
+	metadata := received(api2.IdentitiesAuditScope)
+	assert.Empty(t, metadata.TokenInfo)
+	assert.Equal(t, [][]byte{[]byte("audit info")}, metadata.ReceiverAuditInfos)
+	// the transaction keeps its openings
+	assert.Equal(t, [][]byte{[]byte("opening")}, tx.TokenRequest.Metadata.Transfers[0].TokenInfo)
+}
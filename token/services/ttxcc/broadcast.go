@@ -0,0 +1,216 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+)
+
+const pendingPrefix = "token-sdk.ttxcc.pending"
+
+// MaxRecoveryAttempts is the number of recoveries a pending transaction is submitted again by,
+// before it is marked as failed, see RecoverPendingTransactions
+const MaxRecoveryAttempts = 3
+
+// pendingTransaction is a fully signed envelope submitted to ordering whose final status is not known yet
+type pendingTransaction struct {
+	TxID     string
+	Network  string
+	Channel  string
+	Envelope json.RawMessage
+	// Done is true once the final status of the transaction is known
+	Done bool
+	// Failed is true once the transaction is no longer submitted again, its final status still unknown
+	Failed bool
+	// Recoveries is the number of recoveries that failed to resolve the transaction
+	Recoveries int
+}
+
+// storePending persists the envelope of the passed transaction before it is submitted to ordering,
+// so that it can be submitted again if the process crashes before its final status is known
+func storePending(sp view2.ServiceProvider, tx *Transaction) (*pendingTransaction, error) {
+	env, err := tx.Payload.FabricEnvelope.MarshalJSON()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed marshalling envelope of [%s]", tx.ID())
+	}
+	p := &pendingTransaction{TxID: tx.ID(), Network: tx.Network(), Channel: tx.Channel(), Envelope: env}
+	if err := putPending(sp, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// markDone records that the final status of the passed pending transaction is known
+func markDone(sp view2.ServiceProvider, p *pendingTransaction) {
+	p.Done = true
+	if err := putPending(sp, p); err != nil {
+		logger.Errorf("failed marking transaction [%s] as done: [%s]", p.TxID, err)
+	}
+}
+
+// markFailed records that the passed pending transaction is no longer submitted again
+func markFailed(sp view2.ServiceProvider, p *pendingTransaction) {
+	p.Failed = true
+	if err := putPending(sp, p); err != nil {
+		logger.Errorf("failed marking transaction [%s] as failed: [%s]", p.TxID, err)
+	}
+}
+
+// recoveryFailed records that a recovery failed to resolve the passed pending transaction,
+// the transaction is marked as failed after MaxRecoveryAttempts recoveries
+func recoveryFailed(sp view2.ServiceProvider, p *pendingTransaction, err error) {
+	p.Recoveries++
+	if p.Recoveries >= MaxRecoveryAttempts {
+		logger.Errorf("transaction [%s] not resolved after [%d] recoveries, mark it as failed: [%s]", p.TxID, p.Recoveries, err)
+		markFailed(sp, p)
+		return
+	}
+	logger.Warnf("transaction [%s] still pending: [%s]", p.TxID, err)
+	if err := putPending(sp, p); err != nil {
+		logger.Errorf("failed recording the recovery of [%s]: [%s]", p.TxID, err)
+	}
+}
+
+func putPending(sp view2.ServiceProvider, p *pendingTransaction) error {
+	k, err := kvs.CreateCompositeKey(pendingPrefix, []string{p.TxID})
+	if err != nil {
+		return errors.WithMessagef(err, "failed creating pending key for [%s]", p.TxID)
+	}
+	return kvs.GetService(sp).Put(k, p)
+}
+
+// pendingTransactions returns the transactions whose final status is not known and that have not failed
+func pendingTransactions(sp view2.ServiceProvider) ([]*pendingTransaction, error) {
+	return listPending(sp, func(p *pendingTransaction) bool { return !p.Done && !p.Failed })
+}
+
+// FailedTransactions returns the ids of the transactions whose submission has been given up, their final status unknown.
+// They are no longer submitted again, though they might still be committed.
+func FailedTransactions(sp view2.ServiceProvider) ([]string, error) {
+	failed, err := listPending(sp, func(p *pendingTransaction) bool { return !p.Done && p.Failed })
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, p := range failed {
+		res = append(res, p.TxID)
+	}
+	return res, nil
+}
+
+func listPending(sp view2.ServiceProvider, filter func(p *pendingTransaction) bool) ([]*pendingTransaction, error) {
+	it, err := kvs.GetService(sp).GetByPartialCompositeID(pendingPrefix, []string{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed querying pending transactions")
+	}
+	defer it.Close()
+
+	var res []*pendingTransaction
+	for it.HasNext() {
+		p := &pendingTransaction{}
+		if err := it.Next(p); err != nil {
+			return nil, errors.WithMessage(err, "failed reading pending transaction")
+		}
+		if filter(p) {
+			res = append(res, p)
+		}
+	}
+	return res, nil
+}
+
+// RecoverPendingTransactions resolves the transactions submitted to ordering whose final status is not known,
+// for instance because the process crashed between endorsement and ordering. It is meant to be called at startup.
+// A transaction already committed is just marked as done, otherwise its envelope is submitted again and its finality
+// awaited for at most the passed timeout. Submitting the same envelope again is safe, the transaction id does not change.
+// It returns the ids of the transactions committed as valid, the others are left pending for the next recovery,
+// up to MaxRecoveryAttempts recoveries, then they are marked as failed, see FailedTransactions.
+func RecoverPendingTransactions(sp view2.ServiceProvider, finalityTimeout time.Duration) ([]string, error) {
+	pending, err := pendingTransactions(sp)
+	if err != nil {
+		return nil, err
+	}
+	var valid []string
+	for _, p := range pending {
+		ok, err := recoverPending(sp, p, finalityTimeout)
+		if err != nil {
+			recoveryFailed(sp, p, err)
+			continue
+		}
+		if ok {
+			valid = append(valid, p.TxID)
+		}
+	}
+	return valid, nil
+}
+
+// recoverPending returns true if the passed transaction has been committed as valid, false if invalid,
+// an error if its final status is still unknown
+func recoverPending(sp view2.ServiceProvider, p *pendingTransaction, finalityTimeout time.Duration) (bool, error) {
+	fns := fabric.GetFabricNetworkService(sp, p.Network)
+	if fns == nil {
+		return false, errors.Errorf("network [%s] not found", p.Network)
+	}
+	net, err := network.GetNetwork(sp, p.Network, p.Channel)
+	if err != nil {
+		return false, errors.WithMessagef(err, "failed getting network [%s:%s]", p.Network, p.Channel)
+	}
+	ch := fabric.GetChannel(sp, p.Network, p.Channel)
+	if valid, known := finalStatus(ch, p.TxID); known {
+		markDone(sp, p)
+		return valid, nil
+	}
+
+	env := fns.TransactionManager().NewEnvelope()
+	if err := env.UnmarshalJSON(p.Envelope); err != nil {
+		return false, errors.WithMessage(err, "failed unmarshalling envelope")
+	}
+	logger.Infof("submit again pending transaction [%s]", p.TxID)
+	if err := net.Broadcast(p.TxID, env); err != nil {
+		return false, errors.WithMessage(err, "failed broadcasting envelope")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- net.IsFinal(p.TxID)
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			markDone(sp, p)
+			return true, nil
+		}
+		if valid, known := finalStatus(ch, p.TxID); known {
+			markDone(sp, p)
+			return valid, nil
+		}
+		return false, err
+	case <-time.After(finalityTimeout):
+		return false, errors.Errorf("timeout waiting for the finality of [%s]", p.TxID)
+	}
+}
+
+// finalStatus returns the final status of the passed transaction in the vault, if known
+func finalStatus(ch *fabric.Channel, txID string) (valid bool, known bool) {
+	code, _, err := ch.Vault().Status(txID)
+	if err != nil {
+		return false, false
+	}
+	switch code {
+	case fabric.Valid:
+		return true, true
+	case fabric.Invalid:
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,72 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"testing"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/api"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// configProvider configures the in memory kvs
+type configProvider struct {
+	api.ConfigProvider
+}
+
+func (*configProvider) UnmarshalKey(string, interface{}) error {
+	return nil
+}
+
+func TestPendingTransactionsFail(t *testing.T) {
+	sp := registry.New()
+	assert.NoError(t, sp.RegisterService(&configProvider{}))
+	kvss, err := kvs.New("memory", "", sp)
+	assert.NoError(t, err)
+	assert.NoError(t, sp.RegisterService(kvss))
+
+	ordered := &pendingTransaction{TxID: "tx1", Network: "n", Channel: "c"}
+	recovered := &pendingTransaction{TxID: "tx2", Network: "n", Channel: "c"}
+	committed := &pendingTransaction{TxID: "tx3", Network: "n", Channel: "c"}
+	for _, p := range []*pendingTransaction{ordered, recovered, committed} {
+		assert.NoError(t, putPending(sp, p))
+	}
+	markDone(sp, committed)
+	assertPending(t, sp, "tx1", "tx2")
+
+	// the ordering retries run out
+	markFailed(sp, ordered)
+	assertPending(t, sp, "tx2")
+
+	// the recoveries run out
+	for i := 1; i < MaxRecoveryAttempts; i++ {
+		recoveryFailed(sp, recovered, errors.New("timeout"))
+		assertPending(t, sp, "tx2")
+	}
+	pending, err := pendingTransactions(sp)
+	assert.NoError(t, err)
+	assert.Equal(t, MaxRecoveryAttempts-1, pending[0].Recoveries)
+	recoveryFailed(sp, pending[0], errors.New("timeout"))
+	assertPending(t, sp)
+
+	failed, err := FailedTransactions(sp)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"tx1", "tx2"}, failed)
+}
+
+func assertPending(t *testing.T, sp view2.ServiceProvider, txIDs ...string) {
+	pending, err := pendingTransactions(sp)
+	assert.NoError(t, err)
+	var ids []string
+	for _, p := range pending {
+		ids = append(ids, p.TxID)
+	}
+	assert.ElementsMatch(t, txIDs, ids)
+}
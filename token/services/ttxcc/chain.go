@@ -0,0 +1,118 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// DefaultMaxMergeRounds bounds the rounds of merge transactions of a chained transfer.
+// Each round divides the number of inputs by the maximum number of inputs, at least two.
+const DefaultMaxMergeRounds = 8
+
+// ChainedTransfer appends to the transaction a transfer, as Transfer does, that spends at most maxInputs inputs.
+// If the selection needs more inputs, they are first merged, at most maxInputs at a time, by transfers of the wallet
+// to itself, each in its own transaction, endorsed and committed before the selection is run again.
+// The selection runs again only once the merge transactions are final in the vault of the wallet, so that the merged
+// tokens can be selected. They are held by the wallet, the transaction then spends them as any other token.
+// The merge transactions have the options of this transaction, including its auditor.
+func (t *Transaction) ChainedTransfer(context view.Context, wallet *token.OwnerWallet, typ string, values []uint64, owners []view.Identity, maxInputs int, opts ...token.TransferOption) error {
+	opts = append([]token.TransferOption{token.WithMaxInputs(maxInputs)}, opts...)
+	return chainTransfers(
+		t.ID(),
+		maxInputs,
+		func() error {
+			return t.Transfer(wallet, typ, values, owners, opts...)
+		},
+		func(ids []*token2.Id) error {
+			return t.merge(context, wallet, typ, ids)
+		},
+		func(ids []*token2.Id) error {
+			sm, err := t.TokenService().SelectorManager()
+			if err != nil {
+				return err
+			}
+			return sm.UnlockIDs(ids...)
+		},
+	)
+}
+
+// chainTransfers runs the passed transfer until it does not need more than maxInputs inputs, merging the inputs
+// with the passed merge function, at most maxInputs at a time, and releasing them with the passed unlock function,
+// between the runs
+func chainTransfers(txID string, maxInputs int, transfer func() error, merge func(ids []*token2.Id) error, unlock func(ids []*token2.Id) error) error {
+	for round := 0; ; round++ {
+		err := transfer()
+		var tooMany *token.TooManyInputsError
+		if err == nil || !errors.As(err, &tooMany) {
+			return err
+		}
+		if round >= DefaultMaxMergeRounds {
+			return errors.WithMessagef(err, "inputs still too many after [%d] rounds of merge", round)
+		}
+		logger.Debugf("transfer of [%s] needs [%d] inputs, more than [%d], merge them first [round %d]", txID, len(tooMany.IDs), maxInputs, round)
+		for start := 0; start < len(tooMany.IDs); start += maxInputs {
+			end := start + maxInputs
+			if end > len(tooMany.IDs) {
+				end = len(tooMany.IDs)
+			}
+			if end-start < 2 {
+				// a single input does not need to be merged
+				continue
+			}
+			if err := merge(tooMany.IDs[start:end]); err != nil {
+				return errors.WithMessagef(err, "failed merging inputs of [%s]", txID)
+			}
+		}
+		// the merged inputs are spent, the others can be selected again
+		if err := unlock(tooMany.IDs); err != nil {
+			return errors.WithMessagef(err, "failed releasing inputs of [%s]", txID)
+		}
+	}
+}
+
+// merge commits a transaction transferring the passed tokens of the wallet to a single token of the wallet itself,
+// and waits for its finality in the vault
+func (t *Transaction) merge(context view.Context, wallet *token.OwnerWallet, typ string, ids []*token2.Id) error {
+	opts := *t.opts
+	tx, err := NewTransaction(
+		context,
+		fabric.GetFabricNetworkService(context, t.Network()).LocalMembership().AnonymousIdentity(),
+		func(o *txOptions) error {
+			*o = opts
+			return nil
+		},
+	)
+	if err != nil {
+		return errors.WithMessage(err, "failed creating merge transaction")
+	}
+	// without outputs, the whole value of the inputs is returned to the wallet as change
+	if err := tx.Transfer(wallet, typ, nil, nil, token.WithTokenIDs(ids...)); err != nil {
+		return errors.WithMessagef(err, "failed merging [%v]", ids)
+	}
+	for _, v := range mergeViews(tx) {
+		if _, err := context.RunView(v); err != nil {
+			return errors.WithMessagef(err, "failed committing merge transaction [%s]", tx.ID())
+		}
+	}
+	logger.Debugf("merged [%d] inputs in [%s] for [%s]", len(ids), tx.ID(), t.ID())
+	return nil
+}
+
+// mergeViews returns the views, to be run in order, that endorse and order the passed merge transaction,
+// and wait for its finality in the vault, the merged token cannot be selected before
+func mergeViews(tx *Transaction) []view.View {
+	return []view.View{
+		NewCollectEndorsementsView(tx),
+		NewOrderingView(tx),
+		NewFinalityView(tx),
+	}
+}
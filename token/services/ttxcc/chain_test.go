@@ -0,0 +1,97 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+func tokenIDs(n int) []*token2.Id {
+	ids := make([]*token2.Id, n)
+	for i := range ids {
+		ids[i] = &token2.Id{TxId: fmt.Sprintf("tx%d", i)}
+	}
+	return ids
+}
+
+func TestChainTransfers(t *testing.T) {
+	ids := tokenIDs(5)
+	var merged [][]*token2.Id
+	var unlocked []*token2.Id
+	merge := func(ids []*token2.Id) error {
+		merged = append(merged, ids)
+		return nil
+	}
+	unlock := func(ids []*token2.Id) error {
+		unlocked = append(unlocked, ids...)
+		return nil
+	}
+
+	// the transfer does not need too many inputs
+	runs := 0
+	err := chainTransfers("tx", 2, func() error {
+		runs++
+		return nil
+	}, merge, unlock)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, runs)
+	assert.Empty(t, merged)
+
+	// the inputs are merged two at a time, the last one is left alone, then the transfer runs again
+	runs = 0
+	err = chainTransfers("tx", 2, func() error {
+		runs++
+		if runs == 1 {
+			return errors.WithMessage(&token.TooManyInputsError{Type: "USD", IDs: ids, Max: 2}, "failed preparing transfer")
+		}
+		return nil
+	}, merge, unlock)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, runs)
+	assert.Equal(t, [][]*token2.Id{ids[0:2], ids[2:4]}, merged)
+	assert.Equal(t, ids, unlocked)
+
+	// other failures are returned as they are
+	failure := errors.New("insufficient funds")
+	err = chainTransfers("tx", 2, func() error { return failure }, merge, unlock)
+	assert.Equal(t, failure, err)
+
+	// a failed merge stops the chain
+	err = chainTransfers("tx", 2, func() error {
+		return &token.TooManyInputsError{Type: "USD", IDs: ids, Max: 2}
+	}, func(ids []*token2.Id) error {
+		return errors.New("endorsement failed")
+	}, unlock)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "endorsement failed")
+
+	// the rounds of merge are bounded
+	runs = 0
+	err = chainTransfers("tx", 2, func() error {
+		runs++
+		return &token.TooManyInputsError{Type: "USD", IDs: ids, Max: 2}
+	}, merge, unlock)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("after [%d] rounds of merge", DefaultMaxMergeRounds))
+	assert.Equal(t, DefaultMaxMergeRounds+1, runs)
+}
+
+func TestMergeViewsWaitForFinality(t *testing.T) {
+	tx := &Transaction{}
+	views := mergeViews(tx)
+	assert.Len(t, views, 3)
+	assert.IsType(t, &collectEndorsementsView{}, views[0])
+	assert.IsType(t, &orderingView{}, views[1])
+	// the merged token can be selected only once the merge transaction is final in the vault
+	assert.Equal(t, &finalityView{tx: tx}, views[2])
+}
@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	api2 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tracing"
 
 	"github.com/pkg/errors"
 
@@ -71,7 +73,11 @@ func NewCollectActionsView(tx *Transaction, actions ...*ActionTransfer) *collect
 	}
 }
 
-func (c *collectActionsView) Call(context view.Context) (interface{}, error) {
+func (c *collectActionsView) Call(context view.Context) (res interface{}, err error) {
+	_, span := c.tx.startSpan("ttxcc.collect")
+	span.SetAttribute("actions", len(c.actions.Transfers))
+	defer func() { tracing.EndWithError(span, err) }()
+
 	ts := token.GetManagementService(context, token.WithChannel(c.tx.Channel()))
 
 	for _, actionTransfer := range c.actions.Transfers {
@@ -137,14 +143,18 @@ func (c *collectActionsView) collectRemote(context view.Context, actionTransfer
 	txPayload := &Payload{
 		Transient: map[string][]byte{},
 	}
-	err = json.Unmarshal(msg.Payload, txPayload)
+	raw, err := api2.Decompress(msg.Payload, api2.DefaultMaxDecompressedSize)
+	if err != nil {
+		return errors.Wrap(err, "failed decompressing reply")
+	}
+	err = json.Unmarshal(raw, txPayload)
 	if err != nil {
 		return errors.Wrap(err, "failed unmarshalling reply")
 	}
 
 	// Check
 	txPayload.TokenRequest.SetTokenService(c.tx.TokenService())
-	if err := txPayload.TokenRequest.Verify(); err != nil {
+	if err := txPayload.TokenRequest.Verify(context.Context()); err != nil {
 		return errors.Wrap(err, "failed verifying response")
 	}
 
@@ -0,0 +1,51 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// ErrInputsSpent is returned when a transaction is committed as invalid because some of its inputs
+// have been spent by a conflicting transaction committed first
+var ErrInputsSpent = errors2.New(errors2.Conflict, "inputs spent by a conflicting transaction")
+
+// DefaultConflictRetries is the number of times RetryOnConflict runs a view again
+const DefaultConflictRetries = 3
+
+type tokenGetter interface {
+	GetTokens(inputs ...*token2.Id) ([]*token2.Token, error)
+}
+
+// conflictError returns ErrInputsSpent, annotated with the passed error, if any of the passed inputs is no longer unspent,
+// the passed error otherwise
+func conflictError(err error, inputs []*token2.Id, qe tokenGetter) error {
+	if len(inputs) == 0 {
+		return err
+	}
+	if _, qErr := qe.GetTokens(inputs...); qErr == nil {
+		return err
+	}
+	return errors.WithMessagef(ErrInputsSpent, "%s", err)
+}
+
+// RetryOnConflict runs the passed view, that assembles and orders a token transaction, and runs it again,
+// up to the passed number of retries, when the transaction is committed as invalid because a conflicting transaction
+// spent its inputs first, see ErrInputsSpent.
+// Each run must assemble a new transaction, so that its inputs are selected again among the unspent tokens.
+func RetryOnConflict(context view.Context, retries int, v view.View) (interface{}, error) {
+	for attempt := 0; ; attempt++ {
+		res, err := context.RunView(v)
+		if err == nil || attempt >= retries || !errors.Is(err, ErrInputsSpent) {
+			return res, err
+		}
+		logger.Warnf("transaction conflicted with a concurrent one, assemble it again [%d] of [%d]: [%s]", attempt+1, retries, err)
+	}
+}
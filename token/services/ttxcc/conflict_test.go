@@ -0,0 +1,96 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+type unspentTokens map[string]bool
+
+func (u unspentTokens) GetTokens(inputs ...*token2.Id) ([]*token2.Token, error) {
+	var tokens []*token2.Token
+	for _, id := range inputs {
+		if !u[id.String()] {
+			return nil, errors.Errorf("token [%s] not found", id)
+		}
+		tokens = append(tokens, &token2.Token{})
+	}
+	return tokens, nil
+}
+
+// viewContext names the embedded context, view.Context has a method named Context
+type viewContext = view.Context
+
+// viewRunner runs the views it is passed, returning the queued errors one by one
+type viewRunner struct {
+	viewContext
+	errs []error
+	runs int
+}
+
+func (r *viewRunner) RunView(v view.View) (interface{}, error) {
+	r.runs++
+	if len(r.errs) == 0 {
+		return "done", nil
+	}
+	err := r.errs[0]
+	r.errs = r.errs[1:]
+	return nil, err
+}
+
+func TestConflictError(t *testing.T) {
+	invalid := errors.New("transaction [tx1] is not valid")
+	a := &token2.Id{TxId: "a", Index: 0}
+	b := &token2.Id{TxId: "b", Index: 0}
+
+	// the inputs are still unspent, the transaction is invalid for another reason
+	err := conflictError(invalid, []*token2.Id{a, b}, unspentTokens{a.String(): true, b.String(): true})
+	assert.Equal(t, invalid, err)
+	assert.Equal(t, invalid, conflictError(invalid, nil, unspentTokens{}))
+
+	// an input has been spent by a conflicting transaction
+	err = conflictError(invalid, []*token2.Id{a, b}, unspentTokens{a.String(): true})
+	assert.True(t, errors.Is(err, ErrInputsSpent))
+	assert.True(t, errors2.HasCode(err, errors2.Conflict))
+	assert.Contains(t, err.Error(), "[tx1] is not valid")
+}
+
+func TestRetryOnConflict(t *testing.T) {
+	conflict := errors.WithMessage(ErrInputsSpent, "transaction [tx1] is not valid")
+
+	// conflicts are retried
+	r := &viewRunner{errs: []error{conflict, conflict}}
+	res, err := RetryOnConflict(r, DefaultConflictRetries, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "done", res)
+	assert.Equal(t, 3, r.runs)
+
+	// up to the number of retries
+	r = &viewRunner{errs: []error{conflict, conflict, conflict}}
+	_, err = RetryOnConflict(r, 2, nil)
+	assert.True(t, errors.Is(err, ErrInputsSpent))
+	assert.Equal(t, 3, r.runs)
+
+	// other failures are not
+	r = &viewRunner{errs: []error{errors.New("insufficient funds"), conflict}}
+	_, err = RetryOnConflict(r, DefaultConflictRetries, nil)
+	assert.EqualError(t, err, "insufficient funds")
+	assert.Equal(t, 1, r.runs)
+
+	// nor are the endorsement mismatches, despite sharing the conflict code
+	r = &viewRunner{errs: []error{ErrEndorsementMismatch}}
+	_, err = RetryOnConflict(r, DefaultConflictRetries, nil)
+	assert.Equal(t, ErrEndorsementMismatch, err)
+	assert.Equal(t, 1, r.runs)
+}
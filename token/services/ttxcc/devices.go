@@ -0,0 +1,491 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	session2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/session"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	api2 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tcc"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/processor"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// The same owner wallet, identified by its enrollment ID, can be used from multiple nodes, called devices.
+// Two devices are paired by running ShareWalletView on one of them and RespondShareWalletView on the other:
+// they exchange the recipient identities of the wallet with their audit information, and the unspent tokens of the
+// wallet with the information to open them, and record each other as paired. The shared tokens are checked against
+// the ledger and stored in the vault with the next transaction committed in the namespace, see processor.SyncedToken.
+// From then on, every transaction a device accepts, endorses or distributes involving the wallet is forwarded,
+// with the recipient identities it uses, to the paired devices by SyncTransactionView. The paired devices store
+// the transaction like any other party, so that their vaults, once the transaction commits, contain the same tokens.
+// The devices must register AcceptSyncView as responder of SyncTransactionView.
+// The derivation material of the wallet, its long-term credential and secret keys, is not shared: each device is
+// enrolled with its own credential for the same enrollment ID, and derives its own recipient identities.
+// Only public material crosses the session: the recipient identities, their audit information, which the importing
+// device checks to match the identity and to carry the wallet's enrollment ID, and the information to open the tokens.
+// Then a device can spend only the tokens owned by the recipient identities it has derived,
+// the signing material of the others never leaves the device that derived them. The token selectors skip the others.
+
+const devicePrefix = "token-sdk.ttxcc.device"
+
+// PairedDevice is a device the local owner wallet with the given enrollment ID has been paired with
+type PairedDevice struct {
+	EnrollmentID string
+	WalletID     string
+	Device       view.Identity
+}
+
+// WalletShare carries the recipient identities of an owner wallet to be imported by a paired device and,
+// at pairing, the unspent tokens of the wallet
+type WalletShare struct {
+	EnrollmentID string
+	Identities   []*api2.RecipientIdentity
+	Tokens       []*SharedToken
+}
+
+// SharedToken is an unspent token of a shared wallet with the information needed to open its output on the ledger
+type SharedToken struct {
+	ID   *token2.Id
+	Info []byte
+}
+
+func (w *WalletShare) Bytes() ([]byte, error) {
+	return json.Marshal(w)
+}
+
+func (w *WalletShare) FromBytes(raw []byte) error {
+	return json.Unmarshal(raw, w)
+}
+
+type ShareWalletView struct {
+	wallet *token.OwnerWallet
+	device view.Identity
+}
+
+// NewShareWalletView returns a view that pairs the passed wallet with the same wallet on the passed device
+func NewShareWalletView(wallet *token.OwnerWallet, device view.Identity) *ShareWalletView {
+	return &ShareWalletView{wallet: wallet, device: device}
+}
+
+func (s *ShareWalletView) Call(context view.Context) (interface{}, error) {
+	share, err := exportWallet(s.wallet)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := share.Bytes()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed marshalling wallet share")
+	}
+
+	session, err := context.GetSession(s, s.device)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed getting session")
+	}
+	if err := session.Send(raw); err != nil {
+		return nil, errors.Wrap(err, "failed sending wallet share")
+	}
+	payload, err := session2.ReadMessageWithTimeout(session, 60*time.Second)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed receiving wallet share from [%s]", s.device)
+	}
+	other := &WalletShare{}
+	if err := other.FromBytes(payload); err != nil {
+		return nil, errors.Wrapf(err, "failed unmarshalling wallet share")
+	}
+	if err := importWallet(context, s.wallet, s.device, other); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+type RespondShareWalletView struct {
+	wallet *token.OwnerWallet
+	device view.Identity
+}
+
+// NewRespondShareWalletView returns a view that pairs the passed wallet with the same wallet on the passed device.
+// Requests coming from any other node are rejected.
+func NewRespondShareWalletView(wallet *token.OwnerWallet, device view.Identity) *RespondShareWalletView {
+	return &RespondShareWalletView{wallet: wallet, device: device}
+}
+
+func (s *RespondShareWalletView) Call(context view.Context) (interface{}, error) {
+	session, payload, err := session2.ReadFirstMessage(context)
+	if err != nil {
+		return nil, err
+	}
+	if caller := session.Info().Caller; !s.device.Equal(caller) {
+		return nil, errors2.Errorf(errors2.Unauthorized, "wallet share request from [%s], expected [%s]", caller, s.device)
+	}
+	other := &WalletShare{}
+	if err := other.FromBytes(payload); err != nil {
+		return nil, errors.Wrapf(err, "failed unmarshalling wallet share")
+	}
+	if err := importWallet(context, s.wallet, s.device, other); err != nil {
+		return nil, err
+	}
+
+	share, err := exportWallet(s.wallet)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := share.Bytes()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed marshalling wallet share")
+	}
+	if err := session.Send(raw); err != nil {
+		return nil, errors.Wrap(err, "failed sending wallet share")
+	}
+	return nil, nil
+}
+
+// SyncMessage carries a transaction to a paired device together with the recipient identities
+// of the wallet used by the transaction that the device might not know yet
+type SyncMessage struct {
+	Network      string
+	EnrollmentID string
+	Transaction  []byte
+	Identities   []*api2.RecipientIdentity
+}
+
+type SyncTransactionView struct {
+	tx      *Transaction
+	share   *WalletShare
+	devices []view.Identity
+}
+
+// NewSyncTransactionView returns a view that sends the passed transaction and wallet share to the passed devices
+func NewSyncTransactionView(tx *Transaction, share *WalletShare, devices ...view.Identity) *SyncTransactionView {
+	return &SyncTransactionView{tx: tx, share: share, devices: devices}
+}
+
+func (s *SyncTransactionView) Call(context view.Context) (interface{}, error) {
+	txRaw, err := s.tx.Bytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshalling transaction content")
+	}
+	raw, err := json.Marshal(&SyncMessage{
+		Network:      s.tx.Network(),
+		EnrollmentID: s.share.EnrollmentID,
+		Transaction:  txRaw,
+		Identities:   s.share.Identities,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshalling sync message")
+	}
+
+	for _, device := range s.devices {
+		logger.Debugf("sync transaction [%s] with device [%s]", s.tx.ID(), device)
+		session, err := context.GetSession(s, device)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed getting session")
+		}
+		if err := session.Send(raw); err != nil {
+			return nil, errors.Wrapf(err, "failed sending transaction to device [%s]", device)
+		}
+		ack, err := session2.ReadMessageWithTimeout(session, 60*time.Second)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed syncing transaction [%s] with device [%s]", s.tx.ID(), device)
+		}
+		if string(ack) != "ack" {
+			return nil, errors.Errorf("unexpected ack from device [%s]", device)
+		}
+	}
+	return nil, nil
+}
+
+type AcceptSyncView struct{}
+
+// NewAcceptSyncView returns the responder of SyncTransactionView.
+// It accepts transactions only from the devices the involved wallet has been paired with.
+func NewAcceptSyncView() *AcceptSyncView {
+	return &AcceptSyncView{}
+}
+
+func (a *AcceptSyncView) Call(context view.Context) (interface{}, error) {
+	session, payload, err := session2.ReadFirstMessage(context)
+	if err != nil {
+		return nil, err
+	}
+	msg := &SyncMessage{}
+	if err := json.Unmarshal(payload, msg); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling sync message")
+	}
+	caller := session.Info().Caller
+	paired, err := getPairedDevice(context, msg.EnrollmentID, caller)
+	if err != nil {
+		return nil, err
+	}
+	if paired == nil {
+		return nil, errors2.Errorf(errors2.Unauthorized, "device [%s] is not paired for [%s]", caller, msg.EnrollmentID)
+	}
+
+	tx, err := NewTransactionFromBytes(context, msg.Network, msg.Transaction)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed unmarshalling transaction")
+	}
+	logger.Debugf("sync transaction [%s] from device [%s]", tx.ID(), caller)
+
+	// Import the recipient identities, then the tokens they own get stored when the transaction commits
+	wallet := tx.TokenService().WalletManager().OwnerWallet(paired.WalletID)
+	if wallet == nil {
+		return nil, errors.Errorf("owner wallet [%s] not found", paired.WalletID)
+	}
+	if err := wallet.ImportRecipientIdentities(msg.Identities); err != nil {
+		return nil, errors.WithMessagef(err, "failed importing recipient identities from device [%s]", caller)
+	}
+
+	env := tx.Payload.FabricEnvelope
+	if env == nil {
+		return nil, errors.Errorf("expected fabric envelope")
+	}
+	if err := tx.storeTransient(); err != nil {
+		return nil, errors.Wrapf(err, "failed storing transient")
+	}
+	ch := fabric.GetChannel(context, tx.Network(), tx.Channel())
+	rws, err := ch.Vault().GetRWSet(tx.ID(), env.Results())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting rwset for tx [%s]", tx.ID())
+	}
+	rws.Done()
+	rawEnv, err := env.Bytes()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed marshalling tx env [%s]", tx.ID())
+	}
+	if err := ch.Vault().StoreEnvelope(env.TxID(), rawEnv); err != nil {
+		return nil, errors.WithMessagef(err, "failed storing tx env [%s]", tx.ID())
+	}
+
+	if err := session.Send([]byte("ack")); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// GetPairedDevices returns the devices the owner wallet with the passed enrollment ID has been paired with
+func GetPairedDevices(sp view2.ServiceProvider, enrollmentID string) ([]*PairedDevice, error) {
+	it, err := kvs.GetService(sp).GetByPartialCompositeID(devicePrefix, []string{enrollmentID})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed querying devices of [%s]", enrollmentID)
+	}
+	defer it.Close()
+
+	var devices []*PairedDevice
+	for it.HasNext() {
+		device := &PairedDevice{}
+		if err := it.Next(device); err != nil {
+			return nil, errors.WithMessagef(err, "failed reading device of [%s]", enrollmentID)
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+func getPairedDevice(sp view2.ServiceProvider, enrollmentID string, device view.Identity) (*PairedDevice, error) {
+	k, err := kvs.CreateCompositeKey(devicePrefix, []string{enrollmentID, device.UniqueID()})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed creating device key for [%s]", enrollmentID)
+	}
+	kvss := kvs.GetService(sp)
+	if !kvss.Exists(k) {
+		return nil, nil
+	}
+	paired := &PairedDevice{}
+	if err := kvss.Get(k, paired); err != nil {
+		return nil, errors.WithMessagef(err, "failed getting device [%s] of [%s]", device, enrollmentID)
+	}
+	return paired, nil
+}
+
+func storePairedDevice(sp view2.ServiceProvider, paired *PairedDevice) error {
+	k, err := kvs.CreateCompositeKey(devicePrefix, []string{paired.EnrollmentID, paired.Device.UniqueID()})
+	if err != nil {
+		return errors.WithMessagef(err, "failed creating device key for [%s]", paired.EnrollmentID)
+	}
+	return kvs.GetService(sp).Put(k, paired)
+}
+
+func exportWallet(wallet *token.OwnerWallet) (*WalletShare, error) {
+	ids, err := wallet.ExportRecipientIdentities()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed exporting recipient identities of wallet [%s]", wallet.ID())
+	}
+	share := &WalletShare{EnrollmentID: wallet.EnrollmentID(), Identities: ids}
+
+	unspent, err := wallet.ListTokens()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed listing tokens of wallet [%s]", wallet.ID())
+	}
+	if len(unspent.Tokens) == 0 {
+		return share, nil
+	}
+	tokenIDs := make([]*token2.Id, len(unspent.Tokens))
+	for i, t := range unspent.Tokens {
+		tokenIDs[i] = t.Id
+	}
+	if err := wallet.TokenService().Vault().NewQueryEngine().GetTokenInfos(tokenIDs, func(id *token2.Id, info []byte) error {
+		share.Tokens = append(share.Tokens, &SharedToken{ID: id, Info: info})
+		return nil
+	}); err != nil {
+		return nil, errors.WithMessagef(err, "failed loading the information of the tokens of wallet [%s]", wallet.ID())
+	}
+	return share, nil
+}
+
+func importWallet(context view.Context, wallet *token.OwnerWallet, device view.Identity, share *WalletShare) error {
+	if share.EnrollmentID != wallet.EnrollmentID() {
+		return errors.Errorf("wallet share for [%s], expected [%s]", share.EnrollmentID, wallet.EnrollmentID())
+	}
+	if err := wallet.ImportRecipientIdentities(share.Identities); err != nil {
+		return errors.WithMessagef(err, "failed importing recipient identities from device [%s]", device)
+	}
+	if err := syncTokens(context, wallet, share.Tokens); err != nil {
+		return errors.WithMessagef(err, "failed syncing tokens from device [%s]", device)
+	}
+	if err := storePairedDevice(context, &PairedDevice{
+		EnrollmentID: wallet.EnrollmentID(),
+		WalletID:     wallet.ID(),
+		Device:       device,
+	}); err != nil {
+		return errors.WithMessagef(err, "failed storing paired device [%s]", device)
+	}
+	logger.Debugf("wallet [%s] paired with device [%s]", wallet.ID(), device)
+	return nil
+}
+
+// syncTokens records the passed tokens, shared by a paired device, to be stored in the vault.
+// Their outputs are fetched from the ledger, the shared information must open them, and the wallet must own them.
+// The tokens already known are skipped.
+func syncTokens(context view.Context, wallet *token.OwnerWallet, shared []*SharedToken) error {
+	if len(shared) == 0 {
+		return nil
+	}
+	unspent, err := wallet.ListTokens()
+	if err != nil {
+		return errors.WithMessagef(err, "failed listing tokens of wallet [%s]", wallet.ID())
+	}
+	known := map[string]bool{}
+	for _, t := range unspent.Tokens {
+		known[t.Id.String()] = true
+	}
+	var ids []*token2.Id
+	var infos [][]byte
+	for _, st := range shared {
+		if st == nil || st.ID == nil {
+			return errors.Errorf("invalid shared token, it is empty")
+		}
+		if known[st.ID.String()] {
+			continue
+		}
+		known[st.ID.String()] = true
+		ids = append(ids, st.ID)
+		infos = append(infos, st.Info)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	tms := wallet.TokenService()
+	getTokens := tcc.NewGetTokensView(tms.Channel(), tms.Namespace(), ids...)
+	getTokens.Network = tms.Network()
+	boxed, err := context.RunView(getTokens)
+	if err != nil {
+		return errors.WithMessagef(err, "failed fetching the shared tokens from the ledger")
+	}
+	outputs, ok := boxed.([][]byte)
+	if !ok || len(outputs) != len(ids) {
+		return errors.Errorf("expected [%d] outputs from the ledger", len(ids))
+	}
+	synced := make([]*processor.SyncedToken, len(ids))
+	for i, id := range ids {
+		tok, err := tms.VerifyTokenInfo(outputs[i], infos[i])
+		if err != nil {
+			return errors.WithMessagef(err, "shared token [%s] does not match the ledger", id)
+		}
+		if !wallet.Contains(tok.Owner.Raw) {
+			return errors.Errorf("shared token [%s] is not owned by wallet [%s]", id, wallet.ID())
+		}
+		synced[i] = &processor.SyncedToken{ID: id, Token: tok, Info: infos[i], EnrollmentID: wallet.EnrollmentID()}
+	}
+	if err := processor.AddSyncedTokens(context, tms.Network(), tms.Channel(), tms.Namespace(), synced...); err != nil {
+		return err
+	}
+	logger.Debugf("wallet [%s]: [%d] shared tokens to be stored", wallet.ID(), len(synced))
+	return nil
+}
+
+// syncWithDevices forwards the passed transaction to the devices paired with the local owner wallets involved in it
+func syncWithDevices(context view.Context, tx *Transaction) error {
+	wm := tx.TokenService().WalletManager()
+	shares := map[string]*WalletShare{}
+	var wallets []*token.OwnerWallet
+	addWallet := func(id view.Identity) *token.OwnerWallet {
+		w := wm.OwnerWalletByIdentity(id)
+		if w == nil {
+			return nil
+		}
+		if _, ok := shares[w.ID()]; !ok {
+			shares[w.ID()] = &WalletShare{EnrollmentID: w.EnrollmentID()}
+			wallets = append(wallets, w)
+		}
+		return w
+	}
+
+	for _, transfer := range tx.TokenRequest.Transfers() {
+		for _, sender := range transfer.Senders {
+			addWallet(sender)
+		}
+	}
+	outputs, err := tx.Outputs()
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting outputs of [%s]", tx.ID())
+	}
+	for i := 0; i < outputs.Count(); i++ {
+		owner := outputs.At(i).Owner
+		if owner.IsNone() {
+			continue
+		}
+		w := addWallet(owner)
+		if w == nil {
+			continue
+		}
+		auditInfo, err := w.GetAuditInfo(owner)
+		if err != nil {
+			return errors.WithMessagef(err, "failed getting audit info of [%s]", owner)
+		}
+		shares[w.ID()].Identities = append(shares[w.ID()].Identities, &api2.RecipientIdentity{Identity: owner, AuditInfo: auditInfo})
+	}
+
+	for _, w := range wallets {
+		paired, err := GetPairedDevices(context, w.EnrollmentID())
+		if err != nil {
+			return err
+		}
+		if len(paired) == 0 {
+			continue
+		}
+		var devices []view.Identity
+		for _, p := range paired {
+			devices = append(devices, p.Device)
+		}
+		if _, err := context.RunView(NewSyncTransactionView(tx, shares[w.ID()], devices...)); err != nil {
+			return errors.WithMessagef(err, "failed syncing transaction [%s] of wallet [%s]", tx.ID(), w.ID())
+		}
+	}
+	return nil
+}
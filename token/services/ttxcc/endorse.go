@@ -8,6 +8,8 @@ package ttxcc
 import (
 	"encoding/base64"
 	"encoding/json"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -18,8 +20,14 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tracing"
 )
 
+// ErrEndorsementMismatch signals that the endorsers of a token request returned different results,
+// the endorsement can be retried
+var ErrEndorsementMismatch = errors2.New(errors2.Conflict, "endorsers returned different results")
+
 type signatureRequest struct {
 	Request []byte
 	TxID    []byte
@@ -38,45 +46,58 @@ func NewCollectEndorsementsView(tx *Transaction) *collectEndorsementsView {
 	return &collectEndorsementsView{tx: tx}
 }
 
-func (c *collectEndorsementsView) Call(context view.Context) (interface{}, error) {
+// If it fails, the span of the transaction, see tracing.Tracer, ends here.
+func (c *collectEndorsementsView) Call(context view.Context) (res interface{}, err error) {
+	defer func() {
+		if err != nil {
+			c.tx.endTrace(err)
+		}
+	}()
+
 	// Store transient
-	err := c.tx.storeTransient()
+	err = c.tx.storeTransient()
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed storing transient")
 	}
 
-	// 1. First collect signatures on the token request
-	var distributionList []view.Identity
-
-	parties, err := c.requestSignaturesOnIssues(context)
+	// 1. First collect the signatures of the issuers on the token request
+	// 2. Collect the signatures of the senders and the auditors, in parallel
+	distributionList, err := c.collectSignatures(context)
 	if err != nil {
 		return nil, err
 	}
-	distributionList = append(distributionList, parties...)
+	for _, transfer := range c.tx.TokenRequest.Transfers() {
+		distributionList = append(distributionList, transfer.Senders...)
+		distributionList = append(distributionList, transfer.Receivers...)
+	}
 
-	parties, err = c.requestSignaturesOnTransfers(context)
+	// 2b. Collect the signatures of the issuers co-signing the redemptions, if any
+	parties, err := c.requestSignaturesOnRedemptions(context)
 	if err != nil {
 		return nil, err
 	}
 	distributionList = append(distributionList, parties...)
+	auditors, err := c.tx.auditors()
+	if err != nil {
+		return nil, err
+	}
+	distributionList = append(distributionList, auditors...)
 
-	// 2. Audit
-	if !c.tx.opts.auditor.IsNone() {
-		_, err := context.RunView(newAuditingViewInitiator(c.tx))
-		if err != nil {
-			return nil, errors.WithMessagef(err, "failed requesting auditing from [%s]", c.tx.opts.auditor.String())
+	// 2c. Notarize the signed token request, if requested
+	if c.tx.opts.timestampAuthority != nil {
+		if _, err := context.RunView(NewNotarizeView(c.tx, c.tx.opts.timestampAuthority)); err != nil {
+			return nil, err
 		}
-		distributionList = append(distributionList, c.tx.opts.auditor)
 	}
 
 	// 3. Endorse and return the Fabric transaction envelope
-	env, err := c.callChaincode(context)
+	env, err := c.endorseTransaction(context)
 	if err != nil {
 		return nil, err
 	}
 
 	// Distribute Env to all parties
-	if err := c.distributeEnv(context, env, distributionList); err != nil {
+	if err := c.distribute(context, env, distributionList); err != nil {
 		return nil, err
 	}
 
@@ -84,6 +105,39 @@ func (c *collectEndorsementsView) Call(context view.Context) (interface{}, error
 	return nil, nil
 }
 
+// collectSignatures collects the signatures of the issuers, the senders and the auditors on the token request,
+// and returns the parties that signed
+func (c *collectEndorsementsView) collectSignatures(context view.Context) (distributionList []view.Identity, err error) {
+	_, span := c.tx.startSpan("ttxcc.signatures")
+	defer func() { tracing.EndWithError(span, err) }()
+
+	distributionList, err = c.requestSignaturesOnIssues(context)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := context.RunView(NewSigningRoundView(c.tx)); err != nil {
+		return nil, err
+	}
+	return distributionList, nil
+}
+
+// endorseTransaction has the token chaincode endorse the transaction and returns its envelope
+func (c *collectEndorsementsView) endorseTransaction(context view.Context) (env *fabric.Envelope, err error) {
+	_, span := c.tx.startSpan("ttxcc.endorsement")
+	defer func() { tracing.EndWithError(span, err) }()
+
+	return c.callChaincode(context)
+}
+
+// distribute sends the envelope of the transaction to the passed parties
+func (c *collectEndorsementsView) distribute(context view.Context, env *fabric.Envelope, distributionList []view.Identity) (err error) {
+	_, span := c.tx.startSpan("ttxcc.distribution")
+	span.SetAttribute("parties", len(distributionList))
+	defer func() { tracing.EndWithError(span, err) }()
+
+	return c.distributeEnv(context, env, distributionList)
+}
+
 func (c *collectEndorsementsView) requestSignaturesOnIssues(context view.Context) ([]view.Identity, error) {
 	requestRaw, err := c.requestBytes()
 	if err != nil {
@@ -91,192 +145,180 @@ func (c *collectEndorsementsView) requestSignaturesOnIssues(context view.Context
 	}
 
 	var distributionList []view.Identity
+	// approvals caches the signatures of the approvers, an approver signs once for all its issues
+	approvals := map[string][]byte{}
 	for _, issue := range c.tx.TokenRequest.Issues() {
 		distributionList = append(distributionList, issue.Issuer)
 		distributionList = append(distributionList, issue.Receivers...)
 
-		// contact issuer and ask for the signature unless it is me
-		party := issue.Issuer
-		logger.Debugf("collecting signature on request (issue) from [%s]", party.UniqueID())
-		if w := token.GetManagementService(context, token.WithChannel(c.tx.Channel())).WalletManager().IssuerWalletByIdentity(party); w != nil {
-			// Sign
-			signer, err := w.GetSigner(party)
-			if err != nil {
-				return nil, err
-			}
-			logger.Debugf("signing [%s][%s]", hash.Hashable(requestRaw).String(), c.tx.ID())
-			logger.Debugf("signing tx-id [%s,nonce=%s]", c.tx.ID(), base64.StdEncoding.EncodeToString(c.tx.Id.Nonce))
-			sigma, err := signer.Sign(append(requestRaw, []byte(c.tx.ID())...))
-			if err != nil {
-				return nil, err
+		if err := c.requestSignatureOnIssue(context, requestRaw, issue.Issuer); err != nil {
+			return nil, err
+		}
+
+		// the approver, if any, co-signs right after the issuer
+		if !issue.Approver.IsNone() {
+			sigma, ok := approvals[issue.Approver.UniqueID()]
+			if !ok {
+				logger.Debugf("collecting approval on request (issue) from [%s]", issue.Approver.UniqueID())
+				boxed, err := context.RunView(newRequestIssueApprovalView(c.tx, issue.Approver))
+				if err != nil {
+					return nil, errors.WithMessagef(err, "failed requesting issue approval from [%s]", issue.Approver)
+				}
+				sigma = boxed.([]byte)
+				approvals[issue.Approver.UniqueID()] = sigma
 			}
 			c.tx.TokenRequest.AppendSignature(sigma)
+		}
+	}
+
+	return distributionList, nil
+}
 
+// requestSignaturesOnRedemptions collects the signatures of the issuers declared by the burn receipts,
+// in the order of the receipts. The validator checks them after those of the senders.
+func (c *collectEndorsementsView) requestSignaturesOnRedemptions(context view.Context) ([]view.Identity, error) {
+	var issuers []view.Identity
+	// approvals caches the signatures of the issuers, an issuer signs once for all its redemptions
+	approvals := map[string][]byte{}
+	for _, receipt := range c.tx.BurnReceipts() {
+		if receipt.Issuer.IsNone() {
 			continue
 		}
-
-		session, err := context.GetSession(context.Initiator(), party)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed getting session")
+		sigma, ok := approvals[receipt.Issuer.UniqueID()]
+		if !ok {
+			var err error
+			sigma, err = c.requestSignatureOnRedemption(context, receipt.Issuer)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "failed requesting redeem approval from [%s]", receipt.Issuer)
+			}
+			approvals[receipt.Issuer.UniqueID()] = sigma
+			issuers = append(issuers, receipt.Issuer)
 		}
-		// Wait to receive a content back
-		ch := session.Receive()
+		c.tx.TokenRequest.AppendSignature(sigma)
+	}
+	return issuers, nil
+}
 
-		signatureRequest := &signatureRequest{
-			Request: requestRaw,
-			TxID:    []byte(c.tx.ID()),
-			Signer:  party,
-		}
-		signatureRequestRaw, err := json.Marshal(signatureRequest)
+// requestSignatureOnRedemption returns the signature of the passed issuer on the token request, signing locally if the issuer is me
+func (c *collectEndorsementsView) requestSignatureOnRedemption(context view.Context, issuer view.Identity) ([]byte, error) {
+	if w := token.GetManagementService(context, token.WithChannel(c.tx.Channel())).WalletManager().IssuerWalletByIdentity(issuer); w != nil {
+		signer, err := w.GetSigner(issuer)
 		if err != nil {
 			return nil, err
 		}
-		err = session.Send(signatureRequestRaw)
+		requestRaw, err := c.requestBytes()
 		if err != nil {
-			return nil, errors.Wrap(err, "failed sending transaction content")
-		}
-
-		var msg *view.Message
-		select {
-		case msg = <-ch:
-			logger.Debugf("collect signatures on issue: reply received from [%s]", party)
-		case <-time.After(60 * time.Second):
-			return nil, errors.Errorf("Timeout from party %s", party)
-		}
-		if msg.Status == view.ERROR {
-			return nil, errors.New(string(msg.Payload))
+			return nil, err
 		}
+		return signer.Sign(append(requestRaw, []byte(c.tx.ID())...))
+	}
+	logger.Debugf("collecting approval on request (redeem) from [%s]", issuer.UniqueID())
+	boxed, err := context.RunView(newRequestRedeemApprovalView(c.tx, issuer))
+	if err != nil {
+		return nil, err
+	}
+	return boxed.([]byte), nil
+}
 
-		sigma := msg.Payload
-
-		verifier, err := c.tx.TokenService().SigService().GetVerifier(party)
+func (c *collectEndorsementsView) requestSignatureOnIssue(context view.Context, requestRaw []byte, party view.Identity) error {
+	// contact issuer and ask for the signature unless it is me
+	logger.Debugf("collecting signature on request (issue) from [%s]", party.UniqueID())
+	if w := token.GetManagementService(context, token.WithChannel(c.tx.Channel())).WalletManager().IssuerWalletByIdentity(party); w != nil {
+		// Sign
+		signer, err := w.GetSigner(party)
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed getting verifier for [%s]", party)
+			return err
 		}
-		err = verifier.Verify(signatureRequest.MessageToSign(), sigma)
+		logger.Debugf("signing [%s][%s]", hash.Hashable(requestRaw).String(), c.tx.ID())
+		logger.Debugf("signing tx-id [%s,nonce=%s]", c.tx.ID(), base64.StdEncoding.EncodeToString(c.tx.Id.Nonce))
+		sigma, err := signer.Sign(append(requestRaw, []byte(c.tx.ID())...))
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed verifying signature from [%s]", party)
+			return err
 		}
-
 		c.tx.TokenRequest.AppendSignature(sigma)
+		return nil
 	}
 
-	return distributionList, nil
-}
-
-func (c *collectEndorsementsView) requestSignaturesOnTransfers(context view.Context) ([]view.Identity, error) {
-	requestRaw, err := c.requestBytes()
+	session, err := context.GetSession(context.Initiator(), party)
 	if err != nil {
-		return nil, err
+		return errors.Wrap(err, "failed getting session")
 	}
+	// Wait to receive a content back
+	ch := session.Receive()
 
-	transfers := c.tx.TokenRequest.Transfers()
-	logger.Debugf("collecting signature on [%d] request transfer", len(transfers))
-
-	var distributionList []view.Identity
-	for i, transfer := range transfers {
-		distributionList = append(distributionList, transfer.Senders...)
-		distributionList = append(distributionList, transfer.Receivers...)
-
-		logger.Debugf("collecting signature on [%d]-th request transfer, signers [%d]", i, len(transfer.Senders))
-
-		// contact transfer and ask for the signature unless it is me
-		for _, party := range transfer.Senders {
-			signatureRequest := &signatureRequest{
-				Request: requestRaw,
-				TxID:    []byte(c.tx.ID()),
-				Signer:  party,
-			}
-
-			logger.Debugf("collecting signature on request (transfer) from [%s]", party.UniqueID())
-
-			if w := token.GetManagementService(context, token.WithChannel(c.tx.Channel())).WalletManager().OwnerWalletByIdentity(party); w != nil {
-				logger.Debugf("collecting signature on request (transfer) from [%s], it is me!", party.UniqueID())
-				// Sign
-				si, err := w.GetSigner(party)
-				if err != nil {
-					return nil, err
-				}
-				logger.Debugf("signing tx-id [%s,nonce=%s]", c.tx.ID(), base64.StdEncoding.EncodeToString(c.tx.Id.Nonce))
-				sigma, err := si.Sign(signatureRequest.MessageToSign())
-				if err != nil {
-					return nil, err
-				}
-				logger.Debugf("signature verified (me) [%s,%s,%s]",
-					hash.Hashable(signatureRequest.MessageToSign()).String(),
-					hash.Hashable(sigma).String(),
-					party.UniqueID(),
-				)
-
-				c.tx.TokenRequest.AppendSignature(sigma)
-				continue
-			}
-			logger.Debugf("collecting signature on request (transfer) from [%s], it is not me, connect to party!", party.UniqueID())
-
-			session, err := context.GetSession(context.Initiator(), party)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed getting session")
-			}
-			// Wait to receive a content back
-			ch := session.Receive()
-
-			signatureRequestRaw, err := json.Marshal(signatureRequest)
-			if err != nil {
-				return nil, err
-			}
-			err = session.Send(signatureRequestRaw)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed sending transaction content")
-			}
-
-			var msg *view.Message
-			select {
-			case msg = <-ch:
-				logger.Debugf("collect signatures on transfer: reply received from [%s]", party)
-			case <-time.After(60 * time.Second):
-				return nil, errors.Errorf("Timeout from party %s", party)
-			}
-			if msg.Status == view.ERROR {
-				return nil, errors.New(string(msg.Payload))
-			}
-
-			sigma := msg.Payload
+	signatureRequest := &signatureRequest{
+		Request: requestRaw,
+		TxID:    []byte(c.tx.ID()),
+		Signer:  party,
+	}
+	signatureRequestRaw, err := json.Marshal(signatureRequest)
+	if err != nil {
+		return err
+	}
+	err = session.Send(signatureRequestRaw)
+	if err != nil {
+		return errors.Wrap(err, "failed sending transaction content")
+	}
 
-			verifier, err := c.tx.TokenService().SigService().GetVerifier(party)
-			if err != nil {
-				return nil, errors.Wrapf(err, "failed getting verifier for [%s]", party)
-			}
-			err = verifier.Verify(signatureRequest.MessageToSign(), sigma)
-			if err != nil {
-				return nil, errors.Wrapf(err, "failed verifying signature from [%s]", party)
-			}
+	var msg *view.Message
+	select {
+	case msg = <-ch:
+		logger.Debugf("collect signatures on issue: reply received from [%s]", party)
+	case <-time.After(60 * time.Second):
+		return errors.Errorf("Timeout from party %s", party)
+	}
+	if msg.Status == view.ERROR {
+		return errors.New(string(msg.Payload))
+	}
 
-			logger.Debugf("signature verified [%s,%s,%s]",
-				hash.Hashable(signatureRequest.MessageToSign()).String(),
-				hash.Hashable(sigma).String(),
-				party.UniqueID(),
-			)
+	sigma := msg.Payload
 
-			c.tx.TokenRequest.AppendSignature(sigma)
-		}
+	verifier, err := c.tx.TokenService().SigService().GetVerifier(party)
+	if err != nil {
+		return errors.Wrapf(err, "failed getting verifier for [%s]", party)
+	}
+	err = verifier.Verify(signatureRequest.MessageToSign(), sigma)
+	if err != nil {
+		return errors.Wrapf(err, "failed verifying signature from [%s]", party)
 	}
 
-	return distributionList, nil
+	c.tx.TokenRequest.AppendSignature(sigma)
+	return nil
 }
 
 func (c *collectEndorsementsView) callChaincode(context view.Context) (*fabric.Envelope, error) {
-	requestRaw, err := c.tx.TokenRequest.RequestToBytes()
+	var requestRaw []byte
+	var err error
+	if c.tx.opts.compression {
+		requestRaw, err = c.tx.TokenRequest.RequestToCompressedBytes()
+	} else {
+		requestRaw, err = c.tx.TokenRequest.RequestToBytes()
+	}
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed marshalling request")
 	}
 
 	logger.Debugf("call chaincode for endorsement [nonce=%s]", base64.StdEncoding.EncodeToString(c.tx.Id.Nonce))
 
-	env, err := fabric.GetChannel(context, c.tx.Network(), c.tx.Channel()).Chaincode(c.tx.Namespace()).Endorse(
-		"invoke", requestRaw,
-	).WithInvokerIdentity(c.tx.Signer).WithTxID(c.tx.Payload.Id).Call()
+	// pin the version of the public parameters, if versioned, so that the endorsement does not conflict
+	// with the updates of the public parameters, but only with those superseding this version
+	version, err := c.tx.TokenService().Vault().NewQueryEngine().PublicParamsVersion()
 	if err != nil {
-		return nil, err
+		return nil, errors.WithMessagef(err, "failed getting public parameters version")
+	}
+
+	chaincode := fabric.GetChannel(context, c.tx.Network(), c.tx.Channel()).Chaincode(c.tx.Namespace())
+	var env *fabric.Envelope
+	for attempt := 0; ; attempt++ {
+		env, err = c.endorse(chaincode, requestRaw, version)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrEndorsementMismatch) || attempt >= c.tx.opts.endorsementRetries {
+			return nil, errors.WithMessagef(err, "failed endorsing token request [%s]", c.tx.ID())
+		}
+		logger.Warnf("endorsers disagree on token request [%s], retry [%d] of [%d]: [%s]", c.tx.ID(), attempt+1, c.tx.opts.endorsementRetries, err)
+		time.Sleep(c.tx.opts.retryDelay)
 	}
 
 	err = c.tx.setEnvelope(env)
@@ -287,13 +329,48 @@ func (c *collectEndorsementsView) callChaincode(context view.Context) (*fabric.E
 	return env, nil
 }
 
+// endorse discovers the peers satisfying the endorsement policy of the token chaincode
+// and collects, in parallel, their endorsements on the token request
+func (c *collectEndorsementsView) endorse(chaincode *fabric.Chaincode, requestRaw []byte, version uint64) (*fabric.Envelope, error) {
+	endorsers, err := chaincode.Discover().Call()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed discovering endorsers of [%s]", c.tx.Namespace())
+	}
+	if len(endorsers) == 0 {
+		return nil, errors.Errorf("no endorsers found for [%s]", c.tx.Namespace())
+	}
+	logger.Debugf("endorsers of [%s] for [%s]: [%v]", c.tx.Namespace(), c.tx.ID(), endorsers)
+
+	args := []interface{}{requestRaw}
+	if version != 0 {
+		args = append(args, strconv.FormatUint(version, 10))
+	}
+	env, err := chaincode.Endorse(
+		"invoke", args...,
+	).WithInvokerIdentity(c.tx.Signer).WithTxID(c.tx.Payload.Id).WithEndorsers(endorsers...).Call()
+	if err != nil {
+		return nil, endorsementError(err)
+	}
+	return env, nil
+}
+
+// endorsementError returns the passed endorsement error as an ErrEndorsementMismatch, if it is due to endorsers that
+// returned different results. The fabric client reports the mismatch only in the message of an untyped error,
+// this is the only place where the message is inspected.
+func endorsementError(err error) error {
+	if strings.Contains(err.Error(), "ProposalResponsePayloads do not match") {
+		return errors.Wrap(ErrEndorsementMismatch, err.Error())
+	}
+	return err
+}
+
 func (c *collectEndorsementsView) distributeEnv(context view.Context, env *fabric.Envelope, distributionList []view.Identity) error {
 	if env == nil {
 		return errors.New("fabric transaction envelope is empty")
 	}
 
 	// double check that the transaction is valid
-	if err := c.tx.Verify(); err != nil {
+	if err := c.tx.Verify(context.Context()); err != nil {
 		return errors.Wrap(err, "failed verifying transaction content before distributing it")
 	}
 
@@ -366,6 +443,11 @@ func (c *collectEndorsementsView) distributeEnv(context view.Context, env *fabri
 				return errors.WithMessagef(err, "failed storing tx env [%s]", c.tx.ID())
 			}
 
+			// Forward to the devices sharing the wallets involved
+			if err := syncWithDevices(context, c.tx); err != nil {
+				return errors.WithMessagef(err, "failed syncing tx [%s] with paired devices", c.tx.ID())
+			}
+
 			continue
 		} else {
 			logger.Debugf("This is not me [%s], ask endorse", entry.ID.UniqueID())
@@ -392,9 +474,22 @@ func (c *collectEndorsementsView) distributeEnv(context view.Context, env *fabri
 			return errors.Errorf("Timeout from party %s", entry.ID)
 		}
 		if msg.Status == view.ERROR {
-			return errors.New(string(msg.Payload))
+			// a receiver rejecting the transaction sends back the reason, see NewAcceptExpectedView
+			return parseRejection(msg.Payload)
+		}
+		receipt, err := parseAck(msg.Payload)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid ack from [%s]", entry.ID)
+		}
+		if receipt != nil {
+			if err := receipt.Verify(c.tx); err != nil {
+				return errors.WithMessagef(err, "invalid receipt from [%s]", entry.ID)
+			}
+			if err := storeReceipt(context, receipt); err != nil {
+				return errors.WithMessagef(err, "failed storing receipt from [%s]", entry.ID)
+			}
+			logger.Debugf("receipt for [%s] received from [%s]", c.tx.ID(), entry.ID)
 		}
-		// TODO: Check ack
 
 		logger.Debugf("collectEndorsementsView: collected signature from %s", entry.ID)
 	}
@@ -437,7 +532,12 @@ type endorseView struct {
 	tx *Transaction
 }
 
-func (s *endorseView) Call(context view.Context) (interface{}, error) {
+// Call signs the transaction, as a sender, and accepts the envelope distributed by its creator.
+// Its span is child of the span of the transaction propagated by the creator.
+func (s *endorseView) Call(context view.Context) (res interface{}, err error) {
+	_, span := s.tx.startSpan("ttxcc.sign")
+	defer func() { tracing.EndWithError(span, err) }()
+
 	// Process signature requests
 	requestsToBeSigned, err := s.requestsToBeSigned()
 	if err != nil {
@@ -466,7 +566,7 @@ func (s *endorseView) Call(context view.Context) (interface{}, error) {
 			return nil, errors.Wrap(err, "failed unmarshalling signature request")
 		}
 		if !fabric.GetFabricNetworkService(context, s.tx.Network()).LocalMembership().IsMe(signatureRequest.Signer) {
-			return nil, errors.Errorf("identity [%s] is not me", signatureRequest.Signer.UniqueID())
+			return nil, errors2.Errorf(errors2.Unauthorized, "identity [%s] is not me", signatureRequest.Signer.UniqueID())
 		}
 		signer, err := s.tx.TokenService().SigService().GetSigner(signatureRequest.Signer)
 		if err != nil {
@@ -519,6 +619,11 @@ func (s *endorseView) Call(context view.Context) (interface{}, error) {
 		return nil, errors.WithMessagef(err, "failed storing tx env [%s]", tx.ID())
 	}
 
+	// Forward to the devices sharing the wallets involved
+	if err := syncWithDevices(context, tx); err != nil {
+		return nil, errors.WithMessagef(err, "failed syncing tx [%s] with paired devices", tx.ID())
+	}
+
 	// Send the proposal response back
 	logger.Debugf("Send the ack")
 	err = session.Send([]byte("ack"))
@@ -0,0 +1,29 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+)
+
+func TestEndorsementError(t *testing.T) {
+	err := endorsementError(errors.New("ProposalResponsePayloads do not match [YQ==]!=[Yg==]"))
+	assert.True(t, errors.Is(err, ErrEndorsementMismatch))
+	assert.True(t, errors2.HasCode(err, errors2.Conflict))
+	assert.Contains(t, err.Error(), "[YQ==]!=[Yg==]")
+
+	wrapped := errors.WithMessage(err, "failed endorsing token request [tx1]")
+	assert.True(t, errors.Is(wrapped, ErrEndorsementMismatch))
+
+	err = endorsementError(errors.New("chaincode returned 500"))
+	assert.False(t, errors.Is(err, ErrEndorsementMismatch))
+	assert.EqualError(t, err, "chaincode returned 500")
+}
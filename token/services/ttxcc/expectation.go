@@ -0,0 +1,132 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// Expectation declares what a receiver expects from an incoming transaction, see NewAcceptExpectedView
+type Expectation struct {
+	// Type is the type of the expected tokens
+	Type string
+	// Amount is the expected quantity of tokens of Type received, in total, by the wallets of this node
+	Amount uint64
+	// SenderEnrollmentID, if not empty, is the enrollment ID of the owner of each input,
+	// as bound to the owner identity by the audit info of the input, see token.Request.Inputs
+	SenderEnrollmentID string
+}
+
+// MismatchReason classifies why a transaction does not match an expectation
+type MismatchReason string
+
+const (
+	// TypeMismatch signals that no token of the expected type is received
+	TypeMismatch MismatchReason = "type"
+	// AmountMismatch signals that the quantity received differs from the expected amount
+	AmountMismatch MismatchReason = "amount"
+	// SenderMismatch signals that an input is not owned by the expected sender
+	SenderMismatch MismatchReason = "sender"
+)
+
+// Mismatch is the reason a receiver rejects a transaction not matching its expectation.
+// It is sent back to the sender, that gets it as error of the collection of the endorsements, see AsMismatch.
+type Mismatch struct {
+	TxID     string
+	Reason   MismatchReason
+	Expected string
+	Actual   string
+}
+
+func (m *Mismatch) Error() string {
+	return fmt.Sprintf("transaction [%s] does not match the expected %s: expected [%s], got [%s]", m.TxID, m.Reason, m.Expected, m.Actual)
+}
+
+// AsMismatch returns the mismatch the passed error is caused by, if any
+func AsMismatch(err error) (*Mismatch, bool) {
+	m, ok := errors.Cause(err).(*Mismatch)
+	return m, ok
+}
+
+// Match checks the passed transaction against this expectation, it returns a *Mismatch if it does not match
+func (e *Expectation) Match(tx *Transaction) error {
+	outputs, err := tx.Outputs()
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting outputs of [%s]", tx.ID())
+	}
+	wm := tx.TokenService().WalletManager()
+	received := outputs.Filter(func(o *token.Output) bool {
+		return !o.Owner.IsNone() && wm.OwnerWalletByIdentity(o.Owner) != nil
+	})
+	if received.ByType(e.Type).Count() == 0 {
+		return &Mismatch{TxID: tx.ID(), Reason: TypeMismatch, Expected: e.Type, Actual: strings.Join(received.TokenTypes(), ",")}
+	}
+	sum := received.ByType(e.Type).Sum()
+	if sum.Cmp(token2.NewQuantityFromUInt64(e.Amount)) != 0 {
+		return &Mismatch{TxID: tx.ID(), Reason: AmountMismatch, Expected: fmt.Sprintf("%d", e.Amount), Actual: sum.Decimal()}
+	}
+
+	if len(e.SenderEnrollmentID) == 0 {
+		return nil
+	}
+	inputs, err := tx.Inputs()
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting inputs of [%s]", tx.ID())
+	}
+	eIDs := inputs.EnrollmentIDs()
+	if len(eIDs) != 1 || eIDs[0] != e.SenderEnrollmentID {
+		return &Mismatch{TxID: tx.ID(), Reason: SenderMismatch, Expected: e.SenderEnrollmentID, Actual: strings.Join(eIDs, ",")}
+	}
+	return nil
+}
+
+type acceptExpectedView struct {
+	tx          *Transaction
+	expectation *Expectation
+}
+
+// NewAcceptExpectedView returns a view that accepts the passed transaction, as NewAcceptView does, only if it
+// matches the passed expectation. Otherwise, the mismatch is sent back to the sender and returned as error.
+func NewAcceptExpectedView(tx *Transaction, expectation *Expectation) *acceptExpectedView {
+	return &acceptExpectedView{tx: tx, expectation: expectation}
+}
+
+func (s *acceptExpectedView) Call(context view.Context) (interface{}, error) {
+	err := s.expectation.Match(s.tx)
+	if err == nil {
+		return NewAcceptView(s.tx).Call(context)
+	}
+	m, ok := err.(*Mismatch)
+	if !ok {
+		return nil, err
+	}
+	logger.Debugf("reject transaction [%s]: %s", s.tx.ID(), m)
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed marshalling mismatch of [%s]", s.tx.ID())
+	}
+	if err := context.Session().SendError(raw); err != nil {
+		return nil, errors.Wrapf(err, "failed sending mismatch of [%s]", s.tx.ID())
+	}
+	return nil, m
+}
+
+// parseRejection returns the error carried by the passed error payload, a *Mismatch if the payload encodes one
+func parseRejection(payload []byte) error {
+	m := &Mismatch{}
+	if err := json.Unmarshal(payload, m); err == nil && len(m.Reason) != 0 {
+		return m
+	}
+	return errors.New(string(payload))
+}
@@ -8,6 +8,8 @@ package ttxcc
 import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tracing"
 )
 
 type finalityView struct {
@@ -15,7 +17,10 @@ type finalityView struct {
 	endpoints []view.Identity
 }
 
-func (f *finalityView) Call(context view.Context) (interface{}, error) {
+func (f *finalityView) Call(context view.Context) (res interface{}, err error) {
+	_, span := f.tx.startSpan("ttxcc.finality")
+	defer func() { tracing.EndWithError(span, err) }()
+
 	fs := fabric.GetChannel(context, f.tx.Network(), f.tx.Channel()).Finality()
 	if len(f.endpoints) != 0 {
 		return nil, fs.IsFinalForParties(f.tx.ID(), f.endpoints...)
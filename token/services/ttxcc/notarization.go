@@ -0,0 +1,89 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"time"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/timestamp"
+)
+
+const notarizationPrefix = "token-sdk.ttxcc.notarization"
+
+// Notarization is the evidence, issued by a timestamp authority, of the time a token request has been initiated.
+// The token attests the hash of the token request, see Receipt.
+type Notarization struct {
+	TxID        string
+	RequestHash []byte
+	Token       []byte
+	Time        time.Time
+}
+
+type notarizeView struct {
+	tx        *Transaction
+	authority timestamp.Authority
+}
+
+// NewNotarizeView returns a view that obtains from the passed authority a timestamp over the hash of the token
+// request of the passed transaction, and stores it. It returns the notarization.
+func NewNotarizeView(tx *Transaction, authority timestamp.Authority) *notarizeView {
+	return &notarizeView{tx: tx, authority: authority}
+}
+
+func (n *notarizeView) Call(context view.Context) (interface{}, error) {
+	h, err := requestHash(n.tx)
+	if err != nil {
+		return nil, err
+	}
+	token, t, err := n.authority.Timestamp(h)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed notarizing token request [%s]", n.tx.ID())
+	}
+	notarization := &Notarization{TxID: n.tx.ID(), RequestHash: h, Token: token, Time: t}
+	if err := storeNotarization(context, notarization); err != nil {
+		return nil, errors.WithMessagef(err, "failed storing notarization of [%s]", n.tx.ID())
+	}
+	logger.Debugf("token request [%s] notarized at [%s]", n.tx.ID(), t)
+	return notarization, nil
+}
+
+func notarizationKey(txID string) (string, error) {
+	k, err := kvs.CreateCompositeKey(notarizationPrefix, []string{txID})
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed creating notarization key for [%s]", txID)
+	}
+	return k, nil
+}
+
+func storeNotarization(sp view2.ServiceProvider, notarization *Notarization) error {
+	k, err := notarizationKey(notarization.TxID)
+	if err != nil {
+		return err
+	}
+	return kvs.GetService(sp).Put(k, notarization)
+}
+
+// GetNotarization returns the notarization of the passed transaction, nil if the transaction has not been notarized
+func GetNotarization(sp view2.ServiceProvider, txID string) (*Notarization, error) {
+	k, err := notarizationKey(txID)
+	if err != nil {
+		return nil, err
+	}
+	kv := kvs.GetService(sp)
+	if !kv.Exists(k) {
+		return nil, nil
+	}
+	notarization := &Notarization{}
+	if err := kv.Get(k, notarization); err != nil {
+		return nil, errors.WithMessagef(err, "failed reading notarization of [%s]", txID)
+	}
+	return notarization, nil
+}
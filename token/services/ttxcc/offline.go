@@ -0,0 +1,266 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+const signedBundlePrefix = "token-sdk.ttxcc.offline"
+
+// SigningBundle carries what a sender signs on an air-gapped machine: the token request marshalled to sign,
+// bound to the transaction id, and its summary in the clear. The online part of a transfer, the selection of the
+// tokens and the assembly of the request, produces it with Transaction.SigningBundle. The offline part reviews the
+// summary and signs it with Sign, the online part attaches the signatures with Transaction.AttachSignatures.
+type SigningBundle struct {
+	TxID string
+	// Request is the token request marshalled to sign, see token.Request.MarshallToSign
+	Request []byte
+	// Summary describes the token request to the offline signer
+	Summary *BundleSummary
+	// Signer is the identity expected to sign
+	Signer view.Identity
+	// Signatures is the number of signatures expected from Signer, one per input it owns
+	Signatures int
+}
+
+// BundleSummary describes, in the clear, the token request of a SigningBundle: the tokens it spends and the outputs
+// it creates. The offline signer cannot open the request without the token driver, it reviews the summary instead.
+// AttachSignatures accepts the signatures only if the summary reviewed is the one of the transaction.
+type BundleSummary struct {
+	Inputs  []*token2.Id
+	Outputs []*BundleOutput
+}
+
+// BundleOutput is an output of the token request of a SigningBundle
+type BundleOutput struct {
+	Owner        view.Identity
+	EnrollmentID string `json:",omitempty"`
+	Type         string
+	Quantity     string
+}
+
+func (s *BundleSummary) digest() ([]byte, error) {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed marshalling bundle summary")
+	}
+	return requestDigest(raw), nil
+}
+
+// Approver shows the summary of a bundle to the offline signer, it returns an error if the signer rejects the bundle
+type Approver func(summary *BundleSummary) error
+
+func (b *SigningBundle) Bytes() ([]byte, error) {
+	return json.Marshal(b)
+}
+
+func (b *SigningBundle) FromBytes(raw []byte) error {
+	return json.Unmarshal(raw, b)
+}
+
+// MessageToSign returns the message the signatures are over, the same a sender signs online
+func (b *SigningBundle) MessageToSign() []byte {
+	return append(append([]byte{}, b.Request...), []byte(b.TxID)...)
+}
+
+// Sign signs this bundle with the passed signer, once the passed approver accepts its summary.
+// It needs neither the network nor the wallets of the node.
+func (b *SigningBundle) Sign(signer token.Signer, approve Approver) (*SignedBundle, error) {
+	if b.Signatures <= 0 {
+		return nil, errors.Errorf("bundle for [%s] expects no signature", b.TxID)
+	}
+	if b.Summary == nil {
+		return nil, errors.Errorf("bundle for [%s] carries no summary", b.TxID)
+	}
+	if approve == nil {
+		return nil, errors.Errorf("bundle for [%s] must be reviewed before signing", b.TxID)
+	}
+	if err := approve(b.Summary); err != nil {
+		return nil, errors.WithMessagef(err, "bundle for [%s] rejected", b.TxID)
+	}
+	summaryHash, err := b.Summary.digest()
+	if err != nil {
+		return nil, err
+	}
+	msg := b.MessageToSign()
+	signed := &SignedBundle{
+		TxID:        b.TxID,
+		RequestHash: requestDigest(b.Request),
+		SummaryHash: summaryHash,
+		Signer:      b.Signer,
+		Signatures:  make([][]byte, b.Signatures),
+	}
+	for i := range signed.Signatures {
+		sigma, err := signer.Sign(msg)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed signing bundle for [%s]", b.TxID)
+		}
+		signed.Signatures[i] = sigma
+	}
+	return signed, nil
+}
+
+// SignedBundle carries the signatures produced offline on a SigningBundle
+type SignedBundle struct {
+	TxID string
+	// RequestHash is the SHA-256 digest of the signed request, it binds the signatures to the bundle
+	RequestHash []byte
+	// SummaryHash is the SHA-256 digest of the summary the signer approved
+	SummaryHash []byte
+	Signer      view.Identity
+	Signatures  [][]byte
+}
+
+func (b *SignedBundle) Bytes() ([]byte, error) {
+	return json.Marshal(b)
+}
+
+func (b *SignedBundle) FromBytes(raw []byte) error {
+	return json.Unmarshal(raw, b)
+}
+
+// SigningBundle returns the bundle the passed sender signs offline, see SigningBundle.
+// The token request must not change afterwards, the signatures would not attach.
+func (t *Transaction) SigningBundle(signer view.Identity) (*SigningBundle, error) {
+	n := t.signatureSlots(signer)
+	if n == 0 {
+		return nil, errors.Errorf("[%s] is not a sender of [%s]", signer, t.ID())
+	}
+	requestRaw, err := t.TokenRequest.MarshallToSign()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed marshalling request to sign")
+	}
+	summary, err := t.bundleSummary()
+	if err != nil {
+		return nil, err
+	}
+	return &SigningBundle{TxID: t.ID(), Request: requestRaw, Summary: summary, Signer: signer, Signatures: n}, nil
+}
+
+// bundleSummary returns the summary of the token request of this transaction, see BundleSummary
+func (t *Transaction) bundleSummary() (*BundleSummary, error) {
+	inputs, err := t.Inputs()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting inputs of [%s]", t.ID())
+	}
+	outputs, err := t.Outputs()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting outputs of [%s]", t.ID())
+	}
+	summary := &BundleSummary{Inputs: inputs.IDs()}
+	for i := 0; i < outputs.Count(); i++ {
+		o := outputs.At(i)
+		summary.Outputs = append(summary.Outputs, &BundleOutput{Owner: o.Owner, EnrollmentID: o.EnrollmentID, Type: o.Type, Quantity: o.Quantity})
+	}
+	return summary, nil
+}
+
+// AttachSignatures checks the signatures of the passed bundle, signed offline, against the token request and
+// the summary shown to the signer, and stores them: the signing round uses them instead of asking the sender,
+// see SigningRoundView, also after a restart of the node.
+func (t *Transaction) AttachSignatures(signed *SignedBundle) error {
+	if signed.TxID != t.ID() {
+		return errors.Errorf("signed bundle refers to [%s], expected [%s]", signed.TxID, t.ID())
+	}
+	requestRaw, err := t.TokenRequest.MarshallToSign()
+	if err != nil {
+		return errors.Wrapf(err, "failed marshalling request to sign")
+	}
+	if !bytes.Equal(requestDigest(requestRaw), signed.RequestHash) {
+		return errors.Errorf("signed bundle refers to a different token request for [%s]", t.ID())
+	}
+	summary, err := t.bundleSummary()
+	if err != nil {
+		return err
+	}
+	summaryHash, err := summary.digest()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(summaryHash, signed.SummaryHash) {
+		return errors.Errorf("signed bundle has been reviewed on a different summary of [%s]", t.ID())
+	}
+	if n := t.signatureSlots(signed.Signer); n != len(signed.Signatures) {
+		return errors.Errorf("signed bundle carries [%d] signatures of [%s], expected [%d]", len(signed.Signatures), signed.Signer, n)
+	}
+	verifier, err := t.TokenService().SigService().GetVerifier(signed.Signer)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting verifier for [%s]", signed.Signer)
+	}
+	msg := (&SigningBundle{TxID: t.ID(), Request: requestRaw}).MessageToSign()
+	for i, sigma := range signed.Signatures {
+		if err := verifier.Verify(msg, sigma); err != nil {
+			return errors.WithMessagef(err, "invalid signature [%d] of [%s]", i, signed.Signer)
+		}
+	}
+	if err := storeSignedBundle(t.sp, signed); err != nil {
+		return errors.WithMessagef(err, "failed storing signatures of [%s] for [%s]", signed.Signer, t.ID())
+	}
+	return nil
+}
+
+func signedBundleKey(txID string, signer view.Identity) (string, error) {
+	k, err := kvs.CreateCompositeKey(signedBundlePrefix, []string{txID, signer.UniqueID()})
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed creating signed bundle key for [%s]", txID)
+	}
+	return k, nil
+}
+
+func storeSignedBundle(sp view2.ServiceProvider, signed *SignedBundle) error {
+	k, err := signedBundleKey(signed.TxID, signed.Signer)
+	if err != nil {
+		return err
+	}
+	return kvs.GetService(sp).Put(k, signed)
+}
+
+// GetSignedBundle returns the bundle the passed sender signed offline for the passed transaction, attached with
+// Transaction.AttachSignatures, nil if none has been attached
+func GetSignedBundle(sp view2.ServiceProvider, txID string, signer view.Identity) (*SignedBundle, error) {
+	k, err := signedBundleKey(txID, signer)
+	if err != nil {
+		return nil, err
+	}
+	kv := kvs.GetService(sp)
+	if !kv.Exists(k) {
+		return nil, nil
+	}
+	signed := &SignedBundle{}
+	if err := kv.Get(k, signed); err != nil {
+		return nil, errors.WithMessagef(err, "failed reading signed bundle of [%s] for [%s]", signer, txID)
+	}
+	return signed, nil
+}
+
+// signatureSlots returns the number of signatures the passed sender provides on the token request
+func (t *Transaction) signatureSlots(signer view.Identity) int {
+	n := 0
+	for _, transfer := range t.TokenRequest.Transfers() {
+		for _, party := range transfer.Senders {
+			if party.Equal(signer) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func requestDigest(raw []byte) []byte {
+	h := sha256.Sum256(raw)
+	return h[:]
+}
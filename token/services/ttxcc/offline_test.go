@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// prefixSigner signs a message prepending its name
+type prefixSigner string
+
+func (s prefixSigner) Sign(message []byte) ([]byte, error) {
+	return append([]byte(s), message...), nil
+}
+
+func TestSignBundle(t *testing.T) {
+	summary := &BundleSummary{
+		Inputs:  []*token2.Id{{TxId: "tx0"}},
+		Outputs: []*BundleOutput{{Owner: view.Identity("bob"), Type: "USD", Quantity: "10"}},
+	}
+	bundle := &SigningBundle{TxID: "tx1", Request: []byte("request"), Summary: summary, Signer: view.Identity("alice"), Signatures: 2}
+
+	// the signer reviews the summary before signing
+	_, err := bundle.Sign(prefixSigner("alice"), nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "must be reviewed before signing")
+	_, err = bundle.Sign(prefixSigner("alice"), func(*BundleSummary) error {
+		return errors.New("unknown recipient")
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown recipient")
+
+	var reviewed *BundleSummary
+	signed, err := bundle.Sign(prefixSigner("alice"), func(s *BundleSummary) error {
+		reviewed = s
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, summary, reviewed)
+	sigma := append([]byte("alice"), bundle.MessageToSign()...)
+	assert.Equal(t, [][]byte{sigma, sigma}, signed.Signatures)
+	assert.Equal(t, requestDigest([]byte("request")), signed.RequestHash)
+	summaryHash, err := summary.digest()
+	assert.NoError(t, err)
+	assert.Equal(t, summaryHash, signed.SummaryHash)
+
+	// a bundle without summary is not signed
+	_, err = (&SigningBundle{TxID: "tx1", Signatures: 1}).Sign(prefixSigner("alice"), func(*BundleSummary) error { return nil })
+	assert.Error(t, err)
+}
+
+func TestStoreSignedBundle(t *testing.T) {
+	sp := registry.New()
+	assert.NoError(t, sp.RegisterService(&configProvider{}))
+	kvss, err := kvs.New("memory", "", sp)
+	assert.NoError(t, err)
+	assert.NoError(t, sp.RegisterService(kvss))
+
+	signed, err := GetSignedBundle(sp, "tx1", view.Identity("alice"))
+	assert.NoError(t, err)
+	assert.Nil(t, signed)
+
+	stored := &SignedBundle{TxID: "tx1", RequestHash: []byte("hash"), SummaryHash: []byte("summary"), Signer: view.Identity("alice"), Signatures: [][]byte{[]byte("sigma")}}
+	assert.NoError(t, storeSignedBundle(sp, stored))
+	signed, err = GetSignedBundle(sp, "tx1", view.Identity("alice"))
+	assert.NoError(t, err)
+	assert.Equal(t, stored, signed)
+
+	// the signatures are kept by transaction and by signer
+	signed, err = GetSignedBundle(sp, "tx1", view.Identity("bob"))
+	assert.NoError(t, err)
+	assert.Nil(t, signed)
+}
@@ -5,17 +5,61 @@ SPDX-License-Identifier: Apache-2.0
 */
 package ttxcc
 
-import "github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+import (
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/timestamp"
+)
+
+const (
+	DefaultEndorsementRetries = 3
+	DefaultOrderingRetries    = 3
+	DefaultRetryDelay         = time.Second
+	DefaultFinalityTimeout    = 30 * time.Second
+	DefaultSigningTimeout     = 60 * time.Second
+)
 
 type txOptions struct {
 	auditor   view.Identity
 	network   string
 	channel   string
 	namespace string
+
+	// endorsementRetries is the number of times the endorsement is retried when the endorsers disagree
+	endorsementRetries int
+	// orderingRetries is the number of times the transaction is resubmitted to ordering when it is not committed in time
+	orderingRetries int
+	retryDelay      time.Duration
+	finalityTimeout time.Duration
+	// signingTimeout bounds the signing round, see SigningRoundView
+	signingTimeout time.Duration
+	// compression enables the compression of the transaction in session messages and of the token request submitted
+	compression bool
+	// submitter, if set, signs and submits the Fabric transaction, see WithSubmitter
+	submitter view.Identity
+	// configuredSubmitter makes the submitter configured for the TMS, if any, sign and submit the Fabric transaction,
+	// see WithConfiguredSubmitter
+	configuredSubmitter bool
+	// timestampAuthority, if set, notarizes the token request, see WithTimestampAuthority
+	timestampAuthority timestamp.Authority
+	// memo, if set, is attached to the history records of the transaction, see WithMemo
+	memo string
+}
+
+func defaultTxOptions() *txOptions {
+	return &txOptions{
+		endorsementRetries: DefaultEndorsementRetries,
+		orderingRetries:    DefaultOrderingRetries,
+		retryDelay:         DefaultRetryDelay,
+		finalityTimeout:    DefaultFinalityTimeout,
+		signingTimeout:     DefaultSigningTimeout,
+	}
 }
 
 func compile(opts ...TxOption) (*txOptions, error) {
-	txOptions := &txOptions{}
+	txOptions := defaultTxOptions()
 	for _, opt := range opts {
 		if err := opt(txOptions); err != nil {
 			return nil, err
@@ -26,6 +70,8 @@ func compile(opts ...TxOption) (*txOptions, error) {
 
 type TxOption func(*txOptions) error
 
+// WithAuditor sets the identity the default auditor of the public parameters is reached at.
+// The auditors assigned to token types are reached at their identities in the public parameters.
 func WithAuditor(auditor view.Identity) TxOption {
 	return func(o *txOptions) error {
 		o.auditor = auditor
@@ -53,3 +99,93 @@ func WithNamespace(namespace string) TxOption {
 		return nil
 	}
 }
+
+// WithEndorsementRetries sets the number of times the endorsement of the token request is retried
+// when the endorsers return different results, as it happens when their ledgers are not in sync
+func WithEndorsementRetries(retries int) TxOption {
+	return func(o *txOptions) error {
+		o.endorsementRetries = retries
+		return nil
+	}
+}
+
+// WithOrderingRetries sets the number of times the transaction is resubmitted to ordering
+// when it is not committed within the finality timeout
+func WithOrderingRetries(retries int) TxOption {
+	return func(o *txOptions) error {
+		o.orderingRetries = retries
+		return nil
+	}
+}
+
+// WithRetryDelay sets the time to wait before retrying the endorsement or the submission to ordering
+func WithRetryDelay(delay time.Duration) TxOption {
+	return func(o *txOptions) error {
+		o.retryDelay = delay
+		return nil
+	}
+}
+
+// WithFinalityTimeout sets how long to wait for the transaction to be committed before resubmitting it to ordering
+func WithFinalityTimeout(timeout time.Duration) TxOption {
+	return func(o *txOptions) error {
+		o.finalityTimeout = timeout
+		return nil
+	}
+}
+
+// WithSigningTimeout sets how long to wait for the senders and the auditor to sign the token request
+func WithSigningTimeout(timeout time.Duration) TxOption {
+	return func(o *txOptions) error {
+		o.signingTimeout = timeout
+		return nil
+	}
+}
+
+// WithCompression compresses, with zstd, the transaction sent to the other parties and the token request submitted
+// for endorsement. The parties receiving a compressed transaction reply with compressed transactions.
+func WithCompression() TxOption {
+	return func(o *txOptions) error {
+		o.compression = true
+		return nil
+	}
+}
+
+// WithSubmitter sets the Fabric identity that signs the proposal and the envelope of the transaction, in place of
+// the identity passed to NewTransaction. A gateway identity shared by the nodes of an organization, for instance,
+// does not reveal to the peers which party initiated the transaction. It overrides the submitter configured
+// for the TMS, if any. Anonymous transactions are signed by an anonymous identity, they do not accept a submitter.
+func WithSubmitter(submitter view.Identity) TxOption {
+	return func(o *txOptions) error {
+		o.submitter = submitter
+		return nil
+	}
+}
+
+// WithConfiguredSubmitter makes the submitter configured for the TMS, if any, sign the proposal and the envelope
+// of the transaction, in place of the identity passed to NewTransaction, see WithSubmitter.
+// Without this option, the configured submitter is not used.
+func WithConfiguredSubmitter() TxOption {
+	return func(o *txOptions) error {
+		o.configuredSubmitter = true
+		return nil
+	}
+}
+
+// WithTimestampAuthority notarizes the token request, once signed, with the passed authority.
+// The timestamp, attesting when the transaction has been initiated, is stored, see GetNotarization.
+func WithTimestampAuthority(authority timestamp.Authority) TxOption {
+	return func(o *txOptions) error {
+		o.timestampAuthority = authority
+		return nil
+	}
+}
+
+// WithMemo sets the memo attached to the records of the transaction in the history of the local wallets,
+// see history.Service. The memo stays local, it is not sent to the other parties.
+func WithMemo(memo string) TxOption {
+	return func(o *txOptions) error {
+		o.memo = memo
+		return nil
+	}
+}
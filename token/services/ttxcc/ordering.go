@@ -6,8 +6,14 @@ SPDX-License-Identifier: Apache-2.0
 package ttxcc
 
 import (
+	"time"
+
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/network"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tracing"
 )
 
 type orderingView struct {
@@ -18,9 +24,91 @@ func NewOrderingView(tx *Transaction) *orderingView {
 	return &orderingView{tx: tx}
 }
 
-func (o *orderingView) Call(context view.Context) (interface{}, error) {
-	if err := fabric.GetDefaultNetwork(context).Ordering().Broadcast(o.tx.Payload.FabricEnvelope); err != nil {
-		return nil, err
+// Call submits the transaction to ordering and waits for its finality.
+// If the transaction is not committed in time, the same envelope is submitted again.
+// This is safe because the transaction id does not change, a duplicate is rejected by the committing peers.
+// The envelope is persisted before the first submission, until the final status of the transaction is known,
+// see RecoverPendingTransactions. If the retries run out, the transaction is marked as failed, see FailedTransactions.
+// If the transaction is committed as invalid because a conflicting transaction spent its inputs first,
+// ErrInputsSpent is returned, see RetryOnConflict.
+// The span of the transaction, see tracing.Tracer, ends here.
+func (o *orderingView) Call(context view.Context) (res interface{}, err error) {
+	defer func() { o.tx.endTrace(err) }()
+
+	return nil, o.order(context)
+}
+
+func (o *orderingView) order(context view.Context) error {
+	net, err := network.GetNetwork(context, o.tx.Network(), o.tx.Channel())
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting network [%s:%s]", o.tx.Network(), o.tx.Channel())
+	}
+	ch := fabric.GetChannel(context, o.tx.Network(), o.tx.Channel())
+
+	pending, err := storePending(context, o.tx)
+	if err != nil {
+		return errors.WithMessagef(err, "failed persisting transaction [%s]", o.tx.ID())
+	}
+	for attempt := 0; ; attempt++ {
+		err := o.broadcast(net, attempt)
+		if err == nil {
+			err = o.waitFinality(net)
+			if err == nil {
+				markDone(context, pending)
+				return nil
+			}
+			// the transaction might have been committed as invalid, in this case there is no point in resubmitting it
+			if valid, known := finalStatus(ch, o.tx.ID()); known {
+				markDone(context, pending)
+				if valid {
+					return nil
+				}
+				return o.invalid(errors.WithMessagef(err, "transaction [%s] is not valid", o.tx.ID()))
+			}
+		}
+		if attempt >= o.tx.opts.orderingRetries {
+			markFailed(context, pending)
+			return errors.WithMessagef(err, "failed ordering transaction [%s] after [%d] attempts", o.tx.ID(), attempt+1)
+		}
+		logger.Warnf("transaction [%s] not committed, resubmit [%d] of [%d]: [%s]", o.tx.ID(), attempt+1, o.tx.opts.orderingRetries, err)
+		time.Sleep(o.tx.opts.retryDelay)
+	}
+}
+
+// invalid releases the inputs of the invalid transaction, so that they can be selected again, and returns
+// ErrInputsSpent if any of them has been spent by a conflicting transaction
+func (o *orderingView) invalid(err error) error {
+	o.tx.Release()
+	inputs, iErr := o.tx.Inputs()
+	if iErr != nil {
+		logger.Debugf("failed getting the inputs of [%s]: [%s]", o.tx.ID(), iErr)
+		return err
+	}
+	return conflictError(err, inputs.IDs(), o.tx.TokenService().Vault().NewQueryEngine())
+}
+
+// broadcast submits the transaction to the ordering service
+func (o *orderingView) broadcast(net *network.Network, attempt int) (err error) {
+	_, span := o.tx.startSpan("ttxcc.ordering")
+	span.SetAttribute("attempt", attempt)
+	defer func() { tracing.EndWithError(span, err) }()
+
+	return net.Broadcast(o.tx.ID(), o.tx.Payload.FabricEnvelope)
+}
+
+// waitFinality waits for the finality of the transaction at most for the finality timeout
+func (o *orderingView) waitFinality(net *network.Network) (err error) {
+	_, span := o.tx.startSpan("ttxcc.finality")
+	defer func() { tracing.EndWithError(span, err) }()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- net.IsFinal(o.tx.ID())
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(o.tx.opts.finalityTimeout):
+		return errors.Errorf("timeout waiting for the finality of [%s]", o.tx.ID())
 	}
-	return nil, fabric.GetChannel(context, o.tx.Network(), o.tx.Channel()).Finality().IsFinal(o.tx.ID())
 }
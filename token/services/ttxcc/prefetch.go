@@ -0,0 +1,244 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	session2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/session"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+)
+
+// MaxPrefetchedRecipients bounds the number of recipient identities a counterparty generates for a single request
+const MaxPrefetchedRecipients = 100
+
+// MaxCachedRecipients bounds the number of prefetched recipient identities kept for a single counterparty,
+// the oldest are dropped first
+const MaxCachedRecipients = 10 * MaxPrefetchedRecipients
+
+// recipients holds the recipient identities prefetched from the counterparties, a cache for each token management service
+var recipients = &recipientCaches{caches: map[string]*recipientCache{}}
+
+type recipientCaches struct {
+	lock   sync.Mutex
+	caches map[string]*recipientCache
+}
+
+// tmsKey identifies the passed token management service, its network, channel and namespace are resolved already
+func tmsKey(tms *token.ManagementService) string {
+	return tms.Network() + "/" + tms.Channel() + "/" + tms.Namespace()
+}
+
+// get returns the cache of the token management service with the passed key, see tmsKey
+func (c *recipientCaches) get(key string) *recipientCache {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	cache, ok := c.caches[key]
+	if !ok {
+		cache = &recipientCache{entries: map[string][]*cachedRecipient{}}
+		c.caches[key] = cache
+	}
+	return cache
+}
+
+type cachedRecipient struct {
+	identity view.Identity
+	expiry   time.Time
+}
+
+// recipientCache keeps the recipient identities prefetched from the counterparties, by counterparty
+type recipientCache struct {
+	lock    sync.Mutex
+	entries map[string][]*cachedRecipient
+}
+
+// put adds the passed identities of the passed counterparty, they expire after the passed time to live.
+// The identities the cache holds already are skipped, an identity is handed out once.
+func (c *recipientCache) put(other view.Identity, ids []view.Identity, ttl time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now()
+	c.evict(now)
+	k := other.UniqueID()
+	entries := c.entries[k]
+	known := map[string]bool{}
+	for _, e := range entries {
+		known[e.identity.UniqueID()] = true
+	}
+	expiry := now.Add(ttl)
+	for _, id := range ids {
+		if known[id.UniqueID()] {
+			continue
+		}
+		known[id.UniqueID()] = true
+		entries = append(entries, &cachedRecipient{identity: id, expiry: expiry})
+	}
+	if len(entries) > MaxCachedRecipients {
+		entries = entries[len(entries)-MaxCachedRecipients:]
+	}
+	if len(entries) == 0 {
+		delete(c.entries, k)
+		return
+	}
+	c.entries[k] = entries
+}
+
+// take removes and returns an unexpired identity of the passed counterparty, nil if none is left.
+// An identity is used once, the recipient identities of a counterparty are not linkable.
+func (c *recipientCache) take(other view.Identity) view.Identity {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.evict(time.Now())
+	k := other.UniqueID()
+	entries := c.entries[k]
+	if len(entries) == 0 {
+		return nil
+	}
+	if len(entries) == 1 {
+		delete(c.entries, k)
+	} else {
+		c.entries[k] = entries[1:]
+	}
+	return entries[0].identity
+}
+
+// available returns the number of unexpired identities of the passed counterparty
+func (c *recipientCache) available(other view.Identity) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.evict(time.Now())
+	return len(c.entries[other.UniqueID()])
+}
+
+// evict removes the expired identities of all the counterparties, and the counterparties left with none
+func (c *recipientCache) evict(now time.Time) {
+	for k, entries := range c.entries {
+		var unexpired []*cachedRecipient
+		for _, e := range entries {
+			if now.Before(e.expiry) {
+				unexpired = append(unexpired, e)
+			}
+		}
+		if len(unexpired) == 0 {
+			delete(c.entries, k)
+			continue
+		}
+		c.entries[k] = unexpired
+	}
+}
+
+type prefetchRecipientsView struct {
+	Channel        string
+	Counterparties []view.Identity
+	Count          int
+	TTL            time.Duration
+}
+
+// PrefetchRecipientIdentities asks each of the passed counterparties, concurrently and in a single round trip,
+// for the passed number of recipient identities, together with their audit info, and keeps them for the passed
+// time to live. RequestRecipientIdentity hands out the prefetched identities, if any, before asking the counterparty.
+// The counterparties answer with the view returned by NewRespondRequestRecipientIdentityView.
+func PrefetchRecipientIdentities(context view.Context, counterparties []view.Identity, count int, ttl time.Duration) error {
+	_, err := context.RunView(&prefetchRecipientsView{Counterparties: counterparties, Count: count, TTL: ttl})
+	return err
+}
+
+// AvailableRecipientIdentities returns the number of prefetched recipient identities of the passed counterparty
+// not yet used nor expired, for the token management service selected by the passed options
+func AvailableRecipientIdentities(sp token.ServiceProvider, other view.Identity, opts ...token.ServiceOption) int {
+	return recipients.get(tmsKey(token.GetManagementService(sp, opts...))).available(other)
+}
+
+func (f *prefetchRecipientsView) Call(context view.Context) (interface{}, error) {
+	if f.Count <= 0 || f.Count > MaxPrefetchedRecipients {
+		return nil, errors.Errorf("invalid number of recipient identities [%d], must be in [1, %d]", f.Count, MaxPrefetchedRecipients)
+	}
+	errs := make([]error, len(f.Counterparties))
+	var wg sync.WaitGroup
+	wg.Add(len(f.Counterparties))
+	for i, other := range f.Counterparties {
+		go func(i int, other view.Identity) {
+			defer wg.Done()
+			errs[i] = f.prefetch(context, other)
+		}(i, other)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed prefetching recipient identities of [%s]", f.Counterparties[i])
+		}
+	}
+	return nil, nil
+}
+
+func (f *prefetchRecipientsView) prefetch(context view.Context, other view.Identity) error {
+	session, err := context.GetSession(context.Initiator(), other)
+	if err != nil {
+		return err
+	}
+	rr := &RecipientRequest{
+		Channel:  f.Channel,
+		WalletID: other,
+		Count:    f.Count,
+	}
+	rrRaw, err := rr.Bytes()
+	if err != nil {
+		return errors.Wrapf(err, "failed marshalling recipient request")
+	}
+	if err := session.Send(rrRaw); err != nil {
+		return err
+	}
+	payload, err := session2.ReadMessageWithTimeout(session, 60*time.Second)
+	if err != nil {
+		return err
+	}
+	data, err := parseRecipients(payload)
+	if err != nil {
+		return err
+	}
+
+	ts := token.GetManagementService(context, token.WithChannel(f.Channel))
+	resolver := view2.GetEndpointService(context)
+	ids := make([]view.Identity, len(data))
+	for i, d := range data {
+		if err := ts.WalletManager().RegisterRecipientIdentity(d.Identity, d.AuditInfo, d.Metadata); err != nil {
+			return err
+		}
+		if err := resolver.Bind(other, d.Identity); err != nil {
+			return err
+		}
+		ids[i] = d.Identity
+	}
+	recipients.get(tmsKey(ts)).put(other, ids, f.TTL)
+	logger.Debugf("prefetched [%d] recipient identities of [%s]", len(ids), other)
+	return nil
+}
+
+// parseRecipients unmarshals the reply to a recipient request, a list of recipient data if more than one
+// identity is requested. A responder not supporting prefetching replies with a single recipient data.
+func parseRecipients(raw []byte) ([]*RecipientData, error) {
+	var data []*RecipientData
+	if err := json.Unmarshal(raw, &data); err == nil {
+		for _, d := range data {
+			if d == nil {
+				return nil, errors.Errorf("invalid recipient data")
+			}
+		}
+		return data, nil
+	}
+	d := &RecipientData{}
+	if err := d.FromBytes(raw); err != nil {
+		return nil, err
+	}
+	return []*RecipientData{d}, nil
+}
@@ -0,0 +1,51 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecipientCache(t *testing.T) {
+	caches := &recipientCaches{caches: map[string]*recipientCache{}}
+	alice, bob := view.Identity("alice"), view.Identity("bob")
+	cache := caches.get("network/ch1/ns")
+
+	// the identities are handed out once, in order, even if prefetched twice
+	cache.put(alice, []view.Identity{view.Identity("a1"), view.Identity("a2"), view.Identity("a1")}, time.Hour)
+	cache.put(alice, []view.Identity{view.Identity("a2")}, time.Hour)
+	assert.Equal(t, 2, cache.available(alice))
+	assert.Equal(t, view.Identity("a1"), cache.take(alice))
+	assert.Equal(t, view.Identity("a2"), cache.take(alice))
+	assert.Nil(t, cache.take(alice))
+
+	// the caches of different token management services are distinct
+	cache.put(alice, []view.Identity{view.Identity("a3")}, time.Hour)
+	assert.Equal(t, 0, caches.get("network/ch2/ns").available(alice))
+	assert.Same(t, cache, caches.get("network/ch1/ns"))
+
+	// the expired identities are evicted, of any counterparty
+	cache.put(bob, []view.Identity{view.Identity("b1")}, -time.Second)
+	assert.Len(t, cache.entries, 2)
+	cache.put(alice, nil, time.Hour)
+	assert.Len(t, cache.entries, 1)
+	assert.Equal(t, 0, cache.available(bob))
+	assert.Nil(t, cache.take(bob))
+
+	// the oldest identities are dropped beyond the bound
+	ids := make([]view.Identity, MaxCachedRecipients+1)
+	for i := range ids {
+		ids[i] = view.Identity(fmt.Sprintf("b%d", i))
+	}
+	cache.put(bob, ids, time.Hour)
+	assert.Equal(t, MaxCachedRecipients, cache.available(bob))
+	assert.Equal(t, view.Identity("b1"), cache.take(bob))
+}
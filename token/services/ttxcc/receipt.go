@@ -0,0 +1,140 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+)
+
+const receiptPrefix = "token-sdk.ttxcc.receipt"
+
+// Receipt is the acknowledgement, signed by a receiver, that it has received a token request.
+// The signature is over the hash of the token request and the transaction id.
+type Receipt struct {
+	TxID        string
+	RequestHash []byte
+	Signer      view.Identity
+	Signature   []byte
+}
+
+func (r *Receipt) MessageToSign() []byte {
+	return append(append([]byte{}, r.RequestHash...), []byte(r.TxID)...)
+}
+
+// Verify checks that this receipt refers to the passed transaction and that it has been signed by its signer
+func (r *Receipt) Verify(tx *Transaction) error {
+	if r.TxID != tx.ID() {
+		return errors.Errorf("receipt refers to [%s], expected [%s]", r.TxID, tx.ID())
+	}
+	h, err := requestHash(tx)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(h, r.RequestHash) {
+		return errors.Errorf("receipt refers to a different token request for [%s]", r.TxID)
+	}
+	verifier, err := tx.TokenService().SigService().GetVerifier(r.Signer)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting verifier for [%s]", r.Signer.UniqueID())
+	}
+	if err := verifier.Verify(r.MessageToSign(), r.Signature); err != nil {
+		return errors.WithMessagef(err, "invalid receipt signature by [%s]", r.Signer.UniqueID())
+	}
+	return nil
+}
+
+// newReceipt returns the receipt of the passed transaction signed by the passed identity
+func newReceipt(tx *Transaction, signer view.Identity) (*Receipt, error) {
+	h, err := requestHash(tx)
+	if err != nil {
+		return nil, err
+	}
+	r := &Receipt{TxID: tx.ID(), RequestHash: h, Signer: signer}
+	s, err := tx.TokenService().SigService().GetSigner(signer)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "cannot find signer for [%s]", signer.UniqueID())
+	}
+	r.Signature, err = s.Sign(r.MessageToSign())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed signing receipt for [%s]", r.TxID)
+	}
+	return r, nil
+}
+
+// receiptSigner returns the first owner of the outputs of the passed transaction that belongs to this node, if any
+func receiptSigner(tx *Transaction) (view.Identity, error) {
+	outputs, err := tx.Outputs()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting outputs of [%s]", tx.ID())
+	}
+	for i := 0; i < outputs.Count(); i++ {
+		output := outputs.At(i)
+		if output.Owner.IsNone() {
+			continue
+		}
+		if tx.TokenService().WalletManager().OwnerWalletByIdentity(output.Owner) != nil {
+			return output.Owner, nil
+		}
+	}
+	return nil, nil
+}
+
+func requestHash(tx *Transaction) ([]byte, error) {
+	raw, err := tx.TokenRequest.RequestToBytes()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed marshalling token request [%s]", tx.ID())
+	}
+	h := sha256.Sum256(raw)
+	return h[:], nil
+}
+
+// storeReceipt stores the passed receipt, indexed by transaction id and signer
+func storeReceipt(sp view2.ServiceProvider, receipt *Receipt) error {
+	k, err := kvs.CreateCompositeKey(receiptPrefix, []string{receipt.TxID, receipt.Signer.UniqueID()})
+	if err != nil {
+		return errors.WithMessagef(err, "failed creating receipt key for [%s]", receipt.TxID)
+	}
+	return kvs.GetService(sp).Put(k, receipt)
+}
+
+// GetReceipts returns the receipts collected for the passed transaction
+func GetReceipts(sp view2.ServiceProvider, txID string) ([]*Receipt, error) {
+	it, err := kvs.GetService(sp).GetByPartialCompositeID(receiptPrefix, []string{txID})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed querying receipts of [%s]", txID)
+	}
+	defer it.Close()
+
+	var receipts []*Receipt
+	for it.HasNext() {
+		receipt := &Receipt{}
+		if err := it.Next(receipt); err != nil {
+			return nil, errors.WithMessagef(err, "failed reading receipt of [%s]", txID)
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
+
+// parseAck returns the receipt carried by the passed acknowledgement, if any.
+// Parties that do not receive tokens acknowledge without a receipt.
+func parseAck(raw []byte) (*Receipt, error) {
+	if string(raw) == "ack" {
+		return nil, nil
+	}
+	receipt := &Receipt{}
+	if err := json.Unmarshal(raw, receipt); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling receipt")
+	}
+	return receipt, nil
+}
@@ -50,6 +50,8 @@ func (r *ExchangeRecipientRequest) FromBytes(raw []byte) error {
 type RecipientRequest struct {
 	Channel  string
 	WalletID []byte
+	// Count, if greater than one, is the number of recipient identities requested, see PrefetchRecipientIdentities
+	Count int `json:",omitempty"`
 }
 
 func (r *RecipientRequest) Bytes() ([]byte, error) {
@@ -84,6 +86,11 @@ func (f requestPseudonymView) Call(context view.Context) (interface{}, error) {
 		}
 		return recipient, nil
 	} else {
+		// use a prefetched identity, if any, it is registered and bound already
+		if recipient := recipients.get(tmsKey(ts)).take(f.Other); recipient != nil {
+			logger.Debugf("use prefetched recipient identity of [%s]", f.Other)
+			return recipient, nil
+		}
 		session, err := context.GetSession(context.Initiator(), f.Other)
 		if err != nil {
 			return nil, err
@@ -150,24 +157,31 @@ func (s *respondPseudonymView) Call(context view.Context) (interface{}, error) {
 		wallet = string(rr.WalletID)
 	}
 	w := GetWalletForChannel(context, rr.Channel, wallet)
-	recipientIdentity, err := w.GetRecipientIdentity()
-	if err != nil {
-		return nil, err
+	if rr.Count > MaxPrefetchedRecipients {
+		return nil, errors.Errorf("too many recipient identities requested [%d], at most [%d]", rr.Count, MaxPrefetchedRecipients)
 	}
-	auditInfo, err := w.GetAuditInfo(recipientIdentity)
-	if err != nil {
-		return nil, err
+	count := rr.Count
+	if count < 1 {
+		count = 1
 	}
-	metadata, err := w.GetTokenMetadata(recipientIdentity)
-	if err != nil {
-		return nil, err
+	data := make([]*RecipientData, count)
+	resolver := view2.GetEndpointService(context)
+	for i := range data {
+		data[i], err = newRecipientData(w, session.Info().Caller)
+		if err != nil {
+			return nil, err
+		}
+		// Update the Endpoint Resolver
+		if err := resolver.Bind(context.Me(), data[i].Identity); err != nil {
+			return nil, err
+		}
 	}
-	recipientData := &RecipientData{
-		Identity:  recipientIdentity,
-		AuditInfo: auditInfo,
-		Metadata:  metadata,
+	var recipientDataRaw []byte
+	if rr.Count > 1 {
+		recipientDataRaw, err = json.Marshal(data)
+	} else {
+		recipientDataRaw, err = data[0].Bytes()
 	}
-	recipientDataRaw, err := recipientData.Bytes()
 	if err != nil {
 		return nil, err
 	}
@@ -178,14 +192,28 @@ func (s *respondPseudonymView) Call(context view.Context) (interface{}, error) {
 		return nil, err
 	}
 
-	// Update the Endpoint Resolver
-	resolver := view2.GetEndpointService(context)
-	err = resolver.Bind(context.Me(), recipientIdentity)
+	return data[0].Identity, nil
+}
+
+// newRecipientData returns a fresh recipient identity of the passed wallet, with its audit info and metadata
+func newRecipientData(w *token.OwnerWallet, counterparty view.Identity) (*RecipientData, error) {
+	recipientIdentity, err := w.GetRecipientIdentity(token.WithCounterparty(counterparty))
 	if err != nil {
 		return nil, err
 	}
-
-	return recipientIdentity, nil
+	auditInfo, err := w.GetAuditInfo(recipientIdentity)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := w.GetTokenMetadata(recipientIdentity)
+	if err != nil {
+		return nil, err
+	}
+	return &RecipientData{
+		Identity:  recipientIdentity,
+		AuditInfo: auditInfo,
+		Metadata:  metadata,
+	}, nil
 }
 
 func NewRespondRequestRecipientIdentityView() view.View {
@@ -231,7 +259,7 @@ func (f *exchangePseudonymView) Call(context view.Context) (interface{}, error)
 		ch := fabric.GetChannel(context, f.Network, f.Channel)
 		ts := token.GetManagementService(context, token.WithChannel(ch.Name()))
 		w := ts.WalletManager().OwnerWallet(f.Wallet)
-		me, err := w.GetRecipientIdentity()
+		me, err := w.GetRecipientIdentity(token.WithCounterparty(f.Other))
 		if err != nil {
 			return nil, err
 		}
@@ -321,7 +349,7 @@ func (s *respondExchangePseudonymView) Call(context view.Context) (interface{},
 		wallet = string(request.WalletID)
 	}
 	w := ts.WalletManager().OwnerWallet(wallet)
-	me, err := w.GetRecipientIdentity()
+	me, err := w.GetRecipientIdentity(token.WithCounterparty(session.Info().Caller))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,150 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	session2 "github.com/hyperledger-labs/fabric-smart-client/platform/view/services/session"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+)
+
+// RedemptionNotice carries to an issuer a transaction that redeems tokens.
+// The burn receipts of the redemptions are part of the token request of the transaction.
+type RedemptionNotice struct {
+	Network     string
+	Transaction []byte
+}
+
+type NotifyRedemptionView struct {
+	tx     *Transaction
+	issuer view.Identity
+}
+
+// NewNotifyRedemptionView returns a view that sends the passed transaction, that must carry burn receipts,
+// to the passed issuer and waits for its acknowledgement.
+// The issuer must register AcceptRedemptionView as responder of this view.
+func NewNotifyRedemptionView(tx *Transaction, issuer view.Identity) *NotifyRedemptionView {
+	return &NotifyRedemptionView{tx: tx, issuer: issuer}
+}
+
+func (n *NotifyRedemptionView) Call(context view.Context) (interface{}, error) {
+	if len(n.tx.BurnReceipts()) == 0 {
+		return nil, errors.Errorf("transaction [%s] does not redeem any token", n.tx.ID())
+	}
+	txRaw, err := n.tx.Bytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshalling transaction content")
+	}
+	raw, err := json.Marshal(&RedemptionNotice{Network: n.tx.Network(), Transaction: txRaw})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshalling redemption notice")
+	}
+
+	logger.Debugf("notify redemptions of [%s] to issuer [%s]", n.tx.ID(), n.issuer)
+	session, err := context.GetSession(n, n.issuer)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed getting session")
+	}
+	if err := session.Send(raw); err != nil {
+		return nil, errors.Wrapf(err, "failed sending redemption notice to [%s]", n.issuer)
+	}
+	ack, err := session2.ReadMessageWithTimeout(session, 60*time.Second)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed notifying redemptions of [%s] to [%s]", n.tx.ID(), n.issuer)
+	}
+	if string(ack) != "ack" {
+		return nil, errors.Errorf("unexpected ack from issuer [%s]", n.issuer)
+	}
+	return nil, nil
+}
+
+type AcceptRedemptionView struct{}
+
+// NewAcceptRedemptionView returns the responder of NotifyRedemptionView.
+// It checks that the notified transaction carries valid burn receipts, acknowledges it, and returns it.
+// The burn receipts are available with Transaction.BurnReceipts. They are meaningful only once the transaction
+// is committed, the issuer is expected to run NewFinalityView on the returned transaction before settling them.
+func NewAcceptRedemptionView() *AcceptRedemptionView {
+	return &AcceptRedemptionView{}
+}
+
+func (a *AcceptRedemptionView) Call(context view.Context) (interface{}, error) {
+	session, payload, err := session2.ReadFirstMessage(context)
+	if err != nil {
+		return nil, err
+	}
+	notice := &RedemptionNotice{}
+	if err := json.Unmarshal(payload, notice); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling redemption notice")
+	}
+	tx, err := NewTransactionFromBytes(context, notice.Network, notice.Transaction)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed unmarshalling transaction")
+	}
+	logger.Debugf("received redemptions of [%s] from [%s]", tx.ID(), session.Info().Caller)
+
+	if len(tx.BurnReceipts()) == 0 {
+		return nil, errors.Errorf("transaction [%s] does not redeem any token", tx.ID())
+	}
+	if err := tx.IsValid(context.Context()); err != nil {
+		return nil, errors.WithMessagef(err, "invalid transaction [%s]", tx.ID())
+	}
+
+	if err := session.Send([]byte("ack")); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// RequestRedeemApprovalView asks the issuer declared by a burn receipt, see token.WithRedeemIssuer,
+// to co-sign the transaction. The issuer must register a responder for this view that receives the transaction
+// with ReceiveTransaction, inspects it, and runs NewApproveRedeemView.
+type RequestRedeemApprovalView struct {
+	tx     *Transaction
+	issuer view.Identity
+}
+
+func newRequestRedeemApprovalView(tx *Transaction, issuer view.Identity) *RequestRedeemApprovalView {
+	return &RequestRedeemApprovalView{tx: tx, issuer: issuer}
+}
+
+func (r *RequestRedeemApprovalView) Call(context view.Context) (interface{}, error) {
+	return requestCoSignature(context, r, r.tx, r.issuer)
+}
+
+type ApproveRedeemView struct {
+	tx     *Transaction
+	issuer view.Identity
+}
+
+// NewApproveRedeemView returns a view that co-signs, with the passed issuer identity, the redemptions of the passed
+// transaction and sends the signature back to the redeemer. The issuer must be the one declared by the burn receipts.
+func NewApproveRedeemView(tx *Transaction, issuer view.Identity) *ApproveRedeemView {
+	return &ApproveRedeemView{tx: tx, issuer: issuer}
+}
+
+func (a *ApproveRedeemView) Call(context view.Context) (interface{}, error) {
+	approved := false
+	for _, receipt := range a.tx.BurnReceipts() {
+		if a.issuer.Equal(receipt.Issuer) {
+			approved = true
+			break
+		}
+	}
+	if !approved {
+		return nil, errors.Errorf("[%s] is not the issuer of any redemption in transaction [%s]", a.issuer, a.tx.ID())
+	}
+
+	logger.Debugf("approve redemptions [%s][%s]", a.issuer.UniqueID(), a.tx.ID())
+	if err := coSign(context, a.tx, a.issuer); err != nil {
+		return nil, errors.WithMessagef(err, "failed co-signing redemptions as issuer [%s]", a.issuer)
+	}
+	return nil, nil
+}
@@ -0,0 +1,214 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/hash"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+)
+
+// SigningRoundView circulates the signing payload of the token request, the request marshalled to sign and bound
+// to the transaction id, to the senders of the transfers and to the auditors, and collects their signatures in parallel.
+// Each signature is verified as soon as it arrives. The signatures are appended to the token request, in the order
+// expected by the validator, only once all of them have been collected.
+// If the round does not complete within the signing timeout, see WithSigningTimeout, the error names the parties
+// that have not signed yet.
+type SigningRoundView struct {
+	tx *Transaction
+}
+
+func NewSigningRoundView(tx *Transaction) *SigningRoundView {
+	return &SigningRoundView{tx: tx}
+}
+
+// signingSlot is the position of a signature in the token request
+type signingSlot struct {
+	party view.Identity
+	sigma []byte
+}
+
+type signingResult struct {
+	index int
+	err   error
+}
+
+func (s *SigningRoundView) Call(context view.Context) (interface{}, error) {
+	// a request below the audit threshold skips the auditors, the drivers hiding the values require a proof of it
+	if err := s.tx.TokenRequest.ProveAuditThreshold(); err != nil {
+		return nil, err
+	}
+	requestRaw, err := s.tx.TokenRequest.MarshallToSign()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed marshalling request to sign")
+	}
+
+	// group the slots by party, the requests to the same party go through the same session and must be sequential
+	var slots []*signingSlot
+	var parties []view.Identity
+	slotsByParty := map[string][]*signingSlot{}
+	for _, transfer := range s.tx.TokenRequest.Transfers() {
+		for _, party := range transfer.Senders {
+			slot := &signingSlot{party: party}
+			slots = append(slots, slot)
+			if _, ok := slotsByParty[party.UniqueID()]; !ok {
+				parties = append(parties, party)
+			}
+			slotsByParty[party.UniqueID()] = append(slotsByParty[party.UniqueID()], slot)
+		}
+	}
+	auditors, err := s.tx.auditors()
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf("signing round for [%s]: [%d] signatures from [%d] senders, auditors %v", s.tx.ID(), len(slots), len(parties), auditors)
+
+	waiting := append([]view.Identity(nil), parties...)
+	results := make(chan *signingResult, len(parties)+len(auditors))
+	for i, party := range parties {
+		go func(index int, party view.Identity) {
+			results <- &signingResult{index: index, err: s.collect(context, requestRaw, party, slotsByParty[party.UniqueID()])}
+		}(i, party)
+	}
+	auditorSlots := make([]*signingSlot, len(auditors))
+	for i, auditor := range auditors {
+		auditorSlots[i] = &signingSlot{party: auditor}
+		waiting = append(waiting, auditor)
+		go func(index int, slot *signingSlot) {
+			sigma, err := context.RunView(newAuditingViewInitiator(s.tx, slot.party))
+			if err == nil {
+				slot.sigma = sigma.([]byte)
+			}
+			results <- &signingResult{index: index, err: err}
+		}(len(waiting)-1, auditorSlots[i])
+	}
+
+	timeout := time.After(s.tx.opts.signingTimeout)
+	for pending := len(waiting); pending > 0; pending-- {
+		select {
+		case r := <-results:
+			party := waiting[r.index]
+			if r.err != nil {
+				return nil, errors.WithMessagef(r.err, "failed collecting signature from [%s]", party)
+			}
+			waiting[r.index] = nil
+			logger.Debugf("signing round for [%s]: [%s] signed, [%d] pending", s.tx.ID(), party, pending-1)
+		case <-timeout:
+			var blocking []string
+			for _, party := range waiting {
+				if party != nil {
+					blocking = append(blocking, party.String())
+				}
+			}
+			return nil, errors.Errorf("signing round for [%s] timed out, waiting for %v", s.tx.ID(), blocking)
+		}
+	}
+
+	for i, slot := range auditorSlots {
+		if i == 0 {
+			s.tx.TokenRequest.SetAuditorSignature(slot.sigma)
+			continue
+		}
+		s.tx.TokenRequest.AppendAuditorSignature(slot.sigma)
+	}
+	for _, slot := range slots {
+		s.tx.TokenRequest.AppendSignature(slot.sigma)
+	}
+	return nil, nil
+}
+
+// collect fills the passed slots, all belonging to the passed party, signing locally if the party is me
+func (s *SigningRoundView) collect(context view.Context, requestRaw []byte, party view.Identity, slots []*signingSlot) error {
+	signatureRequest := &signatureRequest{
+		Request: requestRaw,
+		TxID:    []byte(s.tx.ID()),
+		Signer:  party,
+	}
+	signed, err := GetSignedBundle(context, s.tx.ID(), party)
+	if err != nil {
+		return err
+	}
+	if signed != nil {
+		// signed offline, the signatures have been verified when attached
+		logger.Debugf("signing round for [%s]: [%s] signed offline", s.tx.ID(), party.UniqueID())
+		if !bytes.Equal(signed.RequestHash, requestDigest(requestRaw)) {
+			return errors.Errorf("offline signatures of [%s] refer to a different token request", party)
+		}
+		if len(signed.Signatures) != len(slots) {
+			return errors.Errorf("[%d] offline signatures of [%s], expected [%d]", len(signed.Signatures), party, len(slots))
+		}
+		for i, slot := range slots {
+			slot.sigma = signed.Signatures[i]
+		}
+		return nil
+	}
+	tms := token.GetManagementService(context, token.WithChannel(s.tx.Channel()))
+	var signer token.Signer
+	if w := tms.WalletManager().OwnerWalletByIdentity(party); w != nil {
+		signer, err = w.GetSigner(party)
+	} else if w := tms.WalletManager().IssuerWalletByIdentity(party); w != nil {
+		// reclaims of expired tokens are signed by the issuer
+		signer, err = w.GetSigner(party)
+	}
+	if err != nil {
+		return err
+	}
+	if signer != nil {
+		logger.Debugf("signing round for [%s]: [%s] is me", s.tx.ID(), party.UniqueID())
+		for _, slot := range slots {
+			if slot.sigma, err = signer.Sign(signatureRequest.MessageToSign()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	session, err := context.GetSession(context.Initiator(), party)
+	if err != nil {
+		return errors.Wrap(err, "failed getting session")
+	}
+	verifier, err := s.tx.TokenService().SigService().GetVerifier(party)
+	if err != nil {
+		return errors.Wrapf(err, "failed getting verifier for [%s]", party)
+	}
+	signatureRequestRaw, err := json.Marshal(signatureRequest)
+	if err != nil {
+		return err
+	}
+	ch := session.Receive()
+	for _, slot := range slots {
+		if err := session.Send(signatureRequestRaw); err != nil {
+			return errors.Wrap(err, "failed sending signature request")
+		}
+		var msg *view.Message
+		select {
+		case msg = <-ch:
+			logger.Debugf("signing round for [%s]: reply received from [%s]", s.tx.ID(), party)
+		case <-time.After(s.tx.opts.signingTimeout):
+			return errors.Errorf("Timeout from party %s", party)
+		}
+		if msg.Status == view.ERROR {
+			return errors.New(string(msg.Payload))
+		}
+		if err := verifier.Verify(signatureRequest.MessageToSign(), msg.Payload); err != nil {
+			return errors.Wrapf(err, "failed verifying signature from [%s]", party)
+		}
+		logger.Debugf("signature verified [%s,%s,%s]",
+			hash.Hashable(signatureRequest.MessageToSign()).String(),
+			hash.Hashable(msg.Payload).String(),
+			party.UniqueID(),
+		)
+		slot.sigma = msg.Payload
+	}
+	return nil
+}
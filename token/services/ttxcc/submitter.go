@@ -0,0 +1,51 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/config"
+)
+
+// submitter returns the Fabric identity signing and submitting the transaction: the one passed with WithSubmitter,
+// or else the one configured for the passed TMS, if requested with WithConfiguredSubmitter, or else the passed default.
+// A designated submitter decouples the Fabric creator of the transaction from the token identities of its parties.
+func submitter(sp view2.ServiceProvider, tms *token.ManagementService, opts *txOptions, def view.Identity) (view.Identity, error) {
+	if !opts.submitter.IsNone() {
+		return opts.submitter, nil
+	}
+	if !opts.configuredSubmitter {
+		return def, nil
+	}
+	label, err := config.Submitter(sp, tms.Channel(), tms.Namespace())
+	if err != nil {
+		return nil, err
+	}
+	if len(label) == 0 {
+		return def, nil
+	}
+	id, err := fabric.GetFabricNetworkService(sp, tms.Network()).LocalMembership().GetIdentityByID(label)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed resolving submitter [%s]", label)
+	}
+	logger.Debugf("transactions of [%s] submitted by [%s]", tms, label)
+	return id, nil
+}
+
+// checkAnonymousSubmitter fails if the passed options designate a submitter. An anonymous transaction is signed
+// by an anonymous identity, a designated submitter would link all of them to the same identity.
+func checkAnonymousSubmitter(opts *txOptions) error {
+	if !opts.submitter.IsNone() || opts.configuredSubmitter {
+		return errors.New("anonymous transactions do not accept a submitter")
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package ttxcc
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmitter(t *testing.T) {
+	def := view.Identity("alice")
+
+	// without options, the transaction is submitted by its signer, the configuration is not read
+	opts, err := compile()
+	assert.NoError(t, err)
+	id, err := submitter(nil, nil, opts, def)
+	assert.NoError(t, err)
+	assert.Equal(t, def, id)
+
+	// the submitter passed explicitly overrides the signer
+	opts, err = compile(WithSubmitter(view.Identity("gateway")), WithConfiguredSubmitter())
+	assert.NoError(t, err)
+	id, err = submitter(nil, nil, opts, def)
+	assert.NoError(t, err)
+	assert.Equal(t, view.Identity("gateway"), id)
+}
+
+func TestAnonymousTransactionsRejectSubmitters(t *testing.T) {
+	opts, err := compile()
+	assert.NoError(t, err)
+	assert.NoError(t, checkAnonymousSubmitter(opts))
+
+	opts, err = compile(WithSubmitter(view.Identity("gateway")))
+	assert.NoError(t, err)
+	assert.EqualError(t, checkAnonymousSubmitter(opts), "anonymous transactions do not accept a submitter")
+
+	opts, err = compile(WithConfiguredSubmitter())
+	assert.NoError(t, err)
+	assert.EqualError(t, checkAnonymousSubmitter(opts), "anonymous transactions do not accept a submitter")
+}
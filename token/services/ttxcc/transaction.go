@@ -6,6 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package ttxcc
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/pkg/errors"
@@ -15,6 +16,10 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	api2 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/history"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/tracing"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 type Payload struct {
@@ -28,12 +33,17 @@ type Payload struct {
 	TokenRequest *token.Request
 
 	FabricEnvelope *fabric.Envelope
+
+	// TraceContext propagates the span of the transaction to the parties it is sent to, see tracing.Tracer
+	TraceContext map[string]string
 }
 
 type Transaction struct {
 	*Payload
 	sp   view2.ServiceProvider
 	opts *txOptions
+	// span covers the lifecycle of the transaction at its creator, nil at the other parties
+	span tracing.Span
 }
 
 func NewAnonymousTransaction(sp view.Context, opts ...TxOption) (*Transaction, error) {
@@ -41,6 +51,9 @@ func NewAnonymousTransaction(sp view.Context, opts ...TxOption) (*Transaction, e
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed compiling tx options")
 	}
+	if err := checkAnonymousSubmitter(txOpts); err != nil {
+		return nil, err
+	}
 	return NewTransaction(
 		sp,
 		fabric.GetFabricNetworkService(sp, txOpts.network).LocalMembership().AnonymousIdentity(),
@@ -48,6 +61,8 @@ func NewAnonymousTransaction(sp view.Context, opts ...TxOption) (*Transaction, e
 	)
 }
 
+// NewTransaction returns a new transaction whose Fabric proposal and envelope are signed by the passed signer,
+// unless a submitter is designated, see WithSubmitter
 func NewTransaction(sp view.Context, signer view.Identity, opts ...TxOption) (*Transaction, error) {
 	txOpts, err := compile(opts...)
 	if err != nil {
@@ -61,6 +76,10 @@ func NewTransaction(sp view.Context, signer view.Identity, opts ...TxOption) (*T
 		token.WithNamespace(txOpts.namespace),
 	)
 
+	signer, err = submitter(sp, tms, txOpts, signer)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting submitter")
+	}
 	id := &fabric.TxID{Creator: signer}
 	tr, err := tms.NewRequest(fabric.GetFabricNetworkService(sp, tms.Network()).TransactionManager().ComputeTxID(id))
 	if err != nil {
@@ -77,11 +96,24 @@ func NewTransaction(sp view.Context, signer view.Identity, opts ...TxOption) (*T
 			Channel:        tms.Channel(),
 			Namespace:      tms.Namespace(),
 			Transient:      map[string][]byte{},
+			TraceContext:   map[string]string{},
 		},
 		sp:   sp,
 		opts: txOpts,
 	}
-	sp.OnError(tx.Release)
+	var ctx context.Context
+	ctx, tx.span = tracing.Start(sp, sp.Context(), "ttxcc.transaction")
+	tx.span.SetAttribute("tx.id", tx.ID())
+	tracing.GetTracer(sp).Inject(ctx, tx.TraceContext)
+	sp.OnError(func() {
+		tx.endTrace(errors.New("transaction aborted"))
+		tx.Release()
+	})
+	if len(txOpts.memo) != 0 {
+		if err := tx.SetMemo(txOpts.memo); err != nil {
+			return nil, err
+		}
+	}
 	return tx, nil
 }
 
@@ -92,9 +124,16 @@ func NewTransactionFromBytes(sp view.Context, network string, raw []byte) (*Tran
 			FabricEnvelope: fabric.GetFabricNetworkService(sp, network).TransactionManager().NewEnvelope(),
 			Transient:      map[string][]byte{},
 		},
-		sp: sp,
+		sp:   sp,
+		opts: defaultTxOptions(),
 	}
-	err := json.Unmarshal(raw, tx.Payload)
+	// a compressed transaction signals that the sender supports compression, reply in kind
+	tx.opts.compression = api2.IsCompressed(raw)
+	raw, err := api2.Decompress(raw, api2.DefaultMaxDecompressedSize)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed decompressing transaction")
+	}
+	err = json.Unmarshal(raw, tx.Payload)
 	if err != nil {
 		return nil, err
 	}
@@ -148,21 +187,47 @@ func (t *Transaction) Namespace() string {
 }
 
 func (t *Transaction) Bytes() ([]byte, error) {
-	return json.Marshal(t.Payload)
+	raw, err := json.Marshal(t.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if t.opts.compression {
+		return api2.Compress(raw)
+	}
+	return raw, nil
+}
+
+func (t *Transaction) Issue(wallet *token.IssuerWallet, receiver view.Identity, typ string, q uint64, opts ...token.IssueOption) error {
+	_, err := t.TokenRequest.Issue(wallet, receiver, typ, q, append([]token.IssueOption{token.WithIssueContext(t.traceContext())}, opts...)...)
+	return err
+}
+
+// BatchIssue appends to the transaction a single issue action creating a token of the passed type for each receiver and value
+func (t *Transaction) BatchIssue(wallet *token.IssuerWallet, receivers []view.Identity, typ string, values []uint64, opts ...token.IssueOption) error {
+	_, err := t.TokenRequest.BatchIssue(wallet, receivers, typ, values, append([]token.IssueOption{token.WithIssueContext(t.traceContext())}, opts...)...)
+	return err
 }
 
-func (t *Transaction) Issue(wallet *token.IssuerWallet, receiver view.Identity, typ string, q uint64) error {
-	_, err := t.TokenRequest.Issue(wallet, receiver, typ, q)
+// Reclaim appends to the transaction the reclaim of the passed expired tokens, issued by the passed wallet, in favour of the passed receiver
+func (t *Transaction) Reclaim(wallet *token.IssuerWallet, typ string, receiver view.Identity, ids ...*token2.Id) error {
+	_, err := t.TokenRequest.Reclaim(wallet, typ, receiver, ids...)
 	return err
 }
 
 func (t *Transaction) Transfer(wallet *token.OwnerWallet, typ string, values []uint64, owners []view.Identity, opts ...token.TransferOption) error {
-	_, err := t.TokenRequest.Transfer(wallet, typ, values, owners, opts...)
+	_, err := t.TokenRequest.Transfer(wallet, typ, values, owners, append([]token.TransferOption{token.WithTransferContext(t.traceContext())}, opts...)...)
 	return err
 }
 
+// Redeem appends to the transaction the redemption of the passed value, together with its burn receipt.
+// Use token.WithBurnReference to attach reference data to the burn receipt.
 func (t *Transaction) Redeem(wallet *token.OwnerWallet, typ string, value uint64, opts ...token.TransferOption) error {
-	return t.TokenRequest.Redeem(wallet, typ, value, opts...)
+	return t.TokenRequest.Redeem(wallet, typ, value, append([]token.TransferOption{token.WithTransferContext(t.traceContext())}, opts...)...)
+}
+
+// BurnReceipts returns the burn receipts of the redemptions performed by this transaction
+func (t *Transaction) BurnReceipts() []*token.BurnReceipt {
+	return t.TokenRequest.BurnReceipts()
 }
 
 func (t *Transaction) Outputs() (*token.OutputStream, error) {
@@ -173,25 +238,49 @@ func (t *Transaction) Inputs() (*token.InputStream, error) {
 	return t.TokenRequest.Inputs()
 }
 
-func (t *Transaction) Verify() error {
-	return t.TokenRequest.Verify()
+func (t *Transaction) Verify(ctx context.Context) error {
+	return t.TokenRequest.Verify(ctx)
 }
 
-func (t *Transaction) IsValid() error {
-	return t.TokenRequest.IsValid()
+func (t *Transaction) IsValid(ctx context.Context) error {
+	return t.TokenRequest.IsValid(ctx)
 }
 
 func (t *Transaction) MarshallToAudit() ([]byte, error) {
 	return t.TokenRequest.MarshallToAudit()
 }
 
+// Receipts returns the receipts signed by the receivers of this transaction, as collected when it was distributed
+func (t *Transaction) Receipts() ([]*Receipt, error) {
+	return GetReceipts(t.sp, t.ID())
+}
+
+// SetMemo sets the memo attached to the records of this transaction in the history of the local wallets.
+// The history must be enabled.
+func (t *Transaction) SetMemo(memo string) error {
+	h := history.GetService(t.sp)
+	if h == nil {
+		return errors.Errorf("cannot set the memo of [%s], the history is not enabled", t.ID())
+	}
+	return h.SetMemo(t.ID(), memo)
+}
+
 func (t *Transaction) Selector() (token.Selector, error) {
-	return t.TokenService().SelectorManager().NewSelector(t.ID())
+	sm, err := t.TokenService().SelectorManager()
+	if err != nil {
+		return nil, err
+	}
+	return sm.NewSelector(t.ID())
 }
 
 func (t *Transaction) Release() {
 	logger.Debugf("releasing resources for tx [%s]", t.ID())
-	if err := t.TokenService().SelectorManager().Unlock(t.ID()); err != nil {
+	sm, err := t.TokenService().SelectorManager()
+	if err != nil {
+		logger.Warnf("failed releasing tokens locked by [%s], [%s]", t.ID(), err)
+		return
+	}
+	if err := sm.Unlock(t.ID()); err != nil {
 		logger.Warnf("failed releasing tokens locked by [%s], [%s]", t.ID(), err)
 	}
 }
@@ -255,6 +344,27 @@ func (t *Transaction) appendPayload(payload *Payload) error {
 	//return nil
 }
 
+// traceContext returns a context carrying the span of the transaction, as propagated by its creator
+func (t *Transaction) traceContext() context.Context {
+	return tracing.GetTracer(t.sp).Extract(context.Background(), t.TraceContext)
+}
+
+// startSpan starts a span, child of the span of the transaction, for a step of its lifecycle
+func (t *Transaction) startSpan(name string) (context.Context, tracing.Span) {
+	ctx, span := tracing.Start(t.sp, t.traceContext(), name)
+	span.SetAttribute("tx.id", t.ID())
+	return ctx, span
+}
+
+// endTrace ends the span of the transaction, if this party created it
+func (t *Transaction) endTrace(err error) {
+	if t.span == nil {
+		return
+	}
+	tracing.EndWithError(t.span, err)
+	t.span = nil
+}
+
 func (t *Transaction) TokenService() *token.ManagementService {
 	return token.GetManagementService(t.sp, token.WithChannel(t.Channel()))
 }
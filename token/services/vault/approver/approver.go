@@ -7,6 +7,7 @@ package approver
 
 import (
 	"crypto/rand"
+	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
@@ -34,14 +35,21 @@ type approver struct {
 	vault     Vault
 	validator translator.Validator
 	TxID      string
+	txTime    time.Time
+	height    uint64
 	rwset     translator.RWSet
 	namespace string
 }
 
-func NewTokenRWSetApprover(validator translator.Validator, vault Vault, txID string, RWSet translator.RWSet, namespace string) *approver {
+// NewTokenRWSetApprover returns an approver of the token request of the transaction with the passed id and time,
+// at the passed ledger height. The time is the one set by the creator of the transaction, the one the token chaincode
+// validates the request at, it is rejected if it deviates from the local clock by more than token.DefaultMaxClockSkew.
+func NewTokenRWSetApprover(validator translator.Validator, vault Vault, txID string, txTime time.Time, height uint64, RWSet translator.RWSet, namespace string) *approver {
 	return &approver{
 		vault:     vault,
 		TxID:      txID,
+		txTime:    txTime,
+		height:    height,
 		rwset:     RWSet,
 		validator: validator,
 		namespace: namespace,
@@ -52,6 +60,9 @@ func (v *approver) Validate(sp SignatureProvider, tokenRequest *token.Request) e
 	logger.Debugf("approve token request for tx [%d]", v.TxID)
 
 	logger.Debugf("verify token request for tx [%d]", v.TxID)
+	if err := token.CheckTxTime(v.txTime, time.Now(), token.DefaultMaxClockSkew); err != nil {
+		return errors.Wrap(err, "invalid transaction time")
+	}
 	// verify token request
 	qe, err := v.vault.NewQueryExecutor()
 	if err != nil {
@@ -59,7 +70,7 @@ func (v *approver) Validate(sp SignatureProvider, tokenRequest *token.Request) e
 	}
 	defer qe.Done()
 	backend := &backend{qe: qe, sp: sp, namespace: v.namespace}
-	actions, err := v.validator.Verify(backend, backend, v.TxID, tokenRequest)
+	actions, err := v.validator.Verify(backend, backend, v.TxID, tokenRequest, token.WithTxTime(v.txTime), token.WithHeight(v.height))
 	if err != nil {
 		return errors.Wrap(err, "failed verifying token request")
 	}
@@ -70,8 +81,7 @@ func (v *approver) Validate(sp SignatureProvider, tokenRequest *token.Request) e
 	if err != nil {
 		return errors.Wrap(err, "failed creating new rws")
 	}
-	// TODO: use a proper issuerValidator
-	issuingValidator := &allIssuersValid{}
+	issuingValidator := translator.NewPolicyIssuingValidator(rwset, v.namespace, nil)
 	translator := translator.New(issuingValidator, v.TxID, rwset, v.namespace)
 	for _, action := range actions {
 		err = translator.Write(action)
@@ -96,12 +106,6 @@ func (v *approver) Validate(sp SignatureProvider, tokenRequest *token.Request) e
 	return nil
 }
 
-type allIssuersValid struct{}
-
-func (i *allIssuersValid) Validate(creator view.Identity, tokenType string) error {
-	return nil
-}
-
 type backend struct {
 	qe        *fabric.QueryExecutor
 	sp        SignatureProvider
@@ -6,79 +6,328 @@ SPDX-License-Identifier: Apache-2.0
 package certification
 
 import (
+	"encoding/json"
 	"strconv"
+	"sync"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
-	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
+var logger = flogging.MustGetLogger("token-sdk.vault.certification")
+
+const certificationKeyPrefix = "token-sdk.certifier.certification"
+
 type Channel interface {
 	Name() string
 	Vault() *fabric.Vault
 }
 
+// entry is the persisted form of a certification.
+// The KVS does not support deletions, therefore the certification of a spent token is replaced by an entry
+// with no certification. The previous versions stored the certification alone, those entries are migrated
+// when first accessed, see Storage.get.
+type entry struct {
+	ID            *token.Id
+	Certification []byte
+}
+
+// cache holds the ids of the certified tokens of a namespace, it is shared by all the storages of that namespace
+type cache struct {
+	once sync.Once
+	err  error
+	lock sync.RWMutex
+	ids  map[string]bool
+	// loaded is set once the ids have been loaded from the KVS
+	loaded bool
+	// legacy is set if the KVS contains certifications in the previous format, their ids are not known
+	// until they are accessed, then the KVS is checked when an id is not cached
+	legacy bool
+}
+
+var (
+	cachesLock sync.Mutex
+	caches     = map[string]*cache{}
+)
+
+func getCache(channel, namespace string) *cache {
+	cachesLock.Lock()
+	defer cachesLock.Unlock()
+	k := channel + ":" + namespace
+	c, ok := caches[k]
+	if !ok {
+		c = &cache{ids: map[string]bool{}}
+		caches[k] = c
+	}
+	return c
+}
+
+// Storage persists the certifications of the tokens of a namespace in the KVS,
+// and keeps in memory the ids of the certified tokens
 type Storage struct {
 	sp        view.ServiceProvider
 	channel   Channel
 	namespace string
+	cache     *cache
+	// unspent tells, for each passed id, whether the corresponding token is still in the vault
+	unspent func(ids []*token.Id) ([]bool, error)
 }
 
 func NewStorage(sp view.ServiceProvider, channel Channel, namespace string) *Storage {
-	return &Storage{sp: sp, channel: channel, namespace: namespace}
+	s := &Storage{
+		sp:        sp,
+		channel:   channel,
+		namespace: namespace,
+		cache:     getCache(channel.Name(), namespace),
+	}
+	s.unspent = s.unspentInVault
+	return s
+}
+
+// Load pre-warms the in-memory cache from the KVS, once per namespace.
+// Certifications of tokens no longer in the vault are garbage-collected.
+func (v *Storage) Load() error {
+	v.cache.once.Do(func() {
+		v.cache.err = v.load()
+	})
+	return v.cache.err
 }
 
 func (v *Storage) Exists(id *token.Id) bool {
-	k := kvs.CreateCompositeKeyOrPanic(
-		"token-sdk.certifier.certification",
-		[]string{
-			v.channel.Name(),
-			v.namespace,
-			id.TxId,
-			strconv.FormatUint(uint64(id.Index), 10),
-		},
-	)
-	return kvs.GetService(v.sp).Exists(k)
+	if err := v.Load(); err != nil {
+		logger.Errorf("failed loading certifications [%s]", err)
+	}
+	k := v.key(id)
+	v.cache.lock.RLock()
+	certified, complete := v.cache.ids[k], v.cache.loaded && !v.cache.legacy
+	v.cache.lock.RUnlock()
+	if certified || complete {
+		return certified
+	}
+	certified, err := v.certified(k, id)
+	if err != nil {
+		logger.Errorf("failed checking certification of [%s]: [%s]", id, err)
+	}
+	return certified
 }
 
 func (v *Storage) Store(certifications map[*token.Id][]byte) error {
 	for id, certification := range certifications {
-		k := kvs.CreateCompositeKeyOrPanic(
-			"token-sdk.certifier.certification",
-			[]string{
-				v.channel.Name(),
-				v.namespace,
-				id.TxId,
-				strconv.FormatUint(uint64(id.Index), 10),
-			},
-		)
-		if err := kvs.GetService(v.sp).Put(k, certification); err != nil {
+		k := v.key(id)
+		if err := kvs.GetService(v.sp).Put(k, &entry{ID: id, Certification: certification}); err != nil {
 			return err
 		}
+		v.cache.lock.Lock()
+		v.cache.ids[k] = true
+		v.cache.lock.Unlock()
 	}
 	return nil
 }
 
 func (v *Storage) Get(ids []*token.Id, callback func(*token.Id, []byte) error) error {
 	for _, id := range ids {
-		k := kvs.CreateCompositeKeyOrPanic(
-			"token-sdk.certifier.certification",
-			[]string{
-				v.channel.Name(),
-				v.namespace,
-				id.TxId,
-				strconv.FormatUint(uint64(id.Index), 10),
-			},
-		)
-		var certification []byte
-		if err := kvs.GetService(v.sp).Get(k, &certification); err != nil {
-			return errors.WithMessagef(err, "failed getting certification from storage for [%s]", k)
+		k := v.key(id)
+		e, err := v.get(k, id)
+		if err != nil {
+			return err
 		}
-		if err := callback(id, certification); err != nil {
+		if len(e.Certification) == 0 {
+			return errors.Errorf("certification not found for [%s]", k)
+		}
+		if err := callback(id, e.Certification); err != nil {
 			return errors.WithMessagef(err, "failed call back for [%s]", k)
 		}
 	}
 	return nil
 }
+
+// Delete removes the certifications of the passed tokens, if any. It is called when the tokens are spent.
+// It does not load the certifications, it runs while a transaction is committed and the vault cannot be queried.
+func (v *Storage) Delete(ids ...*token.Id) error {
+	for _, id := range ids {
+		k := v.key(id)
+		v.cache.lock.RLock()
+		certified, complete := v.cache.ids[k], v.cache.loaded && !v.cache.legacy
+		v.cache.lock.RUnlock()
+		if !certified && !complete {
+			// the cache does not tell, check the KVS
+			var err error
+			if certified, err = v.certified(k, id); err != nil {
+				return err
+			}
+		}
+		if !certified {
+			continue
+		}
+		if err := v.delete(k, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *Storage) delete(k string, id *token.Id) error {
+	logger.Debugf("delete certification of [%s]", id)
+	if err := kvs.GetService(v.sp).Put(k, &entry{ID: id}); err != nil {
+		return errors.WithMessagef(err, "failed deleting certification for [%s]", k)
+	}
+	v.cache.lock.Lock()
+	delete(v.cache.ids, k)
+	v.cache.lock.Unlock()
+	return nil
+}
+
+func (v *Storage) load() error {
+	logger.Debugf("load certifications for [%s:%s]...", v.channel.Name(), v.namespace)
+	it, err := kvs.GetService(v.sp).GetByPartialCompositeID(certificationKeyPrefix, []string{v.channel.Name(), v.namespace})
+	if err != nil {
+		return errors.WithMessagef(err, "failed iterating over certifications")
+	}
+	var certified []*token.Id
+	legacy := false
+	for it.HasNext() {
+		var raw json.RawMessage
+		if err := it.Next(&raw); err != nil {
+			logger.Warnf("failed loading certification, skipping it [%s]", err)
+			continue
+		}
+		e, err := decodeEntry(raw)
+		if err != nil {
+			logger.Warnf("failed loading certification, skipping it [%s]", err)
+			continue
+		}
+		if e.ID == nil {
+			// a certification in the previous format, its id is in the key the iterator does not return
+			legacy = legacy || len(e.Certification) != 0
+			continue
+		}
+		if len(e.Certification) == 0 {
+			continue
+		}
+		certified = append(certified, e.ID)
+	}
+	if err := it.Close(); err != nil {
+		return errors.WithMessagef(err, "failed closing iterator")
+	}
+
+	// keep the certifications of the tokens still in the vault, garbage-collect the others
+	unspent, err := v.unspent(certified)
+	if err != nil {
+		return err
+	}
+	for i, id := range certified {
+		k := v.key(id)
+		if !unspent[i] {
+			if err := v.delete(k, id); err != nil {
+				return err
+			}
+			continue
+		}
+		v.cache.lock.Lock()
+		v.cache.ids[k] = true
+		v.cache.lock.Unlock()
+	}
+	v.cache.lock.Lock()
+	v.cache.loaded = true
+	v.cache.legacy = v.cache.legacy || legacy
+	v.cache.lock.Unlock()
+	logger.Debugf("load certifications for [%s:%s] done, [%d] loaded, legacy [%v]", v.channel.Name(), v.namespace, len(certified), legacy)
+	return nil
+}
+
+// certified checks in the KVS whether the passed token is certified, and caches the result
+func (v *Storage) certified(k string, id *token.Id) (bool, error) {
+	if !kvs.GetService(v.sp).Exists(k) {
+		return false, nil
+	}
+	e, err := v.get(k, id)
+	if err != nil {
+		return false, err
+	}
+	if len(e.Certification) == 0 {
+		return false, nil
+	}
+	v.cache.lock.Lock()
+	v.cache.ids[k] = true
+	v.cache.lock.Unlock()
+	return true, nil
+}
+
+// get returns the entry stored under the passed key, an entry in the previous format is migrated
+func (v *Storage) get(k string, id *token.Id) (*entry, error) {
+	var raw json.RawMessage
+	if err := kvs.GetService(v.sp).Get(k, &raw); err != nil {
+		return nil, errors.WithMessagef(err, "failed getting certification from storage for [%s]", k)
+	}
+	e, err := decodeEntry(raw)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed decoding certification for [%s]", k)
+	}
+	if e.ID == nil {
+		e.ID = id
+		if len(e.Certification) != 0 {
+			logger.Debugf("migrate certification of [%s]", id)
+			if err := kvs.GetService(v.sp).Put(k, e); err != nil {
+				return nil, errors.WithMessagef(err, "failed migrating certification for [%s]", k)
+			}
+		}
+	}
+	return e, nil
+}
+
+// decodeEntry decodes a stored entry, or a certification stored alone by the previous versions,
+// in which case the id of the returned entry is not set
+func decodeEntry(raw []byte) (*entry, error) {
+	e := &entry{}
+	if err := json.Unmarshal(raw, e); err == nil {
+		return e, nil
+	}
+	var certification []byte
+	if err := json.Unmarshal(raw, &certification); err != nil {
+		return nil, errors.Wrapf(err, "invalid certification entry")
+	}
+	return &entry{Certification: certification}, nil
+}
+
+// unspentInVault returns, for each passed id, whether the corresponding token is still in the vault
+func (v *Storage) unspentInVault(ids []*token.Id) ([]bool, error) {
+	res := make([]bool, len(ids))
+	if len(ids) == 0 {
+		return res, nil
+	}
+	qe, err := v.channel.Vault().NewQueryExecutor()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting query executor")
+	}
+	defer qe.Done()
+	for i, id := range ids {
+		outputID, err := keys.CreateFabtokenKey(id.TxId, int(id.Index))
+		if err != nil {
+			return nil, errors.Wrapf(err, "error creating output ID: %v", id)
+		}
+		raw, err := qe.GetState(v.namespace, outputID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed getting token [%v]", id)
+		}
+		res[i] = len(raw) != 0
+	}
+	return res, nil
+}
+
+func (v *Storage) key(id *token.Id) string {
+	return kvs.CreateCompositeKeyOrPanic(
+		certificationKeyPrefix,
+		[]string{
+			v.channel.Name(),
+			v.namespace,
+			id.TxId,
+			strconv.FormatUint(uint64(id.Index), 10),
+		},
+	)
+}
@@ -0,0 +1,144 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package certification
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/api"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// configProvider configures the in memory kvs
+type configProvider struct {
+	api.ConfigProvider
+}
+
+func (*configProvider) UnmarshalKey(string, interface{}) error {
+	return nil
+}
+
+type channel string
+
+func (c channel) Name() string {
+	return string(c)
+}
+
+func (channel) Vault() *fabric.Vault {
+	return nil
+}
+
+func newServiceProvider(t *testing.T) view2.ServiceProvider {
+	sp := registry.New()
+	assert.NoError(t, sp.RegisterService(&configProvider{}))
+	kvss, err := kvs.New("memory", "", sp)
+	assert.NoError(t, err)
+	assert.NoError(t, sp.RegisterService(kvss))
+	return sp
+}
+
+// newStorage returns a storage whose vault contains the passed unspent tokens
+func newStorage(sp view2.ServiceProvider, ch string, unspent ...*token.Id) *Storage {
+	s := NewStorage(sp, channel(ch), "ns")
+	s.unspent = func(ids []*token.Id) ([]bool, error) {
+		res := make([]bool, len(ids))
+		for i, id := range ids {
+			for _, u := range unspent {
+				res[i] = res[i] || u.String() == id.String()
+			}
+		}
+		return res, nil
+	}
+	return s
+}
+
+func TestStorage(t *testing.T) {
+	sp := newServiceProvider(t)
+	t1, t2, t3 := &token.Id{TxId: "tx1"}, &token.Id{TxId: "tx2"}, &token.Id{TxId: "tx3"}
+
+	s := newStorage(sp, "TestStorage", t1, t2)
+	assert.NoError(t, s.Store(map[*token.Id][]byte{t1: []byte("c1"), t2: []byte("c2")}))
+	assert.True(t, s.Exists(t1))
+	assert.True(t, s.Exists(t2))
+	assert.False(t, s.Exists(t3))
+
+	var got []string
+	assert.NoError(t, s.Get([]*token.Id{t1, t2}, func(id *token.Id, c []byte) error {
+		got = append(got, string(c))
+		return nil
+	}))
+	assert.Equal(t, []string{"c1", "c2"}, got)
+
+	// a spent token loses its certification
+	assert.NoError(t, s.Delete(t2, t3))
+	assert.False(t, s.Exists(t2))
+	assert.Error(t, s.Get([]*token.Id{t2}, func(*token.Id, []byte) error { return nil }))
+
+	// at restart, the certifications of the tokens no longer in the vault are dropped
+	assert.NoError(t, s.Store(map[*token.Id][]byte{t3: []byte("c3")}))
+	caches = map[string]*cache{}
+	s = newStorage(sp, "TestStorage", t1)
+	assert.NoError(t, s.Load())
+	assert.True(t, s.Exists(t1))
+	assert.False(t, s.Exists(t3))
+	assert.Error(t, s.Get([]*token.Id{t3}, func(*token.Id, []byte) error { return nil }))
+}
+
+func TestStorageDeleteDoesNotLoad(t *testing.T) {
+	sp := newServiceProvider(t)
+	t1 := &token.Id{TxId: "tx1"}
+	assert.NoError(t, newStorage(sp, "TestStorageDeleteDoesNotLoad", t1).Store(map[*token.Id][]byte{t1: []byte("c1")}))
+	caches = map[string]*cache{}
+
+	s := newStorage(sp, "TestStorageDeleteDoesNotLoad", t1)
+	s.unspent = func([]*token.Id) ([]bool, error) {
+		t.Fatal("the vault must not be queried")
+		return nil, nil
+	}
+	assert.NoError(t, s.Delete(t1))
+	assert.False(t, s.cache.loaded)
+	e, err := s.get(s.key(t1), t1)
+	assert.NoError(t, err)
+	assert.Empty(t, e.Certification)
+}
+
+func TestStorageMigration(t *testing.T) {
+	sp := newServiceProvider(t)
+	t1, t2 := &token.Id{TxId: "tx1"}, &token.Id{TxId: "tx2"}
+	s := newStorage(sp, "TestStorageMigration", t1, t2)
+
+	// the previous versions stored the certification alone
+	kvss := kvs.GetService(sp)
+	assert.NoError(t, kvss.Put(s.key(t1), []byte("c1")))
+	assert.NoError(t, kvss.Put(s.key(t2), []byte("c2")))
+
+	assert.NoError(t, s.Load())
+	assert.True(t, s.cache.legacy)
+	assert.True(t, s.Exists(t1))
+	assert.NoError(t, s.Get([]*token.Id{t2}, func(id *token.Id, c []byte) error {
+		assert.Equal(t, "c2", string(c))
+		return nil
+	}))
+
+	// the entries accessed are migrated
+	for _, id := range []*token.Id{t1, t2} {
+		e := &entry{}
+		assert.NoError(t, kvss.Get(s.key(id), e))
+		assert.Equal(t, id, e.ID)
+	}
+	caches = map[string]*cache{}
+	s = newStorage(sp, "TestStorageMigration", t1)
+	assert.NoError(t, s.Load())
+	assert.False(t, s.cache.legacy)
+	assert.True(t, s.Exists(t1))
+	assert.False(t, s.Exists(t2))
+}
@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package keys
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// reservedTxIDs are the first attributes of the keys, under TokenKeyPrefix, that are not token keys.
+// A transaction id equal to one of them could make a token key collide with one of those keys.
+var reservedTxIDs = map[string]bool{
+	SerialNumber:                  true,
+	TokenSetupKeyPrefix:           true,
+	TokenMineKeyPrefix:            true,
+	TokenRequestKeyPrefix:         true,
+	RejectedTokenRequestKeyPrefix: true,
+	BurnReceiptKeyPrefix:          true,
+	IssuerPolicyKeyPrefix:         true,
+	SupplyKeyPrefix:               true,
+}
+
+// ValidateTxID checks that the passed transaction id can be used to derive token keys, see CreateTokenKey.
+// A valid transaction id is a valid composite key attribute, not empty, and not reserved by the other keys
+// stored under TokenKeyPrefix.
+func ValidateTxID(txID string) error {
+	if len(txID) == 0 {
+		return errors.New("empty transaction id")
+	}
+	if reservedTxIDs[txID] {
+		return errors.Errorf("transaction id [%s] is reserved", txID)
+	}
+	return ValidateCompositeKeyAttribute(txID)
+}
+
+// ParseCompositeKey parses the passed composite key, as created by CreateCompositeKey, into its object type
+// and attributes. Unlike SplitCompositeKey, it rejects any key CreateCompositeKey cannot have created,
+// so that ParseCompositeKey(CreateCompositeKey(t, a)) returns t and a, and nothing else parses.
+func ParseCompositeKey(key string) (string, []string, error) {
+	if !strings.HasPrefix(key, CompositeKeyNamespace) {
+		return "", nil, errors.Errorf("invalid composite key [%q], missing namespace", key)
+	}
+	sep := string(rune(minUnicodeRuneValue))
+	if !strings.HasSuffix(key, sep) || len(key) < 2 {
+		return "", nil, errors.Errorf("invalid composite key [%q], missing separator", key)
+	}
+	components := strings.Split(key[1:len(key)-1], sep)
+	for _, component := range components {
+		if err := ValidateCompositeKeyAttribute(component); err != nil {
+			return "", nil, errors.WithMessagef(err, "invalid composite key [%q]", key)
+		}
+	}
+	if len(components[0]) == 0 {
+		return "", nil, errors.Errorf("invalid composite key [%q], empty object type", key)
+	}
+	return components[0], components[1:], nil
+}
+
+// ParseTokenKey returns the id of the token stored under the passed ledger key, as created by CreateTokenKey,
+// with the Default scheme.
+// It is the inverse of CreateTokenKey: a key that CreateTokenKey cannot have created is rejected,
+// and the returned id is the one the key has been created from.
+// External indexers can use it to reconstruct the ids of the tokens from the keys written on the ledger.
+func ParseTokenKey(key string) (*token2.Id, error) {
+	return Default.ParseTokenKey(key)
+}
+
+// IsTokenKey returns true if the passed ledger key is a token key, see ParseTokenKey
+func IsTokenKey(key string) bool {
+	return Default.IsTokenKey(key)
+}
+
+// parseIndex parses an index in its canonical decimal form, the one produced by strconv.Itoa
+func parseIndex(s string) (uint32, error) {
+	index, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, errors.Errorf("invalid index [%s]", s)
+	}
+	if strconv.FormatUint(index, 10) != s {
+		return 0, errors.Errorf("index [%s] not in canonical form", s)
+	}
+	return uint32(index), nil
+}
+
+func validateIndex(index int) error {
+	if index < 0 || uint64(index) > math.MaxUint32 {
+		return errors.Errorf("index [%d] out of range", index)
+	}
+	return nil
+}
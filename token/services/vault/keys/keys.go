@@ -16,26 +16,33 @@ import (
 )
 
 const (
-	minUnicodeRuneValue                = 0            //U+0000
-	MaxUnicodeRuneValue                = utf8.MaxRune //U+10FFFF - maximum (and unallocated) code point
-	CompositeKeyNamespace              = "\x00"
-	TokenKeyPrefix                     = "ztoken"
-	FabTokenKeyPrefix                  = "token"
-	AuditTokenKeyPrefix                = "audittoken"
-	TokenMineKeyPrefix                 = "mine"
-	TokenSetupKeyPrefix                = "setup"
-	IssuedHistoryTokenKeyPrefix        = "issued"
-	TokenAuditorKeyPrefix              = "auditor"
-	TokenNameSpace                     = "zkat"
-	numComponentsInKey                 = 2 // 2 components: txid, index, excluding TokenKeyPrefix
-	Action                             = "action"
-	ActionIssue                        = "issue"
-	ActionTransfer                     = "transfer"
-	Precision                   uint64 = 64
-	Info                               = "info"
-	TokenRequestKeyPrefix              = "token_request"
-	OwnerSeparator                     = "/"
-	SerialNumber                       = "sn"
+	minUnicodeRuneValue                  = 0            //U+0000
+	MaxUnicodeRuneValue                  = utf8.MaxRune //U+10FFFF - maximum (and unallocated) code point
+	CompositeKeyNamespace                = "\x00"
+	TokenKeyPrefix                       = "ztoken"
+	FabTokenKeyPrefix                    = "token"
+	AuditTokenKeyPrefix                  = "audittoken"
+	TokenMineKeyPrefix                   = "mine"
+	TokenSetupKeyPrefix                  = "setup"
+	IssuedHistoryTokenKeyPrefix          = "issued"
+	TokenAuditorKeyPrefix                = "auditor"
+	TokenNameSpace                       = "zkat"
+	numComponentsInKey                   = 2 // 2 components: txid, index, excluding TokenKeyPrefix
+	Action                               = "action"
+	ActionIssue                          = "issue"
+	ActionTransfer                       = "transfer"
+	ValidationParameter                  = "VALIDATION_PARAMETER" // metadata key of the state-based endorsement policy, as in Fabric
+	Precision                     uint64 = 64
+	Info                                 = "info"
+	TokenRequestKeyPrefix                = "token_request"
+	RejectedTokenRequestKeyPrefix        = "rejected_token_request"
+	IssuerPolicyKeyPrefix                = "issuer_policy"
+	SupplyKeyPrefix                      = "supply"
+	OwnerSeparator                       = "/"
+	SerialNumber                         = "sn"
+	EnrollmentIDKeyPrefix                = "eid"
+	EnrollmentID                         = "eid"
+	BurnReceiptKeyPrefix                 = "burn"
 )
 
 func GetTokenIdFromKey(key string) (*token2.Id, error) {
@@ -75,14 +82,15 @@ func SplitCompositeKey(compositeKey string) (string, []string, error) {
 }
 
 // CreateTokenKey Creates a rwset key for an individual output in a token transaction, as a function of
-// the token owner, transaction ID, and index of the output
+// the token owner, transaction ID, and index of the output.
+// Distinct valid pairs of transaction ID and index give distinct keys, see ValidateTxID and ParseTokenKey.
 // TODO: move index to uint32 of uint64
 func CreateTokenKey(txID string, index int) (string, error) {
-	return CreateCompositeKey(TokenKeyPrefix, []string{txID, strconv.Itoa(index)})
+	return Default.CreateTokenKey(txID, index)
 }
 
 func CreateSNKey(sn string) (string, error) {
-	return CreateCompositeKey(TokenKeyPrefix, []string{SerialNumber, sn})
+	return Default.CreateSNKey(sn)
 }
 
 // TODO: move index to uint32 of uint64
@@ -98,16 +106,69 @@ func CreateTokenMineKey(txID string, index int) (string, error) {
 	return CreateCompositeKey(TokenKeyPrefix, []string{TokenMineKeyPrefix, txID, strconv.Itoa(index)})
 }
 
+// CreateEnrollmentIDKey creates the key indexing the token with the passed id under the passed enrollment ID
+func CreateEnrollmentIDKey(eID string, txID string, index int) (string, error) {
+	return CreateCompositeKey(EnrollmentIDKeyPrefix, []string{eID, txID, strconv.Itoa(index)})
+}
+
+// CreateEnrollmentIDPrefixKey creates the prefix shared by all the keys indexing tokens under the passed enrollment ID
+func CreateEnrollmentIDPrefixKey(eID string) (string, error) {
+	return CreateCompositeKey(EnrollmentIDKeyPrefix, []string{eID})
+}
+
+// GetTokenIdFromEnrollmentIDKey returns the token id indexed by the passed enrollment ID key
+func GetTokenIdFromEnrollmentIDKey(key string) (*token2.Id, error) {
+	_, components, err := SplitCompositeKey(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error splitting enrollment id composite key")
+	}
+	// 3 components in key: enrollment id, txid, index
+	if len(components) != 3 {
+		return nil, errors.Errorf("invalid enrollment id composite key; expected 3 components, received '%s'", components)
+	}
+	index, err := strconv.Atoi(components[2])
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing output index '%s'", components[2])
+	}
+	return &token2.Id{TxId: components[1], Index: uint32(index)}, nil
+}
+
 func CreateSetupKey() (string, error) {
-	return CreateCompositeKey(TokenKeyPrefix, []string{TokenSetupKeyPrefix})
+	return Default.CreateSetupKey()
+}
+
+func CreateSetupVersionKey() (string, error) {
+	return Default.CreateSetupVersionKey()
+}
+
+func CreateVersionedSetupKey(version uint64) (string, error) {
+	return Default.CreateVersionedSetupKey(version)
 }
 
 func CreateSetupBundleKey() (string, error) {
-	return CreateCompositeKey(TokenKeyPrefix, []string{TokenSetupKeyPrefix, "bundle"})
+	return Default.CreateSetupBundleKey()
+}
+
+// CreateBurnReceiptKey creates the key of the burn receipt at the passed index of the token request of the passed transaction
+func CreateBurnReceiptKey(txID string, index int) (string, error) {
+	return Default.CreateBurnReceiptKey(txID, index)
 }
 
 func CreateTokenRequestKey(txID string) (string, error) {
-	return CreateCompositeKey(TokenKeyPrefix, []string{TokenRequestKeyPrefix, txID})
+	return Default.CreateTokenRequestKey(txID)
+}
+
+func CreateIssuerPolicyKey(tokenType string) (string, error) {
+	return Default.CreateIssuerPolicyKey(tokenType)
+}
+
+func CreateSupplyKey(tokenType string) (string, error) {
+	return Default.CreateSupplyKey(tokenType)
+}
+
+// CreateRejectedTokenRequestKey creates the key recording the rejection of the sub-request of a batch with the passed binding
+func CreateRejectedTokenRequestKey(binding string) (string, error) {
+	return Default.CreateRejectedTokenRequestKey(binding)
 }
 
 // CreateCompositeKey and its related functions and consts copied from core/chaincode/shim/chaincode.go
@@ -115,12 +176,12 @@ func CreateCompositeKey(objectType string, attributes []string) (string, error)
 	if err := ValidateCompositeKeyAttribute(objectType); err != nil {
 		return "", err
 	}
-	ck := CompositeKeyNamespace + objectType + string(minUnicodeRuneValue)
+	ck := CompositeKeyNamespace + objectType + string(rune(minUnicodeRuneValue))
 	for _, att := range attributes {
 		if err := ValidateCompositeKeyAttribute(att); err != nil {
 			return "", err
 		}
-		ck += att + string(minUnicodeRuneValue)
+		ck += att + string(rune(minUnicodeRuneValue))
 	}
 	return ck, nil
 }
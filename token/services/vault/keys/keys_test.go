@@ -0,0 +1,173 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package keys
+
+import (
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+func TestParseTokenKey(t *testing.T) {
+	key, err := CreateTokenKey("a1b2", 7)
+	assert.NoError(t, err)
+	id, err := ParseTokenKey(key)
+	assert.NoError(t, err)
+	assert.Equal(t, &token2.Id{TxId: "a1b2", Index: 7}, id)
+	assert.True(t, IsTokenKey(key))
+
+	// the other keys are not token keys
+	for _, create := range []func() (string, error){
+		func() (string, error) { return CreateSNKey("7") },
+		func() (string, error) { return CreateTokenRequestKey("a1b2") },
+		func() (string, error) { return CreateTokenMineKey("a1b2", 7) },
+		func() (string, error) { return CreateBurnReceiptKey("a1b2", 7) },
+		func() (string, error) { return CreateSetupKey() },
+		func() (string, error) { return CreateFabtokenKey("a1b2", 7) },
+	} {
+		key, err := create()
+		assert.NoError(t, err)
+		assert.False(t, IsTokenKey(key), "[%q] is not a token key", key)
+	}
+
+	// malformed keys
+	for _, key := range []string{
+		"",
+		"\x00",
+		"\x00\x00",
+		"ztoken\x00a1b2\x007\x00",
+		"\x00ztoken\x00a1b2\x007",
+		"\x00ztoken\x00a1b2\x00\x00",
+		"\x00ztoken\x00a1b2\x0007\x00",
+		"\x00ztoken\x00a1b2\x00+7\x00",
+		"\x00ztoken\x00a1b2\x00-7\x00",
+		"\x00ztoken\x00a1b2\x004294967296\x00",
+		"\x00ztoken\x00\x007\x00",
+		"\x00ztoken\x00a1b2\x007\x00x\x00",
+		"\x00ztoken\x00\xff\x007\x00",
+	} {
+		_, err := ParseTokenKey(key)
+		assert.Error(t, err, "[%q] is malformed", key)
+	}
+
+	// reserved transaction ids and out of range indices are rejected
+	_, err = CreateTokenKey(SerialNumber, 7)
+	assert.Error(t, err)
+	_, err = CreateTokenKey(TokenRequestKeyPrefix, 7)
+	assert.Error(t, err)
+	_, err = CreateTokenKey("", 7)
+	assert.Error(t, err)
+	_, err = CreateTokenKey("a1b2", -1)
+	assert.Error(t, err)
+}
+
+func TestTokenKeyRoundTrip(t *testing.T) {
+	// every valid pair of transaction id and index is recovered from its key
+	f := func(txID string, index uint32) bool {
+		key, err := CreateTokenKey(txID, int(index))
+		if err != nil {
+			return ValidateTxID(txID) != nil
+		}
+		id, err := ParseTokenKey(key)
+		return err == nil && id.TxId == txID && id.Index == index
+	}
+	assert.NoError(t, quick.Check(f, &quick.Config{MaxCount: 10000, Rand: rand.New(rand.NewSource(0))}))
+}
+
+func TestTokenKeyInjectivity(t *testing.T) {
+	// distinct pairs give distinct keys, the transaction ids are drawn from a small alphabet,
+	// including the separator of the attributes, to make collisions likely if any
+	r := rand.New(rand.NewSource(0))
+	alphabet := []rune{'a', '0', '1', '\x00', 'n', 's', MaxUnicodeRuneValue}
+	randomTxID := func() string {
+		n := r.Intn(4)
+		runes := make([]rune, n)
+		for i := range runes {
+			runes[i] = alphabet[r.Intn(len(alphabet))]
+		}
+		return string(runes)
+	}
+	seen := map[string]token2.Id{}
+	for i := 0; i < 20000; i++ {
+		id := token2.Id{TxId: randomTxID(), Index: uint32(r.Intn(12))}
+		key, err := CreateTokenKey(id.TxId, int(id.Index))
+		if err != nil {
+			continue
+		}
+		if other, ok := seen[key]; ok {
+			assert.Equal(t, other, id, "collision on [%q]", key)
+		}
+		seen[key] = id
+	}
+	assert.NotEmpty(t, seen)
+}
+
+func TestParseCompositeKey(t *testing.T) {
+	f := func(objectType string, attributes []string) bool {
+		key, err := CreateCompositeKey(objectType, attributes)
+		if err != nil || len(objectType) == 0 {
+			return true
+		}
+		ot, atts, err := ParseCompositeKey(key)
+		if err != nil || ot != objectType || len(atts) != len(attributes) {
+			return false
+		}
+		for i := range atts {
+			if atts[i] != attributes[i] {
+				return false
+			}
+		}
+		return true
+	}
+	assert.NoError(t, quick.Check(f, &quick.Config{MaxCount: 10000, Rand: rand.New(rand.NewSource(0))}))
+}
+
+func TestScheme(t *testing.T) {
+	scheme, err := NewScheme("")
+	assert.NoError(t, err)
+	assert.Equal(t, Default, scheme)
+
+	_, err = NewScheme("a\x00b")
+	assert.Error(t, err)
+
+	app1, err := NewScheme("app1")
+	assert.NoError(t, err)
+	app2, err := NewScheme("app2")
+	assert.NoError(t, err)
+
+	// the keys of different applications do not collide, and are not mistaken for each other
+	k1, err := app1.CreateTokenKey("a1b2", 7)
+	assert.NoError(t, err)
+	k2, err := app2.CreateTokenKey("a1b2", 7)
+	assert.NoError(t, err)
+	k, err := CreateTokenKey("a1b2", 7)
+	assert.NoError(t, err)
+	assert.NotEqual(t, k1, k2)
+	assert.NotEqual(t, k1, k)
+	assert.True(t, app1.IsTokenKey(k1))
+	assert.False(t, app1.IsTokenKey(k2))
+	assert.False(t, IsTokenKey(k1))
+	assert.True(t, app1.Owns(k1))
+	assert.False(t, app1.Owns(k))
+
+	// keys are migrated between schemes preserving their attributes
+	migrated, err := app1.Rekey(k, Default)
+	assert.NoError(t, err)
+	assert.Equal(t, k1, migrated)
+	_, err = app1.Rekey(k2, Default)
+	assert.Error(t, err)
+	setupKey, err := CreateSetupKey()
+	assert.NoError(t, err)
+	migrated, err = app1.Rekey(setupKey, Default)
+	assert.NoError(t, err)
+	expected, err := app1.CreateSetupKey()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, migrated)
+}
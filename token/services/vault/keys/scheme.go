@@ -0,0 +1,143 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package keys
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// Default is the scheme of the deployments that do not configure an application, see NewScheme
+var Default = &Scheme{prefix: TokenKeyPrefix}
+
+// Scheme derives the ledger keys of a token application: its tokens, serial numbers, token requests,
+// burn receipts, and public parameters. All of them share the object type returned by Prefix.
+// Token applications sharing the same namespace must use schemes with different prefixes, so that their keys do not collide.
+type Scheme struct {
+	prefix string
+}
+
+// NewScheme returns the scheme of the passed application, its prefix is TokenKeyPrefix followed by a dot and the application.
+// The empty application gives the Default scheme.
+func NewScheme(application string) (*Scheme, error) {
+	if len(application) == 0 {
+		return Default, nil
+	}
+	if err := ValidateCompositeKeyAttribute(application); err != nil {
+		return nil, errors.WithMessagef(err, "invalid application [%s]", application)
+	}
+	return &Scheme{prefix: TokenKeyPrefix + "." + application}, nil
+}
+
+// Prefix returns the object type of the keys of this scheme
+func (s *Scheme) Prefix() string {
+	return s.prefix
+}
+
+// CreateTokenKey creates the key of the output at the passed index of the passed transaction, see keys.CreateTokenKey
+func (s *Scheme) CreateTokenKey(txID string, index int) (string, error) {
+	if err := ValidateTxID(txID); err != nil {
+		return "", err
+	}
+	if err := validateIndex(index); err != nil {
+		return "", err
+	}
+	return CreateCompositeKey(s.prefix, []string{txID, strconv.Itoa(index)})
+}
+
+func (s *Scheme) CreateSNKey(sn string) (string, error) {
+	return CreateCompositeKey(s.prefix, []string{SerialNumber, sn})
+}
+
+func (s *Scheme) CreateSetupKey() (string, error) {
+	return CreateCompositeKey(s.prefix, []string{TokenSetupKeyPrefix})
+}
+
+// CreateSetupVersionKey creates the key of the version of the current public parameters
+func (s *Scheme) CreateSetupVersionKey() (string, error) {
+	return CreateCompositeKey(s.prefix, []string{TokenSetupKeyPrefix, "version"})
+}
+
+// CreateVersionedSetupKey creates the key of the public parameters with the passed version.
+// The public parameters are never modified under this key, they are deleted once superseded by the next version.
+func (s *Scheme) CreateVersionedSetupKey(version uint64) (string, error) {
+	return CreateCompositeKey(s.prefix, []string{TokenSetupKeyPrefix, "v" + strconv.FormatUint(version, 10)})
+}
+
+func (s *Scheme) CreateSetupBundleKey() (string, error) {
+	return CreateCompositeKey(s.prefix, []string{TokenSetupKeyPrefix, "bundle"})
+}
+
+func (s *Scheme) CreateBurnReceiptKey(txID string, index int) (string, error) {
+	return CreateCompositeKey(s.prefix, []string{BurnReceiptKeyPrefix, txID, strconv.Itoa(index)})
+}
+
+func (s *Scheme) CreateTokenRequestKey(txID string) (string, error) {
+	return CreateCompositeKey(s.prefix, []string{TokenRequestKeyPrefix, txID})
+}
+
+// CreateIssuerPolicyKey creates the key of the issuer policy of the passed token type
+func (s *Scheme) CreateIssuerPolicyKey(tokenType string) (string, error) {
+	return CreateCompositeKey(s.prefix, []string{IssuerPolicyKeyPrefix, tokenType})
+}
+
+// CreateSupplyKey creates the key of the total value issued of the passed token type
+func (s *Scheme) CreateSupplyKey(tokenType string) (string, error) {
+	return CreateCompositeKey(s.prefix, []string{SupplyKeyPrefix, tokenType})
+}
+
+func (s *Scheme) CreateRejectedTokenRequestKey(binding string) (string, error) {
+	return CreateCompositeKey(s.prefix, []string{RejectedTokenRequestKeyPrefix, binding})
+}
+
+// ParseTokenKey returns the id of the token stored under the passed ledger key, see keys.ParseTokenKey
+func (s *Scheme) ParseTokenKey(key string) (*token2.Id, error) {
+	objectType, attributes, err := ParseCompositeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if objectType != s.prefix {
+		return nil, errors.Errorf("invalid token key [%q], unexpected object type [%s]", key, objectType)
+	}
+	if len(attributes) != numComponentsInKey {
+		return nil, errors.Errorf("invalid token key [%q], expected [%d] components, got [%d]", key, numComponentsInKey, len(attributes))
+	}
+	if err := ValidateTxID(attributes[0]); err != nil {
+		return nil, errors.WithMessagef(err, "invalid token key [%q]", key)
+	}
+	index, err := parseIndex(attributes[1])
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid token key [%q]", key)
+	}
+	return &token2.Id{TxId: attributes[0], Index: index}, nil
+}
+
+func (s *Scheme) IsTokenKey(key string) bool {
+	_, err := s.ParseTokenKey(key)
+	return err == nil
+}
+
+// Owns returns true if the passed key has been derived by this scheme
+func (s *Scheme) Owns(key string) bool {
+	objectType, _, err := ParseCompositeKey(key)
+	return err == nil && objectType == s.prefix
+}
+
+// Rekey returns the key of this scheme corresponding to the passed key of the passed scheme,
+// it is used to migrate the keys of an existing deployment to a new scheme
+func (s *Scheme) Rekey(key string, from *Scheme) (string, error) {
+	objectType, attributes, err := ParseCompositeKey(key)
+	if err != nil {
+		return "", err
+	}
+	if objectType != from.prefix {
+		return "", errors.Errorf("key [%q] does not belong to scheme [%s]", key, from.prefix)
+	}
+	return CreateCompositeKey(s.prefix, attributes)
+}
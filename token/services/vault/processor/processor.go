@@ -7,6 +7,7 @@ package processor
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
 	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
@@ -14,7 +15,12 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/history"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/certification"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 var logger = flogging.MustGetLogger("token-sdk.vault.processor")
@@ -27,13 +33,44 @@ type RWSetProcessor struct {
 	network Network
 	nss     []string
 	sp      view2.ServiceProvider
+	keys    *keys.Scheme
+	light   bool
+	// transcripts, if set, makes the processor record the verification transcripts of the known transactions
+	transcripts bool
 }
 
-func NewTokenRWSetProcessor(network Network, ns string, sp view2.ServiceProvider) *RWSetProcessor {
+// NewTokenRWSetProcessor returns a processor of the token transactions of the passed namespace,
+// whose ledger keys are derived with the passed scheme, keys.Default if nil
+func NewTokenRWSetProcessor(network Network, ns string, sp view2.ServiceProvider, scheme *keys.Scheme) *RWSetProcessor {
+	if scheme == nil {
+		scheme = keys.Default
+	}
 	return &RWSetProcessor{
 		network: network,
 		nss:     []string{ns},
-		sp:      sp}
+		sp:      sp,
+		keys:    scheme,
+	}
+}
+
+// WithLightMode makes the processor keep in the vault only the tokens owned by this node.
+// The ledger state of the other tokens, and the token requests of the transactions this node is not involved in,
+// are dropped from the rwset before it is committed. The tokens of the others can still be fetched from the
+// chaincode, see the queryTokens function.
+// Light mode only reduces the storage of the vault: the node still processes all the transactions of the namespace,
+// as committed by its peer, it does not track its tokens by targeted queries or event subscriptions.
+func (r *RWSetProcessor) WithLightMode(light bool) *RWSetProcessor {
+	r.light = light
+	return r
+}
+
+// WithTranscripts makes the processor record the verification transcript of the token request of each transaction
+// known to this node, see token.ManagementService.Transcript. The request is verified before the rwset is committed,
+// against the state of the vault the transaction is committed against. A failure is logged, the transaction is
+// committed anyway.
+func (r *RWSetProcessor) WithTranscripts(transcripts bool) *RWSetProcessor {
+	r.transcripts = transcripts
+	return r
 }
 
 func (r *RWSetProcessor) Process(req fabric.Request, tx fabric.ProcessTransaction, rws *fabric.RWSet, ns string) error {
@@ -60,7 +97,7 @@ func (r *RWSetProcessor) Process(req fabric.Request, tx fabric.ProcessTransactio
 
 func (r *RWSetProcessor) setup(req fabric.Request, tx fabric.ProcessTransaction, rws *fabric.RWSet, ns string) error {
 	logger.Debugf("[setup] store setup bundle")
-	key, err := keys.CreateSetupBundleKey()
+	key, err := r.keys.CreateSetupBundleKey()
 	if err != nil {
 		return err
 	}
@@ -72,9 +109,51 @@ func (r *RWSetProcessor) setup(req fabric.Request, tx fabric.ProcessTransaction,
 	}
 	logger.Debugf("[setup] store setup bundle done")
 
+	r.checkPublicParams(tx, rws, ns)
 	return nil
 }
 
+// checkPublicParams checks the public parameters set by the passed transaction against the pinned ones, if any.
+// A mismatch is reported to the change handlers of the public parameters cache, the transaction is committed anyway.
+func (r *RWSetProcessor) checkPublicParams(tx fabric.ProcessTransaction, rws *fabric.RWSet, ns string) {
+	cache := core.GetPublicParamsCache(r.sp)
+	if cache == nil {
+		return
+	}
+	setupKey, err := r.keys.CreateSetupKey()
+	if err != nil {
+		logger.Errorf("failed creating setup key [%s]", err)
+		return
+	}
+	for i := 0; i < rws.NumWrites(ns); i++ {
+		key, raw, err := rws.GetWriteAt(ns, i)
+		if err != nil {
+			logger.Errorf("failed reading write [%d] of [%s]: [%s]", i, tx.ID(), err)
+			return
+		}
+		if key != setupKey {
+			continue
+		}
+		if err := cache.Check(tx.Network(), tx.Channel(), ns, raw); err != nil {
+			logger.Warnf("[setup] public parameters set by [%s]: [%s]", tx.ID(), err)
+		}
+		return
+	}
+}
+
+// skip updates the vault for a transaction whose tokens cannot be extracted, it stores the synced tokens it does
+// not spend, see SyncedToken, and drops the writes of the others in light mode
+func (r *RWSetProcessor) skip(tx fabric.ProcessTransaction, rws *fabric.RWSet, ns string) error {
+	spent, err := r.deletedTokens(rws, ns)
+	if err != nil {
+		return err
+	}
+	if err := r.storeSyncedTokens(tx.Network(), tx.Channel(), ns, rws, spent); err != nil {
+		return err
+	}
+	return r.prune(tx.ID(), rws, ns, nil)
+}
+
 func (r *RWSetProcessor) tokenRequest(req fabric.Request, tx fabric.ProcessTransaction, rws *fabric.RWSet, ns string) error {
 	txID := tx.ID()
 
@@ -84,7 +163,7 @@ func (r *RWSetProcessor) tokenRequest(req fabric.Request, tx fabric.ProcessTrans
 	}
 	if !ch.MetadataService().Exists(txID) {
 		logger.Debugf("transaction [%s] is not known to this node, no need to extract tokens", txID)
-		return nil
+		return r.skip(tx, rws, ns)
 	}
 
 	logger.Debugf("transaction [%s] is known, extract tokens", txID)
@@ -96,7 +175,7 @@ func (r *RWSetProcessor) tokenRequest(req fabric.Request, tx fabric.ProcessTrans
 	}
 	if !transientMap.Exists("zkat") {
 		logger.Debugf("transaction [%s], no transient map found", txID)
-		return nil
+		return r.skip(tx, rws, ns)
 	}
 
 	tms := token.GetManagementService(
@@ -110,13 +189,22 @@ func (r *RWSetProcessor) tokenRequest(req fabric.Request, tx fabric.ProcessTrans
 		logger.Debugf("transaction [%s], failed getting zkat state from transient map [%s]", txID, err)
 		return err
 	}
+	if r.transcripts {
+		r.recordTranscript(tms, txID, rws, ns)
+	}
 
+	var spent []*token2.Id
+	var records []*history.Record
+	// the outputs owned by this node, kept in light mode
+	mine := map[string]bool{}
 	if tms.PublicParametersManager().GraphHiding() {
 		// Delete inputs
 		for _, id := range metadata.SpentTokenID() {
+			records = r.appendSpentRecord(records, tms, metadata, ns, txID, id, rws)
 			if err := r.deleteFabToken(ns, id.TxId, int(id.Index), rws); err != nil {
 				return err
 			}
+			spent = append(spent, id)
 		}
 	}
 
@@ -130,8 +218,8 @@ func (r *RWSetProcessor) tokenRequest(req fabric.Request, tx fabric.ProcessTrans
 		if err != nil {
 			panic(err)
 		}
-		if prefix != keys.TokenKeyPrefix {
-			logger.Debugf("expected prefix [%s], got [%s], skipping", keys.TokenKeyPrefix, prefix)
+		if prefix != r.keys.Prefix() {
+			logger.Debugf("expected prefix [%s], got [%s], skipping", r.keys.Prefix(), prefix)
 			continue
 		}
 		switch components[0] {
@@ -144,6 +232,21 @@ func (r *RWSetProcessor) tokenRequest(req fabric.Request, tx fabric.ProcessTrans
 		case keys.SerialNumber:
 			logger.Debugf("expected key without the serial number prefix, skipping")
 			continue
+		case keys.BurnReceiptKeyPrefix:
+			logger.Debugf("expected key without the burn receipt prefix, skipping")
+			continue
+		case keys.RejectedTokenRequestKeyPrefix:
+			logger.Debugf("expected key without the rejected token request prefix, skipping")
+			continue
+		case keys.TokenSetupKeyPrefix:
+			logger.Debugf("expected key without the setup prefix, skipping")
+			continue
+		case keys.IssuerPolicyKeyPrefix:
+			logger.Debugf("expected key without the issuer policy prefix, skipping")
+			continue
+		case keys.SupplyKeyPrefix:
+			logger.Debugf("expected key without the supply prefix, skipping")
+			continue
 		}
 
 		index, err := strconv.Atoi(components[1])
@@ -154,9 +257,11 @@ func (r *RWSetProcessor) tokenRequest(req fabric.Request, tx fabric.ProcessTrans
 
 		// This is a delete, add a delete for fabtoken
 		if len(val) == 0 {
+			records = r.appendSpentRecord(records, tms, metadata, ns, txID, &token2.Id{TxId: components[0], Index: uint32(index)}, rws)
 			if err := r.deleteFabToken(ns, components[0], index, rws); err != nil {
 				return err
 			}
+			spent = append(spent, &token2.Id{TxId: components[0], Index: uint32(index)})
 			continue
 		}
 
@@ -176,9 +281,26 @@ func (r *RWSetProcessor) tokenRequest(req fabric.Request, tx fabric.ProcessTrans
 			logger.Warnf("failed getting token in the clear for key [%s, %s]", key, string(val))
 			continue
 		}
+		eID, err := metadata.GetEnrollmentID(val)
+		if errors.Is(err, api.ErrEncryptedAuditInfo) {
+			logger.Debugf("transaction [%s], the audit info of key [%s] is encrypted for the auditor, the token will not be indexed", txID, key)
+		} else if err != nil {
+			logger.Warnf("transaction [%s], failed getting enrollment id for key [%s], the token will not be indexed [%s]", txID, key, err)
+		}
 
-		if tms.WalletManager().OwnerWalletByIdentity(tok.Owner.Raw) != nil {
+		if wallet := tms.WalletManager().OwnerWalletByIdentity(tok.Owner.Raw); wallet != nil {
 			logger.Debugf("transaction [%s], found a token and it is mine", txID)
+			mine[key] = true
+			records = append(records, &history.Record{
+				TokenID:        &token2.Id{TxId: txID, Index: uint32(index)},
+				Direction:      history.Received,
+				TxID:           txID,
+				Wallet:         wallet.ID(),
+				Type:           tok.Type,
+				Quantity:       tok.Quantity,
+				Counterparties: counterparties(metadata.SenderEnrollmentIDs(), wallet.EnrollmentID()),
+				Timestamp:      time.Now(),
+			})
 			// Add a lookup key to identity quickly that this token belongs to this
 			mineTokenID, err := keys.CreateTokenMineKey(components[0], index)
 			if err != nil {
@@ -190,12 +312,12 @@ func (r *RWSetProcessor) tokenRequest(req fabric.Request, tx fabric.ProcessTrans
 			}
 
 			// Store Fabtoken-like entry
-			if err := r.storeFabToken(ns, txID, index, tok, rws, tokenInfoRaw); err != nil {
+			if err := r.storeFabToken(ns, txID, index, tok, rws, tokenInfoRaw, eID); err != nil {
 				return err
 			}
-		} else {
+		} else if !r.light {
 			logger.Debugf("transaction [%s], found a token and I must be the auditor", txID)
-			if err := r.storeAuditToken(ns, txID, index, tok, rws, tokenInfoRaw); err != nil {
+			if err := r.storeAuditToken(ns, txID, index, tok, rws, tokenInfoRaw, eID); err != nil {
 				return err
 			}
 		}
@@ -209,6 +331,26 @@ func (r *RWSetProcessor) tokenRequest(req fabric.Request, tx fabric.ProcessTrans
 
 		logger.Debugf("Done parsing write key [%s]", key)
 	}
+	if err := r.storeSyncedTokens(tx.Network(), tx.Channel(), ns, rws, spent); err != nil {
+		return err
+	}
+	if err := r.prune(txID, rws, ns, mine); err != nil {
+		return err
+	}
+	if hs := history.GetService(r.sp); hs != nil && len(records) != 0 {
+		if err := hs.Append(records...); err != nil {
+			logger.Warnf("transaction [%s], failed recording history [%s]", txID, err)
+		}
+	}
+	// the transaction is not committed yet, the events are published once it is final
+	tms.PublishBalanceEvents(txID, ch.Finality(), balanceEvents(records)...)
+	tms.ReleaseSpentReservations(txID, ch.Finality(), spent...)
+	// Garbage-collect the certifications of the spent tokens
+	if len(spent) != 0 {
+		if err := certification.NewStorage(r.sp, ch, ns).Delete(spent...); err != nil {
+			logger.Warnf("transaction [%s], failed deleting certifications of spent tokens [%s]", txID, err)
+		}
+	}
 	logger.Debugf("transaction [%s] is known, extract tokens, done!", txID)
 
 	return nil
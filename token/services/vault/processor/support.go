@@ -7,12 +7,16 @@ package processor
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
 
+	"github.com/hyperledger-labs/fabric-token-sdk/token"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/atrest"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/history"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
@@ -22,6 +26,9 @@ func (r *RWSetProcessor) deleteFabToken(ns string, txID string, index int, rws *
 	if err != nil {
 		return errors.Wrapf(err, "error creating output ID: %s", err)
 	}
+	if err := r.deleteEnrollmentIDIndex(ns, txID, index, rws); err != nil {
+		return err
+	}
 	logger.Debugf("delete key [%s]", outputID)
 	err = rws.DeleteState(ns, outputID)
 	if err != nil {
@@ -34,7 +41,7 @@ func (r *RWSetProcessor) deleteFabToken(ns string, txID string, index int, rws *
 	return nil
 }
 
-func (r *RWSetProcessor) storeFabToken(ns string, txID string, index int, tok *token2.Token, rws *fabric.RWSet, infoRaw []byte) error {
+func (r *RWSetProcessor) storeFabToken(ns string, txID string, index int, tok *token2.Token, rws *fabric.RWSet, infoRaw []byte, eID string) error {
 	outputID, err := keys.CreateFabtokenKey(txID, index)
 	if err != nil {
 		return errors.Wrapf(err, "error creating output ID: %s", err)
@@ -46,10 +53,14 @@ func (r *RWSetProcessor) storeFabToken(ns string, txID string, index int, tok *t
 	if err := rws.SetState(ns, outputID, raw); err != nil {
 		return err
 	}
-	if err := rws.SetStateMetadata(ns, outputID, map[string][]byte{keys.Info: infoRaw}); err != nil {
+	infoRaw, err = r.sealInfo(outputID, infoRaw)
+	if err != nil {
 		return err
 	}
-	return nil
+	if err := rws.SetStateMetadata(ns, outputID, map[string][]byte{keys.Info: infoRaw, keys.EnrollmentID: []byte(eID)}); err != nil {
+		return err
+	}
+	return r.storeEnrollmentIDIndex(ns, txID, index, eID, rws)
 }
 
 func (r *RWSetProcessor) storeIssuedHistoryToken(ns string, txID string, index int, tok *token2.Token, rws *fabric.RWSet, infoRaw []byte, issuer view.Identity) error {
@@ -85,13 +96,17 @@ func (r *RWSetProcessor) storeIssuedHistoryToken(ns string, txID string, index i
 	if err := rws.SetState(ns, outputID, raw); err != nil {
 		return err
 	}
+	infoRaw, err = r.sealInfo(outputID, infoRaw)
+	if err != nil {
+		return err
+	}
 	if err := rws.SetStateMetadata(ns, outputID, map[string][]byte{keys.Info: infoRaw}); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *RWSetProcessor) storeAuditToken(ns string, txID string, index int, tok *token2.Token, rws *fabric.RWSet, infoRaw []byte) error {
+func (r *RWSetProcessor) storeAuditToken(ns string, txID string, index int, tok *token2.Token, rws *fabric.RWSet, infoRaw []byte, eID string) error {
 	outputID, err := keys.CreateAuditTokenKey(txID, index)
 	if err != nil {
 		return errors.Wrapf(err, "error creating output ID: %s", err)
@@ -103,12 +118,198 @@ func (r *RWSetProcessor) storeAuditToken(ns string, txID string, index int, tok
 	if err := rws.SetState(ns, outputID, raw); err != nil {
 		return err
 	}
-	if err := rws.SetStateMetadata(ns, outputID, map[string][]byte{keys.Info: infoRaw}); err != nil {
+	infoRaw, err = r.sealInfo(outputID, infoRaw)
+	if err != nil {
 		return err
 	}
+	if err := rws.SetStateMetadata(ns, outputID, map[string][]byte{keys.Info: infoRaw, keys.EnrollmentID: []byte(eID)}); err != nil {
+		return err
+	}
+	return r.storeEnrollmentIDIndex(ns, txID, index, eID, rws)
+}
+
+// sealInfo seals the passed token information, the openings of the token stored under the passed key,
+// before it is persisted in the vault, in the context of the key.
+// The token information is stored with the ledger transaction, it keeps the key encryption key it is sealed under.
+func (r *RWSetProcessor) sealInfo(outputID string, infoRaw []byte) ([]byte, error) {
+	sealed, err := atrest.GetSealer(r.sp).Seal(infoRaw, []byte(outputID))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed sealing token information of [%s]", outputID)
+	}
+	return sealed, nil
+}
+
+// storeEnrollmentIDIndex indexes the token with the passed id under the enrollment ID of its owner
+func (r *RWSetProcessor) storeEnrollmentIDIndex(ns string, txID string, index int, eID string, rws *fabric.RWSet) error {
+	if len(eID) == 0 {
+		return nil
+	}
+	key, err := keys.CreateEnrollmentIDKey(eID, txID, index)
+	if err != nil {
+		return errors.Wrapf(err, "error creating enrollment id key: [%s,%s,%d]", eID, txID, index)
+	}
+	logger.Debugf("transaction [%s], index output [%d] under enrollment id [%s]", txID, index, eID)
+	return rws.SetState(ns, key, []byte{1})
+}
+
+// deleteEnrollmentIDIndex removes the token with the passed id from the enrollment ID index, if indexed.
+// The enrollment ID is recovered from the metadata of the stored token.
+func (r *RWSetProcessor) deleteEnrollmentIDIndex(ns string, txID string, index int, rws *fabric.RWSet) error {
+	fabtokenKey, err := keys.CreateFabtokenKey(txID, index)
+	if err != nil {
+		return errors.Wrapf(err, "error creating output ID: [%s,%d]", txID, index)
+	}
+	auditTokenKey, err := keys.CreateAuditTokenKey(txID, index)
+	if err != nil {
+		return errors.Wrapf(err, "error creating output ID: [%s,%d]", txID, index)
+	}
+	for _, outputID := range []string{fabtokenKey, auditTokenKey} {
+		meta, err := rws.GetStateMetadata(ns, outputID)
+		if err != nil {
+			return errors.Wrapf(err, "failed getting metadata for [%s]", outputID)
+		}
+		eID := string(meta[keys.EnrollmentID])
+		if len(eID) == 0 {
+			continue
+		}
+		key, err := keys.CreateEnrollmentIDKey(eID, txID, index)
+		if err != nil {
+			return errors.Wrapf(err, "error creating enrollment id key: [%s,%s,%d]", eID, txID, index)
+		}
+		logger.Debugf("delete enrollment id key [%s]", key)
+		return rws.DeleteState(ns, key)
+	}
+	return nil
+}
+
+// prune drops from the passed rwset, in light mode, the outputs it writes that are not in the passed
+// set of owned outputs. A nil set marks a transaction this node is not involved in, its token request is dropped too.
+func (r *RWSetProcessor) prune(txID string, rws *fabric.RWSet, ns string, mine map[string]bool) error {
+	if !r.light {
+		return nil
+	}
+	// collect first, overwriting a key appends a write to the rwset
+	var dropped []string
+	for i := 0; i < rws.NumWrites(ns); i++ {
+		key, val, err := rws.GetWriteAt(ns, i)
+		if err != nil {
+			return err
+		}
+		if len(val) == 0 || mine[key] || !r.keys.IsTokenKey(key) {
+			continue
+		}
+		dropped = append(dropped, key)
+	}
+	if mine == nil {
+		requestKey, err := r.keys.CreateTokenRequestKey(txID)
+		if err != nil {
+			return errors.Wrapf(err, "failed computing token request key of [%s]", txID)
+		}
+		dropped = append(dropped, requestKey)
+	}
+	for _, key := range dropped {
+		if err := rws.DeleteState(ns, key); err != nil {
+			return errors.Wrapf(err, "failed dropping [%s] of [%s]", key, txID)
+		}
+	}
+	logger.Debugf("transaction [%s], light mode, dropped [%d] keys", txID, len(dropped))
 	return nil
 }
 
+// recordTranscript records the verification transcript of the token request written by the passed transaction.
+// The request is verified against the state of the vault before the commit of the rwset. The reads of the keys
+// in the read set of the transaction fail if their version differs from the one read at endorsement.
+func (r *RWSetProcessor) recordTranscript(tms *token.ManagementService, txID string, rws *fabric.RWSet, ns string) {
+	requestKey, err := r.keys.CreateTokenRequestKey(txID)
+	if err != nil {
+		logger.Warnf("transaction [%s], failed computing token request key [%s]", txID, err)
+		return
+	}
+	for i := 0; i < rws.NumWrites(ns); i++ {
+		key, raw, err := rws.GetWriteAt(ns, i)
+		if err != nil {
+			logger.Warnf("transaction [%s], failed reading write [%d] [%s]", txID, i, err)
+			return
+		}
+		if key != requestKey {
+			continue
+		}
+		getState := func(key string) ([]byte, error) {
+			return rws.GetState(ns, key)
+		}
+		if err := tms.RecordTranscript(txID, raw, getState); err != nil {
+			logger.Warnf("transaction [%s], failed recording transcript [%s]", txID, err)
+		}
+		return
+	}
+	logger.Debugf("transaction [%s], no token request to record", txID)
+}
+
+// appendSpentRecord appends to the passed records the history record of the passed token, spent by the passed
+// transaction, if the token is owned by a local wallet
+func (r *RWSetProcessor) appendSpentRecord(records []*history.Record, tms *token.ManagementService, metadata *token.Metadata, ns string, txID string, id *token2.Id, rws *fabric.RWSet) []*history.Record {
+	outputID, err := keys.CreateFabtokenKey(id.TxId, int(id.Index))
+	if err != nil {
+		logger.Warnf("transaction [%s], failed creating output ID for [%s] [%s]", txID, id, err)
+		return records
+	}
+	raw, err := rws.GetState(ns, outputID)
+	if err != nil || len(raw) == 0 {
+		// not a token of this node
+		return records
+	}
+	tok := &token2.Token{}
+	if err := json.Unmarshal(raw, tok); err != nil {
+		logger.Warnf("transaction [%s], failed unmarshalling spent token [%s] [%s]", txID, id, err)
+		return records
+	}
+	wallet := tms.WalletManager().OwnerWalletByIdentity(tok.Owner.Raw)
+	if wallet == nil {
+		return records
+	}
+	return append(records, &history.Record{
+		TokenID:        id,
+		Direction:      history.Spent,
+		TxID:           txID,
+		Wallet:         wallet.ID(),
+		Type:           tok.Type,
+		Quantity:       tok.Quantity,
+		Counterparties: counterparties(metadata.ReceiverEnrollmentIDs(), wallet.EnrollmentID()),
+		Timestamp:      time.Now(),
+	})
+}
+
+// balanceEvents returns the balance events of the passed history records
+func balanceEvents(records []*history.Record) []*token.BalanceEvent {
+	events := make([]*token.BalanceEvent, len(records))
+	for i, record := range records {
+		change := token.TokenReceived
+		if record.Direction == history.Spent {
+			change = token.TokenSpent
+		}
+		events[i] = &token.BalanceEvent{
+			Wallet:   record.Wallet,
+			Change:   change,
+			TokenID:  record.TokenID,
+			Type:     record.Type,
+			Quantity: record.Quantity,
+			TxID:     record.TxID,
+		}
+	}
+	return events
+}
+
+// counterparties returns the passed enrollment IDs but the one of the local wallet
+func counterparties(eIDs []string, self string) []string {
+	var res []string
+	for _, eID := range eIDs {
+		if eID != self {
+			res = append(res, eID)
+		}
+	}
+	return res
+}
+
 func MarshalOrPanic(o interface{}) []byte {
 	data, err := json.Marshal(o)
 	if err != nil {
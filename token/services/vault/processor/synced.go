@@ -0,0 +1,139 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package processor
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	view2 "github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/atrest"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// SyncedTokensPrefix is the kvs prefix of the synced tokens waiting to be stored in the vault
+const SyncedTokensPrefix = "token-sdk.vault.synced"
+
+// SyncedToken is a token owned by a local wallet but created by a transaction this node was not involved in,
+// like a token received by another node sharing the wallet before the two were paired, see ttxcc.ShareWalletView.
+// The vault is updated only when a transaction commits, then the synced tokens of a namespace are stored
+// together with the next transaction committed in it, unless that transaction spends them.
+type SyncedToken struct {
+	ID           *token2.Id
+	Token        *token2.Token
+	Info         []byte
+	EnrollmentID string
+	// Done is set once the token has been handled, the kvs does not delete entries
+	Done bool
+}
+
+// AddSyncedTokens records the passed tokens to be stored in the vault of the passed namespace
+func AddSyncedTokens(sp view2.ServiceProvider, network, channel, namespace string, tokens ...*SyncedToken) error {
+	for _, t := range tokens {
+		if t == nil || t.ID == nil || t.Token == nil {
+			return errors.Errorf("invalid synced token, it is empty")
+		}
+		if err := atrest.Put(sp, syncedTokenKey(network, channel, namespace, t.ID), t); err != nil {
+			return errors.WithMessagef(err, "failed storing synced token [%s]", t.ID)
+		}
+	}
+	return nil
+}
+
+// SyncedTokens returns the synced tokens of the passed namespace waiting to be stored in the vault
+func SyncedTokens(sp view2.ServiceProvider, network, channel, namespace string) ([]*SyncedToken, error) {
+	it, err := kvs.GetService(sp).GetByPartialCompositeID(SyncedTokensPrefix, []string{network, channel, namespace})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed iterating over the synced tokens of [%s:%s:%s]", network, channel, namespace)
+	}
+	defer it.Close()
+
+	var res []*SyncedToken
+	for it.HasNext() {
+		var raw json.RawMessage
+		if err := it.Next(&raw); err != nil {
+			return nil, errors.WithMessagef(err, "failed reading synced token")
+		}
+		t := &SyncedToken{}
+		k, err := atrest.DecodeEntry(atrest.GetSealer(sp), raw, t)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed reading synced token")
+		}
+		if t.ID == nil || len(k) != 0 && k != syncedTokenKey(network, channel, namespace, t.ID) {
+			return nil, errors.Errorf("synced token stored under another key")
+		}
+		if !t.Done {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+// storeSyncedTokens stores, via the passed rwset, the synced tokens of the passed namespace, but the passed spent ones.
+// The synced tokens are then marked as done.
+func (r *RWSetProcessor) storeSyncedTokens(network, channel, ns string, rws *fabric.RWSet, spent []*token2.Id) error {
+	synced, err := SyncedTokens(r.sp, network, channel, ns)
+	if err != nil {
+		return err
+	}
+	if len(synced) == 0 {
+		return nil
+	}
+	isSpent := map[string]bool{}
+	for _, id := range spent {
+		isSpent[id.String()] = true
+	}
+	for _, t := range synced {
+		if isSpent[t.ID.String()] {
+			logger.Debugf("synced token [%s] spent, skipping", t.ID)
+		} else {
+			mineTokenID, err := keys.CreateTokenMineKey(t.ID.TxId, int(t.ID.Index))
+			if err != nil {
+				return errors.Wrapf(err, "failed computing mine key for [%s]", t.ID)
+			}
+			if err := rws.SetState(ns, mineTokenID, []byte{1}); err != nil {
+				return err
+			}
+			if err := r.storeFabToken(ns, t.ID.TxId, int(t.ID.Index), t.Token, rws, t.Info, t.EnrollmentID); err != nil {
+				return errors.WithMessagef(err, "failed storing synced token [%s]", t.ID)
+			}
+			logger.Debugf("synced token [%s] stored", t.ID)
+		}
+		done := &SyncedToken{ID: t.ID, Token: t.Token, EnrollmentID: t.EnrollmentID, Done: true}
+		if err := atrest.Put(r.sp, syncedTokenKey(network, channel, ns, t.ID), done); err != nil {
+			return errors.WithMessagef(err, "failed marking synced token [%s] as done", t.ID)
+		}
+	}
+	return nil
+}
+
+// deletedTokens returns the tokens deleted by the passed writes, the inputs of a transaction without graph hiding
+func (r *RWSetProcessor) deletedTokens(writes translator.Writes, ns string) ([]*token2.Id, error) {
+	var res []*token2.Id
+	for i := 0; i < writes.NumWrites(ns); i++ {
+		key, val, err := writes.GetWriteAt(ns, i)
+		if err != nil {
+			return nil, err
+		}
+		if len(val) != 0 {
+			continue
+		}
+		if id, err := r.keys.ParseTokenKey(key); err == nil {
+			res = append(res, id)
+		}
+	}
+	return res, nil
+}
+
+func syncedTokenKey(network, channel, namespace string, id *token2.Id) string {
+	return kvs.CreateCompositeKeyOrPanic(SyncedTokensPrefix, []string{network, channel, namespace, id.TxId, strconv.Itoa(int(id.Index))})
+}
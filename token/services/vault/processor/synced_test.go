@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package processor
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/api"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/atrest"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// configProvider configures the in memory kvs
+type configProvider struct {
+	api.ConfigProvider
+}
+
+func (*configProvider) UnmarshalKey(string, interface{}) error {
+	return nil
+}
+
+// writes lists the writes of a single namespace
+type writes [][2]string
+
+func (w writes) NumWrites(string) int {
+	return len(w)
+}
+
+func (w writes) GetWriteAt(_ string, i int) (string, []byte, error) {
+	return w[i][0], []byte(w[i][1]), nil
+}
+
+func TestSyncedTokens(t *testing.T) {
+	sp := registry.New()
+	assert.NoError(t, sp.RegisterService(&configProvider{}))
+	kvss, err := kvs.New("memory", "", sp)
+	assert.NoError(t, err)
+	assert.NoError(t, sp.RegisterService(kvss))
+
+	tok := &token2.Token{Owner: &token2.Owner{Raw: []byte("alice")}, Type: "ABC", Quantity: "0x0a"}
+	t1 := &SyncedToken{ID: &token2.Id{TxId: "tx1", Index: 0}, Token: tok, Info: []byte("info"), EnrollmentID: "alice"}
+	t2 := &SyncedToken{ID: &token2.Id{TxId: "tx1", Index: 1}, Token: tok, EnrollmentID: "alice"}
+	assert.NoError(t, AddSyncedTokens(sp, "n1", "c1", "ns1", t1, t2))
+	assert.Error(t, AddSyncedTokens(sp, "n1", "c1", "ns1", &SyncedToken{ID: &token2.Id{TxId: "tx2"}}))
+
+	synced, err := SyncedTokens(sp, "n1", "c1", "ns1")
+	assert.NoError(t, err)
+	assert.Len(t, synced, 2)
+	assert.Equal(t, []byte("info"), synced[0].Info)
+
+	// the synced tokens are scoped by network, channel and namespace
+	synced, err = SyncedTokens(sp, "n1", "c1", "ns2")
+	assert.NoError(t, err)
+	assert.Empty(t, synced)
+
+	// the tokens handled are not returned anymore
+	assert.NoError(t, atrest.Put(sp, syncedTokenKey("n1", "c1", "ns1", t1.ID), &SyncedToken{ID: t1.ID, Token: tok, Done: true}))
+	synced, err = SyncedTokens(sp, "n1", "c1", "ns1")
+	assert.NoError(t, err)
+	assert.Len(t, synced, 1)
+	assert.Equal(t, t2.ID, synced[0].ID)
+}
+
+func TestDeletedTokens(t *testing.T) {
+	r := NewTokenRWSetProcessor(nil, "ns1", nil, nil)
+	k1, err := keys.Default.CreateTokenKey("tx1", 0)
+	assert.NoError(t, err)
+	k2, err := keys.Default.CreateTokenKey("tx1", 1)
+	assert.NoError(t, err)
+	sn, err := keys.Default.CreateSNKey("sn")
+	assert.NoError(t, err)
+
+	deleted, err := r.deletedTokens(writes{{k1, ""}, {k2, "output"}, {sn, ""}}, "ns1")
+	assert.NoError(t, err)
+	assert.Equal(t, []*token2.Id{{TxId: "tx1", Index: 0}}, deleted)
+}
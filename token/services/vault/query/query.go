@@ -7,12 +7,14 @@ package query
 
 import (
 	"encoding/json"
+	"strconv"
 
 	"github.com/pkg/errors"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/atrest"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
@@ -27,15 +29,29 @@ type Channel interface {
 type Engine struct {
 	channel   Channel
 	namespace string
+	keys      *keys.Scheme
+	sealer    atrest.Sealer
 }
 
-func NewEngine(channel Channel, namespace string) *Engine {
+// NewEngine returns a query engine of the tokens of the passed namespace whose ledger keys are derived
+// with the passed scheme, keys.Default if nil
+func NewEngine(channel Channel, namespace string, scheme *keys.Scheme) *Engine {
+	if scheme == nil {
+		scheme = keys.Default
+	}
 	return &Engine{
 		channel:   channel,
 		namespace: namespace,
+		keys:      scheme,
 	}
 }
 
+// WithSealer makes the engine open the token information with the passed sealer, see atrest.Sealer
+func (e *Engine) WithSealer(sealer atrest.Sealer) *Engine {
+	e.sealer = sealer
+	return e
+}
+
 func (e *Engine) IsMine(id *token.Id) (bool, error) {
 	qe, err := e.channel.Vault().NewQueryExecutor()
 	if err != nil {
@@ -126,6 +142,88 @@ func (e *Engine) ListUnspentTokens() (*token.UnspentTokens, error) {
 	}
 }
 
+// UnspentTokensByEnrollmentID returns the unspent tokens owned by this node whose owner has the passed enrollment ID
+func (e *Engine) UnspentTokensByEnrollmentID(eID string) (*token.UnspentTokens, error) {
+	return e.unspentTokensByEnrollmentID(eID, keys.CreateFabtokenKey)
+}
+
+// UnspentAuditTokensByEnrollmentID returns the unspent audited tokens whose owner has the passed enrollment ID
+func (e *Engine) UnspentAuditTokensByEnrollmentID(eID string) (*token.UnspentTokens, error) {
+	return e.unspentTokensByEnrollmentID(eID, keys.CreateAuditTokenKey)
+}
+
+func (e *Engine) unspentTokensByEnrollmentID(eID string, tokenKey func(txID string, index int) (string, error)) (*token.UnspentTokens, error) {
+	logger.Debugf("List tokens by enrollment id [%s]...", eID)
+	startKey, err := keys.CreateEnrollmentIDPrefixKey(eID)
+	if err != nil {
+		return nil, err
+	}
+	endKey := startKey + string(keys.MaxUnicodeRuneValue)
+
+	qe, err := e.channel.Vault().NewQueryExecutor()
+	if err != nil {
+		return nil, err
+	}
+	defer qe.Done()
+
+	logger.Debugf("Get range query scan iterator... [%s,%s]", startKey, endKey)
+	iterator, err := qe.GetStateRangeScanIterator(e.namespace, startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	tokens := make([]*token.UnspentToken, 0)
+	for {
+		next, err := iterator.Next()
+		switch {
+		case err != nil:
+			logger.Errorf("scan failed [%s]", err)
+			return nil, err
+
+		case next == nil:
+			logger.Debugf("done")
+			return &token.UnspentTokens{Tokens: tokens}, nil
+
+		case len(next.Raw) == 0:
+			continue
+
+		default:
+			id, err := keys.GetTokenIdFromEnrollmentIDKey(next.Key)
+			if err != nil {
+				return nil, err
+			}
+			idKey, err := tokenKey(id.TxId, int(id.Index))
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed generating id key [%v]", id)
+			}
+			tokRaw, err := qe.GetState(e.namespace, idKey)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed getting token for key [%v]", idKey)
+			}
+			if len(tokRaw) == 0 {
+				// the index covers both owned and audited tokens
+				continue
+			}
+			tok := &token.Token{}
+			if err := json.Unmarshal(tokRaw, tok); err != nil {
+				return nil, errors.Wrapf(err, "failed unmarshalling token for key [%v]", idKey)
+			}
+			q, err := token.ToQuantity(tok.Quantity, keys.Precision)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens,
+				&token.UnspentToken{
+					Owner:    tok.Owner,
+					Type:     tok.Type,
+					Quantity: q.Decimal(),
+					Id:       id,
+				})
+		}
+	}
+}
+
 func (e *Engine) ListAuditTokens(ids ...*token.Id) ([]*token.Token, error) {
 	logger.Debugf("retrieve inputs for auditing...")
 	qe, err := e.channel.Vault().NewQueryExecutor()
@@ -235,7 +333,7 @@ func (e *Engine) PublicParams() ([]byte, error) {
 	}
 	defer qe.Done()
 
-	setupKey, err := keys.CreateSetupKey()
+	setupKey, err := e.keys.CreateSetupKey()
 	if err != nil {
 		return nil, err
 	}
@@ -247,6 +345,67 @@ func (e *Engine) PublicParams() ([]byte, error) {
 	return raw, nil
 }
 
+// GetState returns the value stored under the passed ledger key of the namespace, as committed in the vault
+func (e *Engine) GetState(key string) ([]byte, error) {
+	qe, err := e.channel.Vault().NewQueryExecutor()
+	if err != nil {
+		return nil, err
+	}
+	defer qe.Done()
+
+	return qe.GetState(e.namespace, key)
+}
+
+func (e *Engine) PublicParamsVersion() (uint64, error) {
+	qe, err := e.channel.Vault().NewQueryExecutor()
+	if err != nil {
+		return 0, err
+	}
+	defer qe.Done()
+
+	versionKey, err := e.keys.CreateSetupVersionKey()
+	if err != nil {
+		return 0, err
+	}
+	raw, err := qe.GetState(e.namespace, versionKey)
+	if err != nil {
+		return 0, err
+	}
+	if len(raw) == 0 {
+		return 0, nil
+	}
+	version, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid public parameters version [%s]", string(raw))
+	}
+	return version, nil
+}
+
+func (e *Engine) Supply(tokenType string) (*api.Supply, error) {
+	qe, err := e.channel.Vault().NewQueryExecutor()
+	if err != nil {
+		return nil, err
+	}
+	defer qe.Done()
+
+	key, err := e.keys.CreateSupplyKey(tokenType)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := qe.GetState(e.namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	supply := &api.Supply{Type: tokenType}
+	if len(raw) == 0 {
+		return supply, nil
+	}
+	if err := supply.FromBytes(raw); err != nil {
+		return nil, errors.Wrapf(err, "failed unmarshalling supply of [%s]", tokenType)
+	}
+	return supply, nil
+}
+
 func (e *Engine) GetTokenInfos(ids []*token.Id, callback api.QueryCallbackFunc) error {
 	qe, err := e.channel.Vault().NewQueryExecutor()
 	if err != nil {
@@ -263,7 +422,14 @@ func (e *Engine) GetTokenInfos(ids []*token.Id, callback api.QueryCallbackFunc)
 			return errors.Wrapf(err, "failed getting metadata for id [%v]", id)
 		}
 
-		if err := callback(id, meta[keys.Info]); err != nil {
+		info := meta[keys.Info]
+		if e.sealer != nil {
+			info, err = e.sealer.Open(info, []byte(outputID))
+			if err != nil {
+				return errors.WithMessagef(err, "failed opening token information of [%v]", id)
+			}
+		}
+		if err := callback(id, info); err != nil {
 			return err
 		}
 	}
@@ -277,7 +443,7 @@ func (e *Engine) GetTokenCommitments(ids []*token.Id, callback api.QueryCallback
 	}
 	defer qe.Done()
 	for _, id := range ids {
-		outputID, err := keys.CreateTokenKey(id.TxId, int(id.Index))
+		outputID, err := e.keys.CreateTokenKey(id.TxId, int(id.Index))
 		if err != nil {
 			return errors.Wrapf(err, "error creating output ID: %v", id)
 		}
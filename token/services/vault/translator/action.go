@@ -19,6 +19,14 @@ type IssueAction interface {
 	GetIssuer() []byte
 }
 
+// TypedIssueAction is an IssueAction revealing the types of its outputs,
+// the issuer policy of each type is enforced on it, see IssuingValidator
+type TypedIssueAction interface {
+	IssueAction
+	// GetTokenTypes returns the distinct types of the outputs
+	GetTokenTypes() []string
+}
+
 //go:generate counterfeiter -o mock/transfer_action.go -fake-name TransferAction . TransferAction
 
 type TransferAction interface {
@@ -30,3 +38,10 @@ type TransferAction interface {
 	GetInputs() ([]string, error)
 	IsGraphHiding() bool
 }
+
+//go:generate counterfeiter -o mock/burn_action.go -fake-name BurnAction . BurnAction
+
+// BurnAction is the burn receipt of a redeemed output
+type BurnAction interface {
+	Bytes() ([]byte, error)
+}
@@ -0,0 +1,176 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package translator
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+)
+
+// BatchEntry is a verified sub-request of a batch token request
+type BatchEntry struct {
+	// Binding replaces the transaction id for the sub-request, its outputs are identified by it
+	Binding string
+	// Request is the serialized sub-request, it is stored under its binding, see Translator.CommitTokenRequest
+	Request []byte
+	Actions []interface{}
+	// Rejection is the check the sub-request failed, empty if the sub-request is valid.
+	// It is recorded on the ledger in place of the error, whose message may differ between the endorsers.
+	Rejection api.ValidationCheck
+}
+
+// WriteBatch writes the actions of the passed sub-requests, each as if it was committed in its own transaction,
+// with its binding as transaction id, deriving the keys with the passed scheme, keys.Default if nil,
+// enforcing the passed supply caps, if any, and setting the endorsement policies of the tokens with the passed key policy, if any.
+// The writes of a sub-request are visible to the following ones, so a token spent by two sub-requests is detected.
+// If skipInvalid is false, it is all-or-nothing: the writes reach the passed rwset only if all
+// the sub-requests are written successfully.
+// If skipInvalid is true, the sub-requests already rejected, and those that cannot be written, are recorded
+// as rejected, under their binding, with the check they failed, and only the others are written.
+// The rejected entries are returned.
+func WriteBatch(issuingValidator IssuingValidator, rwSet RWSet, namespace string, scheme *keys.Scheme, caps SupplyCaps, policy KeyPolicy, entries []*BatchEntry, skipInvalid bool) ([]*BatchEntry, error) {
+	buffer := newBufferedRWSet(rwSet)
+	var rejected []*BatchEntry
+	for i, entry := range entries {
+		if len(entry.Rejection) == 0 {
+			// write the sub-request on its own, to discard its writes if it cannot be written
+			entryBuffer := newBufferedRWSet(buffer)
+			err := writeEntry(issuingValidator, entryBuffer, namespace, scheme, caps, policy, entry)
+			if err == nil {
+				err = entryBuffer.flush()
+			}
+			if err == nil {
+				continue
+			}
+			if !skipInvalid {
+				return nil, errors.WithMessagef(err, "failed writing sub-request [%d][%s]", i, entry.Binding)
+			}
+			logger.Debugf("sub-request [%d][%s] cannot be written [%s]", i, entry.Binding, err)
+			entry.Rejection = api.RejectionCheck(err)
+			if entry.Rejection == api.OtherCheck {
+				entry.Rejection = api.WriteCheck
+			}
+		}
+		if !skipInvalid {
+			// the report carries the check, to let the callers branch on the code of the rejection
+			report := &api.ValidationReport{}
+			return nil, report.Failed(api.RequestActionType, i, entry.Rejection, errors.Errorf("sub-request [%d][%s] rejected [%s]", i, entry.Binding, entry.Rejection))
+		}
+		if err := recordRejection(buffer, namespace, scheme, entry); err != nil {
+			return nil, errors.WithMessagef(err, "failed recording rejection of sub-request [%d][%s]", i, entry.Binding)
+		}
+		rejected = append(rejected, entry)
+	}
+	if err := buffer.flush(); err != nil {
+		return nil, err
+	}
+	return rejected, nil
+}
+
+func writeEntry(issuingValidator IssuingValidator, rwSet RWSet, namespace string, scheme *keys.Scheme, caps SupplyCaps, policy KeyPolicy, entry *BatchEntry) error {
+	w := New(issuingValidator, entry.Binding, rwSet, namespace)
+	w.Keys = scheme
+	w.SupplyCaps = caps
+	w.KeyPolicy = policy
+	for _, action := range entry.Actions {
+		if err := w.Write(action); err != nil {
+			return err
+		}
+	}
+	return w.CommitTokenRequest(entry.Request)
+}
+
+func recordRejection(rwSet RWSet, namespace string, scheme *keys.Scheme, entry *BatchEntry) error {
+	if scheme == nil {
+		scheme = keys.Default
+	}
+	key, err := scheme.CreateRejectedTokenRequestKey(entry.Binding)
+	if err != nil {
+		return errors.Wrapf(err, "failed creating rejection key for [%s]", entry.Binding)
+	}
+	return rwSet.SetState(namespace, key, []byte(entry.Rejection))
+}
+
+type write struct {
+	namespace string
+	key       string
+	value     []byte
+	delete    bool
+	metadata  map[string][]byte
+	isMeta    bool
+}
+
+// bufferedRWSet holds the writes back until flush, the reads see the writes held back
+type bufferedRWSet struct {
+	RWSet
+	writes   []*write
+	values   map[string]*write
+	metadata map[string]map[string][]byte
+}
+
+func newBufferedRWSet(rwSet RWSet) *bufferedRWSet {
+	return &bufferedRWSet{
+		RWSet:    rwSet,
+		values:   map[string]*write{},
+		metadata: map[string]map[string][]byte{},
+	}
+}
+
+func (b *bufferedRWSet) SetState(namespace string, key string, value []byte) error {
+	w := &write{namespace: namespace, key: key, value: value}
+	b.writes = append(b.writes, w)
+	b.values[namespace+key] = w
+	return nil
+}
+
+func (b *bufferedRWSet) DeleteState(namespace string, key string) error {
+	w := &write{namespace: namespace, key: key, delete: true}
+	b.writes = append(b.writes, w)
+	b.values[namespace+key] = w
+	return nil
+}
+
+func (b *bufferedRWSet) GetState(namespace string, key string, opts ...fabric.GetStateOpt) ([]byte, error) {
+	if w, ok := b.values[namespace+key]; ok {
+		return w.value, nil
+	}
+	return b.RWSet.GetState(namespace, key, opts...)
+}
+
+func (b *bufferedRWSet) SetStateMetadata(namespace, key string, metadata map[string][]byte) error {
+	b.writes = append(b.writes, &write{namespace: namespace, key: key, metadata: metadata, isMeta: true})
+	b.metadata[namespace+key] = metadata
+	return nil
+}
+
+func (b *bufferedRWSet) GetStateMetadata(namespace, key string, opts ...fabric.GetStateOpt) (map[string][]byte, error) {
+	if metadata, ok := b.metadata[namespace+key]; ok {
+		return metadata, nil
+	}
+	return b.RWSet.GetStateMetadata(namespace, key, opts...)
+}
+
+// flush applies the writes held back, in order, to the underlying rwset
+func (b *bufferedRWSet) flush() error {
+	for _, w := range b.writes {
+		var err error
+		switch {
+		case w.isMeta:
+			err = b.RWSet.SetStateMetadata(w.namespace, w.key, w.metadata)
+		case w.delete:
+			err = b.RWSet.DeleteState(w.namespace, w.key)
+		default:
+			err = b.RWSet.SetState(w.namespace, w.key, w.value)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed writing [%s]", w.key)
+		}
+	}
+	return nil
+}
@@ -12,5 +12,5 @@ import (
 //go:generate counterfeiter -o mock/engine.go -fake-name Engine . Engine
 
 type Validator interface {
-	Verify(ledger token.Ledger, sp token.SignatureProvider, binding string, tr *token.Request) ([]interface{}, error)
+	Verify(ledger token.Ledger, sp token.SignatureProvider, binding string, tr *token.Request, opts ...token.ValidationOption) ([]interface{}, error)
 }
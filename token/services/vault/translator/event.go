@@ -0,0 +1,105 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package translator
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// TokenEventName is the name of the chaincode event listing the keys written by a token request
+const TokenEventName = "token"
+
+// Write is a key written by a token request: a token created, or spent if Deleted,
+// or any other state the request has written. The value is not listed, it is in the rwset of the transaction.
+type Write struct {
+	Key     string
+	Deleted bool `json:",omitempty"`
+}
+
+// TokenEvent lists, in order, the keys a token request has written on the ledger.
+// It lets the clients listening to the chaincode events learn the tokens created and spent by a transaction
+// without parsing its rwset. The event is not authoritative, see Verify.
+// The event is emitted by invoke and invokeBatch alike. The vault of the SDK does not consume it, it is updated
+// from the rwset of the committed transactions: the processors of the vault are not handed the chaincode events.
+type TokenEvent struct {
+	TxID   string
+	Writes []*Write
+}
+
+func (e *TokenEvent) Bytes() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func (e *TokenEvent) FromBytes(raw []byte) error {
+	return json.Unmarshal(raw, e)
+}
+
+// Writes gives access to the writes of a namespace, as recorded in the rwset of a transaction in a block
+type Writes interface {
+	NumWrites(ns string) int
+	GetWriteAt(ns string, i int) (string, []byte, error)
+}
+
+// Verify checks that the event lists exactly the keys written in the passed namespace of the passed rwset,
+// as committed in the block, in any order. A key written more than once counts with its last write.
+func (e *TokenEvent) Verify(rws Writes, ns string) error {
+	listed := map[string]bool{}
+	for _, w := range e.Writes {
+		listed[w.Key] = w.Deleted
+	}
+	n := rws.NumWrites(ns)
+	if n != len(listed) {
+		return errors.Errorf("event of [%s] lists [%d] writes, the transaction has [%d]", e.TxID, len(listed), n)
+	}
+	for i := 0; i < n; i++ {
+		key, value, err := rws.GetWriteAt(ns, i)
+		if err != nil {
+			return errors.Wrapf(err, "failed reading write [%d] of [%s]", i, e.TxID)
+		}
+		deleted, ok := listed[key]
+		if !ok {
+			return errors.Errorf("event of [%s] does not list the write of [%s]", e.TxID, key)
+		}
+		if deleted != (len(value) == 0) {
+			return errors.Errorf("event of [%s] lists a different write for [%s]", e.TxID, key)
+		}
+	}
+	return nil
+}
+
+// EventRWSet is an RWSet that records the writes to the RWSet it wraps into a TokenEvent
+type EventRWSet struct {
+	RWSet
+	event *TokenEvent
+}
+
+// NewEventRWSet returns an RWSet recording the writes to the passed RWSet into an event of the passed transaction
+func NewEventRWSet(rwSet RWSet, txID string) *EventRWSet {
+	return &EventRWSet{RWSet: rwSet, event: &TokenEvent{TxID: txID}}
+}
+
+// Event returns the event recorded so far
+func (r *EventRWSet) Event() *TokenEvent {
+	return r.event
+}
+
+func (r *EventRWSet) SetState(namespace string, key string, value []byte) error {
+	if err := r.RWSet.SetState(namespace, key, value); err != nil {
+		return err
+	}
+	r.event.Writes = append(r.event.Writes, &Write{Key: key, Deleted: len(value) == 0})
+	return nil
+}
+
+func (r *EventRWSet) DeleteState(namespace string, key string) error {
+	if err := r.RWSet.DeleteState(namespace, key); err != nil {
+		return err
+	}
+	r.event.Writes = append(r.event.Writes, &Write{Key: key, Deleted: true})
+	return nil
+}
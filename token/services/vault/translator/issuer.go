@@ -5,7 +5,16 @@ SPDX-License-Identifier: Apache-2.0
 */
 package translator
 
-import "github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+)
 
 //go:generate counterfeiter -o mock/issuing_validator.go -fake-name IssuingValidator . IssuingValidator
 
@@ -14,3 +23,121 @@ type IssuingValidator interface {
 	// Validate returns no error if the passed creator can issue tokens of the passed type,, an error otherwise.
 	Validate(creator view.Identity, tokenType string) error
 }
+
+// RedeemIssuerValidator is an IssuingValidator that also checks the issuers co-signing the redemptions.
+// The validators verify the co-signature of an issuer against its certificate only, without checking its chain,
+// then the issuer must be listed explicitly.
+type RedeemIssuerValidator interface {
+	IssuingValidator
+	// ValidateRedeemIssuer returns no error if the passed issuer can co-sign the redemptions of the passed type,
+	// an error otherwise
+	ValidateRedeemIssuer(issuer view.Identity, tokenType string) error
+}
+
+// IssuerPolicy lists the issuers allowed to issue tokens of a given type
+type IssuerPolicy struct {
+	Type    string
+	Issuers []view.Identity
+}
+
+func (p *IssuerPolicy) Bytes() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func (p *IssuerPolicy) FromBytes(raw []byte) error {
+	return json.Unmarshal(raw, p)
+}
+
+// Allows returns true if the passed issuer is in the policy
+func (p *IssuerPolicy) Allows(issuer view.Identity) bool {
+	for _, id := range p.Issuers {
+		if bytes.Equal(id, issuer) {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyIssuingValidator is an IssuingValidator enforcing the issuer policies stored in the namespace,
+// see WriteIssuerPolicy. The token types without a policy can be issued by any issuer.
+type PolicyIssuingValidator struct {
+	rwSet     RWSet
+	namespace string
+	keys      *keys.Scheme
+}
+
+// NewPolicyIssuingValidator returns an IssuingValidator reading the issuer policies from the passed rwset,
+// deriving their keys with the passed scheme, keys.Default if nil
+func NewPolicyIssuingValidator(rwSet RWSet, namespace string, scheme *keys.Scheme) *PolicyIssuingValidator {
+	if scheme == nil {
+		scheme = keys.Default
+	}
+	return &PolicyIssuingValidator{rwSet: rwSet, namespace: namespace, keys: scheme}
+}
+
+func (v *PolicyIssuingValidator) Validate(creator view.Identity, tokenType string) error {
+	policy, err := ReadIssuerPolicy(v.rwSet, v.namespace, v.keys, tokenType)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return nil
+	}
+	if !policy.Allows(creator) {
+		return errors2.Errorf(errors2.Unauthorized, "issuer [%s] is not allowed to issue tokens of type [%s]", creator, tokenType)
+	}
+	return nil
+}
+
+// ValidateRedeemIssuer returns no error if the passed issuer is listed in the issuer policy of the passed type.
+// Unlike the issuers, the issuers co-signing the redemptions of a type without a policy are rejected.
+func (v *PolicyIssuingValidator) ValidateRedeemIssuer(issuer view.Identity, tokenType string) error {
+	policy, err := ReadIssuerPolicy(v.rwSet, v.namespace, v.keys, tokenType)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return errors2.Errorf(errors2.Unauthorized, "no issuer policy for type [%s], redemptions cannot be co-signed", tokenType)
+	}
+	if !policy.Allows(issuer) {
+		return errors2.Errorf(errors2.Unauthorized, "issuer [%s] is not allowed to co-sign redemptions of type [%s]", issuer, tokenType)
+	}
+	return nil
+}
+
+// ReadIssuerPolicy returns the issuer policy of the passed token type, nil if there is none
+func ReadIssuerPolicy(rwSet RWSet, namespace string, scheme *keys.Scheme, tokenType string) (*IssuerPolicy, error) {
+	key, err := scheme.CreateIssuerPolicyKey(tokenType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating issuer policy key for [%s]", tokenType)
+	}
+	raw, err := rwSet.GetState(namespace, key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading issuer policy of [%s]", tokenType)
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	policy := &IssuerPolicy{}
+	if err := policy.FromBytes(raw); err != nil {
+		return nil, errors.Wrapf(err, "failed unmarshalling issuer policy of [%s]", tokenType)
+	}
+	return policy, nil
+}
+
+// WriteIssuerPolicy stores the passed issuer policy, replacing the one of the same type, if any.
+// A policy without issuers removes the policy of its type.
+func WriteIssuerPolicy(rwSet RWSet, namespace string, scheme *keys.Scheme, policy *IssuerPolicy) error {
+	key, err := scheme.CreateIssuerPolicyKey(policy.Type)
+	if err != nil {
+		return errors.Wrapf(err, "failed creating issuer policy key for [%s]", policy.Type)
+	}
+	if len(policy.Issuers) == 0 {
+		return rwSet.DeleteState(namespace, key)
+	}
+	raw, err := policy.Bytes()
+	if err != nil {
+		return errors.Wrapf(err, "failed marshalling issuer policy of [%s]", policy.Type)
+	}
+	return rwSet.SetState(namespace, key, raw)
+}
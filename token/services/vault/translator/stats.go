@@ -0,0 +1,158 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package translator
+
+import (
+	"strconv"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+)
+
+// StatsRWSet is an RWSet that collects the statistics of the accesses to the RWSet it wraps
+type StatsRWSet struct {
+	RWSet
+	stats *api.RWSetStats
+}
+
+// NewStatsRWSet returns an RWSet collecting the statistics of the accesses to the passed RWSet into the passed stats,
+// new stats if nil
+func NewStatsRWSet(rwSet RWSet, stats *api.RWSetStats) *StatsRWSet {
+	if stats == nil {
+		stats = api.NewRWSetStats()
+	}
+	return &StatsRWSet{RWSet: rwSet, stats: stats}
+}
+
+// Stats returns the statistics collected so far
+func (s *StatsRWSet) Stats() *api.RWSetStats {
+	return s.stats
+}
+
+func (s *StatsRWSet) SetState(namespace string, key string, value []byte) error {
+	s.stats.Write(KeyKind(key), key, value)
+	return s.RWSet.SetState(namespace, key, value)
+}
+
+func (s *StatsRWSet) GetState(namespace string, key string, opts ...fabric.GetStateOpt) ([]byte, error) {
+	s.stats.Read(KeyKind(key), key)
+	return s.RWSet.GetState(namespace, key, opts...)
+}
+
+func (s *StatsRWSet) DeleteState(namespace string, key string) error {
+	s.stats.Delete(KeyKind(key), key)
+	return s.RWSet.DeleteState(namespace, key)
+}
+
+// KeyKind returns the kind of the passed ledger key: token for the token keys, the first attribute for the other
+// keys of a token application (sn, setup, token_request, ...), and the object type for any other key
+func KeyKind(key string) string {
+	objectType, attributes, err := keys.ParseCompositeKey(key)
+	if err != nil {
+		return "unknown"
+	}
+	if len(attributes) == 0 {
+		return objectType
+	}
+	switch attributes[0] {
+	case keys.SerialNumber, keys.TokenSetupKeyPrefix, keys.TokenMineKeyPrefix, keys.TokenRequestKeyPrefix,
+		keys.RejectedTokenRequestKeyPrefix, keys.BurnReceiptKeyPrefix, keys.IssuerPolicyKeyPrefix, keys.SupplyKeyPrefix:
+		return attributes[0]
+	}
+	if len(attributes) == 2 {
+		if _, err := strconv.ParseUint(attributes[1], 10, 32); err == nil {
+			return "token"
+		}
+	}
+	return objectType
+}
+
+var (
+	readsOpts = metrics.CounterOpts{
+		Namespace:    "token",
+		Subsystem:    "rwset",
+		Name:         "reads",
+		Help:         "The number of ledger keys read by the token transactions, by kind of key.",
+		LabelNames:   []string{"kind"},
+		StatsdFormat: "%{#fqname}.%{kind}",
+	}
+	writesOpts = metrics.CounterOpts{
+		Namespace:    "token",
+		Subsystem:    "rwset",
+		Name:         "writes",
+		Help:         "The number of ledger keys written by the token transactions, by kind of key.",
+		LabelNames:   []string{"kind"},
+		StatsdFormat: "%{#fqname}.%{kind}",
+	}
+	deletesOpts = metrics.CounterOpts{
+		Namespace:    "token",
+		Subsystem:    "rwset",
+		Name:         "deletes",
+		Help:         "The number of ledger keys deleted by the token transactions, by kind of key.",
+		LabelNames:   []string{"kind"},
+		StatsdFormat: "%{#fqname}.%{kind}",
+	}
+	maxKeySizeOpts = metrics.HistogramOpts{
+		Namespace:    "token",
+		Subsystem:    "rwset",
+		Name:         "max_key_size",
+		Help:         "The size in bytes of the largest ledger key accessed by a token transaction.",
+		Buckets:      []float64{32, 64, 128, 256, 512, 1024},
+		StatsdFormat: "%{#fqname}",
+	}
+	valueBytesOpts = metrics.HistogramOpts{
+		Namespace:    "token",
+		Subsystem:    "rwset",
+		Name:         "value_bytes",
+		Help:         "The total size in bytes of the values written by a token transaction.",
+		Buckets:      []float64{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20},
+		StatsdFormat: "%{#fqname}",
+	}
+)
+
+// Metrics exposes the statistics of the accesses to the ledger of the token transactions
+type Metrics struct {
+	Reads      metrics.Counter
+	Writes     metrics.Counter
+	Deletes    metrics.Counter
+	MaxKeySize metrics.Histogram
+	ValueBytes metrics.Histogram
+}
+
+// NewMetrics returns the metrics created with the passed provider, a provider that discards them if nil
+func NewMetrics(p metrics.Provider) *Metrics {
+	if p == nil {
+		p = &disabled.Provider{}
+	}
+	return &Metrics{
+		Reads:      p.NewCounter(readsOpts),
+		Writes:     p.NewCounter(writesOpts),
+		Deletes:    p.NewCounter(deletesOpts),
+		MaxKeySize: p.NewHistogram(maxKeySizeOpts),
+		ValueBytes: p.NewHistogram(valueBytesOpts),
+	}
+}
+
+// Observe records the passed statistics of a transaction
+func (m *Metrics) Observe(stats *api.RWSetStats) {
+	for kind, ks := range stats.Kinds {
+		if ks.Reads != 0 {
+			m.Reads.With("kind", kind).Add(float64(ks.Reads))
+		}
+		if ks.Writes != 0 {
+			m.Writes.With("kind", kind).Add(float64(ks.Writes))
+		}
+		if ks.Deletes != 0 {
+			m.Deletes.With("kind", kind).Add(float64(ks.Deletes))
+		}
+	}
+	m.MaxKeySize.Observe(float64(stats.MaxKeySize))
+	m.ValueBytes.Observe(float64(stats.ValueBytes))
+}
@@ -0,0 +1,157 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package translator
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+// SupplyIssueAction is an IssueAction that can reveal the total value it issues, and its type.
+// The translator tracks on the ledger the supply of the types of the issue actions revealing it, see ReadSupply.
+type SupplyIssueAction interface {
+	IssueAction
+	// GetIssuedSupply returns the type and the total value issued, false if not revealed
+	GetIssuedSupply() (string, uint64, bool)
+}
+
+// SupplyCaps bounds per token type the total value that can be issued
+type SupplyCaps interface {
+	// SupplyCap returns the cap of the passed token type, false if uncapped
+	SupplyCap(tokenType string) (uint64, bool)
+}
+
+// Supply is the supply of a token type
+type Supply = api.Supply
+
+// ReadSupply returns the supply of the passed token type stored in the namespace, deriving its key with the passed scheme,
+// keys.Default if nil. A type never issued has a zero supply.
+func ReadSupply(rwSet RWSet, namespace string, scheme *keys.Scheme, tokenType string) (*Supply, error) {
+	if scheme == nil {
+		scheme = keys.Default
+	}
+	key, err := scheme.CreateSupplyKey(tokenType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating supply key for [%s]", tokenType)
+	}
+	raw, err := rwSet.GetState(namespace, key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading supply of [%s]", tokenType)
+	}
+	supply := &Supply{Type: tokenType}
+	if len(raw) == 0 {
+		return supply, nil
+	}
+	if err := supply.FromBytes(raw); err != nil {
+		return nil, errors.Wrapf(err, "failed unmarshalling supply of [%s]", tokenType)
+	}
+	return supply, nil
+}
+
+// currentSupply returns the supply of the passed type.
+// The supplies updated by this translator are taken into account, since the rwset does not return its own writes.
+func (w *Translator) currentSupply(tokenType string) (*Supply, error) {
+	if supply, ok := w.supplies[tokenType]; ok {
+		return supply, nil
+	}
+	return ReadSupply(w.RWSet, w.namespace, w.Keys, tokenType)
+}
+
+// nextSupply returns the supply of the passed type once the passed value is issued, enforcing the supply caps, if any
+func (w *Translator) nextSupply(tokenType string, value uint64) (*Supply, error) {
+	supply, err := w.currentSupply(tokenType)
+	if err != nil {
+		return nil, err
+	}
+	issued := supply.Issued + value
+	if issued < supply.Issued {
+		return nil, errors.Errorf("supply of [%s] overflows", tokenType)
+	}
+	if w.SupplyCaps != nil {
+		if supplyCap, capped := w.SupplyCaps.SupplyCap(tokenType); capped && issued > supplyCap {
+			return nil, errors2.Errorf(errors2.Unauthorized, "issuing [%d] of [%s] exceeds its supply cap [%d], already issued [%d]", value, tokenType, supplyCap, supply.Issued)
+		}
+	}
+	return &Supply{Type: tokenType, Issued: issued, Redeemed: supply.Redeemed}, nil
+}
+
+func (w *Translator) checkSupply(issue IssueAction) error {
+	action, ok := issue.(SupplyIssueAction)
+	if !ok {
+		return nil
+	}
+	tokenType, value, revealed := action.GetIssuedSupply()
+	if !revealed {
+		return nil
+	}
+	_, err := w.nextSupply(tokenType, value)
+	return err
+}
+
+func (w *Translator) commitSupply(issue IssueAction) error {
+	action, ok := issue.(SupplyIssueAction)
+	if !ok {
+		return nil
+	}
+	tokenType, value, revealed := action.GetIssuedSupply()
+	if !revealed {
+		return nil
+	}
+	supply, err := w.nextSupply(tokenType, value)
+	if err != nil {
+		return err
+	}
+	return w.writeSupply(supply)
+}
+
+// commitRedeemedSupply adds the quantity of the passed burn receipt to the value redeemed of its type
+func (w *Translator) commitRedeemedSupply(burn BurnAction) error {
+	receipt, ok := burn.(*api.BurnReceipt)
+	if !ok {
+		return nil
+	}
+	q, err := token2.ToQuantity(receipt.Quantity, keys.Precision)
+	if err != nil {
+		return errors.Wrapf(err, "invalid quantity in burn receipt")
+	}
+	value := q.ToBigInt()
+	if !value.IsUint64() {
+		return errors.Errorf("quantity [%s] redeemed of [%s] out of range", receipt.Quantity, receipt.Type)
+	}
+	supply, err := w.currentSupply(receipt.Type)
+	if err != nil {
+		return err
+	}
+	redeemed := supply.Redeemed + value.Uint64()
+	if redeemed < supply.Redeemed {
+		return errors.Errorf("redeemed supply of [%s] overflows", receipt.Type)
+	}
+	return w.writeSupply(&Supply{Type: receipt.Type, Issued: supply.Issued, Redeemed: redeemed})
+}
+
+func (w *Translator) writeSupply(supply *Supply) error {
+	tokenType := supply.Type
+	key, err := w.keys().CreateSupplyKey(tokenType)
+	if err != nil {
+		return errors.Wrapf(err, "failed creating supply key for [%s]", tokenType)
+	}
+	raw, err := supply.Bytes()
+	if err != nil {
+		return errors.Wrapf(err, "failed marshalling supply of [%s]", tokenType)
+	}
+	if err := w.RWSet.SetState(w.namespace, key, raw); err != nil {
+		return errors.Wrapf(err, "failed writing supply of [%s]", tokenType)
+	}
+	if w.supplies == nil {
+		w.supplies = map[string]*Supply{}
+	}
+	w.supplies[tokenType] = supply
+	return nil
+}
@@ -6,24 +6,62 @@ SPDX-License-Identifier: Apache-2.0
 package translator
 
 import (
+	"crypto/sha256"
 	"strconv"
 
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/pkg/errors"
 
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 var logger = flogging.MustGetLogger("token-sdk.vault.translator")
 
+// AlreadyCommittedError is returned when a different token request has been already committed under the same transaction ID
+type AlreadyCommittedError struct {
+	TxID string
+}
+
+func (e *AlreadyCommittedError) Error() string {
+	return "token request with same ID already exists"
+}
+
+func (e *AlreadyCommittedError) Code() errors2.Code {
+	return errors2.AlreadyExists
+}
+
+// IsAlreadyCommitted returns true if the passed error is, or wraps, an AlreadyCommittedError
+func IsAlreadyCommitted(err error) bool {
+	var e *AlreadyCommittedError
+	return errors.As(err, &e)
+}
+
+// KeyPolicy returns the state-based endorsement policy, serialized, of the token created at the passed key with
+// the passed serialized output, nil for none. Under graph hiding, it also applies to the serial numbers of the spent
+// tokens, with a nil output. The tokens without graph hiding are deleted when spent, together with their policy,
+// the deletion must satisfy it.
+type KeyPolicy func(key string, output []byte) ([]byte, error)
+
 // Translator validates token requests and generates the corresponding RWSets
 type Translator struct {
 	IssuingValidator IssuingValidator
 	RWSet            RWSet
 	TxID             string
-	counter          int
-	namespace        string
+	// Keys derives the ledger keys, keys.Default if nil
+	Keys *keys.Scheme
+	// KeyPolicy, if set, sets the state-based endorsement policies of the created tokens, so that they cannot be
+	// tampered with by the organizations that cannot satisfy them
+	KeyPolicy KeyPolicy
+	// SupplyCaps, if set, bounds the supply of the token types, see SupplyIssueAction
+	SupplyCaps SupplyCaps
+	counter    int
+	burns      int
+	namespace  string
+	// supplies are the supplies updated by this translator
+	supplies map[string]*Supply
 }
 
 func New(issuingValidator IssuingValidator, txID string, rwSet RWSet, namespace string) *Translator {
@@ -58,8 +96,11 @@ func (w *Translator) Write(action interface{}) error {
 	return nil
 }
 
+// CommitTokenRequest stores the passed token request under the transaction ID of this translator.
+// If a token request is already stored under the same transaction ID, the call succeeds only if the two requests
+// are identical, otherwise an AlreadyCommittedError is returned.
 func (w *Translator) CommitTokenRequest(raw []byte) error {
-	key, err := keys.CreateTokenRequestKey(w.TxID)
+	key, err := w.keys().CreateTokenRequestKey(w.TxID)
 	if err != nil {
 		return errors.Errorf("can't create for token request '%s'", w.TxID)
 	}
@@ -68,7 +109,11 @@ func (w *Translator) CommitTokenRequest(raw []byte) error {
 		return errors.Wrapf(err, "failed to write token request'%s'", w.TxID)
 	}
 	if tr != nil {
-		return errors.Wrapf(errors.New("token request with same ID already exists"), "failed to write token request'%s'", w.TxID)
+		if sha256.Sum256(tr) == sha256.Sum256(raw) {
+			logger.Debugf("token request '%s' already committed with the same content", w.TxID)
+			return nil
+		}
+		return errors.Wrapf(&AlreadyCommittedError{TxID: w.TxID}, "failed to write token request'%s'", w.TxID)
 	}
 	err = w.RWSet.SetState(w.namespace, key, raw)
 	if err != nil {
@@ -92,6 +137,8 @@ func (w *Translator) checkAction(tokenAction interface{}) error {
 		return w.checkTransfer(action)
 	case SetupAction:
 		return nil
+	case BurnAction:
+		return w.checkBurn(action)
 	default:
 		return errors.Errorf("unknown token action: %T", action)
 	}
@@ -103,6 +150,9 @@ func (w *Translator) checkIssue(issue IssueAction) error {
 	if err != nil {
 		return errors.Wrapf(err, "invalid issue: verification of issue policy failed")
 	}
+	if err := w.checkSupply(issue); err != nil {
+		return errors.WithMessagef(err, "invalid issue")
+	}
 
 	// check if the keys of issued tokens aren't already used.
 	// check is assigned owners are valid
@@ -127,7 +177,7 @@ func (w *Translator) checkTransfer(t TransferAction) error {
 				return errors.Wrapf(err, "invalid transfer: failed getting state [%s]", key)
 			}
 			if len(bytes) == 0 {
-				return errors.Errorf("invalid transfer: input is already spent [%s]", key)
+				return errors2.Errorf(errors2.DoubleSpend, "invalid transfer: input is already spent [%s]", key)
 			}
 		}
 	} else {
@@ -137,7 +187,7 @@ func (w *Translator) checkTransfer(t TransferAction) error {
 				return errors.Wrapf(err, "invalid transfer: failed getting state [%s]", key)
 			}
 			if len(bytes) != 0 {
-				return errors.Errorf("invalid transfer: input is already spent [%s:%v]", key, bytes)
+				return errors2.Errorf(errors2.DoubleSpend, "invalid transfer: input is already spent [%s:%v]", key, bytes)
 			}
 		}
 	}
@@ -155,8 +205,23 @@ func (w *Translator) checkTransfer(t TransferAction) error {
 	return nil
 }
 
+func (w *Translator) checkBurn(burn BurnAction) error {
+	// the issuer co-signing the redemption, if any, must be allowed to issue the redeemed type,
+	// and listed in its issuer policy, if the issuing validator can check it, see RedeemIssuerValidator
+	if receipt, ok := burn.(*api.BurnReceipt); ok && !receipt.Issuer.IsNone() {
+		validate := w.IssuingValidator.Validate
+		if rv, ok := w.IssuingValidator.(RedeemIssuerValidator); ok {
+			validate = rv.ValidateRedeemIssuer
+		}
+		if err := validate(receipt.Issuer, receipt.Type); err != nil {
+			return errors.Wrapf(err, "invalid burn: verification of issue policy failed")
+		}
+	}
+	return w.checkBurnReceiptDoesNotExist(w.burns)
+}
+
 func (w *Translator) checkTokenDoesNotExist(index int, txID string) error {
-	tokenKey, err := keys.CreateTokenKey(txID, index)
+	tokenKey, err := w.keys().CreateTokenKey(txID, index)
 	if err != nil {
 		return errors.Wrapf(err, "error creating output ID")
 	}
@@ -166,14 +231,38 @@ func (w *Translator) checkTokenDoesNotExist(index int, txID string) error {
 		return err
 	}
 	if len(outputBytes) != 0 {
-		return errors.Errorf("token already exists: %s", tokenKey)
+		return errors2.Errorf(errors2.AlreadyExists, "token already exists: %s", tokenKey)
+	}
+	return nil
+}
+
+func (w *Translator) checkBurnReceiptDoesNotExist(index int) error {
+	key, err := w.keys().CreateBurnReceiptKey(w.TxID, index)
+	if err != nil {
+		return errors.Wrapf(err, "error creating burn receipt key")
+	}
+	raw, err := w.RWSet.GetState(w.namespace, key)
+	if err != nil {
+		return err
+	}
+	if len(raw) != 0 {
+		return errors2.Errorf(errors2.AlreadyExists, "burn receipt already exists: %s", key)
 	}
 	return nil
 }
 
 func (w *Translator) checkIssuePolicy(issue IssueAction) error {
-	// TODO: retrieve type from action
-	return w.IssuingValidator.Validate(issue.GetIssuer(), "")
+	typed, ok := issue.(TypedIssueAction)
+	if !ok {
+		// the types of the outputs are hidden
+		return w.IssuingValidator.Validate(issue.GetIssuer(), "")
+	}
+	for _, tokenType := range typed.GetTokenTypes() {
+		if err := w.IssuingValidator.Validate(issue.GetIssuer(), tokenType); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (w *Translator) commitProcess(action interface{}) error {
@@ -196,6 +285,8 @@ func (w *Translator) commitAction(tokenAction interface{}) (err error) {
 		err = w.commitTransferAction(action)
 	case SetupAction:
 		err = w.commitSetupAction(action)
+	case BurnAction:
+		err = w.commitBurnAction(action)
 	}
 	return
 }
@@ -205,7 +296,7 @@ func (w *Translator) commitSetupAction(setup SetupAction) error {
 	if err != nil {
 		return err
 	}
-	setupKey, err := keys.CreateSetupKey()
+	setupKey, err := w.keys().CreateSetupKey()
 	if err != nil {
 		return err
 	}
@@ -213,7 +304,35 @@ func (w *Translator) commitSetupAction(setup SetupAction) error {
 	if err != nil {
 		return err
 	}
-	return nil
+
+	// store the public parameters also under their own version, and delete the superseded one,
+	// so that the token requests pinning the superseded version conflict with this action, and only them
+	version, err := w.ReadSetupVersion()
+	if err != nil {
+		return err
+	}
+	if version != 0 {
+		key, err := w.keys().CreateVersionedSetupKey(version)
+		if err != nil {
+			return err
+		}
+		if err := w.RWSet.DeleteState(w.namespace, key); err != nil {
+			return errors.Wrapf(err, "failed to delete setup parameters version [%d]", version)
+		}
+	}
+	version++
+	key, err := w.keys().CreateVersionedSetupKey(version)
+	if err != nil {
+		return err
+	}
+	if err := w.RWSet.SetState(w.namespace, key, raw); err != nil {
+		return errors.Wrapf(err, "failed to store setup parameters version [%d]", version)
+	}
+	versionKey, err := w.keys().CreateSetupVersionKey()
+	if err != nil {
+		return err
+	}
+	return w.RWSet.SetState(w.namespace, versionKey, []byte(strconv.FormatUint(version, 10)))
 }
 
 func (w *Translator) commitIssueAction(issueAction IssueAction) error {
@@ -224,7 +343,7 @@ func (w *Translator) commitIssueAction(issueAction IssueAction) error {
 		return err
 	}
 	for i, output := range outputs {
-		outputID, err := keys.CreateTokenKey(w.TxID, base+i)
+		outputID, err := w.keys().CreateTokenKey(w.TxID, base+i)
 		if err != nil {
 			return errors.Errorf("error creating output ID: %s", err)
 		}
@@ -233,16 +352,32 @@ func (w *Translator) commitIssueAction(issueAction IssueAction) error {
 			return err
 		}
 
-		if err := w.RWSet.SetStateMetadata(w.namespace, outputID,
-			map[string][]byte{
-				keys.Action: []byte(keys.ActionIssue),
-			},
-		); err != nil {
+		metadata, err := w.tokenMetadata(outputID, output, keys.ActionIssue)
+		if err != nil {
+			return err
+		}
+		if err := w.RWSet.SetStateMetadata(w.namespace, outputID, metadata); err != nil {
 			return err
 		}
 	}
 	w.counter = w.counter + len(outputs)
-	return nil
+	return w.commitSupply(issueAction)
+}
+
+func (w *Translator) commitBurnAction(burnAction BurnAction) error {
+	raw, err := burnAction.Bytes()
+	if err != nil {
+		return errors.Wrapf(err, "failed serializing burn receipt")
+	}
+	key, err := w.keys().CreateBurnReceiptKey(w.TxID, w.burns)
+	if err != nil {
+		return errors.Errorf("error creating burn receipt key: %s", err)
+	}
+	if err := w.RWSet.SetState(w.namespace, key, raw); err != nil {
+		return err
+	}
+	w.burns++
+	return w.commitRedeemedSupply(burnAction)
 }
 
 // commitTransferAction is called for both transfer and redeem transactions
@@ -251,7 +386,7 @@ func (w *Translator) commitTransferAction(transferAction TransferAction) error {
 	base := w.counter
 	for i := 0; i < transferAction.NumOutputs(); i++ {
 		if !transferAction.IsRedeemAt(i) {
-			outputID, err := keys.CreateTokenKey(w.TxID, base+i)
+			outputID, err := w.keys().CreateTokenKey(w.TxID, base+i)
 			if err != nil {
 				return errors.Errorf("error creating output ID: %s", err)
 			}
@@ -264,7 +399,11 @@ func (w *Translator) commitTransferAction(transferAction TransferAction) error {
 			if err != nil {
 				return err
 			}
-			err = w.RWSet.SetStateMetadata(w.namespace, outputID, map[string][]byte{keys.Action: []byte(keys.ActionTransfer)})
+			metadata, err := w.tokenMetadata(outputID, bytes, keys.ActionTransfer)
+			if err != nil {
+				return err
+			}
+			err = w.RWSet.SetStateMetadata(w.namespace, outputID, metadata)
 			if err != nil {
 				return err
 			}
@@ -304,14 +443,43 @@ func (w *Translator) spendTokens(ids []string, graphHiding bool) error {
 			if err != nil {
 				return errors.Wrapf(err, "failed to add serial number %s", id)
 			}
+			if w.KeyPolicy == nil {
+				continue
+			}
+			policy, err := w.KeyPolicy(id, nil)
+			if err != nil {
+				return errors.WithMessagef(err, "failed getting endorsement policy of serial number %s", id)
+			}
+			if len(policy) == 0 {
+				continue
+			}
+			if err := w.RWSet.SetStateMetadata(w.namespace, id, map[string][]byte{keys.ValidationParameter: policy}); err != nil {
+				return errors.Wrapf(err, "failed to set endorsement policy of serial number %s", id)
+			}
 		}
 	}
 
 	return nil
 }
 
+// tokenMetadata returns the metadata of the token created by the passed action at the passed key
+func (w *Translator) tokenMetadata(key string, output []byte, action string) (map[string][]byte, error) {
+	metadata := map[string][]byte{keys.Action: []byte(action)}
+	if w.KeyPolicy == nil {
+		return metadata, nil
+	}
+	policy, err := w.KeyPolicy(key, output)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting endorsement policy of token %s", key)
+	}
+	if len(policy) != 0 {
+		metadata[keys.ValidationParameter] = policy
+	}
+	return metadata, nil
+}
+
 func (w *Translator) ReadSetupParameters() ([]byte, error) {
-	setupKey, err := keys.CreateSetupKey()
+	setupKey, err := w.keys().CreateSetupKey()
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create setup key")
 	}
@@ -322,11 +490,59 @@ func (w *Translator) ReadSetupParameters() ([]byte, error) {
 	return raw, nil
 }
 
+// ReadSetupVersion returns the version of the current setup parameters, zero if they have never been versioned
+func (w *Translator) ReadSetupVersion() (uint64, error) {
+	key, err := w.keys().CreateSetupVersionKey()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to create setup version key")
+	}
+	raw, err := w.RWSet.GetState(w.namespace, key)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get setup parameters version")
+	}
+	if len(raw) == 0 {
+		return 0, nil
+	}
+	version, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid setup parameters version [%s]", string(raw))
+	}
+	return version, nil
+}
+
+// ReadSetupParametersAt returns the setup parameters with the passed version, nil if the version is not the current one.
+// Unlike ReadSetupParameters, it does not read the key written by every update of the setup parameters,
+// but only the one deleted when the passed version is superseded.
+func (w *Translator) ReadSetupParametersAt(version uint64) ([]byte, error) {
+	key, err := w.keys().CreateVersionedSetupKey(version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create setup key for version [%d]", version)
+	}
+	raw, err := w.RWSet.GetState(w.namespace, key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get setup parameters version [%d]", version)
+	}
+	return raw, nil
+}
+
+// ReadTokenRequest returns the token request stored under the passed transaction ID, nil if there is none
+func (w *Translator) ReadTokenRequest(txID string) ([]byte, error) {
+	key, err := w.keys().CreateTokenRequestKey(txID)
+	if err != nil {
+		return nil, errors.Errorf("can't create for token request '%s'", txID)
+	}
+	raw, err := w.RWSet.GetState(w.namespace, key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read token request '%s'", txID)
+	}
+	return raw, nil
+}
+
 func (w *Translator) QueryTokens(ids []*token2.Id) ([][]byte, error) {
 	var res [][]byte
 	var errs []error
 	for _, id := range ids {
-		outputID, err := keys.CreateTokenKey(id.TxId, int(id.Index))
+		outputID, err := w.keys().CreateTokenKey(id.TxId, int(id.Index))
 		if err != nil {
 			errs = append(errs, errors.Errorf("error creating output ID: %s", err))
 			continue
@@ -351,3 +567,10 @@ func (w *Translator) QueryTokens(ids []*token2.Id) ([][]byte, error) {
 	}
 	return res, nil
 }
+
+func (w *Translator) keys() *keys.Scheme {
+	if w.Keys == nil {
+		return keys.Default
+	}
+	return w.Keys
+}
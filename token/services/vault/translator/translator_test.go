@@ -6,11 +6,18 @@ SPDX-License-Identifier: Apache-2.0
 package translator_test
 
 import (
+	"math"
 	"strconv"
 
+	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	errors2 "github.com/hyperledger-labs/fabric-token-sdk/token/errors"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/keys"
 	writer2 "github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator"
 	mock "github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/translator/mock"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/pkg/errors"
@@ -102,6 +109,41 @@ var _ = Describe("Translator", func() {
 			})
 		})
 
+		When("the translator has a key scheme", func() {
+			It("derives the keys with the scheme", func() {
+				scheme, err := keys.NewScheme("app1")
+				Expect(err).NotTo(HaveOccurred())
+				writer.Keys = scheme
+				Expect(writer.Write(fakeissue)).To(Succeed())
+
+				Expect(fakeRWSet.SetStateCallCount()).To(Equal(2))
+				_, id, _ := fakeRWSet.SetStateArgsForCall(0)
+				key, err := scheme.CreateTokenKey("0", 0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(id).To(Equal(key))
+				defaultKey, err := keys.CreateTokenKey("0", 0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(id).NotTo(Equal(defaultKey))
+				Expect(scheme.ParseTokenKey(id)).To(Equal(&token2.Id{TxId: "0", Index: 0}))
+			})
+		})
+
+		When("the translator has a key policy", func() {
+			It("sets the endorsement policy of the created tokens", func() {
+				writer.KeyPolicy = func(key string, output []byte) ([]byte, error) {
+					return append([]byte("policy of "), output...), nil
+				}
+				Expect(writer.Write(fakeissue)).To(Succeed())
+
+				Expect(fakeRWSet.SetStateMetadataCallCount()).To(Equal(2))
+				_, _, metadata := fakeRWSet.SetStateMetadataArgsForCall(1)
+				Expect(metadata).To(Equal(map[string][]byte{
+					action:                   []byte(actionIssue),
+					keys.ValidationParameter: []byte("policy of output-2"),
+				}))
+			})
+		})
+
 		When("created tokens already exist", func() {
 			BeforeEach(func() {
 				fakeRWSet.GetStateReturnsOnCall(0, []byte("this is already occupied"), nil)
@@ -110,6 +152,7 @@ var _ = Describe("Translator", func() {
 				err := writer.Write(fakeissue)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("token already exists"))
+				Expect(errors2.HasCode(err, errors2.AlreadyExists)).To(BeTrue())
 				Expect(fakeRWSet.GetStateCallCount()).To(Equal(1))
 
 			})
@@ -195,6 +238,7 @@ var _ = Describe("Translator", func() {
 				err := writer.Write(faketransfer)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("token already exists"))
+				Expect(errors2.HasCode(err, errors2.AlreadyExists)).To(BeTrue())
 				Expect(fakeRWSet.GetStateCallCount()).To(Equal(4))
 
 			})
@@ -219,6 +263,7 @@ var _ = Describe("Translator", func() {
 				err := writer.Write(faketransfer)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("already spent"))
+				Expect(errors2.HasCode(err, errors2.DoubleSpend)).To(BeTrue())
 				Expect(fakeRWSet.GetStateCallCount()).To(Equal(3))
 			})
 		})
@@ -271,6 +316,21 @@ var _ = Describe("Translator", func() {
 
 			})
 		})
+		When("the translator has a key policy", func() {
+			It("sets the endorsement policy of the serial numbers", func() {
+				writer.KeyPolicy = func(key string, output []byte) ([]byte, error) {
+					return []byte("policy"), nil
+				}
+				Expect(writer.Write(faketransfer)).To(Succeed())
+
+				Expect(fakeRWSet.SetStateMetadataCallCount()).To(Equal(5))
+				ns, id, metadata := fakeRWSet.SetStateMetadataArgsForCall(2)
+				Expect(ns).To(Equal(tokenNameSpace))
+				Expect(id).To(Equal(sn[0]))
+				Expect(metadata).To(Equal(map[string][]byte{keys.ValidationParameter: []byte("policy")}))
+			})
+		})
+
 		When("serial numbers already exist", func() {
 			BeforeEach(func() {
 				fakeRWSet.GetStateReturnsOnCall(2, []byte(strconv.FormatBool(true)), nil)
@@ -279,6 +339,7 @@ var _ = Describe("Translator", func() {
 				err := writer.Write(faketransfer)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("already spent"))
+				Expect(errors2.HasCode(err, errors2.DoubleSpend)).To(BeTrue())
 				Expect(fakeRWSet.GetStateCallCount()).To(Equal(3))
 				ns, snkey, _ := fakeRWSet.GetStateArgsForCall(2)
 				Expect(ns).To(Equal(tokenNameSpace))
@@ -299,6 +360,42 @@ var _ = Describe("Translator", func() {
 		})
 	})
 
+	Describe("Burn", func() {
+		var fakeburn *mock.BurnAction
+		BeforeEach(func() {
+			fakeburn = &mock.BurnAction{}
+			fakeburn.BytesReturns([]byte("receipt"), nil)
+		})
+		When("burn receipts are valid", func() {
+			It("succeeds", func() {
+				Expect(writer.Write(fakeburn)).To(Succeed())
+				Expect(writer.Write(fakeburn)).To(Succeed())
+				Expect(fakeRWSet.SetStateCallCount()).To(Equal(2))
+
+				for i := 0; i < 2; i++ {
+					ns, id, out := fakeRWSet.SetStateArgsForCall(i)
+					Expect(ns).To(Equal(tokenNameSpace))
+					Expect(out).To(Equal([]byte("receipt")))
+					key, err := keys.CreateBurnReceiptKey("0", i)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(id).To(Equal(key))
+				}
+			})
+		})
+		When("the burn receipt already exists", func() {
+			BeforeEach(func() {
+				fakeRWSet.GetStateReturns([]byte("occupied"), nil)
+			})
+			It("burn fails", func() {
+				err := writer.Write(fakeburn)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("burn receipt already exists"))
+				Expect(errors2.HasCode(err, errors2.AlreadyExists)).To(BeTrue())
+				Expect(fakeRWSet.SetStateCallCount()).To(Equal(0))
+			})
+		})
+	})
+
 	Describe("Commit Token Request", func() {
 		When("set state succeeds", func() {
 			It("succeeds", func() {
@@ -346,9 +443,343 @@ var _ = Describe("Translator", func() {
 				err := writer.CommitTokenRequest([]byte("token request"))
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("token request with same ID already exists"))
+				Expect(writer2.IsAlreadyCommitted(err)).To(BeTrue())
+				Expect(fakeRWSet.SetStateCallCount()).To(Equal(0))
+
+			})
+		})
+		When("the same token request already exists", func() {
+			BeforeEach(func() {
+				fakeRWSet.GetStateReturns([]byte("token request"), nil)
+			})
+			It("commit token request succeeds without writing", func() {
+				err := writer.CommitTokenRequest([]byte("token request"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeRWSet.SetStateCallCount()).To(Equal(0))
+			})
+		})
+	})
+	Describe("Batch", func() {
+		BeforeEach(func() {
+			faketransfer.SerializeOutputAtReturns([]byte("output"), nil)
+			faketransfer.IsRedeemAtReturns(false)
+			faketransfer.GetInputsReturns([]string{"key1"}, nil)
+			faketransfer.NumOutputsReturns(1)
+			fakeissue.GetSerializedOutputsReturns([][]byte{[]byte("output-1"), []byte("output-2")}, nil)
+			fakeissue.NumOutputsReturns(2)
+			fakeRWSet.GetStateStub = func(namespace string, key string, opts ...fabric.GetStateOpt) ([]byte, error) {
+				if key == "key1" {
+					return []byte("token-1"), nil
+				}
+				return nil, nil
+			}
+		})
+		When("the sub-requests are valid", func() {
+			It("succeeds", func() {
+				_, err := writer2.WriteBatch(fakeIssuingValidator, fakeRWSet, tokenNameSpace, nil, nil, nil, []*writer2.BatchEntry{
+					{Binding: "a", Request: []byte("request-a"), Actions: []interface{}{faketransfer}},
+					{Binding: "b", Request: []byte("request-b"), Actions: []interface{}{fakeissue}},
+				}, false)
+				Expect(err).NotTo(HaveOccurred())
+				// an output and the request of a, two outputs and the request of b
+				Expect(fakeRWSet.SetStateCallCount()).To(Equal(5))
+				Expect(fakeRWSet.DeleteStateCallCount()).To(Equal(1))
+
+				key, err := keys.CreateTokenKey("a", 0)
+				Expect(err).NotTo(HaveOccurred())
+				_, id, out := fakeRWSet.SetStateArgsForCall(0)
+				Expect(id).To(Equal(key))
+				Expect(out).To(Equal([]byte("output")))
+				key, err = keys.CreateTokenKey("b", 1)
+				Expect(err).NotTo(HaveOccurred())
+				_, id, out = fakeRWSet.SetStateArgsForCall(3)
+				Expect(id).To(Equal(key))
+				Expect(out).To(Equal([]byte("output-2")))
+			})
+		})
+		When("two sub-requests spend the same token", func() {
+			It("fails without writing", func() {
+				_, err := writer2.WriteBatch(fakeIssuingValidator, fakeRWSet, tokenNameSpace, nil, nil, nil, []*writer2.BatchEntry{
+					{Binding: "a", Request: []byte("request-a"), Actions: []interface{}{faketransfer}},
+					{Binding: "b", Request: []byte("request-b"), Actions: []interface{}{faketransfer}},
+				}, false)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("failed writing sub-request [1][b]"))
+				Expect(errors2.HasCode(err, errors2.DoubleSpend)).To(BeTrue())
 				Expect(fakeRWSet.SetStateCallCount()).To(Equal(0))
+				Expect(fakeRWSet.DeleteStateCallCount()).To(Equal(0))
+				Expect(fakeRWSet.SetStateMetadataCallCount()).To(Equal(0))
+			})
+			It("records the second as rejected when skipping the invalid ones", func() {
+				rejected, err := writer2.WriteBatch(fakeIssuingValidator, fakeRWSet, tokenNameSpace, nil, nil, nil, []*writer2.BatchEntry{
+					{Binding: "a", Request: []byte("request-a"), Actions: []interface{}{faketransfer}},
+					{Binding: "b", Request: []byte("request-b"), Actions: []interface{}{faketransfer}},
+					{Binding: "c", Request: []byte("request-c"), Rejection: api.SignatureCheck},
+				}, true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rejected).To(HaveLen(2))
+				Expect(rejected[0].Binding).To(Equal("b"))
+				Expect(rejected[0].Rejection).To(Equal(api.DoubleSpendCheck))
+				Expect(rejected[1].Binding).To(Equal("c"))
 
+				// an output and the request of a, the rejections of b and c
+				Expect(fakeRWSet.SetStateCallCount()).To(Equal(4))
+				Expect(fakeRWSet.DeleteStateCallCount()).To(Equal(1))
+				key, err := keys.CreateTokenKey("a", 0)
+				Expect(err).NotTo(HaveOccurred())
+				_, id, _ := fakeRWSet.SetStateArgsForCall(0)
+				Expect(id).To(Equal(key))
+				key, err = keys.CreateRejectedTokenRequestKey("c")
+				Expect(err).NotTo(HaveOccurred())
+				_, id, reason := fakeRWSet.SetStateArgsForCall(3)
+				Expect(id).To(Equal(key))
+				Expect(reason).To(Equal([]byte(api.SignatureCheck)))
 			})
 		})
 	})
+
+	Describe("Stats", func() {
+		It("counts the accesses to the ledger by kind of key", func() {
+			rwset := writer2.NewStatsRWSet(fakeRWSet, nil)
+			writer = writer2.New(fakeIssuingValidator, "0", rwset, "zkat")
+			Expect(writer.CommitTokenRequest([]byte("request"))).To(Succeed())
+			Expect(rwset.SetState("zkat", sn[0], []byte("1"))).To(Succeed())
+			tokenKey, err := keys.CreateTokenKey("tx1", 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rwset.DeleteState("zkat", tokenKey)).To(Succeed())
+
+			stats := rwset.Stats()
+			Expect(stats.Reads).To(Equal(1))
+			Expect(stats.Writes).To(Equal(2))
+			Expect(stats.Deletes).To(Equal(1))
+			Expect(stats.ValueBytes).To(Equal(len("request") + 1))
+			Expect(stats.Kinds).To(HaveKeyWithValue(keys.TokenRequestKeyPrefix, &api.KeyStats{Reads: 1, Writes: 1}))
+			Expect(stats.Kinds).To(HaveKeyWithValue(keys.SerialNumber, &api.KeyStats{Writes: 1}))
+			Expect(stats.Kinds).To(HaveKeyWithValue("token", &api.KeyStats{Deletes: 1}))
+			Expect(stats.MaxKeySize).To(BeNumerically(">=", len(tokenKey)))
+			Expect(fakeRWSet.SetStateCallCount()).To(Equal(2))
+			Expect(fakeRWSet.DeleteStateCallCount()).To(Equal(1))
+		})
+	})
+
+	Describe("Events", func() {
+		It("records the written keys and verifies them against the rwset", func() {
+			rwset := writer2.NewEventRWSet(fakeRWSet, "0")
+			writer = writer2.New(fakeIssuingValidator, "0", rwset, "zkat")
+			Expect(writer.CommitTokenRequest([]byte("request"))).To(Succeed())
+			tokenKey, err := keys.CreateTokenKey("tx1", 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rwset.DeleteState("zkat", tokenKey)).To(Succeed())
+
+			event := rwset.Event()
+			Expect(event.TxID).To(Equal("0"))
+			Expect(event.Writes).To(HaveLen(2))
+			Expect(event.Writes[0].Deleted).To(BeFalse())
+			Expect(event.Writes[1]).To(Equal(&writer2.Write{Key: tokenKey, Deleted: true}))
+			raw, err := event.Bytes()
+			Expect(err).NotTo(HaveOccurred())
+			event = &writer2.TokenEvent{}
+			Expect(event.FromBytes(raw)).To(Succeed())
+
+			// the writes of the block, in a different order
+			writes := &mock.RWSet{}
+			writes.NumWritesReturns(2)
+			writes.GetWriteAtReturnsOnCall(0, tokenKey, nil, nil)
+			writes.GetWriteAtReturnsOnCall(1, event.Writes[0].Key, []byte("request"), nil)
+			Expect(event.Verify(writes, "zkat")).To(Succeed())
+
+			writes.GetWriteAtReturnsOnCall(2, tokenKey, []byte("token"), nil)
+			writes.GetWriteAtReturnsOnCall(3, event.Writes[0].Key, []byte("request"), nil)
+			Expect(event.Verify(writes, "zkat")).To(MatchError(ContainSubstring("lists a different write")))
+
+			writes.NumWritesReturns(3)
+			Expect(event.Verify(writes, "zkat")).To(MatchError(ContainSubstring("lists [2] writes, the transaction has [3]")))
+		})
+	})
+
+	Describe("Issuer policies", func() {
+		var (
+			states    map[string][]byte
+			typed     *typedIssueAction
+			validator *writer2.PolicyIssuingValidator
+		)
+		BeforeEach(func() {
+			states = map[string][]byte{}
+			fakeRWSet.GetStateStub = func(namespace string, key string, opts ...fabric.GetStateOpt) ([]byte, error) {
+				return states[key], nil
+			}
+			fakeRWSet.SetStateStub = func(namespace string, key string, value []byte) error {
+				states[key] = value
+				return nil
+			}
+			fakeRWSet.DeleteStateStub = func(namespace string, key string) error {
+				delete(states, key)
+				return nil
+			}
+			validator = writer2.NewPolicyIssuingValidator(fakeRWSet, tokenNameSpace, nil)
+			writer = writer2.New(validator, "0", fakeRWSet, tokenNameSpace)
+
+			fakeissue.GetSerializedOutputsReturns([][]byte{[]byte("output-1"), []byte("output-2")}, nil)
+			fakeissue.NumOutputsReturns(2)
+			fakeissue.GetIssuerReturns([]byte("alice"))
+			typed = &typedIssueAction{IssueAction: fakeissue, types: []string{"USD", "EUR"}}
+
+			Expect(writer2.WriteIssuerPolicy(fakeRWSet, tokenNameSpace, keys.Default, &writer2.IssuerPolicy{
+				Type:    "USD",
+				Issuers: []view.Identity{[]byte("alice"), []byte("bob")},
+			})).To(Succeed())
+		})
+		It("accepts the issuers in the policy of each type", func() {
+			Expect(writer.Write(typed)).To(Succeed())
+		})
+		It("rejects the issuers not in the policy", func() {
+			Expect(writer2.WriteIssuerPolicy(fakeRWSet, tokenNameSpace, keys.Default, &writer2.IssuerPolicy{
+				Type:    "EUR",
+				Issuers: []view.Identity{[]byte("bob")},
+			})).To(Succeed())
+			err := writer.Write(typed)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("is not allowed to issue tokens of type [EUR]"))
+			Expect(errors2.HasCode(err, errors2.Unauthorized)).To(BeTrue())
+		})
+		It("accepts any issuer once the policy is removed", func() {
+			Expect(validator.Validate([]byte("charlie"), "USD")).NotTo(Succeed())
+			Expect(writer2.WriteIssuerPolicy(fakeRWSet, tokenNameSpace, keys.Default, &writer2.IssuerPolicy{Type: "USD"})).To(Succeed())
+			Expect(validator.Validate([]byte("charlie"), "USD")).To(Succeed())
+			policy, err := writer2.ReadIssuerPolicy(fakeRWSet, tokenNameSpace, keys.Default, "USD")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy).To(BeNil())
+		})
+		It("validates the hidden types as the empty type", func() {
+			Expect(writer2.WriteIssuerPolicy(fakeRWSet, tokenNameSpace, keys.Default, &writer2.IssuerPolicy{
+				Issuers: []view.Identity{[]byte("bob")},
+			})).To(Succeed())
+			Expect(writer.Write(typed)).To(Succeed())
+			Expect(writer.Write(fakeissue)).NotTo(Succeed())
+		})
+		It("checks the issuers co-signing the redemptions", func() {
+			Expect(writer.Write(&api.BurnReceipt{Type: "USD", Quantity: "0x14", Issuer: []byte("bob")})).To(Succeed())
+			err := writer.Write(&api.BurnReceipt{Type: "USD", Quantity: "0x14", Issuer: []byte("charlie")})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("is not allowed to co-sign redemptions of type [USD]"))
+		})
+		It("rejects the issuers co-signing the redemptions of a type without policy", func() {
+			// any issuer can issue EUR, but its certificate chain is not verified, it must be listed to co-sign
+			Expect(validator.Validate([]byte("charlie"), "EUR")).To(Succeed())
+			err := writer.Write(&api.BurnReceipt{Type: "EUR", Quantity: "0x14", Issuer: []byte("charlie")})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("no issuer policy for type [EUR], redemptions cannot be co-signed"))
+			// redemptions without co-signature are not affected
+			Expect(writer.Write(&api.BurnReceipt{Type: "EUR", Quantity: "0x14"})).To(Succeed())
+		})
+	})
+	Describe("Supply", func() {
+		var (
+			committed map[string][]byte
+			written   map[string][]byte
+			usd       *supplyIssueAction
+		)
+		BeforeEach(func() {
+			// the rwset does not return its own writes, as in the chaincode
+			committed = map[string][]byte{}
+			written = map[string][]byte{}
+			fakeRWSet.GetStateStub = func(namespace string, key string, opts ...fabric.GetStateOpt) ([]byte, error) {
+				return committed[key], nil
+			}
+			fakeRWSet.SetStateStub = func(namespace string, key string, value []byte) error {
+				written[key] = value
+				return nil
+			}
+			fakeissue.GetSerializedOutputsReturns([][]byte{[]byte("output-1")}, nil)
+			fakeissue.NumOutputsReturns(1)
+			usd = &supplyIssueAction{IssueAction: fakeissue, tokenType: "USD", value: 30, revealed: true}
+
+			raw, err := (&writer2.Supply{Type: "USD", Issued: 50}).Bytes()
+			Expect(err).NotTo(HaveOccurred())
+			key, err := keys.CreateSupplyKey("USD")
+			Expect(err).NotTo(HaveOccurred())
+			committed[key] = raw
+		})
+		readSupply := func(tokenType string) *writer2.Supply {
+			key, err := keys.CreateSupplyKey(tokenType)
+			Expect(err).NotTo(HaveOccurred())
+			supply := &writer2.Supply{}
+			Expect(supply.FromBytes(written[key])).To(Succeed())
+			return supply
+		}
+		It("tracks the supply of the issued types", func() {
+			Expect(writer.Write(usd)).To(Succeed())
+			Expect(readSupply("USD")).To(Equal(&writer2.Supply{Type: "USD", Issued: 80}))
+			Expect(writer.Write(usd)).To(Succeed())
+			Expect(readSupply("USD")).To(Equal(&writer2.Supply{Type: "USD", Issued: 110}))
+
+			Expect(writer.Write(&supplyIssueAction{IssueAction: fakeissue, tokenType: "EUR", value: 5, revealed: true})).To(Succeed())
+			Expect(readSupply("EUR")).To(Equal(&writer2.Supply{Type: "EUR", Issued: 5}))
+		})
+		It("does not track the issue actions not revealing their supply", func() {
+			Expect(writer.Write(&supplyIssueAction{IssueAction: fakeissue, tokenType: "EUR"})).To(Succeed())
+			Expect(writer.Write(fakeissue)).To(Succeed())
+			key, err := keys.CreateSupplyKey("EUR")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(written).NotTo(HaveKey(key))
+		})
+		It("enforces the supply caps", func() {
+			writer.SupplyCaps = supplyCaps{"USD": 100}
+			Expect(writer.Write(usd)).To(Succeed())
+			err := writer.Write(usd)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("issuing [30] of [USD] exceeds its supply cap [100], already issued [80]"))
+			Expect(readSupply("USD").Issued).To(Equal(uint64(80)))
+
+			Expect(writer.Write(&supplyIssueAction{IssueAction: fakeissue, tokenType: "EUR", value: 1000, revealed: true})).To(Succeed())
+		})
+		It("fails on overflow", func() {
+			err := writer.Write(&supplyIssueAction{IssueAction: fakeissue, tokenType: "USD", value: math.MaxUint64, revealed: true})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("supply of [USD] overflows"))
+		})
+		It("tracks the value redeemed with a burn receipt", func() {
+			Expect(writer.Write(usd)).To(Succeed())
+			Expect(writer.Write(&api.BurnReceipt{Type: "USD", Quantity: "0x14"})).To(Succeed())
+			supply := readSupply("USD")
+			Expect(supply).To(Equal(&writer2.Supply{Type: "USD", Issued: 80, Redeemed: 20}))
+			Expect(supply.Circulating()).To(Equal(uint64(60)))
+
+			// the value redeemed does not free room under the supply cap
+			writer.SupplyCaps = supplyCaps{"USD": 100}
+			Expect(writer.Write(usd)).NotTo(Succeed())
+		})
+		It("fails on burn receipts with an invalid quantity", func() {
+			err := writer.Write(&api.BurnReceipt{Type: "USD", Quantity: "twenty"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid quantity in burn receipt"))
+		})
+	})
 })
+
+type supplyIssueAction struct {
+	*mock.IssueAction
+	tokenType string
+	value     uint64
+	revealed  bool
+}
+
+func (a *supplyIssueAction) GetIssuedSupply() (string, uint64, bool) {
+	return a.tokenType, a.value, a.revealed
+}
+
+type supplyCaps map[string]uint64
+
+func (c supplyCaps) SupplyCap(tokenType string) (uint64, bool) {
+	supplyCap, ok := c[tokenType]
+	return supplyCap, ok
+}
+
+type typedIssueAction struct {
+	*mock.IssueAction
+	types []string
+}
+
+func (a *typedIssueAction) GetTokenTypes() []string {
+	return a.types
+}
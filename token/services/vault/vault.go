@@ -8,11 +8,16 @@ package vault
 import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/fabric"
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/config"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/atrest"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/certification"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/services/vault/query"
 )
 
+var logger = flogging.MustGetLogger("token-sdk.vault")
+
 type Channel interface {
 	Name() string
 	Vault() *fabric.Vault
@@ -25,8 +30,13 @@ type Vault struct {
 }
 
 func NewVault(sp view.ServiceProvider, channel Channel, namespace string) *Vault {
+	// the drivers reject an invalid application when the token service is created
+	scheme, err := config.KeyScheme(sp, channel.Name(), namespace)
+	if err != nil {
+		logger.Errorf("failed loading key scheme for [%s:%s], using the default one [%s]", channel.Name(), namespace, err)
+	}
 	return &Vault{
-		queryEngine:          query.NewEngine(channel, namespace),
+		queryEngine:          query.NewEngine(channel, namespace, scheme).WithSealer(atrest.GetSealer(sp)),
 		certificationStorage: certification.NewStorage(sp, channel, namespace),
 	}
 }
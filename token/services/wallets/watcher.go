@@ -0,0 +1,135 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package wallets
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/flogging"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/core/config"
+)
+
+var logger = flogging.MustGetLogger("token-sdk.wallets")
+
+// Registry registers wallets at runtime, it is implemented by token.WalletManager
+type Registry interface {
+	RegisterOwnerWallet(id string, mspID string, path string) error
+	RegisterIssuerWallet(id string, mspID string, path string) error
+	RegisterAuditorWallet(id string, mspID string, path string) error
+}
+
+// Watcher watches a wallets configuration file, in the format of the wallets section of a TMS configuration,
+// and registers the owner, issuer, and auditor wallets it lists, as soon as they appear, without restarting the node.
+// Relative msp paths are resolved against the directory of the file.
+type Watcher struct {
+	registry Registry
+	path     string
+	interval time.Duration
+
+	lock    sync.Mutex
+	modTime time.Time
+	// registered maps the usage and id of the registered wallets to their msp path
+	registered map[string]string
+}
+
+func NewWatcher(registry Registry, path string, interval time.Duration) *Watcher {
+	return &Watcher{
+		registry:   registry,
+		path:       path,
+		interval:   interval,
+		registered: map[string]string{},
+	}
+}
+
+// Start loads the configuration file and then polls it, until the passed context is done
+func (w *Watcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			if err := w.Load(); err != nil {
+				logger.Errorf("failed loading wallets from [%s]: [%s]", w.path, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Load registers the wallets listed in the configuration file that are not registered yet.
+// The file is parsed again only if it has been modified since the last successful load.
+func (w *Watcher) Load() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		return errors.Wrapf(err, "failed accessing wallets configuration")
+	}
+	if fi.ModTime().Equal(w.modTime) {
+		return nil
+	}
+	raw, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		return errors.Wrapf(err, "failed reading wallets configuration")
+	}
+	wallets := &config.Wallets{}
+	if err := yaml.Unmarshal(raw, wallets); err != nil {
+		return errors.Wrapf(err, "failed parsing wallets configuration")
+	}
+
+	for _, owner := range wallets.Owners {
+		if err := w.register("owner", owner.ID, owner.MSPID, owner.Path, w.registry.RegisterOwnerWallet); err != nil {
+			return err
+		}
+	}
+	for _, issuer := range wallets.Issuers {
+		if err := w.register("issuer", issuer.ID, issuer.MSPID, issuer.Path, w.registry.RegisterIssuerWallet); err != nil {
+			return err
+		}
+	}
+	for _, auditor := range wallets.Auditors {
+		if err := w.register("auditor", auditor.ID, auditor.MSPID, auditor.Path, w.registry.RegisterAuditorWallet); err != nil {
+			return err
+		}
+	}
+	w.modTime = fi.ModTime()
+	return nil
+}
+
+func (w *Watcher) register(usage string, id string, mspID string, path string, register func(string, string, string) error) error {
+	if len(path) == 0 {
+		// the wallet is not meant to be registered at runtime
+		return nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(w.path), path)
+	}
+	key := usage + ":" + id
+	if registeredPath, ok := w.registered[key]; ok {
+		if registeredPath != path {
+			return errors.Errorf("%s wallet [%s] already registered from [%s], cannot register it from [%s]", usage, id, registeredPath, path)
+		}
+		return nil
+	}
+	if err := register(id, mspID, path); err != nil {
+		return errors.WithMessagef(err, "failed registering %s wallet [%s]", usage, id)
+	}
+	logger.Infof("%s wallet [%s] registered from [%s]", usage, id, path)
+	w.registered[key] = path
+	return nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package wallets
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type registry struct {
+	registered []string
+}
+
+func (r *registry) RegisterOwnerWallet(id string, mspID string, path string) error {
+	r.registered = append(r.registered, "owner:"+id+":"+mspID+":"+path)
+	return nil
+}
+
+func (r *registry) RegisterIssuerWallet(id string, mspID string, path string) error {
+	r.registered = append(r.registered, "issuer:"+id+":"+mspID+":"+path)
+	return nil
+}
+
+func (r *registry) RegisterAuditorWallet(id string, mspID string, path string) error {
+	r.registered = append(r.registered, "auditor:"+id+":"+mspID+":"+path)
+	return nil
+}
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wallets")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "wallets.yaml")
+
+	r := &registry{}
+	w := NewWatcher(r, path, time.Second)
+	assert.Error(t, w.Load())
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`
+owners:
+- id: alice
+  mspID: idemix
+  path: alice
+- id: static
+issuers:
+- id: issuer
+  mspID: Org1MSP
+  path: /msp/issuer
+`), 0644))
+	assert.NoError(t, w.Load())
+	assert.Equal(t, []string{
+		"owner:alice:idemix:" + filepath.Join(dir, "alice"),
+		"issuer:issuer:Org1MSP:/msp/issuer",
+	}, r.registered)
+
+	// new wallets are registered, known ones are skipped
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`
+owners:
+- id: alice
+  mspID: idemix
+  path: alice
+- id: bob
+  mspID: idemix
+  path: bob
+auditors:
+- id: auditor
+  mspID: Org1MSP
+  path: auditor
+`), 0644))
+	assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	assert.NoError(t, w.Load())
+	assert.Equal(t, []string{
+		"owner:alice:idemix:" + filepath.Join(dir, "alice"),
+		"issuer:issuer:Org1MSP:/msp/issuer",
+		"owner:bob:idemix:" + filepath.Join(dir, "bob"),
+		"auditor:auditor:Org1MSP:" + filepath.Join(dir, "auditor"),
+	}, r.registered)
+
+	// a known wallet cannot move to another msp
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`
+owners:
+- id: alice
+  mspID: idemix
+  path: alice2
+`), 0644))
+	assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(2*time.Minute)))
+	assert.Error(t, w.Load())
+}
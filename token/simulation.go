@@ -0,0 +1,141 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+// Simulation is the outcome of the local validation of a token request, see ManagementService.Simulate
+type Simulation struct {
+	// Report lists the actions that passed validation and, if any, the check that failed
+	Report *ValidationReport
+	// Err is the validation error, nil if the request is valid
+	Err error
+	// Missing lists the inputs of the request not found in the vault. The vault cannot tell whether they have been
+	// spent, not yet committed locally, or not kept at all, as in light mode, see Inconclusive.
+	Missing []string
+	// Size and CompressedSize are the sizes, in bytes, of the serialized token request, plain and compressed
+	Size           int
+	CompressedSize int
+	// Reads is the number of ledger keys read by the validation
+	Reads int
+	// Inputs and Outputs count the tokens spent and created by the actions of the request
+	Inputs  int
+	Outputs int
+	// VerificationTime is the time the validation took locally, an estimate of the cost of verifying the proofs
+	VerificationTime time.Duration
+}
+
+// Valid returns true if the simulated request passed validation
+func (s *Simulation) Valid() bool {
+	return s.Err == nil
+}
+
+// Inconclusive returns true if the simulated request failed validation with some of its inputs missing from the vault.
+// The failure might not happen on the ledger, the request must be endorsed to know.
+func (s *Simulation) Inconclusive() bool {
+	return s.Err != nil && len(s.Missing) != 0
+}
+
+// Failed returns true if the simulated request failed validation with all its inputs in the vault
+func (s *Simulation) Failed() bool {
+	return s.Err != nil && len(s.Missing) == 0
+}
+
+// Simulate validates the passed request, bound to its transaction ID, against the state of the vault, without
+// endorsing it. The signatures are checked as well, a request simulated before being signed fails a signature check.
+// The returned error reports the failures preventing the simulation, the validation failures are in the Simulation.
+// The vault of a node in light mode keeps only the tokens the node owns, the simulation of a request spending the
+// tokens of others is then inconclusive, see Simulation.Inconclusive.
+func (t *ManagementService) Simulate(request *Request, opts ...ValidationOption) (*Simulation, error) {
+	raw, err := request.RequestToBytes()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed serializing request [%s]", request.TxID)
+	}
+	compressed, err := request.RequestToCompressedBytes()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed compressing request [%s]", request.TxID)
+	}
+	return simulate(t.tms.Validator(), t.Vault().NewQueryEngine().GetState, request.TxID, raw, len(compressed), opts...)
+}
+
+// simulate validates the passed serialized request against the passed state, see Simulate
+func simulate(backend tokenapi.Validator, getState tokenapi.GetStateFnc, txID string, raw []byte, compressedSize int, opts ...ValidationOption) (*Simulation, error) {
+	sim := &Simulation{Size: len(raw), CompressedSize: compressedSize}
+
+	// the actions are counted even if they are not valid, if they are well-formed
+	actions, _ := backend.UnmarshalActions(raw)
+	for _, action := range actions {
+		switch a := action.(type) {
+		case spendingAction:
+			inputs, err := a.GetInputs()
+			if err != nil {
+				return nil, errors.WithMessagef(err, "failed getting inputs of request [%s]", txID)
+			}
+			for _, input := range inputs {
+				state, err := getState(input)
+				if err != nil {
+					return nil, errors.WithMessagef(err, "failed reading input [%s] of request [%s]", input, txID)
+				}
+				if len(state) == 0 {
+					sim.Missing = append(sim.Missing, input)
+				}
+			}
+			sim.Inputs += len(inputs)
+			sim.Outputs += a.NumOutputs()
+		case creatingAction:
+			sim.Outputs += a.NumOutputs()
+		}
+	}
+
+	ledger := &countingLedger{getState: getState}
+	start := time.Now()
+	actions, err := backend.VerifyTokenRequestFromRaw(ledger.GetState, txID, raw, opts...)
+	sim.VerificationTime = time.Since(start)
+	sim.Reads = ledger.reads
+	if err != nil {
+		sim.Err = err
+		report, ok := GetValidationReport(err)
+		if !ok {
+			report = &ValidationReport{}
+			report.Failed(tokenapi.RequestActionType, 0, "", err)
+		}
+		sim.Report = report
+		if len(sim.Missing) != 0 {
+			logger.Debugf("simulation of [%s] inconclusive, inputs [%v] not in the vault: [%s]", txID, sim.Missing, err)
+		}
+		return sim, nil
+	}
+	sim.Report = tokenapi.SucceededReport(actions)
+	return sim, nil
+}
+
+// spendingAction is implemented by the actions spending tokens, transfers, migrations, and rebindings
+type spendingAction interface {
+	GetInputs() ([]string, error)
+	NumOutputs() int
+}
+
+// creatingAction is implemented by the actions creating tokens
+type creatingAction interface {
+	NumOutputs() int
+}
+
+// countingLedger reads the state, counting the reads
+type countingLedger struct {
+	getState tokenapi.GetStateFnc
+	reads    int
+}
+
+func (l *countingLedger) GetState(key string) ([]byte, error) {
+	l.reads++
+	return l.getState(key)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+// simulatedTransfer spends the passed inputs into the passed number of outputs
+type simulatedTransfer struct {
+	inputs  []string
+	outputs int
+}
+
+func (a *simulatedTransfer) GetInputs() ([]string, error) {
+	return a.inputs, nil
+}
+
+func (a *simulatedTransfer) NumOutputs() int {
+	return a.outputs
+}
+
+// simulatedValidator reads the inputs of its actions and fails if any of them is not on the ledger,
+// or with the passed failure, if any
+type simulatedValidator struct {
+	tokenapi.Validator
+	actions []interface{}
+	failure error
+}
+
+func (v *simulatedValidator) UnmarshalActions(raw []byte) ([]interface{}, error) {
+	return v.actions, nil
+}
+
+func (v *simulatedValidator) VerifyTokenRequestFromRaw(getState tokenapi.GetStateFnc, binding string, raw []byte, opts ...tokenapi.ValidationOption) ([]interface{}, error) {
+	if v.failure != nil {
+		return nil, v.failure
+	}
+	report := &tokenapi.ValidationReport{}
+	for i, action := range v.actions {
+		inputs, _ := action.(*simulatedTransfer).GetInputs()
+		for _, input := range inputs {
+			state, err := getState(input)
+			if err != nil {
+				return nil, err
+			}
+			if len(state) == 0 {
+				return nil, report.Failed(tokenapi.TransferActionType, i, tokenapi.DoubleSpendCheck, errors.Errorf("input [%s] not found", input))
+			}
+		}
+		report.Succeeded(tokenapi.TransferActionType, i)
+	}
+	return v.actions, nil
+}
+
+func TestSimulate(t *testing.T) {
+	vault := map[string][]byte{"a": []byte("token-a"), "b": []byte("token-b")}
+	getState := func(key string) ([]byte, error) {
+		return vault[key], nil
+	}
+	backend := &simulatedValidator{actions: []interface{}{
+		&simulatedTransfer{inputs: []string{"a"}, outputs: 2},
+		&simulatedTransfer{inputs: []string{"b"}, outputs: 1},
+	}}
+
+	// all inputs in the vault
+	sim, err := simulate(backend, getState, "tx1", []byte("request"), 5)
+	assert.NoError(t, err)
+	assert.True(t, sim.Valid())
+	assert.False(t, sim.Failed())
+	assert.False(t, sim.Inconclusive())
+	assert.Equal(t, 7, sim.Size)
+	assert.Equal(t, 5, sim.CompressedSize)
+	assert.Equal(t, 2, sim.Reads)
+	assert.Equal(t, 2, sim.Inputs)
+	assert.Equal(t, 3, sim.Outputs)
+	assert.Empty(t, sim.Missing)
+	assert.True(t, sim.Report.Valid())
+
+	// an input not in the vault, the vault of a light node for instance, does not make the simulation fail
+	backend.actions = append(backend.actions, &simulatedTransfer{inputs: []string{"c"}, outputs: 1})
+	sim, err = simulate(backend, getState, "tx1", []byte("request"), 5)
+	assert.NoError(t, err)
+	assert.False(t, sim.Valid())
+	assert.False(t, sim.Failed())
+	assert.True(t, sim.Inconclusive())
+	assert.Equal(t, []string{"c"}, sim.Missing)
+	assert.Equal(t, 3, sim.Inputs)
+	assert.Equal(t, 4, sim.Outputs)
+	assert.Equal(t, tokenapi.DoubleSpendCheck, sim.Report.Failure().Check)
+	assert.Equal(t, 2, sim.Report.Failure().Index)
+
+	// a failure with all inputs in the vault is a failure
+	vault["c"] = []byte("token-c")
+	backend.failure = errors.New("invalid signature")
+	sim, err = simulate(backend, getState, "tx1", []byte("request"), 5)
+	assert.NoError(t, err)
+	assert.True(t, sim.Failed())
+	assert.False(t, sim.Inconclusive())
+	assert.Empty(t, sim.Missing)
+	assert.Equal(t, tokenapi.RequestActionType, sim.Report.Failure().Type)
+
+	// the vault cannot be read
+	_, err = simulate(backend, func(key string) ([]byte, error) {
+		return nil, errors.New("vault closed")
+	}, "tx1", []byte("request"), 5)
+	assert.Error(t, err)
+}
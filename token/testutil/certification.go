@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package testutil
+
+import (
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/selector"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+var (
+	_ api.CertificationClient      = &CertificationClient{}
+	_ selector.CertificationClient = &CertificationClient{}
+)
+
+// CertificationClient is an in-memory api.CertificationClient, the tokens whose certification is requested
+// are certified immediately, unless Err is set
+type CertificationClient struct {
+	// Err, if set, is returned by RequestCertification
+	Err error
+
+	lock      sync.Mutex
+	certified map[string]bool
+	requests  [][]*token2.Id
+}
+
+func (c *CertificationClient) IsCertified(id *token2.Id) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.certified[id.String()]
+}
+
+func (c *CertificationClient) RequestCertification(ids ...*token2.Id) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.requests = append(c.requests, ids)
+	if c.Err != nil {
+		return c.Err
+	}
+	c.certify(ids)
+	return nil
+}
+
+// Certify marks as certified the passed tokens
+func (c *CertificationClient) Certify(ids ...*token2.Id) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.certify(ids)
+}
+
+// Requests returns the certification requests received so far, in order
+func (c *CertificationClient) Requests() [][]*token2.Id {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([][]*token2.Id{}, c.requests...)
+}
+
+func (c *CertificationClient) certify(ids []*token2.Id) {
+	if c.certified == nil {
+		c.certified = map[string]bool{}
+	}
+	for _, id := range ids {
+		c.certified[id.String()] = true
+	}
+}
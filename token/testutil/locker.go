@@ -0,0 +1,63 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package testutil
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/services/selector"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+var _ selector.Locker = &Locker{}
+
+// Locker is an in-memory selector.Locker. Unlike the locker of the sdk, it never reclaims the locks
+// of the invalid transactions.
+type Locker struct {
+	lock   sync.Mutex
+	locked map[string]string
+}
+
+// Lock locks the passed token for the passed transaction, it returns the transaction holding the lock, if another one
+func (l *Locker) Lock(id *token2.Id, txID string) (string, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.locked == nil {
+		l.locked = map[string]string{}
+	}
+	if holder, ok := l.locked[id.String()]; ok && holder != txID {
+		return holder, errors.Errorf("already locked by [%s]", holder)
+	}
+	l.locked[id.String()] = txID
+	return "", nil
+}
+
+func (l *Locker) UnlockIDs(ids ...*token2.Id) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for _, id := range ids {
+		delete(l.locked, id.String())
+	}
+}
+
+func (l *Locker) UnlockByTxID(txID string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for id, holder := range l.locked {
+		if holder == txID {
+			delete(l.locked, id)
+		}
+	}
+}
+
+// LockedBy returns the transaction holding the lock on the passed token, empty if none
+func (l *Locker) LockedBy(id *token2.Id) string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.locked[id.String()]
+}
@@ -0,0 +1,217 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package testutil
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+var (
+	_ api.PublicParameters    = &PublicParameters{}
+	_ api.PublicParamsManager = &PublicParamsManager{}
+)
+
+// PublicParameters is a stub of api.PublicParameters returning the values of its fields
+type PublicParameters struct {
+	ID               string
+	DataHidden       bool
+	GraphHidden      bool
+	MaxValue         uint64
+	Certification    string
+	Auditor          view.Identity
+	IssueApprovers   map[string]view.Identity
+	TypeAuditors     map[string]view.Identity
+	RedeemWithIssuer bool
+}
+
+func (p *PublicParameters) Identifier() string {
+	return p.ID
+}
+
+func (p *PublicParameters) TokenDataHiding() bool {
+	return p.DataHidden
+}
+
+func (p *PublicParameters) GraphHiding() bool {
+	return p.GraphHidden
+}
+
+func (p *PublicParameters) MaxTokenValue() uint64 {
+	return p.MaxValue
+}
+
+func (p *PublicParameters) CertificationDriver() string {
+	return p.Certification
+}
+
+func (p *PublicParameters) AuditorIdentity() view.Identity {
+	return p.Auditor
+}
+
+// TypeAuditor returns the entry of TypeAuditors for the passed token type, Auditor if none
+func (p *PublicParameters) TypeAuditor(tokenType string) view.Identity {
+	if auditor, ok := p.TypeAuditors[tokenType]; ok {
+		return auditor
+	}
+	return p.Auditor
+}
+
+func (p *PublicParameters) IssueApprover(tokenType string) view.Identity {
+	return p.IssueApprovers[tokenType]
+}
+
+func (p *PublicParameters) RedeemRequiresIssuer() bool {
+	return p.RedeemWithIssuer
+}
+
+// Bytes returns the json encoding of the public parameters
+func (p *PublicParameters) Bytes() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// PublicParamsManager is a stub of api.PublicParamsManager. The setters record what they are passed and return Raw,
+// unless Err is set.
+type PublicParamsManager struct {
+	// PP is returned by PublicParameters
+	PP api.PublicParameters
+	// Raw is returned by the setters, as the updated public parameters
+	Raw []byte
+	// Err, if set, is returned by the setters and by ForceFetch
+	Err error
+	// CertifierPK and CertifierSK are returned by NewCertifierKeyPair
+	CertifierPK []byte
+	CertifierSK []byte
+
+	lock                 sync.Mutex
+	auditor              []byte
+	issuers              [][]byte
+	certifiers           [][]byte
+	issueApprovers       map[string][]byte
+	typeAuditors         map[string][]byte
+	redeemRequiresIssuer bool
+	fetches              int
+}
+
+func (m *PublicParamsManager) SetAuditor(auditor []byte) ([]byte, error) {
+	return m.update(func() { m.auditor = auditor })
+}
+
+func (m *PublicParamsManager) AddIssuer(bytes []byte) ([]byte, error) {
+	return m.update(func() { m.issuers = append(m.issuers, bytes) })
+}
+
+func (m *PublicParamsManager) PublicParameters() api.PublicParameters {
+	return m.PP
+}
+
+func (m *PublicParamsManager) SetCertifier(certifier []byte) ([]byte, error) {
+	return m.update(func() { m.certifiers = append(m.certifiers, certifier) })
+}
+
+func (m *PublicParamsManager) NewCertifierKeyPair() ([]byte, []byte, error) {
+	return m.CertifierPK, m.CertifierSK, nil
+}
+
+func (m *PublicParamsManager) SetIssueApprover(tokenType string, approver []byte) ([]byte, error) {
+	return m.update(func() {
+		if m.issueApprovers == nil {
+			m.issueApprovers = map[string][]byte{}
+		}
+		if len(approver) == 0 {
+			delete(m.issueApprovers, tokenType)
+			return
+		}
+		m.issueApprovers[tokenType] = approver
+	})
+}
+
+func (m *PublicParamsManager) SetTypeAuditor(tokenType string, auditor []byte) ([]byte, error) {
+	return m.update(func() {
+		if m.typeAuditors == nil {
+			m.typeAuditors = map[string][]byte{}
+		}
+		if len(auditor) == 0 {
+			delete(m.typeAuditors, tokenType)
+			return
+		}
+		m.typeAuditors[tokenType] = auditor
+	})
+}
+
+func (m *PublicParamsManager) SetRedeemRequiresIssuer(required bool) ([]byte, error) {
+	return m.update(func() { m.redeemRequiresIssuer = required })
+}
+
+func (m *PublicParamsManager) ForceFetch() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.fetches++
+	return m.Err
+}
+
+// Auditor returns the auditor last set
+func (m *PublicParamsManager) Auditor() []byte {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.auditor
+}
+
+// Issuers returns the issuers added so far
+func (m *PublicParamsManager) Issuers() [][]byte {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([][]byte{}, m.issuers...)
+}
+
+// Certifiers returns the certifiers set so far
+func (m *PublicParamsManager) Certifiers() [][]byte {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([][]byte{}, m.certifiers...)
+}
+
+// IssueApprover returns the approver set for the passed token type, nil if none
+func (m *PublicParamsManager) IssueApprover(tokenType string) []byte {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.issueApprovers[tokenType]
+}
+
+// TypeAuditor returns the auditor set for the passed token type, nil if none
+func (m *PublicParamsManager) TypeAuditor(tokenType string) []byte {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.typeAuditors[tokenType]
+}
+
+// RedeemRequiresIssuer returns the value last set with SetRedeemRequiresIssuer
+func (m *PublicParamsManager) RedeemRequiresIssuer() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.redeemRequiresIssuer
+}
+
+// Fetches returns the number of calls to ForceFetch
+func (m *PublicParamsManager) Fetches() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.fetches
+}
+
+func (m *PublicParamsManager) update(f func()) ([]byte, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	f()
+	return m.Raw, nil
+}
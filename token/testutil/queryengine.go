@@ -0,0 +1,222 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package testutil
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+var _ api.QueryEngine = &QueryEngine{}
+
+// StoredToken is a token of a QueryEngine
+type StoredToken struct {
+	ID    *token2.Id
+	Token *token2.Token
+	// EnrollmentID is the enrollment ID of the owner of the token
+	EnrollmentID string
+	// Commitment is the token as stored on the ledger
+	Commitment []byte
+	// Info is the information needed to spend the token
+	Info  []byte
+	Spent bool
+}
+
+// QueryEngine is an in-memory api.QueryEngine, filled with AddToken, AddIssuedToken, and the setters
+type QueryEngine struct {
+	lock     sync.RWMutex
+	ids      []string
+	tokens   map[string]*StoredToken
+	issued   []*token2.IssuedToken
+	pp       []byte
+	version  uint64
+	supplies map[string]*api.Supply
+	states   map[string][]byte
+}
+
+// AddToken adds the passed token, unspent unless marked as spent
+func (q *QueryEngine) AddToken(t *StoredToken) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.tokens == nil {
+		q.tokens = map[string]*StoredToken{}
+	}
+	if _, ok := q.tokens[t.ID.String()]; !ok {
+		q.ids = append(q.ids, t.ID.String())
+	}
+	q.tokens[t.ID.String()] = t
+}
+
+// Spend marks as spent the tokens with the passed ids
+func (q *QueryEngine) Spend(ids ...*token2.Id) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for _, id := range ids {
+		if t, ok := q.tokens[id.String()]; ok {
+			t.Spent = true
+		}
+	}
+}
+
+// AddIssuedToken adds the passed token to the history of the issued tokens
+func (q *QueryEngine) AddIssuedToken(t *token2.IssuedToken) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.issued = append(q.issued, t)
+}
+
+// SetPublicParams sets the public parameters and their version
+func (q *QueryEngine) SetPublicParams(raw []byte, version uint64) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.pp = raw
+	q.version = version
+}
+
+// SetSupply sets the supply of its token type
+func (q *QueryEngine) SetSupply(s *api.Supply) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.supplies == nil {
+		q.supplies = map[string]*api.Supply{}
+	}
+	q.supplies[s.Type] = s
+}
+
+// SetState sets the value of the passed ledger key
+func (q *QueryEngine) SetState(key string, value []byte) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.states == nil {
+		q.states = map[string][]byte{}
+	}
+	q.states[key] = value
+}
+
+func (q *QueryEngine) IsMine(id *token2.Id) (bool, error) {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	_, ok := q.tokens[id.String()]
+	return ok, nil
+}
+
+func (q *QueryEngine) ListUnspentTokens() (*token2.UnspentTokens, error) {
+	return q.unspent(func(*StoredToken) bool { return true }), nil
+}
+
+func (q *QueryEngine) UnspentTokensByEnrollmentID(eID string) (*token2.UnspentTokens, error) {
+	return q.unspent(func(t *StoredToken) bool { return t.EnrollmentID == eID }), nil
+}
+
+func (q *QueryEngine) UnspentAuditTokensByEnrollmentID(eID string) (*token2.UnspentTokens, error) {
+	return q.unspent(func(t *StoredToken) bool { return t.EnrollmentID == eID }), nil
+}
+
+func (q *QueryEngine) ListAuditTokens(ids ...*token2.Id) ([]*token2.Token, error) {
+	var res []*token2.Token
+	err := q.forEach(ids, false, func(t *StoredToken) error {
+		res = append(res, t.Token)
+		return nil
+	})
+	return res, err
+}
+
+func (q *QueryEngine) ListHistoryIssuedTokens() (*token2.IssuedTokens, error) {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	return &token2.IssuedTokens{Tokens: append([]*token2.IssuedToken{}, q.issued...)}, nil
+}
+
+func (q *QueryEngine) PublicParams() ([]byte, error) {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	return q.pp, nil
+}
+
+func (q *QueryEngine) PublicParamsVersion() (uint64, error) {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	return q.version, nil
+}
+
+// Supply returns the supply set for the passed token type, a zero supply if none
+func (q *QueryEngine) Supply(tokenType string) (*api.Supply, error) {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	if s, ok := q.supplies[tokenType]; ok {
+		return s, nil
+	}
+	return &api.Supply{Type: tokenType}, nil
+}
+
+func (q *QueryEngine) GetTokenInfos(ids []*token2.Id, callback api.QueryCallbackFunc) error {
+	return q.forEach(ids, false, func(t *StoredToken) error {
+		return callback(t.ID, t.Info)
+	})
+}
+
+func (q *QueryEngine) GetTokenCommitments(ids []*token2.Id, callback api.QueryCallbackFunc) error {
+	return q.forEach(ids, false, func(t *StoredToken) error {
+		return callback(t.ID, t.Commitment)
+	})
+}
+
+// GetTokens returns the passed tokens, it fails if any of them is spent
+func (q *QueryEngine) GetTokens(inputs ...*token2.Id) ([]*token2.Token, error) {
+	var res []*token2.Token
+	err := q.forEach(inputs, true, func(t *StoredToken) error {
+		res = append(res, t.Token)
+		return nil
+	})
+	return res, err
+}
+
+func (q *QueryEngine) GetState(key string) ([]byte, error) {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	return q.states[key], nil
+}
+
+func (q *QueryEngine) unspent(filter func(*StoredToken) bool) *token2.UnspentTokens {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	res := &token2.UnspentTokens{}
+	for _, id := range q.ids {
+		t := q.tokens[id]
+		if t.Spent || !filter(t) {
+			continue
+		}
+		res.Tokens = append(res.Tokens, &token2.UnspentToken{
+			Id:       t.ID,
+			Owner:    t.Token.Owner,
+			Type:     t.Token.Type,
+			Quantity: t.Token.Quantity,
+		})
+	}
+	return res
+}
+
+func (q *QueryEngine) forEach(ids []*token2.Id, unspent bool, f func(*StoredToken) error) error {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	for _, id := range ids {
+		t, ok := q.tokens[id.String()]
+		if !ok {
+			return errors.Errorf("token [%s] not found", id)
+		}
+		if unspent && t.Spent {
+			return errors.Errorf("token [%s] is spent", id)
+		}
+		if err := f(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package testutil
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
+)
+
+func TestQueryEngine(t *testing.T) {
+	q := &QueryEngine{}
+	id1 := &token2.Id{TxId: "tx1", Index: 0}
+	id2 := &token2.Id{TxId: "tx1", Index: 1}
+	q.AddToken(&StoredToken{ID: id1, Token: &token2.Token{Type: "USD", Quantity: "0x0a"}, EnrollmentID: "alice", Info: []byte("info1")})
+	q.AddToken(&StoredToken{ID: id2, Token: &token2.Token{Type: "EUR", Quantity: "0x05"}, EnrollmentID: "bob"})
+
+	unspent, err := q.ListUnspentTokens()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, unspent.Count())
+	unspent, err = q.UnspentTokensByEnrollmentID("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, unspent.Count())
+	assert.Equal(t, id1, unspent.Tokens[0].Id)
+
+	var infos [][]byte
+	assert.NoError(t, q.GetTokenInfos([]*token2.Id{id1}, func(id *token2.Id, raw []byte) error {
+		infos = append(infos, raw)
+		return nil
+	}))
+	assert.Equal(t, [][]byte{[]byte("info1")}, infos)
+
+	q.Spend(id1)
+	unspent, err = q.ListUnspentTokens()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, unspent.Count())
+	_, err = q.GetTokens(id1)
+	assert.EqualError(t, err, "token [[tx1:0]] is spent")
+	_, err = q.GetTokens(&token2.Id{TxId: "tx2"})
+	assert.EqualError(t, err, "token [[tx2:0]] not found")
+	toks, err := q.ListAuditTokens(id1, id2)
+	assert.NoError(t, err)
+	assert.Len(t, toks, 2)
+
+	s, err := q.Supply("USD")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), s.Issued)
+}
+
+func TestLocker(t *testing.T) {
+	l := &Locker{}
+	id := &token2.Id{TxId: "tx1"}
+
+	holder, err := l.Lock(id, "a")
+	assert.NoError(t, err)
+	assert.Empty(t, holder)
+	_, err = l.Lock(id, "a")
+	assert.NoError(t, err)
+	holder, err = l.Lock(id, "b")
+	assert.Error(t, err)
+	assert.Equal(t, "a", holder)
+
+	l.UnlockByTxID("a")
+	assert.Empty(t, l.LockedBy(id))
+	_, err = l.Lock(id, "b")
+	assert.NoError(t, err)
+	l.UnlockIDs(id)
+	assert.Empty(t, l.LockedBy(id))
+}
+
+func TestCertificationClient(t *testing.T) {
+	c := &CertificationClient{}
+	id := &token2.Id{TxId: "tx1"}
+
+	assert.False(t, c.IsCertified(id))
+	assert.NoError(t, c.RequestCertification(id))
+	assert.True(t, c.IsCertified(id))
+
+	c.Err = errors.New("certifier unavailable")
+	other := &token2.Id{TxId: "tx2"}
+	assert.EqualError(t, c.RequestCertification(other), "certifier unavailable")
+	assert.False(t, c.IsCertified(other))
+	assert.Len(t, c.Requests(), 2)
+}
+
+func TestPublicParamsManager(t *testing.T) {
+	m := &PublicParamsManager{Raw: []byte("pp"), PP: &PublicParameters{ID: "fabtoken"}}
+
+	raw, err := m.AddIssuer([]byte("issuer"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("pp"), raw)
+	_, err = m.SetCertifier([]byte("c1"))
+	assert.NoError(t, err)
+	_, err = m.SetCertifier([]byte("c2"))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("issuer")}, m.Issuers())
+	assert.Equal(t, [][]byte{[]byte("c1"), []byte("c2")}, m.Certifiers())
+	assert.Equal(t, "fabtoken", m.PublicParameters().Identifier())
+
+	m.Err = errors.New("invalid")
+	_, err = m.SetAuditor([]byte("auditor"))
+	assert.EqualError(t, err, "invalid")
+	assert.Nil(t, m.Auditor())
+}
+
+func TestValidator(t *testing.T) {
+	v := &Validator{}
+	actions, err := v.VerifyTokenRequestFromRaw(nil, "tx1", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, actions)
+
+	v.VerifyTokenRequestFromRawFunc = func(getState api.GetStateFnc, binding string, raw []byte, opts ...api.ValidationOption) ([]interface{}, error) {
+		return nil, errors.New("invalid")
+	}
+	_, err = v.VerifyTokenRequestFromRaw(nil, "tx2", nil)
+	assert.EqualError(t, err, "invalid")
+	assert.Equal(t, []string{"tx1", "tx2"}, v.Bindings())
+}
@@ -0,0 +1,72 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package testutil provides hand-written stubs of the interfaces of the sdk, to unit test the applications
+// without generating fakes. The stubs are safe for concurrent use and their zero value is ready to use.
+package testutil
+
+import (
+	"sync"
+
+	"github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+var _ api.Validator = &Validator{}
+
+// Validator is a stub of api.Validator. Each method calls the function of the same name, if set,
+// otherwise it accepts any token request, returning no actions.
+type Validator struct {
+	VerifyTokenRequestFunc        func(ledger api.Ledger, signatureProvider api.SignatureProvider, binding string, tr *api.TokenRequest, opts ...api.ValidationOption) ([]interface{}, error)
+	VerifyTokenRequestFromRawFunc func(getState api.GetStateFnc, binding string, raw []byte, opts ...api.ValidationOption) ([]interface{}, error)
+	UnmarshalActionsFunc          func(raw []byte) ([]interface{}, error)
+	VerifyOwnershipFunc           func(proof *api.OwnershipProof) error
+
+	lock     sync.Mutex
+	bindings []string
+}
+
+func (v *Validator) VerifyTokenRequest(ledger api.Ledger, signatureProvider api.SignatureProvider, binding string, tr *api.TokenRequest, opts ...api.ValidationOption) ([]interface{}, error) {
+	v.record(binding)
+	if v.VerifyTokenRequestFunc != nil {
+		return v.VerifyTokenRequestFunc(ledger, signatureProvider, binding, tr, opts...)
+	}
+	return nil, nil
+}
+
+func (v *Validator) VerifyTokenRequestFromRaw(getState api.GetStateFnc, binding string, raw []byte, opts ...api.ValidationOption) ([]interface{}, error) {
+	v.record(binding)
+	if v.VerifyTokenRequestFromRawFunc != nil {
+		return v.VerifyTokenRequestFromRawFunc(getState, binding, raw, opts...)
+	}
+	return nil, nil
+}
+
+func (v *Validator) UnmarshalActions(raw []byte) ([]interface{}, error) {
+	if v.UnmarshalActionsFunc != nil {
+		return v.UnmarshalActionsFunc(raw)
+	}
+	return nil, nil
+}
+
+func (v *Validator) VerifyOwnership(proof *api.OwnershipProof) error {
+	if v.VerifyOwnershipFunc != nil {
+		return v.VerifyOwnershipFunc(proof)
+	}
+	return nil
+}
+
+// Bindings returns the bindings of the token requests verified so far, in order
+func (v *Validator) Bindings() []string {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return append([]string{}, v.bindings...)
+}
+
+func (v *Validator) record(binding string) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.bindings = append(v.bindings, binding)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view"
 	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
 	"github.com/hyperledger-labs/fabric-token-sdk/token/core"
+	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
 // ServiceProvider is used to return instances of a given type
@@ -84,6 +85,8 @@ type ManagementService struct {
 	certificationClientProvider CertificationClientProvider
 	selectorManagerProvider     SelectorManagerProvider
 	signatureService            *SignatureService
+	// balances delivers the balance events of the wallets of this service, see OwnerWallet.Subscribe
+	balances *balanceBroker
 }
 
 func (t *ManagementService) String() string {
@@ -121,6 +124,20 @@ func (t *ManagementService) NewMetadataFromBytes(raw []byte) (*Metadata, error)
 	}, nil
 }
 
+// VerifyTokenInfo checks that the passed token information, received out of band for instance, opens the passed
+// output, and returns the token in the clear.
+// Applications call it before relying on a payment whose details they did not get from the token request.
+func (t *ManagementService) VerifyTokenInfo(outputRaw []byte, tokenInfoRaw []byte) (*token2.Token, error) {
+	if err := t.tms.VerifyTokenInfo(outputRaw, tokenInfoRaw); err != nil {
+		return nil, errors.WithMessagef(err, "token information does not open the output")
+	}
+	tok, _, err := t.tms.DeserializeToken(outputRaw, tokenInfoRaw)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed deserializing token")
+	}
+	return tok, nil
+}
+
 func (t *ManagementService) Validator() *Validator {
 	return &Validator{backend: t.tms.Validator()}
 }
@@ -130,7 +147,7 @@ func (t *ManagementService) Vault() *Vault {
 }
 
 func (t *ManagementService) WalletManager() *WalletManager {
-	return &WalletManager{ts: t.tms}
+	return &WalletManager{ts: t.tms, ms: t}
 }
 
 func (t *ManagementService) CertificationManager() *CertificationManager {
@@ -151,8 +168,45 @@ func (t *ManagementService) PublicParametersManager() *PublicParametersManager {
 	return &PublicParametersManager{ppm: t.tms.PublicParamsManager()}
 }
 
-func (t *ManagementService) SelectorManager() SelectorManager {
-	return t.selectorManagerProvider.SelectorManager(t.Network(), t.Channel(), t.Namespace())
+// PinPublicParameters pins the hash of the public parameters expected for this TMS.
+// It must be called before RefreshPublicParameters when the public parameters are updated on purpose.
+func (t *ManagementService) PinPublicParameters(hash []byte) error {
+	cache := core.GetPublicParamsCache(t.sp)
+	if cache == nil {
+		return errors.New("public parameters cache not available")
+	}
+	return cache.Pin(t.Network(), t.Channel(), t.Namespace(), hash)
+}
+
+// RefreshPublicParameters fetches again the public parameters of this TMS and, if they match the pinned hash,
+// replaces the cached ones
+func (t *ManagementService) RefreshPublicParameters() error {
+	if err := t.PublicParametersManager().ForceFetch(); err != nil {
+		return errors.WithMessagef(err, "failed refreshing public parameters of [%s]", t)
+	}
+	return nil
+}
+
+// UpdatePublicParameters pins and loads the passed public parameters, published by an update this node
+// has requested, for instance the registration of an auditor
+func (t *ManagementService) UpdatePublicParameters(raw []byte) error {
+	if len(raw) == 0 {
+		return errors.New("public parameters not specified")
+	}
+	if cache := core.GetPublicParamsCache(t.sp); cache != nil {
+		if err := cache.Pin(t.Network(), t.Channel(), t.Namespace(), core.HashPublicParams(raw)); err != nil {
+			return errors.WithMessagef(err, "failed pinning public parameters of [%s]", t)
+		}
+	}
+	return t.RefreshPublicParameters()
+}
+
+func (t *ManagementService) SelectorManager() (SelectorManager, error) {
+	sm, err := t.selectorManagerProvider.SelectorManager(t.Network(), t.Channel(), t.Namespace())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting selector manager of [%s]", t)
+	}
+	return sm, nil
 }
 
 func (t *ManagementService) SigService() *SignatureService {
@@ -165,9 +219,9 @@ func GetManagementService(sp ServiceProvider, opts ...ServiceOption) *Management
 
 func NewServicesFromPublicParams(params []byte) (*PublicParametersManager, *Validator, error) {
 	logger.Debugf("unmarshall public parameters...")
-	pp, err := core.PublicParametersFromBytes(params)
+	pp, err := checkedPublicParametersFromBytes(params)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed unmarshalling public parameters")
+		return nil, nil, err
 	}
 
 	logger.Debugf("instantiate public parameters manager...")
@@ -184,3 +238,18 @@ func NewServicesFromPublicParams(params []byte) (*PublicParametersManager, *Vali
 
 	return &PublicParametersManager{ppm: ppm}, &Validator{backend: validator}, nil
 }
+
+// checkedPublicParametersFromBytes unmarshals the passed public parameters and refuses them
+// if they can check themselves and are malformed
+func checkedPublicParametersFromBytes(params []byte) (tokenapi.PublicParameters, error) {
+	pp, err := core.PublicParametersFromBytes(params)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed unmarshalling public parameters")
+	}
+	if checkable, ok := pp.(tokenapi.SelfCheckable); ok {
+		if err := checkable.SelfCheck(); err != nil {
+			return nil, errors.WithMessage(err, "refusing public parameters")
+		}
+	}
+	return pp, nil
+}
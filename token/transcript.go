@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/pkg/errors"
+
+	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+const transcriptPrefix = "token-sdk.transcript"
+
+type (
+	Transcript     = tokenapi.Transcript
+	TranscriptRead = tokenapi.TranscriptRead
+)
+
+// RecordTranscript verifies the passed serialized request, bound to the passed transaction ID, against the passed
+// ledger state and stores the record of the verification, to be kept for dispute resolution.
+// It is called at commit time, see processor.WithTranscripts, the passed state being the one the transaction is
+// committed against, when its inputs are still unspent.
+func (t *ManagementService) RecordTranscript(txID string, raw []byte, getState tokenapi.GetStateFnc, opts ...ValidationOption) error {
+	pp, err := t.PublicParametersManager().Bytes()
+	if err != nil {
+		return errors.WithMessagef(err, "failed serializing public parameters of [%s]", t)
+	}
+	transcript, err := tokenapi.NewTranscript(t.tms.Validator(), pp, getState, txID, raw, opts...)
+	if err != nil {
+		return errors.WithMessagef(err, "failed verifying request [%s]", txID)
+	}
+	k, err := t.transcriptKey(txID)
+	if err != nil {
+		return err
+	}
+	if err := kvs.GetService(t.sp).Put(k, transcript); err != nil {
+		return errors.WithMessagef(err, "failed storing transcript of [%s]", txID)
+	}
+	return nil
+}
+
+// Transcript returns the record of the verification of the request of the passed transaction, taken when the
+// transaction was committed. It fails if none was recorded, the transcripts are recorded only if enabled in the
+// configuration of this service, and only for the transactions known to this node.
+func (t *ManagementService) Transcript(txID string) (*Transcript, error) {
+	k, err := t.transcriptKey(txID)
+	if err != nil {
+		return nil, err
+	}
+	kvss := kvs.GetService(t.sp)
+	if !kvss.Exists(k) {
+		return nil, errors.Errorf("no transcript recorded for [%s]", txID)
+	}
+	transcript := &Transcript{}
+	if err := kvss.Get(k, transcript); err != nil {
+		return nil, errors.WithMessagef(err, "failed loading transcript of [%s]", txID)
+	}
+	return transcript, nil
+}
+
+func (t *ManagementService) transcriptKey(txID string) (string, error) {
+	return kvs.CreateCompositeKey(transcriptPrefix, []string{t.Network(), t.Channel(), t.Namespace(), txID})
+}
+
+// VerifyTranscript verifies again, offline, the serialized transcript with the validator of the passed public
+// parameters. It returns the report of the new verification, and an error if the outcome differs from the
+// recorded one. The passed options, the validation hooks of the application for instance, are added to the
+// recorded ones.
+func VerifyTranscript(pp []byte, raw []byte, opts ...ValidationOption) (*ValidationReport, error) {
+	transcript, err := tokenapi.TranscriptFromBytes(raw)
+	if err != nil {
+		return nil, err
+	}
+	_, validator, err := NewServicesFromPublicParams(pp)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed instantiating validator")
+	}
+	return transcript.Verify(validator.backend, pp, opts...)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"testing"
+
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/api"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/kvs"
+	"github.com/hyperledger-labs/fabric-smart-client/platform/view/services/registry"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+// transcriptTMS is the service of a driver whose requests spend the input named by the request, see inputValidator
+type transcriptTMS struct {
+	tokenapi.TokenManagerService
+}
+
+func (s *transcriptTMS) Validator() tokenapi.Validator {
+	return &inputValidator{}
+}
+
+func (s *transcriptTMS) PublicParamsManager() tokenapi.PublicParamsManager {
+	return &transcriptPPM{}
+}
+
+type inputValidator struct {
+	tokenapi.Validator
+}
+
+func (v *inputValidator) VerifyTokenRequestFromRaw(getState tokenapi.GetStateFnc, binding string, raw []byte, opts ...tokenapi.ValidationOption) ([]interface{}, error) {
+	value, err := getState(string(raw))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		report := &tokenapi.ValidationReport{}
+		return nil, report.Failed(tokenapi.TransferActionType, 0, tokenapi.DoubleSpendCheck, errors.Errorf("input [%s] spent", raw))
+	}
+	return []interface{}{&tokenapi.MigrationAction{}}, nil
+}
+
+type transcriptPPM struct {
+	tokenapi.PublicParamsManager
+}
+
+func (p *transcriptPPM) PublicParameters() tokenapi.PublicParameters {
+	return &transcriptPP{}
+}
+
+type transcriptPP struct {
+	tokenapi.PublicParameters
+}
+
+func (p *transcriptPP) Bytes() ([]byte, error) {
+	return []byte("pp"), nil
+}
+
+type configProvider struct {
+	api.ConfigProvider
+}
+
+func (*configProvider) UnmarshalKey(string, interface{}) error {
+	return nil
+}
+
+func TestRecordTranscript(t *testing.T) {
+	sp := registry.New()
+	assert.NoError(t, sp.RegisterService(&configProvider{}))
+	kvss, err := kvs.New("memory", "", sp)
+	assert.NoError(t, err)
+	assert.NoError(t, sp.RegisterService(kvss))
+	tms := &ManagementService{sp: sp, network: "n", channel: "ch", namespace: "ns", tms: &transcriptTMS{}}
+	other := &ManagementService{sp: sp, network: "n", channel: "ch", namespace: "other", tms: &transcriptTMS{}}
+
+	_, err = tms.Transcript("tx1")
+	assert.Error(t, err)
+
+	// the transcript is taken against the state the transaction is committed against, the input is unspent
+	vault := map[string][]byte{"a": []byte("token-a")}
+	getState := func(key string) ([]byte, error) { return vault[key], nil }
+	assert.NoError(t, tms.RecordTranscript("tx1", []byte("a"), getState))
+
+	// and it is still valid once the input is spent
+	delete(vault, "a")
+	transcript, err := tms.Transcript("tx1")
+	assert.NoError(t, err)
+	assert.True(t, transcript.Report.Valid())
+	assert.Equal(t, "tx1", transcript.Binding)
+	assert.Equal(t, []*TranscriptRead{{Key: "a", Value: []byte("token-a")}}, transcript.Reads)
+	report, err := transcript.Verify(&inputValidator{}, []byte("pp"))
+	assert.NoError(t, err)
+	assert.True(t, report.Valid())
+
+	// the transcripts are kept per service
+	_, err = other.Transcript("tx1")
+	assert.Error(t, err)
+}
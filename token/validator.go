@@ -6,7 +6,12 @@ SPDX-License-Identifier: Apache-2.0
 package token
 
 import (
+	"context"
+	"time"
+
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
+
 	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
 )
 
@@ -22,12 +27,112 @@ type SignatureProvider interface {
 	HasBeenSignedBy(id view.Identity, verifier Verifier) error
 }
 
+type ValidationOption = tokenapi.ValidationOption
+
+// WithTxTime sets the time, as fixed by the ledger, of the transaction the token request is bound to
+func WithTxTime(txTime time.Time) ValidationOption {
+	return tokenapi.WithTxTime(txTime)
+}
+
+// DefaultMaxClockSkew is the default bound on the deviation of the time of a transaction from the local clock
+const DefaultMaxClockSkew = 5 * time.Minute
+
+// CheckTxTime checks that the passed transaction time, as set by the creator of the transaction, deviates from now
+// by at most maxSkew, DefaultMaxClockSkew if zero. The committing and the approving parties validate a token request
+// at the same transaction time, its creator can only move it within the skew.
+func CheckTxTime(txTime, now time.Time, maxSkew time.Duration) error {
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxClockSkew
+	}
+	if txTime.IsZero() {
+		return errors.New("transaction time not set")
+	}
+	skew := txTime.Sub(now)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > maxSkew {
+		return errors.Errorf("transaction time [%s] deviates from the local time [%s] by more than [%s]", txTime, now, maxSkew)
+	}
+	return nil
+}
+
+// WithContext sets the context bounding the verification of the token request, for instance to impose a deadline
+func WithContext(ctx context.Context) ValidationOption {
+	return tokenapi.WithContext(ctx)
+}
+
+// WithHeight sets the height of the ledger the token request is validated at.
+// It is needed to enforce the cutover of a driver migration.
+func WithHeight(height uint64) ValidationOption {
+	return tokenapi.WithHeight(height)
+}
+
+type (
+	IssueValidationHook    = tokenapi.IssueValidationHook
+	TransferValidationHook = tokenapi.TransferValidationHook
+	RequestValidationHook  = tokenapi.RequestValidationHook
+)
+
+// WithIssueHook appends a hook to be invoked, during validation, on each issue action
+func WithIssueHook(hook IssueValidationHook) ValidationOption {
+	return tokenapi.WithIssueHook(hook)
+}
+
+// WithTransferHook appends a hook to be invoked, during validation, on each transfer action
+func WithTransferHook(hook TransferValidationHook) ValidationOption {
+	return tokenapi.WithTransferHook(hook)
+}
+
+// WithRequestHook appends a hook to be invoked, during validation, on the token request once all its actions are valid
+func WithRequestHook(hook RequestValidationHook) ValidationOption {
+	return tokenapi.WithRequestHook(hook)
+}
+
+type RequestLimits = tokenapi.RequestLimits
+
+// WithRequestLimits sets the limits on the size and complexity of the token request,
+// enforced before running any cryptographic check
+func WithRequestLimits(limits *RequestLimits) ValidationOption {
+	return tokenapi.WithRequestLimits(limits)
+}
+
+type (
+	MigrationParams   = tokenapi.MigrationParams
+	MigrationAction   = tokenapi.MigrationAction
+	RebindingAction   = tokenapi.RebindingAction
+	RebindingMetadata = tokenapi.RebindingMetadata
+)
+
+type (
+	ValidationReport = tokenapi.ValidationReport
+	RWSetStats       = tokenapi.RWSetStats
+)
+
+// GetValidationReport returns the report carried by an error returned by the validator, if any.
+// The report tells which action failed which check.
+func GetValidationReport(err error) (*ValidationReport, bool) {
+	return tokenapi.GetValidationReport(err)
+}
+
+// ResponseError returns the error of a token request rejected by the token chaincode with the passed message and payload.
+// Its code is the one of the check recorded in the validation report of the payload, if any.
+func ResponseError(message string, payload []byte) error {
+	return tokenapi.ResponseError(message, payload)
+}
+
+type (
+	Version         = tokenapi.Version
+	VersionedLedger = tokenapi.VersionedLedger
+	ReadSet         = tokenapi.ReadSet
+)
+
 type Validator struct {
 	backend tokenapi.Validator
 }
 
-func (c *Validator) Verify(ledger Ledger, sp SignatureProvider, binding string, tr *Request) ([]interface{}, error) {
-	actions, err := c.backend.VerifyTokenRequest(ledger, &signatureProvider{sp: sp}, binding, tr.Actions)
+func (c *Validator) Verify(ledger Ledger, sp SignatureProvider, binding string, tr *Request, opts ...ValidationOption) ([]interface{}, error) {
+	actions, err := c.backend.VerifyTokenRequest(ledger, &signatureProvider{sp: sp}, binding, tr.Actions, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -39,10 +144,10 @@ func (c *Validator) Verify(ledger Ledger, sp SignatureProvider, binding string,
 	return res, nil
 }
 
-func (c *Validator) UnmarshallAndVerify(ledger Ledger, binding string, raw []byte) ([]interface{}, error) {
+func (c *Validator) UnmarshallAndVerify(ledger Ledger, binding string, raw []byte, opts ...ValidationOption) ([]interface{}, error) {
 	actions, err := c.backend.VerifyTokenRequestFromRaw(func(key string) ([]byte, error) {
 		return ledger.GetState(key)
-	}, binding, raw)
+	}, binding, raw, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -54,6 +159,39 @@ func (c *Validator) UnmarshallAndVerify(ledger Ledger, binding string, raw []byt
 	return res, nil
 }
 
+// VersionedAction is an action verified at a ledger snapshot, together with the keys read to verify it and their versions
+type VersionedAction struct {
+	Action interface{}
+	// ReadSet is the read set of the validation of the whole request the action belongs to.
+	// The request is validated atomically, and some keys, like the public parameters, condition all its actions.
+	ReadSet *ReadSet
+}
+
+// UnmarshallAndVerifyAtSnapshot behaves like UnmarshallAndVerify and attaches to each action the keys read during
+// the validation and their versions. If height is not zero, the validation fails when it reads a key written
+// at or after that block height. The read sets allow the validation to happen outside of the ledger
+// and the conflicts with later transactions to be detected, see ReadSet.Conflicts.
+func (c *Validator) UnmarshallAndVerifyAtSnapshot(ledger VersionedLedger, height uint64, binding string, raw []byte, opts ...ValidationOption) ([]*VersionedAction, error) {
+	snapshot := tokenapi.NewSnapshotLedger(ledger, height)
+	actions, err := c.backend.VerifyTokenRequestFromRaw(snapshot.GetState, binding, raw, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	readSet := snapshot.ReadSet()
+	res := make([]*VersionedAction, len(actions))
+	for i, action := range actions {
+		res[i] = &VersionedAction{Action: action, ReadSet: readSet}
+	}
+	return res, nil
+}
+
+// UnmarshalActions returns the actions of the passed serialized token request, without verifying them.
+// The request is expected to be valid, for instance because it has been committed.
+func (c *Validator) UnmarshalActions(raw []byte) ([]interface{}, error) {
+	return c.backend.UnmarshalActions(raw)
+}
+
 type signatureProvider struct {
 	sp SignatureProvider
 }
@@ -0,0 +1,59 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package token
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	tokenapi "github.com/hyperledger-labs/fabric-token-sdk/token/api"
+)
+
+// readingValidator reads the passed keys and returns one action per key
+type readingValidator struct {
+	tokenapi.Validator
+	keys []string
+}
+
+func (v *readingValidator) VerifyTokenRequestFromRaw(getState tokenapi.GetStateFnc, binding string, raw []byte, opts ...tokenapi.ValidationOption) ([]interface{}, error) {
+	var actions []interface{}
+	for _, key := range v.keys {
+		if _, err := getState(key); err != nil {
+			return nil, err
+		}
+		actions = append(actions, key)
+	}
+	return actions, nil
+}
+
+// versionedLedger returns the passed versions, the keys not in the map have never been written
+type versionedLedger map[string]*Version
+
+func (l versionedLedger) GetStateWithVersion(key string) ([]byte, *Version, error) {
+	return []byte(key), l[key], nil
+}
+
+func TestUnmarshallAndVerifyAtSnapshot(t *testing.T) {
+	ledger := versionedLedger{"pp": {BlockNum: 1}, "input": {BlockNum: 3, TxNum: 2}}
+	v := &Validator{backend: &readingValidator{keys: []string{"pp", "input", "output"}}}
+
+	actions, err := v.UnmarshallAndVerifyAtSnapshot(ledger, 0, "tx1", nil)
+	assert.NoError(t, err)
+	assert.Len(t, actions, 3)
+	for i, action := range actions {
+		assert.Equal(t, v.backend.(*readingValidator).keys[i], action.Action)
+		assert.Equal(t, []string{"pp", "input", "output"}, action.ReadSet.Keys)
+		assert.Equal(t, []*Version{{BlockNum: 1}, {BlockNum: 3, TxNum: 2}, nil}, action.ReadSet.Versions)
+	}
+	assert.NoError(t, actions[0].ReadSet.Conflicts(ledger))
+	ledger["output"] = &Version{BlockNum: 4}
+	assert.Error(t, actions[2].ReadSet.Conflicts(ledger))
+
+	// pinned before the input was written
+	_, err = v.UnmarshallAndVerifyAtSnapshot(ledger, 3, "tx1", nil)
+	assert.Error(t, err)
+}
@@ -10,6 +10,9 @@ import (
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
 )
 
+// Supply is the supply of a token type: the value issued, the value redeemed, and the value in circulation
+type Supply = api.Supply
+
 type QueryEngine struct {
 	qe api.QueryEngine
 }
@@ -22,6 +25,16 @@ func (q *QueryEngine) ListUnspentTokens() (*token2.UnspentTokens, error) {
 	return q.qe.ListUnspentTokens()
 }
 
+// UnspentTokensByEnrollmentID returns the unspent tokens, owned by this node, whose owner has the passed enrollment ID
+func (q *QueryEngine) UnspentTokensByEnrollmentID(eID string) (*token2.UnspentTokens, error) {
+	return q.qe.UnspentTokensByEnrollmentID(eID)
+}
+
+// UnspentAuditTokensByEnrollmentID returns the unspent audited tokens whose owner has the passed enrollment ID
+func (q *QueryEngine) UnspentAuditTokensByEnrollmentID(eID string) (*token2.UnspentTokens, error) {
+	return q.qe.UnspentAuditTokensByEnrollmentID(eID)
+}
+
 func (q *QueryEngine) ListAuditTokens(ids ...*token2.Id) ([]*token2.Token, error) {
 	return q.qe.ListAuditTokens(ids...)
 }
@@ -34,10 +47,38 @@ func (q *QueryEngine) PublicParams() ([]byte, error) {
 	return q.qe.PublicParams()
 }
 
+// PublicParamsVersion returns the version of the public parameters, zero if they have never been versioned.
+// A token request can pin it to be validated against these public parameters only.
+func (q *QueryEngine) PublicParamsVersion() (uint64, error) {
+	return q.qe.PublicParamsVersion()
+}
+
+// Supply returns the supply of the passed token type, as tracked on the ledger: the value issued, the value redeemed,
+// and the value in circulation
+func (q *QueryEngine) Supply(tokenType string) (*Supply, error) {
+	return q.qe.Supply(tokenType)
+}
+
+// GetTokenCommitments invokes the passed callback on each of the passed tokens, as stored on the ledger
+func (q *QueryEngine) GetTokenCommitments(ids []*token2.Id, callback api.QueryCallbackFunc) error {
+	return q.qe.GetTokenCommitments(ids, callback)
+}
+
 func (q *QueryEngine) GetTokens(inputs ...*token2.Id) ([]*token2.Token, error) {
 	return q.qe.GetTokens(inputs...)
 }
 
+// GetTokenInfos invokes the passed callback on each of the passed tokens owned by this node, with the information
+// needed to spend it, like the opening of its commitments
+func (q *QueryEngine) GetTokenInfos(ids []*token2.Id, callback api.QueryCallbackFunc) error {
+	return q.qe.GetTokenInfos(ids, callback)
+}
+
+// GetState returns the value stored under the passed ledger key, as committed in the vault
+func (q *QueryEngine) GetState(key string) ([]byte, error) {
+	return q.qe.GetState(key)
+}
+
 type Vault struct {
 	v api.Vault
 }
@@ -7,6 +7,7 @@ package token
 
 import (
 	"github.com/hyperledger-labs/fabric-smart-client/platform/view/view"
+	"github.com/pkg/errors"
 
 	api2 "github.com/hyperledger-labs/fabric-token-sdk/token/api"
 	token2 "github.com/hyperledger-labs/fabric-token-sdk/token/token"
@@ -18,6 +19,32 @@ type ListTokensOptions struct {
 
 type ListTokensOption func(*ListTokensOptions) error
 
+type RecipientIdentityOptions struct {
+	Counterparty view.Identity
+	Fresh        bool
+}
+
+type RecipientIdentityOption func(*RecipientIdentityOptions) error
+
+// WithCounterparty returns a recipient identity option that tells the wallet the party the identity is requested for.
+// Wallets following the FreshPerCounterparty pseudonym policy reuse the identity already derived for the same party.
+func WithCounterparty(counterparty view.Identity) RecipientIdentityOption {
+	return func(o *RecipientIdentityOptions) error {
+		o.Counterparty = counterparty
+		return nil
+	}
+}
+
+// WithFreshPseudonym returns a recipient identity option that requires a new pseudonym, unlinkable to the ones
+// already derived, whatever the pseudonym policy of the wallet. The pseudonym is registered with the wallet as any other.
+// Wallets using their long-term identity as recipient identity ignore it.
+func WithFreshPseudonym() RecipientIdentityOption {
+	return func(o *RecipientIdentityOptions) error {
+		o.Fresh = true
+		return nil
+	}
+}
+
 // WithType returns a list token option that filter by the passed token type.
 // If the passed token type is the empty string, all token types are selected.
 func WithType(tokenType string) ListTokensOption {
@@ -29,6 +56,7 @@ func WithType(tokenType string) ListTokensOption {
 
 type WalletManager struct {
 	ts api2.TokenManagerService
+	ms *ManagementService
 }
 
 func (t *WalletManager) GenerateIssuerKeyPair(tokenType string) (api2.Key, api2.Key, error) {
@@ -39,6 +67,28 @@ func (t *WalletManager) RegisterIssuer(label string, sk api2.Key, pk api2.Key) e
 	return t.ts.RegisterIssuer(label, sk, pk)
 }
 
+// RegisterIssuerCredential registers the anonymous issuer, for the token type passed as label, with the passed
+// secret key and the credential issued to it by the authority of the issuer accumulator of the public parameters
+func (t *WalletManager) RegisterIssuerCredential(label string, sk api2.Key, credential []byte) error {
+	return t.ts.RegisterIssuerCredential(label, sk, credential)
+}
+
+// RegisterOwnerWallet registers, at runtime, the owner wallet with the passed id whose long-term identity
+// has the msp configuration, of the passed msp, at the passed path. Registering again a known wallet is a no-op.
+func (t *WalletManager) RegisterOwnerWallet(id string, mspID string, path string) error {
+	return t.ts.RegisterWallet(api2.OwnerRole, id, mspID, path)
+}
+
+// RegisterIssuerWallet registers, at runtime, the issuer wallet with the passed id, see RegisterOwnerWallet
+func (t *WalletManager) RegisterIssuerWallet(id string, mspID string, path string) error {
+	return t.ts.RegisterWallet(api2.IssuerRole, id, mspID, path)
+}
+
+// RegisterAuditorWallet registers, at runtime, the auditor wallet with the passed id, see RegisterOwnerWallet
+func (t *WalletManager) RegisterAuditorWallet(id string, mspID string, path string) error {
+	return t.ts.RegisterWallet(api2.AuditorRole, id, mspID, path)
+}
+
 func (t *WalletManager) RegisterRecipientIdentity(id view.Identity, auditInfo []byte, metadata []byte) error {
 	return t.ts.RegisterRecipientIdentity(id, auditInfo, metadata)
 }
@@ -54,9 +104,34 @@ func (t *WalletManager) Wallet(identity view.Identity) *Wallet {
 func (t *WalletManager) OwnerWallet(id string) *OwnerWallet {
 	w := t.ts.OwnerWallet(id)
 	if w == nil {
-		return nil
+		if !t.restoreWallet(api2.OwnerRole, "owner", id) {
+			return nil
+		}
+		if w = t.ts.OwnerWallet(id); w == nil {
+			return nil
+		}
+	}
+	return &OwnerWallet{w: w, ms: t.ms}
+}
+
+// NewOwnerWallet creates a new owner wallet with the passed id. The credentials of the wallet are issued by the
+// registered CredentialIssuer and stored under the directory configured with token.wallets.path.
+// The wallet is registered again, after a restart, the first time it is looked up.
+func (t *WalletManager) NewOwnerWallet(id string) (*OwnerWallet, error) {
+	if len(id) == 0 {
+		return nil, errors.New("wallet id not specified")
+	}
+	if t.OwnerWallet(id) != nil {
+		return nil, errors.Errorf("owner wallet [%s] already exists", id)
 	}
-	return &OwnerWallet{w: w}
+	if err := t.newWallet(api2.OwnerRole, "owner", id); err != nil {
+		return nil, errors.WithMessagef(err, "failed creating owner wallet [%s]", id)
+	}
+	w := t.ts.OwnerWallet(id)
+	if w == nil {
+		return nil, errors.Errorf("owner wallet [%s] not found after registration", id)
+	}
+	return &OwnerWallet{w: w, ms: t.ms}, nil
 }
 
 func (t *WalletManager) OwnerWalletByIdentity(identity view.Identity) *OwnerWallet {
@@ -64,7 +139,7 @@ func (t *WalletManager) OwnerWalletByIdentity(identity view.Identity) *OwnerWall
 	if w == nil {
 		return nil
 	}
-	return &OwnerWallet{w: w}
+	return &OwnerWallet{w: w, ms: t.ms}
 }
 
 func (t *WalletManager) IssuerWallet(id string) *IssuerWallet {
@@ -165,19 +240,36 @@ func (a *CertifierWallet) GetSigner(id view.Identity) (api2.Signer, error) {
 }
 
 type OwnerWallet struct {
-	w api2.OwnerWallet
+	w  api2.OwnerWallet
+	ms *ManagementService
 }
 
 func (o *OwnerWallet) ID() string {
 	return o.w.ID()
 }
 
+// TokenService returns the token management service this wallet belongs to
+func (o *OwnerWallet) TokenService() *ManagementService {
+	return o.ms
+}
+
 func (o *OwnerWallet) Contains(identity view.Identity) bool {
 	return o.w.Contains(identity)
 }
 
-func (o *OwnerWallet) GetRecipientIdentity() (view.Identity, error) {
-	return o.w.GetRecipientIdentity()
+func (o *OwnerWallet) GetRecipientIdentity(opts ...RecipientIdentityOption) (view.Identity, error) {
+	options := &RecipientIdentityOptions{}
+	for _, opt := range opts {
+		if err := opt(options); err != nil {
+			return nil, err
+		}
+	}
+	return o.w.GetRecipientIdentity(&api2.RecipientIdentityOptions{Counterparty: options.Counterparty, Fresh: options.Fresh})
+}
+
+// PseudonymPolicy returns the policy this wallet follows to derive recipient identities
+func (o *OwnerWallet) PseudonymPolicy() api2.PseudonymPolicy {
+	return o.w.PseudonymPolicy()
 }
 
 func (o *OwnerWallet) GetAuditInfo(id view.Identity) ([]byte, error) {
@@ -200,6 +292,43 @@ func (o *OwnerWallet) ListTokens(opts ...ListTokensOption) (*token2.UnspentToken
 	return o.w.ListTokens(compiledOpts)
 }
 
+// EnrollmentID returns the enrollment ID of the owner of this wallet
+func (o *OwnerWallet) EnrollmentID() string {
+	return o.w.EnrollmentID()
+}
+
+// ExportRecipientIdentities returns the recipient identities of this wallet together with their audit information,
+// to be imported by the other nodes using the same wallet.
+func (o *OwnerWallet) ExportRecipientIdentities() ([]*api2.RecipientIdentity, error) {
+	return o.w.ExportRecipientIdentities()
+}
+
+// ImportRecipientIdentities adds to this wallet the recipient identities exported by another node using the same wallet
+func (o *OwnerWallet) ImportRecipientIdentities(ids []*api2.RecipientIdentity) error {
+	return o.w.ImportRecipientIdentities(ids)
+}
+
+// ExportTokens returns the knowledge this wallet has of the passed unspent tokens, to be imported by another
+// owner wallet of this node with ImportTokens
+func (o *OwnerWallet) ExportTokens(ids ...*token2.Id) (*api2.TokenExport, error) {
+	return o.w.ExportTokens(ids)
+}
+
+// ImportTokens moves to this wallet the tokens exported by another owner wallet of this node, without a transfer
+func (o *OwnerWallet) ImportTokens(export *api2.TokenExport) (*api2.TokenReassignment, error) {
+	return o.w.ImportTokens(export)
+}
+
+// TokenReassignments returns the audit trail of the tokens moved from or to this wallet without a transfer
+func (o *OwnerWallet) TokenReassignments() ([]*api2.TokenReassignment, error) {
+	return o.w.TokenReassignments()
+}
+
+// CanSpend returns true if the tokens owned by the passed identity can be spent from this node
+func (o *OwnerWallet) CanSpend(identity view.Identity) bool {
+	return o.w.CanSpend(identity)
+}
+
 type IssuerWallet struct {
 	w api2.IssuerWallet
 }